@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/auth"
+	"hearth/internal/config"
+	"hearth/internal/database/postgres"
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// adminCommand is one `hearth admin` subcommand. run gets the arguments
+// after the subcommand's name and returns the exit code.
+type adminCommand struct {
+	usage string
+	run   func(ctx context.Context, admin *services.InstanceAdminService, args []string) int
+}
+
+var adminCommands = map[string]adminCommand{
+	"create-user":          {"[-admin] [-password PASSWORD] -email EMAIL -username USERNAME", runAdminCreateUser},
+	"grant-instance-admin": {"[-revoke] USER", runAdminGrantInstanceAdmin},
+	"reset-password":       {"[-password PASSWORD] USER", runAdminResetPassword},
+	"delete-server":        {"-yes SERVER_ID", runAdminDeleteServer},
+	"stats":                {"[-json]", runAdminStats},
+}
+
+// runAdmin implements `hearth admin` and returns the exit code. The
+// subcommands talk to the database in DATABASE_URL (or CONFIG_FILE)
+// directly, so they work whether or not the instance is running.
+func runAdmin(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		adminUsage(os.Stderr)
+		return 2
+	}
+	cmd, ok := adminCommands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "admin: unknown command %q\n", args[0])
+		adminUsage(os.Stderr)
+		return 2
+	}
+
+	if err := config.ReadFile(os.Getenv("CONFIG_FILE")); err != nil {
+		fmt.Fprintf(os.Stderr, "admin: %v\n", err)
+		return 1
+	}
+	cfg := config.Load()
+	db, err := postgres.NewDBFromURL(cfg.DatabaseURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "admin: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// A fresh database gets its schema, so the first admin can be created
+	// before the instance has ever started
+	if err := postgres.Migrate(ctx, db); err != nil {
+		fmt.Fprintf(os.Stderr, "admin: failed to run migrations: %v\n", err)
+		return 1
+	}
+
+	repos := postgres.NewRepositories(db)
+	jwtService := auth.NewJWTService(cfg.SecretKey, 15*time.Minute, 7*24*time.Hour)
+	admin := services.NewInstanceAdminService(
+		repos.Users,
+		repos.Servers,
+		postgres.NewInstanceStatsRepository(db),
+		services.NewSessionService(repos.Sessions, jwtService),
+	)
+	return cmd.run(ctx, admin, args[1:])
+}
+
+func adminUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: hearth admin COMMAND [flags]")
+	fmt.Fprintln(w, "\nUSER is a user ID, email or username. Passwords not given with -password")
+	fmt.Fprintln(w, "are read from the first line of stdin.")
+	fmt.Fprintln(w, "\ncommands:")
+	names := make([]string, 0, len(adminCommands))
+	for name := range adminCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s %s\n", name, adminCommands[name].usage)
+	}
+}
+
+func runAdminCreateUser(ctx context.Context, admin *services.InstanceAdminService, args []string) int {
+	fs := flag.NewFlagSet("create-user", flag.ContinueOnError)
+	email := fs.String("email", "", "email to log in with")
+	username := fs.String("username", "", "username")
+	password := fs.String("password", "", "password (default: read from stdin)")
+	instanceAdmin := fs.Bool("admin", false, "make the user an instance admin")
+	jsonOut := fs.Bool("json", false, "print the user as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *email == "" || *username == "" {
+		fmt.Fprintln(os.Stderr, "admin: -email and -username are required")
+		return 2
+	}
+	if !strings.Contains(*email, "@") || !strings.Contains(*email, ".") {
+		fmt.Fprintln(os.Stderr, "admin: invalid email format")
+		return 2
+	}
+	pw, err := adminPassword(*password)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "admin: %v\n", err)
+		return 2
+	}
+
+	user, err := admin.CreateUser(ctx, *email, *username, pw, *instanceAdmin)
+	if err != nil {
+		return adminFailed(err)
+	}
+	printAdminUser(user, *jsonOut)
+	return 0
+}
+
+func runAdminGrantInstanceAdmin(ctx context.Context, admin *services.InstanceAdminService, args []string) int {
+	fs := flag.NewFlagSet("grant-instance-admin", flag.ContinueOnError)
+	revoke := fs.Bool("revoke", false, "take instance admin away instead")
+	jsonOut := fs.Bool("json", false, "print the user as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "admin: grant-instance-admin takes one USER")
+		return 2
+	}
+
+	user, err := admin.FindUser(ctx, fs.Arg(0))
+	if err != nil {
+		return adminFailed(err)
+	}
+	user, err = admin.SetInstanceAdmin(ctx, user.ID, !*revoke)
+	if err != nil {
+		return adminFailed(err)
+	}
+	printAdminUser(user, *jsonOut)
+	return 0
+}
+
+func runAdminResetPassword(ctx context.Context, admin *services.InstanceAdminService, args []string) int {
+	fs := flag.NewFlagSet("reset-password", flag.ContinueOnError)
+	password := fs.String("password", "", "new password (default: read from stdin)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "admin: reset-password takes one USER")
+		return 2
+	}
+
+	user, err := admin.FindUser(ctx, fs.Arg(0))
+	if err != nil {
+		return adminFailed(err)
+	}
+	pw, err := adminPassword(*password)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "admin: %v\n", err)
+		return 2
+	}
+	if err := admin.ResetPassword(ctx, user.ID, pw); err != nil {
+		return adminFailed(err)
+	}
+	fmt.Printf("Reset the password of %s (%s) and logged them out everywhere\n", user.Username, user.ID)
+	return 0
+}
+
+func runAdminDeleteServer(ctx context.Context, admin *services.InstanceAdminService, args []string) int {
+	fs := flag.NewFlagSet("delete-server", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "confirm the server and everything in it should be deleted")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "admin: delete-server takes one SERVER_ID")
+		return 2
+	}
+	serverID, err := uuid.Parse(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "admin: invalid server ID")
+		return 2
+	}
+	if !*yes {
+		fmt.Fprintln(os.Stderr, "admin: deleting a server can't be undone; pass -yes to confirm")
+		return 2
+	}
+
+	server, err := admin.DeleteServer(ctx, serverID)
+	if err != nil {
+		return adminFailed(err)
+	}
+	fmt.Printf("Deleted server %s (%s)\n", server.Name, server.ID)
+	return 0
+}
+
+func runAdminStats(ctx context.Context, admin *services.InstanceAdminService, args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	jsonOut := fs.Bool("json", false, "print the stats as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	stats, err := admin.Stats(ctx)
+	if err != nil {
+		return adminFailed(err)
+	}
+	if *jsonOut {
+		printAdminJSON(stats)
+		return 0
+	}
+	fmt.Printf("Users:            %d\n", stats.Users)
+	fmt.Printf("Bots:             %d\n", stats.Bots)
+	fmt.Printf("Servers:          %d\n", stats.Servers)
+	fmt.Printf("Channels:         %d\n", stats.Channels)
+	fmt.Printf("Messages (est.):  %d\n", stats.Messages)
+	fmt.Printf("Active sessions:  %d\n", stats.ActiveSessions)
+	fmt.Printf("Suspended users:  %d\n", stats.SuspendedUsers)
+	return 0
+}
+
+// adminPassword returns the password given as a flag, or else the first
+// line of stdin, so scripts can keep passwords out of process listings
+func adminPassword(flagValue string) (string, error) {
+	if flagValue != "" {
+		return flagValue, nil
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("no password given with -password or on stdin")
+	}
+	return password, nil
+}
+
+func printAdminUser(user *models.User, jsonOut bool) {
+	instanceAdmin := user.Flags&models.UserFlagStaff != 0
+	if jsonOut {
+		printAdminJSON(map[string]interface{}{
+			"id":             user.ID,
+			"username":       user.Username,
+			"email":          user.Email,
+			"instance_admin": instanceAdmin,
+		})
+		return
+	}
+	fmt.Printf("%s  %s  %s  instance admin: %t\n", user.ID, user.Username, user.Email, instanceAdmin)
+}
+
+func printAdminJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func adminFailed(err error) int {
+	fmt.Fprintf(os.Stderr, "admin: %v\n", err)
+	return 1
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"hearth/internal/loadtest"
+)
+
+// runLoadtest implements `hearth loadtest` and returns the exit code
+func runLoadtest(args []string) int {
+	cfg := loadtest.DefaultConfig()
+
+	fs := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	fs.StringVar(&cfg.BaseURL, "url", cfg.BaseURL, "base URL of the instance under test")
+	fs.IntVar(&cfg.Users, "users", cfg.Users, "simulated users, each with its own server and gateway session")
+	fs.DurationVar(&cfg.Duration, "duration", cfg.Duration, "how long to apply load after setup")
+	fs.Float64Var(&cfg.MessagesPerSecond, "messages", cfg.MessagesPerSecond, "messages sent per second")
+	fs.Float64Var(&cfg.ReadsPerSecond, "reads", cfg.ReadsPerSecond, "message history reads per second")
+	fs.Float64Var(&cfg.ReactionsPerSecond, "reactions", cfg.ReactionsPerSecond, "reactions added per second")
+	fs.Float64Var(&cfg.ChurnPerSecond, "churn", cfg.ChurnPerSecond, "gateway sessions reconnected per second")
+	fs.IntVar(&cfg.SetupConcurrency, "setup-concurrency", cfg.SetupConcurrency, "users created at once")
+	fs.IntVar(&cfg.MaxInFlight, "max-in-flight", cfg.MaxInFlight, "concurrent operations before new ones are skipped")
+	fs.DurationVar(&cfg.Timeout, "timeout", cfg.Timeout, "timeout for each request")
+	fs.StringVar(&cfg.Prefix, "prefix", "", "username prefix (default: unique per run)")
+	maxErrorRate := fs.Float64("max-error-rate", 0.01, "exit non-zero if any operation's error rate is higher")
+	jsonOut := fs.Bool("json", false, "print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report, err := loadtest.Run(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "loadtest: %v\n", err)
+		return 1
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		report.Write(os.Stdout)
+	}
+
+	if rate := report.ErrorRate(); rate > *maxErrorRate {
+		fmt.Fprintf(os.Stderr, "loadtest: error rate %.2f%% is over %.2f%%\n", rate*100, *maxErrorRate*100)
+		return 1
+	}
+	return 0
+}
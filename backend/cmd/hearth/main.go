@@ -2,10 +2,16 @@ package main
 
 import (
 	"context"
+	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"syscall"
 	"time"
 
@@ -17,19 +23,33 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"hearth/internal/api"
 	"hearth/internal/api/handlers"
 	"hearth/internal/api/middleware"
 	"hearth/internal/auth"
+	"hearth/internal/auth/oauth"
 	"hearth/internal/cache"
+	"hearth/internal/captcha"
+	"hearth/internal/chaos"
+	"hearth/internal/clamav"
 	"hearth/internal/config"
+	"hearth/internal/database/instrument"
 	"hearth/internal/database/postgres"
 	"hearth/internal/events"
+	"hearth/internal/ipreputation"
+	"hearth/internal/mail"
 	"hearth/internal/metrics"
+	"hearth/internal/models"
+	"hearth/internal/preflight"
 	"hearth/internal/pubsub"
+	"hearth/internal/ratelimit"
+	"hearth/internal/push"
 	"hearth/internal/services"
+	"hearth/internal/storage"
+	"hearth/internal/voice"
 	"hearth/internal/websocket"
 )
 
@@ -45,6 +65,16 @@ func main() {
 		return
 	}
 
+	// Load test another instance
+	if len(os.Args) > 1 && os.Args[1] == "loadtest" {
+		os.Exit(runLoadtest(os.Args[2:]))
+	}
+
+	// Manage the instance from scripts
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		os.Exit(runAdmin(os.Args[2:]))
+	}
+
 	log.Printf("🔥 Hearth %s (%s)", Version, Commit)
 
 	// Initialize Prometheus metrics early
@@ -52,15 +82,58 @@ func main() {
 	log.Printf("📊 Prometheus metrics initialized (instance: %s)", metrics.GetInstanceLabel())
 	_ = wsMetrics // Used implicitly via metrics.GetMetrics()
 
-	// Load configuration
+	// Load configuration: the environment, then CONFIG_FILE if it's set
+	configFile := os.Getenv("CONFIG_FILE")
+	if err := config.ReadFile(configFile); err != nil {
+		log.Fatalf("Failed to read config file: %v", err)
+	}
 	cfg := config.Load()
 
-	// Connect to database
-	db, err := postgres.NewDBFromURL(cfg.DatabaseURL)
+	// Fault injection for resilience testing in CI and staging. Injectors
+	// are armed once startup is done, so connecting and migrating succeed
+	var pgChaos, redisChaos *chaos.Injector
+	if cfg.ChaosEnabled {
+		for _, faults := range []chaos.Faults{cfg.ChaosPostgres, cfg.ChaosRedis} {
+			if err := faults.Validate(); err != nil {
+				log.Fatalf("Invalid fault injection config: %v", err)
+			}
+		}
+		log.Printf("⚠️  FAULT INJECTION ENABLED: postgres %s; redis %s", cfg.ChaosPostgres, cfg.ChaosRedis)
+		if cfg.ChaosPostgres.Active() {
+			pgChaos = chaos.NewInjector("postgres", cfg.ChaosPostgres)
+		}
+		if cfg.ChaosRedis.Active() {
+			redisChaos = chaos.NewInjector("redis", cfg.ChaosRedis)
+		}
+	}
+	var dbWrap []func(driver.Connector) driver.Connector
+	if pgChaos != nil {
+		dbWrap = append(dbWrap, func(c driver.Connector) driver.Connector {
+			return chaos.WrapConnector(c, pgChaos)
+		})
+	}
+
+	// Connect to database. Every query is timed per repository, service
+	// and route, and slow ones are logged
+	queryRecorder := instrument.NewRecorder(metrics.NewDatabaseMetrics(cfg.MetricsDetailedLabels), cfg.SlowQueryThreshold)
+	poolConfig := postgres.PoolConfig{
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: cfg.DBConnMaxLifetime,
+		ConnMaxIdleTime: cfg.DBConnMaxIdleTime,
+	}
+	db, err := postgres.NewInstrumentedDBFromURL(cfg.DatabaseURL, poolConfig, queryRecorder, dbWrap...)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
+	prometheus.MustRegister(metrics.NewDBPoolCollector("primary", db.Stats))
+	log.Printf("Database pool: %d max open, %d max idle, %v max lifetime",
+		poolConfig.MaxOpenConns, poolConfig.MaxIdleConns, poolConfig.ConnMaxLifetime)
+	// Every CPU can be serving a request that needs a connection
+	for _, warning := range postgres.PoolWarnings(context.Background(), db, poolConfig, runtime.GOMAXPROCS(0)) {
+		log.Printf("⚠️  Database pool: %s", warning)
+	}
 
 	// Run migrations
 	if err := postgres.Migrate(context.Background(), db); err != nil {
@@ -81,12 +154,16 @@ func main() {
 		QueueSize:      cfg.BcryptPoolQueue,    // 0 = Workers * 10 (auto)
 		DefaultTimeout: cfg.BcryptPoolTimeout,
 		Cost:           12, // Production bcrypt cost
+		// Grows while logins queue and the CPU has room, up to MaxWorkers
+		MaxWorkers:      cfg.BcryptPoolMaxWorkers,
+		TargetQueueWait: cfg.BcryptPoolTargetWait,
 	}
 	bcryptPool := auth.NewBcryptPool(bcryptPoolConfig)
 	auth.SetGlobalPool(bcryptPool)
 	defer bcryptPool.Close()
-	log.Printf("Bcrypt worker pool initialized: %d workers, queue size %d, timeout %v",
-		bcryptPool.Stats().Workers, bcryptPool.Stats().QueueSize, cfg.BcryptPoolTimeout)
+	prometheus.MustRegister(metrics.NewPasswordPoolCollector(bcryptPool.Stats))
+	log.Printf("Bcrypt worker pool initialized: %d-%d workers, queue size %d, timeout %v",
+		bcryptPool.Stats().MinWorkers, bcryptPool.Stats().MaxWorkers, bcryptPool.Stats().QueueSize, cfg.BcryptPoolTimeout)
 
 	// Initialize auth services
 	jwtService := auth.NewJWTService(
@@ -101,10 +178,39 @@ func main() {
 
 	// Configure graceful shutdown draining
 	drainConfig := &websocket.DrainConfig{
-		DrainTimeout: cfg.DrainTimeout,
-		GracePeriod:  cfg.DrainGracePeriod,
+		DrainTimeout:       cfg.DrainTimeout,
+		GracePeriod:        cfg.DrainGracePeriod,
+		ReconnectURL:       cfg.DrainReconnectURL,
+		StaggerWindow:      cfg.DrainStaggerWindow,
+		ReconnectBatchSize: cfg.DrainBatchSize,
+	}
+	log.Printf("⚙️  Drain config: timeout=%v, grace=%v, stagger=%v (batch %d), reconnect=%s",
+		drainConfig.DrainTimeout, drainConfig.GracePeriod, drainConfig.StaggerWindow,
+		drainConfig.ReconnectBatchSize, drainConfig.ReconnectURL)
+
+	gatewayConfig := websocket.DefaultGatewayConfig()
+	gatewayConfig.MaxSessionsPerUser = cfg.GatewayMaxSessions
+	gatewayConfig.EnforceTokenExpiry = cfg.GatewayEnforceTokenExpiry
+	gatewayConfig.HeartbeatInterval = cfg.GatewayHeartbeatInterval
+	gatewayConfig.MissedHeartbeats = cfg.GatewayMissedHeartbeats
+	gatewayConfig.SessionTimeout = cfg.GatewayResumeWindow
+	gatewayConfig.ResumeBufferSize = cfg.GatewayResumeBuffer
+	gatewayConfig.ResumeURL = cfg.DrainReconnectURL
+	gatewayConfig.OutboundQueueSize = cfg.GatewayOutboundQueue
+	gatewayConfig.OutboundLagThreshold = cfg.GatewayOutboundLag
+	gatewayConfig.OutboundMaxLag = cfg.GatewayOutboundMaxLag
+	if policy, ok := websocket.ParseOutboundPolicy(cfg.GatewayOutboundPolicy); ok {
+		gatewayConfig.OutboundPolicy = policy
+	} else {
+		log.Printf("⚠️  Unknown GATEWAY_OUTBOUND_POLICY %q, using %s", cfg.GatewayOutboundPolicy, gatewayConfig.OutboundPolicy)
 	}
-	log.Printf("⚙️  Drain config: timeout=%v, grace=%v", drainConfig.DrainTimeout, drainConfig.GracePeriod)
+
+	// Per-server rate limits, so one server can't starve the rest; counted
+	// in Redis when it's available so they span instances
+	serverThrottleService := services.NewServerThrottleService(repos.ServerThrottles, repos.Servers, models.ServerThrottleLimits{
+		MessagesPerSecond: cfg.ServerMessagesPerSecond,
+		EventsPerSecond:   cfg.ServerEventsPerSecond,
+	})
 
 	// Initialize WebSocket hub (distributed with Redis, or local fallback)
 	var wsHub websocket.HubInterface
@@ -119,11 +225,15 @@ func main() {
 		localHub := websocket.NewHubWithDrainConfig(drainConfig)
 		wsHub = localHub
 		go localHub.Run(ctx)
-		wsGateway = websocket.NewGateway(localHub, jwtService, nil)
-		_ = websocket.NewEventBridge(localHub, eventBus)
+		wsGateway = websocket.NewGateway(localHub, jwtService, gatewayConfig)
+		bridge := websocket.NewEventBridge(localHub, eventBus)
+		bridge.SetServerThrottle(serverThrottleService)
 	} else {
 		defer redisCache.Close()
 		log.Printf("✅ Redis connected: %s", cfg.RedisURL)
+		if redisChaos != nil {
+			redisCache.Client().AddHook(chaos.RedisHook(redisChaos))
+		}
 
 		// Generate unique node ID for this instance
 		nodeID := os.Getenv("HEARTH_NODE_ID")
@@ -134,12 +244,24 @@ func main() {
 		log.Printf("📡 Node ID: %s", nodeID)
 
 		// Initialize Redis Pub/Sub for distributed messaging
-		ps, err := pubsub.New(cfg.RedisURL, nodeID)
+		ps, err := pubsub.NewWithOptions(cfg.RedisURL, nodeID, pubsub.Options{
+			Transport:    cfg.PubSubTransport,
+			StreamMaxLen: int64(cfg.PubSubStreamMaxLen),
+			StreamTTL:    cfg.PubSubStreamTTL,
+			NodeRouting:  cfg.PubSubNodeRouting,
+		})
 		if err != nil {
 			log.Fatalf("Failed to initialize Redis pub/sub: %v", err)
 		}
 		defer ps.Close()
-		log.Printf("✅ Redis Pub/Sub initialized for distributed messaging")
+		if redisChaos != nil {
+			ps.Client().AddHook(chaos.RedisHook(redisChaos))
+		}
+		ps.SetRecorder(metrics.NewPubSubMetrics())
+		log.Printf("✅ Redis %s transport initialized for distributed messaging", ps.Transport())
+		if ps.NodeRouting() {
+			log.Printf("📡 Events are routed only to nodes with interested clients")
+		}
 
 		// Initialize Distributed WebSocket hub with drain config
 		distributedHub := websocket.NewDistributedHubWithDrainConfig(ps, drainConfig)
@@ -147,46 +269,180 @@ func main() {
 		go distributedHub.Run(ctx)
 
 		// Initialize WebSocket gateway with distributed hub
-		wsGateway = websocket.NewGateway(distributedHub, jwtService, nil)
+		wsGateway = websocket.NewGateway(distributedHub, jwtService, gatewayConfig)
+		// Sessions are kept in Redis so clients can resume on any node
+		wsGateway.SetResumeStore(websocket.NewRedisResumeStore(redisCache.Client(), cfg.GatewayResumeWindow, cfg.GatewayResumeBuffer))
+		// On drain, sessions are saved before clients are told to reconnect,
+		// so they resume on another node without a full re-sync
+		if cfg.DrainHandoff {
+			wsGateway.EnableSessionHandoff()
+		}
 
 		// Initialize distributed event bridge (connects domain events to WebSocket via Redis)
-		_ = websocket.NewDistributedEventBridge(ctx, distributedHub, eventBus)
+		bridge := websocket.NewDistributedEventBridge(ctx, distributedHub, eventBus)
+		bridge.SetServerThrottle(serverThrottleService)
+		serverThrottleService.SetCounter(redisCache)
+	}
+	wsGateway.SetServerThrottle(serverThrottleService)
+	prometheus.MustRegister(metrics.NewGatewayLoadCollector(wsHub))
+
+	// Users, servers, channels, members and role lists are read through
+	// Redis when it's available, and dropped from it as events change them.
+	// Channels, roles and servers are also kept in a small per-node cache.
+	var objectCache services.CacheService
+	if redisCache != nil {
+		cacheMetrics := metrics.NewCacheMetrics()
+		var shared services.CacheService = redisCache
+		if cfg.LocalCacheSize > 0 && cfg.LocalCacheTTL > 0 {
+			tiered := cache.NewTieredCache(redisCache, cfg.LocalCacheSize, cfg.LocalCacheTTL)
+			tiered.SetRecorder(cacheMetrics)
+			go tiered.Listen(ctx, redisCache.Subscribe(ctx, cache.InvalidationChannel))
+			shared = tiered
+		}
+		objectCache = services.NewObservedCache(shared, cacheMetrics)
+		services.NewCacheInvalidator(objectCache).Start(serviceBus)
 	}
 
 	// Initialize services
-	quotaService := services.NewQuotaService(cfg.Quotas, nil, nil, nil)
-	userService := services.NewUserService(repos.Users, nil, serviceBus)
-	authService := services.NewAuthService(repos.Users, jwtService)
+	quotaService := services.NewQuotaService(cfg.Quotas, nil, repos.Users, nil)
+	quotaService.SetOverrides(repos.QuotaOverrides)
+	userService := services.NewUserService(repos.Users, objectCache, serviceBus)
+	userService.SetUsernameRepository(repos.Users, cfg.UsernameChangeCooldown)
+	userService.SetUserBatchRepository(repos.Users)
+	usernameRules := services.NewUsernameRuleService(repos.UsernameRules)
+	userService.SetUsernamePolicy(usernameRules)
+	sessionService := services.NewSessionService(repos.Sessions, jwtService)
+	wsGateway.SetSessions(sessionService)
+	// Suspended users are logged out, and refused until it's lifted
+	suspensionService := services.NewUserSuspensionService(repos.UserSuspensions, repos.Users, sessionService)
+	suspensionService.SetDisconnector(wsGateway)
+	sessionService.SetSuspensions(suspensionService)
+	// Email, for password resets and new login alerts
+	var mailer *mail.SMTP
+	if cfg.SMTPHost != "" {
+		mailer, err = mail.New(mail.Config{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		})
+		if err != nil {
+			log.Fatalf("Invalid SMTP config: %v", err)
+		}
+	}
+	loginSecurity := services.NewLoginSecurityService(repos.LoginSecurity, services.LoginSecurityConfig{
+		LockoutThreshold:   cfg.LockoutThreshold,
+		LockoutDuration:    cfg.LockoutDuration,
+		MaxLockoutDuration: cfg.LockoutMaxDuration,
+		FailureWindow:      cfg.LockoutFailureWindow,
+	})
+	if mailer != nil && cfg.NewDeviceAlerts {
+		loginSecurity.SetMailer(mailer, strings.TrimRight(cfg.PublicURL, "/")+"/forgot-password")
+	}
+	authService := services.NewAuthServiceWithLoginSecurity(repos.Users, jwtService, usernameRules, sessionService, loginSecurity)
 	roleService := services.NewRoleService(
 		repos.Roles,
 		repos.Servers,
-		nil, // cache
+		objectCache,
 		serviceBus,
 	)
+	roleService.SetPageRepository(repos.Roles)
 	serverService := services.NewServerService(
 		repos.Servers,
 		repos.Channels,
 		repos.Roles,
 		quotaService,
-		nil, // cache
+		objectCache,
 		serviceBus,
 	)
+	serverService.SetModerationExpiryRepository(repos.Servers)
+	serverService.SetOwnershipTransferRepository(repos.Servers)
+	serverService.SetBanImportRepository(repos.BanImports)
+	serverService.SetPageRepository(repos.Servers)
+	serverService.SetMemberSearchRepository(repos.Servers)
+	memberVerificationService := services.NewMemberVerificationService(repos.MemberVerification, repos.Servers, repos.Roles, serviceBus)
+	serverService.SetMembershipScreening(memberVerificationService)
+	onboardingService := services.NewOnboardingService(repos.Onboarding, repos.Servers, repos.Channels, repos.Roles, serviceBus)
+	serverService.SetWelcomeScreens(onboardingService)
+	blocklistService := services.NewBlocklistService(repos.Blocklists, repos.Servers, repos.Roles, repos.Users, serviceBus)
+	serverService.SetBlocklists(blocklistService)
+	botTierService := services.NewBotTierService(repos.BotTiers, repos.Users, cfg.Quotas.API)
+	if redisCache != nil {
+		botTierService.SetRequestLimiter(ratelimit.NewLimiter(redisCache))
+	}
+	serverService.SetBotTiers(botTierService)
+	contentErasureService := services.NewContentErasureService(repos.ContentErasures)
+	wsGateway.SetGuildJoiner(serverService)
+	wsGateway.SetMemberListSource(serverService)
+	wsGateway.SetMemberRequestSource(serverService)
+	settingsRepo := postgres.NewSettingsRepository(db)
+	privacyService := services.NewPrivacyService(settingsRepo, repos.Users, repos.Servers)
+	wsGateway.SetPresenceFilter(privacyService)
+
+	// OAuth / OIDC login
+	oauthLoginService := services.NewOAuthLoginService(repos.OAuthIdentities, repos.Users, settingsRepo, jwtService, sessionService)
+	oauthLoginService.SetUsernamePolicy(usernameRules)
+	oauthLoginService.SetLoginSecurity(loginSecurity)
+	oauthDefaults := models.DefaultUserSettings(uuid.Nil)
+	if cfg.OAuthDefaultSettings != "" {
+		if err := json.Unmarshal([]byte(cfg.OAuthDefaultSettings), oauthDefaults); err != nil {
+			log.Fatalf("Invalid OAUTH_DEFAULT_SETTINGS: %v", err)
+		}
+	}
+	oauthLoginService.SetProvisioning(cfg.OAuthAutoProvision, oauthDefaults)
+	var oauthProviders []*oauth.Provider
+	for _, p := range cfg.OAuthProviders {
+		var providerCfg oauth.ProviderConfig
+		switch p.Name {
+		case "google":
+			providerCfg = oauth.Google(p.ClientID, p.ClientSecret)
+		case "github":
+			providerCfg = oauth.GitHub(p.ClientID, p.ClientSecret)
+		default:
+			if p.Issuer == "" {
+				log.Fatalf("OAuth provider %s needs an issuer", p.Name)
+			}
+			providerCfg = oauth.OIDC(p.Name, p.Issuer, p.ClientID, p.ClientSecret)
+		}
+		if p.ClientID == "" {
+			log.Fatalf("OAuth provider %s needs a client ID", p.Name)
+		}
+		if len(p.Scopes) > 0 {
+			providerCfg.Scopes = p.Scopes
+		}
+		oauthProviders = append(oauthProviders, oauth.NewProvider(providerCfg, nil))
+		log.Printf("🔑 OAuth login enabled: %s", p.Name)
+	}
 	channelService := services.NewChannelService(
 		repos.Channels,
 		repos.Servers,
-		nil, // cache
+		objectCache,
 		serviceBus,
 	)
+	channelService.SetBlockRepository(repos.Users)
+	channelService.SetDMPrivacy(privacyService)
+	e2eeService := services.NewE2EEKeyService(repos.E2EE, repos.Channels, repos.Servers, serviceBus)
 	messageService := services.NewMessageService(
 		repos.Messages,
 		repos.Channels,
 		repos.Servers,
 		quotaService,
 		nil, // rate limiter
-		nil, // e2ee service
-		nil, // cache
+		e2eeService,
+		objectCache,
 		serviceBus,
 	)
+	if cfg.EmbedsEnabled {
+		embedConfig := services.DefaultEmbedConfig()
+		embedConfig.FetchTimeout = cfg.EmbedFetchTimeout
+		embedConfig.CacheTTL = cfg.EmbedCacheTTL
+		var embedCache services.CacheService
+		if redisCache != nil {
+			embedCache = redisCache
+		}
+		messageService.SetEmbedService(services.NewEmbedService(embedCache, embedConfig))
+	}
 	searchService := services.NewSearchService(
 		nil, // search repo - TODO: add full-text search
 		repos.Messages,
@@ -196,6 +452,241 @@ func main() {
 		nil, // cache
 	)
 	typingService := services.NewTypingService(serviceBus)
+	if redisCache != nil {
+		// Indicators are kept in Redis so every instance sees who is typing
+		// and each lapsed one is stopped once
+		typingService.SetStore(redisCache)
+	}
+	readStateService := services.NewReadStateService(repos.ReadStates, repos.Channels)
+	readStateService.SetMessages(repos.Messages)
+	readStateService.SetEventBus(serviceBus)
+	readStateService.SetReadReceiptPrivacy(privacyService)
+	// READY carries the user's servers, DMs, read states and relationships
+	wsGateway.SetReadyState(services.NewReadyStateService(userService, serverService, channelService, readStateService))
+	messageService.SetRoleRepository(repos.Roles)
+	messageService.SetMentionCounter(readStateService)
+	messageService.SetMentionPresence(wsHub)
+	messageService.SetAuthorRepositories(repos.Users, repos.Servers)
+	messageService.SetBlockRepository(repos.Users)
+	messageService.SetDMPrivacy(privacyService)
+	if redisCache != nil {
+		messageService.SetReactionLimiter(ratelimit.NewLimiter(redisCache))
+	}
+	if cfg.TombstoneRetention > 0 {
+		messageService.SetTombstoneRepository(repos.Messages)
+	}
+	messageService.SetArchiveRepository(repos.MessageArchive)
+	messageService.SetBulkDeleteRepository(repos.Messages)
+	messageService.SetAuditLogger(services.NewAuditLogService())
+	messageService.SetSendRecorder(metrics.NewMessageSendMetrics())
+
+	// Per-server activity for the busiest servers, to see who drives load
+	var serverActivity *metrics.ServerActivity
+	if cfg.MetricsTopServers > 0 {
+		serverActivity = metrics.NewServerActivity(cfg.MetricsTopServers)
+		prometheus.MustRegister(serverActivity)
+		messageService.SetServerMessageRecorder(serverActivity)
+	}
+	autoModService := services.NewAutoModService(
+		repos.AutoModRules,
+		repos.Servers,
+		repos.Channels,
+		repos.Roles,
+		repos.Messages,
+		serviceBus,
+	)
+	autoModService.SetTimeoutRepository(repos.Servers)
+	if redisCache != nil {
+		autoModService.SetCounter(redisCache)
+	}
+	messageService.SetAutoModService(autoModService)
+	messageService.SetServerThrottle(serverThrottleService)
+	messageService.SetChannelSchedules(repos.ChannelSchedules)
+	var permissionCache services.CacheService
+	if redisCache != nil {
+		permissionCache = redisCache
+	}
+	permissionService := services.NewPermissionService(
+		repos.Channels,
+		repos.Servers,
+		repos.Roles,
+		repos.PermissionOverwrites,
+		permissionCache,
+	)
+	permissionService.SetChannelSchedules(repos.ChannelSchedules)
+	permissionService.Start(serviceBus)
+	messageService.SetChannelPermissions(permissionService)
+	e2eeService.SetChannelPermissions(permissionService)
+	e2eeService.Start(serviceBus)
+	readStateService.SetChannelPermissions(permissionService)
+	// The gateway hub tracks live presence; the cache only keeps what bots
+	// set so it survives their reconnects
+	var presenceCache services.CacheService
+	if redisCache != nil {
+		presenceCache = redisCache
+	}
+	presenceService := services.NewPresenceService(
+		presenceCache,
+		serviceBus,
+		repos.Servers,
+	)
+	presenceService.SetUserRepository(repos.Users)
+	presenceService.SetPrivacy(privacyService)
+	wsGateway.SetBotPresenceStore(presenceService)
+
+	// Fan hub presence changes out to every server the user shares
+	wsHub.OnPresenceChange(func(presence *models.Presence) {
+		presenceService.BroadcastPresence(ctx, presence)
+	})
+
+	// Push notifications to offline devices
+	pushService := services.NewPushService(
+		repos.PushDevices,
+		repos.ChannelNotificationSettings,
+		settingsRepo,
+		repos.Channels,
+		serviceBus,
+	)
+	pushService.SetUserRepository(repos.Users)
+	var vapidPublicKey string
+	if cfg.PushFCMProjectID != "" && cfg.PushFCMCredentialsFile != "" {
+		fcm, err := push.NewFCM(push.FCMConfig{
+			ProjectID:       cfg.PushFCMProjectID,
+			CredentialsFile: cfg.PushFCMCredentialsFile,
+		})
+		if err != nil {
+			log.Printf("FCM push disabled: %v", err)
+		} else {
+			pushService.RegisterDriver(fcm)
+		}
+	}
+	if cfg.PushAPNsKeyFile != "" {
+		apns, err := push.NewAPNs(push.APNsConfig{
+			KeyFile:    cfg.PushAPNsKeyFile,
+			KeyID:      cfg.PushAPNsKeyID,
+			TeamID:     cfg.PushAPNsTeamID,
+			Topic:      cfg.PushAPNsTopic,
+			Production: cfg.PushAPNsProduction,
+		})
+		if err != nil {
+			log.Printf("APNs push disabled: %v", err)
+		} else {
+			pushService.RegisterDriver(apns)
+		}
+	}
+	if cfg.PushVAPIDPrivateKey != "" {
+		webPush, err := push.NewWebPush(push.WebPushConfig{
+			PrivateKey: cfg.PushVAPIDPrivateKey,
+			Subject:    cfg.PushVAPIDSubject,
+			Client:     services.NewWebPushClient(),
+		})
+		if err != nil {
+			log.Printf("WebPush disabled: %v", err)
+		} else {
+			pushService.RegisterDriver(webPush)
+			vapidPublicKey = webPush.PublicKey()
+		}
+	}
+	pushService.Start()
+
+	// Voice rooms on a LiveKit SFU
+	var voiceManager *voice.Manager
+	if cfg.LiveKitURL != "" {
+		sfu, err := voice.NewLiveKit(voice.LiveKitConfig{
+			URL:          cfg.LiveKitURL,
+			APIURL:       cfg.LiveKitAPIURL,
+			APIKey:       cfg.LiveKitAPIKey,
+			APISecret:    cfg.LiveKitAPISecret,
+			TokenTTL:     cfg.VoiceTokenTTL,
+			EmptyTimeout: cfg.VoiceEmptyTimeout,
+		})
+		if err != nil {
+			log.Printf("Voice disabled: %v", err)
+		} else {
+			voiceManager = voice.NewManager(sfu, repos.Channels, permissionService)
+			voiceManager.SetEventBus(serviceBus)
+			log.Printf("🎙️ Voice SFU: %s", cfg.LiveKitURL)
+		}
+	}
+
+	// Uploaded files; custom emoji images are kept here
+	var fileStorage *storage.Service
+	var emojiStorage services.EmojiStorage
+	var fileBackend storage.StorageBackend
+	var storageErr error
+	if cfg.StorageBackend == "s3" {
+		fileBackend, storageErr = storage.NewS3Backend(storage.S3Config{
+			Endpoint:  cfg.StorageEndpoint,
+			Bucket:    cfg.StorageBucket,
+			Region:    cfg.StorageRegion,
+			AccessKey: cfg.StorageAccessKey,
+			SecretKey: cfg.StorageSecretKey,
+		})
+	} else {
+		fileBackend, storageErr = storage.NewLocalBackend(cfg.LocalStoragePath, cfg.PublicURL+"/uploads")
+	}
+	if storageErr != nil {
+		log.Printf("File storage disabled: %v", storageErr)
+	} else {
+		fileStorage = storage.NewService(fileBackend, cfg.Quotas.Storage.MaxFileSizeMB, cfg.Quotas.Storage.BlockedExtensions)
+		emojiStorage = fileStorage
+	}
+	emojiService := services.NewEmojiService(repos.Emojis, repos.Servers, repos.Roles, emojiStorage, quotaService, serviceBus)
+	messageService.SetEmojiRepository(repos.Emojis)
+
+	var attachmentService *services.AttachmentService
+	if fileStorage != nil {
+		attachmentService = services.NewAttachmentService(fileStorage)
+		attachmentService.SetEventBus(serviceBus)
+		attachmentService.SetChannelPermissions(permissionService)
+		attachmentService.SetQuotas(quotaService)
+		// Forget presigned uploads the client never finished
+		go attachmentService.RunUploadCleanup(ctx, 5*time.Minute)
+		if cfg.ClamAVAddress != "" {
+			scanner, err := clamav.New(clamav.Config{Address: cfg.ClamAVAddress, Timeout: cfg.ClamAVTimeout})
+			if err != nil {
+				log.Fatalf("Invalid ClamAV config: %v", err)
+			}
+			attachmentService.SetScanner(scanner)
+			log.Printf("🛡️ Scanning uploads with clamd at %s", cfg.ClamAVAddress)
+		}
+	}
+
+	// Clear custom statuses once they expire
+	go userService.RunCustomStatusExpiry(ctx, time.Minute)
+
+	// Lift timed bans and timeouts once they run out
+	go serverService.RunModerationExpiry(ctx, time.Minute)
+
+	// Apply imported ban lists in the background
+	go serverService.RunBanImports(ctx, 5*time.Second)
+
+	// Scrub the content of users who asked to be forgotten
+	go contentErasureService.RunErasures(ctx, 5*time.Second)
+
+	// Ban members who turn up on the blocklists their servers follow
+	go blocklistService.RunBlocklistSync(ctx, time.Minute)
+
+	// Drop records of deleted messages once moderators no longer need them
+	if cfg.TombstoneRetention > 0 {
+		go messageService.RunTombstonePurge(ctx, time.Hour, cfg.TombstoneRetention)
+	}
+
+	// Move large servers' old messages out of the hot messages table
+	if cfg.MessageArchiveAfter > 0 && cfg.MessageArchiveInterval > 0 {
+		go messageService.RunMessageArchiving(ctx, cfg.MessageArchiveInterval, services.MessageArchivePolicy{
+			After:      cfg.MessageArchiveAfter,
+			MinMembers: cfg.MessageArchiveMinMembers,
+			BatchSize:  cfg.MessageArchiveBatchSize,
+		})
+	}
+
+	// Look for position gaps and orphaned rows left by restores or races
+	integrityService := services.NewIntegrityService(postgres.NewIntegrityRepository(db))
+	integrityService.SetRecorder(metrics.NewIntegrityMetrics())
+	if cfg.IntegrityCheckInterval > 0 {
+		go integrityService.RunIntegrityChecks(ctx, cfg.IntegrityCheckInterval, cfg.IntegrityAutoRepair)
+	}
 
 	// Initialize Fiber app with security settings
 	app := fiber.New(fiber.Config{
@@ -206,6 +697,7 @@ func main() {
 		WriteTimeout:          30 * time.Second,
 		// Security
 		EnableTrustedProxyCheck: true,
+		TrustedProxies:          strings.FieldsFunc(cfg.TrustedProxies, func(r rune) bool { return r == ',' || r == ' ' }),
 		ProxyHeader:             "X-Forwarded-For",
 	})
 
@@ -221,7 +713,7 @@ func main() {
 		CrossOriginEmbedderPolicy: "require-corp",
 		CrossOriginOpenerPolicy:   "same-origin",
 		CrossOriginResourcePolicy: "same-origin",
-		PermissionPolicy:          "camera=(), microphone=(), geolocation=()",
+		PermissionPolicy:          "camera=(self), microphone=(self), display-capture=(self), geolocation=()",
 	}))
 
 	// Rate limiting (can be disabled for testing with RATE_LIMIT_ENABLED=false)
@@ -231,6 +723,10 @@ func main() {
 			Max:               cfg.RateLimitMax,
 			Expiration:        cfg.RateLimitWindow,
 			LimiterMiddleware: limiter.SlidingWindow{},
+			// The admin API has its own limits per admin
+			Next: func(c *fiber.Ctx) bool {
+				return strings.HasPrefix(c.Path(), "/api/v1/admin")
+			},
 			KeyGenerator: func(c *fiber.Ctx) string {
 				return c.IP()
 			},
@@ -251,25 +747,319 @@ func main() {
 		TimeFormat: "2006-01-02 15:04:05",
 	}))
 
+	// Request-scoped loaders batch user and member lookups, so message
+	// history resolves its authors in one query
+	app.Use(func(c *fiber.Ctx) error {
+		c.SetUserContext(services.WithLoaders(c.UserContext(), services.NewLoaders(repos.Users, repos.Servers)))
+		return c.Next()
+	})
+
 	// CORS
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     cfg.PublicURL,
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, If-Match, X-Account-Tokens",
+		ExposeHeaders:    "ETag",
 		AllowMethods:     "GET, POST, PUT, PATCH, DELETE, OPTIONS",
 		AllowCredentials: true,
 		MaxAge:           86400,
 	}))
 
 	// Initialize handlers and middleware
-	// Thread service - TODO: Initialize with proper repository when available
-	var threadService *services.ThreadService = nil
+	threadService := services.NewThreadService(repos.Threads, repos.Channels, repos.Servers, serviceBus)
+	forumService := services.NewForumService(repos.Forums, repos.Channels, repos.Servers, repos.Roles, serviceBus)
+	channelFeedService := services.NewChannelFeedService(repos.ChannelFeeds, repos.Channels, repos.Servers, repos.Roles, repos.Messages, repos.Users)
+	channelScheduleService := services.NewChannelScheduleService(repos.ChannelSchedules, repos.Channels, repos.Servers, repos.Roles, serviceBus)
+	webhookPolicyService := services.NewWebhookPolicyService(repos.WebhookPolicies, repos.Channels, repos.Servers, repos.Roles)
+	webhookService := services.NewWebhookService(repos.Webhooks, repos.Channels, repos.Servers, serviceBus)
+	webhookService.SetPolicies(webhookPolicyService)
+	// Senders retry deliveries; Redis remembers which were already posted
+	if redisCache != nil {
+		webhookService.SetDedupeStore(redisCache, 0)
+	}
+	serverTokenService := services.NewServerTokenService(repos.ServerTokens, repos.Servers, repos.Channels)
+	oauthProviderService := services.NewOAuthProviderService(repos.OAuthApps)
+	sessionService.SetOAuthGrants(oauthProviderService)
 
 	h := handlers.NewHandlersWithTyping(authService, userService, serverService, channelService, messageService, roleService, searchService, threadService, typingService, wsGateway)
+	h.Users.SetAccountTokenValidator(authService)
+	if attachmentService != nil {
+		h.Attachments = handlers.NewAttachmentHandler(attachmentService, channelService)
+	}
+	if len(oauthProviders) > 0 {
+		h.Auth.SetOAuth(oauthLoginService, oauth.NewRegistry(oauthProviders...), oauth.NewStates(cfg.SecretKey, cfg.OAuthStateTTL), cfg.PublicURL, cfg.OAuthCompleteURL)
+	}
+	if cfg.CaptchaProvider != "" {
+		verifier, err := captcha.New(captcha.Config{
+			Provider: cfg.CaptchaProvider,
+			Secret:   cfg.CaptchaSecret,
+			SiteKey:  cfg.CaptchaSiteKey,
+		})
+		if err != nil {
+			log.Fatalf("Invalid captcha config: %v", err)
+		}
+		authChallenges := services.NewAuthChallengeService(verifier, services.AuthChallengeConfig{
+			LoginFailures: cfg.CaptchaLoginFailures,
+			Registrations: cfg.CaptchaRegistrations,
+			Window:        cfg.CaptchaWindow,
+		})
+		if redisCache != nil {
+			authChallenges.SetCounter(redisCache)
+		}
+		h.Auth.SetChallenges(authChallenges)
+	}
+	if mailer != nil {
+		passwordResets := services.NewPasswordResetService(repos.PasswordResets, repos.Users, mailer, cfg.PasswordResetURL, cfg.PasswordResetTTL)
+		passwordResets.SetSessions(sessionService)
+		passwordResets.SetLoginSecurity(loginSecurity)
+		h.Auth.SetPasswordResets(passwordResets)
+	}
+	h.Servers.SetPresenceFilter(privacyService)
+	h.Settings = handlers.NewSettingsHandler(services.NewSettingsService(settingsRepo, serviceBus))
+	h.ReadState = handlers.NewReadStateHandler(readStateService)
+	h.Push = handlers.NewPushHandler(pushService, vapidPublicKey)
+	h.Admin = handlers.NewAdminHandler(userService, integrityService)
+	h.Admin.SetUsernameRules(usernameRules)
+	h.Admin.SetBotTiers(botTierService)
+	h.Admin.SetContentEraser(contentErasureService)
+	h.Admin.SetServerThrottles(serverThrottleService)
+	h.Admin.SetQuotas(quotaService)
+	instanceAdmin := services.NewInstanceAdminService(repos.Users, repos.Servers, postgres.NewInstanceStatsRepository(db), sessionService)
+	instanceAdmin.SetSuspensions(suspensionService)
+	instanceAdmin.SetEventBus(serviceBus)
+	h.Admin.SetInstance(instanceAdmin)
+	h.Admin.SetSuspensions(suspensionService)
+	announcementService := services.NewAnnouncementService(repos.Announcements, serviceBus)
+	h.Admin.SetAnnouncements(announcementService)
+	h.Announcements = handlers.NewAnnouncementHandler(announcementService)
+	reportService := services.NewReportService(repos.Reports, messageService, serverService, userService)
+	h.Admin.SetReports(reportService)
+	h.Reports = handlers.NewReportHandler(reportService)
+	adminLimiter := services.NewAdminRateLimiter(services.AdminRateLimits{
+		ReadsPerMinute:  cfg.AdminRateLimitReads,
+		WritesPerMinute: cfg.AdminRateLimitWrites,
+	})
+	if redisCache != nil {
+		adminLimiter.SetCounter(redisCache)
+	}
+	h.Admin.SetRateLimiter(adminLimiter)
+	// Read-only mode is shared through Redis when there is one
+	readOnly := services.NewReadOnlyService(serviceBus)
+	if redisCache != nil {
+		readOnly.SetStore(redisCache)
+	}
+	h.Admin.SetReadOnly(readOnly)
+	h.Gateway.SetReadOnly(readOnly)
+	wsGateway.SetReadOnly(readOnly)
+	h.Channels.SetServerThrottle(serverThrottleService)
+	h.AutoMod = handlers.NewAutoModHandler(autoModService)
+	h.Permissions = handlers.NewPermissionsHandler(permissionService)
+	h.Emojis = handlers.NewEmojiHandler(emojiService)
+	h.Forums = handlers.NewForumHandler(forumService)
+	h.ChannelFeeds = handlers.NewChannelFeedHandler(channelFeedService, cfg.PublicURL)
+	h.MemberVerification = handlers.NewMemberVerificationHandler(memberVerificationService)
+	h.ChannelSchedules = handlers.NewChannelScheduleHandler(channelScheduleService)
+	h.Onboarding = handlers.NewOnboardingHandler(onboardingService)
+	h.Blocklists = handlers.NewBlocklistHandler(blocklistService)
+	h.Webhooks = handlers.NewWebhookHandlersWithExecutor(webhookService)
+	h.WebhookPolicies = handlers.NewWebhookPolicyHandler(webhookPolicyService)
+	h.ServerTokens = handlers.NewServerTokenHandler(serverTokenService)
+	h.E2EE = handlers.NewE2EEHandler(e2eeService)
+	h.Sessions = handlers.NewSessionHandler(sessionService)
+	h.OAuthApps = handlers.NewOAuthAppHandler(oauthProviderService)
+	if voiceManager != nil {
+		h.Voice = handlers.NewVoiceHandlerWithManager(voiceManager)
+	}
 	m := middleware.NewMiddleware(cfg.SecretKey)
+	m.SetRequestTimeouts(cfg.ReadRequestTimeout, cfg.WriteRequestTimeout)
+	m.SetServerTokens(serverTokenService)
+	m.SetOAuthTokens(oauthProviderService)
+	m.SetBotRateLimiter(botTierService)
+	m.SetSuspensions(suspensionService)
+	m.SetReadOnly(readOnly)
+	if serverActivity != nil {
+		m.SetServerActivityRecorder(serverActivity)
+	}
+
+	// IP filter: the config's lists, admin rules and reputation lookups
+	splitList := func(s string) []string {
+		return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+	}
+	ipFilter, err := services.NewIPFilterService(repos.IPRules, services.IPFilterConfig{
+		Blocklist: splitList(cfg.IPBlocklist),
+		Allowlist: splitList(cfg.IPAllowlist),
+		LookupTTL: cfg.IPLookupTTL,
+	})
+	if err != nil {
+		log.Fatalf("Invalid IP filter config: %v", err)
+	}
+	var reputation []services.IPReputationProvider
+	for _, zone := range splitList(cfg.IPReputationDNSBL) {
+		reputation = append(reputation, ipreputation.NewDNSBL(zone))
+	}
+	ipFilter.SetReputationProviders(reputation...)
+	if cfg.IPASNLookup {
+		ipFilter.SetASNResolver(ipreputation.NewCymruASN())
+	}
+	m.SetIPFilter(ipFilter, metrics.NewIPFilterMetrics())
+	h.Admin.SetIPRules(ipFilter)
+
+	// Config reload: POST /admin/config/reload or SIGHUP re-reads
+	// CONFIG_FILE and applies the settings registered here
+	reloader := config.NewReloader(configFile)
+	reloader.Watch(func(cfg *config.Config) error {
+		loginSecurity.SetConfig(services.LoginSecurityConfig{
+			LockoutThreshold:   cfg.LockoutThreshold,
+			LockoutDuration:    cfg.LockoutDuration,
+			MaxLockoutDuration: cfg.LockoutMaxDuration,
+			FailureWindow:      cfg.LockoutFailureWindow,
+		})
+		return nil
+	}, "LOCKOUT_THRESHOLD", "LOCKOUT_DURATION", "LOCKOUT_MAX_DURATION", "LOCKOUT_FAILURE_WINDOW")
+	reloader.Watch(func(cfg *config.Config) error {
+		return ipFilter.SetLists(splitList(cfg.IPBlocklist), splitList(cfg.IPAllowlist))
+	}, "IP_BLOCKLIST", "IP_ALLOWLIST")
+	reloader.Watch(func(cfg *config.Config) error {
+		serverThrottleService.SetDefaults(models.ServerThrottleLimits{
+			MessagesPerSecond: cfg.ServerMessagesPerSecond,
+			EventsPerSecond:   cfg.ServerEventsPerSecond,
+		})
+		return nil
+	}, "SERVER_MESSAGES_PER_SECOND", "SERVER_EVENTS_PER_SECOND")
+	reloader.Watch(func(cfg *config.Config) error {
+		adminLimiter.SetLimits(services.AdminRateLimits{
+			ReadsPerMinute:  cfg.AdminRateLimitReads,
+			WritesPerMinute: cfg.AdminRateLimitWrites,
+		})
+		return nil
+	}, "ADMIN_RATE_LIMIT_READS", "ADMIN_RATE_LIMIT_WRITES")
+	h.Admin.SetConfigReloader(reloader)
+	if configFile != "" {
+		go func() {
+			hupCh := make(chan os.Signal, 1)
+			signal.Notify(hupCh, syscall.SIGHUP)
+			for range hupCh {
+				result, err := reloader.Reload()
+				if err != nil {
+					log.Printf("⚠️  Config reload failed: %v", err)
+					continue
+				}
+				log.Printf("🔄 Config reloaded: applied %v, restart required for %v, overridden by the environment %v, failed %v",
+					result.Applied, result.RestartRequired, result.Overridden, result.Errors)
+			}
+		}()
+	}
+
+	// Compliance log: an append-only record of every mutating request
+	var complianceLog *services.ComplianceLogger
+	if cfg.ComplianceLogEnabled {
+		complianceLog = services.NewComplianceLogger(repos.ComplianceLog)
+		go complianceLog.Run()
+		m.SetComplianceRecorder(complianceLog)
+		h.Admin.SetComplianceExporter(complianceLog)
+		if cfg.ComplianceLogRetention > 0 {
+			go complianceLog.RunPurge(ctx, time.Hour, cfg.ComplianceLogRetention)
+		}
+		log.Printf("📋 Compliance logging enabled (retention %v)", cfg.ComplianceLogRetention)
+	}
+
+	// Preflight: check the database, Redis, secret and clock before binding
+	// any port, and refuse to start if something critical is wrong
+	var redisPing func(ctx context.Context) error
+	if redisCache != nil {
+		redisPing = func(ctx context.Context) error { return redisCache.Client().Ping(ctx).Err() }
+	}
+	preflightReport := preflight.Run(context.Background(), preflight.DefaultTimeout,
+		preflight.Database(db),
+		preflight.Migrations(func(ctx context.Context) (*postgres.MigrationState, error) {
+			return postgres.MigrationStatus(ctx, db)
+		}),
+		preflight.Redis(redisPing, cfg.RedisRequired),
+		preflight.SecretKey(cfg.SecretKey),
+		preflight.ClockSkew(db, cfg.MaxClockSkew),
+	)
+	preflightReport.Log(log.Printf)
+	if failures := preflightReport.Failures(); len(failures) > 0 {
+		if cfg.PreflightEnforce {
+			log.Fatalf("❌ Preflight failed: %d critical checks; set PREFLIGHT_ENFORCE=false to start anyway", len(failures))
+		}
+		log.Printf("⚠️  Preflight failed: %d critical checks; starting anyway because PREFLIGHT_ENFORCE=false", len(failures))
+	}
+
+	// Prometheus metrics endpoint, on the API port or its own listener.
+	// Scrapers can be limited by network and bearer token, and on its own
+	// listener by client certificate
+	allowedNets, err := metrics.ParseCIDRs(cfg.MetricsAllowedCIDRs)
+	if err != nil {
+		log.Fatalf("Invalid METRICS_ALLOWED_CIDRS: %v", err)
+	}
+	scrapeAuth := metrics.ScrapeAuth{Token: cfg.MetricsToken, AllowedNets: allowedNets}
+	metricsHandler := scrapeAuth.Handler(promhttp.Handler())
+	if !scrapeAuth.Enabled() && cfg.MetricsClientCA == "" {
+		log.Printf("⚠️  /metrics is public; set METRICS_TOKEN, METRICS_ALLOWED_CIDRS or METRICS_CLIENT_CA to restrict it")
+	}
+	if cfg.MetricsAddr == "" {
+		if cfg.MetricsTLSCert != "" || cfg.MetricsClientCA != "" {
+			log.Fatalf("METRICS_TLS_CERT and METRICS_CLIENT_CA need METRICS_ADDR")
+		}
+		app.Get("/metrics", adaptor.HTTPHandler(metricsHandler))
+		log.Printf("📊 Prometheus metrics endpoint: /metrics")
+	} else {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metricsHandler)
+		metricsServer := &http.Server{
+			Addr:              cfg.MetricsAddr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		if cfg.MetricsTLSCert != "" {
+			metricsServer.TLSConfig, err = metrics.ScrapeTLSConfig(cfg.MetricsTLSCert, cfg.MetricsTLSKey, cfg.MetricsClientCA)
+			if err != nil {
+				log.Fatalf("Failed to configure metrics TLS: %v", err)
+			}
+		} else if cfg.MetricsClientCA != "" {
+			log.Fatalf("METRICS_CLIENT_CA needs METRICS_TLS_CERT and METRICS_TLS_KEY")
+		}
+		go func() {
+			var err error
+			if metricsServer.TLSConfig != nil {
+				err = metricsServer.ListenAndServeTLS("", "")
+			} else {
+				err = metricsServer.ListenAndServe()
+			}
+			if err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Metrics server error: %v", err)
+			}
+		}()
+		defer metricsServer.Close()
+		log.Printf("📊 Prometheus metrics endpoint: %s/metrics (tls: %v, client certs: %v)",
+			cfg.MetricsAddr, metricsServer.TLSConfig != nil, cfg.MetricsClientCA != "")
+	}
+
+	// Warm Redis with the largest servers before reporting ready, so a
+	// deploy doesn't start with every request missing the cache
+	if cfg.CacheWarmup {
+		if redisCache == nil {
+			log.Printf("⚠️  CACHE_WARMUP needs Redis; skipping cache warmup")
+		} else {
+			warmer := services.NewCacheWarmer(repos.Servers, repos.Servers, repos.Channels, permissionService, redisCache,
+				services.CacheWarmerConfig{
+					Servers: cfg.CacheWarmupServers,
+					Members: cfg.CacheWarmupMembers,
+					Timeout: cfg.CacheWarmupTimeout,
+				})
+			h.Gateway.SetWarmup(warmer)
+			go func() {
+				stats := warmer.Run(ctx)
+				log.Printf("🔥 Cache warmed in %v: %d servers, %d channels, %d permission entries (%d servers failed)",
+					stats.Duration, stats.Servers, stats.Channels, stats.Permissions, stats.Failed)
+			}()
+		}
+	}
 
-	// Prometheus metrics endpoint (before API routes, no auth required)
-	app.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
-	log.Printf("📊 Prometheus metrics endpoint: /metrics")
+	// Emoji images on local disk are public; S3 serves its own
+	if cfg.StorageBackend != "s3" {
+		app.Static("/uploads/emojis", filepath.Join(cfg.LocalStoragePath, "emojis"))
+	}
 
 	// Setup routes
 	api.SetupRoutes(app, h, m)
@@ -303,10 +1093,20 @@ func main() {
 		// Step 3: Cancel the main context to stop background goroutines
 		log.Println("🔄 Step 3/3: Stopping background services...")
 		cancel()
+		if complianceLog != nil {
+			complianceLog.Close()
+		}
 
 		close(shutdownComplete)
 	}()
 
+	// Start injecting faults now that startup is done
+	for _, injector := range []*chaos.Injector{pgChaos, redisChaos} {
+		if injector != nil {
+			injector.Arm()
+		}
+	}
+
 	// Start server
 	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
 	go func() {
@@ -0,0 +1,1293 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/config"
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// UserGetter looks up the requesting user
+type UserGetter interface {
+	GetUser(ctx context.Context, id uuid.UUID) (*models.User, error)
+}
+
+// IntegrityChecker defines the methods needed from services.IntegrityService
+type IntegrityChecker interface {
+	Check(ctx context.Context, repair bool) (*services.IntegrityReport, error)
+}
+
+// ComplianceExporter defines the methods needed from services.ComplianceLogger
+type ComplianceExporter interface {
+	Export(ctx context.Context, filter models.ComplianceLogFilter, fn func(*models.ComplianceLogEntry) error) error
+}
+
+// UsernameRuleManager defines the methods needed from
+// services.UsernameRuleService
+type UsernameRuleManager interface {
+	ListRules(ctx context.Context) ([]*models.UsernameRule, error)
+	CreateRule(ctx context.Context, creatorID uuid.UUID, req *models.CreateUsernameRuleRequest) (*models.UsernameRule, error)
+	DeleteRule(ctx context.Context, id uuid.UUID) error
+}
+
+// BotTierManager defines the methods needed from services.BotTierService
+type BotTierManager interface {
+	ListBots(ctx context.Context, tier models.BotTier) ([]*models.BotVerification, error)
+	GetBot(ctx context.Context, botID uuid.UUID) (*models.BotVerification, error)
+	SetTier(ctx context.Context, adminID, botID uuid.UUID, req *models.SetBotTierRequest) (*models.BotVerification, error)
+}
+
+// ContentEraser defines the methods needed from
+// services.ContentErasureService
+type ContentEraser interface {
+	RequestErasure(ctx context.Context, userID, requestedBy uuid.UUID) (*models.ContentErasure, error)
+	GetErasure(ctx context.Context, id uuid.UUID) (*models.ContentErasure, error)
+	ListErasures(ctx context.Context, userID uuid.UUID) ([]*models.ContentErasure, error)
+}
+
+// ServerThrottleManager defines the methods needed from
+// services.ServerThrottleService
+type ServerThrottleManager interface {
+	GetThrottle(ctx context.Context, serverID uuid.UUID) (*models.ServerThrottle, error)
+	SetThrottle(ctx context.Context, adminID, serverID uuid.UUID, req *models.SetServerThrottleRequest) (*models.ServerThrottle, error)
+	ResetThrottle(ctx context.Context, serverID uuid.UUID) error
+}
+
+// QuotaManager defines the methods needed from services.QuotaService
+type QuotaManager interface {
+	GetQuotas(ctx context.Context) (*models.QuotaSettings, error)
+	GetTierQuotas(ctx context.Context, tier models.QuotaTier) (*models.QuotaOverride, error)
+	SetTierQuotas(ctx context.Context, adminID uuid.UUID, tier models.QuotaTier, req *models.SetQuotaOverrideRequest) (*models.QuotaOverride, error)
+	ResetTierQuotas(ctx context.Context, tier models.QuotaTier) error
+}
+
+// IPRuleManager defines the methods needed from services.IPFilterService
+type IPRuleManager interface {
+	ListRules(ctx context.Context) ([]*models.IPRule, error)
+	CreateRule(ctx context.Context, creatorID uuid.UUID, req *models.CreateIPRuleRequest) (*models.IPRule, error)
+	DeleteRule(ctx context.Context, id uuid.UUID) error
+	Check(ctx context.Context, ip string) *models.IPVerdict
+}
+
+// InstanceManager defines the methods needed from
+// services.InstanceAdminService
+type InstanceManager interface {
+	ListUsers(ctx context.Context, query string, after *uuid.UUID, limit int) ([]*models.AdminUser, error)
+	DeleteServer(ctx context.Context, serverID uuid.UUID) (*models.Server, error)
+	Stats(ctx context.Context) (*models.InstanceStats, error)
+}
+
+// UserSuspender defines the methods needed from
+// services.UserSuspensionService
+type UserSuspender interface {
+	Suspend(ctx context.Context, adminID, userID uuid.UUID, req *models.SuspendUserRequest) (*models.UserSuspension, error)
+	Unsuspend(ctx context.Context, userID uuid.UUID) error
+}
+
+// ConfigReloader defines the methods needed from config.Reloader
+type ConfigReloader interface {
+	Reload() (*models.ConfigReload, error)
+}
+
+// AnnouncementManager defines the methods needed from
+// services.AnnouncementService
+type AnnouncementManager interface {
+	List(ctx context.Context) ([]*models.Announcement, error)
+	Create(ctx context.Context, creatorID uuid.UUID, req *models.CreateAnnouncementRequest) (*models.Announcement, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// AdminRequestLimiter defines the methods needed from
+// services.AdminRateLimiter
+type AdminRequestLimiter interface {
+	CheckAdminRequest(ctx context.Context, userID uuid.UUID, write bool) error
+}
+
+// ReadOnlyManager defines the methods needed from services.ReadOnlyService
+type ReadOnlyManager interface {
+	Mode(ctx context.Context) *models.ReadOnlyMode
+	SetMode(ctx context.Context, adminID uuid.UUID, req *models.SetReadOnlyModeRequest) (*models.ReadOnlyMode, error)
+}
+
+// ReportQueue defines the methods needed from services.ReportService
+type ReportQueue interface {
+	List(ctx context.Context, filter models.ReportFilter) ([]*models.Report, error)
+	Get(ctx context.Context, id uuid.UUID) (*models.ReportDetail, error)
+	UpdateStatus(ctx context.Context, adminID, id uuid.UUID, req *models.UpdateReportStatusRequest) (*models.Report, error)
+	Assign(ctx context.Context, adminID, id, assigneeID uuid.UUID) (*models.Report, error)
+	Unassign(ctx context.Context, adminID, id uuid.UUID) (*models.Report, error)
+	AddNote(ctx context.Context, adminID, id uuid.UUID, note string) (*models.ReportEvent, error)
+}
+
+// complianceExportTimeout bounds an export. The response is streamed after
+// the handler returns, so it can't use the request's deadline.
+const complianceExportTimeout = 10 * time.Minute
+
+// AdminHandler handles instance maintenance endpoints. They are limited to
+// staff accounts.
+type AdminHandler struct {
+	users      UserGetter
+	integrity  IntegrityChecker
+	compliance ComplianceExporter
+	usernames  UsernameRuleManager
+	botTiers   BotTierManager
+	erasures   ContentEraser
+	throttles  ServerThrottleManager
+	quotas     QuotaManager
+	ipRules    IPRuleManager
+	instance   InstanceManager
+	suspension UserSuspender
+	reloader   ConfigReloader
+	announce   AnnouncementManager
+	limiter    AdminRequestLimiter
+	readOnly   ReadOnlyManager
+	reports    ReportQueue
+}
+
+func NewAdminHandler(users UserGetter, integrity IntegrityChecker) *AdminHandler {
+	return &AdminHandler{users: users, integrity: integrity}
+}
+
+// SetComplianceExporter enables exporting the compliance log
+func (h *AdminHandler) SetComplianceExporter(exporter ComplianceExporter) {
+	h.compliance = exporter
+}
+
+// SetUsernameRules enables managing reserved and banned usernames
+func (h *AdminHandler) SetUsernameRules(rules UsernameRuleManager) {
+	h.usernames = rules
+}
+
+// SetBotTiers enables managing bot verification tiers
+func (h *AdminHandler) SetBotTiers(tiers BotTierManager) {
+	h.botTiers = tiers
+}
+
+// SetContentEraser enables erasing users' content
+func (h *AdminHandler) SetContentEraser(erasures ContentEraser) {
+	h.erasures = erasures
+}
+
+// SetServerThrottles enables overriding servers' rate limits
+func (h *AdminHandler) SetServerThrottles(throttles ServerThrottleManager) {
+	h.throttles = throttles
+}
+
+// SetQuotas enables changing the instance's quotas per tier
+func (h *AdminHandler) SetQuotas(quotas QuotaManager) {
+	h.quotas = quotas
+}
+
+// SetIPRules enables managing the blocked and allowed client networks
+func (h *AdminHandler) SetIPRules(rules IPRuleManager) {
+	h.ipRules = rules
+}
+
+// SetInstance enables listing users, deleting servers and instance stats
+func (h *AdminHandler) SetInstance(instance InstanceManager) {
+	h.instance = instance
+}
+
+// SetSuspensions enables suspending users
+func (h *AdminHandler) SetSuspensions(suspensions UserSuspender) {
+	h.suspension = suspensions
+}
+
+// SetConfigReloader enables reloading the config file
+func (h *AdminHandler) SetConfigReloader(reloader ConfigReloader) {
+	h.reloader = reloader
+}
+
+// SetAnnouncements enables managing instance announcements
+func (h *AdminHandler) SetAnnouncements(announcements AnnouncementManager) {
+	h.announce = announcements
+}
+
+// SetReadOnly enables turning read-only mode on and off
+func (h *AdminHandler) SetReadOnly(readOnly ReadOnlyManager) {
+	h.readOnly = readOnly
+}
+
+// SetReports enables reviewing users' reports
+func (h *AdminHandler) SetReports(reports ReportQueue) {
+	h.reports = reports
+}
+
+// SetRateLimiter limits each admin's requests. Without one, the admin API
+// is unlimited.
+func (h *AdminHandler) SetRateLimiter(limiter AdminRequestLimiter) {
+	h.limiter = limiter
+}
+
+// RateLimit applies the admin API's own rate limits, counting reads and
+// writes apart
+func (h *AdminHandler) RateLimit(c *fiber.Ctx) error {
+	if h.limiter == nil {
+		return c.Next()
+	}
+	userID := c.Locals("userID").(uuid.UUID)
+	write := c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead
+
+	err := h.limiter.CheckAdminRequest(c.UserContext(), userID, write)
+	var limited *services.AdminRateLimitedError
+	if errors.As(err, &limited) {
+		retryAfter := int(limited.RetryAfter.Seconds())
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error":       err.Error(),
+			"retry_after": retryAfter,
+		})
+	}
+	return c.Next()
+}
+
+// RequireStaff rejects users without the staff flag
+func (h *AdminHandler) RequireStaff(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	user, err := h.users.GetUser(c.Context(), userID)
+	if err != nil || user.Flags&models.UserFlagStaff == 0 {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "staff only",
+		})
+	}
+	return c.Next()
+}
+
+// CheckIntegrity reports inconsistent positions and orphaned rows
+// GET /admin/integrity
+func (h *AdminHandler) CheckIntegrity(c *fiber.Ctx) error {
+	return h.runIntegrity(c, false)
+}
+
+// RepairIntegrity repairs inconsistent positions and orphaned rows
+// POST /admin/integrity/repair
+func (h *AdminHandler) RepairIntegrity(c *fiber.Ctx) error {
+	return h.runIntegrity(c, true)
+}
+
+func (h *AdminHandler) runIntegrity(c *fiber.Ctx, repair bool) error {
+	report, err := h.integrity.Check(c.Context(), repair)
+	if err != nil {
+		if errors.Is(err, services.ErrIntegrityCheckRunning) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "integrity check failed",
+		})
+	}
+	return c.JSON(report)
+}
+
+// ExportComplianceLog streams compliance log entries as newline-delimited
+// JSON, oldest first. after, before (RFC 3339) and actor_id narrow it down.
+// GET /admin/compliance-log
+func (h *AdminHandler) ExportComplianceLog(c *fiber.Ctx) error {
+	if h.compliance == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "compliance logging is disabled",
+		})
+	}
+
+	var filter models.ComplianceLogFilter
+	for _, param := range []struct {
+		name string
+		dst  **time.Time
+	}{{"after", &filter.After}, {"before", &filter.Before}} {
+		if value := c.Query(param.name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "invalid " + param.name + " time",
+				})
+			}
+			*param.dst = &t
+		}
+	}
+	if filter.After != nil && filter.Before != nil && !filter.Before.After(*filter.After) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "before must be later than after",
+		})
+	}
+	if actor := c.Query("actor_id"); actor != "" {
+		actorID, err := uuid.Parse(actor)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid actor_id",
+			})
+		}
+		filter.ActorID = &actorID
+	}
+
+	c.Set(fiber.HeaderContentType, "application/x-ndjson")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="compliance-log.ndjson"`)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithTimeout(context.Background(), complianceExportTimeout)
+		defer cancel()
+
+		encoder := json.NewEncoder(w)
+		err := h.compliance.Export(ctx, filter, func(entry *models.ComplianceLogEntry) error {
+			return encoder.Encode(entry)
+		})
+		if err != nil {
+			// The status is already sent, so the last line tells the reader
+			// the export stopped short
+			log.Printf("[Admin] compliance log export failed: %v", err)
+			_ = encoder.Encode(fiber.Map{"error": "export incomplete"})
+		}
+		_ = w.Flush()
+	})
+	return nil
+}
+
+// ListUsernameRules returns the reserved and banned usernames
+// GET /admin/username-rules
+func (h *AdminHandler) ListUsernameRules(c *fiber.Ctx) error {
+	if h.usernames == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "username rules are not available",
+		})
+	}
+	rules, err := h.usernames.ListRules(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list username rules",
+		})
+	}
+	return c.JSON(rules)
+}
+
+// CreateUsernameRule reserves or bans usernames
+// POST /admin/username-rules
+func (h *AdminHandler) CreateUsernameRule(c *fiber.Ctx) error {
+	if h.usernames == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "username rules are not available",
+		})
+	}
+	userID := c.Locals("userID").(uuid.UUID)
+
+	var req models.CreateUsernameRuleRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	rule, err := h.usernames.CreateRule(c.UserContext(), userID, &req)
+	if err != nil {
+		return usernameRuleError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(rule)
+}
+
+// DeleteUsernameRule removes a username rule
+// DELETE /admin/username-rules/:id
+func (h *AdminHandler) DeleteUsernameRule(c *fiber.Ctx) error {
+	if h.usernames == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "username rules are not available",
+		})
+	}
+	ruleID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid rule id",
+		})
+	}
+
+	if err := h.usernames.DeleteRule(c.UserContext(), ruleID); err != nil {
+		return usernameRuleError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func usernameRuleError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidUsernameRule):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrUsernameRuleExists):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrUsernameRuleNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update username rules"})
+	}
+}
+
+// ListBots returns the bots given a verification tier, optionally only
+// those in ?tier=
+// GET /admin/bots
+func (h *AdminHandler) ListBots(c *fiber.Ctx) error {
+	if h.botTiers == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "bot tiers are not available",
+		})
+	}
+	bots, err := h.botTiers.ListBots(c.UserContext(), models.BotTier(c.Query("tier")))
+	if err != nil {
+		return botTierError(c, err)
+	}
+	return c.JSON(bots)
+}
+
+// GetBotTier returns a bot's verification tier
+// GET /admin/bots/:id
+func (h *AdminHandler) GetBotTier(c *fiber.Ctx) error {
+	if h.botTiers == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "bot tiers are not available",
+		})
+	}
+	botID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid bot id",
+		})
+	}
+
+	bot, err := h.botTiers.GetBot(c.UserContext(), botID)
+	if err != nil {
+		return botTierError(c, err)
+	}
+	return c.JSON(bot)
+}
+
+// SetBotTier moves a bot to another verification tier
+// PUT /admin/bots/:id/tier
+func (h *AdminHandler) SetBotTier(c *fiber.Ctx) error {
+	if h.botTiers == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "bot tiers are not available",
+		})
+	}
+	userID := c.Locals("userID").(uuid.UUID)
+	botID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid bot id",
+		})
+	}
+
+	var req models.SetBotTierRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	bot, err := h.botTiers.SetTier(c.UserContext(), userID, botID, &req)
+	if err != nil {
+		return botTierError(c, err)
+	}
+	return c.JSON(bot)
+}
+
+func botTierError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidBotTier), errors.Is(err, services.ErrNotABot):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrUserNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to manage bot tiers"})
+	}
+}
+
+// RequestErasure queues erasing everything a user wrote
+// POST /admin/users/:id/erasure
+func (h *AdminHandler) RequestErasure(c *fiber.Ctx) error {
+	if h.erasures == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "content erasure is not available",
+		})
+	}
+	adminID := c.Locals("userID").(uuid.UUID)
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	job, err := h.erasures.RequestErasure(c.UserContext(), userID, adminID)
+	if err != nil {
+		return erasureError(c, err)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// ListErasures returns every erasure of a user's content, newest first
+// GET /admin/users/:id/erasures
+func (h *AdminHandler) ListErasures(c *fiber.Ctx) error {
+	if h.erasures == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "content erasure is not available",
+		})
+	}
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	jobs, err := h.erasures.ListErasures(c.UserContext(), userID)
+	if err != nil {
+		return erasureError(c, err)
+	}
+	return c.JSON(jobs)
+}
+
+// GetErasure returns an erasure's progress and verification
+// GET /admin/erasures/:id
+func (h *AdminHandler) GetErasure(c *fiber.Ctx) error {
+	if h.erasures == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "content erasure is not available",
+		})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid erasure id",
+		})
+	}
+
+	job, err := h.erasures.GetErasure(c.UserContext(), id)
+	if err != nil {
+		return erasureError(c, err)
+	}
+	return c.JSON(job)
+}
+
+func erasureError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrContentErasureNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrContentErasureInProgress):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to erase content"})
+	}
+}
+
+// GetServerThrottle returns a server's rate limit overrides and the limits
+// that apply
+// GET /admin/servers/:id/throttle
+func (h *AdminHandler) GetServerThrottle(c *fiber.Ctx) error {
+	if h.throttles == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "server throttling is not available",
+		})
+	}
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	throttle, err := h.throttles.GetThrottle(c.UserContext(), serverID)
+	if err != nil {
+		return serverThrottleError(c, err)
+	}
+	return c.JSON(throttle)
+}
+
+// SetServerThrottle overrides a server's rate limits
+// PUT /admin/servers/:id/throttle
+func (h *AdminHandler) SetServerThrottle(c *fiber.Ctx) error {
+	if h.throttles == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "server throttling is not available",
+		})
+	}
+	adminID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	var req models.SetServerThrottleRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	throttle, err := h.throttles.SetThrottle(c.UserContext(), adminID, serverID, &req)
+	if err != nil {
+		return serverThrottleError(c, err)
+	}
+	return c.JSON(throttle)
+}
+
+// ResetServerThrottle removes a server's overrides so the instance limits
+// apply again
+// DELETE /admin/servers/:id/throttle
+func (h *AdminHandler) ResetServerThrottle(c *fiber.Ctx) error {
+	if h.throttles == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "server throttling is not available",
+		})
+	}
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	if err := h.throttles.ResetThrottle(c.UserContext(), serverID); err != nil {
+		return serverThrottleError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func serverThrottleError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidServerThrottle):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrServerNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to manage server throttling"})
+	}
+}
+
+// ListQuotas returns the instance's quotas: the static config and each
+// tier's overrides and the limits that apply to it
+// GET /admin/quotas
+func (h *AdminHandler) ListQuotas(c *fiber.Ctx) error {
+	if h.quotas == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "quota overrides are not available",
+		})
+	}
+
+	settings, err := h.quotas.GetQuotas(c.UserContext())
+	if err != nil {
+		return quotaError(c, err)
+	}
+	return c.JSON(settings)
+}
+
+// GetQuotaTier returns a tier's overrides and the limits that apply to it
+// GET /admin/quotas/:tier
+func (h *AdminHandler) GetQuotaTier(c *fiber.Ctx) error {
+	if h.quotas == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "quota overrides are not available",
+		})
+	}
+
+	override, err := h.quotas.GetTierQuotas(c.UserContext(), models.QuotaTier(c.Params("tier")))
+	if err != nil {
+		return quotaError(c, err)
+	}
+	return c.JSON(override)
+}
+
+// SetQuotaTier overrides a tier's quotas
+// PUT /admin/quotas/:tier
+func (h *AdminHandler) SetQuotaTier(c *fiber.Ctx) error {
+	if h.quotas == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "quota overrides are not available",
+		})
+	}
+	adminID := c.Locals("userID").(uuid.UUID)
+
+	var req models.SetQuotaOverrideRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	override, err := h.quotas.SetTierQuotas(c.UserContext(), adminID, models.QuotaTier(c.Params("tier")), &req)
+	if err != nil {
+		return quotaError(c, err)
+	}
+	return c.JSON(override)
+}
+
+// ResetQuotaTier removes a tier's overrides so the tiers below apply again
+// DELETE /admin/quotas/:tier
+func (h *AdminHandler) ResetQuotaTier(c *fiber.Ctx) error {
+	if h.quotas == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "quota overrides are not available",
+		})
+	}
+
+	if err := h.quotas.ResetTierQuotas(c.UserContext(), models.QuotaTier(c.Params("tier"))); err != nil {
+		return quotaError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func quotaError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidQuota), errors.Is(err, services.ErrInvalidQuotaTier):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to manage quotas"})
+	}
+}
+
+// ListIPRules returns the blocked and allowed client networks
+// GET /admin/ip-rules
+func (h *AdminHandler) ListIPRules(c *fiber.Ctx) error {
+	if h.ipRules == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "ip rules are not available",
+		})
+	}
+	rules, err := h.ipRules.ListRules(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list ip rules",
+		})
+	}
+	return c.JSON(rules)
+}
+
+// CreateIPRule blocks or allows a CIDR range or autonomous system
+// POST /admin/ip-rules
+func (h *AdminHandler) CreateIPRule(c *fiber.Ctx) error {
+	if h.ipRules == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "ip rules are not available",
+		})
+	}
+	userID := c.Locals("userID").(uuid.UUID)
+
+	var req models.CreateIPRuleRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	rule, err := h.ipRules.CreateRule(c.UserContext(), userID, &req)
+	if err != nil {
+		return ipRuleError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(rule)
+}
+
+// DeleteIPRule removes an ip rule
+// DELETE /admin/ip-rules/:id
+func (h *AdminHandler) DeleteIPRule(c *fiber.Ctx) error {
+	if h.ipRules == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "ip rules are not available",
+		})
+	}
+	ruleID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid rule id",
+		})
+	}
+
+	if err := h.ipRules.DeleteRule(c.UserContext(), ruleID); err != nil {
+		return ipRuleError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// CheckIP reports whether requests from ?ip= would be served, and why not
+// GET /admin/ip-rules/check
+func (h *AdminHandler) CheckIP(c *fiber.Ctx) error {
+	if h.ipRules == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "ip rules are not available",
+		})
+	}
+	ip := net.ParseIP(c.Query("ip"))
+	if ip == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid ip",
+		})
+	}
+	return c.JSON(h.ipRules.Check(c.UserContext(), ip.String()))
+}
+
+func ipRuleError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidIPRule):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrIPRuleExists):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrIPRuleNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update ip rules"})
+	}
+}
+
+// ListUsers returns a page of users oldest first, with their suspensions.
+// ?q= matches the start of usernames and emails, and ?after= is the last
+// user of the previous page.
+// GET /admin/users
+func (h *AdminHandler) ListUsers(c *fiber.Ctx) error {
+	if h.instance == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "instance administration is not available",
+		})
+	}
+
+	var after *uuid.UUID
+	if value := c.Query("after"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid after",
+			})
+		}
+		after = &id
+	}
+
+	users, err := h.instance.ListUsers(c.UserContext(), c.Query("q"), after, c.QueryInt("limit", 0))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list users",
+		})
+	}
+	return c.JSON(users)
+}
+
+// SuspendUser stops a user logging in or using the API until the
+// suspension ends or is lifted, and logs them out everywhere
+// PUT /admin/users/:id/suspension
+func (h *AdminHandler) SuspendUser(c *fiber.Ctx) error {
+	if h.suspension == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "user suspension is not available",
+		})
+	}
+	adminID := c.Locals("userID").(uuid.UUID)
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	var req models.SuspendUserRequest
+	if len(c.Body()) > 0 {
+		if err := parseBody(c, &req); err != nil {
+			return invalidBody(c, err)
+		}
+	}
+
+	suspension, err := h.suspension.Suspend(c.UserContext(), adminID, userID, &req)
+	if err != nil {
+		return suspensionError(c, err)
+	}
+	return c.JSON(suspension)
+}
+
+// UnsuspendUser lifts a user's suspension
+// DELETE /admin/users/:id/suspension
+func (h *AdminHandler) UnsuspendUser(c *fiber.Ctx) error {
+	if h.suspension == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "user suspension is not available",
+		})
+	}
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	if err := h.suspension.Unsuspend(c.UserContext(), userID); err != nil {
+		return suspensionError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func suspensionError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidSuspension):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrCannotSuspendAdmin):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrUserNotFound), errors.Is(err, services.ErrSuspensionNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to manage suspensions"})
+	}
+}
+
+// DeleteServer deletes a server whoever owns it
+// DELETE /admin/servers/:id
+func (h *AdminHandler) DeleteServer(c *fiber.Ctx) error {
+	if h.instance == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "instance administration is not available",
+		})
+	}
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	if _, err := h.instance.DeleteServer(c.UserContext(), serverID); err != nil {
+		if errors.Is(err, services.ErrServerNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to delete server",
+		})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetStats counts what the instance holds
+// GET /admin/stats
+func (h *AdminHandler) GetStats(c *fiber.Ctx) error {
+	if h.instance == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "instance administration is not available",
+		})
+	}
+	stats, err := h.instance.Stats(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to count instance stats",
+		})
+	}
+	return c.JSON(stats)
+}
+
+// ReloadConfig re-reads the config file and applies the settings that can
+// change while the instance runs
+// POST /admin/config/reload
+func (h *AdminHandler) ReloadConfig(c *fiber.Ctx) error {
+	if h.reloader == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "config reloading is not available",
+		})
+	}
+	result, err := h.reloader.Reload()
+	if err != nil {
+		if errors.Is(err, config.ErrNoConfigFile) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Printf("[Admin] config reload failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to reload config: " + err.Error(),
+		})
+	}
+	return c.JSON(result)
+}
+
+// ListAnnouncements returns every announcement, including expired ones,
+// newest first
+// GET /admin/announcements
+func (h *AdminHandler) ListAnnouncements(c *fiber.Ctx) error {
+	if h.announce == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "announcements are not available",
+		})
+	}
+	announcements, err := h.announce.List(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list announcements",
+		})
+	}
+	return c.JSON(announcements)
+}
+
+// CreateAnnouncement shows an announcement to every user
+// POST /admin/announcements
+func (h *AdminHandler) CreateAnnouncement(c *fiber.Ctx) error {
+	if h.announce == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "announcements are not available",
+		})
+	}
+	userID := c.Locals("userID").(uuid.UUID)
+
+	var req models.CreateAnnouncementRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	announcement, err := h.announce.Create(c.UserContext(), userID, &req)
+	if err != nil {
+		return announcementError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(announcement)
+}
+
+// DeleteAnnouncement takes an announcement down
+// DELETE /admin/announcements/:id
+func (h *AdminHandler) DeleteAnnouncement(c *fiber.Ctx) error {
+	if h.announce == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "announcements are not available",
+		})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid announcement id",
+		})
+	}
+
+	if err := h.announce.Delete(c.UserContext(), id); err != nil {
+		return announcementError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func announcementError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidAnnouncement):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrAnnouncementNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to manage announcements"})
+	}
+}
+
+// GetReadOnlyMode returns whether the instance is refusing writes
+// GET /admin/read-only
+func (h *AdminHandler) GetReadOnlyMode(c *fiber.Ctx) error {
+	if h.readOnly == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "read-only mode is not available",
+		})
+	}
+	return c.JSON(h.readOnly.Mode(c.UserContext()))
+}
+
+// SetReadOnlyMode turns read-only mode on or off
+// PUT /admin/read-only
+func (h *AdminHandler) SetReadOnlyMode(c *fiber.Ctx) error {
+	if h.readOnly == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "read-only mode is not available",
+		})
+	}
+	adminID := c.Locals("userID").(uuid.UUID)
+
+	var req models.SetReadOnlyModeRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	mode, err := h.readOnly.SetMode(c.UserContext(), adminID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidReadOnlyMode) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Printf("[Admin] failed to set read-only mode: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to set read-only mode",
+		})
+	}
+	return c.JSON(mode)
+}
+
+// ListReports returns the report queue, oldest first. ?status=,
+// ?target_type= and ?target_id= filter it, ?assignee= is an admin's ID or
+// "me", and ?after= is the last report of the previous page.
+// GET /admin/reports
+func (h *AdminHandler) ListReports(c *fiber.Ctx) error {
+	if h.reports == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "reports are not available",
+		})
+	}
+	adminID := c.Locals("userID").(uuid.UUID)
+
+	filter := models.ReportFilter{
+		Status:     models.ReportStatus(c.Query("status")),
+		TargetType: models.ReportTargetType(c.Query("target_type")),
+		Limit:      c.QueryInt("limit", 0),
+	}
+	if value := c.Query("assignee"); value == "me" {
+		filter.AssigneeID = &adminID
+	} else if value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid assignee",
+			})
+		}
+		filter.AssigneeID = &id
+	}
+	if value := c.Query("target_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid target_id",
+			})
+		}
+		filter.TargetID = &id
+	}
+	if value := c.Query("after"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid after",
+			})
+		}
+		filter.After = &id
+	}
+
+	reports, err := h.reports.List(c.UserContext(), filter)
+	if err != nil {
+		return reportError(c, err)
+	}
+	return c.JSON(reports)
+}
+
+// GetReport returns a report with its history
+// GET /admin/reports/:id
+func (h *AdminHandler) GetReport(c *fiber.Ctx) error {
+	if h.reports == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "reports are not available",
+		})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid report id",
+		})
+	}
+
+	report, err := h.reports.Get(c.UserContext(), id)
+	if err != nil {
+		return reportError(c, err)
+	}
+	return c.JSON(report)
+}
+
+// UpdateReportStatus moves a report along the queue
+// PUT /admin/reports/:id/status
+func (h *AdminHandler) UpdateReportStatus(c *fiber.Ctx) error {
+	if h.reports == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "reports are not available",
+		})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid report id",
+		})
+	}
+	adminID := c.Locals("userID").(uuid.UUID)
+
+	var req models.UpdateReportStatusRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	report, err := h.reports.UpdateStatus(c.UserContext(), adminID, id, &req)
+	if err != nil {
+		return reportError(c, err)
+	}
+	return c.JSON(report)
+}
+
+// AssignReport gives a report to an instance admin
+// PUT /admin/reports/:id/assignee
+func (h *AdminHandler) AssignReport(c *fiber.Ctx) error {
+	if h.reports == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "reports are not available",
+		})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid report id",
+		})
+	}
+	adminID := c.Locals("userID").(uuid.UUID)
+
+	var req models.AssignReportRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	report, err := h.reports.Assign(c.UserContext(), adminID, id, req.AssigneeID)
+	if err != nil {
+		return reportError(c, err)
+	}
+	return c.JSON(report)
+}
+
+// UnassignReport takes a report away from whoever has it
+// DELETE /admin/reports/:id/assignee
+func (h *AdminHandler) UnassignReport(c *fiber.Ctx) error {
+	if h.reports == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "reports are not available",
+		})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid report id",
+		})
+	}
+	adminID := c.Locals("userID").(uuid.UUID)
+
+	report, err := h.reports.Unassign(c.UserContext(), adminID, id)
+	if err != nil {
+		return reportError(c, err)
+	}
+	return c.JSON(report)
+}
+
+// AddReportNote adds a note to a report's history
+// POST /admin/reports/:id/notes
+func (h *AdminHandler) AddReportNote(c *fiber.Ctx) error {
+	if h.reports == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "reports are not available",
+		})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid report id",
+		})
+	}
+	adminID := c.Locals("userID").(uuid.UUID)
+
+	var req models.ReportNoteRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	event, err := h.reports.AddNote(c.UserContext(), adminID, id, req.Note)
+	if err != nil {
+		return reportError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(event)
+}
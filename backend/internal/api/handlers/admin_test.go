@@ -0,0 +1,1152 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/config"
+	"hearth/internal/models"
+	"hearth/internal/services"
+	ws "hearth/internal/websocket"
+)
+
+type stubUserGetter map[uuid.UUID]*models.User
+
+func (s stubUserGetter) GetUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	if user, ok := s[id]; ok {
+		return user, nil
+	}
+	return nil, services.ErrUserNotFound
+}
+
+type stubIntegrityChecker struct {
+	repair *bool
+	err    error
+}
+
+func (s *stubIntegrityChecker) Check(ctx context.Context, repair bool) (*services.IntegrityReport, error) {
+	s.repair = &repair
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &services.IntegrityReport{Repair: repair}, nil
+}
+
+func setupAdminTestApp(h *AdminHandler, userID uuid.UUID) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", userID)
+		return c.Next()
+	})
+	admin := app.Group("/admin", h.RequireStaff, h.RateLimit)
+	admin.Get("/integrity", h.CheckIntegrity)
+	admin.Post("/integrity/repair", h.RepairIntegrity)
+	admin.Get("/compliance-log", h.ExportComplianceLog)
+	admin.Get("/username-rules", h.ListUsernameRules)
+	admin.Post("/username-rules", h.CreateUsernameRule)
+	admin.Delete("/username-rules/:id", h.DeleteUsernameRule)
+	admin.Get("/bots", h.ListBots)
+	admin.Get("/bots/:id", h.GetBotTier)
+	admin.Put("/bots/:id/tier", h.SetBotTier)
+	admin.Post("/users/:id/erasure", h.RequestErasure)
+	admin.Get("/users/:id/erasures", h.ListErasures)
+	admin.Get("/erasures/:id", h.GetErasure)
+	admin.Get("/servers/:id/throttle", h.GetServerThrottle)
+	admin.Put("/servers/:id/throttle", h.SetServerThrottle)
+	admin.Delete("/servers/:id/throttle", h.ResetServerThrottle)
+	admin.Get("/quotas", h.ListQuotas)
+	admin.Get("/quotas/:tier", h.GetQuotaTier)
+	admin.Put("/quotas/:tier", h.SetQuotaTier)
+	admin.Delete("/quotas/:tier", h.ResetQuotaTier)
+	admin.Get("/ip-rules", h.ListIPRules)
+	admin.Post("/ip-rules", h.CreateIPRule)
+	admin.Get("/ip-rules/check", h.CheckIP)
+	admin.Delete("/ip-rules/:id", h.DeleteIPRule)
+	admin.Get("/users", h.ListUsers)
+	admin.Put("/users/:id/suspension", h.SuspendUser)
+	admin.Delete("/users/:id/suspension", h.UnsuspendUser)
+	admin.Delete("/servers/:id", h.DeleteServer)
+	admin.Get("/stats", h.GetStats)
+	admin.Post("/config/reload", h.ReloadConfig)
+	admin.Get("/announcements", h.ListAnnouncements)
+	admin.Post("/announcements", h.CreateAnnouncement)
+	admin.Delete("/announcements/:id", h.DeleteAnnouncement)
+	admin.Get("/read-only", h.GetReadOnlyMode)
+	admin.Put("/read-only", h.SetReadOnlyMode)
+	admin.Get("/reports", h.ListReports)
+	admin.Get("/reports/:id", h.GetReport)
+	admin.Put("/reports/:id/status", h.UpdateReportStatus)
+	admin.Put("/reports/:id/assignee", h.AssignReport)
+	admin.Delete("/reports/:id/assignee", h.UnassignReport)
+	admin.Post("/reports/:id/notes", h.AddReportNote)
+	return app
+}
+
+func TestAdminHandler_RequiresStaff(t *testing.T) {
+	userID := uuid.New()
+	checker := &stubIntegrityChecker{}
+	h := NewAdminHandler(stubUserGetter{userID: {ID: userID}}, checker)
+
+	resp, err := setupAdminTestApp(h, userID).Test(httptest.NewRequest("GET", "/admin/integrity", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	assert.Nil(t, checker.repair)
+
+	resp, err = setupAdminTestApp(h, uuid.New()).Test(httptest.NewRequest("GET", "/admin/integrity", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+}
+
+func TestAdminHandler_Integrity(t *testing.T) {
+	userID := uuid.New()
+	checker := &stubIntegrityChecker{}
+	app := setupAdminTestApp(NewAdminHandler(stubUserGetter{userID: {ID: userID, Flags: models.UserFlagStaff}}, checker), userID)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/integrity", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.False(t, *checker.repair)
+
+	resp, err = app.Test(httptest.NewRequest("POST", "/admin/integrity/repair", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.True(t, *checker.repair)
+
+	checker.err = services.ErrIntegrityCheckRunning
+	resp, err = app.Test(httptest.NewRequest("POST", "/admin/integrity/repair", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+}
+
+type stubComplianceExporter struct {
+	entries []*models.ComplianceLogEntry
+	filter  models.ComplianceLogFilter
+	err     error
+}
+
+func (s *stubComplianceExporter) Export(ctx context.Context, filter models.ComplianceLogFilter, fn func(*models.ComplianceLogEntry) error) error {
+	s.filter = filter
+	for _, entry := range s.entries {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return s.err
+}
+
+func TestAdminHandler_ExportComplianceLog(t *testing.T) {
+	userID := uuid.New()
+	h := NewAdminHandler(stubUserGetter{userID: {ID: userID, Flags: models.UserFlagStaff}}, &stubIntegrityChecker{})
+	app := setupAdminTestApp(h, userID)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/compliance-log", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode, "disabled until an exporter is set")
+
+	exporter := &stubComplianceExporter{entries: []*models.ComplianceLogEntry{
+		{ID: uuid.New(), Method: "POST", Route: "/api/v1/servers", Status: 201, Outcome: models.ComplianceOutcomeSuccess},
+		{ID: uuid.New(), Method: "DELETE", Route: "/api/v1/servers/:id", Status: 403, Outcome: models.ComplianceOutcomeDenied},
+	}}
+	h.SetComplianceExporter(exporter)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/admin/compliance-log?after=2026-01-01T00:00:00Z&actor_id="+userID.String(), nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+
+	var lines []models.ComplianceLogEntry
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var entry models.ComplianceLogEntry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		lines = append(lines, entry)
+	}
+	require.Len(t, lines, 2)
+	assert.Equal(t, "/api/v1/servers/:id", lines[1].Route)
+	require.NotNil(t, exporter.filter.After)
+	assert.True(t, exporter.filter.After.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, userID, *exporter.filter.ActorID)
+	assert.Nil(t, exporter.filter.Before)
+
+	for _, query := range []string{"?after=yesterday", "?actor_id=nope", "?after=2026-02-01T00:00:00Z&before=2026-01-01T00:00:00Z"} {
+		resp, err = app.Test(httptest.NewRequest("GET", "/admin/compliance-log"+query, nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, query)
+	}
+
+	exporter.err = errors.New("database down")
+	resp, err = app.Test(httptest.NewRequest("GET", "/admin/compliance-log", nil))
+	require.NoError(t, err)
+	var last map[string]interface{}
+	scanner = bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		last = nil
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &last))
+	}
+	assert.Equal(t, "export incomplete", last["error"], "a failed export ends with an error line")
+}
+
+type stubUsernameRules struct {
+	rules     []*models.UsernameRule
+	creatorID uuid.UUID
+	err       error
+}
+
+func (s *stubUsernameRules) ListRules(ctx context.Context) ([]*models.UsernameRule, error) {
+	return s.rules, s.err
+}
+
+func (s *stubUsernameRules) CreateRule(ctx context.Context, creatorID uuid.UUID, req *models.CreateUsernameRuleRequest) (*models.UsernameRule, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.creatorID = creatorID
+	rule := &models.UsernameRule{ID: uuid.New(), Kind: req.Kind, Pattern: req.Pattern, Regex: req.Regex}
+	s.rules = append(s.rules, rule)
+	return rule, nil
+}
+
+func (s *stubUsernameRules) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	return s.err
+}
+
+func TestAdminHandler_UsernameRules(t *testing.T) {
+	userID := uuid.New()
+	h := NewAdminHandler(stubUserGetter{userID: {ID: userID, Flags: models.UserFlagStaff}}, &stubIntegrityChecker{})
+	app := setupAdminTestApp(h, userID)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/username-rules", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode, "unavailable until rules are set")
+
+	rules := &stubUsernameRules{}
+	h.SetUsernameRules(rules)
+
+	req := httptest.NewRequest("POST", "/admin/username-rules", strings.NewReader(`{"kind":"banned","pattern":"^bad","regex":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	assert.Equal(t, userID, rules.creatorID)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/admin/username-rules", nil))
+	require.NoError(t, err)
+	var listed []models.UsernameRule
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&listed))
+	require.Len(t, listed, 1)
+	assert.Equal(t, models.UsernameRuleBanned, listed[0].Kind)
+	assert.True(t, listed[0].Regex)
+
+	resp, err = app.Test(httptest.NewRequest("DELETE", "/admin/username-rules/"+listed[0].ID.String(), nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("DELETE", "/admin/username-rules/nope", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	for _, tt := range []struct {
+		err    error
+		status int
+	}{
+		{services.ErrInvalidUsernameRule, fiber.StatusBadRequest},
+		{services.ErrUsernameRuleExists, fiber.StatusConflict},
+		{services.ErrUsernameRuleNotFound, fiber.StatusNotFound},
+		{errors.New("database down"), fiber.StatusInternalServerError},
+	} {
+		rules.err = tt.err
+		req := httptest.NewRequest("POST", "/admin/username-rules", strings.NewReader(`{"kind":"reserved","pattern":"admin"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err = app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, tt.status, resp.StatusCode, tt.err.Error())
+	}
+}
+
+type stubBotTiers struct {
+	bots    map[uuid.UUID]*models.BotVerification
+	adminID uuid.UUID
+}
+
+func (s *stubBotTiers) ListBots(ctx context.Context, tier models.BotTier) ([]*models.BotVerification, error) {
+	if tier != "" && !tier.Valid() {
+		return nil, services.ErrInvalidBotTier
+	}
+	bots := []*models.BotVerification{}
+	for _, bot := range s.bots {
+		if tier == "" || bot.Tier == tier {
+			bots = append(bots, bot)
+		}
+	}
+	return bots, nil
+}
+
+func (s *stubBotTiers) GetBot(ctx context.Context, botID uuid.UUID) (*models.BotVerification, error) {
+	bot, ok := s.bots[botID]
+	if !ok {
+		return nil, services.ErrNotABot
+	}
+	return bot, nil
+}
+
+func (s *stubBotTiers) SetTier(ctx context.Context, adminID, botID uuid.UUID, req *models.SetBotTierRequest) (*models.BotVerification, error) {
+	if !req.Tier.Valid() {
+		return nil, services.ErrInvalidBotTier
+	}
+	bot, ok := s.bots[botID]
+	if !ok {
+		return nil, services.ErrUserNotFound
+	}
+	s.adminID = adminID
+	bot.Tier = req.Tier
+	bot.Policy = req.Tier.Policy()
+	return bot, nil
+}
+
+func TestAdminHandler_BotTiers(t *testing.T) {
+	userID, botID := uuid.New(), uuid.New()
+	h := NewAdminHandler(stubUserGetter{userID: {ID: userID, Flags: models.UserFlagStaff}}, &stubIntegrityChecker{})
+	app := setupAdminTestApp(h, userID)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/bots", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode, "unavailable until tiers are set")
+
+	tiers := &stubBotTiers{bots: map[uuid.UUID]*models.BotVerification{
+		botID: {UserID: botID, Tier: models.BotTierUnverified},
+	}}
+	h.SetBotTiers(tiers)
+
+	req := httptest.NewRequest("PUT", "/admin/bots/"+botID.String()+"/tier", strings.NewReader(`{"tier":"verified"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, userID, tiers.adminID)
+	var bot models.BotVerification
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&bot))
+	assert.Equal(t, models.BotTierVerified, bot.Tier)
+	assert.Equal(t, 2, bot.Policy.RateLimitMultiplier)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/admin/bots?tier=verified", nil))
+	require.NoError(t, err)
+	var listed []models.BotVerification
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&listed))
+	assert.Len(t, listed, 1)
+
+	for _, tt := range []struct {
+		method, path, body string
+		status             int
+	}{
+		{"GET", "/admin/bots?tier=gold", "", fiber.StatusBadRequest},
+		{"GET", "/admin/bots/nope", "", fiber.StatusBadRequest},
+		{"GET", "/admin/bots/" + uuid.NewString(), "", fiber.StatusBadRequest},
+		{"PUT", "/admin/bots/" + botID.String() + "/tier", `{"tier":"gold"}`, fiber.StatusBadRequest},
+		{"PUT", "/admin/bots/" + uuid.NewString() + "/tier", `{"tier":"partner"}`, fiber.StatusNotFound},
+	} {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err = app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, tt.status, resp.StatusCode, tt.method+" "+tt.path)
+	}
+}
+
+type stubContentEraser struct {
+	jobs map[uuid.UUID]*models.ContentErasure
+}
+
+func (s *stubContentEraser) RequestErasure(ctx context.Context, userID, requestedBy uuid.UUID) (*models.ContentErasure, error) {
+	for _, job := range s.jobs {
+		if job.UserID == userID && !job.Status.Finished() {
+			return nil, services.ErrContentErasureInProgress
+		}
+	}
+	job := &models.ContentErasure{ID: uuid.New(), UserID: userID, RequestedBy: requestedBy, Status: models.ContentErasureQueued}
+	s.jobs[job.ID] = job
+	return job, nil
+}
+
+func (s *stubContentEraser) GetErasure(ctx context.Context, id uuid.UUID) (*models.ContentErasure, error) {
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, services.ErrContentErasureNotFound
+	}
+	return job, nil
+}
+
+func (s *stubContentEraser) ListErasures(ctx context.Context, userID uuid.UUID) ([]*models.ContentErasure, error) {
+	jobs := []*models.ContentErasure{}
+	for _, job := range s.jobs {
+		if job.UserID == userID {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func TestAdminHandler_ContentErasure(t *testing.T) {
+	adminID, userID := uuid.New(), uuid.New()
+	h := NewAdminHandler(stubUserGetter{adminID: {ID: adminID, Flags: models.UserFlagStaff}}, &stubIntegrityChecker{})
+	app := setupAdminTestApp(h, adminID)
+	path := "/admin/users/" + userID.String() + "/erasure"
+
+	resp, err := app.Test(httptest.NewRequest("POST", path, nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode, "unavailable until an eraser is set")
+
+	h.SetContentEraser(&stubContentEraser{jobs: make(map[uuid.UUID]*models.ContentErasure)})
+
+	resp, err = app.Test(httptest.NewRequest("POST", path, nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+	var job models.ContentErasure
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&job))
+	assert.Equal(t, userID, job.UserID)
+	assert.Equal(t, adminID, job.RequestedBy)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/admin/erasures/"+job.ID.String(), nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/admin/users/"+userID.String()+"/erasures", nil))
+	require.NoError(t, err)
+	var listed []models.ContentErasure
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&listed))
+	assert.Len(t, listed, 1)
+
+	for _, tt := range []struct {
+		method, path string
+		status       int
+	}{
+		{"POST", path, fiber.StatusConflict},
+		{"POST", "/admin/users/nope/erasure", fiber.StatusBadRequest},
+		{"GET", "/admin/erasures/nope", fiber.StatusBadRequest},
+		{"GET", "/admin/erasures/" + uuid.NewString(), fiber.StatusNotFound},
+	} {
+		resp, err = app.Test(httptest.NewRequest(tt.method, tt.path, nil))
+		require.NoError(t, err)
+		assert.Equal(t, tt.status, resp.StatusCode, tt.method+" "+tt.path)
+	}
+}
+
+type stubServerThrottles struct {
+	serverID  uuid.UUID
+	throttle  *models.ServerThrottle
+	adminID   uuid.UUID
+	defaults  models.ServerThrottleLimits
+	resetDone bool
+}
+
+func (s *stubServerThrottles) GetThrottle(ctx context.Context, serverID uuid.UUID) (*models.ServerThrottle, error) {
+	if serverID != s.serverID {
+		return nil, services.ErrServerNotFound
+	}
+	throttle := s.throttle
+	if throttle == nil {
+		throttle = &models.ServerThrottle{ServerID: serverID}
+	}
+	throttle.Limits = throttle.Apply(s.defaults)
+	return throttle, nil
+}
+
+func (s *stubServerThrottles) SetThrottle(ctx context.Context, adminID, serverID uuid.UUID, req *models.SetServerThrottleRequest) (*models.ServerThrottle, error) {
+	if req.MessagesPerSecond != nil && *req.MessagesPerSecond < 0 {
+		return nil, services.ErrInvalidServerThrottle
+	}
+	if serverID != s.serverID {
+		return nil, services.ErrServerNotFound
+	}
+	s.adminID = adminID
+	s.throttle = &models.ServerThrottle{ServerID: serverID, MessagesPerSecond: req.MessagesPerSecond, EventsPerSecond: req.EventsPerSecond, Reason: req.Reason}
+	return s.GetThrottle(ctx, serverID)
+}
+
+func (s *stubServerThrottles) ResetThrottle(ctx context.Context, serverID uuid.UUID) error {
+	if serverID != s.serverID {
+		return services.ErrServerNotFound
+	}
+	s.throttle = nil
+	s.resetDone = true
+	return nil
+}
+
+func TestAdminHandler_ServerThrottles(t *testing.T) {
+	userID, serverID := uuid.New(), uuid.New()
+	h := NewAdminHandler(stubUserGetter{userID: {ID: userID, Flags: models.UserFlagStaff}}, &stubIntegrityChecker{})
+	app := setupAdminTestApp(h, userID)
+	path := "/admin/servers/" + serverID.String() + "/throttle"
+
+	resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode, "unavailable until throttles are set")
+
+	throttles := &stubServerThrottles{serverID: serverID, defaults: models.ServerThrottleLimits{MessagesPerSecond: 50, EventsPerSecond: 200}}
+	h.SetServerThrottles(throttles)
+
+	req := httptest.NewRequest("PUT", path, strings.NewReader(`{"messages_per_second":5,"reason":"spam wave"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, userID, throttles.adminID)
+	var throttle models.ServerThrottle
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&throttle))
+	assert.Equal(t, models.ServerThrottleLimits{MessagesPerSecond: 5, EventsPerSecond: 200}, throttle.Limits)
+	require.NotNil(t, throttle.Reason)
+	assert.Equal(t, "spam wave", *throttle.Reason)
+
+	resp, err = app.Test(httptest.NewRequest("DELETE", path, nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	assert.True(t, throttles.resetDone)
+
+	resp, err = app.Test(httptest.NewRequest("GET", path, nil))
+	require.NoError(t, err)
+	throttle = models.ServerThrottle{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&throttle))
+	assert.Nil(t, throttle.MessagesPerSecond)
+	assert.Equal(t, 50, throttle.Limits.MessagesPerSecond)
+
+	for _, tt := range []struct {
+		method, path, body string
+		status             int
+	}{
+		{"GET", "/admin/servers/nope/throttle", "", fiber.StatusBadRequest},
+		{"GET", "/admin/servers/" + uuid.NewString() + "/throttle", "", fiber.StatusNotFound},
+		{"PUT", path, `{"messages_per_second":-1}`, fiber.StatusBadRequest},
+		{"PUT", path, `{"messages_per_second":"lots"}`, fiber.StatusBadRequest},
+		{"DELETE", "/admin/servers/" + uuid.NewString() + "/throttle", "", fiber.StatusNotFound},
+	} {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err = app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, tt.status, resp.StatusCode, tt.method+" "+tt.path)
+	}
+}
+
+type stubQuotas struct {
+	config    models.QuotaLimits
+	overrides map[models.QuotaTier]*models.QuotaOverride
+	adminID   uuid.UUID
+}
+
+func (s *stubQuotas) GetQuotas(ctx context.Context) (*models.QuotaSettings, error) {
+	settings := &models.QuotaSettings{Config: s.config}
+	for _, tier := range models.QuotaTiers {
+		override, _ := s.GetTierQuotas(ctx, tier)
+		settings.Tiers = append(settings.Tiers, override)
+	}
+	return settings, nil
+}
+
+func (s *stubQuotas) GetTierQuotas(ctx context.Context, tier models.QuotaTier) (*models.QuotaOverride, error) {
+	if !tier.Valid() {
+		return nil, services.ErrInvalidQuotaTier
+	}
+	override := s.overrides[tier]
+	if override == nil {
+		override = &models.QuotaOverride{Tier: tier}
+	}
+	override.Limits = override.Apply(s.overrides[models.QuotaTierDefault].Apply(s.config))
+	return override, nil
+}
+
+func (s *stubQuotas) SetTierQuotas(ctx context.Context, adminID uuid.UUID, tier models.QuotaTier, req *models.SetQuotaOverrideRequest) (*models.QuotaOverride, error) {
+	if !tier.Valid() {
+		return nil, services.ErrInvalidQuotaTier
+	}
+	if req.MaxServersOwned != nil && *req.MaxServersOwned < 0 {
+		return nil, services.ErrInvalidQuota
+	}
+	s.adminID = adminID
+	s.overrides[tier] = &models.QuotaOverride{Tier: tier, MaxServersOwned: req.MaxServersOwned, MaxFileSizeMB: req.MaxFileSizeMB}
+	return s.GetTierQuotas(ctx, tier)
+}
+
+func (s *stubQuotas) ResetTierQuotas(ctx context.Context, tier models.QuotaTier) error {
+	if !tier.Valid() {
+		return services.ErrInvalidQuotaTier
+	}
+	delete(s.overrides, tier)
+	return nil
+}
+
+func TestAdminHandler_Quotas(t *testing.T) {
+	userID := uuid.New()
+	h := NewAdminHandler(stubUserGetter{userID: {ID: userID, Flags: models.UserFlagStaff}}, &stubIntegrityChecker{})
+	app := setupAdminTestApp(h, userID)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/quotas", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode, "unavailable until quotas are set")
+
+	quotas := &stubQuotas{
+		config:    models.QuotaLimits{MaxServersOwned: 10, MaxFileSizeMB: 25},
+		overrides: make(map[models.QuotaTier]*models.QuotaOverride),
+	}
+	h.SetQuotas(quotas)
+
+	req := httptest.NewRequest("PUT", "/admin/quotas/premium", strings.NewReader(`{"max_servers_owned":50,"max_file_size_mb":500}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, userID, quotas.adminID)
+	var override models.QuotaOverride
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&override))
+	assert.Equal(t, models.QuotaLimits{MaxServersOwned: 50, MaxFileSizeMB: 500}, override.Limits)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/admin/quotas", nil))
+	require.NoError(t, err)
+	var settings models.QuotaSettings
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&settings))
+	assert.Equal(t, 10, settings.Config.MaxServersOwned)
+	require.Len(t, settings.Tiers, 3)
+	assert.Equal(t, 50, settings.Tiers[1].Limits.MaxServersOwned)
+
+	resp, err = app.Test(httptest.NewRequest("DELETE", "/admin/quotas/premium", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/admin/quotas/premium", nil))
+	require.NoError(t, err)
+	override = models.QuotaOverride{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&override))
+	assert.Nil(t, override.MaxServersOwned)
+	assert.Equal(t, 10, override.Limits.MaxServersOwned)
+
+	for _, tt := range []struct {
+		method, path, body string
+		status             int
+	}{
+		{"GET", "/admin/quotas/gold", "", fiber.StatusBadRequest},
+		{"PUT", "/admin/quotas/gold", `{}`, fiber.StatusBadRequest},
+		{"PUT", "/admin/quotas/default", `{"max_servers_owned":-1}`, fiber.StatusBadRequest},
+		{"PUT", "/admin/quotas/default", `{"max_servers_owned":"lots"}`, fiber.StatusBadRequest},
+		{"DELETE", "/admin/quotas/gold", "", fiber.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err = app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, tt.status, resp.StatusCode, tt.method+" "+tt.path)
+	}
+}
+
+type stubIPRules struct {
+	rules     []*models.IPRule
+	creatorID uuid.UUID
+	checked   string
+}
+
+func (s *stubIPRules) ListRules(ctx context.Context) ([]*models.IPRule, error) {
+	return s.rules, nil
+}
+
+func (s *stubIPRules) CreateRule(ctx context.Context, creatorID uuid.UUID, req *models.CreateIPRuleRequest) (*models.IPRule, error) {
+	if req.Action != models.IPRuleBlock && req.Action != models.IPRuleAllow {
+		return nil, services.ErrInvalidIPRule
+	}
+	for _, rule := range s.rules {
+		if rule.Action == req.Action && req.CIDR != nil && rule.CIDR != nil && *rule.CIDR == *req.CIDR {
+			return nil, services.ErrIPRuleExists
+		}
+	}
+	s.creatorID = creatorID
+	rule := &models.IPRule{ID: uuid.New(), Action: req.Action, CIDR: req.CIDR, ASN: req.ASN, Reason: req.Reason, CreatorID: &creatorID}
+	s.rules = append(s.rules, rule)
+	return rule, nil
+}
+
+func (s *stubIPRules) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	for i, rule := range s.rules {
+		if rule.ID == id {
+			s.rules = append(s.rules[:i], s.rules[i+1:]...)
+			return nil
+		}
+	}
+	return services.ErrIPRuleNotFound
+}
+
+func (s *stubIPRules) Check(ctx context.Context, ip string) *models.IPVerdict {
+	s.checked = ip
+	return &models.IPVerdict{IP: ip, Blocked: len(s.rules) > 0, Reason: models.IPBlockedCIDR}
+}
+
+func TestAdminHandler_IPRules(t *testing.T) {
+	userID := uuid.New()
+	h := NewAdminHandler(stubUserGetter{userID: {ID: userID, Flags: models.UserFlagStaff}}, &stubIntegrityChecker{})
+	app := setupAdminTestApp(h, userID)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/ip-rules", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode, "unavailable until ip rules are set")
+
+	rules := &stubIPRules{}
+	h.SetIPRules(rules)
+
+	create := func(body string) *http.Response {
+		req := httptest.NewRequest("POST", "/admin/ip-rules", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+	resp = create(`{"action":"block","cidr":"198.51.100.0/24","reason":"scrapers"}`)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	assert.Equal(t, userID, rules.creatorID)
+	var rule models.IPRule
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&rule))
+	assert.Equal(t, "198.51.100.0/24", *rule.CIDR)
+
+	assert.Equal(t, fiber.StatusConflict, create(`{"action":"block","cidr":"198.51.100.0/24"}`).StatusCode)
+	assert.Equal(t, fiber.StatusBadRequest, create(`{"action":"deny","cidr":"198.51.100.0/24"}`).StatusCode)
+	assert.Equal(t, fiber.StatusBadRequest, create(`{"action":`).StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/admin/ip-rules", nil))
+	require.NoError(t, err)
+	var listed []*models.IPRule
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&listed))
+	assert.Len(t, listed, 1)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/admin/ip-rules/check?ip=198.51.100.7", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var verdict models.IPVerdict
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&verdict))
+	assert.True(t, verdict.Blocked)
+	assert.Equal(t, "198.51.100.7", rules.checked)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/admin/ip-rules/check?ip=nope", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("DELETE", "/admin/ip-rules/"+rule.ID.String(), nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	resp, err = app.Test(httptest.NewRequest("DELETE", "/admin/ip-rules/"+rule.ID.String(), nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	resp, err = app.Test(httptest.NewRequest("DELETE", "/admin/ip-rules/not-an-id", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+type stubInstance struct {
+	users   []*models.AdminUser
+	query   string
+	after   *uuid.UUID
+	limit   int
+	servers map[uuid.UUID]*models.Server
+}
+
+func (s *stubInstance) ListUsers(ctx context.Context, query string, after *uuid.UUID, limit int) ([]*models.AdminUser, error) {
+	s.query, s.after, s.limit = query, after, limit
+	return s.users, nil
+}
+
+func (s *stubInstance) DeleteServer(ctx context.Context, serverID uuid.UUID) (*models.Server, error) {
+	server, ok := s.servers[serverID]
+	if !ok {
+		return nil, services.ErrServerNotFound
+	}
+	delete(s.servers, serverID)
+	return server, nil
+}
+
+func (s *stubInstance) Stats(ctx context.Context) (*models.InstanceStats, error) {
+	return &models.InstanceStats{Users: int64(len(s.users)), Servers: int64(len(s.servers))}, nil
+}
+
+func TestAdminHandler_Instance(t *testing.T) {
+	userID := uuid.New()
+	h := NewAdminHandler(stubUserGetter{userID: {ID: userID, Flags: models.UserFlagStaff}}, &stubIntegrityChecker{})
+	app := setupAdminTestApp(h, userID)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/users", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode, "unavailable until the instance is set")
+
+	serverID := uuid.New()
+	instance := &stubInstance{
+		users:   []*models.AdminUser{{User: &models.User{ID: userID, Username: "admin"}}},
+		servers: map[uuid.UUID]*models.Server{serverID: {ID: serverID}},
+	}
+	h.SetInstance(instance)
+
+	after := uuid.New()
+	resp, err = app.Test(httptest.NewRequest("GET", "/admin/users?q=ad&limit=20&after="+after.String(), nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var users []*models.AdminUser
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&users))
+	require.Len(t, users, 1)
+	assert.Equal(t, "admin", users[0].Username)
+	assert.Equal(t, "ad", instance.query)
+	assert.Equal(t, after, *instance.after)
+	assert.Equal(t, 20, instance.limit)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/admin/users?after=nope", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/admin/stats", nil))
+	require.NoError(t, err)
+	var stats models.InstanceStats
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&stats))
+	assert.Equal(t, int64(1), stats.Servers)
+
+	resp, err = app.Test(httptest.NewRequest("DELETE", "/admin/servers/"+serverID.String(), nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	resp, err = app.Test(httptest.NewRequest("DELETE", "/admin/servers/"+serverID.String(), nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+type stubSuspender struct {
+	suspended map[uuid.UUID]*models.UserSuspension
+	staff     uuid.UUID
+}
+
+func (s *stubSuspender) Suspend(ctx context.Context, adminID, userID uuid.UUID, req *models.SuspendUserRequest) (*models.UserSuspension, error) {
+	if userID == s.staff {
+		return nil, services.ErrCannotSuspendAdmin
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return nil, services.ErrInvalidSuspension
+	}
+	suspension := &models.UserSuspension{UserID: userID, Reason: req.Reason, SuspendedBy: &adminID, ExpiresAt: req.ExpiresAt}
+	s.suspended[userID] = suspension
+	return suspension, nil
+}
+
+func (s *stubSuspender) Unsuspend(ctx context.Context, userID uuid.UUID) error {
+	if _, ok := s.suspended[userID]; !ok {
+		return services.ErrSuspensionNotFound
+	}
+	delete(s.suspended, userID)
+	return nil
+}
+
+func TestAdminHandler_Suspensions(t *testing.T) {
+	userID := uuid.New()
+	h := NewAdminHandler(stubUserGetter{userID: {ID: userID, Flags: models.UserFlagStaff}}, &stubIntegrityChecker{})
+	app := setupAdminTestApp(h, userID)
+	suspender := &stubSuspender{suspended: make(map[uuid.UUID]*models.UserSuspension), staff: userID}
+	h.SetSuspensions(suspender)
+	targetID := uuid.New()
+
+	suspend := func(id uuid.UUID, body string) *http.Response {
+		req := httptest.NewRequest("PUT", "/admin/users/"+id.String()+"/suspension", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+	resp := suspend(targetID, `{"reason":"spam"}`)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var suspension models.UserSuspension
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&suspension))
+	assert.Equal(t, "spam", *suspension.Reason)
+	assert.Equal(t, userID, *suspension.SuspendedBy)
+
+	assert.Equal(t, fiber.StatusOK, suspend(uuid.New(), "").StatusCode, "the body is optional")
+	assert.Equal(t, fiber.StatusBadRequest, suspend(targetID, `{"expires_at":"2000-01-01T00:00:00Z"}`).StatusCode)
+	assert.Equal(t, fiber.StatusForbidden, suspend(userID, `{}`).StatusCode)
+
+	resp, err := app.Test(httptest.NewRequest("DELETE", "/admin/users/"+targetID.String()+"/suspension", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	resp, err = app.Test(httptest.NewRequest("DELETE", "/admin/users/"+targetID.String()+"/suspension", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+type stubConfigReloader struct {
+	result *models.ConfigReload
+	err    error
+}
+
+func (s *stubConfigReloader) Reload() (*models.ConfigReload, error) {
+	return s.result, s.err
+}
+
+func TestAdminHandler_ReloadConfig(t *testing.T) {
+	userID := uuid.New()
+	h := NewAdminHandler(stubUserGetter{userID: {ID: userID, Flags: models.UserFlagStaff}}, &stubIntegrityChecker{})
+	app := setupAdminTestApp(h, userID)
+	reloader := &stubConfigReloader{result: &models.ConfigReload{Applied: []string{"LOCKOUT_THRESHOLD"}}}
+	h.SetConfigReloader(reloader)
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/admin/config/reload", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var result models.ConfigReload
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, []string{"LOCKOUT_THRESHOLD"}, result.Applied)
+
+	reloader.err = config.ErrNoConfigFile
+	resp, err = app.Test(httptest.NewRequest("POST", "/admin/config/reload", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+
+	reloader.err = errors.New("hearth.env:3: expected KEY=VALUE")
+	resp, err = app.Test(httptest.NewRequest("POST", "/admin/config/reload", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+	var body map[string]string
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Contains(t, body["error"], "hearth.env:3", "the admin is told what to fix")
+}
+
+type stubAnnouncements struct {
+	announcements []*models.Announcement
+}
+
+func (s *stubAnnouncements) List(ctx context.Context) ([]*models.Announcement, error) {
+	return s.announcements, nil
+}
+
+func (s *stubAnnouncements) Active(ctx context.Context) ([]*models.Announcement, error) {
+	return s.announcements, nil
+}
+
+func (s *stubAnnouncements) Create(ctx context.Context, creatorID uuid.UUID, req *models.CreateAnnouncementRequest) (*models.Announcement, error) {
+	if strings.TrimSpace(req.Content) == "" {
+		return nil, services.ErrInvalidAnnouncement
+	}
+	announcement := &models.Announcement{ID: uuid.New(), Content: req.Content, Level: models.AnnouncementInfo, CreatorID: &creatorID}
+	s.announcements = append(s.announcements, announcement)
+	return announcement, nil
+}
+
+func (s *stubAnnouncements) Delete(ctx context.Context, id uuid.UUID) error {
+	for i, announcement := range s.announcements {
+		if announcement.ID == id {
+			s.announcements = append(s.announcements[:i], s.announcements[i+1:]...)
+			return nil
+		}
+	}
+	return services.ErrAnnouncementNotFound
+}
+
+func TestAdminHandler_Announcements(t *testing.T) {
+	userID := uuid.New()
+	h := NewAdminHandler(stubUserGetter{userID: {ID: userID, Flags: models.UserFlagStaff}}, &stubIntegrityChecker{})
+	app := setupAdminTestApp(h, userID)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/announcements", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode, "unavailable until announcements are set")
+
+	announcements := &stubAnnouncements{}
+	h.SetAnnouncements(announcements)
+
+	create := func(body string) *http.Response {
+		req := httptest.NewRequest("POST", "/admin/announcements", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+	resp = create(`{"content":"Maintenance at 22:00 UTC"}`)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	var announcement models.Announcement
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&announcement))
+	assert.Equal(t, userID, *announcement.CreatorID)
+	assert.Equal(t, fiber.StatusBadRequest, create(`{"content":" "}`).StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/admin/announcements", nil))
+	require.NoError(t, err)
+	var listed []*models.Announcement
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&listed))
+	assert.Len(t, listed, 1)
+
+	resp, err = app.Test(httptest.NewRequest("DELETE", "/admin/announcements/"+announcement.ID.String(), nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	resp, err = app.Test(httptest.NewRequest("DELETE", "/admin/announcements/"+announcement.ID.String(), nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+type stubAdminLimiter struct {
+	writes int
+	limit  int
+}
+
+func (s *stubAdminLimiter) CheckAdminRequest(ctx context.Context, userID uuid.UUID, write bool) error {
+	if !write {
+		return nil
+	}
+	s.writes++
+	if s.writes > s.limit {
+		return &services.AdminRateLimitedError{RetryAfter: 1500 * time.Millisecond}
+	}
+	return nil
+}
+
+func TestAdminHandler_RateLimit(t *testing.T) {
+	userID := uuid.New()
+	h := NewAdminHandler(stubUserGetter{userID: {ID: userID, Flags: models.UserFlagStaff}}, &stubIntegrityChecker{})
+	app := setupAdminTestApp(h, userID)
+	limiter := &stubAdminLimiter{limit: 1}
+	h.SetRateLimiter(limiter)
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/admin/integrity/repair", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("POST", "/admin/integrity/repair", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/admin/integrity", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode, "reads are limited apart from writes")
+	assert.Equal(t, 2, limiter.writes)
+}
+
+type stubReadOnly struct {
+	mode models.ReadOnlyMode
+}
+
+func (s *stubReadOnly) Mode(ctx context.Context) *models.ReadOnlyMode {
+	return &s.mode
+}
+
+func (s *stubReadOnly) SetMode(ctx context.Context, adminID uuid.UUID, req *models.SetReadOnlyModeRequest) (*models.ReadOnlyMode, error) {
+	if req.Reason != nil && len(*req.Reason) > 10 {
+		return nil, services.ErrInvalidReadOnlyMode
+	}
+	s.mode = models.ReadOnlyMode{Enabled: req.Enabled, Reason: req.Reason, EnabledBy: &adminID}
+	return &s.mode, nil
+}
+
+func TestAdminHandler_ReadOnly(t *testing.T) {
+	userID := uuid.New()
+	h := NewAdminHandler(stubUserGetter{userID: {ID: userID, Flags: models.UserFlagStaff}}, &stubIntegrityChecker{})
+	app := setupAdminTestApp(h, userID)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/read-only", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode, "unavailable until read-only mode is set")
+
+	readOnly := &stubReadOnly{}
+	h.SetReadOnly(readOnly)
+
+	set := func(body string) *http.Response {
+		req := httptest.NewRequest("PUT", "/admin/read-only", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+	resp = set(`{"enabled":true,"reason":"failover"}`)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.True(t, readOnly.mode.Enabled)
+	assert.Equal(t, userID, *readOnly.mode.EnabledBy)
+	assert.Equal(t, fiber.StatusBadRequest, set(`{"enabled":true,"reason":"a very long reason"}`).StatusCode)
+	assert.Equal(t, fiber.StatusBadRequest, set(`{"enabled":`).StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/admin/read-only", nil))
+	require.NoError(t, err)
+	var mode models.ReadOnlyMode
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&mode))
+	assert.Equal(t, "failover", *mode.Reason)
+}
+
+func TestAdminHandler_Reports(t *testing.T) {
+	userID := uuid.New()
+	h := NewAdminHandler(stubUserGetter{userID: {ID: userID, Flags: models.UserFlagStaff}}, &stubIntegrityChecker{})
+	app := setupAdminTestApp(h, userID)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/reports", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode, "unavailable until reports are set")
+
+	reports := newStubReports()
+	h.SetReports(reports)
+	report, err := reports.Create(context.Background(), uuid.New(), &models.CreateReportRequest{TargetType: models.ReportTargetUser, TargetID: uuid.New(), Category: models.ReportSpam})
+	require.NoError(t, err)
+	path := "/admin/reports/" + report.ID.String()
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/admin/reports?status=open&assignee=me", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, models.ReportOpen, reports.filter.Status)
+	assert.Equal(t, userID, *reports.filter.AssigneeID)
+	resp, err = app.Test(httptest.NewRequest("GET", "/admin/reports?after=nope", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", path, nil))
+	require.NoError(t, err)
+	var detail models.ReportDetail
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&detail))
+	assert.Equal(t, "secret", *detail.MessageContent, "admins see what was recorded")
+	resp, err = app.Test(httptest.NewRequest("GET", "/admin/reports/"+uuid.NewString(), nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	send := func(method, path, body string) *http.Response {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+	assert.Equal(t, fiber.StatusBadRequest, send("PUT", path+"/status", `{"status":"actioned"}`).StatusCode)
+	assert.Equal(t, fiber.StatusOK, send("PUT", path+"/status", `{"status":"reviewing"}`).StatusCode)
+	assert.Equal(t, models.ReportReviewing, report.Status)
+
+	assignee := uuid.New()
+	assert.Equal(t, fiber.StatusOK, send("PUT", path+"/assignee", `{"assignee_id":"`+assignee.String()+`"}`).StatusCode)
+	assert.Equal(t, assignee, *report.AssigneeID)
+	assert.Equal(t, fiber.StatusOK, send("DELETE", path+"/assignee", "").StatusCode)
+	assert.Nil(t, report.AssigneeID)
+
+	assert.Equal(t, fiber.StatusCreated, send("POST", path+"/notes", `{"note":"checked the server"}`).StatusCode)
+	assert.Equal(t, fiber.StatusBadRequest, send("POST", "/admin/reports/nope/notes", `{"note":"hi"}`).StatusCode)
+}
+
+func TestAdminHandler_GatewayDrain(t *testing.T) {
+	staffID, userID := uuid.New(), uuid.New()
+	h := NewAdminHandler(stubUserGetter{
+		staffID: {ID: staffID, Flags: models.UserFlagStaff},
+		userID:  {ID: userID},
+	}, &stubIntegrityChecker{})
+	gateway := NewGatewayHandler(ws.NewGateway(ws.NewHub(), nil, nil))
+
+	request := func(as uuid.UUID) *http.Response {
+		app := setupAdminTestApp(h, as)
+		app.Get("/admin/gateway/drain", gateway.GetDrainStatus)
+		resp, err := app.Test(httptest.NewRequest("GET", "/admin/gateway/drain", nil))
+		require.NoError(t, err)
+		return resp
+	}
+
+	assert.Equal(t, fiber.StatusForbidden, request(userID).StatusCode)
+
+	resp := request(staffID)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var progress ws.DrainProgress
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&progress))
+	assert.Equal(t, "healthy", progress.State)
+}
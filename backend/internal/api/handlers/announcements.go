@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+
+	"hearth/internal/models"
+)
+
+// AnnouncementReader defines the methods needed from
+// services.AnnouncementService
+type AnnouncementReader interface {
+	Active(ctx context.Context) ([]*models.Announcement, error)
+}
+
+// AnnouncementHandler shows users the announcements instance admins post
+type AnnouncementHandler struct {
+	announcements AnnouncementReader
+}
+
+// NewAnnouncementHandler creates a new announcement handler
+func NewAnnouncementHandler(announcements AnnouncementReader) *AnnouncementHandler {
+	return &AnnouncementHandler{announcements: announcements}
+}
+
+// ListAnnouncements returns the announcements clients should show now,
+// newest first. Clients fetch them on startup; new ones arrive over the
+// gateway.
+// GET /announcements
+func (h *AnnouncementHandler) ListAnnouncements(c *fiber.Ctx) error {
+	announcements, err := h.announcements.Active(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list announcements",
+		})
+	}
+	return c.JSON(announcements)
+}
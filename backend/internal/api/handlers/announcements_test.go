@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+func TestAnnouncementHandler_ListAnnouncements(t *testing.T) {
+	announcements := &stubAnnouncements{announcements: []*models.Announcement{
+		{ID: uuid.New(), Content: "Maintenance at 22:00 UTC", Level: models.AnnouncementWarning},
+	}}
+	h := NewAnnouncementHandler(announcements)
+	app := fiber.New()
+	app.Get("/announcements", h.ListAnnouncements)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/announcements", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var listed []*models.Announcement
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&listed))
+	require.Len(t, listed, 1)
+	assert.Equal(t, models.AnnouncementWarning, listed[0].Level)
+}
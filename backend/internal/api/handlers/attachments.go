@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"io"
 	"time"
 
@@ -8,6 +9,7 @@ import (
 	"github.com/google/uuid"
 
 	"hearth/internal/services"
+	"hearth/internal/storage"
 )
 
 // AttachmentHandler handles attachment endpoints
@@ -29,7 +31,8 @@ func NewAttachmentHandler(
 
 // Upload handles file upload
 // POST /channels/:id/attachments
-// Form fields: file (required), alt_text (optional - for accessibility)
+// Form fields: file (required), alt_text (optional - for accessibility),
+// upload_id (optional - names ATTACHMENT_UPLOAD_PROGRESS events for the upload)
 func (h *AttachmentHandler) Upload(c *fiber.Ctx) error {
 	userID, ok := c.Locals("userID").(uuid.UUID)
 	if !ok {
@@ -56,6 +59,13 @@ func (h *AttachmentHandler) Upload(c *fiber.Ctx) error {
 	// Get optional alt text for accessibility (A11Y-004)
 	altText := c.FormValue("alt_text")
 
+	uploadID := c.FormValue("upload_id")
+	if uploadID != "" && !services.ValidUploadID(uploadID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "upload_id must be 1-64 letters, digits, dashes or underscores",
+		})
+	}
+
 	// Validate file
 	if !services.ValidateFileExtension(file.Filename) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -70,8 +80,8 @@ func (h *AttachmentHandler) Upload(c *fiber.Ctx) error {
 		})
 	}
 
-	// Upload file with alt text
-	attachment, err := h.attachmentService.UploadWithAltText(c.Context(), file, userID, channelID, altText)
+	// Upload file with alt text, reporting progress if the client named the upload
+	attachment, err := h.attachmentService.UploadWithProgress(c.UserContext(), file, userID, channelID, altText, uploadID)
 	if err != nil {
 		if err == services.ErrFileTooLarge {
 			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
@@ -86,6 +96,133 @@ func (h *AttachmentHandler) Upload(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusCreated).JSON(attachment)
 }
 
+// PresignUploadRequest is the body for requesting a direct upload URL
+type PresignUploadRequest struct {
+	Filename    string `json:"filename" validate:"required"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size" validate:"required,min=1"`
+	AltText     string `json:"alt_text,omitempty"`
+}
+
+// PresignUpload returns a presigned PUT URL for uploading a large file
+// directly to object storage, plus an attachment record in "pending" state.
+// POST /channels/:id/attachments/presign
+func (h *AttachmentHandler) PresignUpload(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	var req PresignUploadRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	if !services.ValidateFileExtension(req.Filename) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "file type not allowed",
+		})
+	}
+
+	if !services.ValidateContentType(req.ContentType) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "content type not allowed",
+		})
+	}
+
+	attachment, upload, err := h.attachmentService.CreatePendingUpload(
+		c.UserContext(), userID, channelID, req.Filename, req.ContentType, req.Size, req.AltText,
+	)
+	if err != nil {
+		if errors.Is(err, storage.ErrPresignNotSupported) {
+			return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{
+				"error": "direct uploads are not supported by this storage backend",
+			})
+		}
+		if errors.Is(err, services.ErrFileTooLarge) {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"error": "file too large",
+			})
+		}
+		if errors.Is(err, services.ErrFileTypeNotAllowed) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "file type not allowed",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to create upload",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"attachment": attachment,
+		"upload_url": upload.UploadURL,
+		"method":     upload.Method,
+		"headers":    upload.Headers,
+		"expires_at": upload.ExpiresAt.Unix(),
+	})
+}
+
+// ConfirmUpload finalizes a pending attachment after the client has
+// uploaded it directly to object storage
+// POST /attachments/:id/confirm
+func (h *AttachmentHandler) ConfirmUpload(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	attachmentID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid attachment id",
+		})
+	}
+
+	attachment, err := h.attachmentService.ConfirmUpload(c.UserContext(), attachmentID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAttachmentNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "attachment not found",
+			})
+		case errors.Is(err, services.ErrAttachmentAccessDenied):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access denied",
+			})
+		case errors.Is(err, services.ErrAttachmentNotPending):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrUploadIncomplete), errors.Is(err, services.ErrUploadExpired):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrFileTooLarge):
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"error": "file too large",
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to confirm upload",
+			})
+		}
+	}
+
+	return c.JSON(attachment)
+}
+
 // Get retrieves an attachment by ID
 // GET /attachments/:id
 func (h *AttachmentHandler) Get(c *fiber.Ctx) error {
@@ -96,7 +233,7 @@ func (h *AttachmentHandler) Get(c *fiber.Ctx) error {
 		})
 	}
 
-	attachment, err := h.attachmentService.Get(c.Context(), attachmentID)
+	attachment, err := h.attachmentService.Get(c.UserContext(), attachmentID)
 	if err != nil {
 		if err == services.ErrAttachmentNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -108,7 +245,13 @@ func (h *AttachmentHandler) Get(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(attachment)
+	visible, err := h.visibleAttachment(c, attachment)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to get attachment",
+		})
+	}
+	return c.JSON(visible)
 }
 
 // Download downloads an attachment file
@@ -121,7 +264,11 @@ func (h *AttachmentHandler) Download(c *fiber.Ctx) error {
 		})
 	}
 
-	reader, attachment, err := h.attachmentService.Download(c.Context(), attachmentID)
+	if ok, err := h.checkDownload(c, attachmentID); !ok {
+		return err
+	}
+
+	reader, attachment, err := h.attachmentService.Download(c.UserContext(), attachmentID)
 	if err != nil {
 		if err == services.ErrAttachmentNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -159,10 +306,14 @@ func (h *AttachmentHandler) GetSignedURL(c *fiber.Ctx) error {
 		})
 	}
 
+	if ok, err := h.checkDownload(c, attachmentID); !ok {
+		return err
+	}
+
 	// Default expiry of 1 hour
 	expiry := time.Hour
 
-	url, err := h.attachmentService.GetSignedURL(c.Context(), attachmentID, expiry)
+	url, err := h.attachmentService.GetSignedURL(c.UserContext(), attachmentID, expiry)
 	if err != nil {
 		if err == services.ErrAttachmentNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -197,7 +348,7 @@ func (h *AttachmentHandler) Delete(c *fiber.Ctx) error {
 		})
 	}
 
-	err = h.attachmentService.Delete(c.Context(), attachmentID, userID)
+	err = h.attachmentService.Delete(c.UserContext(), attachmentID, userID)
 	if err != nil {
 		switch err {
 		case services.ErrAttachmentNotFound:
@@ -228,16 +379,62 @@ func (h *AttachmentHandler) GetChannelAttachments(c *fiber.Ctx) error {
 		})
 	}
 
-	attachments, err := h.attachmentService.GetByChannel(c.Context(), channelID)
+	attachments, err := h.attachmentService.GetByChannel(c.UserContext(), channelID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get attachments",
 		})
 	}
 
-	if attachments == nil {
-		attachments = []*services.Attachment{}
+	visible := make([]*services.Attachment, 0, len(attachments))
+	for _, attachment := range attachments {
+		a, err := h.visibleAttachment(c, attachment)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to get attachments",
+			})
+		}
+		visible = append(visible, a)
 	}
 
-	return c.JSON(attachments)
+	return c.JSON(visible)
+}
+
+// checkDownload reports whether the requester may fetch the attachment's
+// file, writing the error response if not
+func (h *AttachmentHandler) checkDownload(c *fiber.Ctx, attachmentID uuid.UUID) (bool, error) {
+	userID, _ := c.Locals("userID").(uuid.UUID)
+	err := h.attachmentService.CheckDownload(c.UserContext(), attachmentID, userID)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, services.ErrAttachmentNotFound):
+		return false, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "attachment not found",
+		})
+	case errors.Is(err, services.ErrAttachmentFlagged):
+		return false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to check attachment",
+		})
+	}
+}
+
+// visibleAttachment hides where flagged files live from anyone who may not
+// download them
+func (h *AttachmentHandler) visibleAttachment(c *fiber.Ctx, attachment *services.Attachment) (*services.Attachment, error) {
+	userID, _ := c.Locals("userID").(uuid.UUID)
+	err := h.attachmentService.CheckDownload(c.UserContext(), attachment.ID, userID)
+	if errors.Is(err, services.ErrAttachmentFlagged) {
+		redacted := *attachment
+		redacted.URL, redacted.Path = "", ""
+		return &redacted, nil
+	}
+	if err != nil && !errors.Is(err, services.ErrAttachmentNotFound) {
+		return nil, err
+	}
+	return attachment, nil
 }
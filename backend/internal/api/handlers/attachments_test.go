@@ -2,12 +2,16 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -15,6 +19,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"hearth/internal/services"
+	"hearth/internal/storage"
 )
 
 // setupAttachmentTestApp creates a test app with attachment routes
@@ -38,6 +43,8 @@ func setupAttachmentTestApp(attachmentSvc *services.AttachmentService) (*fiber.A
 	app.Get("/attachments/:id", handler.Get)
 	app.Get("/attachments/:id/download", handler.Download)
 	app.Get("/attachments/:id/signed-url", handler.GetSignedURL)
+	app.Post("/channels/:id/attachments/presign", handler.PresignUpload)
+	app.Post("/attachments/:id/confirm", handler.ConfirmUpload)
 	app.Delete("/attachments/:id", handler.Delete)
 
 	return app, userID
@@ -374,6 +381,49 @@ func TestAttachmentHandler_Download(t *testing.T) {
 	})
 }
 
+func TestAttachmentHandler_FlaggedAttachment(t *testing.T) {
+	svc := services.NewAttachmentService(nil)
+	app, userID := setupAttachmentTestApp(svc)
+	channelID := uuid.New()
+	id := uuid.New()
+
+	svc.Upload_Test_Add(id, &services.Attachment{
+		ID:          id,
+		ChannelID:   channelID,
+		UploaderID:  userID,
+		Filename:    "invoice.pdf",
+		ContentType: "application/pdf",
+		URL:         "/attachments/" + id.String(),
+		Path:        "attachments/invoice.pdf",
+		ScanStatus:  services.AttachmentScanFlagged,
+	})
+
+	for _, path := range []string{"/download", "/signed-url"} {
+		req := httptest.NewRequest(http.MethodGet, "/attachments/"+id.String()+path, nil)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode, path)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/attachments/"+id.String(), nil)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var attachment services.Attachment
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&attachment))
+	assert.Equal(t, services.AttachmentScanFlagged, attachment.ScanStatus)
+	assert.Empty(t, attachment.URL, "flagged files aren't linked")
+
+	req = httptest.NewRequest(http.MethodGet, "/channels/"+channelID.String()+"/attachments", nil)
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	var attachments []services.Attachment
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&attachments))
+	require.Len(t, attachments, 1)
+	assert.Empty(t, attachments[0].URL)
+	assert.Empty(t, attachments[0].Path)
+}
+
 func TestAttachmentHandler_Unauthorized(t *testing.T) {
 	svc := services.NewAttachmentService(nil)
 	handler := NewAttachmentHandler(svc, nil)
@@ -405,6 +455,118 @@ func TestAttachmentHandler_Unauthorized(t *testing.T) {
 	})
 }
 
+func TestAttachmentHandler_PresignUpload(t *testing.T) {
+	svc := services.NewAttachmentService(nil)
+	app, _ := setupAttachmentTestApp(svc)
+	channelID := uuid.New()
+
+	post := func(body string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, "/channels/"+channelID.String()+"/attachments/presign", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("missing size", func(t *testing.T) {
+		resp := post(`{"filename":"video.mp4","content_type":"video/mp4"}`)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("blocked extension", func(t *testing.T) {
+		resp := post(`{"filename":"setup.exe","content_type":"application/octet-stream","size":10}`)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("storage without presign support", func(t *testing.T) {
+		resp := post(`{"filename":"video.mp4","content_type":"video/mp4","size":10}`)
+		assert.Equal(t, fiber.StatusNotImplemented, resp.StatusCode)
+	})
+}
+
+// failingPresignBackend supports direct uploads but can't reach its bucket
+type failingPresignBackend struct{}
+
+func (failingPresignBackend) Upload(ctx context.Context, path string, file io.Reader, contentType string, size int64) (string, error) {
+	return "", errors.New("unreachable")
+}
+
+func (failingPresignBackend) Download(ctx context.Context, path string) (io.ReadCloser, error) {
+	return nil, errors.New("unreachable")
+}
+
+func (failingPresignBackend) Delete(ctx context.Context, path string) error { return nil }
+
+func (failingPresignBackend) GetURL(path string) string { return "https://files.example.com/" + path }
+
+func (failingPresignBackend) GetSignedURL(ctx context.Context, path string, expiry time.Duration) (string, error) {
+	return "", errors.New("unreachable")
+}
+
+func (failingPresignBackend) GetPresignedUploadURL(ctx context.Context, path, contentType string, expiry time.Duration) (string, error) {
+	return "", errors.New("s3 credentials not configured for bucket hearth-internal")
+}
+
+func (failingPresignBackend) StatObject(ctx context.Context, path string) (int64, error) {
+	return 0, storage.ErrObjectNotFound
+}
+
+func TestAttachmentHandler_PresignUpload_Errors(t *testing.T) {
+	svc := services.NewAttachmentService(storage.NewService(failingPresignBackend{}, 1, []string{"bat"}))
+	app, _ := setupAttachmentTestApp(svc)
+	channelID := uuid.New()
+
+	post := func(body string) (*http.Response, string) {
+		req := httptest.NewRequest(http.MethodPost, "/channels/"+channelID.String()+"/attachments/presign", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp, readBody(resp.Body)
+	}
+
+	t.Run("over the size limit", func(t *testing.T) {
+		resp, _ := post(`{"filename":"video.mp4","content_type":"video/mp4","size":2097152}`)
+		assert.Equal(t, fiber.StatusRequestEntityTooLarge, resp.StatusCode)
+	})
+
+	t.Run("extension blocked by the instance", func(t *testing.T) {
+		resp, _ := post(`{"filename":"run.bat","content_type":"text/plain","size":10}`)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("storage failure is not leaked", func(t *testing.T) {
+		resp, body := post(`{"filename":"video.mp4","content_type":"video/mp4","size":10}`)
+		assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+		assert.False(t, strings.Contains(body, "hearth-internal"))
+	})
+}
+
+func TestAttachmentHandler_ConfirmUpload(t *testing.T) {
+	svc := services.NewAttachmentService(nil)
+	app, userID := setupAttachmentTestApp(svc)
+
+	t.Run("not found", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/attachments/"+uuid.New().String()+"/confirm", nil)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("already ready", func(t *testing.T) {
+		id := uuid.New()
+		svc.Upload_Test_Add(id, &services.Attachment{
+			ID:         id,
+			UploaderID: userID,
+			Status:     services.AttachmentStatusReady,
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/attachments/"+id.String()+"/confirm", nil)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+	})
+}
+
 // Test helper to read response body
 func readBody(r io.Reader) string {
 	body, _ := io.ReadAll(r)
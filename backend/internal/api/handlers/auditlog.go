@@ -152,7 +152,7 @@ func (h *AuditLogHandler) GetAuditLogs(c *fiber.Ctx) error {
 	}
 
 	// Get logs
-	logs, total, err := h.auditLogService.GetLogs(c.Context(), serverID, filter)
+	logs, total, err := h.auditLogService.GetLogs(c.UserContext(), serverID, filter)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get audit logs",
@@ -199,7 +199,7 @@ func (h *AuditLogHandler) GetAuditLogEntry(c *fiber.Ctx) error {
 		})
 	}
 
-	entry, err := h.auditLogService.GetLogByID(c.Context(), serverID, entryID)
+	entry, err := h.auditLogService.GetLogByID(c.UserContext(), serverID, entryID)
 	if err != nil {
 		if err == services.ErrAuditLogNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -247,7 +247,7 @@ func (h *AuditLogHandler) GetActionTypes(c *fiber.Ctx) error {
 // checkViewAuditLogPermission checks if a user has permission to view the audit log
 func (h *AuditLogHandler) checkViewAuditLogPermission(c *fiber.Ctx, serverID, userID uuid.UUID) (bool, error) {
 	// First check if user is a member of the server
-	_, err := h.serverService.GetMember(c.Context(), serverID, userID)
+	_, err := h.serverService.GetMember(c.UserContext(), serverID, userID)
 	if err != nil {
 		if err == services.ErrNotServerMember {
 			return false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
@@ -260,7 +260,7 @@ func (h *AuditLogHandler) checkViewAuditLogPermission(c *fiber.Ctx, serverID, us
 	}
 
 	// Check if user has VIEW_AUDIT_LOG permission
-	perms, err := h.serverService.GetMemberPermissions(c.Context(), serverID, userID)
+	perms, err := h.serverService.GetMemberPermissions(c.UserContext(), serverID, userID)
 	if err != nil {
 		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to check permissions",
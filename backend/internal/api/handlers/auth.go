@@ -1,18 +1,34 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
+	"hearth/internal/auth"
+	"hearth/internal/auth/oauth"
 	"hearth/internal/models"
 	"hearth/internal/services"
 )
 
 type AuthHandler struct {
 	authService services.AuthService
+
+	oauthLogins      OAuthLogin
+	oauthProviders   *oauth.Registry
+	oauthStates      *oauth.States
+	publicURL        string
+	oauthCompleteURL string
+
+	challenges     AuthChallenges
+	passwordResets PasswordResets
 }
 
 func NewAuthHandler(authService services.AuthService) *AuthHandler {
@@ -26,12 +42,30 @@ type RegisterRequest struct {
 	DisplayName string `json:"display_name"`
 	Password    string `json:"password" validate:"required,min=8"`
 	InviteCode  string `json:"invite_code"`
+	// CaptchaToken is sent on retry after a captcha_required error
+	CaptchaToken string `json:"captcha_token"`
 }
 
 // LoginRequest represents login payload
 type LoginRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
+	// CaptchaToken is sent on retry after a captcha_required error
+	CaptchaToken string `json:"captcha_token"`
+}
+
+// PasswordResetRequest asks for a password reset link
+type PasswordResetRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	// CaptchaToken is sent on retry after a captcha_required error
+	CaptchaToken string `json:"captcha_token"`
+}
+
+// ConfirmPasswordResetRequest sets a new password with a reset link's token
+type ConfirmPasswordResetRequest struct {
+	Token string `json:"token" validate:"required"`
+	// The password policy is checked when it's set, as for any password
+	Password string `json:"password" validate:"required"`
 }
 
 // RefreshRequest represents token refresh payload
@@ -50,47 +84,19 @@ type TokenResponse struct {
 // Register handles user registration
 func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	var req RegisterRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "invalid_request",
-			"message": "invalid request body",
-		})
-	}
-
-	// Validate required fields
-	if req.Email == "" || req.Username == "" || req.Password == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "validation_error",
-			"message": "email, username, and password are required",
-		})
-	}
-
-	// Validate email format (basic check)
-	if !strings.Contains(req.Email, "@") || !strings.Contains(req.Email, ".") {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "validation_error",
-			"message": "invalid email format",
-		})
-	}
-
-	// Validate username length
-	if len(req.Username) < 2 || len(req.Username) > 32 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "validation_error",
-			"message": "username must be between 2 and 32 characters",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return authBodyError(c, err)
 	}
 
-	// Validate password length
-	if len(req.Password) < 8 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "validation_error",
-			"message": "password must be at least 8 characters",
-		})
+	if challenged, err := h.challenge(c, services.AuthActionRegister, req.CaptchaToken); challenged {
+		return err
 	}
 
 	// Call auth service
-	_, tokens, err := h.authService.Register(c.Context(), req.Email, req.Username, req.Password)
+	_, tokens, err := h.authService.Register(clientContext(c), req.Email, req.Username, req.Password)
+	if h.challenges != nil {
+		h.challenges.Record(c.UserContext(), services.AuthActionRegister, c.IP())
+	}
 	if err != nil {
 		return handleAuthError(c, err)
 	}
@@ -107,22 +113,19 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 // Login handles user login
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	var req LoginRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "invalid_request",
-			"message": "invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return authBodyError(c, err)
 	}
 
-	if req.Email == "" || req.Password == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "validation_error",
-			"message": "email and password are required",
-		})
+	if challenged, err := h.challenge(c, services.AuthActionLogin, req.CaptchaToken); challenged {
+		return err
 	}
 
-	_, tokens, err := h.authService.Login(c.Context(), req.Email, req.Password)
+	_, tokens, err := h.authService.Login(clientContext(c), req.Email, req.Password)
 	if err != nil {
+		if h.challenges != nil && (errors.Is(err, services.ErrInvalidCredentials) || errors.Is(err, services.ErrAccountLocked)) {
+			h.challenges.Record(c.UserContext(), services.AuthActionLogin, c.IP())
+		}
 		return handleAuthError(c, err)
 	}
 
@@ -138,34 +141,64 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 // Refresh handles token refresh
 func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
 	var req RefreshRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "invalid_request",
-			"message": "invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return authBodyError(c, err)
 	}
 
-	if req.RefreshToken == "" {
+	tokens, err := h.authService.RefreshTokens(clientContext(c), req.RefreshToken)
+	if err != nil {
+		return refreshError(c, err)
+	}
+
+	return c.JSON(TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		TokenType:    "Bearer",
+	})
+}
+
+// authBodyError responds 400 to a parseBody error in the auth error format,
+// listing what's wrong with each invalid field
+func authBodyError(c *fiber.Ctx, err error) error {
+	if fields := validatedBodyFields(err); fields != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   "validation_error",
-			"message": "refresh_token is required",
+			"message": "invalid request body",
+			"fields":  fields,
 		})
 	}
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":   "invalid_request",
+		"message": "invalid request body",
+	})
+}
+
+// refreshError tells clients why a refresh failed, so they can tell a
+// leaked token apart from a session that was simply logged out
+func refreshError(c *fiber.Ctx, err error) error {
+	var suspended *services.AccountSuspendedError
+	if errors.As(err, &suspended) {
+		return accountSuspendedError(c, suspended)
+	}
 
-	tokens, err := h.authService.RefreshTokens(c.Context(), req.RefreshToken)
-	if err != nil {
+	switch {
+	case errors.Is(err, services.ErrRefreshTokenReused):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   "refresh_token_reused",
+			"message": "Refresh token was already used, so its session has been logged out",
+		})
+	case errors.Is(err, services.ErrSessionRevoked), errors.Is(err, auth.ErrRevokedToken):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   "session_revoked",
+			"message": "Session has been logged out",
+		})
+	default:
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error":   "invalid_refresh_token",
 			"message": "Invalid or expired refresh token",
 		})
 	}
-
-	return c.JSON(TokenResponse{
-		AccessToken:  tokens.AccessToken,
-		RefreshToken: tokens.RefreshToken,
-		ExpiresIn:    tokens.ExpiresIn,
-		TokenType:    "Bearer",
-	})
 }
 
 // Logout handles logout
@@ -175,32 +208,198 @@ func (h *AuthHandler) Logout(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// PasswordResets emails password reset links and redeems them. The
+// PasswordResetService implements it.
+type PasswordResets interface {
+	Request(ctx context.Context, email string) error
+	Confirm(ctx context.Context, token, password string) error
+}
+
+// SetPasswordResets enables resetting forgotten passwords by email
+func (h *AuthHandler) SetPasswordResets(resets PasswordResets) {
+	h.passwordResets = resets
+}
+
+// RequestPasswordReset emails a reset link if the email is registered. It
+// answers 202 either way, so it can't be used to find accounts. Every
+// request counts against the address, so ones that keep asking are
+// challenged with a captcha.
+func (h *AuthHandler) RequestPasswordReset(c *fiber.Ctx) error {
+	if h.passwordResets == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   "password_reset_unavailable",
+			"message": "password reset is not available on this instance",
+		})
+	}
+	var req PasswordResetRequest
+	if err := parseBody(c, &req); err != nil {
+		return authBodyError(c, err)
+	}
+
+	if challenged, err := h.challenge(c, services.AuthActionPasswordReset, req.CaptchaToken); challenged {
+		return err
+	}
+	if h.challenges != nil {
+		h.challenges.Record(c.UserContext(), services.AuthActionPasswordReset, c.IP())
+	}
+
+	if err := h.passwordResets.Request(c.UserContext(), req.Email); err != nil {
+		return handleAuthError(c, err)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "if that email is registered, a reset link is on its way",
+	})
+}
+
+// ConfirmPasswordReset sets a new password with the token from a reset
+// link. It unlocks the account and logs it out everywhere.
+func (h *AuthHandler) ConfirmPasswordReset(c *fiber.Ctx) error {
+	if h.passwordResets == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   "password_reset_unavailable",
+			"message": "password reset is not available on this instance",
+		})
+	}
+	var req ConfirmPasswordResetRequest
+	if err := parseBody(c, &req); err != nil {
+		return authBodyError(c, err)
+	}
+
+	if err := h.passwordResets.Confirm(c.UserContext(), req.Token, req.Password); err != nil {
+		return handleAuthError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// AuthChallenges decides when logins and registrations need a captcha.
+// The AuthChallengeService implements it.
+type AuthChallenges interface {
+	Check(ctx context.Context, action services.AuthAction, ip, token string) error
+	Record(ctx context.Context, action services.AuthAction, ip string)
+	Provider() string
+	SiteKey() string
+}
+
+// SetChallenges makes addresses that keep failing to log in, or keep
+// registering, solve a captcha first
+func (h *AuthHandler) SetChallenges(challenges AuthChallenges) {
+	h.challenges = challenges
+}
+
+// challenge responds and returns true if the attempt needs a captcha it
+// doesn't have. The response says which widget to render, so the client
+// can show it and retry with the token.
+func (h *AuthHandler) challenge(c *fiber.Ctx, action services.AuthAction, token string) (bool, error) {
+	if h.challenges == nil {
+		return false, nil
+	}
+	err := h.challenges.Check(c.UserContext(), action, c.IP(), token)
+	if err == nil {
+		return false, nil
+	}
+
+	captcha := fiber.Map{
+		"provider": h.challenges.Provider(),
+		"site_key": h.challenges.SiteKey(),
+	}
+	switch {
+	case errors.Is(err, services.ErrCaptchaRequired):
+		return true, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "captcha_required",
+			"message": "complete the captcha and retry with captcha_token",
+			"captcha": captcha,
+		})
+	case errors.Is(err, services.ErrCaptchaInvalid):
+		return true, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "captcha_invalid",
+			"message": "the captcha was not solved, try again",
+			"captcha": captcha,
+		})
+	default:
+		return true, c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error":   "captcha_unavailable",
+			"message": "the captcha could not be checked, try again shortly",
+		})
+	}
+}
+
+// oauthStateCookie binds a login's state to the browser that started it,
+// so a callback can't be replayed in someone else's
+const oauthStateCookie = "hearth_oauth_state"
+
+// OAuthLogin logs in with an account at an external provider
+type OAuthLogin interface {
+	Login(ctx context.Context, identity *oauth.Identity) (*models.User, *services.AuthTokens, error)
+}
+
+// SetOAuth enables logging in through the providers. Providers send users
+// back to the callback route under publicURL. If completeURL is set, the
+// callback redirects there with the tokens in the URL fragment instead of
+// returning them as JSON.
+func (h *AuthHandler) SetOAuth(logins OAuthLogin, providers *oauth.Registry, states *oauth.States, publicURL, completeURL string) {
+	h.oauthLogins = logins
+	h.oauthProviders = providers
+	h.oauthStates = states
+	h.publicURL = strings.TrimRight(publicURL, "/")
+	h.oauthCompleteURL = completeURL
+}
+
 // OAuthRedirect redirects to OAuth provider
 func (h *AuthHandler) OAuthRedirect(c *fiber.Ctx) error {
-	provider := c.Params("provider")
-
-	// Validate provider
-	validProviders := map[string]bool{"google": true, "github": true, "discord": true}
-	if !validProviders[provider] {
+	provider := h.oauthProvider(c.Params("provider"))
+	if provider == nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   "invalid_provider",
 			"message": "unsupported OAuth provider",
 		})
 	}
 
-	// TODO: Generate state, build OAuth URL based on provider
-	return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{
-		"error":   "not_implemented",
-		"message": "OAuth login is not yet implemented",
+	state, err := h.oauthStates.New(provider.Name())
+	if err != nil {
+		return err
+	}
+	challenge := oauth.Challenge(h.oauthStates.Verifier(state))
+	authURL, err := provider.AuthCodeURL(c.UserContext(), state, challenge, h.oauthRedirectURI(provider))
+	if err != nil {
+		log.Printf("[OAuth] %v", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error":   "provider_unavailable",
+			"message": "the login provider could not be reached",
+		})
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/api/v1/auth/oauth",
+		MaxAge:   int(h.oauthStates.TTL().Seconds()),
+		Secure:   strings.HasPrefix(h.publicURL, "https://"),
+		HTTPOnly: true,
+		// Lax still sends the cookie on the provider's redirect back
+		SameSite: fiber.CookieSameSiteLaxMode,
 	})
+	return c.Redirect(authURL, fiber.StatusFound)
 }
 
 // OAuthCallback handles OAuth callback
 func (h *AuthHandler) OAuthCallback(c *fiber.Ctx) error {
-	provider := c.Params("provider")
+	provider := h.oauthProvider(c.Params("provider"))
+	if provider == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "invalid_provider",
+			"message": "unsupported OAuth provider",
+		})
+	}
+
+	if reason := c.Query("error"); reason != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "oauth_denied",
+			"message": "the login provider did not log you in: " + reason,
+		})
+	}
+
 	code := c.Query("code")
 	state := c.Query("state")
-
 	if code == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   "missing_code",
@@ -208,22 +407,89 @@ func (h *AuthHandler) OAuthCallback(c *fiber.Ctx) error {
 		})
 	}
 
-	// TODO: Validate state
-	// TODO: Exchange code for tokens
-	// TODO: Get user info from provider
-	// TODO: Create or link user
-	// TODO: Generate tokens
+	cookie := c.Cookies(oauthStateCookie)
+	c.ClearCookie(oauthStateCookie)
+	if state == "" || cookie != state || h.oauthStates.Verify(state, provider.Name()) != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "invalid_state",
+			"message": "login expired or was started in another browser; please try again",
+		})
+	}
+
+	ctx := c.UserContext()
+	accessToken, err := provider.Exchange(ctx, code, h.oauthStates.Verifier(state), h.oauthRedirectURI(provider))
+	if err != nil {
+		log.Printf("[OAuth] %v", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error":   "oauth_failed",
+			"message": "the login provider refused the login",
+		})
+	}
+	identity, err := provider.Identity(ctx, accessToken)
+	if err != nil {
+		log.Printf("[OAuth] %v", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error":   "oauth_failed",
+			"message": "the login provider did not say who logged in",
+		})
+	}
 
-	_, _, _ = provider, code, state
+	_, tokens, err := h.oauthLogins.Login(clientContext(c), identity)
+	if errors.Is(err, services.ErrOAuthEmailUnverified) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":   "email_unverified",
+			"message": "the login provider hasn't verified your email",
+		})
+	}
+	if err != nil {
+		return handleAuthError(c, err)
+	}
 
-	return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{
-		"error":   "not_implemented",
-		"message": "OAuth login is not yet implemented",
+	if h.oauthCompleteURL != "" {
+		fragment := url.Values{
+			"access_token":  {tokens.AccessToken},
+			"refresh_token": {tokens.RefreshToken},
+			"expires_in":    {strconv.Itoa(tokens.ExpiresIn)},
+			"token_type":    {"Bearer"},
+		}
+		return c.Redirect(h.oauthCompleteURL+"#"+fragment.Encode(), fiber.StatusFound)
+	}
+	return c.JSON(TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		TokenType:    "Bearer",
 	})
 }
 
+// oauthProvider returns the named provider, or nil if it isn't configured
+func (h *AuthHandler) oauthProvider(name string) *oauth.Provider {
+	if h.oauthProviders == nil {
+		return nil
+	}
+	provider, err := h.oauthProviders.Get(name)
+	if err != nil {
+		return nil
+	}
+	return provider
+}
+
+// oauthRedirectURI is where the provider sends users back to
+func (h *AuthHandler) oauthRedirectURI(provider *oauth.Provider) string {
+	return h.publicURL + "/api/v1/auth/oauth/" + provider.Name() + "/callback"
+}
+
 // Helper functions
 
+// clientContext attaches the client's address and user agent to the
+// request context so they're recorded with the login session
+func clientContext(c *fiber.Ctx) context.Context {
+	return services.WithClientInfo(c.UserContext(), services.ClientInfo{
+		IPAddress: c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	})
+}
+
 func extractBearerToken(c *fiber.Ctx) string {
 	auth := c.Get("Authorization")
 	if strings.HasPrefix(auth, "Bearer ") {
@@ -232,7 +498,45 @@ func extractBearerToken(c *fiber.Ctx) string {
 	return ""
 }
 
+// accountSuspendedError says why an account was suspended and until when,
+// if the suspension expires
+func accountSuspendedError(c *fiber.Ctx, suspended *services.AccountSuspendedError) error {
+	body := fiber.Map{
+		"error":   "account_suspended",
+		"message": "this account has been suspended",
+	}
+	if suspended.Reason != nil {
+		body["reason"] = *suspended.Reason
+	}
+	if suspended.Until != nil {
+		body["suspended_until"] = suspended.Until.UTC().Format(time.RFC3339)
+	}
+	return c.Status(fiber.StatusForbidden).JSON(body)
+}
+
 func handleAuthError(c *fiber.Ctx, err error) error {
+	// Locked accounts say until when, and that resetting the password
+	// unlocks them sooner
+	var locked *services.AccountLockedError
+	if errors.As(err, &locked) {
+		retryAfter := int(time.Until(locked.Until).Seconds()) + 1
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+		return c.Status(fiber.StatusLocked).JSON(fiber.Map{
+			"error":        "account_locked",
+			"message":      "too many failed logins; try again later or reset your password to unlock your account",
+			"locked_until": locked.Until.UTC().Format(time.RFC3339),
+			"unlock":       "password_reset",
+		})
+	}
+
+	var suspended *services.AccountSuspendedError
+	if errors.As(err, &suspended) {
+		return accountSuspendedError(c, suspended)
+	}
+
 	switch err {
 	case services.ErrRegistrationClosed:
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
@@ -254,11 +558,31 @@ func handleAuthError(c *fiber.Ctx, err error) error {
 			"error":   "username_taken",
 			"message": "username is already taken",
 		})
+	case services.ErrInvalidUsername:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "invalid_username",
+			"message": "username may only contain letters, numbers, underscores and periods",
+		})
+	case services.ErrUsernameReserved:
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   "username_reserved",
+			"message": "username is reserved",
+		})
+	case services.ErrUsernameBanned:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "username_not_allowed",
+			"message": "username is not allowed",
+		})
 	case services.ErrInvalidCredentials:
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error":   "invalid_credentials",
 			"message": "invalid email or password",
 		})
+	case services.ErrInvalidResetToken:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "invalid_reset_token",
+			"message": "the reset link is invalid or has expired; request a new one",
+		})
 	case services.ErrPasswordTooShort:
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   "password_too_short",
@@ -274,6 +598,12 @@ func handleAuthError(c *fiber.Ctx, err error) error {
 			"error":   "password_weak",
 			"message": "password must contain at least one uppercase, lowercase, and number",
 		})
+	case services.ErrAuthUnavailable:
+		c.Set(fiber.HeaderRetryAfter, "1")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error":   "auth_capacity_exhausted",
+			"message": "too many logins and registrations right now, try again shortly",
+		})
 	default:
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "internal_error",
@@ -5,13 +5,17 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
+	"hearth/internal/auth"
+	"hearth/internal/auth/oauth"
 	"hearth/internal/models"
 	"hearth/internal/services"
 )
@@ -320,6 +324,265 @@ func TestLogin_InvalidCredentials(t *testing.T) {
 	}
 }
 
+func TestLogin_AuthCapacityExhausted(t *testing.T) {
+	app, service := setupTestApp()
+
+	service.loginFunc = func(ctx context.Context, email, password string) (*models.User, *services.AuthTokens, error) {
+		return nil, nil, services.ErrAuthUnavailable
+	}
+
+	body := map[string]string{
+		"email":    "test@example.com",
+		"password": "password123",
+	}
+
+	resp, result := makeRequest(app, "POST", "/auth/login", body)
+
+	if resp.Code != 503 {
+		t.Errorf("Expected status 503, got %d", resp.Code)
+	}
+
+	if result["error"] != "auth_capacity_exhausted" {
+		t.Errorf("Expected error 'auth_capacity_exhausted', got '%s'", result["error"])
+	}
+}
+
+// testCaptcha accepts the token "solved"
+type testCaptcha struct{}
+
+func (testCaptcha) Verify(ctx context.Context, token, remoteIP string) (bool, error) {
+	return token == "solved", nil
+}
+
+func (testCaptcha) Provider() string { return "hcaptcha" }
+func (testCaptcha) SiteKey() string  { return "site-key" }
+
+func TestLogin_CaptchaAfterFailures(t *testing.T) {
+	service := &mockAuthService{}
+	handler := NewAuthHandler(service)
+	handler.SetChallenges(services.NewAuthChallengeService(testCaptcha{}, services.AuthChallengeConfig{LoginFailures: 2, Registrations: 5}))
+	app := fiber.New()
+	app.Post("/auth/login", handler.Login)
+
+	logins := 0
+	service.loginFunc = func(ctx context.Context, email, password string) (*models.User, *services.AuthTokens, error) {
+		logins++
+		if password != "right-password" {
+			return nil, nil, services.ErrInvalidCredentials
+		}
+		return &models.User{ID: uuid.New()}, &services.AuthTokens{AccessToken: "access"}, nil
+	}
+
+	wrong := map[string]string{"email": "test@example.com", "password": "wrong-password"}
+	for i := 0; i < 2; i++ {
+		resp, _ := makeRequest(app, "POST", "/auth/login", wrong)
+		if resp.Code != 401 {
+			t.Fatalf("Expected status 401, got %d", resp.Code)
+		}
+	}
+
+	right := map[string]string{"email": "test@example.com", "password": "right-password"}
+	resp, result := makeRequest(app, "POST", "/auth/login", right)
+	if resp.Code != 400 || result["error"] != "captcha_required" {
+		t.Fatalf("Expected captcha_required, got %d %v", resp.Code, result["error"])
+	}
+	captcha, _ := result["captcha"].(map[string]interface{})
+	if captcha["provider"] != "hcaptcha" || captcha["site_key"] != "site-key" {
+		t.Errorf("Expected the widget to render, got %v", result["captcha"])
+	}
+	if logins != 2 {
+		t.Errorf("Expected challenged logins not to reach the service, got %d logins", logins)
+	}
+
+	right["captcha_token"] = "guess"
+	_, result = makeRequest(app, "POST", "/auth/login", right)
+	if result["error"] != "captcha_invalid" {
+		t.Errorf("Expected captcha_invalid, got %v", result["error"])
+	}
+
+	right["captcha_token"] = "solved"
+	resp, result = makeRequest(app, "POST", "/auth/login", right)
+	if resp.Code != 200 || result["access_token"] != "access" {
+		t.Errorf("Expected the login to succeed with a solved captcha, got %d %v", resp.Code, result)
+	}
+}
+
+func TestRegister_CaptchaAfterRegistrations(t *testing.T) {
+	service := &mockAuthService{}
+	handler := NewAuthHandler(service)
+	handler.SetChallenges(services.NewAuthChallengeService(testCaptcha{}, services.AuthChallengeConfig{LoginFailures: 5, Registrations: 1}))
+	app := fiber.New()
+	app.Post("/auth/register", handler.Register)
+
+	service.registerFunc = func(ctx context.Context, email, username, password string) (*models.User, *services.AuthTokens, error) {
+		return &models.User{ID: uuid.New()}, &services.AuthTokens{AccessToken: "access"}, nil
+	}
+
+	body := map[string]string{"email": "new@example.com", "username": "newuser", "password": "Password123"}
+	if resp, _ := makeRequest(app, "POST", "/auth/register", body); resp.Code != 201 {
+		t.Fatalf("Expected status 201, got %d", resp.Code)
+	}
+	if _, result := makeRequest(app, "POST", "/auth/register", body); result["error"] != "captcha_required" {
+		t.Errorf("Expected captcha_required, got %v", result["error"])
+	}
+	body["captcha_token"] = "solved"
+	if resp, _ := makeRequest(app, "POST", "/auth/register", body); resp.Code != 201 {
+		t.Errorf("Expected status 201 with a solved captcha, got %d", resp.Code)
+	}
+}
+
+func TestLogin_AccountLocked(t *testing.T) {
+	app, service := setupTestApp()
+
+	until := time.Now().Add(10 * time.Minute)
+	service.loginFunc = func(ctx context.Context, email, password string) (*models.User, *services.AuthTokens, error) {
+		return nil, nil, &services.AccountLockedError{Until: until}
+	}
+
+	body, _ := json.Marshal(map[string]string{"email": "test@example.com", "password": "Password123"})
+	req := httptest.NewRequest("POST", "/auth/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req, -1)
+
+	if resp.StatusCode != 423 {
+		t.Fatalf("Expected status 423, got %d", resp.StatusCode)
+	}
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "600" && retryAfter != "601" {
+		t.Errorf("Expected Retry-After of about 600, got %q", retryAfter)
+	}
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	if result["error"] != "account_locked" || result["unlock"] != "password_reset" {
+		t.Errorf("Expected account_locked with password_reset unlock, got %v", result)
+	}
+	if result["locked_until"] != until.UTC().Format(time.RFC3339) {
+		t.Errorf("Expected locked_until %s, got %v", until.UTC().Format(time.RFC3339), result["locked_until"])
+	}
+}
+
+func TestLogin_AccountSuspended(t *testing.T) {
+	app, service := setupTestApp()
+
+	reason, until := "spam", time.Now().Add(24*time.Hour)
+	service.loginFunc = func(ctx context.Context, email, password string) (*models.User, *services.AuthTokens, error) {
+		return nil, nil, &services.AccountSuspendedError{Reason: &reason, Until: &until}
+	}
+
+	body, _ := json.Marshal(map[string]string{"email": "test@example.com", "password": "Password123"})
+	req := httptest.NewRequest("POST", "/auth/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req, -1)
+
+	if resp.StatusCode != 403 {
+		t.Fatalf("Expected status 403, got %d", resp.StatusCode)
+	}
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	if result["error"] != "account_suspended" || result["reason"] != reason {
+		t.Errorf("Expected account_suspended with its reason, got %v", result)
+	}
+	if result["suspended_until"] != until.UTC().Format(time.RFC3339) {
+		t.Errorf("Expected suspended_until %s, got %v", until.UTC().Format(time.RFC3339), result["suspended_until"])
+	}
+}
+
+// stubPasswordResets records requests and accepts the token "valid" once
+type stubPasswordResets struct {
+	requested []string
+	confirmed []string
+}
+
+func (s *stubPasswordResets) Request(ctx context.Context, email string) error {
+	s.requested = append(s.requested, email)
+	return nil
+}
+
+func (s *stubPasswordResets) Confirm(ctx context.Context, token, password string) error {
+	if password == "weak" {
+		return services.ErrPasswordTooShort
+	}
+	if token != "valid" || len(s.confirmed) > 0 {
+		return services.ErrInvalidResetToken
+	}
+	s.confirmed = append(s.confirmed, password)
+	return nil
+}
+
+func setupPasswordResetTestApp(resets PasswordResets) (*fiber.App, *AuthHandler) {
+	handler := NewAuthHandler(&mockAuthService{})
+	if resets != nil {
+		handler.SetPasswordResets(resets)
+	}
+	app := fiber.New()
+	app.Post("/auth/password-reset", handler.RequestPasswordReset)
+	app.Post("/auth/password-reset/confirm", handler.ConfirmPasswordReset)
+	return app, handler
+}
+
+func TestPasswordReset_Unavailable(t *testing.T) {
+	app, _ := setupPasswordResetTestApp(nil)
+
+	resp, result := makeRequest(app, "POST", "/auth/password-reset", map[string]string{"email": "test@example.com"})
+	if resp.Code != 404 || result["error"] != "password_reset_unavailable" {
+		t.Errorf("Expected password_reset_unavailable, got %d %v", resp.Code, result["error"])
+	}
+	resp, _ = makeRequest(app, "POST", "/auth/password-reset/confirm", map[string]string{"token": "valid", "password": "NewPassword1"})
+	if resp.Code != 404 {
+		t.Errorf("Expected status 404, got %d", resp.Code)
+	}
+}
+
+func TestPasswordReset_Request(t *testing.T) {
+	resets := &stubPasswordResets{}
+	app, handler := setupPasswordResetTestApp(resets)
+	handler.SetChallenges(services.NewAuthChallengeService(testCaptcha{}, services.AuthChallengeConfig{LoginFailures: 5, Registrations: 1}))
+
+	if resp, _ := makeRequest(app, "POST", "/auth/password-reset", map[string]string{"email": "not-an-email"}); resp.Code != 400 {
+		t.Errorf("Expected status 400, got %d", resp.Code)
+	}
+
+	body := map[string]string{"email": "test@example.com"}
+	resp, result := makeRequest(app, "POST", "/auth/password-reset", body)
+	if resp.Code != 202 || result["message"] == nil {
+		t.Fatalf("Expected status 202, got %d %v", resp.Code, result)
+	}
+	if len(resets.requested) != 1 || resets.requested[0] != "test@example.com" {
+		t.Errorf("Expected a reset for test@example.com, got %v", resets.requested)
+	}
+
+	if _, result := makeRequest(app, "POST", "/auth/password-reset", body); result["error"] != "captcha_required" {
+		t.Errorf("Expected captcha_required, got %v", result["error"])
+	}
+	body["captcha_token"] = "solved"
+	if resp, _ := makeRequest(app, "POST", "/auth/password-reset", body); resp.Code != 202 {
+		t.Errorf("Expected status 202 with a solved captcha, got %d", resp.Code)
+	}
+	if len(resets.requested) != 2 {
+		t.Errorf("Expected challenged requests not to reach the service, got %d requests", len(resets.requested))
+	}
+}
+
+func TestPasswordReset_Confirm(t *testing.T) {
+	resets := &stubPasswordResets{}
+	app, _ := setupPasswordResetTestApp(resets)
+
+	if resp, result := makeRequest(app, "POST", "/auth/password-reset/confirm", map[string]string{"token": "valid"}); resp.Code != 400 || result["error"] != "validation_error" {
+		t.Errorf("Expected validation_error, got %d %v", resp.Code, result["error"])
+	}
+	if _, result := makeRequest(app, "POST", "/auth/password-reset/confirm", map[string]string{"token": "valid", "password": "weak"}); result["error"] != "password_too_short" {
+		t.Errorf("Expected password_too_short, got %v", result["error"])
+	}
+
+	body := map[string]string{"token": "valid", "password": "NewPassword1"}
+	if resp, _ := makeRequest(app, "POST", "/auth/password-reset/confirm", body); resp.Code != 204 {
+		t.Errorf("Expected status 204, got %d", resp.Code)
+	}
+	resp, result := makeRequest(app, "POST", "/auth/password-reset/confirm", body)
+	if resp.Code != 400 || result["error"] != "invalid_reset_token" {
+		t.Errorf("Expected invalid_reset_token, got %d %v", resp.Code, result["error"])
+	}
+}
+
 func TestRefresh_Success(t *testing.T) {
 	app, service := setupTestApp()
 
@@ -387,6 +650,36 @@ func TestRefresh_InvalidToken(t *testing.T) {
 	}
 }
 
+func TestRefresh_RevokedToken(t *testing.T) {
+	tests := []struct {
+		err  error
+		code string
+	}{
+		{services.ErrRefreshTokenReused, "refresh_token_reused"},
+		{services.ErrSessionRevoked, "session_revoked"},
+		{auth.ErrRevokedToken, "session_revoked"},
+		{auth.ErrExpiredToken, "invalid_refresh_token"},
+	}
+
+	for _, tt := range tests {
+		app, service := setupTestApp()
+		service.refreshTokensFunc = func(ctx context.Context, refreshToken string) (*services.AuthTokens, error) {
+			return nil, tt.err
+		}
+
+		resp, result := makeRequest(app, "POST", "/auth/refresh", map[string]string{
+			"refresh_token": "stolen-refresh-token",
+		})
+
+		if resp.Code != 401 {
+			t.Errorf("%v: expected status 401, got %d", tt.err, resp.Code)
+		}
+		if result["error"] != tt.code {
+			t.Errorf("%v: expected error '%s', got '%s'", tt.err, tt.code, result["error"])
+		}
+	}
+}
+
 func TestLogout_Success(t *testing.T) {
 	app, _ := setupTestApp()
 
@@ -396,3 +689,168 @@ func TestLogout_Success(t *testing.T) {
 		t.Errorf("Expected status 204, got %d", resp.Code)
 	}
 }
+
+type stubOAuthLogin struct {
+	identity *oauth.Identity
+	err      error
+}
+
+func (s *stubOAuthLogin) Login(ctx context.Context, identity *oauth.Identity) (*models.User, *services.AuthTokens, error) {
+	s.identity = identity
+	if s.err != nil {
+		return nil, nil, s.err
+	}
+	return &models.User{ID: uuid.New()}, &services.AuthTokens{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900}, nil
+}
+
+// setupOAuthTestApp serves a provider that accepts the code "good" and says
+// it belongs to sub "abc123"
+func setupOAuthTestApp(t *testing.T, completeURL string) (*fiber.App, *stubOAuthLogin) {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("code") != "good" || r.PostForm.Get("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "provider-token"})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"sub": "abc123", "email": "alice@example.com", "email_verified": true})
+	})
+	provider := httptest.NewServer(mux)
+	t.Cleanup(provider.Close)
+
+	cfg := oauth.OIDC("corp", provider.URL, "client", "secret")
+	cfg.AuthURL = provider.URL + "/authorize"
+	cfg.TokenURL = provider.URL + "/token"
+	cfg.UserinfoURL = provider.URL + "/userinfo"
+
+	logins := &stubOAuthLogin{}
+	handler := NewAuthHandler(&mockAuthService{})
+	handler.SetOAuth(logins, oauth.NewRegistry(oauth.NewProvider(cfg, provider.Client())), oauth.NewStates("secret", time.Minute), "https://chat.example.com/", completeURL)
+
+	app := fiber.New()
+	app.Get("/api/v1/auth/oauth/:provider/start", handler.OAuthRedirect)
+	app.Get("/api/v1/auth/oauth/:provider/callback", handler.OAuthCallback)
+	return app, logins
+}
+
+// startOAuth starts a login and returns the state and its cookie
+func startOAuth(t *testing.T, app *fiber.App) (string, *http.Cookie) {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/auth/oauth/corp/start", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusFound {
+		t.Fatalf("expected 302, got %d", resp.StatusCode)
+	}
+	location, _ := url.Parse(resp.Header.Get("Location"))
+	if got := location.Query().Get("redirect_uri"); got != "https://chat.example.com/api/v1/auth/oauth/corp/callback" {
+		t.Errorf("unexpected redirect_uri %q", got)
+	}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == oauthStateCookie {
+			if !cookie.HttpOnly || !cookie.Secure {
+				t.Errorf("expected an HttpOnly, Secure state cookie, got %+v", cookie)
+			}
+			return location.Query().Get("state"), cookie
+		}
+	}
+	t.Fatal("no state cookie set")
+	return "", nil
+}
+
+func oauthCallback(app *fiber.App, code, state string, cookie *http.Cookie) (*http.Response, map[string]interface{}) {
+	req := httptest.NewRequest("GET", "/api/v1/auth/oauth/corp/callback?"+url.Values{"code": {code}, "state": {state}}.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	resp, _ := app.Test(req, -1)
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp, result
+}
+
+func TestOAuth_LoginFlow(t *testing.T) {
+	app, logins := setupOAuthTestApp(t, "")
+	state, cookie := startOAuth(t, app)
+
+	resp, result := oauthCallback(app, "good", state, cookie)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d: %v", resp.StatusCode, result)
+	}
+	if result["access_token"] != "access" || result["refresh_token"] != "refresh" {
+		t.Errorf("unexpected tokens %v", result)
+	}
+	if logins.identity == nil || logins.identity.Provider != "corp" || logins.identity.Subject != "abc123" || !logins.identity.EmailVerified {
+		t.Errorf("unexpected identity %+v", logins.identity)
+	}
+}
+
+func TestOAuth_RejectsBadState(t *testing.T) {
+	app, logins := setupOAuthTestApp(t, "")
+	state, cookie := startOAuth(t, app)
+	otherState, _ := startOAuth(t, app)
+
+	for name, tc := range map[string]struct {
+		state  string
+		cookie *http.Cookie
+	}{
+		"no cookie":       {state, nil},
+		"another browser": {otherState, cookie},
+		"forged":          {"forged", &http.Cookie{Name: oauthStateCookie, Value: "forged"}},
+	} {
+		resp, result := oauthCallback(app, "good", tc.state, tc.cookie)
+		if resp.StatusCode != fiber.StatusBadRequest || result["error"] != "invalid_state" {
+			t.Errorf("%s: expected 400 invalid_state, got %d %v", name, resp.StatusCode, result)
+		}
+	}
+	if logins.identity != nil {
+		t.Error("expected no login with a bad state")
+	}
+
+	resp, result := oauthCallback(app, "bad", state, cookie)
+	if resp.StatusCode != fiber.StatusBadGateway || result["error"] != "oauth_failed" {
+		t.Errorf("expected 502 oauth_failed for a refused code, got %d %v", resp.StatusCode, result)
+	}
+}
+
+func TestOAuth_Errors(t *testing.T) {
+	app, logins := setupOAuthTestApp(t, "")
+
+	resp, _ := makeRequest(app, "GET", "/api/v1/auth/oauth/discord/start", nil)
+	if resp.Code != fiber.StatusBadRequest {
+		t.Errorf("expected 400 for an unconfigured provider, got %d", resp.Code)
+	}
+
+	for err, code := range map[error]string{
+		services.ErrOAuthEmailUnverified: "email_unverified",
+		services.ErrRegistrationClosed:   "registration_closed",
+	} {
+		logins.err = err
+		state, cookie := startOAuth(t, app)
+		resp, result := oauthCallback(app, "good", state, cookie)
+		if resp.StatusCode != fiber.StatusForbidden || result["error"] != code {
+			t.Errorf("%v: expected 403 %s, got %d %v", err, code, resp.StatusCode, result)
+		}
+	}
+}
+
+func TestOAuth_CompleteURL(t *testing.T) {
+	app, _ := setupOAuthTestApp(t, "https://chat.example.com/login/complete")
+	state, cookie := startOAuth(t, app)
+
+	resp, _ := oauthCallback(app, "good", state, cookie)
+	if resp.StatusCode != fiber.StatusFound {
+		t.Fatalf("expected 302, got %d", resp.StatusCode)
+	}
+	location, _ := url.Parse(resp.Header.Get("Location"))
+	fragment, _ := url.ParseQuery(location.Fragment)
+	if location.Path != "/login/complete" || fragment.Get("access_token") != "access" || fragment.Get("refresh_token") != "refresh" {
+		t.Errorf("unexpected redirect %s", resp.Header.Get("Location"))
+	}
+}
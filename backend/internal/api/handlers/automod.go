@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// AutoModServiceInterface defines the methods needed from AutoModService
+type AutoModServiceInterface interface {
+	GetRules(ctx context.Context, serverID, requesterID uuid.UUID) ([]*models.AutoModRule, error)
+	GetRule(ctx context.Context, serverID, ruleID, requesterID uuid.UUID) (*models.AutoModRule, error)
+	CreateRule(ctx context.Context, serverID, requesterID uuid.UUID, req *models.CreateAutoModRuleRequest) (*models.AutoModRule, error)
+	UpdateRule(ctx context.Context, serverID, ruleID, requesterID uuid.UUID, req *models.UpdateAutoModRuleRequest) (*models.AutoModRule, error)
+	DeleteRule(ctx context.Context, serverID, ruleID, requesterID uuid.UUID) error
+}
+
+// AutoModHandler handles AutoMod rule requests
+type AutoModHandler struct {
+	autoModService AutoModServiceInterface
+}
+
+// NewAutoModHandler creates a new AutoMod handler
+func NewAutoModHandler(autoModService AutoModServiceInterface) *AutoModHandler {
+	return &AutoModHandler{autoModService: autoModService}
+}
+
+// ListRules returns a server's AutoMod rules
+// GET /servers/:id/automod/rules
+func (h *AutoModHandler) ListRules(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	rules, err := h.autoModService.GetRules(c.UserContext(), serverID, userID)
+	if err != nil {
+		return autoModError(c, err)
+	}
+	if rules == nil {
+		rules = []*models.AutoModRule{}
+	}
+	return c.JSON(rules)
+}
+
+// GetRule returns one AutoMod rule
+// GET /servers/:id/automod/rules/:ruleId
+func (h *AutoModHandler) GetRule(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, ruleID, invalid := autoModRuleParams(c)
+	if invalid != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": invalid,
+		})
+	}
+
+	rule, err := h.autoModService.GetRule(c.UserContext(), serverID, ruleID, userID)
+	if err != nil {
+		return autoModError(c, err)
+	}
+	return c.JSON(rule)
+}
+
+// CreateRule adds an AutoMod rule
+// POST /servers/:id/automod/rules
+func (h *AutoModHandler) CreateRule(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	var req models.CreateAutoModRuleRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	rule, err := h.autoModService.CreateRule(c.UserContext(), serverID, userID, &req)
+	if err != nil {
+		return autoModError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(rule)
+}
+
+// UpdateRule changes an AutoMod rule
+// PATCH /servers/:id/automod/rules/:ruleId
+func (h *AutoModHandler) UpdateRule(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, ruleID, invalid := autoModRuleParams(c)
+	if invalid != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": invalid,
+		})
+	}
+
+	var req models.UpdateAutoModRuleRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	rule, err := h.autoModService.UpdateRule(c.UserContext(), serverID, ruleID, userID, &req)
+	if err != nil {
+		return autoModError(c, err)
+	}
+	return c.JSON(rule)
+}
+
+// DeleteRule removes an AutoMod rule
+// DELETE /servers/:id/automod/rules/:ruleId
+func (h *AutoModHandler) DeleteRule(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, ruleID, invalid := autoModRuleParams(c)
+	if invalid != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": invalid,
+		})
+	}
+
+	if err := h.autoModService.DeleteRule(c.UserContext(), serverID, ruleID, userID); err != nil {
+		return autoModError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// autoModRuleParams parses the server and rule IDs. invalid says which
+// isn't a valid ID.
+func autoModRuleParams(c *fiber.Ctx) (serverID, ruleID uuid.UUID, invalid string) {
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return serverID, ruleID, "invalid server id"
+	}
+	ruleID, err = uuid.Parse(c.Params("ruleId"))
+	if err != nil {
+		return serverID, ruleID, "invalid rule id"
+	}
+	return serverID, ruleID, ""
+}
+
+func autoModError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidAutoModRule), errors.Is(err, services.ErrTooManyAutoModRules):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrCannotManageAutoMod):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrServerNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "server not found"})
+	case errors.Is(err, services.ErrAutoModRuleNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "failed to manage automod rules",
+	})
+}
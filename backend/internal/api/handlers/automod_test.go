@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// MockAutoModService mocks the AutoModService for testing
+type MockAutoModService struct {
+	mock.Mock
+}
+
+func (m *MockAutoModService) GetRules(ctx context.Context, serverID, requesterID uuid.UUID) ([]*models.AutoModRule, error) {
+	args := m.Called(ctx, serverID, requesterID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.AutoModRule), args.Error(1)
+}
+
+func (m *MockAutoModService) GetRule(ctx context.Context, serverID, ruleID, requesterID uuid.UUID) (*models.AutoModRule, error) {
+	args := m.Called(ctx, serverID, ruleID, requesterID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AutoModRule), args.Error(1)
+}
+
+func (m *MockAutoModService) CreateRule(ctx context.Context, serverID, requesterID uuid.UUID, req *models.CreateAutoModRuleRequest) (*models.AutoModRule, error) {
+	args := m.Called(ctx, serverID, requesterID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AutoModRule), args.Error(1)
+}
+
+func (m *MockAutoModService) UpdateRule(ctx context.Context, serverID, ruleID, requesterID uuid.UUID, req *models.UpdateAutoModRuleRequest) (*models.AutoModRule, error) {
+	args := m.Called(ctx, serverID, ruleID, requesterID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AutoModRule), args.Error(1)
+}
+
+func (m *MockAutoModService) DeleteRule(ctx context.Context, serverID, ruleID, requesterID uuid.UUID) error {
+	args := m.Called(ctx, serverID, ruleID, requesterID)
+	return args.Error(0)
+}
+
+func newTestAutoModHandler() (*fiber.App, *MockAutoModService, uuid.UUID) {
+	autoModService := new(MockAutoModService)
+	handler := NewAutoModHandler(autoModService)
+	userID := uuid.New()
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", userID)
+		return c.Next()
+	})
+	app.Get("/servers/:id/automod/rules", handler.ListRules)
+	app.Post("/servers/:id/automod/rules", handler.CreateRule)
+	app.Get("/servers/:id/automod/rules/:ruleId", handler.GetRule)
+	app.Patch("/servers/:id/automod/rules/:ruleId", handler.UpdateRule)
+	app.Delete("/servers/:id/automod/rules/:ruleId", handler.DeleteRule)
+
+	return app, autoModService, userID
+}
+
+func TestAutoModHandler_CreateRule(t *testing.T) {
+	app, autoModService, userID := newTestAutoModHandler()
+	serverID := uuid.New()
+
+	rule := &models.AutoModRule{ID: uuid.New(), ServerID: serverID, Name: "No invites", TriggerType: models.AutoModTriggerInviteLink}
+	autoModService.On("CreateRule", mock.Anything, serverID, userID, mock.MatchedBy(func(req *models.CreateAutoModRuleRequest) bool {
+		return req.TriggerType == models.AutoModTriggerInviteLink && len(req.Actions) == 1 &&
+			req.Actions[0].Type == models.AutoModActionBlock
+	})).Return(rule, nil)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"name":         "No invites",
+		"trigger_type": "invite_link",
+		"actions":      []map[string]string{{"type": "block_message"}},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/servers/"+serverID.String()+"/automod/rules", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	assert.Equal(t, rule.ID.String(), result["id"])
+}
+
+func TestAutoModHandler_CreateRule_Errors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("%w: rules need at least one action", services.ErrInvalidAutoModRule), http.StatusBadRequest},
+		{services.ErrTooManyAutoModRules, http.StatusBadRequest},
+		{services.ErrCannotManageAutoMod, http.StatusForbidden},
+		{services.ErrServerNotFound, http.StatusNotFound},
+		{errors.New("database down"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		app, autoModService, _ := newTestAutoModHandler()
+		autoModService.On("CreateRule", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, tt.err)
+
+		body := []byte(`{"name":"No invites","trigger_type":"invite_link","actions":[{"type":"block_message"}]}`)
+		req := httptest.NewRequest(http.MethodPost, "/servers/"+uuid.NewString()+"/automod/rules", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.status, resp.StatusCode, tt.err.Error())
+	}
+}
+
+func TestAutoModHandler_ListRules_Empty(t *testing.T) {
+	app, autoModService, userID := newTestAutoModHandler()
+	serverID := uuid.New()
+	autoModService.On("GetRules", mock.Anything, serverID, userID).Return(nil, nil)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/servers/"+serverID.String()+"/automod/rules", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var result []interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	assert.NotNil(t, result)
+	assert.Empty(t, result)
+}
+
+func TestAutoModHandler_InvalidRuleID(t *testing.T) {
+	app, autoModService, _ := newTestAutoModHandler()
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodDelete, "/servers/"+uuid.NewString()+"/automod/rules/nope", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	autoModService.AssertNotCalled(t, "DeleteRule", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAutoModHandler_DeleteRule_NotFound(t *testing.T) {
+	app, autoModService, userID := newTestAutoModHandler()
+	serverID := uuid.New()
+	ruleID := uuid.New()
+	autoModService.On("DeleteRule", mock.Anything, serverID, ruleID, userID).Return(services.ErrAutoModRuleNotFound)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodDelete, "/servers/"+serverID.String()+"/automod/rules/"+ruleID.String(), nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// BlocklistServiceInterface defines the methods needed from
+// BlocklistService
+type BlocklistServiceInterface interface {
+	ListBlocklists(ctx context.Context, requesterID uuid.UUID, serverID *uuid.UUID) ([]*models.Blocklist, error)
+	CreateBlocklist(ctx context.Context, requesterID uuid.UUID, req *models.CreateBlocklistRequest) (*models.Blocklist, error)
+	GetBlocklist(ctx context.Context, id, requesterID uuid.UUID) (*models.Blocklist, error)
+	UpdateBlocklist(ctx context.Context, id, requesterID uuid.UUID, req *models.UpdateBlocklistRequest) (*models.Blocklist, error)
+	DeleteBlocklist(ctx context.Context, id, requesterID uuid.UUID) error
+	GetEntries(ctx context.Context, id, requesterID uuid.UUID) ([]*models.BlocklistEntry, error)
+	AddEntries(ctx context.Context, id, requesterID uuid.UUID, req *models.AddBlocklistEntriesRequest) error
+	RemoveEntry(ctx context.Context, id, requesterID, userID uuid.UUID) error
+	GetSubscriptions(ctx context.Context, serverID, requesterID uuid.UUID) ([]*models.BlocklistSubscription, error)
+	Subscribe(ctx context.Context, serverID, blocklistID, requesterID uuid.UUID) (*models.BlocklistSubscription, error)
+	Unsubscribe(ctx context.Context, serverID, blocklistID, requesterID uuid.UUID) error
+	GetExemptions(ctx context.Context, serverID, blocklistID, requesterID uuid.UUID) ([]*models.BlocklistExemption, error)
+	Exempt(ctx context.Context, serverID, blocklistID, requesterID, userID uuid.UUID) (*models.BlocklistExemption, error)
+	Unexempt(ctx context.Context, serverID, blocklistID, requesterID, userID uuid.UUID) error
+}
+
+// BlocklistHandler handles shared blocklist requests
+type BlocklistHandler struct {
+	blocklists BlocklistServiceInterface
+}
+
+// NewBlocklistHandler creates a new blocklist handler
+func NewBlocklistHandler(blocklists BlocklistServiceInterface) *BlocklistHandler {
+	return &BlocklistHandler{blocklists: blocklists}
+}
+
+// ListBlocklists returns the public blocklists, or with ?server_id= the
+// lists that server maintains
+// GET /blocklists
+func (h *BlocklistHandler) ListBlocklists(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	var serverID *uuid.UUID
+	if raw := c.Query("server_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid server id",
+			})
+		}
+		serverID = &id
+	}
+
+	lists, err := h.blocklists.ListBlocklists(c.UserContext(), userID, serverID)
+	if err != nil {
+		return blocklistError(c, err)
+	}
+	return c.JSON(lists)
+}
+
+// CreateBlocklist creates a blocklist for a server, or for the instance
+// POST /blocklists
+func (h *BlocklistHandler) CreateBlocklist(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	var req models.CreateBlocklistRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	list, err := h.blocklists.CreateBlocklist(c.UserContext(), userID, &req)
+	if err != nil {
+		return blocklistError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(list)
+}
+
+// GetBlocklist returns a blocklist
+// GET /blocklists/:id
+func (h *BlocklistHandler) GetBlocklist(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid blocklist id",
+		})
+	}
+
+	list, err := h.blocklists.GetBlocklist(c.UserContext(), id, userID)
+	if err != nil {
+		return blocklistError(c, err)
+	}
+	return c.JSON(list)
+}
+
+// UpdateBlocklist changes a blocklist's details
+// PATCH /blocklists/:id
+func (h *BlocklistHandler) UpdateBlocklist(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid blocklist id",
+		})
+	}
+
+	var req models.UpdateBlocklistRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	list, err := h.blocklists.UpdateBlocklist(c.UserContext(), id, userID, &req)
+	if err != nil {
+		return blocklistError(c, err)
+	}
+	return c.JSON(list)
+}
+
+// DeleteBlocklist deletes a blocklist
+// DELETE /blocklists/:id
+func (h *BlocklistHandler) DeleteBlocklist(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid blocklist id",
+		})
+	}
+
+	if err := h.blocklists.DeleteBlocklist(c.UserContext(), id, userID); err != nil {
+		return blocklistError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetEntries returns the users on a blocklist
+// GET /blocklists/:id/entries
+func (h *BlocklistHandler) GetEntries(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid blocklist id",
+		})
+	}
+
+	entries, err := h.blocklists.GetEntries(c.UserContext(), id, userID)
+	if err != nil {
+		return blocklistError(c, err)
+	}
+	return c.JSON(entries)
+}
+
+// AddEntries adds users to a blocklist
+// POST /blocklists/:id/entries
+func (h *BlocklistHandler) AddEntries(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid blocklist id",
+		})
+	}
+
+	var req models.AddBlocklistEntriesRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	if err := h.blocklists.AddEntries(c.UserContext(), id, userID, &req); err != nil {
+		return blocklistError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RemoveEntry takes a user off a blocklist
+// DELETE /blocklists/:id/entries/:userId
+func (h *BlocklistHandler) RemoveEntry(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid blocklist id",
+		})
+	}
+	targetID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	if err := h.blocklists.RemoveEntry(c.UserContext(), id, userID, targetID); err != nil {
+		return blocklistError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetSubscriptions returns the blocklists a server follows
+// GET /servers/:id/blocklists
+func (h *BlocklistHandler) GetSubscriptions(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	subscriptions, err := h.blocklists.GetSubscriptions(c.UserContext(), serverID, userID)
+	if err != nil {
+		return blocklistError(c, err)
+	}
+	return c.JSON(subscriptions)
+}
+
+// Subscribe has a server follow a blocklist
+// PUT /servers/:id/blocklists/:blocklistId
+func (h *BlocklistHandler) Subscribe(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+	blocklistID, err := uuid.Parse(c.Params("blocklistId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid blocklist id",
+		})
+	}
+
+	subscription, err := h.blocklists.Subscribe(c.UserContext(), serverID, blocklistID, userID)
+	if err != nil {
+		return blocklistError(c, err)
+	}
+	return c.JSON(subscription)
+}
+
+// Unsubscribe stops a server following a blocklist
+// DELETE /servers/:id/blocklists/:blocklistId
+func (h *BlocklistHandler) Unsubscribe(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+	blocklistID, err := uuid.Parse(c.Params("blocklistId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid blocklist id",
+		})
+	}
+
+	if err := h.blocklists.Unsubscribe(c.UserContext(), serverID, blocklistID, userID); err != nil {
+		return blocklistError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetExemptions returns the users a server has exempted from a blocklist
+// GET /servers/:id/blocklists/:blocklistId/exemptions
+func (h *BlocklistHandler) GetExemptions(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+	blocklistID, err := uuid.Parse(c.Params("blocklistId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid blocklist id",
+		})
+	}
+
+	exemptions, err := h.blocklists.GetExemptions(c.UserContext(), serverID, blocklistID, userID)
+	if err != nil {
+		return blocklistError(c, err)
+	}
+	return c.JSON(exemptions)
+}
+
+// Exempt stops a blocklist banning a user from the server
+// PUT /servers/:id/blocklists/:blocklistId/exemptions/:userId
+func (h *BlocklistHandler) Exempt(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+	blocklistID, err := uuid.Parse(c.Params("blocklistId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid blocklist id",
+		})
+	}
+	targetID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	exemption, err := h.blocklists.Exempt(c.UserContext(), serverID, blocklistID, userID, targetID)
+	if err != nil {
+		return blocklistError(c, err)
+	}
+	return c.JSON(exemption)
+}
+
+// Unexempt lifts a user's exemption from a blocklist
+// DELETE /servers/:id/blocklists/:blocklistId/exemptions/:userId
+func (h *BlocklistHandler) Unexempt(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+	blocklistID, err := uuid.Parse(c.Params("blocklistId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid blocklist id",
+		})
+	}
+	targetID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	if err := h.blocklists.Unexempt(c.UserContext(), serverID, blocklistID, userID, targetID); err != nil {
+		return blocklistError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func blocklistError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidBlocklist), errors.Is(err, services.ErrTooManyBlocklistSubscriptions):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrCannotManageBlocklist), errors.Is(err, services.ErrCannotBan):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrBlocklistNotFound), errors.Is(err, services.ErrNotSubscribedToBlocklist),
+		errors.Is(err, services.ErrServerNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "failed to manage blocklists",
+	})
+}
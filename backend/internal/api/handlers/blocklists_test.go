@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// MockBlocklistService mocks the BlocklistService for testing
+type MockBlocklistService struct {
+	mock.Mock
+}
+
+func (m *MockBlocklistService) ListBlocklists(ctx context.Context, requesterID uuid.UUID, serverID *uuid.UUID) ([]*models.Blocklist, error) {
+	args := m.Called(ctx, requesterID, serverID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Blocklist), args.Error(1)
+}
+
+func (m *MockBlocklistService) CreateBlocklist(ctx context.Context, requesterID uuid.UUID, req *models.CreateBlocklistRequest) (*models.Blocklist, error) {
+	args := m.Called(ctx, requesterID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Blocklist), args.Error(1)
+}
+
+func (m *MockBlocklistService) GetBlocklist(ctx context.Context, id, requesterID uuid.UUID) (*models.Blocklist, error) {
+	args := m.Called(ctx, id, requesterID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Blocklist), args.Error(1)
+}
+
+func (m *MockBlocklistService) UpdateBlocklist(ctx context.Context, id, requesterID uuid.UUID, req *models.UpdateBlocklistRequest) (*models.Blocklist, error) {
+	args := m.Called(ctx, id, requesterID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Blocklist), args.Error(1)
+}
+
+func (m *MockBlocklistService) DeleteBlocklist(ctx context.Context, id, requesterID uuid.UUID) error {
+	args := m.Called(ctx, id, requesterID)
+	return args.Error(0)
+}
+
+func (m *MockBlocklistService) GetEntries(ctx context.Context, id, requesterID uuid.UUID) ([]*models.BlocklistEntry, error) {
+	args := m.Called(ctx, id, requesterID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.BlocklistEntry), args.Error(1)
+}
+
+func (m *MockBlocklistService) AddEntries(ctx context.Context, id, requesterID uuid.UUID, req *models.AddBlocklistEntriesRequest) error {
+	args := m.Called(ctx, id, requesterID, req)
+	return args.Error(0)
+}
+
+func (m *MockBlocklistService) RemoveEntry(ctx context.Context, id, requesterID, userID uuid.UUID) error {
+	args := m.Called(ctx, id, requesterID, userID)
+	return args.Error(0)
+}
+
+func (m *MockBlocklistService) GetSubscriptions(ctx context.Context, serverID, requesterID uuid.UUID) ([]*models.BlocklistSubscription, error) {
+	args := m.Called(ctx, serverID, requesterID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.BlocklistSubscription), args.Error(1)
+}
+
+func (m *MockBlocklistService) Subscribe(ctx context.Context, serverID, blocklistID, requesterID uuid.UUID) (*models.BlocklistSubscription, error) {
+	args := m.Called(ctx, serverID, blocklistID, requesterID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BlocklistSubscription), args.Error(1)
+}
+
+func (m *MockBlocklistService) Unsubscribe(ctx context.Context, serverID, blocklistID, requesterID uuid.UUID) error {
+	args := m.Called(ctx, serverID, blocklistID, requesterID)
+	return args.Error(0)
+}
+
+func (m *MockBlocklistService) GetExemptions(ctx context.Context, serverID, blocklistID, requesterID uuid.UUID) ([]*models.BlocklistExemption, error) {
+	args := m.Called(ctx, serverID, blocklistID, requesterID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.BlocklistExemption), args.Error(1)
+}
+
+func (m *MockBlocklistService) Exempt(ctx context.Context, serverID, blocklistID, requesterID, userID uuid.UUID) (*models.BlocklistExemption, error) {
+	args := m.Called(ctx, serverID, blocklistID, requesterID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BlocklistExemption), args.Error(1)
+}
+
+func (m *MockBlocklistService) Unexempt(ctx context.Context, serverID, blocklistID, requesterID, userID uuid.UUID) error {
+	args := m.Called(ctx, serverID, blocklistID, requesterID, userID)
+	return args.Error(0)
+}
+
+func newTestBlocklistHandler() (*fiber.App, *MockBlocklistService, uuid.UUID) {
+	blocklistService := new(MockBlocklistService)
+	handler := NewBlocklistHandler(blocklistService)
+	userID := uuid.New()
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", userID)
+		return c.Next()
+	})
+	app.Get("/blocklists", handler.ListBlocklists)
+	app.Post("/blocklists", handler.CreateBlocklist)
+	app.Post("/blocklists/:id/entries", handler.AddEntries)
+	app.Put("/servers/:id/blocklists/:blocklistId", handler.Subscribe)
+	app.Put("/servers/:id/blocklists/:blocklistId/exemptions/:userId", handler.Exempt)
+
+	return app, blocklistService, userID
+}
+
+func TestBlocklistHandler_ListBlocklists(t *testing.T) {
+	app, blocklistService, userID := newTestBlocklistHandler()
+	serverID := uuid.New()
+	blocklistService.On("ListBlocklists", mock.Anything, userID, (*uuid.UUID)(nil)).Return([]*models.Blocklist{}, nil)
+	blocklistService.On("ListBlocklists", mock.Anything, userID, &serverID).Return(nil, services.ErrCannotBan)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/blocklists", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/blocklists?server_id="+serverID.String(), nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/blocklists?server_id=nope", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestBlocklistHandler_CreateBlocklist(t *testing.T) {
+	app, blocklistService, userID := newTestBlocklistHandler()
+	blocklistService.On("CreateBlocklist", mock.Anything, userID, mock.MatchedBy(func(req *models.CreateBlocklistRequest) bool {
+		return req.Name == "Raiders" && req.Public
+	})).Return(&models.Blocklist{ID: uuid.New(), Name: "Raiders", Public: true}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/blocklists", bytes.NewReader([]byte(`{"name":"Raiders","public":true}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+}
+
+func TestBlocklistHandler_Errors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{services.ErrTooManyBlocklistSubscriptions, fiber.StatusBadRequest},
+		{services.ErrCannotBan, fiber.StatusForbidden},
+		{services.ErrBlocklistNotFound, fiber.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		app, blocklistService, userID := newTestBlocklistHandler()
+		serverID, blocklistID := uuid.New(), uuid.New()
+		blocklistService.On("Subscribe", mock.Anything, serverID, blocklistID, userID).Return(nil, tc.err)
+
+		req := httptest.NewRequest(http.MethodPut, "/servers/"+serverID.String()+"/blocklists/"+blocklistID.String(), nil)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, tc.status, resp.StatusCode, tc.err.Error())
+	}
+
+	app, blocklistService, userID := newTestBlocklistHandler()
+	serverID, blocklistID, targetID := uuid.New(), uuid.New(), uuid.New()
+	blocklistService.On("Exempt", mock.Anything, serverID, blocklistID, userID, targetID).Return(nil, services.ErrNotSubscribedToBlocklist)
+	resp, err := app.Test(httptest.NewRequest(http.MethodPut, "/servers/"+serverID.String()+"/blocklists/"+blocklistID.String()+"/exemptions/"+targetID.String(), nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	blocklistService.On("AddEntries", mock.Anything, blocklistID, userID, mock.Anything).Return(services.ErrInvalidBlocklist)
+	req := httptest.NewRequest(http.MethodPost, "/blocklists/"+blocklistID.String()+"/entries", bytes.NewReader([]byte(`{"entries":[]}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// atomEntryTitleLength is how much of a message's first line titles its
+// feed entry
+const atomEntryTitleLength = 80
+
+// ChannelFeedServiceInterface defines the methods needed from
+// ChannelFeedService
+type ChannelFeedServiceInterface interface {
+	GetSettings(ctx context.Context, channelID, requesterID uuid.UUID) (*models.ChannelFeedSettings, error)
+	UpdateSettings(ctx context.Context, channelID, requesterID uuid.UUID, req *models.UpdateChannelFeedRequest) (*models.ChannelFeedSettings, error)
+	Feed(ctx context.Context, channelID uuid.UUID, token string) (*models.ChannelFeedContent, error)
+}
+
+// ChannelFeedHandler handles channel Atom feeds
+type ChannelFeedHandler struct {
+	feeds     ChannelFeedServiceInterface
+	publicURL string
+}
+
+// NewChannelFeedHandler creates a new channel feed handler. Feed links
+// point at publicURL.
+func NewChannelFeedHandler(feeds ChannelFeedServiceInterface, publicURL string) *ChannelFeedHandler {
+	return &ChannelFeedHandler{feeds: feeds, publicURL: strings.TrimRight(publicURL, "/")}
+}
+
+// GetSettings returns whether a channel has a feed and its URL
+// GET /channels/:id/feed
+func (h *ChannelFeedHandler) GetSettings(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	settings, err := h.feeds.GetSettings(c.UserContext(), channelID, userID)
+	if err != nil {
+		return channelFeedError(c, err)
+	}
+	return c.JSON(h.withURL(settings))
+}
+
+// UpdateSettings turns a channel's feed on or off, or rotates its token
+// PUT /channels/:id/feed
+func (h *ChannelFeedHandler) UpdateSettings(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	var req models.UpdateChannelFeedRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	settings, err := h.feeds.UpdateSettings(c.UserContext(), channelID, userID, &req)
+	if err != nil {
+		return channelFeedError(c, err)
+	}
+	return c.JSON(h.withURL(settings))
+}
+
+// GetFeed serves a channel's recent messages as an Atom feed. Feed readers
+// authenticate with the token query parameter instead of a session.
+// GET /channels/:id/feed.atom?token=
+func (h *ChannelFeedHandler) GetFeed(c *fiber.Ctx) error {
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	content, err := h.feeds.Feed(c.UserContext(), channelID, c.Query("token"))
+	if err != nil {
+		return channelFeedError(c, err)
+	}
+
+	// Anyone with the URL can read the feed, but shared caches shouldn't
+	// keep a copy
+	updated := content.Updated.UTC().Truncate(time.Second)
+	c.Set(fiber.HeaderCacheControl, "private, max-age=60")
+	c.Set(fiber.HeaderLastModified, updated.Format(http.TimeFormat))
+	if since, err := http.ParseTime(c.Get(fiber.HeaderIfModifiedSince)); err == nil && !updated.After(since) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	body, err := xml.MarshalIndent(h.atomFeed(content), "", "  ")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to render feed",
+		})
+	}
+	c.Set(fiber.HeaderContentType, "application/atom+xml; charset=utf-8")
+	return c.Send(append([]byte(xml.Header), body...))
+}
+
+func (h *ChannelFeedHandler) feedPath(channelID uuid.UUID) string {
+	return h.publicURL + "/api/v1/channels/" + channelID.String() + "/feed.atom"
+}
+
+func (h *ChannelFeedHandler) withURL(settings *models.ChannelFeedSettings) *models.ChannelFeedSettings {
+	if settings.Enabled {
+		settings.URL = h.feedPath(settings.ChannelID) + "?token=" + settings.Token
+	}
+	return settings
+}
+
+func channelFeedError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrChannelFeedUnsupported):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrCannotManageChannelFeed):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrChannelFeedNotFound), errors.Is(err, services.ErrChannelNotFound),
+		errors.Is(err, services.ErrServerNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "failed to load channel feed",
+	})
+}
+
+// Atom (RFC 4287) documents
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel    string `xml:"rel,attr,omitempty"`
+	Href   string `xml:"href,attr"`
+	Type   string `xml:"type,attr,omitempty"`
+	Title  string `xml:"title,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Author    atomPerson `xml:"author"`
+	Links     []atomLink `xml:"link"`
+	Content   atomText   `xml:"content"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+func (h *ChannelFeedHandler) atomFeed(content *models.ChannelFeedContent) *atomFeed {
+	channel := content.Channel
+	channelURL := h.publicURL + "/channels/" + content.Server.ID.String() + "/" + channel.ID.String()
+	feed := &atomFeed{
+		ID:       "urn:uuid:" + channel.ID.String(),
+		Title:    "#" + channel.Name + " - " + content.Server.Name,
+		Subtitle: channel.Topic,
+		Updated:  content.Updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "alternate", Type: "text/html", Href: channelURL},
+			{Rel: "self", Type: "application/atom+xml", Href: h.feedPath(channel.ID)},
+		},
+		Entries: make([]atomEntry, 0, len(content.Messages)),
+	}
+
+	for _, message := range content.Messages {
+		updated := message.CreatedAt
+		if message.EditedAt != nil {
+			updated = *message.EditedAt
+		}
+		author := "Deleted User"
+		if message.Author != nil {
+			author = message.Author.Username
+		}
+		entry := atomEntry{
+			ID:        "urn:uuid:" + message.ID.String(),
+			Title:     atomEntryTitle(message),
+			Published: message.CreatedAt.UTC().Format(time.RFC3339),
+			Updated:   updated.UTC().Format(time.RFC3339),
+			Author:    atomPerson{Name: author},
+			Links:     []atomLink{{Rel: "alternate", Type: "text/html", Href: channelURL}},
+			Content:   atomText{Type: "text", Body: message.Content},
+		}
+		for _, attachment := range message.Attachments {
+			link := atomLink{Rel: "enclosure", Href: attachment.URL, Title: attachment.Filename, Length: attachment.Size}
+			if attachment.ContentType != nil {
+				link.Type = *attachment.ContentType
+			}
+			entry.Links = append(entry.Links, link)
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
+
+// atomEntryTitle titles a message's entry with its first line
+func atomEntryTitle(message *models.Message) string {
+	title := strings.TrimSpace(message.Content)
+	if i := strings.IndexByte(title, '\n'); i >= 0 {
+		title = strings.TrimSpace(title[:i])
+	}
+	if title == "" {
+		if len(message.Attachments) > 0 {
+			return message.Attachments[0].Filename
+		}
+		return "Message"
+	}
+	if utf8.RuneCountInString(title) > atomEntryTitleLength {
+		runes := []rune(title)
+		title = strings.TrimSpace(string(runes[:atomEntryTitleLength-1])) + "…"
+	}
+	return title
+}
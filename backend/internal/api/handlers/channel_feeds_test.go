@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// MockChannelFeedService mocks the ChannelFeedService for testing
+type MockChannelFeedService struct {
+	mock.Mock
+}
+
+func (m *MockChannelFeedService) GetSettings(ctx context.Context, channelID, requesterID uuid.UUID) (*models.ChannelFeedSettings, error) {
+	args := m.Called(ctx, channelID, requesterID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChannelFeedSettings), args.Error(1)
+}
+
+func (m *MockChannelFeedService) UpdateSettings(ctx context.Context, channelID, requesterID uuid.UUID, req *models.UpdateChannelFeedRequest) (*models.ChannelFeedSettings, error) {
+	args := m.Called(ctx, channelID, requesterID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChannelFeedSettings), args.Error(1)
+}
+
+func (m *MockChannelFeedService) Feed(ctx context.Context, channelID uuid.UUID, token string) (*models.ChannelFeedContent, error) {
+	args := m.Called(ctx, channelID, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChannelFeedContent), args.Error(1)
+}
+
+func newTestChannelFeedHandler() (*fiber.App, *MockChannelFeedService, uuid.UUID) {
+	feedService := new(MockChannelFeedService)
+	handler := NewChannelFeedHandler(feedService, "https://chat.example.com/")
+	userID := uuid.New()
+
+	app := fiber.New()
+	// The feed itself is public
+	app.Get("/channels/:id/feed.atom", handler.GetFeed)
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", userID)
+		return c.Next()
+	})
+	app.Get("/channels/:id/feed", handler.GetSettings)
+	app.Put("/channels/:id/feed", handler.UpdateSettings)
+
+	return app, feedService, userID
+}
+
+func TestChannelFeedHandler_UpdateSettings(t *testing.T) {
+	app, feedService, userID := newTestChannelFeedHandler()
+	channelID := uuid.New()
+
+	feedService.On("UpdateSettings", mock.Anything, channelID, userID, &models.UpdateChannelFeedRequest{Enabled: true, RotateToken: true}).
+		Return(&models.ChannelFeedSettings{ChannelID: channelID, Enabled: true, Token: "secret"}, nil)
+
+	req := httptest.NewRequest(http.MethodPut, "/channels/"+channelID.String()+"/feed", bytes.NewReader([]byte(`{"enabled":true,"rotate_token":true}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var settings models.ChannelFeedSettings
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&settings))
+	assert.Equal(t, "https://chat.example.com/api/v1/channels/"+channelID.String()+"/feed.atom?token=secret", settings.URL)
+}
+
+func TestChannelFeedHandler_GetSettings_Errors(t *testing.T) {
+	app, feedService, userID := newTestChannelFeedHandler()
+	forbidden, unsupported := uuid.New(), uuid.New()
+	feedService.On("GetSettings", mock.Anything, forbidden, userID).Return(nil, services.ErrCannotManageChannelFeed)
+	feedService.On("GetSettings", mock.Anything, unsupported, userID).Return(nil, services.ErrChannelFeedUnsupported)
+
+	for channelID, status := range map[string]int{
+		forbidden.String():   fiber.StatusForbidden,
+		unsupported.String(): fiber.StatusBadRequest,
+		"not-a-uuid":         fiber.StatusBadRequest,
+	} {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/channels/"+channelID+"/feed", nil))
+		require.NoError(t, err)
+		assert.Equal(t, status, resp.StatusCode, channelID)
+	}
+}
+
+func TestChannelFeedHandler_GetFeed(t *testing.T) {
+	app, feedService, _ := newTestChannelFeedHandler()
+	serverID, channelID := uuid.New(), uuid.New()
+	updated := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	contentType := "image/png"
+	content := &models.ChannelFeedContent{
+		Channel: &models.Channel{ID: channelID, ServerID: &serverID, Name: "changelog", Topic: "Release notes"},
+		Server:  &models.Server{ID: serverID, Name: "Hearth"},
+		Messages: []*models.Message{
+			{ID: uuid.New(), Content: "v1.2 is out\n\nLots of fixes", CreatedAt: updated, Author: &models.PublicUser{Username: "release_bot"}},
+			{ID: uuid.New(), CreatedAt: updated.Add(-time.Hour), Attachments: []models.Attachment{{Filename: "screenshot.png", URL: "https://cdn.example.com/s.png", Size: 1024, ContentType: &contentType}}},
+		},
+		Updated: updated,
+	}
+	feedService.On("Feed", mock.Anything, channelID, "secret").Return(content, nil)
+	feedService.On("Feed", mock.Anything, channelID, "wrong").Return(nil, services.ErrChannelFeedNotFound)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/channels/"+channelID.String()+"/feed.atom?token=secret", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/atom+xml; charset=utf-8", resp.Header.Get(fiber.HeaderContentType))
+	assert.Equal(t, updated.Format(http.TimeFormat), resp.Header.Get(fiber.HeaderLastModified))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "secret", "the self link leaves out the token")
+
+	var feed atomFeed
+	require.NoError(t, xml.Unmarshal(body, &feed))
+	assert.Equal(t, "#changelog - Hearth", feed.Title)
+	assert.Equal(t, "Release notes", feed.Subtitle)
+	assert.Equal(t, "2026-03-01T12:00:00Z", feed.Updated)
+	require.Len(t, feed.Entries, 2)
+	assert.Equal(t, "v1.2 is out", feed.Entries[0].Title)
+	assert.Equal(t, "release_bot", feed.Entries[0].Author.Name)
+	assert.Equal(t, "https://chat.example.com/channels/"+serverID.String()+"/"+channelID.String(), feed.Entries[0].Links[0].Href)
+	assert.Equal(t, "screenshot.png", feed.Entries[1].Title)
+	assert.Equal(t, "Deleted User", feed.Entries[1].Author.Name)
+	require.Len(t, feed.Entries[1].Links, 2)
+	assert.Equal(t, atomLink{Rel: "enclosure", Href: "https://cdn.example.com/s.png", Type: "image/png", Title: "screenshot.png", Length: 1024}, feed.Entries[1].Links[1])
+
+	req := httptest.NewRequest(http.MethodGet, "/channels/"+channelID.String()+"/feed.atom?token=secret", nil)
+	req.Header.Set(fiber.HeaderIfModifiedSince, updated.Format(http.TimeFormat))
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotModified, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/channels/"+channelID.String()+"/feed.atom?token=wrong", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+func TestAtomEntryTitle(t *testing.T) {
+	long := strings.Repeat("é", atomEntryTitleLength+5)
+	title := atomEntryTitle(&models.Message{Content: long})
+	assert.Equal(t, atomEntryTitleLength, len([]rune(title)))
+	assert.True(t, strings.HasSuffix(title, "…"))
+	assert.Equal(t, "Message", atomEntryTitle(&models.Message{Content: "   "}))
+}
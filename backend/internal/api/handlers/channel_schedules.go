@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// ChannelScheduleServiceInterface defines the methods needed from
+// ChannelScheduleService
+type ChannelScheduleServiceInterface interface {
+	Get(ctx context.Context, channelID, requesterID uuid.UUID) (*models.ChannelSchedule, error)
+	Update(ctx context.Context, channelID, requesterID uuid.UUID, req *models.UpdateChannelScheduleRequest) (*models.ChannelSchedule, error)
+	Delete(ctx context.Context, channelID, requesterID uuid.UUID) error
+}
+
+// ChannelScheduleHandler handles channel office hours
+type ChannelScheduleHandler struct {
+	schedules ChannelScheduleServiceInterface
+}
+
+// NewChannelScheduleHandler creates a new channel schedule handler
+func NewChannelScheduleHandler(schedules ChannelScheduleServiceInterface) *ChannelScheduleHandler {
+	return &ChannelScheduleHandler{schedules: schedules}
+}
+
+// GetSchedule returns a channel's schedule, whether it's open and when
+// that next changes
+// GET /channels/:id/schedule
+func (h *ChannelScheduleHandler) GetSchedule(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	schedule, err := h.schedules.Get(c.UserContext(), channelID, userID)
+	if err != nil {
+		return channelScheduleError(c, err)
+	}
+	return c.JSON(schedule)
+}
+
+// UpdateSchedule replaces a channel's schedule
+// PUT /channels/:id/schedule
+func (h *ChannelScheduleHandler) UpdateSchedule(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	var req models.UpdateChannelScheduleRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	schedule, err := h.schedules.Update(c.UserContext(), channelID, userID, &req)
+	if err != nil {
+		return channelScheduleError(c, err)
+	}
+	return c.JSON(schedule)
+}
+
+// DeleteSchedule removes a channel's schedule, leaving it open
+// DELETE /channels/:id/schedule
+func (h *ChannelScheduleHandler) DeleteSchedule(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	if err := h.schedules.Delete(c.UserContext(), channelID, userID); err != nil {
+		return channelScheduleError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func channelScheduleError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidChannelSchedule), errors.Is(err, services.ErrChannelScheduleUnsupported):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrCannotManageChannelSchedule), errors.Is(err, services.ErrNotServerMember):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrChannelScheduleNotFound), errors.Is(err, services.ErrChannelNotFound),
+		errors.Is(err, services.ErrServerNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "failed to manage channel schedule",
+	})
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// MockChannelScheduleService mocks the ChannelScheduleService for testing
+type MockChannelScheduleService struct {
+	mock.Mock
+}
+
+func (m *MockChannelScheduleService) Get(ctx context.Context, channelID, requesterID uuid.UUID) (*models.ChannelSchedule, error) {
+	args := m.Called(ctx, channelID, requesterID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChannelSchedule), args.Error(1)
+}
+
+func (m *MockChannelScheduleService) Update(ctx context.Context, channelID, requesterID uuid.UUID, req *models.UpdateChannelScheduleRequest) (*models.ChannelSchedule, error) {
+	args := m.Called(ctx, channelID, requesterID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChannelSchedule), args.Error(1)
+}
+
+func (m *MockChannelScheduleService) Delete(ctx context.Context, channelID, requesterID uuid.UUID) error {
+	args := m.Called(ctx, channelID, requesterID)
+	return args.Error(0)
+}
+
+func newTestChannelScheduleHandler() (*fiber.App, *MockChannelScheduleService, uuid.UUID) {
+	scheduleService := new(MockChannelScheduleService)
+	handler := NewChannelScheduleHandler(scheduleService)
+	userID := uuid.New()
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", userID)
+		return c.Next()
+	})
+	app.Get("/channels/:id/schedule", handler.GetSchedule)
+	app.Put("/channels/:id/schedule", handler.UpdateSchedule)
+	app.Delete("/channels/:id/schedule", handler.DeleteSchedule)
+
+	return app, scheduleService, userID
+}
+
+func TestChannelScheduleHandler_GetSchedule(t *testing.T) {
+	app, scheduleService, userID := newTestChannelScheduleHandler()
+	channelID, missingID := uuid.New(), uuid.New()
+	opensAt := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	scheduleService.On("Get", mock.Anything, channelID, userID).Return(&models.ChannelSchedule{ChannelID: channelID, Timezone: "UTC", NextChangeAt: &opensAt}, nil)
+	scheduleService.On("Get", mock.Anything, missingID, userID).Return(nil, services.ErrChannelScheduleNotFound)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/channels/"+channelID.String()+"/schedule", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, false, body["open"])
+	assert.Equal(t, "2026-03-02T09:00:00Z", body["next_change_at"])
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/channels/"+missingID.String()+"/schedule", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+func TestChannelScheduleHandler_UpdateSchedule(t *testing.T) {
+	app, scheduleService, userID := newTestChannelScheduleHandler()
+	channelID := uuid.New()
+	scheduleService.On("Update", mock.Anything, channelID, userID, mock.MatchedBy(func(req *models.UpdateChannelScheduleRequest) bool {
+		return req.Timezone == "Europe/Berlin" && len(req.Hours) == 1 && req.Hours[0].Day == time.Monday
+	})).Return(&models.ChannelSchedule{ChannelID: channelID, Open: true}, nil)
+	scheduleService.On("Update", mock.Anything, channelID, userID, mock.Anything).Return(nil, services.ErrInvalidChannelSchedule)
+	scheduleService.On("Delete", mock.Anything, channelID, userID).Return(services.ErrCannotManageChannelSchedule)
+
+	body := `{"timezone":"Europe/Berlin","hours":[{"day":1,"open":"09:00","close":"17:00"}]}`
+	req := httptest.NewRequest(http.MethodPut, "/channels/"+channelID.String()+"/schedule", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	req = httptest.NewRequest(http.MethodPut, "/channels/"+channelID.String()+"/schedule", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodDelete, "/channels/"+channelID.String()+"/schedule", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+}
@@ -1,6 +1,11 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
@@ -8,10 +13,17 @@ import (
 	"hearth/internal/services"
 )
 
+// ServerEventThrottle defines the methods needed from
+// services.ServerThrottleService
+type ServerEventThrottle interface {
+	CheckServerEvent(ctx context.Context, serverID uuid.UUID) error
+}
+
 type ChannelHandler struct {
 	channelService *services.ChannelService
 	messageService *services.MessageService
 	typingService  *services.TypingService
+	serverThrottle ServerEventThrottle
 }
 
 func NewChannelHandler(channelService *services.ChannelService, messageService *services.MessageService) *ChannelHandler {
@@ -30,6 +42,27 @@ func NewChannelHandlerWithTyping(channelService *services.ChannelService, messag
 	}
 }
 
+// SetServerThrottle refuses typing indicators in servers that are over
+// their event limit
+func (h *ChannelHandler) SetServerThrottle(throttle ServerEventThrottle) {
+	h.serverThrottle = throttle
+}
+
+// serverRateLimited responds 429 with how long until the server may send
+// again, if err says it is over its limits
+func serverRateLimited(c *fiber.Ctx, err error) (bool, error) {
+	var limited *services.ServerRateLimitedError
+	if !errors.As(err, &limited) {
+		return false, nil
+	}
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(limited.RetryAfter.Seconds()))))
+	return true, c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error":       "server_rate_limited",
+		"message":     limited.Error(),
+		"retry_after": limited.RetryAfter.Seconds(),
+	})
+}
+
 // Get returns a channel by ID
 func (h *ChannelHandler) Get(c *fiber.Ctx) error {
 	channelID, err := uuid.Parse(c.Params("id"))
@@ -39,7 +72,7 @@ func (h *ChannelHandler) Get(c *fiber.Ctx) error {
 		})
 	}
 
-	channel, err := h.channelService.GetChannel(c.Context(), channelID)
+	channel, err := h.channelService.GetChannel(c.UserContext(), channelID)
 	if err != nil {
 		if err == services.ErrChannelNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -51,6 +84,7 @@ func (h *ChannelHandler) Get(c *fiber.Ctx) error {
 		})
 	}
 
+	setVersionETag(c, channel.Version)
 	return c.JSON(channel)
 }
 
@@ -65,23 +99,33 @@ func (h *ChannelHandler) Update(c *fiber.Ctx) error {
 	}
 
 	var req models.UpdateChannelRequest
-	if err := c.BodyParser(&req); err != nil {
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	version, err := expectedVersion(c, req.Version)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
+			"error": err.Error(),
 		})
 	}
 
 	// Convert request to service update struct
 	update := &models.ChannelUpdate{
-		Name:     req.Name,
-		Topic:    req.Topic,
-		Position: req.Position,
-		NSFW:     req.NSFW,
-		Slowmode: req.SlowmodeSeconds,
+		Name:        req.Name,
+		Topic:       req.Topic,
+		Position:    req.Position,
+		NSFW:        req.NSFW,
+		Slowmode:    req.SlowmodeSeconds,
+		E2EEEnabled: req.E2EEEnabled,
+		Version:     version,
 	}
 
-	channel, err := h.channelService.UpdateChannel(c.Context(), channelID, userID, update)
+	channel, err := h.channelService.UpdateChannel(c.UserContext(), channelID, userID, update)
 	if err != nil {
+		if handled, respErr := versionConflict(c, err); handled {
+			return respErr
+		}
 		switch err {
 		case services.ErrChannelNotFound:
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -91,6 +135,10 @@ func (h *ChannelHandler) Update(c *fiber.Ctx) error {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"error": "not a server member",
 			})
+		case services.ErrCannotDisableE2EE, services.ErrE2EEUnsupported:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
 		default:
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "failed to update channel",
@@ -98,6 +146,7 @@ func (h *ChannelHandler) Update(c *fiber.Ctx) error {
 		}
 	}
 
+	setVersionETag(c, channel.Version)
 	return c.JSON(channel)
 }
 
@@ -111,7 +160,7 @@ func (h *ChannelHandler) Delete(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.channelService.DeleteChannel(c.Context(), channelID, userID); err != nil {
+	if err := h.channelService.DeleteChannel(c.UserContext(), channelID, userID); err != nil {
 		switch err {
 		case services.ErrChannelNotFound:
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -159,7 +208,12 @@ func (h *ChannelHandler) GetMessages(c *fiber.Ctx) error {
 
 	limit := c.QueryInt("limit", 50)
 
-	messages, err := h.messageService.GetMessages(c.Context(), channelID, userID, before, after, limit)
+	ctx := c.UserContext()
+	if !c.QueryBool("with_authors", true) {
+		ctx = services.WithoutAuthors(ctx)
+	}
+
+	messages, err := h.messageService.GetMessages(ctx, channelID, userID, before, after, limit)
 	if err != nil {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": err.Error(),
@@ -169,6 +223,97 @@ func (h *ChannelHandler) GetMessages(c *fiber.Ctx) error {
 	return c.JSON(messages)
 }
 
+// GetMessageReplies returns a page of the replies to a message and the
+// replies to those, oldest first, for rendering the conversation under it.
+// Page on with after set to the last reply returned.
+func (h *ChannelHandler) GetMessageReplies(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+	messageID, err := uuid.Parse(c.Params("messageId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid message id",
+		})
+	}
+
+	var after *uuid.UUID
+	if a := c.Query("after"); a != "" {
+		id, err := uuid.Parse(a)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid after",
+			})
+		}
+		after = &id
+	}
+
+	replies, err := h.messageService.GetReplies(c.UserContext(), channelID, messageID, userID, after, c.QueryInt("limit", 50))
+	if err != nil {
+		if err == services.ErrMessageNotFound || err == services.ErrChannelNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if replies == nil {
+		replies = []*models.Message{}
+	}
+
+	return c.JSON(replies)
+}
+
+// GetArchivedMessages returns a page of the channel's archived messages,
+// newest first. It's slower than GetMessages; clients call it once
+// GetMessages runs out, with before set to the oldest message they have.
+func (h *ChannelHandler) GetArchivedMessages(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	var before *uuid.UUID
+	if b := c.Query("before"); b != "" {
+		id, err := uuid.Parse(b)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid before",
+			})
+		}
+		before = &id
+	}
+
+	messages, err := h.messageService.GetArchivedMessages(c.UserContext(), channelID, userID, before, c.QueryInt("limit", 50))
+	if err != nil {
+		switch err {
+		case services.ErrChannelNotFound:
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "channel not found",
+			})
+		case services.ErrNotServerMember, services.ErrNoPermission:
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to get archived messages",
+			})
+		}
+	}
+
+	return c.JSON(messages)
+}
+
 // SendMessage sends a message
 func (h *ChannelHandler) SendMessage(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
@@ -185,14 +330,25 @@ func (h *ChannelHandler) SendMessage(c *fiber.Ctx) error {
 		// Attachments handled separately via multipart
 	}
 
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
-	message, err := h.messageService.SendMessage(c.Context(), userID, channelID, req.Content, nil, req.ReplyTo)
+	message, err := h.messageService.SendMessage(c.UserContext(), userID, channelID, req.Content, nil, req.ReplyTo)
 	if err != nil {
+		if limited, err := serverRateLimited(c, err); limited {
+			return err
+		}
+		if err == services.ErrKeyEpochStale {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if err == services.ErrUserBlocked || err == services.ErrDMNotAllowed || err == services.ErrMemberTimedOut || err == services.ErrMembershipPending || err == services.ErrChannelClosed || err == services.ErrAutoModBlocked {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -221,14 +377,22 @@ func (h *ChannelHandler) EditMessage(c *fiber.Ctx) error {
 		Content string `json:"content"`
 	}
 
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
-	message, err := h.messageService.EditMessage(c.Context(), messageID, userID, req.Content)
+	message, err := h.messageService.EditMessage(c.UserContext(), messageID, userID, req.Content)
 	if err != nil {
+		if err == services.ErrKeyEpochStale {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if err == services.ErrAutoModBlocked {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -247,7 +411,7 @@ func (h *ChannelHandler) DeleteMessage(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.messageService.DeleteMessage(c.Context(), messageID, userID); err != nil {
+	if err := h.messageService.DeleteMessage(c.UserContext(), messageID, userID); err != nil {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -256,6 +420,138 @@ func (h *ChannelHandler) DeleteMessage(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// GetDeletedMessages lists tombstones of recently deleted messages for
+// moderators
+func (h *ChannelHandler) GetDeletedMessages(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	tombstones, err := h.messageService.GetDeletedMessages(c.UserContext(), channelID, userID, c.QueryInt("limit", 50))
+	if err != nil {
+		switch err {
+		case services.ErrChannelNotFound:
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "channel not found",
+			})
+		case services.ErrCannotModerate:
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to get deleted messages",
+			})
+		}
+	}
+
+	return c.JSON(tombstones)
+}
+
+// BulkDeleteMessages deletes 2-100 recent messages at once
+// POST /channels/:id/messages/bulk-delete
+func (h *ChannelHandler) BulkDeleteMessages(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	var req struct {
+		Messages []uuid.UUID `json:"messages"`
+		Reason   string      `json:"reason"`
+	}
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	if _, err := h.messageService.BulkDeleteMessages(c.UserContext(), channelID, userID, req.Messages, req.Reason); err != nil {
+		switch err {
+		case services.ErrBulkDeleteCount, services.ErrMessagesTooOld:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case services.ErrChannelNotFound:
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "channel not found",
+			})
+		case services.ErrCannotModerate:
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to delete messages",
+			})
+		}
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ForwardMessage copies a message into another channel
+// POST /channels/:id/messages/:messageId/forward
+func (h *ChannelHandler) ForwardMessage(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+	messageID, err := uuid.Parse(c.Params("messageId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid message id",
+		})
+	}
+
+	var req struct {
+		ChannelID uuid.UUID `json:"channel_id" validate:"required"`
+	}
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	message, err := h.messageService.ForwardMessage(c.UserContext(), userID, channelID, messageID, req.ChannelID)
+	if err != nil {
+		if limited, err := serverRateLimited(c, err); limited {
+			return err
+		}
+		switch err {
+		case services.ErrMessageNotFound, services.ErrChannelNotFound:
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case services.ErrNoPermission, services.ErrNotServerMember, services.ErrNotChannelMember, services.ErrUserBlocked, services.ErrDMNotAllowed,
+			services.ErrMemberTimedOut, services.ErrMembershipPending, services.ErrChannelClosed, services.ErrAutoModBlocked:
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case services.ErrRateLimited:
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case services.ErrCannotForward, services.ErrMessageTooLong, services.ErrEmptyMessage, services.ErrForumChannel:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to forward message",
+			})
+		}
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(message)
+}
+
 // AddReaction adds a reaction
 func (h *ChannelHandler) AddReaction(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
@@ -267,8 +563,15 @@ func (h *ChannelHandler) AddReaction(c *fiber.Ctx) error {
 	}
 	emoji := c.Params("emoji")
 
-	if err := h.messageService.AddReaction(c.Context(), messageID, userID, emoji); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+	if err := h.messageService.AddReaction(c.UserContext(), messageID, userID, emoji); err != nil {
+		status := fiber.StatusBadRequest
+		switch err {
+		case services.ErrReactionRateLimited:
+			status = fiber.StatusTooManyRequests
+		case services.ErrMemberTimedOut, services.ErrMembershipPending, services.ErrChannelClosed:
+			status = fiber.StatusForbidden
+		}
+		return c.Status(status).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
@@ -287,7 +590,7 @@ func (h *ChannelHandler) RemoveReaction(c *fiber.Ctx) error {
 	}
 	emoji := c.Params("emoji")
 
-	if err := h.messageService.RemoveReaction(c.Context(), messageID, userID, emoji); err != nil {
+	if err := h.messageService.RemoveReaction(c.UserContext(), messageID, userID, emoji); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -306,7 +609,7 @@ func (h *ChannelHandler) GetReactions(c *fiber.Ctx) error {
 		})
 	}
 
-	reactions, err := h.messageService.GetReactions(c.Context(), messageID, userID)
+	reactions, err := h.messageService.GetReactions(c.UserContext(), messageID, userID)
 	if err != nil {
 		switch err {
 		case services.ErrMessageNotFound:
@@ -344,7 +647,7 @@ func (h *ChannelHandler) GetReactionUsers(c *fiber.Ctx) error {
 
 	limit := c.QueryInt("limit", 25)
 
-	reactionUsers, err := h.messageService.GetReactionUsers(c.Context(), messageID, emoji, userID, limit)
+	reactionUsers, err := h.messageService.GetReactionUsers(c.UserContext(), messageID, emoji, userID, limit)
 	if err != nil {
 		switch err {
 		case services.ErrMessageNotFound:
@@ -384,7 +687,7 @@ func (h *ChannelHandler) PinMessage(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.messageService.PinMessage(c.Context(), messageID, userID); err != nil {
+	if err := h.messageService.PinMessage(c.UserContext(), messageID, userID); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -410,7 +713,7 @@ func (h *ChannelHandler) TriggerTyping(c *fiber.Ctx) error {
 	}
 
 	// Verify user has access to the channel
-	_, err = h.channelService.GetChannel(c.Context(), channelID)
+	channel, err := h.channelService.GetChannel(c.UserContext(), channelID)
 	if err != nil {
 		if err == services.ErrChannelNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -422,9 +725,15 @@ func (h *ChannelHandler) TriggerTyping(c *fiber.Ctx) error {
 		})
 	}
 
+	if h.serverThrottle != nil && channel.ServerID != nil {
+		if limited, err := serverRateLimited(c, h.serverThrottle.CheckServerEvent(c.UserContext(), *channel.ServerID)); limited {
+			return err
+		}
+	}
+
 	// Start typing (will broadcast via event bus)
 	if h.typingService != nil {
-		if err := h.typingService.StartTyping(c.Context(), channelID, userID); err != nil {
+		if err := h.typingService.StartTyping(c.UserContext(), channelID, userID); err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "failed to trigger typing",
 			})
@@ -444,7 +753,7 @@ func (h *ChannelHandler) GetTypingUsers(c *fiber.Ctx) error {
 	}
 
 	// Verify user has access to the channel
-	_, err = h.channelService.GetChannel(c.Context(), channelID)
+	_, err = h.channelService.GetChannel(c.UserContext(), channelID)
 	if err != nil {
 		if err == services.ErrChannelNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -461,7 +770,7 @@ func (h *ChannelHandler) GetTypingUsers(c *fiber.Ctx) error {
 		return c.JSON([]interface{}{})
 	}
 
-	indicators, err := h.typingService.GetTypingUsers(c.Context(), channelID)
+	indicators, err := h.typingService.GetTypingUsers(c.UserContext(), channelID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get typing users",
@@ -2230,3 +2230,46 @@ func TestTypingHandler_GetTypingUsers_ServiceError(t *testing.T) {
 		t.Errorf("Expected 500, got %d", resp.StatusCode)
 	}
 }
+
+func TestServerRateLimited(t *testing.T) {
+	app := fiber.New()
+	app.Get("/:limited", func(c *fiber.Ctx) error {
+		err := services.ErrRateLimited
+		if c.Params("limited") == "yes" {
+			err = &services.ServerRateLimitedError{RetryAfter: 250 * time.Millisecond}
+		}
+		if limited, err := serverRateLimited(c, err); limited {
+			return err
+		}
+		return c.SendStatus(fiber.StatusBadRequest)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/yes", nil))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get(fiber.HeaderRetryAfter); got != "1" {
+		t.Errorf("Expected Retry-After rounded up to 1, got %q", got)
+	}
+	var body struct {
+		Error      string  `json:"error"`
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.Error != "server_rate_limited" || body.RetryAfter != 0.25 {
+		t.Errorf("Unexpected body %+v", body)
+	}
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/no", nil))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected other errors to be left to the handler, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/api/openapi"
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// contractMocks are the services behind the handlers the OpenAPI document
+// covers
+type contractMocks struct {
+	savedMessages *MockSavedMessagesService
+	autoMod       *MockAutoModService
+	emojis        *MockEmojiService
+	permissions   *MockPermissionService
+}
+
+// newContractApp mounts the documented handlers at their real paths
+func newContractApp() (*fiber.App, *contractMocks) {
+	mocks := &contractMocks{
+		savedMessages: new(MockSavedMessagesService),
+		autoMod:       new(MockAutoModService),
+		emojis:        new(MockEmojiService),
+		permissions:   new(MockPermissionService),
+	}
+	gateway := NewGatewayHandler(nil)
+	savedMessages := NewSavedMessagesHandler(mocks.savedMessages)
+	autoMod := NewAutoModHandler(mocks.autoMod)
+	emojis := NewEmojiHandler(mocks.emojis)
+	permissions := NewPermissionsHandler(mocks.permissions)
+
+	app := fiber.New()
+	app.Get("/health", gateway.Health)
+	app.Get("/healthz", gateway.LivenessCheck)
+	app.Get("/readyz", gateway.ReadinessCheck)
+
+	v1 := app.Group("/api/v1", func(c *fiber.Ctx) error {
+		c.Locals("userID", uuid.New())
+		return c.Next()
+	})
+	saved := v1.Group("/users/@me/saved-messages")
+	saved.Post("/", savedMessages.SaveMessage)
+	saved.Get("/", savedMessages.GetSavedMessages)
+	saved.Get("/count", savedMessages.GetSavedCount)
+	saved.Get("/check/:messageId", savedMessages.IsSaved)
+	saved.Get("/:id", savedMessages.GetSavedMessage)
+	saved.Patch("/:id", savedMessages.UpdateSavedMessage)
+	saved.Delete("/:id", savedMessages.RemoveSavedMessage)
+	saved.Delete("/message/:messageId", savedMessages.RemoveSavedMessageByMessage)
+
+	v1.Get("/servers/:id/automod/rules", autoMod.ListRules)
+	v1.Post("/servers/:id/automod/rules", autoMod.CreateRule)
+	v1.Get("/servers/:id/automod/rules/:ruleId", autoMod.GetRule)
+	v1.Patch("/servers/:id/automod/rules/:ruleId", autoMod.UpdateRule)
+	v1.Delete("/servers/:id/automod/rules/:ruleId", autoMod.DeleteRule)
+
+	v1.Get("/servers/:id/emojis", emojis.ListEmojis)
+	v1.Post("/servers/:id/emojis", emojis.CreateEmoji)
+	v1.Patch("/servers/:id/emojis/:emojiId", emojis.RenameEmoji)
+	v1.Delete("/servers/:id/emojis/:emojiId", emojis.DeleteEmoji)
+
+	v1.Get("/channels/:id/permissions/@me", permissions.GetMyChannelPermissions)
+
+	return app, mocks
+}
+
+// TestContract checks handler responses against the OpenAPI document:
+// every status must be documented and every body must match its schema.
+// Each documented operation must be exercised at least once.
+func TestContract(t *testing.T) {
+	doc, err := openapi.Load()
+	require.NoError(t, err)
+
+	note := "read later"
+	savedMessage := &models.SavedMessage{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		MessageID: uuid.New(),
+		Note:      &note,
+		CreatedAt: time.Now(),
+	}
+	alertChannel := uuid.New()
+	rule := &models.AutoModRule{
+		ID:          uuid.New(),
+		ServerID:    uuid.New(),
+		CreatorID:   uuid.New(),
+		Name:        "No invites",
+		TriggerType: models.AutoModTriggerInviteLink,
+		TriggerMetadata: models.AutoModTriggerMetadata{
+			AllowList: []string{"hearth"},
+		},
+		Actions: []models.AutoModAction{
+			{Type: models.AutoModActionBlock},
+			{Type: models.AutoModActionAlert, ChannelID: &alertChannel},
+			{Type: models.AutoModActionTimeout, DurationSeconds: 60},
+		},
+		Enabled:        true,
+		ExemptRoles:    []uuid.UUID{},
+		ExemptChannels: []uuid.UUID{uuid.New()},
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+	emoji := &models.Emoji{
+		ID:        uuid.New(),
+		ServerID:  uuid.New(),
+		CreatorID: uuid.New(),
+		Name:      "blob",
+		Animated:  true,
+		URL:       "https://cdn.example.com/emojis/blob.gif",
+		Size:      2048,
+		CreatedAt: time.Now(),
+	}
+	id := uuid.NewString()
+	failure := errors.New("database down")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		setup  func(m *contractMocks)
+	}{
+		{name: "health", method: "GET", path: "/health"},
+		{name: "liveness", method: "GET", path: "/healthz"},
+		{name: "readiness", method: "GET", path: "/readyz"},
+
+		{name: "list saved", method: "GET", path: "/api/v1/users/@me/saved-messages?limit=10", setup: func(m *contractMocks) {
+			m.savedMessages.On("GetSavedMessages", mock.Anything, mock.Anything, mock.Anything).Return([]*models.SavedMessage{savedMessage}, nil)
+		}},
+		{name: "list saved empty", method: "GET", path: "/api/v1/users/@me/saved-messages", setup: func(m *contractMocks) {
+			m.savedMessages.On("GetSavedMessages", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+		}},
+		{name: "list saved failure", method: "GET", path: "/api/v1/users/@me/saved-messages", setup: func(m *contractMocks) {
+			m.savedMessages.On("GetSavedMessages", mock.Anything, mock.Anything, mock.Anything).Return(nil, failure)
+		}},
+		{name: "save", method: "POST", path: "/api/v1/users/@me/saved-messages", body: `{"message_id":"` + id + `","note":"read later"}`, setup: func(m *contractMocks) {
+			m.savedMessages.On("SaveMessage", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(savedMessage, nil)
+		}},
+		{name: "save invalid", method: "POST", path: "/api/v1/users/@me/saved-messages", body: `{}`},
+		{name: "save missing message", method: "POST", path: "/api/v1/users/@me/saved-messages", body: `{"message_id":"` + id + `"}`, setup: func(m *contractMocks) {
+			m.savedMessages.On("SaveMessage", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, services.ErrMessageNotFound)
+		}},
+		{name: "count saved", method: "GET", path: "/api/v1/users/@me/saved-messages/count", setup: func(m *contractMocks) {
+			m.savedMessages.On("GetSavedCount", mock.Anything, mock.Anything).Return(3, nil)
+		}},
+		{name: "check saved", method: "GET", path: "/api/v1/users/@me/saved-messages/check/" + id, setup: func(m *contractMocks) {
+			m.savedMessages.On("IsSaved", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+		}},
+		{name: "check saved invalid", method: "GET", path: "/api/v1/users/@me/saved-messages/check/nope"},
+		{name: "get saved", method: "GET", path: "/api/v1/users/@me/saved-messages/" + id, setup: func(m *contractMocks) {
+			m.savedMessages.On("GetSavedMessage", mock.Anything, mock.Anything, mock.Anything).Return(savedMessage, nil)
+		}},
+		{name: "get saved forbidden", method: "GET", path: "/api/v1/users/@me/saved-messages/" + id, setup: func(m *contractMocks) {
+			m.savedMessages.On("GetSavedMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil, services.ErrUnauthorized)
+		}},
+		{name: "update saved", method: "PATCH", path: "/api/v1/users/@me/saved-messages/" + id, body: `{"note":"later"}`, setup: func(m *contractMocks) {
+			m.savedMessages.On("UpdateSavedMessageNote", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(savedMessage, nil)
+		}},
+		{name: "update saved not found", method: "PATCH", path: "/api/v1/users/@me/saved-messages/" + id, body: `{}`, setup: func(m *contractMocks) {
+			m.savedMessages.On("UpdateSavedMessageNote", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, services.ErrSavedMessageNotFound)
+		}},
+		{name: "remove saved", method: "DELETE", path: "/api/v1/users/@me/saved-messages/" + id, setup: func(m *contractMocks) {
+			m.savedMessages.On("RemoveSavedMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		}},
+		{name: "remove saved by message", method: "DELETE", path: "/api/v1/users/@me/saved-messages/message/" + id, setup: func(m *contractMocks) {
+			m.savedMessages.On("RemoveSavedMessageByMessageID", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		}},
+		{name: "remove saved by message not found", method: "DELETE", path: "/api/v1/users/@me/saved-messages/message/" + id, setup: func(m *contractMocks) {
+			m.savedMessages.On("RemoveSavedMessageByMessageID", mock.Anything, mock.Anything, mock.Anything).Return(services.ErrSavedMessageNotFound)
+		}},
+
+		{name: "list rules", method: "GET", path: "/api/v1/servers/" + id + "/automod/rules", setup: func(m *contractMocks) {
+			m.autoMod.On("GetRules", mock.Anything, mock.Anything, mock.Anything).Return([]*models.AutoModRule{rule}, nil)
+		}},
+		{name: "list rules empty", method: "GET", path: "/api/v1/servers/" + id + "/automod/rules", setup: func(m *contractMocks) {
+			m.autoMod.On("GetRules", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+		}},
+		{name: "list rules forbidden", method: "GET", path: "/api/v1/servers/" + id + "/automod/rules", setup: func(m *contractMocks) {
+			m.autoMod.On("GetRules", mock.Anything, mock.Anything, mock.Anything).Return(nil, services.ErrCannotManageAutoMod)
+		}},
+		{name: "create rule", method: "POST", path: "/api/v1/servers/" + id + "/automod/rules", body: `{"name":"No invites","trigger_type":"invite_link","actions":[{"type":"block_message"}]}`, setup: func(m *contractMocks) {
+			m.autoMod.On("CreateRule", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(rule, nil)
+		}},
+		{name: "create rule invalid", method: "POST", path: "/api/v1/servers/" + id + "/automod/rules", body: `{}`, setup: func(m *contractMocks) {
+			m.autoMod.On("CreateRule", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, services.ErrInvalidAutoModRule)
+		}},
+		{name: "create rule invalid server", method: "POST", path: "/api/v1/servers/nope/automod/rules", body: `{}`},
+		{name: "get rule", method: "GET", path: "/api/v1/servers/" + id + "/automod/rules/" + id, setup: func(m *contractMocks) {
+			m.autoMod.On("GetRule", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(rule, nil)
+		}},
+		{name: "get rule not found", method: "GET", path: "/api/v1/servers/" + id + "/automod/rules/" + id, setup: func(m *contractMocks) {
+			m.autoMod.On("GetRule", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, services.ErrAutoModRuleNotFound)
+		}},
+		{name: "update rule", method: "PATCH", path: "/api/v1/servers/" + id + "/automod/rules/" + id, body: `{"enabled":false}`, setup: func(m *contractMocks) {
+			m.autoMod.On("UpdateRule", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(rule, nil)
+		}},
+		{name: "update rule failure", method: "PATCH", path: "/api/v1/servers/" + id + "/automod/rules/" + id, body: `{}`, setup: func(m *contractMocks) {
+			m.autoMod.On("UpdateRule", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, failure)
+		}},
+		{name: "delete rule", method: "DELETE", path: "/api/v1/servers/" + id + "/automod/rules/" + id, setup: func(m *contractMocks) {
+			m.autoMod.On("DeleteRule", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		}},
+		{name: "delete rule invalid id", method: "DELETE", path: "/api/v1/servers/" + id + "/automod/rules/nope"},
+
+		{name: "list emojis", method: "GET", path: "/api/v1/servers/" + id + "/emojis", setup: func(m *contractMocks) {
+			m.emojis.On("GetServerEmojis", mock.Anything, mock.Anything, mock.Anything).Return([]*models.Emoji{emoji}, nil)
+		}},
+		{name: "list emojis not member", method: "GET", path: "/api/v1/servers/" + id + "/emojis", setup: func(m *contractMocks) {
+			m.emojis.On("GetServerEmojis", mock.Anything, mock.Anything, mock.Anything).Return(nil, services.ErrNotServerMember)
+		}},
+		{name: "create emoji without image", method: "POST", path: "/api/v1/servers/" + id + "/emojis", body: `{"name":"blob"}`},
+		{name: "rename emoji", method: "PATCH", path: "/api/v1/servers/" + id + "/emojis/" + id, body: `{"name":"blobby"}`, setup: func(m *contractMocks) {
+			m.emojis.On("RenameEmoji", mock.Anything, mock.Anything, mock.Anything, mock.Anything, "blobby").Return(emoji, nil)
+		}},
+		{name: "rename emoji taken", method: "PATCH", path: "/api/v1/servers/" + id + "/emojis/" + id, body: `{"name":"blob"}`, setup: func(m *contractMocks) {
+			m.emojis.On("RenameEmoji", mock.Anything, mock.Anything, mock.Anything, mock.Anything, "blob").Return(nil, services.ErrEmojiNameTaken)
+		}},
+		{name: "delete emoji", method: "DELETE", path: "/api/v1/servers/" + id + "/emojis/" + id, setup: func(m *contractMocks) {
+			m.emojis.On("DeleteEmoji", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		}},
+		{name: "delete emoji not found", method: "DELETE", path: "/api/v1/servers/" + id + "/emojis/" + id, setup: func(m *contractMocks) {
+			m.emojis.On("DeleteEmoji", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(services.ErrEmojiNotFound)
+		}},
+
+		{name: "channel permissions", method: "GET", path: "/api/v1/channels/" + id + "/permissions/@me", setup: func(m *contractMocks) {
+			m.permissions.On("ChannelPermissions", mock.Anything, mock.Anything, mock.Anything).Return(models.PermissionAll|models.PermAdministrator, nil)
+		}},
+		{name: "channel permissions forbidden", method: "GET", path: "/api/v1/channels/" + id + "/permissions/@me", setup: func(m *contractMocks) {
+			m.permissions.On("ChannelPermissions", mock.Anything, mock.Anything, mock.Anything).Return(int64(0), services.ErrNotServerMember)
+		}},
+	}
+
+	covered := map[string]bool{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mocks := newContractApp()
+			if tt.setup != nil {
+				tt.setup(mocks)
+			}
+
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req := httptest.NewRequest(tt.method, tt.path, body)
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			respBody, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			assert.NoError(t, doc.ValidateResponse(tt.method, tt.path, resp.StatusCode, respBody))
+			if resp.StatusCode == http.StatusNotFound && strings.Contains(string(respBody), "Cannot ") {
+				t.Errorf("%s %s isn't routed in the contract app", tt.method, tt.path)
+			}
+
+			template, _ := doc.Match(tt.method, tt.path)
+			covered[tt.method+" "+template] = true
+		})
+	}
+
+	for _, op := range doc.Operations() {
+		assert.True(t, covered[op], "%s is documented but not exercised by TestContract", op)
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// E2EEServiceInterface defines the methods needed from E2EEKeyService
+type E2EEServiceInterface interface {
+	ListDevices(ctx context.Context, userID uuid.UUID) ([]*models.E2EEDevice, error)
+	RegisterDevice(ctx context.Context, userID uuid.UUID, deviceID string, req *models.RegisterDeviceKeysRequest) (*models.E2EEDevice, error)
+	UploadPreKeys(ctx context.Context, userID uuid.UUID, deviceID string, req *models.UploadPreKeysRequest) (*models.E2EEDevice, error)
+	RemoveDevice(ctx context.Context, userID uuid.UUID, deviceID string) error
+	ClaimPreKeyBundles(ctx context.Context, userID uuid.UUID) ([]*models.PreKeyBundle, error)
+	GetChannelKeys(ctx context.Context, channelID, requesterID uuid.UUID, deviceID string) (*models.ChannelKeys, error)
+	DistributeSenderKeys(ctx context.Context, channelID, requesterID uuid.UUID, req *models.DistributeSenderKeysRequest) error
+}
+
+// E2EEHandler handles device keys and the sender keys of encrypted
+// channels
+type E2EEHandler struct {
+	keys E2EEServiceInterface
+}
+
+// NewE2EEHandler creates a new E2EE handler
+func NewE2EEHandler(keys E2EEServiceInterface) *E2EEHandler {
+	return &E2EEHandler{keys: keys}
+}
+
+// ListDevices returns the current user's devices
+// GET /users/@me/keys
+func (h *E2EEHandler) ListDevices(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	devices, err := h.keys.ListDevices(c.UserContext(), userID)
+	if err != nil {
+		return e2eeError(c, err)
+	}
+	return c.JSON(devices)
+}
+
+// RegisterDevice publishes or replaces a device's keys
+// PUT /users/@me/keys/:deviceId
+func (h *E2EEHandler) RegisterDevice(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	var req models.RegisterDeviceKeysRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	device, err := h.keys.RegisterDevice(c.UserContext(), userID, c.Params("deviceId"), &req)
+	if err != nil {
+		return e2eeError(c, err)
+	}
+	return c.JSON(device)
+}
+
+// UploadPreKeys adds one-time pre-keys to a device
+// POST /users/@me/keys/:deviceId/pre-keys
+func (h *E2EEHandler) UploadPreKeys(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	var req models.UploadPreKeysRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	device, err := h.keys.UploadPreKeys(c.UserContext(), userID, c.Params("deviceId"), &req)
+	if err != nil {
+		return e2eeError(c, err)
+	}
+	return c.JSON(device)
+}
+
+// RemoveDevice deletes a device's keys
+// DELETE /users/@me/keys/:deviceId
+func (h *E2EEHandler) RemoveDevice(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	if err := h.keys.RemoveDevice(c.UserContext(), userID, c.Params("deviceId")); err != nil {
+		return e2eeError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ClaimPreKeyBundles returns a pre-key bundle for each of a user's devices
+// GET /users/:id/keys
+func (h *E2EEHandler) ClaimPreKeyBundles(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	bundles, err := h.keys.ClaimPreKeyBundles(c.UserContext(), userID)
+	if err != nil {
+		return e2eeError(c, err)
+	}
+	return c.JSON(bundles)
+}
+
+// GetChannelKeys returns an encrypted channel's epoch and the sender keys
+// sent to one of the current user's devices
+// GET /channels/:id/keys?device_id=
+func (h *E2EEHandler) GetChannelKeys(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	keys, err := h.keys.GetChannelKeys(c.UserContext(), channelID, userID, c.Query("device_id"))
+	if err != nil {
+		return e2eeError(c, err)
+	}
+	return c.JSON(keys)
+}
+
+// DistributeSenderKeys hands a device's sender key to other devices
+// POST /channels/:id/keys
+func (h *E2EEHandler) DistributeSenderKeys(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	var req models.DistributeSenderKeysRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	if err := h.keys.DistributeSenderKeys(c.UserContext(), channelID, userID, &req); err != nil {
+		return e2eeError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func e2eeError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidDeviceKeys), errors.Is(err, services.ErrInvalidSenderKeys),
+		errors.Is(err, services.ErrTooManyE2EEDevices), errors.Is(err, services.ErrChannelNotEncrypted):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrNotChannelMember):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrE2EEDeviceNotFound), errors.Is(err, services.ErrChannelNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrKeyEpochStale):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "failed to manage keys",
+	})
+}
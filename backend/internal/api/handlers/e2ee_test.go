@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// MockE2EEService mocks the E2EEKeyService for testing
+type MockE2EEService struct {
+	mock.Mock
+}
+
+func (m *MockE2EEService) ListDevices(ctx context.Context, userID uuid.UUID) ([]*models.E2EEDevice, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.E2EEDevice), args.Error(1)
+}
+
+func (m *MockE2EEService) RegisterDevice(ctx context.Context, userID uuid.UUID, deviceID string, req *models.RegisterDeviceKeysRequest) (*models.E2EEDevice, error) {
+	args := m.Called(ctx, userID, deviceID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.E2EEDevice), args.Error(1)
+}
+
+func (m *MockE2EEService) UploadPreKeys(ctx context.Context, userID uuid.UUID, deviceID string, req *models.UploadPreKeysRequest) (*models.E2EEDevice, error) {
+	args := m.Called(ctx, userID, deviceID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.E2EEDevice), args.Error(1)
+}
+
+func (m *MockE2EEService) RemoveDevice(ctx context.Context, userID uuid.UUID, deviceID string) error {
+	args := m.Called(ctx, userID, deviceID)
+	return args.Error(0)
+}
+
+func (m *MockE2EEService) ClaimPreKeyBundles(ctx context.Context, userID uuid.UUID) ([]*models.PreKeyBundle, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.PreKeyBundle), args.Error(1)
+}
+
+func (m *MockE2EEService) GetChannelKeys(ctx context.Context, channelID, requesterID uuid.UUID, deviceID string) (*models.ChannelKeys, error) {
+	args := m.Called(ctx, channelID, requesterID, deviceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChannelKeys), args.Error(1)
+}
+
+func (m *MockE2EEService) DistributeSenderKeys(ctx context.Context, channelID, requesterID uuid.UUID, req *models.DistributeSenderKeysRequest) error {
+	args := m.Called(ctx, channelID, requesterID, req)
+	return args.Error(0)
+}
+
+func newTestE2EEHandler() (*fiber.App, *MockE2EEService, uuid.UUID) {
+	keyService := new(MockE2EEService)
+	handler := NewE2EEHandler(keyService)
+	userID := uuid.New()
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", userID)
+		return c.Next()
+	})
+	app.Put("/users/@me/keys/:deviceId", handler.RegisterDevice)
+	app.Delete("/users/@me/keys/:deviceId", handler.RemoveDevice)
+	app.Get("/users/:id/keys", handler.ClaimPreKeyBundles)
+	app.Get("/channels/:id/keys", handler.GetChannelKeys)
+	app.Post("/channels/:id/keys", handler.DistributeSenderKeys)
+
+	return app, keyService, userID
+}
+
+func TestE2EEHandler_RegisterDevice(t *testing.T) {
+	app, keyService, userID := newTestE2EEHandler()
+	keyService.On("RegisterDevice", mock.Anything, userID, "laptop", mock.MatchedBy(func(req *models.RegisterDeviceKeysRequest) bool {
+		return req.IdentityKey == "aWQ=" && len(req.PreKeys) == 1
+	})).Return(&models.E2EEDevice{UserID: userID, DeviceID: "laptop", PreKeyCount: 1}, nil)
+	keyService.On("RegisterDevice", mock.Anything, userID, "phone", mock.Anything).Return(nil, services.ErrTooManyE2EEDevices)
+	keyService.On("RemoveDevice", mock.Anything, userID, "tablet").Return(services.ErrE2EEDeviceNotFound)
+
+	body := `{"identity_key":"aWQ=","signed_pre_key_id":1,"signed_pre_key":"c3Br","signed_key_signature":"c2ln","pre_keys":[{"key_id":1,"public_key":"cGs="}]}`
+	req := httptest.NewRequest(http.MethodPut, "/users/@me/keys/laptop", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	req = httptest.NewRequest(http.MethodPut, "/users/@me/keys/phone", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodDelete, "/users/@me/keys/tablet", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/users/not-a-uuid/keys", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestE2EEHandler_ChannelKeys(t *testing.T) {
+	app, keyService, userID := newTestE2EEHandler()
+	channelID := uuid.New()
+	keyService.On("GetChannelKeys", mock.Anything, channelID, userID, "laptop").Return(&models.ChannelKeys{Epoch: 3, SenderKeys: []*models.SenderKey{}}, nil)
+	keyService.On("GetChannelKeys", mock.Anything, channelID, userID, "").Return(nil, services.ErrE2EEDeviceNotFound)
+	keyService.On("DistributeSenderKeys", mock.Anything, channelID, userID, mock.MatchedBy(func(req *models.DistributeSenderKeysRequest) bool {
+		return req.Epoch == 3
+	})).Return(nil)
+	keyService.On("DistributeSenderKeys", mock.Anything, channelID, userID, mock.Anything).Return(services.ErrKeyEpochStale)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/channels/"+channelID.String()+"/keys?device_id=laptop", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/channels/"+channelID.String()+"/keys", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	for epoch, status := range map[string]int{"3": fiber.StatusNoContent, "2": fiber.StatusConflict} {
+		body := `{"device_id":"laptop","epoch":` + epoch + `,"keys":[{"user_id":"` + userID.String() + `","device_id":"phone","ciphertext":"c2VjcmV0"}]}`
+		req := httptest.NewRequest(http.MethodPost, "/channels/"+channelID.String()+"/keys", bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, status, resp.StatusCode, "epoch %s", epoch)
+	}
+}
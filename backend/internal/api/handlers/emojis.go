@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"mime/multipart"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// EmojiServiceInterface defines the methods needed from EmojiService
+type EmojiServiceInterface interface {
+	GetServerEmojis(ctx context.Context, serverID, requesterID uuid.UUID) ([]*models.Emoji, error)
+	CreateEmoji(ctx context.Context, serverID, requesterID uuid.UUID, name string, image *multipart.FileHeader) (*models.Emoji, error)
+	RenameEmoji(ctx context.Context, serverID, emojiID, requesterID uuid.UUID, name string) (*models.Emoji, error)
+	DeleteEmoji(ctx context.Context, serverID, emojiID, requesterID uuid.UUID) error
+}
+
+// EmojiHandler handles custom emoji requests
+type EmojiHandler struct {
+	emojiService EmojiServiceInterface
+}
+
+// NewEmojiHandler creates a new emoji handler
+func NewEmojiHandler(emojiService EmojiServiceInterface) *EmojiHandler {
+	return &EmojiHandler{emojiService: emojiService}
+}
+
+// ListEmojis returns a server's custom emoji
+// GET /servers/:id/emojis
+func (h *EmojiHandler) ListEmojis(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	emojis, err := h.emojiService.GetServerEmojis(c.UserContext(), serverID, userID)
+	if err != nil {
+		return emojiError(c, err)
+	}
+	if emojis == nil {
+		emojis = []*models.Emoji{}
+	}
+	return c.JSON(emojis)
+}
+
+// CreateEmoji uploads a custom emoji from the multipart fields name and
+// image
+// POST /servers/:id/emojis
+func (h *EmojiHandler) CreateEmoji(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	image, err := c.FormFile("image")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "image file required",
+		})
+	}
+
+	emoji, err := h.emojiService.CreateEmoji(c.UserContext(), serverID, userID, c.FormValue("name"), image)
+	if err != nil {
+		return emojiError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(emoji)
+}
+
+// RenameEmoji changes a custom emoji's name
+// PATCH /servers/:id/emojis/:emojiId
+func (h *EmojiHandler) RenameEmoji(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, emojiID, invalid := emojiParams(c)
+	if invalid != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": invalid,
+		})
+	}
+
+	var req struct {
+		Name string `json:"name" validate:"required,min=2,max=32"`
+	}
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	emoji, err := h.emojiService.RenameEmoji(c.UserContext(), serverID, emojiID, userID, req.Name)
+	if err != nil {
+		return emojiError(c, err)
+	}
+	return c.JSON(emoji)
+}
+
+// DeleteEmoji removes a custom emoji
+// DELETE /servers/:id/emojis/:emojiId
+func (h *EmojiHandler) DeleteEmoji(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, emojiID, invalid := emojiParams(c)
+	if invalid != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": invalid,
+		})
+	}
+
+	if err := h.emojiService.DeleteEmoji(c.UserContext(), serverID, emojiID, userID); err != nil {
+		return emojiError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// emojiParams parses the server and emoji IDs. invalid says which isn't a
+// valid ID.
+func emojiParams(c *fiber.Ctx) (serverID, emojiID uuid.UUID, invalid string) {
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return serverID, emojiID, "invalid server id"
+	}
+	emojiID, err = uuid.Parse(c.Params("emojiId"))
+	if err != nil {
+		return serverID, emojiID, "invalid emoji id"
+	}
+	return serverID, emojiID, ""
+}
+
+func emojiError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidEmojiName), errors.Is(err, services.ErrInvalidEmojiImage),
+		errors.Is(err, services.ErrEmojiTooLarge), errors.Is(err, services.ErrTooManyEmoji):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrCannotManageEmoji), errors.Is(err, services.ErrNotServerMember):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrServerNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "server not found"})
+	case errors.Is(err, services.ErrEmojiNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrEmojiNameTaken):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrEmojiStorageDisabled):
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "failed to manage emoji",
+	})
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// MockEmojiService mocks the EmojiService for testing
+type MockEmojiService struct {
+	mock.Mock
+}
+
+func (m *MockEmojiService) GetServerEmojis(ctx context.Context, serverID, requesterID uuid.UUID) ([]*models.Emoji, error) {
+	args := m.Called(ctx, serverID, requesterID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Emoji), args.Error(1)
+}
+
+func (m *MockEmojiService) CreateEmoji(ctx context.Context, serverID, requesterID uuid.UUID, name string, image *multipart.FileHeader) (*models.Emoji, error) {
+	args := m.Called(ctx, serverID, requesterID, name, image)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Emoji), args.Error(1)
+}
+
+func (m *MockEmojiService) RenameEmoji(ctx context.Context, serverID, emojiID, requesterID uuid.UUID, name string) (*models.Emoji, error) {
+	args := m.Called(ctx, serverID, emojiID, requesterID, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Emoji), args.Error(1)
+}
+
+func (m *MockEmojiService) DeleteEmoji(ctx context.Context, serverID, emojiID, requesterID uuid.UUID) error {
+	args := m.Called(ctx, serverID, emojiID, requesterID)
+	return args.Error(0)
+}
+
+func newTestEmojiHandler() (*fiber.App, *MockEmojiService, uuid.UUID) {
+	emojiService := new(MockEmojiService)
+	handler := NewEmojiHandler(emojiService)
+	userID := uuid.New()
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", userID)
+		return c.Next()
+	})
+	app.Get("/servers/:id/emojis", handler.ListEmojis)
+	app.Post("/servers/:id/emojis", handler.CreateEmoji)
+	app.Patch("/servers/:id/emojis/:emojiId", handler.RenameEmoji)
+	app.Delete("/servers/:id/emojis/:emojiId", handler.DeleteEmoji)
+
+	return app, emojiService, userID
+}
+
+// emojiUploadRequest builds a multipart upload with a name and a PNG image
+func emojiUploadRequest(url, name string) *http.Request {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	writer.WriteField("name", name)
+	part, _ := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Disposition": {`form-data; name="image"; filename="emoji.png"`},
+		"Content-Type":        {"image/png"},
+	})
+	part.Write([]byte("\x89PNG"))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, url, &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestEmojiHandler_CreateEmoji(t *testing.T) {
+	app, emojiService, userID := newTestEmojiHandler()
+	serverID := uuid.New()
+
+	emoji := &models.Emoji{ID: uuid.New(), ServerID: serverID, Name: "blob", URL: "https://cdn.example.com/blob.png"}
+	emojiService.On("CreateEmoji", mock.Anything, serverID, userID, "blob", mock.MatchedBy(func(image *multipart.FileHeader) bool {
+		return image.Filename == "emoji.png" && image.Header.Get("Content-Type") == "image/png"
+	})).Return(emoji, nil)
+
+	resp, err := app.Test(emojiUploadRequest("/servers/"+serverID.String()+"/emojis", "blob"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	assert.Equal(t, emoji.ID.String(), result["id"])
+	assert.NotContains(t, result, "path")
+}
+
+func TestEmojiHandler_CreateEmoji_RequiresImage(t *testing.T) {
+	app, emojiService, _ := newTestEmojiHandler()
+
+	req := httptest.NewRequest(http.MethodPost, "/servers/"+uuid.NewString()+"/emojis", bytes.NewReader([]byte(`{"name":"blob"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	emojiService.AssertNotCalled(t, "CreateEmoji", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestEmojiHandler_CreateEmoji_Errors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{services.ErrInvalidEmojiName, http.StatusBadRequest},
+		{services.ErrTooManyEmoji, http.StatusBadRequest},
+		{services.ErrCannotManageEmoji, http.StatusForbidden},
+		{services.ErrServerNotFound, http.StatusNotFound},
+		{services.ErrEmojiNameTaken, http.StatusConflict},
+		{services.ErrEmojiStorageDisabled, http.StatusNotImplemented},
+		{errors.New("database down"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		app, emojiService, _ := newTestEmojiHandler()
+		emojiService.On("CreateEmoji", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, tt.err)
+
+		resp, err := app.Test(emojiUploadRequest("/servers/"+uuid.NewString()+"/emojis", "blob"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.status, resp.StatusCode, tt.err.Error())
+	}
+}
+
+func TestEmojiHandler_ListEmojis_Empty(t *testing.T) {
+	app, emojiService, userID := newTestEmojiHandler()
+	serverID := uuid.New()
+	emojiService.On("GetServerEmojis", mock.Anything, serverID, userID).Return(nil, nil)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/servers/"+serverID.String()+"/emojis", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var result []interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	assert.NotNil(t, result)
+	assert.Empty(t, result)
+}
+
+func TestEmojiHandler_RenameEmoji(t *testing.T) {
+	app, emojiService, userID := newTestEmojiHandler()
+	serverID := uuid.New()
+	emojiID := uuid.New()
+	emojiService.On("RenameEmoji", mock.Anything, serverID, emojiID, userID, "blobby").
+		Return(&models.Emoji{ID: emojiID, ServerID: serverID, Name: "blobby"}, nil)
+
+	req := httptest.NewRequest(http.MethodPatch, "/servers/"+serverID.String()+"/emojis/"+emojiID.String(), bytes.NewReader([]byte(`{"name":"blobby"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	assert.Equal(t, "blobby", result["name"])
+}
+
+func TestEmojiHandler_DeleteEmoji(t *testing.T) {
+	app, emojiService, userID := newTestEmojiHandler()
+	serverID := uuid.New()
+	emojiID := uuid.New()
+	emojiService.On("DeleteEmoji", mock.Anything, serverID, emojiID, userID).Return(nil).Once()
+	emojiService.On("DeleteEmoji", mock.Anything, serverID, emojiID, userID).Return(services.ErrEmojiNotFound)
+
+	url := "/servers/" + serverID.String() + "/emojis/" + emojiID.String()
+	resp, err := app.Test(httptest.NewRequest(http.MethodDelete, url, nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodDelete, url, nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestEmojiHandler_InvalidEmojiID(t *testing.T) {
+	app, emojiService, _ := newTestEmojiHandler()
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodDelete, "/servers/"+uuid.NewString()+"/emojis/nope", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	emojiService.AssertNotCalled(t, "DeleteEmoji", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
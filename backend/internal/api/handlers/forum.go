@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+const (
+	maxForumPostNameLength    = 100
+	maxForumPostContentLength = 2000
+)
+
+// ForumServiceInterface defines the methods needed from ForumService
+type ForumServiceInterface interface {
+	GetSettings(ctx context.Context, channelID, requesterID uuid.UUID) (*models.ForumSettings, error)
+	UpdateSettings(ctx context.Context, channelID, requesterID uuid.UUID, req *models.UpdateForumSettingsRequest) (*models.ForumSettings, error)
+	CreatePost(ctx context.Context, channelID, authorID uuid.UUID, req *models.CreateForumPostRequest) (*models.ForumPost, error)
+	ListPosts(ctx context.Context, channelID, requesterID uuid.UUID, query models.ForumPostQuery) ([]*models.ForumPost, error)
+}
+
+// ForumHandler handles forum channel requests
+type ForumHandler struct {
+	forumService ForumServiceInterface
+}
+
+// NewForumHandler creates a new forum handler
+func NewForumHandler(forumService ForumServiceInterface) *ForumHandler {
+	return &ForumHandler{forumService: forumService}
+}
+
+// GetSettings returns a forum's tags and listing options
+// GET /channels/:id/forum
+func (h *ForumHandler) GetSettings(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	settings, err := h.forumService.GetSettings(c.UserContext(), channelID, userID)
+	if err != nil {
+		return forumError(c, err)
+	}
+	return c.JSON(settings)
+}
+
+// UpdateSettings changes a forum's tags and listing options
+// PATCH /channels/:id/forum
+func (h *ForumHandler) UpdateSettings(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	var req models.UpdateForumSettingsRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	settings, err := h.forumService.UpdateSettings(c.UserContext(), channelID, userID, &req)
+	if err != nil {
+		return forumError(c, err)
+	}
+	return c.JSON(settings)
+}
+
+// CreatePost starts a post, which is a thread with a first message
+// POST /channels/:id/forum-posts
+func (h *ForumHandler) CreatePost(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	var req models.CreateForumPostRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > maxForumPostNameLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "name must be 1-100 characters",
+		})
+	}
+	if utf8.RuneCountInString(req.Content) > maxForumPostContentLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": services.ErrMessageTooLong.Error(),
+		})
+	}
+
+	post, err := h.forumService.CreatePost(c.UserContext(), channelID, userID, &req)
+	if err != nil {
+		return forumError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(post)
+}
+
+// ListPosts returns a page of a forum's posts. tag takes comma-separated
+// tag IDs and keeps posts with any of them; sort is latest_activity or
+// creation_date and defaults to the forum's setting.
+// GET /channels/:id/forum-posts?tag=&sort=&archived=&limit=&offset=
+func (h *ForumHandler) ListPosts(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	query := models.ForumPostQuery{
+		Sort:     models.ForumSortOrder(c.Query("sort")),
+		Archived: c.QueryBool("archived", false),
+		Limit:    c.QueryInt("limit", 0),
+		Offset:   c.QueryInt("offset", 0),
+	}
+	if tags := c.Query("tag"); tags != "" {
+		for _, raw := range strings.Split(tags, ",") {
+			tagID, err := uuid.Parse(strings.TrimSpace(raw))
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "invalid tag id",
+				})
+			}
+			query.TagIDs = append(query.TagIDs, tagID)
+		}
+	}
+
+	posts, err := h.forumService.ListPosts(c.UserContext(), channelID, userID, query)
+	if err != nil {
+		return forumError(c, err)
+	}
+	if posts == nil {
+		posts = []*models.ForumPost{}
+	}
+	return c.JSON(posts)
+}
+
+func forumError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrNotForumChannel), errors.Is(err, services.ErrForumTagRequired),
+		errors.Is(err, services.ErrInvalidForumTag), errors.Is(err, services.ErrInvalidForumTagName),
+		errors.Is(err, services.ErrTooManyForumTags), errors.Is(err, services.ErrInvalidForumSortOrder),
+		errors.Is(err, services.ErrInvalidAutoArchive), errors.Is(err, services.ErrEmptyMessage):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrCannotManageForum), errors.Is(err, services.ErrNotServerMember):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrChannelNotFound), errors.Is(err, services.ErrServerNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "failed to manage forum",
+	})
+}
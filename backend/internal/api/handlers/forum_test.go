@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// MockForumService mocks the ForumService for testing
+type MockForumService struct {
+	mock.Mock
+}
+
+func (m *MockForumService) GetSettings(ctx context.Context, channelID, requesterID uuid.UUID) (*models.ForumSettings, error) {
+	args := m.Called(ctx, channelID, requesterID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ForumSettings), args.Error(1)
+}
+
+func (m *MockForumService) UpdateSettings(ctx context.Context, channelID, requesterID uuid.UUID, req *models.UpdateForumSettingsRequest) (*models.ForumSettings, error) {
+	args := m.Called(ctx, channelID, requesterID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ForumSettings), args.Error(1)
+}
+
+func (m *MockForumService) CreatePost(ctx context.Context, channelID, authorID uuid.UUID, req *models.CreateForumPostRequest) (*models.ForumPost, error) {
+	args := m.Called(ctx, channelID, authorID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ForumPost), args.Error(1)
+}
+
+func (m *MockForumService) ListPosts(ctx context.Context, channelID, requesterID uuid.UUID, query models.ForumPostQuery) ([]*models.ForumPost, error) {
+	args := m.Called(ctx, channelID, requesterID, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.ForumPost), args.Error(1)
+}
+
+func newTestForumHandler() (*fiber.App, *MockForumService, uuid.UUID) {
+	forumService := new(MockForumService)
+	handler := NewForumHandler(forumService)
+	userID := uuid.New()
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", userID)
+		return c.Next()
+	})
+	app.Get("/channels/:id/forum", handler.GetSettings)
+	app.Patch("/channels/:id/forum", handler.UpdateSettings)
+	app.Get("/channels/:id/forum-posts", handler.ListPosts)
+	app.Post("/channels/:id/forum-posts", handler.CreatePost)
+
+	return app, forumService, userID
+}
+
+func forumPostRequest(channelID uuid.UUID, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/channels/"+channelID.String()+"/forum-posts", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestForumHandler_CreatePost(t *testing.T) {
+	app, forumService, userID := newTestForumHandler()
+	channelID := uuid.New()
+	tagID := uuid.New()
+
+	post := &models.ForumPost{Thread: models.Thread{ID: uuid.New(), ParentChannelID: channelID, Name: "Crash", AppliedTags: []uuid.UUID{tagID}}}
+	forumService.On("CreatePost", mock.Anything, channelID, userID, mock.MatchedBy(func(req *models.CreateForumPostRequest) bool {
+		return req.Name == "Crash" && req.Content == "It crashes" && len(req.AppliedTags) == 1 && req.AppliedTags[0] == tagID
+	})).Return(post, nil)
+
+	resp, err := app.Test(forumPostRequest(channelID, `{"name":" Crash ","content":"It crashes","applied_tags":["`+tagID.String()+`"]}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	assert.Equal(t, post.ID.String(), result["id"])
+	assert.Equal(t, []interface{}{tagID.String()}, result["applied_tags"])
+}
+
+func TestForumHandler_CreatePost_Validation(t *testing.T) {
+	app, forumService, _ := newTestForumHandler()
+
+	for _, body := range []string{
+		`{"name":"","content":"hi"}`,
+		`{"name":"` + strings.Repeat("a", 101) + `","content":"hi"}`,
+		`{"name":"Post","content":"` + strings.Repeat("a", 2001) + `"}`,
+	} {
+		resp, err := app.Test(forumPostRequest(uuid.New(), body))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	}
+	forumService.AssertNotCalled(t, "CreatePost", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestForumHandler_CreatePost_Errors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{services.ErrNotForumChannel, http.StatusBadRequest},
+		{services.ErrForumTagRequired, http.StatusBadRequest},
+		{services.ErrInvalidForumTag, http.StatusBadRequest},
+		{services.ErrNotServerMember, http.StatusForbidden},
+		{services.ErrChannelNotFound, http.StatusNotFound},
+		{errors.New("database down"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		app, forumService, _ := newTestForumHandler()
+		forumService.On("CreatePost", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, tt.err)
+
+		resp, err := app.Test(forumPostRequest(uuid.New(), `{"name":"Post","content":"hi"}`))
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.status, resp.StatusCode, tt.err.Error())
+	}
+}
+
+func TestForumHandler_ListPosts(t *testing.T) {
+	app, forumService, userID := newTestForumHandler()
+	channelID := uuid.New()
+	bug, question := uuid.New(), uuid.New()
+
+	forumService.On("ListPosts", mock.Anything, channelID, userID, models.ForumPostQuery{
+		TagIDs:   []uuid.UUID{bug, question},
+		Sort:     models.ForumSortCreationDate,
+		Archived: true,
+		Limit:    10,
+		Offset:   20,
+	}).Return(nil, nil)
+
+	url := "/channels/" + channelID.String() + "/forum-posts?tag=" + bug.String() + "," + question.String() +
+		"&sort=creation_date&archived=true&limit=10&offset=20"
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, url, nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var result []interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	assert.NotNil(t, result)
+	assert.Empty(t, result)
+}
+
+func TestForumHandler_ListPosts_InvalidTag(t *testing.T) {
+	app, forumService, _ := newTestForumHandler()
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/channels/"+uuid.NewString()+"/forum-posts?tag=bug", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	forumService.AssertNotCalled(t, "ListPosts", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestForumHandler_UpdateSettings(t *testing.T) {
+	app, forumService, userID := newTestForumHandler()
+	channelID := uuid.New()
+
+	forumService.On("UpdateSettings", mock.Anything, channelID, userID, mock.MatchedBy(func(req *models.UpdateForumSettingsRequest) bool {
+		return req.RequireTag != nil && *req.RequireTag && req.DefaultSortOrder == nil &&
+			req.AvailableTags != nil && len(*req.AvailableTags) == 1 && (*req.AvailableTags)[0].Name == "bug"
+	})).Return(&models.ForumSettings{ChannelID: channelID, RequireTag: true}, nil).Once()
+	forumService.On("UpdateSettings", mock.Anything, channelID, userID, mock.Anything).Return(nil, services.ErrCannotManageForum)
+
+	body := `{"require_tag":true,"available_tags":[{"name":"bug"}]}`
+	req := httptest.NewRequest(http.MethodPatch, "/channels/"+channelID.String()+"/forum", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	req = httptest.NewRequest(http.MethodPatch, "/channels/"+channelID.String()+"/forum", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
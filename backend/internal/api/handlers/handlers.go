@@ -23,6 +23,26 @@ type Handlers struct {
 	Polls         *PollHandler
 	AuditLog      *AuditLogHandler
 	ReadState     *ReadStateHandler
+	Push          *PushHandler
+	Admin         *AdminHandler
+	AutoMod       *AutoModHandler
+	Permissions   *PermissionsHandler
+	Emojis        *EmojiHandler
+	Forums        *ForumHandler
+	ChannelFeeds  *ChannelFeedHandler
+
+	MemberVerification *MemberVerificationHandler
+	ChannelSchedules   *ChannelScheduleHandler
+	Onboarding         *OnboardingHandler
+	Blocklists         *BlocklistHandler
+	Webhooks           *WebhookHandlers
+	WebhookPolicies    *WebhookPolicyHandler
+	ServerTokens       *ServerTokenHandler
+	E2EE               *E2EEHandler
+	Sessions           *SessionHandler
+	OAuthApps          *OAuthAppHandler
+	Announcements      *AnnouncementHandler
+	Reports            *ReportHandler
 }
 
 // NewHandlers creates all handlers with dependencies
@@ -40,7 +60,7 @@ func NewHandlers(
 	return &Handlers{
 		Auth:     NewAuthHandler(authService),
 		Users:    NewUserHandler(userService, serverService, channelService),
-		Servers:  NewServerHandler(serverService, channelService, roleService),
+		Servers:  NewServerHandlerWithPresence(serverService, channelService, roleService, presenceProvider(gateway)),
 		Channels: NewChannelHandler(channelService, messageService),
 		Threads:  NewThreadHandler(threadService),
 		Invites:  NewInviteHandler(serverService),
@@ -66,7 +86,7 @@ func NewHandlersWithAttachments(
 	return &Handlers{
 		Auth:        NewAuthHandler(authService),
 		Users:       NewUserHandler(userService, serverService, channelService),
-		Servers:     NewServerHandler(serverService, channelService, roleService),
+		Servers:     NewServerHandlerWithPresence(serverService, channelService, roleService, presenceProvider(gateway)),
 		Channels:    NewChannelHandler(channelService, messageService),
 		Threads:     NewThreadHandler(threadService),
 		Invites:     NewInviteHandler(serverService),
@@ -93,7 +113,7 @@ func NewHandlersWithTyping(
 	return &Handlers{
 		Auth:     NewAuthHandler(authService),
 		Users:    NewUserHandler(userService, serverService, channelService),
-		Servers:  NewServerHandler(serverService, channelService, roleService),
+		Servers:  NewServerHandlerWithPresence(serverService, channelService, roleService, presenceProvider(gateway)),
 		Channels: NewChannelHandlerWithTyping(channelService, messageService, typingService),
 		Threads:  NewThreadHandler(threadService),
 		Invites:  NewInviteHandler(serverService),
@@ -102,3 +122,11 @@ func NewHandlersWithTyping(
 		Search:   NewSearchHandler(searchService),
 	}
 }
+
+// presenceProvider avoids wrapping a nil gateway in a non-nil interface
+func presenceProvider(gateway *websocket.Gateway) PresenceProvider {
+	if gateway == nil {
+		return nil
+	}
+	return gateway
+}
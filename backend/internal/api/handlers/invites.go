@@ -22,8 +22,8 @@ func NewInviteHandlers(inviteService *services.InviteService) *InviteHandlers {
 
 // CreateInviteRequest represents an invite creation request
 type CreateInviteRequest struct {
-	MaxAge    int  `json:"max_age"`    // Seconds, 0 = never expires
-	MaxUses   int  `json:"max_uses"`   // 0 = unlimited
+	MaxAge    int  `json:"max_age" validate:"min=0"`  // Seconds, 0 = never expires
+	MaxUses   int  `json:"max_uses" validate:"min=0"` // 0 = unlimited
 	Temporary bool `json:"temporary"`
 }
 
@@ -51,10 +51,8 @@ func (h *InviteHandlers) CreateInvite(c *fiber.Ctx) error {
 	}
 
 	var req CreateInviteRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
 	// Get channel to find server ID
@@ -66,7 +64,7 @@ func (h *InviteHandlers) CreateInvite(c *fiber.Ctx) error {
 		maxAge = time.Duration(req.MaxAge) * time.Second
 	}
 
-	invite, err := h.inviteService.CreateInvite(c.Context(), &services.CreateInviteRequest{
+	invite, err := h.inviteService.CreateInvite(c.UserContext(), &services.CreateInviteRequest{
 		ServerID:  serverID,
 		ChannelID: channelID,
 		CreatorID: userID,
@@ -92,7 +90,7 @@ func (h *InviteHandlers) GetInvite(c *fiber.Ctx) error {
 		})
 	}
 
-	invite, err := h.inviteService.GetInvite(c.Context(), code)
+	invite, err := h.inviteService.GetInvite(c.UserContext(), code)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Invite not found",
@@ -112,7 +110,7 @@ func (h *InviteHandlers) UseInvite(c *fiber.Ctx) error {
 		})
 	}
 
-	server, err := h.inviteService.UseInvite(c.Context(), code, userID)
+	server, err := h.inviteService.UseInvite(c.UserContext(), code, userID)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
@@ -132,7 +130,7 @@ func (h *InviteHandlers) DeleteInvite(c *fiber.Ctx) error {
 		})
 	}
 
-	err := h.inviteService.DeleteInvite(c.Context(), code, userID)
+	err := h.inviteService.DeleteInvite(c.UserContext(), code, userID)
 	if err != nil {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": err.Error(),
@@ -166,7 +164,7 @@ func (h *InviteHandlers) GetServerInvites(c *fiber.Ctx) error {
 		})
 	}
 
-	invites, err := h.inviteService.GetServerInvites(c.Context(), serverID, userID)
+	invites, err := h.inviteService.GetServerInvites(c.UserContext(), serverID, userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// MemberVerificationServiceInterface defines the methods needed from
+// MemberVerificationService
+type MemberVerificationServiceInterface interface {
+	GetSettings(ctx context.Context, serverID, requesterID uuid.UUID) (*models.MemberVerification, error)
+	UpdateSettings(ctx context.Context, serverID, requesterID uuid.UUID, req *models.UpdateMemberVerificationRequest) (*models.MemberVerification, error)
+	Submit(ctx context.Context, serverID, userID uuid.UUID, req *models.SubmitMemberVerificationRequest) (*models.Member, error)
+	GetResponse(ctx context.Context, serverID, requesterID, userID uuid.UUID) (*models.MemberVerificationResponse, error)
+}
+
+// MemberVerificationHandler handles membership screening requests
+type MemberVerificationHandler struct {
+	verification MemberVerificationServiceInterface
+}
+
+// NewMemberVerificationHandler creates a new membership screening handler
+func NewMemberVerificationHandler(verification MemberVerificationServiceInterface) *MemberVerificationHandler {
+	return &MemberVerificationHandler{verification: verification}
+}
+
+// GetSettings returns a server's rules and questions
+// GET /servers/:id/member-verification
+func (h *MemberVerificationHandler) GetSettings(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	settings, err := h.verification.GetSettings(c.UserContext(), serverID, userID)
+	if err != nil {
+		return memberVerificationError(c, err)
+	}
+	return c.JSON(settings)
+}
+
+// UpdateSettings changes a server's screening form or turns it on or off
+// PATCH /servers/:id/member-verification
+func (h *MemberVerificationHandler) UpdateSettings(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	var req models.UpdateMemberVerificationRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	settings, err := h.verification.UpdateSettings(c.UserContext(), serverID, userID, &req)
+	if err != nil {
+		return memberVerificationError(c, err)
+	}
+	return c.JSON(settings)
+}
+
+// Submit accepts a server's rules and answers its questions, ending the
+// caller's pending membership
+// PUT /servers/:id/member-verification/@me
+func (h *MemberVerificationHandler) Submit(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	var req models.SubmitMemberVerificationRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	member, err := h.verification.Submit(c.UserContext(), serverID, userID, &req)
+	if err != nil {
+		return memberVerificationError(c, err)
+	}
+	return c.JSON(member)
+}
+
+// GetResponse returns what a member answered when they joined
+// GET /servers/:id/member-verification/responses/:userId
+func (h *MemberVerificationHandler) GetResponse(c *fiber.Ctx) error {
+	requesterID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+	userID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	response, err := h.verification.GetResponse(c.UserContext(), serverID, requesterID, userID)
+	if err != nil {
+		return memberVerificationError(c, err)
+	}
+	return c.JSON(response)
+}
+
+func memberVerificationError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidMemberVerification), errors.Is(err, services.ErrMemberVerificationResponseMissing),
+		errors.Is(err, services.ErrMembershipNotPending):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrCannotManageMemberVerification), errors.Is(err, services.ErrNotServerMember):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrServerNotFound), errors.Is(err, services.ErrMemberVerificationNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrMemberVerificationOutdated):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "failed to manage membership screening",
+	})
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// MockMemberVerificationService mocks the MemberVerificationService for
+// testing
+type MockMemberVerificationService struct {
+	mock.Mock
+}
+
+func (m *MockMemberVerificationService) GetSettings(ctx context.Context, serverID, requesterID uuid.UUID) (*models.MemberVerification, error) {
+	args := m.Called(ctx, serverID, requesterID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MemberVerification), args.Error(1)
+}
+
+func (m *MockMemberVerificationService) UpdateSettings(ctx context.Context, serverID, requesterID uuid.UUID, req *models.UpdateMemberVerificationRequest) (*models.MemberVerification, error) {
+	args := m.Called(ctx, serverID, requesterID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MemberVerification), args.Error(1)
+}
+
+func (m *MockMemberVerificationService) Submit(ctx context.Context, serverID, userID uuid.UUID, req *models.SubmitMemberVerificationRequest) (*models.Member, error) {
+	args := m.Called(ctx, serverID, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Member), args.Error(1)
+}
+
+func (m *MockMemberVerificationService) GetResponse(ctx context.Context, serverID, requesterID, userID uuid.UUID) (*models.MemberVerificationResponse, error) {
+	args := m.Called(ctx, serverID, requesterID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MemberVerificationResponse), args.Error(1)
+}
+
+func newTestMemberVerificationHandler() (*fiber.App, *MockMemberVerificationService, uuid.UUID) {
+	verificationService := new(MockMemberVerificationService)
+	handler := NewMemberVerificationHandler(verificationService)
+	userID := uuid.New()
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", userID)
+		return c.Next()
+	})
+	app.Get("/servers/:id/member-verification", handler.GetSettings)
+	app.Patch("/servers/:id/member-verification", handler.UpdateSettings)
+	app.Put("/servers/:id/member-verification/@me", handler.Submit)
+	app.Get("/servers/:id/member-verification/responses/:userId", handler.GetResponse)
+
+	return app, verificationService, userID
+}
+
+func TestMemberVerificationHandler_Submit(t *testing.T) {
+	app, verificationService, userID := newTestMemberVerificationHandler()
+	serverID := uuid.New()
+	version := time.Date(2026, 3, 1, 12, 0, 0, 123000, time.UTC)
+
+	verificationService.On("Submit", mock.Anything, serverID, userID, mock.MatchedBy(func(req *models.SubmitMemberVerificationRequest) bool {
+		return req.Version.Equal(version) && len(req.Responses) == 2 && req.Responses[1] == "A friend"
+	})).Return(&models.Member{ServerID: serverID, UserID: userID}, nil)
+
+	body := `{"version":"2026-03-01T12:00:00.000123Z","responses":["","A friend"]}`
+	req := httptest.NewRequest(http.MethodPut, "/servers/"+serverID.String()+"/member-verification/@me", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var member models.Member
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&member))
+	assert.False(t, member.Pending)
+}
+
+func TestMemberVerificationHandler_Errors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{services.ErrMemberVerificationOutdated, fiber.StatusConflict},
+		{services.ErrMemberVerificationResponseMissing, fiber.StatusBadRequest},
+		{services.ErrMembershipNotPending, fiber.StatusBadRequest},
+		{services.ErrNotServerMember, fiber.StatusForbidden},
+	}
+
+	for _, tc := range tests {
+		app, verificationService, userID := newTestMemberVerificationHandler()
+		serverID := uuid.New()
+		verificationService.On("Submit", mock.Anything, serverID, userID, mock.Anything).Return(nil, tc.err)
+
+		req := httptest.NewRequest(http.MethodPut, "/servers/"+serverID.String()+"/member-verification/@me", bytes.NewReader([]byte(`{"version":"2026-01-01T00:00:00Z"}`)))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, tc.status, resp.StatusCode, tc.err.Error())
+	}
+
+	app, verificationService, userID := newTestMemberVerificationHandler()
+	serverID, memberID := uuid.New(), uuid.New()
+	verificationService.On("GetResponse", mock.Anything, serverID, userID, memberID).Return(nil, services.ErrCannotManageMemberVerification)
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/servers/"+serverID.String()+"/member-verification/responses/"+memberID.String(), nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+}
+
+func TestMemberVerificationHandler_UpdateSettings(t *testing.T) {
+	app, verificationService, userID := newTestMemberVerificationHandler()
+	serverID := uuid.New()
+
+	verificationService.On("UpdateSettings", mock.Anything, serverID, userID, mock.MatchedBy(func(req *models.UpdateMemberVerificationRequest) bool {
+		return req.Enabled != nil && *req.Enabled && req.FormFields != nil && (*req.FormFields)[0].FieldType == models.MemberVerificationTerms
+	})).Return(&models.MemberVerification{ServerID: serverID, Enabled: true}, nil)
+	verificationService.On("UpdateSettings", mock.Anything, serverID, userID, mock.Anything).Return(nil, services.ErrInvalidMemberVerification)
+
+	body := `{"enabled":true,"form_fields":[{"field_type":"terms","label":"Rules","values":["Be kind"]}]}`
+	req := httptest.NewRequest(http.MethodPatch, "/servers/"+serverID.String()+"/member-verification", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	req = httptest.NewRequest(http.MethodPatch, "/servers/"+serverID.String()+"/member-verification", bytes.NewReader([]byte(`{"description":"x"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
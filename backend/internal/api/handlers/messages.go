@@ -8,6 +8,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
+	"hearth/internal/models"
 	"hearth/internal/services"
 )
 
@@ -56,9 +57,9 @@ type AttachmentResponse struct {
 
 // ReactionResponse represents a reaction in API responses
 type ReactionResponse struct {
-	Emoji string `json:"emoji"`
-	Count int    `json:"count"`
-	Me    bool   `json:"me"`
+	Emoji models.ReactionEmoji `json:"emoji"`
+	Count int                  `json:"count"`
+	Me    bool                 `json:"me"`
 }
 
 // SendMessage creates a new message in a channel
@@ -75,24 +76,25 @@ func (h *MessageHandlers) SendMessage(c *fiber.Ctx) error {
 		Content   string  `json:"content"`
 		Nonce     *string `json:"nonce,omitempty"`
 		TTS       bool    `json:"tts"`
-		ReplyToID *string `json:"message_reference,omitempty"`
+		ReplyToID *string `json:"message_reference,omitempty" validate:"omitempty,uuid"`
 	}
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
 	var replyToID *uuid.UUID
 	if req.ReplyToID != nil {
-		id, err := uuid.Parse(*req.ReplyToID)
-		if err == nil {
-			replyToID = &id
-		}
+		id := uuid.MustParse(*req.ReplyToID) // Validated as a UUID
+		replyToID = &id
 	}
 
-	message, err := h.messageService.SendMessage(c.Context(), userID, channelID, req.Content, nil, replyToID)
+	message, err := h.messageService.SendMessage(c.UserContext(), userID, channelID, req.Content, nil, replyToID)
 	if err != nil {
+		if errors.Is(err, services.ErrAutoModBlocked) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -130,7 +132,7 @@ func (h *MessageHandlers) GetMessages(c *fiber.Ctx) error {
 		}
 	}
 
-	messages, err := h.messageService.GetMessages(c.Context(), channelID, userID, before, after, limit)
+	messages, err := h.messageService.GetMessages(c.UserContext(), channelID, userID, before, after, limit)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -150,7 +152,7 @@ func (h *MessageHandlers) GetMessage(c *fiber.Ctx) error {
 		})
 	}
 
-	message, err := h.messageService.GetMessage(c.Context(), messageID, userID)
+	message, err := h.messageService.GetMessage(c.UserContext(), messageID, userID)
 	if err != nil {
 		if errors.Is(err, services.ErrMessageNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -188,13 +190,11 @@ func (h *MessageHandlers) EditMessage(c *fiber.Ctx) error {
 	var req struct {
 		Content string `json:"content"`
 	}
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
-	message, err := h.messageService.EditMessage(c.Context(), messageID, userID, req.Content)
+	message, err := h.messageService.EditMessage(c.UserContext(), messageID, userID, req.Content)
 	if err != nil {
 		if errors.Is(err, services.ErrMessageNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -206,6 +206,11 @@ func (h *MessageHandlers) EditMessage(c *fiber.Ctx) error {
 				"error": "You can only edit your own messages",
 			})
 		}
+		if errors.Is(err, services.ErrAutoModBlocked) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -224,7 +229,7 @@ func (h *MessageHandlers) DeleteMessage(c *fiber.Ctx) error {
 		})
 	}
 
-	err = h.messageService.DeleteMessage(c.Context(), messageID, userID)
+	err = h.messageService.DeleteMessage(c.UserContext(), messageID, userID)
 	if err != nil {
 		if errors.Is(err, services.ErrMessageNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -250,19 +255,45 @@ func (h *MessageHandlers) DeleteMessage(c *fiber.Ctx) error {
 }
 
 // BulkDeleteMessages deletes multiple messages
-// Note: Bulk delete not implemented in service yet
 func (h *MessageHandlers) BulkDeleteMessages(c *fiber.Ctx) error {
-	_, err := uuid.Parse(c.Params("channelID"))
+	userID := c.Locals("userID").(uuid.UUID)
+	channelID, err := uuid.Parse(c.Params("channelID"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid channel ID",
 		})
 	}
 
-	// TODO: Implement BulkDeleteMessages in service
-	return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{
-		"error": "Not implemented",
-	})
+	var req struct {
+		Messages []uuid.UUID `json:"messages"`
+		Reason   string      `json:"reason"`
+	}
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	_, err = h.messageService.BulkDeleteMessages(c.Context(), channelID, userID, req.Messages, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrBulkDeleteCount), errors.Is(err, services.ErrMessagesTooOld):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrChannelNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Channel not found",
+			})
+		case errors.Is(err, services.ErrCannotModerate):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "No permission to delete messages",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
 }
 
 // AddReaction adds a reaction to a message
@@ -276,9 +307,20 @@ func (h *MessageHandlers) AddReaction(c *fiber.Ctx) error {
 	}
 	emoji := c.Params("emoji")
 
-	err = h.messageService.AddReaction(c.Context(), messageID, userID, emoji)
+	err = h.messageService.AddReaction(c.UserContext(), messageID, userID, emoji)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		status := fiber.StatusInternalServerError
+		switch err {
+		case services.ErrReactionRateLimited:
+			status = fiber.StatusTooManyRequests
+		case services.ErrTooManyReactions:
+			status = fiber.StatusBadRequest
+		case services.ErrMemberTimedOut, services.ErrMembershipPending, services.ErrChannelClosed:
+			status = fiber.StatusForbidden
+		case services.ErrEmojiNotFound:
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
@@ -297,7 +339,7 @@ func (h *MessageHandlers) RemoveReaction(c *fiber.Ctx) error {
 	}
 	emoji := c.Params("emoji")
 
-	err = h.messageService.RemoveReaction(c.Context(), messageID, userID, emoji)
+	err = h.messageService.RemoveReaction(c.UserContext(), messageID, userID, emoji)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -317,7 +359,7 @@ func (h *MessageHandlers) GetPinnedMessages(c *fiber.Ctx) error {
 		})
 	}
 
-	messages, err := h.messageService.GetPinnedMessages(c.Context(), channelID, userID)
+	messages, err := h.messageService.GetPinnedMessages(c.UserContext(), channelID, userID)
 	if err != nil {
 		if errors.Is(err, services.ErrChannelNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -352,7 +394,7 @@ func (h *MessageHandlers) PinMessage(c *fiber.Ctx) error {
 		})
 	}
 
-	err = h.messageService.PinMessage(c.Context(), messageID, userID)
+	err = h.messageService.PinMessage(c.UserContext(), messageID, userID)
 	if err != nil {
 		if errors.Is(err, services.ErrMessageNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -392,7 +434,7 @@ func (h *MessageHandlers) UnpinMessage(c *fiber.Ctx) error {
 		})
 	}
 
-	err = h.messageService.UnpinMessage(c.Context(), messageID, userID)
+	err = h.messageService.UnpinMessage(c.UserContext(), messageID, userID)
 	if err != nil {
 		if errors.Is(err, services.ErrMessageNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -30,6 +30,7 @@ type mockMessageService struct {
 	pinMessageFunc        func(ctx context.Context, messageID, requesterID uuid.UUID) error
 	unpinMessageFunc      func(ctx context.Context, messageID, requesterID uuid.UUID) error
 	getPinnedMessagesFunc func(ctx context.Context, channelID, requesterID uuid.UUID) ([]*models.Message, error)
+	bulkDeleteFunc        func(ctx context.Context, channelID, requesterID uuid.UUID, messageIDs []uuid.UUID, reason string) ([]uuid.UUID, error)
 }
 
 func (m *mockMessageService) SendMessage(ctx context.Context, authorID, channelID uuid.UUID, content string, attachments []*models.Attachment, replyTo *uuid.UUID) (*models.Message, error) {
@@ -102,6 +103,13 @@ func (m *mockMessageService) GetPinnedMessages(ctx context.Context, channelID, r
 	return nil, nil
 }
 
+func (m *mockMessageService) BulkDeleteMessages(ctx context.Context, channelID, requesterID uuid.UUID, messageIDs []uuid.UUID, reason string) ([]uuid.UUID, error) {
+	if m.bulkDeleteFunc != nil {
+		return m.bulkDeleteFunc(ctx, channelID, requesterID, messageIDs, reason)
+	}
+	return messageIDs, nil
+}
+
 // setupMessageTestApp creates a test Fiber app with message routes
 func setupMessageTestApp(messageService *mockMessageService) *fiber.App {
 	app := fiber.New()
@@ -286,13 +294,36 @@ func setupMessageTestApp(messageService *mockMessageService) *fiber.App {
 		return c.SendStatus(fiber.StatusNoContent)
 	})
 
-	// BulkDeleteMessages (not implemented)
+	// BulkDeleteMessages
 	app.Post("/channels/:channelID/messages/bulk-delete", func(c *fiber.Ctx) error {
-		_, err := uuid.Parse(c.Params("channelID"))
+		userID := c.Locals("userID").(uuid.UUID)
+		channelID, err := uuid.Parse(c.Params("channelID"))
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid channel ID"})
 		}
-		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": "Not implemented"})
+
+		var req struct {
+			Messages []uuid.UUID `json:"messages"`
+			Reason   string      `json:"reason"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+
+		_, err = messageService.BulkDeleteMessages(c.Context(), channelID, userID, req.Messages, req.Reason)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrBulkDeleteCount), errors.Is(err, services.ErrMessagesTooOld):
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			case errors.Is(err, services.ErrChannelNotFound):
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Channel not found"})
+			case errors.Is(err, services.ErrCannotModerate):
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "No permission to delete messages"})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.SendStatus(fiber.StatusNoContent)
 	})
 
 	// AddReaction
@@ -1100,15 +1131,28 @@ func TestDeleteMessage_NotAuthor(t *testing.T) {
 
 // ========== BulkDeleteMessages Tests ==========
 
-func TestBulkDeleteMessages_NotImplemented(t *testing.T) {
+func TestBulkDeleteMessages_Success(t *testing.T) {
 	userID := uuid.New()
 	channelID := uuid.New()
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
 
-	mockService := &mockMessageService{}
+	var gotIDs []uuid.UUID
+	var gotReason string
+	mockService := &mockMessageService{
+		bulkDeleteFunc: func(ctx context.Context, chID, requesterID uuid.UUID, messageIDs []uuid.UUID, reason string) ([]uuid.UUID, error) {
+			if chID != channelID || requesterID != userID {
+				t.Errorf("unexpected channel %s or requester %s", chID, requesterID)
+			}
+			gotIDs = messageIDs
+			gotReason = reason
+			return messageIDs, nil
+		},
+	}
 	app := setupMessageTestApp(mockService)
 
 	body, _ := json.Marshal(map[string]interface{}{
-		"messages": []string{uuid.New().String(), uuid.New().String()},
+		"messages": []string{ids[0].String(), ids[1].String()},
+		"reason":   "spam",
 	})
 
 	req := httptest.NewRequest("POST", "/channels/"+channelID.String()+"/messages/bulk-delete", bytes.NewReader(body))
@@ -1121,8 +1165,57 @@ func TestBulkDeleteMessages_NotImplemented(t *testing.T) {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != fiber.StatusNotImplemented {
-		t.Fatalf("Expected status 501, got %d", resp.StatusCode)
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", resp.StatusCode)
+	}
+	if len(gotIDs) != 2 || gotIDs[0] != ids[0] || gotIDs[1] != ids[1] {
+		t.Errorf("Expected ids %v, got %v", ids, gotIDs)
+	}
+	if gotReason != "spam" {
+		t.Errorf("Expected reason 'spam', got %q", gotReason)
+	}
+}
+
+func TestBulkDeleteMessages_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"too few or too many", services.ErrBulkDeleteCount, fiber.StatusBadRequest},
+		{"too old", services.ErrMessagesTooOld, fiber.StatusBadRequest},
+		{"channel not found", services.ErrChannelNotFound, fiber.StatusNotFound},
+		{"no permission", services.ErrCannotModerate, fiber.StatusForbidden},
+		{"other", errors.New("boom"), fiber.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockMessageService{
+				bulkDeleteFunc: func(ctx context.Context, channelID, requesterID uuid.UUID, messageIDs []uuid.UUID, reason string) ([]uuid.UUID, error) {
+					return nil, tt.err
+				},
+			}
+			app := setupMessageTestApp(mockService)
+
+			body, _ := json.Marshal(map[string]interface{}{
+				"messages": []string{uuid.New().String(), uuid.New().String()},
+			})
+
+			req := httptest.NewRequest("POST", "/channels/"+uuid.New().String()+"/messages/bulk-delete", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-User-ID", uuid.New().String())
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Fatalf("Expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+		})
 	}
 }
 
@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"context"
+
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
+	"hearth/internal/models"
 	"hearth/internal/services"
 	ws "hearth/internal/websocket"
 )
@@ -27,7 +30,7 @@ func (h *InviteHandler) Get(c *fiber.Ctx) error {
 		})
 	}
 
-	invite, err := h.serverService.GetInvite(c.Context(), code)
+	invite, err := h.serverService.GetInvite(c.UserContext(), code)
 	if err != nil {
 		if err == services.ErrInviteNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -47,7 +50,7 @@ func (h *InviteHandler) Accept(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
 	code := c.Params("code")
 
-	server, err := h.serverService.JoinServer(c.Context(), userID, code)
+	server, err := h.serverService.JoinServer(c.UserContext(), userID, code)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
@@ -67,7 +70,7 @@ func (h *InviteHandler) Delete(c *fiber.Ctx) error {
 		})
 	}
 
-	err := h.serverService.DeleteInvite(c.Context(), code, userID)
+	err := h.serverService.DeleteInvite(c.UserContext(), code, userID)
 	if err != nil {
 		if err == services.ErrInviteNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -87,28 +90,21 @@ func (h *InviteHandler) Delete(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
-// VoiceHandler handles voice operations
-type VoiceHandler struct{}
-
-func NewVoiceHandler() *VoiceHandler {
-	return &VoiceHandler{}
+// GatewayHandler handles WebSocket gateway connections
+type GatewayHandler struct {
+	gateway *ws.Gateway
+	warmup   WarmupStatus
+	readOnly ReadOnlyStatus
 }
 
-// GetRegions returns available voice regions
-func (h *VoiceHandler) GetRegions(c *fiber.Ctx) error {
-	return c.JSON([]fiber.Map{
-		{"id": "us-west", "name": "US West", "optimal": true},
-		{"id": "us-east", "name": "US East", "optimal": false},
-		{"id": "eu-west", "name": "EU West", "optimal": false},
-		{"id": "eu-central", "name": "EU Central", "optimal": false},
-		{"id": "singapore", "name": "Singapore", "optimal": false},
-		{"id": "sydney", "name": "Sydney", "optimal": false},
-	})
+// WarmupStatus reports whether startup cache warming is done
+type WarmupStatus interface {
+	Warmed() bool
 }
 
-// GatewayHandler handles WebSocket gateway connections
-type GatewayHandler struct {
-	gateway *ws.Gateway
+// ReadOnlyStatus reports whether the instance is refusing writes
+type ReadOnlyStatus interface {
+	Mode(ctx context.Context) *models.ReadOnlyMode
 }
 
 func NewGatewayHandler(gateway *ws.Gateway) *GatewayHandler {
@@ -117,6 +113,37 @@ func NewGatewayHandler(gateway *ws.Gateway) *GatewayHandler {
 	}
 }
 
+// SetWarmup keeps the instance reporting not ready until the cache has
+// been warmed
+func (h *GatewayHandler) SetWarmup(warmup WarmupStatus) {
+	h.warmup = warmup
+}
+
+// SetReadOnly reports read-only mode in health and readiness checks. A
+// read-only instance still serves reads, so it stays ready.
+func (h *GatewayHandler) SetReadOnly(readOnly ReadOnlyStatus) {
+	h.readOnly = readOnly
+}
+
+// addReadOnly adds read-only mode to a health or readiness response
+func (h *GatewayHandler) addReadOnly(c *fiber.Ctx, response fiber.Map) fiber.Map {
+	if h.readOnly == nil {
+		return response
+	}
+	mode := h.readOnly.Mode(c.UserContext())
+	if !mode.Enabled {
+		return response
+	}
+	response["read_only"] = true
+	if mode.Reason != nil {
+		response["read_only_reason"] = *mode.Reason
+	}
+	if mode.Since != nil {
+		response["read_only_since"] = *mode.Since
+	}
+	return response
+}
+
 // Connect handles WebSocket connection upgrade and delegates to Gateway
 func (h *GatewayHandler) Connect(conn *websocket.Conn) {
 	h.gateway.HandleConnection(conn)
@@ -127,25 +154,50 @@ func (h *GatewayHandler) GetStats(c *fiber.Ctx) error {
 	return c.JSON(h.gateway.GetStats())
 }
 
+// GetDrainStatus returns progress of connection draining during a deploy
+func (h *GatewayHandler) GetDrainStatus(c *fiber.Ctx) error {
+	return c.JSON(h.gateway.DrainProgress())
+}
+
+// GetMyConnections lists the current user's devices connected to the gateway
+func (h *GatewayHandler) GetMyConnections(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	if h.gateway == nil {
+		return c.JSON([]ws.Device{})
+	}
+	return c.JSON(h.gateway.UserDevices(userID))
+}
+
+// CloseConnection disconnects one of the current user's devices
+func (h *GatewayHandler) CloseConnection(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	if h.gateway == nil || !h.gateway.DisconnectDevice(userID, c.Params("id")) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "session not found",
+		})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
 // Health returns health status for load balancer
 // Returns 200 OK when healthy, 503 Service Unavailable when draining
 // This is the primary health check endpoint for Kubernetes readiness probes
 func (h *GatewayHandler) Health(c *fiber.Ctx) error {
 	if h.gateway == nil {
-		return c.JSON(fiber.Map{
+		return c.JSON(h.addReadOnly(c, fiber.Map{
 			"status":      "ok",
 			"drain_state": "healthy",
-		})
+		}))
 	}
 
 	drainState := h.gateway.DrainState()
 	isHealthy := h.gateway.IsHealthy()
 
-	response := fiber.Map{
+	response := h.addReadOnly(c, fiber.Map{
 		"status":      "ok",
 		"drain_state": drainState.String(),
 		"connections": h.gateway.GetActiveConnections(),
-	}
+	})
 
 	if !isHealthy {
 		response["status"] = "draining"
@@ -159,10 +211,17 @@ func (h *GatewayHandler) Health(c *fiber.Ctx) error {
 // Returns 200 OK when ready, 503 Service Unavailable when not ready or draining
 // Alias for /readyz Kubernetes-style endpoint
 func (h *GatewayHandler) ReadinessCheck(c *fiber.Ctx) error {
+	if h.warmup != nil && !h.warmup.Warmed() {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"ready":  false,
+			"reason": "warming_cache",
+		})
+	}
+
 	if h.gateway == nil {
-		return c.JSON(fiber.Map{
+		return c.JSON(h.addReadOnly(c, fiber.Map{
 			"ready": true,
-		})
+		}))
 	}
 
 	if h.gateway.IsDraining() {
@@ -173,10 +232,10 @@ func (h *GatewayHandler) ReadinessCheck(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(fiber.Map{
+	return c.JSON(h.addReadOnly(c, fiber.Map{
 		"ready":       true,
 		"connections": h.gateway.GetActiveConnections(),
-	})
+	}))
 }
 
 // LivenessCheck checks if the server is alive (basic health)
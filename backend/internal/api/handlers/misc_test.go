@@ -540,3 +540,59 @@ func TestGatewayHandler_GetStats_Custom(t *testing.T) {
 	assert.Equal(t, float64(1), result["active_sessions"])
 	assert.Equal(t, "custom_value", result["custom_field"])
 }
+
+type stubWarmup struct {
+	warmed bool
+}
+
+func (s *stubWarmup) Warmed() bool {
+	return s.warmed
+}
+
+func TestGatewayHandler_ReadinessCheck_WaitsForWarmup(t *testing.T) {
+	warmup := &stubWarmup{}
+	handler := NewGatewayHandler(nil)
+	handler.SetWarmup(warmup)
+	app := fiber.New()
+	app.Get("/readyz", handler.ReadinessCheck)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/readyz", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	assert.Equal(t, false, result["ready"])
+	assert.Equal(t, "warming_cache", result["reason"])
+
+	warmup.warmed = true
+	resp, err = app.Test(httptest.NewRequest("GET", "/readyz", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
+
+func TestGatewayHandler_ReadinessCheck_ReadOnly(t *testing.T) {
+	readOnly := &stubReadOnly{}
+	handler := NewGatewayHandler(nil)
+	handler.SetReadOnly(readOnly)
+	app := fiber.New()
+	app.Get("/readyz", handler.ReadinessCheck)
+	app.Get("/health", handler.Health)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/readyz", nil))
+	assert.NoError(t, err)
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	assert.NotContains(t, result, "read_only")
+
+	reason := "failover"
+	readOnly.mode = models.ReadOnlyMode{Enabled: true, Reason: &reason}
+	for _, path := range []string{"/readyz", "/health"} {
+		resp, err = app.Test(httptest.NewRequest("GET", path, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode, "%s: a read-only instance still serves reads", path)
+		result = nil
+		json.NewDecoder(resp.Body).Decode(&result)
+		assert.Equal(t, true, result["read_only"], path)
+		assert.Equal(t, "failover", result["read_only_reason"], path)
+	}
+}
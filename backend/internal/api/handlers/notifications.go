@@ -61,7 +61,7 @@ func (h *NotificationHandler) GetNotifications(c *fiber.Ctx) error {
 		opts.Unread = &unread
 	}
 
-	notifications, err := h.notificationService.ListNotifications(c.Context(), userID, opts)
+	notifications, err := h.notificationService.ListNotifications(c.UserContext(), userID, opts)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get notifications",
@@ -69,7 +69,7 @@ func (h *NotificationHandler) GetNotifications(c *fiber.Ctx) error {
 	}
 
 	// Get stats as well
-	stats, err := h.notificationService.GetNotificationStats(c.Context(), userID)
+	stats, err := h.notificationService.GetNotificationStats(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get notification stats",
@@ -94,7 +94,7 @@ func (h *NotificationHandler) GetNotification(c *fiber.Ctx) error {
 		})
 	}
 
-	notification, err := h.notificationService.GetNotification(c.Context(), notificationID, userID)
+	notification, err := h.notificationService.GetNotification(c.UserContext(), notificationID, userID)
 	if err != nil {
 		if err == services.ErrNotificationNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -120,7 +120,7 @@ func (h *NotificationHandler) MarkAsRead(c *fiber.Ctx) error {
 		})
 	}
 
-	err = h.notificationService.MarkAsRead(c.Context(), notificationID, userID)
+	err = h.notificationService.MarkAsRead(c.UserContext(), notificationID, userID)
 	if err != nil {
 		if err == services.ErrNotificationNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -139,7 +139,7 @@ func (h *NotificationHandler) MarkAsRead(c *fiber.Ctx) error {
 func (h *NotificationHandler) MarkAllAsRead(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
 
-	count, err := h.notificationService.MarkAllAsRead(c.Context(), userID)
+	count, err := h.notificationService.MarkAllAsRead(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to mark all notifications as read",
@@ -162,7 +162,7 @@ func (h *NotificationHandler) DeleteNotification(c *fiber.Ctx) error {
 		})
 	}
 
-	err = h.notificationService.DeleteNotification(c.Context(), notificationID, userID)
+	err = h.notificationService.DeleteNotification(c.UserContext(), notificationID, userID)
 	if err != nil {
 		if err == services.ErrNotificationNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -181,7 +181,7 @@ func (h *NotificationHandler) DeleteNotification(c *fiber.Ctx) error {
 func (h *NotificationHandler) DeleteAllRead(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
 
-	count, err := h.notificationService.DeleteAllReadNotifications(c.Context(), userID)
+	count, err := h.notificationService.DeleteAllReadNotifications(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to delete read notifications",
@@ -197,7 +197,7 @@ func (h *NotificationHandler) DeleteAllRead(c *fiber.Ctx) error {
 func (h *NotificationHandler) GetNotificationStats(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
 
-	stats, err := h.notificationService.GetNotificationStats(c.Context(), userID)
+	stats, err := h.notificationService.GetNotificationStats(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get notification stats",
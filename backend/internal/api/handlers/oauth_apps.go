@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// OAuthProviderServiceInterface defines the methods needed from
+// OAuthProviderService
+type OAuthProviderServiceInterface interface {
+	ListApplications(ctx context.Context, ownerID uuid.UUID) ([]*models.OAuthApplication, error)
+	CreateApplication(ctx context.Context, ownerID uuid.UUID, req *models.CreateOAuthApplicationRequest) (*models.OAuthApplication, error)
+	ResetClientSecret(ctx context.Context, ownerID, appID uuid.UUID) (*models.OAuthApplication, error)
+	DeleteApplication(ctx context.Context, ownerID, appID uuid.UUID) error
+	GetAuthorization(ctx context.Context, req *models.OAuthAuthorizeRequest) (*models.OAuthAuthorization, error)
+	Authorize(ctx context.Context, userID uuid.UUID, req *models.OAuthAuthorizeRequest) (string, error)
+	Exchange(ctx context.Context, req *models.OAuthTokenRequest) (*models.OAuthTokenResponse, error)
+	ListAuthorizedApplications(ctx context.Context, userID uuid.UUID) ([]*models.AuthorizedOAuthApplication, error)
+	RevokeAuthorization(ctx context.Context, userID, appID uuid.UUID) error
+}
+
+// OAuthAppHandler handles OAuth applications and the OAuth2 endpoints that
+// let them act for users
+type OAuthAppHandler struct {
+	oauth OAuthProviderServiceInterface
+}
+
+// NewOAuthAppHandler creates a new OAuth application handler
+func NewOAuthAppHandler(oauth OAuthProviderServiceInterface) *OAuthAppHandler {
+	return &OAuthAppHandler{oauth: oauth}
+}
+
+// ListApplications returns the user's applications, without their secrets
+// GET /applications
+func (h *OAuthAppHandler) ListApplications(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	apps, err := h.oauth.ListApplications(c.UserContext(), userID)
+	if err != nil {
+		return oauthAppError(c, err)
+	}
+	return c.JSON(apps)
+}
+
+// CreateApplication registers an application, returning its client secret
+// this once
+// POST /applications
+func (h *OAuthAppHandler) CreateApplication(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	var req models.CreateOAuthApplicationRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	app, err := h.oauth.CreateApplication(c.UserContext(), userID, &req)
+	if err != nil {
+		return oauthAppError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(app)
+}
+
+// ResetClientSecret gives an application a new client secret, returning it
+// this once
+// POST /applications/:id/secret
+func (h *OAuthAppHandler) ResetClientSecret(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	appID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid application id",
+		})
+	}
+
+	app, err := h.oauth.ResetClientSecret(c.UserContext(), userID, appID)
+	if err != nil {
+		return oauthAppError(c, err)
+	}
+	return c.JSON(app)
+}
+
+// DeleteApplication deletes an application and revokes its tokens
+// DELETE /applications/:id
+func (h *OAuthAppHandler) DeleteApplication(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	appID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid application id",
+		})
+	}
+
+	if err := h.oauth.DeleteApplication(c.UserContext(), userID, appID); err != nil {
+		return oauthAppError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListAuthorizedApplications returns the applications the user granted
+// access
+// GET /users/@me/applications
+func (h *OAuthAppHandler) ListAuthorizedApplications(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	apps, err := h.oauth.ListAuthorizedApplications(c.UserContext(), userID)
+	if err != nil {
+		return oauthAppError(c, err)
+	}
+	return c.JSON(apps)
+}
+
+// RevokeAuthorization takes back what the user granted an application
+// DELETE /users/@me/applications/:id
+func (h *OAuthAppHandler) RevokeAuthorization(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	appID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid application id",
+		})
+	}
+
+	if err := h.oauth.RevokeAuthorization(c.UserContext(), userID, appID); err != nil {
+		return oauthAppError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetAuthorization checks the query of an authorization URL and returns
+// what the user is asked to approve, for the consent screen
+// GET /oauth2/authorize
+func (h *OAuthAppHandler) GetAuthorization(c *fiber.Ctx) error {
+	var req models.OAuthAuthorizeRequest
+	if err := c.QueryParser(&req); err != nil {
+		return oauthError(c, services.ErrInvalidOAuthRequest)
+	}
+
+	authorization, err := h.oauth.GetAuthorization(c.UserContext(), &req)
+	if err != nil {
+		return oauthError(c, err)
+	}
+	return c.JSON(authorization)
+}
+
+// Authorize records the user's approval and returns where to send them
+// back to the application, with an authorization code
+// POST /oauth2/authorize
+func (h *OAuthAppHandler) Authorize(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	var req models.OAuthAuthorizeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return oauthError(c, services.ErrInvalidOAuthRequest)
+	}
+
+	location, err := h.oauth.Authorize(c.UserContext(), userID, &req)
+	if err != nil {
+		return oauthError(c, err)
+	}
+	return c.JSON(fiber.Map{"location": location})
+}
+
+// Token trades an authorization code or refresh token for tokens. Clients
+// authenticate with HTTP Basic auth or client_id and client_secret in the
+// form.
+// POST /oauth2/token
+func (h *OAuthAppHandler) Token(c *fiber.Ctx) error {
+	var req models.OAuthTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return oauthError(c, services.ErrInvalidOAuthRequest)
+	}
+	if clientID, secret, ok := basicClientCredentials(c.Get(fiber.HeaderAuthorization)); ok {
+		req.ClientID, req.ClientSecret = clientID, secret
+	}
+
+	tokens, err := h.oauth.Exchange(c.UserContext(), &req)
+	if err != nil {
+		return oauthError(c, err)
+	}
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(tokens)
+}
+
+// basicClientCredentials decodes client credentials sent with HTTP Basic
+// auth, which OAuth2 form-encodes before joining them
+func basicClientCredentials(header string) (string, string, bool) {
+	encoded, ok := strings.CutPrefix(header, "Basic ")
+	if !ok {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", false
+	}
+	rawID, rawSecret, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return "", "", false
+	}
+	clientID, err := url.QueryUnescape(rawID)
+	if err != nil {
+		return "", "", false
+	}
+	secret, err := url.QueryUnescape(rawSecret)
+	if err != nil {
+		return "", "", false
+	}
+	return clientID, secret, true
+}
+
+// oauthError answers the OAuth2 endpoints with the error codes OAuth2
+// clients expect
+func oauthError(c *fiber.Ctx, err error) error {
+	status, code := fiber.StatusBadRequest, ""
+	switch {
+	case errors.Is(err, services.ErrInvalidOAuthClient):
+		status, code = fiber.StatusUnauthorized, "invalid_client"
+	case errors.Is(err, services.ErrInvalidOAuthRequest):
+		code = "invalid_request"
+	case errors.Is(err, services.ErrInvalidOAuthScope):
+		code = "invalid_scope"
+	case errors.Is(err, services.ErrInvalidOAuthGrant):
+		code = "invalid_grant"
+	case errors.Is(err, services.ErrUnsupportedOAuthGrantType):
+		code = "unsupported_grant_type"
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "server_error",
+		})
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error":             code,
+		"error_description": err.Error(),
+	})
+}
+
+func oauthAppError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidOAuthApp), errors.Is(err, services.ErrTooManyOAuthApps):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrOAuthAppNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "failed to manage applications",
+	})
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// MockOAuthProviderService mocks the OAuthProviderService for testing
+type MockOAuthProviderService struct {
+	mock.Mock
+}
+
+func (m *MockOAuthProviderService) ListApplications(ctx context.Context, ownerID uuid.UUID) ([]*models.OAuthApplication, error) {
+	args := m.Called(ctx, ownerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.OAuthApplication), args.Error(1)
+}
+
+func (m *MockOAuthProviderService) CreateApplication(ctx context.Context, ownerID uuid.UUID, req *models.CreateOAuthApplicationRequest) (*models.OAuthApplication, error) {
+	args := m.Called(ctx, ownerID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.OAuthApplication), args.Error(1)
+}
+
+func (m *MockOAuthProviderService) ResetClientSecret(ctx context.Context, ownerID, appID uuid.UUID) (*models.OAuthApplication, error) {
+	args := m.Called(ctx, ownerID, appID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.OAuthApplication), args.Error(1)
+}
+
+func (m *MockOAuthProviderService) DeleteApplication(ctx context.Context, ownerID, appID uuid.UUID) error {
+	return m.Called(ctx, ownerID, appID).Error(0)
+}
+
+func (m *MockOAuthProviderService) GetAuthorization(ctx context.Context, req *models.OAuthAuthorizeRequest) (*models.OAuthAuthorization, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.OAuthAuthorization), args.Error(1)
+}
+
+func (m *MockOAuthProviderService) Authorize(ctx context.Context, userID uuid.UUID, req *models.OAuthAuthorizeRequest) (string, error) {
+	args := m.Called(ctx, userID, req)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockOAuthProviderService) Exchange(ctx context.Context, req *models.OAuthTokenRequest) (*models.OAuthTokenResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.OAuthTokenResponse), args.Error(1)
+}
+
+func (m *MockOAuthProviderService) ListAuthorizedApplications(ctx context.Context, userID uuid.UUID) ([]*models.AuthorizedOAuthApplication, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.AuthorizedOAuthApplication), args.Error(1)
+}
+
+func (m *MockOAuthProviderService) RevokeAuthorization(ctx context.Context, userID, appID uuid.UUID) error {
+	return m.Called(ctx, userID, appID).Error(0)
+}
+
+func newTestOAuthAppHandler() (*fiber.App, *MockOAuthProviderService, uuid.UUID) {
+	oauthService := new(MockOAuthProviderService)
+	handler := NewOAuthAppHandler(oauthService)
+	userID := uuid.New()
+
+	app := fiber.New()
+	app.Post("/oauth2/token", handler.Token)
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", userID)
+		return c.Next()
+	})
+	app.Get("/oauth2/authorize", handler.GetAuthorization)
+	app.Post("/oauth2/authorize", handler.Authorize)
+	app.Delete("/applications/:id", handler.DeleteApplication)
+
+	return app, oauthService, userID
+}
+
+func TestOAuthAppHandler_Token(t *testing.T) {
+	app, oauthService, _ := newTestOAuthAppHandler()
+	clientID := uuid.NewString()
+	oauthService.On("Exchange", mock.Anything, mock.MatchedBy(func(req *models.OAuthTokenRequest) bool {
+		return req.GrantType == "authorization_code" && req.Code == "abc" && req.ClientID == clientID && req.ClientSecret == "s3cr:t"
+	})).Return(&models.OAuthTokenResponse{AccessToken: models.OAuthAccessTokenPrefix + "x", TokenType: "Bearer", Scope: "identify"}, nil)
+	oauthService.On("Exchange", mock.Anything, mock.Anything).Return(nil, services.ErrInvalidOAuthGrant)
+
+	// Basic auth credentials are form-encoded before they're joined
+	req := httptest.NewRequest(http.MethodPost, "/oauth2/token", strings.NewReader("grant_type=authorization_code&code=abc"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(clientID, "s3cr%3At")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	var tokens models.OAuthTokenResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&tokens))
+	assert.Equal(t, "Bearer", tokens.TokenType)
+
+	req = httptest.NewRequest(http.MethodPost, "/oauth2/token", strings.NewReader("grant_type=authorization_code&code=used&client_id="+clientID+"&client_secret=s3cr%3At"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	var body map[string]string
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "invalid_grant", body["error"])
+}
+
+func TestOAuthAppHandler_Authorize(t *testing.T) {
+	app, oauthService, userID := newTestOAuthAppHandler()
+	clientID := uuid.NewString()
+	oauthService.On("GetAuthorization", mock.Anything, mock.MatchedBy(func(req *models.OAuthAuthorizeRequest) bool {
+		return req.ClientID == clientID && req.Scope == "identify servers.read"
+	})).Return(&models.OAuthAuthorization{Scopes: []string{"identify", "servers.read"}, RedirectURI: "https://bot.example.com/cb"}, nil)
+	oauthService.On("GetAuthorization", mock.Anything, mock.Anything).Return(nil, services.ErrInvalidOAuthClient)
+	oauthService.On("Authorize", mock.Anything, userID, mock.Anything).Return("https://bot.example.com/cb?code=abc", nil)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/oauth2/authorize?response_type=code&client_id="+clientID+"&scope=identify+servers.read", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/oauth2/authorize?client_id=nope", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+
+	req := httptest.NewRequest(http.MethodPost, "/oauth2/authorize", strings.NewReader(`{"client_id":"`+clientID+`","response_type":"code","scope":"identify"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var body map[string]string
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "https://bot.example.com/cb?code=abc", body["location"])
+}
+
+func TestOAuthAppHandler_DeleteApplication(t *testing.T) {
+	app, oauthService, userID := newTestOAuthAppHandler()
+	appID := uuid.New()
+	oauthService.On("DeleteApplication", mock.Anything, userID, appID).Return(nil)
+	oauthService.On("DeleteApplication", mock.Anything, userID, mock.Anything).Return(services.ErrOAuthAppNotFound)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodDelete, "/applications/"+appID.String(), nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodDelete, "/applications/"+uuid.NewString(), nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// OnboardingServiceInterface defines the methods needed from
+// OnboardingService
+type OnboardingServiceInterface interface {
+	GetWelcomeScreen(ctx context.Context, serverID, requesterID uuid.UUID) (*models.WelcomeScreen, error)
+	UpdateWelcomeScreen(ctx context.Context, serverID, requesterID uuid.UUID, req *models.UpdateWelcomeScreenRequest) (*models.WelcomeScreen, error)
+	GetOnboarding(ctx context.Context, serverID, requesterID uuid.UUID) (*models.Onboarding, error)
+	UpdateOnboarding(ctx context.Context, serverID, requesterID uuid.UUID, req *models.UpdateOnboardingRequest) (*models.Onboarding, error)
+	GetResponses(ctx context.Context, serverID, userID uuid.UUID) (*models.OnboardingResponses, error)
+	Submit(ctx context.Context, serverID, userID uuid.UUID, req *models.SubmitOnboardingRequest) (*models.Member, error)
+}
+
+// OnboardingHandler handles welcome screen and onboarding requests
+type OnboardingHandler struct {
+	onboarding OnboardingServiceInterface
+}
+
+// NewOnboardingHandler creates a new onboarding handler
+func NewOnboardingHandler(onboarding OnboardingServiceInterface) *OnboardingHandler {
+	return &OnboardingHandler{onboarding: onboarding}
+}
+
+// GetWelcomeScreen returns a server's welcome screen
+// GET /servers/:id/welcome-screen
+func (h *OnboardingHandler) GetWelcomeScreen(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	screen, err := h.onboarding.GetWelcomeScreen(c.UserContext(), serverID, userID)
+	if err != nil {
+		return onboardingError(c, err)
+	}
+	return c.JSON(screen)
+}
+
+// UpdateWelcomeScreen changes a server's welcome screen or turns it on or
+// off
+// PATCH /servers/:id/welcome-screen
+func (h *OnboardingHandler) UpdateWelcomeScreen(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	var req models.UpdateWelcomeScreenRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	screen, err := h.onboarding.UpdateWelcomeScreen(c.UserContext(), serverID, userID, &req)
+	if err != nil {
+		return onboardingError(c, err)
+	}
+	return c.JSON(screen)
+}
+
+// GetOnboarding returns a server's onboarding prompts
+// GET /servers/:id/onboarding
+func (h *OnboardingHandler) GetOnboarding(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	onboarding, err := h.onboarding.GetOnboarding(c.UserContext(), serverID, userID)
+	if err != nil {
+		return onboardingError(c, err)
+	}
+	return c.JSON(onboarding)
+}
+
+// UpdateOnboarding changes a server's onboarding prompts or turns them on
+// or off
+// PATCH /servers/:id/onboarding
+func (h *OnboardingHandler) UpdateOnboarding(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	var req models.UpdateOnboardingRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	onboarding, err := h.onboarding.UpdateOnboarding(c.UserContext(), serverID, userID, &req)
+	if err != nil {
+		return onboardingError(c, err)
+	}
+	return c.JSON(onboarding)
+}
+
+// GetResponses returns the options the caller picked
+// GET /servers/:id/onboarding/@me
+func (h *OnboardingHandler) GetResponses(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	responses, err := h.onboarding.GetResponses(c.UserContext(), serverID, userID)
+	if err != nil {
+		return onboardingError(c, err)
+	}
+	return c.JSON(responses)
+}
+
+// Submit answers a server's prompts, giving the caller the roles they
+// picked
+// PUT /servers/:id/onboarding/@me
+func (h *OnboardingHandler) Submit(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	var req models.SubmitOnboardingRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	member, err := h.onboarding.Submit(c.UserContext(), serverID, userID, &req)
+	if err != nil {
+		return onboardingError(c, err)
+	}
+	return c.JSON(member)
+}
+
+func onboardingError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidWelcomeScreen), errors.Is(err, services.ErrInvalidOnboarding),
+		errors.Is(err, services.ErrInvalidOnboardingResponse), errors.Is(err, services.ErrOnboardingDisabled):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrCannotManageOnboarding), errors.Is(err, services.ErrNotServerMember):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrServerNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "failed to manage onboarding",
+	})
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// MockOnboardingService mocks the OnboardingService for testing
+type MockOnboardingService struct {
+	mock.Mock
+}
+
+func (m *MockOnboardingService) GetWelcomeScreen(ctx context.Context, serverID, requesterID uuid.UUID) (*models.WelcomeScreen, error) {
+	args := m.Called(ctx, serverID, requesterID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WelcomeScreen), args.Error(1)
+}
+
+func (m *MockOnboardingService) UpdateWelcomeScreen(ctx context.Context, serverID, requesterID uuid.UUID, req *models.UpdateWelcomeScreenRequest) (*models.WelcomeScreen, error) {
+	args := m.Called(ctx, serverID, requesterID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WelcomeScreen), args.Error(1)
+}
+
+func (m *MockOnboardingService) GetOnboarding(ctx context.Context, serverID, requesterID uuid.UUID) (*models.Onboarding, error) {
+	args := m.Called(ctx, serverID, requesterID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Onboarding), args.Error(1)
+}
+
+func (m *MockOnboardingService) UpdateOnboarding(ctx context.Context, serverID, requesterID uuid.UUID, req *models.UpdateOnboardingRequest) (*models.Onboarding, error) {
+	args := m.Called(ctx, serverID, requesterID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Onboarding), args.Error(1)
+}
+
+func (m *MockOnboardingService) GetResponses(ctx context.Context, serverID, userID uuid.UUID) (*models.OnboardingResponses, error) {
+	args := m.Called(ctx, serverID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.OnboardingResponses), args.Error(1)
+}
+
+func (m *MockOnboardingService) Submit(ctx context.Context, serverID, userID uuid.UUID, req *models.SubmitOnboardingRequest) (*models.Member, error) {
+	args := m.Called(ctx, serverID, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Member), args.Error(1)
+}
+
+func newTestOnboardingHandler() (*fiber.App, *MockOnboardingService, uuid.UUID) {
+	onboardingService := new(MockOnboardingService)
+	handler := NewOnboardingHandler(onboardingService)
+	userID := uuid.New()
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", userID)
+		return c.Next()
+	})
+	app.Get("/servers/:id/welcome-screen", handler.GetWelcomeScreen)
+	app.Patch("/servers/:id/welcome-screen", handler.UpdateWelcomeScreen)
+	app.Get("/servers/:id/onboarding", handler.GetOnboarding)
+	app.Patch("/servers/:id/onboarding", handler.UpdateOnboarding)
+	app.Get("/servers/:id/onboarding/@me", handler.GetResponses)
+	app.Put("/servers/:id/onboarding/@me", handler.Submit)
+
+	return app, onboardingService, userID
+}
+
+func TestOnboardingHandler_Submit(t *testing.T) {
+	app, onboardingService, userID := newTestOnboardingHandler()
+	serverID, optionID, roleID := uuid.New(), uuid.New(), uuid.New()
+
+	onboardingService.On("Submit", mock.Anything, serverID, userID, mock.MatchedBy(func(req *models.SubmitOnboardingRequest) bool {
+		return len(req.OptionIDs) == 1 && req.OptionIDs[0] == optionID
+	})).Return(&models.Member{ServerID: serverID, UserID: userID, Roles: []uuid.UUID{roleID}}, nil)
+	onboardingService.On("Submit", mock.Anything, serverID, userID, mock.Anything).Return(nil, services.ErrInvalidOnboardingResponse)
+
+	body := `{"option_ids":["` + optionID.String() + `"]}`
+	req := httptest.NewRequest(http.MethodPut, "/servers/"+serverID.String()+"/onboarding/@me", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	req = httptest.NewRequest(http.MethodPut, "/servers/"+serverID.String()+"/onboarding/@me", bytes.NewReader([]byte(`{"option_ids":[]}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestOnboardingHandler_Errors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{services.ErrInvalidWelcomeScreen, fiber.StatusBadRequest},
+		{services.ErrCannotManageOnboarding, fiber.StatusForbidden},
+		{services.ErrServerNotFound, fiber.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		app, onboardingService, userID := newTestOnboardingHandler()
+		serverID := uuid.New()
+		onboardingService.On("UpdateWelcomeScreen", mock.Anything, serverID, userID, mock.Anything).Return(nil, tc.err)
+
+		req := httptest.NewRequest(http.MethodPatch, "/servers/"+serverID.String()+"/welcome-screen", bytes.NewReader([]byte(`{"enabled":true}`)))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, tc.status, resp.StatusCode, tc.err.Error())
+	}
+
+	app, onboardingService, userID := newTestOnboardingHandler()
+	serverID := uuid.New()
+	onboardingService.On("GetOnboarding", mock.Anything, serverID, userID).Return(nil, services.ErrNotServerMember)
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/servers/"+serverID.String()+"/onboarding", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+}
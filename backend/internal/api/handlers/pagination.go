@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"hearth/internal/models"
+)
+
+// pageRequest reads the cursor and limit query parameters of a
+// keyset-paginated list. It returns models.ErrInvalidPageCursor if the
+// cursor is malformed or wasn't handed out for list.
+func pageRequest(c *fiber.Ctx, list string, defaultLimit, maxLimit int) (models.PageRequest, error) {
+	req := models.PageRequest{Limit: c.QueryInt("limit", defaultLimit)}
+	if req.Limit < 1 {
+		req.Limit = defaultLimit
+	}
+	if req.Limit > maxLimit {
+		req.Limit = maxLimit
+	}
+
+	if token := c.Query("cursor"); token != "" {
+		cursor, err := models.DecodePageCursor(token, list)
+		if err != nil {
+			return req, err
+		}
+		req.Cursor = cursor
+	}
+	return req, nil
+}
+
+// invalidCursor answers a request whose cursor pageRequest refused
+func invalidCursor(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":   "invalid_cursor",
+		"message": "cursor is malformed or from another list",
+	})
+}
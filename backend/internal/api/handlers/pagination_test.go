@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+func TestPageRequest_RejectsTamperedCursors(t *testing.T) {
+	app := fiber.New()
+	app.Get("/members", func(c *fiber.Ctx) error {
+		req, err := pageRequest(c, models.PageMembers, 100, 1000)
+		if err != nil {
+			return invalidCursor(c)
+		}
+		return c.JSON(fiber.Map{"limit": req.Limit})
+	})
+
+	at := time.Now()
+	position := 2
+	for name, cursor := range map[string]models.PageCursor{
+		"key not uuid":  {List: models.PageMembers, Time: &at, Key: "1' OR '1'='1"},
+		"position sort": {List: models.PageMembers, Position: &position, Key: "00000000-0000-0000-0000-000000000000"},
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", "/members?cursor="+cursor.Encode(), nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, name)
+
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "invalid_cursor", body["error"], name)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/members?limit=5000", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
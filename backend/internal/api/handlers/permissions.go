@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/services"
+)
+
+// PermissionServiceInterface defines the methods needed from PermissionService
+type PermissionServiceInterface interface {
+	ChannelPermissions(ctx context.Context, channelID, userID uuid.UUID) (int64, error)
+}
+
+// PermissionsHandler handles effective permission requests
+type PermissionsHandler struct {
+	permissionService PermissionServiceInterface
+}
+
+// NewPermissionsHandler creates a new permissions handler
+func NewPermissionsHandler(permissionService PermissionServiceInterface) *PermissionsHandler {
+	return &PermissionsHandler{permissionService: permissionService}
+}
+
+// GetMyChannelPermissions returns the current user's effective permissions
+// in a channel
+// GET /channels/:id/permissions/@me
+func (h *PermissionsHandler) GetMyChannelPermissions(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	permissions, err := h.permissionService.ChannelPermissions(c.UserContext(), channelID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrChannelNotFound), errors.Is(err, services.ErrServerNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "channel not found",
+			})
+		case errors.Is(err, services.ErrNotServerMember), errors.Is(err, services.ErrNotChannelMember):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to get permissions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"channel_id":  channelID,
+		"permissions": permissions,
+	})
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// MockPermissionService mocks the PermissionService for testing
+type MockPermissionService struct {
+	mock.Mock
+}
+
+func (m *MockPermissionService) ChannelPermissions(ctx context.Context, channelID, userID uuid.UUID) (int64, error) {
+	args := m.Called(ctx, channelID, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func newTestPermissionsHandler() (*fiber.App, *MockPermissionService, uuid.UUID) {
+	permissionService := new(MockPermissionService)
+	handler := NewPermissionsHandler(permissionService)
+	userID := uuid.New()
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", userID)
+		return c.Next()
+	})
+	app.Get("/channels/:id/permissions/@me", handler.GetMyChannelPermissions)
+
+	return app, permissionService, userID
+}
+
+func TestPermissionsHandler_GetMyChannelPermissions(t *testing.T) {
+	app, permissionService, userID := newTestPermissionsHandler()
+	channelID := uuid.New()
+	perms := models.PermViewChannels | models.PermSendMessages
+	permissionService.On("ChannelPermissions", mock.Anything, channelID, userID).Return(perms, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/channels/"+channelID.String()+"/permissions/@me", nil)
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result struct {
+		ChannelID   uuid.UUID `json:"channel_id"`
+		Permissions int64     `json:"permissions"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	assert.Equal(t, channelID, result.ChannelID)
+	assert.Equal(t, perms, result.Permissions)
+}
+
+func TestPermissionsHandler_GetMyChannelPermissions_Errors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{services.ErrChannelNotFound, http.StatusNotFound},
+		{services.ErrNotServerMember, http.StatusForbidden},
+		{services.ErrNotChannelMember, http.StatusForbidden},
+		{errors.New("database down"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			app, permissionService, _ := newTestPermissionsHandler()
+			permissionService.On("ChannelPermissions", mock.Anything, mock.Anything, mock.Anything).Return(int64(0), tt.err)
+
+			req := httptest.NewRequest(http.MethodGet, "/channels/"+uuid.NewString()+"/permissions/@me", nil)
+			resp, err := app.Test(req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+
+	app, _, _ := newTestPermissionsHandler()
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/channels/nope/permissions/@me", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -35,15 +35,15 @@ func NewPollHandler(pollService *services.PollService) *PollHandler {
 
 // CreatePollRequest represents the request body for creating a poll
 type CreatePollRequest struct {
-	Question   string   `json:"question"`
-	Options    []string `json:"options"`
+	Question   string   `json:"question" validate:"required"`
+	Options    []string `json:"options" validate:"min=2,max=10,dive,required"`
 	IsMultiple bool     `json:"is_multiple"`
 	EndTime    *string  `json:"end_time,omitempty"` // ISO 8601 format
 }
 
 // VoteRequest represents the request body for voting on a poll
 type VoteRequest struct {
-	OptionID string `json:"option_id"`
+	OptionID string `json:"option_id" validate:"required,uuid"`
 }
 
 // CreatePoll creates a new poll in a channel
@@ -57,29 +57,8 @@ func (h *PollHandler) CreatePoll(c *fiber.Ctx) error {
 	}
 
 	var req CreatePollRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
-		})
-	}
-
-	// Validate request
-	if req.Question == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "question is required",
-		})
-	}
-
-	if len(req.Options) < 2 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "at least 2 options are required",
-		})
-	}
-
-	if len(req.Options) > 10 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "maximum 10 options allowed",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
 	// Parse end time if provided
@@ -125,7 +104,7 @@ func (h *PollHandler) CreatePoll(c *fiber.Ctx) error {
 		poll.Options[i].PollID = poll.ID
 	}
 
-	if err := h.pollService.CreatePoll(c.Context(), poll); err != nil {
+	if err := h.pollService.CreatePoll(c.UserContext(), poll); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to create poll",
 		})
@@ -143,7 +122,7 @@ func (h *PollHandler) GetPoll(c *fiber.Ctx) error {
 		})
 	}
 
-	poll, err := h.pollService.GetPoll(c.Context(), pollID)
+	poll, err := h.pollService.GetPoll(c.UserContext(), pollID)
 	if err != nil {
 		if err.Error() == "invalid poll ID" {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -169,20 +148,13 @@ func (h *PollHandler) Vote(c *fiber.Ctx) error {
 	}
 
 	var req VoteRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
-	optionID, err := uuid.Parse(req.OptionID)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid option id",
-		})
-	}
+	optionID := uuid.MustParse(req.OptionID) // Validated as a UUID
 
-	if err := h.pollService.Vote(c.Context(), pollID, optionID, userID); err != nil {
+	if err := h.pollService.Vote(c.UserContext(), pollID, optionID, userID); err != nil {
 		if err.Error() == "user has already voted on this poll" {
 			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 				"error": "you have already voted on this poll",
@@ -213,7 +185,7 @@ func (h *PollHandler) GetResults(c *fiber.Ctx) error {
 		})
 	}
 
-	poll, err := h.pollService.GetPoll(c.Context(), pollID)
+	poll, err := h.pollService.GetPoll(c.UserContext(), pollID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "poll not found",
@@ -262,7 +234,7 @@ func (h *PollHandler) ClosePoll(c *fiber.Ctx) error {
 	}
 
 	// Get the poll to verify ownership
-	poll, err := h.pollService.GetPoll(c.Context(), pollID)
+	poll, err := h.pollService.GetPoll(c.UserContext(), pollID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "poll not found",
@@ -288,7 +260,7 @@ func (h *PollHandler) ClosePoll(c *fiber.Ctx) error {
 	poll.EndTime = &now
 	poll.UpdatedAt = now
 
-	if err := h.pollService.UpdatePoll(c.Context(), poll); err != nil {
+	if err := h.pollService.UpdatePoll(c.UserContext(), poll); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to close poll",
 		})
@@ -312,7 +284,7 @@ func (h *PollHandler) GetChannelPolls(c *fiber.Ctx) error {
 
 	// Note: Using GetGuildPolls as it's already implemented
 	// In a real implementation, we'd have GetByChannelID
-	polls, err := h.pollService.GetGuildPolls(c.Context(), channelID)
+	polls, err := h.pollService.GetGuildPolls(c.UserContext(), channelID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get polls",
@@ -333,7 +305,7 @@ func (h *PollHandler) DeletePoll(c *fiber.Ctx) error {
 	}
 
 	// Get the poll to verify ownership
-	poll, err := h.pollService.GetPoll(c.Context(), pollID)
+	poll, err := h.pollService.GetPoll(c.UserContext(), pollID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "poll not found",
@@ -347,7 +319,7 @@ func (h *PollHandler) DeletePoll(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.pollService.DeletePoll(c.Context(), pollID); err != nil {
+	if err := h.pollService.DeletePoll(c.UserContext(), pollID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to delete poll",
 		})
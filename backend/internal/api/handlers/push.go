@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// PushServiceInterface defines the methods needed from PushService
+type PushServiceInterface interface {
+	RegisterDevice(ctx context.Context, userID uuid.UUID, req *models.RegisterDeviceRequest) (*models.PushDevice, error)
+	ListDevices(ctx context.Context, userID uuid.UUID) ([]*models.PushDevice, error)
+	UnregisterDevice(ctx context.Context, userID, deviceID uuid.UUID) error
+	GetChannelSettings(ctx context.Context, userID, channelID uuid.UUID) (*models.ChannelNotificationSettings, error)
+	UpdateChannelSettings(ctx context.Context, userID, channelID uuid.UUID, req *models.UpdateChannelNotificationSettingsRequest) (*models.ChannelNotificationSettings, error)
+}
+
+// PushHandler handles push device and channel notification settings requests
+type PushHandler struct {
+	pushService PushServiceInterface
+	// vapidPublicKey is the key browsers subscribe to WebPush with
+	vapidPublicKey string
+}
+
+// NewPushHandler creates a new push handler. vapidPublicKey may be empty
+// when WebPush isn't configured.
+func NewPushHandler(pushService PushServiceInterface, vapidPublicKey string) *PushHandler {
+	return &PushHandler{
+		pushService:    pushService,
+		vapidPublicKey: vapidPublicKey,
+	}
+}
+
+// RegisterDevice registers a device for push notifications
+// POST /users/@me/devices
+func (h *PushHandler) RegisterDevice(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	var req models.RegisterDeviceRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	device, err := h.pushService.RegisterDevice(c.UserContext(), userID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPushPlatform) || errors.Is(err, services.ErrInvalidPushToken) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to register device",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(device)
+}
+
+// ListDevices returns the current user's registered devices
+// GET /users/@me/devices
+func (h *PushHandler) ListDevices(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	devices, err := h.pushService.ListDevices(c.UserContext(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to get devices",
+		})
+	}
+	if devices == nil {
+		devices = []*models.PushDevice{}
+	}
+
+	return c.JSON(devices)
+}
+
+// UnregisterDevice removes one of the current user's devices
+// DELETE /users/@me/devices/:id
+func (h *PushHandler) UnregisterDevice(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	deviceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid device id",
+		})
+	}
+
+	if err := h.pushService.UnregisterDevice(c.UserContext(), userID, deviceID); err != nil {
+		if errors.Is(err, services.ErrPushDeviceNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "device not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to remove device",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetVAPIDKey returns the public key browsers need to subscribe to WebPush
+// GET /users/@me/devices/vapid-key
+func (h *PushHandler) GetVAPIDKey(c *fiber.Ctx) error {
+	if h.vapidPublicKey == "" {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "webpush is not enabled",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"public_key": h.vapidPublicKey,
+	})
+}
+
+// GetChannelSettings returns the current user's notification settings for a channel
+// GET /users/@me/channels/:id/notification-settings
+func (h *PushHandler) GetChannelSettings(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	settings, err := h.pushService.GetChannelSettings(c.UserContext(), userID, channelID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to get notification settings",
+		})
+	}
+
+	return c.JSON(settings)
+}
+
+// UpdateChannelSettings replaces the current user's notification settings for a channel
+// PUT /users/@me/channels/:id/notification-settings
+func (h *PushHandler) UpdateChannelSettings(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	var req models.UpdateChannelNotificationSettingsRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	settings, err := h.pushService.UpdateChannelSettings(c.UserContext(), userID, channelID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidNotificationLevel):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrChannelNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "channel not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to update notification settings",
+		})
+	}
+
+	return c.JSON(settings)
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// MockPushService mocks the PushService for testing
+type MockPushService struct {
+	mock.Mock
+}
+
+func (m *MockPushService) RegisterDevice(ctx context.Context, userID uuid.UUID, req *models.RegisterDeviceRequest) (*models.PushDevice, error) {
+	args := m.Called(ctx, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PushDevice), args.Error(1)
+}
+
+func (m *MockPushService) ListDevices(ctx context.Context, userID uuid.UUID) ([]*models.PushDevice, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.PushDevice), args.Error(1)
+}
+
+func (m *MockPushService) UnregisterDevice(ctx context.Context, userID, deviceID uuid.UUID) error {
+	args := m.Called(ctx, userID, deviceID)
+	return args.Error(0)
+}
+
+func (m *MockPushService) GetChannelSettings(ctx context.Context, userID, channelID uuid.UUID) (*models.ChannelNotificationSettings, error) {
+	args := m.Called(ctx, userID, channelID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChannelNotificationSettings), args.Error(1)
+}
+
+func (m *MockPushService) UpdateChannelSettings(ctx context.Context, userID, channelID uuid.UUID, req *models.UpdateChannelNotificationSettingsRequest) (*models.ChannelNotificationSettings, error) {
+	args := m.Called(ctx, userID, channelID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChannelNotificationSettings), args.Error(1)
+}
+
+func newTestPushHandler(vapidKey string) (*fiber.App, *MockPushService, uuid.UUID) {
+	pushService := new(MockPushService)
+	handler := NewPushHandler(pushService, vapidKey)
+	userID := uuid.New()
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", userID)
+		return c.Next()
+	})
+	app.Post("/users/@me/devices", handler.RegisterDevice)
+	app.Get("/users/@me/devices", handler.ListDevices)
+	app.Get("/users/@me/devices/vapid-key", handler.GetVAPIDKey)
+	app.Delete("/users/@me/devices/:id", handler.UnregisterDevice)
+	app.Get("/users/@me/channels/:id/notification-settings", handler.GetChannelSettings)
+	app.Put("/users/@me/channels/:id/notification-settings", handler.UpdateChannelSettings)
+
+	return app, pushService, userID
+}
+
+func TestPushHandler_RegisterDevice(t *testing.T) {
+	app, pushService, userID := newTestPushHandler("")
+
+	device := &models.PushDevice{ID: uuid.New(), UserID: userID, Platform: models.PushPlatformFCM, Token: "secret-token"}
+	pushService.On("RegisterDevice", mock.Anything, userID, mock.MatchedBy(func(req *models.RegisterDeviceRequest) bool {
+		return req.Platform == models.PushPlatformFCM && req.Token == "secret-token"
+	})).Return(device, nil)
+
+	body, _ := json.Marshal(map[string]string{"platform": "fcm", "token": "secret-token"})
+	req := httptest.NewRequest(http.MethodPost, "/users/@me/devices", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	assert.Equal(t, device.ID.String(), result["id"])
+	assert.NotContains(t, result, "token", "tokens are never echoed back")
+}
+
+func TestPushHandler_RegisterDevice_Invalid(t *testing.T) {
+	app, pushService, userID := newTestPushHandler("")
+
+	pushService.On("RegisterDevice", mock.Anything, userID, mock.Anything).Return(nil, services.ErrInvalidPushPlatform)
+
+	body, _ := json.Marshal(map[string]string{"platform": "sms", "token": "x"})
+	req := httptest.NewRequest(http.MethodPost, "/users/@me/devices", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestPushHandler_ListDevices(t *testing.T) {
+	app, pushService, userID := newTestPushHandler("")
+
+	pushService.On("ListDevices", mock.Anything, userID).Return(nil, nil)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/users/@me/devices", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result []interface{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.NotNil(t, result)
+	assert.Empty(t, result)
+}
+
+func TestPushHandler_UnregisterDevice(t *testing.T) {
+	app, pushService, userID := newTestPushHandler("")
+	deviceID := uuid.New()
+	missingID := uuid.New()
+
+	pushService.On("UnregisterDevice", mock.Anything, userID, deviceID).Return(nil)
+	pushService.On("UnregisterDevice", mock.Anything, userID, missingID).Return(services.ErrPushDeviceNotFound)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodDelete, "/users/@me/devices/"+deviceID.String(), nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodDelete, "/users/@me/devices/"+missingID.String(), nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodDelete, "/users/@me/devices/not-a-uuid", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestPushHandler_GetVAPIDKey(t *testing.T) {
+	app, _, _ := newTestPushHandler("BPublicKey")
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/users/@me/devices/vapid-key", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result map[string]string
+	json.NewDecoder(resp.Body).Decode(&result)
+	assert.Equal(t, "BPublicKey", result["public_key"])
+
+	app, _, _ = newTestPushHandler("")
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/users/@me/devices/vapid-key", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestPushHandler_ChannelSettings(t *testing.T) {
+	app, pushService, userID := newTestPushHandler("")
+	channelID := uuid.New()
+
+	pushService.On("GetChannelSettings", mock.Anything, userID, channelID).Return(&models.ChannelNotificationSettings{
+		UserID: userID, ChannelID: channelID, Level: models.NotificationLevelDefault,
+	}, nil)
+	pushService.On("UpdateChannelSettings", mock.Anything, userID, channelID, mock.MatchedBy(func(req *models.UpdateChannelNotificationSettingsRequest) bool {
+		return req.Level == models.NotificationLevelMentions
+	})).Return(&models.ChannelNotificationSettings{
+		UserID: userID, ChannelID: channelID, Level: models.NotificationLevelMentions,
+	}, nil)
+	pushService.On("UpdateChannelSettings", mock.Anything, userID, channelID, mock.Anything).Return(nil, services.ErrInvalidNotificationLevel)
+
+	path := "/users/@me/channels/" + channelID.String() + "/notification-settings"
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	req := httptest.NewRequest(http.MethodPut, path, bytes.NewReader([]byte(`{"level":"mentions"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	assert.Equal(t, "mentions", result["level"])
+
+	req = httptest.NewRequest(http.MethodPut, path, bytes.NewReader([]byte(`{"level":"loud"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
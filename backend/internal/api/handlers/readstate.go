@@ -16,6 +16,7 @@ type ReadStateServiceInterface interface {
 	GetChannelReadState(ctx context.Context, userID, channelID uuid.UUID) (*models.ReadState, error)
 	GetChannelUnreadInfo(ctx context.Context, userID, channelID uuid.UUID) (*models.ChannelUnreadInfo, error)
 	GetUnreadSummary(ctx context.Context, userID uuid.UUID) (*models.UnreadSummary, error)
+	GetReadStates(ctx context.Context, userID uuid.UUID) ([]models.ReadStateWithUnread, error)
 	GetServerUnreadSummary(ctx context.Context, userID, serverID uuid.UUID) (*models.UnreadSummary, error)
 	MarkServerAsRead(ctx context.Context, userID, serverID uuid.UUID) error
 }
@@ -46,21 +47,66 @@ func (h *ReadStateHandler) MarkChannelAsRead(c *fiber.Ctx) error {
 
 	// Parse optional message ID from body
 	var req models.MarkReadRequest
-	if err := c.BodyParser(&req); err != nil && len(c.Body()) > 0 {
+	if len(c.Body()) > 0 {
+		if err := parseBody(c, &req); err != nil {
+			return invalidBody(c, err)
+		}
+	}
+
+	ack, err := h.readStateService.MarkChannelAsRead(c.UserContext(), userID, channelID, req.MessageID)
+	if err != nil {
+		switch err {
+		case services.ErrChannelNotFound, services.ErrMessageNotFound:
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case services.ErrNotChannelMember:
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to mark channel as read",
+		})
+	}
+
+	return c.JSON(ack)
+}
+
+// AckMessage marks a channel as read up to a specific message, which must
+// be in the channel
+// POST /channels/:id/messages/:messageId/ack
+func (h *ReadStateHandler) AckMessage(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
+			"error": "invalid channel id",
 		})
 	}
 
-	ack, err := h.readStateService.MarkChannelAsRead(c.Context(), userID, channelID, req.MessageID)
+	messageID, err := uuid.Parse(c.Params("messageId"))
 	if err != nil {
-		if err == services.ErrChannelNotFound {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid message id",
+		})
+	}
+
+	ack, err := h.readStateService.MarkChannelAsRead(c.UserContext(), userID, channelID, &messageID)
+	if err != nil {
+		switch err {
+		case services.ErrChannelNotFound, services.ErrMessageNotFound:
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "channel not found",
+				"error": err.Error(),
+			})
+		case services.ErrNotChannelMember:
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to mark channel as read",
+			"error": "failed to acknowledge message",
 		})
 	}
 
@@ -79,7 +125,7 @@ func (h *ReadStateHandler) GetChannelUnread(c *fiber.Ctx) error {
 		})
 	}
 
-	info, err := h.readStateService.GetChannelUnreadInfo(c.Context(), userID, channelID)
+	info, err := h.readStateService.GetChannelUnreadInfo(c.UserContext(), userID, channelID)
 	if err != nil {
 		if err == services.ErrChannelNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -99,7 +145,7 @@ func (h *ReadStateHandler) GetChannelUnread(c *fiber.Ctx) error {
 func (h *ReadStateHandler) GetUnreadSummary(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
 
-	summary, err := h.readStateService.GetUnreadSummary(c.Context(), userID)
+	summary, err := h.readStateService.GetUnreadSummary(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get unread summary",
@@ -109,6 +155,22 @@ func (h *ReadStateHandler) GetUnreadSummary(c *fiber.Ctx) error {
 	return c.JSON(summary)
 }
 
+// GetReadStates lists the user's read state for every channel they have
+// read, with unread and mention counts
+// GET /users/@me/read-states
+func (h *ReadStateHandler) GetReadStates(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	states, err := h.readStateService.GetReadStates(c.UserContext(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to get read states",
+		})
+	}
+
+	return c.JSON(states)
+}
+
 // GetServerUnread gets the unread summary for a server
 // GET /servers/:id/unread
 func (h *ReadStateHandler) GetServerUnread(c *fiber.Ctx) error {
@@ -121,7 +183,7 @@ func (h *ReadStateHandler) GetServerUnread(c *fiber.Ctx) error {
 		})
 	}
 
-	summary, err := h.readStateService.GetServerUnreadSummary(c.Context(), userID, serverID)
+	summary, err := h.readStateService.GetServerUnreadSummary(c.UserContext(), userID, serverID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get server unread summary",
@@ -143,7 +205,7 @@ func (h *ReadStateHandler) MarkServerAsRead(c *fiber.Ctx) error {
 		})
 	}
 
-	err = h.readStateService.MarkServerAsRead(c.Context(), userID, serverID)
+	err = h.readStateService.MarkServerAsRead(c.UserContext(), userID, serverID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to mark server as read",
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// ReportCreator defines the methods needed from services.ReportService
+type ReportCreator interface {
+	Create(ctx context.Context, reporterID uuid.UUID, req *models.CreateReportRequest) (*models.Report, error)
+}
+
+// ReportHandler lets users report messages, users and servers to the
+// instance's admins
+type ReportHandler struct {
+	reports ReportCreator
+}
+
+// NewReportHandler creates a new report handler
+func NewReportHandler(reports ReportCreator) *ReportHandler {
+	return &ReportHandler{reports: reports}
+}
+
+// CreateReport files a report for the instance's admins to review
+// POST /reports
+func (h *ReportHandler) CreateReport(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	var req models.CreateReportRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	report, err := h.reports.Create(c.UserContext(), userID, &req)
+	if err != nil {
+		return reportError(c, err)
+	}
+	// Reporters only learn the report was filed, not what was recorded
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"id":          report.ID,
+		"target_type": report.TargetType,
+		"target_id":   report.TargetID,
+		"category":    report.Category,
+		"status":      report.Status,
+		"created_at":  report.CreatedAt,
+	})
+}
+
+func reportError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidReport), errors.Is(err, services.ErrInvalidReportTransition),
+		errors.Is(err, services.ErrInvalidReportAssignee):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrReportNotFound), errors.Is(err, services.ErrMessageNotFound),
+		errors.Is(err, services.ErrUserNotFound), errors.Is(err, services.ErrServerNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrReportExists), errors.Is(err, services.ErrReportConflict):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrTooManyReports):
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to manage reports"})
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// stubReports keeps reports in memory and checks only what the handlers
+// map to status codes
+type stubReports struct {
+	reports map[uuid.UUID]*models.Report
+	filter  models.ReportFilter
+}
+
+func newStubReports() *stubReports {
+	return &stubReports{reports: make(map[uuid.UUID]*models.Report)}
+}
+
+func (s *stubReports) Create(ctx context.Context, reporterID uuid.UUID, req *models.CreateReportRequest) (*models.Report, error) {
+	if !req.Category.Valid() {
+		return nil, services.ErrInvalidReport
+	}
+	if req.TargetID == reporterID {
+		return nil, services.ErrReportExists
+	}
+	content := "secret"
+	report := &models.Report{ID: uuid.New(), ReporterID: &reporterID, TargetType: req.TargetType, TargetID: req.TargetID, Category: req.Category, Status: models.ReportOpen, MessageContent: &content}
+	s.reports[report.ID] = report
+	return report, nil
+}
+
+func (s *stubReports) List(ctx context.Context, filter models.ReportFilter) ([]*models.Report, error) {
+	s.filter = filter
+	reports := []*models.Report{}
+	for _, report := range s.reports {
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func (s *stubReports) get(id uuid.UUID) (*models.Report, error) {
+	report, ok := s.reports[id]
+	if !ok {
+		return nil, services.ErrReportNotFound
+	}
+	return report, nil
+}
+
+func (s *stubReports) Get(ctx context.Context, id uuid.UUID) (*models.ReportDetail, error) {
+	report, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ReportDetail{Report: report, Events: []*models.ReportEvent{}}, nil
+}
+
+func (s *stubReports) UpdateStatus(ctx context.Context, adminID, id uuid.UUID, req *models.UpdateReportStatusRequest) (*models.Report, error) {
+	report, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status == models.ReportActioned && report.Status == models.ReportOpen {
+		return nil, services.ErrInvalidReportTransition
+	}
+	report.Status = req.Status
+	report.AssigneeID = &adminID
+	return report, nil
+}
+
+func (s *stubReports) Assign(ctx context.Context, adminID, id, assigneeID uuid.UUID) (*models.Report, error) {
+	report, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+	report.AssigneeID = &assigneeID
+	return report, nil
+}
+
+func (s *stubReports) Unassign(ctx context.Context, adminID, id uuid.UUID) (*models.Report, error) {
+	report, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+	report.AssigneeID = nil
+	return report, nil
+}
+
+func (s *stubReports) AddNote(ctx context.Context, adminID, id uuid.UUID, note string) (*models.ReportEvent, error) {
+	if _, err := s.get(id); err != nil {
+		return nil, err
+	}
+	return &models.ReportEvent{ID: uuid.New(), ReportID: id, ActorID: &adminID, Action: models.ReportActionNote, Note: &note}, nil
+}
+
+func TestReportHandler_CreateReport(t *testing.T) {
+	userID := uuid.New()
+	h := NewReportHandler(newStubReports())
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", userID)
+		return c.Next()
+	})
+	app.Post("/reports", h.CreateReport)
+
+	create := func(body string) *http.Response {
+		req := httptest.NewRequest("POST", "/reports", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := create(`{"target_type":"message","target_id":"` + uuid.NewString() + `","category":"spam","details":"again"}`)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	var created map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	assert.Equal(t, "open", created["status"])
+	assert.NotContains(t, created, "message_content", "reporters don't see what was recorded")
+
+	assert.Equal(t, fiber.StatusBadRequest, create(`{"target_type":"message","target_id":"`+uuid.NewString()+`","category":"rude"}`).StatusCode)
+	assert.Equal(t, fiber.StatusConflict, create(`{"target_type":"user","target_id":"`+userID.String()+`","category":"spam"}`).StatusCode)
+	assert.Equal(t, fiber.StatusBadRequest, create(`{"target_type":`).StatusCode)
+}
@@ -29,19 +29,17 @@ func (h *RoleHandlers) CreateRole(c *fiber.Ctx) error {
 	}
 
 	var req struct {
-		Name        string `json:"name"`
-		Color       int    `json:"color"`
+		Name        string `json:"name" validate:"max=100"`
+		Color       int    `json:"color" validate:"min=0,max=16777215"`
 		Permissions int64  `json:"permissions"`
 		Hoist       bool   `json:"hoist"`
 		Mentionable bool   `json:"mentionable"`
 	}
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
-	role, err := h.roleService.CreateRole(c.Context(), serverID, userID, req.Name, req.Color, req.Permissions)
+	role, err := h.roleService.CreateRole(c.UserContext(), serverID, userID, req.Name, req.Color, req.Permissions)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -61,7 +59,7 @@ func (h *RoleHandlers) GetRoles(c *fiber.Ctx) error {
 		})
 	}
 
-	roles, err := h.roleService.GetServerRoles(c.Context(), serverID, userID)
+	roles, err := h.roleService.GetServerRoles(c.UserContext(), serverID, userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -98,17 +96,15 @@ func (h *RoleHandlers) UpdateRole(c *fiber.Ctx) error {
 	}
 
 	var req struct {
-		Name        *string `json:"name,omitempty"`
-		Color       *int    `json:"color,omitempty"`
+		Name        *string `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+		Color       *int    `json:"color,omitempty" validate:"omitempty,min=0,max=16777215"`
 		Permissions *int64  `json:"permissions,omitempty"`
 		Hoist       *bool   `json:"hoist,omitempty"`
 		Mentionable *bool   `json:"mentionable,omitempty"`
 		Position    *int    `json:"position,omitempty"`
 	}
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
 	updates := &models.RoleUpdate{
@@ -120,7 +116,7 @@ func (h *RoleHandlers) UpdateRole(c *fiber.Ctx) error {
 		Position:    req.Position,
 	}
 
-	role, err := h.roleService.UpdateRole(c.Context(), roleID, userID, updates)
+	role, err := h.roleService.UpdateRole(c.UserContext(), roleID, userID, updates)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -140,7 +136,7 @@ func (h *RoleHandlers) DeleteRole(c *fiber.Ctx) error {
 		})
 	}
 
-	err = h.roleService.DeleteRole(c.Context(), roleID, userID)
+	err = h.roleService.DeleteRole(c.UserContext(), roleID, userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -172,7 +168,7 @@ func (h *RoleHandlers) AddMemberRole(c *fiber.Ctx) error {
 		})
 	}
 
-	err = h.roleService.AddRoleToMember(c.Context(), serverID, memberID, roleID, userID)
+	err = h.roleService.AddRoleToMember(c.UserContext(), serverID, memberID, roleID, userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -204,7 +200,7 @@ func (h *RoleHandlers) RemoveMemberRole(c *fiber.Ctx) error {
 		})
 	}
 
-	err = h.roleService.RemoveRoleFromMember(c.Context(), serverID, memberID, roleID, userID)
+	err = h.roleService.RemoveRoleFromMember(c.UserContext(), serverID, memberID, roleID, userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
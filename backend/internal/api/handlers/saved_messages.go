@@ -41,33 +41,13 @@ func (h *SavedMessagesHandler) SaveMessage(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
 
 	var req models.SaveMessageRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
-	if req.MessageID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "message_id is required",
-		})
-	}
+	messageID := uuid.MustParse(req.MessageID) // Validated as a UUID
 
-	messageID, err := uuid.Parse(req.MessageID)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid message_id format",
-		})
-	}
-
-	// Validate note length if provided
-	if req.Note != nil && len(*req.Note) > 500 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "note must be 500 characters or less",
-		})
-	}
-
-	saved, err := h.service.SaveMessage(c.Context(), userID, messageID, req.Note)
+	saved, err := h.service.SaveMessage(c.UserContext(), userID, messageID, req.Note)
 	if err != nil {
 		if errors.Is(err, services.ErrMessageNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -106,12 +86,15 @@ func (h *SavedMessagesHandler) GetSavedMessages(c *fiber.Ctx) error {
 		opts.Limit = limit
 	}
 
-	saved, err := h.service.GetSavedMessages(c.Context(), userID, opts)
+	saved, err := h.service.GetSavedMessages(c.UserContext(), userID, opts)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get saved messages",
 		})
 	}
+	if saved == nil {
+		saved = []*models.SavedMessage{}
+	}
 
 	return c.JSON(saved)
 }
@@ -128,7 +111,7 @@ func (h *SavedMessagesHandler) GetSavedMessage(c *fiber.Ctx) error {
 		})
 	}
 
-	saved, err := h.service.GetSavedMessage(c.Context(), userID, savedID)
+	saved, err := h.service.GetSavedMessage(c.UserContext(), userID, savedID)
 	if err != nil {
 		if errors.Is(err, services.ErrSavedMessageNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -161,20 +144,11 @@ func (h *SavedMessagesHandler) UpdateSavedMessage(c *fiber.Ctx) error {
 	}
 
 	var req models.UpdateSavedMessageRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
-		})
-	}
-
-	// Validate note length if provided
-	if req.Note != nil && len(*req.Note) > 500 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "note must be 500 characters or less",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
-	saved, err := h.service.UpdateSavedMessageNote(c.Context(), userID, savedID, req.Note)
+	saved, err := h.service.UpdateSavedMessageNote(c.UserContext(), userID, savedID, req.Note)
 	if err != nil {
 		if errors.Is(err, services.ErrSavedMessageNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -206,7 +180,7 @@ func (h *SavedMessagesHandler) RemoveSavedMessage(c *fiber.Ctx) error {
 		})
 	}
 
-	err = h.service.RemoveSavedMessage(c.Context(), userID, savedID)
+	err = h.service.RemoveSavedMessage(c.UserContext(), userID, savedID)
 	if err != nil {
 		if errors.Is(err, services.ErrSavedMessageNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -238,7 +212,7 @@ func (h *SavedMessagesHandler) RemoveSavedMessageByMessage(c *fiber.Ctx) error {
 		})
 	}
 
-	err = h.service.RemoveSavedMessageByMessageID(c.Context(), userID, messageID)
+	err = h.service.RemoveSavedMessageByMessageID(c.UserContext(), userID, messageID)
 	if err != nil {
 		if errors.Is(err, services.ErrSavedMessageNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -265,7 +239,7 @@ func (h *SavedMessagesHandler) IsSaved(c *fiber.Ctx) error {
 		})
 	}
 
-	isSaved, err := h.service.IsSaved(c.Context(), userID, messageID)
+	isSaved, err := h.service.IsSaved(c.UserContext(), userID, messageID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to check saved status",
@@ -282,7 +256,7 @@ func (h *SavedMessagesHandler) IsSaved(c *fiber.Ctx) error {
 func (h *SavedMessagesHandler) GetSavedCount(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
 
-	count, err := h.service.GetSavedCount(c.Context(), userID)
+	count, err := h.service.GetSavedCount(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get saved count",
@@ -216,7 +216,8 @@ func TestSavedMessagesHandler_SaveMessage_MissingMessageID(t *testing.T) {
 
 	var result map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&result)
-	assert.Equal(t, "message_id is required", result["error"])
+	assert.Equal(t, "invalid request body", result["error"])
+	assert.Equal(t, map[string]interface{}{"message_id": "is required"}, result["fields"])
 }
 
 func TestSavedMessagesHandler_SaveMessage_InvalidMessageID(t *testing.T) {
@@ -236,7 +237,8 @@ func TestSavedMessagesHandler_SaveMessage_InvalidMessageID(t *testing.T) {
 
 	var result map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&result)
-	assert.Equal(t, "invalid message_id format", result["error"])
+	assert.Equal(t, "invalid request body", result["error"])
+	assert.Equal(t, map[string]interface{}{"message_id": "must be a UUID"}, result["fields"])
 }
 
 func TestSavedMessagesHandler_SaveMessage_NoteTooLong(t *testing.T) {
@@ -264,7 +266,8 @@ func TestSavedMessagesHandler_SaveMessage_NoteTooLong(t *testing.T) {
 
 	var result map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&result)
-	assert.Equal(t, "note must be 500 characters or less", result["error"])
+	assert.Equal(t, "invalid request body", result["error"])
+	assert.Equal(t, map[string]interface{}{"note": "must be at most 500 characters"}, result["fields"])
 }
 
 func TestSavedMessagesHandler_SaveMessage_MessageNotFound(t *testing.T) {
@@ -508,7 +511,8 @@ func TestSavedMessagesHandler_UpdateSavedMessage_NoteTooLong(t *testing.T) {
 
 	var result map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&result)
-	assert.Equal(t, "note must be 500 characters or less", result["error"])
+	assert.Equal(t, "invalid request body", result["error"])
+	assert.Equal(t, map[string]interface{}{"note": "must be at most 500 characters"}, result["fields"])
 }
 
 func TestSavedMessagesHandler_UpdateSavedMessage_NotFound(t *testing.T) {
@@ -36,6 +36,7 @@ type SearchMessagesRequest struct {
 	Mentions       []string `query:"mentions"`        // User IDs
 	Limit          int      `query:"limit"`
 	Offset         int      `query:"offset"`
+	Cursor         string   `query:"cursor"`
 }
 
 // SearchMessagesResponse represents the message search response
@@ -43,6 +44,8 @@ type SearchMessagesResponse struct {
 	Messages   []*MessageSearchResult `json:"messages"`
 	TotalCount int                    `json:"total_count"`
 	HasMore    bool                   `json:"has_more"`
+	NextCursor string                 `json:"next_cursor,omitempty"`
+	PrevCursor string                 `json:"prev_cursor,omitempty"`
 }
 
 // MessageSearchResult represents a message in search results
@@ -114,6 +117,14 @@ func (h *SearchHandler) SearchMessages(c *fiber.Ctx) error {
 		Offset:      req.Offset,
 	}
 
+	if req.Cursor != "" {
+		cursor, err := models.DecodePageCursor(req.Cursor, models.PageSearch)
+		if err != nil {
+			return invalidCursor(c)
+		}
+		opts.Cursor = cursor
+	}
+
 	// Parse optional server ID
 	if req.ServerID != "" {
 		serverID, err := uuid.Parse(req.ServerID)
@@ -199,7 +210,7 @@ func (h *SearchHandler) SearchMessages(c *fiber.Ctx) error {
 	}
 
 	// Perform search
-	result, err := h.searchService.SearchMessages(c.Context(), opts)
+	result, err := h.searchService.SearchMessages(c.UserContext(), opts)
 	if err != nil {
 		switch err {
 		case services.ErrNotServerMember:
@@ -247,6 +258,8 @@ func (h *SearchHandler) SearchMessages(c *fiber.Ctx) error {
 		Messages:   messages,
 		TotalCount: result.Total,
 		HasMore:    result.HasMore,
+		NextCursor: result.NextCursor,
+		PrevCursor: result.PrevCursor,
 	})
 }
 
@@ -289,7 +302,7 @@ func (h *SearchHandler) SearchUsers(c *fiber.Ctx) error {
 	}
 
 	// Perform search
-	users, err := h.searchService.SearchUsers(c.Context(), query, serverID, userID, limit)
+	users, err := h.searchService.SearchUsers(c.UserContext(), query, serverID, userID, limit)
 	if err != nil {
 		switch err {
 		case services.ErrNotServerMember:
@@ -347,7 +360,7 @@ func (h *SearchHandler) SearchChannels(c *fiber.Ctx) error {
 	}
 
 	// Perform search
-	channels, err := h.searchService.SearchChannels(c.Context(), query, serverID, userID, limit)
+	channels, err := h.searchService.SearchChannels(c.UserContext(), query, serverID, userID, limit)
 	if err != nil {
 		switch err {
 		case services.ErrNotServerMember:
@@ -421,7 +434,7 @@ func (h *SearchHandler) SearchAll(c *fiber.Ctx) error {
 		ServerID:    serverID,
 		Limit:       10,
 	}
-	msgResult, err := h.searchService.SearchMessages(c.Context(), msgOpts)
+	msgResult, err := h.searchService.SearchMessages(c.UserContext(), msgOpts)
 	if err == nil && len(msgResult.Messages) > 0 {
 		messages := make([]*MessageSearchResult, 0, len(msgResult.Messages))
 		for _, msg := range msgResult.Messages {
@@ -446,13 +459,13 @@ func (h *SearchHandler) SearchAll(c *fiber.Ctx) error {
 	}
 
 	// Search users (limit 5 for combined search)
-	users, err := h.searchService.SearchUsers(c.Context(), query, serverID, userID, 5)
+	users, err := h.searchService.SearchUsers(c.UserContext(), query, serverID, userID, 5)
 	if err == nil && len(users) > 0 {
 		response["users"] = users
 	}
 
 	// Search channels (limit 5 for combined search)
-	channels, err := h.searchService.SearchChannels(c.Context(), query, serverID, userID, 5)
+	channels, err := h.searchService.SearchChannels(c.UserContext(), query, serverID, userID, 5)
 	if err == nil && len(channels) > 0 {
 		results := make([]*ChannelSearchResult, 0, len(channels))
 		for _, ch := range channels {
@@ -508,7 +521,7 @@ func (h *SearchHandler) GetSuggestions(c *fiber.Ctx) error {
 	}
 
 	// Get suggestions
-	result, err := h.searchService.GetSearchSuggestions(c.Context(), services.SearchSuggestionsRequest{
+	result, err := h.searchService.GetSearchSuggestions(c.UserContext(), services.SearchSuggestionsRequest{
 		Query:    query,
 		ServerID: serverID,
 		Limit:    limit,
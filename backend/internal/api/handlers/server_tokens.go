@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// ServerTokenServiceInterface defines the methods needed from
+// ServerTokenService
+type ServerTokenServiceInterface interface {
+	ListTokens(ctx context.Context, serverID, requesterID uuid.UUID) ([]*models.ServerToken, error)
+	CreateToken(ctx context.Context, serverID, requesterID uuid.UUID, req *models.CreateServerTokenRequest) (*models.ServerToken, error)
+	RevokeToken(ctx context.Context, serverID, tokenID, requesterID uuid.UUID) error
+}
+
+// ServerTokenHandler handles servers' API tokens
+type ServerTokenHandler struct {
+	tokens ServerTokenServiceInterface
+}
+
+// NewServerTokenHandler creates a new server token handler
+func NewServerTokenHandler(tokens ServerTokenServiceInterface) *ServerTokenHandler {
+	return &ServerTokenHandler{tokens: tokens}
+}
+
+// ListTokens returns a server's tokens, without their secrets
+// GET /servers/:id/tokens
+func (h *ServerTokenHandler) ListTokens(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	tokens, err := h.tokens.ListTokens(c.UserContext(), serverID, userID)
+	if err != nil {
+		return serverTokenError(c, err)
+	}
+	return c.JSON(tokens)
+}
+
+// CreateToken creates a token, returning its secret this once
+// POST /servers/:id/tokens
+func (h *ServerTokenHandler) CreateToken(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	var req models.CreateServerTokenRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	token, err := h.tokens.CreateToken(c.UserContext(), serverID, userID, &req)
+	if err != nil {
+		return serverTokenError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(token)
+}
+
+// RevokeToken deletes a token
+// DELETE /servers/:id/tokens/:tokenId
+func (h *ServerTokenHandler) RevokeToken(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+	tokenID, err := uuid.Parse(c.Params("tokenId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid token id",
+		})
+	}
+
+	if err := h.tokens.RevokeToken(c.UserContext(), serverID, tokenID, userID); err != nil {
+		return serverTokenError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func serverTokenError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidServerTokenRequest), errors.Is(err, services.ErrTooManyServerTokens):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrNotServerOwner):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrServerTokenNotFound), errors.Is(err, services.ErrServerNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "failed to manage server tokens",
+	})
+}
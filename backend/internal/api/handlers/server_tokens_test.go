@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// MockServerTokenService mocks the ServerTokenService for testing
+type MockServerTokenService struct {
+	mock.Mock
+}
+
+func (m *MockServerTokenService) ListTokens(ctx context.Context, serverID, requesterID uuid.UUID) ([]*models.ServerToken, error) {
+	args := m.Called(ctx, serverID, requesterID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.ServerToken), args.Error(1)
+}
+
+func (m *MockServerTokenService) CreateToken(ctx context.Context, serverID, requesterID uuid.UUID, req *models.CreateServerTokenRequest) (*models.ServerToken, error) {
+	args := m.Called(ctx, serverID, requesterID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ServerToken), args.Error(1)
+}
+
+func (m *MockServerTokenService) RevokeToken(ctx context.Context, serverID, tokenID, requesterID uuid.UUID) error {
+	args := m.Called(ctx, serverID, tokenID, requesterID)
+	return args.Error(0)
+}
+
+func newTestServerTokenHandler() (*fiber.App, *MockServerTokenService, uuid.UUID) {
+	tokenService := new(MockServerTokenService)
+	handler := NewServerTokenHandler(tokenService)
+	userID := uuid.New()
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", userID)
+		return c.Next()
+	})
+	app.Get("/servers/:id/tokens", handler.ListTokens)
+	app.Post("/servers/:id/tokens", handler.CreateToken)
+	app.Delete("/servers/:id/tokens/:tokenId", handler.RevokeToken)
+
+	return app, tokenService, userID
+}
+
+func TestServerTokenHandler_CreateToken(t *testing.T) {
+	app, tokenService, userID := newTestServerTokenHandler()
+	serverID := uuid.New()
+	expiresAt := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	tokenService.On("CreateToken", mock.Anything, serverID, userID, mock.MatchedBy(func(req *models.CreateServerTokenRequest) bool {
+		return req.Name == "CI" && len(req.Scopes) == 1 && req.ExpiresAt.Equal(expiresAt)
+	})).Return(&models.ServerToken{ID: uuid.New(), ServerID: serverID, Name: "CI", TokenHash: "hash", Token: models.ServerTokenPrefix + "secret"}, nil)
+	tokenService.On("CreateToken", mock.Anything, serverID, userID, mock.Anything).Return(nil, services.ErrInvalidServerTokenRequest)
+
+	body := `{"name":"CI","scopes":["messages.write"],"expires_at":"2026-06-01T00:00:00Z"}`
+	req := httptest.NewRequest(http.MethodPost, "/servers/"+serverID.String()+"/tokens", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	var token map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&token))
+	assert.Equal(t, models.ServerTokenPrefix+"secret", token["token"])
+	assert.NotContains(t, token, "token_hash")
+
+	req = httptest.NewRequest(http.MethodPost, "/servers/"+serverID.String()+"/tokens", bytes.NewReader([]byte(`{"name":"CI"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestServerTokenHandler_RevokeToken(t *testing.T) {
+	app, tokenService, userID := newTestServerTokenHandler()
+	serverID, tokenID := uuid.New(), uuid.New()
+	tokenService.On("RevokeToken", mock.Anything, serverID, tokenID, userID).Return(services.ErrServerTokenNotFound)
+	tokenService.On("ListTokens", mock.Anything, serverID, userID).Return(nil, services.ErrNotServerOwner)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodDelete, "/servers/"+serverID.String()+"/tokens/"+tokenID.String(), nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/servers/"+serverID.String()+"/tokens", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+}
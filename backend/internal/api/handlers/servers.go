@@ -1,7 +1,8 @@
 package handlers
 
 import (
-	"strconv"
+	"context"
+	"errors"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -28,6 +29,18 @@ type ServerHandler struct {
 	serverService  *services.ServerService
 	channelService *services.ChannelService
 	roleService    *services.RoleService
+	presence       PresenceProvider
+	presenceFilter PresenceFilter
+}
+
+// PresenceProvider reports the live presence of users
+type PresenceProvider interface {
+	GetPresences(userIDs []uuid.UUID) map[uuid.UUID]*models.Presence
+}
+
+// PresenceFilter hides presence from viewers its users don't show it to
+type PresenceFilter interface {
+	FilterPresences(ctx context.Context, viewerID uuid.UUID, presences map[uuid.UUID]*models.Presence) error
 }
 
 func NewServerHandler(
@@ -42,6 +55,24 @@ func NewServerHandler(
 	}
 }
 
+// NewServerHandlerWithPresence creates a server handler that can include
+// member presence
+func NewServerHandlerWithPresence(
+	serverService *services.ServerService,
+	channelService *services.ChannelService,
+	roleService *services.RoleService,
+	presence PresenceProvider,
+) *ServerHandler {
+	h := NewServerHandler(serverService, channelService, roleService)
+	h.presence = presence
+	return h
+}
+
+// SetPresenceFilter hides member presence from viewers who may not see it
+func (h *ServerHandler) SetPresenceFilter(filter PresenceFilter) {
+	h.presenceFilter = filter
+}
+
 // Create creates a new server
 func (h *ServerHandler) Create(c *fiber.Ctx) error {
 	userID, err := getUserIDFromContext(c)
@@ -53,22 +84,14 @@ func (h *ServerHandler) Create(c *fiber.Ctx) error {
 
 	var req struct {
 		Name string `json:"name" validate:"required,min=2,max=100"`
-		Icon string `json:"icon"`
-	}
-
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
-		})
+		Icon string `json:"icon" validate:"omitempty,url"`
 	}
 
-	if req.Name == "" || len(req.Name) < 2 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "name must be at least 2 characters",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
-	server, err := h.serverService.CreateServer(c.Context(), userID, req.Name, req.Icon)
+	server, err := h.serverService.CreateServer(c.UserContext(), userID, req.Name, req.Icon)
 	if err != nil {
 		if err == services.ErrMaxServersReached {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
@@ -92,7 +115,7 @@ func (h *ServerHandler) Get(c *fiber.Ctx) error {
 		})
 	}
 
-	server, err := h.serverService.GetServer(c.Context(), id)
+	server, err := h.serverService.GetServer(c.UserContext(), id)
 	if err != nil {
 		if err == services.ErrServerNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -104,6 +127,7 @@ func (h *ServerHandler) Get(c *fiber.Ctx) error {
 		})
 	}
 
+	setVersionETag(c, server.Version)
 	return c.JSON(server)
 }
 
@@ -123,15 +147,21 @@ func (h *ServerHandler) Update(c *fiber.Ctx) error {
 	}
 
 	var req struct {
-		Name        *string `json:"name"`
-		Icon        *string `json:"icon"`
-		Banner      *string `json:"banner"`
+		Name        *string `json:"name" validate:"omitempty,min=2,max=100"`
+		Icon        *string `json:"icon" validate:"omitempty,url"`
+		Banner      *string `json:"banner" validate:"omitempty,url"`
 		Description *string `json:"description"`
+		Version     *int    `json:"version"` // Alternative to If-Match
 	}
 
-	if err := c.BodyParser(&req); err != nil {
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	version, err := expectedVersion(c, req.Version)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
+			"error": err.Error(),
 		})
 	}
 
@@ -140,10 +170,14 @@ func (h *ServerHandler) Update(c *fiber.Ctx) error {
 		IconURL:     req.Icon,
 		BannerURL:   req.Banner,
 		Description: req.Description,
+		Version:     version,
 	}
 
-	server, err := h.serverService.UpdateServer(c.Context(), id, userID, updates)
+	server, err := h.serverService.UpdateServer(c.UserContext(), id, userID, updates)
 	if err != nil {
+		if handled, respErr := versionConflict(c, err); handled {
+			return respErr
+		}
 		switch err {
 		case services.ErrServerNotFound:
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -160,6 +194,7 @@ func (h *ServerHandler) Update(c *fiber.Ctx) error {
 		}
 	}
 
+	setVersionETag(c, server.Version)
 	return c.JSON(server)
 }
 
@@ -178,7 +213,7 @@ func (h *ServerHandler) Delete(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.serverService.DeleteServer(c.Context(), id, userID); err != nil {
+	if err := h.serverService.DeleteServer(c.UserContext(), id, userID); err != nil {
 		switch err {
 		case services.ErrServerNotFound:
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -198,7 +233,8 @@ func (h *ServerHandler) Delete(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
-// TransferOwnership transfers server ownership to another member
+// TransferOwnership offers the server to another member, who has 48 hours
+// to accept it
 func (h *ServerHandler) TransferOwnership(c *fiber.Ctx) error {
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
@@ -214,29 +250,21 @@ func (h *ServerHandler) TransferOwnership(c *fiber.Ctx) error {
 	}
 
 	var req struct {
-		NewOwnerID string `json:"new_owner_id"`
+		NewOwnerID  string `json:"new_owner_id" validate:"required,uuid"`
+		KeepCoOwner bool   `json:"keep_co_owner"`
 	}
 
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
+	newOwnerID := uuid.MustParse(req.NewOwnerID) // Validated as a UUID
 
-	newOwnerID, err := uuid.Parse(req.NewOwnerID)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid new_owner_id",
-		})
-	}
-
-	server, err := h.serverService.TransferOwnership(c.Context(), id, userID, newOwnerID)
+	transfer, err := h.serverService.RequestOwnershipTransfer(c.UserContext(), id, userID, &models.OwnershipTransferRequest{
+		NewOwnerID:  newOwnerID,
+		KeepCoOwner: req.KeepCoOwner,
+	})
 	if err != nil {
 		switch err {
-		case services.ErrServerNotFound:
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "server not found",
-			})
 		case services.ErrNotServerOwner:
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"error": "only server owner can transfer ownership",
@@ -249,18 +277,68 @@ func (h *ServerHandler) TransferOwnership(c *fiber.Ctx) error {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"error": "cannot transfer ownership to yourself",
 			})
-		default:
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": err.Error(),
-			})
 		}
+		return ownershipError(c, err)
 	}
 
+	return c.Status(fiber.StatusAccepted).JSON(transfer)
+}
+
+// GetOwnershipTransfer returns the server's pending ownership transfer to
+// its owner or the member it's offered to
+func (h *ServerHandler) GetOwnershipTransfer(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	transfer, err := h.serverService.GetOwnershipTransfer(c.UserContext(), id, userID)
+	if err != nil {
+		return ownershipError(c, err)
+	}
+	return c.JSON(transfer)
+}
+
+// AcceptOwnershipTransfer makes the member the pending transfer is offered
+// to the server's owner
+func (h *ServerHandler) AcceptOwnershipTransfer(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	server, err := h.serverService.AcceptOwnershipTransfer(c.UserContext(), id, userID)
+	if err != nil {
+		return ownershipError(c, err)
+	}
 	return c.JSON(server)
 }
 
-// GetMembers returns server members
-func (h *ServerHandler) GetMembers(c *fiber.Ctx) error {
+// CancelOwnershipTransfer cancels the pending transfer, or declines it for
+// the member it's offered to
+func (h *ServerHandler) CancelOwnershipTransfer(c *fiber.Ctx) error {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
 	id, err := uuid.Parse(c.Params("id"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -268,28 +346,118 @@ func (h *ServerHandler) GetMembers(c *fiber.Ctx) error {
 		})
 	}
 
-	// Parse pagination with defaults
-	limit, err := strconv.Atoi(c.Query("limit", "100"))
-	if err != nil || limit < 1 {
-		limit = 100
+	if err := h.serverService.CancelOwnershipTransfer(c.UserContext(), id, userID); err != nil {
+		return ownershipError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// SetCoOwner makes a member a co-owner
+func (h *ServerHandler) SetCoOwner(c *fiber.Ctx) error {
+	return h.setCoOwner(c, h.serverService.SetCoOwner)
+}
+
+// RemoveCoOwner removes a co-owner, or lets a co-owner step down
+func (h *ServerHandler) RemoveCoOwner(c *fiber.Ctx) error {
+	return h.setCoOwner(c, h.serverService.RemoveCoOwner)
+}
+
+func (h *ServerHandler) setCoOwner(c *fiber.Ctx, apply func(ctx context.Context, serverID, requesterID, targetID uuid.UUID) (*models.Member, error)) error {
+	requesterID, err := getUserIDFromContext(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	targetID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	member, err := apply(c.UserContext(), serverID, requesterID, targetID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotServerMember):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "member not found"})
+		case errors.Is(err, services.ErrSelfAction):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "the owner cannot be a co-owner"})
+		}
+		return ownershipError(c, err)
 	}
-	if limit > 1000 {
-		limit = 1000
+
+	return c.JSON(member)
+}
+
+// ownershipError maps ownership transfer and co-owner errors to responses
+func ownershipError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrServerNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "server not found"})
+	case errors.Is(err, services.ErrOwnershipTransferNotFound), errors.Is(err, services.ErrNotCoOwner):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrNotServerOwner):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrOwnershipTransferStale):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrNotServerMember):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "you are not a member of this server"})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// GetMembers returns a page of server members, newest first
+func (h *ServerHandler) GetMembers(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
 	}
 
-	offset, err := strconv.Atoi(c.Query("offset", "0"))
-	if err != nil || offset < 0 {
-		offset = 0
+	req, err := pageRequest(c, models.PageMembers, 100, 1000)
+	if err != nil {
+		return invalidCursor(c)
 	}
 
-	members, err := h.serverService.GetMembers(c.Context(), id, limit, offset)
+	page, err := h.serverService.ListMembers(c.UserContext(), id, req)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	return c.JSON(members)
+	members := page.Items
+	if c.QueryBool("with_presence") && h.presence != nil && len(members) > 0 {
+		userIDs := make([]uuid.UUID, len(members))
+		for i, m := range members {
+			userIDs[i] = m.UserID
+		}
+		presences := h.presence.GetPresences(userIDs)
+		if h.presenceFilter != nil {
+			viewerID, _ := c.Locals("userID").(uuid.UUID)
+			if err := h.presenceFilter.FilterPresences(c.UserContext(), viewerID, presences); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "failed to get presences",
+				})
+			}
+		}
+		for _, m := range members {
+			m.Presence = presences[m.UserID]
+		}
+	}
+
+	return c.JSON(page)
 }
 
 // GetMember returns a specific member
@@ -308,7 +476,7 @@ func (h *ServerHandler) GetMember(c *fiber.Ctx) error {
 		})
 	}
 
-	member, err := h.serverService.GetMember(c.Context(), serverID, userID)
+	member, err := h.serverService.GetMember(c.UserContext(), serverID, userID)
 	if err != nil {
 		if err == services.ErrNotServerMember {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -346,22 +514,27 @@ func (h *ServerHandler) UpdateMember(c *fiber.Ctx) error {
 	}
 
 	var req struct {
-		Nickname *string     `json:"nick"`
-		Roles    []uuid.UUID `json:"roles"`
+		Nickname *string     `json:"nick" validate:"omitempty,max=32"`
+		Roles    []uuid.UUID `json:"roles" validate:"omitempty,unique"`
 	}
 
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
-	member, err := h.serverService.UpdateMember(c.Context(), serverID, requesterID, targetID, req.Nickname, req.Roles)
+	member, err := h.serverService.UpdateMember(c.UserContext(), serverID, requesterID, targetID, req.Nickname, req.Roles)
 	if err != nil {
-		if err == services.ErrNotServerMember {
+		switch {
+		case errors.Is(err, services.ErrNotServerMember):
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "member not found",
 			})
+		case errors.Is(err, services.ErrRoleNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, services.ErrCannotManageRoles),
+			errors.Is(err, services.ErrMemberHierarchy),
+			errors.Is(err, services.ErrRoleHierarchy):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -396,7 +569,7 @@ func (h *ServerHandler) RemoveMember(c *fiber.Ctx) error {
 	// Optional reason in body or query
 	reason := c.Query("reason", "")
 
-	if err := h.serverService.KickMember(c.Context(), serverID, requesterID, targetID, reason); err != nil {
+	if err := h.serverService.KickMember(c.UserContext(), serverID, requesterID, targetID, reason); err != nil {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -420,7 +593,7 @@ func (h *ServerHandler) Leave(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.serverService.LeaveServer(c.Context(), id, userID); err != nil {
+	if err := h.serverService.LeaveServer(c.UserContext(), id, userID); err != nil {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -429,7 +602,7 @@ func (h *ServerHandler) Leave(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
-// GetBans returns server bans
+// GetBans returns a page of server bans, newest first
 func (h *ServerHandler) GetBans(c *fiber.Ctx) error {
 	serverID, err := uuid.Parse(c.Params("id"))
 	if err != nil {
@@ -438,17 +611,19 @@ func (h *ServerHandler) GetBans(c *fiber.Ctx) error {
 		})
 	}
 
-	bans, err := h.serverService.GetBans(c.Context(), serverID)
+	req, err := pageRequest(c, models.PageBans, 100, 1000)
+	if err != nil {
+		return invalidCursor(c)
+	}
+
+	page, err := h.serverService.ListBans(c.UserContext(), serverID, req)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	if bans == nil {
-		bans = []*models.Ban{}
-	}
-	return c.JSON(bans)
+	return c.JSON(page)
 }
 
 // CreateBan bans a user
@@ -474,11 +649,17 @@ func (h *ServerHandler) CreateBan(c *fiber.Ctx) error {
 	}
 
 	var req struct {
-		Reason               string `json:"reason"`
-		DeleteMessageDays    int    `json:"delete_message_days"`
-		DeleteMessageSeconds int    `json:"delete_message_seconds"`
+		Reason               string `json:"reason" validate:"max=512"`
+		DeleteMessageDays    int    `json:"delete_message_days" validate:"min=0,max=7"`
+		DeleteMessageSeconds int    `json:"delete_message_seconds" validate:"min=0,max=604800"`
+		DurationSeconds      int    `json:"duration_seconds" validate:"min=0"` // 0 = permanent
+	}
+	// The body is optional
+	if len(c.Body()) > 0 {
+		if err := parseBody(c, &req); err != nil {
+			return invalidBody(c, err)
+		}
 	}
-	_ = c.BodyParser(&req)
 
 	// Convert delete days to actual days if seconds provided
 	deleteDays := req.DeleteMessageDays
@@ -486,7 +667,13 @@ func (h *ServerHandler) CreateBan(c *fiber.Ctx) error {
 		deleteDays = req.DeleteMessageSeconds / 86400
 	}
 
-	if err := h.serverService.BanMember(c.Context(), serverID, requesterID, targetID, req.Reason, deleteDays); err != nil {
+	duration := time.Duration(req.DurationSeconds) * time.Second
+	if err := h.serverService.BanMember(c.UserContext(), serverID, requesterID, targetID, req.Reason, deleteDays, duration); err != nil {
+		if errors.Is(err, services.ErrBanDuration) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -517,7 +704,7 @@ func (h *ServerHandler) RemoveBan(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.serverService.UnbanMember(c.Context(), serverID, requesterID, targetID); err != nil {
+	if err := h.serverService.UnbanMember(c.UserContext(), serverID, requesterID, targetID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -526,8 +713,15 @@ func (h *ServerHandler) RemoveBan(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
-// GetInvites returns server invites
-func (h *ServerHandler) GetInvites(c *fiber.Ctx) error {
+// ExportBans returns the server's ban list, ready to import elsewhere
+// GET /servers/:id/bans/export
+func (h *ServerHandler) ExportBans(c *fiber.Ctx) error {
+	requesterID, err := getUserIDFromContext(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
 	serverID, err := uuid.Parse(c.Params("id"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -535,20 +729,174 @@ func (h *ServerHandler) GetInvites(c *fiber.Ctx) error {
 		})
 	}
 
-	invites, err := h.serverService.GetInvites(c.Context(), serverID)
+	list, err := h.serverService.ExportBans(c.UserContext(), serverID, requesterID)
 	if err != nil {
+		return banImportError(c, err)
+	}
+	return c.JSON(list)
+}
+
+// ImportBans queues a ban list to be applied in the background
+// POST /servers/:id/bans/import
+func (h *ServerHandler) ImportBans(c *fiber.Ctx) error {
+	requesterID, err := getUserIDFromContext(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	var req models.BanImportRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	job, err := h.serverService.ImportBans(c.UserContext(), serverID, requesterID, &req)
+	if err != nil {
+		return banImportError(c, err)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// GetBanImport reports how far an import has got
+// GET /servers/:id/bans/imports/:importId
+func (h *ServerHandler) GetBanImport(c *fiber.Ctx) error {
+	requesterID, err := getUserIDFromContext(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+	importID, err := uuid.Parse(c.Params("importId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid import id",
+		})
+	}
+
+	job, err := h.serverService.GetBanImport(c.UserContext(), serverID, requesterID, importID)
+	if err != nil {
+		return banImportError(c, err)
+	}
+	return c.JSON(job)
+}
+
+func banImportError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidBanList):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrCannotBan):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrServerNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "server not found"})
+	case errors.Is(err, services.ErrBanImportNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrBanImportInProgress):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// TimeoutMember times a member out for duration_seconds
+func (h *ServerHandler) TimeoutMember(c *fiber.Ctx) error {
+	var req struct {
+		DurationSeconds int `json:"duration_seconds" validate:"required,min=1,max=2419200"` // Up to 28 days
+	}
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	return h.setTimeout(c, func(ctx context.Context, serverID, requesterID, targetID uuid.UUID) (*models.Member, error) {
+		duration := time.Duration(req.DurationSeconds) * time.Second
+		return h.serverService.TimeoutMember(ctx, serverID, requesterID, targetID, duration)
+	})
+}
+
+// RemoveTimeout lifts a member's timeout
+func (h *ServerHandler) RemoveTimeout(c *fiber.Ctx) error {
+	return h.setTimeout(c, h.serverService.RemoveTimeout)
+}
+
+func (h *ServerHandler) setTimeout(c *fiber.Ctx, apply func(ctx context.Context, serverID, requesterID, targetID uuid.UUID) (*models.Member, error)) error {
+	requesterID, err := getUserIDFromContext(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	targetID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	member, err := apply(c.UserContext(), serverID, requesterID, targetID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTimeoutDuration):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, services.ErrCannotTimeout), errors.Is(err, services.ErrMemberHierarchy):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, services.ErrServerNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "server not found"})
+		case errors.Is(err, services.ErrNotServerMember):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "member not found"})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	if invites == nil {
-		invites = []*models.Invite{}
+	return c.JSON(member)
+}
+
+// GetInvites returns a page of server invites, newest first
+func (h *ServerHandler) GetInvites(c *fiber.Ctx) error {
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	req, err := pageRequest(c, models.PageInvites, 100, 1000)
+	if err != nil {
+		return invalidCursor(c)
+	}
+
+	page, err := h.serverService.ListInvites(c.UserContext(), serverID, req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
 	}
-	return c.JSON(invites)
+
+	return c.JSON(page)
 }
 
-// GetRoles returns server roles
+// GetRoles returns a page of server roles, highest first
 func (h *ServerHandler) GetRoles(c *fiber.Ctx) error {
 	requesterID, err := getUserIDFromContext(c)
 	if err != nil {
@@ -563,17 +911,62 @@ func (h *ServerHandler) GetRoles(c *fiber.Ctx) error {
 		})
 	}
 
-	roles, err := h.roleService.GetServerRoles(c.Context(), serverID, requesterID)
+	req, err := pageRequest(c, models.PageRoles, 250, 250)
+	if err != nil {
+		return invalidCursor(c)
+	}
+
+	page, err := h.roleService.ListServerRoles(c.UserContext(), serverID, requesterID, req)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	if roles == nil {
-		roles = []*models.Role{}
+	return c.JSON(page)
+}
+
+// GetRole returns one of the server's roles
+func (h *ServerHandler) GetRole(c *fiber.Ctx) error {
+	requesterID, err := getUserIDFromContext(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
 	}
-	return c.JSON(roles)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+	roleID, err := uuid.Parse(c.Params("roleId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid role id",
+		})
+	}
+
+	role, err := h.roleService.GetRole(c.UserContext(), serverID, roleID, requesterID)
+	if err != nil {
+		switch err {
+		case services.ErrNotServerMember:
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "not a member of this server",
+			})
+		case services.ErrRoleNotFound:
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "role not found",
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	setVersionETag(c, role.Version)
+	return c.JSON(role)
 }
 
 // CreateRole creates a new role
@@ -592,22 +985,20 @@ func (h *ServerHandler) CreateRole(c *fiber.Ctx) error {
 	}
 
 	var req struct {
-		Name        string `json:"name"`
-		Color       int    `json:"color"`
+		Name        string `json:"name" validate:"max=100"`
+		Color       int    `json:"color" validate:"min=0,max=16777215"` // RGB
 		Permissions int64  `json:"permissions"`
 	}
 
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
 	if req.Name == "" {
 		req.Name = "new role"
 	}
 
-	role, err := h.roleService.CreateRole(c.Context(), serverID, requesterID, req.Name, req.Color, req.Permissions)
+	role, err := h.roleService.CreateRole(c.UserContext(), serverID, requesterID, req.Name, req.Color, req.Permissions)
 	if err != nil {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": err.Error(),
@@ -640,17 +1031,23 @@ func (h *ServerHandler) UpdateRole(c *fiber.Ctx) error {
 	}
 
 	var req struct {
-		Name        *string `json:"name"`
-		Color       *int    `json:"color"`
+		Name        *string `json:"name" validate:"omitempty,min=1,max=100"`
+		Color       *int    `json:"color" validate:"omitempty,min=0,max=16777215"` // RGB
 		Hoist       *bool   `json:"hoist"`
 		Permissions *int64  `json:"permissions"`
 		Mentionable *bool   `json:"mentionable"`
 		Position    *int    `json:"position"`
+		Version     *int    `json:"version"` // Alternative to If-Match
 	}
 
-	if err := c.BodyParser(&req); err != nil {
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	version, err := expectedVersion(c, req.Version)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
+			"error": err.Error(),
 		})
 	}
 
@@ -661,18 +1058,82 @@ func (h *ServerHandler) UpdateRole(c *fiber.Ctx) error {
 		Permissions: req.Permissions,
 		Mentionable: req.Mentionable,
 		Position:    req.Position,
+		Version:     version,
 	}
 
-	role, err := h.roleService.UpdateRole(c.Context(), roleID, requesterID, updates)
+	role, err := h.roleService.UpdateRole(c.UserContext(), roleID, requesterID, updates)
 	if err != nil {
+		if handled, respErr := versionConflict(c, err); handled {
+			return respErr
+		}
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
+	setVersionETag(c, role.Version)
 	return c.JSON(role)
 }
 
+// UpdateRolePositions moves several roles at once and returns the
+// server's roles in their new order
+func (h *ServerHandler) UpdateRolePositions(c *fiber.Ctx) error {
+	requesterID, err := getUserIDFromContext(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	var req []struct {
+		ID       uuid.UUID `json:"id" validate:"required"`
+		Position int       `json:"position"`
+	}
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+	if len(req) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "no roles to move",
+		})
+	}
+
+	positions := make(map[uuid.UUID]int, len(req))
+	for _, p := range req {
+		positions[p.ID] = p.Position
+	}
+
+	if err := h.roleService.UpdateRolePositions(c.UserContext(), serverID, requesterID, positions); err != nil {
+		switch {
+		case errors.Is(err, services.ErrServerNotFound), errors.Is(err, services.ErrRoleNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidRolePosition):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, services.ErrNotServerMember),
+			errors.Is(err, services.ErrCannotManageRoles),
+			errors.Is(err, services.ErrRoleHierarchy):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	roles, err := h.roleService.GetServerRoles(c.UserContext(), serverID, requesterID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(roles)
+}
+
 // DeleteRole deletes a role
 func (h *ServerHandler) DeleteRole(c *fiber.Ctx) error {
 	requesterID, err := getUserIDFromContext(c)
@@ -695,7 +1156,7 @@ func (h *ServerHandler) DeleteRole(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.roleService.DeleteRole(c.Context(), roleID, requesterID); err != nil {
+	if err := h.roleService.DeleteRole(c.UserContext(), roleID, requesterID); err != nil {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -719,7 +1180,7 @@ func (h *ServerHandler) GetChannels(c *fiber.Ctx) error {
 		})
 	}
 
-	channels, err := h.channelService.GetServerChannels(c.Context(), serverID, requesterID)
+	channels, err := h.channelService.GetServerChannels(c.UserContext(), serverID, requesterID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -748,21 +1209,13 @@ func (h *ServerHandler) CreateChannel(c *fiber.Ctx) error {
 	}
 
 	var req struct {
-		Name     string             `json:"name"`
-		Type     models.ChannelType `json:"type"`
+		Name     string             `json:"name" validate:"required,min=1,max=100"`
+		Type     models.ChannelType `json:"type" validate:"omitempty,oneof=text voice category announcement forum stage"`
 		ParentID *uuid.UUID         `json:"parent_id"`
 	}
 
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
-		})
-	}
-
-	if req.Name == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "name is required",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
 	// Default to text channel
@@ -771,7 +1224,7 @@ func (h *ServerHandler) CreateChannel(c *fiber.Ctx) error {
 	}
 
 	channel, err := h.channelService.CreateChannel(
-		c.Context(),
+		c.UserContext(),
 		serverID,
 		requesterID,
 		req.Name,
@@ -803,14 +1256,19 @@ func (h *ServerHandler) CreateInvite(c *fiber.Ctx) error {
 	}
 
 	var req struct {
-		MaxAge  int `json:"max_age"`  // seconds, 0 = never
-		MaxUses int `json:"max_uses"` // 0 = unlimited
+		MaxAge  int `json:"max_age" validate:"min=0"`  // seconds, 0 = never
+		MaxUses int `json:"max_uses" validate:"min=0"` // 0 = unlimited
 	}
 
-	_ = c.BodyParser(&req)
+	// The body is optional
+	if len(c.Body()) > 0 {
+		if err := parseBody(c, &req); err != nil {
+			return invalidBody(c, err)
+		}
+	}
 
 	// Get default channel for invite
-	channels, err := h.serverService.GetChannels(c.Context(), serverID)
+	channels, err := h.serverService.GetChannels(c.UserContext(), serverID)
 	if err != nil || len(channels) == 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "no channels in server",
@@ -825,7 +1283,7 @@ func (h *ServerHandler) CreateInvite(c *fiber.Ctx) error {
 		expiresIn = &d
 	}
 
-	invite, err := h.serverService.CreateInvite(c.Context(), serverID, channelID, requesterID, req.MaxUses, expiresIn)
+	invite, err := h.serverService.CreateInvite(c.UserContext(), serverID, channelID, requesterID, req.MaxUses, expiresIn)
 	if err != nil {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": err.Error(),
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// SessionManager defines the methods needed from SessionService
+type SessionManager interface {
+	ListSessions(ctx context.Context, userID, currentID uuid.UUID) ([]*models.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	RevokeAllSessions(ctx context.Context, userID uuid.UUID) error
+}
+
+// SessionHandler handles the current user's login sessions
+type SessionHandler struct {
+	sessions SessionManager
+}
+
+// NewSessionHandler creates a new session handler
+func NewSessionHandler(sessions SessionManager) *SessionHandler {
+	return &SessionHandler{sessions: sessions}
+}
+
+// ListSessions returns where the current user is logged in
+// GET /users/@me/sessions
+func (h *SessionHandler) ListSessions(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	currentID, _ := c.Locals("sessionID").(uuid.UUID)
+
+	sessions, err := h.sessions.ListSessions(c.UserContext(), userID, currentID)
+	if err != nil {
+		return sessionError(c, err)
+	}
+	return c.JSON(sessions)
+}
+
+// RevokeSession logs one of the current user's sessions out
+// DELETE /users/@me/sessions/:id
+func (h *SessionHandler) RevokeSession(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	sessionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid session id",
+		})
+	}
+
+	if err := h.sessions.RevokeSession(c.UserContext(), userID, sessionID); err != nil {
+		return sessionError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RevokeAllSessions logs the current user out everywhere, including here
+// DELETE /users/@me/sessions
+func (h *SessionHandler) RevokeAllSessions(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	if err := h.sessions.RevokeAllSessions(c.UserContext(), userID); err != nil {
+		return sessionError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func sessionError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, services.ErrSessionNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "failed to manage sessions",
+	})
+}
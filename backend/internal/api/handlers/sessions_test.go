@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// MockSessionService mocks the SessionManager for testing
+type MockSessionService struct {
+	mock.Mock
+}
+
+func (m *MockSessionService) ListSessions(ctx context.Context, userID, currentID uuid.UUID) ([]*models.Session, error) {
+	args := m.Called(ctx, userID, currentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Session), args.Error(1)
+}
+
+func (m *MockSessionService) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockSessionService) RevokeAllSessions(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func TestSessionHandler(t *testing.T) {
+	sessions := new(MockSessionService)
+	handler := NewSessionHandler(sessions)
+	userID, sessionID := uuid.New(), uuid.New()
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", userID)
+		c.Locals("sessionID", sessionID)
+		return c.Next()
+	})
+	app.Get("/users/@me/sessions", handler.ListSessions)
+	app.Delete("/users/@me/sessions", handler.RevokeAllSessions)
+	app.Delete("/users/@me/sessions/:id", handler.RevokeSession)
+
+	ip := "203.0.113.7"
+	sessions.On("ListSessions", mock.Anything, userID, sessionID).Return([]*models.Session{
+		{ID: sessionID, UserID: userID, IPAddress: &ip, RefreshToken: "secret-hash", Current: true},
+	}, nil)
+	missingID := uuid.New()
+	sessions.On("RevokeSession", mock.Anything, userID, missingID).Return(services.ErrSessionNotFound)
+	sessions.On("RevokeSession", mock.Anything, userID, sessionID).Return(nil)
+	sessions.On("RevokeAllSessions", mock.Anything, userID).Return(nil)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/users/@me/sessions", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var listed []map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&listed))
+	require.Len(t, listed, 1)
+	assert.Equal(t, ip, listed[0]["ip_address"])
+	assert.Equal(t, true, listed[0]["current"])
+	assert.NotContains(t, listed[0], "refresh_token_hash", "token hashes are never returned")
+
+	for path, status := range map[string]int{
+		"/users/@me/sessions/" + sessionID.String(): fiber.StatusNoContent,
+		"/users/@me/sessions/" + missingID.String(): fiber.StatusNotFound,
+		"/users/@me/sessions/nope":                  fiber.StatusBadRequest,
+		"/users/@me/sessions":                       fiber.StatusNoContent,
+	} {
+		resp, err := app.Test(httptest.NewRequest(http.MethodDelete, path, nil))
+		require.NoError(t, err)
+		assert.Equal(t, status, resp.StatusCode, path)
+	}
+	sessions.AssertCalled(t, "RevokeAllSessions", mock.Anything, userID)
+}
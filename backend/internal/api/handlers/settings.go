@@ -32,7 +32,7 @@ func NewSettingsHandler(settingsService SettingsServiceInterface) *SettingsHandl
 func (h *SettingsHandler) GetSettings(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
 
-	settings, err := h.settingsService.GetSettings(c.Context(), userID)
+	settings, err := h.settingsService.GetSettings(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get settings",
@@ -47,42 +47,11 @@ func (h *SettingsHandler) UpdateSettings(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
 
 	var req models.UpdateUserSettingsRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
-		})
-	}
-
-	// Validate theme if provided
-	if req.Theme != nil {
-		validThemes := map[string]bool{"dark": true, "light": true, "system": true}
-		if !validThemes[*req.Theme] {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "invalid theme: must be 'dark', 'light', or 'system'",
-			})
-		}
-	}
-
-	// Validate message display if provided
-	if req.MessageDisplay != nil {
-		validDisplays := map[string]bool{"cozy": true, "compact": true}
-		if !validDisplays[*req.MessageDisplay] {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "invalid message_display: must be 'cozy' or 'compact'",
-			})
-		}
-	}
-
-	// Validate locale if provided
-	if req.Locale != nil {
-		if len(*req.Locale) < 2 || len(*req.Locale) > 10 {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "invalid locale: must be 2-10 characters",
-			})
-		}
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
-	settings, err := h.settingsService.UpdateSettings(c.Context(), userID, &req)
+	settings, err := h.settingsService.UpdateSettings(c.UserContext(), userID, &req)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to update settings",
@@ -96,7 +65,7 @@ func (h *SettingsHandler) UpdateSettings(c *fiber.Ctx) error {
 func (h *SettingsHandler) ResetSettings(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
 
-	settings, err := h.settingsService.ResetSettings(c.Context(), userID)
+	settings, err := h.settingsService.ResetSettings(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to reset settings",
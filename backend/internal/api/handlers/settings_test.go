@@ -200,7 +200,8 @@ func TestSettingsHandler_UpdateSettings_InvalidTheme(t *testing.T) {
 
 	var result map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&result)
-	assert.Contains(t, result["error"], "invalid theme")
+	assert.Equal(t, "invalid request body", result["error"])
+	assert.Equal(t, map[string]interface{}{"theme": "must be one of dark, light, system"}, result["fields"])
 }
 
 func TestSettingsHandler_UpdateSettings_Notifications(t *testing.T) {
@@ -306,7 +307,8 @@ func TestSettingsHandler_UpdateSettings_InvalidMessageDisplay(t *testing.T) {
 
 	var result map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&result)
-	assert.Contains(t, result["error"], "invalid message_display")
+	assert.Equal(t, "invalid request body", result["error"])
+	assert.Equal(t, map[string]interface{}{"message_display": "must be one of cozy, compact"}, result["fields"])
 }
 
 func TestSettingsHandler_UpdateSettings_InvalidLocale(t *testing.T) {
@@ -326,7 +328,8 @@ func TestSettingsHandler_UpdateSettings_InvalidLocale(t *testing.T) {
 
 	var result map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&result)
-	assert.Contains(t, result["error"], "invalid locale")
+	assert.Equal(t, "invalid request body", result["error"])
+	assert.Equal(t, map[string]interface{}{"locale": "must be at least 2 characters"}, result["fields"])
 }
 
 func TestSettingsHandler_UpdateSettings_InvalidBody(t *testing.T) {
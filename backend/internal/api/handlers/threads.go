@@ -32,10 +32,8 @@ func (h *ThreadHandler) CreateThread(c *fiber.Ctx) error {
 	}
 
 	var req models.CreateThreadRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
 	if req.Name == "" {
@@ -44,7 +42,7 @@ func (h *ThreadHandler) CreateThread(c *fiber.Ctx) error {
 		})
 	}
 
-	thread, err := h.threadService.CreateThread(c.Context(), channelID, userID, req.Name, req.AutoArchive)
+	thread, err := h.threadService.CreateThread(c.UserContext(), channelID, userID, req.Name, req.AutoArchive)
 	if err != nil {
 		switch err {
 		case services.ErrChannelNotFound:
@@ -59,6 +57,10 @@ func (h *ThreadHandler) CreateThread(c *fiber.Ctx) error {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"error": "invalid auto archive duration",
 			})
+		case services.ErrForumChannel:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "forum channels take posts; use POST /channels/:id/forum-posts",
+			})
 		default:
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "failed to create thread",
@@ -79,7 +81,7 @@ func (h *ThreadHandler) GetThread(c *fiber.Ctx) error {
 		})
 	}
 
-	thread, err := h.threadService.GetThread(c.Context(), threadID)
+	thread, err := h.threadService.GetThread(c.UserContext(), threadID)
 	if err != nil {
 		if err == services.ErrThreadNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -114,7 +116,7 @@ func (h *ThreadHandler) GetThreadMessages(c *fiber.Ctx) error {
 
 	limit := c.QueryInt("limit", 50)
 
-	messages, err := h.threadService.GetThreadMessages(c.Context(), threadID, userID, before, limit)
+	messages, err := h.threadService.GetThreadMessages(c.UserContext(), threadID, userID, before, limit)
 	if err != nil {
 		switch err {
 		case services.ErrThreadNotFound:
@@ -147,10 +149,8 @@ func (h *ThreadHandler) SendThreadMessage(c *fiber.Ctx) error {
 	}
 
 	var req models.CreateThreadMessageRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
 	if req.Content == "" {
@@ -159,7 +159,7 @@ func (h *ThreadHandler) SendThreadMessage(c *fiber.Ctx) error {
 		})
 	}
 
-	message, err := h.threadService.SendThreadMessage(c.Context(), threadID, userID, req.Content)
+	message, err := h.threadService.SendThreadMessage(c.UserContext(), threadID, userID, req.Content)
 	if err != nil {
 		switch err {
 		case services.ErrThreadNotFound:
@@ -199,7 +199,7 @@ func (h *ThreadHandler) ArchiveThread(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.threadService.ArchiveThread(c.Context(), threadID, userID); err != nil {
+	if err := h.threadService.ArchiveThread(c.UserContext(), threadID, userID); err != nil {
 		switch err {
 		case services.ErrThreadNotFound:
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -234,7 +234,7 @@ func (h *ThreadHandler) UnarchiveThread(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.threadService.UnarchiveThread(c.Context(), threadID, userID); err != nil {
+	if err := h.threadService.UnarchiveThread(c.UserContext(), threadID, userID); err != nil {
 		switch err {
 		case services.ErrThreadNotFound:
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -271,7 +271,7 @@ func (h *ThreadHandler) GetChannelThreads(c *fiber.Ctx) error {
 
 	includeArchived := c.QueryBool("include_archived", false)
 
-	threads, err := h.threadService.GetChannelThreads(c.Context(), channelID, userID, includeArchived)
+	threads, err := h.threadService.GetChannelThreads(c.UserContext(), channelID, userID, includeArchived)
 	if err != nil {
 		switch err {
 		case services.ErrChannelNotFound:
@@ -303,7 +303,7 @@ func (h *ThreadHandler) JoinThread(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.threadService.JoinThread(c.Context(), threadID, userID); err != nil {
+	if err := h.threadService.JoinThread(c.UserContext(), threadID, userID); err != nil {
 		if err == services.ErrThreadNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "thread not found",
@@ -328,7 +328,7 @@ func (h *ThreadHandler) LeaveThread(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.threadService.LeaveThread(c.Context(), threadID, userID); err != nil {
+	if err := h.threadService.LeaveThread(c.UserContext(), threadID, userID); err != nil {
 		if err == services.ErrThreadNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "thread not found",
@@ -353,7 +353,7 @@ func (h *ThreadHandler) DeleteThread(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.threadService.DeleteThread(c.Context(), threadID, userID); err != nil {
+	if err := h.threadService.DeleteThread(c.UserContext(), threadID, userID); err != nil {
 		switch err {
 		case services.ErrThreadNotFound:
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"mime/multipart"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
+	"hearth/internal/auth"
 	"hearth/internal/models"
 	"hearth/internal/services"
 	"hearth/internal/storage"
@@ -18,6 +20,7 @@ import (
 // UserServiceInterface defines the methods needed from UserService
 type UserServiceInterface interface {
 	GetUser(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetUsers(ctx context.Context, ids []uuid.UUID) ([]*models.User, error)
 	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
 	UpdateUser(ctx context.Context, id uuid.UUID, updates *models.UserUpdate) (*models.User, error)
 	GetFriends(ctx context.Context, userID uuid.UUID) ([]*models.User, error)
@@ -33,6 +36,14 @@ type UserServiceInterface interface {
 	AcceptFriendRequest(ctx context.Context, receiverID, senderID uuid.UUID) error
 	DeclineFriendRequest(ctx context.Context, userID, otherID uuid.UUID) error
 	GetRelationship(ctx context.Context, userID, targetID uuid.UUID) (int, error)
+
+	// Custom status
+	SetCustomStatus(ctx context.Context, userID uuid.UUID, status *models.CustomStatus) (*models.User, error)
+	ClearCustomStatus(ctx context.Context, userID uuid.UUID) (*models.User, error)
+
+	// Usernames
+	CheckUsername(ctx context.Context, requesterID uuid.UUID, name string) (*models.UsernameAvailability, error)
+	GetUsernameHistory(ctx context.Context, userID uuid.UUID) ([]*models.UsernameChange, time.Time, error)
 	
 	// Profile enhancements (UX-003) - optional, check via type assertion
 	// GetMutualFriends(ctx context.Context, userID1, userID2 uuid.UUID, limit int) ([]*models.User, int, error)
@@ -55,6 +66,11 @@ type MutualFriendsService interface {
 	GetMutualFriends(ctx context.Context, userID1, userID2 uuid.UUID, limit int) ([]*models.User, int, error)
 }
 
+// BlockedUsersService is an optional interface for listing blocked users
+type BlockedUsersService interface {
+	GetBlockedUsers(ctx context.Context, userID uuid.UUID) ([]*models.User, error)
+}
+
 // RecentActivityService is an optional interface for getting recent activity
 type RecentActivityService interface {
 	GetRecentActivity(ctx context.Context, requesterID, targetID uuid.UUID) (*services.RecentActivityInfo, error)
@@ -83,6 +99,7 @@ type UserHandler struct {
 	serverService  ServerServiceForUsersInterface
 	channelService ChannelServiceForUsersInterface
 	storageService StorageServiceInterface
+	accountTokens  AccountTokenValidator
 }
 
 func NewUserHandler(
@@ -116,7 +133,7 @@ func NewUserHandlerWithStorage(
 func (h *UserHandler) GetMe(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
 
-	user, err := h.userService.GetUser(c.Context(), userID)
+	user, err := h.userService.GetUser(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "user not found",
@@ -137,38 +154,119 @@ func (h *UserHandler) GetMe(c *fiber.Ctx) error {
 	})
 }
 
-// UpdateMe updates the current user
-func (h *UserHandler) UpdateMe(c *fiber.Ctx) error {
+// AccountTokenValidator resolves another account's access token for the
+// account switcher. The AuthService implements it.
+type AccountTokenValidator interface {
+	ValidateToken(ctx context.Context, token string) (uuid.UUID, error)
+}
+
+// maxSwitchAccounts caps the other accounts GET /users/@me/accounts checks
+const maxSwitchAccounts = 5
+
+// Token states reported by GET /users/@me/accounts
+const (
+	AccountTokenValid   = "valid"
+	AccountTokenExpired = "expired"
+	AccountTokenInvalid = "invalid"
+)
+
+// AccountResponse is one signed-in account in the account switcher
+type AccountResponse struct {
+	User        *UserResponse `json:"user,omitempty"`
+	Current     bool          `json:"current"`
+	TokenStatus string        `json:"token_status"`
+}
+
+// SetAccountTokenValidator enables checking the other accounts passed to
+// GetMyAccounts
+func (h *UserHandler) SetAccountTokenValidator(validator AccountTokenValidator) {
+	h.accountTokens = validator
+}
+
+// GetMyAccounts summarizes every account signed in on the device so clients
+// can switch between them without logging in again. The current account
+// comes first, followed by one entry per token in X-Account-Tokens, in the
+// order given. Expired tokens are reported so the client can refresh them.
+func (h *UserHandler) GetMyAccounts(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
 
-	var req struct {
-		Username     *string `json:"username"`
-		AvatarURL    *string `json:"avatar_url"`
-		BannerURL    *string `json:"banner_url"`
-		Bio          *string `json:"bio"`
-		CustomStatus *string `json:"custom_status"`
+	current, err := h.userService.GetUser(c.UserContext(), userID)
+	if err != nil || current == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "user not found",
+		})
 	}
 
-	if err := c.BodyParser(&req); err != nil {
+	var tokens []string
+	for _, token := range strings.Split(c.Get("X-Account-Tokens"), ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	if len(tokens) > maxSwitchAccounts {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
+			"error": fmt.Sprintf("at most %d other accounts can be checked", maxSwitchAccounts),
 		})
 	}
 
-	// Validate username if provided
-	if req.Username != nil {
-		if len(*req.Username) < 2 || len(*req.Username) > 32 {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "username must be between 2 and 32 characters",
-			})
-		}
+	accounts := make([]AccountResponse, 0, len(tokens)+1)
+	accounts = append(accounts, AccountResponse{User: accountUser(current), Current: true, TokenStatus: AccountTokenValid})
+	for _, token := range tokens {
+		accounts = append(accounts, h.resolveAccount(c.UserContext(), token, userID))
 	}
+	return c.JSON(accounts)
+}
 
-	// Validate bio if provided
-	if req.Bio != nil && len(*req.Bio) > 190 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "bio must be 190 characters or less",
-		})
+// resolveAccount checks one switcher token. Only valid tokens reveal whose
+// they are.
+func (h *UserHandler) resolveAccount(ctx context.Context, token string, currentID uuid.UUID) AccountResponse {
+	if h.accountTokens == nil {
+		return AccountResponse{TokenStatus: AccountTokenInvalid}
+	}
+
+	accountID, err := h.accountTokens.ValidateToken(ctx, token)
+	if errors.Is(err, auth.ErrExpiredToken) {
+		return AccountResponse{TokenStatus: AccountTokenExpired}
+	}
+	if err != nil {
+		return AccountResponse{TokenStatus: AccountTokenInvalid}
+	}
+
+	user, err := h.userService.GetUser(ctx, accountID)
+	if err != nil || user == nil {
+		return AccountResponse{TokenStatus: AccountTokenInvalid}
+	}
+	return AccountResponse{User: accountUser(user), Current: accountID == currentID, TokenStatus: AccountTokenValid}
+}
+
+func accountUser(user *models.User) *UserResponse {
+	return &UserResponse{
+		ID:            user.ID,
+		Username:      user.Username,
+		Discriminator: user.Discriminator,
+		Email:         &user.Email,
+		AvatarURL:     user.AvatarURL,
+		BannerURL:     user.BannerURL,
+		CustomStatus:  user.CustomStatus,
+		Flags:         user.Flags,
+		CreatedAt:     user.CreatedAt,
+	}
+}
+
+// UpdateMe updates the current user
+func (h *UserHandler) UpdateMe(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	var req struct {
+		Username     *string `json:"username" validate:"omitempty,min=2,max=32"`
+		AvatarURL    *string `json:"avatar_url" validate:"omitempty,url"`
+		BannerURL    *string `json:"banner_url" validate:"omitempty,url"`
+		Bio          *string `json:"bio" validate:"omitempty,max=190"`
+		CustomStatus *string `json:"custom_status" validate:"omitempty,max=128"`
+	}
+
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
 	updates := &models.UserUpdate{
@@ -179,13 +277,32 @@ func (h *UserHandler) UpdateMe(c *fiber.Ctx) error {
 		CustomStatus: req.CustomStatus,
 	}
 
-	user, err := h.userService.UpdateUser(c.Context(), userID, updates)
+	user, err := h.userService.UpdateUser(c.UserContext(), userID, updates)
 	if err != nil {
 		if err == services.ErrUsernameTaken {
 			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 				"error": "username already taken",
 			})
 		}
+		if err == services.ErrUsernameReserved {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if err == services.ErrInvalidUsername || err == services.ErrUsernameBanned {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		var cooldown *services.UsernameCooldownError
+		if errors.As(err, &cooldown) {
+			retryAfter := int(time.Until(cooldown.RetryAt).Seconds()) + 1
+			c.Set(fiber.HeaderRetryAfter, fmt.Sprint(retryAfter))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error":          err.Error(),
+				"next_change_at": cooldown.RetryAt,
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to update user",
 		})
@@ -205,6 +322,104 @@ func (h *UserHandler) UpdateMe(c *fiber.Ctx) error {
 	})
 }
 
+// CheckUsername says whether the current user could take a username, with
+// free alternatives when they can't
+// GET /users/@me/username-availability?username=
+func (h *UserHandler) CheckUsername(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	name := c.Query("username")
+	if name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "username is required",
+		})
+	}
+
+	availability, err := h.userService.CheckUsername(c.UserContext(), userID, name)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to check username",
+		})
+	}
+	return c.JSON(availability)
+}
+
+// GetUsernameHistory returns the current user's previous usernames and when
+// they may next change it
+// GET /users/@me/username-history
+func (h *UserHandler) GetUsernameHistory(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	history, retryAt, err := h.userService.GetUsernameHistory(c.UserContext(), userID)
+	if err != nil {
+		if err == services.ErrUserNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "user not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to get username history",
+		})
+	}
+
+	var nextChangeAt *time.Time
+	if !retryAt.IsZero() {
+		nextChangeAt = &retryAt
+	}
+	return c.JSON(fiber.Map{
+		"usernames":      history,
+		"next_change_at": nextChangeAt,
+	})
+}
+
+// SetCustomStatus sets the current user's custom status
+// PUT /users/@me/custom-status
+func (h *UserHandler) SetCustomStatus(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	var req models.CustomStatus
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	user, err := h.userService.SetCustomStatus(c.UserContext(), userID, &req)
+	if err != nil {
+		switch err {
+		case services.ErrCustomStatusEmpty, services.ErrCustomStatusTooLong, services.ErrCustomStatusExpired:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case services.ErrUserNotFound:
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "user not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to set custom status",
+		})
+	}
+
+	return c.JSON(user.ActiveCustomStatus(time.Now()))
+}
+
+// ClearCustomStatus removes the current user's custom status
+// DELETE /users/@me/custom-status
+func (h *UserHandler) ClearCustomStatus(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+
+	if _, err := h.userService.ClearCustomStatus(c.UserContext(), userID); err != nil {
+		if err == services.ErrUserNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "user not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to clear custom status",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
 // allowedAvatarTypes defines allowed content types for avatar uploads
 var allowedAvatarTypes = map[string]bool{
 	"image/jpeg": true,
@@ -251,7 +466,7 @@ func (h *UserHandler) UpdateAvatar(c *fiber.Ctx) error {
 	}
 
 	// Upload file
-	fileInfo, err := h.storageService.UploadFile(c.Context(), file, userID, "avatars")
+	fileInfo, err := h.storageService.UploadFile(c.UserContext(), file, userID, "avatars")
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to upload avatar",
@@ -263,10 +478,10 @@ func (h *UserHandler) UpdateAvatar(c *fiber.Ctx) error {
 		AvatarURL: &fileInfo.URL,
 	}
 
-	user, err := h.userService.UpdateUser(c.Context(), userID, updates)
+	user, err := h.userService.UpdateUser(c.UserContext(), userID, updates)
 	if err != nil {
 		// Attempt to clean up uploaded file on failure
-		_ = h.storageService.DeleteFile(c.Context(), fileInfo.Path)
+		_ = h.storageService.DeleteFile(c.UserContext(), fileInfo.Path)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to update avatar",
 		})
@@ -296,7 +511,7 @@ func (h *UserHandler) DeleteAvatar(c *fiber.Ctx) error {
 		AvatarURL: nilAvatar,
 	}
 
-	user, err := h.userService.UpdateUser(c.Context(), userID, updates)
+	user, err := h.userService.UpdateUser(c.UserContext(), userID, updates)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to remove avatar",
@@ -333,7 +548,7 @@ type ServerResponse struct {
 func (h *UserHandler) GetMyServers(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
 
-	servers, err := h.serverService.GetUserServers(c.Context(), userID)
+	servers, err := h.serverService.GetUserServers(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get servers",
@@ -374,7 +589,7 @@ type DMChannelResponse struct {
 func (h *UserHandler) GetMyDMs(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
 
-	channels, err := h.channelService.GetUserDMs(c.Context(), userID)
+	channels, err := h.channelService.GetUserDMs(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get DMs",
@@ -401,33 +616,29 @@ func (h *UserHandler) CreateDM(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
 
 	var req models.CreateDMRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
-		})
-	}
-
-	if req.RecipientID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "recipient_id is required",
-		})
-	}
-
-	recipientID, err := uuid.Parse(req.RecipientID)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid recipient_id",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
+	recipientID := uuid.MustParse(req.RecipientID) // Validated as a UUID
 	if recipientID == userID {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "cannot create DM with yourself",
 		})
 	}
 
-	channel, err := h.channelService.GetOrCreateDM(c.Context(), userID, recipientID)
+	channel, err := h.channelService.GetOrCreateDM(c.UserContext(), userID, recipientID)
 	if err != nil {
+		if err == services.ErrUserBlocked {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "cannot message this user",
+			})
+		}
+		if err == services.ErrDMNotAllowed {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to create DM channel",
 		})
@@ -447,33 +658,14 @@ func (h *UserHandler) CreateGroupDM(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
 
 	var req models.CreateGroupDMRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
-	if len(req.RecipientIDs) == 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "at least one recipient is required",
-		})
-	}
-
-	if len(req.RecipientIDs) > 9 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "group DM can have at most 10 members",
-		})
-	}
-
-	// Parse recipient UUIDs
+	// Parse recipient UUIDs, which were validated as UUIDs
 	recipientIDs := make([]uuid.UUID, 0, len(req.RecipientIDs))
 	for _, idStr := range req.RecipientIDs {
-		id, err := uuid.Parse(idStr)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": fmt.Sprintf("invalid recipient_id: %s", idStr),
-			})
-		}
+		id := uuid.MustParse(idStr)
 		if id == userID {
 			continue // Skip self, owner is automatically added
 		}
@@ -492,8 +684,13 @@ func (h *UserHandler) CreateGroupDM(c *fiber.Ctx) error {
 		name = *req.Name
 	}
 
-	channel, err := h.channelService.CreateGroupDM(c.Context(), userID, name, recipientIDs)
+	channel, err := h.channelService.CreateGroupDM(c.UserContext(), userID, name, recipientIDs)
 	if err != nil {
+		if err == services.ErrDMNotAllowed {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to create group DM",
 		})
@@ -518,7 +715,7 @@ func (h *UserHandler) GetUser(c *fiber.Ctx) error {
 		})
 	}
 
-	user, err := h.userService.GetUser(c.Context(), id)
+	user, err := h.userService.GetUser(c.UserContext(), id)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "user not found",
@@ -538,6 +735,36 @@ func (h *UserHandler) GetUser(c *fiber.Ctx) error {
 	})
 }
 
+// GetUsersBulk returns the public profiles of up to 100 users in one call,
+// for resolving the authors of messages a client hasn't seen before. Users
+// that don't exist are left out.
+func (h *UserHandler) GetUsersBulk(c *fiber.Ctx) error {
+	var req struct {
+		IDs []uuid.UUID `json:"ids" validate:"required,min=1"`
+	}
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	users, err := h.userService.GetUsers(c.UserContext(), req.IDs)
+	if err != nil {
+		if err == services.ErrTooManyUsers {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to get users",
+		})
+	}
+
+	response := make([]*UserResponse, len(users))
+	for i, user := range users {
+		response[i] = toUserResponse(user)
+	}
+	return c.JSON(response)
+}
+
 // MutualServerResponse represents a mutual server in API responses
 type MutualServerResponse struct {
 	ID      uuid.UUID `json:"id"`
@@ -596,7 +823,7 @@ func (h *UserHandler) GetUserProfile(c *fiber.Ctx) error {
 	}
 
 	// Get target user
-	user, err := h.userService.GetUser(c.Context(), targetID)
+	user, err := h.userService.GetUser(c.UserContext(), targetID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "user not found",
@@ -626,7 +853,7 @@ func (h *UserHandler) GetUserProfile(c *fiber.Ctx) error {
 
 	// Get mutual servers (limit to 10 for popout)
 	if svc, ok := h.serverService.(MutualServersService); ok {
-		servers, total, err := svc.GetMutualServersLimited(c.Context(), requesterID, targetID, 10)
+		servers, total, err := svc.GetMutualServersLimited(c.UserContext(), requesterID, targetID, 10)
 		if err == nil {
 			response.TotalMutual.Servers = total
 			for _, s := range servers {
@@ -641,7 +868,7 @@ func (h *UserHandler) GetUserProfile(c *fiber.Ctx) error {
 
 	// Get shared channels (limit to 10 for popout)
 	if svc, ok := h.channelService.(SharedChannelsService); ok {
-		channels, total, err := svc.GetSharedChannelsWithServerNames(c.Context(), requesterID, targetID, 10)
+		channels, total, err := svc.GetSharedChannelsWithServerNames(c.UserContext(), requesterID, targetID, 10)
 		if err == nil {
 			response.TotalMutual.Channels = total
 			for _, ch := range channels {
@@ -660,7 +887,7 @@ func (h *UserHandler) GetUserProfile(c *fiber.Ctx) error {
 
 	// Get mutual friends (limit to 10 for popout)
 	if svc, ok := h.userService.(MutualFriendsService); ok {
-		friends, total, err := svc.GetMutualFriends(c.Context(), requesterID, targetID, 10)
+		friends, total, err := svc.GetMutualFriends(c.UserContext(), requesterID, targetID, 10)
 		if err == nil {
 			response.TotalMutual.Friends = total
 			for _, f := range friends {
@@ -675,7 +902,7 @@ func (h *UserHandler) GetUserProfile(c *fiber.Ctx) error {
 
 	// Get recent activity
 	if svc, ok := h.userService.(RecentActivityService); ok {
-		activity, err := svc.GetRecentActivity(c.Context(), requesterID, targetID)
+		activity, err := svc.GetRecentActivity(c.UserContext(), requesterID, targetID)
 		if err == nil && activity != nil {
 			response.RecentActivity = &RecentActivityResponse{
 				LastMessageAt:   activity.LastMessageAt,
@@ -711,7 +938,7 @@ func (h *UserHandler) GetRelationships(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
 
 	// Get friends
-	friends, err := h.userService.GetFriends(c.Context(), userID)
+	friends, err := h.userService.GetFriends(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get relationships",
@@ -719,7 +946,7 @@ func (h *UserHandler) GetRelationships(c *fiber.Ctx) error {
 	}
 
 	// Get incoming friend requests
-	incoming, err := h.userService.GetIncomingFriendRequests(c.Context(), userID)
+	incoming, err := h.userService.GetIncomingFriendRequests(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get incoming requests",
@@ -727,14 +954,25 @@ func (h *UserHandler) GetRelationships(c *fiber.Ctx) error {
 	}
 
 	// Get outgoing friend requests
-	outgoing, err := h.userService.GetOutgoingFriendRequests(c.Context(), userID)
+	outgoing, err := h.userService.GetOutgoingFriendRequests(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get outgoing requests",
 		})
 	}
 
-	relationships := make([]RelationshipResponse, 0, len(friends)+len(incoming)+len(outgoing))
+	// Get blocked users when the service can list them
+	var blocked []*models.User
+	if blockedService, ok := h.userService.(BlockedUsersService); ok {
+		blocked, err = blockedService.GetBlockedUsers(c.UserContext(), userID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to get blocked users",
+			})
+		}
+	}
+
+	relationships := make([]RelationshipResponse, 0, len(friends)+len(incoming)+len(outgoing)+len(blocked))
 
 	for _, friend := range friends {
 		relationships = append(relationships, RelationshipResponse{
@@ -778,6 +1016,20 @@ func (h *UserHandler) GetRelationships(c *fiber.Ctx) error {
 		})
 	}
 
+	for _, user := range blocked {
+		relationships = append(relationships, RelationshipResponse{
+			ID:   user.ID,
+			Type: RelationshipTypeBlocked,
+			User: UserResponse{
+				ID:            user.ID,
+				Username:      user.Username,
+				Discriminator: user.Discriminator,
+				AvatarURL:     user.AvatarURL,
+				Flags:         user.Flags,
+			},
+		})
+	}
+
 	return c.JSON(relationships)
 }
 
@@ -787,20 +1039,18 @@ func (h *UserHandler) CreateRelationship(c *fiber.Ctx) error {
 
 	var req struct {
 		UserID   uuid.UUID        `json:"user_id"`
-		Type     RelationshipType `json:"type"`
+		Type     RelationshipType `json:"type" validate:"required,oneof=1 2"`
 		Username string           `json:"username"` // Alternative to user_id
 	}
 
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
 	// Resolve username to user_id if provided
 	targetID := req.UserID
 	if req.Username != "" && targetID == uuid.Nil {
-		targetUser, err := h.userService.GetUserByUsername(c.Context(), req.Username)
+		targetUser, err := h.userService.GetUserByUsername(c.UserContext(), req.Username)
 		if err != nil {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "user not found",
@@ -824,7 +1074,7 @@ func (h *UserHandler) CreateRelationship(c *fiber.Ctx) error {
 	switch req.Type {
 	case RelationshipTypeFriend:
 		// Send a friend request (pending state)
-		if err := h.userService.SendFriendRequest(c.Context(), userID, targetID); err != nil {
+		if err := h.userService.SendFriendRequest(c.UserContext(), userID, targetID); err != nil {
 			if strings.Contains(err.Error(), "already friends") {
 				return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 					"error": "already friends",
@@ -845,7 +1095,7 @@ func (h *UserHandler) CreateRelationship(c *fiber.Ctx) error {
 			})
 		}
 	case RelationshipTypeBlocked:
-		if err := h.userService.BlockUser(c.Context(), userID, targetID); err != nil {
+		if err := h.userService.BlockUser(c.UserContext(), userID, targetID); err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "failed to block user",
 			})
@@ -871,7 +1121,7 @@ func (h *UserHandler) AcceptFriendRequest(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.userService.AcceptFriendRequest(c.Context(), userID, senderID); err != nil {
+	if err := h.userService.AcceptFriendRequest(c.UserContext(), userID, senderID); err != nil {
 		if strings.Contains(err.Error(), "no pending") {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "no pending friend request from this user",
@@ -897,7 +1147,7 @@ func (h *UserHandler) DeclineFriendRequest(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.userService.DeclineFriendRequest(c.Context(), userID, otherID); err != nil {
+	if err := h.userService.DeclineFriendRequest(c.UserContext(), userID, otherID); err != nil {
 		if strings.Contains(err.Error(), "no pending") {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "no pending friend request",
@@ -915,7 +1165,7 @@ func (h *UserHandler) DeclineFriendRequest(c *fiber.Ctx) error {
 func (h *UserHandler) GetFriends(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
 
-	friends, err := h.userService.GetFriends(c.Context(), userID)
+	friends, err := h.userService.GetFriends(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get friends",
@@ -942,7 +1192,7 @@ func (h *UserHandler) GetPendingFriendRequests(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uuid.UUID)
 
 	// Get incoming friend requests
-	incoming, err := h.userService.GetIncomingFriendRequests(c.Context(), userID)
+	incoming, err := h.userService.GetIncomingFriendRequests(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get incoming requests",
@@ -950,7 +1200,7 @@ func (h *UserHandler) GetPendingFriendRequests(c *fiber.Ctx) error {
 	}
 
 	// Get outgoing friend requests
-	outgoing, err := h.userService.GetOutgoingFriendRequests(c.Context(), userID)
+	outgoing, err := h.userService.GetOutgoingFriendRequests(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to get outgoing requests",
@@ -997,14 +1247,24 @@ func (h *UserHandler) DeleteRelationship(c *fiber.Ctx) error {
 		})
 	}
 
-	// Try to remove friend first
-	if err := h.userService.RemoveFriend(c.Context(), userID, targetID); err != nil {
-		// If not a friend, try to unblock
-		if err := h.userService.UnblockUser(c.Context(), userID, targetID); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "failed to remove relationship",
-			})
-		}
+	relType, err := h.userService.GetRelationship(c.UserContext(), userID, targetID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to remove relationship",
+		})
+	}
+
+	// Blocks are lifted separately; anything else is a friendship or a
+	// pending request, which RemoveFriend clears on both sides
+	if RelationshipType(relType) == RelationshipTypeBlocked {
+		err = h.userService.UnblockUser(c.UserContext(), userID, targetID)
+	} else {
+		err = h.userService.RemoveFriend(c.UserContext(), userID, targetID)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to remove relationship",
+		})
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
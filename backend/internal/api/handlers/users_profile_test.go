@@ -51,6 +51,14 @@ func (m *MockMutualFriendsService) GetUser(ctx context.Context, id uuid.UUID) (*
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *MockMutualFriendsService) GetUsers(ctx context.Context, ids []uuid.UUID) ([]*models.User, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
 func (m *MockMutualFriendsService) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
 	args := m.Called(ctx, username)
 	if args.Get(0) == nil {
@@ -131,6 +139,38 @@ func (m *MockMutualFriendsService) GetRelationship(ctx context.Context, userID,
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockMutualFriendsService) SetCustomStatus(ctx context.Context, userID uuid.UUID, status *models.CustomStatus) (*models.User, error) {
+	args := m.Called(ctx, userID, status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockMutualFriendsService) ClearCustomStatus(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockMutualFriendsService) CheckUsername(ctx context.Context, requesterID uuid.UUID, name string) (*models.UsernameAvailability, error) {
+	args := m.Called(ctx, requesterID, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UsernameAvailability), args.Error(1)
+}
+
+func (m *MockMutualFriendsService) GetUsernameHistory(ctx context.Context, userID uuid.UUID) ([]*models.UsernameChange, time.Time, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, time.Time{}, args.Error(2)
+	}
+	return args.Get(0).([]*models.UsernameChange), args.Get(1).(time.Time), args.Error(2)
+}
+
 func (m *MockMutualFriendsService) GetMutualFriends(ctx context.Context, userID1, userID2 uuid.UUID, limit int) ([]*models.User, int, error) {
 	args := m.Called(ctx, userID1, userID2, limit)
 	if args.Get(0) == nil {
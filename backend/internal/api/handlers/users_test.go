@@ -15,6 +15,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"hearth/internal/auth"
 	"hearth/internal/models"
 	"hearth/internal/services"
 	"hearth/internal/storage"
@@ -33,6 +34,14 @@ func (m *MockUserService) GetUser(ctx context.Context, id uuid.UUID) (*models.Us
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *MockUserService) GetUsers(ctx context.Context, ids []uuid.UUID) ([]*models.User, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
 func (m *MockUserService) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
 	args := m.Called(ctx, username)
 	if args.Get(0) == nil {
@@ -113,6 +122,38 @@ func (m *MockUserService) GetRelationship(ctx context.Context, userID, targetID
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockUserService) SetCustomStatus(ctx context.Context, userID uuid.UUID, status *models.CustomStatus) (*models.User, error) {
+	args := m.Called(ctx, userID, status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserService) ClearCustomStatus(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserService) CheckUsername(ctx context.Context, requesterID uuid.UUID, name string) (*models.UsernameAvailability, error) {
+	args := m.Called(ctx, requesterID, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UsernameAvailability), args.Error(1)
+}
+
+func (m *MockUserService) GetUsernameHistory(ctx context.Context, userID uuid.UUID) ([]*models.UsernameChange, time.Time, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, time.Time{}, args.Error(2)
+	}
+	return args.Get(0).([]*models.UsernameChange), args.Get(1).(time.Time), args.Error(2)
+}
+
 // MockServerServiceForUsers mocks the ServerService for user handler testing
 type MockServerServiceForUsers struct {
 	mock.Mock
@@ -187,7 +228,12 @@ func newTestUserHandler() *testUserHandler {
 
 	// Setup routes
 	app.Get("/users/@me", handler.GetMe)
+	app.Get("/users/@me/accounts", handler.GetMyAccounts)
+	app.Get("/users/@me/username-availability", handler.CheckUsername)
+	app.Get("/users/@me/username-history", handler.GetUsernameHistory)
 	app.Patch("/users/@me", handler.UpdateMe)
+	app.Put("/users/@me/custom-status", handler.SetCustomStatus)
+	app.Delete("/users/@me/custom-status", handler.ClearCustomStatus)
 	app.Get("/users/@me/servers", handler.GetMyServers)
 	app.Get("/users/@me/channels", handler.GetMyDMs)
 	app.Post("/users/@me/channels", handler.CreateDM)
@@ -199,6 +245,7 @@ func newTestUserHandler() *testUserHandler {
 	app.Get("/users/@me/friends/pending", handler.GetPendingFriendRequests)
 	app.Put("/users/@me/friends/:id", handler.AcceptFriendRequest)
 	app.Delete("/users/@me/friends/:id/request", handler.DeclineFriendRequest)
+	app.Post("/users/bulk", handler.GetUsersBulk)
 	app.Get("/users/:id", handler.GetUser)
 
 	return &testUserHandler{
@@ -256,6 +303,73 @@ func TestUserHandler_GetMe_NotFound(t *testing.T) {
 	th.userService.AssertExpectations(t)
 }
 
+// fakeAccountTokens maps switcher tokens to the account or error they resolve to
+type fakeAccountTokens map[string]interface{}
+
+func (f fakeAccountTokens) ValidateToken(ctx context.Context, token string) (uuid.UUID, error) {
+	switch v := f[token].(type) {
+	case uuid.UUID:
+		return v, nil
+	case error:
+		return uuid.Nil, v
+	}
+	return uuid.Nil, auth.ErrInvalidToken
+}
+
+func TestUserHandler_GetMyAccounts(t *testing.T) {
+	th := newTestUserHandler()
+	otherID, deletedID := uuid.New(), uuid.New()
+	th.handler.SetAccountTokenValidator(fakeAccountTokens{
+		"other":   otherID,
+		"self":    th.userID,
+		"expired": auth.ErrExpiredToken,
+		"deleted": deletedID,
+	})
+
+	th.userService.On("GetUser", mock.Anything, th.userID).Return(&models.User{ID: th.userID, Username: "main", Email: "main@example.com"}, nil)
+	th.userService.On("GetUser", mock.Anything, otherID).Return(&models.User{ID: otherID, Username: "alt", Email: "alt@example.com"}, nil)
+	th.userService.On("GetUser", mock.Anything, deletedID).Return(nil, services.ErrUserNotFound)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/@me/accounts", nil)
+	req.Header.Set("X-Account-Tokens", "other, expired,garbage,deleted,self")
+	resp, err := th.app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var accounts []AccountResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&accounts))
+	if assert.Len(t, accounts, 6) {
+		assert.True(t, accounts[0].Current)
+		assert.Equal(t, "main", accounts[0].User.Username)
+
+		assert.False(t, accounts[1].Current)
+		assert.Equal(t, AccountTokenValid, accounts[1].TokenStatus)
+		assert.Equal(t, "alt", accounts[1].User.Username)
+		assert.Equal(t, "alt@example.com", *accounts[1].User.Email)
+
+		assert.Equal(t, AccountTokenExpired, accounts[2].TokenStatus)
+		assert.Nil(t, accounts[2].User)
+		assert.Equal(t, AccountTokenInvalid, accounts[3].TokenStatus)
+		assert.Equal(t, AccountTokenInvalid, accounts[4].TokenStatus, "tokens of deleted accounts")
+		assert.Nil(t, accounts[4].User)
+
+		assert.True(t, accounts[5].Current)
+		assert.Equal(t, AccountTokenValid, accounts[5].TokenStatus)
+	}
+}
+
+func TestUserHandler_GetMyAccounts_TooMany(t *testing.T) {
+	th := newTestUserHandler()
+	th.handler.SetAccountTokenValidator(fakeAccountTokens{})
+	th.userService.On("GetUser", mock.Anything, th.userID).Return(&models.User{ID: th.userID}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/@me/accounts", nil)
+	req.Header.Set("X-Account-Tokens", "a,b,c,d,e,f")
+	resp, err := th.app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
 func TestUserHandler_UpdateMe(t *testing.T) {
 	th := newTestUserHandler()
 
@@ -334,6 +448,169 @@ func TestUserHandler_UpdateMe_UsernameTaken(t *testing.T) {
 	th.userService.AssertExpectations(t)
 }
 
+func TestUserHandler_UpdateMe_UsernameCooldown(t *testing.T) {
+	th := newTestUserHandler()
+	retryAt := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+
+	th.userService.On("UpdateUser", mock.Anything, th.userID, mock.Anything).Return(nil, &services.UsernameCooldownError{RetryAt: retryAt})
+
+	req := httptest.NewRequest(http.MethodPatch, "/users/@me", bytes.NewReader([]byte(`{"username":"newname"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := th.app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	assert.Equal(t, retryAt.Format(time.RFC3339), result["next_change_at"])
+}
+
+func TestUserHandler_UpdateMe_InvalidUsername(t *testing.T) {
+	th := newTestUserHandler()
+
+	th.userService.On("UpdateUser", mock.Anything, th.userID, mock.Anything).Return(nil, services.ErrInvalidUsername)
+
+	req := httptest.NewRequest(http.MethodPatch, "/users/@me", bytes.NewReader([]byte(`{"username":"has space"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := th.app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestUserHandler_UpdateMe_UsernameRules(t *testing.T) {
+	for _, tt := range []struct {
+		err    error
+		status int
+	}{
+		{services.ErrUsernameReserved, http.StatusConflict},
+		{services.ErrUsernameBanned, http.StatusBadRequest},
+	} {
+		th := newTestUserHandler()
+		th.userService.On("UpdateUser", mock.Anything, th.userID, mock.Anything).Return(nil, tt.err)
+
+		req := httptest.NewRequest(http.MethodPatch, "/users/@me", bytes.NewReader([]byte(`{"username":"admin"}`)))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := th.app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.status, resp.StatusCode, tt.err.Error())
+	}
+}
+
+func TestUserHandler_CheckUsername(t *testing.T) {
+	th := newTestUserHandler()
+
+	th.userService.On("CheckUsername", mock.Anything, th.userID, "bob").Return(&models.UsernameAvailability{
+		Username:    "bob",
+		Reason:      "taken",
+		Suggestions: []string{"bob_", "bob42"},
+	}, nil)
+
+	resp, err := th.app.Test(httptest.NewRequest(http.MethodGet, "/users/@me/username-availability?username=bob", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	assert.Equal(t, false, result["available"])
+	assert.Equal(t, "taken", result["reason"])
+	assert.Equal(t, []interface{}{"bob_", "bob42"}, result["suggestions"])
+
+	resp, err = th.app.Test(httptest.NewRequest(http.MethodGet, "/users/@me/username-availability", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestUserHandler_GetUsernameHistory(t *testing.T) {
+	th := newTestUserHandler()
+	changedAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+
+	th.userService.On("GetUsernameHistory", mock.Anything, th.userID).Return(
+		[]*models.UsernameChange{{Username: "oldname", ChangedAt: changedAt}}, time.Time{}, nil)
+
+	resp, err := th.app.Test(httptest.NewRequest(http.MethodGet, "/users/@me/username-history", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	assert.Nil(t, result["next_change_at"])
+	usernames := result["usernames"].([]interface{})
+	assert.Len(t, usernames, 1)
+	assert.Equal(t, "oldname", usernames[0].(map[string]interface{})["username"])
+}
+
+func TestUserHandler_SetCustomStatus(t *testing.T) {
+	th := newTestUserHandler()
+
+	text := "In a meeting"
+	emoji := "📅"
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	th.userService.On("SetCustomStatus", mock.Anything, th.userID, mock.MatchedBy(func(s *models.CustomStatus) bool {
+		return s.Text != nil && *s.Text == text && s.ExpiresAt != nil && s.ExpiresAt.Equal(expiresAt)
+	})).Return(&models.User{
+		ID:                    th.userID,
+		CustomStatus:          &text,
+		CustomStatusEmoji:     &emoji,
+		CustomStatusExpiresAt: &expiresAt,
+	}, nil)
+
+	bodyBytes, _ := json.Marshal(map[string]interface{}{
+		"text":       text,
+		"emoji":      emoji,
+		"expires_at": expiresAt,
+	})
+
+	req := httptest.NewRequest(http.MethodPut, "/users/@me/custom-status", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := th.app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result models.CustomStatus
+	json.NewDecoder(resp.Body).Decode(&result)
+
+	assert.Equal(t, text, *result.Text)
+	assert.Equal(t, emoji, *result.Emoji)
+	assert.True(t, expiresAt.Equal(*result.ExpiresAt))
+
+	th.userService.AssertExpectations(t)
+}
+
+func TestUserHandler_SetCustomStatus_Invalid(t *testing.T) {
+	th := newTestUserHandler()
+
+	th.userService.On("SetCustomStatus", mock.Anything, th.userID, mock.Anything).
+		Return(nil, services.ErrCustomStatusEmpty)
+
+	req := httptest.NewRequest(http.MethodPut, "/users/@me/custom-status", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := th.app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestUserHandler_ClearCustomStatus(t *testing.T) {
+	th := newTestUserHandler()
+
+	th.userService.On("ClearCustomStatus", mock.Anything, th.userID).
+		Return(&models.User{ID: th.userID}, nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/users/@me/custom-status", nil)
+	resp, err := th.app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	th.userService.AssertExpectations(t)
+}
+
 func TestUserHandler_GetMyServers(t *testing.T) {
 	th := newTestUserHandler()
 
@@ -446,7 +723,8 @@ func TestUserHandler_CreateDM_MissingRecipient(t *testing.T) {
 
 	var result map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&result)
-	assert.Equal(t, "recipient_id is required", result["error"])
+	assert.Equal(t, "invalid request body", result["error"])
+	assert.Equal(t, map[string]interface{}{"recipient_id": "is required"}, result["fields"])
 }
 
 func TestUserHandler_CreateDM_InvalidRecipient(t *testing.T) {
@@ -466,7 +744,8 @@ func TestUserHandler_CreateDM_InvalidRecipient(t *testing.T) {
 
 	var result map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&result)
-	assert.Equal(t, "invalid recipient_id", result["error"])
+	assert.Equal(t, "invalid request body", result["error"])
+	assert.Equal(t, map[string]interface{}{"recipient_id": "must be a UUID"}, result["fields"])
 }
 
 func TestUserHandler_CreateDM_Self(t *testing.T) {
@@ -572,7 +851,8 @@ func TestUserHandler_CreateGroupDM_NoRecipients(t *testing.T) {
 
 	var result map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&result)
-	assert.Equal(t, "at least one recipient is required", result["error"])
+	assert.Equal(t, "invalid request body", result["error"])
+	assert.Equal(t, map[string]interface{}{"recipient_ids": "must be at least 1 item"}, result["fields"])
 }
 
 func TestUserHandler_CreateGroupDM_TooManyRecipients(t *testing.T) {
@@ -598,7 +878,8 @@ func TestUserHandler_CreateGroupDM_TooManyRecipients(t *testing.T) {
 
 	var result map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&result)
-	assert.Equal(t, "group DM can have at most 10 members", result["error"])
+	assert.Equal(t, "invalid request body", result["error"])
+	assert.Equal(t, map[string]interface{}{"recipient_ids": "must be at most 9 items"}, result["fields"])
 }
 
 func TestUserHandler_CreateGroupDM_InvalidRecipient(t *testing.T) {
@@ -618,7 +899,8 @@ func TestUserHandler_CreateGroupDM_InvalidRecipient(t *testing.T) {
 
 	var result map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&result)
-	assert.Contains(t, result["error"], "invalid recipient_id")
+	assert.Equal(t, "invalid request body", result["error"])
+	assert.Equal(t, map[string]interface{}{"recipient_ids[0]": "must be a UUID"}, result["fields"])
 }
 
 func TestUserHandler_CreateGroupDM_OnlySelf(t *testing.T) {
@@ -735,6 +1017,58 @@ func TestUserHandler_GetUser(t *testing.T) {
 	th.userService.AssertExpectations(t)
 }
 
+func TestUserHandler_GetUsersBulk(t *testing.T) {
+	th := newTestUserHandler()
+
+	first, second := uuid.New(), uuid.New()
+	users := []*models.User{
+		{ID: first, Username: "first", Email: "first@example.com"},
+		{ID: second, Username: "second"},
+	}
+	th.userService.On("GetUsers", mock.Anything, []uuid.UUID{first, second}).Return(users, nil)
+
+	body, _ := json.Marshal(map[string]interface{}{"ids": []uuid.UUID{first, second}})
+	req := httptest.NewRequest(http.MethodPost, "/users/bulk", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := th.app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result []UserResponse
+	json.NewDecoder(resp.Body).Decode(&result)
+
+	assert.Len(t, result, 2)
+	assert.Equal(t, "first", result[0].Username)
+	assert.Nil(t, result[0].Email)
+	assert.Equal(t, second, result[1].ID)
+}
+
+func TestUserHandler_GetUsersBulk_Invalid(t *testing.T) {
+	th := newTestUserHandler()
+
+	for name, body := range map[string]string{
+		"empty":   `{"ids":[]}`,
+		"bad id":  `{"ids":["nope"]}`,
+		"no body": `{`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/users/bulk", bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := th.app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, name)
+	}
+
+	th.userService.On("GetUsers", mock.Anything, mock.Anything).Return(nil, services.ErrTooManyUsers)
+	req := httptest.NewRequest(http.MethodPost, "/users/bulk", bytes.NewReader([]byte(`{"ids":["`+uuid.NewString()+`"]}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := th.app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
 func TestUserHandler_GetRelationships(t *testing.T) {
 	th := newTestUserHandler()
 
@@ -833,6 +1167,7 @@ func TestUserHandler_DeleteRelationship(t *testing.T) {
 	th := newTestUserHandler()
 
 	friendID := uuid.New()
+	th.userService.On("GetRelationship", mock.Anything, th.userID, friendID).Return(int(RelationshipTypeFriend), nil)
 	th.userService.On("RemoveFriend", mock.Anything, th.userID, friendID).Return(nil)
 
 	req := httptest.NewRequest(http.MethodDelete, "/users/@me/relationships/"+friendID.String(), nil)
@@ -844,6 +1179,79 @@ func TestUserHandler_DeleteRelationship(t *testing.T) {
 	th.userService.AssertExpectations(t)
 }
 
+// blockingUserService adds the optional BlockedUsersService to MockUserService
+type blockingUserService struct {
+	*MockUserService
+}
+
+func (m *blockingUserService) GetBlockedUsers(ctx context.Context, userID uuid.UUID) ([]*models.User, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func TestUserHandler_GetRelationships_IncludesBlocked(t *testing.T) {
+	th := newTestUserHandler()
+	th.handler.userService = &blockingUserService{th.userService}
+
+	blockedID := uuid.New()
+	th.userService.On("GetFriends", mock.Anything, th.userID).Return([]*models.User{}, nil)
+	th.userService.On("GetIncomingFriendRequests", mock.Anything, th.userID).Return([]*models.User{}, nil)
+	th.userService.On("GetOutgoingFriendRequests", mock.Anything, th.userID).Return([]*models.User{}, nil)
+	th.userService.On("GetBlockedUsers", mock.Anything, th.userID).Return([]*models.User{{ID: blockedID, Username: "blocked"}}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/@me/relationships", nil)
+	resp, err := th.app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result []RelationshipResponse
+	json.NewDecoder(resp.Body).Decode(&result)
+
+	if assert.Len(t, result, 1) {
+		assert.Equal(t, blockedID, result[0].ID)
+		assert.Equal(t, RelationshipTypeBlocked, result[0].Type)
+	}
+}
+
+func TestUserHandler_DeleteRelationship_Unblocks(t *testing.T) {
+	th := newTestUserHandler()
+
+	blockedID := uuid.New()
+	th.userService.On("GetRelationship", mock.Anything, th.userID, blockedID).Return(int(RelationshipTypeBlocked), nil)
+	th.userService.On("UnblockUser", mock.Anything, th.userID, blockedID).Return(nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/users/@me/relationships/"+blockedID.String(), nil)
+	resp, err := th.app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	th.userService.AssertExpectations(t)
+	th.userService.AssertNotCalled(t, "RemoveFriend", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_CreateDM_Blocked(t *testing.T) {
+	th := newTestUserHandler()
+
+	recipientID := uuid.New()
+	th.channelService.On("GetOrCreateDM", mock.Anything, th.userID, recipientID).Return(nil, services.ErrUserBlocked)
+
+	body := map[string]interface{}{
+		"recipient_id": recipientID.String(),
+	}
+	bodyBytes, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/users/@me/channels", bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := th.app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
 // MockStorageService mocks the storage service for testing
 type MockStorageService struct {
 	mock.Mock
@@ -1311,6 +1719,7 @@ func TestUserHandler_RemoveFriend_Success(t *testing.T) {
 	th := newTestUserHandler()
 
 	friendID := uuid.New()
+	th.userService.On("GetRelationship", mock.Anything, th.userID, friendID).Return(int(RelationshipTypeFriend), nil)
 	th.userService.On("RemoveFriend", mock.Anything, th.userID, friendID).Return(nil)
 
 	req := httptest.NewRequest(http.MethodDelete, "/users/@me/relationships/"+friendID.String(), nil)
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+// errInvalidBody is returned for a request body that couldn't be decoded
+var errInvalidBody = errors.New("invalid request body")
+
+// requestValidator checks request bodies against their validate tags,
+// naming fields by their JSON names
+var requestValidator = newRequestValidator()
+
+func newRequestValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
+// fieldErrors maps each invalid field of a request body, by its JSON path,
+// to what's wrong with it
+type fieldErrors map[string]string
+
+func (e fieldErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field, problem := range e {
+		fields = append(fields, field+" "+problem)
+	}
+	return "invalid request body: " + strings.Join(fields, "; ")
+}
+
+// parseBody decodes the request body into req and checks it against its
+// validate tags. It returns errInvalidBody if the body can't be decoded
+// and fieldErrors if it breaks the rules.
+func parseBody(c *fiber.Ctx, req interface{}) error {
+	if err := c.BodyParser(req); err != nil {
+		return errInvalidBody
+	}
+	return validateRequest(req)
+}
+
+// validateRequest checks a request struct, or a slice of them, against its
+// validate tags
+func validateRequest(req interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(req))
+
+	var err error
+	switch value.Kind() {
+	case reflect.Struct:
+		err = requestValidator.Struct(value.Interface())
+	case reflect.Slice, reflect.Array:
+		err = requestValidator.Var(value.Interface(), "dive")
+	default:
+		return nil
+	}
+
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return err
+	}
+	fields := make(fieldErrors, len(invalid))
+	for _, fe := range invalid {
+		fields[fieldPath(fe)] = fieldProblem(fe)
+	}
+	return fields
+}
+
+// invalidBody responds 400 to a parseBody error, listing what's wrong with
+// each invalid field
+func invalidBody(c *fiber.Ctx, err error) error {
+	body := fiber.Map{"error": errInvalidBody.Error()}
+	var fields fieldErrors
+	if errors.As(err, &fields) {
+		body["fields"] = fields
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(body)
+}
+
+// fieldPath is a field's JSON path within the request, like name or
+// options[1].text
+func fieldPath(fe validator.FieldError) string {
+	path := fe.Namespace()
+	// The namespace starts with the request type, which anonymous structs
+	// leave empty
+	if i := strings.IndexAny(path, ".["); i >= 0 {
+		path = strings.TrimPrefix(path[i:], ".")
+	}
+	return path
+}
+
+// fieldProblem describes why a field failed its check
+func fieldProblem(fe validator.FieldError) string {
+	kind := fe.Kind()
+	if kind == reflect.Ptr {
+		kind = fe.Type().Elem().Kind()
+	}
+
+	switch fe.Tag() {
+	case "required", "required_without", "required_with":
+		return "is required"
+	case "min", "gte":
+		return "must be at least " + sizeOf(kind, fe.Param())
+	case "max", "lte":
+		return "must be at most " + sizeOf(kind, fe.Param())
+	case "len":
+		return "must be exactly " + sizeOf(kind, fe.Param())
+	case "gt":
+		return "must be more than " + fe.Param()
+	case "lt":
+		return "must be less than " + fe.Param()
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "url", "http_url":
+		return "must be a valid URL"
+	case "email":
+		return "must be a valid email address"
+	case "uuid", "uuid4":
+		return "must be a UUID"
+	case "hexcolor":
+		return "must be a hex color"
+	case "unique":
+		return "must not repeat items"
+	case "excluded_with":
+		return "can't be set together with " + fe.Param()
+	default:
+		return "is invalid"
+	}
+}
+
+// sizeOf puts a min or max in the units the field is measured in
+func sizeOf(kind reflect.Kind, param string) string {
+	switch kind {
+	case reflect.String:
+		if param == "1" {
+			return "1 character"
+		}
+		return param + " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if param == "1" {
+			return "1 item"
+		}
+		return param + " items"
+	default:
+		return param
+	}
+}
+
+// validatedBodyFields returns the field errors in a parseBody error, for
+// handlers with their own error format
+func validatedBodyFields(err error) fieldErrors {
+	var fields fieldErrors
+	errors.As(err, &fields)
+	return fields
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type validationTestOption struct {
+	Text  string `json:"text" validate:"required,max=5"`
+	Votes int    `json:"votes" validate:"min=0"`
+}
+
+type validationTestRequest struct {
+	Name    string                 `json:"name" validate:"required,min=2,max=10"`
+	Kind    string                 `json:"kind" validate:"omitempty,oneof=a b"`
+	Link    *string                `json:"link,omitempty" validate:"omitempty,url"`
+	Owner   string                 `json:"owner_id" validate:"omitempty,uuid"`
+	Options []validationTestOption `json:"options" validate:"max=3,dive"`
+}
+
+func newValidationTestApp() *fiber.App {
+	app := fiber.New()
+	app.Post("/", func(c *fiber.Ctx) error {
+		var req validationTestRequest
+		if err := parseBody(c, &req); err != nil {
+			return invalidBody(c, err)
+		}
+		return c.JSON(req)
+	})
+	return app
+}
+
+func postValidationTest(t *testing.T, app *fiber.App, body string) (int, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+
+	var result map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	return resp.StatusCode, result
+}
+
+func TestParseBody_Valid(t *testing.T) {
+	status, result := postValidationTest(t, newValidationTestApp(), `{"name":"General","kind":"a","link":"https://example.com","options":[{"text":"yes"}]}`)
+
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "General", result["name"])
+}
+
+func TestParseBody_FieldErrors(t *testing.T) {
+	status, result := postValidationTest(t, newValidationTestApp(), `{
+		"name": "x",
+		"kind": "c",
+		"link": "not a url",
+		"owner_id": "123",
+		"options": [{"text": "fine"}, {"text": "too long", "votes": -1}]
+	}`)
+
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Equal(t, "invalid request body", result["error"])
+	assert.Equal(t, map[string]interface{}{
+		"name":             "must be at least 2 characters",
+		"kind":             "must be one of a, b",
+		"link":             "must be a valid URL",
+		"owner_id":         "must be a UUID",
+		"options[1].text":  "must be at most 5 characters",
+		"options[1].votes": "must be at least 0",
+	}, result["fields"])
+}
+
+func TestParseBody_Required(t *testing.T) {
+	status, result := postValidationTest(t, newValidationTestApp(), `{"options":[{},{},{},{}]}`)
+
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Equal(t, map[string]interface{}{
+		"name":    "is required",
+		"options": "must be at most 3 items",
+	}, result["fields"])
+}
+
+func TestParseBody_Malformed(t *testing.T) {
+	status, result := postValidationTest(t, newValidationTestApp(), `{"name":`)
+
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Equal(t, "invalid request body", result["error"])
+	assert.NotContains(t, result, "fields")
+}
+
+func TestValidateRequest_Slice(t *testing.T) {
+	req := []validationTestOption{{Text: "ok"}, {}}
+
+	err := validateRequest(&req)
+
+	var fields fieldErrors
+	require.ErrorAs(t, err, &fields)
+	assert.Equal(t, fieldErrors{"[1].text": "is required"}, fields)
+}
+
+func TestValidateRequest_Untagged(t *testing.T) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	assert.NoError(t, validateRequest(&req))
+	assert.NoError(t, validateRequest(&[]string{""}))
+}
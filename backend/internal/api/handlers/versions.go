@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"hearth/internal/services"
+)
+
+// errInvalidIfMatch is returned for an If-Match header that isn't a version
+// ETag we issued
+var errInvalidIfMatch = errors.New("invalid If-Match header")
+
+// setVersionETag advertises a resource's version so clients can send it back
+// in If-Match
+func setVersionETag(c *fiber.Ctx, version int) {
+	c.Set(fiber.HeaderETag, `"`+strconv.Itoa(version)+`"`)
+}
+
+// expectedVersion returns the version a PATCH was based on: the If-Match
+// header if present, otherwise the body's version field. nil means the
+// client didn't ask for a check; "If-Match: *" is treated the same way.
+func expectedVersion(c *fiber.Ctx, bodyVersion *int) (*int, error) {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" {
+		return bodyVersion, nil
+	}
+	if header == "*" {
+		return nil, nil
+	}
+
+	tag := strings.TrimPrefix(header, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return nil, errInvalidIfMatch
+	}
+	version, err := strconv.Atoi(tag[1 : len(tag)-1])
+	if err != nil {
+		return nil, errInvalidIfMatch
+	}
+	return &version, nil
+}
+
+// versionConflict responds 412 with the current state when err is a
+// version conflict, and reports whether it did
+func versionConflict(c *fiber.Ctx, err error) (bool, error) {
+	var conflict *services.VersionConflictError
+	if !errors.As(err, &conflict) {
+		return false, nil
+	}
+	setVersionETag(c, conflict.Version)
+	return true, c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+		"error":   "modified since it was read",
+		"current": conflict.Current,
+	})
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+func TestExpectedVersion(t *testing.T) {
+	bodyVersion := 2
+
+	tests := []struct {
+		name    string
+		ifMatch string
+		body    *int
+		want    *int
+		wantErr bool
+	}{
+		{name: "no header uses body", body: &bodyVersion, want: &bodyVersion},
+		{name: "no header no body", want: nil},
+		{name: "header wins over body", ifMatch: `"5"`, body: &bodyVersion, want: intPtr(5)},
+		{name: "weak etag", ifMatch: `W/"7"`, want: intPtr(7)},
+		{name: "wildcard skips check", ifMatch: "*", body: &bodyVersion, want: nil},
+		{name: "unquoted", ifMatch: "5", wantErr: true},
+		{name: "not a number", ifMatch: `"abc"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error {
+				got, err := expectedVersion(c, tt.body)
+				if tt.wantErr {
+					assert.ErrorIs(t, err, errInvalidIfMatch)
+					return nil
+				}
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+				return nil
+			})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			_, err := app.Test(req)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestVersionConflict(t *testing.T) {
+	app := fiber.New()
+	app.Patch("/", func(c *fiber.Ctx) error {
+		err := &services.VersionConflictError{
+			Current: &models.Channel{Name: "theirs", Version: 4},
+			Version: 4,
+		}
+		if handled, resp := versionConflict(c, err); handled {
+			return resp
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("PATCH", "/", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusPreconditionFailed, resp.StatusCode)
+	assert.Equal(t, `"4"`, resp.Header.Get("ETag"))
+
+	var body struct {
+		Current models.Channel `json:"current"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "theirs", body.Current.Name)
+	assert.Equal(t, 4, body.Current.Version)
+}
+
+func TestVersionConflict_OtherError(t *testing.T) {
+	app := fiber.New()
+	app.Patch("/", func(c *fiber.Ctx) error {
+		if handled, resp := versionConflict(c, services.ErrChannelNotFound); handled {
+			return resp
+		}
+		return c.SendStatus(fiber.StatusNotFound)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("PATCH", "/", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("ETag"))
+}
+
+func intPtr(v int) *int { return &v }
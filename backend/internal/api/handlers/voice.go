@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/voice"
+)
+
+// VoiceManager defines the methods needed from voice.Manager
+type VoiceManager interface {
+	Join(ctx context.Context, p voice.Participant, channelID uuid.UUID) (*voice.Session, error)
+	Leave(ctx context.Context, userID uuid.UUID) error
+	HandleWebhook(ctx context.Context, authorization string, body []byte) error
+	StartStream(userID uuid.UUID, applicationName string) (*voice.Stream, error)
+	StopStream(userID uuid.UUID)
+	WatchStream(viewerID, channelID, streamerID uuid.UUID) (*voice.Stream, error)
+	UnwatchStream(viewerID, channelID, streamerID uuid.UUID) (*voice.Stream, error)
+	Streams(ctx context.Context, requesterID, channelID uuid.UUID) ([]*voice.Stream, error)
+}
+
+// VoiceHandler handles voice operations
+type VoiceHandler struct {
+	// manager is nil when no SFU is configured
+	manager VoiceManager
+}
+
+func NewVoiceHandler() *VoiceHandler {
+	return &VoiceHandler{}
+}
+
+// NewVoiceHandlerWithManager creates a voice handler that can join users
+// to channels on an SFU
+func NewVoiceHandlerWithManager(manager VoiceManager) *VoiceHandler {
+	return &VoiceHandler{manager: manager}
+}
+
+// GetRegions returns available voice regions
+func (h *VoiceHandler) GetRegions(c *fiber.Ctx) error {
+	return c.JSON([]fiber.Map{
+		{"id": "us-west", "name": "US West", "optimal": true},
+		{"id": "us-east", "name": "US East", "optimal": false},
+		{"id": "eu-west", "name": "EU West", "optimal": false},
+		{"id": "eu-central", "name": "EU Central", "optimal": false},
+		{"id": "singapore", "name": "Singapore", "optimal": false},
+		{"id": "sydney", "name": "Sydney", "optimal": false},
+	})
+}
+
+// JoinChannel returns SFU credentials for a voice channel
+// POST /voice/channels/:id/join
+func (h *VoiceHandler) JoinChannel(c *fiber.Ctx) error {
+	if h.manager == nil {
+		return voiceNotConfigured(c)
+	}
+	userID := c.Locals("userID").(uuid.UUID)
+	username, _ := c.Locals("username").(string)
+
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	session, err := h.manager.Join(c.Context(), voice.Participant{UserID: userID, Name: username}, channelID)
+	if err != nil {
+		switch {
+		case errors.Is(err, voice.ErrChannelNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "channel not found",
+			})
+		case errors.Is(err, voice.ErrNotVoiceChannel):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "not a voice channel",
+			})
+		case errors.Is(err, voice.ErrNoConnectPermission):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "missing permission to connect",
+			})
+		default:
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+				"error": "voice server unavailable",
+			})
+		}
+	}
+
+	return c.JSON(session)
+}
+
+// Leave takes the user out of their voice channel
+// POST /voice/leave
+func (h *VoiceHandler) Leave(c *fiber.Ctx) error {
+	if h.manager == nil {
+		return voiceNotConfigured(c)
+	}
+	userID := c.Locals("userID").(uuid.UUID)
+
+	if err := h.manager.Leave(c.Context(), userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to leave voice channel",
+		})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// StartStream marks the user as streaming in their voice channel
+// PUT /voice/stream
+func (h *VoiceHandler) StartStream(c *fiber.Ctx) error {
+	if h.manager == nil {
+		return voiceNotConfigured(c)
+	}
+	userID := c.Locals("userID").(uuid.UUID)
+
+	var req struct {
+		ApplicationName string `json:"application_name" validate:"max=100"` // voice.MaxApplicationNameLength
+	}
+	if len(c.Body()) > 0 {
+		if err := parseBody(c, &req); err != nil {
+			return invalidBody(c, err)
+		}
+	}
+
+	stream, err := h.manager.StartStream(userID, req.ApplicationName)
+	if err != nil {
+		return voiceStreamError(c, err)
+	}
+	return c.JSON(stream)
+}
+
+// StopStream ends the user's stream
+// DELETE /voice/stream
+func (h *VoiceHandler) StopStream(c *fiber.Ctx) error {
+	if h.manager == nil {
+		return voiceNotConfigured(c)
+	}
+	h.manager.StopStream(c.Locals("userID").(uuid.UUID))
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetStreams lists the active streams in a voice channel
+// GET /voice/channels/:id/streams
+func (h *VoiceHandler) GetStreams(c *fiber.Ctx) error {
+	if h.manager == nil {
+		return voiceNotConfigured(c)
+	}
+	userID := c.Locals("userID").(uuid.UUID)
+
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	streams, err := h.manager.Streams(c.Context(), userID, channelID)
+	if err != nil {
+		switch {
+		case errors.Is(err, voice.ErrChannelNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "channel not found",
+			})
+		case errors.Is(err, voice.ErrNotVoiceChannel):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "not a voice channel",
+			})
+		case errors.Is(err, voice.ErrNoConnectPermission):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "missing permission to connect",
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to get streams",
+			})
+		}
+	}
+	return c.JSON(streams)
+}
+
+// WatchStream adds the user to a stream's viewers
+// PUT /voice/channels/:id/streams/:userId/viewers/@me
+func (h *VoiceHandler) WatchStream(c *fiber.Ctx) error {
+	return h.updateViewers(c, true)
+}
+
+// UnwatchStream removes the user from a stream's viewers
+// DELETE /voice/channels/:id/streams/:userId/viewers/@me
+func (h *VoiceHandler) UnwatchStream(c *fiber.Ctx) error {
+	return h.updateViewers(c, false)
+}
+
+func (h *VoiceHandler) updateViewers(c *fiber.Ctx, watch bool) error {
+	if h.manager == nil {
+		return voiceNotConfigured(c)
+	}
+	userID := c.Locals("userID").(uuid.UUID)
+
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+	streamerID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	update := h.manager.UnwatchStream
+	if watch {
+		update = h.manager.WatchStream
+	}
+	stream, err := update(userID, channelID, streamerID)
+	if err != nil {
+		return voiceStreamError(c, err)
+	}
+	return c.JSON(stream)
+}
+
+func voiceStreamError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, voice.ErrNotInVoice):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "not in this voice channel",
+		})
+	case errors.Is(err, voice.ErrNoStreamPermission):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "missing permission to stream",
+		})
+	case errors.Is(err, voice.ErrStreamNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "stream not found",
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to update stream",
+		})
+	}
+}
+
+// Webhook receives room events from the SFU. It's authenticated by the
+// SFU's signature rather than a user token.
+// POST /voice/webhook
+func (h *VoiceHandler) Webhook(c *fiber.Ctx) error {
+	if h.manager == nil {
+		return voiceNotConfigured(c)
+	}
+
+	err := h.manager.HandleWebhook(c.Context(), c.Get(fiber.HeaderAuthorization), c.Body())
+	switch {
+	case err == nil:
+		return c.SendStatus(fiber.StatusNoContent)
+	case errors.Is(err, voice.ErrInvalidWebhook):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "invalid signature",
+		})
+	case errors.Is(err, voice.ErrWebhooksUnsupported):
+		return c.SendStatus(fiber.StatusNotFound)
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+}
+
+func voiceNotConfigured(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+		"error": "voice is not configured on this server",
+	})
+}
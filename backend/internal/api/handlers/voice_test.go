@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/voice"
+)
+
+type mockVoiceManager struct {
+	joinErr   error
+	joinedBy  voice.Participant
+	streamErr error
+	appName   string
+}
+
+func (m *mockVoiceManager) Join(ctx context.Context, p voice.Participant, channelID uuid.UUID) (*voice.Session, error) {
+	m.joinedBy = p
+	if m.joinErr != nil {
+		return nil, m.joinErr
+	}
+	return &voice.Session{ChannelID: channelID, URL: "wss://sfu", Room: voice.RoomName(channelID), Token: "token"}, nil
+}
+
+func (m *mockVoiceManager) Leave(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
+
+func (m *mockVoiceManager) HandleWebhook(ctx context.Context, authorization string, body []byte) error {
+	if authorization == "" {
+		return voice.ErrInvalidWebhook
+	}
+	return nil
+}
+
+func (m *mockVoiceManager) StartStream(userID uuid.UUID, applicationName string) (*voice.Stream, error) {
+	if m.streamErr != nil {
+		return nil, m.streamErr
+	}
+	m.appName = applicationName
+	return &voice.Stream{UserID: userID, ApplicationName: applicationName, Viewers: []uuid.UUID{}}, nil
+}
+
+func (m *mockVoiceManager) StopStream(userID uuid.UUID) {}
+
+func (m *mockVoiceManager) WatchStream(viewerID, channelID, streamerID uuid.UUID) (*voice.Stream, error) {
+	if m.streamErr != nil {
+		return nil, m.streamErr
+	}
+	return &voice.Stream{UserID: streamerID, ChannelID: channelID, Viewers: []uuid.UUID{viewerID}}, nil
+}
+
+func (m *mockVoiceManager) UnwatchStream(viewerID, channelID, streamerID uuid.UUID) (*voice.Stream, error) {
+	if m.streamErr != nil {
+		return nil, m.streamErr
+	}
+	return &voice.Stream{UserID: streamerID, ChannelID: channelID, Viewers: []uuid.UUID{}}, nil
+}
+
+func (m *mockVoiceManager) Streams(ctx context.Context, requesterID, channelID uuid.UUID) ([]*voice.Stream, error) {
+	if m.joinErr != nil {
+		return nil, m.joinErr
+	}
+	return []*voice.Stream{}, nil
+}
+
+func setupVoiceTestApp(h *VoiceHandler, userID uuid.UUID) *fiber.App {
+	app := fiber.New()
+	app.Post("/voice/webhook", h.Webhook)
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", userID)
+		c.Locals("username", "alice")
+		return c.Next()
+	})
+	app.Post("/voice/channels/:id/join", h.JoinChannel)
+	app.Post("/voice/leave", h.Leave)
+	app.Put("/voice/stream", h.StartStream)
+	app.Delete("/voice/stream", h.StopStream)
+	app.Get("/voice/channels/:id/streams", h.GetStreams)
+	app.Put("/voice/channels/:id/streams/:userId/viewers/@me", h.WatchStream)
+	return app
+}
+
+func TestVoiceHandler_JoinChannel(t *testing.T) {
+	userID := uuid.New()
+	channelID := uuid.New()
+	manager := &mockVoiceManager{}
+	app := setupVoiceTestApp(NewVoiceHandlerWithManager(manager), userID)
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/voice/channels/"+channelID.String()+"/join", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var session voice.Session
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&session))
+	assert.Equal(t, channelID, session.ChannelID)
+	assert.Equal(t, "token", session.Token)
+	assert.Equal(t, voice.Participant{UserID: userID, Name: "alice"}, manager.joinedBy)
+}
+
+func TestVoiceHandler_JoinChannelErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{voice.ErrChannelNotFound, fiber.StatusNotFound},
+		{voice.ErrNotVoiceChannel, fiber.StatusBadRequest},
+		{voice.ErrNoConnectPermission, fiber.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			app := setupVoiceTestApp(NewVoiceHandlerWithManager(&mockVoiceManager{joinErr: tt.err}), uuid.New())
+
+			resp, err := app.Test(httptest.NewRequest("POST", "/voice/channels/"+uuid.New().String()+"/join", nil))
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestVoiceHandler_NotConfigured(t *testing.T) {
+	app := setupVoiceTestApp(NewVoiceHandler(), uuid.New())
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/voice/channels/"+uuid.New().String()+"/join", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("POST", "/voice/leave", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+}
+
+func TestVoiceHandler_Webhook(t *testing.T) {
+	app := setupVoiceTestApp(NewVoiceHandlerWithManager(&mockVoiceManager{}), uuid.New())
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/voice/webhook", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+
+	req := httptest.NewRequest("POST", "/voice/webhook", nil)
+	req.Header.Set("Authorization", "signed")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+}
+
+func TestVoiceHandler_StartStream(t *testing.T) {
+	manager := &mockVoiceManager{}
+	app := setupVoiceTestApp(NewVoiceHandlerWithManager(manager), uuid.New())
+
+	req := httptest.NewRequest("PUT", "/voice/stream", strings.NewReader(`{"application_name":"Factorio"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Factorio", manager.appName)
+
+	req = httptest.NewRequest("PUT", "/voice/stream", strings.NewReader(`{"application_name":"`+strings.Repeat("x", voice.MaxApplicationNameLength+1)+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("DELETE", "/voice/stream", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+}
+
+func TestVoiceHandler_StreamErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{voice.ErrNotInVoice, fiber.StatusBadRequest},
+		{voice.ErrNoStreamPermission, fiber.StatusForbidden},
+		{voice.ErrStreamNotFound, fiber.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			app := setupVoiceTestApp(NewVoiceHandlerWithManager(&mockVoiceManager{streamErr: tt.err}), uuid.New())
+
+			resp, err := app.Test(httptest.NewRequest("PUT", "/voice/channels/"+uuid.New().String()+"/streams/"+uuid.New().String()+"/viewers/@me", nil))
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestVoiceHandler_GetStreams(t *testing.T) {
+	app := setupVoiceTestApp(NewVoiceHandlerWithManager(&mockVoiceManager{}), uuid.New())
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/voice/channels/"+uuid.New().String()+"/streams", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	app = setupVoiceTestApp(NewVoiceHandlerWithManager(&mockVoiceManager{joinErr: voice.ErrNoConnectPermission}), uuid.New())
+	resp, err = app.Test(httptest.NewRequest("GET", "/voice/channels/"+uuid.New().String()+"/streams", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// WebhookPolicyServiceInterface defines the methods needed from
+// WebhookPolicyService
+type WebhookPolicyServiceInterface interface {
+	GetServerPolicy(ctx context.Context, serverID, requesterID uuid.UUID) (*models.WebhookPolicy, error)
+	UpdateServerPolicy(ctx context.Context, serverID, requesterID uuid.UUID, req *models.UpdateWebhookPolicyRequest) (*models.WebhookPolicy, error)
+	DeleteServerPolicy(ctx context.Context, serverID, requesterID uuid.UUID) error
+	GetChannelPolicy(ctx context.Context, channelID, requesterID uuid.UUID) (*models.WebhookPolicy, error)
+	UpdateChannelPolicy(ctx context.Context, channelID, requesterID uuid.UUID, req *models.UpdateWebhookPolicyRequest) (*models.WebhookPolicy, error)
+	DeleteChannelPolicy(ctx context.Context, channelID, requesterID uuid.UUID) error
+}
+
+// WebhookPolicyHandler handles servers' and channels' webhook policies
+type WebhookPolicyHandler struct {
+	policies WebhookPolicyServiceInterface
+}
+
+// NewWebhookPolicyHandler creates a new webhook policy handler
+func NewWebhookPolicyHandler(policies WebhookPolicyServiceInterface) *WebhookPolicyHandler {
+	return &WebhookPolicyHandler{policies: policies}
+}
+
+// GetServerPolicy returns a server's webhook policy
+// GET /servers/:id/webhook-policy
+func (h *WebhookPolicyHandler) GetServerPolicy(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	policy, err := h.policies.GetServerPolicy(c.UserContext(), serverID, userID)
+	if err != nil {
+		return webhookPolicyError(c, err)
+	}
+	return c.JSON(policy)
+}
+
+// UpdateServerPolicy replaces a server's webhook policy
+// PUT /servers/:id/webhook-policy
+func (h *WebhookPolicyHandler) UpdateServerPolicy(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	var req models.UpdateWebhookPolicyRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	policy, err := h.policies.UpdateServerPolicy(c.UserContext(), serverID, userID, &req)
+	if err != nil {
+		return webhookPolicyError(c, err)
+	}
+	return c.JSON(policy)
+}
+
+// DeleteServerPolicy lifts a server's webhook policy
+// DELETE /servers/:id/webhook-policy
+func (h *WebhookPolicyHandler) DeleteServerPolicy(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid server id",
+		})
+	}
+
+	if err := h.policies.DeleteServerPolicy(c.UserContext(), serverID, userID); err != nil {
+		return webhookPolicyError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetChannelPolicy returns a channel's own webhook policy
+// GET /channels/:id/webhook-policy
+func (h *WebhookPolicyHandler) GetChannelPolicy(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	policy, err := h.policies.GetChannelPolicy(c.UserContext(), channelID, userID)
+	if err != nil {
+		return webhookPolicyError(c, err)
+	}
+	return c.JSON(policy)
+}
+
+// UpdateChannelPolicy replaces a channel's webhook policy
+// PUT /channels/:id/webhook-policy
+func (h *WebhookPolicyHandler) UpdateChannelPolicy(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	var req models.UpdateWebhookPolicyRequest
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
+	}
+
+	policy, err := h.policies.UpdateChannelPolicy(c.UserContext(), channelID, userID, &req)
+	if err != nil {
+		return webhookPolicyError(c, err)
+	}
+	return c.JSON(policy)
+}
+
+// DeleteChannelPolicy drops a channel's webhook policy, leaving the
+// server's to apply
+// DELETE /channels/:id/webhook-policy
+func (h *WebhookPolicyHandler) DeleteChannelPolicy(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uuid.UUID)
+	channelID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel id",
+		})
+	}
+
+	if err := h.policies.DeleteChannelPolicy(c.UserContext(), channelID, userID); err != nil {
+		return webhookPolicyError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func webhookPolicyError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidWebhookPolicy):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrCannotManageWebhookPolicy):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrWebhookPolicyNotFound), errors.Is(err, services.ErrChannelNotFound),
+		errors.Is(err, services.ErrServerNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "failed to manage webhook policy",
+	})
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// MockWebhookPolicyService mocks the WebhookPolicyService for testing
+type MockWebhookPolicyService struct {
+	mock.Mock
+}
+
+func (m *MockWebhookPolicyService) GetServerPolicy(ctx context.Context, serverID, requesterID uuid.UUID) (*models.WebhookPolicy, error) {
+	args := m.Called(ctx, serverID, requesterID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WebhookPolicy), args.Error(1)
+}
+
+func (m *MockWebhookPolicyService) UpdateServerPolicy(ctx context.Context, serverID, requesterID uuid.UUID, req *models.UpdateWebhookPolicyRequest) (*models.WebhookPolicy, error) {
+	args := m.Called(ctx, serverID, requesterID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WebhookPolicy), args.Error(1)
+}
+
+func (m *MockWebhookPolicyService) DeleteServerPolicy(ctx context.Context, serverID, requesterID uuid.UUID) error {
+	args := m.Called(ctx, serverID, requesterID)
+	return args.Error(0)
+}
+
+func (m *MockWebhookPolicyService) GetChannelPolicy(ctx context.Context, channelID, requesterID uuid.UUID) (*models.WebhookPolicy, error) {
+	args := m.Called(ctx, channelID, requesterID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WebhookPolicy), args.Error(1)
+}
+
+func (m *MockWebhookPolicyService) UpdateChannelPolicy(ctx context.Context, channelID, requesterID uuid.UUID, req *models.UpdateWebhookPolicyRequest) (*models.WebhookPolicy, error) {
+	args := m.Called(ctx, channelID, requesterID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WebhookPolicy), args.Error(1)
+}
+
+func (m *MockWebhookPolicyService) DeleteChannelPolicy(ctx context.Context, channelID, requesterID uuid.UUID) error {
+	args := m.Called(ctx, channelID, requesterID)
+	return args.Error(0)
+}
+
+func newTestWebhookPolicyHandler() (*fiber.App, *MockWebhookPolicyService, uuid.UUID) {
+	policyService := new(MockWebhookPolicyService)
+	handler := NewWebhookPolicyHandler(policyService)
+	userID := uuid.New()
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", userID)
+		return c.Next()
+	})
+	app.Get("/servers/:id/webhook-policy", handler.GetServerPolicy)
+	app.Put("/servers/:id/webhook-policy", handler.UpdateServerPolicy)
+	app.Delete("/servers/:id/webhook-policy", handler.DeleteServerPolicy)
+	app.Get("/channels/:id/webhook-policy", handler.GetChannelPolicy)
+	app.Put("/channels/:id/webhook-policy", handler.UpdateChannelPolicy)
+	app.Delete("/channels/:id/webhook-policy", handler.DeleteChannelPolicy)
+
+	return app, policyService, userID
+}
+
+func TestWebhookPolicyHandler_UpdateServerPolicy(t *testing.T) {
+	app, policyService, userID := newTestWebhookPolicyHandler()
+	serverID, roleID := uuid.New(), uuid.New()
+	policyService.On("UpdateServerPolicy", mock.Anything, serverID, userID, mock.MatchedBy(func(req *models.UpdateWebhookPolicyRequest) bool {
+		return len(req.CreatorRoleIDs) == 1 && req.CreatorRoleIDs[0] == roleID && len(req.AllowedDomains) == 1
+	})).Return(&models.WebhookPolicy{ServerID: serverID, CreatorRoleIDs: []uuid.UUID{roleID}, AllowedDomains: []string{"example.com"}}, nil)
+	policyService.On("UpdateServerPolicy", mock.Anything, serverID, userID, mock.Anything).Return(nil, services.ErrInvalidWebhookPolicy)
+
+	body := `{"creator_role_ids":["` + roleID.String() + `"],"allowed_domains":["example.com"]}`
+	req := httptest.NewRequest(http.MethodPut, "/servers/"+serverID.String()+"/webhook-policy", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var policy map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&policy))
+	assert.Equal(t, []interface{}{"example.com"}, policy["allowed_domains"])
+
+	req = httptest.NewRequest(http.MethodPut, "/servers/"+serverID.String()+"/webhook-policy", bytes.NewReader([]byte(`{"allowed_domains":["https://"]}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestWebhookPolicyHandler_ChannelPolicy(t *testing.T) {
+	app, policyService, userID := newTestWebhookPolicyHandler()
+	channelID := uuid.New()
+	policyService.On("GetChannelPolicy", mock.Anything, channelID, userID).Return(nil, services.ErrWebhookPolicyNotFound)
+	policyService.On("DeleteChannelPolicy", mock.Anything, channelID, userID).Return(services.ErrCannotManageWebhookPolicy)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/channels/"+channelID.String()+"/webhook-policy", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodDelete, "/channels/"+channelID.String()+"/webhook-policy", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/channels/nope/webhook-policy", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
@@ -1,16 +1,26 @@
 package handlers
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
 )
 
+// WebhookExecutor posts incoming webhook messages
+type WebhookExecutor interface {
+	ExecuteWebhook(ctx context.Context, webhookID uuid.UUID, token string, req *services.ExecuteWebhookRequest) (*models.Message, error)
+}
+
 // WebhookHandlers handles webhook-related HTTP requests
 type WebhookHandlers struct {
 	// webhookRepo would go here
+	executor WebhookExecutor
 }
 
 // NewWebhookHandlers creates new webhook handlers
@@ -18,6 +28,31 @@ func NewWebhookHandlers() *WebhookHandlers {
 	return &WebhookHandlers{}
 }
 
+// NewWebhookHandlersWithExecutor creates webhook handlers that post
+// executed webhooks through executor
+func NewWebhookHandlersWithExecutor(executor WebhookExecutor) *WebhookHandlers {
+	return &WebhookHandlers{executor: executor}
+}
+
+// providerMessageIDHeaders carry a sender's delivery ID, which stays the
+// same across retries. Checked in order.
+var providerMessageIDHeaders = []string{
+	"Idempotency-Key",
+	"X-Webhook-Message-Id",
+	"X-GitHub-Delivery",
+	"X-Gitlab-Event-UUID",
+}
+
+// providerMessageID returns the sender's delivery ID, if any
+func providerMessageID(c *fiber.Ctx) string {
+	for _, h := range providerMessageIDHeaders {
+		if v := c.Get(h); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
 // WebhookResponse represents a webhook in API responses
 type WebhookResponse struct {
 	ID        string  `json:"id"`
@@ -39,19 +74,11 @@ func (h *WebhookHandlers) CreateWebhook(c *fiber.Ctx) error {
 	}
 
 	var req struct {
-		Name   string  `json:"name"`
+		Name   string  `json:"name" validate:"required,max=80"`
 		Avatar *string `json:"avatar,omitempty"`
 	}
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
-	}
-
-	if req.Name == "" || len(req.Name) > 80 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Name must be between 1 and 80 characters",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
 	// Generate webhook token
@@ -127,14 +154,12 @@ func (h *WebhookHandlers) UpdateWebhook(c *fiber.Ctx) error {
 	}
 
 	var req struct {
-		Name      *string `json:"name,omitempty"`
+		Name      *string `json:"name,omitempty" validate:"omitempty,min=1,max=80"`
 		Avatar    *string `json:"avatar,omitempty"`
-		ChannelID *string `json:"channel_id,omitempty"`
+		ChannelID *string `json:"channel_id,omitempty" validate:"omitempty,uuid"`
 	}
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
 	// TODO: Update webhook in database
@@ -172,25 +197,26 @@ func (h *WebhookHandlers) ExecuteWebhook(c *fiber.Ctx) error {
 	}
 
 	var req struct {
-		Content   string  `json:"content,omitempty"`
-		Username  *string `json:"username,omitempty"`
-		AvatarURL *string `json:"avatar_url,omitempty"`
+		Content   string  `json:"content,omitempty" validate:"required"`
+		Username  *string `json:"username,omitempty" validate:"omitempty,max=80"`
+		AvatarURL *string `json:"avatar_url,omitempty" validate:"omitempty,url"`
 		TTS       bool    `json:"tts"`
 	}
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return invalidBody(c, err)
 	}
 
-	if req.Content == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Content is required",
+	if h.executor != nil {
+		return h.executeWithService(c, webhookID, token, &services.ExecuteWebhookRequest{
+			Content:           req.Content,
+			Username:          req.Username,
+			AvatarURL:         req.AvatarURL,
+			TTS:               req.TTS,
+			ProviderMessageID: providerMessageID(c),
 		})
 	}
 
 	// TODO: Verify webhook token and send message
-	_ = webhookID
 
 	// Return 204 if wait=false (default), or message if wait=true
 	if c.Query("wait") == "true" {
@@ -203,3 +229,43 @@ func (h *WebhookHandlers) ExecuteWebhook(c *fiber.Ctx) error {
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
+
+// executeWithService posts through the executor. Retries of a delivery that
+// was already posted succeed without posting again, so senders stop retrying.
+func (h *WebhookHandlers) executeWithService(c *fiber.Ctx, webhookID uuid.UUID, token string, req *services.ExecuteWebhookRequest) error {
+	message, err := h.executor.ExecuteWebhook(c.UserContext(), webhookID, token, req)
+	switch err {
+	case nil, services.ErrDuplicateWebhookMessage:
+	case services.ErrWebhookNotFound:
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Unknown webhook",
+		})
+	case services.ErrInvalidWebhookToken:
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid webhook token",
+		})
+	case services.ErrEmptyMessage:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Content is required",
+		})
+	case services.ErrWebhookCreatorNotAllowed, services.ErrWebhookURLNotAllowed:
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to execute webhook",
+		})
+	}
+
+	if c.Query("wait") == "true" {
+		return c.JSON(fiber.Map{
+			"id":         message.ID.String(),
+			"content":    req.Content,
+			"webhook_id": webhookID.String(),
+			"duplicate":  err == services.ErrDuplicateWebhookMessage,
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -2,13 +2,18 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
 )
 
 // setupWebhookTestApp creates a test Fiber app with webhook routes
@@ -799,3 +804,148 @@ func TestWebhookHandler_ExecuteWebhook_TableDriven(t *testing.T) {
 		})
 	}
 }
+
+// dedupingExecutor posts each provider message ID once
+type dedupingExecutor struct {
+	posted map[string]uuid.UUID
+	calls  []*services.ExecuteWebhookRequest
+}
+
+func (e *dedupingExecutor) ExecuteWebhook(ctx context.Context, webhookID uuid.UUID, token string, req *services.ExecuteWebhookRequest) (*models.Message, error) {
+	e.calls = append(e.calls, req)
+	if id, ok := e.posted[req.ProviderMessageID]; ok {
+		return &models.Message{ID: id}, services.ErrDuplicateWebhookMessage
+	}
+	msg := &models.Message{ID: uuid.New(), Content: req.Content}
+	e.posted[req.ProviderMessageID] = msg.ID
+	return msg, nil
+}
+
+func TestWebhookHandler_ExecuteWebhook_RetryIsDeduped(t *testing.T) {
+	executor := &dedupingExecutor{posted: make(map[string]uuid.UUID)}
+	app := fiber.New()
+	app.Post("/webhooks/:webhookID/:token", NewWebhookHandlersWithExecutor(executor).ExecuteWebhook)
+
+	webhookID := uuid.New()
+	send := func() map[string]interface{} {
+		body, _ := json.Marshal(map[string]string{"content": "Deploy finished"})
+		req := httptest.NewRequest("POST", "/webhooks/"+webhookID.String()+"/token?wait=true", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-GitHub-Delivery", "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != fiber.StatusOK {
+			respBody, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected 200, got %d: %s", resp.StatusCode, string(respBody))
+		}
+
+		var result map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return result
+	}
+
+	first := send()
+	retry := send()
+
+	if first["duplicate"] != false || retry["duplicate"] != true {
+		t.Errorf("Expected only the retry to be a duplicate, got %v and %v", first["duplicate"], retry["duplicate"])
+	}
+	if first["id"] != retry["id"] {
+		t.Errorf("Expected retry to return original message %v, got %v", first["id"], retry["id"])
+	}
+	if executor.calls[0].ProviderMessageID != "72d3162e-cc78-11e3-81ab-4c9367dc0958" {
+		t.Errorf("Expected provider message ID from X-GitHub-Delivery, got %q", executor.calls[0].ProviderMessageID)
+	}
+}
+
+// storedWebhooks serves webhooks by ID
+type storedWebhooks struct {
+	services.WebhookRepository
+	webhooks map[uuid.UUID]*models.Webhook
+}
+
+func (s *storedWebhooks) GetByID(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	return s.webhooks[id], nil
+}
+
+// memoryDedupeStore is a WebhookDedupeStore without expiry
+type memoryDedupeStore map[string][]byte
+
+func (m memoryDedupeStore) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	if _, ok := m[key]; ok {
+		return false, nil
+	}
+	m[key] = value
+	return true, nil
+}
+
+func (m memoryDedupeStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return m[key], nil
+}
+
+// postedMessages records webhook.executed events
+type postedMessages struct {
+	messages []uuid.UUID
+}
+
+func (p *postedMessages) Publish(event string, data interface{}) {
+	if executed, ok := data.(*services.WebhookExecutedEvent); ok {
+		p.messages = append(p.messages, executed.MessageID)
+	}
+}
+
+func (p *postedMessages) Subscribe(event string, handler func(data interface{}))   {}
+func (p *postedMessages) Unsubscribe(event string, handler func(data interface{})) {}
+
+func TestWebhookHandler_ExecuteWebhook_ServiceDedupesRetry(t *testing.T) {
+	webhook := &models.Webhook{ID: uuid.New(), ChannelID: uuid.New(), Name: "CI", Token: "secret"}
+	posted := &postedMessages{}
+	service := services.NewWebhookService(&storedWebhooks{webhooks: map[uuid.UUID]*models.Webhook{webhook.ID: webhook}}, nil, nil, posted)
+	service.SetDedupeStore(memoryDedupeStore{}, 0)
+
+	app := fiber.New()
+	app.Post("/webhooks/:webhookID/:token", NewWebhookHandlersWithExecutor(service).ExecuteWebhook)
+
+	send := func(delivery string) map[string]interface{} {
+		body, _ := json.Marshal(map[string]string{"content": "Deploy finished"})
+		req := httptest.NewRequest("POST", "/webhooks/"+webhook.ID.String()+"/secret?wait=true", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", delivery)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != fiber.StatusOK {
+			respBody, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected 200, got %d: %s", resp.StatusCode, string(respBody))
+		}
+
+		var result map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return result
+	}
+
+	first := send("delivery-1")
+	retry := send("delivery-1")
+	other := send("delivery-2")
+
+	if first["duplicate"] != false || retry["duplicate"] != true || other["duplicate"] != false {
+		t.Errorf("Expected only the retry to be a duplicate, got %v, %v and %v", first["duplicate"], retry["duplicate"], other["duplicate"])
+	}
+	if first["id"] != retry["id"] {
+		t.Errorf("Expected retry to return original message %v, got %v", first["id"], retry["id"])
+	}
+	if len(posted.messages) != 2 {
+		t.Errorf("Expected 2 messages posted, got %d", len(posted.messages))
+	}
+}
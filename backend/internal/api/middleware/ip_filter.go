@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+
+	"hearth/internal/models"
+)
+
+// IPChecker decides whether client addresses may call the API. The
+// services.IPFilterService implements it.
+type IPChecker interface {
+	// Check never fails; lookups that go wrong let the address through
+	Check(ctx context.Context, ip string) *models.IPVerdict
+}
+
+// IPBlockRecorder counts refused requests. The metrics.IPFilterMetrics
+// collector implements it.
+type IPBlockRecorder interface {
+	IPBlocked(reason string)
+}
+
+// SetIPFilter turns on client address filtering. The recorder may be nil.
+func (m *Middleware) SetIPFilter(checker IPChecker, recorder IPBlockRecorder) {
+	m.ipFilter = checker
+	m.ipBlocks = recorder
+}
+
+// IPFilter refuses requests from blocked addresses with a 403. The
+// address is c.IP(), so behind a proxy it's only the client's when
+// TRUSTED_PROXIES is set.
+func (m *Middleware) IPFilter() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if m.ipFilter == nil {
+			return c.Next()
+		}
+		// c.IP() points into a buffer fiber reuses, and checkers keep
+		// addresses as cache keys
+		verdict := m.ipFilter.Check(c.UserContext(), utils.CopyString(c.IP()))
+		if !verdict.Blocked {
+			return c.Next()
+		}
+		if m.ipBlocks != nil {
+			m.ipBlocks.IPBlocked(verdict.Reason)
+		}
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "ip blocked",
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"hearth/internal/models"
+)
+
+// fakeIPChecker blocks the addresses in blocked, for the reason given
+type fakeIPChecker struct {
+	blocked map[string]string
+	checked []string
+}
+
+func (f *fakeIPChecker) Check(ctx context.Context, ip string) *models.IPVerdict {
+	f.checked = append(f.checked, ip)
+	reason, blocked := f.blocked[ip]
+	return &models.IPVerdict{IP: ip, Blocked: blocked, Reason: reason}
+}
+
+type recordedIPBlocks map[string]int
+
+func (r recordedIPBlocks) IPBlocked(reason string) {
+	r[reason]++
+}
+
+func TestIPFilter(t *testing.T) {
+	m := NewMiddleware(testSecret)
+	checker := &fakeIPChecker{blocked: map[string]string{}}
+	blocks := recordedIPBlocks{}
+
+	app := fiber.New(fiber.Config{ProxyHeader: fiber.HeaderXForwardedFor})
+	app.Use(m.IPFilter())
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	get := func(ip string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(fiber.HeaderXForwardedFor, ip)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test failed: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	if status := get("198.51.100.1"); status != fiber.StatusOK {
+		t.Errorf("expected requests through without a filter, got %d", status)
+	}
+
+	m.SetIPFilter(checker, blocks)
+	checker.blocked["198.51.100.1"] = models.IPBlockedReputation
+	if status := get("198.51.100.1"); status != fiber.StatusForbidden {
+		t.Errorf("expected 403 for a blocked address, got %d", status)
+	}
+	if status := get("203.0.113.1"); status != fiber.StatusOK {
+		t.Errorf("expected 200 for other addresses, got %d", status)
+	}
+	if len(checker.checked) != 2 || checker.checked[0] != "198.51.100.1" {
+		t.Errorf("expected the client address to be checked, got %v", checker.checked)
+	}
+	if len(blocks) != 1 || blocks[models.IPBlockedReputation] != 1 {
+		t.Errorf("expected one blocked request recorded, got %v", blocks)
+	}
+
+	// Blocking without metrics
+	m.SetIPFilter(checker, nil)
+	if status := get("198.51.100.1"); status != fiber.StatusForbidden {
+		t.Errorf("expected 403 without a recorder, got %d", status)
+	}
+}
@@ -1,18 +1,37 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/contrib/websocket"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+
+	"hearth/internal/database/instrument"
+	"hearth/internal/models"
 )
 
 // Middleware contains all middleware handlers
 type Middleware struct {
 	jwtSecret []byte
+
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+
+	compliance     ComplianceRecorder
+	serverTokens   ServerTokenAuthenticator
+	oauthTokens    OAuthTokenAuthenticator
+	botLimits      BotRateLimiter
+	serverActivity ServerActivityRecorder
+	ipFilter       IPChecker
+	ipBlocks       IPBlockRecorder
+	suspensions    SuspensionChecker
+	readOnly       ReadOnlyChecker
 }
 
 // NewMiddleware creates middleware with dependencies
@@ -28,6 +47,21 @@ type Claims struct {
 	UserID   string `json:"uid"`
 	Username string `json:"usr"`
 	Type     string `json:"typ"`
+	Bot      bool   `json:"bot,omitempty"`
+	// SessionID is the login session the token was issued for, if any
+	SessionID string `json:"sid,omitempty"`
+}
+
+// BotRateLimiter counts the API requests of bot accounts. The
+// services.BotTierService implements it.
+type BotRateLimiter interface {
+	// CheckBotRequest returns an error once the bot is over its limits
+	CheckBotRequest(ctx context.Context, userID uuid.UUID) error
+}
+
+// SetBotRateLimiter rate limits requests made with bot tokens
+func (m *Middleware) SetBotRateLimiter(limiter BotRateLimiter) {
+	m.botLimits = limiter
 }
 
 // RequireAuth validates JWT and sets userID in context
@@ -48,6 +82,12 @@ func (m *Middleware) RequireAuth(c *fiber.Ctx) error {
 	}
 	
 	tokenString := parts[1]
+	if m.serverTokens != nil && strings.HasPrefix(tokenString, models.ServerTokenPrefix) {
+		return m.requireServerToken(c, tokenString)
+	}
+	if m.oauthTokens != nil && strings.HasPrefix(tokenString, models.OAuthAccessTokenPrefix) {
+		return m.requireOAuthToken(c, tokenString)
+	}
 	
 	// Parse and validate JWT
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
@@ -92,8 +132,24 @@ func (m *Middleware) RequireAuth(c *fiber.Ctx) error {
 		})
 	}
 	
+	if suspension := m.suspension(c, userID); suspension != nil {
+		return accountSuspended(c, suspension)
+	}
+
+	if claims.Bot && m.botLimits != nil {
+		if err := m.botLimits.CheckBotRequest(c.UserContext(), userID); err != nil {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error":   "rate_limited",
+				"message": "Too many requests",
+			})
+		}
+	}
+	
 	c.Locals("userID", userID)
 	c.Locals("username", claims.Username)
+	if sessionID, err := uuid.Parse(claims.SessionID); err == nil {
+		c.Locals("sessionID", sessionID)
+	}
 	return c.Next()
 }
 
@@ -105,6 +161,192 @@ func (m *Middleware) WebSocketUpgrade(c *fiber.Ctx) error {
 	return fiber.ErrUpgradeRequired
 }
 
+// SetRequestTimeouts sets the deadlines RequestTimeout gives reads (GET,
+// HEAD, OPTIONS) and writes. Zero leaves that kind of request unbounded.
+func (m *Middleware) SetRequestTimeouts(read, write time.Duration) {
+	m.readTimeout = read
+	m.writeTimeout = write
+}
+
+// RequestTimeout bounds each request's context by the read or write timeout
+// so slow queries are cancelled instead of holding the worker
+func (m *Middleware) RequestTimeout() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		timeout := m.writeTimeout
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			timeout = m.readTimeout
+		}
+		return withDeadline(c, timeout)
+	}
+}
+
+// Timeout gives a route its own deadline in place of RequestTimeout's, for
+// routes such as uploads that legitimately run long
+func (m *Middleware) Timeout(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return withDeadline(c, timeout)
+	}
+}
+
+// QueryLabels tags the request context with the matched route so database
+// metrics and the slow query log can attribute queries to it. The route is
+// resolved per query because Fiber only knows it once routing reaches the
+// handler; after the request it's frozen, as the Ctx gets reused.
+func (m *Middleware) QueryLabels() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var (
+			mu    sync.Mutex
+			route string
+		)
+		resolve := func() string {
+			mu.Lock()
+			defer mu.Unlock()
+			if route != "" {
+				return route
+			}
+			return c.Method() + " " + c.Route().Path
+		}
+		c.SetUserContext(instrument.WithRoute(c.UserContext(), resolve))
+
+		err := c.Next()
+
+		mu.Lock()
+		route = strings.Clone(c.Method()) + " " + c.Route().Path
+		mu.Unlock()
+		return err
+	}
+}
+
+// ComplianceRecorder receives an entry for each mutating request. The
+// services.ComplianceLogger implements it.
+type ComplianceRecorder interface {
+	Record(entry *models.ComplianceLogEntry)
+}
+
+// SetComplianceRecorder turns on compliance logging
+func (m *Middleware) SetComplianceRecorder(recorder ComplianceRecorder) {
+	m.compliance = recorder
+}
+
+// ComplianceLog records the route, actor, target IDs and outcome of every
+// POST, PUT, PATCH and DELETE. Path parameters that aren't UUIDs are
+// redacted, and neither the query string nor the body is kept.
+func (m *Middleware) ComplianceLog() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if m.compliance == nil {
+			return c.Next()
+		}
+		switch c.Method() {
+		case fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete:
+		default:
+			return c.Next()
+		}
+
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			// The app's error handler writes the response later
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+
+		// Everything read from the Ctx is copied, as Fiber reuses it
+		route := c.Route()
+		entry := &models.ComplianceLogEntry{
+			Method:     strings.Clone(c.Method()),
+			Route:      strings.Clone(route.Path),
+			Targets:    make(map[string]string, len(route.Params)),
+			Status:     status,
+			Outcome:    models.ComplianceOutcomeFor(status),
+			DurationMS: time.Since(start).Milliseconds(),
+			CreatedAt:  start,
+		}
+		if requestID, ok := c.Locals("requestID").(string); ok {
+			entry.RequestID = strings.Clone(requestID)
+		}
+		if userID, ok := c.Locals("userID").(uuid.UUID); ok {
+			entry.ActorID = &userID
+		}
+		for _, name := range route.Params {
+			if id, parseErr := uuid.Parse(c.Params(name)); parseErr == nil {
+				entry.Targets[strings.Clone(name)] = id.String()
+			} else {
+				entry.Targets[strings.Clone(name)] = models.ComplianceRedacted
+			}
+		}
+		m.compliance.Record(entry)
+		return err
+	}
+}
+
+// ServerActivityRecorder counts API requests per server. The
+// metrics.ServerActivity collector implements it.
+type ServerActivityRecorder interface {
+	ServerAPIRequest(serverID uuid.UUID)
+}
+
+// SetServerActivityRecorder turns on per-server request metrics
+func (m *Middleware) SetServerActivityRecorder(recorder ServerActivityRecorder) {
+	m.serverActivity = recorder
+}
+
+// serverRoutePrefix is where routes about a single server start
+const serverRoutePrefix = "/api/v1/servers/:id"
+
+// ServerActivity records each request to a /servers/:id route against that
+// server
+func (m *Middleware) ServerActivity() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if m.serverActivity == nil {
+			return c.Next()
+		}
+		err := c.Next()
+
+		if path := c.Route().Path; strings.HasPrefix(path, serverRoutePrefix) &&
+			(len(path) == len(serverRoutePrefix) || path[len(serverRoutePrefix)] == '/') {
+			if serverID, parseErr := uuid.Parse(c.Params("id")); parseErr == nil {
+				m.serverActivity.ServerAPIRequest(serverID)
+			}
+		}
+		return err
+	}
+}
+
+const baseContextKey = "baseContext"
+
+// withDeadline runs the rest of the chain with a context that expires after
+// timeout. Nested deadlines replace rather than shorten the outer one. A
+// request that runs out of time gets a 504, whatever the handler wrote.
+func withDeadline(c *fiber.Ctx, timeout time.Duration) error {
+	base, ok := c.Locals(baseContextKey).(context.Context)
+	if !ok {
+		base = c.UserContext()
+		c.Locals(baseContextKey, base)
+	}
+	if timeout <= 0 {
+		c.SetUserContext(base)
+		return c.Next()
+	}
+
+	ctx, cancel := context.WithTimeout(base, timeout)
+	defer cancel()
+	c.SetUserContext(ctx)
+
+	err := c.Next()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return c.Status(fiber.StatusGatewayTimeout).JSON(fiber.Map{
+			"error": "request timed out",
+		})
+	}
+	return err
+}
+
 // RateLimit applies rate limiting
 func (m *Middleware) RateLimit(limit int, window int) fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"io"
 	"net/http/httptest"
 	"testing"
@@ -9,6 +11,9 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+
+	"hearth/internal/database/instrument"
+	"hearth/internal/models"
 )
 
 const testSecret = "test-secret"
@@ -290,6 +295,316 @@ func TestRequestID(t *testing.T) {
 	}
 }
 
+func TestRequestTimeout(t *testing.T) {
+	m := NewMiddleware("test-secret")
+	m.SetRequestTimeouts(20*time.Millisecond, 50*time.Millisecond)
+
+	deadlines := make(map[string]time.Duration)
+	record := func(c *fiber.Ctx) error {
+		deadline, ok := c.UserContext().Deadline()
+		if !ok {
+			t.Errorf("%s %s: expected a deadline", c.Method(), c.Path())
+			return c.SendStatus(fiber.StatusOK)
+		}
+		deadlines[c.Method()+" "+c.Path()] = time.Until(deadline)
+		return c.SendStatus(fiber.StatusOK)
+	}
+	slow := func(c *fiber.Ctx) error {
+		<-c.UserContext().Done()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": c.UserContext().Err().Error()})
+	}
+
+	app := fiber.New()
+	app.Use(m.RequestTimeout())
+	app.Get("/read", record)
+	app.Post("/write", record)
+	app.Post("/upload", m.Timeout(time.Second), record)
+	app.Get("/slow", slow)
+
+	for _, r := range []struct{ method, path string }{{"GET", "/read"}, {"POST", "/write"}, {"POST", "/upload"}} {
+		resp, err := app.Test(httptest.NewRequest(r.method, r.path, nil))
+		if err != nil {
+			t.Fatalf("app.Test failed: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Errorf("%s %s: expected 200, got %d", r.method, r.path, resp.StatusCode)
+		}
+	}
+
+	if d := deadlines["GET /read"]; d <= 0 || d > 20*time.Millisecond {
+		t.Errorf("expected read deadline within 20ms, got %v", d)
+	}
+	if d := deadlines["POST /write"]; d <= 20*time.Millisecond || d > 50*time.Millisecond {
+		t.Errorf("expected write deadline within 50ms, got %v", d)
+	}
+	if d := deadlines["POST /upload"]; d <= 50*time.Millisecond {
+		t.Errorf("expected route timeout to replace the write deadline, got %v", d)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/slow", nil))
+	if err != nil {
+		t.Fatalf("app.Test failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusGatewayTimeout {
+		t.Errorf("expected 504 for a timed out request, got %d", resp.StatusCode)
+	}
+}
+
+func TestRequestTimeoutDisabled(t *testing.T) {
+	m := NewMiddleware("test-secret")
+
+	app := fiber.New()
+	app.Use(m.RequestTimeout())
+	app.Get("/test", func(c *fiber.Ctx) error {
+		if _, ok := c.UserContext().Deadline(); ok {
+			t.Error("expected no deadline without timeouts configured")
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	if _, err := app.Test(httptest.NewRequest("GET", "/test", nil)); err != nil {
+		t.Fatalf("app.Test failed: %v", err)
+	}
+}
+
+func TestQueryLabels(t *testing.T) {
+	m := NewMiddleware("test-secret")
+
+	var during string
+	var ctx context.Context
+	app := fiber.New()
+	api := app.Group("/api", m.QueryLabels(), m.RequestTimeout())
+	api.Get("/channels/:id/messages", func(c *fiber.Ctx) error {
+		ctx = c.UserContext()
+		during = instrument.RouteFrom(ctx)
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/channels/123/messages", nil))
+	if err != nil {
+		t.Fatalf("app.Test failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	if during != "GET /api/channels/:id/messages" {
+		t.Errorf("expected the matched route pattern, got %q", during)
+	}
+	if after := instrument.RouteFrom(ctx); after != during {
+		t.Errorf("expected the route to be kept after the request, got %q", after)
+	}
+}
+
+// recordedEntries collects compliance entries
+type recordedEntries []*models.ComplianceLogEntry
+
+func (r *recordedEntries) Record(entry *models.ComplianceLogEntry) {
+	*r = append(*r, entry)
+}
+
+type stubBotLimits map[uuid.UUID]bool
+
+func (s stubBotLimits) CheckBotRequest(ctx context.Context, userID uuid.UUID) error {
+	if s[userID] {
+		return errors.New("too many requests")
+	}
+	return nil
+}
+
+func TestRequireAuthBotRateLimit(t *testing.T) {
+	m := NewMiddleware(testSecret)
+	limitedID, userID := uuid.New(), uuid.New()
+	m.SetBotRateLimiter(stubBotLimits{limitedID: true, userID: true})
+
+	app := fiber.New()
+	app.Get("/", m.RequireAuth, func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	botToken := func(id uuid.UUID) string {
+		claims := &Claims{
+			RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+			UserID:           id.String(),
+			Type:             "access",
+			Bot:              true,
+		}
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+		return token
+	}
+
+	for _, tt := range []struct {
+		name, token string
+		status      int
+	}{
+		{"limited bot", botToken(limitedID), fiber.StatusTooManyRequests},
+		{"bot within its limits", botToken(uuid.New()), fiber.StatusOK},
+		{"user tokens aren't counted", generateTestToken(userID, "access", false), fiber.StatusOK},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test failed: %v", err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.status, resp.StatusCode)
+		}
+	}
+}
+
+func TestRequireAuthSessionID(t *testing.T) {
+	m := NewMiddleware(testSecret)
+	sessionID := uuid.New()
+
+	app := fiber.New()
+	app.Get("/", m.RequireAuth, func(c *fiber.Ctx) error {
+		id, _ := c.Locals("sessionID").(uuid.UUID)
+		return c.SendString(id.String())
+	})
+
+	claims := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+		UserID:           uuid.NewString(),
+		Type:             "access",
+		SessionID:        sessionID.String(),
+	}
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+
+	for token, want := range map[string]string{
+		token: sessionID.String(),
+		generateTestToken(uuid.New(), "access", false): uuid.Nil.String(),
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if string(body) != want {
+			t.Errorf("expected session %s, got %s", want, body)
+		}
+	}
+}
+
+func TestComplianceLog(t *testing.T) {
+	m := NewMiddleware("test-secret")
+	var recorded recordedEntries
+	m.SetComplianceRecorder(&recorded)
+	userID := uuid.New()
+	channelID := uuid.New()
+
+	app := fiber.New()
+	api := app.Group("/api", m.RequestID(), m.ComplianceLog(), func(c *fiber.Ctx) error {
+		c.Locals("userID", userID)
+		return c.Next()
+	})
+	api.Put("/channels/:id/messages/:messageId/reactions/:emoji/@me", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	api.Delete("/channels/:id", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "no"})
+	})
+	api.Post("/fail", func(c *fiber.Ctx) error {
+		return fiber.NewError(fiber.StatusConflict, "conflict")
+	})
+	api.Get("/channels/:id", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	messageID := uuid.New()
+	for _, req := range []struct{ method, path string }{
+		{"PUT", "/api/channels/" + channelID.String() + "/messages/" + messageID.String() + "/reactions/%F0%9F%94%A5/@me?secret=1"},
+		{"DELETE", "/api/channels/" + channelID.String()},
+		{"POST", "/api/fail"},
+		{"GET", "/api/channels/" + channelID.String()},
+	} {
+		if _, err := app.Test(httptest.NewRequest(req.method, req.path, nil)); err != nil {
+			t.Fatalf("app.Test failed: %v", err)
+		}
+	}
+
+	if len(recorded) != 3 {
+		t.Fatalf("expected 3 entries (reads aren't logged), got %d", len(recorded))
+	}
+
+	reaction := recorded[0]
+	if reaction.Route != "/api/channels/:id/messages/:messageId/reactions/:emoji/@me" || reaction.Method != "PUT" {
+		t.Errorf("expected the route template, got %s %s", reaction.Method, reaction.Route)
+	}
+	if reaction.ActorID == nil || *reaction.ActorID != userID {
+		t.Errorf("expected actor %s, got %v", userID, reaction.ActorID)
+	}
+	if reaction.Targets["id"] != channelID.String() || reaction.Targets["messageId"] != messageID.String() {
+		t.Errorf("expected the channel and message IDs as targets, got %v", reaction.Targets)
+	}
+	if reaction.Targets["emoji"] != models.ComplianceRedacted {
+		t.Errorf("expected the emoji to be redacted, got %q", reaction.Targets["emoji"])
+	}
+	if reaction.Status != fiber.StatusNoContent || reaction.Outcome != models.ComplianceOutcomeSuccess || reaction.RequestID == "" {
+		t.Errorf("unexpected entry %+v", reaction)
+	}
+
+	if recorded[1].Outcome != models.ComplianceOutcomeDenied {
+		t.Errorf("expected a denied outcome, got %s", recorded[1].Outcome)
+	}
+	if recorded[2].Status != fiber.StatusConflict || recorded[2].Outcome != models.ComplianceOutcomeInvalid {
+		t.Errorf("expected the returned error's status, got %d %s", recorded[2].Status, recorded[2].Outcome)
+	}
+}
+
+func TestComplianceLogDisabled(t *testing.T) {
+	m := NewMiddleware("test-secret")
+	app := fiber.New()
+	app.Post("/", m.ComplianceLog(), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/", nil))
+	if err != nil {
+		t.Fatalf("app.Test failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Errorf("expected 201, got %d", resp.StatusCode)
+	}
+}
+
+type recordedServerRequests map[uuid.UUID]int
+
+func (r recordedServerRequests) ServerAPIRequest(serverID uuid.UUID) {
+	r[serverID]++
+}
+
+func TestServerActivity(t *testing.T) {
+	m := NewMiddleware("test-secret")
+	recorded := recordedServerRequests{}
+	m.SetServerActivityRecorder(recorded)
+	serverID := uuid.New()
+
+	app := fiber.New()
+	v1 := app.Group("/api/v1", m.ServerActivity())
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	v1.Get("/servers/:id", ok)
+	v1.Get("/servers/:id/members", ok)
+	v1.Get("/channels/:id", ok)
+
+	for _, path := range []string{
+		"/api/v1/servers/" + serverID.String(),
+		"/api/v1/servers/" + serverID.String() + "/members",
+		"/api/v1/channels/" + serverID.String(),
+		"/api/v1/servers/not-an-id/members",
+	} {
+		if _, err := app.Test(httptest.NewRequest("GET", path, nil)); err != nil {
+			t.Fatalf("app.Test failed: %v", err)
+		}
+	}
+
+	if len(recorded) != 1 || recorded[serverID] != 2 {
+		t.Errorf("expected 2 requests for the server only, got %v", recorded)
+	}
+}
+
 func TestRateLimit(t *testing.T) {
 	m := NewMiddleware("test-secret")
 
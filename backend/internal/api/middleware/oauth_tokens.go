@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"hearth/internal/models"
+)
+
+// OAuthTokenAuthenticator resolves the access tokens users granted OAuth
+// applications. The services.OAuthProviderService implements it.
+type OAuthTokenAuthenticator interface {
+	// AuthenticateOAuthToken returns an error for unknown, expired and
+	// revoked tokens
+	AuthenticateOAuthToken(ctx context.Context, raw string) (*models.OAuthToken, error)
+}
+
+// SetOAuthTokens lets RequireAuth accept OAuth access tokens on the
+// endpoints in oauthTokenRoutes
+func (m *Middleware) SetOAuthTokens(tokens OAuthTokenAuthenticator) {
+	m.oauthTokens = tokens
+}
+
+// oauthTokenRoutes are the endpoints OAuth applications can call, with the
+// scope each needs. Everything else is refused, including managing
+// applications and authorizing them.
+var oauthTokenRoutes = []serverTokenRoute{
+	tokenRoute(fiber.MethodGet, "/users/@me", models.OAuthScopeIdentify),
+
+	tokenRoute(fiber.MethodGet, "/users/@me/servers", models.OAuthScopeServersRead),
+	tokenRoute(fiber.MethodGet, "/servers/:id", models.OAuthScopeServersRead),
+	tokenRoute(fiber.MethodGet, "/servers/:id/channels", models.OAuthScopeServersRead),
+	tokenRoute(fiber.MethodGet, "/channels/:id", models.OAuthScopeServersRead),
+
+	tokenRoute(fiber.MethodPost, "/channels/:id/messages", models.OAuthScopeMessagesWrite),
+}
+
+// requireOAuthToken authenticates an OAuth access token and lets the
+// request through as the user who granted it if the endpoint is one
+// applications can call and the user granted its scope. The endpoint's own
+// permission checks then apply as for the user.
+func (m *Middleware) requireOAuthToken(c *fiber.Ctx, raw string) error {
+	token, err := m.oauthTokens.AuthenticateOAuthToken(c.UserContext(), raw)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "invalid token",
+		})
+	}
+
+	route, _ := findTokenRoute(oauthTokenRoutes, c.Method(), strings.TrimPrefix(c.Path(), "/api/v1"))
+	if route == nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "applications can't use this endpoint",
+		})
+	}
+	if !token.HasScope(route.scope) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "token is missing the " + route.scope + " scope",
+		})
+	}
+
+	if suspension := m.suspension(c, token.UserID); suspension != nil {
+		return accountSuspended(c, suspension)
+	}
+
+	c.Locals("userID", token.UserID)
+	return c.Next()
+}
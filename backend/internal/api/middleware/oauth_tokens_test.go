@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// fakeOAuthTokens knows one access token
+type fakeOAuthTokens struct {
+	raw   string
+	token *models.OAuthToken
+}
+
+func (f *fakeOAuthTokens) AuthenticateOAuthToken(ctx context.Context, raw string) (*models.OAuthToken, error) {
+	if raw != f.raw {
+		return nil, errors.New("invalid access token")
+	}
+	return f.token, nil
+}
+
+func TestRequireAuth_OAuthToken(t *testing.T) {
+	userID := uuid.New()
+	tokens := &fakeOAuthTokens{
+		raw: models.OAuthAccessTokenPrefix + "secret",
+		token: &models.OAuthToken{
+			ID:            uuid.New(),
+			ApplicationID: uuid.New(),
+			UserID:        userID,
+			Scopes:        []string{models.OAuthScopeIdentify, models.OAuthScopeServersRead},
+		},
+	}
+
+	m := NewMiddleware(testSecret)
+	m.SetOAuthTokens(tokens)
+	app := fiber.New()
+	api := app.Group("/api/v1", m.RequireAuth)
+	api.All("/*", func(c *fiber.Ctx) error {
+		if c.Locals("userID").(uuid.UUID) != userID {
+			t.Error("access tokens should act as the user who granted them")
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{"identifies the user", fiber.MethodGet, "/users/@me", tokens.raw, fiber.StatusOK},
+		{"lists their servers", fiber.MethodGet, "/users/@me/servers", tokens.raw, fiber.StatusOK},
+		{"reads any of their servers", fiber.MethodGet, "/servers/" + uuid.NewString() + "/channels", tokens.raw, fiber.StatusOK},
+		{"unknown token", fiber.MethodGet, "/users/@me", models.OAuthAccessTokenPrefix + "other", fiber.StatusUnauthorized},
+		{"missing scope", fiber.MethodPost, "/channels/" + uuid.NewString() + "/messages", tokens.raw, fiber.StatusForbidden},
+		{"other users", fiber.MethodGet, "/users/" + uuid.NewString(), tokens.raw, fiber.StatusForbidden},
+		{"account changes", fiber.MethodPatch, "/users/@me", tokens.raw, fiber.StatusForbidden},
+		{"authorizing applications", fiber.MethodPost, "/oauth2/authorize", tokens.raw, fiber.StatusForbidden},
+		{"managing applications", fiber.MethodGet, "/applications", tokens.raw, fiber.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1"+tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestRequireAuth_OAuthTokensDisabled(t *testing.T) {
+	m := NewMiddleware(testSecret)
+	app := fiber.New()
+	app.Get("/api/v1/users/@me", m.RequireAuth, func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	req := httptest.NewRequest(fiber.MethodGet, "/api/v1/users/@me", nil)
+	req.Header.Set("Authorization", "Bearer "+models.OAuthAccessTokenPrefix+"secret")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", fiber.StatusUnauthorized, resp.StatusCode)
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"hearth/internal/models"
+)
+
+// ReadOnlyChecker says whether the instance is refusing writes. The
+// services.ReadOnlyService implements it.
+type ReadOnlyChecker interface {
+	// Mode never returns nil
+	Mode(ctx context.Context) *models.ReadOnlyMode
+}
+
+// SetReadOnly turns on refusing writes while instance admins have the
+// instance in read-only mode
+func (m *Middleware) SetReadOnly(checker ReadOnlyChecker) {
+	m.readOnly = checker
+}
+
+// ReadOnly refuses requests other than GET, HEAD and OPTIONS with a 503
+// while the instance is read-only. Paths starting with one of exempt are
+// let through, so admins can still turn the mode off and users stay
+// logged in.
+func (m *Middleware) ReadOnly(exempt ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if m.readOnly == nil {
+			return c.Next()
+		}
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+		for _, prefix := range exempt {
+			if strings.HasPrefix(c.Path(), prefix) {
+				return c.Next()
+			}
+		}
+
+		mode := m.readOnly.Mode(c.UserContext())
+		if !mode.Enabled {
+			return c.Next()
+		}
+		body := fiber.Map{
+			"error":   "read_only",
+			"message": "this instance is read-only for maintenance; try again later",
+		}
+		if mode.Reason != nil {
+			body["reason"] = *mode.Reason
+		}
+		if mode.Since != nil {
+			body["since"] = mode.Since
+		}
+		return c.Status(fiber.StatusServiceUnavailable).JSON(body)
+	}
+}
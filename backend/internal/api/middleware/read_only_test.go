@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"hearth/internal/models"
+)
+
+type fakeReadOnly struct {
+	mode models.ReadOnlyMode
+}
+
+func (f *fakeReadOnly) Mode(ctx context.Context) *models.ReadOnlyMode {
+	return &f.mode
+}
+
+func TestReadOnly(t *testing.T) {
+	m := NewMiddleware(testSecret)
+	checker := &fakeReadOnly{}
+
+	app := fiber.New()
+	app.Use(m.ReadOnly("/admin", "/auth/login"))
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	app.Get("/messages", ok)
+	app.Post("/messages", ok)
+	app.Post("/admin/read-only", ok)
+	app.Post("/auth/login", ok)
+
+	do := func(method, path string) *fiber.Map {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest(method, path, nil))
+		if err != nil {
+			t.Fatalf("app.Test failed: %v", err)
+		}
+		if resp.StatusCode == fiber.StatusOK {
+			return nil
+		}
+		if resp.StatusCode != fiber.StatusServiceUnavailable {
+			t.Fatalf("%s %s: expected 200 or 503, got %d", method, path, resp.StatusCode)
+		}
+		var body fiber.Map
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return &body
+	}
+
+	if do("POST", "/messages") != nil {
+		t.Error("expected writes through without a checker")
+	}
+	m.SetReadOnly(checker)
+	if do("POST", "/messages") != nil {
+		t.Error("expected writes through while the instance is writable")
+	}
+
+	reason := "failing over"
+	checker.mode = models.ReadOnlyMode{Enabled: true, Reason: &reason}
+	body := do("POST", "/messages")
+	if body == nil {
+		t.Fatal("expected writes to be refused while read-only")
+	}
+	if (*body)["error"] != "read_only" || (*body)["reason"] != reason {
+		t.Errorf("unexpected body %v", *body)
+	}
+	if do("GET", "/messages") != nil {
+		t.Error("expected reads through while read-only")
+	}
+	if do("POST", "/admin/read-only") != nil || do("POST", "/auth/login") != nil {
+		t.Error("expected exempt paths through while read-only")
+	}
+}
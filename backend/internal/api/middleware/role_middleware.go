@@ -58,7 +58,7 @@ func (rm *RoleMiddleware) RequireRole(roleIDs ...uuid.UUID) fiber.Handler {
 		}
 
 		// Check if user is a member of the server
-		member, err := rm.serverRepo.GetMember(c.Context(), serverID, userID)
+		member, err := rm.serverRepo.GetMember(c.UserContext(), serverID, userID)
 		if err != nil || member == nil {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"error": "not a member of this server",
@@ -66,7 +66,7 @@ func (rm *RoleMiddleware) RequireRole(roleIDs ...uuid.UUID) fiber.Handler {
 		}
 
 		// Get user's roles
-		userRoles, err := rm.roleService.GetMemberRoles(c.Context(), serverID, userID)
+		userRoles, err := rm.roleService.GetMemberRoles(c.UserContext(), serverID, userID)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "failed to check roles",
@@ -116,7 +116,7 @@ func (rm *RoleMiddleware) RequirePermission(permission int64) fiber.Handler {
 		}
 
 		// Check if user is a member of the server
-		member, err := rm.serverRepo.GetMember(c.Context(), serverID, userID)
+		member, err := rm.serverRepo.GetMember(c.UserContext(), serverID, userID)
 		if err != nil || member == nil {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"error": "not a member of this server",
@@ -124,7 +124,7 @@ func (rm *RoleMiddleware) RequirePermission(permission int64) fiber.Handler {
 		}
 
 		// Check server ownership
-		server, err := rm.serverRepo.GetByID(c.Context(), serverID)
+		server, err := rm.serverRepo.GetByID(c.UserContext(), serverID)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "failed to check server",
@@ -142,7 +142,7 @@ func (rm *RoleMiddleware) RequirePermission(permission int64) fiber.Handler {
 		}
 
 		// Get user's effective permissions
-		permissions, err := rm.roleService.ComputeMemberPermissions(c.Context(), serverID, userID)
+		permissions, err := rm.roleService.ComputeMemberPermissions(c.UserContext(), serverID, userID)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "failed to check permissions",
@@ -185,7 +185,7 @@ func (rm *RoleMiddleware) RequirePermissionOrRole(permission int64, roleIDs ...u
 		}
 
 		// Check if user is a member of the server
-		member, err := rm.serverRepo.GetMember(c.Context(), serverID, userID)
+		member, err := rm.serverRepo.GetMember(c.UserContext(), serverID, userID)
 		if err != nil || member == nil {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"error": "not a member of this server",
@@ -193,7 +193,7 @@ func (rm *RoleMiddleware) RequirePermissionOrRole(permission int64, roleIDs ...u
 		}
 
 		// Get server for ownership check
-		server, err := rm.serverRepo.GetByID(c.Context(), serverID)
+		server, err := rm.serverRepo.GetByID(c.UserContext(), serverID)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "failed to check server",
@@ -212,7 +212,7 @@ func (rm *RoleMiddleware) RequirePermissionOrRole(permission int64, roleIDs ...u
 
 		// Check roles first (faster check)
 		if len(roleIDs) > 0 {
-			userRoles, err := rm.roleService.GetMemberRoles(c.Context(), serverID, userID)
+			userRoles, err := rm.roleService.GetMemberRoles(c.UserContext(), serverID, userID)
 			if err != nil {
 				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 					"error": "failed to check roles",
@@ -232,7 +232,7 @@ func (rm *RoleMiddleware) RequirePermissionOrRole(permission int64, roleIDs ...u
 		}
 
 		// Check permission
-		permissions, err := rm.roleService.ComputeMemberPermissions(c.Context(), serverID, userID)
+		permissions, err := rm.roleService.ComputeMemberPermissions(c.UserContext(), serverID, userID)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "failed to check permissions",
@@ -278,7 +278,7 @@ func (rm *RoleMiddleware) RequireServerOwner() fiber.Handler {
 			})
 		}
 
-		server, err := rm.serverRepo.GetByID(c.Context(), serverID)
+		server, err := rm.serverRepo.GetByID(c.UserContext(), serverID)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "failed to check server",
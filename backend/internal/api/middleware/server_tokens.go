@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// ServerTokenAuthenticator resolves server tokens. The
+// services.ServerTokenService implements it.
+type ServerTokenAuthenticator interface {
+	// AuthenticateServerToken returns an error for unknown, expired and
+	// revoked tokens
+	AuthenticateServerToken(ctx context.Context, raw string) (*models.ServerToken, error)
+	// ChannelServerID returns uuid.Nil for DMs and missing channels
+	ChannelServerID(ctx context.Context, channelID uuid.UUID) (uuid.UUID, error)
+}
+
+// SetServerTokens lets RequireAuth accept server tokens on the endpoints
+// in serverTokenRoutes
+func (m *Middleware) SetServerTokens(tokens ServerTokenAuthenticator) {
+	m.serverTokens = tokens
+}
+
+// serverTokenRoute is an endpoint server tokens can call. Its path starts
+// with /servers/:id or /channels/:id, the server or channel the request
+// is about.
+type serverTokenRoute struct {
+	method string
+	path   []string
+	scope  string
+}
+
+func tokenRoute(method, path, scope string) serverTokenRoute {
+	return serverTokenRoute{method: method, path: strings.Split(strings.Trim(path, "/"), "/"), scope: scope}
+}
+
+// serverTokenRoutes are the endpoints server tokens can call, with the
+// scope each needs. Everything else is refused.
+var serverTokenRoutes = []serverTokenRoute{
+	tokenRoute(fiber.MethodGet, "/servers/:id", models.ServerTokenScopeServerRead),
+	tokenRoute(fiber.MethodGet, "/servers/:id/channels", models.ServerTokenScopeServerRead),
+	tokenRoute(fiber.MethodGet, "/servers/:id/roles", models.ServerTokenScopeServerRead),
+	tokenRoute(fiber.MethodGet, "/channels/:id", models.ServerTokenScopeServerRead),
+
+	tokenRoute(fiber.MethodGet, "/servers/:id/members", models.ServerTokenScopeMembersRead),
+	tokenRoute(fiber.MethodGet, "/servers/:id/members/:userId", models.ServerTokenScopeMembersRead),
+
+	tokenRoute(fiber.MethodGet, "/channels/:id/messages", models.ServerTokenScopeMessagesRead),
+	tokenRoute(fiber.MethodGet, "/channels/:id/messages/:messageId", models.ServerTokenScopeMessagesRead),
+	tokenRoute(fiber.MethodGet, "/channels/:id/messages/:messageId/reactions", models.ServerTokenScopeMessagesRead),
+	tokenRoute(fiber.MethodGet, "/channels/:id/messages/:messageId/reactions/:emoji", models.ServerTokenScopeMessagesRead),
+
+	tokenRoute(fiber.MethodPost, "/channels/:id/messages", models.ServerTokenScopeMessagesWrite),
+	tokenRoute(fiber.MethodPatch, "/channels/:id/messages/:messageId", models.ServerTokenScopeMessagesWrite),
+	tokenRoute(fiber.MethodDelete, "/channels/:id/messages/:messageId", models.ServerTokenScopeMessagesWrite),
+	tokenRoute(fiber.MethodPut, "/channels/:id/messages/:messageId/reactions/:emoji/@me", models.ServerTokenScopeMessagesWrite),
+	tokenRoute(fiber.MethodDelete, "/channels/:id/messages/:messageId/reactions/:emoji/@me", models.ServerTokenScopeMessagesWrite),
+}
+
+// matchServerTokenRoute finds the route a request for path, relative to
+// /api/v1, calls and returns it with the ID of the server or channel the
+// request is about
+func matchServerTokenRoute(method, path string) (*serverTokenRoute, string, bool) {
+	route, segments := findTokenRoute(serverTokenRoutes, method, path)
+	if route == nil {
+		return nil, "", false
+	}
+	return route, segments[1], true
+}
+
+// findTokenRoute returns the route in routes a request for path calls, or
+// nil if there's none, with the path's segments
+func findTokenRoute(routes []serverTokenRoute, method, path string) (*serverTokenRoute, []string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := range routes {
+		route := &routes[i]
+		if route.method != method || len(route.path) != len(segments) {
+			continue
+		}
+		matched := true
+		for j, part := range route.path {
+			if strings.HasPrefix(part, ":") {
+				if segments[j] == "" {
+					matched = false
+					break
+				}
+			} else if part != segments[j] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return route, segments
+		}
+	}
+	return nil, segments
+}
+
+// requireServerToken authenticates a server token and lets the request
+// through as the token's creator if the endpoint is one tokens can call,
+// the token has its scope and the endpoint is about the token's server
+func (m *Middleware) requireServerToken(c *fiber.Ctx, raw string) error {
+	ctx := c.UserContext()
+	token, err := m.serverTokens.AuthenticateServerToken(ctx, raw)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "invalid token",
+		})
+	}
+
+	route, resource, ok := matchServerTokenRoute(c.Method(), strings.TrimPrefix(c.Path(), "/api/v1"))
+	if !ok {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "server tokens can't use this endpoint",
+		})
+	}
+	if !token.HasScope(route.scope) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "token is missing the " + route.scope + " scope",
+		})
+	}
+
+	resourceID, err := uuid.Parse(resource)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid id",
+		})
+	}
+	serverID := resourceID
+	if route.path[0] == "channels" {
+		if serverID, err = m.serverTokens.ChannelServerID(ctx, resourceID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to check token",
+			})
+		}
+	}
+	if serverID != token.ServerID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "token is limited to its own server",
+		})
+	}
+
+	if suspension := m.suspension(c, token.CreatorID); suspension != nil {
+		return accountSuspended(c, suspension)
+	}
+
+	c.Locals("userID", token.CreatorID)
+	return c.Next()
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// fakeServerTokens knows one token and which server each channel is in
+type fakeServerTokens struct {
+	raw      string
+	token    *models.ServerToken
+	channels map[uuid.UUID]uuid.UUID
+}
+
+func (f *fakeServerTokens) AuthenticateServerToken(ctx context.Context, raw string) (*models.ServerToken, error) {
+	if raw != f.raw {
+		return nil, errors.New("invalid server token")
+	}
+	return f.token, nil
+}
+
+func (f *fakeServerTokens) ChannelServerID(ctx context.Context, channelID uuid.UUID) (uuid.UUID, error) {
+	return f.channels[channelID], nil
+}
+
+func TestRequireAuth_ServerToken(t *testing.T) {
+	serverID, otherServerID := uuid.New(), uuid.New()
+	channelID, otherChannelID := uuid.New(), uuid.New()
+	creatorID := uuid.New()
+	tokens := &fakeServerTokens{
+		raw: models.ServerTokenPrefix + "secret",
+		token: &models.ServerToken{
+			ID:        uuid.New(),
+			ServerID:  serverID,
+			CreatorID: creatorID,
+			Scopes:    []string{models.ServerTokenScopeServerRead, models.ServerTokenScopeMessagesWrite},
+		},
+		channels: map[uuid.UUID]uuid.UUID{channelID: serverID, otherChannelID: otherServerID},
+	}
+
+	m := NewMiddleware(testSecret)
+	m.SetServerTokens(tokens)
+	app := fiber.New()
+	api := app.Group("/api/v1", m.RequireAuth)
+	api.All("/*", func(c *fiber.Ctx) error {
+		if c.Locals("userID").(uuid.UUID) != creatorID {
+			t.Error("server tokens should act as their creator")
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{"reads its server", fiber.MethodGet, "/servers/" + serverID.String(), tokens.raw, fiber.StatusOK},
+		{"posts to its server's channel", fiber.MethodPost, "/channels/" + channelID.String() + "/messages", tokens.raw, fiber.StatusOK},
+		{"reacts", fiber.MethodPut, "/channels/" + channelID.String() + "/messages/" + uuid.NewString() + "/reactions/%F0%9F%9A%80/@me", tokens.raw, fiber.StatusOK},
+		{"unknown token", fiber.MethodGet, "/servers/" + serverID.String(), models.ServerTokenPrefix + "other", fiber.StatusUnauthorized},
+		{"missing scope", fiber.MethodGet, "/channels/" + channelID.String() + "/messages", tokens.raw, fiber.StatusForbidden},
+		{"another server", fiber.MethodGet, "/servers/" + otherServerID.String(), tokens.raw, fiber.StatusForbidden},
+		{"another server's channel", fiber.MethodPost, "/channels/" + otherChannelID.String() + "/messages", tokens.raw, fiber.StatusForbidden},
+		{"missing channel", fiber.MethodPost, "/channels/" + uuid.NewString() + "/messages", tokens.raw, fiber.StatusForbidden},
+		{"endpoint tokens can't call", fiber.MethodDelete, "/servers/" + serverID.String(), tokens.raw, fiber.StatusForbidden},
+		{"token management", fiber.MethodPost, "/servers/" + serverID.String() + "/tokens", tokens.raw, fiber.StatusForbidden},
+		{"user endpoints", fiber.MethodGet, "/users/@me", tokens.raw, fiber.StatusForbidden},
+		{"malformed id", fiber.MethodGet, "/servers/nope", tokens.raw, fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1"+tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestRequireAuth_ServerTokensDisabled(t *testing.T) {
+	m := NewMiddleware(testSecret)
+	app := fiber.New()
+	app.Get("/api/v1/servers/:id", m.RequireAuth, func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	req := httptest.NewRequest(fiber.MethodGet, "/api/v1/servers/"+uuid.NewString(), nil)
+	req.Header.Set("Authorization", "Bearer "+models.ServerTokenPrefix+"secret")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", fiber.StatusUnauthorized, resp.StatusCode)
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// SuspensionChecker looks up whether instance admins suspended an account.
+// The services.UserSuspensionService implements it.
+type SuspensionChecker interface {
+	// ActiveSuspension returns nil if the user isn't suspended. Lookups
+	// that fail let the user through.
+	ActiveSuspension(ctx context.Context, userID uuid.UUID) *models.UserSuspension
+}
+
+// SetSuspensions makes RequireAuth refuse suspended accounts, whichever
+// kind of token they authenticate with
+func (m *Middleware) SetSuspensions(suspensions SuspensionChecker) {
+	m.suspensions = suspensions
+}
+
+// suspension returns the user's active suspension, if any
+func (m *Middleware) suspension(c *fiber.Ctx, userID uuid.UUID) *models.UserSuspension {
+	if m.suspensions == nil {
+		return nil
+	}
+	return m.suspensions.ActiveSuspension(c.UserContext(), userID)
+}
+
+// accountSuspended refuses a request from a suspended account with a 403
+// saying why and until when
+func accountSuspended(c *fiber.Ctx, suspension *models.UserSuspension) error {
+	body := fiber.Map{"error": "account suspended"}
+	if suspension.Reason != nil {
+		body["reason"] = *suspension.Reason
+	}
+	if suspension.ExpiresAt != nil {
+		body["suspended_until"] = suspension.ExpiresAt
+	}
+	return c.Status(fiber.StatusForbidden).JSON(body)
+}
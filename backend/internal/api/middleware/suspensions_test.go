@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+type stubSuspensions map[uuid.UUID]*models.UserSuspension
+
+func (s stubSuspensions) ActiveSuspension(ctx context.Context, userID uuid.UUID) *models.UserSuspension {
+	return s[userID]
+}
+
+func TestRequireAuthSuspended(t *testing.T) {
+	m := NewMiddleware(testSecret)
+	suspendedID := uuid.New()
+	reason, until := "spam", time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	m.SetSuspensions(stubSuspensions{suspendedID: {UserID: suspendedID, Reason: &reason, ExpiresAt: &until}})
+
+	app := fiber.New()
+	app.Get("/", m.RequireAuth, func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+generateTestToken(suspendedID, "access", false))
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("expected 403, got %d", resp.StatusCode)
+	}
+	var body struct {
+		Error          string    `json:"error"`
+		Reason         string    `json:"reason"`
+		SuspendedUntil time.Time `json:"suspended_until"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if body.Error != "account suspended" || body.Reason != reason || !body.SuspendedUntil.Equal(until) {
+		t.Errorf("unexpected body %+v", body)
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+generateTestToken(uuid.New(), "access", false))
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("expected 200 for users who aren't suspended, got %d", resp.StatusCode)
+	}
+}
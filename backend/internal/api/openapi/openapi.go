@@ -0,0 +1,345 @@
+// Package openapi holds the API's OpenAPI document and checks responses
+// against it, so the docs and the handlers can't drift apart unnoticed.
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+// Spec returns the raw OpenAPI document
+func Spec() []byte {
+	return spec
+}
+
+// Document is the part of an OpenAPI 3.0 document needed to check responses
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// PathItem holds the operations on one path template
+type PathItem struct {
+	Get    *Operation `json:"get"`
+	Post   *Operation `json:"post"`
+	Put    *Operation `json:"put"`
+	Patch  *Operation `json:"patch"`
+	Delete *Operation `json:"delete"`
+}
+
+// Operation is one method on a path
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Response is one documented status code
+type Response struct {
+	Ref         string                `json:"$ref"`
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+// MediaType holds the body schema for one content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of JSON Schema the document uses
+type Schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Format     string             `json:"format"`
+	Nullable   bool               `json:"nullable"`
+	Enum       []interface{}      `json:"enum"`
+	Required   []string           `json:"required"`
+	Properties map[string]*Schema `json:"properties"`
+	Items      *Schema            `json:"items"`
+}
+
+// Components holds the reusable parts of the document
+type Components struct {
+	Schemas   map[string]*Schema   `json:"schemas"`
+	Responses map[string]*Response `json:"responses"`
+}
+
+// Load parses the embedded document
+func Load() (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("openapi: parse document: %w", err)
+	}
+	return &doc, nil
+}
+
+// Operations lists every documented operation as "METHOD /path/{param}",
+// sorted
+func (d *Document) Operations() []string {
+	var ops []string
+	for path, item := range d.Paths {
+		for method, op := range item.operations() {
+			if op != nil {
+				ops = append(ops, method+" "+path)
+			}
+		}
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+func (p *PathItem) operations() map[string]*Operation {
+	return map[string]*Operation{
+		"GET":    p.Get,
+		"POST":   p.Post,
+		"PUT":    p.Put,
+		"PATCH":  p.Patch,
+		"DELETE": p.Delete,
+	}
+}
+
+// Match finds the path template a request path falls under. Literal
+// segments win over parameters, so /saved-messages/count isn't taken for
+// /saved-messages/{id}.
+func (d *Document) Match(method, path string) (template string, op *Operation) {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	best := -1
+	for tmpl, item := range d.Paths {
+		candidate := item.operations()[strings.ToUpper(method)]
+		if candidate == nil {
+			continue
+		}
+		literals, ok := matchTemplate(tmpl, segments)
+		if ok && literals > best {
+			best, template, op = literals, tmpl, candidate
+		}
+	}
+	return template, op
+}
+
+// matchTemplate reports whether segments fit tmpl and how many literal
+// segments matched
+func matchTemplate(tmpl string, segments []string) (int, bool) {
+	parts := strings.Split(strings.Trim(tmpl, "/"), "/")
+	if len(parts) != len(segments) {
+		return 0, false
+	}
+	literals := 0
+	for i, part := range parts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if segments[i] == "" {
+				return 0, false
+			}
+			continue
+		}
+		if part != segments[i] {
+			return 0, false
+		}
+		literals++
+	}
+	return literals, true
+}
+
+// ValidateResponse checks that status is documented for the request and
+// that body matches its schema. Properties the schema doesn't list are
+// allowed; missing required ones, wrong types and undocumented statuses
+// aren't.
+func (d *Document) ValidateResponse(method, path string, status int, body []byte) error {
+	template, op := d.Match(method, path)
+	if op == nil {
+		return fmt.Errorf("openapi: %s %s is not documented", method, path)
+	}
+	name := method + " " + template
+
+	resp := op.Responses[strconv.Itoa(status)]
+	if resp == nil {
+		resp = op.Responses[fmt.Sprintf("%dXX", status/100)]
+	}
+	if resp == nil {
+		resp = op.Responses["default"]
+	}
+	if resp == nil {
+		return fmt.Errorf("openapi: %s: status %d is not documented", name, status)
+	}
+	resp, err := d.resolveResponse(resp)
+	if err != nil {
+		return fmt.Errorf("openapi: %s: %w", name, err)
+	}
+
+	if len(resp.Content) == 0 {
+		if len(bytes.TrimSpace(body)) > 0 {
+			return fmt.Errorf("openapi: %s: status %d has no documented body but got %q", name, status, body)
+		}
+		return nil
+	}
+	media := resp.Content["application/json"]
+	if media == nil || media.Schema == nil {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("openapi: %s: status %d body isn't JSON: %w", name, status, err)
+	}
+
+	var errs []error
+	d.validate(media.Schema, value, "$", &errs)
+	if len(errs) > 0 {
+		return fmt.Errorf("openapi: %s: status %d body doesn't match the schema: %w", name, status, errors.Join(errs...))
+	}
+	return nil
+}
+
+func (d *Document) resolveResponse(resp *Response) (*Response, error) {
+	if resp.Ref == "" {
+		return resp, nil
+	}
+	name := strings.TrimPrefix(resp.Ref, "#/components/responses/")
+	resolved := d.Components.Responses[name]
+	if resolved == nil {
+		return nil, fmt.Errorf("unknown response %s", resp.Ref)
+	}
+	return resolved, nil
+}
+
+func (d *Document) resolveSchema(schema *Schema) (*Schema, error) {
+	if schema.Ref == "" {
+		return schema, nil
+	}
+	name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+	resolved := d.Components.Schemas[name]
+	if resolved == nil {
+		return nil, fmt.Errorf("unknown schema %s", schema.Ref)
+	}
+	return resolved, nil
+}
+
+// validate appends an error to errs for each way value breaks schema. at
+// is where value sits in the body, e.g. "$.actions[0].type".
+func (d *Document) validate(schema *Schema, value interface{}, at string, errs *[]error) {
+	schema, err := d.resolveSchema(schema)
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("%s: %w", at, err))
+		return
+	}
+
+	if value == nil {
+		if !schema.Nullable && schema.Type != "" {
+			*errs = append(*errs, fmt.Errorf("%s: is null, want %s", at, schema.Type))
+		}
+		return
+	}
+
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, fmt.Errorf("%s: "+format, append([]interface{}{at}, args...)...))
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			fail("is %s, want object", jsonType(value))
+			return
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				fail("required property %q is missing", name)
+			}
+		}
+		for name, prop := range schema.Properties {
+			if v, ok := object[name]; ok {
+				d.validate(prop, v, at+"."+name, errs)
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			fail("is %s, want array", jsonType(value))
+			return
+		}
+		if schema.Items != nil {
+			for i, item := range array {
+				d.validate(schema.Items, item, fmt.Sprintf("%s[%d]", at, i), errs)
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			fail("is %s, want string", jsonType(value))
+			return
+		}
+		switch schema.Format {
+		case "uuid":
+			if _, err := uuid.Parse(s); err != nil {
+				fail("%q is not a uuid", s)
+			}
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+				fail("%q is not a date-time", s)
+			}
+		}
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			fail("is %s, want integer", jsonType(value))
+			return
+		}
+		if _, err := n.Int64(); err != nil {
+			fail("%s is not an integer", n)
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			fail("is %s, want number", jsonType(value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("is %s, want boolean", jsonType(value))
+		}
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		fail("%v is not one of %v", value, schema.Enum)
+	}
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return "null"
+}
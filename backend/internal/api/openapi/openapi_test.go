@@ -0,0 +1,132 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	doc, err := Load()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(doc.OpenAPI, "3.0"))
+	assert.NotEmpty(t, doc.Operations())
+}
+
+func TestDocument_RefsResolve(t *testing.T) {
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal(Spec(), &raw))
+
+	var walk func(v interface{}, at string)
+	walk = func(v interface{}, at string) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				target := interface{}(raw)
+				for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+					object, _ := target.(map[string]interface{})
+					target = object[part]
+				}
+				assert.NotNil(t, target, "%s: %s doesn't resolve", at, ref)
+			}
+			for k, child := range v {
+				walk(child, at+"."+k)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child, at)
+			}
+		}
+	}
+	walk(raw, "$")
+}
+
+func TestDocument_OperationsAreComplete(t *testing.T) {
+	doc, err := Load()
+	require.NoError(t, err)
+
+	ids := map[string]string{}
+	for path, item := range doc.Paths {
+		for method, op := range item.operations() {
+			if op == nil {
+				continue
+			}
+			name := method + " " + path
+			if assert.NotEmpty(t, op.OperationID, name) {
+				assert.Empty(t, ids[op.OperationID], "%s reuses operationId %s", name, op.OperationID)
+				ids[op.OperationID] = name
+			}
+			success := false
+			for status := range op.Responses {
+				success = success || strings.HasPrefix(status, "2")
+			}
+			assert.True(t, success, "%s documents no success response", name)
+		}
+	}
+}
+
+func TestDocument_Match(t *testing.T) {
+	doc, err := Load()
+	require.NoError(t, err)
+
+	tests := []struct {
+		method, path, template string
+	}{
+		{"GET", "/api/v1/users/@me/saved-messages/count", "/api/v1/users/@me/saved-messages/count"},
+		{"GET", "/api/v1/users/@me/saved-messages/4b0c0fa4-8aa6-4c59-9b1f-8b2b9b1e2a11", "/api/v1/users/@me/saved-messages/{id}"},
+		{"GET", "/api/v1/users/@me/saved-messages?limit=10", "/api/v1/users/@me/saved-messages"},
+		{"DELETE", "/api/v1/servers/a/automod/rules/b", "/api/v1/servers/{id}/automod/rules/{ruleId}"},
+		{"PUT", "/api/v1/servers/a/automod/rules/b", ""},
+		{"GET", "/api/v1/nope", ""},
+	}
+	for _, tt := range tests {
+		template, _ := doc.Match(tt.method, tt.path)
+		assert.Equal(t, tt.template, template, "%s %s", tt.method, tt.path)
+	}
+}
+
+func TestDocument_ValidateResponse(t *testing.T) {
+	doc, err := Load()
+	require.NoError(t, err)
+
+	const rule = `{"id":"4b0c0fa4-8aa6-4c59-9b1f-8b2b9b1e2a11","server_id":"4b0c0fa4-8aa6-4c59-9b1f-8b2b9b1e2a12",` +
+		`"creator_id":"4b0c0fa4-8aa6-4c59-9b1f-8b2b9b1e2a13","name":"No invites","trigger_type":"invite_link",` +
+		`"trigger_metadata":{},"actions":[{"type":"block_message"}],"enabled":true,"exempt_roles":[],` +
+		`"exempt_channels":[],"created_at":"2026-02-14T12:00:00Z","updated_at":"2026-02-14T12:00:00.123456Z"}`
+	const path = "/api/v1/servers/x/automod/rules/y"
+
+	tests := []struct {
+		name    string
+		method  string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"valid", "GET", 200, rule, ""},
+		{"extra properties allowed", "GET", 200, strings.Replace(rule, `"name"`, `"extra":1,"name"`, 1), ""},
+		{"error body", "GET", 404, `{"error":"automod rule not found"}`, ""},
+		{"no content", "DELETE", 204, ``, ""},
+		{"undocumented status", "GET", 409, `{"error":"conflict"}`, "status 409 is not documented"},
+		{"undocumented route", "PUT", 200, `{}`, "is not documented"},
+		{"missing required", "GET", 200, strings.Replace(rule, `"enabled":true,`, ``, 1), `required property "enabled" is missing`},
+		{"null array", "GET", 200, strings.Replace(rule, `"exempt_roles":[]`, `"exempt_roles":null`, 1), "$.exempt_roles: is null"},
+		{"wrong type", "GET", 200, strings.Replace(rule, `"enabled":true`, `"enabled":"yes"`, 1), "$.enabled: is string, want boolean"},
+		{"bad enum", "GET", 200, strings.Replace(rule, `"block_message"`, `"shout"`, 1), "$.actions[0].type"},
+		{"bad uuid", "GET", 200, strings.Replace(rule, `"4b0c0fa4-8aa6-4c59-9b1f-8b2b9b1e2a11"`, `"1"`, 1), "is not a uuid"},
+		{"body on no content", "DELETE", 204, `{"ok":true}`, "no documented body"},
+		{"not json", "GET", 200, `<html>`, "isn't JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := doc.ValidateResponse(tt.method, path, tt.status, []byte(tt.body))
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,13 +1,23 @@
 package api
 
 import (
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/contrib/websocket"
 	
 	"hearth/internal/api/handlers"
+	"hearth/internal/api/openapi"
 	"hearth/internal/api/middleware"
 )
 
+// transferTimeout bounds file transfers, which outlast the default request
+// deadlines. It matches the server's read and write timeouts.
+const transferTimeout = 30 * time.Second
+
+// oauthTimeout bounds OAuth logins, which wait on the login provider
+const oauthTimeout = 15 * time.Second
+
 // SetupRoutes configures all API routes
 func SetupRoutes(app *fiber.App, h *handlers.Handlers, m *middleware.Middleware) {
 	// Health check endpoints for Kubernetes/load balancers
@@ -18,8 +28,27 @@ func SetupRoutes(app *fiber.App, h *handlers.Handlers, m *middleware.Middleware)
 	// /readyz - Kubernetes-style readiness probe (returns 503 when draining)
 	app.Get("/readyz", h.Gateway.ReadinessCheck)
 	
-	// API v1
-	v1 := app.Group("/api/v1")
+	// API v1. Requests get a read or write deadline that is passed down
+	// to services and repositories, and their queries are labelled with
+	// the route for the slow query log. Mutating requests are recorded in
+	// the compliance log when it's enabled. Blocked client addresses are
+	// refused before anything else runs, then writes while the instance is
+	// read-only. Admins can still write so they can turn it off, and users
+	// can still log in.
+	v1 := app.Group("/api/v1", m.IPFilter(), m.ReadOnly(
+		"/api/v1/admin",
+		"/api/v1/auth/login",
+		"/api/v1/auth/refresh",
+		"/api/v1/auth/logout",
+		"/api/v1/oauth2/token",
+		"/api/v1/voice/webhook",
+	), m.QueryLabels(), m.ComplianceLog(), m.ServerActivity(), m.RequestTimeout())
+	
+	// OpenAPI document (public)
+	v1.Get("/openapi.json", func(c *fiber.Ctx) error {
+		c.Type("json")
+		return c.Send(openapi.Spec())
+	})
 	
 	// Auth routes (public)
 	auth := v1.Group("/auth")
@@ -27,8 +56,31 @@ func SetupRoutes(app *fiber.App, h *handlers.Handlers, m *middleware.Middleware)
 	auth.Post("/login", h.Auth.Login)
 	auth.Post("/refresh", h.Auth.Refresh)
 	auth.Post("/logout", h.Auth.Logout)
-	auth.Get("/oauth/:provider", h.Auth.OAuthRedirect)
-	auth.Get("/oauth/:provider/callback", h.Auth.OAuthCallback)
+	auth.Post("/password-reset", h.Auth.RequestPasswordReset)
+	auth.Post("/password-reset/confirm", h.Auth.ConfirmPasswordReset)
+	auth.Get("/oauth/:provider/start", m.Timeout(oauthTimeout), h.Auth.OAuthRedirect)
+	auth.Get("/oauth/:provider/callback", m.Timeout(oauthTimeout), h.Auth.OAuthCallback)
+	// Kept for links made before /start
+	auth.Get("/oauth/:provider", m.Timeout(oauthTimeout), h.Auth.OAuthRedirect)
+	
+	// OAuth2 token endpoint (authenticated by the application's client
+	// credentials)
+	if h.OAuthApps != nil {
+		v1.Post("/oauth2/token", h.OAuthApps.Token)
+	}
+	
+	// SFU webhooks (signed by the SFU, not a user)
+	v1.Post("/voice/webhook", h.Voice.Webhook)
+	
+	// Incoming webhooks (authenticated by the webhook's token)
+	if h.Webhooks != nil {
+		v1.Post("/webhooks/:webhookID/:token", h.Webhooks.ExecuteWebhook)
+	}
+	
+	// Channel Atom feeds (authenticated by the feed's token)
+	if h.ChannelFeeds != nil {
+		v1.Get("/channels/:id/feed.atom", h.ChannelFeeds.GetFeed)
+	}
 	
 	// Protected routes
 	api := v1.Group("", m.RequireAuth)
@@ -37,10 +89,18 @@ func SetupRoutes(app *fiber.App, h *handlers.Handlers, m *middleware.Middleware)
 	users := api.Group("/users")
 	users.Get("/@me", h.Users.GetMe)
 	users.Patch("/@me", h.Users.UpdateMe)
+	users.Put("/@me/custom-status", h.Users.SetCustomStatus)
+	users.Delete("/@me/custom-status", h.Users.ClearCustomStatus)
 	users.Get("/@me/servers", h.Users.GetMyServers)
 	users.Get("/@me/channels", h.Users.GetMyDMs)
 	users.Post("/@me/channels", h.Users.CreateDM)
 	users.Post("/@me/channels/group", h.Users.CreateGroupDM)
+	users.Get("/@me/accounts", h.Users.GetMyAccounts)
+	users.Get("/@me/username-availability", h.Users.CheckUsername)
+	users.Get("/@me/username-history", h.Users.GetUsernameHistory)
+	users.Get("/@me/connections", h.Gateway.GetMyConnections)
+	users.Delete("/@me/connections/:id", h.Gateway.CloseConnection)
+	users.Post("/bulk", h.Users.GetUsersBulk)
 	users.Get("/:id", h.Users.GetUser)
 	users.Get("/:id/profile", h.Users.GetUserProfile)
 	
@@ -54,6 +114,49 @@ func SetupRoutes(app *fiber.App, h *handlers.Handlers, m *middleware.Middleware)
 	// Read State / Unread
 	if h.ReadState != nil {
 		users.Get("/@me/unread", h.ReadState.GetUnreadSummary)
+		users.Get("/@me/read-states", h.ReadState.GetReadStates)
+	}
+	
+	// Push devices and per-channel notification settings
+	if h.Push != nil {
+		users.Get("/@me/devices", h.Push.ListDevices)
+		users.Post("/@me/devices", h.Push.RegisterDevice)
+		users.Get("/@me/devices/vapid-key", h.Push.GetVAPIDKey)
+		users.Delete("/@me/devices/:id", h.Push.UnregisterDevice)
+		users.Get("/@me/channels/:id/notification-settings", h.Push.GetChannelSettings)
+		users.Put("/@me/channels/:id/notification-settings", h.Push.UpdateChannelSettings)
+	}
+	
+	// End-to-end encryption device keys
+	if h.E2EE != nil {
+		users.Get("/@me/keys", h.E2EE.ListDevices)
+		users.Put("/@me/keys/:deviceId", h.E2EE.RegisterDevice)
+		users.Post("/@me/keys/:deviceId/pre-keys", h.E2EE.UploadPreKeys)
+		users.Delete("/@me/keys/:deviceId", h.E2EE.RemoveDevice)
+		users.Get("/:id/keys", h.E2EE.ClaimPreKeyBundles)
+	}
+	
+	// Login sessions
+	if h.Sessions != nil {
+		users.Get("/@me/sessions", h.Sessions.ListSessions)
+		users.Delete("/@me/sessions", h.Sessions.RevokeAllSessions)
+		users.Delete("/@me/sessions/:id", h.Sessions.RevokeSession)
+	}
+	
+	// OAuth applications: registering them, and authorizing them to act
+	// for the user
+	if h.OAuthApps != nil {
+		applications := api.Group("/applications")
+		applications.Get("/", h.OAuthApps.ListApplications)
+		applications.Post("/", h.OAuthApps.CreateApplication)
+		applications.Delete("/:id", h.OAuthApps.DeleteApplication)
+		applications.Post("/:id/secret", h.OAuthApps.ResetClientSecret)
+		
+		api.Get("/oauth2/authorize", h.OAuthApps.GetAuthorization)
+		api.Post("/oauth2/authorize", h.OAuthApps.Authorize)
+		
+		users.Get("/@me/applications", h.OAuthApps.ListAuthorizedApplications)
+		users.Delete("/@me/applications/:id", h.OAuthApps.RevokeAuthorization)
 	}
 	
 	// Notifications
@@ -99,16 +202,26 @@ func SetupRoutes(app *fiber.App, h *handlers.Handlers, m *middleware.Middleware)
 	servers.Patch("/:id", h.Servers.Update)
 	servers.Delete("/:id", h.Servers.Delete)
 	servers.Post("/:id/transfer-ownership", h.Servers.TransferOwnership)
+	servers.Get("/:id/transfer-ownership", h.Servers.GetOwnershipTransfer)
+	servers.Delete("/:id/transfer-ownership", h.Servers.CancelOwnershipTransfer)
+	servers.Post("/:id/transfer-ownership/accept", h.Servers.AcceptOwnershipTransfer)
 	
 	// Server members
 	servers.Get("/:id/members", h.Servers.GetMembers)
 	servers.Get("/:id/members/:userId", h.Servers.GetMember)
 	servers.Patch("/:id/members/:userId", h.Servers.UpdateMember)
 	servers.Delete("/:id/members/:userId", h.Servers.RemoveMember)
+	servers.Put("/:id/members/:userId/timeout", h.Servers.TimeoutMember)
+	servers.Delete("/:id/members/:userId/timeout", h.Servers.RemoveTimeout)
+	servers.Put("/:id/members/:userId/co-owner", h.Servers.SetCoOwner)
+	servers.Delete("/:id/members/:userId/co-owner", h.Servers.RemoveCoOwner)
 	servers.Delete("/:id/members/@me", h.Servers.Leave)
 	
 	// Server bans
 	servers.Get("/:id/bans", h.Servers.GetBans)
+	servers.Get("/:id/bans/export", h.Servers.ExportBans)
+	servers.Post("/:id/bans/import", h.Servers.ImportBans)
+	servers.Get("/:id/bans/imports/:importId", h.Servers.GetBanImport)
 	servers.Put("/:id/bans/:userId", h.Servers.CreateBan)
 	servers.Delete("/:id/bans/:userId", h.Servers.RemoveBan)
 	
@@ -118,6 +231,8 @@ func SetupRoutes(app *fiber.App, h *handlers.Handlers, m *middleware.Middleware)
 	// Server roles
 	servers.Get("/:id/roles", h.Servers.GetRoles)
 	servers.Post("/:id/roles", h.Servers.CreateRole)
+	servers.Patch("/:id/roles/positions", h.Servers.UpdateRolePositions)
+	servers.Get("/:id/roles/:roleId", h.Servers.GetRole)
 	servers.Patch("/:id/roles/:roleId", h.Servers.UpdateRole)
 	servers.Delete("/:id/roles/:roleId", h.Servers.DeleteRole)
 	
@@ -128,6 +243,75 @@ func SetupRoutes(app *fiber.App, h *handlers.Handlers, m *middleware.Middleware)
 		servers.Get("/:id/audit-logs/:entryId", h.AuditLog.GetAuditLogEntry)
 	}
 	
+	// Server AutoMod rules
+	if h.AutoMod != nil {
+		servers.Get("/:id/automod/rules", h.AutoMod.ListRules)
+		servers.Post("/:id/automod/rules", h.AutoMod.CreateRule)
+		servers.Get("/:id/automod/rules/:ruleId", h.AutoMod.GetRule)
+		servers.Patch("/:id/automod/rules/:ruleId", h.AutoMod.UpdateRule)
+		servers.Delete("/:id/automod/rules/:ruleId", h.AutoMod.DeleteRule)
+	}
+	
+	// Server custom emoji
+	if h.Emojis != nil {
+		servers.Get("/:id/emojis", h.Emojis.ListEmojis)
+		servers.Post("/:id/emojis", m.Timeout(transferTimeout), h.Emojis.CreateEmoji)
+		servers.Patch("/:id/emojis/:emojiId", h.Emojis.RenameEmoji)
+		servers.Delete("/:id/emojis/:emojiId", h.Emojis.DeleteEmoji)
+	}
+	
+	// Server membership screening
+	if h.MemberVerification != nil {
+		servers.Get("/:id/member-verification", h.MemberVerification.GetSettings)
+		servers.Patch("/:id/member-verification", h.MemberVerification.UpdateSettings)
+		servers.Put("/:id/member-verification/@me", h.MemberVerification.Submit)
+		servers.Get("/:id/member-verification/responses/:userId", h.MemberVerification.GetResponse)
+	}
+	
+	// Server welcome screen and onboarding
+	if h.Onboarding != nil {
+		servers.Get("/:id/welcome-screen", h.Onboarding.GetWelcomeScreen)
+		servers.Patch("/:id/welcome-screen", h.Onboarding.UpdateWelcomeScreen)
+		servers.Get("/:id/onboarding", h.Onboarding.GetOnboarding)
+		servers.Patch("/:id/onboarding", h.Onboarding.UpdateOnboarding)
+		servers.Get("/:id/onboarding/@me", h.Onboarding.GetResponses)
+		servers.Put("/:id/onboarding/@me", h.Onboarding.Submit)
+	}
+	
+	// Shared blocklists and the servers following them
+	if h.Blocklists != nil {
+		servers.Get("/:id/blocklists", h.Blocklists.GetSubscriptions)
+		servers.Put("/:id/blocklists/:blocklistId", h.Blocklists.Subscribe)
+		servers.Delete("/:id/blocklists/:blocklistId", h.Blocklists.Unsubscribe)
+		servers.Get("/:id/blocklists/:blocklistId/exemptions", h.Blocklists.GetExemptions)
+		servers.Put("/:id/blocklists/:blocklistId/exemptions/:userId", h.Blocklists.Exempt)
+		servers.Delete("/:id/blocklists/:blocklistId/exemptions/:userId", h.Blocklists.Unexempt)
+		
+		blocklists := api.Group("/blocklists")
+		blocklists.Get("/", h.Blocklists.ListBlocklists)
+		blocklists.Post("/", h.Blocklists.CreateBlocklist)
+		blocklists.Get("/:id", h.Blocklists.GetBlocklist)
+		blocklists.Patch("/:id", h.Blocklists.UpdateBlocklist)
+		blocklists.Delete("/:id", h.Blocklists.DeleteBlocklist)
+		blocklists.Get("/:id/entries", h.Blocklists.GetEntries)
+		blocklists.Post("/:id/entries", h.Blocklists.AddEntries)
+		blocklists.Delete("/:id/entries/:userId", h.Blocklists.RemoveEntry)
+	}
+	
+	// Webhook policies
+	if h.WebhookPolicies != nil {
+		servers.Get("/:id/webhook-policy", h.WebhookPolicies.GetServerPolicy)
+		servers.Put("/:id/webhook-policy", h.WebhookPolicies.UpdateServerPolicy)
+		servers.Delete("/:id/webhook-policy", h.WebhookPolicies.DeleteServerPolicy)
+	}
+	
+	// API tokens for automation
+	if h.ServerTokens != nil {
+		servers.Get("/:id/tokens", h.ServerTokens.ListTokens)
+		servers.Post("/:id/tokens", h.ServerTokens.CreateToken)
+		servers.Delete("/:id/tokens/:tokenId", h.ServerTokens.RevokeToken)
+	}
+	
 	// Server read state / Ack
 	if h.ReadState != nil {
 		servers.Get("/:id/unread", h.ReadState.GetServerUnread)
@@ -139,13 +323,21 @@ func SetupRoutes(app *fiber.App, h *handlers.Handlers, m *middleware.Middleware)
 	channels.Get("/:id", h.Channels.Get)
 	channels.Patch("/:id", h.Channels.Update)
 	channels.Delete("/:id", h.Channels.Delete)
+	if h.Permissions != nil {
+		channels.Get("/:id/permissions/@me", h.Permissions.GetMyChannelPermissions)
+	}
 	
 	// Channel messages
 	channels.Get("/:id/messages", h.Channels.GetMessages)
 	channels.Post("/:id/messages", h.Channels.SendMessage)
+	channels.Get("/:id/messages/archive", h.Channels.GetArchivedMessages)
 	channels.Get("/:id/messages/:messageId", h.Channels.GetMessage)
+	channels.Get("/:id/messages/:messageId/replies", h.Channels.GetMessageReplies)
 	channels.Patch("/:id/messages/:messageId", h.Channels.EditMessage)
 	channels.Delete("/:id/messages/:messageId", h.Channels.DeleteMessage)
+	channels.Post("/:id/messages/bulk-delete", h.Channels.BulkDeleteMessages)
+	channels.Post("/:id/messages/:messageId/forward", h.Channels.ForwardMessage)
+	channels.Get("/:id/tombstones", h.Channels.GetDeletedMessages)
 	
 	// Reactions
 	channels.Get("/:id/messages/:messageId/reactions", h.Channels.GetReactions)
@@ -165,6 +357,7 @@ func SetupRoutes(app *fiber.App, h *handlers.Handlers, m *middleware.Middleware)
 	// Read state / Ack
 	if h.ReadState != nil {
 		channels.Post("/:id/ack", h.ReadState.MarkChannelAsRead)
+		channels.Post("/:id/messages/:messageId/ack", h.ReadState.AckMessage)
 		channels.Get("/:id/unread", h.ReadState.GetChannelUnread)
 	}
 	
@@ -172,6 +365,32 @@ func SetupRoutes(app *fiber.App, h *handlers.Handlers, m *middleware.Middleware)
 	channels.Get("/:id/threads", h.Threads.GetChannelThreads)
 	channels.Post("/:id/threads", h.Threads.CreateThread)
 	
+	// Forum posts and settings
+	if h.Forums != nil {
+		channels.Get("/:id/forum", h.Forums.GetSettings)
+		channels.Patch("/:id/forum", h.Forums.UpdateSettings)
+		channels.Get("/:id/forum-posts", h.Forums.ListPosts)
+		channels.Post("/:id/forum-posts", h.Forums.CreatePost)
+	}
+	if h.ChannelFeeds != nil {
+		channels.Get("/:id/feed", h.ChannelFeeds.GetSettings)
+		channels.Put("/:id/feed", h.ChannelFeeds.UpdateSettings)
+	}
+	if h.ChannelSchedules != nil {
+		channels.Get("/:id/schedule", h.ChannelSchedules.GetSchedule)
+		channels.Put("/:id/schedule", h.ChannelSchedules.UpdateSchedule)
+		channels.Delete("/:id/schedule", h.ChannelSchedules.DeleteSchedule)
+	}
+	if h.WebhookPolicies != nil {
+		channels.Get("/:id/webhook-policy", h.WebhookPolicies.GetChannelPolicy)
+		channels.Put("/:id/webhook-policy", h.WebhookPolicies.UpdateChannelPolicy)
+		channels.Delete("/:id/webhook-policy", h.WebhookPolicies.DeleteChannelPolicy)
+	}
+	if h.E2EE != nil {
+		channels.Get("/:id/keys", h.E2EE.GetChannelKeys)
+		channels.Post("/:id/keys", h.E2EE.DistributeSenderKeys)
+	}
+	
 	// Threads
 	threads := api.Group("/threads")
 	threads.Get("/:id", h.Threads.GetThread)
@@ -215,14 +434,16 @@ func SetupRoutes(app *fiber.App, h *handlers.Handlers, m *middleware.Middleware)
 	// Attachments (if handler is configured)
 	if h.Attachments != nil {
 		// Channel attachments
-		channels.Post("/:id/attachments", h.Attachments.Upload)
+		channels.Post("/:id/attachments", m.Timeout(transferTimeout), h.Attachments.Upload)
+		channels.Post("/:id/attachments/presign", h.Attachments.PresignUpload)
 		channels.Get("/:id/attachments", h.Attachments.GetChannelAttachments)
 		
 		// Attachments
 		attachments := api.Group("/attachments")
 		attachments.Get("/:id", h.Attachments.Get)
-		attachments.Get("/:id/download", h.Attachments.Download)
+		attachments.Get("/:id/download", m.Timeout(transferTimeout), h.Attachments.Download)
 		attachments.Get("/:id/signed-url", h.Attachments.GetSignedURL)
+		attachments.Post("/:id/confirm", h.Attachments.ConfirmUpload)
 		attachments.Delete("/:id", h.Attachments.Delete)
 	}
 	
@@ -237,12 +458,75 @@ func SetupRoutes(app *fiber.App, h *handlers.Handlers, m *middleware.Middleware)
 	// Voice
 	voice := api.Group("/voice")
 	voice.Get("/regions", h.Voice.GetRegions)
-	
+	voice.Post("/channels/:id/join", h.Voice.JoinChannel)
+	voice.Post("/leave", h.Voice.Leave)
+	voice.Put("/stream", h.Voice.StartStream)
+	voice.Delete("/stream", h.Voice.StopStream)
+	voice.Get("/channels/:id/streams", h.Voice.GetStreams)
+	voice.Put("/channels/:id/streams/:userId/viewers/@me", h.Voice.WatchStream)
+	voice.Delete("/channels/:id/streams/:userId/viewers/@me", h.Voice.UnwatchStream)
+	
+	// Instance maintenance (staff only)
+	if h.Admin != nil {
+		admin := api.Group("/admin", h.Admin.RequireStaff, h.Admin.RateLimit)
+		admin.Get("/integrity", h.Admin.CheckIntegrity)
+		admin.Post("/integrity/repair", h.Admin.RepairIntegrity)
+		admin.Get("/compliance-log", h.Admin.ExportComplianceLog)
+		admin.Get("/username-rules", h.Admin.ListUsernameRules)
+		admin.Post("/username-rules", h.Admin.CreateUsernameRule)
+		admin.Delete("/username-rules/:id", h.Admin.DeleteUsernameRule)
+		admin.Get("/bots", h.Admin.ListBots)
+		admin.Get("/bots/:id", h.Admin.GetBotTier)
+		admin.Put("/bots/:id/tier", h.Admin.SetBotTier)
+		admin.Post("/users/:id/erasure", h.Admin.RequestErasure)
+		admin.Get("/users/:id/erasures", h.Admin.ListErasures)
+		admin.Get("/erasures/:id", h.Admin.GetErasure)
+		admin.Get("/servers/:id/throttle", h.Admin.GetServerThrottle)
+		admin.Put("/servers/:id/throttle", h.Admin.SetServerThrottle)
+		admin.Delete("/servers/:id/throttle", h.Admin.ResetServerThrottle)
+		admin.Get("/quotas", h.Admin.ListQuotas)
+		admin.Get("/quotas/:tier", h.Admin.GetQuotaTier)
+		admin.Put("/quotas/:tier", h.Admin.SetQuotaTier)
+		admin.Delete("/quotas/:tier", h.Admin.ResetQuotaTier)
+		admin.Get("/ip-rules", h.Admin.ListIPRules)
+		admin.Post("/ip-rules", h.Admin.CreateIPRule)
+		admin.Get("/ip-rules/check", h.Admin.CheckIP)
+		admin.Delete("/ip-rules/:id", h.Admin.DeleteIPRule)
+		admin.Get("/users", h.Admin.ListUsers)
+		admin.Put("/users/:id/suspension", h.Admin.SuspendUser)
+		admin.Delete("/users/:id/suspension", h.Admin.UnsuspendUser)
+		admin.Delete("/servers/:id", h.Admin.DeleteServer)
+		admin.Get("/stats", h.Admin.GetStats)
+		admin.Post("/config/reload", h.Admin.ReloadConfig)
+		admin.Get("/announcements", h.Admin.ListAnnouncements)
+		admin.Post("/announcements", h.Admin.CreateAnnouncement)
+		admin.Delete("/announcements/:id", h.Admin.DeleteAnnouncement)
+		admin.Get("/read-only", h.Admin.GetReadOnlyMode)
+		admin.Put("/read-only", h.Admin.SetReadOnlyMode)
+		admin.Get("/reports", h.Admin.ListReports)
+		admin.Get("/reports/:id", h.Admin.GetReport)
+		admin.Put("/reports/:id/status", h.Admin.UpdateReportStatus)
+		admin.Put("/reports/:id/assignee", h.Admin.AssignReport)
+		admin.Delete("/reports/:id/assignee", h.Admin.UnassignReport)
+		admin.Post("/reports/:id/notes", h.Admin.AddReportNote)
+		admin.Get("/gateway/drain", h.Gateway.GetDrainStatus)
+	}
+
+	// Instance announcements
+	if h.Announcements != nil {
+		api.Get("/announcements", h.Announcements.ListAnnouncements)
+	}
+
+	// Reports to the instance's trust and safety team
+	if h.Reports != nil {
+		api.Post("/reports", h.Reports.CreateReport)
+	}
+
 	// Gateway stats (admin)
 	api.Get("/gateway/stats", h.Gateway.GetStats)
 	
 	// WebSocket gateway
-	app.Get("/gateway", m.WebSocketUpgrade, websocket.New(h.Gateway.Connect))
+	app.Get("/gateway", m.IPFilter(), m.WebSocketUpgrade, websocket.New(h.Gateway.Connect))
 	
 	// Static files (for self-hosted frontend)
 	app.Static("/", "./public")
@@ -24,6 +24,24 @@
 //   - BCRYPT_POOL_WORKERS: Number of workers (default: NumCPU)
 //   - BCRYPT_POOL_QUEUE: Queue size (default: Workers * 10)
 //   - BCRYPT_POOL_TIMEOUT: Operation timeout (default: 5s)
+//   - BCRYPT_POOL_MAX_WORKERS: Most workers the pool grows to (default: 0,
+//     fixed at BCRYPT_POOL_WORKERS)
+//   - BCRYPT_POOL_TARGET_WAIT: Average queue wait the pool scales to stay
+//     under (default: 100ms)
+//
+// # Backpressure
+//
+// Jobs that can't start in time are refused rather than left to time out:
+// submitting fails with ErrQueueFull when the queue is full or so backed up
+// that the job would finish after its deadline, and workers skip jobs whose
+// caller gave up while they were queued. IsOverloaded tells these apart from
+// a wrong password, so callers can answer 503 instead of 401.
+//
+// With MaxWorkers above Workers, the pool adds a worker every ScaleInterval
+// while jobs wait longer than TargetQueueWait on average and the process has
+// MinCPUHeadroom to spare, and removes one when the queue is empty and waits
+// are well under target. Adding workers when the CPU is already busy would
+// only slow every job down.
 //
 // # Usage
 //
@@ -39,6 +57,7 @@ import (
 	"context"
 	"errors"
 	"runtime"
+	runtimemetrics "runtime/metrics"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -51,10 +70,17 @@ var (
 	ErrPoolClosed = errors.New("bcrypt pool is closed")
 	// ErrPoolTimeout is returned when a job times out waiting for a worker
 	ErrPoolTimeout = errors.New("bcrypt operation timed out")
-	// ErrQueueFull is returned when the job queue is at capacity
+	// ErrQueueFull is returned when the job queue is at capacity, or so
+	// backed up that the job would finish after its deadline
 	ErrQueueFull = errors.New("bcrypt job queue is full")
 )
 
+// IsOverloaded reports whether err means the pool had no capacity for the
+// job, rather than anything being wrong with the password
+func IsOverloaded(err error) bool {
+	return errors.Is(err, ErrQueueFull) || errors.Is(err, ErrPoolTimeout) || errors.Is(err, ErrPoolClosed)
+}
+
 // PoolConfig configures the bcrypt worker pool
 type PoolConfig struct {
 	// Workers is the number of concurrent bcrypt workers.
@@ -72,6 +98,39 @@ type PoolConfig struct {
 	// Cost is the bcrypt cost factor.
 	// Default: 12 (same as bcryptCost constant)
 	Cost int
+
+	// MaxWorkers is the most workers the pool scales up to; Workers is
+	// then the least it scales down to.
+	// Default: 0 (no autoscaling)
+	MaxWorkers int
+
+	// TargetQueueWait is the average time jobs may wait for a worker before
+	// the pool adds one.
+	// Default: 100ms
+	TargetQueueWait time.Duration
+
+	// MinCPUHeadroom is the share of the process's CPU that must have gone
+	// unused for the pool to add a worker.
+	// Default: 0.2
+	MinCPUHeadroom float64
+
+	// ScaleInterval is how often the pool considers scaling.
+	// Default: 1 second
+	ScaleInterval time.Duration
+}
+
+// QueueWaitBuckets are the upper bounds of PoolStats.QueueWait's buckets
+var QueueWaitBuckets = [...]time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
 }
 
 // DefaultPoolConfig returns sensible defaults for the pool
@@ -89,6 +148,9 @@ func DefaultPoolConfig() PoolConfig {
 type hashJob struct {
 	password string
 	result   chan hashResult
+	queued   time.Time
+	// done is closed once the caller stops waiting
+	done <-chan struct{}
 }
 
 // hashResult contains the result of a hash operation
@@ -102,6 +164,9 @@ type checkJob struct {
 	password string
 	hash     string
 	result   chan error
+	queued   time.Time
+	// done is closed once the caller stops waiting
+	done <-chan struct{}
 }
 
 // BcryptPool manages bounded concurrency for bcrypt operations
@@ -114,11 +179,53 @@ type BcryptPool struct {
 	shutdownCtx context.Context
 	shutdown    context.CancelFunc
 
+	// workers is how many workers are running; sending on retire stops an
+	// idle one
+	workers atomic.Int64
+	retire  chan struct{}
+	// serviceTime is a moving average of how long a job takes, in
+	// nanoseconds, for estimating whether new jobs can finish in time
+	serviceTime atomic.Int64
+
+	// scalerDone is closed when the autoscaler stops; nil without one
+	scalerDone  chan struct{}
+	cpuHeadroom func() float64
+	lastWaits   uint64
+	lastWaitSum int64
+
 	// Metrics (atomic for thread-safety)
-	hashCount    atomic.Int64
-	checkCount   atomic.Int64
-	timeoutCount atomic.Int64
-	queuedCount  atomic.Int64
+	hashCount     atomic.Int64
+	checkCount    atomic.Int64
+	timeoutCount  atomic.Int64
+	queuedCount   atomic.Int64
+	rejectedCount atomic.Int64
+	expiredCount  atomic.Int64
+	queueWait     waitHistogram
+}
+
+// waitHistogram counts queue waits into QueueWaitBuckets
+type waitHistogram struct {
+	buckets [len(QueueWaitBuckets) + 1]atomic.Uint64 // the last is +Inf
+	count   atomic.Uint64
+	sum     atomic.Int64
+}
+
+func (h *waitHistogram) observe(wait time.Duration) {
+	i := 0
+	for i < len(QueueWaitBuckets) && wait > QueueWaitBuckets[i] {
+		i++
+	}
+	h.buckets[i].Add(1)
+	h.sum.Add(int64(wait))
+	h.count.Add(1)
+}
+
+// QueueWaitHistogram is how long jobs waited for a worker. Buckets are
+// cumulative, one per QueueWaitBuckets bound.
+type QueueWaitHistogram struct {
+	Count   uint64
+	Sum     time.Duration
+	Buckets []uint64
 }
 
 // NewBcryptPool creates a new bounded bcrypt worker pool
@@ -135,6 +242,18 @@ func NewBcryptPool(config PoolConfig) *BcryptPool {
 	if config.Cost <= 0 {
 		config.Cost = bcryptCost
 	}
+	if config.MaxWorkers < config.Workers {
+		config.MaxWorkers = config.Workers
+	}
+	if config.TargetQueueWait <= 0 {
+		config.TargetQueueWait = 100 * time.Millisecond
+	}
+	if config.MinCPUHeadroom <= 0 {
+		config.MinCPUHeadroom = 0.2
+	}
+	if config.ScaleInterval <= 0 {
+		config.ScaleInterval = time.Second
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -144,51 +263,163 @@ func NewBcryptPool(config PoolConfig) *BcryptPool {
 		checkJobs:   make(chan checkJob, config.QueueSize),
 		shutdownCtx: ctx,
 		shutdown:    cancel,
+		retire:      make(chan struct{}),
+		cpuHeadroom: newCPUSampler().headroom,
 	}
 
 	// Start workers
 	for i := 0; i < config.Workers; i++ {
-		pool.wg.Add(1)
-		go pool.worker()
+		pool.spawn()
+	}
+
+	if config.MaxWorkers > config.Workers {
+		pool.scalerDone = make(chan struct{})
+		go pool.runScaler()
 	}
 
 	return pool
 }
 
+// spawn starts a worker
+func (p *BcryptPool) spawn() {
+	p.workers.Add(1)
+	p.wg.Add(1)
+	go p.worker()
+}
+
 // worker processes bcrypt jobs from both hash and check queues
 func (p *BcryptPool) worker() {
 	defer p.wg.Done()
+	defer p.workers.Add(-1)
 
 	for {
 		select {
 		case <-p.shutdownCtx.Done():
 			return
 
+		case <-p.retire:
+			return
+
 		case job, ok := <-p.hashJobs:
 			if !ok {
 				return
 			}
-			p.queuedCount.Add(-1)
+			if !p.dequeued(job.queued, job.done) {
+				continue
+			}
+			start := time.Now()
 			hash, err := bcrypt.GenerateFromPassword([]byte(job.password), p.config.Cost)
+			p.served(time.Since(start))
+			p.hashCount.Add(1)
 			select {
 			case job.result <- hashResult{hash: string(hash), err: err}:
 			default:
 				// Result channel closed or blocked - job was likely cancelled
 			}
-			p.hashCount.Add(1)
 
 		case job, ok := <-p.checkJobs:
 			if !ok {
 				return
 			}
-			p.queuedCount.Add(-1)
+			if !p.dequeued(job.queued, job.done) {
+				continue
+			}
+			start := time.Now()
 			err := bcrypt.CompareHashAndPassword([]byte(job.hash), []byte(job.password))
+			p.served(time.Since(start))
+			p.checkCount.Add(1)
 			select {
 			case job.result <- err:
 			default:
 				// Result channel closed or blocked - job was likely cancelled
 			}
-			p.checkCount.Add(1)
+		}
+	}
+}
+
+// dequeued records how long a job waited and reports whether it's still
+// worth doing. Jobs whose caller gave up are skipped, so a backlog of
+// abandoned jobs doesn't hold up the ones behind it.
+func (p *BcryptPool) dequeued(queued time.Time, done <-chan struct{}) bool {
+	p.queuedCount.Add(-1)
+	p.queueWait.observe(time.Since(queued))
+	select {
+	case <-done:
+		p.expiredCount.Add(1)
+		return false
+	default:
+		return true
+	}
+}
+
+// served folds how long a job took into the moving average
+func (p *BcryptPool) served(d time.Duration) {
+	for {
+		old := p.serviceTime.Load()
+		next := int64(d)
+		if old > 0 {
+			next = old + (int64(d)-old)/8
+		}
+		if p.serviceTime.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+// saturated reports whether a job submitted now would likely finish after
+// timeout, given the jobs queued ahead of it
+func (p *BcryptPool) saturated(timeout time.Duration) bool {
+	service := p.serviceTime.Load()
+	workers := p.workers.Load()
+	if service == 0 || workers <= 0 {
+		return false
+	}
+	finish := time.Duration((p.queuedCount.Load() + workers) * service / workers)
+	return finish > timeout
+}
+
+// runScaler calls autoscale every ScaleInterval until the pool closes
+func (p *BcryptPool) runScaler() {
+	defer close(p.scalerDone)
+
+	ticker := time.NewTicker(p.config.ScaleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.shutdownCtx.Done():
+			return
+		case <-ticker.C:
+			p.autoscale()
+		}
+	}
+}
+
+// autoscale adds a worker if jobs waited too long since the last call and
+// there's CPU to spare, or removes one if the pool had more than it needed
+func (p *BcryptPool) autoscale() {
+	waits, waitSum := p.queueWait.count.Load(), p.queueWait.sum.Load()
+	jobs, waited := waits-p.lastWaits, waitSum-p.lastWaitSum
+	p.lastWaits, p.lastWaitSum = waits, waitSum
+	headroom := p.cpuHeadroom()
+
+	var avgWait time.Duration
+	switch {
+	case jobs > 0:
+		avgWait = time.Duration(waited / int64(jobs))
+	case p.queuedCount.Load() > 0:
+		// Nothing left the queue, so whatever is in it waited all along
+		avgWait = p.config.ScaleInterval
+	}
+
+	workers := int(p.workers.Load())
+	switch {
+	case avgWait > p.config.TargetQueueWait && headroom >= p.config.MinCPUHeadroom && workers < p.config.MaxWorkers:
+		p.spawn()
+	case avgWait < p.config.TargetQueueWait/4 && p.queuedCount.Load() == 0 && workers > p.config.Workers:
+		select {
+		case p.retire <- struct{}{}:
+		default:
+			// Every worker is busy; try again next time
 		}
 	}
 }
@@ -204,11 +435,6 @@ func (p *BcryptPool) HashPassword(ctx context.Context, password string) (string,
 		return "", err
 	}
 
-	job := hashJob{
-		password: password,
-		result:   make(chan hashResult, 1),
-	}
-
 	// Apply timeout from context or default
 	timeout := p.config.DefaultTimeout
 	if deadline, ok := ctx.Deadline(); ok {
@@ -217,17 +443,31 @@ func (p *BcryptPool) HashPassword(ctx context.Context, password string) (string,
 			timeout = remaining
 		}
 	}
+	if p.saturated(timeout) {
+		p.rejectedCount.Add(1)
+		return "", ErrQueueFull
+	}
 
 	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
+	job := hashJob{
+		password: password,
+		result:   make(chan hashResult, 1),
+		queued:   time.Now(),
+		done:     timeoutCtx.Done(),
+	}
+
 	// Try to submit job - check shutdown to avoid sending to closed channel
+	p.queuedCount.Add(1)
 	select {
 	case <-p.shutdownCtx.Done():
+		p.queuedCount.Add(-1)
 		return "", ErrPoolClosed
 	case p.hashJobs <- job:
-		p.queuedCount.Add(1)
 	default:
+		p.queuedCount.Add(-1)
+		p.rejectedCount.Add(1)
 		return "", ErrQueueFull
 	}
 
@@ -250,12 +490,6 @@ func (p *BcryptPool) CheckPassword(ctx context.Context, password, hash string) e
 		return ErrPoolClosed
 	}
 
-	job := checkJob{
-		password: password,
-		hash:     hash,
-		result:   make(chan error, 1),
-	}
-
 	// Apply timeout from context or default
 	timeout := p.config.DefaultTimeout
 	if deadline, ok := ctx.Deadline(); ok {
@@ -264,17 +498,32 @@ func (p *BcryptPool) CheckPassword(ctx context.Context, password, hash string) e
 			timeout = remaining
 		}
 	}
+	if p.saturated(timeout) {
+		p.rejectedCount.Add(1)
+		return ErrQueueFull
+	}
 
 	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
+	job := checkJob{
+		password: password,
+		hash:     hash,
+		result:   make(chan error, 1),
+		queued:   time.Now(),
+		done:     timeoutCtx.Done(),
+	}
+
 	// Try to submit job - check shutdown to avoid sending to closed channel
+	p.queuedCount.Add(1)
 	select {
 	case <-p.shutdownCtx.Done():
+		p.queuedCount.Add(-1)
 		return ErrPoolClosed
 	case p.checkJobs <- job:
-		p.queuedCount.Add(1)
 	default:
+		p.queuedCount.Add(-1)
+		p.rejectedCount.Add(1)
 		return ErrQueueFull
 	}
 
@@ -301,6 +550,9 @@ func (p *BcryptPool) Close() error {
 	}
 
 	p.shutdown()
+	if p.scalerDone != nil {
+		<-p.scalerDone
+	}
 	close(p.hashJobs)
 	close(p.checkJobs)
 	p.wg.Wait()
@@ -310,24 +562,83 @@ func (p *BcryptPool) Close() error {
 
 // Stats returns current pool statistics
 type PoolStats struct {
+	// Workers is how many workers are running now, between MinWorkers
+	// and MaxWorkers
 	Workers      int
+	MinWorkers   int
+	MaxWorkers   int
 	QueueSize    int
 	Queued       int64
 	HashCount    int64
 	CheckCount   int64
 	TimeoutCount int64
+	// RejectedCount is jobs refused with ErrQueueFull
+	RejectedCount int64
+	// ExpiredCount is jobs skipped because their caller gave up while
+	// they were queued
+	ExpiredCount int64
+	QueueWait    QueueWaitHistogram
 }
 
 // Stats returns current pool statistics
 func (p *BcryptPool) Stats() PoolStats {
+	wait := QueueWaitHistogram{
+		Count:   p.queueWait.count.Load(),
+		Sum:     time.Duration(p.queueWait.sum.Load()),
+		Buckets: make([]uint64, len(QueueWaitBuckets)),
+	}
+	var cumulative uint64
+	for i := range QueueWaitBuckets {
+		cumulative += p.queueWait.buckets[i].Load()
+		wait.Buckets[i] = cumulative
+	}
+
 	return PoolStats{
-		Workers:      p.config.Workers,
-		QueueSize:    p.config.QueueSize,
-		Queued:       p.queuedCount.Load(),
-		HashCount:    p.hashCount.Load(),
-		CheckCount:   p.checkCount.Load(),
-		TimeoutCount: p.timeoutCount.Load(),
+		Workers:       int(p.workers.Load()),
+		MinWorkers:    p.config.Workers,
+		MaxWorkers:    p.config.MaxWorkers,
+		QueueSize:     p.config.QueueSize,
+		Queued:        p.queuedCount.Load(),
+		HashCount:     p.hashCount.Load(),
+		CheckCount:    p.checkCount.Load(),
+		TimeoutCount:  p.timeoutCount.Load(),
+		RejectedCount: p.rejectedCount.Load(),
+		ExpiredCount:  p.expiredCount.Load(),
+		QueueWait:     wait,
+	}
+}
+
+// cpuSampler measures how much of the CPU available to the process went
+// unused between samples, from the runtime's own accounting
+type cpuSampler struct {
+	samples   []runtimemetrics.Sample
+	lastIdle  float64
+	lastTotal float64
+}
+
+func newCPUSampler() *cpuSampler {
+	s := &cpuSampler{samples: []runtimemetrics.Sample{
+		{Name: "/cpu/classes/idle:cpu-seconds"},
+		{Name: "/cpu/classes/total:cpu-seconds"},
+	}}
+	s.headroom()
+	return s
+}
+
+// headroom returns the idle share of CPU since the last call, or 1 if the
+// runtime doesn't report it
+func (s *cpuSampler) headroom() float64 {
+	runtimemetrics.Read(s.samples)
+	if s.samples[0].Value.Kind() != runtimemetrics.KindFloat64 || s.samples[1].Value.Kind() != runtimemetrics.KindFloat64 {
+		return 1
+	}
+	idle, total := s.samples[0].Value.Float64(), s.samples[1].Value.Float64()
+	idleDelta, totalDelta := idle-s.lastIdle, total-s.lastTotal
+	s.lastIdle, s.lastTotal = idle, total
+	if totalDelta <= 0 {
+		return 1
 	}
+	return idleDelta / totalDelta
 }
 
 // Global pool instance (lazily initialized)
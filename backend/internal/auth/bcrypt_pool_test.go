@@ -479,3 +479,90 @@ func BenchmarkBcrypt_Direct_Parallel(b *testing.B) {
 		}
 	})
 }
+
+func TestBcryptPool_Autoscale(t *testing.T) {
+	pool := NewBcryptPool(PoolConfig{
+		Workers:         1,
+		MaxWorkers:      3,
+		QueueSize:       10,
+		Cost:            4,
+		TargetQueueWait: 100 * time.Millisecond,
+		ScaleInterval:   time.Hour, // autoscale is called by hand
+	})
+	defer pool.Close()
+	headroom := 0.5
+	pool.cpuHeadroom = func() float64 { return headroom }
+
+	// Jobs waiting too long with CPU to spare add workers, up to the max
+	for i := 0; i < 3; i++ {
+		pool.queueWait.observe(200 * time.Millisecond)
+		pool.autoscale()
+	}
+	assert.Equal(t, 3, pool.Stats().Workers)
+
+	// No more are added once the CPU is busy, however long jobs wait
+	pool.config.MaxWorkers = 4
+	headroom = 0.05
+	pool.queueWait.observe(time.Second)
+	pool.autoscale()
+	assert.Equal(t, 3, pool.Stats().Workers)
+
+	// An idle pool shrinks back to Workers
+	for i := 0; i < 4; i++ {
+		pool.autoscale()
+		time.Sleep(10 * time.Millisecond) // let the retired worker exit
+	}
+	assert.Eventually(t, func() bool { return pool.Stats().Workers == 1 }, time.Second, 10*time.Millisecond)
+
+	stats := pool.Stats()
+	assert.Equal(t, 1, stats.MinWorkers)
+	assert.Equal(t, 4, stats.MaxWorkers)
+}
+
+func TestBcryptPool_RejectsJobsThatCantFinishInTime(t *testing.T) {
+	pool := NewBcryptPool(PoolConfig{
+		Workers:        1,
+		QueueSize:      10,
+		Cost:           4,
+		DefaultTimeout: 100 * time.Millisecond,
+	})
+	defer pool.Close()
+
+	// Jobs have been taking a second each
+	pool.serviceTime.Store(int64(time.Second))
+
+	_, err := pool.HashPassword(context.Background(), "Password123abc")
+	assert.ErrorIs(t, err, ErrQueueFull)
+	assert.True(t, IsOverloaded(err))
+	err = pool.CheckPassword(context.Background(), "Password123abc", "somehash")
+	assert.ErrorIs(t, err, ErrQueueFull)
+	assert.Equal(t, int64(2), pool.Stats().RejectedCount)
+	assert.Equal(t, int64(0), pool.Stats().Queued)
+
+	assert.False(t, IsOverloaded(ErrPasswordMismatch))
+}
+
+func TestBcryptPool_SkipsAbandonedJobs(t *testing.T) {
+	pool := NewBcryptPool(PoolConfig{Workers: 1, QueueSize: 10, Cost: 4})
+	defer pool.Close()
+
+	done := make(chan struct{})
+	close(done)
+	pool.queuedCount.Add(1)
+	assert.False(t, pool.dequeued(time.Now().Add(-time.Second), done))
+	assert.Equal(t, int64(1), pool.Stats().ExpiredCount)
+}
+
+func TestBcryptPool_QueueWaitStats(t *testing.T) {
+	pool := NewBcryptPool(PoolConfig{Workers: 1, QueueSize: 10, Cost: 4})
+	defer pool.Close()
+
+	_, err := pool.HashPassword(context.Background(), "Password123abc")
+	require.NoError(t, err)
+
+	wait := pool.Stats().QueueWait
+	assert.Equal(t, uint64(1), wait.Count)
+	require.Len(t, wait.Buckets, len(QueueWaitBuckets))
+	assert.Equal(t, uint64(1), wait.Buckets[len(wait.Buckets)-1], "buckets are cumulative")
+	assert.Greater(t, pool.serviceTime.Load(), int64(0))
+}
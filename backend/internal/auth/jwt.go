@@ -11,6 +11,7 @@ import (
 var (
 	ErrInvalidToken = errors.New("invalid token")
 	ErrExpiredToken = errors.New("token has expired")
+	ErrRevokedToken = errors.New("token has been revoked")
 )
 
 // Claims represents JWT claims
@@ -19,6 +20,19 @@ type Claims struct {
 	UserID   uuid.UUID `json:"uid"`
 	Username string    `json:"usr"`
 	Type     string    `json:"typ"` // "access" or "refresh"
+	Bot      bool      `json:"bot,omitempty"`
+	// SessionID is the login session, or token family, the token belongs
+	// to. Tokens issued before sessions were tracked have none.
+	SessionID *uuid.UUID `json:"sid,omitempty"`
+	// Version is the user's token version when the token was issued
+	Version int `json:"ver,omitempty"`
+}
+
+// TokenFamily ties a token pair to a login session. Refreshed pairs keep
+// the family; logging out everywhere moves the user to a new version.
+type TokenFamily struct {
+	SessionID uuid.UUID
+	Version   int
 }
 
 // JWTService handles JWT operations
@@ -41,43 +55,12 @@ func NewJWTService(secretKey string, accessExpiry, refreshExpiry time.Duration)
 
 // GenerateAccessToken creates an access token
 func (s *JWTService) GenerateAccessToken(userID uuid.UUID, username string) (string, error) {
-	now := time.Now()
-	claims := Claims{
-		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    s.issuer,
-			Subject:   userID.String(),
-			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(s.accessExpiry)),
-			NotBefore: jwt.NewNumericDate(now),
-			ID:        uuid.New().String(),
-		},
-		UserID:   userID,
-		Username: username,
-		Type:     "access",
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.secretKey)
+	return s.generateToken(userID, username, "access", s.accessExpiry, false, nil)
 }
 
 // GenerateRefreshToken creates a refresh token
 func (s *JWTService) GenerateRefreshToken(userID uuid.UUID) (string, error) {
-	now := time.Now()
-	claims := Claims{
-		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    s.issuer,
-			Subject:   userID.String(),
-			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(s.refreshExpiry)),
-			NotBefore: jwt.NewNumericDate(now),
-			ID:        uuid.New().String(),
-		},
-		UserID: userID,
-		Type:   "refresh",
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.secretKey)
+	return s.generateToken(userID, "", "refresh", s.refreshExpiry, false, nil)
 }
 
 // GenerateTokenPair creates both access and refresh tokens
@@ -95,6 +78,73 @@ func (s *JWTService) GenerateTokenPair(userID uuid.UUID, username string) (acces
 	return accessToken, refreshToken, nil
 }
 
+// GenerateBotTokenPair creates a token pair for a bot account. Both tokens
+// carry the bot claim so refreshing keeps the session marked as a bot.
+func (s *JWTService) GenerateBotTokenPair(userID uuid.UUID, username string) (accessToken, refreshToken string, err error) {
+	accessToken, err = s.generateToken(userID, username, "access", s.accessExpiry, true, nil)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err = s.generateToken(userID, "", "refresh", s.refreshExpiry, true, nil)
+	if err != nil {
+		return "", "", err
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// GenerateSessionTokenPair creates a token pair belonging to a login
+// session. The refresh token keeps the username so refreshed access tokens
+// still carry it.
+func (s *JWTService) GenerateSessionTokenPair(userID uuid.UUID, username string, bot bool, family TokenFamily) (accessToken, refreshToken string, err error) {
+	accessToken, err = s.generateToken(userID, username, "access", s.accessExpiry, bot, &family)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err = s.generateToken(userID, username, "refresh", s.refreshExpiry, bot, &family)
+	if err != nil {
+		return "", "", err
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// CheckTokenVersion returns ErrRevokedToken if the token was issued before
+// the user moved to version, i.e. before they last logged out everywhere
+func (s *JWTService) CheckTokenVersion(claims *Claims, version int) error {
+	if claims.Version != version {
+		return ErrRevokedToken
+	}
+	return nil
+}
+
+func (s *JWTService) generateToken(userID uuid.UUID, username, tokenType string, expiry time.Duration, bot bool, family *TokenFamily) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.issuer,
+			Subject:   userID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
+			NotBefore: jwt.NewNumericDate(now),
+			ID:        uuid.New().String(),
+		},
+		UserID:   userID,
+		Username: username,
+		Type:     tokenType,
+		Bot:      bot,
+	}
+	if family != nil {
+		claims.SessionID = &family.SessionID
+		claims.Version = family.Version
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.secretKey)
+}
+
 // ValidateToken validates a token and returns claims
 func (s *JWTService) ValidateToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
@@ -147,6 +197,11 @@ func (s *JWTService) ValidateRefreshToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
+// GetRefreshExpiry returns how long refresh tokens are valid for
+func (s *JWTService) GetRefreshExpiry() time.Duration {
+	return s.refreshExpiry
+}
+
 // GetExpirySeconds returns access token expiry in seconds
 func (s *JWTService) GetExpirySeconds() int {
 	return int(s.accessExpiry.Seconds())
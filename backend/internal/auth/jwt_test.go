@@ -85,6 +85,62 @@ func TestGenerateTokenPair(t *testing.T) {
 	assert.Equal(t, "refresh", refreshClaims.Type)
 }
 
+func TestGenerateBotTokenPair(t *testing.T) {
+	service := NewJWTService("test-secret-key-for-testing", 15*time.Minute, 7*24*time.Hour)
+	userID := uuid.New()
+
+	accessToken, refreshToken, err := service.GenerateBotTokenPair(userID, "helperbot")
+	require.NoError(t, err)
+
+	accessClaims, err := service.ValidateAccessToken(accessToken)
+	require.NoError(t, err)
+	assert.True(t, accessClaims.Bot)
+	assert.Equal(t, "helperbot", accessClaims.Username)
+
+	refreshClaims, err := service.ValidateRefreshToken(refreshToken)
+	require.NoError(t, err)
+	assert.True(t, refreshClaims.Bot)
+
+	// Regular tokens are never marked as bots
+	userToken, err := service.GenerateAccessToken(userID, "someone")
+	require.NoError(t, err)
+	userClaims, err := service.ValidateAccessToken(userToken)
+	require.NoError(t, err)
+	assert.False(t, userClaims.Bot)
+}
+
+func TestGenerateSessionTokenPair(t *testing.T) {
+	service := NewJWTService("test-secret-key-for-testing", 15*time.Minute, 7*24*time.Hour)
+	userID, sessionID := uuid.New(), uuid.New()
+
+	accessToken, refreshToken, err := service.GenerateSessionTokenPair(userID, "testuser", false, TokenFamily{SessionID: sessionID, Version: 3})
+	require.NoError(t, err)
+
+	accessClaims, err := service.ValidateAccessToken(accessToken)
+	require.NoError(t, err)
+	require.NotNil(t, accessClaims.SessionID)
+	assert.Equal(t, sessionID, *accessClaims.SessionID)
+
+	refreshClaims, err := service.ValidateRefreshToken(refreshToken)
+	require.NoError(t, err)
+	require.NotNil(t, refreshClaims.SessionID)
+	assert.Equal(t, sessionID, *refreshClaims.SessionID)
+	assert.Equal(t, 3, refreshClaims.Version)
+	assert.Equal(t, "testuser", refreshClaims.Username, "refreshed access tokens keep the username")
+
+	assert.NoError(t, service.CheckTokenVersion(refreshClaims, 3))
+	assert.ErrorIs(t, service.CheckTokenVersion(refreshClaims, 4), ErrRevokedToken)
+
+	// Tokens from before sessions were tracked are on version 0
+	legacy, err := service.GenerateRefreshToken(userID)
+	require.NoError(t, err)
+	legacyClaims, err := service.ValidateRefreshToken(legacy)
+	require.NoError(t, err)
+	assert.Nil(t, legacyClaims.SessionID)
+	assert.NoError(t, service.CheckTokenVersion(legacyClaims, 0))
+	assert.ErrorIs(t, service.CheckTokenVersion(legacyClaims, 1), ErrRevokedToken)
+}
+
 func TestValidateToken_InvalidFormat(t *testing.T) {
 	service := NewJWTService("test-secret", 15*time.Minute, 7*24*time.Hour)
 
@@ -0,0 +1,399 @@
+// Package oauth logs users in through external OAuth2 and OpenID Connect
+// providers such as Google, GitHub or a self-hosted identity server.
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kind is the identity API a provider speaks
+type Kind string
+
+const (
+	// KindOIDC providers describe users through the OIDC userinfo endpoint
+	KindOIDC Kind = "oidc"
+	// KindGitHub is GitHub's OAuth app API, which isn't OIDC
+	KindGitHub Kind = "github"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown OAuth provider")
+	ErrNoSubject       = errors.New("provider did not identify the user")
+)
+
+// ProviderConfig configures a login provider
+type ProviderConfig struct {
+	// Name identifies the provider in login URLs and linked identities
+	Name string
+	Kind Kind
+
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+
+	// Issuer is where an OIDC provider's endpoints are discovered from
+	// when they aren't set
+	Issuer      string
+	AuthURL     string
+	TokenURL    string
+	UserinfoURL string
+
+	// EmailsURL lists a GitHub user's emails and whether they're verified
+	EmailsURL string
+}
+
+// Google configures logging in with a Google account
+func Google(clientID, clientSecret string) ProviderConfig {
+	return ProviderConfig{
+		Name:         "google",
+		Kind:         KindOIDC,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       []string{"openid", "email", "profile"},
+		Issuer:       "https://accounts.google.com",
+		AuthURL:      "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL:     "https://oauth2.googleapis.com/token",
+		UserinfoURL:  "https://openidconnect.googleapis.com/v1/userinfo",
+	}
+}
+
+// GitHub configures logging in with a GitHub account
+func GitHub(clientID, clientSecret string) ProviderConfig {
+	return ProviderConfig{
+		Name:         "github",
+		Kind:         KindGitHub,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       []string{"read:user", "user:email"},
+		AuthURL:      "https://github.com/login/oauth/authorize",
+		TokenURL:     "https://github.com/login/oauth/access_token",
+		UserinfoURL:  "https://api.github.com/user",
+		EmailsURL:    "https://api.github.com/user/emails",
+	}
+}
+
+// OIDC configures logging in with any OpenID Connect provider, whose
+// endpoints are discovered from its issuer
+func OIDC(name, issuer, clientID, clientSecret string) ProviderConfig {
+	return ProviderConfig{
+		Name:         name,
+		Kind:         KindOIDC,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       []string{"openid", "email", "profile"},
+		Issuer:       strings.TrimRight(issuer, "/"),
+	}
+}
+
+// Identity is the account a provider says logged in
+type Identity struct {
+	Provider string
+	// Subject is the provider's stable ID for the account
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	// Username is the account's handle at the provider, if it has one
+	Username  string
+	AvatarURL string
+}
+
+// Provider runs the authorization code flow against one provider
+type Provider struct {
+	cfg    ProviderConfig
+	client *http.Client
+
+	// Guards the endpoints filled in by discovery
+	mu         sync.Mutex
+	discovered bool
+}
+
+// NewProvider creates a provider. client may be nil to use a default with a
+// timeout.
+func NewProvider(cfg ProviderConfig, client *http.Client) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(cfg.Scopes) == 0 && cfg.Kind == KindOIDC {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Provider{cfg: cfg, client: client}
+}
+
+// Name returns the provider's name
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// AuthCodeURL returns where to send the user to log in. The provider sends
+// them back to redirectURI with a code and state; challenge is the PKCE S256
+// challenge of the verifier later passed to Exchange.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, challenge, redirectURI string) (string, error) {
+	cfg, err := p.endpoints(ctx)
+	if err != nil {
+		return "", err
+	}
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {cfg.ClientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {strings.Join(cfg.Scopes, " ")},
+		"state":                 {state},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(cfg.AuthURL, "?") {
+		sep = "&"
+	}
+	return cfg.AuthURL + sep + params.Encode(), nil
+}
+
+// Exchange trades the code the provider sent back for an access token
+func (p *Provider) Exchange(ctx context.Context, code, verifier, redirectURI string) (string, error) {
+	cfg, err := p.endpoints(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"client_id":     {cfg.ClientID},
+		"client_secret": {cfg.ClientSecret},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// GitHub answers form-encoded unless asked for JSON
+	req.Header.Set("Accept", "application/json")
+
+	var result struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := p.do(req, &result); err != nil {
+		return "", fmt.Errorf("%s token exchange: %w", p.cfg.Name, err)
+	}
+	// GitHub refuses codes with a 200 and an error body
+	if result.Error != "" {
+		return "", fmt.Errorf("%s token exchange: %s %s", p.cfg.Name, result.Error, result.ErrorDescription)
+	}
+	if result.AccessToken == "" {
+		return "", fmt.Errorf("%s token exchange returned no access token", p.cfg.Name)
+	}
+	return result.AccessToken, nil
+}
+
+// Identity fetches the account the access token belongs to
+func (p *Provider) Identity(ctx context.Context, accessToken string) (*Identity, error) {
+	cfg, err := p.endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var identity *Identity
+	if cfg.Kind == KindGitHub {
+		identity, err = p.githubIdentity(ctx, cfg, accessToken)
+	} else {
+		identity, err = p.oidcIdentity(ctx, cfg, accessToken)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s identity: %w", p.cfg.Name, err)
+	}
+	if identity.Subject == "" {
+		return nil, ErrNoSubject
+	}
+	identity.Provider = p.cfg.Name
+	return identity, nil
+}
+
+func (p *Provider) oidcIdentity(ctx context.Context, cfg ProviderConfig, accessToken string) (*Identity, error) {
+	var claims struct {
+		Subject           string       `json:"sub"`
+		Email             string       `json:"email"`
+		EmailVerified     flexibleBool `json:"email_verified"`
+		Name              string       `json:"name"`
+		PreferredUsername string       `json:"preferred_username"`
+		Picture           string       `json:"picture"`
+	}
+	if err := p.get(ctx, cfg.UserinfoURL, accessToken, &claims); err != nil {
+		return nil, err
+	}
+	return &Identity{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+		Username:      claims.PreferredUsername,
+		AvatarURL:     claims.Picture,
+	}, nil
+}
+
+func (p *Provider) githubIdentity(ctx context.Context, cfg ProviderConfig, accessToken string) (*Identity, error) {
+	var user struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := p.get(ctx, cfg.UserinfoURL, accessToken, &user); err != nil {
+		return nil, err
+	}
+	identity := &Identity{
+		Name:      user.Name,
+		Username:  user.Login,
+		AvatarURL: user.AvatarURL,
+	}
+	if user.ID != 0 {
+		identity.Subject = strconv.FormatInt(user.ID, 10)
+	}
+
+	// The profile email is whatever the user chose to show, verified or
+	// not, so the primary email is looked up instead
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.get(ctx, cfg.EmailsURL, accessToken, &emails); err != nil {
+		return nil, err
+	}
+	for _, email := range emails {
+		if email.Primary {
+			identity.Email = email.Email
+			identity.EmailVerified = email.Verified
+			break
+		}
+	}
+	return identity, nil
+}
+
+// endpoints returns the provider's config, discovering an OIDC provider's
+// endpoints from its issuer the first time they're needed
+func (p *Provider) endpoints(ctx context.Context) (ProviderConfig, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovered || p.cfg.Kind != KindOIDC || (p.cfg.AuthURL != "" && p.cfg.TokenURL != "" && p.cfg.UserinfoURL != "") {
+		return p.cfg, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return p.cfg, err
+	}
+	var doc struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	}
+	if err := p.do(req, &doc); err != nil {
+		return p.cfg, fmt.Errorf("%s discovery: %w", p.cfg.Name, err)
+	}
+	if strings.TrimRight(doc.Issuer, "/") != p.cfg.Issuer {
+		return p.cfg, fmt.Errorf("%s discovery: issuer %q doesn't match %q", p.cfg.Name, doc.Issuer, p.cfg.Issuer)
+	}
+	if p.cfg.AuthURL == "" {
+		p.cfg.AuthURL = doc.AuthorizationEndpoint
+	}
+	if p.cfg.TokenURL == "" {
+		p.cfg.TokenURL = doc.TokenEndpoint
+	}
+	if p.cfg.UserinfoURL == "" {
+		p.cfg.UserinfoURL = doc.UserinfoEndpoint
+	}
+	if p.cfg.AuthURL == "" || p.cfg.TokenURL == "" || p.cfg.UserinfoURL == "" {
+		return p.cfg, fmt.Errorf("%s discovery: provider is missing an authorization, token or userinfo endpoint", p.cfg.Name)
+	}
+	p.discovered = true
+	return p.cfg, nil
+}
+
+func (p *Provider) get(ctx context.Context, endpoint, accessToken string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+	return p.do(req, v)
+}
+
+func (p *Provider) do(req *http.Request, v interface{}) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Token endpoints explain refused codes in the body
+		var failure struct {
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&failure) == nil && failure.Error != "" {
+			return fmt.Errorf("%s returned %d: %s %s", req.URL.Host, resp.StatusCode, failure.Error, failure.ErrorDescription)
+		}
+		return fmt.Errorf("%s returned %d", req.URL.Host, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode response from %s: %w", req.URL.Host, err)
+	}
+	return nil
+}
+
+// flexibleBool accepts the "true" some providers send for email_verified
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "true":
+		*b = true
+	default:
+		*b = false
+	}
+	return nil
+}
+
+// Registry holds the configured providers by name
+type Registry struct {
+	providers map[string]*Provider
+}
+
+// NewRegistry creates a registry of providers
+func NewRegistry(providers ...*Provider) *Registry {
+	r := &Registry{providers: make(map[string]*Provider, len(providers))}
+	for _, p := range providers {
+		r.providers[p.Name()] = p
+	}
+	return r
+}
+
+// Get returns the named provider or ErrUnknownProvider
+func (r *Registry) Get(name string) (*Provider, error) {
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// Len returns how many providers are configured
+func (r *Registry) Len() int {
+	return len(r.providers)
+}
@@ -0,0 +1,181 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOIDC is an OIDC provider that issues "token-for-<code>" and describes
+// the user with claims
+func fakeOIDC(t *testing.T, claims map[string]interface{}) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"userinfo_endpoint":      server.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		if r.PostForm.Get("code") == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		assert.Equal(t, "authorization_code", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "client", r.PostForm.Get("client_id"))
+		assert.Equal(t, "secret", r.PostForm.Get("client_secret"))
+		assert.Equal(t, "verifier", r.PostForm.Get("code_verifier"))
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "token-for-" + r.PostForm.Get("code")})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-for-good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(claims)
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProvider_OIDC(t *testing.T) {
+	server := fakeOIDC(t, map[string]interface{}{
+		"sub":                "abc123",
+		"email":              "alice@example.com",
+		"email_verified":     "true",
+		"name":               "Alice Liddell",
+		"preferred_username": "alice",
+	})
+	p := NewProvider(OIDC("corp", server.URL+"/", "client", "secret"), server.Client())
+	ctx := context.Background()
+
+	authURL, err := p.AuthCodeURL(ctx, "state", Challenge("verifier"), "https://chat.example.com/callback")
+	require.NoError(t, err)
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path, "endpoints are discovered from the issuer")
+	query := parsed.Query()
+	assert.Equal(t, "code", query.Get("response_type"))
+	assert.Equal(t, "openid email profile", query.Get("scope"))
+	assert.Equal(t, "state", query.Get("state"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.Equal(t, Challenge("verifier"), query.Get("code_challenge"))
+
+	token, err := p.Exchange(ctx, "good", "verifier", "https://chat.example.com/callback")
+	require.NoError(t, err)
+	identity, err := p.Identity(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{
+		Provider:      "corp",
+		Subject:       "abc123",
+		Email:         "alice@example.com",
+		EmailVerified: true,
+		Name:          "Alice Liddell",
+		Username:      "alice",
+	}, identity)
+
+	_, err = p.Exchange(ctx, "bad", "verifier", "https://chat.example.com/callback")
+	assert.ErrorContains(t, err, "invalid_grant")
+	_, err = p.Identity(ctx, "stolen")
+	assert.Error(t, err)
+}
+
+func TestProvider_OIDC_IssuerMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 "https://evil.example.com",
+			"authorization_endpoint": "https://evil.example.com/authorize",
+			"token_endpoint":         "https://evil.example.com/token",
+			"userinfo_endpoint":      "https://evil.example.com/userinfo",
+		})
+	}))
+	defer server.Close()
+
+	p := NewProvider(OIDC("corp", server.URL, "client", "secret"), server.Client())
+	_, err := p.AuthCodeURL(context.Background(), "state", "challenge", "https://chat.example.com/callback")
+	assert.ErrorContains(t, err, "doesn't match")
+}
+
+func TestProvider_OIDC_NoSubject(t *testing.T) {
+	server := fakeOIDC(t, map[string]interface{}{"email": "alice@example.com"})
+	p := NewProvider(OIDC("corp", server.URL, "client", "secret"), server.Client())
+
+	_, err := p.Identity(context.Background(), "token-for-good")
+	assert.ErrorIs(t, err, ErrNoSubject)
+}
+
+func TestProvider_GitHub(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Accept"))
+		require.NoError(t, r.ParseForm())
+		if r.PostForm.Get("code") == "expired" {
+			// GitHub refuses codes with a 200
+			json.NewEncoder(w).Encode(map[string]string{"error": "bad_verification_code"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "gho_token"})
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer gho_token", r.Header.Get("Authorization"))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":         583231,
+			"login":      "octocat",
+			"name":       "The Octocat",
+			"avatar_url": "https://avatars.example.com/u/583231",
+			"email":      "public@example.com",
+		})
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"email": "public@example.com", "primary": false, "verified": false},
+			{"email": "octocat@example.com", "primary": true, "verified": true},
+		})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cfg := GitHub("client", "secret")
+	cfg.AuthURL = server.URL + "/login/oauth/authorize"
+	cfg.TokenURL = server.URL + "/login/oauth/access_token"
+	cfg.UserinfoURL = server.URL + "/user"
+	cfg.EmailsURL = server.URL + "/user/emails"
+	p := NewProvider(cfg, server.Client())
+	ctx := context.Background()
+
+	token, err := p.Exchange(ctx, "good", "verifier", "https://chat.example.com/callback")
+	require.NoError(t, err)
+	identity, err := p.Identity(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, "583231", identity.Subject)
+	assert.Equal(t, "octocat", identity.Username)
+	assert.Equal(t, "octocat@example.com", identity.Email, "the primary email is used, not the profile one")
+	assert.True(t, identity.EmailVerified)
+
+	_, err = p.Exchange(ctx, "expired", "verifier", "https://chat.example.com/callback")
+	assert.ErrorContains(t, err, "bad_verification_code")
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry(NewProvider(Google("client", "secret"), nil), NewProvider(GitHub("client", "secret"), nil))
+
+	p, err := registry.Get("github")
+	require.NoError(t, err)
+	assert.Equal(t, "github", p.Name())
+	_, err = registry.Get("discord")
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+	assert.Equal(t, 2, registry.Len())
+}
@@ -0,0 +1,95 @@
+package oauth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidState = errors.New("invalid or expired OAuth state")
+
+// States issues and checks the state a login carries through the provider.
+// States are signed rather than stored, so a callback can land on any
+// instance; the PKCE verifier is derived from the state for the same reason.
+type States struct {
+	key []byte
+	ttl time.Duration
+	now func() time.Time
+}
+
+type statePayload struct {
+	Provider  string `json:"p"`
+	Nonce     string `json:"n"`
+	ExpiresAt int64  `json:"e"`
+}
+
+// NewStates creates states signed with secret that expire after ttl
+func NewStates(secret string, ttl time.Duration) *States {
+	return &States{key: []byte(secret), ttl: ttl, now: time.Now}
+}
+
+// TTL returns how long a login may take
+func (s *States) TTL() time.Duration {
+	return s.ttl
+}
+
+// New returns a fresh state for logging in with the provider
+func (s *States) New(provider string) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(statePayload{
+		Provider:  provider,
+		Nonce:     base64.RawURLEncoding.EncodeToString(nonce),
+		ExpiresAt: s.now().Add(s.ttl).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.sign("state:"+encoded), nil
+}
+
+// Verify returns ErrInvalidState unless state was issued for the provider
+// and hasn't expired
+func (s *States) Verify(state, provider string) error {
+	encoded, sig, ok := strings.Cut(state, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.sign("state:"+encoded))) {
+		return ErrInvalidState
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ErrInvalidState
+	}
+	var payload statePayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return ErrInvalidState
+	}
+	if payload.Provider != provider || s.now().Unix() > payload.ExpiresAt {
+		return ErrInvalidState
+	}
+	return nil
+}
+
+// Verifier returns the PKCE code verifier for the login with this state
+func (s *States) Verifier(state string) string {
+	return s.sign("pkce:" + state)
+}
+
+// Challenge returns the S256 PKCE challenge for a verifier
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (s *States) sign(value string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,40 @@
+package oauth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStates(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	states := NewStates("secret", 10*time.Minute)
+	states.now = func() time.Time { return now }
+
+	state, err := states.New("google")
+	require.NoError(t, err)
+	assert.NoError(t, states.Verify(state, "google"))
+	assert.ErrorIs(t, states.Verify(state, "github"), ErrInvalidState, "states are only good for their provider")
+
+	other, err := states.New("google")
+	require.NoError(t, err)
+	assert.NotEqual(t, state, other)
+	assert.NotEqual(t, states.Verifier(state), states.Verifier(other))
+	assert.Equal(t, states.Verifier(state), NewStates("secret", time.Minute).Verifier(state), "verifiers are derived, not stored")
+
+	assert.ErrorIs(t, NewStates("other-secret", 10*time.Minute).Verify(state, "google"), ErrInvalidState)
+	payload, sig, _ := strings.Cut(state, ".")
+	assert.ErrorIs(t, states.Verify(payload+"x."+sig, "google"), ErrInvalidState)
+	assert.ErrorIs(t, states.Verify("garbage", "google"), ErrInvalidState)
+
+	now = now.Add(11 * time.Minute)
+	assert.ErrorIs(t, states.Verify(state, "google"), ErrInvalidState, "states expire")
+}
+
+func TestChallenge(t *testing.T) {
+	// Unpadded base64url of the verifier's SHA-256
+	assert.Equal(t, "pUEvmUOa3o5rRjL8H5QzHLl3xaLR6S2zXEtDd4R9otM", Challenge("dBjftJeZ4CVP-mB92K9uhbvsd6Cd5D7uXe1sZzpb0Hc"))
+}
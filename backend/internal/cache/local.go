@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is a fixed-size in-process cache that evicts the least recently used
+// entry when full. Entries also expire after the TTL given to NewLRU, which
+// bounds how stale a copy can get if an invalidation broadcast is missed.
+type LRU struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List
+	entries map[string]*list.Element
+	now     func() time.Time
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewLRU creates an LRU holding up to size entries for ttl each
+func NewLRU(size int, ttl time.Duration) *LRU {
+	return &LRU{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element, size),
+		now:     time.Now,
+	}
+}
+
+// Get returns the value stored under key, if it hasn't expired
+func (l *LRU) Get(key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*lruEntry)
+	if !l.now().Before(entry.expires) {
+		l.removeElement(element)
+		return nil, false
+	}
+	l.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// Set stores value under key, evicting the least recently used entry if
+// the cache is full. A ttl shorter than the cache's is kept.
+func (l *LRU) Set(key string, value []byte, ttl time.Duration) {
+	if ttl <= 0 || ttl > l.ttl {
+		ttl = l.ttl
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	expires := l.now().Add(ttl)
+	if element, ok := l.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value, entry.expires = value, expires
+		l.order.MoveToFront(element)
+		return
+	}
+
+	l.entries[key] = l.order.PushFront(&lruEntry{key: key, value: value, expires: expires})
+	for l.order.Len() > l.size {
+		l.removeElement(l.order.Back())
+	}
+}
+
+// Delete drops key
+func (l *LRU) Delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if element, ok := l.entries[key]; ok {
+		l.removeElement(element)
+	}
+}
+
+// Len returns how many entries are held, including expired ones not yet
+// evicted
+func (l *LRU) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
+
+func (l *LRU) removeElement(element *list.Element) {
+	l.order.Remove(element)
+	delete(l.entries, element.Value.(*lruEntry).key)
+}
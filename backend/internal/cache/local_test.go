@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	lru := NewLRU(2, time.Minute)
+
+	lru.Set("a", []byte("1"), 0)
+	lru.Set("b", []byte("2"), 0)
+	_, _ = lru.Get("a")
+	lru.Set("c", []byte("3"), 0)
+
+	_, ok := lru.Get("b")
+	assert.False(t, ok, "b was used least recently")
+	value, ok := lru.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+	assert.Equal(t, 2, lru.Len())
+}
+
+func TestLRU_Expiry(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	lru := NewLRU(10, time.Minute)
+	lru.now = func() time.Time { return now }
+
+	lru.Set("long", []byte("1"), time.Hour)
+	lru.Set("short", []byte("2"), 10*time.Second)
+
+	now = now.Add(30 * time.Second)
+	_, ok := lru.Get("short")
+	assert.False(t, ok, "a shorter ttl is kept")
+	_, ok = lru.Get("long")
+	assert.True(t, ok)
+
+	now = now.Add(time.Minute)
+	_, ok = lru.Get("long")
+	assert.False(t, ok, "no entry outlives the cache's ttl")
+	assert.Zero(t, lru.Len())
+}
+
+func TestLRU_Delete(t *testing.T) {
+	lru := NewLRU(10, time.Minute)
+	lru.Set("a", []byte("1"), 0)
+	lru.Set("a", []byte("2"), 0)
+
+	value, _ := lru.Get("a")
+	assert.Equal(t, []byte("2"), value)
+	lru.Delete("a")
+	_, ok := lru.Get("a")
+	assert.False(t, ok)
+}
@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -158,6 +159,11 @@ func (c *RedisCache) DeleteChannel(ctx context.Context, id uuid.UUID) error {
 	return c.Delete(ctx, "channel:"+id.String())
 }
 
+// SetNX sets key only if it doesn't exist yet and reports whether it did
+func (c *RedisCache) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, c.prefix+key, value, ttl).Result()
+}
+
 // Rate limiting
 
 func (c *RedisCache) IncrementWithExpiry(ctx context.Context, key string, ttl time.Duration) (int64, error) {
@@ -171,6 +177,42 @@ func (c *RedisCache) IncrementWithExpiry(ctx context.Context, key string, ttl ti
 	return incr.Val(), nil
 }
 
+// Read-only mode
+
+const readOnlyModeKey = "instance:read_only"
+
+// GetReadOnlyMode returns the instance's read-only mode, or nil if it's
+// writable
+func (c *RedisCache) GetReadOnlyMode(ctx context.Context) (*models.ReadOnlyMode, error) {
+	data, err := c.Get(ctx, readOnlyModeKey)
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var mode models.ReadOnlyMode
+	if err := json.Unmarshal(data, &mode); err != nil {
+		return nil, err
+	}
+	return &mode, nil
+}
+
+// SetReadOnlyMode makes every instance read-only until it's cleared
+func (c *RedisCache) SetReadOnlyMode(ctx context.Context, mode *models.ReadOnlyMode) error {
+	data, err := json.Marshal(mode)
+	if err != nil {
+		return err
+	}
+	return c.Set(ctx, readOnlyModeKey, data, 0)
+}
+
+// ClearReadOnlyMode makes every instance writable again
+func (c *RedisCache) ClearReadOnlyMode(ctx context.Context) error {
+	return c.Delete(ctx, readOnlyModeKey)
+}
+
 // Presence
 
 func (c *RedisCache) SetPresence(ctx context.Context, userID uuid.UUID, status string, ttl time.Duration) error {
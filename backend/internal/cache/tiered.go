@@ -0,0 +1,228 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"hearth/internal/models"
+)
+
+// InvalidationChannel is the Redis pub/sub channel nodes announce dropped
+// keys on, so each can evict its own local copy
+const InvalidationChannel = "cache:invalidate"
+
+// LocalObjects are the key prefixes kept in the local tier: objects that are
+// read on nearly every request and rarely change
+var LocalObjects = []string{"channel", "roles", "server"}
+
+// remoteStore is the shared tier
+type remoteStore interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	Publish(ctx context.Context, channel string, message interface{}) error
+}
+
+// LocalRecorder counts local tier lookups by the kind of object looked up
+type LocalRecorder interface {
+	LocalCacheLookup(object string, hit bool)
+}
+
+// invalidation is broadcast when a node drops a key
+type invalidation struct {
+	Node string `json:"node"`
+	Key  string `json:"key"`
+}
+
+// TieredCache keeps copies of read-mostly objects in a per-node LRU in
+// front of Redis. Everything else goes straight to Redis. Deletes are
+// broadcast so other nodes evict their copies too.
+type TieredCache struct {
+	remote   remoteStore
+	local    *LRU
+	node     string
+	objects  map[string]bool
+	recorder LocalRecorder
+}
+
+// NewTieredCache puts an LRU of size entries, each kept for up to ttl, in
+// front of remote for the LocalObjects. Call Listen to receive other nodes'
+// invalidations.
+func NewTieredCache(remote *RedisCache, size int, ttl time.Duration) *TieredCache {
+	return newTieredCache(remote, size, ttl)
+}
+
+func newTieredCache(remote remoteStore, size int, ttl time.Duration) *TieredCache {
+	objects := make(map[string]bool, len(LocalObjects))
+	for _, object := range LocalObjects {
+		objects[object] = true
+	}
+	return &TieredCache{
+		remote:  remote,
+		local:   NewLRU(size, ttl),
+		node:    uuid.NewString(),
+		objects: objects,
+	}
+}
+
+// SetRecorder records local tier hits and misses
+func (c *TieredCache) SetRecorder(recorder LocalRecorder) {
+	c.recorder = recorder
+}
+
+// Listen evicts keys other nodes drop until ctx is done
+func (c *TieredCache) Listen(ctx context.Context, sub *redis.PubSub) {
+	defer sub.Close()
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+			c.handleInvalidation(message.Payload)
+		}
+	}
+}
+
+func (c *TieredCache) handleInvalidation(payload string) {
+	var event invalidation
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		log.Printf("[Cache] failed to read invalidation: %v", err)
+		return
+	}
+	if event.Node != c.node {
+		c.local.Delete(event.Key)
+	}
+}
+
+// localObject returns the kind of object key holds, and whether it's kept
+// locally
+func (c *TieredCache) localObject(key string) (string, bool) {
+	object, _, _ := strings.Cut(key, ":")
+	return object, c.objects[object]
+}
+
+// Generic operations
+
+func (c *TieredCache) Get(ctx context.Context, key string) ([]byte, error) {
+	object, local := c.localObject(key)
+	if !local {
+		return c.remote.Get(ctx, key)
+	}
+
+	data, ok := c.local.Get(key)
+	if c.recorder != nil {
+		c.recorder.LocalCacheLookup(object, ok)
+	}
+	if ok {
+		return data, nil
+	}
+	data, err := c.remote.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	c.local.Set(key, data, 0)
+	return data, nil
+}
+
+func (c *TieredCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := c.remote.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	if _, local := c.localObject(key); local {
+		c.local.Set(key, value, ttl)
+	}
+	return nil
+}
+
+// Delete drops key from Redis and every node's local tier
+func (c *TieredCache) Delete(ctx context.Context, key string) error {
+	if _, local := c.localObject(key); local {
+		c.local.Delete(key)
+		defer func() {
+			if err := c.remote.Publish(ctx, InvalidationChannel, invalidation{Node: c.node, Key: key}); err != nil {
+				log.Printf("[Cache] failed to broadcast invalidation of %s: %v", key, err)
+			}
+		}()
+	}
+	return c.remote.Delete(ctx, key)
+}
+
+func (c *TieredCache) getJSON(ctx context.Context, key string, v interface{}) error {
+	data, err := c.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (c *TieredCache) setJSON(ctx context.Context, key string, v interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Set(ctx, key, data, ttl)
+}
+
+// User caching
+
+func (c *TieredCache) GetUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	var user models.User
+	if err := c.getJSON(ctx, "user:"+id.String(), &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (c *TieredCache) SetUser(ctx context.Context, user *models.User, ttl time.Duration) error {
+	return c.setJSON(ctx, "user:"+user.ID.String(), user, ttl)
+}
+
+func (c *TieredCache) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	return c.Delete(ctx, "user:"+id.String())
+}
+
+// Server caching
+
+func (c *TieredCache) GetServer(ctx context.Context, id uuid.UUID) (*models.Server, error) {
+	var server models.Server
+	if err := c.getJSON(ctx, "server:"+id.String(), &server); err != nil {
+		return nil, err
+	}
+	return &server, nil
+}
+
+func (c *TieredCache) SetServer(ctx context.Context, server *models.Server, ttl time.Duration) error {
+	return c.setJSON(ctx, "server:"+server.ID.String(), server, ttl)
+}
+
+func (c *TieredCache) DeleteServer(ctx context.Context, id uuid.UUID) error {
+	return c.Delete(ctx, "server:"+id.String())
+}
+
+// Channel caching
+
+func (c *TieredCache) GetChannel(ctx context.Context, id uuid.UUID) (*models.Channel, error) {
+	var channel models.Channel
+	if err := c.getJSON(ctx, "channel:"+id.String(), &channel); err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+func (c *TieredCache) SetChannel(ctx context.Context, channel *models.Channel, ttl time.Duration) error {
+	return c.setJSON(ctx, "channel:"+channel.ID.String(), channel, ttl)
+}
+
+func (c *TieredCache) DeleteChannel(ctx context.Context, id uuid.UUID) error {
+	return c.Delete(ctx, "channel:"+id.String())
+}
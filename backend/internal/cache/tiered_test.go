@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+// fakeRemote stands in for Redis, counting reads and keeping what's
+// published
+type fakeRemote struct {
+	data      map[string][]byte
+	gets      int
+	published []string
+}
+
+func newFakeRemote() *fakeRemote {
+	return &fakeRemote{data: map[string][]byte{}}
+}
+
+func (f *fakeRemote) Get(ctx context.Context, key string) ([]byte, error) {
+	f.gets++
+	data, ok := f.data[key]
+	if !ok {
+		return nil, redis.Nil
+	}
+	return data, nil
+}
+
+func (f *fakeRemote) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	f.data[key] = value
+	return nil
+}
+
+func (f *fakeRemote) Delete(ctx context.Context, key string) error {
+	delete(f.data, key)
+	return nil
+}
+
+func (f *fakeRemote) Publish(ctx context.Context, channel string, message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	f.published = append(f.published, string(data))
+	return nil
+}
+
+func TestTieredCache_KeepsReadMostlyObjectsLocally(t *testing.T) {
+	remote := newFakeRemote()
+	c := newTieredCache(remote, 100, time.Minute)
+	ctx := context.Background()
+	channel := &models.Channel{ID: uuid.New(), Name: "general"}
+	user := &models.User{ID: uuid.New(), Username: "alice"}
+
+	require.NoError(t, c.SetChannel(ctx, channel, 5*time.Minute))
+	require.NoError(t, c.SetUser(ctx, user, 5*time.Minute))
+
+	got, err := c.GetChannel(ctx, channel.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "general", got.Name)
+	assert.Zero(t, remote.gets, "channels are read from the local tier")
+
+	_, err = c.GetUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, remote.gets, "users always go to Redis")
+
+	_, err = c.GetServer(ctx, uuid.New())
+	assert.ErrorIs(t, err, redis.Nil)
+}
+
+func TestTieredCache_FillsFromRedis(t *testing.T) {
+	remote := newFakeRemote()
+	c := newTieredCache(remote, 100, time.Minute)
+	ctx := context.Background()
+	remote.data["roles:abc"] = []byte("[]")
+
+	for i := 0; i < 3; i++ {
+		data, err := c.Get(ctx, "roles:abc")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("[]"), data)
+	}
+	assert.Equal(t, 1, remote.gets)
+}
+
+func TestTieredCache_BroadcastsDeletes(t *testing.T) {
+	remote := newFakeRemote()
+	here := newTieredCache(remote, 100, time.Minute)
+	there := newTieredCache(remote, 100, time.Minute)
+	ctx := context.Background()
+	channel := &models.Channel{ID: uuid.New()}
+	require.NoError(t, there.SetChannel(ctx, channel, time.Minute))
+	_, ok := there.local.Get("channel:" + channel.ID.String())
+	require.True(t, ok)
+
+	require.NoError(t, here.DeleteChannel(ctx, channel.ID))
+	require.Len(t, remote.published, 1)
+
+	// The node that dropped the key ignores its own broadcast
+	here.local.Set("channel:"+channel.ID.String(), []byte("{}"), 0)
+	here.handleInvalidation(remote.published[0])
+	_, ok = here.local.Get("channel:" + channel.ID.String())
+	assert.True(t, ok)
+
+	there.handleInvalidation(remote.published[0])
+	_, ok = there.local.Get("channel:" + channel.ID.String())
+	assert.False(t, ok)
+
+	require.NoError(t, here.DeleteUser(ctx, uuid.New()))
+	assert.Len(t, remote.published, 1, "keys only kept in Redis aren't broadcast")
+}
@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"hearth/internal/models"
+)
+
+// Typing indicators are kept in a sorted set per channel of user IDs scored
+// by when they expire (unix ms), with a sorted set of channels scored by
+// their latest expiry so lapsed indicators can be found
+
+const (
+	typingChannelsKey = "typing:channels"
+	typingSweepKey    = "typing:sweep"
+
+	// typingSweepLock is how long one instance holds the sweep, so each
+	// lapsed indicator is stopped by exactly one instance
+	typingSweepLock = time.Second
+
+	// typingKeyGrace keeps a channel's key a while past its last expiry,
+	// so the sweep still finds what lapsed
+	typingKeyGrace = time.Minute
+)
+
+func (c *RedisCache) typingKey(channelID uuid.UUID) string {
+	return c.prefix + "typing:" + channelID.String()
+}
+
+// StartTyping records that a user is typing in a channel until expires
+func (c *RedisCache) StartTyping(ctx context.Context, channelID, userID uuid.UUID, expires time.Time) error {
+	key := c.typingKey(channelID)
+	score := float64(expires.UnixMilli())
+
+	pipe := c.client.Pipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: score, Member: userID.String()})
+	pipe.Expire(ctx, key, time.Until(expires)+typingKeyGrace)
+	pipe.ZAddGT(ctx, c.prefix+typingChannelsKey, redis.Z{Score: score, Member: channelID.String()})
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// StopTyping removes an indicator and reports whether there was one
+func (c *RedisCache) StopTyping(ctx context.Context, channelID, userID uuid.UUID) (bool, error) {
+	removed, err := c.client.ZRem(ctx, c.typingKey(channelID), userID.String()).Result()
+	return removed > 0, err
+}
+
+// TypingUsers returns when each user typing in a channel expires, leaving
+// out those that expired by now
+func (c *RedisCache) TypingUsers(ctx context.Context, channelID uuid.UUID, now time.Time) (map[uuid.UUID]time.Time, error) {
+	entries, err := c.client.ZRangeByScoreWithScores(ctx, c.typingKey(channelID), &redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(now.UnixMilli(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	typing := make(map[uuid.UUID]time.Time, len(entries))
+	for _, entry := range entries {
+		member, _ := entry.Member.(string)
+		if userID, err := uuid.Parse(member); err == nil {
+			typing[userID] = time.UnixMilli(int64(entry.Score))
+		}
+	}
+	return typing, nil
+}
+
+// ExpireTyping removes the indicators that expired by now and returns
+// them. Only the instance holding the sweep lock looks, and an indicator
+// started again while it does is kept.
+func (c *RedisCache) ExpireTyping(ctx context.Context, now time.Time) ([]models.TypingIndicator, error) {
+	locked, err := c.client.SetNX(ctx, c.prefix+typingSweepKey, "1", typingSweepLock).Result()
+	if err != nil || !locked {
+		return nil, err
+	}
+
+	cutoff := strconv.FormatInt(now.UnixMilli(), 10)
+	channels, err := c.client.ZRange(ctx, c.prefix+typingChannelsKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []models.TypingIndicator
+	for _, member := range channels {
+		channelID, err := uuid.Parse(member)
+		if err != nil {
+			continue
+		}
+		key := c.typingKey(channelID)
+
+		due, err := c.client.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{Min: "-inf", Max: cutoff}).Result()
+		if err != nil {
+			return expired, err
+		}
+		if len(due) == 0 {
+			continue
+		}
+		users := make([]string, len(due))
+		for i, entry := range due {
+			users[i], _ = entry.Member.(string)
+		}
+
+		// Whatever still has a score after the removal was started again
+		// between the two reads
+		var scores *redis.FloatSliceCmd
+		if _, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.ZRemRangeByScore(ctx, key, "-inf", cutoff)
+			scores = pipe.ZMScore(ctx, key, users...)
+			return nil
+		}); err != nil {
+			return expired, err
+		}
+
+		for i, entry := range due {
+			userID, err := uuid.Parse(users[i])
+			if err != nil || scores.Val()[i] != 0 {
+				continue
+			}
+			expired = append(expired, models.TypingIndicator{
+				ChannelID: channelID,
+				UserID:    userID,
+				Timestamp: time.UnixMilli(int64(entry.Score)),
+			})
+		}
+	}
+
+	// Channels whose last indicator lapsed have nothing left to sweep
+	err = c.client.ZRemRangeByScore(ctx, c.prefix+typingChannelsKey, "-inf", cutoff).Err()
+	return expired, err
+}
+
+// ClearTyping removes all of a channel's indicators
+func (c *RedisCache) ClearTyping(ctx context.Context, channelID uuid.UUID) error {
+	pipe := c.client.Pipeline()
+	pipe.Del(ctx, c.typingKey(channelID))
+	pipe.ZRem(ctx, c.prefix+typingChannelsKey, channelID.String())
+	_, err := pipe.Exec(ctx)
+	return err
+}
@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedisCache(t *testing.T) *RedisCache {
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		url = "redis://localhost:6379/1" // Use DB 1 for tests
+	}
+	c, err := NewRedisCache(url)
+	if err != nil {
+		t.Skip("Redis not available, skipping integration test")
+	}
+	// Keys are namespaced per test so sweeps don't see each other's
+	c.prefix = "test:" + uuid.NewString() + ":"
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestRedisTyping(t *testing.T) {
+	c := newTestRedisCache(t)
+	ctx := context.Background()
+	channelID := uuid.New()
+	lapsing, restarted, stopped := uuid.New(), uuid.New(), uuid.New()
+	now := time.Now()
+
+	require.NoError(t, c.StartTyping(ctx, channelID, lapsing, now.Add(time.Second)))
+	require.NoError(t, c.StartTyping(ctx, channelID, restarted, now.Add(time.Second)))
+	require.NoError(t, c.StartTyping(ctx, channelID, stopped, now.Add(10*time.Second)))
+	defer c.ClearTyping(ctx, channelID)
+
+	typing, err := c.TypingUsers(ctx, channelID, now)
+	require.NoError(t, err)
+	assert.Len(t, typing, 3)
+
+	ok, err := c.StopTyping(ctx, channelID, stopped)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = c.StopTyping(ctx, channelID, stopped)
+	require.NoError(t, err)
+	assert.False(t, ok, "already stopped")
+
+	// One user starts typing again before the sweep; the other lapses
+	later := now.Add(2 * time.Second)
+	require.NoError(t, c.StartTyping(ctx, channelID, restarted, later.Add(10*time.Second)))
+	expired, err := c.ExpireTyping(ctx, later)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, lapsing, expired[0].UserID)
+	assert.Equal(t, channelID, expired[0].ChannelID)
+
+	typing, err = c.TypingUsers(ctx, channelID, later)
+	require.NoError(t, err)
+	assert.Contains(t, typing, restarted)
+	assert.Len(t, typing, 1)
+
+	// Another sweep in the same second is left to the instance holding
+	// the lock, which has nothing more to stop
+	expired, err = c.ExpireTyping(ctx, later.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, expired)
+}
@@ -0,0 +1,70 @@
+package captcha
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fakeSiteverify(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.PostForm.Get("secret") != "secret":
+			w.Write([]byte(`{"success":false,"error-codes":["invalid-input-secret"]}`))
+		case r.PostForm.Get("response") == "good" && r.PostForm.Get("remoteip") == "203.0.113.7":
+			w.Write([]byte(`{"success":true}`))
+		case r.PostForm.Get("response") == "broken":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.Write([]byte(`{"success":false,"error-codes":["invalid-input-response"]}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	ctx := context.Background()
+	srv := fakeSiteverify(t)
+	v, err := New(Config{Provider: "turnstile", Secret: "secret", SiteKey: "site", VerifyURL: srv.URL})
+	require.NoError(t, err)
+	assert.Equal(t, "turnstile", v.Provider())
+	assert.Equal(t, "site", v.SiteKey())
+
+	ok, err := v.Verify(ctx, "good", "203.0.113.7")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = v.Verify(ctx, "bad", "203.0.113.7")
+	require.NoError(t, err)
+	assert.False(t, ok, "a wrong token is rejected, not an error")
+
+	_, err = v.Verify(ctx, "broken", "203.0.113.7")
+	assert.Error(t, err)
+
+	misconfigured, err := New(Config{Provider: "hcaptcha", Secret: "wrong", VerifyURL: srv.URL})
+	require.NoError(t, err)
+	_, err = misconfigured.Verify(ctx, "good", "203.0.113.7")
+	assert.Error(t, err, "a bad secret isn't the user's fault")
+}
+
+func TestNew(t *testing.T) {
+	v, err := New(Config{Provider: "hcaptcha", Secret: "s"})
+	require.NoError(t, err)
+	assert.Equal(t, hcaptchaVerifyURL, v.verifyURL)
+
+	v, err = New(Config{Provider: "turnstile", Secret: "s"})
+	require.NoError(t, err)
+	assert.Equal(t, turnstileVerifyURL, v.verifyURL)
+
+	_, err = New(Config{Provider: "recaptcha", Secret: "s"})
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+	_, err = New(Config{Provider: "hcaptcha"})
+	assert.Error(t, err)
+}
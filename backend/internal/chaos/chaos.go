@@ -0,0 +1,106 @@
+// Package chaos injects latency and failures into calls to Postgres and
+// Redis, so retries, timeouts and degraded modes can be exercised in CI and
+// staging. It is off unless CHAOS_ENABLED is set and must never be enabled
+// in production.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// ErrInjected is returned by calls the injector decided to fail
+var ErrInjected = errors.New("chaos: injected failure")
+
+// Faults describes what to inject into calls to one dependency
+type Faults struct {
+	ErrorRate   float64       // Fraction of calls that fail, 0 to 1
+	LatencyRate float64       // Fraction of calls that are delayed, 0 to 1
+	Latency     time.Duration // Delay added to delayed calls
+}
+
+// Active reports whether the faults would change anything
+func (f Faults) Active() bool {
+	return f.ErrorRate > 0 || (f.LatencyRate > 0 && f.Latency > 0)
+}
+
+// Validate checks that rates are fractions and latency isn't negative
+func (f Faults) Validate() error {
+	if f.ErrorRate < 0 || f.ErrorRate > 1 {
+		return fmt.Errorf("chaos: error rate %v must be between 0 and 1", f.ErrorRate)
+	}
+	if f.LatencyRate < 0 || f.LatencyRate > 1 {
+		return fmt.Errorf("chaos: latency rate %v must be between 0 and 1", f.LatencyRate)
+	}
+	if f.Latency < 0 {
+		return fmt.Errorf("chaos: latency %v must not be negative", f.Latency)
+	}
+	return nil
+}
+
+func (f Faults) String() string {
+	return fmt.Sprintf("%.0f%% errors, %.0f%% delayed by %v", f.ErrorRate*100, f.LatencyRate*100, f.Latency)
+}
+
+// Injector decides, call by call, whether to delay or fail a dependency
+// call. It starts disarmed so the server can connect, migrate and warm up
+// before faults begin.
+type Injector struct {
+	name   string
+	faults Faults
+	armed  atomic.Bool
+	random func() float64
+
+	delayed atomic.Int64
+	failed  atomic.Int64
+}
+
+// NewInjector creates a disarmed injector. name labels its errors, e.g.
+// "postgres".
+func NewInjector(name string, faults Faults) *Injector {
+	return &Injector{name: name, faults: faults, random: rand.Float64}
+}
+
+// Arm starts injecting faults
+func (i *Injector) Arm() {
+	i.armed.Store(true)
+}
+
+// Disarm stops injecting faults
+func (i *Injector) Disarm() {
+	i.armed.Store(false)
+}
+
+// Stats returns how many calls have been delayed and failed
+func (i *Injector) Stats() (delayed, failed int64) {
+	return i.delayed.Load(), i.failed.Load()
+}
+
+// Inject delays and fails the call about to be made, as configured. The
+// delay ends early when ctx is done, returning ctx's error.
+func (i *Injector) Inject(ctx context.Context) error {
+	if i == nil || !i.armed.Load() {
+		return nil
+	}
+
+	if i.faults.Latency > 0 && i.random() < i.faults.LatencyRate {
+		i.delayed.Add(1)
+		timer := time.NewTimer(i.faults.Latency)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+
+	if i.random() < i.faults.ErrorRate {
+		i.failed.Add(1)
+		return fmt.Errorf("%w (%s)", ErrInjected, i.name)
+	}
+	return nil
+}
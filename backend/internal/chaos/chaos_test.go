@@ -0,0 +1,180 @@
+package chaos
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConnector hands out connections that answer every query with no rows
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+func (fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return fakeRows{}, nil
+}
+
+func (fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string              { return []string{"id"} }
+func (fakeRows) Close() error                   { return nil }
+func (fakeRows) Next(dest []driver.Value) error { return io.EOF }
+
+// newTestInjector returns an armed injector whose rolls come from rolls, in
+// order
+func newTestInjector(faults Faults, rolls ...float64) *Injector {
+	injector := NewInjector("test", faults)
+	injector.random = func() float64 {
+		roll := rolls[0]
+		rolls = rolls[1:]
+		return roll
+	}
+	injector.Arm()
+	return injector
+}
+
+func TestInjector_Disarmed(t *testing.T) {
+	injector := NewInjector("test", Faults{ErrorRate: 1, LatencyRate: 1, Latency: time.Hour})
+
+	assert.NoError(t, injector.Inject(context.Background()))
+
+	var nilInjector *Injector
+	assert.NoError(t, nilInjector.Inject(context.Background()))
+}
+
+func TestInjector_Rates(t *testing.T) {
+	injector := newTestInjector(Faults{ErrorRate: 0.5, LatencyRate: 0.25, Latency: time.Millisecond},
+		0.9, 0.1, // not delayed, failed
+		0.1, 0.9, // delayed, not failed
+	)
+
+	err := injector.Inject(context.Background())
+	assert.ErrorIs(t, err, ErrInjected)
+	assert.Contains(t, err.Error(), "test")
+	assert.NoError(t, injector.Inject(context.Background()))
+
+	delayed, failed := injector.Stats()
+	assert.Equal(t, int64(1), delayed)
+	assert.Equal(t, int64(1), failed)
+}
+
+func TestInjector_LatencyHonoursContext(t *testing.T) {
+	injector := newTestInjector(Faults{LatencyRate: 1, Latency: time.Hour}, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := injector.Inject(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestInjector_Disarm(t *testing.T) {
+	injector := newTestInjector(Faults{ErrorRate: 1}, 0)
+	assert.ErrorIs(t, injector.Inject(context.Background()), ErrInjected)
+
+	injector.Disarm()
+	assert.NoError(t, injector.Inject(context.Background()))
+}
+
+func TestFaults_Validate(t *testing.T) {
+	tests := []struct {
+		faults Faults
+		valid  bool
+	}{
+		{Faults{}, true},
+		{Faults{ErrorRate: 1, LatencyRate: 0.5, Latency: time.Second}, true},
+		{Faults{ErrorRate: 1.5}, false},
+		{Faults{ErrorRate: -0.1}, false},
+		{Faults{LatencyRate: 2}, false},
+		{Faults{Latency: -time.Second}, false},
+	}
+	for _, tt := range tests {
+		err := tt.faults.Validate()
+		assert.Equal(t, tt.valid, err == nil, "%+v: %v", tt.faults, err)
+	}
+
+	assert.False(t, Faults{LatencyRate: 1}.Active(), "no latency to add")
+	assert.True(t, Faults{ErrorRate: 0.01}.Active())
+}
+
+func TestWrapConnector(t *testing.T) {
+	injector := NewInjector("postgres", Faults{ErrorRate: 1})
+	db := sql.OpenDB(WrapConnector(fakeConnector{}, injector))
+	defer db.Close()
+	ctx := context.Background()
+
+	// Disarmed, calls go straight through
+	require.NoError(t, db.PingContext(ctx))
+	rows, err := db.QueryContext(ctx, "SELECT id FROM users")
+	require.NoError(t, err)
+	rows.Close()
+
+	injector.Arm()
+
+	_, err = db.QueryContext(ctx, "SELECT id FROM users")
+	assert.ErrorIs(t, err, ErrInjected)
+	_, err = db.ExecContext(ctx, "DELETE FROM users")
+	assert.ErrorIs(t, err, ErrInjected)
+	_, err = db.BeginTx(ctx, nil)
+	assert.ErrorIs(t, err, ErrInjected)
+}
+
+func TestRedisHook(t *testing.T) {
+	injector := NewInjector("redis", Faults{ErrorRate: 1})
+	// Nothing listens here, so the command only succeeds in getting as far
+	// as dialling when the hook lets it through
+	client := redis.NewClient(&redis.Options{
+		Addr:        "127.0.0.1:1",
+		DialTimeout: 100 * time.Millisecond,
+		MaxRetries:  -1,
+	})
+	defer client.Close()
+	client.AddHook(RedisHook(injector))
+	ctx := context.Background()
+
+	err := client.Get(ctx, "key").Err()
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInjected)
+
+	injector.Arm()
+
+	cmd := client.Get(ctx, "key")
+	assert.ErrorIs(t, cmd.Err(), ErrInjected)
+
+	cmds, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, "a")
+		pipe.Incr(ctx, "b")
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrInjected)
+	for _, cmd := range cmds {
+		assert.ErrorIs(t, cmd.Err(), ErrInjected)
+	}
+}
@@ -0,0 +1,107 @@
+package chaos
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+)
+
+// WrapConnector returns a connector whose connections run every query,
+// exec, prepare and transaction start past injector first. Use it with
+// sql.OpenDB.
+func WrapConnector(connector driver.Connector, injector *Injector) driver.Connector {
+	return &faultyConnector{Connector: connector, injector: injector}
+}
+
+type faultyConnector struct {
+	driver.Connector
+	injector *Injector
+}
+
+func (c *faultyConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if err := c.injector.Inject(ctx); err != nil {
+		return nil, err
+	}
+	cn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &faultyConn{Conn: cn, injector: c.injector}, nil
+}
+
+// faultyConn injects faults before handing calls to the real connection.
+// Injected errors aren't driver.ErrBadConn, so database/sql doesn't retry
+// them on another connection.
+type faultyConn struct {
+	driver.Conn
+	injector *Injector
+}
+
+func (c *faultyConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := c.injector.Inject(ctx); err != nil {
+		return nil, err
+	}
+	return queryer.QueryContext(ctx, query, args)
+}
+
+func (c *faultyConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := c.injector.Inject(ctx); err != nil {
+		return nil, err
+	}
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (c *faultyConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := c.injector.Inject(ctx); err != nil {
+		return nil, err
+	}
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *faultyConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.injector.Inject(ctx); err != nil {
+		return nil, err
+	}
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != 0 || opts.ReadOnly {
+		return nil, errors.New("chaos: driver does not support transaction options")
+	}
+	return c.Conn.Begin()
+}
+
+func (c *faultyConn) Ping(ctx context.Context) error {
+	if err := c.injector.Inject(ctx); err != nil {
+		return err
+	}
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *faultyConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *faultyConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
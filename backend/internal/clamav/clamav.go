@@ -0,0 +1,133 @@
+// Package clamav scans files with a clamd daemon over its INSTREAM
+// protocol.
+package clamav
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// chunkSize is how much of a file is sent per INSTREAM chunk. clamd
+	// rejects chunks above its StreamMaxLength, which defaults to 25MB.
+	chunkSize      = 64 * 1024
+	defaultTimeout = 2 * time.Minute
+)
+
+var ErrScanFailed = errors.New("clamd could not scan the file")
+
+// Config configures a client
+type Config struct {
+	// Address is clamd's host:port, or the path of its unix socket
+	Address string
+	// Timeout bounds one scan, including the upload to clamd
+	Timeout time.Duration
+}
+
+// Client scans files with clamd. It opens a connection per scan, so it is
+// safe for concurrent use.
+type Client struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// New creates a client for the clamd at cfg.Address
+func New(cfg Config) (*Client, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("clamd address is required")
+	}
+	network := "tcp"
+	if strings.HasPrefix(cfg.Address, "/") {
+		network = "unix"
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Client{network: network, address: cfg.Address, timeout: timeout}, nil
+}
+
+// Scan streams content to clamd and reports whether it found malware.
+// filename is only used in errors; clamd sees the bytes alone.
+func (c *Client) Scan(ctx context.Context, filename string, content io.Reader) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return false, fmt.Errorf("connect to clamd: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if err := stream(conn, content); err != nil {
+		return false, fmt.Errorf("send %s to clamd: %w", filename, err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("read clamd reply for %s: %w", filename, err)
+	}
+	return parseReply(filename, strings.TrimRight(reply, "\x00\n"))
+}
+
+// stream sends content as an INSTREAM command: length-prefixed chunks
+// ended by an empty one
+func stream(conn net.Conn, content io.Reader) error {
+	w := bufio.NewWriterSize(conn, chunkSize+4)
+	if _, err := w.WriteString("zINSTREAM\x00"); err != nil {
+		return err
+	}
+
+	buf := make([]byte, chunkSize)
+	var size [4]byte
+	for {
+		n, err := content.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size[:], uint32(n))
+			if _, werr := w.Write(size[:]); werr != nil {
+				return werr
+			}
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	binary.BigEndian.PutUint32(size[:], 0)
+	if _, err := w.Write(size[:]); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// parseReply reads clamd's verdict, "stream: OK" or
+// "stream: <signature> FOUND". Anything else, such as a file over clamd's
+// size limit, is an error rather than a clean result.
+func parseReply(filename, reply string) (bool, error) {
+	switch {
+	case strings.HasSuffix(reply, " OK"):
+		return false, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return true, nil
+	default:
+		return false, fmt.Errorf("%w %s: %q", ErrScanFailed, filename, reply)
+	}
+}
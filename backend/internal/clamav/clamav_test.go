@@ -0,0 +1,103 @@
+package clamav
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// fakeClamd accepts INSTREAM scans on loopback and flags the EICAR test
+// string. It returns the daemon's address.
+func fakeClamd(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveScan(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func serveScan(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	if cmd, err := r.ReadString(0); err != nil || cmd != "zINSTREAM\x00" {
+		conn.Write([]byte("UNKNOWN COMMAND\x00"))
+		return
+	}
+
+	var body bytes.Buffer
+	for {
+		var size uint32
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return
+		}
+		if size == 0 {
+			break
+		}
+		if _, err := io.CopyN(&body, r, int64(size)); err != nil {
+			return
+		}
+	}
+
+	if strings.Contains(body.String(), "EICAR-STANDARD-ANTIVIRUS-TEST-FILE") {
+		conn.Write([]byte("stream: Eicar-Signature FOUND\x00"))
+		return
+	}
+	conn.Write([]byte("stream: OK\x00"))
+}
+
+func TestClient_Scan(t *testing.T) {
+	client, err := New(Config{Address: fakeClamd(t)})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	flagged, err := client.Scan(ctx, "notes.txt", strings.NewReader("just some notes"))
+	require.NoError(t, err)
+	assert.False(t, flagged)
+
+	flagged, err = client.Scan(ctx, "eicar.com", strings.NewReader(eicar))
+	require.NoError(t, err)
+	assert.True(t, flagged)
+
+	// Larger than one chunk
+	big := strings.Repeat("a", 3*chunkSize) + eicar
+	flagged, err = client.Scan(ctx, "big.bin", strings.NewReader(big))
+	require.NoError(t, err)
+	assert.True(t, flagged)
+}
+
+func TestParseReply_Errors(t *testing.T) {
+	_, err := parseReply("huge.iso", "INSTREAM size limit exceeded. ERROR")
+	assert.ErrorIs(t, err, ErrScanFailed)
+
+	_, err = parseReply("empty", "")
+	assert.ErrorIs(t, err, ErrScanFailed)
+}
+
+func TestNew_RequiresAddress(t *testing.T) {
+	_, err := New(Config{})
+	assert.Error(t, err)
+
+	client, err := New(Config{Address: "/run/clamav/clamd.ctl"})
+	require.NoError(t, err)
+	assert.Equal(t, "unix", client.network)
+}
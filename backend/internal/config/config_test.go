@@ -315,6 +315,101 @@ func TestRateLimitConfig_CustomValues(t *testing.T) {
 	}
 }
 
+func TestRequestTimeoutConfig(t *testing.T) {
+	os.Unsetenv("READ_REQUEST_TIMEOUT")
+	os.Unsetenv("WRITE_REQUEST_TIMEOUT")
+
+	cfg := Load()
+
+	if cfg.ReadRequestTimeout != 2*time.Second {
+		t.Errorf("expected default ReadRequestTimeout 2s, got %v", cfg.ReadRequestTimeout)
+	}
+	if cfg.WriteRequestTimeout != 5*time.Second {
+		t.Errorf("expected default WriteRequestTimeout 5s, got %v", cfg.WriteRequestTimeout)
+	}
+
+	os.Setenv("READ_REQUEST_TIMEOUT", "500ms")
+	os.Setenv("WRITE_REQUEST_TIMEOUT", "10s")
+	defer func() {
+		os.Unsetenv("READ_REQUEST_TIMEOUT")
+		os.Unsetenv("WRITE_REQUEST_TIMEOUT")
+	}()
+
+	cfg = Load()
+
+	if cfg.ReadRequestTimeout != 500*time.Millisecond {
+		t.Errorf("expected ReadRequestTimeout 500ms, got %v", cfg.ReadRequestTimeout)
+	}
+	if cfg.WriteRequestTimeout != 10*time.Second {
+		t.Errorf("expected WriteRequestTimeout 10s, got %v", cfg.WriteRequestTimeout)
+	}
+}
+
+func TestDBPoolConfig(t *testing.T) {
+	os.Unsetenv("DB_MAX_OPEN_CONNS")
+	os.Unsetenv("DB_MAX_IDLE_CONNS")
+	os.Unsetenv("DB_CONN_MAX_LIFETIME")
+	os.Unsetenv("DB_CONN_MAX_IDLE_TIME")
+
+	cfg := Load()
+
+	if cfg.DBMaxOpenConns != 25 {
+		t.Errorf("expected default DBMaxOpenConns 25, got %d", cfg.DBMaxOpenConns)
+	}
+	if cfg.DBMaxIdleConns != 5 {
+		t.Errorf("expected default DBMaxIdleConns 5, got %d", cfg.DBMaxIdleConns)
+	}
+	if cfg.DBConnMaxLifetime != 5*time.Minute {
+		t.Errorf("expected default DBConnMaxLifetime 5m, got %v", cfg.DBConnMaxLifetime)
+	}
+	if cfg.DBConnMaxIdleTime != 0 {
+		t.Errorf("expected default DBConnMaxIdleTime 0, got %v", cfg.DBConnMaxIdleTime)
+	}
+
+	os.Setenv("DB_MAX_OPEN_CONNS", "50")
+	os.Setenv("DB_MAX_IDLE_CONNS", "10")
+	os.Setenv("DB_CONN_MAX_LIFETIME", "30m")
+	os.Setenv("DB_CONN_MAX_IDLE_TIME", "1m")
+	defer func() {
+		os.Unsetenv("DB_MAX_OPEN_CONNS")
+		os.Unsetenv("DB_MAX_IDLE_CONNS")
+		os.Unsetenv("DB_CONN_MAX_LIFETIME")
+		os.Unsetenv("DB_CONN_MAX_IDLE_TIME")
+	}()
+
+	cfg = Load()
+
+	if cfg.DBMaxOpenConns != 50 {
+		t.Errorf("expected DBMaxOpenConns 50, got %d", cfg.DBMaxOpenConns)
+	}
+	if cfg.DBMaxIdleConns != 10 {
+		t.Errorf("expected DBMaxIdleConns 10, got %d", cfg.DBMaxIdleConns)
+	}
+	if cfg.DBConnMaxLifetime != 30*time.Minute {
+		t.Errorf("expected DBConnMaxLifetime 30m, got %v", cfg.DBConnMaxLifetime)
+	}
+	if cfg.DBConnMaxIdleTime != time.Minute {
+		t.Errorf("expected DBConnMaxIdleTime 1m, got %v", cfg.DBConnMaxIdleTime)
+	}
+}
+
+func TestSlowQueryThresholdConfig(t *testing.T) {
+	os.Unsetenv("SLOW_QUERY_THRESHOLD")
+
+	cfg := Load()
+	if cfg.SlowQueryThreshold != 200*time.Millisecond {
+		t.Errorf("expected default SlowQueryThreshold 200ms, got %v", cfg.SlowQueryThreshold)
+	}
+
+	os.Setenv("SLOW_QUERY_THRESHOLD", "0s")
+	defer os.Unsetenv("SLOW_QUERY_THRESHOLD")
+
+	cfg = Load()
+	if cfg.SlowQueryThreshold != 0 {
+		t.Errorf("expected SlowQueryThreshold 0 to disable the log, got %v", cfg.SlowQueryThreshold)
+	}
+}
+
 func TestBcryptPoolConfig_Defaults(t *testing.T) {
 	// Clear any existing env vars
 	os.Unsetenv("BCRYPT_POOL_WORKERS")
@@ -390,3 +485,159 @@ func TestDrainConfig_CustomValues(t *testing.T) {
 		t.Errorf("expected DrainGracePeriod 10s, got %v", cfg.DrainGracePeriod)
 	}
 }
+
+func TestDrainConfig_ReconnectURL(t *testing.T) {
+	os.Unsetenv("DRAIN_RECONNECT_URL")
+	os.Setenv("PUBLIC_URL", "https://chat.example.com/")
+	defer os.Unsetenv("PUBLIC_URL")
+
+	cfg := Load()
+
+	if cfg.DrainReconnectURL != "wss://chat.example.com/gateway" {
+		t.Errorf("expected DrainReconnectURL derived from PUBLIC_URL, got %q", cfg.DrainReconnectURL)
+	}
+
+	os.Setenv("DRAIN_RECONNECT_URL", "wss://gw.example.com/gateway")
+	defer os.Unsetenv("DRAIN_RECONNECT_URL")
+
+	cfg = Load()
+	if cfg.DrainReconnectURL != "wss://gw.example.com/gateway" {
+		t.Errorf("expected DrainReconnectURL override, got %q", cfg.DrainReconnectURL)
+	}
+}
+
+func TestChaosConfig(t *testing.T) {
+	cfg := Load()
+	if cfg.ChaosEnabled || cfg.ChaosPostgres.Active() || cfg.ChaosRedis.Active() {
+		t.Errorf("expected fault injection off by default, got %+v", cfg)
+	}
+
+	t.Setenv("CHAOS_ENABLED", "true")
+	t.Setenv("CHAOS_POSTGRES_ERROR_RATE", "0.05")
+	t.Setenv("CHAOS_POSTGRES_LATENCY_RATE", "0.5")
+	t.Setenv("CHAOS_POSTGRES_LATENCY", "250ms")
+	t.Setenv("CHAOS_REDIS_ERROR_RATE", "not-a-number")
+
+	cfg = Load()
+	if !cfg.ChaosEnabled {
+		t.Error("expected ChaosEnabled true")
+	}
+	if cfg.ChaosPostgres.ErrorRate != 0.05 || cfg.ChaosPostgres.LatencyRate != 0.5 || cfg.ChaosPostgres.Latency != 250*time.Millisecond {
+		t.Errorf("unexpected postgres faults %+v", cfg.ChaosPostgres)
+	}
+	if cfg.ChaosRedis.ErrorRate != 0 {
+		t.Errorf("expected an unparsable rate to fall back to 0, got %v", cfg.ChaosRedis.ErrorRate)
+	}
+}
+
+func TestMetricsConfig(t *testing.T) {
+	cfg := Load()
+	if cfg.MetricsToken != "" || cfg.MetricsAllowedCIDRs != "" || cfg.MetricsAddr != "" {
+		t.Errorf("expected /metrics unrestricted on the API port by default, got %+v", cfg)
+	}
+	if cfg.MetricsDetailedLabels {
+		t.Error("expected MetricsDetailedLabels false by default")
+	}
+	if cfg.MetricsTopServers != 10 {
+		t.Errorf("expected MetricsTopServers 10 by default, got %d", cfg.MetricsTopServers)
+	}
+
+	t.Setenv("METRICS_TOKEN", "s3cret")
+	t.Setenv("METRICS_ALLOWED_CIDRS", "10.0.0.0/8")
+	t.Setenv("METRICS_ADDR", ":9090")
+	t.Setenv("METRICS_CLIENT_CA", "/etc/hearth/ca.pem")
+	t.Setenv("METRICS_DETAILED_LABELS", "true")
+	t.Setenv("METRICS_TOP_SERVERS", "0")
+
+	cfg = Load()
+	if cfg.MetricsToken != "s3cret" || cfg.MetricsAllowedCIDRs != "10.0.0.0/8" || cfg.MetricsAddr != ":9090" {
+		t.Errorf("unexpected metrics config %+v", cfg)
+	}
+	if cfg.MetricsClientCA != "/etc/hearth/ca.pem" || !cfg.MetricsDetailedLabels || cfg.MetricsTopServers != 0 {
+		t.Errorf("unexpected metrics config %+v", cfg)
+	}
+}
+
+func TestComplianceLogConfig(t *testing.T) {
+	cfg := Load()
+	if cfg.ComplianceLogEnabled {
+		t.Error("expected compliance logging off by default")
+	}
+	if cfg.ComplianceLogRetention != 365*24*time.Hour {
+		t.Errorf("expected a year's retention by default, got %v", cfg.ComplianceLogRetention)
+	}
+
+	t.Setenv("COMPLIANCE_LOG_ENABLED", "true")
+	t.Setenv("COMPLIANCE_LOG_RETENTION", "0")
+
+	cfg = Load()
+	if !cfg.ComplianceLogEnabled || cfg.ComplianceLogRetention != 0 {
+		t.Errorf("unexpected compliance log config %+v", cfg)
+	}
+}
+
+func TestPreflightConfig(t *testing.T) {
+	cfg := Load()
+	if !cfg.PreflightEnforce || cfg.RedisRequired {
+		t.Errorf("expected preflight enforced and Redis optional by default, got %+v", cfg)
+	}
+	if cfg.MaxClockSkew != 30*time.Second {
+		t.Errorf("expected 30s max clock skew by default, got %v", cfg.MaxClockSkew)
+	}
+
+	t.Setenv("PREFLIGHT_ENFORCE", "false")
+	t.Setenv("REDIS_REQUIRED", "true")
+	t.Setenv("MAX_CLOCK_SKEW", "5s")
+
+	cfg = Load()
+	if cfg.PreflightEnforce || !cfg.RedisRequired || cfg.MaxClockSkew != 5*time.Second {
+		t.Errorf("unexpected preflight config %+v", cfg)
+	}
+}
+
+func TestOAuthConfig(t *testing.T) {
+	cfg := Load()
+	if len(cfg.OAuthProviders) != 0 {
+		t.Errorf("expected no OAuth providers by default, got %+v", cfg.OAuthProviders)
+	}
+	if !cfg.OAuthAutoProvision || cfg.OAuthStateTTL != 10*time.Minute {
+		t.Errorf("unexpected OAuth defaults %+v", cfg)
+	}
+
+	t.Setenv("OAUTH_PROVIDERS", "GitHub, corp-sso,")
+	t.Setenv("OAUTH_GITHUB_CLIENT_ID", "gh-client")
+	t.Setenv("OAUTH_GITHUB_CLIENT_SECRET", "gh-secret")
+	t.Setenv("OAUTH_CORP_SSO_CLIENT_ID", "corp-client")
+	t.Setenv("OAUTH_CORP_SSO_ISSUER", "https://sso.example.com")
+	t.Setenv("OAUTH_CORP_SSO_SCOPES", "openid, email")
+	t.Setenv("OAUTH_AUTO_PROVISION", "false")
+
+	cfg = Load()
+	if len(cfg.OAuthProviders) != 2 {
+		t.Fatalf("expected 2 OAuth providers, got %+v", cfg.OAuthProviders)
+	}
+	github, corp := cfg.OAuthProviders[0], cfg.OAuthProviders[1]
+	if github.Name != "github" || github.ClientID != "gh-client" || github.ClientSecret != "gh-secret" {
+		t.Errorf("unexpected github provider %+v", github)
+	}
+	if corp.Name != "corp-sso" || corp.ClientID != "corp-client" || corp.Issuer != "https://sso.example.com" || len(corp.Scopes) != 2 || corp.Scopes[1] != "email" {
+		t.Errorf("unexpected OIDC provider %+v", corp)
+	}
+	if cfg.OAuthAutoProvision {
+		t.Error("expected OAUTH_AUTO_PROVISION=false to turn provisioning off")
+	}
+}
+
+func TestServerThrottleConfig(t *testing.T) {
+	cfg := Load()
+	if cfg.ServerMessagesPerSecond != 50 || cfg.ServerEventsPerSecond != 200 {
+		t.Errorf("unexpected server throttle defaults %d, %d", cfg.ServerMessagesPerSecond, cfg.ServerEventsPerSecond)
+	}
+
+	t.Setenv("SERVER_MESSAGES_PER_SECOND", "0")
+	t.Setenv("SERVER_EVENTS_PER_SECOND", "1000")
+	cfg = Load()
+	if cfg.ServerMessagesPerSecond != 0 || cfg.ServerEventsPerSecond != 1000 {
+		t.Errorf("expected overridden server throttles, got %d, %d", cfg.ServerMessagesPerSecond, cfg.ServerEventsPerSecond)
+	}
+}
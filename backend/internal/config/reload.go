@@ -0,0 +1,205 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"hearth/internal/models"
+)
+
+// ErrNoConfigFile is returned when reloading without a config file
+var ErrNoConfigFile = errors.New("no config file is set; set CONFIG_FILE to reload settings at runtime")
+
+var (
+	fileMu     sync.RWMutex
+	fileValues map[string]string
+)
+
+// ReadFile reads settings from a file of KEY=VALUE lines, which Load uses
+// for anything not set in the environment. Blank lines and lines starting
+// with # are skipped, and values may be quoted. An empty path reads
+// nothing.
+func ReadFile(path string) error {
+	if path == "" {
+		return nil
+	}
+	values, err := parseFile(path)
+	if err != nil {
+		return err
+	}
+	fileMu.Lock()
+	fileValues = values
+	fileMu.Unlock()
+	return nil
+}
+
+func parseFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, n)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// lookupEnv returns a setting from the environment, or from the config
+// file when the environment doesn't set it
+func lookupEnv(key string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	fileMu.RLock()
+	defer fileMu.RUnlock()
+	return fileValues[key]
+}
+
+// Reloader re-reads the config file while the instance runs and hands the
+// settings that changed to whatever registered for them
+type Reloader struct {
+	path string
+	now  func() time.Time
+
+	mu       sync.Mutex
+	watchers []configWatcher
+}
+
+type configWatcher struct {
+	keys  []string
+	apply func(*Config) error
+}
+
+// NewReloader creates a reloader for the config file at path
+func NewReloader(path string) *Reloader {
+	return &Reloader{path: path, now: time.Now}
+}
+
+// Watch calls apply with the new config when any of keys changes. If
+// apply fails, those settings keep their old values.
+func (r *Reloader) Watch(apply func(*Config) error, keys ...string) {
+	r.mu.Lock()
+	r.watchers = append(r.watchers, configWatcher{keys: keys, apply: apply})
+	r.mu.Unlock()
+}
+
+// Reload re-reads the config file and applies the settings that changed.
+// It fails without changing anything if the file can't be read. Settings
+// nothing watches are reported as needing a restart.
+func (r *Reloader) Reload() (*models.ConfigReload, error) {
+	if r.path == "" {
+		return nil, ErrNoConfigFile
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	values, err := parseFile(r.path)
+	if err != nil {
+		return nil, err
+	}
+	fileMu.Lock()
+	old := fileValues
+	fileValues = values
+	fileMu.Unlock()
+
+	result := &models.ConfigReload{
+		File:            r.path,
+		ReloadedAt:      r.now(),
+		Applied:         []string{},
+		RestartRequired: []string{},
+		Overridden:      []string{},
+	}
+	changed := make(map[string]bool)
+	for _, key := range changedKeys(old, values) {
+		if os.Getenv(key) != "" {
+			result.Overridden = append(result.Overridden, key)
+		} else {
+			changed[key] = true
+		}
+	}
+	if len(changed) == 0 {
+		return result, nil
+	}
+
+	cfg := Load()
+	for _, w := range r.watchers {
+		var keys []string
+		for _, key := range w.keys {
+			if changed[key] {
+				keys = append(keys, key)
+				delete(changed, key)
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		if err := w.apply(cfg); err != nil {
+			if result.Errors == nil {
+				result.Errors = make(map[string]string)
+			}
+			fileMu.Lock()
+			for _, key := range keys {
+				result.Errors[key] = err.Error()
+				if value, ok := old[key]; ok {
+					fileValues[key] = value
+				} else {
+					delete(fileValues, key)
+				}
+			}
+			fileMu.Unlock()
+			continue
+		}
+		result.Applied = append(result.Applied, keys...)
+	}
+	for key := range changed {
+		result.RestartRequired = append(result.RestartRequired, key)
+	}
+	sort.Strings(result.Applied)
+	sort.Strings(result.RestartRequired)
+	return result, nil
+}
+
+// changedKeys returns the keys whose values differ between old and
+// values, sorted
+func changedKeys(old, values map[string]string) []string {
+	var keys []string
+	for key, value := range values {
+		if previous, ok := old[key]; !ok || previous != value {
+			keys = append(keys, key)
+		}
+	}
+	for key := range old {
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func resetFileValues(t *testing.T) {
+	t.Cleanup(func() {
+		fileMu.Lock()
+		fileValues = nil
+		fileMu.Unlock()
+	})
+}
+
+func TestReadFile(t *testing.T) {
+	resetFileValues(t)
+	t.Setenv("PORT", "")
+	t.Setenv("LOG_LEVEL", "warn")
+	path := filepath.Join(t.TempDir(), "hearth.env")
+	writeConfigFile(t, path, `# Hearth settings
+
+export PORT=9090
+LOG_LEVEL=debug
+PUBLIC_URL="https://chat.example.com"
+`)
+
+	if err := ReadFile(path); err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	cfg := Load()
+	if cfg.Port != 9090 {
+		t.Errorf("expected Port 9090 from the file, got %d", cfg.Port)
+	}
+	if cfg.LogLevel != "warn" {
+		t.Errorf("expected the environment to win, got LogLevel %q", cfg.LogLevel)
+	}
+	if cfg.PublicURL != "https://chat.example.com" {
+		t.Errorf("expected quotes to be stripped, got %q", cfg.PublicURL)
+	}
+
+	writeConfigFile(t, path, "PORT=9090\nnot a setting\n")
+	err := ReadFile(path)
+	if err == nil || err.Error() != path+":2: expected KEY=VALUE" {
+		t.Errorf("expected the bad line to be reported, got %v", err)
+	}
+	if err := ReadFile(""); err != nil {
+		t.Errorf("expected an empty path to read nothing, got %v", err)
+	}
+}
+
+func TestReloader(t *testing.T) {
+	resetFileValues(t)
+	for _, key := range []string{"LOCKOUT_THRESHOLD", "IP_BLOCKLIST", "PORT"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("LOG_LEVEL", "info")
+	path := filepath.Join(t.TempDir(), "hearth.env")
+	writeConfigFile(t, path, "LOCKOUT_THRESHOLD=10\nIP_BLOCKLIST=\nPORT=8080\n")
+	if err := ReadFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	var threshold int
+	reloader := NewReloader(path)
+	reloader.Watch(func(cfg *Config) error {
+		threshold = cfg.LockoutThreshold
+		return nil
+	}, "LOCKOUT_THRESHOLD")
+	reloader.Watch(func(cfg *Config) error {
+		return errors.New("blocklist: not a network")
+	}, "IP_BLOCKLIST")
+
+	writeConfigFile(t, path, "LOCKOUT_THRESHOLD=3\nIP_BLOCKLIST=nonsense\nPORT=9090\nLOG_LEVEL=debug\n")
+	result, err := reloader.Reload()
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if threshold != 3 {
+		t.Errorf("expected the watcher to get the new threshold, got %d", threshold)
+	}
+	if !reflect.DeepEqual(result.Applied, []string{"LOCKOUT_THRESHOLD"}) {
+		t.Errorf("unexpected Applied %v", result.Applied)
+	}
+	if !reflect.DeepEqual(result.RestartRequired, []string{"PORT"}) {
+		t.Errorf("unexpected RestartRequired %v", result.RestartRequired)
+	}
+	if !reflect.DeepEqual(result.Overridden, []string{"LOG_LEVEL"}) {
+		t.Errorf("unexpected Overridden %v", result.Overridden)
+	}
+	if result.Errors["IP_BLOCKLIST"] != "blocklist: not a network" {
+		t.Errorf("unexpected Errors %v", result.Errors)
+	}
+	if cfg := Load(); cfg.IPBlocklist != "" {
+		t.Errorf("expected a setting that failed to keep its old value, got %q", cfg.IPBlocklist)
+	}
+
+	result, err = reloader.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Applied) != 0 || len(result.RestartRequired) != 0 {
+		t.Errorf("expected settings applied before not to be reported again, got %+v", result)
+	}
+	if _, ok := result.Errors["IP_BLOCKLIST"]; !ok {
+		t.Errorf("expected the failed setting to be retried, got %+v", result)
+	}
+
+	writeConfigFile(t, path, "PORT")
+	if _, err := reloader.Reload(); err == nil {
+		t.Error("expected a file that doesn't parse to fail the reload")
+	}
+	if cfg := Load(); cfg.LockoutThreshold != 3 {
+		t.Errorf("expected a failed reload to change nothing, got threshold %d", cfg.LockoutThreshold)
+	}
+}
+
+func TestReloader_NoFile(t *testing.T) {
+	if _, err := NewReloader("").Reload(); !errors.Is(err, ErrNoConfigFile) {
+		t.Errorf("expected ErrNoConfigFile, got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+// Package instrument times database queries and attributes them to the
+// repository method, service and API route that issued them.
+package instrument
+
+import (
+	"context"
+	"log"
+	"runtime"
+	"strings"
+	"time"
+
+	"hearth/internal/metrics"
+)
+
+const (
+	repositoryPackage = "hearth/internal/database/postgres."
+	servicePackage    = "hearth/internal/services."
+
+	// maxCallerDepth bounds the stack walk; database/sql and sqlx add about
+	// a dozen frames between a repository and the driver
+	maxCallerDepth = 48
+
+	// maxLoggedQueryLength keeps slow query log lines readable
+	maxLoggedQueryLength = 500
+
+	unknownLabel = "unknown"
+)
+
+// Query describes one completed statement
+type Query struct {
+	SQL        string
+	Repository string // e.g. "MessageRepository.GetByID"
+	Service    string // e.g. "MessageService.SendMessage"
+	Route      string // e.g. "GET /api/v1/channels/:id/messages"
+	Duration   time.Duration
+	Err        error
+}
+
+// Observer receives every query run through an instrumented connection
+type Observer interface {
+	ObserveQuery(q *Query)
+}
+
+type routeKey struct{}
+
+// WithRoute labels queries run with the returned context as coming from an
+// API route. route is called per query, so it may resolve lazily while the
+// request is being routed.
+func WithRoute(ctx context.Context, route func() string) context.Context {
+	return context.WithValue(ctx, routeKey{}, route)
+}
+
+// RouteFrom returns the route set by WithRoute, or "" outside a request
+func RouteFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if route, ok := ctx.Value(routeKey{}).(func() string); ok {
+		return route()
+	}
+	return ""
+}
+
+// callers finds the innermost repository and service frames on the stack
+func callers(skip int) (repository, service string) {
+	pcs := make([]uintptr, maxCallerDepth)
+	n := runtime.Callers(skip+1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		switch {
+		case repository == "" && strings.HasPrefix(frame.Function, repositoryPackage):
+			repository = methodName(frame.Function, repositoryPackage)
+		case strings.HasPrefix(frame.Function, servicePackage):
+			return repository, methodName(frame.Function, servicePackage)
+		}
+		if !more {
+			return repository, service
+		}
+	}
+}
+
+// methodName turns "pkg.(*MessageService).SendMessage.func1" into
+// "MessageService.SendMessage"
+func methodName(function, pkg string) string {
+	name := strings.TrimPrefix(function, pkg)
+	if i := strings.Index(name, ".func"); i >= 0 {
+		name = name[:i]
+	}
+	name = strings.TrimPrefix(name, "(*")
+	return strings.Replace(name, ").", ".", 1)
+}
+
+// Recorder feeds query durations to Prometheus and logs slow queries
+type Recorder struct {
+	metrics       *metrics.DatabaseMetrics
+	slowThreshold time.Duration
+	logf          func(format string, args ...interface{})
+}
+
+// NewRecorder creates a recorder. Queries taking at least slowThreshold are
+// logged; zero disables the slow query log. metrics may be nil.
+func NewRecorder(m *metrics.DatabaseMetrics, slowThreshold time.Duration) *Recorder {
+	return &Recorder{
+		metrics:       m,
+		slowThreshold: slowThreshold,
+		logf:          log.Printf,
+	}
+}
+
+// ObserveQuery implements Observer
+func (r *Recorder) ObserveQuery(q *Query) {
+	repository, service, route := label(q.Repository), label(q.Service), label(q.Route)
+	slow := r.slowThreshold > 0 && q.Duration >= r.slowThreshold
+
+	if r.metrics != nil {
+		r.metrics.QueryCompleted(repository, service, route, q.Duration.Seconds(), q.Err != nil)
+		if slow {
+			r.metrics.SlowQuery(repository, service, route)
+		}
+	}
+
+	if slow {
+		// Arguments are left out; they can hold message content and tokens
+		r.logf("[SlowQuery] %s repository=%s service=%s route=%q query=%q",
+			q.Duration.Round(time.Millisecond), repository, service, route, compactSQL(q.SQL))
+	}
+}
+
+func label(value string) string {
+	if value == "" {
+		return unknownLabel
+	}
+	return value
+}
+
+// compactSQL collapses whitespace and truncates long statements
+func compactSQL(query string) string {
+	query = strings.Join(strings.Fields(query), " ")
+	if len(query) > maxLoggedQueryLength {
+		query = query[:maxLoggedQueryLength] + "..."
+	}
+	return query
+}
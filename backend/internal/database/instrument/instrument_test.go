@@ -0,0 +1,198 @@
+package instrument
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConnector hands out connections that answer every query with no rows
+type fakeConnector struct {
+	err error
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{err: c.err}, nil
+}
+func (c *fakeConnector) Driver() driver.Driver { return nil }
+
+type fakeConn struct {
+	err error
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{conn: c}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return fakeRows{}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type fakeStmt struct {
+	conn *fakeConn
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) { return fakeRows{}, nil }
+func (s *fakeStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string              { return []string{"id"} }
+func (fakeRows) Close() error                   { return nil }
+func (fakeRows) Next(dest []driver.Value) error { return io.EOF }
+
+type recordingObserver struct {
+	mu      sync.Mutex
+	queries []*Query
+}
+
+func (o *recordingObserver) ObserveQuery(q *Query) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.queries = append(o.queries, q)
+}
+
+func openTestDB(t *testing.T, connector *fakeConnector) (*sql.DB, *recordingObserver) {
+	observer := &recordingObserver{}
+	db := sql.OpenDB(WrapConnector(connector, observer))
+	t.Cleanup(func() { db.Close() })
+	return db, observer
+}
+
+func TestWrapConnector_ObservesQueries(t *testing.T) {
+	db, observer := openTestDB(t, &fakeConnector{})
+	ctx := WithRoute(context.Background(), func() string { return "GET /api/v1/users/@me" })
+
+	rows, err := db.QueryContext(ctx, "SELECT id FROM users WHERE id = $1", 1)
+	require.NoError(t, err)
+	rows.Close()
+
+	_, err = db.ExecContext(ctx, "UPDATE users SET username = $1", "alice")
+	require.NoError(t, err)
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = tx.ExecContext(ctx, "DELETE FROM sessions")
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	stmt, err := db.PrepareContext(ctx, "INSERT INTO users (id) VALUES ($1)")
+	require.NoError(t, err)
+	_, err = stmt.ExecContext(ctx, 1)
+	require.NoError(t, err)
+	stmt.Close()
+
+	require.Len(t, observer.queries, 4)
+	assert.Equal(t, "SELECT id FROM users WHERE id = $1", observer.queries[0].SQL)
+	assert.Equal(t, "DELETE FROM sessions", observer.queries[2].SQL, "queries inside transactions are observed")
+	assert.Equal(t, "INSERT INTO users (id) VALUES ($1)", observer.queries[3].SQL, "prepared statements are observed")
+	for _, q := range observer.queries {
+		assert.Equal(t, "GET /api/v1/users/@me", q.Route)
+		assert.NoError(t, q.Err)
+	}
+}
+
+func TestWrapConnector_ObservesErrors(t *testing.T) {
+	queryErr := errors.New("relation does not exist")
+	db, observer := openTestDB(t, &fakeConnector{err: queryErr})
+
+	_, err := db.ExecContext(context.Background(), "DELETE FROM missing")
+	require.Error(t, err)
+
+	require.Len(t, observer.queries, 1)
+	assert.ErrorIs(t, observer.queries[0].Err, queryErr)
+	assert.Empty(t, observer.queries[0].Route, "queries outside a request have no route")
+}
+
+func TestMethodName(t *testing.T) {
+	tests := map[string]string{
+		"hearth/internal/services.(*MessageService).SendMessage":      "MessageService.SendMessage",
+		"hearth/internal/services.(*PushService).Start.func1":         "PushService.Start",
+		"hearth/internal/services.(*PushService).Start.func1.1":       "PushService.Start",
+		"hearth/internal/services.NewServerService":                   "NewServerService",
+		"hearth/internal/database/postgres.(*UserRepository).GetByID": "UserRepository.GetByID",
+	}
+	for function, want := range tests {
+		pkg := servicePackage
+		if strings.HasPrefix(function, repositoryPackage) {
+			pkg = repositoryPackage
+		}
+		assert.Equal(t, want, methodName(function, pkg), function)
+	}
+}
+
+func TestRecorder_SlowQueryLog(t *testing.T) {
+	var logged []string
+	recorder := NewRecorder(nil, 100*time.Millisecond)
+	recorder.logf = func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}
+
+	recorder.ObserveQuery(&Query{SQL: "SELECT 1", Duration: 5 * time.Millisecond})
+	assert.Empty(t, logged, "fast queries aren't logged")
+
+	recorder.ObserveQuery(&Query{
+		SQL:        "SELECT *\n\t\tFROM messages\n\t\tWHERE channel_id = $1",
+		Repository: "MessageRepository.GetChannelMessages",
+		Service:    "MessageService.GetMessages",
+		Route:      "GET /api/v1/channels/:id/messages",
+		Duration:   250 * time.Millisecond,
+	})
+	require.Len(t, logged, 1)
+	assert.Contains(t, logged[0], "250ms")
+	assert.Contains(t, logged[0], "repository=MessageRepository.GetChannelMessages")
+	assert.Contains(t, logged[0], "service=MessageService.GetMessages")
+	assert.Contains(t, logged[0], `route="GET /api/v1/channels/:id/messages"`)
+	assert.Contains(t, logged[0], `query="SELECT * FROM messages WHERE channel_id = $1"`)
+
+	recorder.ObserveQuery(&Query{SQL: "SELECT 1", Duration: time.Second})
+	require.Len(t, logged, 2)
+	assert.Contains(t, logged[1], "repository=unknown service=unknown route=\"unknown\"")
+}
+
+func TestRecorder_SlowQueryLogDisabled(t *testing.T) {
+	recorder := NewRecorder(nil, 0)
+	recorder.logf = func(format string, args ...interface{}) {
+		t.Errorf("unexpected log: "+format, args...)
+	}
+	recorder.ObserveQuery(&Query{SQL: "SELECT pg_sleep(10)", Duration: 10 * time.Second})
+}
+
+func TestCompactSQL(t *testing.T) {
+	long := "SELECT " + strings.Repeat("a, ", 300) + "b FROM t"
+	compacted := compactSQL(long)
+	assert.Len(t, compacted, maxLoggedQueryLength+3)
+	assert.True(t, strings.HasSuffix(compacted, "..."))
+}
@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"hearth/internal/models"
+)
+
+// AnnouncementRepository stores instance admins' announcements
+type AnnouncementRepository struct {
+	db *sqlx.DB
+}
+
+func NewAnnouncementRepository(db *sqlx.DB) *AnnouncementRepository {
+	return &AnnouncementRepository{db: db}
+}
+
+// List returns every announcement, including expired ones, newest first
+func (r *AnnouncementRepository) List(ctx context.Context) ([]*models.Announcement, error) {
+	announcements := []*models.Announcement{}
+	err := r.db.SelectContext(ctx, &announcements, `SELECT * FROM announcements ORDER BY created_at DESC, id`)
+	return announcements, err
+}
+
+// ListActive returns the announcements that haven't expired at now, newest
+// first
+func (r *AnnouncementRepository) ListActive(ctx context.Context, now time.Time) ([]*models.Announcement, error) {
+	announcements := []*models.Announcement{}
+	err := r.db.SelectContext(ctx, &announcements, `
+		SELECT * FROM announcements
+		WHERE expires_at IS NULL OR expires_at > $1
+		ORDER BY created_at DESC, id
+	`, now)
+	return announcements, err
+}
+
+func (r *AnnouncementRepository) Create(ctx context.Context, announcement *models.Announcement) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO announcements (id, content, level, creator_id, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, announcement.ID, announcement.Content, announcement.Level, announcement.CreatorID, announcement.CreatedAt, announcement.ExpiresAt)
+	return err
+}
+
+// Delete removes an announcement, reporting whether it existed
+func (r *AnnouncementRepository) Delete(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM announcements WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
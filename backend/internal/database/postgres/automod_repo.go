@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"hearth/internal/models"
+)
+
+// AutoModRuleRepository handles AutoMod rule database operations
+type AutoModRuleRepository struct {
+	db *sqlx.DB
+}
+
+// NewAutoModRuleRepository creates a new AutoMod rule repository
+func NewAutoModRuleRepository(db *sqlx.DB) *AutoModRuleRepository {
+	return &AutoModRuleRepository{db: db}
+}
+
+const autoModRuleColumns = `id, server_id, creator_id, name, trigger_type, trigger_metadata, actions,
+	enabled, exempt_roles, exempt_channels, created_at, updated_at`
+
+// autoModRuleRow is a rule as stored, with its JSON and array columns still
+// encoded
+type autoModRuleRow struct {
+	ID              uuid.UUID                 `db:"id"`
+	ServerID        uuid.UUID                 `db:"server_id"`
+	CreatorID       uuid.UUID                 `db:"creator_id"`
+	Name            string                    `db:"name"`
+	TriggerType     models.AutoModTriggerType `db:"trigger_type"`
+	TriggerMetadata []byte                    `db:"trigger_metadata"`
+	Actions         []byte                    `db:"actions"`
+	Enabled         bool                      `db:"enabled"`
+	ExemptRoles     pq.StringArray            `db:"exempt_roles"`
+	ExemptChannels  pq.StringArray            `db:"exempt_channels"`
+	CreatedAt       sql.NullTime              `db:"created_at"`
+	UpdatedAt       sql.NullTime              `db:"updated_at"`
+}
+
+func (row *autoModRuleRow) rule() (*models.AutoModRule, error) {
+	rule := &models.AutoModRule{
+		ID:          row.ID,
+		ServerID:    row.ServerID,
+		CreatorID:   row.CreatorID,
+		Name:        row.Name,
+		TriggerType: row.TriggerType,
+		Enabled:     row.Enabled,
+		CreatedAt:   row.CreatedAt.Time,
+		UpdatedAt:   row.UpdatedAt.Time,
+	}
+	if err := json.Unmarshal(row.TriggerMetadata, &rule.TriggerMetadata); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(row.Actions, &rule.Actions); err != nil {
+		return nil, err
+	}
+	var err error
+	if rule.ExemptRoles, err = parseUUIDs(row.ExemptRoles); err != nil {
+		return nil, err
+	}
+	if rule.ExemptChannels, err = parseUUIDs(row.ExemptChannels); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func parseUUIDs(values []string) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(values))
+	for _, v := range values {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Create stores a new rule
+func (r *AutoModRuleRepository) Create(ctx context.Context, rule *models.AutoModRule) error {
+	metadata, err := json.Marshal(rule.TriggerMetadata)
+	if err != nil {
+		return err
+	}
+	actions, err := json.Marshal(rule.Actions)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO automod_rules (` + autoModRuleColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+	_, err = r.db.ExecContext(ctx, query,
+		rule.ID, rule.ServerID, rule.CreatorID, rule.Name, rule.TriggerType, metadata, actions,
+		rule.Enabled, pq.Array(rule.ExemptRoles), pq.Array(rule.ExemptChannels), rule.CreatedAt, rule.UpdatedAt,
+	)
+	return err
+}
+
+// GetByID returns a rule, or nil if there's no such rule
+func (r *AutoModRuleRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.AutoModRule, error) {
+	var row autoModRuleRow
+	query := `SELECT ` + autoModRuleColumns + ` FROM automod_rules WHERE id = $1`
+	err := r.db.GetContext(ctx, &row, query, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row.rule()
+}
+
+// GetByServerID returns a server's rules, oldest first
+func (r *AutoModRuleRepository) GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*models.AutoModRule, error) {
+	var rows []autoModRuleRow
+	query := `SELECT ` + autoModRuleColumns + ` FROM automod_rules WHERE server_id = $1 ORDER BY created_at, id`
+	if err := r.db.SelectContext(ctx, &rows, query, serverID); err != nil {
+		return nil, err
+	}
+
+	rules := make([]*models.AutoModRule, 0, len(rows))
+	for i := range rows {
+		rule, err := rows[i].rule()
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Update saves a rule's editable fields
+func (r *AutoModRuleRepository) Update(ctx context.Context, rule *models.AutoModRule) error {
+	metadata, err := json.Marshal(rule.TriggerMetadata)
+	if err != nil {
+		return err
+	}
+	actions, err := json.Marshal(rule.Actions)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE automod_rules SET
+			name = $2, trigger_metadata = $3, actions = $4, enabled = $5,
+			exempt_roles = $6, exempt_channels = $7, updated_at = $8
+		WHERE id = $1
+	`
+	result, err := r.db.ExecContext(ctx, query,
+		rule.ID, rule.Name, metadata, actions, rule.Enabled,
+		pq.Array(rule.ExemptRoles), pq.Array(rule.ExemptChannels), rule.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Delete removes a rule
+func (r *AutoModRuleRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM automod_rules WHERE id = $1`, id)
+	return err
+}
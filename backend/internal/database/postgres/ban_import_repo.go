@@ -0,0 +1,194 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// BanImportRepository queues ban list imports and applies them batch by
+// batch
+type BanImportRepository struct {
+	db *sqlx.DB
+}
+
+func NewBanImportRepository(db *sqlx.DB) *BanImportRepository {
+	return &BanImportRepository{db: db}
+}
+
+const banImportColumns = `id, server_id, requested_by, status, total, processed, banned, skipped, error, created_at, updated_at, completed_at`
+
+// CreateBanImport queues an import with its entries. It returns
+// services.ErrBanImportInProgress if the server already has one that hasn't
+// finished.
+func (r *BanImportRepository) CreateBanImport(ctx context.Context, job *models.BanImport, entries []models.BanListEntry) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO ban_imports (id, server_id, requested_by, status, total, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, job.ID, job.ServerID, job.RequestedBy, job.Status, job.Total, job.CreatedAt, job.UpdatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return services.ErrBanImportInProgress
+	}
+	if err != nil {
+		return err
+	}
+
+	userIDs := make([]uuid.UUID, len(entries))
+	reasons := make([]sql.NullString, len(entries))
+	for i, entry := range entries {
+		userIDs[i] = entry.UserID
+		if entry.Reason != nil {
+			reasons[i] = sql.NullString{String: *entry.Reason, Valid: true}
+		}
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO ban_import_entries (import_id, position, user_id, reason)
+		SELECT $1, e.position - 1, e.user_id, e.reason
+		FROM unnest($2::uuid[], $3::text[]) WITH ORDINALITY AS e(user_id, reason, position)
+	`, job.ID, pq.Array(userIDs), pq.Array(reasons))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetBanImport returns nil if the server has no import with that ID
+func (r *BanImportRepository) GetBanImport(ctx context.Context, serverID, id uuid.UUID) (*models.BanImport, error) {
+	var job models.BanImport
+	err := r.db.GetContext(ctx, &job, `SELECT `+banImportColumns+` FROM ban_imports WHERE server_id = $1 AND id = $2`, serverID, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ClaimBanImport marks the oldest unfinished import that nobody holds as
+// running and holds it until until. It returns the import with its next
+// limit entries, or nil if there's nothing to do.
+func (r *BanImportRepository) ClaimBanImport(ctx context.Context, now, until time.Time, limit int) (*models.BanImport, []models.BanListEntry, error) {
+	var job models.BanImport
+	err := r.db.GetContext(ctx, &job, `
+		UPDATE ban_imports SET status = 'running', locked_until = $2, updated_at = $1
+		WHERE id = (
+			SELECT id FROM ban_imports
+			WHERE status IN ('queued', 'running') AND (locked_until IS NULL OR locked_until <= $1)
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+banImportColumns, now, until)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows []struct {
+		UserID uuid.UUID `db:"user_id"`
+		Reason *string   `db:"reason"`
+	}
+	err = r.db.SelectContext(ctx, &rows, `
+		SELECT user_id, reason FROM ban_import_entries
+		WHERE import_id = $1 AND position >= $2
+		ORDER BY position
+		LIMIT $3
+	`, job.ID, job.Processed, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries := make([]models.BanListEntry, len(rows))
+	for i, row := range rows {
+		entries[i] = models.BanListEntry{UserID: row.UserID, Reason: row.Reason}
+	}
+	return &job, entries, nil
+}
+
+// AddBans bans every user in entries who exists and isn't banned already,
+// removing them from the server, and returns the users it banned
+func (r *BanImportRepository) AddBans(ctx context.Context, serverID, bannedBy uuid.UUID, entries []models.BanListEntry, now time.Time) ([]uuid.UUID, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	userIDs := make([]uuid.UUID, len(entries))
+	reasons := make([]sql.NullString, len(entries))
+	for i, entry := range entries {
+		userIDs[i] = entry.UserID
+		if entry.Reason != nil {
+			reasons[i] = sql.NullString{String: *entry.Reason, Valid: true}
+		}
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var banned []uuid.UUID
+	err = tx.SelectContext(ctx, &banned, `
+		INSERT INTO bans (server_id, user_id, reason, banned_by, created_at)
+		SELECT $1, e.user_id, e.reason, $2, $3
+		FROM unnest($4::uuid[], $5::text[]) AS e(user_id, reason)
+		JOIN users u ON u.id = e.user_id
+		ON CONFLICT (server_id, user_id) DO NOTHING
+		RETURNING user_id
+	`, serverID, bannedBy, now, pq.Array(userIDs), pq.Array(reasons))
+	if err != nil {
+		return nil, err
+	}
+	if len(banned) == 0 {
+		return banned, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM members WHERE server_id = $1 AND user_id = ANY($2)`, serverID, pq.Array(banned))
+	if err != nil {
+		return nil, err
+	}
+	return banned, tx.Commit()
+}
+
+// SaveBanImportProgress stores the import's status and counters and lets
+// it go, so the next batch can be claimed. Entries are dropped once it
+// has finished.
+func (r *BanImportRepository) SaveBanImportProgress(ctx context.Context, job *models.BanImport) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE ban_imports SET
+			status = $2, processed = $3, banned = $4, skipped = $5, error = $6,
+			updated_at = $7, completed_at = $8, locked_until = NULL
+		WHERE id = $1
+	`, job.ID, job.Status, job.Processed, job.Banned, job.Skipped, job.Error, job.UpdatedAt, job.CompletedAt)
+	if err != nil {
+		return err
+	}
+	if job.Status.Finished() {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM ban_import_entries WHERE import_id = $1`, job.ID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
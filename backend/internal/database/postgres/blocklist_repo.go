@@ -0,0 +1,319 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"hearth/internal/models"
+)
+
+// BlocklistRepository stores shared blocklists and the servers following
+// them
+type BlocklistRepository struct {
+	db *sqlx.DB
+}
+
+func NewBlocklistRepository(db *sqlx.DB) *BlocklistRepository {
+	return &BlocklistRepository{db: db}
+}
+
+const blocklistColumns = `
+	b.id, b.server_id, b.name, b.description, b.public, b.created_by, b.created_at, b.updated_at,
+	(SELECT COUNT(*) FROM blocklist_entries e WHERE e.blocklist_id = b.id) AS entry_count
+`
+
+func (r *BlocklistRepository) CreateBlocklist(ctx context.Context, list *models.Blocklist) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO blocklists (id, server_id, name, description, public, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, list.ID, list.ServerID, list.Name, list.Description, list.Public, list.CreatedBy, list.CreatedAt, list.UpdatedAt)
+	return err
+}
+
+// GetBlocklist returns nil if there's no such list
+func (r *BlocklistRepository) GetBlocklist(ctx context.Context, id uuid.UUID) (*models.Blocklist, error) {
+	var list models.Blocklist
+	err := r.db.GetContext(ctx, &list, `SELECT `+blocklistColumns+` FROM blocklists b WHERE b.id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// ListPublicBlocklists returns every public list, by name
+func (r *BlocklistRepository) ListPublicBlocklists(ctx context.Context) ([]*models.Blocklist, error) {
+	lists := []*models.Blocklist{}
+	err := r.db.SelectContext(ctx, &lists, `SELECT `+blocklistColumns+` FROM blocklists b WHERE b.public ORDER BY b.name, b.id`)
+	return lists, err
+}
+
+// ListServerBlocklists returns the lists a server maintains, by name
+func (r *BlocklistRepository) ListServerBlocklists(ctx context.Context, serverID uuid.UUID) ([]*models.Blocklist, error) {
+	lists := []*models.Blocklist{}
+	err := r.db.SelectContext(ctx, &lists, `SELECT `+blocklistColumns+` FROM blocklists b WHERE b.server_id = $1 ORDER BY b.name, b.id`, serverID)
+	return lists, err
+}
+
+func (r *BlocklistRepository) UpdateBlocklist(ctx context.Context, list *models.Blocklist) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE blocklists SET name = $2, description = $3, public = $4, updated_at = $5 WHERE id = $1
+	`, list.ID, list.Name, list.Description, list.Public, list.UpdatedAt)
+	return err
+}
+
+// DeleteBlocklist removes a list, its entries and every subscription to it
+func (r *BlocklistRepository) DeleteBlocklist(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM blocklists WHERE id = $1`, id)
+	return err
+}
+
+// GetEntries returns a list's entries, newest first
+func (r *BlocklistRepository) GetEntries(ctx context.Context, blocklistID uuid.UUID) ([]*models.BlocklistEntry, error) {
+	entries := []*models.BlocklistEntry{}
+	err := r.db.SelectContext(ctx, &entries, `
+		SELECT blocklist_id, user_id, reason, added_by, created_at FROM blocklist_entries
+		WHERE blocklist_id = $1
+		ORDER BY created_at DESC, user_id
+	`, blocklistID)
+	return entries, err
+}
+
+// AddEntries adds users to a list, replacing the reason of any already on
+// it, and marks the list updated so subscribers sync again
+func (r *BlocklistRepository) AddEntries(ctx context.Context, blocklistID, addedBy uuid.UUID, entries []models.BanListEntry, now time.Time) error {
+	userIDs := make([]uuid.UUID, len(entries))
+	reasons := make([]sql.NullString, len(entries))
+	for i, entry := range entries {
+		userIDs[i] = entry.UserID
+		if entry.Reason != nil {
+			reasons[i] = sql.NullString{String: *entry.Reason, Valid: true}
+		}
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO blocklist_entries (blocklist_id, user_id, reason, added_by, created_at)
+		SELECT $1, e.user_id, e.reason, $2, $3
+		FROM unnest($4::uuid[], $5::text[]) AS e(user_id, reason)
+		ON CONFLICT (blocklist_id, user_id) DO UPDATE SET reason = EXCLUDED.reason
+	`, blocklistID, addedBy, now, pq.Array(userIDs), pq.Array(reasons))
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE blocklists SET updated_at = $2 WHERE id = $1`, blocklistID, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveEntry takes a user off a list, reporting whether they were on it
+func (r *BlocklistRepository) RemoveEntry(ctx context.Context, blocklistID, userID uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM blocklist_entries WHERE blocklist_id = $1 AND user_id = $2`, blocklistID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+const subscriptionColumns = `s.server_id, s.blocklist_id, s.subscribed_by, s.created_at, s.synced_at`
+
+// withBlocklists fills in each subscription's list
+func (r *BlocklistRepository) withBlocklists(ctx context.Context, subscriptions []*models.BlocklistSubscription) ([]*models.BlocklistSubscription, error) {
+	if len(subscriptions) == 0 {
+		return subscriptions, nil
+	}
+	ids := make([]uuid.UUID, len(subscriptions))
+	for i, subscription := range subscriptions {
+		ids[i] = subscription.BlocklistID
+	}
+
+	var lists []*models.Blocklist
+	if err := r.db.SelectContext(ctx, &lists, `SELECT `+blocklistColumns+` FROM blocklists b WHERE b.id = ANY($1)`, pq.Array(ids)); err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*models.Blocklist, len(lists))
+	for _, list := range lists {
+		byID[list.ID] = list
+	}
+	for _, subscription := range subscriptions {
+		subscription.Blocklist = byID[subscription.BlocklistID]
+	}
+	return subscriptions, nil
+}
+
+// GetSubscriptions returns the lists a server follows, oldest first
+func (r *BlocklistRepository) GetSubscriptions(ctx context.Context, serverID uuid.UUID) ([]*models.BlocklistSubscription, error) {
+	subscriptions := []*models.BlocklistSubscription{}
+	err := r.db.SelectContext(ctx, &subscriptions, `
+		SELECT `+subscriptionColumns+` FROM blocklist_subscriptions s
+		WHERE s.server_id = $1
+		ORDER BY s.created_at, s.blocklist_id
+	`, serverID)
+	if err != nil {
+		return nil, err
+	}
+	return r.withBlocklists(ctx, subscriptions)
+}
+
+// Subscribe has a server follow a list. It leaves existing subscriptions
+// alone.
+func (r *BlocklistRepository) Subscribe(ctx context.Context, subscription *models.BlocklistSubscription) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO blocklist_subscriptions (server_id, blocklist_id, subscribed_by, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (server_id, blocklist_id) DO NOTHING
+	`, subscription.ServerID, subscription.BlocklistID, subscription.SubscribedBy, subscription.CreatedAt)
+	return err
+}
+
+// Unsubscribe stops a server following a list, dropping its exemptions,
+// and reports whether it was following it
+func (r *BlocklistRepository) Unsubscribe(ctx context.Context, serverID, blocklistID uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM blocklist_subscriptions WHERE server_id = $1 AND blocklist_id = $2`, serverID, blocklistID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// GetExemptions returns the users a server exempted from a list
+func (r *BlocklistRepository) GetExemptions(ctx context.Context, serverID, blocklistID uuid.UUID) ([]*models.BlocklistExemption, error) {
+	exemptions := []*models.BlocklistExemption{}
+	err := r.db.SelectContext(ctx, &exemptions, `
+		SELECT server_id, blocklist_id, user_id, created_by, created_at FROM blocklist_exemptions
+		WHERE server_id = $1 AND blocklist_id = $2
+		ORDER BY created_at, user_id
+	`, serverID, blocklistID)
+	return exemptions, err
+}
+
+func (r *BlocklistRepository) AddExemption(ctx context.Context, exemption *models.BlocklistExemption) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO blocklist_exemptions (server_id, blocklist_id, user_id, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (server_id, blocklist_id, user_id) DO NOTHING
+	`, exemption.ServerID, exemption.BlocklistID, exemption.UserID, exemption.CreatedBy, exemption.CreatedAt)
+	return err
+}
+
+// RemoveExemption reports whether the user was exempt
+func (r *BlocklistRepository) RemoveExemption(ctx context.Context, serverID, blocklistID, userID uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM blocklist_exemptions WHERE server_id = $1 AND blocklist_id = $2 AND user_id = $3
+	`, serverID, blocklistID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+const blocklistMatchQuery = `
+	SELECT s.blocklist_id, b.name AS blocklist_name, e.user_id, e.reason, s.subscribed_by
+	FROM blocklist_subscriptions s
+	JOIN blocklists b ON b.id = s.blocklist_id
+	JOIN blocklist_entries e ON e.blocklist_id = s.blocklist_id
+	WHERE s.server_id = $1 AND NOT EXISTS (
+		SELECT 1 FROM blocklist_exemptions x
+		WHERE x.server_id = s.server_id AND x.blocklist_id = s.blocklist_id AND x.user_id = e.user_id
+	)
+`
+
+// FindMatch returns the oldest subscribed list the user is on and not
+// exempt from, or nil if there isn't one
+func (r *BlocklistRepository) FindMatch(ctx context.Context, serverID, userID uuid.UUID) (*models.BlocklistMatch, error) {
+	var match models.BlocklistMatch
+	err := r.db.GetContext(ctx, &match, blocklistMatchQuery+` AND e.user_id = $2 ORDER BY s.created_at LIMIT 1`, serverID, userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &match, nil
+}
+
+// MatchMembers returns the server's members who are on the list and not
+// exempt from it
+func (r *BlocklistRepository) MatchMembers(ctx context.Context, serverID, blocklistID uuid.UUID) ([]*models.BlocklistMatch, error) {
+	matches := []*models.BlocklistMatch{}
+	err := r.db.SelectContext(ctx, &matches, blocklistMatchQuery+`
+		AND s.blocklist_id = $2
+		AND EXISTS (SELECT 1 FROM members m WHERE m.server_id = s.server_id AND m.user_id = e.user_id)
+	`, serverID, blocklistID)
+	return matches, err
+}
+
+// StaleSubscriptions returns up to limit subscriptions whose list changed
+// since they were last synced, least recently synced first
+func (r *BlocklistRepository) StaleSubscriptions(ctx context.Context, limit int) ([]*models.BlocklistSubscription, error) {
+	subscriptions := []*models.BlocklistSubscription{}
+	err := r.db.SelectContext(ctx, &subscriptions, `
+		SELECT `+subscriptionColumns+` FROM blocklist_subscriptions s
+		JOIN blocklists b ON b.id = s.blocklist_id
+		WHERE s.synced_at IS NULL OR s.synced_at < b.updated_at
+		ORDER BY s.synced_at NULLS FIRST, s.created_at
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return r.withBlocklists(ctx, subscriptions)
+}
+
+// MarkSynced records the version of the list a subscription has been
+// synced against
+func (r *BlocklistRepository) MarkSynced(ctx context.Context, serverID, blocklistID uuid.UUID, version time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE blocklist_subscriptions SET synced_at = $3 WHERE server_id = $1 AND blocklist_id = $2
+	`, serverID, blocklistID, version)
+	return err
+}
+
+// BanMember bans a blocklisted user and removes them from the server. A
+// ban that has run out but not been lifted yet is replaced; any other ban
+// is left alone, and BanMember reports false.
+func (r *BlocklistRepository) BanMember(ctx context.Context, ban *models.Ban) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO bans (server_id, user_id, reason, banned_by, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (server_id, user_id) DO UPDATE SET
+			reason = EXCLUDED.reason,
+			banned_by = EXCLUDED.banned_by,
+			created_at = EXCLUDED.created_at,
+			expires_at = NULL
+		WHERE bans.expires_at IS NOT NULL AND bans.expires_at <= EXCLUDED.created_at
+	`, ban.ServerID, ban.UserID, ban.Reason, ban.BannedBy, ban.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM members WHERE server_id = $1 AND user_id = $2`, ban.ServerID, ban.UserID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"hearth/internal/models"
+)
+
+// BotTierRepository stores bot verification tiers
+type BotTierRepository struct {
+	db *sqlx.DB
+}
+
+func NewBotTierRepository(db *sqlx.DB) *BotTierRepository {
+	return &BotTierRepository{db: db}
+}
+
+// Get returns a bot's tier, or nil if it was never given one
+func (r *BotTierRepository) Get(ctx context.Context, userID uuid.UUID) (*models.BotVerification, error) {
+	var verification models.BotVerification
+	err := r.db.GetContext(ctx, &verification, `SELECT * FROM bot_tiers WHERE user_id = $1`, userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+// Save sets a bot's tier
+func (r *BotTierRepository) Save(ctx context.Context, verification *models.BotVerification) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO bot_tiers (user_id, tier, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET
+			tier = EXCLUDED.tier,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+	`, verification.UserID, verification.Tier, verification.UpdatedBy, verification.UpdatedAt)
+	return err
+}
+
+// List returns the bots given a tier, or any tier if it's empty, most
+// recently changed first
+func (r *BotTierRepository) List(ctx context.Context, tier models.BotTier) ([]*models.BotVerification, error) {
+	verifications := []*models.BotVerification{}
+	err := r.db.SelectContext(ctx, &verifications, `
+		SELECT * FROM bot_tiers
+		WHERE $1::text = '' OR tier = $1::text
+		ORDER BY updated_at DESC, user_id
+	`, tier)
+	return verifications, err
+}
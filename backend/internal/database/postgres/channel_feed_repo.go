@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"hearth/internal/models"
+)
+
+// ChannelFeedRepository stores which channels have Atom feeds
+type ChannelFeedRepository struct {
+	db *sqlx.DB
+}
+
+func NewChannelFeedRepository(db *sqlx.DB) *ChannelFeedRepository {
+	return &ChannelFeedRepository{db: db}
+}
+
+// Get returns the channel's feed, or nil if it has none
+func (r *ChannelFeedRepository) Get(ctx context.Context, channelID uuid.UUID) (*models.ChannelFeed, error) {
+	var feed models.ChannelFeed
+	err := r.db.GetContext(ctx, &feed, `SELECT * FROM channel_feeds WHERE channel_id = $1`, channelID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// Save creates the channel's feed or replaces its token
+func (r *ChannelFeedRepository) Save(ctx context.Context, feed *models.ChannelFeed) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO channel_feeds (channel_id, token, creator_id, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (channel_id) DO UPDATE SET token = EXCLUDED.token
+	`, feed.ChannelID, feed.Token, feed.CreatorID, feed.CreatedAt)
+	return err
+}
+
+// Delete turns the channel's feed off
+func (r *ChannelFeedRepository) Delete(ctx context.Context, channelID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM channel_feeds WHERE channel_id = $1`, channelID)
+	return err
+}
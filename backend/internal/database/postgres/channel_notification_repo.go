@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"hearth/internal/models"
+)
+
+// ChannelNotificationSettingsRepository handles per-channel notification
+// override database operations
+type ChannelNotificationSettingsRepository struct {
+	db *sqlx.DB
+}
+
+// NewChannelNotificationSettingsRepository creates a new channel notification settings repository
+func NewChannelNotificationSettingsRepository(db *sqlx.DB) *ChannelNotificationSettingsRepository {
+	return &ChannelNotificationSettingsRepository{db: db}
+}
+
+// Get returns a user's overrides for a channel, or nil if there are none
+func (r *ChannelNotificationSettingsRepository) Get(ctx context.Context, userID, channelID uuid.UUID) (*models.ChannelNotificationSettings, error) {
+	var settings models.ChannelNotificationSettings
+	query := `SELECT * FROM channel_notification_settings WHERE user_id = $1 AND channel_id = $2`
+	err := r.db.GetContext(ctx, &settings, query, userID, channelID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// Upsert replaces a user's overrides for a channel
+func (r *ChannelNotificationSettingsRepository) Upsert(ctx context.Context, settings *models.ChannelNotificationSettings) error {
+	query := `
+		INSERT INTO channel_notification_settings (user_id, channel_id, level, muted_until, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, channel_id) DO UPDATE SET
+			level = EXCLUDED.level,
+			muted_until = EXCLUDED.muted_until,
+			updated_at = EXCLUDED.updated_at
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		settings.UserID, settings.ChannelID, settings.Level, settings.MutedUntil, settings.UpdatedAt,
+	)
+	return err
+}
+
+// GetUserIDsWithLevel lists the users who set a channel to level and are
+// still members of its server
+func (r *ChannelNotificationSettingsRepository) GetUserIDsWithLevel(ctx context.Context, channelID uuid.UUID, level models.NotificationLevel) ([]uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	query := `
+		SELECT s.user_id FROM channel_notification_settings s
+		JOIN channels c ON c.id = s.channel_id
+		JOIN members m ON m.server_id = c.server_id AND m.user_id = s.user_id
+		WHERE s.channel_id = $1 AND s.level = $2
+	`
+	if err := r.db.SelectContext(ctx, &userIDs, query, channelID, level); err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
@@ -32,6 +32,7 @@ func (r *ChannelRepository) Create(ctx context.Context, channel *models.Channel)
 	if err != nil {
 		return err
 	}
+	channel.Version = 1 // Column default
 	
 	// For DM channels, add recipients
 	if len(channel.Recipients) > 0 {
@@ -68,18 +69,27 @@ func (r *ChannelRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.
 	return &channel, nil
 }
 
+// Update saves channel if it is still at channel.Version, then bumps the
+// version. It returns services.ErrVersionConflict if someone else saved first.
 func (r *ChannelRepository) Update(ctx context.Context, channel *models.Channel) error {
 	query := `
 		UPDATE channels SET
 			name = $2, topic = $3, position = $4, parent_id = $5,
-			slowmode = $6, nsfw = $7, e2ee_enabled = $8
-		WHERE id = $1
+			slowmode = $6, nsfw = $7, e2ee_enabled = $8, version = version + 1
+		WHERE id = $1 AND version = $9
 	`
-	_, err := r.db.ExecContext(ctx, query,
+	result, err := r.db.ExecContext(ctx, query,
 		channel.ID, channel.Name, channel.Topic, channel.Position, channel.ParentID,
-		channel.Slowmode, channel.NSFW, channel.E2EEEnabled,
+		channel.Slowmode, channel.NSFW, channel.E2EEEnabled, channel.Version,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if err := checkVersionedUpdate(result); err != nil {
+		return err
+	}
+	channel.Version++
+	return nil
 }
 
 func (r *ChannelRepository) Delete(ctx context.Context, id uuid.UUID) error {
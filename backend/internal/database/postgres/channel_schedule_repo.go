@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"hearth/internal/models"
+)
+
+// ChannelScheduleRepository stores channels' office hours
+type ChannelScheduleRepository struct {
+	db *sqlx.DB
+}
+
+func NewChannelScheduleRepository(db *sqlx.DB) *ChannelScheduleRepository {
+	return &ChannelScheduleRepository{db: db}
+}
+
+// channelScheduleRow is a schedule as stored, with its hours and closures
+// still encoded
+type channelScheduleRow struct {
+	ChannelID uuid.UUID `db:"channel_id"`
+	Timezone  string    `db:"timezone"`
+	Hours     []byte    `db:"hours"`
+	Closures  []byte    `db:"closures"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// Get returns the channel's schedule, or nil if it has none
+func (r *ChannelScheduleRepository) Get(ctx context.Context, channelID uuid.UUID) (*models.ChannelSchedule, error) {
+	var row channelScheduleRow
+	err := r.db.GetContext(ctx, &row, `
+		SELECT channel_id, timezone, hours, closures, updated_at
+		FROM channel_schedules WHERE channel_id = $1
+	`, channelID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := &models.ChannelSchedule{
+		ChannelID: row.ChannelID,
+		Timezone:  row.Timezone,
+		UpdatedAt: row.UpdatedAt,
+	}
+	if err := json.Unmarshal(row.Hours, &schedule.Hours); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(row.Closures, &schedule.Closures); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// Save creates or replaces the channel's schedule
+func (r *ChannelScheduleRepository) Save(ctx context.Context, schedule *models.ChannelSchedule) error {
+	hours, err := json.Marshal(schedule.Hours)
+	if err != nil {
+		return err
+	}
+	closures, err := json.Marshal(schedule.Closures)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO channel_schedules (channel_id, timezone, hours, closures, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (channel_id) DO UPDATE SET
+			timezone = EXCLUDED.timezone,
+			hours = EXCLUDED.hours,
+			closures = EXCLUDED.closures,
+			updated_at = EXCLUDED.updated_at
+	`, schedule.ChannelID, schedule.Timezone, hours, closures, schedule.UpdatedAt)
+	return err
+}
+
+// Delete removes the channel's schedule, leaving it open
+func (r *ChannelScheduleRepository) Delete(ctx context.Context, channelID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM channel_schedules WHERE channel_id = $1`, channelID)
+	return err
+}
@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"hearth/internal/models"
+)
+
+// ComplianceLogRepository stores compliance log entries. The table is
+// append-only; see migration 015.
+type ComplianceLogRepository struct {
+	db *sqlx.DB
+}
+
+// NewComplianceLogRepository creates a new compliance log repository
+func NewComplianceLogRepository(db *sqlx.DB) *ComplianceLogRepository {
+	return &ComplianceLogRepository{db: db}
+}
+
+const complianceLogColumns = `id, request_id, method, route, actor_id, targets, status, outcome, duration_ms, created_at`
+
+// complianceLogRow is an entry as stored, with its targets still encoded
+type complianceLogRow struct {
+	ID         uuid.UUID                `db:"id"`
+	RequestID  string                   `db:"request_id"`
+	Method     string                   `db:"method"`
+	Route      string                   `db:"route"`
+	ActorID    *uuid.UUID               `db:"actor_id"`
+	Targets    []byte                   `db:"targets"`
+	Status     int                      `db:"status"`
+	Outcome    models.ComplianceOutcome `db:"outcome"`
+	DurationMS int64                    `db:"duration_ms"`
+	CreatedAt  time.Time                `db:"created_at"`
+}
+
+// Append inserts entries in one statement
+func (r *ComplianceLogRepository) Append(ctx context.Context, entries []*models.ComplianceLogEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	const columns = 10
+	placeholders := make([]string, 0, len(entries))
+	args := make([]interface{}, 0, len(entries)*columns)
+	for i, entry := range entries {
+		targets, err := json.Marshal(entry.Targets)
+		if err != nil {
+			return err
+		}
+		n := i * columns
+		placeholders = append(placeholders, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10))
+		args = append(args, entry.ID, entry.RequestID, entry.Method, entry.Route, entry.ActorID,
+			targets, entry.Status, entry.Outcome, entry.DurationMS, entry.CreatedAt)
+	}
+	query := `INSERT INTO compliance_log (` + complianceLogColumns + `) VALUES ` + strings.Join(placeholders, ", ")
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// Export calls fn for each entry matching filter, oldest first, without
+// loading them all at once
+func (r *ComplianceLogRepository) Export(ctx context.Context, filter models.ComplianceLogFilter, fn func(*models.ComplianceLogEntry) error) error {
+	var (
+		where []string
+		args  []interface{}
+	)
+	if filter.After != nil {
+		args = append(args, *filter.After)
+		where = append(where, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if filter.Before != nil {
+		args = append(args, *filter.Before)
+		where = append(where, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	if filter.ActorID != nil {
+		args = append(args, *filter.ActorID)
+		where = append(where, fmt.Sprintf("actor_id = $%d", len(args)))
+	}
+	query := `SELECT ` + complianceLogColumns + ` FROM compliance_log`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY created_at, id`
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var row complianceLogRow
+		if err := rows.StructScan(&row); err != nil {
+			return err
+		}
+		entry := &models.ComplianceLogEntry{
+			ID:         row.ID,
+			RequestID:  row.RequestID,
+			Method:     row.Method,
+			Route:      row.Route,
+			ActorID:    row.ActorID,
+			Status:     row.Status,
+			Outcome:    row.Outcome,
+			DurationMS: row.DurationMS,
+			CreatedAt:  row.CreatedAt,
+		}
+		if err := json.Unmarshal(row.Targets, &entry.Targets); err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Purge deletes entries created before cutoff. It is the only delete the
+// table's trigger allows.
+func (r *ComplianceLogRepository) Purge(ctx context.Context, cutoff time.Time) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT set_config('hearth.compliance_purge', 'on', true)`); err != nil {
+		return 0, err
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM compliance_log WHERE created_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	count, _ := result.RowsAffected()
+	return int(count), nil
+}
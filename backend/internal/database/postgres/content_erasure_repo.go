@@ -0,0 +1,238 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// ContentErasureRepository queues content erasures and scrubs users'
+// content batch by batch
+type ContentErasureRepository struct {
+	db *sqlx.DB
+}
+
+func NewContentErasureRepository(db *sqlx.DB) *ContentErasureRepository {
+	return &ContentErasureRepository{db: db}
+}
+
+const contentErasureColumns = `id, user_id, requested_by, status, messages_scrubbed, forwards_scrubbed, attachments_removed, remaining, error, created_at, updated_at, completed_at, verified_at`
+
+// messageHasContent matches messages m that still hold something scrubbing
+// clears
+const messageHasContent = `(COALESCE(m.content, '') <> '' OR m.encrypted_content IS NOT NULL OR EXISTS (SELECT 1 FROM attachments a WHERE a.message_id = m.id))`
+
+// archivedMessageHasContent is messageHasContent for archived_messages
+const archivedMessageHasContent = `(COALESCE(content, '') <> '' OR encrypted_content IS NOT NULL OR attachments <> '[]')`
+
+// CreateContentErasure queues an erasure. It returns
+// services.ErrContentErasureInProgress if the user already has one that
+// hasn't finished.
+func (r *ContentErasureRepository) CreateContentErasure(ctx context.Context, job *models.ContentErasure) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO content_erasures (id, user_id, requested_by, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, job.ID, job.UserID, job.RequestedBy, job.Status, job.CreatedAt, job.UpdatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return services.ErrContentErasureInProgress
+	}
+	return err
+}
+
+// GetContentErasure returns nil if there's no erasure with that ID
+func (r *ContentErasureRepository) GetContentErasure(ctx context.Context, id uuid.UUID) (*models.ContentErasure, error) {
+	var job models.ContentErasure
+	err := r.db.GetContext(ctx, &job, `SELECT `+contentErasureColumns+` FROM content_erasures WHERE id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListContentErasures returns the user's erasures, newest first
+func (r *ContentErasureRepository) ListContentErasures(ctx context.Context, userID uuid.UUID) ([]*models.ContentErasure, error) {
+	jobs := []*models.ContentErasure{}
+	err := r.db.SelectContext(ctx, &jobs, `
+		SELECT `+contentErasureColumns+` FROM content_erasures
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	return jobs, err
+}
+
+// ClaimContentErasure marks the oldest unfinished erasure that nobody holds
+// as running and holds it until until, or returns nil if there's nothing to
+// do
+func (r *ContentErasureRepository) ClaimContentErasure(ctx context.Context, now, until time.Time) (*models.ContentErasure, error) {
+	var job models.ContentErasure
+	err := r.db.GetContext(ctx, &job, `
+		UPDATE content_erasures SET status = 'running', locked_until = $2, updated_at = $1
+		WHERE id = (
+			SELECT id FROM content_erasures
+			WHERE status IN ('queued', 'running') AND (locked_until IS NULL OR locked_until <= $1)
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+contentErasureColumns, now, until)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ScrubUserContent clears the content of up to limit of the user's messages
+// and thread replies, then of copies of their messages others forwarded,
+// and removes those messages' attachments. Archived messages and forwards
+// are scrubbed after the ones still in the messages table.
+func (r *ContentErasureRepository) ScrubUserContent(ctx context.Context, userID uuid.UUID, limit int) (models.ContentScrubCounts, error) {
+	var counts models.ContentScrubCounts
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return counts, err
+	}
+	defer tx.Rollback()
+
+	var own []uuid.UUID
+	err = tx.SelectContext(ctx, &own, `
+		SELECT m.id FROM messages m
+		WHERE m.author_id = $1 AND `+messageHasContent+`
+		LIMIT $2
+		FOR UPDATE OF m
+	`, userID, limit)
+	if err != nil {
+		return counts, err
+	}
+
+	threadReplies := 0
+	if len(own) < limit {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE thread_messages SET content = ''
+			WHERE id IN (
+				SELECT id FROM thread_messages
+				WHERE author_id = $1 AND content <> ''
+				LIMIT $2
+				FOR UPDATE
+			)
+		`, userID, limit-len(own))
+		if err != nil {
+			return counts, err
+		}
+		affected, _ := res.RowsAffected()
+		threadReplies = int(affected)
+	}
+
+	var forwards []uuid.UUID
+	if len(own)+threadReplies < limit {
+		err = tx.SelectContext(ctx, &forwards, `
+			SELECT m.id FROM messages m
+			WHERE m.forwarded_from IS NOT NULL AND m.forwarded_from->>'author_id' = $1 AND m.author_id <> $2 AND `+messageHasContent+`
+			LIMIT $3
+			FOR UPDATE OF m
+		`, userID.String(), userID, limit-len(own)-threadReplies)
+		if err != nil {
+			return counts, err
+		}
+	}
+
+	ids := append(own, forwards...)
+	if len(ids) > 0 {
+		res, err := tx.ExecContext(ctx, `DELETE FROM attachments WHERE message_id = ANY($1::uuid[])`, pq.Array(ids))
+		if err != nil {
+			return counts, err
+		}
+		affected, _ := res.RowsAffected()
+		counts.Attachments = int(affected)
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE messages SET content = '', encrypted_content = NULL, embeds = NULL
+			WHERE id = ANY($1::uuid[])
+		`, pq.Array(ids))
+		if err != nil {
+			return counts, err
+		}
+	}
+
+	var archived struct {
+		Own         int `db:"own"`
+		Forwards    int `db:"forwards"`
+		Attachments int `db:"attachments"`
+	}
+	if scrubbed := len(own) + threadReplies + len(forwards); scrubbed < limit {
+		err = tx.GetContext(ctx, &archived, `
+			WITH target AS (
+				SELECT id, created_at, author_id = $1 AS own, jsonb_array_length(attachments) AS attachments
+				FROM archived_messages
+				WHERE (author_id = $1 OR (forwarded_from->>'author_id' = $2 AND author_id <> $1)) AND `+archivedMessageHasContent+`
+				LIMIT $3
+				FOR UPDATE
+			), scrubbed AS (
+				UPDATE archived_messages a SET content = '', encrypted_content = NULL, embeds = NULL, attachments = '[]'
+				FROM target t
+				WHERE a.id = t.id AND a.created_at = t.created_at
+				RETURNING t.own, t.attachments
+			)
+			SELECT
+				COUNT(*) FILTER (WHERE own) AS own,
+				COUNT(*) FILTER (WHERE NOT own) AS forwards,
+				COALESCE(SUM(attachments), 0) AS attachments
+			FROM scrubbed
+		`, userID, userID.String(), limit-scrubbed)
+		if err != nil {
+			return counts, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.ContentScrubCounts{}, err
+	}
+	counts.Messages = len(own) + threadReplies + archived.Own
+	counts.Forwards = len(forwards) + archived.Forwards
+	counts.Attachments += archived.Attachments
+	return counts, nil
+}
+
+// CountUserContent counts the user's messages, thread replies and forwarded
+// copies that still hold content, archived or not
+func (r *ContentErasureRepository) CountUserContent(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, `
+		SELECT
+			(SELECT COUNT(*) FROM messages m WHERE m.author_id = $1 AND `+messageHasContent+`) +
+			(SELECT COUNT(*) FROM thread_messages WHERE author_id = $1 AND content <> '') +
+			(SELECT COUNT(*) FROM messages m
+			 WHERE m.forwarded_from IS NOT NULL AND m.forwarded_from->>'author_id' = $2 AND m.author_id <> $1 AND `+messageHasContent+`) +
+			(SELECT COUNT(*) FROM archived_messages
+			 WHERE (author_id = $1 OR (forwarded_from->>'author_id' = $2 AND author_id <> $1)) AND `+archivedMessageHasContent+`)
+	`, userID, userID.String())
+	return count, err
+}
+
+// SaveContentErasureProgress stores the erasure's status and counters and
+// releases it
+func (r *ContentErasureRepository) SaveContentErasureProgress(ctx context.Context, job *models.ContentErasure) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE content_erasures SET
+			status = $2, messages_scrubbed = $3, forwards_scrubbed = $4, attachments_removed = $5,
+			remaining = $6, error = $7, updated_at = $8, completed_at = $9, verified_at = $10,
+			locked_until = NULL
+		WHERE id = $1
+	`, job.ID, job.Status, job.MessagesScrubbed, job.ForwardsScrubbed, job.AttachmentsRemoved,
+		job.Remaining, job.Error, job.UpdatedAt, job.CompletedAt, job.VerifiedAt)
+	return err
+}
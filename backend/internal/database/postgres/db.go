@@ -2,12 +2,17 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
 	"embed"
 	"fmt"
 	"time"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+
+	"hearth/internal/database/instrument"
+	"hearth/internal/services"
 )
 
 //go:embed migrations/*.sql
@@ -59,9 +64,31 @@ func NewDBFromURL(databaseURL string) (*sqlx.DB, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 	
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	ConfigurePool(db, DefaultPoolConfig())
+	
+	return db, nil
+}
+
+// NewInstrumentedDBFromURL creates a database connection from URL whose
+// queries are reported to observer. wrap is applied to the driver connector
+// underneath the instrumentation, e.g. to inject faults, so what it adds is
+// measured too.
+func NewInstrumentedDBFromURL(databaseURL string, pool PoolConfig, observer instrument.Observer, wrap ...func(driver.Connector) driver.Connector) (*sqlx.DB, error) {
+	pqConnector, err := pq.NewConnector(databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database URL: %w", err)
+	}
+	
+	var connector driver.Connector = pqConnector
+	for _, w := range wrap {
+		connector = w(connector)
+	}
+	db := sqlx.NewDb(sql.OpenDB(instrument.WrapConnector(connector, observer)), "postgres")
+	ConfigurePool(db, pool)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
 	
 	return db, nil
 }
@@ -138,6 +165,50 @@ func Migrate(ctx context.Context, db *sqlx.DB) error {
 	return nil
 }
 
+// MigrationState compares the migrations built into this binary with the
+// ones recorded in the database
+type MigrationState struct {
+	Applied []string // Recorded and known to this build
+	Pending []string // Known to this build but not yet applied
+	Unknown []string // Recorded but not built in, e.g. after a rollback to an older build
+}
+
+// MigrationStatus reports which migrations have been applied, without
+// applying any
+func MigrationStatus(ctx context.Context, db *sqlx.DB) (*MigrationState, error) {
+	var recorded []string
+	if err := db.SelectContext(ctx, &recorded, `SELECT version FROM schema_migrations ORDER BY version`); err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	entries, err := migrationsFS.ReadDir("migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	known := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			known[entry.Name()] = true
+		}
+	}
+	applied := make(map[string]bool, len(recorded))
+	state := &MigrationState{}
+	for _, version := range recorded {
+		applied[version] = true
+		if known[version] {
+			state.Applied = append(state.Applied, version)
+		} else {
+			state.Unknown = append(state.Unknown, version)
+		}
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && !applied[entry.Name()] {
+			state.Pending = append(state.Pending, entry.Name())
+		}
+	}
+	return state, nil
+}
+
 // Repositories holds all database repositories
 type Repositories struct {
 	Users    *UserRepository
@@ -145,6 +216,43 @@ type Repositories struct {
 	Channels *ChannelRepository
 	Messages *MessageRepository
 	Roles    *RoleRepository
+
+	ReadStates *ReadStateRepository
+
+	PushDevices                 *PushDeviceRepository
+	ChannelNotificationSettings *ChannelNotificationSettingsRepository
+
+	AutoModRules         *AutoModRuleRepository
+	PermissionOverwrites *PermissionOverwriteRepository
+	ComplianceLog        *ComplianceLogRepository
+	Emojis               *EmojiRepository
+	Threads              *ThreadRepository
+	Forums               *ForumRepository
+	UsernameRules        *UsernameRuleRepository
+	ChannelFeeds         *ChannelFeedRepository
+	MemberVerification   *MemberVerificationRepository
+	ChannelSchedules     *ChannelScheduleRepository
+	Onboarding           *OnboardingRepository
+	BanImports           *BanImportRepository
+	Blocklists           *BlocklistRepository
+	Webhooks             *WebhookRepository
+	WebhookPolicies      *WebhookPolicyRepository
+	ServerTokens         *ServerTokenRepository
+	E2EE                 *E2EERepository
+	BotTiers             *BotTierRepository
+	Sessions             *SessionRepository
+	ContentErasures      *ContentErasureRepository
+	OAuthIdentities      *OAuthIdentityRepository
+	ServerThrottles      *ServerThrottleRepository
+	OAuthApps            *OAuthAppRepository
+	QuotaOverrides       *QuotaOverrideRepository
+	IPRules              *IPRuleRepository
+	LoginSecurity        *LoginSecurityRepository
+	PasswordResets       *PasswordResetRepository
+	UserSuspensions      *UserSuspensionRepository
+	Announcements        *AnnouncementRepository
+	Reports              *ReportRepository
+	MessageArchive       *MessageArchiveRepository
 }
 
 // NewRepositories creates all repositories
@@ -155,5 +263,55 @@ func NewRepositories(db *sqlx.DB) *Repositories {
 		Channels: NewChannelRepository(db),
 		Messages: NewMessageRepository(db),
 		Roles:    NewRoleRepository(db),
+
+		ReadStates: NewReadStateRepository(db),
+
+		PushDevices:                 NewPushDeviceRepository(db),
+		ChannelNotificationSettings: NewChannelNotificationSettingsRepository(db),
+
+		AutoModRules:         NewAutoModRuleRepository(db),
+		PermissionOverwrites: NewPermissionOverwriteRepository(db),
+		ComplianceLog:        NewComplianceLogRepository(db),
+		Emojis:               NewEmojiRepository(db),
+		Threads:              NewThreadRepository(db),
+		Forums:               NewForumRepository(db),
+		UsernameRules:        NewUsernameRuleRepository(db),
+		ChannelFeeds:         NewChannelFeedRepository(db),
+		MemberVerification:   NewMemberVerificationRepository(db),
+		ChannelSchedules:     NewChannelScheduleRepository(db),
+		Onboarding:           NewOnboardingRepository(db),
+		BanImports:           NewBanImportRepository(db),
+		Blocklists:           NewBlocklistRepository(db),
+		Webhooks:             NewWebhookRepository(db),
+		WebhookPolicies:      NewWebhookPolicyRepository(db),
+		ServerTokens:         NewServerTokenRepository(db),
+		E2EE:                 NewE2EERepository(db),
+		BotTiers:             NewBotTierRepository(db),
+		Sessions:             NewSessionRepository(db),
+		ContentErasures:      NewContentErasureRepository(db),
+		OAuthIdentities:      NewOAuthIdentityRepository(db),
+		ServerThrottles:      NewServerThrottleRepository(db),
+		OAuthApps:            NewOAuthAppRepository(db),
+		QuotaOverrides:       NewQuotaOverrideRepository(db),
+		IPRules:              NewIPRuleRepository(db),
+		LoginSecurity:        NewLoginSecurityRepository(db),
+		PasswordResets:       NewPasswordResetRepository(db),
+		UserSuspensions:      NewUserSuspensionRepository(db),
+		Announcements:        NewAnnouncementRepository(db),
+		Reports:              NewReportRepository(db),
+		MessageArchive:       NewMessageArchiveRepository(db),
+	}
+}
+
+// checkVersionedUpdate reports services.ErrVersionConflict when an update
+// guarded by a version matched no row
+func checkVersionedUpdate(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
 	}
+	if rows == 0 {
+		return services.ErrVersionConflict
+	}
+	return nil
 }
@@ -0,0 +1,186 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"hearth/internal/models"
+)
+
+// E2EERepository stores public device keys, channel key epochs and the
+// sender keys devices relay to each other
+type E2EERepository struct {
+	db *sqlx.DB
+}
+
+func NewE2EERepository(db *sqlx.DB) *E2EERepository {
+	return &E2EERepository{db: db}
+}
+
+const e2eeDeviceColumns = `
+	d.user_id, d.device_id, d.identity_key, d.signed_pre_key_id, d.signed_pre_key, d.signed_key_signature,
+	d.created_at, d.updated_at,
+	(SELECT COUNT(*) FROM e2ee_one_time_pre_keys k WHERE k.user_id = d.user_id AND k.device_id = d.device_id) AS pre_key_count`
+
+// GetDevices returns a user's devices, oldest first
+func (r *E2EERepository) GetDevices(ctx context.Context, userID uuid.UUID) ([]*models.E2EEDevice, error) {
+	devices := []*models.E2EEDevice{}
+	err := r.db.SelectContext(ctx, &devices, `
+		SELECT `+e2eeDeviceColumns+` FROM e2ee_devices d
+		WHERE d.user_id = $1
+		ORDER BY d.created_at, d.device_id
+	`, userID)
+	return devices, err
+}
+
+// GetDevice returns one of a user's devices, or nil if there's none
+func (r *E2EERepository) GetDevice(ctx context.Context, userID uuid.UUID, deviceID string) (*models.E2EEDevice, error) {
+	var device models.E2EEDevice
+	err := r.db.GetContext(ctx, &device, `
+		SELECT `+e2eeDeviceColumns+` FROM e2ee_devices d
+		WHERE d.user_id = $1 AND d.device_id = $2
+	`, userID, deviceID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// SaveDevice creates a device or updates its keys
+func (r *E2EERepository) SaveDevice(ctx context.Context, device *models.E2EEDevice) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO e2ee_devices (user_id, device_id, identity_key, signed_pre_key_id, signed_pre_key,
+			signed_key_signature, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (user_id, device_id) DO UPDATE SET
+			identity_key = EXCLUDED.identity_key,
+			signed_pre_key_id = EXCLUDED.signed_pre_key_id,
+			signed_pre_key = EXCLUDED.signed_pre_key,
+			signed_key_signature = EXCLUDED.signed_key_signature,
+			updated_at = EXCLUDED.updated_at
+	`, device.UserID, device.DeviceID, device.IdentityKey, device.SignedPreKeyID, device.SignedPreKey,
+		device.SignedKeySign, device.CreatedAt, device.UpdatedAt)
+	return err
+}
+
+// DeleteDevice removes a device, which takes its pre-keys and the sender
+// keys sent to it along, and reports whether the user had it
+func (r *E2EERepository) DeleteDevice(ctx context.Context, userID uuid.UUID, deviceID string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM e2ee_devices WHERE user_id = $1 AND device_id = $2`, userID, deviceID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// AddPreKeys stores one-time pre-keys, skipping IDs the device already has
+func (r *E2EERepository) AddPreKeys(ctx context.Context, userID uuid.UUID, deviceID string, keys []models.OneTimePreKey) error {
+	keyIDs := make([]int64, len(keys))
+	publicKeys := make([]string, len(keys))
+	for i, key := range keys {
+		keyIDs[i] = int64(key.KeyID)
+		publicKeys[i] = key.PublicKey
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO e2ee_one_time_pre_keys (user_id, device_id, key_id, public_key)
+		SELECT $1, $2, k.key_id, k.public_key
+		FROM unnest($3::integer[], $4::text[]) AS k(key_id, public_key)
+		ON CONFLICT (user_id, device_id, key_id) DO NOTHING
+	`, userID, deviceID, pq.Array(keyIDs), pq.Array(publicKeys))
+	return err
+}
+
+// ClaimPreKey removes and returns the device's lowest one-time pre-key,
+// or nil if it has none left. Concurrent claims get different keys.
+func (r *E2EERepository) ClaimPreKey(ctx context.Context, userID uuid.UUID, deviceID string) (*models.OneTimePreKey, error) {
+	var key models.OneTimePreKey
+	err := r.db.GetContext(ctx, &key, `
+		DELETE FROM e2ee_one_time_pre_keys
+		WHERE (user_id, device_id, key_id) = (
+			SELECT user_id, device_id, key_id FROM e2ee_one_time_pre_keys
+			WHERE user_id = $1 AND device_id = $2
+			ORDER BY key_id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING key_id, public_key
+	`, userID, deviceID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetEpoch returns a channel's key epoch, 0 if it was never rotated
+func (r *E2EERepository) GetEpoch(ctx context.Context, channelID uuid.UUID) (int64, error) {
+	var epoch int64
+	err := r.db.GetContext(ctx, &epoch, `SELECT epoch FROM channel_key_epochs WHERE channel_id = $1`, channelID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return epoch, err
+}
+
+// RotateEpoch moves a channel's key epoch on and returns the new one
+func (r *E2EERepository) RotateEpoch(ctx context.Context, channelID uuid.UUID, at time.Time) (int64, error) {
+	var epoch int64
+	err := r.db.GetContext(ctx, &epoch, `
+		INSERT INTO channel_key_epochs (channel_id, epoch, rotated_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (channel_id) DO UPDATE SET
+			epoch = channel_key_epochs.epoch + 1,
+			rotated_at = EXCLUDED.rotated_at
+		RETURNING epoch
+	`, channelID, at)
+	return epoch, err
+}
+
+// SaveSenderKeys stores sender keys, replacing what the same sender device
+// sent the same recipient device for the epoch
+func (r *E2EERepository) SaveSenderKeys(ctx context.Context, keys []*models.SenderKey) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, key := range keys {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO channel_sender_keys (channel_id, epoch, sender_id, sender_device_id,
+				recipient_id, recipient_device_id, ciphertext, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (channel_id, epoch, sender_id, sender_device_id, recipient_id, recipient_device_id)
+			DO UPDATE SET ciphertext = EXCLUDED.ciphertext, created_at = EXCLUDED.created_at
+		`, key.ChannelID, key.Epoch, key.SenderID, key.SenderDeviceID,
+			key.RecipientID, key.RecipientDeviceID, key.Ciphertext, key.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetSenderKeys returns the sender keys sent to a device in a channel,
+// oldest epoch first
+func (r *E2EERepository) GetSenderKeys(ctx context.Context, channelID, recipientID uuid.UUID, recipientDeviceID string) ([]*models.SenderKey, error) {
+	keys := []*models.SenderKey{}
+	err := r.db.SelectContext(ctx, &keys, `
+		SELECT channel_id, epoch, sender_id, sender_device_id, recipient_id, recipient_device_id, ciphertext, created_at
+		FROM channel_sender_keys
+		WHERE channel_id = $1 AND recipient_id = $2 AND recipient_device_id = $3
+		ORDER BY epoch, created_at
+	`, channelID, recipientID, recipientDeviceID)
+	return keys, err
+}
@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"hearth/internal/models"
+)
+
+// EmojiRepository handles custom emoji database operations
+type EmojiRepository struct {
+	db *sqlx.DB
+}
+
+// NewEmojiRepository creates a new emoji repository
+func NewEmojiRepository(db *sqlx.DB) *EmojiRepository {
+	return &EmojiRepository{db: db}
+}
+
+const emojiColumns = `id, server_id, creator_id, name, animated, url, path, size, created_at`
+
+// Create stores a new emoji
+func (r *EmojiRepository) Create(ctx context.Context, emoji *models.Emoji) error {
+	query := `
+		INSERT INTO emojis (` + emojiColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		emoji.ID, emoji.ServerID, emoji.CreatorID, emoji.Name, emoji.Animated,
+		emoji.URL, emoji.Path, emoji.Size, emoji.CreatedAt,
+	)
+	return err
+}
+
+// GetByID returns an emoji, or nil if there's no such emoji
+func (r *EmojiRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Emoji, error) {
+	var emoji models.Emoji
+	query := `SELECT ` + emojiColumns + ` FROM emojis WHERE id = $1`
+	err := r.db.GetContext(ctx, &emoji, query, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &emoji, nil
+}
+
+// GetByIDs returns the emoji that still exist out of ids
+func (r *EmojiRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Emoji, error) {
+	if len(ids) == 0 {
+		return []*models.Emoji{}, nil
+	}
+
+	query, args, err := sqlx.In(`SELECT `+emojiColumns+` FROM emojis WHERE id IN (?)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	query = r.db.Rebind(query)
+
+	var emojis []*models.Emoji
+	err = r.db.SelectContext(ctx, &emojis, query, args...)
+	return emojis, err
+}
+
+// GetByServerID returns a server's emoji, oldest first
+func (r *EmojiRepository) GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*models.Emoji, error) {
+	var emojis []*models.Emoji
+	query := `SELECT ` + emojiColumns + ` FROM emojis WHERE server_id = $1 ORDER BY created_at, id`
+	err := r.db.SelectContext(ctx, &emojis, query, serverID)
+	return emojis, err
+}
+
+// Update saves an emoji's name
+func (r *EmojiRepository) Update(ctx context.Context, emoji *models.Emoji) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE emojis SET name = $2 WHERE id = $1`, emoji.ID, emoji.Name)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Delete removes an emoji and every reaction made with it
+func (r *EmojiRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM reactions WHERE emoji = $1`, id.String()); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM emojis WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
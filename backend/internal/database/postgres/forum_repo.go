@@ -0,0 +1,187 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"hearth/internal/models"
+)
+
+// ForumRepository handles forum channel settings, tags and posts. Posts
+// are rows in threads, so they share the thread tables with ThreadRepository.
+type ForumRepository struct {
+	db *sqlx.DB
+}
+
+// NewForumRepository creates a new forum repository
+func NewForumRepository(db *sqlx.DB) *ForumRepository {
+	return &ForumRepository{db: db}
+}
+
+const forumPostColumns = `t.id, t.parent_channel_id, t.owner_id, t.name, t.message_count, t.member_count,
+	t.archived, t.auto_archive, t.locked, t.created_at, t.archive_timestamp`
+
+// GetSettings returns a forum's settings and tags, or the defaults if the
+// forum was never configured
+func (r *ForumRepository) GetSettings(ctx context.Context, channelID uuid.UUID) (*models.ForumSettings, error) {
+	settings := models.DefaultForumSettings(channelID)
+	err := r.db.GetContext(ctx, settings,
+		`SELECT channel_id, default_sort_order, require_tag FROM forum_settings WHERE channel_id = $1`, channelID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	err = r.db.SelectContext(ctx, &settings.Tags,
+		`SELECT id, channel_id, name, emoji, position FROM forum_tags WHERE channel_id = $1 ORDER BY position, id`, channelID)
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// SaveSettings stores a forum's settings and replaces its tags. Tags that
+// are no longer listed are deleted, and with them their place on posts.
+func (r *ForumRepository) SaveSettings(ctx context.Context, settings *models.ForumSettings) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO forum_settings (channel_id, default_sort_order, require_tag)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (channel_id) DO UPDATE SET
+			default_sort_order = EXCLUDED.default_sort_order,
+			require_tag = EXCLUDED.require_tag
+	`, settings.ChannelID, settings.DefaultSortOrder, settings.RequireTag)
+	if err != nil {
+		return err
+	}
+
+	keep := make([]uuid.UUID, len(settings.Tags))
+	for i, tag := range settings.Tags {
+		keep[i] = tag.ID
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM forum_tags WHERE channel_id = $1 AND NOT (id = ANY($2))`,
+		settings.ChannelID, pq.Array(keep))
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range settings.Tags {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO forum_tags (id, channel_id, name, emoji, position)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (id) DO UPDATE SET
+				name = EXCLUDED.name,
+				emoji = EXCLUDED.emoji,
+				position = EXCLUDED.position
+		`, tag.ID, settings.ChannelID, tag.Name, tag.Emoji, tag.Position)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// CreatePost creates a post's thread, its first message and its tags in
+// one transaction
+func (r *ForumRepository) CreatePost(ctx context.Context, thread *models.Thread, message *models.ThreadMessage) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertThread(ctx, tx, thread); err != nil {
+		return err
+	}
+	if err := insertThreadMessage(ctx, tx, message); err != nil {
+		return err
+	}
+	for _, tagID := range thread.AppliedTags {
+		_, err := tx.ExecContext(ctx, `INSERT INTO thread_tags (thread_id, tag_id) VALUES ($1, $2)`, thread.ID, tagID)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListPosts returns a page of a forum's posts with their tags and first
+// messages. Latest activity is the newest message, or the creation time of
+// posts without one.
+func (r *ForumRepository) ListPosts(ctx context.Context, channelID uuid.UUID, q models.ForumPostQuery) ([]*models.ForumPost, error) {
+	query := `
+		SELECT ` + forumPostColumns + `,
+			COALESCE((SELECT MAX(m.created_at) FROM thread_messages m WHERE m.thread_id = t.id), t.created_at) AS last_activity_at
+		FROM threads t
+		WHERE t.parent_channel_id = $1 AND t.archived = $2
+			AND (cardinality($3::uuid[]) = 0 OR EXISTS (
+				SELECT 1 FROM thread_tags tt WHERE tt.thread_id = t.id AND tt.tag_id = ANY($3)
+			))
+	`
+	if q.Sort == models.ForumSortCreationDate {
+		query += ` ORDER BY t.created_at DESC, t.id`
+	} else {
+		query += ` ORDER BY last_activity_at DESC, t.id`
+	}
+	query += ` LIMIT $4 OFFSET $5`
+
+	tagIDs := q.TagIDs
+	if tagIDs == nil {
+		tagIDs = []uuid.UUID{}
+	}
+	posts := []*models.ForumPost{}
+	if err := r.db.SelectContext(ctx, &posts, query, channelID, q.Archived, pq.Array(tagIDs), q.Limit, q.Offset); err != nil {
+		return nil, err
+	}
+	if len(posts) == 0 {
+		return posts, nil
+	}
+
+	byID := make(map[uuid.UUID]*models.ForumPost, len(posts))
+	ids := make([]uuid.UUID, len(posts))
+	for i, post := range posts {
+		byID[post.ID] = post
+		ids[i] = post.ID
+		post.AppliedTags = []uuid.UUID{}
+	}
+
+	var applied []struct {
+		ThreadID uuid.UUID `db:"thread_id"`
+		TagID    uuid.UUID `db:"tag_id"`
+	}
+	err := r.db.SelectContext(ctx, &applied, `
+		SELECT tt.thread_id, tt.tag_id FROM thread_tags tt
+		JOIN forum_tags ft ON ft.id = tt.tag_id
+		WHERE tt.thread_id = ANY($1)
+		ORDER BY ft.position, ft.id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range applied {
+		byID[a.ThreadID].AppliedTags = append(byID[a.ThreadID].AppliedTags, a.TagID)
+	}
+
+	var first []*models.ThreadMessage
+	err = r.db.SelectContext(ctx, &first, `
+		SELECT DISTINCT ON (thread_id) id, thread_id, author_id, content, created_at, edited_at
+		FROM thread_messages
+		WHERE thread_id = ANY($1)
+		ORDER BY thread_id, created_at
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range first {
+		byID[msg.ThreadID].FirstMessage = msg
+	}
+	return posts, nil
+}
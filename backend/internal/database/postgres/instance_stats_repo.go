@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"hearth/internal/models"
+)
+
+// InstanceStatsRepository counts rows across the instance for operators
+type InstanceStatsRepository struct {
+	db *sqlx.DB
+}
+
+func NewInstanceStatsRepository(db *sqlx.DB) *InstanceStatsRepository {
+	return &InstanceStatsRepository{db: db}
+}
+
+// Stats counts users, bots, servers, channels, active sessions and
+// suspended users, and
+// estimates messages from the planner's statistics
+func (r *InstanceStatsRepository) Stats(ctx context.Context) (*models.InstanceStats, error) {
+	var stats models.InstanceStats
+	err := r.db.GetContext(ctx, &stats, `
+		SELECT
+			(SELECT COUNT(*) FROM users WHERE flags & $1 = 0) AS users,
+			(SELECT COUNT(*) FROM users WHERE flags & $1 <> 0) AS bots,
+			(SELECT COUNT(*) FROM servers) AS servers,
+			(SELECT COUNT(*) FROM channels) AS channels,
+			(SELECT GREATEST(reltuples, 0)::BIGINT FROM pg_class WHERE oid = 'messages'::regclass) AS messages,
+			(SELECT COUNT(*) FROM sessions WHERE expires_at > NOW()) AS active_sessions,
+			(SELECT COUNT(*) FROM user_suspensions WHERE expires_at IS NULL OR expires_at > NOW()) AS suspended_users
+	`, models.UserFlagBot|models.UserFlagSystemBot)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
@@ -0,0 +1,1999 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+	"hearth/internal/testenv"
+)
+
+func TestMain(m *testing.M) {
+	testenv.Main(m, testenv.Postgres)
+}
+
+// migratedDB returns an empty database with every migration applied
+func migratedDB(t *testing.T) *sqlx.DB {
+	db := testenv.DB(t)
+	require.NoError(t, Migrate(context.Background(), db))
+	return db
+}
+
+func createUser(t *testing.T, db *sqlx.DB) *models.User {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	id := uuid.New()
+	user := &models.User{
+		ID:            id,
+		Username:      "user" + id.String()[:8],
+		Discriminator: "0001",
+		Email:         id.String() + "@example.com",
+		PasswordHash:  "hash",
+		Status:        models.StatusOffline,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	require.NoError(t, NewUserRepository(db).Create(context.Background(), user))
+	return user
+}
+
+func createServer(t *testing.T, db *sqlx.DB, owner uuid.UUID) *models.Server {
+	now := time.Now()
+	server := &models.Server{ID: uuid.New(), Name: "test", OwnerID: owner, CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, NewServerRepository(db).Create(context.Background(), server))
+	return server
+}
+
+func createChannel(t *testing.T, db *sqlx.DB, serverID uuid.UUID, position int) *models.Channel {
+	channel := &models.Channel{
+		ID:        uuid.New(),
+		ServerID:  &serverID,
+		Name:      "general",
+		Type:      models.ChannelTypeText,
+		Position:  position,
+		CreatedAt: time.Now(),
+	}
+	require.NoError(t, NewChannelRepository(db).Create(context.Background(), channel))
+	return channel
+}
+
+// insertMessage writes a message row directly so fixtures can be backdated
+func insertMessage(t *testing.T, db *sqlx.DB, channelID, authorID uuid.UUID, createdAt time.Time) uuid.UUID {
+	id := uuid.New()
+	_, err := db.Exec(`INSERT INTO messages (id, channel_id, author_id, content, created_at) VALUES ($1, $2, $3, 'hi', $4)`,
+		id, channelID, authorID, createdAt)
+	require.NoError(t, err)
+	return id
+}
+
+func countRows(t *testing.T, db *sqlx.DB, query string, args ...interface{}) int {
+	var n int
+	require.NoError(t, db.Get(&n, query, args...))
+	return n
+}
+
+func TestMigrate_AppliesEveryMigrationOnce(t *testing.T) {
+	db := migratedDB(t)
+	ctx := context.Background()
+
+	entries, err := migrationsFS.ReadDir("migrations")
+	require.NoError(t, err)
+	assert.Equal(t, len(entries), countRows(t, db, `SELECT COUNT(*) FROM schema_migrations`))
+
+	// A second run finds nothing to do
+	require.NoError(t, Migrate(ctx, db))
+	assert.Equal(t, len(entries), countRows(t, db, `SELECT COUNT(*) FROM schema_migrations`))
+}
+
+func TestUserRepository_RoundTrip(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+	user := createUser(t, db)
+
+	got, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, user.Username, got.Username)
+	assert.Equal(t, user.Email, got.Email)
+
+	byEmail, err := repo.GetByEmail(ctx, user.Email)
+	require.NoError(t, err)
+	require.NotNil(t, byEmail)
+	assert.Equal(t, user.ID, byEmail.ID)
+
+	missing, err := repo.GetByID(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestChannelRepository_UpdateVersionConflict(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewChannelRepository(db)
+	ctx := context.Background()
+	owner := createUser(t, db)
+	server := createServer(t, db, owner.ID)
+	channel := createChannel(t, db, server.ID, 0)
+
+	stale := *channel
+	channel.Name = "renamed"
+	require.NoError(t, repo.Update(ctx, channel))
+	assert.Equal(t, 2, channel.Version)
+
+	stale.Name = "lost update"
+	assert.ErrorIs(t, repo.Update(ctx, &stale), services.ErrVersionConflict)
+	assert.Equal(t, 1, countRows(t, db, `SELECT COUNT(*) FROM channels WHERE name = 'renamed'`))
+}
+
+func TestMessageRepository_BulkDelete(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewMessageRepository(db)
+	ctx := context.Background()
+	author := createUser(t, db)
+	moderator := createUser(t, db)
+	server := createServer(t, db, author.ID)
+	channel := createChannel(t, db, server.ID, 0)
+	other := createChannel(t, db, server.ID, 1)
+
+	now := time.Now()
+	recent := []uuid.UUID{
+		insertMessage(t, db, channel.ID, author.ID, now.Add(-time.Hour)),
+		insertMessage(t, db, channel.ID, author.ID, now.Add(-time.Minute)),
+	}
+	old := insertMessage(t, db, channel.ID, author.ID, now.Add(-15*24*time.Hour))
+	elsewhere := insertMessage(t, db, other.ID, author.ID, now)
+	notBefore := now.Add(-services.BulkDeleteMaxAge)
+
+	// One message too old and nothing is deleted
+	_, err := repo.BulkDelete(ctx, channel.ID, append(recent, old), notBefore, moderator.ID, true)
+	assert.ErrorIs(t, err, services.ErrMessagesTooOld)
+	assert.Equal(t, 3, countRows(t, db, `SELECT COUNT(*) FROM messages WHERE channel_id = $1`, channel.ID))
+	assert.Zero(t, countRows(t, db, `SELECT COUNT(*) FROM message_tombstones`))
+
+	// Messages from other channels are skipped
+	deleted, err := repo.BulkDelete(ctx, channel.ID, append(recent, elsewhere), notBefore, moderator.ID, true)
+	require.NoError(t, err)
+	assert.Len(t, deleted, 2)
+	assert.Equal(t, 1, countRows(t, db, `SELECT COUNT(*) FROM messages WHERE channel_id = $1`, channel.ID))
+	assert.Equal(t, 1, countRows(t, db, `SELECT COUNT(*) FROM messages WHERE id = $1`, elsewhere))
+
+	tombstones, err := repo.GetChannelTombstones(ctx, channel.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, tombstones, 2)
+	for _, ts := range tombstones {
+		assert.Contains(t, recent, ts.MessageID)
+		assert.Equal(t, author.ID, ts.AuthorID)
+		assert.Equal(t, moderator.ID, ts.DeletedBy)
+	}
+}
+
+func TestMessageRepository_PurgeTombstones(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewMessageRepository(db)
+	ctx := context.Background()
+	author := createUser(t, db)
+	server := createServer(t, db, author.ID)
+	channel := createChannel(t, db, server.ID, 0)
+
+	ids := []uuid.UUID{
+		insertMessage(t, db, channel.ID, author.ID, time.Now()),
+		insertMessage(t, db, channel.ID, author.ID, time.Now()),
+	}
+	_, err := repo.BulkDelete(ctx, channel.ID, ids, time.Now().Add(-time.Hour), author.ID, true)
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE message_tombstones SET deleted_at = NOW() - INTERVAL '4 days' WHERE message_id = $1`, ids[0])
+	require.NoError(t, err)
+
+	purged, err := repo.PurgeTombstones(ctx, time.Now().Add(-72*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	assert.Equal(t, 1, countRows(t, db, `SELECT COUNT(*) FROM message_tombstones WHERE message_id = $1`, ids[1]))
+}
+
+func TestMessageRepository_GetReplies(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewMessageRepository(db)
+	ctx := context.Background()
+	author := createUser(t, db)
+	server := createServer(t, db, author.ID)
+	channel := createChannel(t, db, server.ID, 0)
+
+	now := time.Now().Add(-time.Hour)
+	reply := func(to uuid.UUID, at time.Duration) uuid.UUID {
+		id := insertMessage(t, db, channel.ID, author.ID, now.Add(at))
+		_, err := db.Exec(`UPDATE messages SET reply_to_id = $2 WHERE id = $1`, id, to)
+		require.NoError(t, err)
+		return id
+	}
+	root := insertMessage(t, db, channel.ID, author.ID, now)
+	first := reply(root, time.Minute)
+	second := reply(root, 2*time.Minute)
+	nested := reply(first, 3*time.Minute)
+	deeper := reply(nested, 4*time.Minute)
+	unrelated := insertMessage(t, db, channel.ID, author.ID, now.Add(5*time.Minute))
+	reply(unrelated, 6*time.Minute)
+
+	ids := func(messages []*models.Message) []uuid.UUID {
+		var result []uuid.UUID
+		for _, m := range messages {
+			result = append(result, m.ID)
+		}
+		return result
+	}
+
+	page, err := repo.GetReplies(ctx, root, nil, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{first, second, nested}, ids(page), "descendants come oldest first")
+	page, err = repo.GetReplies(ctx, root, &nested, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{deeper}, ids(page))
+
+	page, err = repo.GetReplies(ctx, first, nil, 50)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{nested, deeper}, ids(page))
+	page, err = repo.GetReplies(ctx, deeper, nil, 50)
+	require.NoError(t, err)
+	assert.Empty(t, page)
+}
+
+func TestIntegrityRepository_ChannelPositions(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewIntegrityRepository(db)
+	ctx := context.Background()
+	owner := createUser(t, db)
+	drifted := createServer(t, db, owner.ID)
+	clean := createServer(t, db, owner.ID)
+
+	first := createChannel(t, db, drifted.ID, 0)
+	second := createChannel(t, db, drifted.ID, 5)
+	third := createChannel(t, db, drifted.ID, 5)
+	createChannel(t, db, clean.ID, 0)
+	createChannel(t, db, clean.ID, 1)
+
+	servers, err := repo.ServersWithChannelPositionDrift(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{drifted.ID}, servers)
+
+	moved, err := repo.CompactChannelPositions(ctx, drifted.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, moved)
+
+	servers, err = repo.ServersWithChannelPositionDrift(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, servers)
+
+	// Order is kept and only moved channels get a new version
+	var rows []struct {
+		ID       uuid.UUID `db:"id"`
+		Position int       `db:"position"`
+		Version  int       `db:"version"`
+	}
+	require.NoError(t, db.Select(&rows, `SELECT id, position, version FROM channels WHERE server_id = $1 ORDER BY position`, drifted.ID))
+	require.Len(t, rows, 3)
+	assert.Equal(t, first.ID, rows[0].ID)
+	assert.Equal(t, 1, rows[0].Version)
+	assert.ElementsMatch(t, []uuid.UUID{second.ID, third.ID}, []uuid.UUID{rows[1].ID, rows[2].ID})
+	assert.Equal(t, []int{0, 1, 2}, []int{rows[0].Position, rows[1].Position, rows[2].Position})
+	assert.Equal(t, 2, rows[1].Version)
+}
+
+func TestIntegrityRepository_OrphanedMembers(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewIntegrityRepository(db)
+	ctx := context.Background()
+	owner := createUser(t, db)
+	server := createServer(t, db, owner.ID)
+
+	// Orphans only appear when foreign keys were bypassed, as a restore
+	// with triggers disabled does
+	tx, err := db.Beginx()
+	require.NoError(t, err)
+	_, err = tx.Exec(`SET LOCAL session_replication_role = replica`)
+	require.NoError(t, err)
+	_, err = tx.Exec(`INSERT INTO members (server_id, user_id) VALUES ($1, $2), ($1, $3)`, server.ID, owner.ID, uuid.New())
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	found, err := repo.CountOrphanedMembers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, found)
+
+	removed, err := repo.DeleteOrphanedMembers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, 1, countRows(t, db, `SELECT COUNT(*) FROM members WHERE server_id = $1`, server.ID))
+}
+
+func TestServerRepository_ModerationExpiry(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewServerRepository(db)
+	ctx := context.Background()
+	owner := createUser(t, db)
+	banned := createUser(t, db)
+	permanent := createUser(t, db)
+	timedOut := createUser(t, db)
+	server := createServer(t, db, owner.ID)
+
+	now := time.Now()
+	lapsed := now.Add(-time.Minute)
+	pending := now.Add(time.Hour)
+	require.NoError(t, repo.AddBan(ctx, &models.Ban{ServerID: server.ID, UserID: banned.ID, BannedBy: &owner.ID, CreatedAt: now, ExpiresAt: &lapsed}))
+	require.NoError(t, repo.AddBan(ctx, &models.Ban{ServerID: server.ID, UserID: permanent.ID, BannedBy: &owner.ID, CreatedAt: now}))
+
+	require.NoError(t, repo.AddMember(ctx, &models.Member{ServerID: server.ID, UserID: timedOut.ID, JoinedAt: now}))
+	require.NoError(t, repo.SetMemberTimeout(ctx, server.ID, timedOut.ID, &pending))
+	member, err := repo.GetMember(ctx, server.ID, timedOut.ID)
+	require.NoError(t, err)
+	require.NotNil(t, member.CommunicationDisabledUntil)
+	assert.WithinDuration(t, pending, *member.CommunicationDisabledUntil, time.Millisecond)
+
+	// Only the lapsed ban goes; the timeout is still pending
+	bans, err := repo.RemoveExpiredBans(ctx, now)
+	require.NoError(t, err)
+	require.Len(t, bans, 1)
+	assert.Equal(t, banned.ID, bans[0].UserID)
+	remaining, err := repo.GetBans(ctx, server.ID)
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	assert.Nil(t, remaining[0].ExpiresAt)
+
+	members, err := repo.ClearExpiredTimeouts(ctx, now)
+	require.NoError(t, err)
+	assert.Empty(t, members)
+
+	members, err = repo.ClearExpiredTimeouts(ctx, pending)
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, timedOut.ID, members[0].UserID)
+	assert.Nil(t, members[0].CommunicationDisabledUntil)
+}
+
+func TestAutoModRuleRepository_RoundTrip(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewAutoModRuleRepository(db)
+	ctx := context.Background()
+	owner := createUser(t, db)
+	server := createServer(t, db, owner.ID)
+	alerts := createChannel(t, db, server.ID, 0)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	rule := &models.AutoModRule{
+		ID:              uuid.New(),
+		ServerID:        server.ID,
+		CreatorID:       owner.ID,
+		Name:            "No slurs",
+		TriggerType:     models.AutoModTriggerKeyword,
+		TriggerMetadata: models.AutoModTriggerMetadata{Keywords: []string{"bad*"}, AllowList: []string{"badminton"}},
+		Actions: []models.AutoModAction{
+			{Type: models.AutoModActionBlock},
+			{Type: models.AutoModActionAlert, ChannelID: &alerts.ID},
+		},
+		Enabled:        true,
+		ExemptRoles:    []uuid.UUID{uuid.New()},
+		ExemptChannels: []uuid.UUID{},
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	require.NoError(t, repo.Create(ctx, rule))
+
+	got, err := repo.GetByID(ctx, rule.ID)
+	require.NoError(t, err)
+	assert.Equal(t, rule.TriggerMetadata, got.TriggerMetadata)
+	assert.Equal(t, rule.Actions, got.Actions)
+	assert.Equal(t, rule.ExemptRoles, got.ExemptRoles)
+	assert.Empty(t, got.ExemptChannels)
+
+	rule.Enabled = false
+	rule.TriggerMetadata.Keywords = []string{"worse"}
+	require.NoError(t, repo.Update(ctx, rule))
+	rules, err := repo.GetByServerID(ctx, server.ID)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.False(t, rules[0].Enabled)
+	assert.Equal(t, []string{"worse"}, rules[0].TriggerMetadata.Keywords)
+
+	require.NoError(t, repo.Delete(ctx, rule.ID))
+	got, err = repo.GetByID(ctx, rule.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestComplianceLogRepository_AppendOnly(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewComplianceLogRepository(db)
+	ctx := context.Background()
+	actor := uuid.New()
+
+	old := &models.ComplianceLogEntry{
+		ID: uuid.New(), Method: "POST", Route: "/api/v1/servers", ActorID: &actor,
+		Targets: map[string]string{}, Status: 201, Outcome: models.ComplianceOutcomeSuccess,
+		CreatedAt: time.Now().Add(-48 * time.Hour),
+	}
+	recent := &models.ComplianceLogEntry{
+		ID: uuid.New(), Method: "DELETE", Route: "/api/v1/channels/:id",
+		Targets: map[string]string{"id": uuid.NewString()}, Status: 403, Outcome: models.ComplianceOutcomeDenied,
+		CreatedAt: time.Now(),
+	}
+	require.NoError(t, repo.Append(ctx, []*models.ComplianceLogEntry{old, recent}))
+
+	_, err := db.Exec(`UPDATE compliance_log SET status = 200 WHERE id = $1`, recent.ID)
+	assert.Error(t, err, "entries can't be rewritten")
+	_, err = db.Exec(`DELETE FROM compliance_log WHERE id = $1`, recent.ID)
+	assert.Error(t, err, "entries can only be deleted by the purge")
+	_, err = db.Exec(`TRUNCATE compliance_log`)
+	assert.Error(t, err)
+
+	var exported []*models.ComplianceLogEntry
+	require.NoError(t, repo.Export(ctx, models.ComplianceLogFilter{ActorID: &actor}, func(e *models.ComplianceLogEntry) error {
+		exported = append(exported, e)
+		return nil
+	}))
+	require.Len(t, exported, 1)
+	assert.Equal(t, old.ID, exported[0].ID)
+
+	purged, err := repo.Purge(ctx, time.Now().Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, purged, 1)
+	assert.Equal(t, 0, countRows(t, db, `SELECT COUNT(*) FROM compliance_log WHERE id = $1`, old.ID))
+	assert.Equal(t, 1, countRows(t, db, `SELECT COUNT(*) FROM compliance_log WHERE id = $1`, recent.ID))
+}
+
+func TestThreadRepository_RoundTrip(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewThreadRepository(db)
+	ctx := context.Background()
+
+	owner := createUser(t, db)
+	other := createUser(t, db)
+	server := createServer(t, db, owner.ID)
+	channel := createChannel(t, db, server.ID, 0)
+
+	thread := &models.Thread{
+		ID: uuid.New(), ParentChannelID: channel.ID, OwnerID: owner.ID, Name: "release plan",
+		MemberCount: 1, AutoArchive: models.AutoArchive24Hour, CreatedAt: time.Now(),
+	}
+	require.NoError(t, repo.Create(ctx, thread))
+	isMember, err := repo.IsMember(ctx, thread.ID, owner.ID)
+	require.NoError(t, err)
+	assert.True(t, isMember, "the owner joins their thread")
+
+	// Joining twice only counts once
+	require.NoError(t, repo.AddMember(ctx, thread.ID, other.ID))
+	require.NoError(t, repo.AddMember(ctx, thread.ID, other.ID))
+	first, err := repo.CreateMessage(ctx, thread.ID, other.ID, "first")
+	require.NoError(t, err)
+	_, err = repo.CreateMessage(ctx, thread.ID, owner.ID, "second")
+	require.NoError(t, err)
+
+	got, err := repo.GetByID(ctx, thread.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.MemberCount)
+	assert.Equal(t, 2, got.MessageCount)
+
+	messages, err := repo.GetMessages(ctx, thread.ID, nil, 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "second", messages[0].Content)
+
+	require.NoError(t, repo.Archive(ctx, thread.ID))
+	active, err := repo.GetActiveByChannelID(ctx, channel.ID)
+	require.NoError(t, err)
+	assert.Empty(t, active)
+	all, err := repo.GetByChannelID(ctx, channel.ID)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.NotNil(t, all[0].ArchiveTimestamp)
+
+	require.NoError(t, repo.RemoveMember(ctx, thread.ID, other.ID))
+	require.NoError(t, repo.RemoveMember(ctx, thread.ID, other.ID))
+	got, err = repo.GetByID(ctx, thread.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.MemberCount)
+
+	require.NoError(t, repo.Delete(ctx, thread.ID))
+	got, err = repo.GetByID(ctx, thread.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	assert.Equal(t, 0, countRows(t, db, `SELECT COUNT(*) FROM thread_messages WHERE id = $1`, first.ID))
+}
+
+func TestPushDeviceRepository_TokenPerAccount(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewPushDeviceRepository(db)
+	ctx := context.Background()
+
+	primary := createUser(t, db)
+	alt := createUser(t, db)
+	now := time.Now()
+	register := func(userID uuid.UUID) *models.PushDevice {
+		device := &models.PushDevice{
+			ID: uuid.New(), UserID: userID, Platform: models.PushPlatformFCM, Token: "shared-device-token",
+			CreatedAt: now, LastSeenAt: now,
+		}
+		require.NoError(t, repo.Upsert(ctx, device))
+		return device
+	}
+
+	// Both accounts on the device keep their registration
+	first := register(primary.ID)
+	register(alt.ID)
+	assert.Equal(t, first.ID, register(primary.ID).ID, "re-registering refreshes the existing row")
+	assert.Equal(t, 2, countRows(t, db, `SELECT COUNT(*) FROM push_devices WHERE token = $1`, "shared-device-token"))
+
+	require.NoError(t, repo.DeleteByToken(ctx, "shared-device-token"))
+	devices, err := repo.GetByUserID(ctx, alt.ID)
+	require.NoError(t, err)
+	assert.Empty(t, devices)
+}
+
+func TestForumRepository_PostsAndTags(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewForumRepository(db)
+	ctx := context.Background()
+
+	owner := createUser(t, db)
+	server := createServer(t, db, owner.ID)
+	forum := createChannel(t, db, server.ID, 0)
+
+	settings, err := repo.GetSettings(ctx, forum.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ForumSortLatestActivity, settings.DefaultSortOrder, "unconfigured forums get the defaults")
+
+	bug := models.ForumTag{ID: uuid.New(), ChannelID: forum.ID, Name: "bug", Position: 0}
+	idea := models.ForumTag{ID: uuid.New(), ChannelID: forum.ID, Name: "idea", Position: 1}
+	settings.RequireTag = true
+	settings.Tags = []models.ForumTag{bug, idea}
+	require.NoError(t, repo.SaveSettings(ctx, settings))
+
+	post := func(name string, createdAt time.Time, tags ...uuid.UUID) *models.Thread {
+		thread := &models.Thread{
+			ID: uuid.New(), ParentChannelID: forum.ID, OwnerID: owner.ID, Name: name,
+			MemberCount: 1, AutoArchive: models.AutoArchive24Hour, CreatedAt: createdAt, AppliedTags: tags,
+		}
+		message := &models.ThreadMessage{ID: uuid.New(), ThreadID: thread.ID, AuthorID: owner.ID, Content: name, CreatedAt: createdAt}
+		require.NoError(t, repo.CreatePost(ctx, thread, message))
+		return thread
+	}
+	now := time.Now()
+	older := post("older", now.Add(-time.Hour), bug.ID)
+	newer := post("newer", now.Add(-time.Minute), idea.ID)
+
+	// A reply bumps the older post to the top of latest activity
+	_, err = NewThreadRepository(db).CreateMessage(ctx, older.ID, owner.ID, "bump")
+	require.NoError(t, err)
+
+	posts, err := repo.ListPosts(ctx, forum.ID, models.ForumPostQuery{Sort: models.ForumSortLatestActivity, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, posts, 2)
+	assert.Equal(t, older.ID, posts[0].ID)
+	assert.Equal(t, 2, posts[0].MessageCount)
+	assert.Equal(t, []uuid.UUID{bug.ID}, posts[0].AppliedTags)
+	assert.Equal(t, "older", posts[0].FirstMessage.Content)
+
+	posts, err = repo.ListPosts(ctx, forum.ID, models.ForumPostQuery{Sort: models.ForumSortCreationDate, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, posts, 2)
+	assert.Equal(t, newer.ID, posts[0].ID)
+
+	posts, err = repo.ListPosts(ctx, forum.ID, models.ForumPostQuery{TagIDs: []uuid.UUID{idea.ID}, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+	assert.Equal(t, newer.ID, posts[0].ID)
+
+	// Dropping a tag takes it off its posts
+	settings.Tags = []models.ForumTag{bug}
+	require.NoError(t, repo.SaveSettings(ctx, settings))
+	got, err := repo.GetSettings(ctx, forum.ID)
+	require.NoError(t, err)
+	assert.True(t, got.RequireTag)
+	require.Len(t, got.Tags, 1)
+	assert.Equal(t, bug.ID, got.Tags[0].ID)
+	assert.Equal(t, 0, countRows(t, db, `SELECT COUNT(*) FROM thread_tags WHERE tag_id = $1`, idea.ID))
+}
+
+func TestUserRepository_Usernames(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+	alice := createUser(t, db)
+	bob := createUser(t, db)
+
+	at := time.Now().UTC().Truncate(time.Microsecond)
+	require.NoError(t, repo.ChangeUsername(ctx, alice.ID, alice.Username, "Alice", at))
+
+	got, err := repo.GetByUsername(ctx, "alice")
+	require.NoError(t, err)
+	require.NotNil(t, got, "lookups ignore case")
+	assert.Equal(t, alice.ID, got.ID)
+	require.NotNil(t, got.UsernameChangedAt)
+	assert.True(t, at.Equal(*got.UsernameChangedAt))
+
+	history, err := repo.GetUsernameHistory(ctx, alice.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, alice.Username, history[0].Username)
+
+	err = repo.ChangeUsername(ctx, bob.ID, bob.Username, "ALICE", at)
+	assert.ErrorIs(t, err, services.ErrUsernameTaken)
+	assert.Equal(t, 0, countRows(t, db, `SELECT COUNT(*) FROM username_history WHERE user_id = $1`, bob.ID), "a failed rename records nothing")
+
+	bob.Username = "alice"
+	assert.ErrorIs(t, repo.Update(ctx, bob), services.ErrUsernameTaken)
+
+	taken, err := repo.TakenUsernames(ctx, []string{"ALICE", "carol"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, taken)
+}
+
+func TestUsernameRuleRepository_RoundTrip(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewUsernameRuleRepository(db)
+	ctx := context.Background()
+	creator := createUser(t, db)
+
+	reason := "trademark"
+	rule := &models.UsernameRule{
+		ID:        uuid.New(),
+		Kind:      models.UsernameRuleReserved,
+		Pattern:   "Hearth",
+		Reason:    &reason,
+		CreatorID: &creator.ID,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	require.NoError(t, repo.Create(ctx, rule))
+
+	duplicate := *rule
+	duplicate.ID = uuid.New()
+	duplicate.Pattern = "hearth"
+	assert.ErrorIs(t, repo.Create(ctx, &duplicate), services.ErrUsernameRuleExists)
+
+	regex := duplicate
+	regex.ID = uuid.New()
+	regex.Regex = true
+	require.NoError(t, repo.Create(ctx, &regex), "the same pattern can be listed as a regex")
+
+	rules, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, rule.ID, rules[0].ID)
+	assert.Equal(t, "trademark", *rules[0].Reason)
+
+	deleted, err := repo.Delete(ctx, rule.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = repo.Delete(ctx, rule.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+}
+
+func TestChannelFeedRepository_RoundTrip(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewChannelFeedRepository(db)
+	ctx := context.Background()
+	owner := createUser(t, db)
+	channel := createChannel(t, db, createServer(t, db, owner.ID).ID, 0)
+
+	feed, err := repo.Get(ctx, channel.ID)
+	require.NoError(t, err)
+	assert.Nil(t, feed)
+
+	createdAt := time.Now().UTC().Truncate(time.Microsecond)
+	require.NoError(t, repo.Save(ctx, &models.ChannelFeed{ChannelID: channel.ID, Token: "first", CreatorID: &owner.ID, CreatedAt: createdAt}))
+	require.NoError(t, repo.Save(ctx, &models.ChannelFeed{ChannelID: channel.ID, Token: "second", CreatedAt: createdAt.Add(time.Hour)}))
+
+	feed, err = repo.Get(ctx, channel.ID)
+	require.NoError(t, err)
+	require.NotNil(t, feed)
+	assert.Equal(t, "second", feed.Token, "saving again rotates the token")
+	assert.Equal(t, owner.ID, *feed.CreatorID)
+	assert.True(t, feed.CreatedAt.Equal(createdAt))
+
+	require.NoError(t, repo.Delete(ctx, channel.ID))
+	feed, err = repo.Get(ctx, channel.ID)
+	require.NoError(t, err)
+	assert.Nil(t, feed)
+}
+
+func TestMemberVerificationRepository_Screening(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewMemberVerificationRepository(db)
+	servers := NewServerRepository(db)
+	ctx := context.Background()
+	owner := createUser(t, db)
+	server := createServer(t, db, owner.ID)
+
+	settings, err := repo.GetSettings(ctx, server.ID)
+	require.NoError(t, err)
+	assert.False(t, settings.Enabled)
+	assert.Empty(t, settings.FormFields)
+
+	settings.Enabled = true
+	settings.FormFields = []models.MemberVerificationField{
+		{FieldType: models.MemberVerificationTerms, Label: "Rules", Values: []string{"Be kind"}, Required: true},
+		{FieldType: models.MemberVerificationTextInput, Label: "Why join?"},
+	}
+	settings.Version = time.Now().UTC().Truncate(time.Microsecond)
+	require.NoError(t, repo.SaveSettings(ctx, settings))
+	saved, err := repo.GetSettings(ctx, server.ID)
+	require.NoError(t, err)
+	assert.Equal(t, settings.FormFields, saved.FormFields)
+	assert.True(t, saved.Version.Equal(settings.Version), "clients send the version back unchanged")
+
+	pending, other := createUser(t, db), createUser(t, db)
+	now := time.Now()
+	require.NoError(t, servers.AddMember(ctx, &models.Member{ServerID: server.ID, UserID: pending.ID, JoinedAt: now, Pending: true}))
+	require.NoError(t, servers.AddMember(ctx, &models.Member{ServerID: server.ID, UserID: other.ID, JoinedAt: now, Pending: true}))
+
+	response := &models.MemberVerificationResponse{
+		ServerID:    server.ID,
+		UserID:      pending.ID,
+		Answers:     []models.MemberVerificationAnswer{{Label: "Why join?", Response: "Friends"}},
+		SubmittedAt: now.UTC().Truncate(time.Microsecond),
+	}
+	accepted, err := repo.Accept(ctx, response)
+	require.NoError(t, err)
+	assert.True(t, accepted)
+	accepted, err = repo.Accept(ctx, response)
+	require.NoError(t, err)
+	assert.False(t, accepted, "members only pass screening once")
+
+	member, err := servers.GetMember(ctx, server.ID, pending.ID)
+	require.NoError(t, err)
+	assert.False(t, member.Pending)
+	stored, err := repo.GetResponse(ctx, server.ID, pending.ID)
+	require.NoError(t, err)
+	assert.Equal(t, response.Answers, stored.Answers)
+
+	cleared, err := repo.ClearPending(ctx, server.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{other.ID}, cleared)
+
+	// Answers go with the membership
+	require.NoError(t, servers.RemoveMember(ctx, server.ID, pending.ID))
+	stored, err = repo.GetResponse(ctx, server.ID, pending.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+}
+
+func TestChannelScheduleRepository_RoundTrip(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewChannelScheduleRepository(db)
+	ctx := context.Background()
+	owner := createUser(t, db)
+	channel := createChannel(t, db, createServer(t, db, owner.ID).ID, 0)
+
+	schedule, err := repo.Get(ctx, channel.ID)
+	require.NoError(t, err)
+	assert.Nil(t, schedule)
+
+	startsAt := time.Now().UTC().Truncate(time.Second).Add(time.Hour)
+	saved := &models.ChannelSchedule{
+		ChannelID: channel.ID,
+		Timezone:  "Europe/Berlin",
+		Hours:     []models.ChannelHours{{Day: time.Monday, Open: "09:00", Close: "17:00"}},
+		Closures:  []models.ChannelClosure{{StartsAt: startsAt, EndsAt: startsAt.Add(time.Hour), Reason: "Holiday"}},
+		UpdatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	require.NoError(t, repo.Save(ctx, saved))
+	saved.Timezone = "UTC"
+	require.NoError(t, repo.Save(ctx, saved))
+
+	schedule, err = repo.Get(ctx, channel.ID)
+	require.NoError(t, err)
+	require.NotNil(t, schedule)
+	assert.Equal(t, "UTC", schedule.Timezone, "saving again replaces the schedule")
+	assert.Equal(t, saved.Hours, schedule.Hours)
+	require.Len(t, schedule.Closures, 1)
+	assert.True(t, schedule.Closures[0].StartsAt.Equal(startsAt))
+	assert.Equal(t, "Holiday", schedule.Closures[0].Reason)
+
+	require.NoError(t, repo.Delete(ctx, channel.ID))
+	schedule, err = repo.Get(ctx, channel.ID)
+	require.NoError(t, err)
+	assert.Nil(t, schedule)
+}
+
+func TestServerRepository_OwnershipTransfer(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewServerRepository(db)
+	ctx := context.Background()
+	owner, heir := createUser(t, db), createUser(t, db)
+	server := createServer(t, db, owner.ID)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	for _, userID := range []uuid.UUID{owner.ID, heir.ID} {
+		require.NoError(t, repo.AddMember(ctx, &models.Member{ServerID: server.ID, UserID: userID, JoinedAt: now}))
+	}
+
+	transfer, err := repo.GetOwnershipTransfer(ctx, server.ID)
+	require.NoError(t, err)
+	assert.Nil(t, transfer)
+
+	saved := &models.OwnershipTransfer{
+		ServerID:    server.ID,
+		FromUserID:  owner.ID,
+		ToUserID:    heir.ID,
+		KeepCoOwner: true,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(time.Hour),
+	}
+	require.NoError(t, repo.SaveOwnershipTransfer(ctx, saved))
+	transfer, err = repo.GetOwnershipTransfer(ctx, server.ID)
+	require.NoError(t, err)
+	require.NotNil(t, transfer)
+	assert.True(t, transfer.KeepCoOwner)
+	assert.True(t, transfer.ExpiresAt.Equal(saved.ExpiresAt))
+
+	completed, err := repo.CompleteOwnershipTransfer(ctx, saved, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.False(t, completed, "expired transfers can't be completed")
+
+	completed, err = repo.CompleteOwnershipTransfer(ctx, saved, now)
+	require.NoError(t, err)
+	assert.True(t, completed)
+	got, err := repo.GetByID(ctx, server.ID)
+	require.NoError(t, err)
+	assert.Equal(t, heir.ID, got.OwnerID)
+	member, err := repo.GetMember(ctx, server.ID, owner.ID)
+	require.NoError(t, err)
+	assert.True(t, member.CoOwner, "the old owner stays on as a co-owner")
+	transfer, err = repo.GetOwnershipTransfer(ctx, server.ID)
+	require.NoError(t, err)
+	assert.Nil(t, transfer)
+
+	require.NoError(t, repo.SetCoOwner(ctx, server.ID, owner.ID, false))
+	member, err = repo.GetMember(ctx, server.ID, owner.ID)
+	require.NoError(t, err)
+	assert.False(t, member.CoOwner)
+}
+
+func TestOnboardingRepository_RoundTrip(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewOnboardingRepository(db)
+	servers := NewServerRepository(db)
+	ctx := context.Background()
+	owner, stranger := createUser(t, db), createUser(t, db)
+	server := createServer(t, db, owner.ID)
+	channel := createChannel(t, db, server.ID, 0)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	kept, dropped, picked := uuid.New(), uuid.New(), uuid.New()
+	require.NoError(t, servers.AddMember(ctx, &models.Member{ServerID: server.ID, UserID: owner.ID, JoinedAt: now, Roles: []uuid.UUID{kept, dropped}}))
+
+	screen, err := repo.GetWelcomeScreen(ctx, server.ID)
+	require.NoError(t, err)
+	assert.False(t, screen.Enabled)
+	description := "Say hi"
+	require.NoError(t, repo.SaveWelcomeScreen(ctx, &models.WelcomeScreen{
+		ServerID:        server.ID,
+		Enabled:         true,
+		Description:     &description,
+		WelcomeChannels: []models.WelcomeChannel{{ChannelID: channel.ID, Description: "Start here"}},
+		UpdatedAt:       now,
+	}))
+	screen, err = repo.GetWelcomeScreen(ctx, server.ID)
+	require.NoError(t, err)
+	assert.True(t, screen.Enabled)
+	assert.Equal(t, channel.ID, screen.WelcomeChannels[0].ChannelID)
+
+	option := models.OnboardingOption{ID: uuid.New(), Title: "Art", RoleIDs: []uuid.UUID{picked}}
+	require.NoError(t, repo.SaveOnboarding(ctx, &models.Onboarding{
+		ServerID:  server.ID,
+		Enabled:   true,
+		Prompts:   []models.OnboardingPrompt{{ID: uuid.New(), Title: "Pick", Options: []models.OnboardingOption{option}}},
+		UpdatedAt: now,
+	}))
+	onboarding, err := repo.GetOnboarding(ctx, server.ID)
+	require.NoError(t, err)
+	require.Len(t, onboarding.Prompts, 1)
+	assert.Equal(t, option.RoleIDs, onboarding.Prompts[0].Options[0].RoleIDs)
+
+	responses := &models.OnboardingResponses{
+		ServerID:   server.ID,
+		UserID:     owner.ID,
+		OptionIDs:  []uuid.UUID{option.ID},
+		RoleIDs:    []uuid.UUID{picked},
+		ChannelIDs: []uuid.UUID{},
+		UpdatedAt:  now,
+	}
+	saved, err := repo.SaveResponses(ctx, responses, []uuid.UUID{dropped})
+	require.NoError(t, err)
+	assert.True(t, saved)
+	members, err := servers.GetMembersByUserIDs(ctx, server.ID, []uuid.UUID{owner.ID})
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.ElementsMatch(t, []uuid.UUID{kept, picked}, members[0].Roles)
+	got, err := repo.GetResponses(ctx, server.ID, owner.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, responses.OptionIDs, got.OptionIDs)
+
+	saved, err = repo.SaveResponses(ctx, &models.OnboardingResponses{ServerID: server.ID, UserID: stranger.ID, UpdatedAt: now}, nil)
+	require.NoError(t, err)
+	assert.False(t, saved, "only members can go through onboarding")
+	got, err = repo.GetResponses(ctx, server.ID, stranger.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestBanImportRepository_Batches(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewBanImportRepository(db)
+	servers := NewServerRepository(db)
+	ctx := context.Background()
+	owner, member, banned := createUser(t, db), createUser(t, db), createUser(t, db)
+	server := createServer(t, db, owner.ID)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	require.NoError(t, servers.AddMember(ctx, &models.Member{ServerID: server.ID, UserID: member.ID, JoinedAt: now}))
+	require.NoError(t, servers.AddBan(ctx, &models.Ban{ServerID: server.ID, UserID: banned.ID, BannedBy: &owner.ID, CreatedAt: now}))
+
+	reason := "spam"
+	entries := []models.BanListEntry{{UserID: member.ID, Reason: &reason}, {UserID: banned.ID}, {UserID: uuid.New()}}
+	job := &models.BanImport{ID: uuid.New(), ServerID: server.ID, RequestedBy: owner.ID, Status: models.BanImportQueued, Total: len(entries), CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, repo.CreateBanImport(ctx, job, entries))
+	again := *job
+	again.ID = uuid.New()
+	assert.ErrorIs(t, repo.CreateBanImport(ctx, &again, entries), services.ErrBanImportInProgress)
+
+	claimed, batch, err := repo.ClaimBanImport(ctx, now, now.Add(time.Minute), 2)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, models.BanImportRunning, claimed.Status)
+	assert.Equal(t, entries[:2], batch)
+	held, _, err := repo.ClaimBanImport(ctx, now, now.Add(time.Minute), 2)
+	require.NoError(t, err)
+	assert.Nil(t, held, "a held import isn't handed out twice")
+
+	added, err := repo.AddBans(ctx, server.ID, owner.ID, batch, now)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{member.ID}, added, "users already banned are left alone")
+	gone, err := servers.GetMember(ctx, server.ID, member.ID)
+	require.NoError(t, err)
+	assert.Nil(t, gone)
+	claimed.Processed, claimed.Banned, claimed.Skipped = 2, 1, 1
+	require.NoError(t, repo.SaveBanImportProgress(ctx, claimed))
+
+	claimed, batch, err = repo.ClaimBanImport(ctx, now, now.Add(time.Minute), 2)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, entries[2:], batch)
+	added, err = repo.AddBans(ctx, server.ID, owner.ID, batch, now)
+	require.NoError(t, err)
+	assert.Empty(t, added, "users who don't exist can't be banned")
+
+	claimed.Status, claimed.Processed, claimed.Skipped, claimed.CompletedAt = models.BanImportCompleted, 3, 2, &now
+	require.NoError(t, repo.SaveBanImportProgress(ctx, claimed))
+	got, err := repo.GetBanImport(ctx, server.ID, job.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, models.BanImportCompleted, got.Status)
+	assert.Equal(t, 0, countRows(t, db, `SELECT COUNT(*) FROM ban_import_entries WHERE import_id = $1`, job.ID))
+	require.NoError(t, repo.CreateBanImport(ctx, &again, entries), "a new import can start once the last one finished")
+}
+
+func TestBlocklistRepository_RoundTrip(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewBlocklistRepository(db)
+	servers := NewServerRepository(db)
+	ctx := context.Background()
+	owner, raider, exempt, outsider := createUser(t, db), createUser(t, db), createUser(t, db), createUser(t, db)
+	maintainer, subscriber := createServer(t, db, owner.ID), createServer(t, db, owner.ID)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	for _, userID := range []uuid.UUID{raider.ID, exempt.ID} {
+		require.NoError(t, servers.AddMember(ctx, &models.Member{ServerID: subscriber.ID, UserID: userID, JoinedAt: now}))
+	}
+
+	list := &models.Blocklist{ID: uuid.New(), ServerID: &maintainer.ID, Name: "Raiders", Public: true, CreatedBy: owner.ID, CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, repo.CreateBlocklist(ctx, list))
+	reason := "raid"
+	later := now.Add(time.Second)
+	require.NoError(t, repo.AddEntries(ctx, list.ID, owner.ID, []models.BanListEntry{
+		{UserID: raider.ID, Reason: &reason}, {UserID: exempt.ID}, {UserID: outsider.ID},
+	}, later))
+	got, err := repo.GetBlocklist(ctx, list.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, 3, got.EntryCount)
+	assert.True(t, got.UpdatedAt.Equal(later), "adding users bumps the list's version")
+
+	require.NoError(t, repo.Subscribe(ctx, &models.BlocklistSubscription{ServerID: subscriber.ID, BlocklistID: list.ID, SubscribedBy: owner.ID, CreatedAt: now}))
+	require.NoError(t, repo.AddExemption(ctx, &models.BlocklistExemption{ServerID: subscriber.ID, BlocklistID: list.ID, UserID: exempt.ID, CreatedBy: owner.ID, CreatedAt: now}))
+	subscriptions, err := repo.GetSubscriptions(ctx, subscriber.ID)
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 1)
+	require.NotNil(t, subscriptions[0].Blocklist)
+	assert.Equal(t, "Raiders", subscriptions[0].Blocklist.Name)
+
+	match, err := repo.FindMatch(ctx, subscriber.ID, outsider.ID)
+	require.NoError(t, err)
+	require.NotNil(t, match)
+	assert.Equal(t, "Raiders", match.BlocklistName)
+	match, err = repo.FindMatch(ctx, subscriber.ID, exempt.ID)
+	require.NoError(t, err)
+	assert.Nil(t, match, "exempt users don't match")
+
+	matches, err := repo.MatchMembers(ctx, subscriber.ID, list.ID)
+	require.NoError(t, err)
+	require.Len(t, matches, 1, "only members who aren't exempt match")
+	assert.Equal(t, raider.ID, matches[0].UserID)
+	assert.Equal(t, reason, *matches[0].Reason)
+
+	stale, err := repo.StaleSubscriptions(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, stale, 1)
+	require.NoError(t, repo.MarkSynced(ctx, subscriber.ID, list.ID, stale[0].Blocklist.UpdatedAt))
+	stale, err = repo.StaleSubscriptions(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, stale)
+
+	ban := &models.Ban{ServerID: subscriber.ID, UserID: raider.ID, Reason: &reason, BannedBy: &owner.ID, CreatedAt: now}
+	banned, err := repo.BanMember(ctx, ban)
+	require.NoError(t, err)
+	assert.True(t, banned)
+	gone, err := servers.GetMember(ctx, subscriber.ID, raider.ID)
+	require.NoError(t, err)
+	assert.Nil(t, gone)
+	banned, err = repo.BanMember(ctx, ban)
+	require.NoError(t, err)
+	assert.False(t, banned, "users already banned are left alone")
+
+	removed, err := repo.Unsubscribe(ctx, subscriber.ID, list.ID)
+	require.NoError(t, err)
+	assert.True(t, removed)
+	assert.Equal(t, 0, countRows(t, db, `SELECT COUNT(*) FROM blocklist_exemptions WHERE server_id = $1`, subscriber.ID))
+	require.NoError(t, repo.DeleteBlocklist(ctx, list.ID))
+	assert.Equal(t, 0, countRows(t, db, `SELECT COUNT(*) FROM blocklist_entries WHERE blocklist_id = $1`, list.ID))
+}
+
+func TestWebhookPolicyRepository_RoundTrip(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewWebhookPolicyRepository(db)
+	ctx := context.Background()
+	owner := createUser(t, db)
+	server := createServer(t, db, owner.ID)
+	channel := createChannel(t, db, server.ID, 0)
+
+	policy, err := repo.Get(ctx, server.ID, nil)
+	require.NoError(t, err)
+	assert.Nil(t, policy)
+
+	roleID := uuid.New()
+	serverPolicy := &models.WebhookPolicy{
+		ServerID:       server.ID,
+		CreatorRoleIDs: []uuid.UUID{roleID},
+		AllowedDomains: []string{"example.com"},
+		UpdatedBy:      owner.ID,
+		UpdatedAt:      time.Now().UTC().Truncate(time.Microsecond),
+	}
+	require.NoError(t, repo.Save(ctx, serverPolicy))
+	serverPolicy.AllowedDomains = []string{"hooks.example.com"}
+	require.NoError(t, repo.Save(ctx, serverPolicy))
+	require.NoError(t, repo.Save(ctx, &models.WebhookPolicy{
+		ServerID:       server.ID,
+		ChannelID:      &channel.ID,
+		CreatorRoleIDs: []uuid.UUID{},
+		AllowedDomains: []string{},
+		UpdatedBy:      owner.ID,
+		UpdatedAt:      time.Now().UTC(),
+	}))
+
+	policy, err = repo.Get(ctx, server.ID, nil)
+	require.NoError(t, err)
+	require.NotNil(t, policy)
+	assert.Nil(t, policy.ChannelID)
+	assert.Equal(t, []uuid.UUID{roleID}, policy.CreatorRoleIDs)
+	assert.Equal(t, []string{"hooks.example.com"}, policy.AllowedDomains, "saving again replaces the policy")
+	assert.Equal(t, owner.ID, policy.UpdatedBy)
+
+	policy, err = repo.Get(ctx, server.ID, &channel.ID)
+	require.NoError(t, err)
+	require.NotNil(t, policy)
+	assert.Equal(t, channel.ID, *policy.ChannelID)
+	assert.Empty(t, policy.CreatorRoleIDs)
+
+	require.NoError(t, repo.Delete(ctx, server.ID, nil))
+	policy, err = repo.Get(ctx, server.ID, nil)
+	require.NoError(t, err)
+	assert.Nil(t, policy)
+	policy, err = repo.Get(ctx, server.ID, &channel.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, policy, "deleting the server's policy keeps the channel's")
+}
+
+func TestServerTokenRepository_RoundTrip(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewServerTokenRepository(db)
+	ctx := context.Background()
+	owner := createUser(t, db)
+	server := createServer(t, db, owner.ID)
+
+	token := &models.ServerToken{
+		ID:        uuid.New(),
+		ServerID:  server.ID,
+		CreatorID: owner.ID,
+		Name:      "CI",
+		Scopes:    []string{models.ServerTokenScopeMessagesWrite},
+		TokenHash: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		ExpiresAt: time.Now().UTC().Add(time.Hour).Truncate(time.Microsecond),
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	require.NoError(t, repo.Create(ctx, token))
+
+	found, err := repo.GetByHash(ctx, token.TokenHash)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, token.Scopes, found.Scopes)
+	assert.True(t, found.ExpiresAt.Equal(token.ExpiresAt))
+	assert.Nil(t, found.LastUsedAt)
+
+	usedAt := time.Now().UTC().Truncate(time.Microsecond)
+	require.NoError(t, repo.Touch(ctx, token.ID, usedAt))
+	tokens, err := repo.GetByServerID(ctx, server.ID)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	require.NotNil(t, tokens[0].LastUsedAt)
+	assert.True(t, tokens[0].LastUsedAt.Equal(usedAt))
+
+	deleted, err := repo.Delete(ctx, uuid.New(), token.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted, "tokens can only be deleted through their server")
+	deleted, err = repo.Delete(ctx, server.ID, token.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	found, err = repo.GetByHash(ctx, token.TokenHash)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func TestE2EERepository_RoundTrip(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewE2EERepository(db)
+	ctx := context.Background()
+	owner := createUser(t, db)
+	member := createUser(t, db)
+	server := createServer(t, db, owner.ID)
+	channel := createChannel(t, db, server.ID, 0)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	for _, device := range []*models.E2EEDevice{
+		{UserID: owner.ID, DeviceID: "laptop", IdentityKey: "aWQ=", SignedPreKey: "c3Br", SignedKeySign: "c2ln", CreatedAt: now, UpdatedAt: now},
+		{UserID: member.ID, DeviceID: "phone", IdentityKey: "aWQ=", SignedPreKey: "c3Br", SignedKeySign: "c2ln", CreatedAt: now, UpdatedAt: now},
+	} {
+		require.NoError(t, repo.SaveDevice(ctx, device))
+	}
+	require.NoError(t, repo.AddPreKeys(ctx, member.ID, "phone", []models.OneTimePreKey{{KeyID: 2, PublicKey: "Mg=="}, {KeyID: 1, PublicKey: "MQ=="}}))
+	require.NoError(t, repo.AddPreKeys(ctx, member.ID, "phone", []models.OneTimePreKey{{KeyID: 1, PublicKey: "MQ=="}}))
+
+	device, err := repo.GetDevice(ctx, member.ID, "phone")
+	require.NoError(t, err)
+	require.NotNil(t, device)
+	assert.Equal(t, 2, device.PreKeyCount)
+
+	claimed, err := repo.ClaimPreKey(ctx, member.ID, "phone")
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, 1, claimed.KeyID)
+	_, err = repo.ClaimPreKey(ctx, member.ID, "phone")
+	require.NoError(t, err)
+	claimed, err = repo.ClaimPreKey(ctx, member.ID, "phone")
+	require.NoError(t, err)
+	assert.Nil(t, claimed, "pre-keys are handed out once")
+
+	epoch, err := repo.GetEpoch(ctx, channel.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), epoch)
+	epoch, err = repo.RotateEpoch(ctx, channel.ID, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), epoch)
+
+	key := &models.SenderKey{
+		ChannelID: channel.ID, Epoch: epoch, SenderID: owner.ID, SenderDeviceID: "laptop",
+		RecipientID: member.ID, RecipientDeviceID: "phone", Ciphertext: "b2xk", CreatedAt: now,
+	}
+	require.NoError(t, repo.SaveSenderKeys(ctx, []*models.SenderKey{key}))
+	key.Ciphertext = "bmV3"
+	require.NoError(t, repo.SaveSenderKeys(ctx, []*models.SenderKey{key}))
+	keys, err := repo.GetSenderKeys(ctx, channel.ID, member.ID, "phone")
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "bmV3", keys[0].Ciphertext)
+
+	deleted, err := repo.DeleteDevice(ctx, member.ID, "phone")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	keys, err = repo.GetSenderKeys(ctx, channel.ID, member.ID, "phone")
+	require.NoError(t, err)
+	assert.Empty(t, keys, "sender keys go with the device they were sent to")
+}
+
+func TestBotTierRepository_RoundTrip(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewBotTierRepository(db)
+	ctx := context.Background()
+	admin := createUser(t, db)
+	bot := createUser(t, db)
+	other := createUser(t, db)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	found, err := repo.Get(ctx, bot.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found, "bots start out without a tier")
+
+	require.NoError(t, repo.Save(ctx, &models.BotVerification{UserID: bot.ID, Tier: models.BotTierVerified, UpdatedBy: &admin.ID, UpdatedAt: &now}))
+	later := now.Add(time.Minute)
+	require.NoError(t, repo.Save(ctx, &models.BotVerification{UserID: other.ID, Tier: models.BotTierVerified, UpdatedBy: &admin.ID, UpdatedAt: &later}))
+	require.NoError(t, repo.Save(ctx, &models.BotVerification{UserID: bot.ID, Tier: models.BotTierPartner, UpdatedBy: &admin.ID, UpdatedAt: &now}))
+
+	found, err = repo.Get(ctx, bot.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, models.BotTierPartner, found.Tier)
+	assert.Equal(t, admin.ID, *found.UpdatedBy)
+
+	all, err := repo.List(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, other.ID, all[0].UserID, "most recently changed first")
+	verified, err := repo.List(ctx, models.BotTierVerified)
+	require.NoError(t, err)
+	require.Len(t, verified, 1)
+	assert.Equal(t, other.ID, verified[0].UserID)
+}
+
+func TestSessionRepository_RoundTrip(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewSessionRepository(db)
+	ctx := context.Background()
+	user := createUser(t, db)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	ip := "203.0.113.7"
+
+	expired := &models.Session{ID: uuid.New(), UserID: user.ID, RefreshToken: "old", CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(-time.Minute)}
+	require.NoError(t, repo.Create(ctx, expired))
+	session := &models.Session{ID: uuid.New(), UserID: user.ID, RefreshToken: "first", IPAddress: &ip, CreatedAt: now, LastUsedAt: &now, ExpiresAt: now.Add(time.Hour)}
+	require.NoError(t, repo.Create(ctx, session))
+
+	found, err := repo.Get(ctx, expired.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found, "expired sessions are cleared out")
+
+	session.RefreshToken = "second"
+	rotated, err := repo.Rotate(ctx, session, "first")
+	require.NoError(t, err)
+	assert.True(t, rotated)
+	rotated, err = repo.Rotate(ctx, session, "first")
+	require.NoError(t, err)
+	assert.False(t, rotated, "a hash is only swapped once")
+
+	sessions, err := repo.ListByUser(ctx, user.ID, now)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "second", sessions[0].RefreshToken)
+	require.NotNil(t, sessions[0].IPAddress)
+	assert.Equal(t, ip, *sessions[0].IPAddress)
+
+	version, err := repo.TokenVersion(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+	version, err = repo.BumpTokenVersion(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	deleted, err := repo.Delete(ctx, uuid.New(), session.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted, "only the owner can delete a session")
+	require.NoError(t, repo.DeleteByUser(ctx, user.ID))
+	found, err = repo.Get(ctx, session.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func TestContentErasureRepository_Scrub(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewContentErasureRepository(db)
+	ctx := context.Background()
+	user, other := createUser(t, db), createUser(t, db)
+	server := createServer(t, db, other.ID)
+	channel := createChannel(t, db, server.ID, 0)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	own := insertMessage(t, db, channel.ID, user.ID, now)
+	kept := insertMessage(t, db, channel.ID, other.ID, now)
+	forward := insertMessage(t, db, channel.ID, other.ID, now)
+	_, err := db.Exec(`UPDATE messages SET forwarded_from = $2 WHERE id = $1`, forward,
+		models.MessageForward{MessageID: own, ChannelID: channel.ID, AuthorID: user.ID, CreatedAt: now})
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO attachments (id, message_id, filename, url, size) VALUES ($1, $2, 'cat.png', 'https://cdn.example/cat.png', 1)`, uuid.New(), own)
+	require.NoError(t, err)
+
+	job := &models.ContentErasure{ID: uuid.New(), UserID: user.ID, RequestedBy: other.ID, Status: models.ContentErasureQueued, CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, repo.CreateContentErasure(ctx, job))
+	again := *job
+	again.ID = uuid.New()
+	assert.ErrorIs(t, repo.CreateContentErasure(ctx, &again), services.ErrContentErasureInProgress)
+
+	claimed, err := repo.ClaimContentErasure(ctx, now, now.Add(time.Minute))
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	held, err := repo.ClaimContentErasure(ctx, now, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Nil(t, held, "a held erasure isn't handed out twice")
+
+	remaining, err := repo.CountUserContent(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, remaining)
+	counts, err := repo.ScrubUserContent(ctx, user.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.ContentScrubCounts{Messages: 1, Attachments: 1}, counts)
+	counts, err = repo.ScrubUserContent(ctx, user.ID, 10)
+	require.NoError(t, err)
+	assert.Equal(t, models.ContentScrubCounts{Forwards: 1}, counts)
+	remaining, err = repo.CountUserContent(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Zero(t, remaining)
+	assert.Equal(t, 1, countRows(t, db, `SELECT COUNT(*) FROM messages WHERE id = $1 AND content = 'hi'`, kept), "others' messages are left alone")
+
+	claimed.Status, claimed.MessagesScrubbed, claimed.ForwardsScrubbed = models.ContentErasureVerified, 1, 1
+	claimed.CompletedAt, claimed.VerifiedAt = &now, &now
+	require.NoError(t, repo.SaveContentErasureProgress(ctx, claimed))
+	got, err := repo.GetContentErasure(ctx, job.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, models.ContentErasureVerified, got.Status)
+	require.NoError(t, repo.CreateContentErasure(ctx, &again), "a user can be erased again once the last erasure finished")
+	listed, err := repo.ListContentErasures(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Len(t, listed, 2)
+}
+
+func TestOAuthIdentityRepository(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewOAuthIdentityRepository(db)
+	ctx := context.Background()
+	user := createUser(t, db)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	missing, err := repo.GetOAuthIdentity(ctx, "google", "abc123")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	email := user.Email
+	identity := &models.OAuthIdentity{Provider: "google", Subject: "abc123", UserID: user.ID, Email: &email, CreatedAt: now}
+	require.NoError(t, repo.CreateOAuthIdentity(ctx, identity))
+	assert.ErrorIs(t, repo.CreateOAuthIdentity(ctx, identity), services.ErrOAuthIdentityLinked)
+
+	later := now.Add(time.Hour)
+	require.NoError(t, repo.RecordOAuthLogin(ctx, "google", "abc123", later))
+	got, err := repo.GetOAuthIdentity(ctx, "google", "abc123")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, user.ID, got.UserID)
+	require.NotNil(t, got.LastLoginAt)
+	assert.True(t, later.Equal(*got.LastLoginAt))
+
+	other, err := repo.GetOAuthIdentity(ctx, "github", "abc123")
+	require.NoError(t, err)
+	assert.Nil(t, other, "subjects are only unique within a provider")
+}
+
+func TestServerThrottleRepository(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewServerThrottleRepository(db)
+	ctx := context.Background()
+	admin := createUser(t, db)
+	server := createServer(t, db, admin.ID)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	missing, err := repo.Get(ctx, server.ID)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	messages, reason := 5, "spam wave"
+	throttle := &models.ServerThrottle{ServerID: server.ID, MessagesPerSecond: &messages, Reason: &reason, UpdatedBy: &admin.ID, UpdatedAt: &now}
+	require.NoError(t, repo.Save(ctx, throttle))
+	got, err := repo.Get(ctx, server.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, 5, *got.MessagesPerSecond)
+	assert.Nil(t, got.EventsPerSecond, "limits left out use the instance defaults")
+
+	events := 0
+	throttle.MessagesPerSecond, throttle.EventsPerSecond = nil, &events
+	require.NoError(t, repo.Save(ctx, throttle))
+	got, err = repo.Get(ctx, server.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got.MessagesPerSecond)
+	assert.Equal(t, 0, *got.EventsPerSecond)
+
+	negative := -1
+	throttle.EventsPerSecond = &negative
+	assert.Error(t, repo.Save(ctx, throttle))
+
+	require.NoError(t, repo.Delete(ctx, server.ID))
+	missing, err = repo.Get(ctx, server.ID)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestOAuthAppRepository(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewOAuthAppRepository(db)
+	ctx := context.Background()
+	owner := createUser(t, db)
+	user := createUser(t, db)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	app := &models.OAuthApplication{
+		ID:               uuid.New(),
+		OwnerID:          owner.ID,
+		Name:             "Release bot",
+		RedirectURIs:     []string{"https://bot.example.com/callback"},
+		ClientSecretHash: strings.Repeat("a", 64),
+		CreatedAt:        now,
+	}
+	require.NoError(t, repo.CreateApplication(ctx, app))
+	got, err := repo.GetApplication(ctx, app.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, app.RedirectURIs, got.RedirectURIs)
+
+	code := &models.OAuthAuthorizationCode{
+		CodeHash:      strings.Repeat("b", 64),
+		ApplicationID: app.ID,
+		UserID:        user.ID,
+		RedirectURI:   "https://bot.example.com/callback",
+		Scopes:        []string{models.OAuthScopeIdentify},
+		ExpiresAt:     now.Add(time.Minute),
+		CreatedAt:     now,
+	}
+	require.NoError(t, repo.CreateAuthorizationCode(ctx, code))
+	taken, err := repo.TakeAuthorizationCode(ctx, code.CodeHash)
+	require.NoError(t, err)
+	require.NotNil(t, taken)
+	assert.Equal(t, code.Scopes, taken.Scopes)
+	taken, err = repo.TakeAuthorizationCode(ctx, code.CodeHash)
+	require.NoError(t, err)
+	assert.Nil(t, taken, "codes are used once")
+
+	token := &models.OAuthToken{
+		ID:               uuid.New(),
+		ApplicationID:    app.ID,
+		UserID:           user.ID,
+		Scopes:           []string{models.OAuthScopeIdentify, models.OAuthScopeServersRead},
+		AccessTokenHash:  strings.Repeat("c", 64),
+		RefreshTokenHash: strings.Repeat("d", 64),
+		ExpiresAt:        now.Add(time.Hour),
+		CreatedAt:        now,
+	}
+	require.NoError(t, repo.CreateToken(ctx, token))
+	gotToken, err := repo.GetTokenByAccessHash(ctx, token.AccessTokenHash)
+	require.NoError(t, err)
+	require.NotNil(t, gotToken)
+	assert.Equal(t, user.ID, gotToken.UserID)
+
+	authorized, err := repo.GetAuthorizedApplications(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, authorized, 1)
+	assert.ElementsMatch(t, token.Scopes, authorized[0].Scopes)
+	assert.Empty(t, authorized[0].Application.ClientSecretHash)
+
+	deleted, err := repo.DeleteApplication(ctx, user.ID, app.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted, "only the owner can delete it")
+	deleted, err = repo.DeleteApplication(ctx, owner.ID, app.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	gotToken, err = repo.GetTokenByAccessHash(ctx, token.AccessTokenHash)
+	require.NoError(t, err)
+	assert.Nil(t, gotToken, "deleting an application revokes its tokens")
+}
+
+func TestInstanceStatsRepository(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewInstanceStatsRepository(db)
+	ctx := context.Background()
+
+	before, err := repo.Stats(ctx)
+	require.NoError(t, err)
+
+	owner := createUser(t, db)
+	createServer(t, db, owner.ID)
+	bot := createUser(t, db)
+	bot.Flags = models.UserFlagBot
+	require.NoError(t, NewUserRepository(db).Update(ctx, bot))
+
+	after, err := repo.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, before.Users+1, after.Users)
+	assert.Equal(t, before.Bots+1, after.Bots)
+	assert.Equal(t, before.Servers+1, after.Servers)
+	assert.GreaterOrEqual(t, after.Messages, int64(0))
+
+	require.NoError(t, NewUserSuspensionRepository(db).Save(ctx, &models.UserSuspension{UserID: owner.ID, CreatedAt: time.Now()}))
+	suspended, err := repo.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, after.SuspendedUsers+1, suspended.SuspendedUsers)
+}
+
+func TestQuotaOverrideRepository(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewQuotaOverrideRepository(db)
+	ctx := context.Background()
+	admin := createUser(t, db)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	for _, tier := range models.QuotaTiers {
+		require.NoError(t, repo.Delete(ctx, tier))
+	}
+
+	owned, fileSize := 20, int64(100)
+	override := &models.QuotaOverride{Tier: models.QuotaTierPremium, MaxServersOwned: &owned, MaxFileSizeMB: &fileSize, UpdatedBy: &admin.ID, UpdatedAt: &now}
+	require.NoError(t, repo.Save(ctx, override))
+	got, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, models.QuotaTierPremium, got[0].Tier)
+	assert.Equal(t, 20, *got[0].MaxServersOwned)
+	assert.Equal(t, int64(100), *got[0].MaxFileSizeMB)
+	assert.Nil(t, got[0].MaxMessageLength, "limits left out use the tier below")
+
+	override.MaxServersOwned = nil
+	require.NoError(t, repo.Save(ctx, override))
+	got, err = repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Nil(t, got[0].MaxServersOwned)
+
+	negative := -1
+	override.MaxServersJoined = &negative
+	assert.Error(t, repo.Save(ctx, override))
+	assert.Error(t, repo.Save(ctx, &models.QuotaOverride{Tier: "gold", UpdatedAt: &now}))
+
+	require.NoError(t, repo.Delete(ctx, models.QuotaTierPremium))
+	got, err = repo.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestIPRuleRepository(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewIPRuleRepository(db)
+	ctx := context.Background()
+	admin := createUser(t, db)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	cidr, asn, reason := "198.51.100.0/24", int64(64500), "scrapers"
+	byCIDR := &models.IPRule{ID: uuid.New(), Action: models.IPRuleBlock, CIDR: &cidr, Reason: &reason, CreatorID: &admin.ID, CreatedAt: now}
+	byASN := &models.IPRule{ID: uuid.New(), Action: models.IPRuleBlock, ASN: &asn, CreatorID: &admin.ID, CreatedAt: now.Add(time.Second)}
+	require.NoError(t, repo.Create(ctx, byCIDR))
+	require.NoError(t, repo.Create(ctx, byASN))
+	t.Cleanup(func() {
+		_, _ = repo.Delete(ctx, byCIDR.ID)
+		_, _ = repo.Delete(ctx, byASN.ID)
+	})
+
+	dup := &models.IPRule{ID: uuid.New(), Action: models.IPRuleBlock, CIDR: &cidr, CreatedAt: now}
+	assert.ErrorIs(t, repo.Create(ctx, dup), services.ErrIPRuleExists)
+	dup.Action = models.IPRuleAllow
+	require.NoError(t, repo.Create(ctx, dup), "the same network can be allowed and blocked")
+	both := &models.IPRule{ID: uuid.New(), Action: models.IPRuleBlock, CIDR: &cidr, ASN: &asn, CreatedAt: now}
+	assert.Error(t, repo.Create(ctx, both))
+
+	rules, err := repo.List(ctx)
+	require.NoError(t, err)
+	found := map[uuid.UUID]*models.IPRule{}
+	for _, rule := range rules {
+		found[rule.ID] = rule
+	}
+	require.Contains(t, found, byCIDR.ID)
+	assert.Equal(t, cidr, *found[byCIDR.ID].CIDR)
+	assert.Equal(t, "scrapers", *found[byCIDR.ID].Reason)
+	require.Contains(t, found, byASN.ID)
+	assert.Equal(t, asn, *found[byASN.ID].ASN)
+	assert.Nil(t, found[byASN.ID].CIDR)
+
+	deleted, err := repo.Delete(ctx, dup.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = repo.Delete(ctx, dup.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+}
+
+func TestLoginSecurityRepository(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewLoginSecurityRepository(db)
+	ctx := context.Background()
+	user := createUser(t, db)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	lockout, err := repo.GetLockout(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, lockout)
+
+	lockout, err = repo.RecordFailure(ctx, user.ID, now, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, lockout.Failures)
+	lockout, err = repo.RecordFailure(ctx, user.ID, now.Add(time.Minute), now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 2, lockout.Failures)
+	lockout, err = repo.RecordFailure(ctx, user.ID, now.Add(2*time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, lockout.Failures, "failures before the window don't count")
+
+	until := now.Add(5 * time.Minute)
+	require.NoError(t, repo.Lock(ctx, user.ID, until))
+	lockout, err = repo.GetLockout(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, lockout.Failures)
+	assert.Equal(t, 1, lockout.Lockouts)
+	assert.True(t, until.Equal(*lockout.LockedUntil))
+
+	require.NoError(t, repo.ClearLockout(ctx, user.ID))
+	lockout, err = repo.GetLockout(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, lockout)
+
+	hasDevices, err := repo.HasDevices(ctx, user.ID)
+	require.NoError(t, err)
+	assert.False(t, hasDevices)
+	device := &models.LoginDevice{UserID: user.ID, DeviceHash: "abc", FirstSeenAt: now, LastSeenAt: now}
+	known, err := repo.TouchDevice(ctx, device)
+	require.NoError(t, err)
+	assert.False(t, known)
+	device.LastSeenAt = now.Add(time.Hour)
+	known, err = repo.TouchDevice(ctx, device)
+	require.NoError(t, err)
+	assert.True(t, known)
+	hasDevices, err = repo.HasDevices(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, hasDevices)
+}
+
+func TestPasswordResetRepository(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewPasswordResetRepository(db)
+	users := NewUserRepository(db)
+	ctx := context.Background()
+	user := createUser(t, db)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	reset := &models.PasswordReset{UserID: user.ID, TokenHash: uuid.NewString(), CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	created, err := repo.Create(ctx, reset, now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	again := &models.PasswordReset{UserID: user.ID, TokenHash: uuid.NewString(), CreatedAt: now.Add(time.Second), ExpiresAt: now.Add(time.Hour)}
+	created, err = repo.Create(ctx, again, now.Add(time.Second-time.Minute))
+	require.NoError(t, err)
+	assert.False(t, created, "resets within the cooldown are refused")
+
+	later := &models.PasswordReset{UserID: user.ID, TokenHash: uuid.NewString(), CreatedAt: now.Add(2 * time.Minute), ExpiresAt: now.Add(time.Hour)}
+	created, err = repo.Create(ctx, later, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	userID, err := repo.Consume(ctx, reset.TokenHash, now)
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, userID, "the new link replaces the old one")
+	userID, err = repo.Consume(ctx, later.TokenHash, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, userID, "expired links don't work")
+
+	created, err = repo.Create(ctx, reset, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.True(t, created)
+	userID, err = repo.Consume(ctx, reset.TokenHash, now)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, userID)
+	userID, err = repo.Consume(ctx, reset.TokenHash, now)
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, userID, "links work once")
+
+	require.NoError(t, users.UpdatePassword(ctx, user.ID, "new-hash", now))
+	assert.ErrorIs(t, users.UpdatePassword(ctx, uuid.New(), "new-hash", now), services.ErrUserNotFound)
+}
+
+func TestUserRepository_List(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+	first := createUser(t, db)
+	second := createUser(t, db)
+
+	users, err := repo.List(ctx, strings.ToUpper(first.Username), nil, 10)
+	require.NoError(t, err)
+	require.Len(t, users, 1, "usernames match ignoring case")
+	assert.Equal(t, first.ID, users[0].ID)
+
+	users, err = repo.List(ctx, second.Email[:8], nil, 10)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, second.ID, users[0].ID)
+
+	users, err = repo.List(ctx, "%", nil, 10)
+	require.NoError(t, err)
+	assert.Empty(t, users, "wildcards in the query are literal")
+
+	page, err := repo.List(ctx, "", nil, 1000)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(page), 2)
+	rest, err := repo.List(ctx, "", &page[0].ID, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, len(page)-1, len(rest))
+	assert.Equal(t, page[1].ID, rest[0].ID)
+}
+
+func TestUserSuspensionRepository(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewUserSuspensionRepository(db)
+	ctx := context.Background()
+	user := createUser(t, db)
+	admin := createUser(t, db)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	suspension, err := repo.Get(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, suspension)
+
+	reason := "spam"
+	require.NoError(t, repo.Save(ctx, &models.UserSuspension{UserID: user.ID, Reason: &reason, SuspendedBy: &admin.ID, CreatedAt: now}))
+	until := now.Add(time.Hour)
+	require.NoError(t, repo.Save(ctx, &models.UserSuspension{UserID: user.ID, SuspendedBy: &admin.ID, CreatedAt: now, ExpiresAt: &until}))
+	suspension, err = repo.Get(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, suspension.Reason, "saving replaces the suspension")
+	assert.True(t, until.Equal(*suspension.ExpiresAt))
+
+	suspensions, err := repo.GetMany(ctx, []uuid.UUID{user.ID, admin.ID})
+	require.NoError(t, err)
+	require.Len(t, suspensions, 1)
+	assert.Equal(t, user.ID, suspensions[0].UserID)
+
+	deleted, err := repo.Delete(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = repo.Delete(ctx, user.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+}
+
+func TestAnnouncementRepository(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewAnnouncementRepository(db)
+	ctx := context.Background()
+	admin := createUser(t, db)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	past := now.Add(-time.Minute)
+	expired := &models.Announcement{ID: uuid.New(), Content: "over", Level: models.AnnouncementInfo, CreatorID: &admin.ID, CreatedAt: now.Add(-time.Hour), ExpiresAt: &past}
+	current := &models.Announcement{ID: uuid.New(), Content: "Maintenance at 22:00 UTC", Level: models.AnnouncementWarning, CreatorID: &admin.ID, CreatedAt: now}
+	require.NoError(t, repo.Create(ctx, expired))
+	require.NoError(t, repo.Create(ctx, current))
+
+	active, err := repo.ListActive(ctx, now)
+	require.NoError(t, err)
+	var ids []uuid.UUID
+	for _, announcement := range active {
+		ids = append(ids, announcement.ID)
+	}
+	assert.Contains(t, ids, current.ID)
+	assert.NotContains(t, ids, expired.ID)
+
+	all, err := repo.List(ctx)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, len(all), 2)
+
+	deleted, err := repo.Delete(ctx, expired.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = repo.Delete(ctx, expired.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+}
+
+func TestReportRepository(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewReportRepository(db)
+	ctx := context.Background()
+	reporter := createUser(t, db)
+	admin := createUser(t, db)
+	target := createUser(t, db)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	report := &models.Report{
+		ID: uuid.New(), ReporterID: &reporter.ID, TargetType: models.ReportTargetUser, TargetID: target.ID,
+		Category: models.ReportHarassment, TargetUserID: &target.ID, Status: models.ReportOpen, CreatedAt: now, UpdatedAt: now,
+	}
+	created := &models.ReportEvent{ID: uuid.New(), ReportID: report.ID, ActorID: &reporter.ID, Action: models.ReportActionCreated, Status: &report.Status, CreatedAt: now}
+	require.NoError(t, repo.Create(ctx, report, created))
+
+	found, err := repo.FindUnresolved(ctx, reporter.ID, models.ReportTargetUser, target.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	count, err := repo.CountUnresolved(ctx, reporter.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	listed, err := repo.List(ctx, models.ReportFilter{Status: models.ReportOpen, TargetID: &target.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	listed, err = repo.List(ctx, models.ReportFilter{TargetID: &target.ID, After: &report.ID, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+
+	report.Status = models.ReportReviewing
+	report.AssigneeID = &admin.ID
+	reviewing := &models.ReportEvent{ID: uuid.New(), ReportID: report.ID, ActorID: &admin.ID, Action: models.ReportActionStatus, Status: &report.Status, AssigneeID: &admin.ID, CreatedAt: now.Add(time.Second)}
+	saved, err := repo.Update(ctx, report, models.ReportOpen, reviewing)
+	require.NoError(t, err)
+	assert.True(t, saved)
+	saved, err = repo.Update(ctx, report, models.ReportOpen, &models.ReportEvent{ID: uuid.New(), ReportID: report.ID, Action: models.ReportActionStatus, CreatedAt: now})
+	require.NoError(t, err)
+	assert.False(t, saved, "updates from a stale status are refused")
+
+	note := "talked to both"
+	require.NoError(t, repo.AddEvent(ctx, &models.ReportEvent{ID: uuid.New(), ReportID: report.ID, ActorID: &admin.ID, Action: models.ReportActionNote, Note: &note, CreatedAt: now.Add(2 * time.Second)}))
+
+	got, err := repo.Get(ctx, report.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ReportReviewing, got.Status)
+	assert.Equal(t, admin.ID, *got.AssigneeID)
+	events, err := repo.Events(ctx, report.ID)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, models.ReportActionNote, events[2].Action)
+
+	missing, err := repo.Get(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestMessageArchiveRepository(t *testing.T) {
+	db := migratedDB(t)
+	repo := NewMessageArchiveRepository(db)
+	ctx := context.Background()
+	author := createUser(t, db)
+	server := createServer(t, db, author.ID)
+	channel := createChannel(t, db, server.ID, 0)
+	_, err := db.Exec(`INSERT INTO members (server_id, user_id) VALUES ($1, $2), ($1, $3)`, server.ID, author.ID, createUser(t, db).ID)
+	require.NoError(t, err)
+
+	large, err := repo.LargeServers(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{server.ID}, large)
+	large, err = repo.LargeServers(ctx, 3)
+	require.NoError(t, err)
+	assert.Empty(t, large)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	old := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	replyTo := func(id, to uuid.UUID) {
+		_, err := db.Exec(`UPDATE messages SET reply_to_id = $2 WHERE id = $1`, id, to)
+		require.NoError(t, err)
+	}
+	withAttachment := insertMessage(t, db, channel.ID, author.ID, old)
+	_, err = db.Exec(`INSERT INTO attachments (id, message_id, filename, url, size) VALUES ($1, $2, 'cat.png', 'https://cdn.example/cat.png', 1)`, uuid.New(), withAttachment)
+	require.NoError(t, err)
+	pinned := insertMessage(t, db, channel.ID, author.ID, old)
+	_, err = db.Exec(`UPDATE messages SET pinned = TRUE WHERE id = $1`, pinned)
+	require.NoError(t, err)
+	repliedToRecently := insertMessage(t, db, channel.ID, author.ID, old.Add(time.Minute))
+	repliedToOld := insertMessage(t, db, channel.ID, author.ID, old.Add(2*time.Minute))
+	oldReply := insertMessage(t, db, channel.ID, author.ID, time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC))
+	replyTo(oldReply, repliedToOld)
+	recent := insertMessage(t, db, channel.ID, author.ID, now)
+	replyTo(recent, repliedToRecently)
+
+	cutoff := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	moved, err := repo.ArchiveServerMessages(ctx, server.ID, cutoff, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, moved, "messages still replied to wait for their replies")
+	moved, err = repo.ArchiveServerMessages(ctx, server.ID, cutoff, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, moved)
+	moved, err = repo.ArchiveServerMessages(ctx, server.ID, cutoff, 10)
+	require.NoError(t, err)
+	assert.Zero(t, moved)
+	assert.Equal(t, 3, countRows(t, db, `SELECT COUNT(*) FROM messages WHERE channel_id = $1`, channel.ID))
+	assert.Equal(t, 2, countRows(t, db, `SELECT COUNT(*) FROM pg_inherits WHERE inhparent = 'archived_messages'::regclass`), "a partition per month")
+
+	page, err := repo.GetChannelMessages(ctx, channel.ID, &recent, 2)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, oldReply, page[0].ID, "paging carries on from the messages table")
+	assert.Equal(t, repliedToOld, *page[0].ReplyToID)
+	assert.Equal(t, server.ID, *page[0].ServerID)
+	assert.Equal(t, repliedToOld, page[1].ID)
+	page, err = repo.GetChannelMessages(ctx, channel.ID, &page[1].ID, 2)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, withAttachment, page[0].ID)
+	assert.Equal(t, "hi", page[0].Content)
+	require.Len(t, page[0].Attachments, 1)
+	assert.Equal(t, "cat.png", page[0].Attachments[0].Filename)
+
+	erasure := NewContentErasureRepository(db)
+	remaining, err := erasure.CountUserContent(ctx, author.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 6, remaining, "archived messages are counted for erasure")
+	counts, err := erasure.ScrubUserContent(ctx, author.ID, 10)
+	require.NoError(t, err)
+	assert.Equal(t, models.ContentScrubCounts{Messages: 6, Attachments: 1}, counts)
+	assert.Zero(t, countRows(t, db, `SELECT COUNT(*) FROM archived_messages WHERE content <> '' OR attachments <> '[]'`))
+}
+
+func TestKeysetPages(t *testing.T) {
+	db := migratedDB(t)
+	servers := NewServerRepository(db)
+	ctx := context.Background()
+	owner := createUser(t, db)
+	server := createServer(t, db, owner.ID)
+	channel := createChannel(t, db, server.ID, 0)
+
+	// Two members joined at the same instant, so only the key tells them apart
+	joined := time.Date(2026, 2, 14, 12, 0, 0, 0, time.UTC)
+	for _, at := range []time.Time{joined, joined, joined.Add(time.Hour)} {
+		_, err := db.Exec(`INSERT INTO members (server_id, user_id, joined_at) VALUES ($1, $2, $3)`, server.ID, createUser(t, db).ID, at)
+		require.NoError(t, err)
+	}
+
+	all, err := servers.PageMembers(ctx, server.ID, nil, 10)
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	cursorOf := func(member *models.Member) models.PageCursor {
+		return models.PageCursor{List: models.PageMembers, Time: &member.JoinedAt, Key: member.UserID.String()}
+	}
+	after := cursorOf(all[0])
+	page, err := servers.PageMembers(ctx, server.ID, &after, 1)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, all[1].UserID, page[0].UserID)
+	after = cursorOf(all[1])
+	page, err = servers.PageMembers(ctx, server.ID, &after, 10)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, all[2].UserID, page[0].UserID, "ties are broken by user ID")
+	before := cursorOf(all[2])
+	before.Backward = true
+	page, err = servers.PageMembers(ctx, server.ID, &before, 10)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, all[1].UserID, page[0].UserID, "read backward nearest first")
+
+	roles := NewRoleRepository(db)
+	for position := 1; position <= 2; position++ {
+		require.NoError(t, roles.Create(ctx, &models.Role{ID: uuid.New(), ServerID: server.ID, Name: "role", Position: position, CreatedAt: joined}))
+	}
+	rolePage, err := roles.PageRoles(ctx, server.ID, nil, 1)
+	require.NoError(t, err)
+	require.Len(t, rolePage, 1)
+	assert.Equal(t, 2, rolePage[0].Position)
+	next := models.PageCursor{List: models.PageRoles, Position: &rolePage[0].Position, Key: rolePage[0].ID.String()}
+	rolePage, err = roles.PageRoles(ctx, server.ID, &next, 1)
+	require.NoError(t, err)
+	require.Len(t, rolePage, 1)
+	assert.Equal(t, 1, rolePage[0].Position)
+
+	search := NewSearchRepository(db)
+	for i := 0; i < 3; i++ {
+		insertMessage(t, db, channel.ID, owner.ID, joined)
+	}
+	result, err := search.SearchMessages(ctx, services.SearchMessageOptions{Query: "hi", ChannelID: &channel.ID, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, result.Messages, 2)
+	assert.True(t, result.HasMore)
+	last := result.Messages[1]
+	searchAfter := models.PageCursor{List: models.PageSearch, Time: &last.CreatedAt, Key: last.ID.String()}
+	result, err = search.SearchMessages(ctx, services.SearchMessageOptions{Query: "hi", ChannelID: &channel.ID, Limit: 2, Cursor: &searchAfter})
+	require.NoError(t, err)
+	require.Len(t, result.Messages, 1)
+	assert.False(t, result.HasMore)
+	assert.Equal(t, 3, result.Total, "the total counts every match, not just those past the cursor")
+}
@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// IntegrityRepository finds and repairs rows that break invariants the
+// rest of the schema relies on: dense position sequences, and children
+// whose parent row is gone (possible after restores run without foreign
+// key checks).
+type IntegrityRepository struct {
+	db *sqlx.DB
+}
+
+func NewIntegrityRepository(db *sqlx.DB) *IntegrityRepository {
+	return &IntegrityRepository{db: db}
+}
+
+// Channels in a server are positioned 0..n-1 in display order
+const channelOrder = `position NULLS LAST, created_at, id`
+
+// Roles are positioned 0..n-1 with @everyone always at the bottom
+const roleOrder = `is_default DESC, position NULLS LAST, created_at, id`
+
+// ServersWithChannelPositionDrift returns servers whose channel positions
+// have gaps or duplicates
+func (r *IntegrityRepository) ServersWithChannelPositionDrift(ctx context.Context) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.SelectContext(ctx, &ids, `
+		SELECT DISTINCT server_id FROM (
+			SELECT server_id, position,
+				ROW_NUMBER() OVER (PARTITION BY server_id ORDER BY `+channelOrder+`) - 1 AS expected
+			FROM channels
+			WHERE server_id IS NOT NULL
+		) c
+		WHERE position IS DISTINCT FROM expected
+	`)
+	return ids, err
+}
+
+// ServersWithRolePositionDrift returns servers whose role positions have
+// gaps or duplicates
+func (r *IntegrityRepository) ServersWithRolePositionDrift(ctx context.Context) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.SelectContext(ctx, &ids, `
+		SELECT DISTINCT server_id FROM (
+			SELECT server_id, position,
+				ROW_NUMBER() OVER (PARTITION BY server_id ORDER BY `+roleOrder+`) - 1 AS expected
+			FROM roles
+		) r
+		WHERE position IS DISTINCT FROM expected
+	`)
+	return ids, err
+}
+
+// CompactChannelPositions renumbers a server's channels 0..n-1, keeping
+// their current order. Moved channels get a new version.
+func (r *IntegrityRepository) CompactChannelPositions(ctx context.Context, serverID uuid.UUID) (int, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE channels c SET position = o.expected, version = c.version + 1
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (ORDER BY `+channelOrder+`) - 1 AS expected
+			FROM channels
+			WHERE server_id = $1
+		) o
+		WHERE c.id = o.id AND c.position IS DISTINCT FROM o.expected
+	`, serverID)
+	if err != nil {
+		return 0, err
+	}
+	count, _ := result.RowsAffected()
+	return int(count), nil
+}
+
+// CompactRolePositions renumbers a server's roles 0..n-1, keeping their
+// current order
+func (r *IntegrityRepository) CompactRolePositions(ctx context.Context, serverID uuid.UUID) (int, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE roles ro SET position = o.expected
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (ORDER BY `+roleOrder+`) - 1 AS expected
+			FROM roles
+			WHERE server_id = $1
+		) o
+		WHERE ro.id = o.id AND ro.position IS DISTINCT FROM o.expected
+	`, serverID)
+	if err != nil {
+		return 0, err
+	}
+	count, _ := result.RowsAffected()
+	return int(count), nil
+}
+
+const orphanedChannels = `
+	server_id IS NOT NULL
+	AND NOT EXISTS (SELECT 1 FROM servers s WHERE s.id = channels.server_id)
+`
+
+const orphanedMembers = `
+	NOT EXISTS (SELECT 1 FROM users u WHERE u.id = members.user_id)
+	OR NOT EXISTS (SELECT 1 FROM servers s WHERE s.id = members.server_id)
+`
+
+// CountOrphanedChannels counts server channels whose server is gone
+func (r *IntegrityRepository) CountOrphanedChannels(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM channels WHERE `+orphanedChannels)
+	return count, err
+}
+
+// DeleteOrphanedChannels removes server channels whose server is gone
+func (r *IntegrityRepository) DeleteOrphanedChannels(ctx context.Context) (int, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM channels WHERE `+orphanedChannels)
+	if err != nil {
+		return 0, err
+	}
+	count, _ := result.RowsAffected()
+	return int(count), nil
+}
+
+// CountOrphanedMembers counts memberships whose user or server is gone
+func (r *IntegrityRepository) CountOrphanedMembers(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM members WHERE `+orphanedMembers)
+	return count, err
+}
+
+// DeleteOrphanedMembers removes memberships whose user or server is gone
+func (r *IntegrityRepository) DeleteOrphanedMembers(ctx context.Context) (int, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM members WHERE `+orphanedMembers)
+	if err != nil {
+		return 0, err
+	}
+	count, _ := result.RowsAffected()
+	return int(count), nil
+}
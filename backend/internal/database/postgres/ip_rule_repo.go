@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// IPRuleRepository stores the networks instance admins block or allow
+type IPRuleRepository struct {
+	db *sqlx.DB
+}
+
+func NewIPRuleRepository(db *sqlx.DB) *IPRuleRepository {
+	return &IPRuleRepository{db: db}
+}
+
+// List returns every rule, including expired ones, oldest first
+func (r *IPRuleRepository) List(ctx context.Context) ([]*models.IPRule, error) {
+	rules := []*models.IPRule{}
+	err := r.db.SelectContext(ctx, &rules, `
+		SELECT id, action, cidr::text AS cidr, asn, reason, creator_id, created_at, expires_at
+		FROM ip_rules ORDER BY created_at, id
+	`)
+	return rules, err
+}
+
+// Create adds a rule. It returns services.ErrIPRuleExists if the network
+// already has a rule with the same action.
+func (r *IPRuleRepository) Create(ctx context.Context, rule *models.IPRule) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO ip_rules (id, action, cidr, asn, reason, creator_id, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, rule.ID, rule.Action, rule.CIDR, rule.ASN, rule.Reason, rule.CreatorID, rule.CreatedAt, rule.ExpiresAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return services.ErrIPRuleExists
+	}
+	return err
+}
+
+// Delete removes a rule, reporting whether it existed
+func (r *IPRuleRepository) Delete(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM ip_rules WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
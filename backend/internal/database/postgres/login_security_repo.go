@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"hearth/internal/models"
+)
+
+// LoginSecurityRepository stores failed login counts, lockouts and the
+// devices users log in from
+type LoginSecurityRepository struct {
+	db *sqlx.DB
+}
+
+func NewLoginSecurityRepository(db *sqlx.DB) *LoginSecurityRepository {
+	return &LoginSecurityRepository{db: db}
+}
+
+// GetLockout returns the account's lockout state, or nil if there's none
+func (r *LoginSecurityRepository) GetLockout(ctx context.Context, userID uuid.UUID) (*models.AccountLockout, error) {
+	var lockout models.AccountLockout
+	err := r.db.GetContext(ctx, &lockout, `SELECT * FROM account_lockouts WHERE user_id = $1`, userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &lockout, nil
+}
+
+// RecordFailure counts a failed login in one statement, so concurrent
+// failures are all counted
+func (r *LoginSecurityRepository) RecordFailure(ctx context.Context, userID uuid.UUID, at, windowStart time.Time) (*models.AccountLockout, error) {
+	var lockout models.AccountLockout
+	err := r.db.GetContext(ctx, &lockout, `
+		INSERT INTO account_lockouts (user_id, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET
+			failures = CASE
+				WHEN account_lockouts.last_failure_at IS NULL OR account_lockouts.last_failure_at < $3 THEN 1
+				ELSE account_lockouts.failures + 1
+			END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING *
+	`, userID, at, windowStart)
+	if err != nil {
+		return nil, err
+	}
+	return &lockout, nil
+}
+
+// Lock locks the account until the given time and counts the lockout
+func (r *LoginSecurityRepository) Lock(ctx context.Context, userID uuid.UUID, until time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE account_lockouts SET failures = 0, lockouts = lockouts + 1, locked_until = $2
+		WHERE user_id = $1
+	`, userID, until)
+	return err
+}
+
+// ClearLockout forgets the account's failures and lockouts
+func (r *LoginSecurityRepository) ClearLockout(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM account_lockouts WHERE user_id = $1`, userID)
+	return err
+}
+
+// HasDevices reports whether the user has logged in from any device
+func (r *LoginSecurityRepository) HasDevices(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM login_devices WHERE user_id = $1)`, userID)
+	return exists, err
+}
+
+// TouchDevice records a login from the device, keeping when it was first
+// seen, and reports whether it was already known
+func (r *LoginSecurityRepository) TouchDevice(ctx context.Context, device *models.LoginDevice) (bool, error) {
+	// xmax is only set on rows the upsert updated rather than inserted
+	var known bool
+	err := r.db.GetContext(ctx, &known, `
+		INSERT INTO login_devices (user_id, device_hash, user_agent, ip_address, first_seen_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, device_hash) DO UPDATE SET
+			user_agent = EXCLUDED.user_agent,
+			ip_address = EXCLUDED.ip_address,
+			last_seen_at = EXCLUDED.last_seen_at
+		RETURNING xmax <> 0
+	`, device.UserID, device.DeviceHash, device.UserAgent, device.IPAddress, device.FirstSeenAt, device.LastSeenAt)
+	return known, err
+}
@@ -0,0 +1,149 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"hearth/internal/models"
+)
+
+// MemberVerificationRepository stores servers' membership screening forms
+// and the answers members gave
+type MemberVerificationRepository struct {
+	db *sqlx.DB
+}
+
+func NewMemberVerificationRepository(db *sqlx.DB) *MemberVerificationRepository {
+	return &MemberVerificationRepository{db: db}
+}
+
+// memberVerificationRow is a form as stored, with its fields still encoded
+type memberVerificationRow struct {
+	ServerID    uuid.UUID `db:"server_id"`
+	Enabled     bool      `db:"enabled"`
+	Description *string   `db:"description"`
+	FormFields  []byte    `db:"form_fields"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+// GetSettings returns the server's form, or the default form if it never
+// set one up
+func (r *MemberVerificationRepository) GetSettings(ctx context.Context, serverID uuid.UUID) (*models.MemberVerification, error) {
+	var row memberVerificationRow
+	err := r.db.GetContext(ctx, &row, `
+		SELECT server_id, enabled, description, form_fields, updated_at
+		FROM member_verification WHERE server_id = $1
+	`, serverID)
+	if err == sql.ErrNoRows {
+		return models.DefaultMemberVerification(serverID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	settings := &models.MemberVerification{
+		ServerID:    row.ServerID,
+		Enabled:     row.Enabled,
+		Description: row.Description,
+		Version:     row.UpdatedAt,
+	}
+	if err := json.Unmarshal(row.FormFields, &settings.FormFields); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// SaveSettings creates or replaces the server's form. Version is stored
+// as the form's updated_at.
+func (r *MemberVerificationRepository) SaveSettings(ctx context.Context, settings *models.MemberVerification) error {
+	fields, err := json.Marshal(settings.FormFields)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO member_verification (server_id, enabled, description, form_fields, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (server_id) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			description = EXCLUDED.description,
+			form_fields = EXCLUDED.form_fields,
+			updated_at = EXCLUDED.updated_at
+	`, settings.ServerID, settings.Enabled, settings.Description, fields, settings.Version)
+	return err
+}
+
+// ClearPending lets every pending member of the server in and returns who
+// they were
+func (r *MemberVerificationRepository) ClearPending(ctx context.Context, serverID uuid.UUID) ([]uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	err := r.db.SelectContext(ctx, &userIDs,
+		`UPDATE members SET pending = FALSE WHERE server_id = $1 AND pending RETURNING user_id`, serverID)
+	return userIDs, err
+}
+
+// Accept clears a member's pending flag and stores their answers. It
+// reports false if the member wasn't pending.
+func (r *MemberVerificationRepository) Accept(ctx context.Context, response *models.MemberVerificationResponse) (bool, error) {
+	answers, err := json.Marshal(response.Answers)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE members SET pending = FALSE WHERE server_id = $1 AND user_id = $2 AND pending`,
+		response.ServerID, response.UserID)
+	if err != nil {
+		return false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO member_verification_responses (server_id, user_id, answers, submitted_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (server_id, user_id) DO UPDATE SET
+			answers = EXCLUDED.answers,
+			submitted_at = EXCLUDED.submitted_at
+	`, response.ServerID, response.UserID, answers, response.SubmittedAt)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// GetResponse returns what a member submitted, or nil if they never went
+// through screening
+func (r *MemberVerificationRepository) GetResponse(ctx context.Context, serverID, userID uuid.UUID) (*models.MemberVerificationResponse, error) {
+	var row struct {
+		Answers     []byte    `db:"answers"`
+		SubmittedAt time.Time `db:"submitted_at"`
+	}
+	err := r.db.GetContext(ctx, &row, `
+		SELECT answers, submitted_at FROM member_verification_responses
+		WHERE server_id = $1 AND user_id = $2
+	`, serverID, userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.MemberVerificationResponse{ServerID: serverID, UserID: userID, SubmittedAt: row.SubmittedAt}
+	if err := json.Unmarshal(row.Answers, &response.Answers); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
@@ -0,0 +1,179 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"hearth/internal/models"
+)
+
+// MessageArchiveRepository moves old messages into the partitioned
+// archived_messages table and reads them back
+type MessageArchiveRepository struct {
+	db *sqlx.DB
+}
+
+func NewMessageArchiveRepository(db *sqlx.DB) *MessageArchiveRepository {
+	return &MessageArchiveRepository{db: db}
+}
+
+const archivedMessageColumns = `id, channel_id, server_id, author_id, COALESCE(content, '') AS content,
+	COALESCE(encrypted_content, '') AS encrypted_content, COALESCE(type, 0) AS type, reply_to_id, thread_id,
+	COALESCE(pinned, FALSE) AS pinned, COALESCE(tts, FALSE) AS tts, COALESCE(mentions_everyone, FALSE) AS mention_everyone,
+	COALESCE(flags, 0) AS flags, created_at, edited_at, forwarded_from, embeds, attachments AS attachments_json`
+
+type archivedMessageRow struct {
+	models.Message
+	AttachmentsJSON []byte `db:"attachments_json"`
+}
+
+// LargeServers returns the servers with at least minMembers members
+func (r *MessageArchiveRepository) LargeServers(ctx context.Context, minMembers int) ([]uuid.UUID, error) {
+	servers := []uuid.UUID{}
+	err := r.db.SelectContext(ctx, &servers, `
+		SELECT server_id FROM members
+		GROUP BY server_id
+		HAVING COUNT(*) >= $1
+	`, minMembers)
+	return servers, err
+}
+
+// ArchiveServerMessages moves up to limit of the server's messages sent
+// before cutoff into the archive, oldest first, and returns how many it
+// moved. Pinned and saved messages stay, as do messages that are still
+// replied to from the messages table, so deleting them doesn't detach the
+// replies; they're moved once their replies have been.
+func (r *MessageArchiveRepository) ArchiveServerMessages(ctx context.Context, serverID uuid.UUID, cutoff time.Time, limit int) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var batch []struct {
+		ID        uuid.UUID `db:"id"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+	err = tx.SelectContext(ctx, &batch, `
+		SELECT m.id, m.created_at FROM messages m
+		JOIN channels c ON c.id = m.channel_id
+		WHERE c.server_id = $1 AND m.created_at < $2
+			AND NOT COALESCE(m.pinned, FALSE)
+			AND NOT EXISTS (SELECT 1 FROM saved_messages s WHERE s.message_id = m.id)
+			AND NOT EXISTS (SELECT 1 FROM messages r WHERE r.reply_to_id = m.id)
+		ORDER BY m.created_at
+		LIMIT $3
+		FOR UPDATE OF m SKIP LOCKED
+	`, serverID, cutoff, limit)
+	if err != nil {
+		return 0, err
+	}
+	if len(batch) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]uuid.UUID, len(batch))
+	for i, message := range batch {
+		ids[i] = message.ID
+	}
+	if err := ensureArchivePartitions(ctx, tx, batch[0].CreatedAt, batch[len(batch)-1].CreatedAt); err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO archived_messages (
+			id, channel_id, server_id, author_id, content, encrypted_content, type, edited_at, pinned, tts,
+			mentions_everyone, reply_to_id, thread_id, flags, forwarded_from, embeds, attachments, created_at
+		)
+		SELECT m.id, m.channel_id, c.server_id, m.author_id, m.content, m.encrypted_content, m.type, m.edited_at, m.pinned, m.tts,
+			m.mentions_everyone, m.reply_to_id, m.thread_id, m.flags, m.forwarded_from, m.embeds,
+			COALESCE((SELECT jsonb_agg(to_jsonb(a) ORDER BY a.created_at) FROM attachments a WHERE a.message_id = m.id), '[]'),
+			m.created_at
+		FROM messages m
+		JOIN channels c ON c.id = m.channel_id
+		WHERE m.id = ANY($1::uuid[])
+	`, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM messages WHERE id = ANY($1::uuid[])`, pq.Array(ids)); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(batch), nil
+}
+
+// ensureArchivePartitions creates the monthly partitions covering first to
+// last. The lock keeps instances archiving at once from racing to create
+// the same partition.
+func ensureArchivePartitions(ctx context.Context, tx *sqlx.Tx, first, last time.Time) error {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('archived_messages_partitions'))`); err != nil {
+		return err
+	}
+
+	first = first.UTC()
+	month := time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for !month.After(last) {
+		next := month.AddDate(0, 1, 0)
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(
+			`CREATE TABLE IF NOT EXISTS archived_messages_%04d_%02d PARTITION OF archived_messages FOR VALUES FROM ('%s') TO ('%s')`,
+			month.Year(), month.Month(), month.Format(time.RFC3339), next.Format(time.RFC3339),
+		))
+		if err != nil {
+			return err
+		}
+		month = next
+	}
+	return nil
+}
+
+// GetChannelMessages returns a page of the channel's archived messages,
+// newest first. before may be an archived message or a message still in
+// the messages table, so paging carries on from GetMessages' last page.
+func (r *MessageArchiveRepository) GetChannelMessages(ctx context.Context, channelID uuid.UUID, before *uuid.UUID, limit int) ([]*models.Message, error) {
+	var rows []archivedMessageRow
+	var err error
+	if before != nil {
+		err = r.db.SelectContext(ctx, &rows, `
+			WITH cursor AS (
+				(SELECT created_at, id FROM archived_messages WHERE id = $2 AND channel_id = $1)
+				UNION ALL
+				(SELECT created_at, id FROM messages WHERE id = $2 AND channel_id = $1)
+				LIMIT 1
+			)
+			SELECT `+archivedMessageColumns+` FROM archived_messages
+			WHERE channel_id = $1 AND (created_at, id) < (SELECT created_at, id FROM cursor)
+			ORDER BY created_at DESC, id DESC
+			LIMIT $3
+		`, channelID, *before, limit)
+	} else {
+		err = r.db.SelectContext(ctx, &rows, `
+			SELECT `+archivedMessageColumns+` FROM archived_messages
+			WHERE channel_id = $1
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2
+		`, channelID, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]*models.Message, len(rows))
+	for i := range rows {
+		message := rows[i].Message
+		if err := json.Unmarshal(rows[i].AttachmentsJSON, &message.Attachments); err != nil {
+			return nil, err
+		}
+		messages[i] = &message
+	}
+	return messages, nil
+}
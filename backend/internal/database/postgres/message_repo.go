@@ -9,8 +9,12 @@ import (
 	"github.com/jmoiron/sqlx"
 
 	"hearth/internal/models"
+	"hearth/internal/services"
 )
 
+// maxReplyDepth is how far down a chain of replies GetReplies follows
+const maxReplyDepth = 100
+
 type MessageRepository struct {
 	db *sqlx.DB
 }
@@ -21,27 +25,20 @@ func NewMessageRepository(db *sqlx.DB) *MessageRepository {
 
 func (r *MessageRepository) Create(ctx context.Context, message *models.Message) error {
 	query := `
-		INSERT INTO messages (id, channel_id, server_id, author_id, content, encrypted_content, type, reply_to_id, pinned, tts, flags, created_at, edited_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		INSERT INTO messages (id, channel_id, server_id, author_id, content, encrypted_content, type, reply_to_id, pinned, tts, mentions_everyone, flags, created_at, edited_at, forwarded_from, embeds)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 	`
 	_, err := r.db.ExecContext(ctx, query,
 		message.ID, message.ChannelID, message.ServerID, message.AuthorID, message.Content,
 		message.EncryptedContent, message.Type, message.ReplyToID, message.Pinned,
-		message.TTS, message.Flags, message.CreatedAt, message.EditedAt,
+		message.TTS, message.MentionEveryone, message.Flags, message.CreatedAt, message.EditedAt,
+		message.ForwardedFrom, message.Embeds,
 	)
 	if err != nil {
 		return err
 	}
 	
-	// Insert mentions
-	if len(message.Mentions) > 0 {
-		for _, userID := range message.Mentions {
-			_, _ = r.db.ExecContext(ctx,
-				`INSERT INTO message_mentions (message_id, user_id) VALUES ($1, $2)`,
-				message.ID, userID,
-			)
-		}
-	}
+	r.saveMentions(ctx, message)
 	
 	// Insert attachments
 	if len(message.Attachments) > 0 {
@@ -72,18 +69,65 @@ func (r *MessageRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.
 	_ = r.db.SelectContext(ctx, &attachments, `SELECT * FROM attachments WHERE message_id = $1`, id)
 	message.Attachments = attachments
 	
+	// Load mentions
+	_ = r.db.SelectContext(ctx, &message.Mentions, `SELECT user_id FROM message_mentions WHERE message_id = $1`, id)
+	_ = r.db.SelectContext(ctx, &message.MentionRoles, `SELECT role_id FROM message_role_mentions WHERE message_id = $1`, id)
+	
 	return &message, nil
 }
 
 func (r *MessageRepository) Update(ctx context.Context, message *models.Message) error {
 	query := `
-		UPDATE messages SET content = $2, pinned = $3, edited_at = $4, flags = $5
+		UPDATE messages SET content = $2, pinned = $3, edited_at = $4, flags = $5, mentions_everyone = $6,
+			encrypted_content = $7, embeds = $8
 		WHERE id = $1
 	`
 	_, err := r.db.ExecContext(ctx, query,
-		message.ID, message.Content, message.Pinned, message.EditedAt, message.Flags,
+		message.ID, message.Content, message.Pinned, message.EditedAt, message.Flags, message.MentionEveryone,
+		message.EncryptedContent, message.Embeds,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	_, _ = r.db.ExecContext(ctx, `DELETE FROM message_mentions WHERE message_id = $1`, message.ID)
+	_, _ = r.db.ExecContext(ctx, `DELETE FROM message_role_mentions WHERE message_id = $1`, message.ID)
+	r.saveMentions(ctx, message)
+
+	return nil
+}
+
+// UpdateEmbeds saves embeds unfurled from content. If the message has been
+// edited to say something else since, they're dropped and it returns false.
+func (r *MessageRepository) UpdateEmbeds(ctx context.Context, messageID uuid.UUID, content string, embeds []models.Embed) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE messages SET embeds = $3 WHERE id = $1 AND content = $2`,
+		messageID, content, models.Embeds(embeds),
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// saveMentions records a message's resolved user and role mentions
+func (r *MessageRepository) saveMentions(ctx context.Context, message *models.Message) {
+	for _, userID := range message.Mentions {
+		_, _ = r.db.ExecContext(ctx,
+			`INSERT INTO message_mentions (message_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			message.ID, userID,
+		)
+	}
+	for _, roleID := range message.MentionRoles {
+		_, _ = r.db.ExecContext(ctx,
+			`INSERT INTO message_role_mentions (message_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			message.ID, roleID,
+		)
+	}
 }
 
 func (r *MessageRepository) Delete(ctx context.Context, id uuid.UUID) error {
@@ -91,6 +135,117 @@ func (r *MessageRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+// DeleteWithTombstone deletes a message and records a tombstone for it in
+// one transaction
+func (r *MessageRepository) DeleteWithTombstone(ctx context.Context, message *models.Message, deletedBy uuid.UUID) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO message_tombstones (message_id, channel_id, author_id, deleted_by, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (message_id) DO NOTHING
+	`, message.ID, message.ChannelID, message.AuthorID, deletedBy, message.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM messages WHERE id = $1`, message.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// BulkDelete deletes the given messages from a channel in one transaction,
+// leaving tombstones if tombstone is set. IDs not in the channel are
+// skipped. If any message was created before notBefore nothing is deleted
+// and services.ErrMessagesTooOld is returned.
+func (r *MessageRepository) BulkDelete(ctx context.Context, channelID uuid.UUID, messageIDs []uuid.UUID, notBefore time.Time, deletedBy uuid.UUID, tombstone bool) ([]*models.Message, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query, args, err := sqlx.In(`
+		SELECT id, channel_id, author_id, created_at FROM messages
+		WHERE channel_id = ? AND id IN (?)
+		FOR UPDATE
+	`, channelID, messageIDs)
+	if err != nil {
+		return nil, err
+	}
+	var messages []*models.Message
+	if err := tx.SelectContext(ctx, &messages, tx.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return messages, nil
+	}
+
+	ids := make([]uuid.UUID, len(messages))
+	for i, m := range messages {
+		if m.CreatedAt.Before(notBefore) {
+			return nil, services.ErrMessagesTooOld
+		}
+		ids[i] = m.ID
+	}
+
+	if tombstone {
+		for _, m := range messages {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO message_tombstones (message_id, channel_id, author_id, deleted_by, created_at)
+				VALUES ($1, $2, $3, $4, $5)
+				ON CONFLICT (message_id) DO NOTHING
+			`, m.ID, m.ChannelID, m.AuthorID, deletedBy, m.CreatedAt)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	query, args, err = sqlx.In(`DELETE FROM messages WHERE id IN (?)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, tx.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// GetChannelTombstones lists a channel's deleted messages, most recently
+// deleted first
+func (r *MessageRepository) GetChannelTombstones(ctx context.Context, channelID uuid.UUID, limit int) ([]*models.MessageTombstone, error) {
+	var tombstones []*models.MessageTombstone
+	err := r.db.SelectContext(ctx, &tombstones, `
+		SELECT message_id, channel_id, author_id, deleted_by, created_at, deleted_at
+		FROM message_tombstones
+		WHERE channel_id = $1
+		ORDER BY deleted_at DESC
+		LIMIT $2
+	`, channelID, limit)
+	return tombstones, err
+}
+
+// PurgeTombstones removes tombstones for messages deleted before cutoff
+func (r *MessageRepository) PurgeTombstones(ctx context.Context, cutoff time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM message_tombstones WHERE deleted_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	count, _ := result.RowsAffected()
+	return int(count), nil
+}
+
+// GetChannelMessages returns a page of the channel's messages in the order
+// they were sent, newest first unless reading after a message. before and
+// after must be messages in the channel.
 func (r *MessageRepository) GetChannelMessages(ctx context.Context, channelID uuid.UUID, before, after *uuid.UUID, limit int) ([]*models.Message, error) {
 	var messages []*models.Message
 	var query string
@@ -99,16 +254,16 @@ func (r *MessageRepository) GetChannelMessages(ctx context.Context, channelID uu
 	if before != nil {
 		query = `
 			SELECT * FROM messages 
-			WHERE channel_id = $1 AND id < $2
-			ORDER BY created_at DESC
+			WHERE channel_id = $1 AND (created_at, id) < (SELECT created_at, id FROM messages WHERE id = $2)
+			ORDER BY created_at DESC, id DESC
 			LIMIT $3
 		`
 		args = []interface{}{channelID, *before, limit}
 	} else if after != nil {
 		query = `
 			SELECT * FROM messages 
-			WHERE channel_id = $1 AND id > $2
-			ORDER BY created_at ASC
+			WHERE channel_id = $1 AND (created_at, id) > (SELECT created_at, id FROM messages WHERE id = $2)
+			ORDER BY created_at ASC, id ASC
 			LIMIT $3
 		`
 		args = []interface{}{channelID, *after, limit}
@@ -116,7 +271,7 @@ func (r *MessageRepository) GetChannelMessages(ctx context.Context, channelID uu
 		query = `
 			SELECT * FROM messages 
 			WHERE channel_id = $1
-			ORDER BY created_at DESC
+			ORDER BY created_at DESC, id DESC
 			LIMIT $2
 		`
 		args = []interface{}{channelID, limit}
@@ -127,31 +282,59 @@ func (r *MessageRepository) GetChannelMessages(ctx context.Context, channelID uu
 		return nil, err
 	}
 	
-	// Load attachments for all messages
-	if len(messages) > 0 {
-		messageIDs := make([]uuid.UUID, len(messages))
-		for i, m := range messages {
-			messageIDs[i] = m.ID
-		}
-		
-		var attachments []models.Attachment
-		query, args, _ := sqlx.In(`SELECT * FROM attachments WHERE message_id IN (?)`, messageIDs)
-		query = r.db.Rebind(query)
-		_ = r.db.SelectContext(ctx, &attachments, query, args...)
-		
-		// Map attachments to messages
-		attMap := make(map[uuid.UUID][]models.Attachment)
-		for _, att := range attachments {
-			attMap[att.MessageID] = append(attMap[att.MessageID], att)
-		}
-		for _, m := range messages {
-			m.Attachments = attMap[m.ID]
-		}
+	r.loadAttachments(ctx, messages)
+	return messages, nil
+}
+
+// GetReplies returns the message's replies, the replies to those and so on,
+// oldest first, starting after the given reply. Chains deeper than
+// maxReplyDepth are cut off there.
+func (r *MessageRepository) GetReplies(ctx context.Context, messageID uuid.UUID, after *uuid.UUID, limit int) ([]*models.Message, error) {
+	var messages []*models.Message
+	err := r.db.SelectContext(ctx, &messages, `
+		WITH RECURSIVE replies (id, depth) AS (
+			SELECT id, 1 FROM messages WHERE reply_to_id = $1
+			UNION ALL
+			SELECT m.id, replies.depth + 1
+			FROM messages m JOIN replies ON m.reply_to_id = replies.id
+			WHERE replies.depth < $2
+		)
+		SELECT m.* FROM messages m JOIN replies ON m.id = replies.id
+		WHERE $3::uuid IS NULL OR (m.created_at, m.id) > (SELECT created_at, id FROM messages WHERE id = $3)
+		ORDER BY m.created_at, m.id
+		LIMIT $4
+	`, messageID, maxReplyDepth, after, limit)
+	if err != nil {
+		return nil, err
 	}
-	
+	r.loadAttachments(ctx, messages)
 	return messages, nil
 }
 
+// loadAttachments fills in the attachments of the messages
+func (r *MessageRepository) loadAttachments(ctx context.Context, messages []*models.Message) {
+	if len(messages) == 0 {
+		return
+	}
+	messageIDs := make([]uuid.UUID, len(messages))
+	for i, m := range messages {
+		messageIDs[i] = m.ID
+	}
+
+	var attachments []models.Attachment
+	query, args, _ := sqlx.In(`SELECT * FROM attachments WHERE message_id IN (?)`, messageIDs)
+	query = r.db.Rebind(query)
+	_ = r.db.SelectContext(ctx, &attachments, query, args...)
+
+	attMap := make(map[uuid.UUID][]models.Attachment)
+	for _, att := range attachments {
+		attMap[att.MessageID] = append(attMap[att.MessageID], att)
+	}
+	for _, m := range messages {
+		m.Attachments = attMap[m.ID]
+	}
+}
+
 func (r *MessageRepository) GetPinnedMessages(ctx context.Context, channelID uuid.UUID) ([]*models.Message, error) {
 	var messages []*models.Message
 	query := `SELECT * FROM messages WHERE channel_id = $1 AND pinned = true ORDER BY created_at DESC`
@@ -164,7 +347,7 @@ func (r *MessageRepository) SearchMessages(ctx context.Context, query string, ch
 	
 	sqlQuery := `
 		SELECT * FROM messages 
-		WHERE content ILIKE $1
+		WHERE content ILIKE $1 AND COALESCE(encrypted_content, '') = ''
 	`
 	args := []interface{}{"%" + query + "%"}
 	argNum := 2
@@ -0,0 +1,10 @@
+-- Migration 008: Custom status emoji and expiry
+-- Users can pair their custom status with an emoji and let it clear itself
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS custom_status_emoji VARCHAR(64);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS custom_status_expires_at TIMESTAMPTZ;
+
+-- The expiry job only scans users with a pending expiry
+CREATE INDEX IF NOT EXISTS idx_users_custom_status_expires_at
+    ON users (custom_status_expires_at)
+    WHERE custom_status_expires_at IS NOT NULL;
@@ -0,0 +1,17 @@
+-- Migration 009: Message mentions
+-- Resolved user and role mentions, written when a message is sent or edited
+
+CREATE TABLE IF NOT EXISTS message_mentions (
+    message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (message_id, user_id)
+);
+
+-- Recent mentions lookups go by user
+CREATE INDEX IF NOT EXISTS idx_message_mentions_user ON message_mentions(user_id);
+
+CREATE TABLE IF NOT EXISTS message_role_mentions (
+    message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    PRIMARY KEY (message_id, role_id)
+);
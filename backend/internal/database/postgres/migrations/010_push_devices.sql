@@ -0,0 +1,29 @@
+-- Migration 010: Push notifications
+-- Device registrations for FCM, APNs and WebPush, and per-channel
+-- notification overrides
+
+CREATE TABLE IF NOT EXISTS push_devices (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    platform VARCHAR(16) NOT NULL CHECK (platform IN ('fcm', 'apns', 'webpush')),
+    token TEXT NOT NULL UNIQUE, -- Device token, or WebPush endpoint URL
+    p256dh TEXT,
+    auth TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_push_devices_user ON push_devices(user_id);
+
+CREATE TABLE IF NOT EXISTS channel_notification_settings (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    channel_id UUID NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    level VARCHAR(16) NOT NULL DEFAULT 'default' CHECK (level IN ('default', 'all', 'mentions', 'none')),
+    muted_until TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, channel_id)
+);
+
+-- The dispatcher looks up who follows every message in a channel
+CREATE INDEX IF NOT EXISTS idx_channel_notification_settings_level
+    ON channel_notification_settings(channel_id, level);
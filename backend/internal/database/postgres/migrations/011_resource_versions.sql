@@ -0,0 +1,6 @@
+-- Migration 011: Optimistic concurrency for channel and server edits
+-- Every update bumps version; PATCH requests may send the version they
+-- were based on (If-Match) and are refused if it has moved on
+
+ALTER TABLE channels ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE servers ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
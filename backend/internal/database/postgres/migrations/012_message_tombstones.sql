@@ -0,0 +1,18 @@
+-- Migration 012: Message tombstones
+-- Deleting a message removes its content but leaves a record of who wrote
+-- it, who deleted it and when, for moderation review. Tombstones are
+-- purged once they're older than the retention period.
+
+CREATE TABLE IF NOT EXISTS message_tombstones (
+    message_id UUID PRIMARY KEY,
+    channel_id UUID NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    author_id UUID NOT NULL, -- No FK: outlives account deletion for review
+    deleted_by UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL, -- When the message was sent, for ordering
+    deleted_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_tombstones_channel
+    ON message_tombstones(channel_id, deleted_at DESC);
+CREATE INDEX IF NOT EXISTS idx_message_tombstones_deleted_at
+    ON message_tombstones(deleted_at);
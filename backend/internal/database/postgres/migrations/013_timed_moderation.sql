@@ -0,0 +1,14 @@
+-- Migration 013: Timed bans and member timeouts
+-- A ban can end on its own, and a member can be timed out (no messages or
+-- reactions) until a set time. The expiry job lifts both.
+
+ALTER TABLE bans ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+ALTER TABLE members ADD COLUMN IF NOT EXISTS communication_disabled_until TIMESTAMPTZ;
+
+-- The expiry job only scans rows with a pending expiry
+CREATE INDEX IF NOT EXISTS idx_bans_expires_at
+    ON bans (expires_at)
+    WHERE expires_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_members_communication_disabled_until
+    ON members (communication_disabled_until)
+    WHERE communication_disabled_until IS NOT NULL;
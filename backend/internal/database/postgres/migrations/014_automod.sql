@@ -0,0 +1,21 @@
+-- Migration 014: AutoMod rules
+-- Server-defined filters checked before a message is stored. Each rule has
+-- one trigger and a list of actions; both are JSON so new trigger options
+-- don't need a migration.
+
+CREATE TABLE IF NOT EXISTS automod_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    creator_id UUID NOT NULL, -- Not a foreign key so rules outlive their creator
+    name VARCHAR(100) NOT NULL,
+    trigger_type VARCHAR(32) NOT NULL CHECK (trigger_type IN ('keyword', 'regex', 'invite_link', 'mention_spam', 'message_rate')),
+    trigger_metadata JSONB NOT NULL DEFAULT '{}',
+    actions JSONB NOT NULL DEFAULT '[]',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    exempt_roles UUID[] NOT NULL DEFAULT '{}',
+    exempt_channels UUID[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_automod_rules_server ON automod_rules(server_id);
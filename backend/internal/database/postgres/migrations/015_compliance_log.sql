@@ -0,0 +1,38 @@
+-- Migration 015: Compliance log
+-- One row per mutating API request when compliance logging is on. The
+-- table is append-only: rows can't be updated, and can only be deleted by
+-- the retention purge, which sets hearth.compliance_purge for its
+-- transaction.
+
+CREATE TABLE IF NOT EXISTS compliance_log (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    method VARCHAR(10) NOT NULL,
+    route VARCHAR(255) NOT NULL, -- Route template, never the raw path
+    actor_id UUID, -- Not a foreign key so entries outlive deleted accounts
+    targets JSONB NOT NULL DEFAULT '{}',
+    status SMALLINT NOT NULL,
+    outcome VARCHAR(16) NOT NULL CHECK (outcome IN ('success', 'denied', 'invalid', 'error')),
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_compliance_log_created_at ON compliance_log(created_at);
+CREATE INDEX IF NOT EXISTS idx_compliance_log_actor ON compliance_log(actor_id, created_at);
+
+CREATE OR REPLACE FUNCTION compliance_log_append_only()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' AND current_setting('hearth.compliance_purge', true) = 'on' THEN
+        RETURN OLD;
+    END IF;
+    RAISE EXCEPTION 'compliance_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS compliance_log_no_update ON compliance_log;
+CREATE TRIGGER compliance_log_no_update BEFORE UPDATE OR DELETE ON compliance_log
+    FOR EACH ROW EXECUTE FUNCTION compliance_log_append_only();
+DROP TRIGGER IF EXISTS compliance_log_no_truncate ON compliance_log;
+CREATE TRIGGER compliance_log_no_truncate BEFORE TRUNCATE ON compliance_log
+    FOR EACH STATEMENT EXECUTE FUNCTION compliance_log_append_only();
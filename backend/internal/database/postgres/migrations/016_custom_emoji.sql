@@ -0,0 +1,18 @@
+-- Migration 016: Custom emoji
+-- Server-uploaded emoji for messages and reactions. The image lives in the
+-- file storage backend; path is kept so it can be deleted with the emoji.
+-- Reactions refer to a custom emoji by its ID in reactions.emoji.
+
+CREATE TABLE IF NOT EXISTS emojis (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    creator_id UUID NOT NULL, -- Not a foreign key so emoji outlive their creator
+    name VARCHAR(32) NOT NULL,
+    animated BOOLEAN NOT NULL DEFAULT FALSE,
+    url TEXT NOT NULL,
+    path TEXT NOT NULL,
+    size BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_emojis_server_name ON emojis(server_id, LOWER(name));
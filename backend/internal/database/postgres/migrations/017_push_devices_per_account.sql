@@ -0,0 +1,11 @@
+-- Migration 017: Push registrations per account
+-- A device signed in to several accounts registers its token once per
+-- account, so every account on it keeps getting pushes. Tokens are unique
+-- per user instead of globally.
+
+ALTER TABLE push_devices DROP CONSTRAINT IF EXISTS push_devices_token_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_push_devices_user_token ON push_devices(user_id, token);
+
+-- DeleteByToken drops a dead token from every account that registered it
+CREATE INDEX IF NOT EXISTS idx_push_devices_token ON push_devices(token);
@@ -0,0 +1,30 @@
+-- Migration 018: Forum channels
+-- Every post in a forum channel is a thread whose first message is the
+-- post body. Forums have their own tags, which posts can be filed under,
+-- and settings for how posts are listed and whether a tag is required.
+
+CREATE TABLE IF NOT EXISTS forum_settings (
+    channel_id UUID PRIMARY KEY REFERENCES channels(id) ON DELETE CASCADE,
+    default_sort_order VARCHAR(16) NOT NULL DEFAULT 'latest_activity'
+        CHECK (default_sort_order IN ('latest_activity', 'creation_date')),
+    require_tag BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS forum_tags (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    channel_id UUID NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    name VARCHAR(20) NOT NULL,
+    emoji VARCHAR(64), -- A unicode emoji or a custom emoji's ID
+    position INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_forum_tags_channel ON forum_tags(channel_id, position);
+
+CREATE TABLE IF NOT EXISTS thread_tags (
+    thread_id UUID NOT NULL REFERENCES threads(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES forum_tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (thread_id, tag_id)
+);
+
+-- Filtering a forum by tag starts from the tag
+CREATE INDEX IF NOT EXISTS idx_thread_tags_tag ON thread_tags(tag_id);
@@ -0,0 +1,51 @@
+-- Migration 019: Unique usernames
+-- Usernames become unique handles, compared case-insensitively. The
+-- username#discriminator pairs were never assigned, so every existing
+-- account is moved onto a handle here.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username_changed_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS username_history (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    username VARCHAR(32) NOT NULL,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_username_history_user ON username_history(user_id, changed_at DESC);
+
+-- Handles are 2-32 letters, numbers, underscores or periods. Other
+-- characters become underscores, and when several accounts end up with the
+-- same handle the oldest keeps it while the rest get a suffix from their
+-- ID. Renamed accounts are flagged (1 << 7) so they can pick a new handle
+-- without waiting out the change cooldown.
+WITH cleaned AS (
+    SELECT id, username, created_at,
+        RPAD(LEFT(REGEXP_REPLACE(username, '[^A-Za-z0-9_.]', '_', 'g'), 32), 2, '_') AS handle
+    FROM users
+), ranked AS (
+    SELECT id, username, handle,
+        ROW_NUMBER() OVER (PARTITION BY LOWER(handle) ORDER BY created_at, id) AS claim
+    FROM cleaned
+), resolved AS (
+    SELECT id, username,
+        CASE WHEN claim = 1 THEN handle
+            ELSE LEFT(handle, 23) || '_' || LEFT(REPLACE(id::text, '-', ''), 8)
+        END AS handle
+    FROM ranked
+), history AS (
+    INSERT INTO username_history (user_id, username)
+    SELECT id, username FROM resolved WHERE handle <> username
+)
+UPDATE users u
+SET username = r.handle,
+    flags = COALESCE(u.flags, 0) | 128
+FROM resolved r
+WHERE u.id = r.id AND r.handle <> r.username;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_username_discriminator_key;
+ALTER TABLE users ALTER COLUMN discriminator SET DEFAULT '0000';
+UPDATE users SET discriminator = '0000' WHERE discriminator <> '0000';
+
+DROP INDEX IF EXISTS idx_users_username;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users(LOWER(username));
@@ -0,0 +1,16 @@
+-- Migration 020: Username rules
+-- Instance-wide reserved and banned usernames. Reserved names can only be
+-- taken by staff; banned names can't be taken by anyone. Existing accounts
+-- keep their names.
+
+CREATE TABLE IF NOT EXISTS username_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('reserved', 'banned')),
+    pattern VARCHAR(260) NOT NULL,
+    regex BOOLEAN NOT NULL DEFAULT FALSE,
+    reason VARCHAR(255),
+    creator_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_username_rules_pattern ON username_rules(kind, regex, LOWER(pattern));
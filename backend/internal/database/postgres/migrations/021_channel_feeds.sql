@@ -0,0 +1,10 @@
+-- Migration 021: Channel feeds
+-- A row makes the channel's recent messages readable as an Atom feed by
+-- anyone with the token. Deleting the row turns the feed off.
+
+CREATE TABLE IF NOT EXISTS channel_feeds (
+    channel_id UUID PRIMARY KEY REFERENCES channels(id) ON DELETE CASCADE,
+    token VARCHAR(64) NOT NULL,
+    creator_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,22 @@
+-- Migration 022: Membership screening
+-- Servers with screening on ask new members to accept their rules and
+-- answer a few questions. Until they do, members.pending is set and they
+-- can read but not take part.
+
+CREATE TABLE IF NOT EXISTS member_verification (
+    server_id UUID PRIMARY KEY REFERENCES servers(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    description TEXT,
+    form_fields JSONB NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- A member's answers, kept while they stay in the server
+CREATE TABLE IF NOT EXISTS member_verification_responses (
+    server_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    answers JSONB NOT NULL DEFAULT '[]',
+    submitted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (server_id, user_id),
+    FOREIGN KEY (server_id, user_id) REFERENCES members(server_id, user_id) ON DELETE CASCADE
+);
@@ -0,0 +1,11 @@
+-- Migration 023: Channel schedules (office hours)
+-- A row opens the channel during its weekly hours and closes it outside
+-- them and during closures. Deleting the row leaves the channel open.
+
+CREATE TABLE IF NOT EXISTS channel_schedules (
+    channel_id UUID PRIMARY KEY REFERENCES channels(id) ON DELETE CASCADE,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    hours JSONB NOT NULL DEFAULT '[]',
+    closures JSONB NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
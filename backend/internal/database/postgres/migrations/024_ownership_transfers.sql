@@ -0,0 +1,15 @@
+-- Migration 024: Two-step ownership transfers and co-owners
+-- A transfer waits for the new owner to accept it. Co-owners hold every
+-- permission and outrank everyone but the owner.
+
+ALTER TABLE members ADD COLUMN IF NOT EXISTS co_owner BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- At most one pending transfer per server
+CREATE TABLE IF NOT EXISTS ownership_transfers (
+    server_id UUID PRIMARY KEY REFERENCES servers(id) ON DELETE CASCADE,
+    from_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    to_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    keep_co_owner BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
@@ -0,0 +1,31 @@
+-- Migration 025: Welcome screens and onboarding
+-- The welcome screen is shown to members as they join. Onboarding prompts
+-- let them pick roles and channels; their picks are kept so they can
+-- change them later.
+
+CREATE TABLE IF NOT EXISTS welcome_screens (
+    server_id UUID PRIMARY KEY REFERENCES servers(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    description TEXT,
+    welcome_channels JSONB NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS onboarding (
+    server_id UUID PRIMARY KEY REFERENCES servers(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    prompts JSONB NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- A member's picks, kept while they stay in the server
+CREATE TABLE IF NOT EXISTS onboarding_responses (
+    server_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    option_ids JSONB NOT NULL DEFAULT '[]',
+    role_ids JSONB NOT NULL DEFAULT '[]',
+    channel_ids JSONB NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (server_id, user_id),
+    FOREIGN KEY (server_id, user_id) REFERENCES members(server_id, user_id) ON DELETE CASCADE
+);
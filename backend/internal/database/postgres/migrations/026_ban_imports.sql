@@ -0,0 +1,35 @@
+-- Migration 026: Ban list imports
+-- Imports are queued and worked through in batches in the background. The
+-- entries are kept until the import finishes; the import row stays as a
+-- record of what it did.
+
+CREATE TABLE IF NOT EXISTS ban_imports (
+    id UUID PRIMARY KEY,
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    requested_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(16) NOT NULL DEFAULT 'queued',
+    total INT NOT NULL,
+    processed INT NOT NULL DEFAULT 0,
+    banned INT NOT NULL DEFAULT 0,
+    skipped INT NOT NULL DEFAULT 0,
+    error TEXT,
+    -- Held by whichever instance is working on it
+    locked_until TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_ban_imports_server ON ban_imports(server_id, created_at DESC);
+-- One unfinished import per server at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ban_imports_unfinished ON ban_imports(server_id)
+    WHERE status IN ('queued', 'running');
+
+-- Users may come from another instance's list and needn't exist here
+CREATE TABLE IF NOT EXISTS ban_import_entries (
+    import_id UUID NOT NULL REFERENCES ban_imports(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    user_id UUID NOT NULL,
+    reason TEXT,
+    PRIMARY KEY (import_id, position)
+);
@@ -0,0 +1,53 @@
+-- Migration 027: Shared blocklists
+-- Servers subscribe to blocklists kept by other servers or the instance.
+-- Users on a subscribed list are banned when they join, and members added
+-- to one later are banned by a background sync. A server can exempt
+-- individual users from a list it follows.
+
+CREATE TABLE IF NOT EXISTS blocklists (
+    id UUID PRIMARY KEY,
+    -- NULL for lists kept by the instance's staff
+    server_id UUID REFERENCES servers(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    public BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_blocklists_server ON blocklists(server_id);
+CREATE INDEX IF NOT EXISTS idx_blocklists_public ON blocklists(name) WHERE public;
+
+-- Users may come from another instance's list and needn't exist here
+CREATE TABLE IF NOT EXISTS blocklist_entries (
+    blocklist_id UUID NOT NULL REFERENCES blocklists(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    reason TEXT,
+    added_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (blocklist_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_blocklist_entries_user ON blocklist_entries(user_id);
+
+CREATE TABLE IF NOT EXISTS blocklist_subscriptions (
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    blocklist_id UUID NOT NULL REFERENCES blocklists(id) ON DELETE CASCADE,
+    subscribed_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    synced_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (server_id, blocklist_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_blocklist_subscriptions_blocklist ON blocklist_subscriptions(blocklist_id);
+
+CREATE TABLE IF NOT EXISTS blocklist_exemptions (
+    server_id UUID NOT NULL,
+    blocklist_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (server_id, blocklist_id, user_id),
+    FOREIGN KEY (server_id, blocklist_id) REFERENCES blocklist_subscriptions(server_id, blocklist_id) ON DELETE CASCADE
+);
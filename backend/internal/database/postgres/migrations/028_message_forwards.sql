@@ -0,0 +1,6 @@
+-- Migration 028: Message forwarding
+-- A forwarded message is a copy of another message, possibly from another
+-- channel or server. forwarded_from records where it came from, as it was
+-- when it was forwarded, so it survives the original being deleted.
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS forwarded_from JSONB;
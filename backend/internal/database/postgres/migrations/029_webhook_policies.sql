@@ -0,0 +1,18 @@
+-- Migration 029: Webhook policies
+-- Restrict which roles can create a server's webhooks and which domains
+-- their URLs may point at. A row without a channel is the server's policy;
+-- a channel's row replaces it for webhooks in that channel.
+
+CREATE TABLE IF NOT EXISTS webhook_policies (
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    channel_id UUID REFERENCES channels(id) ON DELETE CASCADE,
+    creator_role_ids UUID[] NOT NULL DEFAULT '{}',
+    allowed_domains TEXT[] NOT NULL DEFAULT '{}',
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_policies_server
+    ON webhook_policies(server_id) WHERE channel_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_policies_channel
+    ON webhook_policies(channel_id) WHERE channel_id IS NOT NULL;
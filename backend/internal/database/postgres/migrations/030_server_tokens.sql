@@ -0,0 +1,17 @@
+-- Migration 030: Server tokens
+-- API tokens for automation, limited to one server and a set of scopes.
+-- Only a hash of each token is kept.
+
+CREATE TABLE IF NOT EXISTS server_tokens (
+    id UUID PRIMARY KEY,
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    creator_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_server_tokens_server ON server_tokens(server_id, created_at DESC);
@@ -0,0 +1,49 @@
+-- Migration 031: End-to-end encryption keys
+-- Public device keys, the key epoch of each encrypted channel and the
+-- sender keys members send each other's devices. Only public keys and
+-- ciphertext are stored.
+
+CREATE TABLE IF NOT EXISTS e2ee_devices (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    device_id VARCHAR(64) NOT NULL,
+    identity_key TEXT NOT NULL,
+    signed_pre_key_id INTEGER NOT NULL,
+    signed_pre_key TEXT NOT NULL,
+    signed_key_signature TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, device_id)
+);
+
+CREATE TABLE IF NOT EXISTS e2ee_one_time_pre_keys (
+    user_id UUID NOT NULL,
+    device_id VARCHAR(64) NOT NULL,
+    key_id INTEGER NOT NULL,
+    public_key TEXT NOT NULL,
+    PRIMARY KEY (user_id, device_id, key_id),
+    FOREIGN KEY (user_id, device_id) REFERENCES e2ee_devices(user_id, device_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS channel_key_epochs (
+    channel_id UUID PRIMARY KEY REFERENCES channels(id) ON DELETE CASCADE,
+    epoch BIGINT NOT NULL DEFAULT 0,
+    rotated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Sender keys outlive their sender's device, so history stays readable,
+-- but go with the device they were sent to
+CREATE TABLE IF NOT EXISTS channel_sender_keys (
+    channel_id UUID NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    epoch BIGINT NOT NULL,
+    sender_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    sender_device_id VARCHAR(64) NOT NULL,
+    recipient_id UUID NOT NULL,
+    recipient_device_id VARCHAR(64) NOT NULL,
+    ciphertext TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (channel_id, epoch, sender_id, sender_device_id, recipient_id, recipient_device_id),
+    FOREIGN KEY (recipient_id, recipient_device_id) REFERENCES e2ee_devices(user_id, device_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_channel_sender_keys_recipient
+    ON channel_sender_keys(recipient_id, recipient_device_id, channel_id, epoch);
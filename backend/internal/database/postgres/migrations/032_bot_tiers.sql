@@ -0,0 +1,13 @@
+-- Migration 032: Bot verification tiers
+-- The tier instance admins gave a bot account, which scales its rate
+-- limits and caps the servers it can join. Bots without a row are
+-- unverified.
+
+CREATE TABLE IF NOT EXISTS bot_tiers (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    tier VARCHAR(16) NOT NULL CHECK (tier IN ('unverified', 'verified', 'partner')),
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_bot_tiers_tier ON bot_tiers(tier, updated_at DESC);
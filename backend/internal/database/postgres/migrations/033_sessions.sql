@@ -0,0 +1,16 @@
+-- Login sessions. Each row is a refresh token family: refreshing replaces
+-- the stored token hash, and deleting the row revokes the session. The
+-- table from 001 was never used, so it's reshaped in place.
+ALTER TABLE sessions RENAME COLUMN token_hash TO refresh_token_hash;
+ALTER TABLE sessions RENAME COLUMN last_used TO last_used_at;
+ALTER TABLE sessions DROP COLUMN device;
+DROP INDEX IF EXISTS idx_sessions_token;
+DROP INDEX IF EXISTS idx_sessions_user;
+CREATE INDEX idx_sessions_user ON sessions(user_id, last_used_at DESC);
+
+-- Logging out everywhere moves a user to a new token version; tokens
+-- issued under an older one are refused
+CREATE TABLE token_versions (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL DEFAULT 0
+);
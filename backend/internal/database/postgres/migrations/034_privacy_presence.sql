@@ -0,0 +1,2 @@
+-- Lets users show their online status to friends only
+ALTER TABLE user_settings ADD COLUMN privacy_presence_friends_only BOOLEAN NOT NULL DEFAULT false;
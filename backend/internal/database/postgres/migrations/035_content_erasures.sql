@@ -0,0 +1,34 @@
+-- Migration 035: Content erasures
+-- Erasing a user scrubs everything they wrote: their messages, thread
+-- replies and attachments, and copies of their messages others forwarded.
+-- Search reads message content directly, so scrubbed content also drops out
+-- of search. Erasures are worked through in batches in the background and
+-- the row stays as a record of what was scrubbed and when it was verified.
+
+CREATE TABLE IF NOT EXISTS content_erasures (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL, -- No FK: outlives account deletion for compliance
+    requested_by UUID NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'queued',
+    messages_scrubbed INT NOT NULL DEFAULT 0,
+    forwards_scrubbed INT NOT NULL DEFAULT 0,
+    attachments_removed INT NOT NULL DEFAULT 0,
+    -- Authored content still found by the last verification
+    remaining INT NOT NULL DEFAULT 0,
+    error TEXT,
+    -- Held by whichever instance is working on it
+    locked_until TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    verified_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_content_erasures_user ON content_erasures(user_id, created_at DESC);
+-- One unfinished erasure per user at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_content_erasures_unfinished ON content_erasures(user_id)
+    WHERE status IN ('queued', 'running');
+
+-- Finding forwarded copies of a user's messages
+CREATE INDEX IF NOT EXISTS idx_messages_forwarded_author
+    ON messages((forwarded_from->>'author_id')) WHERE forwarded_from IS NOT NULL;
@@ -0,0 +1,17 @@
+-- Migration 036: OAuth identities
+-- Accounts at external login providers (Google, GitHub, OIDC servers) and
+-- the users they log in as. An identity is linked the first time it logs
+-- in, either to the existing user with the same verified email or to a user
+-- created for it.
+
+CREATE TABLE IF NOT EXISTS oauth_identities (
+    provider VARCHAR(64) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_login_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_identities_user ON oauth_identities(user_id);
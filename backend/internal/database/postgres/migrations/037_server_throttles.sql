@@ -0,0 +1,13 @@
+-- Migration 037: Server throttles
+-- Per-server rate limits instance admins set in place of the instance
+-- defaults, to rein in an abusive server or give a large one more room.
+-- NULL limits use the default; 0 means unlimited.
+
+CREATE TABLE IF NOT EXISTS server_throttles (
+    server_id UUID PRIMARY KEY REFERENCES servers(id) ON DELETE CASCADE,
+    messages_per_second INTEGER CHECK (messages_per_second >= 0),
+    events_per_second INTEGER CHECK (events_per_second >= 0),
+    reason TEXT,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
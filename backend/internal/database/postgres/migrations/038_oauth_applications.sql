@@ -0,0 +1,39 @@
+-- Migration 038: OAuth applications
+-- Third-party apps users can authorize to act for them with OAuth2, the
+-- authorization codes they trade for tokens and the tokens themselves.
+-- Only hashes of client secrets, codes and tokens are kept.
+
+CREATE TABLE IF NOT EXISTS oauth_applications (
+    id UUID PRIMARY KEY,
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    redirect_uris TEXT[] NOT NULL DEFAULT '{}',
+    client_secret_hash CHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_applications_owner ON oauth_applications(owner_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+    code_hash CHAR(64) PRIMARY KEY,
+    application_id UUID NOT NULL REFERENCES oauth_applications(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    redirect_uri TEXT NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    code_challenge VARCHAR(128),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS oauth_tokens (
+    id UUID PRIMARY KEY,
+    application_id UUID NOT NULL REFERENCES oauth_applications(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    access_token_hash CHAR(64) NOT NULL UNIQUE,
+    refresh_token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_tokens_user ON oauth_tokens(user_id, application_id);
@@ -0,0 +1,16 @@
+-- Migration 039: Quota overrides
+-- Quotas instance admins set per tier of accounts in place of the static
+-- config. The default tier applies to everyone; premium and bot accounts
+-- get their tier on top of it. NULL limits use the tier below; 0 means
+-- unlimited.
+
+CREATE TABLE IF NOT EXISTS quota_overrides (
+    tier TEXT PRIMARY KEY CHECK (tier IN ('default', 'premium', 'bot')),
+    max_servers_owned INTEGER CHECK (max_servers_owned >= 0),
+    max_servers_joined INTEGER CHECK (max_servers_joined >= 0),
+    max_message_length INTEGER CHECK (max_message_length >= 0),
+    storage_mb BIGINT CHECK (storage_mb >= 0),
+    max_file_size_mb BIGINT CHECK (max_file_size_mb >= 0),
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
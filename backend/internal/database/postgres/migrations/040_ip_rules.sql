@@ -0,0 +1,19 @@
+-- Migration 040: IP rules
+-- Networks instance admins block or allow, by CIDR range or autonomous
+-- system number. Allow rules win over block rules and reputation
+-- providers. Rules past expires_at no longer apply.
+
+CREATE TABLE IF NOT EXISTS ip_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    action VARCHAR(16) NOT NULL CHECK (action IN ('block', 'allow')),
+    cidr CIDR,
+    asn BIGINT CHECK (asn > 0 AND asn <= 4294967295),
+    reason VARCHAR(255),
+    creator_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE,
+    CHECK ((cidr IS NULL) <> (asn IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ip_rules_cidr ON ip_rules(action, cidr) WHERE cidr IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ip_rules_asn ON ip_rules(action, asn) WHERE asn IS NOT NULL;
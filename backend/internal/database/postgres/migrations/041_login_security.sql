@@ -0,0 +1,30 @@
+-- Migration 041: Account lockout, login devices and password resets
+-- Failed logins are counted per account, and accounts that fail too often
+-- are locked for a while that doubles with each lockout. Devices users
+-- log in from are remembered so logins from new ones can be emailed about.
+-- Password resets unlock accounts.
+
+CREATE TABLE IF NOT EXISTS account_lockouts (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP WITH TIME ZONE,
+    lockouts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS login_devices (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    device_hash TEXT NOT NULL,
+    user_agent TEXT,
+    ip_address TEXT,
+    first_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, device_hash)
+);
+
+CREATE TABLE IF NOT EXISTS password_resets (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
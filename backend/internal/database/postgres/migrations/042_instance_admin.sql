@@ -0,0 +1,23 @@
+-- Migration 042: User suspensions and announcements
+-- Instance admins suspend accounts, which can't log in or use the API
+-- until the suspension is lifted or expires, and post announcements that
+-- every client shows until they're deleted or expire.
+
+CREATE TABLE IF NOT EXISTS user_suspensions (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    reason VARCHAR(512),
+    suspended_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS announcements (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    content VARCHAR(2000) NOT NULL,
+    level VARCHAR(16) NOT NULL CHECK (level IN ('info', 'warning', 'critical')),
+    creator_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_announcements_created ON announcements(created_at DESC);
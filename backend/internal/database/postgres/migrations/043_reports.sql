@@ -0,0 +1,41 @@
+-- Migration 043: Content reports
+-- Users report messages, users and servers to the instance's trust and
+-- safety team. Reports wait in a queue for an instance admin to review,
+-- and every change to one is kept in report_events.
+
+CREATE TABLE IF NOT EXISTS reports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    reporter_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    target_type VARCHAR(16) NOT NULL CHECK (target_type IN ('message', 'user', 'server')),
+    target_id UUID NOT NULL,
+    category VARCHAR(32) NOT NULL,
+    details VARCHAR(1000),
+    -- What was reported as it was then, since it may be edited or deleted
+    -- before anyone looks
+    target_user_id UUID,
+    server_id UUID,
+    channel_id UUID,
+    message_content TEXT,
+    status VARCHAR(16) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'reviewing', 'actioned', 'dismissed')),
+    assignee_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_reports_queue ON reports(status, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_reports_target ON reports(target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_reports_reporter ON reports(reporter_id) WHERE status IN ('open', 'reviewing');
+
+CREATE TABLE IF NOT EXISTS report_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    report_id UUID NOT NULL REFERENCES reports(id) ON DELETE CASCADE,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(16) NOT NULL,
+    status VARCHAR(16),
+    assignee_id UUID,
+    note VARCHAR(2000),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_events_report ON report_events(report_id, created_at);
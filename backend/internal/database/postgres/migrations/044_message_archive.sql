@@ -0,0 +1,34 @@
+-- Migration 044: Message archive
+-- Large servers' old messages move out of messages into archived_messages,
+-- which keeps GetMessages' indexes small. The archive is partitioned by
+-- month of created_at; partitions are created as messages are moved into
+-- them. Archived messages keep their attachments as a snapshot, but not
+-- reactions or mentions.
+
+CREATE TABLE IF NOT EXISTS archived_messages (
+    id UUID NOT NULL,
+    channel_id UUID NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    server_id UUID,
+    author_id UUID NOT NULL,
+    content TEXT,
+    encrypted_content TEXT,
+    type INTEGER DEFAULT 0,
+    edited_at TIMESTAMP WITH TIME ZONE,
+    pinned BOOLEAN DEFAULT FALSE,
+    tts BOOLEAN DEFAULT FALSE,
+    mentions_everyone BOOLEAN DEFAULT FALSE,
+    reply_to_id UUID,
+    thread_id UUID,
+    flags INTEGER DEFAULT 0,
+    forwarded_from JSONB,
+    attachments JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    archived_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+CREATE INDEX IF NOT EXISTS idx_archived_messages_channel ON archived_messages(channel_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_archived_messages_author ON archived_messages(author_id);
+-- Finding archived copies of a user's messages when erasing them
+CREATE INDEX IF NOT EXISTS idx_archived_messages_forwarded_author
+    ON archived_messages((forwarded_from->>'author_id')) WHERE forwarded_from IS NOT NULL;
@@ -0,0 +1,6 @@
+-- Migration 045: Optimistic concurrency for role edits
+-- Roles get the same version column as channels and servers (migration
+-- 011), so a PATCH based on a stale read is refused instead of silently
+-- overwriting someone else's change
+
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,7 @@
+-- Migration 046: Message embeds
+-- Link previews unfurled from a message's content. They're fetched after
+-- the message is sent and kept, so reading a message doesn't fetch them
+-- again.
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS embeds JSONB;
+ALTER TABLE archived_messages ADD COLUMN IF NOT EXISTS embeds JSONB;
@@ -0,0 +1,284 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"hearth/internal/models"
+)
+
+// OAuthAppRepository stores OAuth applications, their authorization codes
+// and the tokens users granted them
+type OAuthAppRepository struct {
+	db *sqlx.DB
+}
+
+func NewOAuthAppRepository(db *sqlx.DB) *OAuthAppRepository {
+	return &OAuthAppRepository{db: db}
+}
+
+const oauthAppColumns = `id, owner_id, name, redirect_uris, client_secret_hash, created_at`
+
+// oauthAppRow is an application as stored, with its redirect URIs still
+// encoded
+type oauthAppRow struct {
+	ID               uuid.UUID      `db:"id"`
+	OwnerID          uuid.UUID      `db:"owner_id"`
+	Name             string         `db:"name"`
+	RedirectURIs     pq.StringArray `db:"redirect_uris"`
+	ClientSecretHash string         `db:"client_secret_hash"`
+	CreatedAt        time.Time      `db:"created_at"`
+}
+
+func (row *oauthAppRow) application() *models.OAuthApplication {
+	return &models.OAuthApplication{
+		ID:               row.ID,
+		OwnerID:          row.OwnerID,
+		Name:             row.Name,
+		RedirectURIs:     stringsOrEmpty(row.RedirectURIs),
+		ClientSecretHash: row.ClientSecretHash,
+		CreatedAt:        row.CreatedAt,
+	}
+}
+
+const oauthCodeColumns = `code_hash, application_id, user_id, redirect_uri, scopes, code_challenge, expires_at, created_at`
+
+type oauthCodeRow struct {
+	CodeHash      string         `db:"code_hash"`
+	ApplicationID uuid.UUID      `db:"application_id"`
+	UserID        uuid.UUID      `db:"user_id"`
+	RedirectURI   string         `db:"redirect_uri"`
+	Scopes        pq.StringArray `db:"scopes"`
+	CodeChallenge *string        `db:"code_challenge"`
+	ExpiresAt     time.Time      `db:"expires_at"`
+	CreatedAt     time.Time      `db:"created_at"`
+}
+
+const oauthTokenColumns = `id, application_id, user_id, scopes, access_token_hash, refresh_token_hash, expires_at, created_at`
+
+type oauthTokenRow struct {
+	ID               uuid.UUID      `db:"id"`
+	ApplicationID    uuid.UUID      `db:"application_id"`
+	UserID           uuid.UUID      `db:"user_id"`
+	Scopes           pq.StringArray `db:"scopes"`
+	AccessTokenHash  string         `db:"access_token_hash"`
+	RefreshTokenHash string         `db:"refresh_token_hash"`
+	ExpiresAt        time.Time      `db:"expires_at"`
+	CreatedAt        time.Time      `db:"created_at"`
+}
+
+func (row *oauthTokenRow) token() *models.OAuthToken {
+	return &models.OAuthToken{
+		ID:               row.ID,
+		ApplicationID:    row.ApplicationID,
+		UserID:           row.UserID,
+		Scopes:           stringsOrEmpty(row.Scopes),
+		AccessTokenHash:  row.AccessTokenHash,
+		RefreshTokenHash: row.RefreshTokenHash,
+		ExpiresAt:        row.ExpiresAt,
+		CreatedAt:        row.CreatedAt,
+	}
+}
+
+func stringsOrEmpty(values pq.StringArray) []string {
+	if values == nil {
+		return []string{}
+	}
+	return []string(values)
+}
+
+// CreateApplication stores a new application
+func (r *OAuthAppRepository) CreateApplication(ctx context.Context, app *models.OAuthApplication) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO oauth_applications (`+oauthAppColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, app.ID, app.OwnerID, app.Name, pq.Array(app.RedirectURIs), app.ClientSecretHash, app.CreatedAt)
+	return err
+}
+
+// GetApplication returns the application, or nil if there's none
+func (r *OAuthAppRepository) GetApplication(ctx context.Context, id uuid.UUID) (*models.OAuthApplication, error) {
+	var row oauthAppRow
+	err := r.db.GetContext(ctx, &row, `SELECT `+oauthAppColumns+` FROM oauth_applications WHERE id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row.application(), nil
+}
+
+// GetApplicationsByOwner returns the user's applications, newest first
+func (r *OAuthAppRepository) GetApplicationsByOwner(ctx context.Context, ownerID uuid.UUID) ([]*models.OAuthApplication, error) {
+	var rows []oauthAppRow
+	err := r.db.SelectContext(ctx, &rows, `
+		SELECT `+oauthAppColumns+` FROM oauth_applications
+		WHERE owner_id = $1
+		ORDER BY created_at DESC
+	`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	apps := make([]*models.OAuthApplication, 0, len(rows))
+	for i := range rows {
+		apps = append(apps, rows[i].application())
+	}
+	return apps, nil
+}
+
+// SetClientSecretHash replaces an application's client secret
+func (r *OAuthAppRepository) SetClientSecretHash(ctx context.Context, id uuid.UUID, secretHash string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE oauth_applications SET client_secret_hash = $2 WHERE id = $1`, id, secretHash)
+	return err
+}
+
+// DeleteApplication removes the owner's application, with its codes and
+// tokens, and reports whether they had it
+func (r *OAuthAppRepository) DeleteApplication(ctx context.Context, ownerID, id uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM oauth_applications WHERE id = $1 AND owner_id = $2`, id, ownerID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// CreateAuthorizationCode stores a new authorization code
+func (r *OAuthAppRepository) CreateAuthorizationCode(ctx context.Context, code *models.OAuthAuthorizationCode) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO oauth_authorization_codes (`+oauthCodeColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, code.CodeHash, code.ApplicationID, code.UserID, code.RedirectURI, pq.Array(code.Scopes),
+		code.CodeChallenge, code.ExpiresAt, code.CreatedAt)
+	return err
+}
+
+// TakeAuthorizationCode deletes and returns the code with the hash, or nil
+// if there's none. Expired codes are cleared out along the way.
+func (r *OAuthAppRepository) TakeAuthorizationCode(ctx context.Context, codeHash string) (*models.OAuthAuthorizationCode, error) {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM oauth_authorization_codes WHERE expires_at < NOW()`); err != nil {
+		return nil, err
+	}
+	var row oauthCodeRow
+	err := r.db.GetContext(ctx, &row, `
+		DELETE FROM oauth_authorization_codes WHERE code_hash = $1
+		RETURNING `+oauthCodeColumns, codeHash)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &models.OAuthAuthorizationCode{
+		CodeHash:      row.CodeHash,
+		ApplicationID: row.ApplicationID,
+		UserID:        row.UserID,
+		RedirectURI:   row.RedirectURI,
+		Scopes:        stringsOrEmpty(row.Scopes),
+		CodeChallenge: row.CodeChallenge,
+		ExpiresAt:     row.ExpiresAt,
+		CreatedAt:     row.CreatedAt,
+	}, nil
+}
+
+// CreateToken stores a new token
+func (r *OAuthAppRepository) CreateToken(ctx context.Context, token *models.OAuthToken) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO oauth_tokens (`+oauthTokenColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, token.ID, token.ApplicationID, token.UserID, pq.Array(token.Scopes), token.AccessTokenHash,
+		token.RefreshTokenHash, token.ExpiresAt, token.CreatedAt)
+	return err
+}
+
+// GetTokenByAccessHash returns the token with the access token hash, or nil
+// if there's none
+func (r *OAuthAppRepository) GetTokenByAccessHash(ctx context.Context, accessHash string) (*models.OAuthToken, error) {
+	var row oauthTokenRow
+	err := r.db.GetContext(ctx, &row, `SELECT `+oauthTokenColumns+` FROM oauth_tokens WHERE access_token_hash = $1`, accessHash)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row.token(), nil
+}
+
+// TakeTokenByRefreshHash deletes and returns the token with the refresh
+// token hash, or nil if there's none
+func (r *OAuthAppRepository) TakeTokenByRefreshHash(ctx context.Context, refreshHash string) (*models.OAuthToken, error) {
+	var row oauthTokenRow
+	err := r.db.GetContext(ctx, &row, `
+		DELETE FROM oauth_tokens WHERE refresh_token_hash = $1
+		RETURNING `+oauthTokenColumns, refreshHash)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row.token(), nil
+}
+
+// GetAuthorizedApplications returns the applications the user granted
+// access, most recently granted first, each with every scope it was granted
+func (r *OAuthAppRepository) GetAuthorizedApplications(ctx context.Context, userID uuid.UUID) ([]*models.AuthorizedOAuthApplication, error) {
+	var rows []struct {
+		oauthAppRow
+		Scopes    pq.StringArray `db:"scopes"`
+		GrantedAt time.Time      `db:"granted_at"`
+	}
+	err := r.db.SelectContext(ctx, &rows, `
+		SELECT a.id, a.owner_id, a.name, a.redirect_uris, a.created_at, g.scopes, g.granted_at
+		FROM (
+			SELECT application_id, array_agg(DISTINCT scope) AS scopes, MAX(created_at) AS granted_at
+			FROM oauth_tokens, unnest(scopes) AS scope
+			WHERE user_id = $1
+			GROUP BY application_id
+		) AS g
+		JOIN oauth_applications a ON a.id = g.application_id
+		ORDER BY g.granted_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	apps := make([]*models.AuthorizedOAuthApplication, 0, len(rows))
+	for i := range rows {
+		app := rows[i].application()
+		app.ClientSecretHash = ""
+		apps = append(apps, &models.AuthorizedOAuthApplication{
+			Application: app,
+			Scopes:      stringsOrEmpty(rows[i].Scopes),
+			GrantedAt:   rows[i].GrantedAt,
+		})
+	}
+	return apps, nil
+}
+
+// DeleteTokens revokes every token the user granted the application and
+// reports whether there were any
+func (r *OAuthAppRepository) DeleteTokens(ctx context.Context, userID, applicationID uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM oauth_tokens WHERE user_id = $1 AND application_id = $2`, userID, applicationID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// DeleteUserGrants revokes every token and authorization code the user
+// granted any application
+func (r *OAuthAppRepository) DeleteUserGrants(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		WITH codes AS (DELETE FROM oauth_authorization_codes WHERE user_id = $1)
+		DELETE FROM oauth_tokens WHERE user_id = $1
+	`, userID)
+	return err
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// OAuthIdentityRepository stores which external login provider accounts log
+// in as which users
+type OAuthIdentityRepository struct {
+	db *sqlx.DB
+}
+
+func NewOAuthIdentityRepository(db *sqlx.DB) *OAuthIdentityRepository {
+	return &OAuthIdentityRepository{db: db}
+}
+
+// GetOAuthIdentity returns nil if the provider account isn't linked
+func (r *OAuthIdentityRepository) GetOAuthIdentity(ctx context.Context, provider, subject string) (*models.OAuthIdentity, error) {
+	var identity models.OAuthIdentity
+	err := r.db.GetContext(ctx, &identity, `
+		SELECT provider, subject, user_id, email, created_at, last_login_at
+		FROM oauth_identities
+		WHERE provider = $1 AND subject = $2
+	`, provider, subject)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// CreateOAuthIdentity links a provider account to a user. It returns
+// services.ErrOAuthIdentityLinked if the account is already linked.
+func (r *OAuthIdentityRepository) CreateOAuthIdentity(ctx context.Context, identity *models.OAuthIdentity) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO oauth_identities (provider, subject, user_id, email, created_at, last_login_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, identity.Provider, identity.Subject, identity.UserID, identity.Email, identity.CreatedAt, identity.LastLoginAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return services.ErrOAuthIdentityLinked
+	}
+	return err
+}
+
+// RecordOAuthLogin notes when a linked provider account last logged in
+func (r *OAuthIdentityRepository) RecordOAuthLogin(ctx context.Context, provider, subject string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE oauth_identities SET last_login_at = $3
+		WHERE provider = $1 AND subject = $2
+	`, provider, subject, at)
+	return err
+}
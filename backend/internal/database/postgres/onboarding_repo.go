@@ -0,0 +1,201 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"hearth/internal/models"
+)
+
+// OnboardingRepository stores servers' welcome screens and onboarding
+// prompts and the options members picked
+type OnboardingRepository struct {
+	db *sqlx.DB
+}
+
+func NewOnboardingRepository(db *sqlx.DB) *OnboardingRepository {
+	return &OnboardingRepository{db: db}
+}
+
+// GetWelcomeScreen returns the server's welcome screen, or the default one
+// if it never set one up
+func (r *OnboardingRepository) GetWelcomeScreen(ctx context.Context, serverID uuid.UUID) (*models.WelcomeScreen, error) {
+	var row struct {
+		Enabled         bool      `db:"enabled"`
+		Description     *string   `db:"description"`
+		WelcomeChannels []byte    `db:"welcome_channels"`
+		UpdatedAt       time.Time `db:"updated_at"`
+	}
+	err := r.db.GetContext(ctx, &row, `
+		SELECT enabled, description, welcome_channels, updated_at
+		FROM welcome_screens WHERE server_id = $1
+	`, serverID)
+	if err == sql.ErrNoRows {
+		return models.DefaultWelcomeScreen(serverID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	screen := &models.WelcomeScreen{
+		ServerID:    serverID,
+		Enabled:     row.Enabled,
+		Description: row.Description,
+		UpdatedAt:   row.UpdatedAt,
+	}
+	if err := json.Unmarshal(row.WelcomeChannels, &screen.WelcomeChannels); err != nil {
+		return nil, err
+	}
+	return screen, nil
+}
+
+// SaveWelcomeScreen creates or replaces the server's welcome screen
+func (r *OnboardingRepository) SaveWelcomeScreen(ctx context.Context, screen *models.WelcomeScreen) error {
+	channels, err := json.Marshal(screen.WelcomeChannels)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO welcome_screens (server_id, enabled, description, welcome_channels, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (server_id) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			description = EXCLUDED.description,
+			welcome_channels = EXCLUDED.welcome_channels,
+			updated_at = EXCLUDED.updated_at
+	`, screen.ServerID, screen.Enabled, screen.Description, channels, screen.UpdatedAt)
+	return err
+}
+
+// GetOnboarding returns the server's onboarding, or the default if it
+// never set it up
+func (r *OnboardingRepository) GetOnboarding(ctx context.Context, serverID uuid.UUID) (*models.Onboarding, error) {
+	var row struct {
+		Enabled   bool      `db:"enabled"`
+		Prompts   []byte    `db:"prompts"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}
+	err := r.db.GetContext(ctx, &row, `SELECT enabled, prompts, updated_at FROM onboarding WHERE server_id = $1`, serverID)
+	if err == sql.ErrNoRows {
+		return models.DefaultOnboarding(serverID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	onboarding := &models.Onboarding{ServerID: serverID, Enabled: row.Enabled, UpdatedAt: row.UpdatedAt}
+	if err := json.Unmarshal(row.Prompts, &onboarding.Prompts); err != nil {
+		return nil, err
+	}
+	return onboarding, nil
+}
+
+// SaveOnboarding creates or replaces the server's onboarding
+func (r *OnboardingRepository) SaveOnboarding(ctx context.Context, onboarding *models.Onboarding) error {
+	prompts, err := json.Marshal(onboarding.Prompts)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO onboarding (server_id, enabled, prompts, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (server_id) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			prompts = EXCLUDED.prompts,
+			updated_at = EXCLUDED.updated_at
+	`, onboarding.ServerID, onboarding.Enabled, prompts, onboarding.UpdatedAt)
+	return err
+}
+
+// GetResponses returns what a member picked, or nil if they never went
+// through onboarding
+func (r *OnboardingRepository) GetResponses(ctx context.Context, serverID, userID uuid.UUID) (*models.OnboardingResponses, error) {
+	var row struct {
+		OptionIDs  []byte    `db:"option_ids"`
+		RoleIDs    []byte    `db:"role_ids"`
+		ChannelIDs []byte    `db:"channel_ids"`
+		UpdatedAt  time.Time `db:"updated_at"`
+	}
+	err := r.db.GetContext(ctx, &row, `
+		SELECT option_ids, role_ids, channel_ids, updated_at FROM onboarding_responses
+		WHERE server_id = $1 AND user_id = $2
+	`, serverID, userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	responses := &models.OnboardingResponses{ServerID: serverID, UserID: userID, UpdatedAt: row.UpdatedAt}
+	if err := json.Unmarshal(row.OptionIDs, &responses.OptionIDs); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(row.RoleIDs, &responses.RoleIDs); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(row.ChannelIDs, &responses.ChannelIDs); err != nil {
+		return nil, err
+	}
+	return responses, nil
+}
+
+// SaveResponses stores a member's picks and, in the same transaction,
+// takes away the roles in remove and gives them responses.RoleIDs. It
+// reports false, changing nothing, if they aren't a member.
+func (r *OnboardingRepository) SaveResponses(ctx context.Context, responses *models.OnboardingResponses, remove []uuid.UUID) (bool, error) {
+	options, err := json.Marshal(responses.OptionIDs)
+	if err != nil {
+		return false, err
+	}
+	roles, err := json.Marshal(responses.RoleIDs)
+	if err != nil {
+		return false, err
+	}
+	channels, err := json.Marshal(responses.ChannelIDs)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE members SET roles = ARRAY(
+			SELECT DISTINCT role_id FROM unnest(array_cat(
+				ARRAY(SELECT unnest(COALESCE(roles, '{}')) EXCEPT SELECT unnest($3::uuid[])),
+				$4::uuid[]
+			)) AS role_id
+		)
+		WHERE server_id = $1 AND user_id = $2
+	`, responses.ServerID, responses.UserID, pq.Array(remove), pq.Array(responses.RoleIDs))
+	if err != nil {
+		return false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO onboarding_responses (server_id, user_id, option_ids, role_ids, channel_ids, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (server_id, user_id) DO UPDATE SET
+			option_ids = EXCLUDED.option_ids,
+			role_ids = EXCLUDED.role_ids,
+			channel_ids = EXCLUDED.channel_ids,
+			updated_at = EXCLUDED.updated_at
+	`, responses.ServerID, responses.UserID, options, roles, channels, responses.UpdatedAt)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
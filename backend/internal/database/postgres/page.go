@@ -0,0 +1,27 @@
+package postgres
+
+import (
+	"fmt"
+
+	"hearth/internal/models"
+)
+
+// keysetPage pages a list sorted by sortColumn then keyColumn, highest
+// first. It returns the condition (with a leading AND, empty for the first
+// page) that keeps the rows past cursor, its arguments starting at $argNum,
+// and the ORDER BY that reads those rows nearest the cursor first. Going
+// backward that's lowest first, so callers reverse what they read.
+func keysetPage(cursor *models.PageCursor, sortColumn, keyColumn string, argNum int) (string, []interface{}, string) {
+	order := fmt.Sprintf("%s DESC, %s DESC", sortColumn, keyColumn)
+	if cursor == nil {
+		return "", nil, order
+	}
+
+	args := []interface{}{cursor.SortValue(), cursor.Key}
+	if cursor.Backward {
+		condition := fmt.Sprintf(" AND (%s, %s) > ($%d, $%d)", sortColumn, keyColumn, argNum, argNum+1)
+		return condition, args, fmt.Sprintf("%s ASC, %s ASC", sortColumn, keyColumn)
+	}
+	condition := fmt.Sprintf(" AND (%s, %s) < ($%d, $%d)", sortColumn, keyColumn, argNum, argNum+1)
+	return condition, args, order
+}
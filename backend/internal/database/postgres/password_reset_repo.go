@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"hearth/internal/models"
+)
+
+// PasswordResetRepository stores outstanding password reset links
+type PasswordResetRepository struct {
+	db *sqlx.DB
+}
+
+func NewPasswordResetRepository(db *sqlx.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{db: db}
+}
+
+// Create replaces the user's reset link, unless the current one was made
+// after cooldownStart. It reports whether the link was created.
+func (r *PasswordResetRepository) Create(ctx context.Context, reset *models.PasswordReset, cooldownStart time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO password_resets (user_id, token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET
+			token_hash = EXCLUDED.token_hash,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at
+		WHERE password_resets.created_at < $5
+	`, reset.UserID, reset.TokenHash, reset.CreatedAt, reset.ExpiresAt, cooldownStart)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// Consume deletes the reset with the token hash and returns its user, or
+// uuid.Nil if there's none or it has expired
+func (r *PasswordResetRepository) Consume(ctx context.Context, tokenHash string, now time.Time) (uuid.UUID, error) {
+	var userID uuid.UUID
+	err := r.db.GetContext(ctx, &userID, `
+		DELETE FROM password_resets WHERE token_hash = $1 AND expires_at > $2
+		RETURNING user_id
+	`, tokenHash, now)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, nil
+	}
+	return userID, err
+}
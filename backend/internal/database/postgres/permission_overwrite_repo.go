@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"hearth/internal/models"
+)
+
+// PermissionOverwriteRepository reads channel permission overwrites
+type PermissionOverwriteRepository struct {
+	db *sqlx.DB
+}
+
+// NewPermissionOverwriteRepository creates a new permission overwrite repository
+func NewPermissionOverwriteRepository(db *sqlx.DB) *PermissionOverwriteRepository {
+	return &PermissionOverwriteRepository{db: db}
+}
+
+// GetByChannelID returns a channel's overwrites. target_type is stored as
+// 0 for roles and 1 for users.
+func (r *PermissionOverwriteRepository) GetByChannelID(ctx context.Context, channelID uuid.UUID) ([]models.PermissionOverride, error) {
+	query := `
+		SELECT channel_id, target_id,
+			CASE target_type WHEN 1 THEN 'user' ELSE 'role' END AS target_type,
+			COALESCE(allow, 0) AS allow, COALESCE(deny, 0) AS deny
+		FROM permission_overwrites
+		WHERE channel_id = $1
+	`
+	var overwrites []models.PermissionOverride
+	err := r.db.SelectContext(ctx, &overwrites, query, channelID)
+	return overwrites, err
+}
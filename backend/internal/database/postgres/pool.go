@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PoolConfig sizes the connection pool
+type PoolConfig struct {
+	MaxOpenConns    int           // 0 = unlimited
+	MaxIdleConns    int           // 0 = database/sql default (2)
+	ConnMaxLifetime time.Duration // 0 = connections are reused forever
+	ConnMaxIdleTime time.Duration // 0 = idle connections are kept until MaxLifetime
+}
+
+// DefaultPoolConfig returns the pool settings used when none are configured
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		MaxOpenConns:    25,
+		MaxIdleConns:    5,
+		ConnMaxLifetime: 5 * time.Minute,
+	}
+}
+
+// ConfigurePool applies pool settings to db
+func ConfigurePool(db *sqlx.DB, cfg PoolConfig) {
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
+
+// PoolWarnings reports pool settings that are clearly mismatched with the
+// process or the server: fewer connections than workers that need them,
+// idle limits database/sql will silently cap, or more connections than
+// Postgres accepts. workers is how many goroutines are expected to hold a
+// connection at once.
+func PoolWarnings(ctx context.Context, db *sqlx.DB, cfg PoolConfig, workers int) []string {
+	var warnings []string
+
+	if cfg.MaxOpenConns <= 0 {
+		warnings = append(warnings, "DB_MAX_OPEN_CONNS is unlimited; a traffic spike can exhaust Postgres connections")
+	} else {
+		if cfg.MaxOpenConns < workers {
+			warnings = append(warnings, fmt.Sprintf(
+				"DB_MAX_OPEN_CONNS=%d is below the %d workers; requests will queue for connections",
+				cfg.MaxOpenConns, workers))
+		}
+		if cfg.MaxIdleConns > cfg.MaxOpenConns {
+			warnings = append(warnings, fmt.Sprintf(
+				"DB_MAX_IDLE_CONNS=%d exceeds DB_MAX_OPEN_CONNS=%d and will be capped",
+				cfg.MaxIdleConns, cfg.MaxOpenConns))
+		}
+	}
+
+	var available int
+	err := db.GetContext(ctx, &available, `
+		SELECT current_setting('max_connections')::int - current_setting('superuser_reserved_connections')::int
+	`)
+	if err != nil {
+		return append(warnings, fmt.Sprintf("could not read Postgres max_connections: %v", err))
+	}
+	if cfg.MaxOpenConns > available {
+		warnings = append(warnings, fmt.Sprintf(
+			"DB_MAX_OPEN_CONNS=%d exceeds the %d connections Postgres accepts; one replica can starve the rest",
+			cfg.MaxOpenConns, available))
+	}
+
+	return warnings
+}
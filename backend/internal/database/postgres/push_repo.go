@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"hearth/internal/models"
+)
+
+// PushDeviceRepository handles push device database operations
+type PushDeviceRepository struct {
+	db *sqlx.DB
+}
+
+// NewPushDeviceRepository creates a new push device repository
+func NewPushDeviceRepository(db *sqlx.DB) *PushDeviceRepository {
+	return &PushDeviceRepository{db: db}
+}
+
+// Upsert registers a device for a user. Re-registering a token refreshes
+// it; other accounts signed in on the same device keep their own rows.
+func (r *PushDeviceRepository) Upsert(ctx context.Context, device *models.PushDevice) error {
+	query := `
+		INSERT INTO push_devices (id, user_id, platform, token, p256dh, auth, created_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (user_id, token) DO UPDATE SET
+			platform = EXCLUDED.platform,
+			p256dh = EXCLUDED.p256dh,
+			auth = EXCLUDED.auth,
+			last_seen_at = EXCLUDED.last_seen_at
+		RETURNING id, created_at
+	`
+	return r.db.QueryRowContext(ctx, query,
+		device.ID, device.UserID, device.Platform, device.Token, device.P256dh, device.Auth,
+		device.CreatedAt, device.LastSeenAt,
+	).Scan(&device.ID, &device.CreatedAt)
+}
+
+// GetByUserID returns a user's registered devices
+func (r *PushDeviceRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*models.PushDevice, error) {
+	var devices []*models.PushDevice
+	query := `SELECT * FROM push_devices WHERE user_id = $1 ORDER BY created_at`
+	if err := r.db.SelectContext(ctx, &devices, query, userID); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// Delete removes one of a user's devices
+func (r *PushDeviceRepository) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM push_devices WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteByToken forgets a token the push service no longer accepts, for
+// every account that registered it
+func (r *PushDeviceRepository) DeleteByToken(ctx context.Context, token string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM push_devices WHERE token = $1`, token)
+	return err
+}
@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"hearth/internal/models"
+)
+
+// QuotaOverrideRepository stores per-tier quota overrides
+type QuotaOverrideRepository struct {
+	db *sqlx.DB
+}
+
+func NewQuotaOverrideRepository(db *sqlx.DB) *QuotaOverrideRepository {
+	return &QuotaOverrideRepository{db: db}
+}
+
+// List returns the tiers that have overrides
+func (r *QuotaOverrideRepository) List(ctx context.Context) ([]*models.QuotaOverride, error) {
+	var overrides []*models.QuotaOverride
+	err := r.db.SelectContext(ctx, &overrides, `SELECT * FROM quota_overrides ORDER BY tier`)
+	return overrides, err
+}
+
+// Save sets a tier's overrides
+func (r *QuotaOverrideRepository) Save(ctx context.Context, override *models.QuotaOverride) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO quota_overrides (tier, max_servers_owned, max_servers_joined, max_message_length, storage_mb, max_file_size_mb, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (tier) DO UPDATE SET
+			max_servers_owned = EXCLUDED.max_servers_owned,
+			max_servers_joined = EXCLUDED.max_servers_joined,
+			max_message_length = EXCLUDED.max_message_length,
+			storage_mb = EXCLUDED.storage_mb,
+			max_file_size_mb = EXCLUDED.max_file_size_mb,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+	`, override.Tier, override.MaxServersOwned, override.MaxServersJoined, override.MaxMessageLength,
+		override.StorageMB, override.MaxFileSizeMB, override.UpdatedBy, override.UpdatedAt)
+	return err
+}
+
+// Delete removes a tier's overrides
+func (r *QuotaOverrideRepository) Delete(ctx context.Context, tier models.QuotaTier) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM quota_overrides WHERE tier = $1`, tier)
+	return err
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 
 	"hearth/internal/models"
 )
@@ -49,16 +50,18 @@ func (r *ReadStateRepository) SetReadState(ctx context.Context, userID, channelI
 	return err
 }
 
-// IncrementMentionCount increments the mention count for a user in a channel
-func (r *ReadStateRepository) IncrementMentionCount(ctx context.Context, userID, channelID uuid.UUID) error {
+// IncrementMentionCounts increments the mention count of each user in a
+// channel in one statement, so an @everyone costs one round trip
+func (r *ReadStateRepository) IncrementMentionCounts(ctx context.Context, channelID uuid.UUID, userIDs []uuid.UUID) error {
 	query := `
 		INSERT INTO read_states (user_id, channel_id, mention_count, updated_at)
-		VALUES ($1, $2, 1, $3)
+		SELECT u.user_id, $1, 1, $3
+		FROM unnest($2::uuid[]) AS u(user_id)
 		ON CONFLICT (user_id, channel_id) DO UPDATE SET
 			mention_count = read_states.mention_count + 1,
 			updated_at = $3
 	`
-	_, err := r.db.ExecContext(ctx, query, userID, channelID, time.Now())
+	_, err := r.db.ExecContext(ctx, query, channelID, pq.Array(userIDs), time.Now())
 	return err
 }
 
@@ -179,6 +182,26 @@ func (r *ReadStateRepository) GetUserUnreadSummary(ctx context.Context, userID u
 	return summary, nil
 }
 
+// GetUserReadStates gets every read state for a user with its unread count
+func (r *ReadStateRepository) GetUserReadStates(ctx context.Context, userID uuid.UUID) ([]models.ReadStateWithUnread, error) {
+	query := `
+		SELECT rs.user_id, rs.channel_id, rs.last_message_id, rs.mention_count, rs.updated_at,
+			(
+				SELECT COUNT(*) FROM messages m
+				WHERE m.channel_id = rs.channel_id
+				AND (rs.last_message_id IS NULL OR m.created_at > (SELECT created_at FROM messages WHERE id = rs.last_message_id))
+			) AS unread_count
+		FROM read_states rs
+		WHERE rs.user_id = $1
+		ORDER BY rs.updated_at DESC
+	`
+	states := []models.ReadStateWithUnread{}
+	if err := r.db.SelectContext(ctx, &states, query, userID); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
 // GetServerUnreadSummary gets unread information for all channels in a server
 func (r *ReadStateRepository) GetServerUnreadSummary(ctx context.Context, userID, serverID uuid.UUID) (*models.UnreadSummary, error) {
 	// Get all channels in the server
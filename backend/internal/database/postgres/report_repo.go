@@ -0,0 +1,185 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"hearth/internal/models"
+)
+
+// ReportRepository stores users' reports and their review history
+type ReportRepository struct {
+	db *sqlx.DB
+}
+
+func NewReportRepository(db *sqlx.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+func insertReportEvent(ctx context.Context, tx *sqlx.Tx, event *models.ReportEvent) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO report_events (id, report_id, actor_id, action, status, assignee_id, note, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, event.ID, event.ReportID, event.ActorID, event.Action, event.Status, event.AssigneeID, event.Note, event.CreatedAt)
+	return err
+}
+
+// Create saves a new report along with the event recording it
+func (r *ReportRepository) Create(ctx context.Context, report *models.Report, event *models.ReportEvent) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO reports (id, reporter_id, target_type, target_id, category, details,
+			target_user_id, server_id, channel_id, message_content, status, assignee_id,
+			created_at, updated_at, resolved_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`, report.ID, report.ReporterID, report.TargetType, report.TargetID, report.Category, report.Details,
+		report.TargetUserID, report.ServerID, report.ChannelID, report.MessageContent, report.Status, report.AssigneeID,
+		report.CreatedAt, report.UpdatedAt, report.ResolvedAt)
+	if err != nil {
+		return err
+	}
+	if err := insertReportEvent(ctx, tx, event); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Get returns a report, or nil if there's no such report
+func (r *ReportRepository) Get(ctx context.Context, id uuid.UUID) (*models.Report, error) {
+	var report models.Report
+	err := r.db.GetContext(ctx, &report, `SELECT * FROM reports WHERE id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Events returns a report's history, oldest first
+func (r *ReportRepository) Events(ctx context.Context, reportID uuid.UUID) ([]*models.ReportEvent, error) {
+	events := []*models.ReportEvent{}
+	err := r.db.SelectContext(ctx, &events, `
+		SELECT * FROM report_events WHERE report_id = $1 ORDER BY created_at, id
+	`, reportID)
+	return events, err
+}
+
+// List returns the reports matching filter, oldest first
+func (r *ReportRepository) List(ctx context.Context, filter models.ReportFilter) ([]*models.Report, error) {
+	var where []string
+	var args []interface{}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Status != "" {
+		add("status = $%d", filter.Status)
+	}
+	if filter.AssigneeID != nil {
+		add("assignee_id = $%d", *filter.AssigneeID)
+	}
+	if filter.TargetType != "" {
+		add("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID != nil {
+		add("target_id = $%d", *filter.TargetID)
+	}
+	if filter.After != nil {
+		add("(created_at, id) > (SELECT created_at, id FROM reports WHERE id = $%d)", *filter.After)
+	}
+
+	query := `SELECT * FROM reports`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY created_at, id LIMIT $%d`, len(args))
+
+	reports := []*models.Report{}
+	err := r.db.SelectContext(ctx, &reports, query, args...)
+	return reports, err
+}
+
+// FindUnresolved returns the reporter's open or reviewing report of a
+// target, or nil if they have none
+func (r *ReportRepository) FindUnresolved(ctx context.Context, reporterID uuid.UUID, targetType models.ReportTargetType, targetID uuid.UUID) (*models.Report, error) {
+	var report models.Report
+	err := r.db.GetContext(ctx, &report, `
+		SELECT * FROM reports
+		WHERE reporter_id = $1 AND target_type = $2 AND target_id = $3
+			AND status IN ('open', 'reviewing')
+		LIMIT 1
+	`, reporterID, targetType, targetID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// CountUnresolved counts the reporter's reports that are open or being
+// reviewed
+func (r *ReportRepository) CountUnresolved(ctx context.Context, reporterID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, `
+		SELECT COUNT(*) FROM reports WHERE reporter_id = $1 AND status IN ('open', 'reviewing')
+	`, reporterID)
+	return count, err
+}
+
+// Update saves a report's status and assignee and records event, as long
+// as its status is still from. It reports whether it was; if another admin
+// moved the report first, nothing changes.
+func (r *ReportRepository) Update(ctx context.Context, report *models.Report, from models.ReportStatus, event *models.ReportEvent) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE reports SET status = $3, assignee_id = $4, updated_at = $5, resolved_at = $6
+		WHERE id = $1 AND status = $2
+	`, report.ID, from, report.Status, report.AssigneeID, report.UpdatedAt, report.ResolvedAt)
+	if err != nil {
+		return false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if err := insertReportEvent(ctx, tx, event); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// AddEvent records an event without changing the report, such as a note
+func (r *ReportRepository) AddEvent(ctx context.Context, event *models.ReportEvent) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE reports SET updated_at = $2 WHERE id = $1`, event.ReportID, event.CreatedAt); err != nil {
+		return err
+	}
+	if err := insertReportEvent(ctx, tx, event); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -27,7 +27,11 @@ func (r *RoleRepository) Create(ctx context.Context, role *models.Role) error {
 		role.ID, role.ServerID, role.Name, role.Color, role.Hoist, role.Position,
 		role.Permissions, role.Mentionable, role.IsDefault, role.CreatedAt,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	role.Version = 1 // Column default
+	return nil
 }
 
 func (r *RoleRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Role, error) {
@@ -47,6 +51,16 @@ func (r *RoleRepository) GetByServerID(ctx context.Context, serverID uuid.UUID)
 	return roles, err
 }
 
+// PageRoles returns up to limit of the server's roles after cursor,
+// highest first
+func (r *RoleRepository) PageRoles(ctx context.Context, serverID uuid.UUID, cursor *models.PageCursor, limit int) ([]*models.Role, error) {
+	condition, args, order := keysetPage(cursor, "position", "id", 3)
+	roles := []*models.Role{}
+	err := r.db.SelectContext(ctx, &roles, `SELECT * FROM roles WHERE server_id = $1`+condition+` ORDER BY `+order+` LIMIT $2`,
+		append([]interface{}{serverID, limit}, args...)...)
+	return roles, err
+}
+
 func (r *RoleRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Role, error) {
 	if len(ids) == 0 {
 		return []*models.Role{}, nil
@@ -63,18 +77,27 @@ func (r *RoleRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*mode
 	return roles, err
 }
 
+// Update saves role if it is still at role.Version, then bumps the
+// version. It returns services.ErrVersionConflict if someone else saved first.
 func (r *RoleRepository) Update(ctx context.Context, role *models.Role) error {
 	query := `
 		UPDATE roles SET
 			name = $2, color = $3, hoist = $4, position = $5,
-			permissions = $6, mentionable = $7
-		WHERE id = $1
+			permissions = $6, mentionable = $7, version = version + 1
+		WHERE id = $1 AND version = $8
 	`
-	_, err := r.db.ExecContext(ctx, query,
+	result, err := r.db.ExecContext(ctx, query,
 		role.ID, role.Name, role.Color, role.Hoist, role.Position,
-		role.Permissions, role.Mentionable,
+		role.Permissions, role.Mentionable, role.Version,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if err := checkVersionedUpdate(result); err != nil {
+		return err
+	}
+	role.Version++
+	return nil
 }
 
 func (r *RoleRepository) Delete(ctx context.Context, id uuid.UUID) error {
@@ -109,9 +132,11 @@ func (r *RoleRepository) UpdatePositions(ctx context.Context, serverID uuid.UUID
 	}
 	defer tx.Rollback()
 
+	// Moving a role is an edit like any other, so it bumps the version
 	for roleID, position := range positions {
 		_, err := tx.ExecContext(ctx,
-			`UPDATE roles SET position = $1 WHERE id = $2 AND server_id = $3`,
+			`UPDATE roles SET position = $1, version = version + 1
+			WHERE id = $2 AND server_id = $3 AND position <> $1`,
 			position, roleID, serverID,
 		)
 		if err != nil {
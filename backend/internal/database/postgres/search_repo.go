@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -140,12 +141,20 @@ func (r *SearchRepository) SearchMessages(ctx context.Context, opts services.Sea
 		query += " AND " + condition
 	}
 
+	// Page past the cursor outside the count, so total_count stays the
+	// number of matches
+	query = `SELECT * FROM (` + query + `) m WHERE 1=1`
+	condition, cursorArgs, order := keysetPage(opts.Cursor, "m.created_at", "m.id", argNum)
+	query += condition
+	args = append(args, cursorArgs...)
+	argNum += len(cursorArgs)
+
 	// Order and limit
-	query += fmt.Sprintf(" ORDER BY m.created_at DESC LIMIT $%d", argNum)
+	query += fmt.Sprintf(" ORDER BY %s LIMIT $%d", order, argNum)
 	args = append(args, opts.Limit+1) // Fetch one extra to check if there are more
 	argNum++
 
-	if opts.Offset > 0 {
+	if opts.Offset > 0 && opts.Cursor == nil {
 		query += fmt.Sprintf(" OFFSET $%d", argNum)
 		args = append(args, opts.Offset)
 	}
@@ -177,6 +186,9 @@ func (r *SearchRepository) SearchMessages(ctx context.Context, opts services.Sea
 		msg := r.Message
 		messages[i] = &msg
 	}
+	if opts.Cursor != nil && opts.Cursor.Backward {
+		slices.Reverse(messages)
+	}
 
 	// Load attachments for found messages
 	if len(messages) > 0 {
@@ -3,6 +3,8 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
@@ -28,7 +30,11 @@ func (r *ServerRepository) Create(ctx context.Context, server *models.Server) er
 		server.ID, server.Name, server.IconURL, server.BannerURL, server.Description,
 		server.OwnerID, server.CreatedAt, server.UpdatedAt,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	server.Version = 1 // Column default
+	return nil
 }
 
 func (r *ServerRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Server, error) {
@@ -39,7 +45,7 @@ func (r *ServerRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.S
 			owner_id, verification_level, 
 			explicit_filter as explicit_content_filter,
 			default_notifications, features, vanity_url as vanity_url_code,
-			created_at, updated_at
+			created_at, updated_at, version
 		FROM servers WHERE id = $1
 	`
 	err := r.db.GetContext(ctx, &server, query, id)
@@ -49,16 +55,27 @@ func (r *ServerRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.S
 	return &server, err
 }
 
+// Update saves server if it is still at server.Version, then bumps the
+// version. It returns services.ErrVersionConflict if someone else saved first.
 func (r *ServerRepository) Update(ctx context.Context, server *models.Server) error {
 	query := `
 		UPDATE servers SET
-			name = $2, icon_url = $3, banner_url = $4, description = $5, updated_at = $6
-		WHERE id = $1
+			name = $2, icon_url = $3, banner_url = $4, description = $5, updated_at = $6,
+			version = version + 1
+		WHERE id = $1 AND version = $7
 	`
-	_, err := r.db.ExecContext(ctx, query,
+	result, err := r.db.ExecContext(ctx, query,
 		server.ID, server.Name, server.IconURL, server.BannerURL, server.Description, server.UpdatedAt,
+		server.Version,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if err := checkVersionedUpdate(result); err != nil {
+		return err
+	}
+	server.Version++
+	return nil
 }
 
 func (r *ServerRepository) Delete(ctx context.Context, id uuid.UUID) error {
@@ -88,9 +105,26 @@ func (r *ServerRepository) GetMembers(ctx context.Context, serverID uuid.UUID, l
 	return members, err
 }
 
+// PageMembers returns up to limit of the server's members after cursor,
+// newest first
+func (r *ServerRepository) PageMembers(ctx context.Context, serverID uuid.UUID, cursor *models.PageCursor, limit int) ([]*models.Member, error) {
+	condition, args, order := keysetPage(cursor, "m.joined_at", "m.user_id", 3)
+	query := `
+		SELECT m.*, u.username, u.display_name, u.avatar
+		FROM members m
+		INNER JOIN users u ON u.id = m.user_id
+		WHERE m.server_id = $1` + condition + `
+		ORDER BY ` + order + `
+		LIMIT $2
+	`
+	members := []*models.Member{}
+	err := r.db.SelectContext(ctx, &members, query, append([]interface{}{serverID, limit}, args...)...)
+	return members, err
+}
+
 func (r *ServerRepository) GetMember(ctx context.Context, serverID, userID uuid.UUID) (*models.Member, error) {
 	var member models.Member
-	query := `SELECT server_id, user_id, nickname, joined_at, premium_since, deaf, mute, pending, temporary FROM members WHERE server_id = $1 AND user_id = $2`
+	query := `SELECT server_id, user_id, nickname, joined_at, premium_since, deaf, mute, pending, temporary, co_owner, communication_disabled_until FROM members WHERE server_id = $1 AND user_id = $2`
 	err := r.db.GetContext(ctx, &member, query, serverID, userID)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -101,13 +135,140 @@ func (r *ServerRepository) GetMember(ctx context.Context, serverID, userID uuid.
 	return &member, nil
 }
 
+// GetMembersByUserIDs loads many members of a server, with their role IDs,
+// in one query. Users who aren't members are skipped.
+func (r *ServerRepository) GetMembersByUserIDs(ctx context.Context, serverID uuid.UUID, userIDs []uuid.UUID) ([]*models.Member, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	
+	query, args, err := sqlx.In(`
+		SELECT server_id, user_id, nickname, joined_at, premium_since, deaf, mute, pending, temporary, co_owner, communication_disabled_until,
+			COALESCE(roles, '{}')::text[] AS role_ids
+		FROM members
+		WHERE server_id = ? AND user_id IN (?)
+	`, serverID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	query = r.db.Rebind(query)
+	
+	var rows []struct {
+		models.Member
+		RoleIDs pq.StringArray `db:"role_ids"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	
+	members := make([]*models.Member, len(rows))
+	for i := range rows {
+		member := rows[i].Member
+		member.Roles = make([]uuid.UUID, 0, len(rows[i].RoleIDs))
+		for _, id := range rows[i].RoleIDs {
+			roleID, err := uuid.Parse(id)
+			if err != nil {
+				return nil, err
+			}
+			member.Roles = append(member.Roles, roleID)
+		}
+		members[i] = &member
+	}
+	return members, nil
+}
+
+// memberUserColumns selects a member with its role IDs and the public parts
+// of its user, for scanning into memberUserRow
+const memberUserColumns = `
+	m.server_id, m.user_id, m.nickname, m.joined_at, m.premium_since, m.deaf, m.mute, m.pending, m.temporary, m.co_owner, m.communication_disabled_until,
+	COALESCE(m.roles, '{}')::text[] AS role_ids,
+	u.username AS user_username, u.discriminator AS user_discriminator, u.avatar_url AS user_avatar_url, u.flags AS user_flags`
+
+type memberUserRow struct {
+	models.Member
+	RoleIDs       pq.StringArray `db:"role_ids"`
+	Username      string         `db:"user_username"`
+	Discriminator string         `db:"user_discriminator"`
+	AvatarURL     *string        `db:"user_avatar_url"`
+	Flags         sql.NullInt64  `db:"user_flags"`
+}
+
+func (row *memberUserRow) member() (*models.Member, error) {
+	member := row.Member
+	member.User = &models.PublicUser{
+		ID:            member.UserID,
+		Username:      row.Username,
+		Discriminator: row.Discriminator,
+		AvatarURL:     row.AvatarURL,
+		Flags:         row.Flags.Int64,
+	}
+	member.Roles = make([]uuid.UUID, 0, len(row.RoleIDs))
+	for _, id := range row.RoleIDs {
+		roleID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, err
+		}
+		member.Roles = append(member.Roles, roleID)
+	}
+	return &member, nil
+}
+
+func (r *ServerRepository) selectMemberUsers(ctx context.Context, query string, args ...interface{}) ([]*models.Member, error) {
+	var rows []memberUserRow
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	members := make([]*models.Member, len(rows))
+	for i := range rows {
+		member, err := rows[i].member()
+		if err != nil {
+			return nil, err
+		}
+		members[i] = member
+	}
+	return members, nil
+}
+
+// SearchMembers returns up to limit of the server's members whose username
+// or nickname starts with prefix, ignoring case, with their users and role
+// IDs. Members are ordered by user ID; after is the last of the previous
+// page.
+func (r *ServerRepository) SearchMembers(ctx context.Context, serverID uuid.UUID, prefix string, after *uuid.UUID, limit int) ([]*models.Member, error) {
+	pattern := strings.ToLower(likeEscaper.Replace(prefix)) + "%"
+	return r.selectMemberUsers(ctx, `
+		SELECT`+memberUserColumns+`
+		FROM members m
+		INNER JOIN users u ON u.id = m.user_id
+		WHERE m.server_id = $1
+			AND ($2 = '' OR LOWER(u.username) LIKE $3 OR LOWER(m.nickname) LIKE $3)
+			AND ($4::uuid IS NULL OR m.user_id > $4)
+		ORDER BY m.user_id
+		LIMIT $5
+	`, serverID, prefix, pattern, after, limit)
+}
+
+// GetMembersWithUsers loads many members of a server with their users and
+// role IDs. Users who aren't members are skipped.
+func (r *ServerRepository) GetMembersWithUsers(ctx context.Context, serverID uuid.UUID, userIDs []uuid.UUID) ([]*models.Member, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	return r.selectMemberUsers(ctx, `
+		SELECT`+memberUserColumns+`
+		FROM members m
+		INNER JOIN users u ON u.id = m.user_id
+		WHERE m.server_id = $1 AND m.user_id = ANY($2)
+		ORDER BY m.user_id
+	`, serverID, pq.Array(userIDs))
+}
+
 func (r *ServerRepository) AddMember(ctx context.Context, member *models.Member) error {
 	query := `
-		INSERT INTO members (user_id, server_id, nickname, joined_at, roles)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO members (user_id, server_id, nickname, joined_at, roles, pending)
+		VALUES ($1, $2, $3, $4, $5, $6)
 	`
 	_, err := r.db.ExecContext(ctx, query,
-		member.UserID, member.ServerID, member.Nickname, member.JoinedAt, pq.Array(member.Roles),
+		member.UserID, member.ServerID, member.Nickname, member.JoinedAt, pq.Array(member.Roles), member.Pending,
 	)
 	return err
 }
@@ -143,7 +304,7 @@ func (r *ServerRepository) GetUserServers(ctx context.Context, userID uuid.UUID)
 			s.owner_id, s.verification_level, 
 			s.explicit_filter as explicit_content_filter,
 			s.default_notifications, s.features, s.vanity_url as vanity_url_code,
-			s.created_at, s.updated_at
+			s.created_at, s.updated_at, s.version
 		FROM servers s
 		INNER JOIN members m ON m.server_id = s.id
 		WHERE m.user_id = $1
@@ -154,6 +315,31 @@ func (r *ServerRepository) GetUserServers(ctx context.Context, userID uuid.UUID)
 	return servers, err
 }
 
+// GetLargestServers returns the servers with the most members, largest
+// first
+func (r *ServerRepository) GetLargestServers(ctx context.Context, limit int) ([]*models.Server, error) {
+	query := `
+		SELECT
+			s.id, s.name, s.icon_url, s.banner_url, s.description,
+			s.owner_id, s.verification_level,
+			s.explicit_filter as explicit_content_filter,
+			s.default_notifications, s.features, s.vanity_url as vanity_url_code,
+			s.created_at, s.updated_at, s.version
+		FROM servers s
+		INNER JOIN (
+			SELECT server_id, COUNT(*) AS member_count
+			FROM members
+			GROUP BY server_id
+			ORDER BY member_count DESC
+			LIMIT $1
+		) c ON c.server_id = s.id
+		ORDER BY c.member_count DESC, s.id
+	`
+	var servers []*models.Server
+	err := r.db.SelectContext(ctx, &servers, query, limit)
+	return servers, err
+}
+
 func (r *ServerRepository) GetOwnedServersCount(ctx context.Context, userID uuid.UUID) (int, error) {
 	var count int
 	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM servers WHERE owner_id = $1`, userID)
@@ -174,11 +360,11 @@ func (r *ServerRepository) GetBan(ctx context.Context, serverID, userID uuid.UUI
 
 func (r *ServerRepository) AddBan(ctx context.Context, ban *models.Ban) error {
 	query := `
-		INSERT INTO bans (server_id, user_id, reason, banned_by, created_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO bans (server_id, user_id, reason, banned_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
 	`
 	_, err := r.db.ExecContext(ctx, query,
-		ban.ServerID, ban.UserID, ban.Reason, ban.BannedBy, ban.CreatedAt,
+		ban.ServerID, ban.UserID, ban.Reason, ban.BannedBy, ban.CreatedAt, ban.ExpiresAt,
 	)
 	return err
 }
@@ -194,6 +380,134 @@ func (r *ServerRepository) GetBans(ctx context.Context, serverID uuid.UUID) ([]*
 	return bans, err
 }
 
+// PageBans returns up to limit of the server's bans after cursor, newest
+// first
+func (r *ServerRepository) PageBans(ctx context.Context, serverID uuid.UUID, cursor *models.PageCursor, limit int) ([]*models.Ban, error) {
+	condition, args, order := keysetPage(cursor, "created_at", "user_id", 3)
+	bans := []*models.Ban{}
+	err := r.db.SelectContext(ctx, &bans, `SELECT * FROM bans WHERE server_id = $1`+condition+` ORDER BY `+order+` LIMIT $2`,
+		append([]interface{}{serverID, limit}, args...)...)
+	return bans, err
+}
+
+// Timed moderation
+
+// SetMemberTimeout times a member out until the given time, or lifts the
+// timeout if until is nil
+func (r *ServerRepository) SetMemberTimeout(ctx context.Context, serverID, userID uuid.UUID, until *time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE members SET communication_disabled_until = $3 WHERE server_id = $1 AND user_id = $2`,
+		serverID, userID, until)
+	return err
+}
+
+// RemoveExpiredBans deletes every ban that expired at or before the given
+// time and returns them
+func (r *ServerRepository) RemoveExpiredBans(ctx context.Context, before time.Time) ([]*models.Ban, error) {
+	var bans []*models.Ban
+	err := r.db.SelectContext(ctx, &bans,
+		`DELETE FROM bans WHERE expires_at IS NOT NULL AND expires_at <= $1 RETURNING *`, before)
+	return bans, err
+}
+
+// ClearExpiredTimeouts lifts every timeout that ended at or before the
+// given time and returns the affected members
+func (r *ServerRepository) ClearExpiredTimeouts(ctx context.Context, before time.Time) ([]*models.Member, error) {
+	query := `
+		UPDATE members SET communication_disabled_until = NULL
+		WHERE communication_disabled_until IS NOT NULL AND communication_disabled_until <= $1
+		RETURNING server_id, user_id, nickname, joined_at, premium_since, deaf, mute, pending, temporary, co_owner, communication_disabled_until
+	`
+	var members []*models.Member
+	err := r.db.SelectContext(ctx, &members, query, before)
+	return members, err
+}
+
+// Ownership transfers
+
+// SaveOwnershipTransfer stores a pending transfer, replacing any the
+// server already has
+func (r *ServerRepository) SaveOwnershipTransfer(ctx context.Context, transfer *models.OwnershipTransfer) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO ownership_transfers (server_id, from_user_id, to_user_id, keep_co_owner, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (server_id) DO UPDATE SET
+			from_user_id = EXCLUDED.from_user_id,
+			to_user_id = EXCLUDED.to_user_id,
+			keep_co_owner = EXCLUDED.keep_co_owner,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at
+	`, transfer.ServerID, transfer.FromUserID, transfer.ToUserID, transfer.KeepCoOwner, transfer.CreatedAt, transfer.ExpiresAt)
+	return err
+}
+
+// GetOwnershipTransfer returns the server's pending transfer, or nil if it
+// has none
+func (r *ServerRepository) GetOwnershipTransfer(ctx context.Context, serverID uuid.UUID) (*models.OwnershipTransfer, error) {
+	var transfer models.OwnershipTransfer
+	err := r.db.GetContext(ctx, &transfer, `SELECT * FROM ownership_transfers WHERE server_id = $1`, serverID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func (r *ServerRepository) DeleteOwnershipTransfer(ctx context.Context, serverID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM ownership_transfers WHERE server_id = $1`, serverID)
+	return err
+}
+
+// CompleteOwnershipTransfer hands the server to the transfer's recipient
+// and settles both owners' co-owner flags. It reports false, changing
+// nothing, if the transfer is gone or expired or the owner has changed.
+func (r *ServerRepository) CompleteOwnershipTransfer(ctx context.Context, transfer *models.OwnershipTransfer, now time.Time) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM ownership_transfers
+		WHERE server_id = $1 AND from_user_id = $2 AND to_user_id = $3 AND expires_at > $4
+	`, transfer.ServerID, transfer.FromUserID, transfer.ToUserID, now)
+	if err != nil {
+		return false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	result, err = tx.ExecContext(ctx,
+		`UPDATE servers SET owner_id = $3, updated_at = NOW() WHERE id = $1 AND owner_id = $2`,
+		transfer.ServerID, transfer.FromUserID, transfer.ToUserID)
+	if err != nil {
+		return false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE members SET co_owner = (user_id = $2 AND $3)
+		WHERE server_id = $1 AND user_id IN ($2, $4)
+	`, transfer.ServerID, transfer.FromUserID, transfer.KeepCoOwner, transfer.ToUserID)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (r *ServerRepository) SetCoOwner(ctx context.Context, serverID, userID uuid.UUID, coOwner bool) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE members SET co_owner = $3 WHERE server_id = $1 AND user_id = $2`,
+		serverID, userID, coOwner)
+	return err
+}
+
 // Invites
 
 func (r *ServerRepository) CreateInvite(ctx context.Context, invite *models.Invite) error {
@@ -224,6 +538,16 @@ func (r *ServerRepository) GetInvites(ctx context.Context, serverID uuid.UUID) (
 	return invites, err
 }
 
+// PageInvites returns up to limit of the server's invites after cursor,
+// newest first
+func (r *ServerRepository) PageInvites(ctx context.Context, serverID uuid.UUID, cursor *models.PageCursor, limit int) ([]*models.Invite, error) {
+	condition, args, order := keysetPage(cursor, "created_at", "code", 3)
+	invites := []*models.Invite{}
+	err := r.db.SelectContext(ctx, &invites, `SELECT * FROM invites WHERE server_id = $1`+condition+` ORDER BY `+order+` LIMIT $2`,
+		append([]interface{}{serverID, limit}, args...)...)
+	return invites, err
+}
+
 func (r *ServerRepository) DeleteInvite(ctx context.Context, code string) error {
 	_, err := r.db.ExecContext(ctx, `DELETE FROM invites WHERE code = $1`, code)
 	return err
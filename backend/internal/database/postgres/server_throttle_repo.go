@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"hearth/internal/models"
+)
+
+// ServerThrottleRepository stores per-server rate limit overrides
+type ServerThrottleRepository struct {
+	db *sqlx.DB
+}
+
+func NewServerThrottleRepository(db *sqlx.DB) *ServerThrottleRepository {
+	return &ServerThrottleRepository{db: db}
+}
+
+// Get returns a server's overrides, or nil if it has none
+func (r *ServerThrottleRepository) Get(ctx context.Context, serverID uuid.UUID) (*models.ServerThrottle, error) {
+	var throttle models.ServerThrottle
+	err := r.db.GetContext(ctx, &throttle, `SELECT * FROM server_throttles WHERE server_id = $1`, serverID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+// Save sets a server's overrides
+func (r *ServerThrottleRepository) Save(ctx context.Context, throttle *models.ServerThrottle) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO server_throttles (server_id, messages_per_second, events_per_second, reason, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (server_id) DO UPDATE SET
+			messages_per_second = EXCLUDED.messages_per_second,
+			events_per_second = EXCLUDED.events_per_second,
+			reason = EXCLUDED.reason,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+	`, throttle.ServerID, throttle.MessagesPerSecond, throttle.EventsPerSecond, throttle.Reason, throttle.UpdatedBy, throttle.UpdatedAt)
+	return err
+}
+
+// Delete removes a server's overrides
+func (r *ServerThrottleRepository) Delete(ctx context.Context, serverID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM server_throttles WHERE server_id = $1`, serverID)
+	return err
+}
@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"hearth/internal/models"
+)
+
+// ServerTokenRepository stores server tokens
+type ServerTokenRepository struct {
+	db *sqlx.DB
+}
+
+func NewServerTokenRepository(db *sqlx.DB) *ServerTokenRepository {
+	return &ServerTokenRepository{db: db}
+}
+
+const serverTokenColumns = `id, server_id, creator_id, name, scopes, token_hash, expires_at, last_used_at, created_at`
+
+// serverTokenRow is a token as stored, with its scopes still encoded
+type serverTokenRow struct {
+	ID         uuid.UUID      `db:"id"`
+	ServerID   uuid.UUID      `db:"server_id"`
+	CreatorID  uuid.UUID      `db:"creator_id"`
+	Name       string         `db:"name"`
+	Scopes     pq.StringArray `db:"scopes"`
+	TokenHash  string         `db:"token_hash"`
+	ExpiresAt  time.Time      `db:"expires_at"`
+	LastUsedAt *time.Time     `db:"last_used_at"`
+	CreatedAt  time.Time      `db:"created_at"`
+}
+
+func (row *serverTokenRow) token() *models.ServerToken {
+	scopes := []string(row.Scopes)
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return &models.ServerToken{
+		ID:         row.ID,
+		ServerID:   row.ServerID,
+		CreatorID:  row.CreatorID,
+		Name:       row.Name,
+		Scopes:     scopes,
+		TokenHash:  row.TokenHash,
+		ExpiresAt:  row.ExpiresAt,
+		LastUsedAt: row.LastUsedAt,
+		CreatedAt:  row.CreatedAt,
+	}
+}
+
+// Create stores a new token
+func (r *ServerTokenRepository) Create(ctx context.Context, token *models.ServerToken) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO server_tokens (`+serverTokenColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, token.ID, token.ServerID, token.CreatorID, token.Name, pq.Array(token.Scopes), token.TokenHash,
+		token.ExpiresAt, token.LastUsedAt, token.CreatedAt)
+	return err
+}
+
+// GetByHash returns the token with the hash, or nil if there's none
+func (r *ServerTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.ServerToken, error) {
+	var row serverTokenRow
+	err := r.db.GetContext(ctx, &row, `SELECT `+serverTokenColumns+` FROM server_tokens WHERE token_hash = $1`, tokenHash)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row.token(), nil
+}
+
+// GetByServerID returns the server's tokens, newest first
+func (r *ServerTokenRepository) GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*models.ServerToken, error) {
+	var rows []serverTokenRow
+	err := r.db.SelectContext(ctx, &rows, `
+		SELECT `+serverTokenColumns+` FROM server_tokens
+		WHERE server_id = $1
+		ORDER BY created_at DESC
+	`, serverID)
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]*models.ServerToken, 0, len(rows))
+	for i := range rows {
+		tokens = append(tokens, rows[i].token())
+	}
+	return tokens, nil
+}
+
+// Delete removes a server's token and reports whether it had it
+func (r *ServerTokenRepository) Delete(ctx context.Context, serverID, tokenID uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM server_tokens WHERE id = $1 AND server_id = $2`, tokenID, serverID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// Touch records when the token was last used
+func (r *ServerTokenRepository) Touch(ctx context.Context, tokenID uuid.UUID, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE server_tokens SET last_used_at = $2 WHERE id = $1`, tokenID, at)
+	return err
+}
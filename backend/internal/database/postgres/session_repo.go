@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"hearth/internal/models"
+)
+
+// SessionRepository stores login sessions and token versions
+type SessionRepository struct {
+	db *sqlx.DB
+}
+
+func NewSessionRepository(db *sqlx.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// Create saves a new session, clearing out the user's expired ones
+func (r *SessionRepository) Create(ctx context.Context, session *models.Session) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = $1 AND expires_at <= $2`, session.UserID, session.CreatedAt); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO sessions (id, user_id, refresh_token_hash, ip_address, user_agent, last_used_at, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, session.ID, session.UserID, session.RefreshToken, session.IPAddress, session.UserAgent,
+		session.LastUsedAt, session.ExpiresAt, session.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Get returns a session, or nil if there's none
+func (r *SessionRepository) Get(ctx context.Context, id uuid.UUID) (*models.Session, error) {
+	var session models.Session
+	err := r.db.GetContext(ctx, &session, `SELECT * FROM sessions WHERE id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Rotate replaces the session's refresh token hash, client details and
+// expiry, as long as the stored hash is still oldHash. It returns false
+// if another refresh got there first.
+func (r *SessionRepository) Rotate(ctx context.Context, session *models.Session, oldHash string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE sessions
+		SET refresh_token_hash = $3, ip_address = $4, user_agent = $5, last_used_at = $6, expires_at = $7
+		WHERE id = $1 AND refresh_token_hash = $2
+	`, session.ID, oldHash, session.RefreshToken, session.IPAddress, session.UserAgent,
+		session.LastUsedAt, session.ExpiresAt)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// ListByUser returns a user's sessions that haven't expired, most recently
+// used first
+func (r *SessionRepository) ListByUser(ctx context.Context, userID uuid.UUID, now time.Time) ([]*models.Session, error) {
+	var sessions []*models.Session
+	err := r.db.SelectContext(ctx, &sessions, `
+		SELECT * FROM sessions
+		WHERE user_id = $1 AND expires_at > $2
+		ORDER BY last_used_at DESC NULLS LAST, created_at DESC
+	`, userID, now)
+	return sessions, err
+}
+
+// Delete removes one of a user's sessions. It returns false if the user
+// has no such session.
+func (r *SessionRepository) Delete(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// DeleteByUser removes all of a user's sessions
+func (r *SessionRepository) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = $1`, userID)
+	return err
+}
+
+// TokenVersion returns a user's token version, which starts at 0
+func (r *SessionRepository) TokenVersion(ctx context.Context, userID uuid.UUID) (int, error) {
+	var version int
+	err := r.db.GetContext(ctx, &version, `SELECT version FROM token_versions WHERE user_id = $1`, userID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return version, err
+}
+
+// BumpTokenVersion moves a user to the next token version and returns it
+func (r *SessionRepository) BumpTokenVersion(ctx context.Context, userID uuid.UUID) (int, error) {
+	var version int
+	err := r.db.GetContext(ctx, &version, `
+		INSERT INTO token_versions (user_id, version) VALUES ($1, 1)
+		ON CONFLICT (user_id) DO UPDATE SET version = token_versions.version + 1
+		RETURNING version
+	`, userID)
+	return version, err
+}
@@ -39,6 +39,7 @@ func (r *SettingsRepository) Get(ctx context.Context, userID uuid.UUID) (*models
 			COALESCE(privacy_show_activity, true) as privacy_show_activity,
 			COALESCE(privacy_friend_requests_all, true) as privacy_friend_requests_all,
 			COALESCE(privacy_read_receipts, true) as privacy_read_receipts,
+			privacy_presence_friends_only,
 			COALESCE(locale, 'en-US') as locale,
 			updated_at
 		FROM user_settings 
@@ -60,9 +61,10 @@ func (r *SettingsRepository) Create(ctx context.Context, settings *models.UserSe
 			notifications_enabled, notifications_sound, notifications_desktop, notifications_mentions_only,
 			notifications_dm, notifications_server_defaults,
 			privacy_dm_from_servers, privacy_dm_from_friends_only, privacy_show_activity,
-			privacy_friend_requests_all, privacy_read_receipts, locale, updated_at
+			privacy_friend_requests_all, privacy_read_receipts, locale, updated_at,
+			privacy_presence_friends_only
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25
 		)
 	`
 	_, err := r.db.ExecContext(ctx, query,
@@ -72,6 +74,7 @@ func (r *SettingsRepository) Create(ctx context.Context, settings *models.UserSe
 		settings.NotificationsDM, settings.NotificationsServerDefaults,
 		settings.PrivacyDMFromServers, settings.PrivacyDMFromFriendsOnly, settings.PrivacyShowActivity,
 		settings.PrivacyFriendRequestsAll, settings.PrivacyReadReceipts, settings.Locale, settings.UpdatedAt,
+		settings.PrivacyPresenceFriendsOnly,
 	)
 	return err
 }
@@ -85,7 +88,8 @@ func (r *SettingsRepository) Update(ctx context.Context, settings *models.UserSe
 			notifications_enabled = $12, notifications_sound = $13, notifications_desktop = $14, notifications_mentions_only = $15,
 			notifications_dm = $16, notifications_server_defaults = $17,
 			privacy_dm_from_servers = $18, privacy_dm_from_friends_only = $19, privacy_show_activity = $20,
-			privacy_friend_requests_all = $21, privacy_read_receipts = $22, locale = $23, updated_at = $24
+			privacy_friend_requests_all = $21, privacy_read_receipts = $22, locale = $23, updated_at = $24,
+			privacy_presence_friends_only = $25
 		WHERE user_id = $1
 	`
 	_, err := r.db.ExecContext(ctx, query,
@@ -95,6 +99,7 @@ func (r *SettingsRepository) Update(ctx context.Context, settings *models.UserSe
 		settings.NotificationsDM, settings.NotificationsServerDefaults,
 		settings.PrivacyDMFromServers, settings.PrivacyDMFromFriendsOnly, settings.PrivacyShowActivity,
 		settings.PrivacyFriendRequestsAll, settings.PrivacyReadReceipts, settings.Locale, settings.UpdatedAt,
+		settings.PrivacyPresenceFriendsOnly,
 	)
 	return err
 }
@@ -109,9 +114,10 @@ func (r *SettingsRepository) Upsert(ctx context.Context, settings *models.UserSe
 			notifications_enabled, notifications_sound, notifications_desktop, notifications_mentions_only,
 			notifications_dm, notifications_server_defaults,
 			privacy_dm_from_servers, privacy_dm_from_friends_only, privacy_show_activity,
-			privacy_friend_requests_all, privacy_read_receipts, locale, updated_at
+			privacy_friend_requests_all, privacy_read_receipts, locale, updated_at,
+			privacy_presence_friends_only
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25
 		)
 		ON CONFLICT (user_id) DO UPDATE SET
 			theme = EXCLUDED.theme,
@@ -135,6 +141,7 @@ func (r *SettingsRepository) Upsert(ctx context.Context, settings *models.UserSe
 			privacy_show_activity = EXCLUDED.privacy_show_activity,
 			privacy_friend_requests_all = EXCLUDED.privacy_friend_requests_all,
 			privacy_read_receipts = EXCLUDED.privacy_read_receipts,
+			privacy_presence_friends_only = EXCLUDED.privacy_presence_friends_only,
 			locale = EXCLUDED.locale,
 			updated_at = EXCLUDED.updated_at
 	`
@@ -145,6 +152,7 @@ func (r *SettingsRepository) Upsert(ctx context.Context, settings *models.UserSe
 		settings.NotificationsDM, settings.NotificationsServerDefaults,
 		settings.PrivacyDMFromServers, settings.PrivacyDMFromFriendsOnly, settings.PrivacyShowActivity,
 		settings.PrivacyFriendRequestsAll, settings.PrivacyReadReceipts, settings.Locale, settings.UpdatedAt,
+		settings.PrivacyPresenceFriendsOnly,
 	)
 	return err
 }
@@ -19,13 +19,28 @@ func NewThreadRepository(db *sqlx.DB) *ThreadRepository {
 	return &ThreadRepository{db: db}
 }
 
-// Create creates a new thread
+// Create creates a new thread with its owner as the first member
 func (r *ThreadRepository) Create(ctx context.Context, thread *models.Thread) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertThread(ctx, tx, thread); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertThread inserts a thread and its owner's membership. Forum posts
+// are created with it too.
+func insertThread(ctx context.Context, tx *sqlx.Tx, thread *models.Thread) error {
 	query := `
 		INSERT INTO threads (id, parent_channel_id, owner_id, name, message_count, member_count, archived, auto_archive, locked, created_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := tx.ExecContext(ctx, query,
 		thread.ID, thread.ParentChannelID, thread.OwnerID, thread.Name,
 		thread.MessageCount, thread.MemberCount, thread.Archived, thread.AutoArchive,
 		thread.Locked, thread.CreatedAt,
@@ -35,7 +50,7 @@ func (r *ThreadRepository) Create(ctx context.Context, thread *models.Thread) er
 	}
 
 	// Add owner as thread member
-	_, err = r.db.ExecContext(ctx,
+	_, err = tx.ExecContext(ctx,
 		`INSERT INTO thread_members (thread_id, user_id) VALUES ($1, $2)`,
 		thread.ID, thread.OwnerID,
 	)
@@ -110,33 +125,44 @@ func (r *ThreadRepository) Unarchive(ctx context.Context, id uuid.UUID) error {
 
 // AddMember adds a user to a thread
 func (r *ThreadRepository) AddMember(ctx context.Context, threadID, userID uuid.UUID) error {
-	query := `INSERT INTO thread_members (thread_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
-	result, err := r.db.ExecContext(ctx, query, threadID, userID)
-	if err != nil {
-		return err
-	}
-
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected > 0 {
-		// Increment member count
-		_, err = r.db.ExecContext(ctx, `UPDATE threads SET member_count = member_count + 1 WHERE id = $1`, threadID)
-	}
-	return err
+	return r.changeMembers(ctx,
+		`INSERT INTO thread_members (thread_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		`UPDATE threads SET member_count = member_count + 1 WHERE id = $1`,
+		threadID, userID)
 }
 
 // RemoveMember removes a user from a thread
 func (r *ThreadRepository) RemoveMember(ctx context.Context, threadID, userID uuid.UUID) error {
-	result, err := r.db.ExecContext(ctx, `DELETE FROM thread_members WHERE thread_id = $1 AND user_id = $2`, threadID, userID)
+	return r.changeMembers(ctx,
+		`DELETE FROM thread_members WHERE thread_id = $1 AND user_id = $2`,
+		`UPDATE threads SET member_count = GREATEST(member_count - 1, 0) WHERE id = $1`,
+		threadID, userID)
+}
+
+// changeMembers runs a membership change and, only when it changed a row,
+// the matching member count update, in one transaction so the count can't
+// drift from thread_members
+func (r *ThreadRepository) changeMembers(ctx context.Context, change, count string, threadID, userID uuid.UUID) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, _ := result.RowsAffected()
+	result, err := tx.ExecContext(ctx, change, threadID, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
 	if rowsAffected > 0 {
-		// Decrement member count
-		_, err = r.db.ExecContext(ctx, `UPDATE threads SET member_count = GREATEST(member_count - 1, 0) WHERE id = $1`, threadID)
+		if _, err := tx.ExecContext(ctx, count, threadID); err != nil {
+			return err
+		}
 	}
-	return err
+	return tx.Commit()
 }
 
 // IsMember checks if a user is a member of a thread
@@ -163,21 +189,34 @@ func (r *ThreadRepository) CreateMessage(ctx context.Context, threadID, authorID
 		CreatedAt: time.Now(),
 	}
 
-	query := `INSERT INTO thread_messages (id, thread_id, author_id, content, created_at) VALUES ($1, $2, $3, $4, $5)`
-	_, err := r.db.ExecContext(ctx, query, msg.ID, msg.ThreadID, msg.AuthorID, msg.Content, msg.CreatedAt)
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	// Increment message count
-	_, err = r.db.ExecContext(ctx, `UPDATE threads SET message_count = message_count + 1 WHERE id = $1`, threadID)
-	if err != nil {
+	if err := insertThreadMessage(ctx, tx, msg); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
-
 	return msg, nil
 }
 
+// insertThreadMessage inserts a message and counts it on its thread
+func insertThreadMessage(ctx context.Context, tx *sqlx.Tx, msg *models.ThreadMessage) error {
+	query := `INSERT INTO thread_messages (id, thread_id, author_id, content, created_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err := tx.ExecContext(ctx, query, msg.ID, msg.ThreadID, msg.AuthorID, msg.Content, msg.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	// Increment message count
+	_, err = tx.ExecContext(ctx, `UPDATE threads SET message_count = message_count + 1 WHERE id = $1`, msg.ThreadID)
+	return err
+}
+
 // GetMessages retrieves messages from a thread with pagination
 func (r *ThreadRepository) GetMessages(ctx context.Context, threadID uuid.UUID, before *uuid.UUID, limit int) ([]*models.ThreadMessage, error) {
 	if limit <= 0 || limit > 100 {
@@ -3,10 +3,13 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	
 	"hearth/internal/models"
 	"hearth/internal/services"
@@ -32,6 +35,19 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 		user.AvatarURL, user.BannerURL, user.Bio, user.Status, user.MFAEnabled,
 		user.Verified, user.Flags, user.CreatedAt, user.UpdatedAt,
 	)
+	return usernameError(err)
+}
+
+// usernameIndex keeps usernames unique regardless of case
+const usernameIndex = "idx_users_username_lower"
+
+// usernameError turns a clash on usernameIndex into ErrUsernameTaken, so
+// two accounts racing for a username get a clean error
+func usernameError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == usernameIndex {
+		return services.ErrUsernameTaken
+	}
 	return err
 }
 
@@ -45,9 +61,28 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Use
 	return &user, err
 }
 
+// GetByIDs loads many users in one query. Missing IDs are skipped.
+func (r *UserRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.User, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	
+	query, args, err := sqlx.In(`SELECT * FROM users WHERE id IN (?)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	query = r.db.Rebind(query)
+	
+	var users []*models.User
+	if err := r.db.SelectContext(ctx, &users, query, args...); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
-	query := `SELECT * FROM users WHERE username = $1`
+	query := `SELECT * FROM users WHERE LOWER(username) = LOWER($1)`
 	err := r.db.GetContext(ctx, &user, query, username)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -70,17 +105,96 @@ func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 		UPDATE users SET
 			username = $2, discriminator = $3, email = $4, password_hash = $5,
 			avatar_url = $6, banner_url = $7, bio = $8, status = $9, 
-			custom_status = $10, mfa_enabled = $11, verified = $12, flags = $13, updated_at = $14
+			custom_status = $10, mfa_enabled = $11, verified = $12, flags = $13, updated_at = $14,
+			custom_status_emoji = $15, custom_status_expires_at = $16
 		WHERE id = $1
 	`
 	_, err := r.db.ExecContext(ctx, query,
 		user.ID, user.Username, user.Discriminator, user.Email, user.PasswordHash,
 		user.AvatarURL, user.BannerURL, user.Bio, user.Status, user.CustomStatus,
 		user.MFAEnabled, user.Verified, user.Flags, user.UpdatedAt,
+		user.CustomStatusEmoji, user.CustomStatusExpiresAt,
 	)
+	return usernameError(err)
+}
+
+// UpdatePassword sets a user's password hash
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string, at time.Time) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE users SET password_hash = $2, updated_at = $3 WHERE id = $1`, userID, passwordHash, at)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrUserNotFound
+	}
 	return err
 }
 
+// ChangeUsername renames a user and records the old username in their
+// history. It clears UserFlagUsernameReset.
+func (r *UserRepository) ChangeUsername(ctx context.Context, userID uuid.UUID, oldName, newName string, at time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE users SET username = $2, username_changed_at = $3, updated_at = $3, flags = COALESCE(flags, 0) & ~$4::bigint
+		WHERE id = $1
+	`, userID, newName, at, models.UserFlagUsernameReset)
+	if err != nil {
+		return usernameError(err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO username_history (user_id, username, changed_at) VALUES ($1, $2, $3)`,
+		userID, oldName, at)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetUsernameHistory returns a user's previous usernames, newest first
+func (r *UserRepository) GetUsernameHistory(ctx context.Context, userID uuid.UUID, limit int) ([]*models.UsernameChange, error) {
+	history := []*models.UsernameChange{}
+	err := r.db.SelectContext(ctx, &history, `
+		SELECT username, changed_at FROM username_history
+		WHERE user_id = $1
+		ORDER BY changed_at DESC
+		LIMIT $2
+	`, userID, limit)
+	return history, err
+}
+
+// TakenUsernames returns which of the names an account already has,
+// lowercased
+func (r *UserRepository) TakenUsernames(ctx context.Context, names []string) ([]string, error) {
+	lowered := make([]string, len(names))
+	for i, name := range names {
+		lowered[i] = strings.ToLower(name)
+	}
+	taken := []string{}
+	err := r.db.SelectContext(ctx, &taken,
+		`SELECT LOWER(username) FROM users WHERE LOWER(username) = ANY($1)`, pq.Array(lowered))
+	return taken, err
+}
+
+// ClearExpiredCustomStatuses clears every custom status that expired at or
+// before the given time and returns the affected users
+func (r *UserRepository) ClearExpiredCustomStatuses(ctx context.Context, before time.Time) ([]uuid.UUID, error) {
+	query := `
+		UPDATE users SET
+			custom_status = NULL, custom_status_emoji = NULL,
+			custom_status_expires_at = NULL, updated_at = NOW()
+		WHERE custom_status_expires_at IS NOT NULL AND custom_status_expires_at <= $1
+		RETURNING id
+	`
+	var ids []uuid.UUID
+	err := r.db.SelectContext(ctx, &ids, query, before)
+	return ids, err
+}
+
 func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	_, err := r.db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
 	return err
@@ -109,8 +223,13 @@ func (r *UserRepository) AddFriend(ctx context.Context, userID, friendID uuid.UU
 	return err
 }
 
+// RemoveFriend clears a friendship or pending request on both sides. Blocks
+// are left for UnblockUser, so neither user can lift the other's block.
 func (r *UserRepository) RemoveFriend(ctx context.Context, userID, friendID uuid.UUID) error {
-	query := `DELETE FROM relationships WHERE (user_id = $1 AND target_id = $2) OR (user_id = $2 AND target_id = $1)`
+	query := `
+		DELETE FROM relationships
+		WHERE ((user_id = $1 AND target_id = $2) OR (user_id = $2 AND target_id = $1)) AND type <> 2
+	`
 	_, err := r.db.ExecContext(ctx, query, userID, friendID)
 	return err
 }
@@ -413,3 +532,22 @@ func (r *UserRepository) GetMutualFriends(ctx context.Context, userID1, userID2
 	err := r.db.SelectContext(ctx, &users, query, userID1, userID2, limit)
 	return users, total, err
 }
+
+// List returns users oldest first, for instance admins. A non-empty query
+// matches the start of usernames and emails, ignoring case. after is the
+// last user of the previous page.
+func (r *UserRepository) List(ctx context.Context, query string, after *uuid.UUID, limit int) ([]*models.User, error) {
+	pattern := strings.ToLower(likeEscaper.Replace(query)) + "%"
+	users := []*models.User{}
+	err := r.db.SelectContext(ctx, &users, `
+		SELECT * FROM users
+		WHERE ($1 = '' OR LOWER(username) LIKE $2 OR LOWER(email) LIKE $2)
+			AND ($3::uuid IS NULL OR (created_at, id) > (SELECT created_at, id FROM users WHERE id = $3))
+		ORDER BY created_at, id
+		LIMIT $4
+	`, query, pattern, after, limit)
+	return users, err
+}
+
+// likeEscaper escapes LIKE's wildcards, so they match themselves
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
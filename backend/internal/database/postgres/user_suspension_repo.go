@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"hearth/internal/models"
+)
+
+// UserSuspensionRepository stores the accounts instance admins suspended
+type UserSuspensionRepository struct {
+	db *sqlx.DB
+}
+
+func NewUserSuspensionRepository(db *sqlx.DB) *UserSuspensionRepository {
+	return &UserSuspensionRepository{db: db}
+}
+
+// Get returns the user's suspension, expired or not, or nil if there's none
+func (r *UserSuspensionRepository) Get(ctx context.Context, userID uuid.UUID) (*models.UserSuspension, error) {
+	var suspension models.UserSuspension
+	err := r.db.GetContext(ctx, &suspension, `SELECT * FROM user_suspensions WHERE user_id = $1`, userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &suspension, nil
+}
+
+// GetMany returns the suspensions of the users that have one
+func (r *UserSuspensionRepository) GetMany(ctx context.Context, userIDs []uuid.UUID) ([]*models.UserSuspension, error) {
+	suspensions := []*models.UserSuspension{}
+	if len(userIDs) == 0 {
+		return suspensions, nil
+	}
+	query, args, err := sqlx.In(`SELECT * FROM user_suspensions WHERE user_id IN (?)`, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	err = r.db.SelectContext(ctx, &suspensions, r.db.Rebind(query), args...)
+	return suspensions, err
+}
+
+// Save suspends the user, replacing any suspension they already have
+func (r *UserSuspensionRepository) Save(ctx context.Context, suspension *models.UserSuspension) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO user_suspensions (user_id, reason, suspended_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET
+			reason = EXCLUDED.reason,
+			suspended_by = EXCLUDED.suspended_by,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at
+	`, suspension.UserID, suspension.Reason, suspension.SuspendedBy, suspension.CreatedAt, suspension.ExpiresAt)
+	return err
+}
+
+// Delete lifts the user's suspension, reporting whether they had one
+func (r *UserSuspensionRepository) Delete(ctx context.Context, userID uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM user_suspensions WHERE user_id = $1`, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
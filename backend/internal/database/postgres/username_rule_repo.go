@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// UsernameRuleRepository stores reserved and banned usernames
+type UsernameRuleRepository struct {
+	db *sqlx.DB
+}
+
+func NewUsernameRuleRepository(db *sqlx.DB) *UsernameRuleRepository {
+	return &UsernameRuleRepository{db: db}
+}
+
+// List returns every rule, oldest first
+func (r *UsernameRuleRepository) List(ctx context.Context) ([]*models.UsernameRule, error) {
+	rules := []*models.UsernameRule{}
+	err := r.db.SelectContext(ctx, &rules, `SELECT * FROM username_rules ORDER BY created_at, id`)
+	return rules, err
+}
+
+// Create adds a rule. It returns services.ErrUsernameRuleExists if the
+// same pattern is already listed.
+func (r *UsernameRuleRepository) Create(ctx context.Context, rule *models.UsernameRule) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO username_rules (id, kind, pattern, regex, reason, creator_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, rule.ID, rule.Kind, rule.Pattern, rule.Regex, rule.Reason, rule.CreatorID, rule.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return services.ErrUsernameRuleExists
+	}
+	return err
+}
+
+// Delete removes a rule, reporting whether it existed
+func (r *UsernameRuleRepository) Delete(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM username_rules WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"hearth/internal/models"
+)
+
+// WebhookPolicyRepository stores servers' and channels' webhook policies
+type WebhookPolicyRepository struct {
+	db *sqlx.DB
+}
+
+func NewWebhookPolicyRepository(db *sqlx.DB) *WebhookPolicyRepository {
+	return &WebhookPolicyRepository{db: db}
+}
+
+// webhookPolicyRow is a policy as stored, with its arrays still encoded
+type webhookPolicyRow struct {
+	ServerID       uuid.UUID      `db:"server_id"`
+	ChannelID      *uuid.UUID     `db:"channel_id"`
+	CreatorRoleIDs pq.StringArray `db:"creator_role_ids"`
+	AllowedDomains pq.StringArray `db:"allowed_domains"`
+	UpdatedBy      *uuid.UUID     `db:"updated_by"`
+	UpdatedAt      time.Time      `db:"updated_at"`
+}
+
+// Get returns the policy, or nil if there's none. A nil channelID gets the
+// server's own policy.
+func (r *WebhookPolicyRepository) Get(ctx context.Context, serverID uuid.UUID, channelID *uuid.UUID) (*models.WebhookPolicy, error) {
+	var row webhookPolicyRow
+	err := r.db.GetContext(ctx, &row, `
+		SELECT server_id, channel_id, creator_role_ids, allowed_domains, updated_by, updated_at
+		FROM webhook_policies
+		WHERE server_id = $1 AND channel_id IS NOT DISTINCT FROM $2
+	`, serverID, channelID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &models.WebhookPolicy{
+		ServerID:       row.ServerID,
+		ChannelID:      row.ChannelID,
+		AllowedDomains: []string(row.AllowedDomains),
+		UpdatedAt:      row.UpdatedAt,
+	}
+	if row.UpdatedBy != nil {
+		policy.UpdatedBy = *row.UpdatedBy
+	}
+	if policy.CreatorRoleIDs, err = parseUUIDs(row.CreatorRoleIDs); err != nil {
+		return nil, err
+	}
+	if policy.AllowedDomains == nil {
+		policy.AllowedDomains = []string{}
+	}
+	return policy, nil
+}
+
+// Save creates or replaces the policy
+func (r *WebhookPolicyRepository) Save(ctx context.Context, policy *models.WebhookPolicy) error {
+	conflict := `(server_id) WHERE channel_id IS NULL`
+	if policy.ChannelID != nil {
+		conflict = `(channel_id) WHERE channel_id IS NOT NULL`
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO webhook_policies (server_id, channel_id, creator_role_ids, allowed_domains, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT `+conflict+` DO UPDATE SET
+			creator_role_ids = EXCLUDED.creator_role_ids,
+			allowed_domains = EXCLUDED.allowed_domains,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+	`, policy.ServerID, policy.ChannelID, pq.Array(policy.CreatorRoleIDs), pq.Array(policy.AllowedDomains),
+		policy.UpdatedBy, policy.UpdatedAt)
+	return err
+}
+
+// Delete removes the policy. A nil channelID removes the server's own.
+func (r *WebhookPolicyRepository) Delete(ctx context.Context, serverID uuid.UUID, channelID *uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM webhook_policies
+		WHERE server_id = $1 AND channel_id IS NOT DISTINCT FROM $2
+	`, serverID, channelID)
+	return err
+}
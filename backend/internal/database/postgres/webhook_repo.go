@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"hearth/internal/models"
+)
+
+// webhookColumns maps the webhooks table onto models.Webhook. Every stored
+// webhook is incoming; the table has no type column.
+const webhookColumns = `
+	id, 1 AS type, server_id, channel_id, creator_id, name, avatar_url AS avatar,
+	token, source_server_id, source_channel_id, created_at`
+
+// WebhookRepository handles webhook database operations
+type WebhookRepository struct {
+	db *sqlx.DB
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db *sqlx.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// Create stores a new webhook
+func (r *WebhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	query := `
+		INSERT INTO webhooks (id, channel_id, server_id, creator_id, name, avatar_url, token,
+			source_server_id, source_channel_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		webhook.ID, webhook.ChannelID, webhook.ServerID, webhook.CreatorID, webhook.Name, webhook.Avatar,
+		webhook.Token, webhook.SourceServerID, webhook.SourceChannelID, webhook.CreatedAt,
+	)
+	return err
+}
+
+// GetByID returns a webhook, or nil if there is none
+func (r *WebhookRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	var webhook models.Webhook
+	err := r.db.GetContext(ctx, &webhook, `SELECT `+webhookColumns+` FROM webhooks WHERE id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// GetByChannelID returns a channel's webhooks, oldest first
+func (r *WebhookRepository) GetByChannelID(ctx context.Context, channelID uuid.UUID) ([]*models.Webhook, error) {
+	var webhooks []*models.Webhook
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE channel_id = $1 ORDER BY created_at`
+	if err := r.db.SelectContext(ctx, &webhooks, query, channelID); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// GetByServerID returns a server's webhooks, oldest first
+func (r *WebhookRepository) GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*models.Webhook, error) {
+	var webhooks []*models.Webhook
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE server_id = $1 ORDER BY created_at`
+	if err := r.db.SelectContext(ctx, &webhooks, query, serverID); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// Update saves a webhook's name, avatar and channel
+func (r *WebhookRepository) Update(ctx context.Context, webhook *models.Webhook) error {
+	query := `UPDATE webhooks SET name = $2, avatar_url = $3, channel_id = $4 WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, webhook.ID, webhook.Name, webhook.Avatar, webhook.ChannelID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Delete removes a webhook
+func (r *WebhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	return err
+}
+
+// CountByChannelID counts a channel's webhooks
+func (r *WebhookRepository) CountByChannelID(ctx context.Context, channelID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM webhooks WHERE channel_id = $1`, channelID)
+	return count, err
+}
@@ -190,39 +190,84 @@ const (
 	UserDeleted    = "user.deleted"
 	PresenceUpdate = "presence.updated"
 
+	CustomStatusUpdated = "user.custom_status_updated"
+
+	// Relationship events
+	FriendAdded           = "friend.added"
+	FriendRemoved         = "friend.removed"
+	FriendRequestSent     = "friend.request_sent"
+	FriendRequestDeclined = "friend.request_declined"
+	UserBlocked           = "user.blocked"
+	UserUnblocked         = "user.unblocked"
+
 	// Server events
 	ServerCreated = "server.created"
 	ServerUpdated = "server.updated"
 	ServerDeleted = "server.deleted"
 
 	// Member events
-	MemberJoined  = "server.member_joined"
-	MemberLeft    = "server.member_left"
-	MemberKicked  = "server.member_kicked"
-	MemberBanned  = "server.member_banned"
-	MemberUpdated = "server.member_updated"
+	MemberJoined   = "server.member_joined"
+	MemberLeft     = "server.member_left"
+	MemberKicked   = "server.member_kicked"
+	MemberBanned   = "server.member_banned"
+	MemberUnbanned = "server.member_unbanned"
+	MemberUpdated  = "server.member_updated"
+
+	// Emoji events
+	EmojisUpdated = "server.emojis_updated"
 
 	// Channel events
 	ChannelCreated = "channel.created"
 	ChannelUpdated = "channel.updated"
 	ChannelDeleted = "channel.deleted"
 
+	// Encrypted channel key events
+	ChannelKeysRotated    = "channel.keys_rotated"
+	SenderKeysDistributed = "channel.sender_keys_distributed"
+
 	// Message events
-	MessageCreated = "message.created"
-	MessageUpdated = "message.updated"
-	MessageDeleted = "message.deleted"
-	MessagePinned  = "message.pinned"
+	MessageCreated      = "message.created"
+	MessageUpdated      = "message.updated"
+	MessageDeleted      = "message.deleted"
+	MessagesBulkDeleted = "message.deleted_bulk"
+	MessagePinned       = "message.pinned"
+	MessageAcked        = "message.acked"
+	MessageMentioned    = "message.mentioned"
+
+	// Attachment events
+	AttachmentUpdated        = "attachment.updated"
+	AttachmentUploadProgress = "attachment.upload_progress"
 
 	// Reaction events
 	ReactionAdded   = "reaction.added"
 	ReactionRemoved = "reaction.removed"
 
+	// Thread events
+	ThreadCreated        = "thread.created"
+	ThreadUpdated        = "thread.updated"
+	ThreadDeleted        = "thread.deleted"
+	ThreadMessageCreated = "thread.message_created"
+
 	// Typing events
 	TypingStarted = "typing.started"
+	TypingStopped = "typing.stopped"
+
+	// AutoMod events
+	AutoModActionExecuted = "automod.action_executed"
 
 	// Voice events
 	VoiceJoined   = "voice.joined"
 	VoiceLeft     = "voice.left"
 	VoiceMuted    = "voice.muted"
 	VoiceDeafened = "voice.deafened"
+
+	VoiceStateUpdated = "voice.state_updated"
+	StreamCreated     = "voice.stream_created"
+	StreamUpdated     = "voice.stream_updated"
+	StreamDeleted     = "voice.stream_deleted"
+
+	// Instance events
+	AnnouncementCreated = "announcement.created"
+	AnnouncementDeleted = "announcement.deleted"
+	ReadOnlyModeUpdated = "instance.read_only_updated"
 )
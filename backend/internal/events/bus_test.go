@@ -166,12 +166,18 @@ func TestEventTypes(t *testing.T) {
 	eventTypes := []string{
 		UserCreated, UserUpdated, UserDeleted, PresenceUpdate,
 		ServerCreated, ServerUpdated, ServerDeleted,
-		MemberJoined, MemberLeft, MemberKicked, MemberBanned, MemberUpdated,
+		MemberJoined, MemberLeft, MemberKicked, MemberBanned, MemberUnbanned, MemberUpdated,
+		EmojisUpdated,
 		ChannelCreated, ChannelUpdated, ChannelDeleted,
-		MessageCreated, MessageUpdated, MessageDeleted, MessagePinned,
+		ChannelKeysRotated, SenderKeysDistributed,
+		MessageCreated, MessageUpdated, MessageDeleted, MessagesBulkDeleted, MessagePinned,
+		AttachmentUpdated,
 		ReactionAdded, ReactionRemoved,
-		TypingStarted,
+		ThreadCreated, ThreadUpdated, ThreadDeleted, ThreadMessageCreated,
+		TypingStarted, TypingStopped,
+		AutoModActionExecuted,
 		VoiceJoined, VoiceLeft, VoiceMuted, VoiceDeafened,
+		VoiceStateUpdated, StreamCreated, StreamUpdated, StreamDeleted,
 	}
 
 	for _, et := range eventTypes {
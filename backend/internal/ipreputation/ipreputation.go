@@ -0,0 +1,116 @@
+// Package ipreputation looks up what the outside world knows about client
+// addresses: whether DNS blocklists such as Spamhaus ZEN list them, and
+// which autonomous system announces them.
+package ipreputation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	cymruOriginZone  = "origin.asn.cymru.com"
+	cymruOrigin6Zone = "origin6.asn.cymru.com"
+)
+
+// DNSBL checks addresses against a DNS blocklist. Listed addresses resolve
+// to an address in 127.0.0.0/8 under the zone; unlisted ones don't resolve.
+type DNSBL struct {
+	zone       string
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+}
+
+// NewDNSBL creates a checker for the blocklist at zone, e.g.
+// zen.spamhaus.org
+func NewDNSBL(zone string) *DNSBL {
+	return &DNSBL{
+		zone:       strings.TrimSuffix(zone, "."),
+		lookupHost: net.DefaultResolver.LookupHost,
+	}
+}
+
+// Name is the blocklist's zone
+func (d *DNSBL) Name() string { return d.zone }
+
+// Listed reports whether the blocklist lists ip
+func (d *DNSBL) Listed(ctx context.Context, ip net.IP) (bool, error) {
+	addrs, err := d.lookupHost(ctx, reverseName(ip)+"."+d.zone)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	for _, addr := range addrs {
+		// Spamhaus answers 127.255.255.x when it refuses the query, e.g.
+		// from a public resolver, which says nothing about the address
+		if strings.HasPrefix(addr, "127.255.255.") {
+			return false, fmt.Errorf("%s refused the query (%s)", d.zone, addr)
+		}
+		if strings.HasPrefix(addr, "127.") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CymruASN looks up the autonomous system announcing an address with Team
+// Cymru's IP to ASN DNS service
+type CymruASN struct {
+	lookupTXT func(ctx context.Context, name string) ([]string, error)
+}
+
+func NewCymruASN() *CymruASN {
+	return &CymruASN{lookupTXT: net.DefaultResolver.LookupTXT}
+}
+
+// ASN returns the autonomous system announcing ip, or 0 if none does.
+// Addresses announced by several take the first.
+func (c *CymruASN) ASN(ctx context.Context, ip net.IP) (int64, error) {
+	zone := cymruOriginZone
+	if ip.To4() == nil {
+		zone = cymruOrigin6Zone
+	}
+	records, err := c.lookupTXT(ctx, reverseName(ip)+"."+zone)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	// "15169 | 8.8.8.0/24 | US | arin | 2023-12-28"
+	for _, record := range records {
+		field, _, _ := strings.Cut(record, "|")
+		asns := strings.Fields(field)
+		if len(asns) == 0 {
+			continue
+		}
+		asn, err := strconv.ParseInt(asns[0], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("unexpected answer %q", record)
+		}
+		return asn, nil
+	}
+	return 0, nil
+}
+
+// reverseName is ip in the reversed form DNS lookup services take: the
+// octets of IPv4 addresses and the nibbles of IPv6 ones, last first
+func reverseName(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d", v4[3], v4[2], v4[1], v4[0])
+	}
+	const hex = "0123456789abcdef"
+	v6 := ip.To16()
+	labels := make([]string, 0, 32)
+	for i := len(v6) - 1; i >= 0; i-- {
+		labels = append(labels, string(hex[v6[i]&0x0f]), string(hex[v6[i]>>4]))
+	}
+	return strings.Join(labels, ".")
+}
@@ -0,0 +1,87 @@
+package ipreputation
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func notFound(name string) error {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func TestReverseName(t *testing.T) {
+	assert.Equal(t, "4.3.2.1", reverseName(net.ParseIP("1.2.3.4")))
+	assert.Equal(t,
+		"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2",
+		reverseName(net.ParseIP("2001:db8::1")))
+}
+
+func TestDNSBL_Listed(t *testing.T) {
+	ctx := context.Background()
+	d := NewDNSBL("zen.example.org.")
+	var queried string
+	d.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		queried = host
+		switch host {
+		case "2.0.0.127.zen.example.org":
+			return []string{"127.0.0.2", "127.0.0.10"}, nil
+		case "1.1.1.1.zen.example.org":
+			return []string{"127.255.255.254"}, nil
+		case "9.9.9.9.zen.example.org":
+			return nil, errors.New("timeout")
+		}
+		return nil, notFound(host)
+	}
+
+	assert.Equal(t, "zen.example.org", d.Name())
+
+	listed, err := d.Listed(ctx, net.ParseIP("127.0.0.2"))
+	require.NoError(t, err)
+	assert.True(t, listed)
+	assert.Equal(t, "2.0.0.127.zen.example.org", queried)
+
+	listed, err = d.Listed(ctx, net.ParseIP("8.8.8.8"))
+	require.NoError(t, err)
+	assert.False(t, listed)
+
+	_, err = d.Listed(ctx, net.ParseIP("1.1.1.1"))
+	assert.Error(t, err, "refused queries aren't answers")
+	_, err = d.Listed(ctx, net.ParseIP("9.9.9.9"))
+	assert.Error(t, err)
+}
+
+func TestCymruASN(t *testing.T) {
+	ctx := context.Background()
+	c := NewCymruASN()
+	c.lookupTXT = func(ctx context.Context, name string) ([]string, error) {
+		switch name {
+		case "8.8.8.8.origin.asn.cymru.com":
+			return []string{"15169 | 8.8.8.0/24 | US | arin | 2023-12-28"}, nil
+		case "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.origin6.asn.cymru.com":
+			return []string{"64500 64501 | 2001:db8::/32 | ZZ | ripencc | 2020-01-01"}, nil
+		case "5.5.5.5.origin.asn.cymru.com":
+			return []string{"garbage"}, nil
+		}
+		return nil, notFound(name)
+	}
+
+	asn, err := c.ASN(ctx, net.ParseIP("8.8.8.8"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(15169), asn)
+
+	asn, err = c.ASN(ctx, net.ParseIP("2001:db8::1"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(64500), asn, "the first of several announcing systems")
+
+	asn, err = c.ASN(ctx, net.ParseIP("10.0.0.1"))
+	require.NoError(t, err)
+	assert.Zero(t, asn)
+
+	_, err = c.ASN(ctx, net.ParseIP("5.5.5.5"))
+	assert.Error(t, err)
+}
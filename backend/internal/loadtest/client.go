@@ -0,0 +1,140 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// StatusError is a REST call that didn't return the expected status
+type StatusError struct {
+	Method string
+	Path   string
+	Status int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s: status %d", e.Method, e.Path, e.Status)
+}
+
+// client makes authenticated REST calls against /api/v1
+type client struct {
+	http    *http.Client
+	baseURL string
+	token   string
+}
+
+// do sends body as JSON and decodes the response into out, if set
+func (c *client) do(ctx context.Context, method, path string, body, out interface{}, want int) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/api/v1"+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != want {
+		io.Copy(io.Discard, resp.Body)
+		return &StatusError{Method: method, Path: path, Status: resp.StatusCode}
+	}
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// register creates an account and keeps its access token
+func (c *client) register(ctx context.Context, username, password string) error {
+	var tokens struct {
+		AccessToken string `json:"access_token"`
+	}
+	err := c.do(ctx, http.MethodPost, "/auth/register", map[string]string{
+		"email":    username + "@loadtest.invalid",
+		"username": username,
+		"password": password,
+	}, &tokens, http.StatusCreated)
+	if err != nil {
+		return err
+	}
+	c.token = tokens.AccessToken
+	return nil
+}
+
+type created struct {
+	ID string `json:"id"`
+}
+
+// createChannel creates a server with one text channel and returns the
+// channel's ID
+func (c *client) createChannel(ctx context.Context, name string) (string, error) {
+	var server created
+	if err := c.do(ctx, http.MethodPost, "/servers", map[string]string{"name": name}, &server, http.StatusCreated); err != nil {
+		return "", err
+	}
+	var channel created
+	err := c.do(ctx, http.MethodPost, "/servers/"+server.ID+"/channels", map[string]string{
+		"name": "load",
+		"type": "text",
+	}, &channel, http.StatusCreated)
+	return channel.ID, err
+}
+
+func (c *client) sendMessage(ctx context.Context, channelID, content string) (string, error) {
+	var message created
+	err := c.do(ctx, http.MethodPost, "/channels/"+channelID+"/messages", map[string]string{
+		"content": content,
+	}, &message, http.StatusCreated)
+	return message.ID, err
+}
+
+func (c *client) readMessages(ctx context.Context, channelID string) error {
+	return c.do(ctx, http.MethodGet, "/channels/"+channelID+"/messages?limit=50", nil, nil, http.StatusOK)
+}
+
+func (c *client) addReaction(ctx context.Context, channelID, messageID, emoji string) error {
+	path := "/channels/" + channelID + "/messages/" + messageID + "/reactions/" + url.PathEscape(emoji) + "/@me"
+	return c.do(ctx, http.MethodPut, path, nil, nil, http.StatusNoContent)
+}
+
+// gatewayURL turns an http(s) base URL into the gateway's ws(s) URL
+func gatewayURL(baseURL string) (string, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	default:
+		return "", fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	u.Path += "/gateway"
+	return u.String(), nil
+}
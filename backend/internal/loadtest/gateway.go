@@ -0,0 +1,193 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Gateway opcodes used by the harness
+const (
+	opDispatch  = 0
+	opHeartbeat = 1
+	opIdentify  = 2
+	opInvalid   = 9
+	opHello     = 10
+)
+
+type frame struct {
+	Op   int             `json:"op"`
+	Data json.RawMessage `json:"d,omitempty"`
+	Type string          `json:"t,omitempty"`
+}
+
+// gatewaySession is one simulated gateway connection subscribed to a
+// channel. It heartbeats until closed and reports every MESSAGE_CREATE.
+type gatewaySession struct {
+	conn      *websocket.Conn
+	startedAt time.Time
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	done      chan struct{}
+
+	// dropped is set if the server ended the session
+	dropped atomic.Bool
+}
+
+// dialGateway connects, identifies and subscribes to channelID. It returns
+// once READY has been received.
+func dialGateway(ctx context.Context, gatewayURL, token, channelID string, onMessage func(content string)) (*gatewaySession, error) {
+	u, err := url.Parse(gatewayURL)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("token", token)
+	q.Set("client_type", "loadtest")
+	u.RawQuery = q.Encode()
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetReadDeadline(deadline)
+	}
+
+	heartbeat, err := handshake(conn, channelID)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	s := &gatewaySession{
+		conn:      conn,
+		startedAt: time.Now(),
+		closed:    make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go s.heartbeat(heartbeat)
+	go s.read(onMessage)
+	return s, nil
+}
+
+// handshake waits for HELLO, identifies, waits for READY and subscribes.
+// It returns the heartbeat interval.
+func handshake(conn *websocket.Conn, channelID string) (time.Duration, error) {
+	var hello frame
+	if err := conn.ReadJSON(&hello); err != nil {
+		return 0, err
+	}
+	if hello.Op != opHello {
+		return 0, fmt.Errorf("expected hello, got op %d", hello.Op)
+	}
+	var helloData struct {
+		HeartbeatInterval int `json:"heartbeat_interval"`
+	}
+	json.Unmarshal(hello.Data, &helloData)
+	interval := time.Duration(helloData.HeartbeatInterval) * time.Millisecond
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	identify := map[string]interface{}{
+		"op": opIdentify,
+		"d": map[string]interface{}{
+			"properties": map[string]string{
+				"os":      "loadtest",
+				"browser": "hearth-loadtest",
+				"device":  "loadtest",
+			},
+		},
+	}
+	if err := conn.WriteJSON(identify); err != nil {
+		return 0, err
+	}
+
+	for {
+		var msg frame
+		if err := conn.ReadJSON(&msg); err != nil {
+			return 0, err
+		}
+		if msg.Op == opInvalid {
+			return 0, fmt.Errorf("invalid session")
+		}
+		if msg.Op == opDispatch && msg.Type == "READY" {
+			break
+		}
+	}
+
+	subscribe := map[string]interface{}{
+		"op": opDispatch,
+		"d": map[string]interface{}{
+			"t": "SUBSCRIBE",
+			"d": map[string]string{"channel_id": channelID},
+		},
+	}
+	return interval, conn.WriteJSON(subscribe)
+}
+
+// heartbeat is the only writer once the session is running
+func (s *gatewaySession) heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.closed:
+			return
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := s.conn.WriteJSON(frame{Op: opHeartbeat}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *gatewaySession) read(onMessage func(content string)) {
+	defer close(s.done)
+
+	for {
+		var msg frame
+		if err := s.conn.ReadJSON(&msg); err != nil {
+			select {
+			case <-s.closed:
+			default:
+				s.dropped.Store(true)
+			}
+			return
+		}
+		if msg.Op != opDispatch || msg.Type != "MESSAGE_CREATE" {
+			continue
+		}
+		var data struct {
+			Content string `json:"content"`
+		}
+		if json.Unmarshal(msg.Data, &data) == nil {
+			onMessage(data.Content)
+		}
+	}
+}
+
+// Close ends the session and waits for its reader to stop
+func (s *gatewaySession) Close() {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		s.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+			time.Now().Add(time.Second))
+		s.conn.Close()
+	})
+	<-s.done
+}
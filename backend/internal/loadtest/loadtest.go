@@ -0,0 +1,444 @@
+// Package loadtest drives simulated users against a running Hearth instance
+// over REST and the gateway, and reports latency percentiles per operation.
+// It backs the `hearth loadtest` subcommand.
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// deliveryGrace is how long a run waits after its last operation for
+// messages still on their way over the gateway
+const deliveryGrace = 2 * time.Second
+
+var reactionEmoji = []string{"👍", "🔥", "😂", "🎉", "👀"}
+
+// Config describes a load test. Rates are totals across all users.
+type Config struct {
+	BaseURL  string
+	Users    int
+	Duration time.Duration
+
+	MessagesPerSecond  float64
+	ReadsPerSecond     float64
+	ReactionsPerSecond float64
+	// ChurnPerSecond is how many gateway sessions are closed and reopened
+	// per second
+	ChurnPerSecond float64
+
+	// SetupConcurrency bounds how many users are created at once
+	SetupConcurrency int
+	// MaxInFlight bounds concurrent operations. Operations due while it is
+	// reached are skipped and counted, so an overloaded target shows up as
+	// skips rather than an ever-growing queue.
+	MaxInFlight int
+	// Timeout bounds each REST call and gateway handshake
+	Timeout time.Duration
+
+	// Prefix starts every generated username. Defaults to one unique to
+	// the run.
+	Prefix   string
+	Password string
+}
+
+// DefaultConfig returns a small mixed workload against a local instance
+func DefaultConfig() Config {
+	return Config{
+		BaseURL:            "http://localhost:8080",
+		Users:              50,
+		Duration:           time.Minute,
+		MessagesPerSecond:  20,
+		ReadsPerSecond:     10,
+		ReactionsPerSecond: 5,
+		ChurnPerSecond:     1,
+		SetupConcurrency:   10,
+		MaxInFlight:        200,
+		Timeout:            10 * time.Second,
+		Password:           "LoadTest123!",
+	}
+}
+
+// Validate checks the config is usable
+func (c Config) Validate() error {
+	switch {
+	case c.Users < 1:
+		return errors.New("users must be at least 1")
+	case c.Duration <= 0:
+		return errors.New("duration must be positive")
+	case c.MessagesPerSecond < 0, c.ReadsPerSecond < 0, c.ReactionsPerSecond < 0, c.ChurnPerSecond < 0:
+		return errors.New("rates can't be negative")
+	case c.SetupConcurrency < 1:
+		return errors.New("setup concurrency must be at least 1")
+	case c.MaxInFlight < 1:
+		return errors.New("max in flight must be at least 1")
+	case c.Timeout <= 0:
+		return errors.New("timeout must be positive")
+	}
+	_, err := gatewayURL(c.BaseURL)
+	return err
+}
+
+// Report is the result of a run
+type Report struct {
+	Users      int           `json:"users"`
+	Duration   time.Duration `json:"duration"`
+	Skipped    int64         `json:"skipped"`
+	Operations []Summary     `json:"operations"`
+}
+
+// ErrorRate is the highest error rate of any operation
+func (r *Report) ErrorRate() float64 {
+	worst := 0.0
+	for _, op := range r.Operations {
+		total := op.Count + op.Errors
+		if total == 0 {
+			continue
+		}
+		if rate := float64(op.Errors) / float64(total); rate > worst {
+			worst = rate
+		}
+	}
+	return worst
+}
+
+// Write prints the report as a table
+func (r *Report) Write(w io.Writer) {
+	fmt.Fprintf(w, "%d users for %s, %d operations skipped\n\n", r.Users, r.Duration.Round(time.Second), r.Skipped)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "operation\tcount\terrors\tp50\tp90\tp99\tmax\t")
+	for _, op := range r.Operations {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t\n", op.Op, op.Count, op.Errors,
+			round(op.P50), round(op.P90), round(op.P99), round(op.Max))
+	}
+	tw.Flush()
+}
+
+func round(d time.Duration) time.Duration {
+	if d > time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(10 * time.Microsecond)
+}
+
+// user is one simulated account with its own server and channel
+type user struct {
+	client    *client
+	name      string
+	channelID string
+
+	mu          sync.Mutex
+	session     *gatewaySession
+	pending     map[string]time.Time // content -> when it was sent
+	seq         int
+	lastMessage string
+}
+
+type runner struct {
+	cfg        Config
+	gatewayURL string
+	stats      *Recorder
+	users      []*user
+
+	inFlight chan struct{}
+	ops      sync.WaitGroup
+	skipped  atomic.Int64
+}
+
+// Run sets up cfg.Users users, drives the configured mix for cfg.Duration
+// and reports what it measured. Users that fail setup are left out; the run
+// fails only if none succeed.
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "lt" + strconv.FormatInt(time.Now().UnixNano()%1e10, 36)
+	}
+	wsURL, _ := gatewayURL(cfg.BaseURL)
+
+	r := &runner{
+		cfg:        cfg,
+		gatewayURL: wsURL,
+		stats:      NewRecorder(),
+		inFlight:   make(chan struct{}, cfg.MaxInFlight),
+	}
+
+	r.setup(ctx)
+	if len(r.users) == 0 {
+		return nil, fmt.Errorf("no users could be set up against %s", cfg.BaseURL)
+	}
+	log.Printf("[LoadTest] %d of %d users ready, running for %s", len(r.users), cfg.Users, cfg.Duration)
+
+	start := time.Now()
+	runCtx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	var schedulers sync.WaitGroup
+	for _, s := range []struct {
+		rate float64
+		op   func(context.Context, *user)
+	}{
+		{cfg.MessagesPerSecond, r.sendMessage},
+		{cfg.ReadsPerSecond, r.readMessages},
+		{cfg.ReactionsPerSecond, r.addReaction},
+		{cfg.ChurnPerSecond, r.churn},
+	} {
+		if s.rate <= 0 {
+			continue
+		}
+		schedulers.Add(1)
+		go func(rate float64, op func(context.Context, *user)) {
+			defer schedulers.Done()
+			r.every(runCtx, rate, op)
+		}(s.rate, s.op)
+	}
+	schedulers.Wait()
+	cancel()
+	r.ops.Wait()
+	elapsed := time.Since(start)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(deliveryGrace):
+	}
+	r.teardown()
+
+	return &Report{
+		Users:      len(r.users),
+		Duration:   elapsed,
+		Skipped:    r.skipped.Load(),
+		Operations: r.stats.Summaries(),
+	}, nil
+}
+
+func (r *runner) setup(ctx context.Context) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, r.cfg.SetupConcurrency)
+
+	for i := 0; i < r.cfg.Users; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+
+			u, err := r.newUser(ctx, fmt.Sprintf("%s_%d", r.cfg.Prefix, i))
+			if err != nil {
+				log.Printf("[LoadTest] setup of user %d failed: %v", i, err)
+				return
+			}
+			mu.Lock()
+			r.users = append(r.users, u)
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+}
+
+// newUser registers an account, gives it a channel and connects it to
+// the gateway
+func (r *runner) newUser(ctx context.Context, name string) (*user, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	u := &user{
+		client:  &client{http: &http.Client{}, baseURL: strings.TrimRight(r.cfg.BaseURL, "/")},
+		name:    name,
+		pending: make(map[string]time.Time),
+	}
+
+	start := time.Now()
+	err := u.client.register(ctx, name, r.cfg.Password)
+	if err == nil {
+		u.channelID, err = u.client.createChannel(ctx, name)
+	}
+	if err != nil {
+		r.stats.Fail(OpSetup)
+		return nil, err
+	}
+	r.stats.Observe(OpSetup, time.Since(start))
+
+	if err := r.connect(ctx, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// every runs op against a random user rate times a second until ctx ends
+func (r *runner) every(ctx context.Context, rate float64, op func(context.Context, *user)) {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		select {
+		case r.inFlight <- struct{}{}:
+		default:
+			r.skipped.Add(1)
+			continue
+		}
+		u := r.users[rand.Intn(len(r.users))]
+		r.ops.Add(1)
+		go func() {
+			defer func() { <-r.inFlight; r.ops.Done() }()
+			opCtx, cancel := context.WithTimeout(context.Background(), r.cfg.Timeout)
+			defer cancel()
+			op(opCtx, u)
+		}()
+	}
+}
+
+// connect opens a gateway session for u, timing it up to READY
+func (r *runner) connect(ctx context.Context, u *user) error {
+	start := time.Now()
+	session, err := dialGateway(ctx, r.gatewayURL, u.client.token, u.channelID, func(content string) {
+		r.delivered(u, content)
+	})
+	if err != nil {
+		r.stats.Fail(OpConnect)
+		return err
+	}
+	r.stats.Observe(OpConnect, time.Since(start))
+
+	u.mu.Lock()
+	old := u.session
+	u.session = session
+	u.mu.Unlock()
+	if old != nil {
+		r.closeSession(old)
+	}
+	return nil
+}
+
+// closeSession ends a session, counting it as an error if the server had
+// already dropped it
+func (r *runner) closeSession(s *gatewaySession) {
+	s.Close()
+	if s.dropped.Load() {
+		r.stats.Fail(OpSession)
+		return
+	}
+	r.stats.Observe(OpSession, time.Since(s.startedAt))
+}
+
+func (r *runner) delivered(u *user, content string) {
+	u.mu.Lock()
+	sent, ok := u.pending[content]
+	delete(u.pending, content)
+	u.mu.Unlock()
+
+	if ok {
+		r.stats.Observe(OpDelivery, time.Since(sent))
+	}
+}
+
+// sendMessage times the REST call, and delivery over the gateway if the
+// user has a live session
+func (r *runner) sendMessage(ctx context.Context, u *user) {
+	u.mu.Lock()
+	u.seq++
+	content := fmt.Sprintf("load test %s #%d", u.name, u.seq)
+	tracked := u.session != nil && !u.session.dropped.Load()
+	start := time.Now()
+	if tracked {
+		u.pending[content] = start
+	}
+	u.mu.Unlock()
+
+	id, err := u.client.sendMessage(ctx, u.channelID, content)
+	if err != nil {
+		r.stats.Fail(OpSendMessage)
+		u.mu.Lock()
+		delete(u.pending, content)
+		u.mu.Unlock()
+		return
+	}
+	r.stats.Observe(OpSendMessage, time.Since(start))
+
+	u.mu.Lock()
+	u.lastMessage = id
+	u.mu.Unlock()
+}
+
+func (r *runner) readMessages(ctx context.Context, u *user) {
+	start := time.Now()
+	if err := u.client.readMessages(ctx, u.channelID); err != nil {
+		r.stats.Fail(OpReadMessages)
+		return
+	}
+	r.stats.Observe(OpReadMessages, time.Since(start))
+}
+
+// addReaction reacts to the user's latest message. Users that haven't sent
+// one yet are skipped.
+func (r *runner) addReaction(ctx context.Context, u *user) {
+	u.mu.Lock()
+	messageID := u.lastMessage
+	u.mu.Unlock()
+	if messageID == "" {
+		return
+	}
+
+	start := time.Now()
+	emoji := reactionEmoji[rand.Intn(len(reactionEmoji))]
+	if err := u.client.addReaction(ctx, u.channelID, messageID, emoji); err != nil {
+		r.stats.Fail(OpAddReaction)
+		return
+	}
+	r.stats.Observe(OpAddReaction, time.Since(start))
+}
+
+// churn closes the user's gateway session and opens a new one. Messages
+// still awaiting delivery on the old session are forgotten rather than
+// counted as lost.
+func (r *runner) churn(ctx context.Context, u *user) {
+	u.mu.Lock()
+	old := u.session
+	u.session = nil
+	u.pending = make(map[string]time.Time)
+	u.mu.Unlock()
+
+	if old != nil {
+		r.closeSession(old)
+	}
+	r.connect(ctx, u)
+}
+
+// teardown closes every session. Messages never delivered count as
+// delivery errors.
+func (r *runner) teardown() {
+	for _, u := range r.users {
+		u.mu.Lock()
+		session := u.session
+		lost := len(u.pending)
+		u.session = nil
+		u.pending = nil
+		u.mu.Unlock()
+
+		for i := 0; i < lost; i++ {
+			r.stats.Fail(OpDelivery)
+		}
+		if session != nil {
+			r.closeSession(session)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeInstance implements just enough of the API and gateway for a run.
+// Every message sent to a channel is dispatched to gateway sessions
+// subscribed to it.
+type fakeInstance struct {
+	mu          sync.Mutex
+	subscribers map[string][]*websocket.Conn
+	writeMu     sync.Mutex
+}
+
+func (f *fakeInstance) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1")
+	created := func(v interface{}) {
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(v)
+	}
+
+	switch {
+	case r.URL.Path == "/gateway":
+		f.gateway(w, r)
+	case path == "/auth/register":
+		created(map[string]string{"access_token": uuid.NewString()})
+	case path == "/servers" || strings.HasSuffix(path, "/channels"):
+		created(map[string]string{"id": uuid.NewString()})
+	case strings.HasSuffix(path, "/messages") && r.Method == http.MethodPost:
+		var body struct {
+			Content string `json:"content"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		channelID := strings.Split(path, "/")[2]
+		f.dispatch(channelID, body.Content)
+		created(map[string]string{"id": uuid.NewString()})
+	case strings.HasSuffix(path, "/messages"):
+		w.Write([]byte("[]"))
+	case strings.HasSuffix(path, "/@me"):
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeInstance) gateway(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	f.write(conn, map[string]interface{}{"op": opHello, "d": map[string]int{"heartbeat_interval": 1000}})
+	for {
+		var msg struct {
+			Op   int `json:"op"`
+			Data struct {
+				T string `json:"t"`
+				D struct {
+					ChannelID string `json:"channel_id"`
+				} `json:"d"`
+			} `json:"d"`
+		}
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		switch msg.Op {
+		case opIdentify:
+			f.write(conn, map[string]interface{}{"op": opDispatch, "t": "READY", "d": map[string]string{}})
+		case opDispatch:
+			f.mu.Lock()
+			f.subscribers[msg.Data.D.ChannelID] = append(f.subscribers[msg.Data.D.ChannelID], conn)
+			f.mu.Unlock()
+		}
+	}
+}
+
+func (f *fakeInstance) dispatch(channelID, content string) {
+	f.mu.Lock()
+	conns := append([]*websocket.Conn(nil), f.subscribers[channelID]...)
+	f.mu.Unlock()
+
+	for _, conn := range conns {
+		f.write(conn, map[string]interface{}{
+			"op": opDispatch,
+			"t":  "MESSAGE_CREATE",
+			"d":  map[string]string{"content": content},
+		})
+	}
+}
+
+func (f *fakeInstance) write(conn *websocket.Conn, v interface{}) {
+	f.writeMu.Lock()
+	conn.WriteJSON(v)
+	f.writeMu.Unlock()
+}
+
+func TestRun(t *testing.T) {
+	server := httptest.NewServer(&fakeInstance{subscribers: make(map[string][]*websocket.Conn)})
+	defer server.Close()
+
+	cfg := DefaultConfig()
+	cfg.BaseURL = server.URL
+	cfg.Users = 3
+	cfg.Duration = 500 * time.Millisecond
+	cfg.MessagesPerSecond = 40
+	cfg.ReadsPerSecond = 20
+	cfg.ReactionsPerSecond = 20
+	cfg.ChurnPerSecond = 0
+
+	report, err := Run(context.Background(), cfg)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, report.Users)
+	assert.Zero(t, report.ErrorRate())
+
+	counts := make(map[string]int)
+	for _, op := range report.Operations {
+		counts[op.Op] = op.Count
+	}
+	assert.Equal(t, 3, counts[OpSetup])
+	assert.Equal(t, 3, counts[OpConnect])
+	assert.Equal(t, 3, counts[OpSession])
+	assert.Positive(t, counts[OpSendMessage])
+	assert.Equal(t, counts[OpSendMessage], counts[OpDelivery])
+	assert.Positive(t, counts[OpReadMessages])
+}
+
+func TestRun_NoUsers(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	cfg := DefaultConfig()
+	cfg.BaseURL = server.URL
+	cfg.Users = 2
+
+	_, err := Run(context.Background(), cfg)
+	assert.Error(t, err)
+}
+
+func TestGatewayURL(t *testing.T) {
+	u, err := gatewayURL("https://chat.example.com/")
+	require.NoError(t, err)
+	assert.Equal(t, "wss://chat.example.com/gateway", u)
+
+	u, err = gatewayURL("http://localhost:8080")
+	require.NoError(t, err)
+	assert.Equal(t, "ws://localhost:8080/gateway", u)
+
+	_, err = gatewayURL("ftp://example.com")
+	assert.Error(t, err)
+}
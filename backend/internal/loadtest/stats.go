@@ -0,0 +1,97 @@
+package loadtest
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Operations timed by a run
+const (
+	OpSetup        = "setup"
+	OpConnect      = "gateway_connect"
+	OpSession      = "gateway_session"
+	OpSendMessage  = "send_message"
+	OpDelivery     = "message_delivery"
+	OpReadMessages = "read_messages"
+	OpAddReaction  = "add_reaction"
+)
+
+// Summary is the latency distribution of one operation
+type Summary struct {
+	Op     string        `json:"op"`
+	Count  int           `json:"count"`
+	Errors int           `json:"errors"`
+	P50    time.Duration `json:"p50"`
+	P90    time.Duration `json:"p90"`
+	P99    time.Duration `json:"p99"`
+	Max    time.Duration `json:"max"`
+}
+
+// Recorder collects latencies per operation. It is safe for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	samples map[string][]time.Duration
+	errors  map[string]int
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{
+		samples: make(map[string][]time.Duration),
+		errors:  make(map[string]int),
+	}
+}
+
+// Observe records a successful operation
+func (r *Recorder) Observe(op string, d time.Duration) {
+	r.mu.Lock()
+	r.samples[op] = append(r.samples[op], d)
+	r.mu.Unlock()
+}
+
+// Fail records a failed operation
+func (r *Recorder) Fail(op string) {
+	r.mu.Lock()
+	r.errors[op]++
+	r.mu.Unlock()
+}
+
+// Summaries returns every recorded operation, sorted by name
+func (r *Recorder) Summaries() []Summary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ops := make(map[string]bool)
+	for op := range r.samples {
+		ops[op] = true
+	}
+	for op := range r.errors {
+		ops[op] = true
+	}
+
+	summaries := make([]Summary, 0, len(ops))
+	for op := range ops {
+		samples := append([]time.Duration(nil), r.samples[op]...)
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+		s := Summary{Op: op, Count: len(samples), Errors: r.errors[op]}
+		if len(samples) > 0 {
+			s.P50 = percentile(samples, 50)
+			s.P90 = percentile(samples, 90)
+			s.P99 = percentile(samples, 99)
+			s.Max = samples[len(samples)-1]
+		}
+		summaries = append(summaries, s)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Op < summaries[j].Op })
+	return summaries
+}
+
+// percentile returns the nearest-rank percentile of sorted samples
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,39 @@
+package loadtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder_Summaries(t *testing.T) {
+	r := NewRecorder()
+	for i := 1; i <= 100; i++ {
+		r.Observe(OpSendMessage, time.Duration(i)*time.Millisecond)
+	}
+	r.Fail(OpSendMessage)
+	r.Fail(OpConnect)
+
+	summaries := r.Summaries()
+	require.Len(t, summaries, 2)
+
+	assert.Equal(t, Summary{Op: OpConnect, Errors: 1}, summaries[0])
+
+	send := summaries[1]
+	assert.Equal(t, OpSendMessage, send.Op)
+	assert.Equal(t, 100, send.Count)
+	assert.Equal(t, 1, send.Errors)
+	assert.Equal(t, 50*time.Millisecond, send.P50)
+	assert.Equal(t, 90*time.Millisecond, send.P90)
+	assert.Equal(t, 99*time.Millisecond, send.P99)
+	assert.Equal(t, 100*time.Millisecond, send.Max)
+}
+
+func TestPercentile_FewSamples(t *testing.T) {
+	samples := []time.Duration{time.Millisecond, 2 * time.Millisecond}
+
+	assert.Equal(t, time.Millisecond, percentile(samples, 50))
+	assert.Equal(t, 2*time.Millisecond, percentile(samples, 99))
+}
@@ -0,0 +1,186 @@
+// Package mail sends the instance's emails, such as password reset links
+// and new login alerts, through an SMTP server.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// implicitTLSPort is the SMTP submission port that speaks TLS from the
+// start rather than upgrading with STARTTLS
+const implicitTLSPort = 465
+
+const defaultTimeout = 30 * time.Second
+
+// Config configures the SMTP server mail is sent through
+type Config struct {
+	Host string
+	// Port 465 uses TLS from the start; other ports upgrade with STARTTLS
+	// when the server offers it
+	Port     int
+	Username string
+	Password string
+	// From is the sender, e.g. "Hearth <noreply@example.com>"
+	From string
+	// Timeout bounds sending one message (default 30s)
+	Timeout time.Duration
+}
+
+// SMTP sends plain text mail through an SMTP server
+type SMTP struct {
+	host    string
+	port    int
+	auth    smtp.Auth
+	from    *netmail.Address
+	timeout time.Duration
+	now     func() time.Time
+}
+
+// New creates a mailer for the configured server
+func New(cfg Config) (*SMTP, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("smtp host is required")
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	from, err := netmail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", cfg.From, err)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	s := &SMTP{
+		host:    cfg.Host,
+		port:    cfg.Port,
+		from:    from,
+		timeout: cfg.Timeout,
+		now:     time.Now,
+	}
+	// PlainAuth refuses to send the password without TLS, except to
+	// localhost
+	if cfg.Username != "" {
+		s.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return s, nil
+}
+
+// Send emails body to the address to
+func (s *SMTP) Send(ctx context.Context, to, subject, body string) error {
+	rcpt, err := netmail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid recipient: %w", err)
+	}
+	msg, err := s.message(rcpt, subject, body)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	if err := s.send(ctx, rcpt.Address, msg); err != nil {
+		return fmt.Errorf("smtp %s: %w", s.host, err)
+	}
+	return nil
+}
+
+func (s *SMTP) send(ctx context.Context, to string, msg []byte) error {
+	addr := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if s.port == implicitTLSPort {
+		conn = tls.Client(conn, &tls.Config{ServerName: s.host})
+	}
+
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if s.port != implicitTLSPort {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+				return err
+			}
+		}
+	}
+	if s.auth != nil {
+		if err := c.Auth(s.auth); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(s.from.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// message formats a plain text email. The subject is encoded so any
+// characters are safe in a header, and the body is quoted-printable.
+func (s *SMTP) message(to *netmail.Address, subject, body string) ([]byte, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	domain := s.from.Address[strings.LastIndex(s.from.Address, "@")+1:]
+
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		buf.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", s.from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", s.now().Format(time.RFC1123Z))
+	header("Message-ID", "<"+hex.EncodeToString(id)+"@"+domain+">")
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	w := quotedprintable.NewWriter(&buf)
+	if _, err := w.Write([]byte(body)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,132 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	netmail "net/mail"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer accepts one message and hands back its envelope and data
+func fakeSMTPServer(t *testing.T) (port int, received chan []string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	received = make(chan []string, 1)
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+		var lines []string
+		reply("220 fake ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				reply("250 fake")
+			case strings.HasPrefix(line, "MAIL"), strings.HasPrefix(line, "RCPT"):
+				lines = append(lines, line)
+				reply("250 ok")
+			case line == "DATA":
+				reply("354 go ahead")
+				for {
+					data, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if data == ".\r\n" {
+						break
+					}
+					lines = append(lines, strings.TrimRight(data, "\r\n"))
+				}
+				reply("250 queued")
+			case line == "QUIT":
+				reply("221 bye")
+				received <- lines
+				return
+			default:
+				reply("502 unknown")
+			}
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, received
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{From: "noreply@example.com"})
+	assert.Error(t, err, "a host is required")
+	_, err = New(Config{Host: "smtp.example.com", From: "not an address"})
+	assert.Error(t, err)
+
+	m, err := New(Config{Host: "smtp.example.com", From: "Hearth <noreply@example.com>"})
+	require.NoError(t, err)
+	assert.Equal(t, 587, m.port)
+	assert.Nil(t, m.auth)
+}
+
+func TestSMTP_Send(t *testing.T) {
+	port, received := fakeSMTPServer(t)
+	m, err := New(Config{Host: "127.0.0.1", Port: port, From: "Hearth <noreply@example.com>", Timeout: 5 * time.Second})
+	require.NoError(t, err)
+
+	require.NoError(t, m.Send(context.Background(), "Ada <ada@example.com>", "New login to your account", "Hi ada,\n\nSomeone logged in.\n"))
+	lines := <-received
+	require.GreaterOrEqual(t, len(lines), 2)
+	assert.Equal(t, "MAIL FROM:<noreply@example.com>", strings.SplitN(lines[0], " BODY", 2)[0])
+	assert.Equal(t, "RCPT TO:<ada@example.com>", lines[1])
+	assert.Contains(t, lines, "Subject: New login to your account")
+	assert.Contains(t, lines, `To: "Ada" <ada@example.com>`)
+}
+
+func TestSMTP_SendRejectsBadRecipients(t *testing.T) {
+	m, err := New(Config{Host: "127.0.0.1", Port: 1, From: "noreply@example.com"})
+	require.NoError(t, err)
+	assert.Error(t, m.Send(context.Background(), "ada@example.com\r\nBcc: eve@example.com", "hi", "body"))
+	assert.Error(t, m.Send(context.Background(), "", "hi", "body"))
+}
+
+func TestSMTP_Message(t *testing.T) {
+	m, err := New(Config{Host: "smtp.example.com", From: "Hearth <noreply@example.com>"})
+	require.NoError(t, err)
+	m.now = func() time.Time { return time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC) }
+
+	raw, err := m.message(&netmail.Address{Address: "ada@example.com"}, "Réinitialiser", "Lien : https://example.com/reset?token="+strings.Repeat("a", 80)+"\n")
+	require.NoError(t, err)
+	msg, err := netmail.ReadMessage(strings.NewReader(string(raw)))
+	require.NoError(t, err)
+
+	assert.Equal(t, "<ada@example.com>", msg.Header.Get("To"))
+	assert.Equal(t, "Mon, 15 Jan 2024 10:30:00 +0000", msg.Header.Get("Date"))
+	assert.True(t, strings.HasSuffix(msg.Header.Get("Message-ID"), "@example.com>"))
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Réinitialiser", subject)
+
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	require.NoError(t, err)
+	assert.Equal(t, "Lien : https://example.com/reset?token="+strings.Repeat("a", 80)+"\r\n", string(body),
+		"long lines are wrapped and unwrapped again")
+	for _, line := range strings.Split(string(raw), "\r\n") {
+		assert.LessOrEqual(t, len(line), 78, strconv.Quote(line))
+	}
+}
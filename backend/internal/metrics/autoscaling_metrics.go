@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics meant for HPA/KEDA scaling decisions. They're kept small and
+// per-instance so an autoscaler can average them across pods without
+// aggregating away the signal.
+const (
+	gatewaySubsystem  = "gateway"
+	messageSubsystem  = "messages"
+	dispatchStageHub  = "hub"
+	dispatchStageSend = "clients"
+)
+
+// GatewayLoad is what an instance's WebSocket hub reports about its load.
+// The websocket Hub and DistributedHub implement it.
+type GatewayLoad interface {
+	GetClientCount() int
+	DispatchQueueDepth() (hub, clients int)
+}
+
+// GatewayLoadCollector exports connections and dispatch backlog for
+// autoscaling. Like DBPoolCollector it reads the hub on each scrape, so the
+// values can't drift from what the hub actually holds.
+type GatewayLoadCollector struct {
+	instance string
+	load     GatewayLoad
+
+	connections   *prometheus.Desc
+	dispatchQueue *prometheus.Desc
+}
+
+// NewGatewayLoadCollector creates a collector for the hub. Register the
+// result with prometheus.MustRegister.
+func NewGatewayLoadCollector(load GatewayLoad) *GatewayLoadCollector {
+	return &GatewayLoadCollector{
+		instance: GetInstanceLabel(),
+		load:     load,
+
+		connections: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, gatewaySubsystem, "connections"),
+			"WebSocket connections held by this instance",
+			[]string{"instance"}, nil,
+		),
+		dispatchQueue: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, gatewaySubsystem, "dispatch_queue_depth"),
+			"Events waiting to be dispatched, by stage (hub: waiting for routing, clients: buffered for sending)",
+			[]string{"instance", "stage"}, nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *GatewayLoadCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.connections
+	ch <- c.dispatchQueue
+}
+
+// Collect implements prometheus.Collector
+func (c *GatewayLoadCollector) Collect(ch chan<- prometheus.Metric) {
+	hub, clients := c.load.DispatchQueueDepth()
+
+	ch <- prometheus.MustNewConstMetric(c.connections, prometheus.GaugeValue, float64(c.load.GetClientCount()), c.instance)
+	ch <- prometheus.MustNewConstMetric(c.dispatchQueue, prometheus.GaugeValue, float64(hub), c.instance, dispatchStageHub)
+	ch <- prometheus.MustNewConstMetric(c.dispatchQueue, prometheus.GaugeValue, float64(clients), c.instance, dispatchStageSend)
+}
+
+// MessageSendMetrics times message sends, from the request reaching the
+// message service to the message being stored and published
+type MessageSendMetrics struct {
+	// SendDuration is how long successful sends took
+	SendDuration *prometheus.HistogramVec
+
+	instance string
+}
+
+// NewMessageSendMetrics creates and registers message send metrics
+func NewMessageSendMetrics() *MessageSendMetrics {
+	return newMessageSendMetrics(prometheus.DefaultRegisterer)
+}
+
+func newMessageSendMetrics(registerer prometheus.Registerer) *MessageSendMetrics {
+	factory := promauto.With(registerer)
+
+	return &MessageSendMetrics{
+		instance: GetInstanceLabel(),
+
+		SendDuration: factory.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Subsystem: messageSubsystem,
+				Name:      "send_duration_seconds",
+				Help:      "Time taken to send a message, including storing and publishing it",
+				Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+			},
+			[]string{"instance"},
+		),
+	}
+}
+
+// MessageSent records how long a successful send took
+func (m *MessageSendMetrics) MessageSent(duration time.Duration) {
+	m.SendDuration.WithLabelValues(m.instance).Observe(duration.Seconds())
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeGatewayLoad struct {
+	connections, hub, clients int
+}
+
+func (f *fakeGatewayLoad) GetClientCount() int { return f.connections }
+
+func (f *fakeGatewayLoad) DispatchQueueDepth() (int, int) { return f.hub, f.clients }
+
+func TestGatewayLoadCollector(t *testing.T) {
+	load := &fakeGatewayLoad{connections: 1200, hub: 4, clients: 37}
+	collector := NewGatewayLoadCollector(load)
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	expected := `
+# HELP hearth_gateway_connections WebSocket connections held by this instance
+# TYPE hearth_gateway_connections gauge
+hearth_gateway_connections{instance="` + collector.instance + `"} 1200
+# HELP hearth_gateway_dispatch_queue_depth Events waiting to be dispatched, by stage (hub: waiting for routing, clients: buffered for sending)
+# TYPE hearth_gateway_dispatch_queue_depth gauge
+hearth_gateway_dispatch_queue_depth{instance="` + collector.instance + `",stage="clients"} 37
+hearth_gateway_dispatch_queue_depth{instance="` + collector.instance + `",stage="hub"} 4
+`
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+
+	// Values are read at scrape time
+	load.connections = 800
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(
+		strings.Replace(expected, "} 1200", "} 800", 1))))
+}
+
+func TestMessageSendMetrics(t *testing.T) {
+	m := newMessageSendMetrics(prometheus.NewRegistry())
+
+	m.MessageSent(20 * time.Millisecond)
+	m.MessageSent(2 * time.Second)
+
+	assert.Equal(t, 1, testutil.CollectAndCount(m.SendDuration))
+	expected := `
+# HELP hearth_messages_send_duration_seconds Time taken to send a message, including storing and publishing it
+# TYPE hearth_messages_send_duration_seconds histogram
+hearth_messages_send_duration_seconds_bucket{instance="` + m.instance + `",le="0.005"} 0
+hearth_messages_send_duration_seconds_bucket{instance="` + m.instance + `",le="0.01"} 0
+hearth_messages_send_duration_seconds_bucket{instance="` + m.instance + `",le="0.025"} 1
+hearth_messages_send_duration_seconds_bucket{instance="` + m.instance + `",le="0.05"} 1
+hearth_messages_send_duration_seconds_bucket{instance="` + m.instance + `",le="0.1"} 1
+hearth_messages_send_duration_seconds_bucket{instance="` + m.instance + `",le="0.25"} 1
+hearth_messages_send_duration_seconds_bucket{instance="` + m.instance + `",le="0.5"} 1
+hearth_messages_send_duration_seconds_bucket{instance="` + m.instance + `",le="1"} 1
+hearth_messages_send_duration_seconds_bucket{instance="` + m.instance + `",le="2.5"} 2
+hearth_messages_send_duration_seconds_bucket{instance="` + m.instance + `",le="5"} 2
+hearth_messages_send_duration_seconds_bucket{instance="` + m.instance + `",le="+Inf"} 2
+hearth_messages_send_duration_seconds_sum{instance="` + m.instance + `"} 2.02
+hearth_messages_send_duration_seconds_count{instance="` + m.instance + `"} 2
+`
+	assert.NoError(t, testutil.CollectAndCompare(m.SendDuration, strings.NewReader(expected)))
+}
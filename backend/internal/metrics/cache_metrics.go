@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const cacheSubsystem = "cache"
+
+// CacheMetrics holds read-through object cache metrics
+type CacheMetrics struct {
+	// HitsTotal counts lookups answered from the cache, by object: user,
+	// server, channel, member or roles
+	HitsTotal *prometheus.CounterVec
+
+	// MissesTotal counts lookups that fell through to the database, by
+	// object
+	MissesTotal *prometheus.CounterVec
+
+	// LocalHitsTotal and LocalMissesTotal count lookups in this node's
+	// in-process tier, which sits in front of Redis for read-mostly objects
+	LocalHitsTotal   *prometheus.CounterVec
+	LocalMissesTotal *prometheus.CounterVec
+
+	instance string
+}
+
+// NewCacheMetrics creates and registers cache metrics
+func NewCacheMetrics() *CacheMetrics {
+	return newCacheMetrics(prometheus.DefaultRegisterer)
+}
+
+func newCacheMetrics(registerer prometheus.Registerer) *CacheMetrics {
+	factory := promauto.With(registerer)
+
+	return &CacheMetrics{
+		instance: GetInstanceLabel(),
+
+		HitsTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: cacheSubsystem,
+				Name:      "hits_total",
+				Help:      "Total number of object lookups answered from the cache",
+			},
+			[]string{"instance", "object"},
+		),
+
+		MissesTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: cacheSubsystem,
+				Name:      "misses_total",
+				Help:      "Total number of object lookups not found in the cache",
+			},
+			[]string{"instance", "object"},
+		),
+
+		LocalHitsTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: cacheSubsystem,
+				Name:      "local_hits_total",
+				Help:      "Total number of object lookups answered without going to Redis",
+			},
+			[]string{"instance", "object"},
+		),
+
+		LocalMissesTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: cacheSubsystem,
+				Name:      "local_misses_total",
+				Help:      "Total number of object lookups not found in the in-process cache",
+			},
+			[]string{"instance", "object"},
+		),
+	}
+}
+
+// CacheLookup records a cache lookup
+func (m *CacheMetrics) CacheLookup(object string, hit bool) {
+	if hit {
+		m.HitsTotal.WithLabelValues(m.instance, object).Inc()
+		return
+	}
+	m.MissesTotal.WithLabelValues(m.instance, object).Inc()
+}
+
+// LocalCacheLookup records a lookup in the in-process tier
+func (m *CacheMetrics) LocalCacheLookup(object string, hit bool) {
+	if hit {
+		m.LocalHitsTotal.WithLabelValues(m.instance, object).Inc()
+		return
+	}
+	m.LocalMissesTotal.WithLabelValues(m.instance, object).Inc()
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheMetrics(t *testing.T) {
+	m := newCacheMetrics(prometheus.NewRegistry())
+
+	m.CacheLookup("server", true)
+	m.CacheLookup("server", true)
+	m.CacheLookup("server", false)
+	m.CacheLookup("member", false)
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.HitsTotal.WithLabelValues(m.instance, "server")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.MissesTotal.WithLabelValues(m.instance, "server")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.HitsTotal.WithLabelValues(m.instance, "member")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.MissesTotal.WithLabelValues(m.instance, "member")))
+}
+
+func TestCacheMetrics_Local(t *testing.T) {
+	m := newCacheMetrics(prometheus.NewRegistry())
+
+	m.LocalCacheLookup("channel", true)
+	m.LocalCacheLookup("channel", false)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.LocalHitsTotal.WithLabelValues(m.instance, "channel")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.LocalMissesTotal.WithLabelValues(m.instance, "channel")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.HitsTotal.WithLabelValues(m.instance, "channel")))
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const databaseSubsystem = "database"
+
+// DatabaseMetrics holds repository query metrics
+type DatabaseMetrics struct {
+	// QueryDuration tracks query latency by repository method and, with
+	// detailed labels, calling service and API route
+	QueryDuration *prometheus.HistogramVec
+
+	// QueryErrorsTotal tracks failed queries
+	QueryErrorsTotal *prometheus.CounterVec
+
+	// SlowQueriesTotal tracks queries over the slow query threshold
+	SlowQueriesTotal *prometheus.CounterVec
+
+	instance string
+	detailed bool
+}
+
+// NewDatabaseMetrics creates and registers database metrics. detailed adds
+// service and route labels; every route times every service it reaches
+// makes for many series, so it's meant for load tests and debugging.
+func NewDatabaseMetrics(detailed bool) *DatabaseMetrics {
+	return newDatabaseMetrics(prometheus.DefaultRegisterer, detailed)
+}
+
+func newDatabaseMetrics(registerer prometheus.Registerer, detailed bool) *DatabaseMetrics {
+	factory := promauto.With(registerer)
+	labels := []string{"instance", "repository"}
+	if detailed {
+		labels = append(labels, "service", "route")
+	}
+
+	return &DatabaseMetrics{
+		instance: GetInstanceLabel(),
+		detailed: detailed,
+
+		QueryDuration: factory.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Subsystem: databaseSubsystem,
+				Name:      "query_duration_seconds",
+				Help:      "Repository query latency in seconds",
+				Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+			},
+			labels,
+		),
+
+		QueryErrorsTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: databaseSubsystem,
+				Name:      "query_errors_total",
+				Help:      "Total number of repository queries that returned an error",
+			},
+			labels,
+		),
+
+		SlowQueriesTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: databaseSubsystem,
+				Name:      "slow_queries_total",
+				Help:      "Total number of repository queries slower than the slow query threshold",
+			},
+			labels,
+		),
+	}
+}
+
+// QueryCompleted records a query's latency and whether it failed
+func (m *DatabaseMetrics) QueryCompleted(repository, service, route string, seconds float64, failed bool) {
+	labels := m.labelValues(repository, service, route)
+	m.QueryDuration.WithLabelValues(labels...).Observe(seconds)
+	if failed {
+		m.QueryErrorsTotal.WithLabelValues(labels...).Inc()
+	}
+}
+
+// SlowQuery records a query over the slow query threshold
+func (m *DatabaseMetrics) SlowQuery(repository, service, route string) {
+	m.SlowQueriesTotal.WithLabelValues(m.labelValues(repository, service, route)...).Inc()
+}
+
+func (m *DatabaseMetrics) labelValues(repository, service, route string) []string {
+	if m.detailed {
+		return []string{m.instance, repository, service, route}
+	}
+	return []string{m.instance, repository}
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatabaseMetrics(t *testing.T) {
+	m := newDatabaseMetrics(prometheus.NewRegistry(), true)
+	labels := []string{m.instance, "MessageRepository.GetByID", "MessageService.GetMessage", "GET /api/v1/channels/:id/messages/:messageId"}
+
+	m.QueryCompleted(labels[1], labels[2], labels[3], 0.002, false)
+	m.QueryCompleted(labels[1], labels[2], labels[3], 0.5, true)
+	m.SlowQuery(labels[1], labels[2], labels[3])
+
+	assert.Equal(t, 1, testutil.CollectAndCount(m.QueryDuration))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.QueryErrorsTotal.WithLabelValues(labels...)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.SlowQueriesTotal.WithLabelValues(labels...)))
+}
+
+func TestDatabaseMetrics_WithoutDetailedLabels(t *testing.T) {
+	m := newDatabaseMetrics(prometheus.NewRegistry(), false)
+
+	m.QueryCompleted("MessageRepository.GetByID", "MessageService.GetMessage", "GET /api/v1/channels/:id/messages/:messageId", 0.002, true)
+	m.QueryCompleted("MessageRepository.GetByID", "SearchService.Search", "GET /api/v1/search", 0.002, true)
+	m.SlowQuery("MessageRepository.GetByID", "MessageService.GetMessage", "GET /api/v1/channels/:id/messages/:messageId")
+
+	assert.Equal(t, 1, testutil.CollectAndCount(m.QueryDuration), "service and route don't split series")
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.QueryErrorsTotal.WithLabelValues(m.instance, "MessageRepository.GetByID")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.SlowQueriesTotal.WithLabelValues(m.instance, "MessageRepository.GetByID")))
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const databasePoolSubsystem = "database_pool"
+
+// DBPoolCollector exports connection pool statistics. Stats are read on
+// each scrape, so nothing needs updating on the query path.
+type DBPoolCollector struct {
+	pool     string
+	instance string
+	stats    func() sql.DBStats
+
+	maxOpen           *prometheus.Desc
+	open              *prometheus.Desc
+	inUse             *prometheus.Desc
+	idle              *prometheus.Desc
+	saturation        *prometheus.Desc
+	waitCount         *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	maxIdleClosed     *prometheus.Desc
+	maxIdleTimeClosed *prometheus.Desc
+	maxLifetimeClosed *prometheus.Desc
+}
+
+// NewDBPoolCollector creates a collector for the named pool. Pass db.Stats
+// and register the result with prometheus.MustRegister.
+func NewDBPoolCollector(pool string, stats func() sql.DBStats) *DBPoolCollector {
+	labels := []string{"instance", "pool"}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, databasePoolSubsystem, name), help, labels, nil)
+	}
+
+	return &DBPoolCollector{
+		pool:     pool,
+		instance: GetInstanceLabel(),
+		stats:    stats,
+
+		maxOpen:           desc("max_open_connections", "Maximum number of open connections (0 = unlimited)"),
+		open:              desc("open_connections", "Number of established connections, in use and idle"),
+		inUse:             desc("in_use_connections", "Number of connections currently in use"),
+		idle:              desc("idle_connections", "Number of idle connections"),
+		saturation:        desc("saturation_ratio", "Connections in use as a fraction of the maximum (0 when unlimited)"),
+		waitCount:         desc("wait_count_total", "Total number of times a query waited for a free connection"),
+		waitDuration:      desc("wait_duration_seconds_total", "Total time spent waiting for a free connection"),
+		maxIdleClosed:     desc("max_idle_closed_total", "Total connections closed because the idle pool was full"),
+		maxIdleTimeClosed: desc("max_idle_time_closed_total", "Total connections closed for exceeding the max idle time"),
+		maxLifetimeClosed: desc("max_lifetime_closed_total", "Total connections closed for exceeding the max lifetime"),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *DBPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.saturation
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.maxIdleTimeClosed
+	ch <- c.maxLifetimeClosed
+}
+
+// Collect implements prometheus.Collector
+func (c *DBPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats()
+
+	var saturation float64
+	if stats.MaxOpenConnections > 0 {
+		saturation = float64(stats.InUse) / float64(stats.MaxOpenConnections)
+	}
+
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, c.instance, c.pool)
+	}
+	counter := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value, c.instance, c.pool)
+	}
+
+	gauge(c.maxOpen, float64(stats.MaxOpenConnections))
+	gauge(c.open, float64(stats.OpenConnections))
+	gauge(c.inUse, float64(stats.InUse))
+	gauge(c.idle, float64(stats.Idle))
+	gauge(c.saturation, saturation)
+	counter(c.waitCount, float64(stats.WaitCount))
+	counter(c.waitDuration, stats.WaitDuration.Seconds())
+	counter(c.maxIdleClosed, float64(stats.MaxIdleClosed))
+	counter(c.maxIdleTimeClosed, float64(stats.MaxIdleTimeClosed))
+	counter(c.maxLifetimeClosed, float64(stats.MaxLifetimeClosed))
+}
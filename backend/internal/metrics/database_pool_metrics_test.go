@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDBPoolCollector(t *testing.T) {
+	stats := sql.DBStats{
+		MaxOpenConnections: 20,
+		OpenConnections:    12,
+		InUse:              10,
+		Idle:               2,
+		WaitCount:          7,
+		WaitDuration:       1500 * time.Millisecond,
+	}
+	collector := NewDBPoolCollector("primary", func() sql.DBStats { return stats })
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	assert.Equal(t, 10, testutil.CollectAndCount(collector))
+
+	expected := `
+# HELP hearth_database_pool_saturation_ratio Connections in use as a fraction of the maximum (0 when unlimited)
+# TYPE hearth_database_pool_saturation_ratio gauge
+hearth_database_pool_saturation_ratio{instance="` + collector.instance + `",pool="primary"} 0.5
+# HELP hearth_database_pool_wait_duration_seconds_total Total time spent waiting for a free connection
+# TYPE hearth_database_pool_wait_duration_seconds_total counter
+hearth_database_pool_wait_duration_seconds_total{instance="` + collector.instance + `",pool="primary"} 1.5
+`
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"hearth_database_pool_saturation_ratio", "hearth_database_pool_wait_duration_seconds_total"))
+}
+
+func TestDBPoolCollector_Unlimited(t *testing.T) {
+	collector := NewDBPoolCollector("primary", func() sql.DBStats {
+		return sql.DBStats{InUse: 40}
+	})
+
+	expected := `
+# HELP hearth_database_pool_saturation_ratio Connections in use as a fraction of the maximum (0 when unlimited)
+# TYPE hearth_database_pool_saturation_ratio gauge
+hearth_database_pool_saturation_ratio{instance="` + collector.instance + `",pool="primary"} 0
+`
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"hearth_database_pool_saturation_ratio"))
+}
@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const integritySubsystem = "integrity"
+
+// IntegrityMetrics holds database integrity check metrics
+type IntegrityMetrics struct {
+	// Issues is how many problems the last run left unrepaired, by check
+	Issues *prometheus.GaugeVec
+
+	// RepairsTotal counts problems repaired, by check
+	RepairsTotal *prometheus.CounterVec
+
+	// RunsTotal counts integrity runs by result
+	RunsTotal *prometheus.CounterVec
+
+	// LastSuccess is when a run last completed
+	LastSuccess *prometheus.GaugeVec
+
+	instance string
+}
+
+// NewIntegrityMetrics creates and registers integrity metrics
+func NewIntegrityMetrics() *IntegrityMetrics {
+	return newIntegrityMetrics(prometheus.DefaultRegisterer)
+}
+
+func newIntegrityMetrics(registerer prometheus.Registerer) *IntegrityMetrics {
+	factory := promauto.With(registerer)
+
+	return &IntegrityMetrics{
+		instance: GetInstanceLabel(),
+
+		Issues: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: integritySubsystem,
+				Name:      "issues",
+				Help:      "Problems left unrepaired by the last integrity run",
+			},
+			[]string{"instance", "check"},
+		),
+
+		RepairsTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: integritySubsystem,
+				Name:      "repairs_total",
+				Help:      "Total number of integrity problems repaired",
+			},
+			[]string{"instance", "check"},
+		),
+
+		RunsTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: integritySubsystem,
+				Name:      "runs_total",
+				Help:      "Total number of integrity runs",
+			},
+			[]string{"instance", "result"},
+		),
+
+		LastSuccess: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: integritySubsystem,
+				Name:      "last_success_timestamp_seconds",
+				Help:      "Unix time the last integrity run completed",
+			},
+			[]string{"instance"},
+		),
+	}
+}
+
+// CheckCompleted records one check's findings. Found is what was wrong
+// before any repair, so a detect-only run reports everything it found.
+func (m *IntegrityMetrics) CheckCompleted(check string, found, repaired int) {
+	m.Issues.WithLabelValues(m.instance, check).Set(float64(found - repaired))
+	if repaired > 0 {
+		m.RepairsTotal.WithLabelValues(m.instance, check).Add(float64(repaired))
+	}
+}
+
+// RunCompleted records the end of an integrity run
+func (m *IntegrityMetrics) RunCompleted(at time.Time, failed bool) {
+	if failed {
+		m.RunsTotal.WithLabelValues(m.instance, "error").Inc()
+		return
+	}
+	m.RunsTotal.WithLabelValues(m.instance, "success").Inc()
+	m.LastSuccess.WithLabelValues(m.instance).Set(float64(at.Unix()))
+}
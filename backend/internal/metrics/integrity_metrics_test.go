@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntegrityMetrics(t *testing.T) {
+	m := newIntegrityMetrics(prometheus.NewRegistry())
+
+	m.CheckCompleted("orphaned_members", 3, 0)
+	m.CheckCompleted("channel_positions", 2, 2)
+	m.RunCompleted(time.Unix(1700000000, 0), false)
+	m.RunCompleted(time.Now(), true)
+
+	assert.Equal(t, float64(3), testutil.ToFloat64(m.Issues.WithLabelValues(m.instance, "orphaned_members")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.Issues.WithLabelValues(m.instance, "channel_positions")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.RepairsTotal.WithLabelValues(m.instance, "channel_positions")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.RunsTotal.WithLabelValues(m.instance, "error")))
+	assert.Equal(t, float64(1700000000), testutil.ToFloat64(m.LastSuccess.WithLabelValues(m.instance)))
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const ipFilterSubsystem = "ip_filter"
+
+// IPFilterMetrics holds client address filtering metrics
+type IPFilterMetrics struct {
+	// BlockedTotal counts requests refused by the IP filter, by why the
+	// address was blocked: cidr, asn or reputation
+	BlockedTotal *prometheus.CounterVec
+
+	instance string
+}
+
+// NewIPFilterMetrics creates and registers IP filter metrics
+func NewIPFilterMetrics() *IPFilterMetrics {
+	return newIPFilterMetrics(prometheus.DefaultRegisterer)
+}
+
+func newIPFilterMetrics(registerer prometheus.Registerer) *IPFilterMetrics {
+	factory := promauto.With(registerer)
+
+	return &IPFilterMetrics{
+		instance: GetInstanceLabel(),
+
+		BlockedTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: ipFilterSubsystem,
+				Name:      "blocked_requests_total",
+				Help:      "Total number of requests refused because of the client address",
+			},
+			[]string{"instance", "reason"},
+		),
+	}
+}
+
+// IPBlocked records a refused request
+func (m *IPFilterMetrics) IPBlocked(reason string) {
+	m.BlockedTotal.WithLabelValues(m.instance, reason).Inc()
+}
@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPFilterMetrics(t *testing.T) {
+	m := newIPFilterMetrics(prometheus.NewRegistry())
+
+	m.IPBlocked("cidr")
+	m.IPBlocked("cidr")
+	m.IPBlocked("reputation")
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.BlockedTotal.WithLabelValues(m.instance, "cidr")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.BlockedTotal.WithLabelValues(m.instance, "reputation")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.BlockedTotal.WithLabelValues(m.instance, "asn")))
+}
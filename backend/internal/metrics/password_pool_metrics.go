@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"hearth/internal/auth"
+)
+
+const passwordPoolSubsystem = "password_pool"
+
+// PasswordPoolCollector exports the password worker pool's size, backlog
+// and queue waits. Like DBPoolCollector it reads the pool's stats on each
+// scrape.
+type PasswordPoolCollector struct {
+	instance string
+	stats    func() auth.PoolStats
+
+	workers    *prometheus.Desc
+	maxWorkers *prometheus.Desc
+	queued     *prometheus.Desc
+	queueSize  *prometheus.Desc
+	queueWait  *prometheus.Desc
+	jobs       *prometheus.Desc
+	rejected   *prometheus.Desc
+	timeouts   *prometheus.Desc
+	expired    *prometheus.Desc
+}
+
+// NewPasswordPoolCollector creates a collector for the pool. Pass
+// pool.Stats and register the result with prometheus.MustRegister.
+func NewPasswordPoolCollector(stats func() auth.PoolStats) *PasswordPoolCollector {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, passwordPoolSubsystem, name), help, append([]string{"instance"}, labels...), nil)
+	}
+
+	return &PasswordPoolCollector{
+		instance: GetInstanceLabel(),
+		stats:    stats,
+
+		workers:    desc("workers", "Password hashing workers running now"),
+		maxWorkers: desc("max_workers", "Most workers the pool may scale up to"),
+		queued:     desc("queued_jobs", "Password jobs waiting for a worker"),
+		queueSize:  desc("queue_capacity", "Password jobs that can wait before new ones are refused"),
+		queueWait:  desc("queue_wait_seconds", "How long password jobs waited for a worker"),
+		jobs:       desc("jobs_total", "Password jobs done, by operation", "operation"),
+		rejected:   desc("rejected_total", "Password jobs refused because the pool couldn't finish them in time"),
+		timeouts:   desc("timeouts_total", "Password jobs whose caller gave up waiting for the result"),
+		expired:    desc("expired_total", "Queued password jobs skipped because their caller had given up"),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *PasswordPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.workers
+	ch <- c.maxWorkers
+	ch <- c.queued
+	ch <- c.queueSize
+	ch <- c.queueWait
+	ch <- c.jobs
+	ch <- c.rejected
+	ch <- c.timeouts
+	ch <- c.expired
+}
+
+// Collect implements prometheus.Collector
+func (c *PasswordPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats()
+
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, c.instance)
+	}
+	counter := func(desc *prometheus.Desc, value float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value, append([]string{c.instance}, labels...)...)
+	}
+
+	gauge(c.workers, float64(stats.Workers))
+	gauge(c.maxWorkers, float64(stats.MaxWorkers))
+	gauge(c.queued, float64(stats.Queued))
+	gauge(c.queueSize, float64(stats.QueueSize))
+
+	buckets := make(map[float64]uint64, len(auth.QueueWaitBuckets))
+	for i, bound := range auth.QueueWaitBuckets {
+		buckets[bound.Seconds()] = stats.QueueWait.Buckets[i]
+	}
+	ch <- prometheus.MustNewConstHistogram(c.queueWait, stats.QueueWait.Count, stats.QueueWait.Sum.Seconds(), buckets, c.instance)
+
+	counter(c.jobs, float64(stats.HashCount), "hash")
+	counter(c.jobs, float64(stats.CheckCount), "check")
+	counter(c.rejected, float64(stats.RejectedCount))
+	counter(c.timeouts, float64(stats.TimeoutCount))
+	counter(c.expired, float64(stats.ExpiredCount))
+}
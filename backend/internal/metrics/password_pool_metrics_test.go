@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/auth"
+)
+
+func TestPasswordPoolCollector(t *testing.T) {
+	buckets := make([]uint64, len(auth.QueueWaitBuckets))
+	for i := range buckets {
+		buckets[i] = 3 // all three waits were under 5ms
+	}
+	stats := auth.PoolStats{
+		Workers:       3,
+		MinWorkers:    2,
+		MaxWorkers:    8,
+		QueueSize:     80,
+		Queued:        5,
+		HashCount:     10,
+		CheckCount:    40,
+		RejectedCount: 2,
+		QueueWait:     auth.QueueWaitHistogram{Count: 3, Sum: 9 * time.Millisecond, Buckets: buckets},
+	}
+	collector := NewPasswordPoolCollector(func() auth.PoolStats { return stats })
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	// The histogram and each operation's count are one metric apiece
+	assert.Equal(t, 10, testutil.CollectAndCount(collector))
+
+	expected := `
+# HELP hearth_password_pool_jobs_total Password jobs done, by operation
+# TYPE hearth_password_pool_jobs_total counter
+hearth_password_pool_jobs_total{instance="` + collector.instance + `",operation="check"} 40
+hearth_password_pool_jobs_total{instance="` + collector.instance + `",operation="hash"} 10
+# HELP hearth_password_pool_rejected_total Password jobs refused because the pool couldn't finish them in time
+# TYPE hearth_password_pool_rejected_total counter
+hearth_password_pool_rejected_total{instance="` + collector.instance + `"} 2
+# HELP hearth_password_pool_workers Password hashing workers running now
+# TYPE hearth_password_pool_workers gauge
+hearth_password_pool_workers{instance="` + collector.instance + `"} 3
+`
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"hearth_password_pool_jobs_total", "hearth_password_pool_rejected_total", "hearth_password_pool_workers"))
+}
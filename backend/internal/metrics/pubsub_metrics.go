@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const pubsubSubsystem = "pubsub"
+
+// PubSubMetrics holds metrics for events moving between gateway nodes
+type PubSubMetrics struct {
+	// DeliveryLagSeconds tracks how long after publishing another node's
+	// events reach this one, by transport
+	DeliveryLagSeconds *prometheus.HistogramVec
+
+	// ReplayedTotal counts events delivered again after a reconnect or
+	// restart because they were never acknowledged
+	ReplayedTotal *prometheus.CounterVec
+
+	// TransportErrorsTotal counts failed reads, acknowledgements and
+	// backlog checks, by transport and operation
+	TransportErrorsTotal *prometheus.CounterVec
+
+	// StreamLag tracks how many stream entries this node has yet to read
+	StreamLag *prometheus.GaugeVec
+
+	// StreamPending tracks how many stream entries this node has read but
+	// not acknowledged
+	StreamPending *prometheus.GaugeVec
+
+	// RoutedNodes tracks how many other nodes each routed event was
+	// published to
+	RoutedNodes *prometheus.HistogramVec
+
+	instance string
+}
+
+// NewPubSubMetrics creates and registers pub/sub metrics
+func NewPubSubMetrics() *PubSubMetrics {
+	return newPubSubMetrics(prometheus.DefaultRegisterer)
+}
+
+func newPubSubMetrics(registerer prometheus.Registerer) *PubSubMetrics {
+	factory := promauto.With(registerer)
+
+	return &PubSubMetrics{
+		instance: GetInstanceLabel(),
+
+		DeliveryLagSeconds: factory.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Subsystem: pubsubSubsystem,
+				Name:      "delivery_lag_seconds",
+				Help:      "Time from another node publishing an event to this node receiving it",
+				Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+			},
+			[]string{"instance", "transport"},
+		),
+
+		ReplayedTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: pubsubSubsystem,
+				Name:      "replayed_total",
+				Help:      "Total number of events delivered again because they were never acknowledged",
+			},
+			[]string{"instance", "transport"},
+		),
+
+		TransportErrorsTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: pubsubSubsystem,
+				Name:      "transport_errors_total",
+				Help:      "Total number of failed pub/sub transport operations",
+			},
+			[]string{"instance", "transport", "op"},
+		),
+
+		StreamLag: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: pubsubSubsystem,
+				Name:      "stream_lag",
+				Help:      "Stream entries published that this node has not read yet",
+			},
+			[]string{"instance"},
+		),
+
+		StreamPending: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: pubsubSubsystem,
+				Name:      "stream_pending",
+				Help:      "Stream entries this node has read but not acknowledged",
+			},
+			[]string{"instance"},
+		),
+
+		RoutedNodes: factory.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Subsystem: pubsubSubsystem,
+				Name:      "routed_nodes",
+				Help:      "Number of other nodes each routed event was published to",
+				Buckets:   []float64{0, 1, 2, 4, 8, 16, 32, 64},
+			},
+			[]string{"instance"},
+		),
+	}
+}
+
+// MessageDelivered records an event from another node
+func (m *PubSubMetrics) MessageDelivered(transport string, latency time.Duration, replayed bool) {
+	m.DeliveryLagSeconds.WithLabelValues(m.instance, transport).Observe(latency.Seconds())
+	if replayed {
+		m.ReplayedTotal.WithLabelValues(m.instance, transport).Inc()
+	}
+}
+
+// TransportFailed records a failed transport operation
+func (m *PubSubMetrics) TransportFailed(transport, op string) {
+	m.TransportErrorsTotal.WithLabelValues(m.instance, transport, op).Inc()
+}
+
+// StreamBacklog records how far behind this node's stream consumers are
+func (m *PubSubMetrics) StreamBacklog(lag, pending int64) {
+	m.StreamLag.WithLabelValues(m.instance).Set(float64(lag))
+	m.StreamPending.WithLabelValues(m.instance).Set(float64(pending))
+}
+
+// MessageRouted records how many other nodes an event was published to
+func (m *PubSubMetrics) MessageRouted(nodes int) {
+	m.RoutedNodes.WithLabelValues(m.instance).Observe(float64(nodes))
+}
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPubSubMetrics(t *testing.T) {
+	m := newPubSubMetrics(prometheus.NewRegistry())
+
+	m.MessageDelivered("streams", 20*time.Millisecond, false)
+	m.MessageDelivered("streams", time.Second, true)
+	m.TransportFailed("streams", "ack")
+	m.StreamBacklog(12, 3)
+	m.MessageRouted(2)
+
+	assert.Equal(t, 1, testutil.CollectAndCount(m.DeliveryLagSeconds))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.RoutedNodes))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.ReplayedTotal.WithLabelValues(m.instance, "streams")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.TransportErrorsTotal.WithLabelValues(m.instance, "streams", "ack")))
+	assert.Equal(t, float64(12), testutil.ToFloat64(m.StreamLag.WithLabelValues(m.instance)))
+	assert.Equal(t, float64(3), testutil.ToFloat64(m.StreamPending.WithLabelValues(m.instance)))
+}
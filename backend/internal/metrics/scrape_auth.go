@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ScrapeAuth restricts who may read the metrics endpoint. The zero value
+// lets everyone through.
+type ScrapeAuth struct {
+	// Token, when set, must be sent as "Authorization: Bearer <token>"
+	Token string
+
+	// AllowedNets, when set, lists the networks scrapers connect from. The
+	// connection's address is checked, not X-Forwarded-For, which clients
+	// can set to anything.
+	AllowedNets []*net.IPNet
+}
+
+// Enabled reports whether any restriction is configured
+func (a ScrapeAuth) Enabled() bool {
+	return a.Token != "" || len(a.AllowedNets) > 0
+}
+
+// Handler wraps next so that only allowed scrapers reach it. Requests from
+// outside the allowed networks get 403; requests without the token get 401.
+func (a ScrapeAuth) Handler(next http.Handler) http.Handler {
+	if !a.Enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(a.AllowedNets) > 0 && !a.allowedAddr(r.RemoteAddr) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if a.Token != "" && !a.validToken(r.Header.Get("Authorization")) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a ScrapeAuth) allowedAddr(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range a.AllowedNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a ScrapeAuth) validToken(header string) bool {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(a.Token)) == 1
+}
+
+// ParseCIDRs parses a comma-separated list of networks such as
+// "10.0.0.0/8, 192.168.1.5". Bare addresses allow just that address.
+func ParseCIDRs(list string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %w", entry, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// ScrapeTLSConfig loads the TLS config for a dedicated metrics listener.
+// With clientCAFile set, scrapers must present a certificate signed by one
+// of its CAs (mutual TLS).
+func ScrapeTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load metrics certificate: %w", err)
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if clientCAFile == "" {
+		return cfg, nil
+	}
+
+	pem, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metrics client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", clientCAFile)
+	}
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	return cfg, nil
+}
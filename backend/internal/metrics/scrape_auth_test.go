@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCIDRs(t *testing.T) {
+	nets, err := ParseCIDRs(" 10.0.0.0/8, 192.168.1.5 ,,fd00::/8,::1")
+	require.NoError(t, err)
+	require.Len(t, nets, 4)
+	assert.Equal(t, "10.0.0.0/8", nets[0].String())
+	assert.Equal(t, "192.168.1.5/32", nets[1].String())
+	assert.Equal(t, "::1/128", nets[3].String())
+
+	nets, err = ParseCIDRs("")
+	assert.NoError(t, err)
+	assert.Empty(t, nets)
+
+	_, err = ParseCIDRs("10.0.0.0/33")
+	assert.Error(t, err)
+	_, err = ParseCIDRs("prometheus.local")
+	assert.Error(t, err)
+}
+
+func TestScrapeAuth_Handler(t *testing.T) {
+	nets, err := ParseCIDRs("10.0.0.0/8")
+	require.NoError(t, err)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		auth       ScrapeAuth
+		remoteAddr string
+		header     string
+		status     int
+	}{
+		{"open", ScrapeAuth{}, "203.0.113.7:5000", "", http.StatusOK},
+		{"token", ScrapeAuth{Token: "s3cret"}, "203.0.113.7:5000", "Bearer s3cret", http.StatusOK},
+		{"missing token", ScrapeAuth{Token: "s3cret"}, "203.0.113.7:5000", "", http.StatusUnauthorized},
+		{"wrong token", ScrapeAuth{Token: "s3cret"}, "203.0.113.7:5000", "Bearer nope", http.StatusUnauthorized},
+		{"basic auth", ScrapeAuth{Token: "s3cret"}, "203.0.113.7:5000", "Basic czNjcmV0", http.StatusUnauthorized},
+		{"allowed network", ScrapeAuth{AllowedNets: nets}, "10.1.2.3:5000", "", http.StatusOK},
+		{"other network", ScrapeAuth{AllowedNets: nets}, "203.0.113.7:5000", "", http.StatusForbidden},
+		{"network and token", ScrapeAuth{Token: "s3cret", AllowedNets: nets}, "10.1.2.3:5000", "Bearer s3cret", http.StatusOK},
+		{"network without token", ScrapeAuth{Token: "s3cret", AllowedNets: nets}, "10.1.2.3:5000", "", http.StatusUnauthorized},
+		{"token from other network", ScrapeAuth{Token: "s3cret", AllowedNets: nets}, "203.0.113.7:5000", "Bearer s3cret", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-For", "10.9.9.9")
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			tt.auth.Handler(ok).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+			if tt.status == http.StatusUnauthorized {
+				assert.Contains(t, rec.Header().Get("WWW-Authenticate"), "Bearer")
+			}
+		})
+	}
+}
+
+// writeTestCert writes a self-signed certificate and key to dir
+func writeTestCert(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "metrics"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestScrapeTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir)
+
+	cfg, err := ScrapeTLSConfig(certFile, keyFile, "")
+	require.NoError(t, err)
+	assert.Equal(t, tls.NoClientCert, cfg.ClientAuth)
+
+	cfg, err = ScrapeTLSConfig(certFile, keyFile, certFile)
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, cfg.ClientAuth)
+	assert.NotNil(t, cfg.ClientCAs)
+
+	_, err = ScrapeTLSConfig(certFile, keyFile, keyFile)
+	assert.Error(t, err, "a key isn't a CA certificate")
+	_, err = ScrapeTLSConfig(filepath.Join(dir, "missing.pem"), keyFile, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Per-server activity kinds
+const (
+	ServerActivityMessages    = "messages"
+	ServerActivityAPIRequests = "api_requests"
+
+	// serverActivityOther sums the servers outside the top N
+	serverActivityOther = "other"
+	// serverActivityWindow is how long activity is counted before it's
+	// reported
+	serverActivityWindow = time.Minute
+)
+
+var serverActivityKinds = []string{ServerActivityMessages, ServerActivityAPIRequests}
+
+type serverActivityCounts map[string]int
+
+func (c serverActivityCounts) total() int {
+	n := 0
+	for _, count := range c {
+		n += count
+	}
+	return n
+}
+
+// ServerActivity counts what each server does on this instance and exports
+// the busiest ones, labelled by server ID. A series per server would grow
+// with every community on the instance, so only the top N of the last
+// complete minute get their own; the rest are summed under
+// server_id="other".
+type ServerActivity struct {
+	instance string
+	top      int
+	now      func() time.Time
+
+	mu          sync.Mutex
+	windowStart time.Time
+	current     map[uuid.UUID]serverActivityCounts
+	previous    map[uuid.UUID]serverActivityCounts
+
+	activity *prometheus.Desc
+	active   *prometheus.Desc
+}
+
+// NewServerActivity creates a collector reporting the top busiest servers.
+// Register the result with prometheus.MustRegister.
+func NewServerActivity(top int) *ServerActivity {
+	return newServerActivity(top, time.Now)
+}
+
+func newServerActivity(top int, now func() time.Time) *ServerActivity {
+	return &ServerActivity{
+		instance:    GetInstanceLabel(),
+		top:         top,
+		now:         now,
+		windowStart: now(),
+		current:     make(map[uuid.UUID]serverActivityCounts),
+		previous:    make(map[uuid.UUID]serverActivityCounts),
+
+		activity: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "servers", "top_activity"),
+			"Activity of the busiest servers on this instance in the last complete minute, by kind; the rest are summed as server_id=\"other\"",
+			[]string{"instance", "server_id", "kind"}, nil,
+		),
+		active: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "servers", "active"),
+			"Servers with any activity on this instance in the last complete minute",
+			[]string{"instance"}, nil,
+		),
+	}
+}
+
+// ServerMessageSent records a message sent in one of the server's channels
+func (a *ServerActivity) ServerMessageSent(serverID uuid.UUID) {
+	a.record(serverID, ServerActivityMessages)
+}
+
+// ServerAPIRequest records an API request about the server
+func (a *ServerActivity) ServerAPIRequest(serverID uuid.UUID) {
+	a.record(serverID, ServerActivityAPIRequests)
+}
+
+func (a *ServerActivity) record(serverID uuid.UUID, kind string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rotate()
+
+	counts, ok := a.current[serverID]
+	if !ok {
+		counts = make(serverActivityCounts, len(serverActivityKinds))
+		a.current[serverID] = counts
+	}
+	counts[kind]++
+}
+
+// rotate moves to a new window once the current one is over. The caller
+// holds a.mu.
+func (a *ServerActivity) rotate() {
+	elapsed := a.now().Sub(a.windowStart)
+	if elapsed < serverActivityWindow {
+		return
+	}
+	if elapsed < 2*serverActivityWindow {
+		a.previous = a.current
+	} else {
+		// Nothing happened in the window before this one
+		a.previous = make(map[uuid.UUID]serverActivityCounts)
+	}
+	a.current = make(map[uuid.UUID]serverActivityCounts, len(a.previous))
+	a.windowStart = a.windowStart.Add(elapsed.Truncate(serverActivityWindow))
+}
+
+// Describe implements prometheus.Collector
+func (a *ServerActivity) Describe(ch chan<- *prometheus.Desc) {
+	ch <- a.activity
+	ch <- a.active
+}
+
+// Collect implements prometheus.Collector
+func (a *ServerActivity) Collect(ch chan<- prometheus.Metric) {
+	a.mu.Lock()
+	a.rotate()
+	previous := a.previous
+	a.mu.Unlock()
+
+	// Windows are replaced rather than changed once complete, so previous
+	// can be read without the lock
+	ids := make([]uuid.UUID, 0, len(previous))
+	for id := range previous {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		ti, tj := previous[ids[i]].total(), previous[ids[j]].total()
+		if ti != tj {
+			return ti > tj
+		}
+		return bytes.Compare(ids[i][:], ids[j][:]) < 0
+	})
+
+	other := make(serverActivityCounts, len(serverActivityKinds))
+	for i, id := range ids {
+		if i >= a.top {
+			for kind, count := range previous[id] {
+				other[kind] += count
+			}
+			continue
+		}
+		a.collectServer(ch, id.String(), previous[id])
+	}
+	a.collectServer(ch, serverActivityOther, other)
+	ch <- prometheus.MustNewConstMetric(a.active, prometheus.GaugeValue, float64(len(previous)), a.instance)
+}
+
+func (a *ServerActivity) collectServer(ch chan<- prometheus.Metric, serverID string, counts serverActivityCounts) {
+	for _, kind := range serverActivityKinds {
+		ch <- prometheus.MustNewConstMetric(a.activity, prometheus.GaugeValue, float64(counts[kind]), a.instance, serverID, kind)
+	}
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerActivity_TopServers(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	a := newServerActivity(1, func() time.Time { return now })
+	busy := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	quiet := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	quieter := uuid.MustParse("33333333-3333-3333-3333-333333333333")
+
+	for i := 0; i < 3; i++ {
+		a.ServerMessageSent(busy)
+	}
+	a.ServerAPIRequest(busy)
+	a.ServerMessageSent(quiet)
+	a.ServerAPIRequest(quieter)
+
+	header := `
+# HELP hearth_servers_top_activity Activity of the busiest servers on this instance in the last complete minute, by kind; the rest are summed as server_id="other"
+# TYPE hearth_servers_top_activity gauge
+`
+	inst := `instance="` + a.instance + `"`
+	assert.NoError(t, testutil.CollectAndCompare(a, strings.NewReader(header+`
+hearth_servers_top_activity{`+inst+`,kind="api_requests",server_id="other"} 0
+hearth_servers_top_activity{`+inst+`,kind="messages",server_id="other"} 0
+`), "hearth_servers_top_activity"), "nothing is reported until the minute is over")
+
+	now = now.Add(serverActivityWindow)
+	expected := header + `
+hearth_servers_top_activity{` + inst + `,kind="api_requests",server_id="11111111-1111-1111-1111-111111111111"} 1
+hearth_servers_top_activity{` + inst + `,kind="messages",server_id="11111111-1111-1111-1111-111111111111"} 3
+hearth_servers_top_activity{` + inst + `,kind="api_requests",server_id="other"} 1
+hearth_servers_top_activity{` + inst + `,kind="messages",server_id="other"} 1
+# HELP hearth_servers_active Servers with any activity on this instance in the last complete minute
+# TYPE hearth_servers_active gauge
+hearth_servers_active{` + inst + `} 3
+`
+	assert.NoError(t, testutil.CollectAndCompare(a, strings.NewReader(expected)))
+
+	// Activity in the new minute waits for it to finish
+	a.ServerMessageSent(quiet)
+	assert.NoError(t, testutil.CollectAndCompare(a, strings.NewReader(expected)))
+
+	// A quiet minute clears everything
+	now = now.Add(2 * serverActivityWindow)
+	assert.Equal(t, 3, testutil.CollectAndCount(a))
+}
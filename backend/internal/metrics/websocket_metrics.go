@@ -71,6 +71,28 @@ type WebSocketMetrics struct {
 	// ConnectionDuration tracks how long connections stay open
 	ConnectionDuration *prometheus.HistogramVec
 
+	// ProtocolErrorsTotal tracks rejected inbound payloads by close code
+	ProtocolErrorsTotal *prometheus.CounterVec
+
+	// ZombiesReapedTotal tracks connections closed for missing heartbeats
+	ZombiesReapedTotal *prometheus.CounterVec
+
+	// OutboundQueueDepth tracks how many events are waiting in a
+	// connection's send queue as each one is queued
+	OutboundQueueDepth *prometheus.HistogramVec
+
+	// OutboundDroppedTotal tracks events dropped for connections that
+	// couldn't keep up, by reason
+	OutboundDroppedTotal *prometheus.CounterVec
+
+	// OutboundDroppedPerConnection tracks how many events each connection
+	// had dropped by the time it closed
+	OutboundDroppedPerConnection *prometheus.HistogramVec
+
+	// SlowConsumersEvictedTotal tracks connections closed for falling
+	// too far behind
+	SlowConsumersEvictedTotal *prometheus.CounterVec
+
 	// instance is the pod/instance name for labeling
 	instance string
 }
@@ -186,6 +208,68 @@ func NewWebSocketMetrics() *WebSocketMetrics {
 			},
 			[]string{"instance", "client_type"},
 		),
+
+		ProtocolErrorsTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: subsystem,
+				Name:      "protocol_errors_total",
+				Help:      "Total number of inbound payloads rejected by schema validation",
+			},
+			[]string{"instance", "code"},
+		),
+
+		ZombiesReapedTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: subsystem,
+				Name:      "zombie_connections_reaped_total",
+				Help:      "Total number of connections closed for missing heartbeats",
+			},
+			[]string{"instance", "client_type"},
+		),
+
+		OutboundQueueDepth: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Subsystem: subsystem,
+				Name:      "outbound_queue_depth",
+				Help:      "Events waiting in a connection's send queue, observed as each is queued",
+				Buckets:   []float64{1, 2, 4, 8, 16, 32, 64, 128, 192, 256, 512, 1024},
+			},
+			[]string{"instance"},
+		),
+
+		OutboundDroppedTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: subsystem,
+				Name:      "outbound_events_dropped_total",
+				Help:      "Total number of events dropped for connections that couldn't keep up",
+			},
+			[]string{"instance", "reason"},
+		),
+
+		OutboundDroppedPerConnection: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Subsystem: subsystem,
+				Name:      "outbound_events_dropped_per_connection",
+				Help:      "Events a connection had dropped by the time it closed",
+				Buckets:   []float64{0, 1, 10, 100, 1000, 10000},
+			},
+			[]string{"instance", "client_type"},
+		),
+
+		SlowConsumersEvictedTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: subsystem,
+				Name:      "slow_consumers_evicted_total",
+				Help:      "Total number of connections closed for falling too far behind",
+			},
+			[]string{"instance", "client_type"},
+		),
 	}
 
 	globalMetrics = m
@@ -270,6 +354,39 @@ func (m *WebSocketMetrics) HeartbeatReceived() {
 	m.HeartbeatsTotal.WithLabelValues(m.instance).Inc()
 }
 
+// ProtocolError records a rejected inbound payload
+func (m *WebSocketMetrics) ProtocolError(code string) {
+	m.ProtocolErrorsTotal.WithLabelValues(m.instance, code).Inc()
+}
+
+// ZombieReaped records a connection closed for missing heartbeats
+func (m *WebSocketMetrics) ZombieReaped(clientType string) {
+	m.ZombiesReapedTotal.WithLabelValues(m.instance, clientType).Inc()
+}
+
+// OutboundQueued records a connection's send queue depth after queueing
+func (m *WebSocketMetrics) OutboundQueued(depth int) {
+	m.OutboundQueueDepth.WithLabelValues(m.instance).Observe(float64(depth))
+}
+
+// OutboundDropped records an event dropped for a connection that couldn't
+// keep up
+func (m *WebSocketMetrics) OutboundDropped(reason string) {
+	m.OutboundDroppedTotal.WithLabelValues(m.instance, reason).Inc()
+}
+
+// OutboundClosed records how many events a connection had dropped when it
+// closed
+func (m *WebSocketMetrics) OutboundClosed(clientType string, dropped int64) {
+	m.OutboundDroppedPerConnection.WithLabelValues(m.instance, clientType).Observe(float64(dropped))
+}
+
+// SlowConsumerEvicted records a connection closed for falling too far
+// behind
+func (m *WebSocketMetrics) SlowConsumerEvicted(clientType string) {
+	m.SlowConsumersEvictedTotal.WithLabelValues(m.instance, clientType).Inc()
+}
+
 // SetActiveConnections sets the gauge directly (for sync with hub stats)
 func (m *WebSocketMetrics) SetActiveConnections(clientType string, count float64) {
 	m.ConnectionsActive.WithLabelValues(m.instance, clientType).Set(count)
@@ -315,6 +432,10 @@ func OpcodeToString(op int) string {
 		return "hello"
 	case 11:
 		return "heartbeat_ack"
+	case 12:
+		return "join_guild"
+	case 13:
+		return "token_refresh"
 	default:
 		return "unknown"
 	}
@@ -177,6 +177,7 @@ func TestOpcodeToString(t *testing.T) {
 		{9, "invalid_session"},
 		{10, "hello"},
 		{11, "heartbeat_ack"},
+		{12, "join_guild"},
 		{99, "unknown"},
 		{-1, "unknown"},
 	}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AnnouncementLevel is how prominently clients show an announcement
+type AnnouncementLevel string
+
+const (
+	AnnouncementInfo     AnnouncementLevel = "info"
+	AnnouncementWarning  AnnouncementLevel = "warning"
+	AnnouncementCritical AnnouncementLevel = "critical"
+)
+
+// Valid reports whether l is a known level
+func (l AnnouncementLevel) Valid() bool {
+	return l == AnnouncementInfo || l == AnnouncementWarning || l == AnnouncementCritical
+}
+
+// Announcement is a message from the instance's admins that every client
+// shows, e.g. planned maintenance
+type Announcement struct {
+	ID        uuid.UUID         `json:"id" db:"id"`
+	Content   string            `json:"content" db:"content"`
+	Level     AnnouncementLevel `json:"level" db:"level"`
+	CreatorID *uuid.UUID        `json:"creator_id,omitempty" db:"creator_id"`
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+	// ExpiresAt is when clients stop showing the announcement, if ever
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+}
+
+// CreateAnnouncementRequest posts an announcement. Level defaults to info.
+type CreateAnnouncementRequest struct {
+	Content   string            `json:"content" validate:"required,max=2000"`
+	Level     AnnouncementLevel `json:"level,omitempty" validate:"omitempty,oneof=info warning critical"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"`
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AutoModTriggerType is what an AutoMod rule looks for in a message
+type AutoModTriggerType string
+
+const (
+	AutoModTriggerKeyword     AutoModTriggerType = "keyword"      // Blocked words, with * wildcards
+	AutoModTriggerRegex       AutoModTriggerType = "regex"        // RE2 patterns
+	AutoModTriggerInviteLink  AutoModTriggerType = "invite_link"  // Links to server invites
+	AutoModTriggerMentionSpam AutoModTriggerType = "mention_spam" // Too many mentions in one message
+	AutoModTriggerMessageRate AutoModTriggerType = "message_rate" // Too many messages in a short time
+)
+
+// Valid reports whether t is a known trigger type
+func (t AutoModTriggerType) Valid() bool {
+	switch t {
+	case AutoModTriggerKeyword, AutoModTriggerRegex, AutoModTriggerInviteLink,
+		AutoModTriggerMentionSpam, AutoModTriggerMessageRate:
+		return true
+	}
+	return false
+}
+
+// AutoModActionType is what AutoMod does when a rule matches
+type AutoModActionType string
+
+const (
+	// AutoModActionBlock rejects the message and tells the author why
+	AutoModActionBlock AutoModActionType = "block_message"
+	// AutoModActionDelete drops the message without telling the author:
+	// they get it back as if it was sent, but nobody else sees it
+	AutoModActionDelete AutoModActionType = "delete_message"
+	// AutoModActionTimeout times the author out for DurationSeconds
+	AutoModActionTimeout AutoModActionType = "timeout"
+	// AutoModActionAlert posts what happened to ChannelID
+	AutoModActionAlert AutoModActionType = "send_alert"
+)
+
+// Valid reports whether t is a known action type
+func (t AutoModActionType) Valid() bool {
+	switch t {
+	case AutoModActionBlock, AutoModActionDelete, AutoModActionTimeout, AutoModActionAlert:
+		return true
+	}
+	return false
+}
+
+// AutoModTriggerMetadata configures a rule's trigger. Which fields apply
+// depends on the trigger type.
+type AutoModTriggerMetadata struct {
+	// Keywords match whole words unless they start or end with *
+	Keywords []string `json:"keywords,omitempty"`
+	Patterns []string `json:"patterns,omitempty"`
+	// AllowList exempts matched words, or invite codes for invite_link
+	AllowList []string `json:"allow_list,omitempty"`
+
+	// MentionLimit is the most user and role mentions a message may have
+	MentionLimit int `json:"mention_limit,omitempty"`
+
+	// MessageLimit is the most messages a member may send every
+	// IntervalSeconds
+	MessageLimit    int `json:"message_limit,omitempty"`
+	IntervalSeconds int `json:"interval_seconds,omitempty"`
+}
+
+// AutoModAction is one thing to do when a rule matches
+type AutoModAction struct {
+	Type AutoModActionType `json:"type" validate:"required,oneof=block_message delete_message timeout send_alert"`
+	// ChannelID is where send_alert posts
+	ChannelID *uuid.UUID `json:"channel_id,omitempty"`
+	// DurationSeconds is how long timeout lasts
+	DurationSeconds int `json:"duration_seconds,omitempty" validate:"min=0"`
+}
+
+// AutoModRule filters messages sent in a server
+type AutoModRule struct {
+	ID              uuid.UUID              `json:"id" db:"id"`
+	ServerID        uuid.UUID              `json:"server_id" db:"server_id"`
+	CreatorID       uuid.UUID              `json:"creator_id" db:"creator_id"`
+	Name            string                 `json:"name" db:"name"`
+	TriggerType     AutoModTriggerType     `json:"trigger_type" db:"trigger_type"`
+	TriggerMetadata AutoModTriggerMetadata `json:"trigger_metadata" db:"trigger_metadata"`
+	Actions         []AutoModAction        `json:"actions" db:"actions"`
+	Enabled         bool                   `json:"enabled" db:"enabled"`
+	// Members with any of these roles, and messages in these channels,
+	// aren't checked
+	ExemptRoles    []uuid.UUID `json:"exempt_roles" db:"exempt_roles"`
+	ExemptChannels []uuid.UUID `json:"exempt_channels" db:"exempt_channels"`
+	CreatedAt      time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// CreateAutoModRuleRequest creates an AutoMod rule. Rules are enabled
+// unless enabled is false.
+type CreateAutoModRuleRequest struct {
+	Name            string                 `json:"name" validate:"required,max=100"`
+	TriggerType     AutoModTriggerType     `json:"trigger_type" validate:"required,oneof=keyword regex invite_link mention_spam message_rate"`
+	TriggerMetadata AutoModTriggerMetadata `json:"trigger_metadata"`
+	Actions         []AutoModAction        `json:"actions" validate:"required,min=1,dive"`
+	Enabled         *bool                  `json:"enabled,omitempty"`
+	ExemptRoles     []uuid.UUID            `json:"exempt_roles,omitempty"`
+	ExemptChannels  []uuid.UUID            `json:"exempt_channels,omitempty"`
+}
+
+// UpdateAutoModRuleRequest changes the fields that are set. A rule's
+// trigger type can't change.
+type UpdateAutoModRuleRequest struct {
+	Name            *string                 `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	TriggerMetadata *AutoModTriggerMetadata `json:"trigger_metadata,omitempty"`
+	Actions         *[]AutoModAction        `json:"actions,omitempty"`
+	Enabled         *bool                   `json:"enabled,omitempty"`
+	ExemptRoles     *[]uuid.UUID            `json:"exempt_roles,omitempty"`
+	ExemptChannels  *[]uuid.UUID            `json:"exempt_channels,omitempty"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BanListEntry is one ban in an exported or imported ban list
+type BanListEntry struct {
+	UserID uuid.UUID `json:"user_id" validate:"required"`
+	Reason *string   `json:"reason,omitempty"`
+}
+
+// BanList is a server's ban list, in the form imports accept
+type BanList struct {
+	ServerID   uuid.UUID      `json:"server_id"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Bans       []BanListEntry `json:"bans"`
+}
+
+// BanImportRequest imports a ban list. Reason is used for entries that
+// don't have their own.
+type BanImportRequest struct {
+	Bans   []BanListEntry `json:"bans"`
+	Reason string         `json:"reason"`
+}
+
+// BanImportStatus is how far a ban import has got
+type BanImportStatus string
+
+const (
+	BanImportQueued    BanImportStatus = "queued"
+	BanImportRunning   BanImportStatus = "running"
+	BanImportCompleted BanImportStatus = "completed"
+	BanImportFailed    BanImportStatus = "failed"
+)
+
+// Finished reports whether the import has stopped for good
+func (s BanImportStatus) Finished() bool {
+	return s == BanImportCompleted || s == BanImportFailed
+}
+
+// BanImport is a ban list being applied to a server in the background
+type BanImport struct {
+	ID          uuid.UUID       `json:"id" db:"id"`
+	ServerID    uuid.UUID       `json:"server_id" db:"server_id"`
+	RequestedBy uuid.UUID       `json:"requested_by" db:"requested_by"`
+	Status      BanImportStatus `json:"status" db:"status"`
+	Total       int             `json:"total" db:"total"`
+	Processed   int             `json:"processed" db:"processed"`
+	// Banned counts new bans; Skipped counts entries for users who were
+	// already banned, don't exist or outrank the importer
+	Banned      int        `json:"banned" db:"banned"`
+	Skipped     int        `json:"skipped" db:"skipped"`
+	Error       *string    `json:"error,omitempty" db:"error"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+}
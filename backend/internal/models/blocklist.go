@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Blocklist is a curated list of users that servers can subscribe to, so
+// the users on it are banned as soon as they join. Lists are maintained
+// by a server's moderators, or by instance staff when ServerID is nil.
+type Blocklist struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	ServerID    *uuid.UUID `json:"server_id,omitempty" db:"server_id"`
+	Name        string     `json:"name" db:"name"`
+	Description *string    `json:"description,omitempty" db:"description"`
+	// Public lists can be subscribed to by any server; private ones only
+	// by the server that maintains them
+	Public     bool      `json:"public" db:"public"`
+	CreatedBy  uuid.UUID `json:"created_by" db:"created_by"`
+	EntryCount int       `json:"entry_count" db:"entry_count"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	// UpdatedAt changes whenever the list changes or users are added to it
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// BlocklistEntry is a user on a blocklist
+type BlocklistEntry struct {
+	BlocklistID uuid.UUID `json:"blocklist_id" db:"blocklist_id"`
+	UserID      uuid.UUID `json:"user_id" db:"user_id"`
+	Reason      *string   `json:"reason,omitempty" db:"reason"`
+	AddedBy     uuid.UUID `json:"added_by" db:"added_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// BlocklistSubscription is a server following a blocklist. Bans it makes
+// are credited to the moderator who subscribed.
+type BlocklistSubscription struct {
+	ServerID     uuid.UUID `json:"server_id" db:"server_id"`
+	BlocklistID  uuid.UUID `json:"blocklist_id" db:"blocklist_id"`
+	SubscribedBy uuid.UUID `json:"subscribed_by" db:"subscribed_by"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	// SyncedAt is the version of the list the server's members were last
+	// checked against
+	SyncedAt *time.Time `json:"synced_at,omitempty" db:"synced_at"`
+
+	Blocklist *Blocklist `json:"blocklist,omitempty" db:"-"`
+}
+
+// BlocklistExemption opts one user out of a blocklist for a server
+type BlocklistExemption struct {
+	ServerID    uuid.UUID `json:"server_id" db:"server_id"`
+	BlocklistID uuid.UUID `json:"blocklist_id" db:"blocklist_id"`
+	UserID      uuid.UUID `json:"user_id" db:"user_id"`
+	CreatedBy   uuid.UUID `json:"created_by" db:"created_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// BlocklistMatch is a user found on a list a server subscribes to
+type BlocklistMatch struct {
+	BlocklistID   uuid.UUID `db:"blocklist_id"`
+	BlocklistName string    `db:"blocklist_name"`
+	UserID        uuid.UUID `db:"user_id"`
+	Reason        *string   `db:"reason"`
+	SubscribedBy  uuid.UUID `db:"subscribed_by"`
+}
+
+// CreateBlocklistRequest creates a blocklist. Without a server ID the list
+// is maintained by the instance, which only staff can do.
+type CreateBlocklistRequest struct {
+	ServerID    *uuid.UUID `json:"server_id"`
+	Name        string     `json:"name" validate:"required,min=2,max=100"`
+	Description *string    `json:"description" validate:"omitempty,max=300"`
+	Public      bool       `json:"public"`
+}
+
+// UpdateBlocklistRequest changes a blocklist's details
+type UpdateBlocklistRequest struct {
+	Name        *string `json:"name" validate:"omitempty,min=2,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=300"`
+	Public      *bool   `json:"public"`
+}
+
+// AddBlocklistEntriesRequest adds users to a blocklist, updating the reason
+// of any already on it
+type AddBlocklistEntriesRequest struct {
+	Entries []BanListEntry `json:"entries" validate:"required,min=1,max=1000,dive"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BotTier is how far the instance trusts a bot account. Bots start out
+// unverified; instance admins move them up.
+type BotTier string
+
+const (
+	BotTierUnverified BotTier = "unverified"
+	BotTierVerified   BotTier = "verified"
+	BotTierPartner    BotTier = "partner"
+)
+
+// Valid reports whether t is a known tier
+func (t BotTier) Valid() bool {
+	_, ok := botTierPolicies[t]
+	return ok
+}
+
+// BotTierPolicy is what a tier allows a bot
+type BotTierPolicy struct {
+	// RateLimitMultiplier scales the instance's bot_requests_per_minute
+	// and bot_burst_limit
+	RateLimitMultiplier int `json:"rate_limit_multiplier"`
+	// MaxServers is how many servers the bot can be in, in place of the
+	// instance's max_servers_joined. 0 means unlimited.
+	MaxServers int `json:"max_servers"`
+}
+
+var botTierPolicies = map[BotTier]BotTierPolicy{
+	BotTierUnverified: {RateLimitMultiplier: 1, MaxServers: 100},
+	BotTierVerified:   {RateLimitMultiplier: 2, MaxServers: 2500},
+	BotTierPartner:    {RateLimitMultiplier: 5, MaxServers: 0},
+}
+
+// Policy returns the tier's policy. Unknown tiers get the unverified one.
+func (t BotTier) Policy() BotTierPolicy {
+	if policy, ok := botTierPolicies[t]; ok {
+		return policy
+	}
+	return botTierPolicies[BotTierUnverified]
+}
+
+// BotVerification is a bot account's tier
+type BotVerification struct {
+	UserID    uuid.UUID     `json:"user_id" db:"user_id"`
+	Tier      BotTier       `json:"tier" db:"tier"`
+	Policy    BotTierPolicy `json:"policy" db:"-"`
+	UpdatedBy *uuid.UUID    `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt *time.Time    `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// SetBotTierRequest moves a bot to another tier
+type SetBotTierRequest struct {
+	Tier BotTier `json:"tier" validate:"required,oneof=unverified verified partner"`
+}
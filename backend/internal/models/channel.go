@@ -40,6 +40,7 @@ type Channel struct {
 	LastMessageID      *uuid.UUID  `json:"last_message_id,omitempty" db:"last_message_id"`
 	CreatedAt          time.Time   `json:"created_at" db:"created_at"`
 	UpdatedAt          time.Time   `json:"updated_at" db:"updated_at"`
+	Version            int         `json:"version" db:"version"` // Bumped on every update
 
 	// Populated from joins
 	PermissionOverrides []PermissionOverride `json:"permission_overrides,omitempty"`
@@ -76,6 +77,8 @@ type UpdateChannelRequest struct {
 	SlowmodeSeconds *int    `json:"slowmode_seconds,omitempty" validate:"omitempty,min=0,max=21600"`
 	Bitrate         *int    `json:"bitrate,omitempty" validate:"omitempty,min=8000,max=384000"`
 	UserLimit       *int    `json:"user_limit,omitempty" validate:"omitempty,min=0,max=99"`
+	E2EEEnabled     *bool   `json:"e2ee_enabled,omitempty"`
+	Version         *int    `json:"version,omitempty"` // Alternative to If-Match
 }
 
 // ChannelUpdate is used for partial updates via services
@@ -86,6 +89,9 @@ type ChannelUpdate struct {
 	Slowmode    *int    `json:"slowmode,omitempty"`
 	NSFW        *bool   `json:"nsfw,omitempty"`
 	E2EEEnabled *bool   `json:"e2ee_enabled,omitempty"`
+
+	// Version the edit was based on; if set and stale the update is refused
+	Version *int `json:"version,omitempty"`
 }
 
 // PermissionOverride represents channel-specific permission overrides
@@ -112,7 +118,8 @@ type Thread struct {
 	ArchiveTimestamp *time.Time `json:"archive_timestamp,omitempty" db:"archive_timestamp"`
 
 	// Populated from joins
-	ParentChannel *Channel `json:"parent_channel,omitempty"`
+	ParentChannel *Channel    `json:"parent_channel,omitempty"`
+	AppliedTags   []uuid.UUID `json:"applied_tags,omitempty" db:"-"` // Forum posts only
 }
 
 // CreateThreadRequest is the input for creating a thread
@@ -155,11 +162,11 @@ type DMChannelRecipient struct {
 
 // CreateDMRequest is the input for creating/getting a DM channel
 type CreateDMRequest struct {
-	RecipientID string `json:"recipient_id" validate:"required"`
+	RecipientID string `json:"recipient_id" validate:"required,uuid"`
 }
 
 // CreateGroupDMRequest is the input for creating a group DM
 type CreateGroupDMRequest struct {
-	RecipientIDs []string `json:"recipient_ids" validate:"required,min=1,max=9"`
+	RecipientIDs []string `json:"recipient_ids" validate:"required,min=1,max=9,dive,uuid"`
 	Name         *string  `json:"name,omitempty" validate:"omitempty,max=100"`
 }
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChannelFeed makes a channel's recent messages readable as an Atom feed by
+// anyone with its token
+type ChannelFeed struct {
+	ChannelID uuid.UUID  `db:"channel_id"`
+	Token     string     `db:"token"`
+	CreatorID *uuid.UUID `db:"creator_id"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+// ChannelFeedSettings is a channel's feed as its managers see it
+type ChannelFeedSettings struct {
+	ChannelID uuid.UUID  `json:"channel_id"`
+	Enabled   bool       `json:"enabled"`
+	Token     string     `json:"token,omitempty"`
+	URL       string     `json:"url,omitempty"`
+	EnabledAt *time.Time `json:"enabled_at,omitempty"`
+}
+
+// UpdateChannelFeedRequest turns a channel's feed on or off. Rotating the
+// token breaks every copy of the old feed URL.
+type UpdateChannelFeedRequest struct {
+	Enabled     bool `json:"enabled"`
+	RotateToken bool `json:"rotate_token"`
+}
+
+// ChannelFeedContent is what a channel's feed shows. Messages are newest
+// first, with their authors.
+type ChannelFeedContent struct {
+	Channel  *Channel
+	Server   *Server
+	Messages []*Message
+	// Updated is when the newest message was sent or edited, or when the
+	// feed was turned on if there are none
+	Updated time.Time
+}
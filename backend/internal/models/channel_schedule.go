@@ -0,0 +1,159 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// channelScheduleLookaheadDays is how many days ahead a schedule's next
+// open or close is looked for. Eight covers every weekly window.
+const channelScheduleLookaheadDays = 8
+
+// ChannelSchedule opens a channel during its weekly hours and closes it
+// outside them and during closures. Members can read a closed channel but
+// not send to it.
+type ChannelSchedule struct {
+	ChannelID uuid.UUID `json:"channel_id" db:"channel_id"`
+	// Timezone is the IANA zone the hours are in, e.g. "Europe/Berlin"
+	Timezone string `json:"timezone" db:"timezone"`
+	// Hours are when the channel is open. Without any the channel is only
+	// closed during closures.
+	Hours     []ChannelHours   `json:"hours"`
+	Closures  []ChannelClosure `json:"closures"`
+	UpdatedAt time.Time        `json:"updated_at" db:"updated_at"`
+
+	// Computed when the schedule is read
+	Open         bool       `json:"open" db:"-"`
+	NextChangeAt *time.Time `json:"next_change_at,omitempty" db:"-"`
+
+	loc *time.Location
+}
+
+// ChannelHours opens a channel on a day of the week. Open and Close are
+// "HH:MM" in the schedule's timezone; Close may be "24:00".
+type ChannelHours struct {
+	Day   time.Weekday `json:"day" validate:"min=0,max=6"` // 0 is Sunday
+	Open  string       `json:"open"`
+	Close string       `json:"close"`
+}
+
+// ChannelClosure closes a channel for a while whatever its hours, e.g. for
+// a holiday
+type ChannelClosure struct {
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+// UpdateChannelScheduleRequest replaces a channel's schedule
+type UpdateChannelScheduleRequest struct {
+	Timezone string           `json:"timezone"`
+	Hours    []ChannelHours   `json:"hours"`
+	Closures []ChannelClosure `json:"closures"`
+}
+
+// ParseClock parses an "HH:MM" time of day into minutes after midnight.
+// "24:00" is allowed as the end of a day.
+func ParseClock(clock string) (int, bool) {
+	if len(clock) != 5 || clock[2] != ':' {
+		return 0, false
+	}
+	for _, i := range []int{0, 1, 3, 4} {
+		if clock[i] < '0' || clock[i] > '9' {
+			return 0, false
+		}
+	}
+	hours := int(clock[0]-'0')*10 + int(clock[1]-'0')
+	minutes := int(clock[3]-'0')*10 + int(clock[4]-'0')
+	if minutes > 59 || hours > 24 || (hours == 24 && minutes != 0) {
+		return 0, false
+	}
+	return hours*60 + minutes, true
+}
+
+// OpenAt reports whether the channel is open at t
+func (s *ChannelSchedule) OpenAt(t time.Time) bool {
+	for _, closure := range s.Closures {
+		if !t.Before(closure.StartsAt) && t.Before(closure.EndsAt) {
+			return false
+		}
+	}
+	if len(s.Hours) == 0 {
+		return true
+	}
+
+	local := t.In(s.location())
+	minute := local.Hour()*60 + local.Minute()
+	for _, hours := range s.Hours {
+		opens, okOpen := ParseClock(hours.Open)
+		closes, okClose := ParseClock(hours.Close)
+		if okOpen && okClose && hours.Day == local.Weekday() && minute >= opens && minute < closes {
+			return true
+		}
+	}
+	return false
+}
+
+// State fills in Open and NextChangeAt as of now. NextChangeAt is left nil
+// if the channel stays as it is for the next week and has no closures
+// coming up.
+func (s *ChannelSchedule) State(now time.Time) {
+	s.Open = s.OpenAt(now)
+	s.NextChangeAt = nil
+	for _, at := range s.transitions(now) {
+		if s.OpenAt(at) != s.Open {
+			at := at
+			s.NextChangeAt = &at
+			return
+		}
+	}
+}
+
+// transitions lists, in order, every instant after now the channel might
+// open or close
+func (s *ChannelSchedule) transitions(now time.Time) []time.Time {
+	var times []time.Time
+	add := func(t time.Time) {
+		if t.After(now) {
+			times = append(times, t)
+		}
+	}
+	for _, closure := range s.Closures {
+		add(closure.StartsAt)
+		add(closure.EndsAt)
+	}
+
+	// Building each day's times with time.Date keeps them right across
+	// daylight saving changes
+	loc := s.location()
+	local := now.In(loc)
+	for day := 0; day < channelScheduleLookaheadDays; day++ {
+		date := time.Date(local.Year(), local.Month(), local.Day()+day, 0, 0, 0, 0, loc)
+		for _, hours := range s.Hours {
+			if hours.Day != date.Weekday() {
+				continue
+			}
+			for _, clock := range []string{hours.Open, hours.Close} {
+				if minutes, ok := ParseClock(clock); ok {
+					add(time.Date(date.Year(), date.Month(), date.Day(), minutes/60, minutes%60, 0, 0, loc))
+				}
+			}
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	return times
+}
+
+// location loads the schedule's timezone once, falling back to UTC
+func (s *ChannelSchedule) location() *time.Location {
+	if s.loc == nil || s.loc.String() != s.Timezone {
+		loc, err := time.LoadLocation(s.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		s.loc = loc
+	}
+	return s.loc
+}
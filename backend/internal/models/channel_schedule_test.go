@@ -0,0 +1,89 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func weekdayHours(opens, closes string) []ChannelHours {
+	var hours []ChannelHours
+	for day := time.Monday; day <= time.Friday; day++ {
+		hours = append(hours, ChannelHours{Day: day, Open: opens, Close: closes})
+	}
+	return hours
+}
+
+func TestChannelScheduleState(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no timezone data")
+	}
+	schedule := &ChannelSchedule{Timezone: "America/New_York", Hours: weekdayHours("09:00", "17:00")}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		open bool
+		next time.Time
+	}{
+		{"during hours", time.Date(2026, 3, 2, 10, 0, 0, 0, ny), true, time.Date(2026, 3, 2, 17, 0, 0, 0, ny)},
+		{"at closing time", time.Date(2026, 3, 2, 17, 0, 0, 0, ny), false, time.Date(2026, 3, 3, 9, 0, 0, 0, ny)},
+		{"over the weekend", time.Date(2026, 2, 27, 18, 0, 0, 0, ny), false, time.Date(2026, 3, 2, 9, 0, 0, 0, ny)},
+		// Clocks go forward on Sunday, March 8
+		{"across daylight saving", time.Date(2026, 3, 7, 12, 0, 0, 0, ny), false, time.Date(2026, 3, 9, 13, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range tests {
+		schedule.State(tc.now)
+		if schedule.Open != tc.open {
+			t.Errorf("%s: open = %v, want %v", tc.name, schedule.Open, tc.open)
+		}
+		if schedule.NextChangeAt == nil || !schedule.NextChangeAt.Equal(tc.next) {
+			t.Errorf("%s: next change = %v, want %v", tc.name, schedule.NextChangeAt, tc.next)
+		}
+	}
+}
+
+func TestChannelScheduleState_Closures(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	schedule := &ChannelSchedule{
+		Timezone: "UTC",
+		Hours: []ChannelHours{
+			{Day: time.Monday, Open: "20:00", Close: "24:00"},
+			{Day: time.Tuesday, Open: "00:00", Close: "02:00"},
+		},
+	}
+
+	schedule.State(monday.Add(21 * time.Hour))
+	if !schedule.Open || !schedule.NextChangeAt.Equal(monday.Add(26*time.Hour)) {
+		t.Errorf("hours past midnight should run on, got open=%v next=%v", schedule.Open, schedule.NextChangeAt)
+	}
+
+	schedule.Closures = []ChannelClosure{{StartsAt: monday.Add(22 * time.Hour), EndsAt: monday.Add(23 * time.Hour)}}
+	schedule.State(monday.Add(22*time.Hour + 30*time.Minute))
+	if schedule.Open || !schedule.NextChangeAt.Equal(monday.Add(23*time.Hour)) {
+		t.Errorf("closure should close the channel until it ends, got open=%v next=%v", schedule.Open, schedule.NextChangeAt)
+	}
+
+	closedOnly := &ChannelSchedule{Closures: schedule.Closures}
+	closedOnly.State(monday)
+	if !closedOnly.Open || !closedOnly.NextChangeAt.Equal(monday.Add(22*time.Hour)) {
+		t.Errorf("schedule without hours should be open until the closure, got open=%v next=%v", closedOnly.Open, closedOnly.NextChangeAt)
+	}
+	closedOnly.State(monday.Add(24 * time.Hour))
+	if !closedOnly.Open || closedOnly.NextChangeAt != nil {
+		t.Errorf("schedule should stay open once its closures are over, got open=%v next=%v", closedOnly.Open, closedOnly.NextChangeAt)
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	for clock, want := range map[string]int{"00:00": 0, "09:30": 570, "24:00": 1440} {
+		if got, ok := ParseClock(clock); !ok || got != want {
+			t.Errorf("ParseClock(%q) = %d, %v, want %d", clock, got, ok, want)
+		}
+	}
+	for _, clock := range []string{"", "9:30", "24:01", "12:60", "ab:cd", "12-00"} {
+		if _, ok := ParseClock(clock); ok {
+			t.Errorf("ParseClock(%q) should fail", clock)
+		}
+	}
+}
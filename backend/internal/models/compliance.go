@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ComplianceOutcome describes how a logged request ended
+type ComplianceOutcome string
+
+const (
+	ComplianceOutcomeSuccess ComplianceOutcome = "success"
+	ComplianceOutcomeDenied  ComplianceOutcome = "denied"  // 401 or 403
+	ComplianceOutcomeInvalid ComplianceOutcome = "invalid" // Any other 4xx
+	ComplianceOutcomeError   ComplianceOutcome = "error"   // 5xx
+)
+
+// ComplianceOutcomeFor maps an HTTP status to its outcome
+func ComplianceOutcomeFor(status int) ComplianceOutcome {
+	switch {
+	case status >= 500:
+		return ComplianceOutcomeError
+	case status == 401 || status == 403:
+		return ComplianceOutcomeDenied
+	case status >= 400:
+		return ComplianceOutcomeInvalid
+	}
+	return ComplianceOutcomeSuccess
+}
+
+// ComplianceRedacted replaces route parameters that aren't IDs, such as
+// invite codes and emoji
+const ComplianceRedacted = "[redacted]"
+
+// ComplianceLogEntry records one mutating API request. Only metadata is
+// kept: the route template rather than the path, the IDs in the path, and
+// never the query string or body.
+type ComplianceLogEntry struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	RequestID string     `json:"request_id,omitempty" db:"request_id"`
+	Method    string     `json:"method" db:"method"`
+	Route     string     `json:"route" db:"route"` // e.g. /api/v1/channels/:id/messages
+	ActorID   *uuid.UUID `json:"actor_id,omitempty" db:"actor_id"`
+	// Targets maps route parameter names to their values; values that
+	// aren't UUIDs are ComplianceRedacted
+	Targets    map[string]string `json:"targets"`
+	Status     int               `json:"status" db:"status"`
+	Outcome    ComplianceOutcome `json:"outcome" db:"outcome"`
+	DurationMS int64             `json:"duration_ms" db:"duration_ms"`
+	CreatedAt  time.Time         `json:"created_at" db:"created_at"`
+}
+
+// ComplianceLogFilter narrows a compliance log export
+type ComplianceLogFilter struct {
+	After   *time.Time // Entries at or after this time
+	Before  *time.Time // Entries before this time
+	ActorID *uuid.UUID
+}
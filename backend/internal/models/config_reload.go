@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// ConfigReload reports what re-reading the config file changed. Each list
+// holds setting names, e.g. LOCKOUT_THRESHOLD.
+type ConfigReload struct {
+	File       string    `json:"file"`
+	ReloadedAt time.Time `json:"reloaded_at"`
+	// Applied changed and took effect straight away
+	Applied []string `json:"applied"`
+	// RestartRequired changed but only take effect once the instance
+	// restarts
+	RestartRequired []string `json:"restart_required"`
+	// Overridden changed in the file but are also set in the environment,
+	// which wins
+	Overridden []string `json:"overridden"`
+	// Errors holds the settings that changed but couldn't be applied, and
+	// why. They keep their old values until the next reload.
+	Errors map[string]string `json:"errors,omitempty"`
+}
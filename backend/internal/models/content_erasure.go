@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ContentErasureStatus is how far erasing a user's content has got
+type ContentErasureStatus string
+
+const (
+	ContentErasureQueued  ContentErasureStatus = "queued"
+	ContentErasureRunning ContentErasureStatus = "running"
+	// ContentErasureVerified means a final check found none of the user's
+	// content left
+	ContentErasureVerified ContentErasureStatus = "verified"
+	ContentErasureFailed   ContentErasureStatus = "failed"
+)
+
+// Finished reports whether the erasure has stopped for good
+func (s ContentErasureStatus) Finished() bool {
+	return s == ContentErasureVerified || s == ContentErasureFailed
+}
+
+// ContentErasure scrubs everything a user wrote, in the background. The
+// record is kept after the user's account is gone as proof it was done.
+type ContentErasure struct {
+	ID          uuid.UUID            `json:"id" db:"id"`
+	UserID      uuid.UUID            `json:"user_id" db:"user_id"`
+	RequestedBy uuid.UUID            `json:"requested_by" db:"requested_by"`
+	Status      ContentErasureStatus `json:"status" db:"status"`
+	// MessagesScrubbed counts the user's messages and thread replies;
+	// ForwardsScrubbed counts copies of them forwarded by others
+	MessagesScrubbed   int `json:"messages_scrubbed" db:"messages_scrubbed"`
+	ForwardsScrubbed   int `json:"forwards_scrubbed" db:"forwards_scrubbed"`
+	AttachmentsRemoved int `json:"attachments_removed" db:"attachments_removed"`
+	// Remaining is how much of the user's content the last verification
+	// still found
+	Remaining   int        `json:"remaining" db:"remaining"`
+	Error       *string    `json:"error,omitempty" db:"error"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	VerifiedAt  *time.Time `json:"verified_at,omitempty" db:"verified_at"`
+}
+
+// ContentScrubCounts is what one batch of an erasure scrubbed
+type ContentScrubCounts struct {
+	Messages    int
+	Forwards    int
+	Attachments int
+}
+
+// Total is the number of messages and forwards scrubbed
+func (c ContentScrubCounts) Total() int {
+	return c.Messages + c.Forwards
+}
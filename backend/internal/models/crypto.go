@@ -1,21 +1,159 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"time"
 
-// PreKeyBundle contains the pre-keys needed for E2EE key exchange
+	"github.com/google/uuid"
+)
+
+// EncryptedPayloadVersion is the envelope version encrypted messages use
+const EncryptedPayloadVersion = 1
+
+// deviceIDPattern is what device IDs, chosen by clients, may look like
+var deviceIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ValidDeviceID reports whether id can name an E2EE device
+func ValidDeviceID(id string) bool {
+	return deviceIDPattern.MatchString(id)
+}
+
+// E2EEDevice is one of a user's devices and the public keys other devices
+// use to set up sessions with it. Private keys never leave the device.
+type E2EEDevice struct {
+	UserID         uuid.UUID `json:"user_id" db:"user_id"`
+	DeviceID       string    `json:"device_id" db:"device_id"`
+	IdentityKey    string    `json:"identity_key" db:"identity_key"`
+	SignedPreKeyID int       `json:"signed_pre_key_id" db:"signed_pre_key_id"`
+	SignedPreKey   string    `json:"signed_pre_key" db:"signed_pre_key"`
+	SignedKeySign  string    `json:"signed_key_signature" db:"signed_key_signature"`
+	// PreKeyCount is how many one-time pre-keys are left, so the device
+	// knows when to upload more
+	PreKeyCount int       `json:"pre_key_count" db:"pre_key_count"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// OneTimePreKey is a pre-key handed out to a single session and then
+// deleted
+type OneTimePreKey struct {
+	KeyID     int    `json:"key_id" db:"key_id" validate:"min=0"`
+	PublicKey string `json:"public_key" db:"public_key" validate:"required,max=1024"`
+}
+
+// PreKeyBundle contains the pre-keys needed for E2EE key exchange with one
+// of a user's devices. PreKeyID and PreKey are empty once the device has
+// run out of one-time pre-keys.
 type PreKeyBundle struct {
-	UserID          uuid.UUID `json:"user_id" db:"user_id"`
-	IdentityKey     string    `json:"identity_key" db:"identity_key"`
-	SignedPreKeyID  int       `json:"signed_pre_key_id" db:"signed_pre_key_id"`
-	SignedPreKey    string    `json:"signed_pre_key" db:"signed_pre_key"`
-	SignedKeySign   string    `json:"signed_key_signature" db:"signed_key_signature"`
-	PreKeyID        int       `json:"pre_key_id" db:"pre_key_id"`
-	PreKey          string    `json:"pre_key" db:"pre_key"`
-}
-
-// MLSGroup represents an MLS group for group E2EE
-type MLSGroup struct {
-	ChannelID   uuid.UUID `json:"channel_id" db:"channel_id"`
-	GroupState  []byte    `json:"-" db:"group_state"`
-	Epoch       int64     `json:"epoch" db:"epoch"`
+	UserID         uuid.UUID `json:"user_id" db:"user_id"`
+	DeviceID       string    `json:"device_id" db:"device_id"`
+	IdentityKey    string    `json:"identity_key" db:"identity_key"`
+	SignedPreKeyID int       `json:"signed_pre_key_id" db:"signed_pre_key_id"`
+	SignedPreKey   string    `json:"signed_pre_key" db:"signed_pre_key"`
+	SignedKeySign  string    `json:"signed_key_signature" db:"signed_key_signature"`
+	PreKeyID       *int      `json:"pre_key_id,omitempty" db:"pre_key_id"`
+	PreKey         string    `json:"pre_key,omitempty" db:"pre_key"`
+}
+
+// RegisterDeviceKeysRequest registers a device's keys, or replaces them
+type RegisterDeviceKeysRequest struct {
+	IdentityKey    string          `json:"identity_key" validate:"required,max=1024"`
+	SignedPreKeyID int             `json:"signed_pre_key_id" validate:"min=0"`
+	SignedPreKey   string          `json:"signed_pre_key" validate:"required,max=1024"`
+	SignedKeySign  string          `json:"signed_key_signature" validate:"required,max=1024"`
+	PreKeys        []OneTimePreKey `json:"pre_keys" validate:"dive"`
+}
+
+// UploadPreKeysRequest adds one-time pre-keys to a device
+type UploadPreKeysRequest struct {
+	PreKeys []OneTimePreKey `json:"pre_keys" validate:"required,min=1,dive"`
+}
+
+// ChannelKeyEpoch counts how often an encrypted channel's keys were
+// rotated. Members rotate their sender keys whenever the epoch moves on,
+// and messages must be sealed with the current epoch's keys.
+type ChannelKeyEpoch struct {
+	ChannelID uuid.UUID `json:"channel_id" db:"channel_id"`
+	Epoch     int64     `json:"epoch" db:"epoch"`
+	RotatedAt time.Time `json:"rotated_at" db:"rotated_at"`
+}
+
+// SenderKey is a device's sender key for a channel epoch, encrypted for
+// one recipient device over their pairwise session. The server only
+// relays it.
+type SenderKey struct {
+	ChannelID         uuid.UUID `json:"channel_id" db:"channel_id"`
+	Epoch             int64     `json:"epoch" db:"epoch"`
+	SenderID          uuid.UUID `json:"sender_id" db:"sender_id"`
+	SenderDeviceID    string    `json:"sender_device_id" db:"sender_device_id"`
+	RecipientID       uuid.UUID `json:"recipient_id" db:"recipient_id"`
+	RecipientDeviceID string    `json:"recipient_device_id" db:"recipient_device_id"`
+	Ciphertext        string    `json:"ciphertext" db:"ciphertext"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+}
+
+// SenderKeyEnvelope is a sender key encrypted for one recipient device
+type SenderKeyEnvelope struct {
+	UserID     uuid.UUID `json:"user_id" validate:"required"`
+	DeviceID   string    `json:"device_id" validate:"required,max=64"`
+	Ciphertext string    `json:"ciphertext" validate:"required,max=4096"`
+}
+
+// DistributeSenderKeysRequest hands a device's sender key for the
+// channel's current epoch to other devices
+type DistributeSenderKeysRequest struct {
+	DeviceID string              `json:"device_id" validate:"required,max=64"`
+	Epoch    int64               `json:"epoch"`
+	Keys     []SenderKeyEnvelope `json:"keys" validate:"required,min=1,dive"`
+}
+
+// ChannelKeys is a channel's current epoch and the sender keys addressed
+// to one device
+type ChannelKeys struct {
+	Epoch      int64        `json:"epoch"`
+	SenderKeys []*SenderKey `json:"sender_keys"`
+}
+
+// EncryptedPayload is the envelope clients put in a message's content in
+// encrypted channels. The server can't read Ciphertext; it only checks the
+// envelope is well formed and sealed with the channel's current epoch.
+type EncryptedPayload struct {
+	Version    int    `json:"version"`
+	Epoch      int64  `json:"epoch"`
+	DeviceID   string `json:"device_id"`
+	IV         string `json:"iv"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// ParseEncryptedPayload decodes and checks an encrypted message envelope
+func ParseEncryptedPayload(raw string) (*EncryptedPayload, error) {
+	var payload EncryptedPayload
+	if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+		return nil, errors.New("content must be an encrypted envelope")
+	}
+	if payload.Version != EncryptedPayloadVersion {
+		return nil, errors.New("unsupported envelope version")
+	}
+	if payload.Epoch < 0 {
+		return nil, errors.New("epoch can't be negative")
+	}
+	if !ValidDeviceID(payload.DeviceID) {
+		return nil, errors.New("invalid device_id")
+	}
+	if !ValidBase64(payload.IV) || !ValidBase64(payload.Ciphertext) {
+		return nil, errors.New("iv and ciphertext must be base64")
+	}
+	return &payload, nil
+}
+
+// ValidBase64 reports whether s is non-empty, padded standard base64
+func ValidBase64(s string) bool {
+	if s == "" {
+		return false
+	}
+	_, err := base64.StdEncoding.DecodeString(s)
+	return err == nil
 }
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Emoji is a custom emoji uploaded to a server
+type Emoji struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	ServerID  uuid.UUID `json:"server_id" db:"server_id"`
+	CreatorID uuid.UUID `json:"creator_id" db:"creator_id"`
+	Name      string    `json:"name" db:"name"`
+	Animated  bool      `json:"animated" db:"animated"`
+	URL       string    `json:"url" db:"url"`
+	Path      string    `json:"-" db:"path"` // Where the image is in storage
+	Size      int64     `json:"size" db:"size"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// ReactionEmoji is the emoji of a reaction. Unicode emoji only have a
+// name; custom emoji have an ID too, and no name once they're deleted.
+type ReactionEmoji struct {
+	ID       *uuid.UUID `json:"id"`
+	Name     string     `json:"name"`
+	Animated bool       `json:"animated,omitempty"`
+	URL      string     `json:"url,omitempty"`
+}
+
+// ParseReactionEmoji reads the emoji of a reaction route, which is a
+// unicode emoji, a custom emoji's ID, or name:id as in messages. It returns
+// the key reactions are stored under: the emoji itself, or the custom
+// emoji's ID.
+func ParseReactionEmoji(raw string) (key string, customID *uuid.UUID) {
+	ref := strings.TrimSuffix(strings.TrimPrefix(raw, "<"), ">")
+	if i := strings.LastIndexByte(ref, ':'); i >= 0 {
+		ref = ref[i+1:]
+	}
+	if id, err := uuid.Parse(ref); err == nil {
+		return id.String(), &id
+	}
+	return raw, nil
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ForumSortOrder is how a forum channel lists its posts
+type ForumSortOrder string
+
+const (
+	ForumSortLatestActivity ForumSortOrder = "latest_activity"
+	ForumSortCreationDate   ForumSortOrder = "creation_date"
+)
+
+// Valid reports whether the sort order is one clients may pick
+func (o ForumSortOrder) Valid() bool {
+	return o == ForumSortLatestActivity || o == ForumSortCreationDate
+}
+
+// ForumTag is a tag posts in a forum channel can be filed under
+type ForumTag struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	ChannelID uuid.UUID `json:"channel_id" db:"channel_id"`
+	Name      string    `json:"name" db:"name"`
+	Emoji     *string   `json:"emoji,omitempty" db:"emoji"` // Unicode emoji or custom emoji ID
+	Position  int       `json:"position" db:"position"`
+}
+
+// ForumSettings are a forum channel's tags and listing options
+type ForumSettings struct {
+	ChannelID        uuid.UUID      `json:"channel_id" db:"channel_id"`
+	DefaultSortOrder ForumSortOrder `json:"default_sort_order" db:"default_sort_order"`
+	RequireTag       bool           `json:"require_tag" db:"require_tag"`
+
+	// Populated from joins
+	Tags []ForumTag `json:"available_tags" db:"-"`
+}
+
+// DefaultForumSettings are the settings of a forum nobody has configured
+func DefaultForumSettings(channelID uuid.UUID) *ForumSettings {
+	return &ForumSettings{
+		ChannelID:        channelID,
+		DefaultSortOrder: ForumSortLatestActivity,
+		Tags:             []ForumTag{},
+	}
+}
+
+// ForumPost is a thread in a forum channel as listed in the forum
+type ForumPost struct {
+	Thread
+	LastActivityAt time.Time `json:"last_activity_at" db:"last_activity_at"`
+
+	// Populated from joins
+	FirstMessage *ThreadMessage `json:"first_message,omitempty" db:"-"`
+}
+
+// ForumPostQuery filters and orders a forum's posts
+type ForumPostQuery struct {
+	TagIDs   []uuid.UUID // Posts with any of these tags; empty = all posts
+	Sort     ForumSortOrder
+	Archived bool
+	Limit    int
+	Offset   int
+}
+
+// CreateForumPostRequest is the input for posting in a forum channel
+type CreateForumPostRequest struct {
+	Name        string      `json:"name" validate:"required,min=1,max=100"`
+	Content     string      `json:"content" validate:"required,min=1,max=2000"`
+	AppliedTags []uuid.UUID `json:"applied_tags,omitempty" validate:"omitempty,max=5"`
+	AutoArchive *int        `json:"auto_archive,omitempty"` // 60, 1440, 4320, 10080
+}
+
+// ForumTagInput is a tag in UpdateForumSettingsRequest. Tags with an ID
+// keep it, and the posts filed under them.
+type ForumTagInput struct {
+	ID    *uuid.UUID `json:"id,omitempty"`
+	Name  string     `json:"name" validate:"required,min=1,max=20"`
+	Emoji *string    `json:"emoji,omitempty" validate:"omitempty,max=64"`
+}
+
+// UpdateForumSettingsRequest is the input for configuring a forum channel.
+// AvailableTags replaces the forum's tags when set.
+type UpdateForumSettingsRequest struct {
+	DefaultSortOrder *ForumSortOrder  `json:"default_sort_order,omitempty" validate:"omitempty,oneof=latest_activity creation_date"`
+	RequireTag       *bool            `json:"require_tag,omitempty"`
+	AvailableTags    *[]ForumTagInput `json:"available_tags,omitempty" validate:"omitempty,max=20,dive"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// InstanceStats counts what an instance holds, for operators
+type InstanceStats struct {
+	Users    int64 `json:"users" db:"users"`
+	Bots     int64 `json:"bots" db:"bots"`
+	Servers  int64 `json:"servers" db:"servers"`
+	Channels int64 `json:"channels" db:"channels"`
+	// Messages is Postgres's estimate, as counting a large messages table
+	// exactly takes too long
+	Messages int64 `json:"messages" db:"messages"`
+	// ActiveSessions are login sessions that haven't expired
+	ActiveSessions int64 `json:"active_sessions" db:"active_sessions"`
+	// SuspendedUsers are accounts with a suspension that hasn't expired
+	SuspendedUsers int64 `json:"suspended_users" db:"suspended_users"`
+}
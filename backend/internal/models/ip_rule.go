@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IPRuleAction is what an IP rule does to the requests it matches
+type IPRuleAction string
+
+const (
+	// IPRuleBlock refuses requests from the network
+	IPRuleBlock IPRuleAction = "block"
+	// IPRuleAllow serves requests from the network whatever the block
+	// rules and reputation providers say
+	IPRuleAllow IPRuleAction = "allow"
+)
+
+// Valid reports whether a is a known rule action
+func (a IPRuleAction) Valid() bool {
+	return a == IPRuleBlock || a == IPRuleAllow
+}
+
+// IPRule blocks or allows a network, given as a CIDR range or an
+// autonomous system number. Exactly one of CIDR and ASN is set.
+type IPRule struct {
+	ID        uuid.UUID    `json:"id" db:"id"`
+	Action    IPRuleAction `json:"action" db:"action"`
+	CIDR      *string      `json:"cidr,omitempty" db:"cidr"`
+	ASN       *int64       `json:"asn,omitempty" db:"asn"`
+	Reason    *string      `json:"reason,omitempty" db:"reason"`
+	CreatorID *uuid.UUID   `json:"creator_id,omitempty" db:"creator_id"`
+	CreatedAt time.Time    `json:"created_at" db:"created_at"`
+	// ExpiresAt is when the rule stops applying, if ever
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+}
+
+// CreateIPRuleRequest creates an IP rule. A single address is taken as a
+// range of one.
+type CreateIPRuleRequest struct {
+	Action    IPRuleAction `json:"action" validate:"required,oneof=block allow"`
+	CIDR      *string      `json:"cidr,omitempty"`
+	ASN       *int64       `json:"asn,omitempty" validate:"omitempty,min=1,max=4294967295"`
+	Reason    *string      `json:"reason,omitempty" validate:"omitempty,max=255"`
+	ExpiresAt *time.Time   `json:"expires_at,omitempty"`
+}
+
+// Why an IP verdict blocked a request
+const (
+	IPBlockedCIDR       = "cidr"
+	IPBlockedASN        = "asn"
+	IPBlockedReputation = "reputation"
+)
+
+// IPVerdict is whether requests from an address are served, and why
+type IPVerdict struct {
+	IP      string `json:"ip"`
+	Blocked bool   `json:"blocked"`
+	// Allowed is set when an allow rule matched, which overrides any block
+	Allowed bool `json:"allowed"`
+	// Reason is cidr, asn or reputation when Blocked
+	Reason string `json:"reason,omitempty"`
+	// RuleID is the admin rule that decided, if one did
+	RuleID *uuid.UUID `json:"rule_id,omitempty"`
+	// ASN is the address's autonomous system, if it was looked up
+	ASN *int64 `json:"asn,omitempty"`
+	// Provider is the reputation provider that listed the address
+	Provider string `json:"provider,omitempty"`
+}
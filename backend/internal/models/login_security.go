@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccountLockout is an account's recent failed logins and, once they pass
+// the threshold, how long it's locked for
+type AccountLockout struct {
+	UserID uuid.UUID `db:"user_id"`
+	// Failures counts failed logins since the last success or lockout
+	Failures      int        `db:"failures"`
+	LastFailureAt *time.Time `db:"last_failure_at"`
+	// Lockouts counts the lockouts since the last successful login. Each
+	// one lasts twice as long as the one before.
+	Lockouts    int        `db:"lockouts"`
+	LockedUntil *time.Time `db:"locked_until"`
+}
+
+// LoginDevice is a client an account has logged in from, identified by
+// its user agent with version numbers left out
+type LoginDevice struct {
+	UserID      uuid.UUID `db:"user_id"`
+	DeviceHash  string    `db:"device_hash"`
+	UserAgent   *string   `db:"user_agent"`
+	IPAddress   *string   `db:"ip_address"`
+	FirstSeenAt time.Time `db:"first_seen_at"`
+	LastSeenAt  time.Time `db:"last_seen_at"`
+}
+
+// PasswordReset is an emailed link to set a new password. Only the hash of
+// its token is stored, and a user has at most one.
+type PasswordReset struct {
+	UserID    uuid.UUID `db:"user_id"`
+	TokenHash string    `db:"token_hash"`
+	CreatedAt time.Time `db:"created_at"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemberVerificationFieldType is the kind of a membership screening field
+type MemberVerificationFieldType string
+
+const (
+	// MemberVerificationTerms lists the server's rules; submitting the form
+	// accepts them
+	MemberVerificationTerms     MemberVerificationFieldType = "terms"
+	MemberVerificationTextInput MemberVerificationFieldType = "text_input"
+	MemberVerificationParagraph MemberVerificationFieldType = "paragraph"
+)
+
+// Valid reports whether the field type is one servers may use
+func (t MemberVerificationFieldType) Valid() bool {
+	return t == MemberVerificationTerms || t == MemberVerificationTextInput || t == MemberVerificationParagraph
+}
+
+// MemberVerificationField is a rules list or a question in a server's
+// membership screening form
+type MemberVerificationField struct {
+	FieldType MemberVerificationFieldType `json:"field_type" validate:"required,oneof=terms text_input paragraph"`
+	Label     string                      `json:"label" validate:"required,max=300"`
+	Values    []string                    `json:"values,omitempty"` // The rules, for terms fields
+	Required  bool                        `json:"required"`
+}
+
+// MemberVerification is a server's membership screening form. Version
+// changes whenever the form does, so members can't accept rules they
+// haven't seen.
+type MemberVerification struct {
+	ServerID    uuid.UUID                 `json:"server_id"`
+	Enabled     bool                      `json:"enabled"`
+	Description *string                   `json:"description,omitempty"`
+	FormFields  []MemberVerificationField `json:"form_fields"`
+	Version     time.Time                 `json:"version"`
+}
+
+// DefaultMemberVerification is the form of a server that never set one up
+func DefaultMemberVerification(serverID uuid.UUID) *MemberVerification {
+	return &MemberVerification{ServerID: serverID, FormFields: []MemberVerificationField{}}
+}
+
+// UpdateMemberVerificationRequest is the input for changing a server's
+// screening form. Omitted fields are left alone.
+type UpdateMemberVerificationRequest struct {
+	Enabled     *bool                      `json:"enabled,omitempty"`
+	Description *string                    `json:"description,omitempty" validate:"omitempty,max=300"`
+	FormFields  *[]MemberVerificationField `json:"form_fields,omitempty" validate:"omitempty,max=5,dive"`
+}
+
+// SubmitMemberVerificationRequest is a pending member accepting the rules.
+// Responses answer the form's fields in order; terms fields take none.
+type SubmitMemberVerificationRequest struct {
+	Version   time.Time `json:"version" validate:"required"`
+	Responses []string  `json:"responses,omitempty"`
+}
+
+// MemberVerificationAnswer is a member's answer to one question
+type MemberVerificationAnswer struct {
+	Label    string `json:"label"`
+	Response string `json:"response"`
+}
+
+// MemberVerificationResponse is what a member submitted when they passed
+// screening
+type MemberVerificationResponse struct {
+	ServerID    uuid.UUID                  `json:"server_id"`
+	UserID      uuid.UUID                  `json:"user_id"`
+	Answers     []MemberVerificationAnswer `json:"answers"`
+	SubmittedAt time.Time                  `json:"submitted_at"`
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,6 +23,7 @@ const (
 	MessageTypePinned             MessageType = "pinned"
 	MessageTypeMemberJoin         MessageType = "member_join"
 	MessageTypeThreadCreated      MessageType = "thread_created"
+	MessageTypeAutoModAction      MessageType = "auto_moderation_action"
 )
 
 // Message represents a chat message
@@ -39,17 +43,59 @@ type Message struct {
 	Flags            int         `json:"flags" db:"flags"`
 	CreatedAt        time.Time   `json:"created_at" db:"created_at"`
 	EditedAt         *time.Time  `json:"edited_at,omitempty" db:"edited_at"`
+	// ForwardedFrom is set on messages forwarded from another message
+	ForwardedFrom *MessageForward `json:"forwarded_from,omitempty" db:"forwarded_from"`
+	// Embeds holds link previews, unfurled just after the message is sent
+	Embeds Embeds `json:"embeds,omitempty" db:"embeds"`
 
 	// Populated from joins/aggregations
 	Author        *PublicUser  `json:"author,omitempty"`
+	Member        *Member      `json:"member,omitempty"` // Author's server membership, for nicknames
 	Attachments   []Attachment `json:"attachments,omitempty"`
-	Embeds        []Embed      `json:"embeds,omitempty"`
 	Reactions     []Reaction   `json:"reactions,omitempty"`
 	Mentions      []uuid.UUID  `json:"mentions,omitempty"`
 	MentionRoles  []uuid.UUID  `json:"mention_roles,omitempty"`
 	ReferencedMsg *Message     `json:"referenced_message,omitempty"`
 }
 
+// MessageTombstone records a deleted message, without its content, for
+// moderation review until it's purged
+type MessageTombstone struct {
+	MessageID uuid.UUID `json:"message_id" db:"message_id"`
+	ChannelID uuid.UUID `json:"channel_id" db:"channel_id"`
+	AuthorID  uuid.UUID `json:"author_id" db:"author_id"`
+	DeletedBy uuid.UUID `json:"deleted_by" db:"deleted_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	DeletedAt time.Time `json:"deleted_at" db:"deleted_at"`
+}
+
+// MessageForward points a forwarded message back at the one it's a copy
+// of. It's kept as it was when the message was forwarded, so it outlives
+// the original being edited or deleted.
+type MessageForward struct {
+	MessageID uuid.UUID  `json:"message_id"`
+	ChannelID uuid.UUID  `json:"channel_id"`
+	ServerID  *uuid.UUID `json:"server_id,omitempty"`
+	AuthorID  uuid.UUID  `json:"author_id"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Value stores the forward as JSON
+func (f MessageForward) Value() (driver.Value, error) {
+	return json.Marshal(f)
+}
+
+// Scan reads a forward stored as JSON
+func (f *MessageForward) Scan(src interface{}) error {
+	switch data := src.(type) {
+	case []byte:
+		return json.Unmarshal(data, f)
+	case string:
+		return json.Unmarshal([]byte(data), f)
+	}
+	return errors.New("message forward must be JSON")
+}
+
 // MessageFlags
 const (
 	MessageFlagCrossposted          = 1 << 0
@@ -82,6 +128,31 @@ type Attachment struct {
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 }
 
+// Embeds are a message's embeds, stored as JSON
+type Embeds []Embed
+
+// Value stores the embeds as JSON, or NULL if there are none
+func (e Embeds) Value() (driver.Value, error) {
+	if len(e) == 0 {
+		return nil, nil
+	}
+	return json.Marshal([]Embed(e))
+}
+
+// Scan reads embeds stored as JSON
+func (e *Embeds) Scan(src interface{}) error {
+	switch data := src.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(data, (*[]Embed)(e))
+	case string:
+		return json.Unmarshal([]byte(data), (*[]Embed)(e))
+	}
+	return errors.New("message embeds must be JSON")
+}
+
 // Embed represents a rich embed in a message
 type Embed struct {
 	Type        string        `json:"type,omitempty"`
@@ -130,10 +201,11 @@ type EmbedField struct {
 
 // Reaction represents emoji reactions on a message
 type Reaction struct {
-	MessageID uuid.UUID `json:"message_id" db:"message_id"`
-	Emoji     string    `json:"emoji" db:"emoji"`
-	Count     int       `json:"count"`
-	Me        bool      `json:"me"` // Did the current user react
+	MessageID uuid.UUID     `json:"message_id" db:"message_id"`
+	Emoji     string        `json:"-" db:"emoji"` // Unicode emoji, or a custom emoji's ID
+	EmojiInfo ReactionEmoji `json:"emoji" db:"-"`
+	Count     int           `json:"count"`
+	Me        bool          `json:"me"` // Did the current user react
 }
 
 // ReactionUser tracks individual user reactions
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMessageForwardRoundTrip(t *testing.T) {
+	serverID := uuid.New()
+	forward := MessageForward{
+		MessageID: uuid.New(),
+		ChannelID: uuid.New(),
+		ServerID:  &serverID,
+		AuthorID:  uuid.New(),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	value, err := forward.Value()
+	if err != nil {
+		t.Fatalf("Value: %v", err)
+	}
+	var scanned MessageForward
+	if err := scanned.Scan(value); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if scanned.MessageID != forward.MessageID || *scanned.ServerID != serverID || !scanned.CreatedAt.Equal(forward.CreatedAt) {
+		t.Errorf("forward changed in storage: got %+v, want %+v", scanned, forward)
+	}
+
+	if err := scanned.Scan(42); err == nil {
+		t.Error("non-JSON values should be rejected")
+	}
+}
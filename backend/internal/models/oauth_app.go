@@ -0,0 +1,143 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OAuth token prefixes, so tokens issued to applications can be told apart
+// from user access tokens and server tokens
+const (
+	OAuthAccessTokenPrefix  = "hoa_"
+	OAuthRefreshTokenPrefix = "hor_"
+)
+
+// OAuth scopes users can grant applications
+const (
+	// OAuthScopeIdentify reads the user's own account
+	OAuthScopeIdentify = "identify"
+	// OAuthScopeServersRead lists the user's servers and reads them and
+	// their channels
+	OAuthScopeServersRead = "servers.read"
+	// OAuthScopeMessagesWrite sends messages as the user
+	OAuthScopeMessagesWrite = "messages.write"
+)
+
+// OAuthScopes are the scopes an application can ask for
+var OAuthScopes = []string{
+	OAuthScopeIdentify,
+	OAuthScopeServersRead,
+	OAuthScopeMessagesWrite,
+}
+
+// OAuthApplication is a third-party app users can authorize to act for them
+// with OAuth2, without giving it their password. Its ID is its client ID.
+type OAuthApplication struct {
+	ID               uuid.UUID `json:"id" db:"id"`
+	OwnerID          uuid.UUID `json:"owner_id" db:"owner_id"`
+	Name             string    `json:"name" db:"name"`
+	RedirectURIs     []string  `json:"redirect_uris"`
+	ClientSecretHash string    `json:"-" db:"client_secret_hash"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+
+	// ClientSecret is the secret itself, only returned when the
+	// application is created or its secret is reset
+	ClientSecret string `json:"client_secret,omitempty" db:"-"`
+}
+
+// CreateOAuthApplicationRequest registers an application
+type CreateOAuthApplicationRequest struct {
+	Name         string   `json:"name" validate:"required,max=100"`
+	RedirectURIs []string `json:"redirect_uris" validate:"required,min=1,max=10,dive,url"`
+}
+
+// OAuthAuthorizationCode is a one-time code a user's approval is traded
+// for, which the application exchanges for tokens
+type OAuthAuthorizationCode struct {
+	CodeHash      string    `db:"code_hash"`
+	ApplicationID uuid.UUID `db:"application_id"`
+	UserID        uuid.UUID `db:"user_id"`
+	// RedirectURI is the redirect_uri the authorization request named,
+	// empty if it relied on the application's only one
+	RedirectURI string `db:"redirect_uri"`
+	Scopes      []string
+	// CodeChallenge is the S256 PKCE challenge, if the application sent one
+	CodeChallenge *string   `db:"code_challenge"`
+	ExpiresAt     time.Time `db:"expires_at"`
+	CreatedAt     time.Time `db:"created_at"`
+}
+
+// OAuthToken is an access token a user granted an application, with the
+// refresh token that replaces it
+type OAuthToken struct {
+	ID               uuid.UUID `db:"id"`
+	ApplicationID    uuid.UUID `db:"application_id"`
+	UserID           uuid.UUID `db:"user_id"`
+	Scopes           []string
+	AccessTokenHash  string    `db:"access_token_hash"`
+	RefreshTokenHash string    `db:"refresh_token_hash"`
+	ExpiresAt        time.Time `db:"expires_at"`
+	CreatedAt        time.Time `db:"created_at"`
+}
+
+// HasScope reports whether the user granted scope
+func (t *OAuthToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// OAuthAuthorizeRequest asks a user to authorize an application. It carries
+// the query parameters of the authorization URL; Scope is a space
+// separated list of scopes.
+type OAuthAuthorizeRequest struct {
+	ClientID            string `json:"client_id" query:"client_id"`
+	RedirectURI         string `json:"redirect_uri" query:"redirect_uri"`
+	ResponseType        string `json:"response_type" query:"response_type"`
+	Scope               string `json:"scope" query:"scope"`
+	State               string `json:"state" query:"state"`
+	CodeChallenge       string `json:"code_challenge" query:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" query:"code_challenge_method"`
+}
+
+// OAuthAuthorization is what a user is asked to approve: which application
+// wants which scopes, and where they'll be sent back to
+type OAuthAuthorization struct {
+	Application *OAuthApplication `json:"application"`
+	Scopes      []string          `json:"scopes"`
+	RedirectURI string            `json:"redirect_uri"`
+}
+
+// AuthorizedOAuthApplication is an application a user has granted access,
+// with the scopes it has
+type AuthorizedOAuthApplication struct {
+	Application *OAuthApplication `json:"application"`
+	Scopes      []string          `json:"scopes"`
+	GrantedAt   time.Time         `json:"granted_at"`
+}
+
+// OAuthTokenRequest is a request to the token endpoint, sent as a form as
+// OAuth2 clients do
+type OAuthTokenRequest struct {
+	GrantType    string `json:"grant_type" form:"grant_type"`
+	Code         string `json:"code" form:"code"`
+	RedirectURI  string `json:"redirect_uri" form:"redirect_uri"`
+	CodeVerifier string `json:"code_verifier" form:"code_verifier"`
+	RefreshToken string `json:"refresh_token" form:"refresh_token"`
+	ClientID     string `json:"client_id" form:"client_id"`
+	ClientSecret string `json:"client_secret" form:"client_secret"`
+}
+
+// OAuthTokenResponse is the token endpoint's answer
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	// Scope is a space separated list of the granted scopes
+	Scope string `json:"scope"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OAuthIdentity links an account at an external login provider to the user
+// it logs in as
+type OAuthIdentity struct {
+	Provider string    `json:"provider" db:"provider"`
+	Subject  string    `json:"subject" db:"subject"`
+	UserID   uuid.UUID `json:"user_id" db:"user_id"`
+	// Email is what the provider reported when the identity was linked
+	Email       *string    `json:"email,omitempty" db:"email"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty" db:"last_login_at"`
+}
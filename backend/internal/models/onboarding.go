@@ -0,0 +1,108 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WelcomeChannel is a channel a server's welcome screen points new members
+// to
+type WelcomeChannel struct {
+	ChannelID   uuid.UUID `json:"channel_id" validate:"required"`
+	Description string    `json:"description" validate:"required,max=50"`
+	Emoji       *string   `json:"emoji,omitempty"`
+}
+
+// WelcomeScreen is what a server shows members when they join
+type WelcomeScreen struct {
+	ServerID        uuid.UUID        `json:"server_id"`
+	Enabled         bool             `json:"enabled"`
+	Description     *string          `json:"description,omitempty"`
+	WelcomeChannels []WelcomeChannel `json:"welcome_channels"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+}
+
+// DefaultWelcomeScreen is the welcome screen of a server that never set
+// one up
+func DefaultWelcomeScreen(serverID uuid.UUID) *WelcomeScreen {
+	return &WelcomeScreen{ServerID: serverID, WelcomeChannels: []WelcomeChannel{}}
+}
+
+// UpdateWelcomeScreenRequest is the input for changing a server's welcome
+// screen. Omitted fields are left alone.
+type UpdateWelcomeScreenRequest struct {
+	Enabled         *bool             `json:"enabled,omitempty"`
+	Description     *string           `json:"description,omitempty" validate:"omitempty,max=140"`
+	WelcomeChannels *[]WelcomeChannel `json:"welcome_channels,omitempty" validate:"omitempty,max=5,dive"`
+}
+
+// OnboardingOption is one answer to an onboarding prompt. Picking it gives
+// the member its roles and opts them into its channels.
+type OnboardingOption struct {
+	ID          uuid.UUID   `json:"id"`
+	Title       string      `json:"title" validate:"required,max=100"`
+	Description *string     `json:"description,omitempty" validate:"omitempty,max=100"`
+	Emoji       *string     `json:"emoji,omitempty"`
+	RoleIDs     []uuid.UUID `json:"role_ids"`
+	ChannelIDs  []uuid.UUID `json:"channel_ids"`
+}
+
+// OnboardingPrompt is a question members answer to pick their roles and
+// channels
+type OnboardingPrompt struct {
+	ID           uuid.UUID          `json:"id"`
+	Title        string             `json:"title" validate:"required,max=100"`
+	SingleSelect bool               `json:"single_select"`
+	Required     bool               `json:"required"`
+	Options      []OnboardingOption `json:"options" validate:"required,min=1,max=50,dive"`
+}
+
+// Onboarding is a server's onboarding prompts
+type Onboarding struct {
+	ServerID  uuid.UUID          `json:"server_id"`
+	Enabled   bool               `json:"enabled"`
+	Prompts   []OnboardingPrompt `json:"prompts"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+// DefaultOnboarding is the onboarding of a server that never set it up
+func DefaultOnboarding(serverID uuid.UUID) *Onboarding {
+	return &Onboarding{ServerID: serverID, Prompts: []OnboardingPrompt{}}
+}
+
+// Option returns the prompt and option with the given option ID, or nils
+func (o *Onboarding) Option(id uuid.UUID) (*OnboardingPrompt, *OnboardingOption) {
+	for i := range o.Prompts {
+		for j := range o.Prompts[i].Options {
+			if o.Prompts[i].Options[j].ID == id {
+				return &o.Prompts[i], &o.Prompts[i].Options[j]
+			}
+		}
+	}
+	return nil, nil
+}
+
+// UpdateOnboardingRequest is the input for changing a server's onboarding.
+// Omitted fields are left alone. Prompts and options without an ID get one.
+type UpdateOnboardingRequest struct {
+	Enabled *bool               `json:"enabled,omitempty"`
+	Prompts *[]OnboardingPrompt `json:"prompts,omitempty" validate:"omitempty,max=15,dive"`
+}
+
+// SubmitOnboardingRequest is a member's answers to a server's prompts. It
+// replaces their earlier answers.
+type SubmitOnboardingRequest struct {
+	OptionIDs []uuid.UUID `json:"option_ids"`
+}
+
+// OnboardingResponses are the options a member picked. RoleIDs are the
+// roles onboarding gave them, taken away again if they pick differently.
+type OnboardingResponses struct {
+	ServerID   uuid.UUID   `json:"server_id"`
+	UserID     uuid.UUID   `json:"user_id"`
+	OptionIDs  []uuid.UUID `json:"option_ids"`
+	RoleIDs    []uuid.UUID `json:"role_ids"`
+	ChannelIDs []uuid.UUID `json:"channel_ids"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
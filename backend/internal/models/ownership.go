@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OwnershipTransfer is an ownership transfer waiting for the new owner to
+// accept it
+type OwnershipTransfer struct {
+	ServerID   uuid.UUID `json:"server_id" db:"server_id"`
+	FromUserID uuid.UUID `json:"from_user_id" db:"from_user_id"`
+	ToUserID   uuid.UUID `json:"to_user_id" db:"to_user_id"`
+	// KeepCoOwner makes the outgoing owner a co-owner once it's accepted
+	KeepCoOwner bool      `json:"keep_co_owner" db:"keep_co_owner"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// Expired reports whether the transfer can no longer be accepted
+func (t *OwnershipTransfer) Expired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// OwnershipTransferRequest starts an ownership transfer
+type OwnershipTransferRequest struct {
+	NewOwnerID  uuid.UUID `json:"new_owner_id"`
+	KeepCoOwner bool      `json:"keep_co_owner"`
+}
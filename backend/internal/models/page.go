@@ -0,0 +1,105 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidPageCursor is returned for cursor tokens that weren't handed
+// out for the list they're used with
+var ErrInvalidPageCursor = errors.New("invalid cursor")
+
+// Keyset-paginated lists. A cursor names the list it pages so one can't be
+// passed to another.
+const (
+	PageMembers = "members"
+	PageBans    = "bans"
+	PageInvites = "invites"
+	PageRoles   = "roles"
+	PageSearch  = "search"
+)
+
+// pageSort is how a list sorts: by position or else by time, then by a
+// UUID key unless it's keyed by something else
+type pageSort struct {
+	byPosition bool
+	uuidKey    bool
+}
+
+var pageSorts = map[string]pageSort{
+	PageMembers: {uuidKey: true},
+	PageBans:    {uuidKey: true},
+	PageInvites: {},
+	PageRoles:   {byPosition: true, uuidKey: true},
+	PageSearch:  {uuidKey: true},
+}
+
+// PageCursor is where a page starts: just after the row with these sort
+// keys, or just before it when paging backward. Lists sort by a time or a
+// position, then by the row's ID (or an invite's code) to break ties.
+// Clients only see cursors as opaque tokens.
+type PageCursor struct {
+	List     string     `json:"l"`
+	Time     *time.Time `json:"t,omitempty"`
+	Position *int       `json:"p,omitempty"`
+	Key      string     `json:"k"`
+	Backward bool       `json:"b,omitempty"`
+}
+
+// SortValue is the value of the list's sort column at the cursor
+func (c *PageCursor) SortValue() interface{} {
+	if c.Time != nil {
+		return *c.Time
+	}
+	return *c.Position
+}
+
+// Encode returns the cursor as a token for clients
+func (c PageCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodePageCursor reads a token Encode returned for list. Tokens for
+// another list, or whose sort keys aren't the kind list sorts by, are
+// refused before they reach a query.
+func DecodePageCursor(token, list string) (*PageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidPageCursor
+	}
+	var cursor PageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidPageCursor
+	}
+	sort, ok := pageSorts[list]
+	if !ok || cursor.List != list || cursor.Key == "" || (cursor.Position != nil) != sort.byPosition || (cursor.Time != nil) == sort.byPosition {
+		return nil, ErrInvalidPageCursor
+	}
+	if sort.uuidKey {
+		if _, err := uuid.Parse(cursor.Key); err != nil {
+			return nil, ErrInvalidPageCursor
+		}
+	}
+	return &cursor, nil
+}
+
+// PageRequest asks for up to Limit rows starting at Cursor, or the first
+// page if Cursor is nil
+type PageRequest struct {
+	Cursor *PageCursor
+	Limit  int
+}
+
+// Page is one page of a keyset-paginated list. Pass NextCursor or
+// PrevCursor back as cursor for the pages either side; each is left out
+// when there's nothing that way.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageCursorRoundTrip(t *testing.T) {
+	at := time.Date(2026, 2, 14, 12, 0, 0, 0, time.UTC)
+	cursor := PageCursor{List: PageMembers, Time: &at, Key: uuid.NewString(), Backward: true}
+
+	decoded, err := DecodePageCursor(cursor.Encode(), PageMembers)
+	require.NoError(t, err)
+	assert.Equal(t, cursor, *decoded)
+	assert.Equal(t, at, decoded.SortValue())
+
+	position := 3
+	decoded, err = DecodePageCursor(PageCursor{List: PageRoles, Position: &position, Key: uuid.NewString()}.Encode(), PageRoles)
+	require.NoError(t, err)
+	assert.Equal(t, 3, decoded.SortValue())
+
+	// Invites are keyed by code
+	_, err = DecodePageCursor(PageCursor{List: PageInvites, Time: &at, Key: "aB3dE6gH"}.Encode(), PageInvites)
+	assert.NoError(t, err)
+}
+
+func TestDecodePageCursorRejects(t *testing.T) {
+	at := time.Now()
+	position := 1
+	key := uuid.NewString()
+	for name, token := range map[string]string{
+		"not base64":    "%%%",
+		"not json":      "bm9wZQ",
+		"another list":  PageCursor{List: PageBans, Time: &at, Key: key}.Encode(),
+		"no key":        PageCursor{List: PageMembers, Time: &at}.Encode(),
+		"no sort value": PageCursor{List: PageMembers, Key: key}.Encode(),
+		"both values":   PageCursor{List: PageMembers, Time: &at, Position: &position, Key: key}.Encode(),
+		"position":      PageCursor{List: PageMembers, Position: &position, Key: key}.Encode(),
+		"key not uuid":  PageCursor{List: PageMembers, Time: &at, Key: "k'; --"}.Encode(),
+	} {
+		_, err := DecodePageCursor(token, PageMembers)
+		assert.ErrorIs(t, err, ErrInvalidPageCursor, name)
+	}
+
+	// Roles sort by position, not time
+	_, err := DecodePageCursor(PageCursor{List: PageRoles, Time: &at, Key: key}.Encode(), PageRoles)
+	assert.ErrorIs(t, err, ErrInvalidPageCursor)
+	_, err = DecodePageCursor(PageCursor{List: "channels", Time: &at, Key: key}.Encode(), "channels")
+	assert.ErrorIs(t, err, ErrInvalidPageCursor, "unknown lists have no cursors")
+}
@@ -8,12 +8,14 @@ import (
 
 // Presence represents a user's online status (uses PresenceStatus from user.go)
 type Presence struct {
-	UserID       uuid.UUID      `json:"user_id" db:"user_id"`
-	Status       PresenceStatus `json:"status" db:"status"`
-	CustomStatus *string        `json:"custom_status,omitempty" db:"custom_status"`
-	Activities   []Activity     `json:"activities,omitempty"`
-	ClientStatus *ClientStatus  `json:"client_status,omitempty"`
-	UpdatedAt    time.Time      `json:"updated_at" db:"updated_at"`
+	UserID                uuid.UUID      `json:"user_id" db:"user_id"`
+	Status                PresenceStatus `json:"status" db:"status"`
+	CustomStatus          *string        `json:"custom_status,omitempty" db:"custom_status"`
+	CustomStatusEmoji     *string        `json:"custom_status_emoji,omitempty" db:"-"`
+	CustomStatusExpiresAt *time.Time     `json:"custom_status_expires_at,omitempty" db:"-"`
+	Activities            []Activity     `json:"activities,omitempty"`
+	ClientStatus          *ClientStatus  `json:"client_status,omitempty"`
+	UpdatedAt             time.Time      `json:"updated_at" db:"updated_at"`
 }
 
 // ClientStatus represents status per client
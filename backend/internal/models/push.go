@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PushPlatform identifies the push service a device is registered with
+type PushPlatform string
+
+const (
+	PushPlatformFCM     PushPlatform = "fcm"
+	PushPlatformAPNs    PushPlatform = "apns"
+	PushPlatformWebPush PushPlatform = "webpush"
+)
+
+// Valid reports whether p is a supported push platform
+func (p PushPlatform) Valid() bool {
+	switch p {
+	case PushPlatformFCM, PushPlatformAPNs, PushPlatformWebPush:
+		return true
+	}
+	return false
+}
+
+// PushDevice is a device registered to receive push notifications
+type PushDevice struct {
+	ID       uuid.UUID    `json:"id" db:"id"`
+	UserID   uuid.UUID    `json:"user_id" db:"user_id"`
+	Platform PushPlatform `json:"platform" db:"platform"`
+	// Token is the FCM or APNs device token, or the WebPush endpoint URL
+	Token string `json:"-" db:"token"`
+
+	// WebPush subscription keys (base64url)
+	P256dh *string `json:"-" db:"p256dh"`
+	Auth   *string `json:"-" db:"auth"`
+
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at" db:"last_seen_at"`
+}
+
+// RegisterDeviceRequest registers a device for push notifications
+type RegisterDeviceRequest struct {
+	Platform PushPlatform `json:"platform" validate:"required,oneof=fcm apns webpush"`
+	Token    string       `json:"token" validate:"required,max=4096"`
+	Keys     *struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys,omitempty"`
+}
+
+// NotificationLevel controls which messages in a channel notify a user
+type NotificationLevel string
+
+const (
+	NotificationLevelDefault  NotificationLevel = "default"  // Follow the user's settings
+	NotificationLevelAll      NotificationLevel = "all"      // Every message
+	NotificationLevelMentions NotificationLevel = "mentions" // Only mentions
+	NotificationLevelNone     NotificationLevel = "none"     // Nothing
+)
+
+// Valid reports whether l is a known notification level
+func (l NotificationLevel) Valid() bool {
+	switch l {
+	case NotificationLevelDefault, NotificationLevelAll, NotificationLevelMentions, NotificationLevelNone:
+		return true
+	}
+	return false
+}
+
+// ChannelNotificationSettings are a user's notification overrides for a channel
+type ChannelNotificationSettings struct {
+	UserID     uuid.UUID         `json:"user_id" db:"user_id"`
+	ChannelID  uuid.UUID         `json:"channel_id" db:"channel_id"`
+	Level      NotificationLevel `json:"level" db:"level"`
+	MutedUntil *time.Time        `json:"muted_until,omitempty" db:"muted_until"`
+	UpdatedAt  time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// Muted reports whether the channel is muted at the given time
+func (s *ChannelNotificationSettings) Muted(now time.Time) bool {
+	return s.MutedUntil != nil && s.MutedUntil.After(now)
+}
+
+// UpdateChannelNotificationSettingsRequest replaces a channel's notification
+// overrides. An empty level means default; omitting muted_until unmutes.
+type UpdateChannelNotificationSettingsRequest struct {
+	Level      NotificationLevel `json:"level" validate:"required,oneof=default all mentions none"`
+	MutedUntil *time.Time        `json:"muted_until,omitempty"`
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// QuotaTier is a group of accounts instance admins can set quotas for
+type QuotaTier string
+
+const (
+	// QuotaTierDefault applies to every account, in place of the static
+	// config
+	QuotaTierDefault QuotaTier = "default"
+	// QuotaTierPremium applies to accounts with the premium flag, on top of
+	// the default tier
+	QuotaTierPremium QuotaTier = "premium"
+	// QuotaTierBot applies to bot accounts, on top of the default tier
+	QuotaTierBot QuotaTier = "bot"
+)
+
+// QuotaTiers lists the tiers in the order they apply
+var QuotaTiers = []QuotaTier{QuotaTierDefault, QuotaTierPremium, QuotaTierBot}
+
+// Valid reports whether t is one of QuotaTiers
+func (t QuotaTier) Valid() bool {
+	for _, tier := range QuotaTiers {
+		if t == tier {
+			return true
+		}
+	}
+	return false
+}
+
+// QuotaTierOf returns the tier a user's quotas come from. Bots are in the
+// bot tier even if they have the premium flag.
+func QuotaTierOf(user *User) QuotaTier {
+	switch {
+	case user.Flags&UserFlagBot != 0:
+		return QuotaTierBot
+	case user.Flags&UserFlagPremium != 0:
+		return QuotaTierPremium
+	default:
+		return QuotaTierDefault
+	}
+}
+
+// QuotaLimits are the quotas instance admins can change without a restart.
+// 0 means unlimited.
+type QuotaLimits struct {
+	MaxServersOwned  int   `json:"max_servers_owned"`
+	MaxServersJoined int   `json:"max_servers_joined"`
+	MaxMessageLength int   `json:"max_message_length"`
+	StorageMB        int64 `json:"storage_mb"`
+	MaxFileSizeMB    int64 `json:"max_file_size_mb"`
+}
+
+// Limits returns the config's values for the quotas instance admins can
+// override
+func (c *QuotaConfig) Limits() QuotaLimits {
+	return QuotaLimits{
+		MaxServersOwned:  c.Servers.MaxServersOwned,
+		MaxServersJoined: c.Servers.MaxServersJoined,
+		MaxMessageLength: c.Messages.MaxMessageLength,
+		StorageMB:        c.Storage.UserStorageMB,
+		MaxFileSizeMB:    c.Storage.MaxFileSizeMB,
+	}
+}
+
+// QuotaOverride is a tier's quotas, with the limits instance admins set
+// for it in place of the tiers below
+type QuotaOverride struct {
+	Tier QuotaTier `json:"tier" db:"tier"`
+	// The limits below override the tiers below when set
+	MaxServersOwned  *int   `json:"max_servers_owned" db:"max_servers_owned"`
+	MaxServersJoined *int   `json:"max_servers_joined" db:"max_servers_joined"`
+	MaxMessageLength *int   `json:"max_message_length" db:"max_message_length"`
+	StorageMB        *int64 `json:"storage_mb" db:"storage_mb"`
+	MaxFileSizeMB    *int64 `json:"max_file_size_mb" db:"max_file_size_mb"`
+	// Limits are the limits that apply to the tier after overrides
+	Limits    QuotaLimits `json:"limits" db:"-"`
+	UpdatedBy *uuid.UUID  `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt *time.Time  `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// Apply returns base with the tier's overrides in place
+func (o *QuotaOverride) Apply(base QuotaLimits) QuotaLimits {
+	limits := base
+	if o == nil {
+		return limits
+	}
+	if o.MaxServersOwned != nil {
+		limits.MaxServersOwned = *o.MaxServersOwned
+	}
+	if o.MaxServersJoined != nil {
+		limits.MaxServersJoined = *o.MaxServersJoined
+	}
+	if o.MaxMessageLength != nil {
+		limits.MaxMessageLength = *o.MaxMessageLength
+	}
+	if o.StorageMB != nil {
+		limits.StorageMB = *o.StorageMB
+	}
+	if o.MaxFileSizeMB != nil {
+		limits.MaxFileSizeMB = *o.MaxFileSizeMB
+	}
+	return limits
+}
+
+// QuotaSettings are the instance's quotas: the static config and every
+// tier with its overrides
+type QuotaSettings struct {
+	// Config is what the static config sets, before any overrides
+	Config QuotaLimits      `json:"config"`
+	Tiers  []*QuotaOverride `json:"tiers"`
+}
+
+// SetQuotaOverrideRequest overrides a tier's quotas. Limits left out use
+// the tiers below.
+type SetQuotaOverrideRequest struct {
+	MaxServersOwned  *int   `json:"max_servers_owned" validate:"omitempty,min=0"`
+	MaxServersJoined *int   `json:"max_servers_joined" validate:"omitempty,min=0"`
+	MaxMessageLength *int   `json:"max_message_length" validate:"omitempty,min=0"`
+	StorageMB        *int64 `json:"storage_mb" validate:"omitempty,min=0"`
+	MaxFileSizeMB    *int64 `json:"max_file_size_mb" validate:"omitempty,min=0"`
+}
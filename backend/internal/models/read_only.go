@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReadOnlyMode is whether the instance refuses writes, e.g. while it fails
+// over or its data is repaired. Reads keep working.
+type ReadOnlyMode struct {
+	Enabled   bool       `json:"enabled"`
+	Reason    *string    `json:"reason,omitempty"`
+	EnabledBy *uuid.UUID `json:"enabled_by,omitempty"`
+	Since     *time.Time `json:"since,omitempty"`
+}
+
+// SetReadOnlyModeRequest turns read-only mode on or off. Reason is shown to
+// users while it's on.
+type SetReadOnlyModeRequest struct {
+	Enabled bool    `json:"enabled"`
+	Reason  *string `json:"reason,omitempty" validate:"omitempty,max=512"`
+}
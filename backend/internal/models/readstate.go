@@ -18,7 +18,7 @@ type ReadState struct {
 // ReadStateWithUnread includes the unread message count
 type ReadStateWithUnread struct {
 	ReadState
-	UnreadCount int `json:"unread_count"`
+	UnreadCount int `json:"unread_count" db:"unread_count"`
 }
 
 // ChannelUnreadInfo provides unread information for a channel
@@ -0,0 +1,39 @@
+package models
+
+import "github.com/google/uuid"
+
+// Relationship types, as in GET /users/@me/relationships
+const (
+	RelationshipFriend     = 1
+	RelationshipBlocked    = 2
+	RelationshipPendingIn  = 3
+	RelationshipPendingOut = 4
+)
+
+// ReadyState is what a client needs to render once connected: the user,
+// their servers, DMs, read states and relationships. The gateway sends it
+// in READY so clients don't have to fetch each over REST.
+type ReadyState struct {
+	User            *User                 `json:"user"`
+	Guilds          []*ReadyGuild         `json:"guilds"`
+	PrivateChannels []*Channel            `json:"private_channels"`
+	ReadStates      []ReadStateWithUnread `json:"read_states"`
+	Relationships   []Relationship        `json:"relationships"`
+}
+
+// ReadyGuild is a server in READY, with its channels and roles and the
+// user's own membership
+type ReadyGuild struct {
+	*Server
+	Channels []*Channel `json:"channels"`
+	Roles    []*Role    `json:"roles"`
+	Member   *Member    `json:"member,omitempty"`
+}
+
+// Relationship is another user the user has befriended, blocked or has a
+// pending friend request with
+type Relationship struct {
+	ID   uuid.UUID  `json:"id"`
+	Type int        `json:"type"`
+	User PublicUser `json:"user"`
+}
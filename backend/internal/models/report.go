@@ -0,0 +1,163 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReportTargetType is what kind of thing a report is about
+type ReportTargetType string
+
+const (
+	ReportTargetMessage ReportTargetType = "message"
+	ReportTargetUser    ReportTargetType = "user"
+	ReportTargetServer  ReportTargetType = "server"
+)
+
+// Valid reports whether t is a known target type
+func (t ReportTargetType) Valid() bool {
+	return t == ReportTargetMessage || t == ReportTargetUser || t == ReportTargetServer
+}
+
+// ReportCategory is why something was reported
+type ReportCategory string
+
+const (
+	ReportSpam          ReportCategory = "spam"
+	ReportHarassment    ReportCategory = "harassment"
+	ReportHateSpeech    ReportCategory = "hate_speech"
+	ReportViolence      ReportCategory = "violence"
+	ReportSexualContent ReportCategory = "sexual_content"
+	ReportSelfHarm      ReportCategory = "self_harm"
+	ReportIllegal       ReportCategory = "illegal"
+	ReportImpersonation ReportCategory = "impersonation"
+	ReportOther         ReportCategory = "other"
+)
+
+// ReportCategories are the categories users can choose from
+var ReportCategories = []ReportCategory{
+	ReportSpam, ReportHarassment, ReportHateSpeech, ReportViolence,
+	ReportSexualContent, ReportSelfHarm, ReportIllegal, ReportImpersonation,
+	ReportOther,
+}
+
+// Valid reports whether c is a known category
+func (c ReportCategory) Valid() bool {
+	for _, category := range ReportCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// ReportStatus is where a report is in the review queue. Reports start
+// open, move to reviewing when an admin picks them up, and end actioned
+// or dismissed.
+type ReportStatus string
+
+const (
+	ReportOpen      ReportStatus = "open"
+	ReportReviewing ReportStatus = "reviewing"
+	ReportActioned  ReportStatus = "actioned"
+	ReportDismissed ReportStatus = "dismissed"
+)
+
+// Valid reports whether s is a known status
+func (s ReportStatus) Valid() bool {
+	return s == ReportOpen || s == ReportReviewing || s == ReportActioned || s == ReportDismissed
+}
+
+// Resolved reports whether s ends a review
+func (s ReportStatus) Resolved() bool {
+	return s == ReportActioned || s == ReportDismissed
+}
+
+// Report is a user's report of a message, user or server to the
+// instance's admins
+type Report struct {
+	ID         uuid.UUID        `json:"id" db:"id"`
+	ReporterID *uuid.UUID       `json:"reporter_id,omitempty" db:"reporter_id"`
+	TargetType ReportTargetType `json:"target_type" db:"target_type"`
+	TargetID   uuid.UUID        `json:"target_id" db:"target_id"`
+	Category   ReportCategory   `json:"category" db:"category"`
+	Details    *string          `json:"details,omitempty" db:"details"`
+	// TargetUserID is the reported user, or the reported message's author
+	TargetUserID *uuid.UUID `json:"target_user_id,omitempty" db:"target_user_id"`
+	ServerID     *uuid.UUID `json:"server_id,omitempty" db:"server_id"`
+	ChannelID    *uuid.UUID `json:"channel_id,omitempty" db:"channel_id"`
+	// MessageContent is the reported message as it was when reported
+	MessageContent *string      `json:"message_content,omitempty" db:"message_content"`
+	Status         ReportStatus `json:"status" db:"status"`
+	AssigneeID     *uuid.UUID   `json:"assignee_id,omitempty" db:"assignee_id"`
+	CreatedAt      time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at" db:"updated_at"`
+	ResolvedAt     *time.Time   `json:"resolved_at,omitempty" db:"resolved_at"`
+}
+
+// ReportAction is what a ReportEvent records
+type ReportAction string
+
+const (
+	ReportActionCreated    ReportAction = "created"
+	ReportActionStatus     ReportAction = "status"
+	ReportActionAssigned   ReportAction = "assigned"
+	ReportActionUnassigned ReportAction = "unassigned"
+	ReportActionNote       ReportAction = "note"
+)
+
+// ReportEvent is an entry in a report's history: who changed its status or
+// assignee, or added a note, and when
+type ReportEvent struct {
+	ID         uuid.UUID     `json:"id" db:"id"`
+	ReportID   uuid.UUID     `json:"report_id" db:"report_id"`
+	ActorID    *uuid.UUID    `json:"actor_id,omitempty" db:"actor_id"`
+	Action     ReportAction  `json:"action" db:"action"`
+	Status     *ReportStatus `json:"status,omitempty" db:"status"`
+	AssigneeID *uuid.UUID    `json:"assignee_id,omitempty" db:"assignee_id"`
+	Note       *string       `json:"note,omitempty" db:"note"`
+	CreatedAt  time.Time     `json:"created_at" db:"created_at"`
+}
+
+// ReportDetail is a report with its history, oldest first
+type ReportDetail struct {
+	*Report
+	Events []*ReportEvent `json:"events"`
+}
+
+// CreateReportRequest reports a message, user or server
+type CreateReportRequest struct {
+	TargetType ReportTargetType `json:"target_type" validate:"required,oneof=message user server"`
+	TargetID   uuid.UUID        `json:"target_id" validate:"required"`
+	Category   ReportCategory   `json:"category" validate:"required,oneof=spam harassment hate_speech violence sexual_content self_harm illegal impersonation other"`
+	Details    *string          `json:"details,omitempty" validate:"omitempty,max=1000"`
+}
+
+// ReportFilter picks reports from the queue. Reports come oldest first;
+// After is the last report of the previous page.
+type ReportFilter struct {
+	Status     ReportStatus
+	AssigneeID *uuid.UUID
+	TargetType ReportTargetType
+	TargetID   *uuid.UUID
+	After      *uuid.UUID
+	Limit      int
+}
+
+// UpdateReportStatusRequest moves a report along the queue. Note is kept in
+// the report's history.
+type UpdateReportStatusRequest struct {
+	Status ReportStatus `json:"status" validate:"required,oneof=open reviewing actioned dismissed"`
+	Note   *string      `json:"note,omitempty" validate:"omitempty,max=2000"`
+}
+
+// AssignReportRequest gives a report to an instance admin
+type AssignReportRequest struct {
+	AssigneeID uuid.UUID `json:"assignee_id" validate:"required"`
+}
+
+// ReportNoteRequest adds a note to a report's history
+type ReportNoteRequest struct {
+	Note string `json:"note" validate:"required,max=2000"`
+}
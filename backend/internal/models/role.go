@@ -20,6 +20,7 @@ type Role struct {
 	IsDefault    bool      `json:"is_default" db:"is_default"`   // @everyone role
 	IconURL      *string   `json:"icon_url,omitempty" db:"icon_url"`
 	UnicodeEmoji *string   `json:"unicode_emoji,omitempty" db:"unicode_emoji"`
+	Version      int       `json:"version" db:"version"` // Bumped on every update
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 }
 
@@ -132,8 +133,8 @@ type MemberRole struct {
 
 // CalculatePermissions computes effective permissions for a member
 func CalculatePermissions(member *Member, roles []*Role, server *Server, channel *Channel, overrides []PermissionOverride) int64 {
-	// Server owner has all permissions
-	if member.UserID == server.OwnerID {
+	// Server owner and co-owners have all permissions
+	if member.UserID == server.OwnerID || member.CoOwner {
 		return PermissionAll | PermAdministrator
 	}
 
@@ -141,9 +142,10 @@ func CalculatePermissions(member *Member, roles []*Role, server *Server, channel
 	var permissions int64 = 0
 	
 	// Find @everyone role and add its permissions
+	var everyoneID uuid.UUID
 	for _, role := range roles {
-		// @everyone role has same ID as server
-		if role.ID == server.ID {
+		if isEveryoneRole(role, server) {
+			everyoneID = role.ID
 			permissions = role.Permissions
 			break
 		}
@@ -167,7 +169,7 @@ func CalculatePermissions(member *Member, roles []*Role, server *Server, channel
 	if channel != nil && len(overrides) > 0 {
 		// First, apply @everyone overrides
 		for _, override := range overrides {
-			if override.TargetType == "role" && override.TargetID == server.ID {
+			if override.TargetType == "role" && (override.TargetID == server.ID || override.TargetID == everyoneID) {
 				permissions &= ^override.Deny
 				permissions |= override.Allow
 			}
@@ -198,3 +200,9 @@ func CalculatePermissions(member *Member, roles []*Role, server *Server, channel
 
 	return permissions
 }
+
+// isEveryoneRole reports whether role is the server's @everyone role. Older
+// servers gave it the server's ID; newer ones flag it as the default role.
+func isEveryoneRole(role *Role, server *Server) bool {
+	return role.IsDefault || role.ID == server.ID
+}
@@ -25,6 +25,10 @@ type Server struct {
 	VanityURLCode         *string    `json:"vanity_url_code,omitempty" db:"vanity_url_code"`
 	CreatedAt             time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt             time.Time  `json:"updated_at" db:"updated_at"`
+	Version               int        `json:"version" db:"version"` // Bumped on every update
+
+	// WelcomeScreen is only set on the server returned to a member joining it
+	WelcomeScreen *WelcomeScreen `json:"welcome_screen,omitempty" db:"-"`
 }
 
 // VerificationLevel constants
@@ -80,10 +84,15 @@ type Member struct {
 	Mute         bool       `json:"mute" db:"mute"`
 	Pending      bool       `json:"pending" db:"pending"`
 	Temporary    bool       `json:"temporary" db:"temporary"`
+	CoOwner      bool       `json:"co_owner" db:"co_owner"`
+
+	// CommunicationDisabledUntil is set while the member is timed out
+	CommunicationDisabledUntil *time.Time `json:"communication_disabled_until,omitempty" db:"communication_disabled_until"`
 
 	// Populated from joins
-	User  *PublicUser `json:"user,omitempty"`
-	Roles []uuid.UUID `json:"roles,omitempty"`
+	User     *PublicUser `json:"user,omitempty"`
+	Roles    []uuid.UUID `json:"roles,omitempty"`
+	Presence *Presence   `json:"presence,omitempty"`
 }
 
 // DisplayName returns the member's display name (nickname or username)
@@ -97,6 +106,11 @@ func (m *Member) DisplayName(user *User) string {
 	return ""
 }
 
+// TimedOut reports whether the member is timed out at now
+func (m *Member) TimedOut(now time.Time) bool {
+	return m.CommunicationDisabledUntil != nil && m.CommunicationDisabledUntil.After(now)
+}
+
 // Ban represents a server ban
 type Ban struct {
 	ServerID  uuid.UUID  `json:"server_id" db:"server_id"`
@@ -104,11 +118,17 @@ type Ban struct {
 	Reason    *string    `json:"reason,omitempty" db:"reason"`
 	BannedBy  *uuid.UUID `json:"banned_by,omitempty" db:"banned_by"`
 	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"` // nil for permanent bans
 
 	// Populated from joins
 	User *PublicUser `json:"user,omitempty"`
 }
 
+// Active reports whether the ban is still in force at now
+func (b *Ban) Active(now time.Time) bool {
+	return b.ExpiresAt == nil || b.ExpiresAt.After(now)
+}
+
 // Invite represents a server invite
 type Invite struct {
 	Code      string     `json:"code" db:"code"`
@@ -7,6 +7,38 @@ import (
 	"github.com/google/uuid"
 )
 
+func TestMemberTimedOut(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	if (&Member{}).TimedOut(now) {
+		t.Error("member without a timeout should not be timed out")
+	}
+	if (&Member{CommunicationDisabledUntil: &past}).TimedOut(now) {
+		t.Error("expired timeout should not apply")
+	}
+	if !(&Member{CommunicationDisabledUntil: &future}).TimedOut(now) {
+		t.Error("pending timeout should apply")
+	}
+}
+
+func TestBanActive(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	if !(&Ban{}).Active(now) {
+		t.Error("permanent ban should be active")
+	}
+	if (&Ban{ExpiresAt: &past}).Active(now) {
+		t.Error("expired ban should not be active")
+	}
+	if !(&Ban{ExpiresAt: &future}).Active(now) {
+		t.Error("pending ban should be active")
+	}
+}
+
 func TestMemberDisplayName(t *testing.T) {
 	tests := []struct {
 		name     string
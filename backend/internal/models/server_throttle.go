@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ServerThrottleLimits are how much traffic a server may cause across all
+// its members together. 0 means unlimited.
+type ServerThrottleLimits struct {
+	// MessagesPerSecond caps messages sent in the server's channels
+	MessagesPerSecond int `json:"messages_per_second"`
+	// EventsPerSecond caps the server's gateway traffic: typing and
+	// presence events fanned out to its members, and member requests
+	EventsPerSecond int `json:"events_per_second"`
+}
+
+// ServerThrottle is a server's throttling, with the limits instance admins
+// set for it in place of the instance defaults
+type ServerThrottle struct {
+	ServerID uuid.UUID `json:"server_id" db:"server_id"`
+	// MessagesPerSecond and EventsPerSecond override the instance defaults
+	// when set
+	MessagesPerSecond *int    `json:"messages_per_second" db:"messages_per_second"`
+	EventsPerSecond   *int    `json:"events_per_second" db:"events_per_second"`
+	Reason            *string `json:"reason,omitempty" db:"reason"`
+	// Limits are the limits that apply after overrides
+	Limits    ServerThrottleLimits `json:"limits" db:"-"`
+	UpdatedBy *uuid.UUID           `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt *time.Time           `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// Apply returns defaults with the server's overrides in place
+func (t *ServerThrottle) Apply(defaults ServerThrottleLimits) ServerThrottleLimits {
+	limits := defaults
+	if t == nil {
+		return limits
+	}
+	if t.MessagesPerSecond != nil {
+		limits.MessagesPerSecond = *t.MessagesPerSecond
+	}
+	if t.EventsPerSecond != nil {
+		limits.EventsPerSecond = *t.EventsPerSecond
+	}
+	return limits
+}
+
+// SetServerThrottleRequest overrides a server's limits. Limits left out use
+// the instance defaults.
+type SetServerThrottleRequest struct {
+	MessagesPerSecond *int    `json:"messages_per_second" validate:"omitempty,min=0"`
+	EventsPerSecond   *int    `json:"events_per_second" validate:"omitempty,min=0"`
+	Reason            *string `json:"reason"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ServerTokenPrefix starts every server token, so they can be told apart
+// from user access tokens
+const ServerTokenPrefix = "hst_"
+
+// Server token scopes
+const (
+	// ServerTokenScopeServerRead reads the server, its channels and roles
+	ServerTokenScopeServerRead = "server.read"
+	// ServerTokenScopeMembersRead reads the server's members
+	ServerTokenScopeMembersRead = "members.read"
+	// ServerTokenScopeMessagesRead reads messages and their reactions
+	ServerTokenScopeMessagesRead = "messages.read"
+	// ServerTokenScopeMessagesWrite sends, edits and deletes messages and
+	// reacts to them
+	ServerTokenScopeMessagesWrite = "messages.write"
+)
+
+// ServerTokenScopes are the scopes a server token can be given
+var ServerTokenScopes = []string{
+	ServerTokenScopeServerRead,
+	ServerTokenScopeMembersRead,
+	ServerTokenScopeMessagesRead,
+	ServerTokenScopeMessagesWrite,
+}
+
+// ServerToken is an API token for automation, such as a CI job posting
+// release notes. It's limited to one server and to its scopes, acts as the
+// owner or co-owner who created it and stops working once it expires or
+// they no longer own the server.
+type ServerToken struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	ServerID   uuid.UUID  `json:"server_id" db:"server_id"`
+	CreatorID  uuid.UUID  `json:"creator_id" db:"creator_id"`
+	Name       string     `json:"name" db:"name"`
+	Scopes     []string   `json:"scopes"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+
+	// Token is the secret itself, only returned when the token is created
+	Token string `json:"token,omitempty" db:"-"`
+}
+
+// CreateServerTokenRequest creates a server token
+type CreateServerTokenRequest struct {
+	Name      string    `json:"name" validate:"required,max=100"`
+	Scopes    []string  `json:"scopes" validate:"required,min=1,dive,oneof=server.read members.read messages.read messages.write"`
+	ExpiresAt time.Time `json:"expires_at" validate:"required"`
+}
+
+// HasScope reports whether the token was given scope
+func (t *ServerToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -23,6 +23,9 @@ type ServerUpdate struct {
 	VerificationLevel     *int       `json:"verification_level,omitempty"`
 	ExplicitContentFilter *int       `json:"explicit_content_filter,omitempty"`
 	DefaultNotifications  *int       `json:"default_notifications,omitempty"`
+
+	// Version the edit was based on; if set and stale the update is refused
+	Version *int `json:"version,omitempty"`
 }
 
 // RoleUpdate represents a partial update to a role
@@ -33,4 +36,7 @@ type RoleUpdate struct {
 	Mentionable *bool   `json:"mentionable,omitempty"`
 	Permissions *int64  `json:"permissions,omitempty"`
 	Position    *int    `json:"position,omitempty"`
+
+	// Version the edit was based on; if set and stale the update is refused
+	Version *int `json:"version,omitempty"`
 }
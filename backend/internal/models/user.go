@@ -19,77 +19,143 @@ const (
 
 // User represents a Hearth user account
 type User struct {
-	ID            uuid.UUID      `json:"id" db:"id"`
-	Email         string         `json:"email" db:"email"`
-	Username      string         `json:"username" db:"username"`
-	Discriminator string         `json:"discriminator" db:"discriminator"`
-	PasswordHash  string         `json:"-" db:"password_hash"`
-	AvatarURL     *string        `json:"avatar_url,omitempty" db:"avatar_url"`
-	BannerURL     *string        `json:"banner_url,omitempty" db:"banner_url"`
-	Bio           *string        `json:"bio,omitempty" db:"bio"`
-	Status        PresenceStatus `json:"status" db:"status"`
-	CustomStatus  *string        `json:"custom_status,omitempty" db:"custom_status"`
-	MFAEnabled    bool           `json:"mfa_enabled" db:"mfa_enabled"`
-	MFASecret     *string        `json:"-" db:"mfa_secret"`
-	Verified      bool           `json:"verified" db:"verified"`
-	Flags         int64          `json:"flags" db:"flags"`
-	CreatedAt     time.Time      `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at" db:"updated_at"`
+	ID                    uuid.UUID      `json:"id" db:"id"`
+	Email                 string         `json:"email" db:"email"`
+	Username              string         `json:"username" db:"username"`
+	Discriminator         string         `json:"discriminator" db:"discriminator"`
+	PasswordHash          string         `json:"-" db:"password_hash"`
+	AvatarURL             *string        `json:"avatar_url,omitempty" db:"avatar_url"`
+	BannerURL             *string        `json:"banner_url,omitempty" db:"banner_url"`
+	Bio                   *string        `json:"bio,omitempty" db:"bio"`
+	Status                PresenceStatus `json:"status" db:"status"`
+	CustomStatus          *string        `json:"custom_status,omitempty" db:"custom_status"`
+	CustomStatusEmoji     *string        `json:"custom_status_emoji,omitempty" db:"custom_status_emoji"`
+	CustomStatusExpiresAt *time.Time     `json:"custom_status_expires_at,omitempty" db:"custom_status_expires_at"`
+	MFAEnabled            bool           `json:"mfa_enabled" db:"mfa_enabled"`
+	MFASecret             *string        `json:"-" db:"mfa_secret"`
+	Verified              bool           `json:"verified" db:"verified"`
+	Flags                 int64          `json:"flags" db:"flags"`
+	UsernameChangedAt     *time.Time     `json:"-" db:"username_changed_at"`
+	CreatedAt             time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt             time.Time      `json:"updated_at" db:"updated_at"`
 }
 
 // UserFlags for system-level user attributes
 const (
-	UserFlagStaff         int64 = 1 << 0
-	UserFlagPartner       int64 = 1 << 1
-	UserFlagBugHunter     int64 = 1 << 2
-	UserFlagPremium       int64 = 1 << 3
-	UserFlagSystemBot     int64 = 1 << 4
-	UserFlagDeletedUser   int64 = 1 << 5
+	UserFlagStaff       int64 = 1 << 0
+	UserFlagPartner     int64 = 1 << 1
+	UserFlagBugHunter   int64 = 1 << 2
+	UserFlagPremium     int64 = 1 << 3
+	UserFlagSystemBot   int64 = 1 << 4
+	UserFlagDeletedUser int64 = 1 << 5
+	UserFlagBot         int64 = 1 << 6
+	// UserFlagUsernameReset marks accounts renamed to resolve a username
+	// collision. They may pick a new username without waiting out the
+	// change cooldown; changing it clears the flag.
+	UserFlagUsernameReset int64 = 1 << 7
 )
 
+// LegacyDiscriminator is the discriminator every account has now that
+// usernames are unique on their own
+const LegacyDiscriminator = "0000"
+
+// IsBot reports whether the account is a bot
+func (u *User) IsBot() bool {
+	return u.Flags&(UserFlagBot|UserFlagSystemBot) != 0
+}
+
 // PublicUser is a safe representation for API responses
 type PublicUser struct {
-	ID            uuid.UUID      `json:"id"`
-	Username      string         `json:"username"`
-	Discriminator string         `json:"discriminator"`
-	AvatarURL     *string        `json:"avatar_url,omitempty"`
-	BannerURL     *string        `json:"banner_url,omitempty"`
-	Bio           *string        `json:"bio,omitempty"`
-	Status        PresenceStatus `json:"status"`
-	CustomStatus  *string        `json:"custom_status,omitempty"`
-	Flags         int64          `json:"flags"`
+	ID                    uuid.UUID      `json:"id"`
+	Username              string         `json:"username"`
+	Discriminator         string         `json:"discriminator"`
+	AvatarURL             *string        `json:"avatar_url,omitempty"`
+	BannerURL             *string        `json:"banner_url,omitempty"`
+	Bio                   *string        `json:"bio,omitempty"`
+	Status                PresenceStatus `json:"status"`
+	CustomStatus          *string        `json:"custom_status,omitempty"`
+	CustomStatusEmoji     *string        `json:"custom_status_emoji,omitempty"`
+	CustomStatusExpiresAt *time.Time     `json:"custom_status_expires_at,omitempty"`
+	Flags                 int64          `json:"flags"`
 }
 
 // ToPublic converts a User to a PublicUser (safe for API responses)
 func (u *User) ToPublic() PublicUser {
 	return PublicUser{
-		ID:            u.ID,
-		Username:      u.Username,
-		Discriminator: u.Discriminator,
-		AvatarURL:     u.AvatarURL,
-		BannerURL:     u.BannerURL,
-		Bio:           u.Bio,
-		Status:        u.Status,
-		CustomStatus:  u.CustomStatus,
-		Flags:         u.Flags,
+		ID:                    u.ID,
+		Username:              u.Username,
+		Discriminator:         u.Discriminator,
+		AvatarURL:             u.AvatarURL,
+		BannerURL:             u.BannerURL,
+		Bio:                   u.Bio,
+		Status:                u.Status,
+		CustomStatus:          u.CustomStatus,
+		CustomStatusEmoji:     u.CustomStatusEmoji,
+		CustomStatusExpiresAt: u.CustomStatusExpiresAt,
+		Flags:                 u.Flags,
 	}
 }
 
-// Tag returns the full username with discriminator (e.g., "user#1234")
+// CustomStatus is a user-set status message shown next to their presence
+type CustomStatus struct {
+	Text      *string    `json:"text,omitempty" validate:"omitempty,max=128"`
+	Emoji     *string    `json:"emoji,omitempty" validate:"omitempty,max=64"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// ActiveCustomStatus returns the user's custom status, or nil if none is
+// set or it has expired
+func (u *User) ActiveCustomStatus(now time.Time) *CustomStatus {
+	if u.CustomStatus == nil && u.CustomStatusEmoji == nil {
+		return nil
+	}
+	if u.CustomStatusExpiresAt != nil && !u.CustomStatusExpiresAt.After(now) {
+		return nil
+	}
+	return &CustomStatus{
+		Text:      u.CustomStatus,
+		Emoji:     u.CustomStatusEmoji,
+		ExpiresAt: u.CustomStatusExpiresAt,
+	}
+}
+
+// Tag returns the full username with discriminator (e.g., "user#1234"),
+// or just the username for accounts on the legacy discriminator
 func (u *User) Tag() string {
+	if u.Discriminator == "" || u.Discriminator == LegacyDiscriminator {
+		return u.Username
+	}
 	return u.Username + "#" + u.Discriminator
 }
 
-// Session represents an authenticated user session
+// UsernameChange is a username an account used to have
+type UsernameChange struct {
+	Username  string    `json:"username" db:"username"`
+	ChangedAt time.Time `json:"changed_at" db:"changed_at"`
+}
+
+// UsernameAvailability says whether a username can be claimed, with free
+// alternatives when it can't
+type UsernameAvailability struct {
+	Username    string   `json:"username"`
+	Available   bool     `json:"available"`
+	Reason      string   `json:"reason,omitempty"` // invalid, taken, reserved or banned
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// Session represents an authenticated user session: a login and the
+// refresh tokens issued from it
 type Session struct {
 	ID           uuid.UUID  `json:"id" db:"id"`
 	UserID       uuid.UUID  `json:"user_id" db:"user_id"`
 	RefreshToken string     `json:"-" db:"refresh_token_hash"`
 	UserAgent    *string    `json:"user_agent,omitempty" db:"user_agent"`
-	IPAddress    *string    `json:"-" db:"ip_address"`
+	IPAddress    *string    `json:"ip_address,omitempty" db:"ip_address"`
 	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
 	ExpiresAt    time.Time  `json:"expires_at" db:"expires_at"`
 	LastUsedAt   *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	// Current marks the session the request was made with
+	Current bool `json:"current" db:"-"`
 }
 
 // CreateUserRequest is the input for user registration
@@ -33,11 +33,12 @@ type UserSettings struct {
 	NotificationsServerDefaults bool `json:"notifications_server_defaults" db:"notifications_server_defaults"`
 
 	// Privacy settings
-	PrivacyDMFromServers     bool `json:"privacy_dm_from_servers" db:"privacy_dm_from_servers"`
-	PrivacyDMFromFriendsOnly bool `json:"privacy_dm_from_friends_only" db:"privacy_dm_from_friends_only"`
-	PrivacyShowActivity      bool `json:"privacy_show_activity" db:"privacy_show_activity"`
-	PrivacyFriendRequestsAll bool `json:"privacy_friend_requests_all" db:"privacy_friend_requests_all"`
-	PrivacyReadReceipts      bool `json:"privacy_read_receipts" db:"privacy_read_receipts"`
+	PrivacyDMFromServers       bool `json:"privacy_dm_from_servers" db:"privacy_dm_from_servers"`
+	PrivacyDMFromFriendsOnly   bool `json:"privacy_dm_from_friends_only" db:"privacy_dm_from_friends_only"`
+	PrivacyShowActivity        bool `json:"privacy_show_activity" db:"privacy_show_activity"`
+	PrivacyFriendRequestsAll   bool `json:"privacy_friend_requests_all" db:"privacy_friend_requests_all"`
+	PrivacyReadReceipts        bool `json:"privacy_read_receipts" db:"privacy_read_receipts"`
+	PrivacyPresenceFriendsOnly bool `json:"privacy_presence_friends_only" db:"privacy_presence_friends_only"`
 
 	// Locale settings
 	Locale string `json:"locale" db:"locale"` // e.g., "en-US", "es", "fr"
@@ -73,11 +74,12 @@ func DefaultUserSettings(userID uuid.UUID) *UserSettings {
 		NotificationsServerDefaults: true,
 
 		// Privacy defaults
-		PrivacyDMFromServers:     true,
-		PrivacyDMFromFriendsOnly: false,
-		PrivacyShowActivity:      true,
-		PrivacyFriendRequestsAll: true,
-		PrivacyReadReceipts:      true,
+		PrivacyDMFromServers:       true,
+		PrivacyDMFromFriendsOnly:   false,
+		PrivacyShowActivity:        true,
+		PrivacyFriendRequestsAll:   true,
+		PrivacyReadReceipts:        true,
+		PrivacyPresenceFriendsOnly: false,
 
 		// Locale default
 		Locale: "en-US",
@@ -111,11 +113,12 @@ type UpdateUserSettingsRequest struct {
 	NotificationsServerDefaults *bool `json:"notifications_server_defaults,omitempty"`
 
 	// Privacy settings
-	PrivacyDMFromServers     *bool `json:"privacy_dm_from_servers,omitempty"`
-	PrivacyDMFromFriendsOnly *bool `json:"privacy_dm_from_friends_only,omitempty"`
-	PrivacyShowActivity      *bool `json:"privacy_show_activity,omitempty"`
-	PrivacyFriendRequestsAll *bool `json:"privacy_friend_requests_all,omitempty"`
-	PrivacyReadReceipts      *bool `json:"privacy_read_receipts,omitempty"`
+	PrivacyDMFromServers       *bool `json:"privacy_dm_from_servers,omitempty"`
+	PrivacyDMFromFriendsOnly   *bool `json:"privacy_dm_from_friends_only,omitempty"`
+	PrivacyShowActivity        *bool `json:"privacy_show_activity,omitempty"`
+	PrivacyFriendRequestsAll   *bool `json:"privacy_friend_requests_all,omitempty"`
+	PrivacyReadReceipts        *bool `json:"privacy_read_receipts,omitempty"`
+	PrivacyPresenceFriendsOnly *bool `json:"privacy_presence_friends_only,omitempty"`
 
 	// Locale settings
 	Locale *string `json:"locale,omitempty" validate:"omitempty,min=2,max=10"`
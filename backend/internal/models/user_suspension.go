@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserSuspension stops an account logging in or using the API until it's
+// lifted or expires
+type UserSuspension struct {
+	UserID      uuid.UUID  `json:"user_id" db:"user_id"`
+	Reason      *string    `json:"reason,omitempty" db:"reason"`
+	SuspendedBy *uuid.UUID `json:"suspended_by,omitempty" db:"suspended_by"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	// ExpiresAt is when the suspension ends by itself, if ever
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+}
+
+// Active reports whether the suspension still applies at now
+func (s *UserSuspension) Active(now time.Time) bool {
+	return s.ExpiresAt == nil || now.Before(*s.ExpiresAt)
+}
+
+// SuspendUserRequest suspends an account, replacing any suspension it
+// already has
+type SuspendUserRequest struct {
+	Reason    *string    `json:"reason,omitempty" validate:"omitempty,max=512"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// AdminUser is a user as instance admins see them, with their suspension
+// if they're suspended
+type AdminUser struct {
+	*User
+	Suspension *UserSuspension `json:"suspension,omitempty"`
+}
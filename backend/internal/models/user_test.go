@@ -78,6 +78,13 @@ func TestUserTag(t *testing.T) {
 	}
 }
 
+func TestUserTag_LegacyDiscriminator(t *testing.T) {
+	user := &User{Username: "testuser", Discriminator: LegacyDiscriminator}
+	if tag := user.Tag(); tag != "testuser" {
+		t.Errorf("expected tag testuser, got %s", tag)
+	}
+}
+
 func TestPresenceStatus(t *testing.T) {
 	statuses := []PresenceStatus{
 		StatusOnline,
@@ -131,6 +138,9 @@ func TestUserFlags(t *testing.T) {
 	if UserFlagDeletedUser != 1<<5 {
 		t.Errorf("UserFlagDeletedUser should be 1<<5")
 	}
+	if UserFlagBot != 1<<6 {
+		t.Errorf("UserFlagBot should be 1<<6")
+	}
 
 	// Test combining flags
 	combined := UserFlagStaff | UserFlagPremium
@@ -145,6 +155,42 @@ func TestUserFlags(t *testing.T) {
 	}
 }
 
+func TestUserIsBot(t *testing.T) {
+	if (&User{Flags: UserFlagPremium}).IsBot() {
+		t.Error("regular user should not be a bot")
+	}
+	if !(&User{Flags: UserFlagBot}).IsBot() {
+		t.Error("bot flag should mark a bot")
+	}
+	if !(&User{Flags: UserFlagSystemBot}).IsBot() {
+		t.Error("system bots are bots")
+	}
+}
+
+func TestUserActiveCustomStatus(t *testing.T) {
+	now := time.Now()
+	text := "Away"
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Minute)
+
+	if (&User{}).ActiveCustomStatus(now) != nil {
+		t.Error("user without a custom status should have none active")
+	}
+
+	cs := (&User{CustomStatus: &text, CustomStatusExpiresAt: &future}).ActiveCustomStatus(now)
+	if cs == nil || *cs.Text != text || !cs.ExpiresAt.Equal(future) {
+		t.Errorf("expected active custom status %q, got %+v", text, cs)
+	}
+
+	if (&User{CustomStatus: &text}).ActiveCustomStatus(now) == nil {
+		t.Error("custom status without expiry should stay active")
+	}
+
+	if (&User{CustomStatus: &text, CustomStatusExpiresAt: &past}).ActiveCustomStatus(now) != nil {
+		t.Error("expired custom status should not be active")
+	}
+}
+
 func TestUserToPublicWithNilFields(t *testing.T) {
 	user := &User{
 		ID:            uuid.New(),
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UsernameRuleKind is what a username rule does to the names it matches
+type UsernameRuleKind string
+
+const (
+	// UsernameRuleReserved names can only be taken by staff
+	UsernameRuleReserved UsernameRuleKind = "reserved"
+	// UsernameRuleBanned names can't be taken by anyone
+	UsernameRuleBanned UsernameRuleKind = "banned"
+)
+
+// Valid reports whether k is a known rule kind
+func (k UsernameRuleKind) Valid() bool {
+	return k == UsernameRuleReserved || k == UsernameRuleBanned
+}
+
+// UsernameRule reserves or bans usernames across the instance. A plain
+// pattern matches a whole username; a regex pattern matches anywhere in it
+// unless anchored. Both ignore case.
+type UsernameRule struct {
+	ID        uuid.UUID        `json:"id" db:"id"`
+	Kind      UsernameRuleKind `json:"kind" db:"kind"`
+	Pattern   string           `json:"pattern" db:"pattern"`
+	Regex     bool             `json:"regex" db:"regex"`
+	Reason    *string          `json:"reason,omitempty" db:"reason"`
+	CreatorID *uuid.UUID       `json:"creator_id,omitempty" db:"creator_id"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at"`
+}
+
+// CreateUsernameRuleRequest creates a username rule
+type CreateUsernameRuleRequest struct {
+	Kind    UsernameRuleKind `json:"kind" validate:"required,oneof=reserved banned"`
+	Pattern string           `json:"pattern" validate:"required,max=260"`
+	Regex   bool             `json:"regex"`
+	Reason  *string          `json:"reason,omitempty" validate:"omitempty,max=255"`
+}
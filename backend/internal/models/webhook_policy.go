@@ -0,0 +1,74 @@
+package models
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookPolicy restricts who can create a server's webhooks and which
+// external domains its webhooks may point at. A channel's policy replaces
+// the server's for webhooks in that channel.
+type WebhookPolicy struct {
+	ServerID  uuid.UUID  `json:"server_id" db:"server_id"`
+	ChannelID *uuid.UUID `json:"channel_id,omitempty" db:"channel_id"`
+	// CreatorRoleIDs are the roles allowed to create webhooks. Without any,
+	// anyone who can manage webhooks may.
+	CreatorRoleIDs []uuid.UUID `json:"creator_role_ids"`
+	// AllowedDomains are the hosts webhook URLs may point at, subdomains
+	// included. Without any, every domain is allowed.
+	AllowedDomains []string  `json:"allowed_domains"`
+	UpdatedBy      uuid.UUID `json:"updated_by" db:"updated_by"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// UpdateWebhookPolicyRequest replaces a server's or channel's webhook policy
+type UpdateWebhookPolicyRequest struct {
+	CreatorRoleIDs []uuid.UUID `json:"creator_role_ids" validate:"max=25"`
+	AllowedDomains []string    `json:"allowed_domains" validate:"max=50"`
+}
+
+// AllowsCreator reports whether a member with roleIDs may create webhooks
+func (p *WebhookPolicy) AllowsCreator(roleIDs []uuid.UUID) bool {
+	if len(p.CreatorRoleIDs) == 0 {
+		return true
+	}
+	for _, allowed := range p.CreatorRoleIDs {
+		for _, id := range roleIDs {
+			if id == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AllowsURL reports whether a webhook may point at rawURL. Values without
+// a scheme or host, such as an uploaded avatar's hash, aren't external and
+// are always allowed; schemes other than http and https never are.
+func (p *WebhookPolicy) AllowsURL(rawURL string) bool {
+	if len(p.AllowedDomains) == 0 {
+		return true
+	}
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+	case "":
+		return u.Host == ""
+	default:
+		return false
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for _, domain := range p.AllowedDomains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookPolicyAllowsCreator(t *testing.T) {
+	roleID := uuid.New()
+
+	assert.True(t, (&WebhookPolicy{}).AllowsCreator(nil), "without roles anyone may")
+	policy := &WebhookPolicy{CreatorRoleIDs: []uuid.UUID{roleID}}
+	assert.True(t, policy.AllowsCreator([]uuid.UUID{uuid.New(), roleID}))
+	assert.False(t, policy.AllowsCreator([]uuid.UUID{uuid.New()}))
+	assert.False(t, policy.AllowsCreator(nil))
+}
+
+func TestWebhookPolicyAllowsURL(t *testing.T) {
+	assert.True(t, (&WebhookPolicy{}).AllowsURL("https://anywhere.net/x"), "without domains any may")
+
+	policy := &WebhookPolicy{AllowedDomains: []string{"example.com"}}
+	assert.True(t, policy.AllowsURL("https://example.com./a"))
+	assert.True(t, policy.AllowsURL("http://a.b.example.com:8443/a"))
+	assert.True(t, policy.AllowsURL("avatar-hash"))
+	assert.False(t, policy.AllowsURL("https://badexample.com/a"))
+	assert.False(t, policy.AllowsURL("data:image/png;base64,AAAA"))
+	assert.False(t, policy.AllowsURL("HTTPS://evil.net"))
+}
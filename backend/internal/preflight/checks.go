@@ -0,0 +1,152 @@
+package preflight
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"hearth/internal/database/postgres"
+)
+
+// MinSecretKeyLength is the shortest SECRET_KEY that doesn't warn. Tokens
+// are signed with HMAC-SHA256, which wants a key of at least 32 bytes.
+const MinSecretKeyLength = 32
+
+// ClockSkewWarning is how far the clock can drift from Postgres before
+// preflight warns
+const ClockSkewWarning = time.Second
+
+// placeholderSecrets are the built-in default and the example values from
+// the docs, none of which are secret
+var placeholderSecrets = []string{
+	"change-me-in-production",
+	"change-me-to-random-32-bytes",
+	"your-32-byte-secret-key-here",
+	"your-secret-key",
+}
+
+// Querier runs a single-row query; *sqlx.DB and *sql.DB implement it
+type Querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Database checks that Postgres answers queries and reports its version
+func Database(db Querier) Check {
+	return Check{
+		Name:     "database",
+		Critical: true,
+		Run: func(ctx context.Context) (string, error) {
+			var version string
+			if err := db.QueryRowContext(ctx, `SELECT current_setting('server_version')`).Scan(&version); err != nil {
+				return "", fmt.Errorf("postgres is unreachable: %w", err)
+			}
+			return "PostgreSQL " + version, nil
+		},
+	}
+}
+
+// Migrations checks that every migration built in has been applied. The
+// database having migrations this build doesn't know about only warns: it
+// usually means a newer build ran against it.
+func Migrations(status func(ctx context.Context) (*postgres.MigrationState, error)) Check {
+	return Check{
+		Name:     "migrations",
+		Critical: true,
+		Run: func(ctx context.Context) (string, error) {
+			state, err := status(ctx)
+			if err != nil {
+				return "", err
+			}
+			if len(state.Pending) > 0 {
+				return "", fmt.Errorf("%d migrations not applied: %s", len(state.Pending), strings.Join(state.Pending, ", "))
+			}
+			if len(state.Unknown) > 0 {
+				return "", Warnf("database has %d migrations this build doesn't know about: %s", len(state.Unknown), strings.Join(state.Unknown, ", "))
+			}
+			return fmt.Sprintf("%d applied", len(state.Applied)), nil
+		},
+	}
+}
+
+// Redis reports whether the server runs distributed or single-instance.
+// ping is nil when Redis couldn't be reached at startup, which only warns
+// unless required is set.
+func Redis(ping func(ctx context.Context) error, required bool) Check {
+	return Check{
+		Name:     "redis",
+		Critical: required,
+		Run: func(ctx context.Context) (string, error) {
+			if ping == nil {
+				return "", fmt.Errorf("redis unavailable; running single-instance with an in-memory hub")
+			}
+			if err := ping(ctx); err != nil {
+				return "", fmt.Errorf("redis is unreachable: %w", err)
+			}
+			return "distributed", nil
+		},
+	}
+}
+
+// SecretKey rejects missing and placeholder signing keys and warns about
+// short ones
+func SecretKey(key string) Check {
+	return Check{
+		Name:     "secret_key",
+		Critical: true,
+		Run: func(ctx context.Context) (string, error) {
+			if key == "" {
+				return "", fmt.Errorf("SECRET_KEY is empty")
+			}
+			for _, placeholder := range placeholderSecrets {
+				if key == placeholder {
+					return "", fmt.Errorf("SECRET_KEY is the placeholder %q; generate one with `openssl rand -base64 32`", placeholder)
+				}
+			}
+			if len(key) < MinSecretKeyLength {
+				return "", Warnf("SECRET_KEY is %d bytes; use at least %d", len(key), MinSecretKeyLength)
+			}
+			return fmt.Sprintf("%d bytes", len(key)), nil
+		},
+	}
+}
+
+// ClockSkew compares the local clock with Postgres's. Token expiry, timed
+// bans and timeouts all mix the two, so a skew above limit fails.
+func ClockSkew(db Querier, limit time.Duration) Check {
+	return clockSkew(func(ctx context.Context) (time.Time, error) {
+		var now time.Time
+		err := db.QueryRowContext(ctx, `SELECT clock_timestamp()`).Scan(&now)
+		return now, err
+	}, limit)
+}
+
+func clockSkew(remoteNow func(ctx context.Context) (time.Time, error), limit time.Duration) Check {
+	return Check{
+		Name:     "clock_skew",
+		Critical: true,
+		Run: func(ctx context.Context) (string, error) {
+			start := time.Now()
+			remote, err := remoteNow(ctx)
+			if err != nil {
+				return "", fmt.Errorf("failed to read the postgres clock: %w", err)
+			}
+			// Postgres read its clock somewhere during the round trip
+			local := start.Add(time.Since(start) / 2)
+
+			skew := remote.Sub(local)
+			if skew < 0 {
+				skew = -skew
+			}
+			skew = skew.Round(time.Millisecond)
+			switch {
+			case limit > 0 && skew > limit:
+				return "", fmt.Errorf("clock is %v off from postgres (max %v)", skew, limit)
+			case skew > ClockSkewWarning:
+				return "", Warnf("clock is %v off from postgres", skew)
+			}
+			return fmt.Sprintf("%v off from postgres", skew), nil
+		},
+	}
+}
@@ -0,0 +1,122 @@
+// Package preflight checks the environment before the server starts
+// listening, so a bad database, secret or clock shows up as one report at
+// boot instead of as errors under traffic.
+package preflight
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout bounds each check when Run is given no timeout
+const DefaultTimeout = 5 * time.Second
+
+// Status is the outcome of one check
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// Check is one startup check. Run describes what it found; an error means
+// the check failed, or only warned if it's from Warnf.
+type Check struct {
+	Name     string
+	Critical bool // Failing stops startup; otherwise failures only warn
+	Run      func(ctx context.Context) (string, error)
+}
+
+type warning struct{ msg string }
+
+func (w *warning) Error() string { return w.msg }
+
+// Warnf returns an error that makes a check warn rather than fail, even a
+// critical one
+func Warnf(format string, args ...interface{}) error {
+	return &warning{msg: fmt.Sprintf(format, args...)}
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name       string `json:"name"`
+	Status     Status `json:"status"`
+	Critical   bool   `json:"critical"`
+	Detail     string `json:"detail,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Report holds the results of every check, in the order they ran
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// Run runs checks one after another, giving each at most timeout
+func Run(ctx context.Context, timeout time.Duration, checks ...Check) *Report {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	report := &Report{Results: make([]Result, 0, len(checks))}
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		detail, err := check.Run(checkCtx)
+		cancel()
+
+		result := Result{
+			Name:       check.Name,
+			Status:     StatusOK,
+			Critical:   check.Critical,
+			Detail:     detail,
+			DurationMS: time.Since(start).Milliseconds(),
+		}
+		if err != nil {
+			var w *warning
+			result.Status = StatusWarn
+			if check.Critical && !errors.As(err, &w) {
+				result.Status = StatusFail
+			}
+			result.Detail = err.Error()
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report
+}
+
+// Failures returns the critical checks that failed
+func (r *Report) Failures() []Result {
+	var failed []Result
+	for _, result := range r.Results {
+		if result.Status == StatusFail {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Summary counts results by status, e.g. "4 ok, 1 warn, 0 fail"
+func (r *Report) Summary() string {
+	counts := map[Status]int{}
+	for _, result := range r.Results {
+		counts[result.Status]++
+	}
+	return fmt.Sprintf("%d ok, %d warn, %d fail", counts[StatusOK], counts[StatusWarn], counts[StatusFail])
+}
+
+// Log writes one line per check followed by the whole report as JSON, for
+// log pipelines that parse it
+func (r *Report) Log(logf func(format string, args ...interface{})) {
+	logf("[Preflight] %s", r.Summary())
+	for _, result := range r.Results {
+		logf("[Preflight] %-4s %-12s %s (%dms)", strings.ToUpper(string(result.Status)), result.Name, result.Detail, result.DurationMS)
+	}
+	if data, err := json.Marshal(r); err == nil {
+		logf("[Preflight] report %s", data)
+	}
+}
@@ -0,0 +1,144 @@
+package preflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/database/postgres"
+)
+
+func fixed(detail string, err error) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) { return detail, err }
+}
+
+func TestRun(t *testing.T) {
+	report := Run(context.Background(), 0,
+		Check{Name: "fine", Critical: true, Run: fixed("all good", nil)},
+		Check{Name: "optional", Run: fixed("", errors.New("down"))},
+		Check{Name: "shaky", Critical: true, Run: fixed("", Warnf("a bit off"))},
+		Check{Name: "broken", Critical: true, Run: fixed("", errors.New("down"))},
+	)
+
+	require.Len(t, report.Results, 4)
+	assert.Equal(t, StatusOK, report.Results[0].Status)
+	assert.Equal(t, "all good", report.Results[0].Detail)
+	assert.Equal(t, StatusWarn, report.Results[1].Status, "non-critical failures only warn")
+	assert.Equal(t, StatusWarn, report.Results[2].Status)
+	assert.Equal(t, "a bit off", report.Results[2].Detail)
+	assert.Equal(t, StatusFail, report.Results[3].Status)
+
+	failures := report.Failures()
+	require.Len(t, failures, 1)
+	assert.Equal(t, "broken", failures[0].Name)
+	assert.Equal(t, "1 ok, 2 warn, 1 fail", report.Summary())
+}
+
+func TestRun_Timeout(t *testing.T) {
+	report := Run(context.Background(), 10*time.Millisecond, Check{
+		Name:     "slow",
+		Critical: true,
+		Run: func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		},
+	})
+
+	assert.Equal(t, StatusFail, report.Results[0].Status)
+	assert.Contains(t, report.Results[0].Detail, "deadline exceeded")
+}
+
+func TestReport_Log(t *testing.T) {
+	report := Run(context.Background(), 0, Check{Name: "fine", Run: fixed("all good", nil)})
+
+	var lines []string
+	report.Log(func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	})
+
+	require.Len(t, lines, 3)
+	assert.Equal(t, "[Preflight] 1 ok, 0 warn, 0 fail", lines[0])
+	assert.Contains(t, lines[1], "OK   fine")
+	assert.True(t, strings.HasPrefix(lines[2], `[Preflight] report {"results":[{"name":"fine","status":"ok"`), lines[2])
+}
+
+func TestSecretKey(t *testing.T) {
+	tests := []struct {
+		key    string
+		status Status
+	}{
+		{"", StatusFail},
+		{"change-me-in-production", StatusFail},
+		{"your-32-byte-secret-key-here", StatusFail},
+		{"short-but-random", StatusWarn},
+		{strings.Repeat("k", MinSecretKeyLength), StatusOK},
+	}
+	for _, tt := range tests {
+		report := Run(context.Background(), 0, SecretKey(tt.key))
+		assert.Equal(t, tt.status, report.Results[0].Status, "key %q", tt.key)
+		assert.NotContains(t, report.Results[0].Detail, "short-but-random", "the key itself is never reported")
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	status := func(state *postgres.MigrationState, err error) func(ctx context.Context) (*postgres.MigrationState, error) {
+		return func(ctx context.Context) (*postgres.MigrationState, error) { return state, err }
+	}
+
+	report := Run(context.Background(), 0,
+		Migrations(status(&postgres.MigrationState{Applied: []string{"001_initial.sql", "002_next.sql"}}, nil)),
+		Migrations(status(&postgres.MigrationState{Applied: []string{"001_initial.sql"}, Pending: []string{"002_next.sql"}}, nil)),
+		Migrations(status(&postgres.MigrationState{Applied: []string{"001_initial.sql"}, Unknown: []string{"099_future.sql"}}, nil)),
+		Migrations(status(nil, errors.New("no schema_migrations table"))),
+	)
+
+	assert.Equal(t, StatusOK, report.Results[0].Status)
+	assert.Equal(t, "2 applied", report.Results[0].Detail)
+	assert.Equal(t, StatusFail, report.Results[1].Status)
+	assert.Contains(t, report.Results[1].Detail, "002_next.sql")
+	assert.Equal(t, StatusWarn, report.Results[2].Status, "a newer build's migrations only warn")
+	assert.Equal(t, StatusFail, report.Results[3].Status)
+}
+
+func TestRedis(t *testing.T) {
+	up := func(ctx context.Context) error { return nil }
+	down := func(ctx context.Context) error { return errors.New("connection refused") }
+
+	report := Run(context.Background(), 0,
+		Redis(up, true),
+		Redis(nil, false),
+		Redis(nil, true),
+		Redis(down, true),
+	)
+
+	assert.Equal(t, StatusOK, report.Results[0].Status)
+	assert.Equal(t, "distributed", report.Results[0].Detail)
+	assert.Equal(t, StatusWarn, report.Results[1].Status, "single-instance mode is allowed unless Redis is required")
+	assert.Equal(t, StatusFail, report.Results[2].Status)
+	assert.Equal(t, StatusFail, report.Results[3].Status)
+}
+
+func TestClockSkew(t *testing.T) {
+	offset := func(d time.Duration) func(ctx context.Context) (time.Time, error) {
+		return func(ctx context.Context) (time.Time, error) { return time.Now().Add(d), nil }
+	}
+
+	report := Run(context.Background(), 0,
+		clockSkew(offset(0), 30*time.Second),
+		clockSkew(offset(-5*time.Second), 30*time.Second),
+		clockSkew(offset(time.Minute), 30*time.Second),
+		clockSkew(offset(time.Minute), 0),
+	)
+
+	assert.Equal(t, StatusOK, report.Results[0].Status)
+	assert.Equal(t, StatusWarn, report.Results[1].Status)
+	assert.Equal(t, StatusFail, report.Results[2].Status)
+	assert.Contains(t, report.Results[2].Detail, "1m0s off")
+	assert.Equal(t, StatusWarn, report.Results[3].Status, "no limit only warns")
+}
@@ -0,0 +1,15 @@
+//go:build integration
+
+package pubsub
+
+import (
+	"testing"
+
+	"hearth/internal/testenv"
+)
+
+// With the integration tag the Redis-backed tests run against a real
+// server instead of skipping
+func TestMain(m *testing.M) {
+	testenv.Main(m, testenv.Redis)
+}
@@ -0,0 +1,60 @@
+package pubsub
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// presenceKey holds one hash per user: node ID -> that node's presence view
+func (p *PubSub) presenceKey(userID uuid.UUID) string {
+	return "hearth:presence:" + userID.String()
+}
+
+// SetNodePresence stores this node's presence view for a user. The whole
+// hash expires after ttl unless a node refreshes it.
+func (p *PubSub) SetNodePresence(ctx context.Context, userID uuid.UUID, data []byte, ttl time.Duration) error {
+	key := p.presenceKey(userID)
+
+	pipe := p.client.TxPipeline()
+	pipe.HSet(ctx, key, p.nodeID, data)
+	pipe.Expire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// ClearNodePresence removes this node's presence view for a user
+func (p *PubSub) ClearNodePresence(ctx context.Context, userID uuid.UUID) error {
+	return p.client.HDel(ctx, p.presenceKey(userID), p.nodeID).Err()
+}
+
+// GetNodePresences returns every node's presence view for each user.
+// Users with no views are omitted.
+func (p *PubSub) GetNodePresences(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID][][]byte, error) {
+	if len(userIDs) == 0 {
+		return map[uuid.UUID][][]byte{}, nil
+	}
+
+	pipe := p.client.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(userIDs))
+	for i, id := range userIDs {
+		cmds[i] = pipe.HGetAll(ctx, p.presenceKey(id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	result := make(map[uuid.UUID][][]byte, len(userIDs))
+	for i, cmd := range cmds {
+		views := cmd.Val()
+		if len(views) == 0 {
+			continue
+		}
+		for _, v := range views {
+			result[userIDs[i]] = append(result[userIDs[i]], []byte(v))
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,49 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodePresence(t *testing.T) {
+	skipIfNoRedis(t)
+
+	node1, err := New(getRedisURL(), "presence-node-1")
+	require.NoError(t, err)
+	defer node1.Close()
+
+	node2, err := New(getRedisURL(), "presence-node-2")
+	require.NoError(t, err)
+	defer node2.Close()
+
+	ctx := context.Background()
+	userID := uuid.New()
+	otherID := uuid.New()
+
+	require.NoError(t, node1.SetNodePresence(ctx, userID, []byte(`{"status":"online"}`), time.Minute))
+	require.NoError(t, node2.SetNodePresence(ctx, userID, []byte(`{"status":"idle"}`), time.Minute))
+
+	views, err := node1.GetNodePresences(ctx, []uuid.UUID{userID, otherID})
+	require.NoError(t, err)
+	assert.Len(t, views[userID], 2)
+	assert.NotContains(t, views, otherID)
+
+	// Each node only clears its own view
+	require.NoError(t, node1.ClearNodePresence(ctx, userID))
+
+	views, err = node2.GetNodePresences(ctx, []uuid.UUID{userID})
+	require.NoError(t, err)
+	require.Len(t, views[userID], 1)
+	assert.JSONEq(t, `{"status":"idle"}`, string(views[userID][0]))
+
+	require.NoError(t, node2.ClearNodePresence(ctx, userID))
+
+	views, err = node2.GetNodePresences(ctx, []uuid.UUID{userID})
+	require.NoError(t, err)
+	assert.Empty(t, views)
+}
@@ -20,6 +20,7 @@ const (
 	TypeMessageUpdate  MessageType = "MESSAGE_UPDATE"
 	TypeMessageDelete  MessageType = "MESSAGE_DELETE"
 	TypeTypingStart    MessageType = "TYPING_START"
+	TypeTypingStop     MessageType = "TYPING_STOP"
 	TypePresenceUpdate MessageType = "PRESENCE_UPDATE"
 	TypeReactionAdd    MessageType = "REACTION_ADD"
 	TypeReactionRemove MessageType = "REACTION_REMOVE"
@@ -44,58 +45,157 @@ type BroadcastMessage struct {
 // Handler is a function that handles incoming pub/sub messages
 type Handler func(msg *BroadcastMessage)
 
+// Recorder receives measurements of messages moving between nodes
+type Recorder interface {
+	// MessageDelivered records a message from another node and how long
+	// after it was published it arrived
+	MessageDelivered(transport string, latency time.Duration, replayed bool)
+	// TransportFailed records a transport operation that failed
+	TransportFailed(transport, op string)
+	// StreamBacklog records how many stream entries this node has yet to
+	// read and how many it read without acknowledging
+	StreamBacklog(lag, pending int64)
+	// MessageRouted records how many other nodes a routed event went to
+	MessageRouted(nodes int)
+}
+
+// backlogReporter is a transport that can say how far behind this node is
+type backlogReporter interface {
+	Backlog(ctx context.Context) (lag, pending int64, err error)
+}
+
+// backlogInterval is how often a stream transport's backlog is recorded
+const backlogInterval = 15 * time.Second
+
+// Options configure how a PubSub moves messages between nodes
+type Options struct {
+	// Transport is TransportPubSub (the default) or TransportStreams
+	Transport string
+
+	// StreamMaxLen caps each topic's stream. Older entries are trimmed
+	// whether or not every node has read them.
+	StreamMaxLen int64
+
+	// StreamTTL drops a topic's stream once nothing has been published to
+	// it or subscribed to it for this long
+	StreamTTL time.Duration
+
+	// NodeRouting keeps track in Redis of which nodes have clients in each
+	// channel, server and user, and publishes events only to those nodes.
+	// Every node in a cluster has to agree on it.
+	NodeRouting bool
+}
+
 // PubSub manages Redis pub/sub connections for real-time message fan-out
 type PubSub struct {
-	client     *redis.Client
-	prefix     string
-	nodeID     string
-	
-	// Subscription management
-	subscriptions map[string]*redis.PubSub
-	subMux        sync.RWMutex
-	
+	client        *redis.Client
+	prefix        string
+	nodeID        string
+	transport     Transport
+	transportName string
+
+	// Which nodes want which topics; nil publishes to topics directly
+	routes *nodeRoutes
+
 	// Local handlers
-	handlers []Handler
+	handlers   []Handler
+	recorder   Recorder
 	handlerMux sync.RWMutex
-	
+
 	// Lifecycle
 	ctx    context.Context
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 }
 
-// New creates a new PubSub manager
+// New creates a new PubSub manager using Redis Pub/Sub
 func New(redisURL string, nodeID string) (*PubSub, error) {
-	opts, err := redis.ParseURL(redisURL)
+	return NewWithOptions(redisURL, nodeID, Options{})
+}
+
+// NewWithOptions creates a new PubSub manager with the transport opts
+// selects
+func NewWithOptions(redisURL string, nodeID string, opts Options) (*PubSub, error) {
+	redisOpts, err := redis.ParseURL(redisURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse redis URL: %w", err)
 	}
 
-	client := redis.NewClient(opts)
+	client := redis.NewClient(redisOpts)
+	psCtx, psCancel := context.WithCancel(context.Background())
+
+	ps := &PubSub{
+		client:        client,
+		prefix:        "hearth:pubsub:",
+		nodeID:        nodeID,
+		transportName: opts.Transport,
+		handlers:      make([]Handler, 0),
+		ctx:           psCtx,
+		cancel:        psCancel,
+	}
+	if ps.transportName == "" {
+		ps.transportName = TransportPubSub
+	}
+
+	ps.transport, err = newTransport(psCtx, client, nodeID, opts, ps.handleMessage, ps.transportFailed)
+	if err != nil {
+		psCancel()
+		client.Close()
+		return nil, err
+	}
 
 	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
+		psCancel()
+		client.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	psCtx, psCancel := context.WithCancel(context.Background())
+	if opts.NodeRouting {
+		ps.routes = newNodeRoutes(client, ps.prefix, nodeID)
+		if err := ps.transport.Subscribe(ps.routes.inbox(nodeID)); err != nil {
+			psCancel()
+			client.Close()
+			return nil, err
+		}
+		ps.wg.Add(1)
+		go ps.refreshRoutes()
+	}
 
-	ps := &PubSub{
-		client:        client,
-		prefix:        "hearth:pubsub:",
-		nodeID:        nodeID,
-		subscriptions: make(map[string]*redis.PubSub),
-		handlers:      make([]Handler, 0),
-		ctx:           psCtx,
-		cancel:        psCancel,
+	if reporter, ok := ps.transport.(backlogReporter); ok {
+		ps.wg.Add(1)
+		go ps.reportBacklog(reporter)
 	}
 
 	return ps, nil
 }
 
+// Client returns the underlying Redis client
+func (p *PubSub) Client() *redis.Client {
+	return p.client
+}
+
+// Transport returns the name of the transport in use
+func (p *PubSub) Transport() string {
+	return p.transportName
+}
+
+// SetRecorder sets where delivery measurements are reported
+func (p *PubSub) SetRecorder(recorder Recorder) {
+	p.handlerMux.Lock()
+	defer p.handlerMux.Unlock()
+	p.recorder = recorder
+}
+
+func (p *PubSub) getRecorder() Recorder {
+	p.handlerMux.RLock()
+	defer p.handlerMux.RUnlock()
+	return p.recorder
+}
+
 // OnMessage registers a handler for incoming pub/sub messages
 func (p *PubSub) OnMessage(handler Handler) {
 	p.handlerMux.Lock()
@@ -114,7 +214,28 @@ func (p *PubSub) Publish(ctx context.Context, msg *BroadcastMessage) error {
 	}
 
 	channel := p.resolveChannel(msg)
-	return p.client.Publish(ctx, channel, data).Err()
+	if p.routes == nil || channel == p.globalTopic() {
+		return p.transport.Publish(ctx, data, channel)
+	}
+
+	// Only to the other nodes with clients that want it
+	nodes, err := p.routes.nodes(ctx, channel)
+	if err != nil {
+		return fmt.Errorf("failed to look up routes for %s: %w", channel, err)
+	}
+	inboxes := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		if node != p.nodeID {
+			inboxes = append(inboxes, p.routes.inbox(node))
+		}
+	}
+	if recorder := p.getRecorder(); recorder != nil {
+		recorder.MessageRouted(len(inboxes))
+	}
+	if len(inboxes) == 0 {
+		return nil
+	}
+	return p.transport.Publish(ctx, data, inboxes...)
 }
 
 // PublishToChannel publishes a message to a specific channel
@@ -200,67 +321,31 @@ func (p *PubSub) UnsubscribeUser(userID uuid.UUID) error {
 
 // SubscribeGlobal subscribes to global broadcast events
 func (p *PubSub) SubscribeGlobal() error {
-	return p.subscribe(p.prefix + "global")
+	return p.transport.Subscribe(p.globalTopic())
 }
 
-func (p *PubSub) subscribe(channel string) error {
-	p.subMux.Lock()
-	defer p.subMux.Unlock()
-
-	if _, exists := p.subscriptions[channel]; exists {
-		return nil // Already subscribed
-	}
-
-	sub := p.client.Subscribe(p.ctx, channel)
-	
-	// Wait for subscription confirmation
-	_, err := sub.Receive(p.ctx)
-	if err != nil {
-		return fmt.Errorf("failed to subscribe to %s: %w", channel, err)
-	}
-
-	p.subscriptions[channel] = sub
-
-	// Start listening in a goroutine
-	p.wg.Add(1)
-	go p.listen(channel, sub)
-
-	return nil
+// globalTopic carries events for every node, so it's never routed
+func (p *PubSub) globalTopic() string {
+	return p.prefix + "global"
 }
 
-func (p *PubSub) unsubscribe(channel string) error {
-	p.subMux.Lock()
-	defer p.subMux.Unlock()
-
-	sub, exists := p.subscriptions[channel]
-	if !exists {
-		return nil
+func (p *PubSub) subscribe(channel string) error {
+	if p.routes != nil {
+		return p.routes.add(p.ctx, channel)
 	}
-
-	delete(p.subscriptions, channel)
-	return sub.Close()
+	return p.transport.Subscribe(channel)
 }
 
-func (p *PubSub) listen(channel string, sub *redis.PubSub) {
-	defer p.wg.Done()
-
-	ch := sub.Channel()
-	for {
-		select {
-		case <-p.ctx.Done():
-			return
-		case msg, ok := <-ch:
-			if !ok {
-				return
-			}
-			p.handleMessage(msg)
-		}
+func (p *PubSub) unsubscribe(channel string) error {
+	if p.routes != nil {
+		return p.routes.remove(p.ctx, channel)
 	}
+	return p.transport.Unsubscribe(channel)
 }
 
-func (p *PubSub) handleMessage(redisMsg *redis.Message) {
+func (p *PubSub) handleMessage(payload []byte, replayed bool) {
 	var msg BroadcastMessage
-	if err := json.Unmarshal([]byte(redisMsg.Payload), &msg); err != nil {
+	if err := json.Unmarshal(payload, &msg); err != nil {
 		log.Printf("Failed to unmarshal pub/sub message: %v", err)
 		return
 	}
@@ -274,13 +359,75 @@ func (p *PubSub) handleMessage(redisMsg *redis.Message) {
 	p.handlerMux.RLock()
 	handlers := make([]Handler, len(p.handlers))
 	copy(handlers, p.handlers)
+	recorder := p.recorder
 	p.handlerMux.RUnlock()
 
+	if recorder != nil {
+		recorder.MessageDelivered(p.transportName, time.Since(msg.Timestamp), replayed)
+	}
+
 	for _, handler := range handlers {
 		handler(&msg)
 	}
 }
 
+func (p *PubSub) transportFailed(op string) {
+	if recorder := p.getRecorder(); recorder != nil {
+		recorder.TransportFailed(p.transportName, op)
+	}
+}
+
+// refreshRoutes keeps this node's routes from expiring until closed
+func (p *PubSub) refreshRoutes() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(routeRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := p.routes.refresh(p.ctx); err != nil && p.ctx.Err() == nil {
+			p.transportFailed("route_refresh")
+			log.Printf("Failed to refresh pub/sub routes: %v", err)
+		}
+	}
+}
+
+// reportBacklog records how far behind the transport is until closed
+func (p *PubSub) reportBacklog(reporter backlogReporter) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(backlogInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		recorder := p.getRecorder()
+		if recorder == nil {
+			continue
+		}
+		lag, pending, err := reporter.Backlog(p.ctx)
+		if err != nil {
+			p.transportFailed("backlog")
+			continue
+		}
+		recorder.StreamBacklog(lag, pending)
+	}
+}
+
+// NodeRouting reports whether events are routed to interested nodes only
+func (p *PubSub) NodeRouting() bool {
+	return p.routes != nil
+}
+
 func (p *PubSub) resolveChannel(msg *BroadcastMessage) string {
 	if msg.ChannelID != nil {
 		return p.prefix + "channel:" + msg.ChannelID.String()
@@ -291,23 +438,24 @@ func (p *PubSub) resolveChannel(msg *BroadcastMessage) string {
 	if msg.UserID != nil {
 		return p.prefix + "user:" + msg.UserID.String()
 	}
-	return p.prefix + "global"
+	return p.globalTopic()
 }
 
 // Close gracefully shuts down the pub/sub manager
 func (p *PubSub) Close() error {
-	p.cancel()
-
-	p.subMux.Lock()
-	for _, sub := range p.subscriptions {
-		sub.Close()
+	if p.routes != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		if err := p.routes.clear(ctx); err != nil {
+			log.Printf("Failed to clear pub/sub routes: %v", err)
+		}
+		cancel()
 	}
-	p.subscriptions = make(map[string]*redis.PubSub)
-	p.subMux.Unlock()
+	p.cancel()
 
 	// Wait for all listeners to stop
 	done := make(chan struct{})
 	go func() {
+		p.transport.Close()
 		p.wg.Wait()
 		close(done)
 	}()
@@ -323,17 +471,24 @@ func (p *PubSub) Close() error {
 
 // Stats returns current subscription statistics
 func (p *PubSub) Stats() map[string]interface{} {
-	p.subMux.RLock()
-	defer p.subMux.RUnlock()
-
-	channels := make([]string, 0, len(p.subscriptions))
-	for ch := range p.subscriptions {
-		channels = append(channels, ch)
+	channels := p.transport.Topics()
+	if p.routes != nil {
+		// The inbox stands in for the routed topics
+		inbox := p.routes.inbox(p.nodeID)
+		routed := p.routes.list()
+		for _, topic := range channels {
+			if topic != inbox {
+				routed = append(routed, topic)
+			}
+		}
+		channels = routed
 	}
 
 	return map[string]interface{}{
 		"node_id":            p.nodeID,
-		"subscription_count": len(p.subscriptions),
+		"transport":          p.transportName,
+		"node_routing":       p.routes != nil,
+		"subscription_count": len(channels),
 		"channels":           channels,
 	}
 }
@@ -0,0 +1,146 @@
+package pubsub
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// routeTTL is how long a node's route lasts without being refreshed,
+	// so a node that dies stops receiving events soon after
+	routeTTL = 90 * time.Second
+
+	// routeRefreshInterval is how often a node renews its routes
+	routeRefreshInterval = 30 * time.Second
+)
+
+// nodeRoutes records which nodes have clients interested in each topic, in
+// a sorted set per topic of node IDs scored by when their entry expires.
+// Events are published only to those nodes' inboxes instead of to every
+// node subscribed to the topic.
+type nodeRoutes struct {
+	client *redis.Client
+	prefix string
+	nodeID string
+
+	topics map[string]bool
+	mu     sync.Mutex
+}
+
+func newNodeRoutes(client *redis.Client, prefix, nodeID string) *nodeRoutes {
+	return &nodeRoutes{
+		client: client,
+		prefix: prefix,
+		nodeID: nodeID,
+		topics: make(map[string]bool),
+	}
+}
+
+// key is the sorted set of nodes routed to for topic
+func (r *nodeRoutes) key(topic string) string {
+	return r.prefix + "route:" + strings.TrimPrefix(topic, r.prefix)
+}
+
+// inbox is the topic a node reads its routed events from
+func (r *nodeRoutes) inbox(nodeID string) string {
+	return r.prefix + "node:" + nodeID
+}
+
+// add routes topic's events to this node
+func (r *nodeRoutes) add(ctx context.Context, topic string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.topics[topic] {
+		return nil
+	}
+	if err := r.register(ctx, []string{topic}); err != nil {
+		return err
+	}
+	r.topics[topic] = true
+	return nil
+}
+
+// remove stops routing topic's events to this node
+func (r *nodeRoutes) remove(ctx context.Context, topic string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.topics[topic] {
+		return nil
+	}
+	delete(r.topics, topic)
+	return r.client.ZRem(ctx, r.key(topic), r.nodeID).Err()
+}
+
+// register adds this node to each topic's route until routeTTL from now.
+// The key lives as long as its newest entry.
+func (r *nodeRoutes) register(ctx context.Context, topics []string) error {
+	expires := float64(time.Now().Add(routeTTL).Unix())
+
+	pipe := r.client.Pipeline()
+	for _, topic := range topics {
+		key := r.key(topic)
+		pipe.ZAdd(ctx, key, redis.Z{Score: expires, Member: r.nodeID})
+		pipe.Expire(ctx, key, routeTTL)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// refresh renews this node's routes and prunes nodes whose entries expired
+func (r *nodeRoutes) refresh(ctx context.Context) error {
+	topics := r.list()
+	if len(topics) == 0 {
+		return nil
+	}
+	if err := r.register(ctx, topics); err != nil {
+		return err
+	}
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	pipe := r.client.Pipeline()
+	for _, topic := range topics {
+		pipe.ZRemRangeByScore(ctx, r.key(topic), "-inf", now)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// clear removes all of this node's routes, so other nodes stop sending to
+// it as soon as it shuts down
+func (r *nodeRoutes) clear(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pipe := r.client.Pipeline()
+	for topic := range r.topics {
+		pipe.ZRem(ctx, r.key(topic), r.nodeID)
+	}
+	r.topics = make(map[string]bool)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// nodes returns the nodes with a live route to topic
+func (r *nodeRoutes) nodes(ctx context.Context, topic string) ([]string, error) {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	return r.client.ZRangeByScore(ctx, r.key(topic), &redis.ZRangeBy{Min: "(" + now, Max: "+inf"}).Result()
+}
+
+// list returns the topics routed to this node
+func (r *nodeRoutes) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	topics := make([]string, 0, len(r.topics))
+	for topic := range r.topics {
+		topics = append(topics, topic)
+	}
+	return topics
+}
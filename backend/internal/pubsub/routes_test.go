@@ -0,0 +1,66 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRoutedPubSub(t *testing.T, nodeID string) *PubSub {
+	ps, err := NewWithOptions(getRedisURL(), nodeID, Options{NodeRouting: true})
+	require.NoError(t, err)
+	return ps
+}
+
+func TestNodeRoutesKeys(t *testing.T) {
+	r := newNodeRoutes(nil, "hearth:pubsub:", "node-1")
+	assert.Equal(t, "hearth:pubsub:route:server:abc", r.key("hearth:pubsub:server:abc"))
+	assert.Equal(t, "hearth:pubsub:node:node-2", r.inbox("node-2"))
+}
+
+func TestNodeRoutingPublishesOnlyToInterestedNodes(t *testing.T) {
+	skipIfNoRedis(t)
+
+	publisher := newRoutedPubSub(t, "routed-pub-"+uuid.NewString())
+	defer publisher.Close()
+	interested := newRoutedPubSub(t, "routed-yes-"+uuid.NewString())
+	defer interested.Close()
+	other := newRoutedPubSub(t, "routed-no-"+uuid.NewString())
+	defer other.Close()
+
+	serverID := uuid.New()
+	require.NoError(t, interested.SubscribeServer(serverID))
+	require.NoError(t, other.SubscribeServer(uuid.New()))
+
+	received := make(chan *BroadcastMessage, 1)
+	interested.OnMessage(func(msg *BroadcastMessage) { received <- msg })
+	stray := make(chan *BroadcastMessage, 1)
+	other.OnMessage(func(msg *BroadcastMessage) { stray <- msg })
+
+	nodes, err := publisher.routes.nodes(context.Background(), publisher.resolveChannel(&BroadcastMessage{ServerID: &serverID}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{interested.nodeID}, nodes)
+
+	require.NoError(t, publisher.PublishToServer(context.Background(), serverID, TypeServerUpdate, "hello"))
+	select {
+	case msg := <-received:
+		assert.Equal(t, serverID, *msg.ServerID)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for routed message")
+	}
+	select {
+	case <-stray:
+		t.Fatal("Node without clients in the server should not receive it")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	// Once the node closes, nothing is routed to it
+	require.NoError(t, interested.Close())
+	nodes, err = publisher.routes.nodes(context.Background(), publisher.resolveChannel(&BroadcastMessage{ServerID: &serverID}))
+	require.NoError(t, err)
+	assert.Empty(t, nodes)
+}
@@ -0,0 +1,299 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// Defaults for Options left unset
+	defaultStreamMaxLen = 1000
+	defaultStreamTTL    = 24 * time.Hour
+
+	// streamPayloadField is the stream entry field holding the message
+	streamPayloadField = "m"
+
+	// streamReadCount is how many entries are read per stream at a time
+	streamReadCount = 128
+
+	// streamReadBlock is how long a read waits for new entries. Topics
+	// subscribed meanwhile are read from once it returns; nothing
+	// published to them is lost, since their group already exists.
+	streamReadBlock = 500 * time.Millisecond
+
+	// streamRetryBackoff is how long to wait after a failed read
+	streamRetryBackoff = time.Second
+)
+
+// streamTransport keeps one Redis Stream per topic. Every node has its own
+// consumer group, named after the node, on each stream it subscribes to, so
+// each node sees every entry and Redis remembers how far it got. Entries
+// are acknowledged once handled; a node that drops its connection or
+// restarts with the same node ID picks up where it stopped.
+type streamTransport struct {
+	client  *redis.Client
+	ctx     context.Context
+	group   string
+	maxLen  int64
+	ttl     time.Duration
+	deliver deliverFunc
+	failed  func(op string)
+
+	topics map[string]bool
+	mu     sync.Mutex
+	wake   chan struct{}
+	start  sync.Once
+	wg     sync.WaitGroup
+}
+
+func newStreamTransport(ctx context.Context, client *redis.Client, nodeID string, opts Options, deliver deliverFunc, failed func(op string)) *streamTransport {
+	t := &streamTransport{
+		client:  client,
+		ctx:     ctx,
+		group:   nodeID,
+		maxLen:  opts.StreamMaxLen,
+		ttl:     opts.StreamTTL,
+		deliver: deliver,
+		failed:  failed,
+		topics:  make(map[string]bool),
+		wake:    make(chan struct{}, 1),
+	}
+	if t.maxLen <= 0 {
+		t.maxLen = defaultStreamMaxLen
+	}
+	if t.ttl <= 0 {
+		t.ttl = defaultStreamTTL
+	}
+	return t
+}
+
+// Publish appends payload to each topic's stream, trimming it to about
+// maxLen entries. Streams nobody publishes to expire after ttl.
+func (t *streamTransport) Publish(ctx context.Context, payload []byte, topics ...string) error {
+	pipe := t.client.Pipeline()
+	for _, topic := range topics {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: topic,
+			MaxLen: t.maxLen,
+			Approx: true,
+			Values: []interface{}{streamPayloadField, payload},
+		})
+		pipe.Expire(ctx, topic, t.ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (t *streamTransport) Subscribe(topic string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.topics[topic] {
+		return nil // Already subscribed
+	}
+	if err := t.createGroup(t.ctx, topic); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", topic, err)
+	}
+	t.topics[topic] = true
+
+	t.start.Do(func() {
+		t.wg.Add(1)
+		go t.run()
+	})
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Unsubscribe destroys this node's group, so subscribing again later
+// starts from new entries instead of replaying everything since
+func (t *streamTransport) Unsubscribe(topic string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.topics[topic] {
+		return nil
+	}
+	delete(t.topics, topic)
+	return t.client.XGroupDestroy(t.ctx, topic, t.group).Err()
+}
+
+// createGroup makes this node's group on topic's stream. A new group starts
+// at the end of the stream; one this node left behind before a restart
+// carries on from where it stopped.
+func (t *streamTransport) createGroup(ctx context.Context, topic string) error {
+	err := t.client.XGroupCreateMkStream(ctx, topic, t.group, "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	// A stream only subscribed to would otherwise never expire
+	return t.client.Expire(ctx, topic, t.ttl).Err()
+}
+
+// run reads every subscribed stream until the transport is closed
+func (t *streamTransport) run() {
+	defer t.wg.Done()
+
+	// Entries read but not acknowledged before a restart or a dropped
+	// connection are read again first
+	replay := true
+	for t.ctx.Err() == nil {
+		topics := t.Topics()
+		if len(topics) == 0 {
+			select {
+			case <-t.wake:
+			case <-t.ctx.Done():
+			}
+			continue
+		}
+
+		read, err := t.read(topics, replay)
+		if err != nil {
+			if t.ctx.Err() != nil {
+				return
+			}
+			// Unsubscribing destroys a group mid-read, and a stream that
+			// expired took this node's group with it
+			if strings.HasPrefix(err.Error(), "NOGROUP") {
+				t.recreateGroups()
+			} else {
+				t.failed("read")
+				log.Printf("Failed to read pub/sub streams: %v", err)
+				select {
+				case <-time.After(streamRetryBackoff):
+				case <-t.ctx.Done():
+				}
+			}
+			replay = true
+			continue
+		}
+		if replay && read == 0 {
+			replay = false
+		}
+	}
+}
+
+// read delivers and acknowledges one batch from each stream: unacknowledged
+// entries when replaying, otherwise new ones, waiting for them a while
+func (t *streamTransport) read(topics []string, replay bool) (int, error) {
+	start := ">"
+	if replay {
+		start = "0"
+	}
+	streams := make([]string, 0, 2*len(topics))
+	streams = append(streams, topics...)
+	for range topics {
+		streams = append(streams, start)
+	}
+	args := &redis.XReadGroupArgs{
+		Group:    t.group,
+		Consumer: t.group,
+		Streams:  streams,
+		Count:    streamReadCount,
+	}
+	if !replay {
+		args.Block = streamReadBlock
+	}
+
+	result, err := t.client.XReadGroup(t.ctx, args).Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	read := 0
+	for _, stream := range result {
+		if len(stream.Messages) == 0 {
+			continue
+		}
+		ids := make([]string, 0, len(stream.Messages))
+		for _, msg := range stream.Messages {
+			if payload, ok := msg.Values[streamPayloadField].(string); ok {
+				t.deliver([]byte(payload), replay)
+			}
+			ids = append(ids, msg.ID)
+		}
+		read += len(ids)
+
+		if err := t.client.XAck(t.ctx, stream.Stream, t.group, ids...).Err(); err != nil {
+			// They're delivered again on the next replay
+			t.failed("ack")
+			log.Printf("Failed to acknowledge %d entries on %s: %v", len(ids), stream.Stream, err)
+		}
+	}
+	return read, nil
+}
+
+// recreateGroups makes sure this node has a group on each subscribed
+// stream again
+func (t *streamTransport) recreateGroups() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for topic := range t.topics {
+		if err := t.createGroup(t.ctx, topic); err != nil {
+			log.Printf("Failed to recreate pub/sub group on %s: %v", topic, err)
+		}
+	}
+}
+
+// Backlog returns how many entries on the subscribed streams this node has
+// yet to read, and how many it read without acknowledging
+func (t *streamTransport) Backlog(ctx context.Context) (lag, pending int64, err error) {
+	topics := t.Topics()
+	if len(topics) == 0 {
+		return 0, 0, nil
+	}
+
+	pipe := t.client.Pipeline()
+	cmds := make([]*redis.XInfoGroupsCmd, len(topics))
+	for i, topic := range topics {
+		cmds[i] = pipe.XInfoGroups(ctx, topic)
+	}
+	// Streams that went away fail on their own; the rest still count
+	if _, err := pipe.Exec(ctx); err != nil && ctx.Err() != nil {
+		return 0, 0, err
+	}
+
+	for _, cmd := range cmds {
+		groups, err := cmd.Result()
+		if err != nil {
+			continue
+		}
+		for _, group := range groups {
+			if group.Name == t.group {
+				lag += group.Lag
+				pending += group.Pending
+			}
+		}
+	}
+	return lag, pending, nil
+}
+
+func (t *streamTransport) Topics() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	topics := make([]string, 0, len(t.topics))
+	for topic := range t.topics {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// Close waits for the reader to stop. Groups are left in place, so a node
+// restarting under the same ID replays what it missed.
+func (t *streamTransport) Close() error {
+	t.wg.Wait()
+	return nil
+}
@@ -0,0 +1,148 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRecorder struct {
+	delivered []time.Duration
+	replayed  int
+}
+
+func (r *fakeRecorder) MessageDelivered(transport string, latency time.Duration, replayed bool) {
+	r.delivered = append(r.delivered, latency)
+	if replayed {
+		r.replayed++
+	}
+}
+
+func (r *fakeRecorder) TransportFailed(transport, op string) {}
+
+func (r *fakeRecorder) StreamBacklog(lag, pending int64) {}
+
+func (r *fakeRecorder) MessageRouted(nodes int) {}
+
+func newStreamsPubSub(t *testing.T, nodeID string) *PubSub {
+	ps, err := NewWithOptions(getRedisURL(), nodeID, Options{Transport: TransportStreams})
+	require.NoError(t, err)
+	return ps
+}
+
+func TestNewWithOptionsUnknownTransport(t *testing.T) {
+	_, err := NewWithOptions(getRedisURL(), "test-node", Options{Transport: "carrier-pigeon"})
+	assert.ErrorContains(t, err, "unknown pub/sub transport")
+}
+
+func TestHandleMessageRecordsDelivery(t *testing.T) {
+	recorder := &fakeRecorder{}
+	ps := &PubSub{nodeID: "node-2", transportName: TransportStreams}
+	ps.SetRecorder(recorder)
+
+	var received []*BroadcastMessage
+	ps.OnMessage(func(msg *BroadcastMessage) { received = append(received, msg) })
+
+	theirs, _ := json.Marshal(&BroadcastMessage{Type: TypeMessageCreate, OriginNode: "node-1", Timestamp: time.Now().Add(-time.Second)})
+	ours, _ := json.Marshal(&BroadcastMessage{Type: TypeMessageCreate, OriginNode: "node-2", Timestamp: time.Now()})
+	ps.handleMessage(theirs, true)
+	ps.handleMessage(ours, false)
+
+	require.Len(t, received, 1)
+	require.Len(t, recorder.delivered, 1, "our own messages aren't counted")
+	assert.GreaterOrEqual(t, recorder.delivered[0], time.Second)
+	assert.Equal(t, 1, recorder.replayed)
+}
+
+func TestStreamsPublishAndReceive(t *testing.T) {
+	skipIfNoRedis(t)
+
+	ps1 := newStreamsPubSub(t, "streams-node-1-"+uuid.NewString())
+	defer ps1.Close()
+	ps2 := newStreamsPubSub(t, "streams-node-2-"+uuid.NewString())
+	defer ps2.Close()
+	assert.Equal(t, TransportStreams, ps2.Stats()["transport"])
+
+	received := make(chan *BroadcastMessage, 10)
+	ps2.OnMessage(func(msg *BroadcastMessage) { received <- msg })
+
+	channelID := uuid.New()
+	require.NoError(t, ps2.SubscribeChannel(channelID))
+	require.NoError(t, ps1.PublishToChannel(context.Background(), channelID, TypeMessageCreate, "hello"))
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, channelID, *msg.ChannelID)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for message")
+	}
+}
+
+func TestStreamsReplayMissedMessages(t *testing.T) {
+	skipIfNoRedis(t)
+
+	publisher := newStreamsPubSub(t, "streams-pub-"+uuid.NewString())
+	defer publisher.Close()
+
+	// The subscriber goes away without unsubscribing, as a node that
+	// crashes or loses Redis does
+	nodeID := "streams-sub-" + uuid.NewString()
+	channelID := uuid.New()
+	subscriber := newStreamsPubSub(t, nodeID)
+	require.NoError(t, subscriber.SubscribeChannel(channelID))
+	require.NoError(t, subscriber.Close())
+
+	for _, content := range []string{"1", "2", "3"} {
+		require.NoError(t, publisher.PublishToChannel(context.Background(), channelID, TypeMessageCreate, content))
+	}
+
+	// Coming back under the same node ID picks up where it stopped
+	subscriber = newStreamsPubSub(t, nodeID)
+	defer subscriber.Close()
+	received := make(chan string, 10)
+	subscriber.OnMessage(func(msg *BroadcastMessage) {
+		var content string
+		json.Unmarshal(msg.Data, &content)
+		received <- content
+	})
+	require.NoError(t, subscriber.SubscribeChannel(channelID))
+
+	for _, want := range []string{"1", "2", "3"} {
+		select {
+		case got := <-received:
+			assert.Equal(t, want, got)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for message %s", want)
+		}
+	}
+}
+
+func TestStreamsUnsubscribeDoesNotReplay(t *testing.T) {
+	skipIfNoRedis(t)
+
+	publisher := newStreamsPubSub(t, "streams-pub-"+uuid.NewString())
+	defer publisher.Close()
+	subscriber := newStreamsPubSub(t, "streams-sub-"+uuid.NewString())
+	defer subscriber.Close()
+
+	received := make(chan *BroadcastMessage, 10)
+	subscriber.OnMessage(func(msg *BroadcastMessage) { received <- msg })
+
+	channelID := uuid.New()
+	require.NoError(t, subscriber.SubscribeChannel(channelID))
+	require.NoError(t, subscriber.UnsubscribeChannel(channelID))
+	require.NoError(t, publisher.PublishToChannel(context.Background(), channelID, TypeMessageCreate, "missed"))
+
+	// Subscribing again starts from new messages
+	require.NoError(t, subscriber.SubscribeChannel(channelID))
+	select {
+	case <-received:
+		t.Fatal("Should not replay messages from while unsubscribed")
+	case <-time.After(time.Second):
+	}
+}
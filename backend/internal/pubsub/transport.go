@@ -0,0 +1,164 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Transport carries encoded messages between nodes. A node subscribes to
+// the topics it has local interest in and is handed every message
+// published to them.
+type Transport interface {
+	// Publish sends payload to every node subscribed to each topic
+	Publish(ctx context.Context, payload []byte, topics ...string) error
+	// Subscribe starts delivering topic's messages. Subscribing twice is
+	// a no-op.
+	Subscribe(topic string) error
+	// Unsubscribe stops delivering topic's messages
+	Unsubscribe(topic string) error
+	// Topics returns the subscribed topics
+	Topics() []string
+	// Close stops delivery and waits for it to finish
+	Close() error
+}
+
+// Transport names
+const (
+	// TransportPubSub uses Redis Pub/Sub: fire and forget, so a node that
+	// is disconnected or falls behind misses messages
+	TransportPubSub = "pubsub"
+	// TransportStreams uses a Redis Stream per topic with a consumer group
+	// per node: delivery is at least once, and messages published while a
+	// node was away are replayed when it comes back
+	TransportStreams = "streams"
+)
+
+// deliverFunc hands a received payload to the PubSub. replayed is set for
+// messages delivered again after a reconnect.
+type deliverFunc func(payload []byte, replayed bool)
+
+// newTransport creates the transport opts name. failed is told about
+// transport operations that fail outside a caller's request.
+func newTransport(ctx context.Context, client *redis.Client, nodeID string, opts Options, deliver deliverFunc, failed func(op string)) (Transport, error) {
+	switch opts.Transport {
+	case "", TransportPubSub:
+		return newChannelTransport(ctx, client, deliver), nil
+	case TransportStreams:
+		return newStreamTransport(ctx, client, nodeID, opts, deliver, failed), nil
+	}
+	return nil, fmt.Errorf("unknown pub/sub transport %q", opts.Transport)
+}
+
+// channelTransport is the Redis Pub/Sub transport, one subscription per
+// topic
+type channelTransport struct {
+	client  *redis.Client
+	ctx     context.Context
+	deliver deliverFunc
+
+	subscriptions map[string]*redis.PubSub
+	subMux        sync.RWMutex
+	wg            sync.WaitGroup
+}
+
+func newChannelTransport(ctx context.Context, client *redis.Client, deliver deliverFunc) *channelTransport {
+	return &channelTransport{
+		client:        client,
+		ctx:           ctx,
+		deliver:       deliver,
+		subscriptions: make(map[string]*redis.PubSub),
+	}
+}
+
+func (t *channelTransport) Publish(ctx context.Context, payload []byte, topics ...string) error {
+	if len(topics) == 1 {
+		return t.client.Publish(ctx, topics[0], payload).Err()
+	}
+	pipe := t.client.Pipeline()
+	for _, topic := range topics {
+		pipe.Publish(ctx, topic, payload)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (t *channelTransport) Subscribe(topic string) error {
+	t.subMux.Lock()
+	defer t.subMux.Unlock()
+
+	if _, exists := t.subscriptions[topic]; exists {
+		return nil // Already subscribed
+	}
+
+	sub := t.client.Subscribe(t.ctx, topic)
+
+	// Wait for subscription confirmation
+	_, err := sub.Receive(t.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", topic, err)
+	}
+
+	t.subscriptions[topic] = sub
+
+	// Start listening in a goroutine
+	t.wg.Add(1)
+	go t.listen(sub)
+
+	return nil
+}
+
+func (t *channelTransport) Unsubscribe(topic string) error {
+	t.subMux.Lock()
+	defer t.subMux.Unlock()
+
+	sub, exists := t.subscriptions[topic]
+	if !exists {
+		return nil
+	}
+
+	delete(t.subscriptions, topic)
+	return sub.Close()
+}
+
+func (t *channelTransport) listen(sub *redis.PubSub) {
+	defer t.wg.Done()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			t.deliver([]byte(msg.Payload), false)
+		}
+	}
+}
+
+func (t *channelTransport) Topics() []string {
+	t.subMux.RLock()
+	defer t.subMux.RUnlock()
+
+	topics := make([]string, 0, len(t.subscriptions))
+	for topic := range t.subscriptions {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+func (t *channelTransport) Close() error {
+	t.subMux.Lock()
+	for _, sub := range t.subscriptions {
+		sub.Close()
+	}
+	t.subscriptions = make(map[string]*redis.PubSub)
+	t.subMux.Unlock()
+
+	t.wg.Wait()
+	return nil
+}
@@ -0,0 +1,182 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"hearth/internal/models"
+)
+
+const (
+	apnsProduction = "https://api.push.apple.com"
+	apnsSandbox    = "https://api.sandbox.push.apple.com"
+
+	// Apple rejects provider tokens older than an hour and throttles
+	// refreshing them more often than every 20 minutes
+	apnsTokenLifetime = 50 * time.Minute
+)
+
+// APNsConfig configures token-based delivery to Apple devices
+type APNsConfig struct {
+	// KeyFile is the .p8 signing key from the Apple developer portal
+	KeyFile string
+	KeyID   string
+	TeamID  string
+	// Topic is the app's bundle ID
+	Topic      string
+	Production bool
+
+	// Endpoint overrides the APNs base URL
+	Endpoint string
+	Client   *http.Client
+}
+
+// APNs sends notifications to iOS and macOS devices
+type APNs struct {
+	keyID    string
+	teamID   string
+	topic    string
+	endpoint string
+	client   *http.Client
+	key      *ecdsa.PrivateKey
+
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+// NewAPNs creates an APNs driver from a .p8 signing key
+func NewAPNs(cfg APNsConfig) (*APNs, error) {
+	raw, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read APNs key: %w", err)
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse APNs key: %w", err)
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = apnsSandbox
+		if cfg.Production {
+			endpoint = apnsProduction
+		}
+	}
+
+	// APNs requires HTTP/2, which the default transport negotiates over TLS
+	return &APNs{
+		keyID:    cfg.KeyID,
+		teamID:   cfg.TeamID,
+		topic:    cfg.Topic,
+		endpoint: strings.TrimRight(endpoint, "/"),
+		client:   defaultClient(cfg.Client),
+		key:      key,
+	}, nil
+}
+
+// Platform implements Driver
+func (a *APNs) Platform() models.PushPlatform {
+	return models.PushPlatformAPNs
+}
+
+// Send implements Driver
+func (a *APNs) Send(ctx context.Context, device *models.PushDevice, n *Notification) error {
+	token, err := a.providerToken()
+	if err != nil {
+		return err
+	}
+
+	aps := map[string]interface{}{
+		"alert": map[string]string{
+			"title": n.Title,
+			"body":  n.Body,
+		},
+		"sound": "default",
+	}
+	if n.Tag != "" {
+		aps["thread-id"] = n.Tag
+	}
+	payload := map[string]interface{}{"aps": aps}
+	for k, v := range n.Data {
+		payload[k] = v
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint+"/3/device/"+device.Token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+token)
+	req.Header.Set("apns-topic", a.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	if resp.StatusCode == http.StatusBadRequest {
+		// A bad token comes back as a 400 with a reason rather than a 410
+		var reason struct {
+			Reason string `json:"reason"`
+		}
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if json.Unmarshal(raw, &reason) == nil && (reason.Reason == "BadDeviceToken" || reason.Reason == "DeviceTokenNotForTopic") {
+			return ErrInvalidToken
+		}
+		return fmt.Errorf("%w: apns returned 400: %s", ErrRejected, raw)
+	}
+	if resp.StatusCode == http.StatusForbidden {
+		// Stale or invalid provider token
+		a.mu.Lock()
+		a.token = ""
+		a.mu.Unlock()
+	}
+	return statusError("apns", resp)
+}
+
+// providerToken returns the signed JWT APNs authenticates requests with
+func (a *APNs) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != "" && time.Since(a.issuedAt) < apnsTokenLifetime {
+		return a.token, nil
+	}
+
+	now := time.Now()
+	t := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": a.teamID,
+		"iat": now.Unix(),
+	})
+	t.Header["kid"] = a.keyID
+
+	signed, err := t.SignedString(a.key)
+	if err != nil {
+		return "", fmt.Errorf("sign APNs token: %w", err)
+	}
+	a.token = signed
+	a.issuedAt = now
+	return signed, nil
+}
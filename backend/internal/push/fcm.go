@@ -0,0 +1,200 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"hearth/internal/models"
+)
+
+const (
+	fcmEndpoint = "https://fcm.googleapis.com"
+	fcmScope    = "https://www.googleapis.com/auth/firebase.messaging"
+)
+
+// FCMConfig configures delivery through the FCM HTTP v1 API
+type FCMConfig struct {
+	ProjectID string
+	// CredentialsFile is a Google service account key in JSON
+	CredentialsFile string
+
+	// Endpoint overrides the FCM API base URL
+	Endpoint string
+	Client   *http.Client
+}
+
+type fcmCredentials struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// FCM sends notifications to Android and iOS devices through Firebase
+type FCM struct {
+	projectID string
+	endpoint  string
+	client    *http.Client
+	creds     fcmCredentials
+	key       *rsa.PrivateKey
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFCM creates an FCM driver from a service account key
+func NewFCM(cfg FCMConfig) (*FCM, error) {
+	raw, err := os.ReadFile(cfg.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("read FCM credentials: %w", err)
+	}
+
+	var creds fcmCredentials
+	if err := json.Unmarshal(raw, &creds); err != nil {
+		return nil, fmt.Errorf("parse FCM credentials: %w", err)
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(creds.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("parse FCM private key: %w", err)
+	}
+	if creds.TokenURI == "" {
+		creds.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = fcmEndpoint
+	}
+
+	return &FCM{
+		projectID: cfg.ProjectID,
+		endpoint:  strings.TrimRight(endpoint, "/"),
+		client:    defaultClient(cfg.Client),
+		creds:     creds,
+		key:       key,
+	}, nil
+}
+
+// Platform implements Driver
+func (f *FCM) Platform() models.PushPlatform {
+	return models.PushPlatformFCM
+}
+
+// Send implements Driver
+func (f *FCM) Send(ctx context.Context, device *models.PushDevice, n *Notification) error {
+	token, err := f.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	message := map[string]interface{}{
+		"token": device.Token,
+		"notification": map[string]string{
+			"title": n.Title,
+			"body":  n.Body,
+		},
+	}
+	if len(n.Data) > 0 {
+		message["data"] = n.Data
+	}
+	if n.Tag != "" {
+		message["android"] = map[string]interface{}{
+			"notification": map[string]string{"tag": n.Tag},
+		}
+	}
+	body, err := json.Marshal(map[string]interface{}{"message": message})
+	if err != nil {
+		return err
+	}
+
+	sendURL := fmt.Sprintf("%s/v1/projects/%s/messages:send", f.endpoint, f.projectID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sendURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		// Let the next attempt fetch a fresh access token
+		f.mu.Lock()
+		f.accessToken = ""
+		f.mu.Unlock()
+	}
+	return statusError("fcm", resp)
+}
+
+// token returns an OAuth2 access token, exchanging a signed service account
+// assertion when the cached one is about to expire
+func (f *FCM) token(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.accessToken != "" && time.Until(f.expiresAt) > time.Minute {
+		return f.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   f.creds.ClientEmail,
+		"scope": fcmScope,
+		"aud":   f.creds.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(f.key)
+	if err != nil {
+		return "", fmt.Errorf("sign FCM assertion: %w", err)
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.creds.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fcm token exchange returned %d", resp.StatusCode)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decode FCM token: %w", err)
+	}
+
+	f.accessToken = result.AccessToken
+	f.expiresAt = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	return f.accessToken, nil
+}
@@ -0,0 +1,66 @@
+// Package push delivers notifications to devices through FCM, APNs and
+// WebPush.
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"hearth/internal/models"
+)
+
+var (
+	// ErrInvalidToken means the device is no longer registered with the
+	// push service and should be forgotten
+	ErrInvalidToken = errors.New("push: device token is no longer valid")
+
+	// ErrRejected means the push service refused the notification itself.
+	// Retrying won't help.
+	ErrRejected = errors.New("push: notification rejected")
+)
+
+// Notification is the content delivered to a device
+type Notification struct {
+	Title string
+	Body  string
+	// Tag groups related notifications, e.g. by channel
+	Tag  string
+	Data map[string]string
+}
+
+// Driver delivers notifications for one push platform
+type Driver interface {
+	Platform() models.PushPlatform
+	Send(ctx context.Context, device *models.PushDevice, n *Notification) error
+}
+
+const defaultTimeout = 10 * time.Second
+
+func defaultClient(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return &http.Client{Timeout: defaultTimeout}
+}
+
+// statusError classifies a push service response. 404 and 410 mean the
+// token is gone, other 4xx the notification was refused, and anything
+// else is worth retrying.
+func statusError(service string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrInvalidToken
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("%s: rate limited: %s", service, body)
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return fmt.Errorf("%w: %s returned %d: %s", ErrRejected, service, resp.StatusCode, body)
+	default:
+		return fmt.Errorf("%s returned %d: %s", service, resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,329 @@
+package push
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+func testNotification() *Notification {
+	return &Notification{
+		Title: "alice in #general",
+		Body:  "hello",
+		Tag:   "channel-1",
+		Data:  map[string]string{"channel_id": "channel-1"},
+	}
+}
+
+func writeTempFile(t *testing.T, name string, data []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+// decryptWebPush reverses encryptWebPush the way a browser would
+func decryptWebPush(t *testing.T, body []byte, uaKey *ecdh.PrivateKey, auth []byte) []byte {
+	salt := body[:16]
+	rs := binary.BigEndian.Uint32(body[16:20])
+	idLen := int(body[20])
+	asPublic := body[21 : 21+idLen]
+	ciphertext := body[21+idLen:]
+	assert.Equal(t, uint32(webPushRecordSize), rs)
+
+	asKey, err := ecdh.P256().NewPublicKey(asPublic)
+	require.NoError(t, err)
+	shared, err := uaKey.ECDH(asKey)
+	require.NoError(t, err)
+
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaKey.PublicKey().Bytes()...), asPublic...)
+	ikm, err := hkdfBytes(shared, auth, keyInfo, 32)
+	require.NoError(t, err)
+	cek, err := hkdfBytes(ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	require.NoError(t, err)
+	nonce, err := hkdfBytes(ikm, salt, []byte("Content-Encoding: nonce\x00"), 12)
+	require.NoError(t, err)
+
+	block, err := aes.NewCipher(cek)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, byte(0x02), plaintext[len(plaintext)-1])
+	return plaintext[:len(plaintext)-1]
+}
+
+func newWebPushSubscription(t *testing.T, endpoint string) (*models.PushDevice, *ecdh.PrivateKey, []byte) {
+	uaKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	auth := make([]byte, 16)
+	_, err = rand.Read(auth)
+	require.NoError(t, err)
+
+	p256dh := base64.RawURLEncoding.EncodeToString(uaKey.PublicKey().Bytes())
+	authSecret := base64.RawURLEncoding.EncodeToString(auth)
+	return &models.PushDevice{
+		Platform: models.PushPlatformWebPush,
+		Token:    endpoint,
+		P256dh:   &p256dh,
+		Auth:     &authSecret,
+	}, uaKey, auth
+}
+
+func newWebPush(t *testing.T) *WebPush {
+	vapid, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	w, err := NewWebPush(WebPushConfig{
+		PrivateKey: base64.RawURLEncoding.EncodeToString(vapid.Bytes()),
+		Subject:    "mailto:admin@example.com",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(vapid.PublicKey().Bytes()), w.PublicKey())
+	return w
+}
+
+func TestWebPushSend(t *testing.T) {
+	var (
+		gotBody    []byte
+		gotHeaders http.Header
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotHeaders = r.Header
+		rw.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	w := newWebPush(t)
+	device, uaKey, auth := newWebPushSubscription(t, srv.URL+"/push/abc")
+
+	require.NoError(t, w.Send(context.Background(), device, testNotification()))
+
+	assert.Equal(t, "aes128gcm", gotHeaders.Get("Content-Encoding"))
+	assert.Equal(t, "86400", gotHeaders.Get("TTL"))
+	assert.Len(t, gotHeaders.Get("Topic"), 32)
+	assert.True(t, strings.HasPrefix(gotHeaders.Get("Authorization"), "vapid t="))
+	assert.Contains(t, gotHeaders.Get("Authorization"), "k="+w.PublicKey())
+
+	var payload struct {
+		Title string            `json:"title"`
+		Body  string            `json:"body"`
+		Data  map[string]string `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(decryptWebPush(t, gotBody, uaKey, auth), &payload))
+	assert.Equal(t, "alice in #general", payload.Title)
+	assert.Equal(t, "hello", payload.Body)
+	assert.Equal(t, "channel-1", payload.Data["channel_id"])
+}
+
+func TestWebPushSend_Errors(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusGone, ErrInvalidToken},
+		{http.StatusNotFound, ErrInvalidToken},
+		{http.StatusRequestEntityTooLarge, ErrRejected},
+	}
+
+	w := newWebPush(t)
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(tt.status)
+		}))
+		device, _, _ := newWebPushSubscription(t, srv.URL)
+
+		err := w.Send(context.Background(), device, testNotification())
+		assert.ErrorIs(t, err, tt.want, "status %d", tt.status)
+		srv.Close()
+	}
+
+	t.Run("server errors are retryable", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+		device, _, _ := newWebPushSubscription(t, srv.URL)
+
+		err := w.Send(context.Background(), device, testNotification())
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrRejected))
+	})
+
+	t.Run("missing keys", func(t *testing.T) {
+		err := w.Send(context.Background(), &models.PushDevice{Token: "https://push.example.com"}, testNotification())
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
+
+func TestNewWebPush_InvalidKey(t *testing.T) {
+	_, err := NewWebPush(WebPushConfig{PrivateKey: "not-a-key"})
+	assert.Error(t, err)
+}
+
+func newAPNs(t *testing.T, endpoint string) *APNs {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	keyFile := writeTempFile(t, "AuthKey.p8", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+
+	a, err := NewAPNs(APNsConfig{
+		KeyFile:  keyFile,
+		KeyID:    "KEY123",
+		TeamID:   "TEAM123",
+		Topic:    "chat.hearth.app",
+		Endpoint: endpoint,
+	})
+	require.NoError(t, err)
+	return a
+}
+
+func TestAPNsSend(t *testing.T) {
+	var (
+		gotPath    string
+		gotHeaders http.Header
+		gotPayload map[string]interface{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotHeaders = r.Header
+		_ = json.NewDecoder(r.Body).Decode(&gotPayload)
+	}))
+	defer srv.Close()
+
+	a := newAPNs(t, srv.URL)
+	device := &models.PushDevice{Platform: models.PushPlatformAPNs, Token: "devicetoken"}
+
+	require.NoError(t, a.Send(context.Background(), device, testNotification()))
+
+	assert.Equal(t, "/3/device/devicetoken", gotPath)
+	assert.Equal(t, "chat.hearth.app", gotHeaders.Get("apns-topic"))
+	assert.Equal(t, "alert", gotHeaders.Get("apns-push-type"))
+	assert.True(t, strings.HasPrefix(gotHeaders.Get("Authorization"), "bearer "))
+	assert.Equal(t, "channel-1", gotPayload["channel_id"])
+
+	aps := gotPayload["aps"].(map[string]interface{})
+	assert.Equal(t, "channel-1", aps["thread-id"])
+	assert.Equal(t, "hello", aps["alert"].(map[string]interface{})["body"])
+
+	// The provider token is reused between requests
+	first := gotHeaders.Get("Authorization")
+	require.NoError(t, a.Send(context.Background(), device, testNotification()))
+	assert.Equal(t, first, gotHeaders.Get("Authorization"))
+}
+
+func TestAPNsSend_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		reason string
+		want   error
+	}{
+		{"unregistered", http.StatusGone, "Unregistered", ErrInvalidToken},
+		{"bad token", http.StatusBadRequest, "BadDeviceToken", ErrInvalidToken},
+		{"bad payload", http.StatusBadRequest, "PayloadTooLarge", ErrRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				rw.WriteHeader(tt.status)
+				_, _ = rw.Write([]byte(`{"reason":"` + tt.reason + `"}`))
+			}))
+			defer srv.Close()
+
+			err := newAPNs(t, srv.URL).Send(context.Background(), &models.PushDevice{Token: "t"}, testNotification())
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestFCMSend(t *testing.T) {
+	tokenRequests := 0
+	var (
+		gotAuth    string
+		gotPath    string
+		gotMessage map[string]interface{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			tokenRequests++
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "urn:ietf:params:oauth:grant-type:jwt-bearer", r.PostForm.Get("grant_type"))
+			assert.NotEmpty(t, r.PostForm.Get("assertion"))
+			_, _ = rw.Write([]byte(`{"access_token":"ya29.token","expires_in":3600}`))
+		case "/v1/projects/hearth-test/messages:send":
+			gotPath = r.URL.Path
+			gotAuth = r.Header.Get("Authorization")
+			var body struct {
+				Message map[string]interface{} `json:"message"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			gotMessage = body.Message
+			if body.Message["token"] == "stale" {
+				rw.WriteHeader(http.StatusNotFound)
+				_, _ = rw.Write([]byte(`{"error":{"status":"NOT_FOUND"}}`))
+			}
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	creds, err := json.Marshal(map[string]string{
+		"client_email": "push@hearth-test.iam.gserviceaccount.com",
+		"private_key":  string(keyPEM),
+		"token_uri":    srv.URL + "/token",
+	})
+	require.NoError(t, err)
+
+	f, err := NewFCM(FCMConfig{
+		ProjectID:       "hearth-test",
+		CredentialsFile: writeTempFile(t, "service-account.json", creds),
+		Endpoint:        srv.URL,
+	})
+	require.NoError(t, err)
+
+	device := &models.PushDevice{Platform: models.PushPlatformFCM, Token: "fcmtoken"}
+	require.NoError(t, f.Send(context.Background(), device, testNotification()))
+	require.NoError(t, f.Send(context.Background(), device, testNotification()))
+
+	assert.Equal(t, 1, tokenRequests, "access token should be cached")
+	assert.Equal(t, "/v1/projects/hearth-test/messages:send", gotPath)
+	assert.Equal(t, "Bearer ya29.token", gotAuth)
+	assert.Equal(t, "fcmtoken", gotMessage["token"])
+	assert.Equal(t, "hello", gotMessage["notification"].(map[string]interface{})["body"])
+
+	err = f.Send(context.Background(), &models.PushDevice{Token: "stale"}, testNotification())
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
@@ -0,0 +1,241 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/hkdf"
+
+	"hearth/internal/models"
+)
+
+const (
+	webPushTTL        = 24 * time.Hour
+	webPushRecordSize = 4096
+)
+
+// WebPushConfig configures browser push with VAPID authentication
+type WebPushConfig struct {
+	// PrivateKey is the VAPID P-256 private key, base64url encoded
+	PrivateKey string
+	// Subject is a mailto: or https: contact URL for push services
+	Subject string
+
+	Client *http.Client
+}
+
+// WebPush sends notifications to browsers through their push services
+type WebPush struct {
+	subject   string
+	key       *ecdsa.PrivateKey
+	publicKey string
+	client    *http.Client
+}
+
+// NewWebPush creates a WebPush driver from a VAPID key pair
+func NewWebPush(cfg WebPushConfig) (*WebPush, error) {
+	raw, err := decodeBase64URL(cfg.PrivateKey)
+	if err != nil || len(raw) != 32 {
+		return nil, errors.New("webpush: VAPID private key must be 32 bytes of base64url")
+	}
+
+	ecdhKey, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("webpush: invalid VAPID private key: %w", err)
+	}
+	// Uncompressed point: 0x04 || X || Y
+	public := ecdhKey.PublicKey().Bytes()
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(public[1:33]),
+			Y:     new(big.Int).SetBytes(public[33:]),
+		},
+		D: new(big.Int).SetBytes(raw),
+	}
+
+	return &WebPush{
+		subject:   cfg.Subject,
+		key:       key,
+		publicKey: base64.RawURLEncoding.EncodeToString(public),
+		client:    defaultClient(cfg.Client),
+	}, nil
+}
+
+// PublicKey returns the VAPID application server key browsers subscribe with
+func (w *WebPush) PublicKey() string {
+	return w.publicKey
+}
+
+// Platform implements Driver
+func (w *WebPush) Platform() models.PushPlatform {
+	return models.PushPlatformWebPush
+}
+
+// Send implements Driver
+func (w *WebPush) Send(ctx context.Context, device *models.PushDevice, n *Notification) error {
+	if device.P256dh == nil || device.Auth == nil {
+		return ErrInvalidToken
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"title": n.Title,
+		"body":  n.Body,
+		"tag":   n.Tag,
+		"data":  n.Data,
+	})
+	if err != nil {
+		return err
+	}
+	body, err := encryptWebPush(payload, *device.P256dh, *device.Auth)
+	if err != nil {
+		// A subscription with unusable keys will never work
+		return ErrInvalidToken
+	}
+
+	endpoint, err := url.Parse(device.Token)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	vapid, err := w.vapidToken(endpoint.Scheme + "://" + endpoint.Host)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, device.Token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("vapid t=%s, k=%s", vapid, w.publicKey))
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", fmt.Sprint(int(webPushTTL.Seconds())))
+	req.Header.Set("Urgency", "high")
+	if n.Tag != "" {
+		req.Header.Set("Topic", webPushTopic(n.Tag))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	return statusError("webpush", resp)
+}
+
+// vapidToken signs the JWT identifying this server to a push service (RFC 8292)
+func (w *WebPush) vapidToken(audience string) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": audience,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": w.subject,
+	}).SignedString(w.key)
+}
+
+// encryptWebPush encrypts a payload for a subscription as a single
+// aes128gcm record (RFC 8291)
+func encryptWebPush(plaintext []byte, p256dh, authSecret string) ([]byte, error) {
+	uaPublic, err := decodeBase64URL(p256dh)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := decodeBase64URL(authSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	curve := ecdh.P256()
+	uaKey, err := curve.NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+	asKey, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := asKey.ECDH(uaKey)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asKey.PublicKey().Bytes()
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPublic...), asPublic...)
+	ikm, err := hkdfBytes(shared, auth, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdfBytes(ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdfBytes(ikm, salt, []byte("Content-Encoding: nonce\x00"), 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// Header: salt, record size, key ID length and the key ID, which is our
+	// ephemeral public key. The 0x02 delimiter marks the last record.
+	header := make([]byte, 0, 16+4+1+len(asPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, webPushRecordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+
+	return gcm.Seal(header, nonce, append(plaintext, 0x02), nil), nil
+}
+
+func hkdfBytes(secret, salt, info []byte, length int) ([]byte, error) {
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// webPushTopic fits a tag into the Topic header, which allows at most
+// 32 base64url characters
+func webPushTopic(tag string) string {
+	sum := sha256.Sum256([]byte(tag))
+	return base64.RawURLEncoding.EncodeToString(sum[:])[:32]
+}
+
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
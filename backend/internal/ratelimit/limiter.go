@@ -46,6 +46,7 @@ var (
 	MessageSend     = Config{Limit: 5, Window: 5 * time.Second}
 	MessageEdit     = Config{Limit: 10, Window: time.Minute}
 	MessageReaction = Config{Limit: 20, Window: time.Minute}
+	ReactionBurst   = Config{Limit: 5, Window: 2 * time.Second}
 
 	// Server rate limits
 	ServerCreate = Config{Limit: 10, Window: time.Hour}
@@ -79,6 +80,29 @@ func (l *Limiter) CheckChannel(ctx context.Context, userID, channelID uuid.UUID,
 	return l.Check(ctx, key, cfg)
 }
 
+// CheckReaction checks a user's reaction rate: a short burst limit that
+// stops bots adding many reactions at once, and a sustained limit
+func (l *Limiter) CheckReaction(ctx context.Context, userID uuid.UUID) error {
+	if err := l.CheckUser(ctx, userID, "reaction_burst", ReactionBurst); err != nil {
+		return err
+	}
+	return l.CheckUser(ctx, userID, "reaction", MessageReaction)
+}
+
+// CheckBotRequest checks a bot's API request rate: burst requests a second
+// and perMinute a minute. A limit of 0 isn't enforced.
+func (l *Limiter) CheckBotRequest(ctx context.Context, userID uuid.UUID, perMinute, burst int) error {
+	if burst > 0 {
+		if err := l.CheckUser(ctx, userID, "bot_burst", Config{Limit: burst, Window: time.Second}); err != nil {
+			return err
+		}
+	}
+	if perMinute > 0 {
+		return l.CheckUser(ctx, userID, "bot_requests", Config{Limit: perMinute, Window: time.Minute})
+	}
+	return nil
+}
+
 // CheckIP checks rate limit for an IP address
 func (l *Limiter) CheckIP(ctx context.Context, ip string, action string, cfg Config) error {
 	key := fmt.Sprintf("ip:%s:%s", ip, action)
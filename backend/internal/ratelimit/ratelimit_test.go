@@ -192,6 +192,44 @@ func TestCheckUser(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestCheckReaction_Burst(t *testing.T) {
+	cache := NewMockCache()
+	limiter := NewLimiter(cache)
+	ctx := context.Background()
+
+	userID := uuid.New()
+
+	for i := 0; i < ReactionBurst.Limit; i++ {
+		assert.NoError(t, limiter.CheckReaction(ctx, userID))
+	}
+	assert.Equal(t, ErrRateLimited, limiter.CheckReaction(ctx, userID))
+
+	// Other users have their own budget
+	assert.NoError(t, limiter.CheckReaction(ctx, uuid.New()))
+}
+
+func TestCheckBotRequest(t *testing.T) {
+	cache := NewMockCache()
+	limiter := NewLimiter(cache)
+	ctx := context.Background()
+
+	userID := uuid.New()
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, limiter.CheckBotRequest(ctx, userID, 10, 3))
+	}
+	assert.Equal(t, ErrRateLimited, limiter.CheckBotRequest(ctx, userID, 10, 3), "over the burst limit")
+
+	other := uuid.New()
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, limiter.CheckBotRequest(ctx, other, 2, 0))
+	}
+	assert.Equal(t, ErrRateLimited, limiter.CheckBotRequest(ctx, other, 2, 0), "over the per-minute limit")
+
+	// No limits, no counting
+	assert.NoError(t, limiter.CheckBotRequest(ctx, uuid.New(), 0, 0))
+}
+
 func TestCheckChannel(t *testing.T) {
 	cache := NewMockCache()
 	limiter := NewLimiter(cache)
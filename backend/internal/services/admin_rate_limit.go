@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AdminRateLimits caps the admin API requests each instance admin makes per
+// minute. Reads and writes are counted apart, so bulk changes can't starve
+// an admin of the endpoints they need to see what happened. 0 means
+// unlimited.
+type AdminRateLimits struct {
+	ReadsPerMinute  int
+	WritesPerMinute int
+}
+
+// AdminRateLimiter applies AdminRateLimits. The admin API uses it instead
+// of the instance-wide rate limit, so operators aren't throttled along with
+// the clients they're dealing with.
+type AdminRateLimiter struct {
+	counter ServerThrottleCounter
+	now     func() time.Time
+
+	mu     sync.RWMutex
+	limits AdminRateLimits
+}
+
+// NewAdminRateLimiter creates an admin rate limiter that counts on this
+// instance only
+func NewAdminRateLimiter(limits AdminRateLimits) *AdminRateLimiter {
+	return &AdminRateLimiter{
+		counter: newMemoryCounter(),
+		now:     time.Now,
+		limits:  limits,
+	}
+}
+
+// SetCounter shares the counts between instances, so limits apply to the
+// whole deployment rather than to each instance
+func (l *AdminRateLimiter) SetCounter(counter ServerThrottleCounter) {
+	l.counter = counter
+}
+
+// SetLimits changes the limits, e.g. when the config is reloaded
+func (l *AdminRateLimiter) SetLimits(limits AdminRateLimits) {
+	l.mu.Lock()
+	l.limits = limits
+	l.mu.Unlock()
+}
+
+// Limits returns the limits that apply
+func (l *AdminRateLimiter) Limits() AdminRateLimits {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.limits
+}
+
+// CheckAdminRequest counts an admin API request and returns an
+// *AdminRateLimitedError once the admin is over their limit for this
+// minute. Counting fails open so an unreachable Redis doesn't lock
+// operators out.
+func (l *AdminRateLimiter) CheckAdminRequest(ctx context.Context, userID uuid.UUID, write bool) error {
+	limits := l.Limits()
+	kind, limit := "reads", limits.ReadsPerMinute
+	if write {
+		kind, limit = "writes", limits.WritesPerMinute
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	now := l.now()
+	window := now.Truncate(time.Minute)
+	key := fmt.Sprintf("admin_rate:%s:%s:%d", kind, userID, window.Unix())
+	count, err := l.counter.IncrementWithExpiry(ctx, key, 2*time.Minute)
+	if err != nil {
+		log.Printf("[AdminRateLimit] failed to count %s for %s: %v", kind, userID, err)
+		return nil
+	}
+	if count > int64(limit) {
+		return &AdminRateLimitedError{RetryAfter: window.Add(time.Minute).Sub(now)}
+	}
+	return nil
+}
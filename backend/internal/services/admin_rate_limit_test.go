@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewAdminRateLimiter(AdminRateLimits{ReadsPerMinute: 3, WritesPerMinute: 1})
+	now := time.Date(2026, 3, 1, 12, 0, 45, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+	adminID := uuid.New()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, limiter.CheckAdminRequest(ctx, adminID, false))
+	}
+	var limited *AdminRateLimitedError
+	require.ErrorAs(t, limiter.CheckAdminRequest(ctx, adminID, false), &limited)
+	assert.Equal(t, 15*time.Second, limited.RetryAfter)
+
+	require.NoError(t, limiter.CheckAdminRequest(ctx, adminID, true), "writes are counted apart from reads")
+	assert.ErrorIs(t, limiter.CheckAdminRequest(ctx, adminID, true), ErrAdminRateLimited)
+	assert.NoError(t, limiter.CheckAdminRequest(ctx, uuid.New(), true), "each admin has their own limit")
+
+	now = now.Add(15 * time.Second)
+	assert.NoError(t, limiter.CheckAdminRequest(ctx, adminID, false), "limits reset each minute")
+
+	limiter.SetLimits(AdminRateLimits{})
+	for i := 0; i < 10; i++ {
+		require.NoError(t, limiter.CheckAdminRequest(ctx, adminID, true), "0 is unlimited")
+	}
+}
+
+func TestAdminRateLimiter_FailsOpen(t *testing.T) {
+	limiter := NewAdminRateLimiter(AdminRateLimits{ReadsPerMinute: 1})
+	limiter.SetCounter(failingCounter{})
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, limiter.CheckAdminRequest(context.Background(), uuid.New(), false))
+	}
+}
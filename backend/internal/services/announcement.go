@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	maxAnnouncementLength = 2000
+	// maxAnnouncements caps how many announcements the instance keeps,
+	// expired ones included
+	maxAnnouncements = 500
+)
+
+// AnnouncementRepository stores instance announcements. The Postgres
+// AnnouncementRepository implements it.
+type AnnouncementRepository interface {
+	List(ctx context.Context) ([]*models.Announcement, error)
+	ListActive(ctx context.Context, now time.Time) ([]*models.Announcement, error)
+	Create(ctx context.Context, announcement *models.Announcement) error
+	Delete(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+// AnnouncementCreatedEvent is published when an instance admin posts an
+// announcement
+type AnnouncementCreatedEvent struct {
+	Announcement *models.Announcement
+}
+
+// AnnouncementDeletedEvent is published when an announcement is taken down
+type AnnouncementDeletedEvent struct {
+	ID uuid.UUID
+}
+
+// AnnouncementService manages the announcements instance admins show every
+// user, such as planned maintenance. Connected clients get them over the
+// gateway as they're posted.
+type AnnouncementService struct {
+	repo     AnnouncementRepository
+	eventBus EventBus
+	now      func() time.Time
+}
+
+func NewAnnouncementService(repo AnnouncementRepository, eventBus EventBus) *AnnouncementService {
+	return &AnnouncementService{
+		repo:     repo,
+		eventBus: eventBus,
+		now:      time.Now,
+	}
+}
+
+// List returns every announcement, including expired ones, newest first
+func (s *AnnouncementService) List(ctx context.Context) ([]*models.Announcement, error) {
+	return s.repo.List(ctx)
+}
+
+// Active returns the announcements clients should show now, newest first
+func (s *AnnouncementService) Active(ctx context.Context) ([]*models.Announcement, error) {
+	return s.repo.ListActive(ctx, s.now())
+}
+
+func invalidAnnouncement(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidAnnouncement, fmt.Sprintf(format, args...))
+}
+
+// Create posts an announcement and sends it to every connected client
+func (s *AnnouncementService) Create(ctx context.Context, creatorID uuid.UUID, req *models.CreateAnnouncementRequest) (*models.Announcement, error) {
+	content := strings.TrimSpace(req.Content)
+	if content == "" || utf8.RuneCountInString(content) > maxAnnouncementLength {
+		return nil, invalidAnnouncement("content must be 1-%d characters", maxAnnouncementLength)
+	}
+	level := req.Level
+	if level == "" {
+		level = models.AnnouncementInfo
+	}
+	if !level.Valid() {
+		return nil, invalidAnnouncement("level must be info, warning or critical")
+	}
+
+	announcement := &models.Announcement{
+		ID:        uuid.New(),
+		Content:   content,
+		Level:     level,
+		CreatorID: &creatorID,
+		CreatedAt: s.now(),
+	}
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(announcement.CreatedAt) {
+			return nil, invalidAnnouncement("expires_at must be in the future")
+		}
+		expiresAt := *req.ExpiresAt
+		announcement.ExpiresAt = &expiresAt
+	}
+
+	existing, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxAnnouncements {
+		return nil, invalidAnnouncement("an instance can have at most %d announcements; delete old ones first", maxAnnouncements)
+	}
+	if err := s.repo.Create(ctx, announcement); err != nil {
+		return nil, err
+	}
+
+	if s.eventBus != nil {
+		s.eventBus.Publish("announcement.created", &AnnouncementCreatedEvent{Announcement: announcement})
+	}
+	return announcement, nil
+}
+
+// Delete takes an announcement down, and clients stop showing it
+func (s *AnnouncementService) Delete(ctx context.Context, id uuid.UUID) error {
+	deleted, err := s.repo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAnnouncementNotFound
+	}
+
+	if s.eventBus != nil {
+		s.eventBus.Publish("announcement.deleted", &AnnouncementDeletedEvent{ID: id})
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+// fakeAnnouncementRepo stores announcements in memory, oldest first
+type fakeAnnouncementRepo struct {
+	announcements []*models.Announcement
+}
+
+func (r *fakeAnnouncementRepo) List(ctx context.Context) ([]*models.Announcement, error) {
+	return r.announcements, nil
+}
+
+func (r *fakeAnnouncementRepo) ListActive(ctx context.Context, now time.Time) ([]*models.Announcement, error) {
+	var active []*models.Announcement
+	for _, announcement := range r.announcements {
+		if announcement.ExpiresAt == nil || announcement.ExpiresAt.After(now) {
+			active = append(active, announcement)
+		}
+	}
+	return active, nil
+}
+
+func (r *fakeAnnouncementRepo) Create(ctx context.Context, announcement *models.Announcement) error {
+	r.announcements = append(r.announcements, announcement)
+	return nil
+}
+
+func (r *fakeAnnouncementRepo) Delete(ctx context.Context, id uuid.UUID) (bool, error) {
+	for i, announcement := range r.announcements {
+		if announcement.ID == id {
+			r.announcements = append(r.announcements[:i], r.announcements[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestAnnouncementService_Create(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeAnnouncementRepo{}
+	bus := new(MockEventBus)
+	svc := NewAnnouncementService(repo, bus)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	adminID := uuid.New()
+
+	bus.On("Publish", "announcement.created", mock.MatchedBy(func(e *AnnouncementCreatedEvent) bool {
+		return e.Announcement.Content == "Maintenance at 22:00 UTC"
+	})).Return().Once()
+	until := now.Add(time.Hour)
+	announcement, err := svc.Create(ctx, adminID, &models.CreateAnnouncementRequest{Content: "  Maintenance at 22:00 UTC ", ExpiresAt: &until})
+	require.NoError(t, err)
+	assert.Equal(t, models.AnnouncementInfo, announcement.Level, "level defaults to info")
+	assert.Equal(t, adminID, *announcement.CreatorID)
+	bus.AssertExpectations(t)
+
+	past := now.Add(-time.Minute)
+	for name, req := range map[string]*models.CreateAnnouncementRequest{
+		"empty":         {Content: "   "},
+		"too long":      {Content: strings.Repeat("a", maxAnnouncementLength+1)},
+		"unknown level": {Content: "hi", Level: "loud"},
+		"already over":  {Content: "hi", ExpiresAt: &past},
+	} {
+		_, err := svc.Create(ctx, adminID, req)
+		assert.ErrorIs(t, err, ErrInvalidAnnouncement, name)
+	}
+	assert.Len(t, repo.announcements, 1)
+
+	now = until
+	active, err := svc.Active(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, active, "expired announcements aren't shown")
+}
+
+func TestAnnouncementService_Delete(t *testing.T) {
+	ctx := context.Background()
+	announcement := &models.Announcement{ID: uuid.New(), Content: "hi", Level: models.AnnouncementInfo}
+	repo := &fakeAnnouncementRepo{announcements: []*models.Announcement{announcement}}
+	bus := new(MockEventBus)
+	svc := NewAnnouncementService(repo, bus)
+
+	bus.On("Publish", "announcement.deleted", &AnnouncementDeletedEvent{ID: announcement.ID}).Return().Once()
+	require.NoError(t, svc.Delete(ctx, announcement.ID))
+	assert.Empty(t, repo.announcements)
+	assert.ErrorIs(t, svc.Delete(ctx, announcement.ID), ErrAnnouncementNotFound)
+	bus.AssertExpectations(t)
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"mime/multipart"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// UploadProgressMinSize is how large an upload must be for its progress
+	// to be reported; smaller ones finish before a progress bar would help
+	UploadProgressMinSize = 1 << 20
+	// uploadProgressInterval is how often an upload reports its progress at
+	// most. The last report, once the whole file is in, is always sent.
+	uploadProgressInterval = 250 * time.Millisecond
+
+	maxUploadIDLength = 64
+)
+
+// AttachmentUploadProgressEvent is published as a large upload is stored,
+// for the uploader's gateway connections
+type AttachmentUploadProgressEvent struct {
+	// UploadID is the ID the client gave the upload, as the attachment's
+	// own ID isn't known until it's stored
+	UploadID      string
+	UserID        uuid.UUID
+	ChannelID     uuid.UUID
+	BytesReceived int64
+	TotalBytes    int64
+}
+
+// ValidUploadID reports whether id can name an upload: 1-64 letters,
+// digits, dashes and underscores, so UUIDs and most client IDs fit
+func ValidUploadID(id string) bool {
+	if id == "" || len(id) > maxUploadIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// UploadWithProgress uploads like UploadWithAltText and reports how much of
+// the file has been stored to the uploader's gateway connections under
+// uploadID, so clients can show progress for the time the server spends
+// storing large files. Uploads under UploadProgressMinSize and uploads
+// without an ID aren't reported.
+func (s *AttachmentService) UploadWithProgress(
+	ctx context.Context,
+	file *multipart.FileHeader,
+	uploaderID uuid.UUID,
+	channelID uuid.UUID,
+	altText string,
+	uploadID string,
+) (*Attachment, error) {
+	if uploadID == "" || file.Size < UploadProgressMinSize || s.eventBus == nil {
+		return s.upload(ctx, file, uploaderID, channelID, altText, nil)
+	}
+	reporter := &uploadProgress{
+		bus: s.eventBus,
+		now: time.Now,
+		event: AttachmentUploadProgressEvent{
+			UploadID:   uploadID,
+			UserID:     uploaderID,
+			ChannelID:  channelID,
+			TotalBytes: file.Size,
+		},
+	}
+	return s.upload(ctx, file, uploaderID, channelID, altText, reporter.report)
+}
+
+// uploadProgress publishes an upload's progress at most once per
+// uploadProgressInterval
+type uploadProgress struct {
+	bus   EventBus
+	now   func() time.Time
+	event AttachmentUploadProgressEvent
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+func (p *uploadProgress) report(written int64) {
+	p.mu.Lock()
+	now := p.now()
+	if written < p.event.TotalBytes && now.Sub(p.last) < uploadProgressInterval {
+		p.mu.Unlock()
+		return
+	}
+	p.last = now
+	event := p.event
+	event.BytesReceived = written
+	p.mu.Unlock()
+
+	p.bus.Publish("attachment.upload_progress", &event)
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/storage"
+)
+
+// progressRecorder records the upload progress an AttachmentService
+// publishes
+type progressRecorder struct {
+	mu     sync.Mutex
+	events []*AttachmentUploadProgressEvent
+}
+
+func (r *progressRecorder) Publish(eventType string, data interface{}) {
+	if event, ok := data.(*AttachmentUploadProgressEvent); ok && eventType == "attachment.upload_progress" {
+		r.mu.Lock()
+		r.events = append(r.events, event)
+		r.mu.Unlock()
+	}
+}
+
+func (r *progressRecorder) Subscribe(event string, handler func(data interface{}))   {}
+func (r *progressRecorder) Unsubscribe(event string, handler func(data interface{})) {}
+
+func TestAttachmentService_UploadWithProgress(t *testing.T) {
+	ctx := context.Background()
+	uploaderID, channelID := uuid.New(), uuid.New()
+
+	newSvc := func() (*AttachmentService, *progressRecorder) {
+		bus := &progressRecorder{}
+		svc := NewAttachmentService(storage.NewService(newMockStorageBackend(), 10, nil))
+		svc.SetEventBus(bus)
+		return svc, bus
+	}
+
+	t.Run("large upload", func(t *testing.T) {
+		svc, bus := newSvc()
+		content := bytes.Repeat([]byte("a"), 2*UploadProgressMinSize)
+		file := createTestFileHeader("big.txt", "text/plain", content)
+
+		_, err := svc.UploadWithProgress(ctx, file, uploaderID, channelID, "", "upload-1")
+		require.NoError(t, err)
+
+		require.NotEmpty(t, bus.events)
+		last := bus.events[len(bus.events)-1]
+		assert.Equal(t, "upload-1", last.UploadID)
+		assert.Equal(t, uploaderID, last.UserID)
+		assert.Equal(t, channelID, last.ChannelID)
+		assert.Equal(t, file.Size, last.TotalBytes)
+		assert.Equal(t, file.Size, last.BytesReceived, "the last report is the whole file")
+		assert.Less(t, len(bus.events), 10, "reports are throttled")
+	})
+
+	t.Run("small upload", func(t *testing.T) {
+		svc, bus := newSvc()
+		_, err := svc.UploadWithProgress(ctx, createTestFileHeader("notes.txt", "text/plain", []byte("hello")), uploaderID, channelID, "", "upload-1")
+		require.NoError(t, err)
+		assert.Empty(t, bus.events)
+	})
+
+	t.Run("no upload id", func(t *testing.T) {
+		svc, bus := newSvc()
+		content := bytes.Repeat([]byte("a"), 2*UploadProgressMinSize)
+		_, err := svc.UploadWithProgress(ctx, createTestFileHeader("big.txt", "text/plain", content), uploaderID, channelID, "", "")
+		require.NoError(t, err)
+		assert.Empty(t, bus.events)
+	})
+}
+
+func TestUploadProgress_Throttles(t *testing.T) {
+	bus := &progressRecorder{}
+	now := time.Unix(0, 0)
+	p := &uploadProgress{
+		bus:   bus,
+		now:   func() time.Time { return now },
+		event: AttachmentUploadProgressEvent{UploadID: "u", TotalBytes: 100},
+	}
+
+	p.report(10)
+	p.report(20)
+	now = now.Add(uploadProgressInterval)
+	p.report(30)
+	p.report(100)
+
+	require.Len(t, bus.events, 3)
+	assert.Equal(t, int64(10), bus.events[0].BytesReceived)
+	assert.Equal(t, int64(30), bus.events[1].BytesReceived)
+	assert.Equal(t, int64(100), bus.events[2].BytesReceived)
+}
+
+func TestValidUploadID(t *testing.T) {
+	assert.True(t, ValidUploadID(uuid.NewString()))
+	assert.True(t, ValidUploadID("upload_1"))
+	assert.False(t, ValidUploadID(""))
+	assert.False(t, ValidUploadID("has space"))
+	assert.False(t, ValidUploadID("../etc"))
+	assert.False(t, ValidUploadID(string(bytes.Repeat([]byte("a"), 65))))
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// Attachment virus scan states
+const (
+	AttachmentScanPending = "pending"
+	AttachmentScanClean   = "clean"
+	AttachmentScanFlagged = "flagged"
+)
+
+// attachmentScanTimeout bounds how long one upload may take to scan
+const attachmentScanTimeout = 2 * time.Minute
+
+// AttachmentScanner checks uploaded files for malware
+type AttachmentScanner interface {
+	Scan(ctx context.Context, filename string, content io.Reader) (flagged bool, err error)
+}
+
+// AttachmentUpdatedEvent is published when an attachment's scan status changes
+type AttachmentUpdatedEvent struct {
+	AttachmentID uuid.UUID
+	ChannelID    uuid.UUID
+	MessageID    uuid.UUID
+	ScanStatus   string
+}
+
+// SetScanner enables virus scanning. Uploads stay pending until the scanner
+// has looked at them.
+func (s *AttachmentService) SetScanner(scanner AttachmentScanner) {
+	s.scanner = scanner
+}
+
+// SetEventBus sets the event bus for attachment updates
+func (s *AttachmentService) SetEventBus(eventBus EventBus) {
+	s.eventBus = eventBus
+}
+
+// SetChannelPermissions sets the checker for who may download flagged
+// attachments. Without one, nobody may.
+func (s *AttachmentService) SetChannelPermissions(checker ChannelPermissionChecker) {
+	s.permissions = checker
+}
+
+// SetScanStatus records a scan result and tells the channel about it
+func (s *AttachmentService) SetScanStatus(ctx context.Context, attachmentID uuid.UUID, status string) error {
+	s.mu.Lock()
+	a, ok := s.attachments[attachmentID]
+	if !ok {
+		s.mu.Unlock()
+		return ErrAttachmentNotFound
+	}
+	changed := a.ScanStatus != status
+	a.ScanStatus = status
+	event := &AttachmentUpdatedEvent{
+		AttachmentID: a.ID,
+		ChannelID:    a.ChannelID,
+		MessageID:    a.MessageID,
+		ScanStatus:   status,
+	}
+	s.mu.Unlock()
+
+	if changed && s.eventBus != nil {
+		s.eventBus.Publish("attachment.updated", event)
+	}
+	return nil
+}
+
+// CheckDownload returns ErrAttachmentFlagged if the attachment was flagged
+// and the requester can't manage messages in its channel
+func (s *AttachmentService) CheckDownload(ctx context.Context, attachmentID, requesterID uuid.UUID) error {
+	a, err := s.Get(ctx, attachmentID)
+	if err != nil {
+		return err
+	}
+	s.mu.RLock()
+	flagged := a.ScanStatus == AttachmentScanFlagged
+	s.mu.RUnlock()
+	if !flagged {
+		return nil
+	}
+
+	if s.permissions != nil && a.ChannelID != uuid.Nil {
+		allowed, err := s.permissions.HasChannelPermission(ctx, a.ChannelID, requesterID, models.PermManageMessages)
+		if err != nil {
+			return err
+		}
+		if allowed {
+			return nil
+		}
+	}
+	return ErrAttachmentFlagged
+}
+
+// startScan marks a newly stored attachment pending and scans it in the
+// background. The caller holds s.mu.
+func (s *AttachmentService) startScan(a *Attachment) {
+	if s.scanner == nil || s.storage == nil {
+		return
+	}
+	a.ScanStatus = AttachmentScanPending
+	go s.scan(a.ID, a.Filename, a.Path)
+}
+
+func (s *AttachmentService) scan(attachmentID uuid.UUID, filename, path string) {
+	ctx, cancel := context.WithTimeout(context.Background(), attachmentScanTimeout)
+	defer cancel()
+
+	reader, err := s.storage.Download(ctx, path)
+	if err != nil {
+		log.Printf("[Attachments] Failed to fetch %s for scanning: %v", attachmentID, err)
+		return
+	}
+	defer reader.Close()
+
+	flagged, err := s.scanner.Scan(ctx, filename, reader)
+	if err != nil {
+		// Leave it pending so it isn't reported clean without a scan
+		log.Printf("[Attachments] Failed to scan %s: %v", attachmentID, err)
+		return
+	}
+
+	status := AttachmentScanClean
+	if flagged {
+		status = AttachmentScanFlagged
+	}
+	if err := s.SetScanStatus(ctx, attachmentID, status); err != nil && err != ErrAttachmentNotFound {
+		log.Printf("[Attachments] Failed to record scan of %s: %v", attachmentID, err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"mime/multipart"
 	"path/filepath"
 	"strings"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/google/uuid"
 
+	"hearth/internal/models"
 	"hearth/internal/storage"
 )
 
@@ -21,8 +23,21 @@ var (
 	ErrFileTooLarge          = errors.New("file too large")
 	ErrFileTypeNotAllowed    = errors.New("file type not allowed")
 	ErrAttachmentAccessDenied = errors.New("access denied")
+	ErrAttachmentNotPending  = errors.New("attachment is not awaiting upload")
+	ErrUploadIncomplete      = errors.New("upload has not completed")
+	ErrUploadExpired         = errors.New("upload url has expired")
+	ErrAttachmentFlagged     = errors.New("attachment was flagged by the virus scanner")
 )
 
+// Attachment upload states
+const (
+	AttachmentStatusPending = "pending" // Presigned URL issued, waiting for client upload
+	AttachmentStatusReady   = "ready"
+)
+
+// presignedUploadExpiry is how long a client has to complete a direct upload
+const presignedUploadExpiry = 15 * time.Minute
+
 // Attachment represents a file attachment
 type Attachment struct {
 	ID          uuid.UUID `json:"id"`
@@ -35,7 +50,14 @@ type Attachment struct {
 	URL         string    `json:"url"`
 	Path        string    `json:"path"`
 	AltText     string    `json:"alt_text,omitempty"` // Accessibility: description for screen readers
-	CreatedAt   time.Time `json:"created_at"`
+	Status      string    `json:"status"`
+	// ScanStatus is pending, clean or flagged, and empty if the instance
+	// doesn't scan uploads
+	ScanStatus string    `json:"scan_status,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	// Set while a presigned upload is outstanding
+	UploadExpiresAt *time.Time `json:"upload_expires_at,omitempty"`
 }
 
 // AttachmentService handles file attachments
@@ -43,6 +65,11 @@ type AttachmentService struct {
 	mu          sync.RWMutex
 	attachments map[uuid.UUID]*Attachment
 	storage     *storage.Service
+
+	scanner     AttachmentScanner
+	eventBus    EventBus
+	permissions ChannelPermissionChecker
+	quotas      AttachmentQuotas
 }
 
 // NewAttachmentService creates a new attachment service
@@ -53,6 +80,32 @@ func NewAttachmentService(storageService *storage.Service) *AttachmentService {
 	}
 }
 
+// AttachmentQuotas checks uploads against the uploader's quotas. The
+// QuotaService implements it.
+type AttachmentQuotas interface {
+	CheckStorageQuota(ctx context.Context, userID uuid.UUID, serverID *uuid.UUID, fileSizeBytes int64) error
+}
+
+// SetQuotas holds uploads to the uploader's file size quota, which instance
+// admins can change per tier. The storage service's own limit still applies.
+func (s *AttachmentService) SetQuotas(quotas AttachmentQuotas) {
+	s.quotas = quotas
+}
+
+// checkQuota returns ErrFileTooLarge if the uploader may not upload size
+// bytes
+func (s *AttachmentService) checkQuota(ctx context.Context, uploaderID uuid.UUID, size int64) error {
+	if s.quotas == nil {
+		return nil
+	}
+	err := s.quotas.CheckStorageQuota(ctx, uploaderID, nil, size)
+	var quotaErr *models.QuotaError
+	if errors.As(err, &quotaErr) {
+		return ErrFileTooLarge
+	}
+	return err
+}
+
 // Upload handles file upload
 func (s *AttachmentService) Upload(
 	ctx context.Context,
@@ -71,12 +124,28 @@ func (s *AttachmentService) UploadWithAltText(
 	channelID uuid.UUID,
 	altText string,
 ) (*Attachment, error) {
+	return s.upload(ctx, file, uploaderID, channelID, altText, nil)
+}
+
+// upload stores an upload, calling progress as it's written if it isn't nil
+func (s *AttachmentService) upload(
+	ctx context.Context,
+	file *multipart.FileHeader,
+	uploaderID uuid.UUID,
+	channelID uuid.UUID,
+	altText string,
+	progress func(written int64),
+) (*Attachment, error) {
+	if err := s.checkQuota(ctx, uploaderID, file.Size); err != nil {
+		return nil, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	// Use storage service if available
 	if s.storage != nil {
-		fileInfo, err := s.storage.UploadFile(ctx, file, uploaderID, "attachments")
+		fileInfo, err := s.storage.UploadFileWithProgress(ctx, file, uploaderID, "attachments", progress)
 		if err != nil {
 			return nil, fmt.Errorf("failed to upload file: %w", err)
 		}
@@ -91,9 +160,11 @@ func (s *AttachmentService) UploadWithAltText(
 			URL:         fileInfo.URL,
 			Path:        fileInfo.Path,
 			AltText:     altText,
+			Status:      AttachmentStatusReady,
 			CreatedAt:   fileInfo.UploadedAt,
 		}
 		s.attachments[a.ID] = a
+		s.startScan(a)
 		return a, nil
 	}
 
@@ -107,12 +178,163 @@ func (s *AttachmentService) UploadWithAltText(
 		Size:        file.Size,
 		URL:         "/attachments/" + uuid.New().String() + filepath.Ext(file.Filename),
 		AltText:     altText,
+		Status:      AttachmentStatusReady,
 		CreatedAt:   time.Now(),
 	}
 	s.attachments[a.ID] = a
 	return a, nil
 }
 
+// CreatePendingUpload issues a presigned upload URL and records the
+// attachment in pending state. The client uploads directly to object
+// storage and then calls ConfirmUpload.
+func (s *AttachmentService) CreatePendingUpload(
+	ctx context.Context,
+	uploaderID uuid.UUID,
+	channelID uuid.UUID,
+	filename string,
+	contentType string,
+	size int64,
+	altText string,
+) (*Attachment, *storage.PresignedUpload, error) {
+	if s.storage == nil {
+		return nil, nil, storage.ErrPresignNotSupported
+	}
+	if err := s.checkQuota(ctx, uploaderID, size); err != nil {
+		return nil, nil, err
+	}
+
+	upload, err := s.storage.PresignUpload(ctx, filename, contentType, size, uploaderID, "attachments", presignedUploadExpiry)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrPresignNotSupported):
+			return nil, nil, err
+		case errors.Is(err, storage.ErrFileTooLarge):
+			return nil, nil, ErrFileTooLarge
+		case errors.Is(err, storage.ErrFileTypeNotAllowed), errors.Is(err, storage.ErrContentTypeNotAllowed):
+			return nil, nil, ErrFileTypeNotAllowed
+		}
+		return nil, nil, fmt.Errorf("failed to create upload: %w", err)
+	}
+
+	expiresAt := upload.ExpiresAt
+	a := &Attachment{
+		ID:              upload.ID,
+		ChannelID:       channelID,
+		UploaderID:      uploaderID,
+		Filename:        upload.Filename,
+		ContentType:     upload.ContentType,
+		Size:            upload.Size,
+		URL:             upload.URL,
+		Path:            upload.Path,
+		AltText:         altText,
+		Status:          AttachmentStatusPending,
+		CreatedAt:       upload.UploadedAt,
+		UploadExpiresAt: &expiresAt,
+	}
+
+	s.mu.Lock()
+	s.attachments[a.ID] = a
+	s.mu.Unlock()
+
+	return a, upload, nil
+}
+
+// ConfirmUpload finalizes a pending attachment once the client has uploaded
+// the object. The stored object must exist and must not exceed the size
+// that was declared when the URL was issued.
+func (s *AttachmentService) ConfirmUpload(ctx context.Context, attachmentID, requesterID uuid.UUID) (*Attachment, error) {
+	s.mu.RLock()
+	a, ok := s.attachments[attachmentID]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrAttachmentNotFound
+	}
+	if a.UploaderID != requesterID {
+		return nil, ErrAttachmentAccessDenied
+	}
+	if a.Status != AttachmentStatusPending {
+		return nil, ErrAttachmentNotPending
+	}
+	if s.storage == nil {
+		return nil, storage.ErrPresignNotSupported
+	}
+
+	size, err := s.storage.StatObject(ctx, a.Path)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			if a.UploadExpiresAt != nil && time.Now().After(*a.UploadExpiresAt) {
+				return nil, ErrUploadExpired
+			}
+			return nil, ErrUploadIncomplete
+		}
+		return nil, fmt.Errorf("failed to verify upload: %w", err)
+	}
+
+	if size > a.Size {
+		// Client uploaded more than it declared; don't let it bypass quotas
+		if err := s.storage.DeleteFile(ctx, a.Path); err != nil {
+			return nil, fmt.Errorf("failed to delete oversized upload: %w", err)
+		}
+		s.mu.Lock()
+		delete(s.attachments, attachmentID)
+		s.mu.Unlock()
+		return nil, ErrFileTooLarge
+	}
+	if size < a.Size {
+		return nil, ErrUploadIncomplete
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a.Status != AttachmentStatusPending {
+		return nil, ErrAttachmentNotPending
+	}
+	a.Status = AttachmentStatusReady
+	a.UploadExpiresAt = nil
+	s.startScan(a)
+	return a, nil
+}
+
+// CleanupExpiredUploads removes pending attachments whose upload window has
+// passed without confirmation. Returns the number removed.
+func (s *AttachmentService) CleanupExpiredUploads(ctx context.Context) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	removed := 0
+	for id, a := range s.attachments {
+		if a.Status != AttachmentStatusPending || a.UploadExpiresAt == nil || now.Before(*a.UploadExpiresAt) {
+			continue
+		}
+		if s.storage != nil && a.Path != "" {
+			s.storage.DeleteFile(ctx, a.Path)
+		}
+		delete(s.attachments, id)
+		removed++
+	}
+	return removed
+}
+
+// RunUploadCleanup removes expired pending uploads every interval until ctx
+// is done
+func (s *AttachmentService) RunUploadCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if removed := s.CleanupExpiredUploads(ctx); removed > 0 {
+				log.Printf("[Attachments] Removed %d expired uploads", removed)
+			}
+		}
+	}
+}
+
 // UploadForMessage uploads a file and associates it with a message
 func (s *AttachmentService) UploadForMessage(
 	ctx context.Context,
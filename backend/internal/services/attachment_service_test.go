@@ -3,6 +3,7 @@ package services
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"mime/multipart"
 	"net/textproto"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"hearth/internal/storage"
@@ -427,3 +429,205 @@ func TestAttachmentErrors(t *testing.T) {
 	assert.Equal(t, "file type not allowed", ErrFileTypeNotAllowed.Error())
 	assert.Equal(t, "access denied", ErrAttachmentAccessDenied.Error())
 }
+
+// mockPresignBackend adds direct-upload support to mockStorageBackend
+type mockPresignBackend struct {
+	*mockStorageBackend
+}
+
+func (m *mockPresignBackend) GetPresignedUploadURL(ctx context.Context, path, contentType string, expiry time.Duration) (string, error) {
+	return "http://test.com/presigned/" + path, nil
+}
+
+func (m *mockPresignBackend) StatObject(ctx context.Context, path string) (int64, error) {
+	data, ok := m.files[path]
+	if !ok {
+		return 0, storage.ErrObjectNotFound
+	}
+	return int64(len(data)), nil
+}
+
+func TestAttachmentService_PresignedUpload(t *testing.T) {
+	ctx := context.Background()
+	uploaderID := uuid.New()
+	channelID := uuid.New()
+
+	newSvc := func() (*AttachmentService, *mockPresignBackend) {
+		backend := &mockPresignBackend{newMockStorageBackend()}
+		return NewAttachmentService(storage.NewService(backend, 10, nil)), backend
+	}
+
+	t.Run("create and confirm", func(t *testing.T) {
+		svc, backend := newSvc()
+
+		a, upload, err := svc.CreatePendingUpload(ctx, uploaderID, channelID, "video.mp4", "video/mp4", 5, "")
+		require.NoError(t, err)
+		assert.Equal(t, AttachmentStatusPending, a.Status)
+		assert.NotNil(t, a.UploadExpiresAt)
+		assert.Equal(t, "PUT", upload.Method)
+		assert.Contains(t, upload.UploadURL, "presigned/"+a.Path)
+		assert.Equal(t, "video/mp4", upload.Headers["Content-Type"])
+
+		// Not uploaded yet
+		_, err = svc.ConfirmUpload(ctx, a.ID, uploaderID)
+		assert.ErrorIs(t, err, ErrUploadIncomplete)
+
+		backend.files[a.Path] = []byte("12345")
+		confirmed, err := svc.ConfirmUpload(ctx, a.ID, uploaderID)
+		require.NoError(t, err)
+		assert.Equal(t, AttachmentStatusReady, confirmed.Status)
+		assert.Nil(t, confirmed.UploadExpiresAt)
+
+		_, err = svc.ConfirmUpload(ctx, a.ID, uploaderID)
+		assert.ErrorIs(t, err, ErrAttachmentNotPending)
+	})
+
+	t.Run("confirm by other user denied", func(t *testing.T) {
+		svc, _ := newSvc()
+		a, _, err := svc.CreatePendingUpload(ctx, uploaderID, channelID, "a.png", "image/png", 3, "")
+		require.NoError(t, err)
+
+		_, err = svc.ConfirmUpload(ctx, a.ID, uuid.New())
+		assert.ErrorIs(t, err, ErrAttachmentAccessDenied)
+	})
+
+	t.Run("oversized upload is rejected and removed", func(t *testing.T) {
+		svc, backend := newSvc()
+		a, _, err := svc.CreatePendingUpload(ctx, uploaderID, channelID, "a.png", "image/png", 3, "")
+		require.NoError(t, err)
+
+		backend.files[a.Path] = []byte("way more than three bytes")
+		_, err = svc.ConfirmUpload(ctx, a.ID, uploaderID)
+		assert.ErrorIs(t, err, ErrFileTooLarge)
+		assert.NotContains(t, backend.files, a.Path)
+
+		_, err = svc.Get(ctx, a.ID)
+		assert.ErrorIs(t, err, ErrAttachmentNotFound)
+	})
+
+	t.Run("declared size over limit", func(t *testing.T) {
+		svc, _ := newSvc()
+		_, _, err := svc.CreatePendingUpload(ctx, uploaderID, channelID, "big.bin", "application/octet-stream", 11*1024*1024, "")
+		assert.ErrorIs(t, err, ErrFileTooLarge)
+	})
+
+	t.Run("backend without presign support", func(t *testing.T) {
+		svc := NewAttachmentService(storage.NewService(newMockStorageBackend(), 10, nil))
+		_, _, err := svc.CreatePendingUpload(ctx, uploaderID, channelID, "a.png", "image/png", 3, "")
+		assert.ErrorIs(t, err, storage.ErrPresignNotSupported)
+	})
+
+	t.Run("cleanup expired uploads", func(t *testing.T) {
+		svc, _ := newSvc()
+		a, _, err := svc.CreatePendingUpload(ctx, uploaderID, channelID, "a.png", "image/png", 3, "")
+		require.NoError(t, err)
+
+		past := time.Now().Add(-time.Minute)
+		a.UploadExpiresAt = &past
+
+		assert.Equal(t, 1, svc.CleanupExpiredUploads(ctx))
+		_, err = svc.Get(ctx, a.ID)
+		assert.ErrorIs(t, err, ErrAttachmentNotFound)
+	})
+
+	t.Run("periodic cleanup", func(t *testing.T) {
+		svc, _ := newSvc()
+		a, _, err := svc.CreatePendingUpload(ctx, uploaderID, channelID, "a.png", "image/png", 3, "")
+		require.NoError(t, err)
+		past := time.Now().Add(-time.Minute)
+		a.UploadExpiresAt = &past
+
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go svc.RunUploadCleanup(runCtx, 10*time.Millisecond)
+
+		assert.Eventually(t, func() bool {
+			_, err := svc.Get(ctx, a.ID)
+			return errors.Is(err, ErrAttachmentNotFound)
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+// stubScanner flags files containing the EICAR test marker
+type stubScanner struct {
+	err error
+}
+
+func (s *stubScanner) Scan(ctx context.Context, filename string, content io.Reader) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	data, err := io.ReadAll(content)
+	return bytes.Contains(data, []byte("EICAR")), err
+}
+
+// moderatorPermissions grants every permission to one user
+type moderatorPermissions uuid.UUID
+
+func (m moderatorPermissions) HasChannelPermission(ctx context.Context, channelID, userID uuid.UUID, perm int64) (bool, error) {
+	return userID == uuid.UUID(m), nil
+}
+
+func TestAttachmentService_Scan(t *testing.T) {
+	ctx := context.Background()
+	uploaderID, channelID, moderatorID := uuid.New(), uuid.New(), uuid.New()
+
+	newSvc := func(scanner AttachmentScanner) (*AttachmentService, *MockEventBus) {
+		bus := new(MockEventBus)
+		bus.On("Publish", "attachment.updated", mock.Anything).Return()
+		svc := NewAttachmentService(storage.NewService(newMockStorageBackend(), 10, nil))
+		svc.SetScanner(scanner)
+		svc.SetEventBus(bus)
+		svc.SetChannelPermissions(moderatorPermissions(moderatorID))
+		return svc, bus
+	}
+	upload := func(t *testing.T, svc *AttachmentService, content string) *Attachment {
+		a, err := svc.Upload(ctx, createTestFileHeader("notes.txt", "text/plain", []byte(content)), uploaderID, channelID)
+		require.NoError(t, err)
+		return a
+	}
+	scanStatus := func(svc *AttachmentService, id uuid.UUID) string {
+		svc.mu.RLock()
+		defer svc.mu.RUnlock()
+		return svc.attachments[id].ScanStatus
+	}
+
+	t.Run("clean", func(t *testing.T) {
+		svc, bus := newSvc(&stubScanner{})
+		a := upload(t, svc, "hello")
+		assert.Eventually(t, func() bool { return scanStatus(svc, a.ID) == AttachmentScanClean }, time.Second, 10*time.Millisecond)
+
+		assert.NoError(t, svc.CheckDownload(ctx, a.ID, uploaderID))
+		bus.AssertCalled(t, "Publish", "attachment.updated", &AttachmentUpdatedEvent{
+			AttachmentID: a.ID,
+			ChannelID:    channelID,
+			ScanStatus:   AttachmentScanClean,
+		})
+	})
+
+	t.Run("flagged", func(t *testing.T) {
+		svc, _ := newSvc(&stubScanner{})
+		a := upload(t, svc, "X5O!P%@AP EICAR")
+		assert.Eventually(t, func() bool { return scanStatus(svc, a.ID) == AttachmentScanFlagged }, time.Second, 10*time.Millisecond)
+
+		assert.ErrorIs(t, svc.CheckDownload(ctx, a.ID, uploaderID), ErrAttachmentFlagged)
+		assert.NoError(t, svc.CheckDownload(ctx, a.ID, moderatorID), "moderators can still inspect it")
+	})
+
+	t.Run("scanner failure stays pending", func(t *testing.T) {
+		svc, bus := newSvc(&stubScanner{err: errors.New("scanner unavailable")})
+		a := upload(t, svc, "hello")
+		assert.Equal(t, AttachmentScanPending, a.ScanStatus)
+
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, AttachmentScanPending, scanStatus(svc, a.ID))
+		bus.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	})
+
+	t.Run("without a scanner", func(t *testing.T) {
+		svc := NewAttachmentService(storage.NewService(newMockStorageBackend(), 10, nil))
+		a := upload(t, svc, "X5O!P%@AP EICAR")
+		assert.Empty(t, a.ScanStatus)
+		assert.NoError(t, svc.CheckDownload(ctx, a.ID, uploaderID))
+	})
+}
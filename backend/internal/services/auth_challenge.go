@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+)
+
+// CaptchaVerifier checks the tokens a captcha widget gives clients.
+// captcha.Verifier implements it for hCaptcha and Turnstile.
+type CaptchaVerifier interface {
+	// Verify reports whether the token is valid. An error means the
+	// provider couldn't be asked.
+	Verify(ctx context.Context, token, remoteIP string) (bool, error)
+	// Provider and SiteKey tell clients which widget to render
+	Provider() string
+	SiteKey() string
+}
+
+// AuthChallengeCounter counts attempts per address in windows that the
+// counter expires. RedisCache implements it.
+type AuthChallengeCounter interface {
+	IncrementWithExpiry(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// AuthAction is what a client is trying to do when they may be challenged
+type AuthAction string
+
+const (
+	AuthActionLogin    AuthAction = "login"
+	AuthActionRegister AuthAction = "register"
+	// AuthActionPasswordReset is asking for a reset email. It's counted
+	// on its own but against the registrations threshold: every request is
+	// counted, not only failures.
+	AuthActionPasswordReset AuthAction = "password_reset"
+)
+
+// AuthChallengeConfig configures when clients are challenged
+type AuthChallengeConfig struct {
+	// LoginFailures is how many failed logins an address may make in a
+	// window before it must pass a captcha. 0 challenges every login.
+	LoginFailures int
+	// Registrations is how many accounts an address may try to register in
+	// a window before it must pass a captcha. 0 challenges every
+	// registration. Password reset requests have the same threshold.
+	Registrations int
+	Window        time.Duration
+}
+
+// AuthChallengeService makes addresses that fail to log in, or register
+// repeatedly, pass a captcha before trying again. That slows credential
+// stuffing and signup spam down without bothering everyone else.
+type AuthChallengeService struct {
+	verifier CaptchaVerifier
+	config   AuthChallengeConfig
+	counter  AuthChallengeCounter
+}
+
+// NewAuthChallengeService creates a challenge service that counts on this
+// instance only
+func NewAuthChallengeService(verifier CaptchaVerifier, config AuthChallengeConfig) *AuthChallengeService {
+	if config.Window <= 0 {
+		config.Window = 15 * time.Minute
+	}
+	return &AuthChallengeService{
+		verifier: verifier,
+		config:   config,
+		counter:  newMemoryCounter(),
+	}
+}
+
+// SetCounter shares the counts between instances, so an address can't
+// dodge the captcha by landing on another one
+func (s *AuthChallengeService) SetCounter(counter AuthChallengeCounter) {
+	s.counter = counter
+}
+
+// Provider names the captcha provider clients must solve
+func (s *AuthChallengeService) Provider() string {
+	return s.verifier.Provider()
+}
+
+// SiteKey is the key clients render the captcha widget with
+func (s *AuthChallengeService) SiteKey() string {
+	return s.verifier.SiteKey()
+}
+
+// Check decides whether the attempt may go ahead. Once the address is over
+// its threshold it returns ErrCaptchaRequired without a token and
+// ErrCaptchaInvalid with one the provider rejects. A token is only checked
+// when it's needed.
+func (s *AuthChallengeService) Check(ctx context.Context, action AuthAction, ip, token string) error {
+	if !s.challenged(ctx, action, ip) {
+		return nil
+	}
+	if token == "" {
+		return ErrCaptchaRequired
+	}
+
+	ok, err := s.verifier.Verify(ctx, token, ip)
+	if err != nil {
+		log.Printf("[AuthChallenge] %v", err)
+		return ErrCaptchaUnavailable
+	}
+	if !ok {
+		return ErrCaptchaInvalid
+	}
+	return nil
+}
+
+// Record counts an attempt against the address: a failed login, or any
+// registration
+func (s *AuthChallengeService) Record(ctx context.Context, action AuthAction, ip string) {
+	if _, err := s.counter.IncrementWithExpiry(ctx, s.key(action, ip), s.config.Window); err != nil {
+		log.Printf("[AuthChallenge] failed to count %s attempt: %v", action, err)
+	}
+}
+
+func (s *AuthChallengeService) challenged(ctx context.Context, action AuthAction, ip string) bool {
+	threshold := s.config.LoginFailures
+	if action == AuthActionRegister || action == AuthActionPasswordReset {
+		threshold = s.config.Registrations
+	}
+	if threshold <= 0 {
+		return true
+	}
+
+	// A miss or an unreachable counter counts as no attempts, so losing
+	// Redis doesn't challenge everyone
+	raw, err := s.counter.Get(ctx, s.key(action, ip))
+	if err != nil || raw == nil {
+		return false
+	}
+	count, err := strconv.ParseInt(string(raw), 10, 64)
+	return err == nil && count >= int64(threshold)
+}
+
+func (s *AuthChallengeService) key(action AuthAction, ip string) string {
+	return "auth_challenge:" + string(action) + ":" + ip
+}
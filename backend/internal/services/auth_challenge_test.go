@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeCaptcha accepts the token "solved"
+type fakeCaptcha struct {
+	err   error
+	calls int
+}
+
+func (f *fakeCaptcha) Verify(ctx context.Context, token, remoteIP string) (bool, error) {
+	f.calls++
+	return token == "solved", f.err
+}
+
+func (f *fakeCaptcha) Provider() string { return "turnstile" }
+func (f *fakeCaptcha) SiteKey() string  { return "site-key" }
+
+func TestAuthChallengeService_Login(t *testing.T) {
+	ctx := context.Background()
+	verifier := &fakeCaptcha{}
+	svc := NewAuthChallengeService(verifier, AuthChallengeConfig{LoginFailures: 2, Registrations: 5, Window: time.Minute})
+
+	assert.NoError(t, svc.Check(ctx, AuthActionLogin, "203.0.113.7", ""))
+	svc.Record(ctx, AuthActionLogin, "203.0.113.7")
+	assert.NoError(t, svc.Check(ctx, AuthActionLogin, "203.0.113.7", ""))
+	svc.Record(ctx, AuthActionLogin, "203.0.113.7")
+
+	assert.ErrorIs(t, svc.Check(ctx, AuthActionLogin, "203.0.113.7", ""), ErrCaptchaRequired)
+	assert.ErrorIs(t, svc.Check(ctx, AuthActionLogin, "203.0.113.7", "guess"), ErrCaptchaInvalid)
+	assert.NoError(t, svc.Check(ctx, AuthActionLogin, "203.0.113.7", "solved"))
+
+	assert.NoError(t, svc.Check(ctx, AuthActionLogin, "198.51.100.1", ""), "other addresses aren't challenged")
+	assert.NoError(t, svc.Check(ctx, AuthActionRegister, "203.0.113.7", ""), "actions are counted apart")
+
+	calls := verifier.calls
+	assert.NoError(t, svc.Check(ctx, AuthActionLogin, "198.51.100.1", "solved"))
+	assert.Equal(t, calls, verifier.calls, "tokens aren't checked until they're needed")
+}
+
+func TestAuthChallengeService_AlwaysChallenge(t *testing.T) {
+	svc := NewAuthChallengeService(&fakeCaptcha{}, AuthChallengeConfig{LoginFailures: 5, Registrations: 0})
+	assert.ErrorIs(t, svc.Check(context.Background(), AuthActionRegister, "203.0.113.7", ""), ErrCaptchaRequired)
+	assert.NoError(t, svc.Check(context.Background(), AuthActionLogin, "203.0.113.7", ""))
+}
+
+func TestAuthChallengeService_ProviderDown(t *testing.T) {
+	svc := NewAuthChallengeService(&fakeCaptcha{err: errors.New("timeout")}, AuthChallengeConfig{Registrations: 0})
+	assert.ErrorIs(t, svc.Check(context.Background(), AuthActionRegister, "203.0.113.7", "solved"), ErrCaptchaUnavailable)
+}
@@ -38,18 +38,54 @@ type authRepository interface {
 type authService struct {
 	repo       authRepository
 	jwtService *auth.JWTService
+	usernames  UsernamePolicy
+	sessions   *SessionService
+	security   *LoginSecurityService
 }
 
 // NewAuthService creates a new auth service instance.
 func NewAuthService(repo authRepository, jwtService *auth.JWTService) AuthService {
+	return NewAuthServiceWithUsernamePolicy(repo, jwtService, nil)
+}
+
+// NewAuthServiceWithUsernamePolicy creates an auth service that refuses
+// registrations with reserved or banned usernames.
+func NewAuthServiceWithUsernamePolicy(repo authRepository, jwtService *auth.JWTService, usernames UsernamePolicy) AuthService {
+	return NewAuthServiceWithSessions(repo, jwtService, usernames, nil)
+}
+
+// NewAuthServiceWithSessions creates an auth service that opens a login
+// session for every token pair it issues, so sessions can be listed and
+// revoked. sessions may be nil, in which case tokens aren't tracked.
+func NewAuthServiceWithSessions(repo authRepository, jwtService *auth.JWTService, usernames UsernamePolicy, sessions *SessionService) AuthService {
+	return NewAuthServiceWithLoginSecurity(repo, jwtService, usernames, sessions, nil)
+}
+
+// NewAuthServiceWithLoginSecurity creates an auth service that locks
+// accounts after repeated failed logins and remembers the devices users
+// log in from. security may be nil to do neither.
+func NewAuthServiceWithLoginSecurity(repo authRepository, jwtService *auth.JWTService, usernames UsernamePolicy, sessions *SessionService, security *LoginSecurityService) AuthService {
 	return &authService{
 		repo:       repo,
 		jwtService: jwtService,
+		usernames:  usernames,
+		sessions:   sessions,
+		security:   security,
 	}
 }
 
 // Register handles new user registration.
 func (s *authService) Register(ctx context.Context, email, username, password string) (*models.User, *AuthTokens, error) {
+	if err := validateUsername(username); err != nil {
+		return nil, nil, err
+	}
+	if s.usernames != nil {
+		// Nobody registers as staff, so reserved usernames are refused too
+		if err := s.usernames.Check(ctx, username, false); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// Check if user already exists
 	_, err := s.repo.GetByEmail(ctx, email)
 	if err == nil {
@@ -59,42 +95,60 @@ func (s *authService) Register(ctx context.Context, email, username, password st
 		return nil, nil, err // Return unexpected database errors
 	}
 
-	// Hash password using bounded worker pool (prevents CPU saturation under load)
-	hashedPassword, err := auth.HashPasswordPooled(ctx, password)
+	hashedPassword, err := hashPassword(ctx, password)
 	if err != nil {
-		// Convert auth package errors to services errors for proper HTTP handling
-		switch err {
-		case auth.ErrPasswordTooShort:
-			return nil, nil, ErrPasswordTooShort
-		case auth.ErrPasswordTooLong:
-			return nil, nil, ErrPasswordTooLong
-		case auth.ErrPasswordWeak:
-			return nil, nil, ErrPasswordWeak
-		default:
-			return nil, nil, err
-		}
+		return nil, nil, err
 	}
 
 	user := &models.User{
-		ID:           uuid.New(),
-		Email:        email,
-		Username:     username,
-		PasswordHash: hashedPassword,
+		ID:            uuid.New(),
+		Email:         email,
+		Username:      username,
+		Discriminator: models.LegacyDiscriminator,
+		PasswordHash:  hashedPassword,
 	}
 
+	// The repository enforces unique usernames and returns
+	// ErrUsernameTaken on a clash
 	if err := s.repo.Create(ctx, user); err != nil {
 		return nil, nil, err
 	}
 
 	// Generate JWT tokens
-	tokens, err := s.generateTokens(user)
+	tokens, err := s.generateTokens(ctx, user)
 	if err != nil {
 		return nil, nil, err
 	}
+	if s.security != nil {
+		// The first device is remembered without an alert
+		s.security.LoginSucceeded(ctx, user)
+	}
 
 	return user, tokens, nil
 }
 
+// hashPassword checks a new password's strength and hashes it
+func hashPassword(ctx context.Context, password string) (string, error) {
+	// Hash password using bounded worker pool (prevents CPU saturation under load)
+	hashedPassword, err := auth.HashPasswordPooled(ctx, password)
+	if err != nil {
+		// Convert auth package errors to services errors for proper HTTP handling
+		switch {
+		case err == auth.ErrPasswordTooShort:
+			return "", ErrPasswordTooShort
+		case err == auth.ErrPasswordTooLong:
+			return "", ErrPasswordTooLong
+		case err == auth.ErrPasswordWeak:
+			return "", ErrPasswordWeak
+		case auth.IsOverloaded(err):
+			return "", ErrAuthUnavailable
+		default:
+			return "", err
+		}
+	}
+	return hashedPassword, nil
+}
+
 // Login handles user login and credentials verification.
 func (s *authService) Login(ctx context.Context, email, password string) (*models.User, *AuthTokens, error) {
 	user, err := s.repo.GetByEmail(ctx, email)
@@ -104,17 +158,36 @@ func (s *authService) Login(ctx context.Context, email, password string) (*model
 		}
 		return nil, nil, err
 	}
+	// A locked account's password isn't checked at all, so guessing can't
+	// go on while it's locked
+	if s.security != nil {
+		if err := s.security.CheckLocked(ctx, user.ID); err != nil {
+			return nil, nil, err
+		}
+	}
 
 	// Verify password using bounded worker pool (prevents CPU saturation under load)
 	if err := auth.CheckPasswordPooled(ctx, password, user.PasswordHash); err != nil {
+		// A busy pool says nothing about the password
+		if auth.IsOverloaded(err) {
+			return nil, nil, ErrAuthUnavailable
+		}
+		if s.security != nil {
+			if err := s.security.LoginFailed(ctx, user.ID); err != nil {
+				return nil, nil, err
+			}
+		}
 		return nil, nil, ErrInvalidCredentials
 	}
 
 	// Generate JWT tokens
-	tokens, err := s.generateTokens(user)
+	tokens, err := s.generateTokens(ctx, user)
 	if err != nil {
 		return nil, nil, err
 	}
+	if s.security != nil {
+		s.security.LoginSucceeded(ctx, user)
+	}
 
 	return user, tokens, nil
 }
@@ -125,9 +198,16 @@ func (s *authService) RefreshTokens(ctx context.Context, refreshToken string) (*
 	if err != nil {
 		return nil, err
 	}
+	if s.sessions != nil {
+		return s.sessions.Refresh(ctx, claims, refreshToken)
+	}
 
 	// Generate new token pair
-	accessToken, newRefreshToken, err := s.jwtService.GenerateTokenPair(claims.UserID, claims.Username)
+	generate := s.jwtService.GenerateTokenPair
+	if claims.Bot {
+		generate = s.jwtService.GenerateBotTokenPair
+	}
+	accessToken, newRefreshToken, err := generate(claims.UserID, claims.Username)
 	if err != nil {
 		return nil, err
 	}
@@ -150,8 +230,21 @@ func (s *authService) ValidateToken(ctx context.Context, token string) (uuid.UUI
 }
 
 // generateTokens creates a new token pair for a user
-func (s *authService) generateTokens(user *models.User) (*AuthTokens, error) {
-	accessToken, refreshToken, err := s.jwtService.GenerateTokenPair(user.ID, user.Username)
+func (s *authService) generateTokens(ctx context.Context, user *models.User) (*AuthTokens, error) {
+	return issueTokens(ctx, s.jwtService, s.sessions, user)
+}
+
+// issueTokens creates a token pair for a user, opening a session for it if
+// sessions are tracked
+func issueTokens(ctx context.Context, jwtService *auth.JWTService, sessions *SessionService, user *models.User) (*AuthTokens, error) {
+	if sessions != nil {
+		return sessions.Start(ctx, user.ID, user.Username, user.IsBot())
+	}
+	generate := jwtService.GenerateTokenPair
+	if user.IsBot() {
+		generate = jwtService.GenerateBotTokenPair
+	}
+	accessToken, refreshToken, err := generate(user.ID, user.Username)
 	if err != nil {
 		return nil, err
 	}
@@ -159,6 +252,6 @@ func (s *authService) generateTokens(user *models.User) (*AuthTokens, error) {
 	return &AuthTokens{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
-		ExpiresIn:    s.jwtService.GetExpirySeconds(),
+		ExpiresIn:    jwtService.GetExpirySeconds(),
 	}, nil
 }
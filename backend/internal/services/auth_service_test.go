@@ -86,6 +86,7 @@ func TestAuthService_Register_Success(t *testing.T) {
 		user := args.Get(1).(*models.User)
 		assert.Equal(t, email, user.Email)
 		assert.Equal(t, username, user.Username)
+		assert.Equal(t, models.LegacyDiscriminator, user.Discriminator)
 		assert.NotEmpty(t, user.PasswordHash)
 		assert.NotEqual(t, password, user.PasswordHash)
 	})
@@ -123,6 +124,36 @@ func TestAuthService_Register_UserExists(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestAuthService_Register_InvalidUsername(t *testing.T) {
+	mockRepo := new(MockAuthRepository)
+	jwtService := testJWTService()
+	service := NewAuthService(mockRepo, jwtService)
+	ctx := context.Background()
+
+	user, tokens, err := service.Register(ctx, "test@example.com", "test user", "Password123")
+
+	assert.ErrorIs(t, err, ErrInvalidUsername)
+	assert.Nil(t, user)
+	assert.Nil(t, tokens)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Register_UsernameRules(t *testing.T) {
+	mockRepo := new(MockAuthRepository)
+	rules := NewUsernameRuleService(&fakeUsernameRuleRepository{})
+	_, err := rules.CreateRule(context.Background(), uuid.New(), &models.CreateUsernameRuleRequest{Kind: models.UsernameRuleReserved, Pattern: "admin"})
+	assert.NoError(t, err)
+	service := NewAuthServiceWithUsernamePolicy(mockRepo, testJWTService(), rules)
+	ctx := context.Background()
+
+	user, tokens, err := service.Register(ctx, "test@example.com", "Admin", "Password123")
+
+	assert.ErrorIs(t, err, ErrUsernameReserved)
+	assert.Nil(t, user)
+	assert.Nil(t, tokens)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
 func TestAuthService_Register_RepositoryError(t *testing.T) {
 	mockRepo := new(MockAuthRepository)
 	jwtService := testJWTService()
@@ -0,0 +1,788 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// AutoMod limits
+const (
+	// MaxAutoModRules is how many rules a server can have
+	MaxAutoModRules = 25
+
+	maxAutoModRuleName      = 100
+	maxAutoModKeywords      = 1000
+	maxAutoModKeywordLength = 60
+	maxAutoModPatterns      = 10
+	maxAutoModPatternLength = 260
+	maxAutoModAllowList     = 100
+	maxAutoModExemptions    = 50
+	maxAutoModMessageLimit  = 100
+	maxAutoModInterval      = 5 * time.Minute
+
+	// autoModCacheTTL bounds how long another instance's edits take to
+	// apply here. Edits made on this instance apply straight away.
+	autoModCacheTTL  = time.Minute
+	autoModCacheSize = 10000
+
+	// autoModAlertQuote is how much of a flagged message an alert repeats
+	autoModAlertQuote = 1000
+)
+
+// inviteLinkPattern matches invite links to this or any other instance,
+// and Discord's. The code is captured.
+var inviteLinkPattern = regexp.MustCompile(`(?i)(?:https?://)?(?:discord(?:app)?\.com/invite|discord\.gg|[\w-]+(?:\.[\w-]+)+(?::\d+)?/invite)/([\w-]+)`)
+
+// AutoModRuleRepository stores AutoMod rules
+type AutoModRuleRepository interface {
+	Create(ctx context.Context, rule *models.AutoModRule) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.AutoModRule, error)
+	GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*models.AutoModRule, error)
+	Update(ctx context.Context, rule *models.AutoModRule) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// AutoModCounter counts messages for message_rate rules. RedisCache
+// implements it.
+type AutoModCounter interface {
+	IncrementWithExpiry(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// MemberTimeoutRepository stores member timeouts. The Postgres
+// ServerRepository implements it.
+type MemberTimeoutRepository interface {
+	SetMemberTimeout(ctx context.Context, serverID, userID uuid.UUID, until *time.Time) error
+}
+
+// AutoModActionEvent is published for every action AutoMod takes
+type AutoModActionEvent struct {
+	ServerID    uuid.UUID
+	ChannelID   uuid.UUID
+	UserID      uuid.UUID
+	RuleID      uuid.UUID
+	RuleName    string
+	TriggerType models.AutoModTriggerType
+	Action      models.AutoModAction
+	// MessageID is set when the message was still sent
+	MessageID *uuid.UUID
+	Content   string
+	// MatchedContent is the text that broke the rule. It's empty for
+	// mention_spam and message_rate.
+	MatchedContent string
+}
+
+// AutoModVerdict is what AutoMod decided about a message
+type AutoModVerdict struct {
+	// Blocked messages are rejected with ErrAutoModBlocked
+	Blocked bool
+	// Dropped messages look sent to their author but aren't stored or
+	// delivered
+	Dropped bool
+}
+
+// AutoModService manages AutoMod rules and checks messages against them
+type AutoModService struct {
+	repo        AutoModRuleRepository
+	serverRepo  ServerRepository
+	channelRepo ChannelRepository
+	roleRepo    RoleRepository
+	messages    MessageRepository
+	eventBus    EventBus
+
+	counter  AutoModCounter
+	timeouts MemberTimeoutRepository
+
+	mu    sync.Mutex
+	cache map[uuid.UUID]autoModRuleSet
+}
+
+// autoModRuleSet is a server's compiled, enabled rules
+type autoModRuleSet struct {
+	rules   []*compiledAutoModRule
+	expires time.Time
+}
+
+// NewAutoModService creates a new AutoMod service. Message rates are
+// counted per instance until SetCounter is called.
+func NewAutoModService(
+	repo AutoModRuleRepository,
+	serverRepo ServerRepository,
+	channelRepo ChannelRepository,
+	roleRepo RoleRepository,
+	messages MessageRepository,
+	eventBus EventBus,
+) *AutoModService {
+	return &AutoModService{
+		repo:        repo,
+		serverRepo:  serverRepo,
+		channelRepo: channelRepo,
+		roleRepo:    roleRepo,
+		messages:    messages,
+		eventBus:    eventBus,
+		counter:     newMemoryCounter(),
+		cache:       make(map[uuid.UUID]autoModRuleSet),
+	}
+}
+
+// SetCounter shares message_rate counts between instances
+func (s *AutoModService) SetCounter(counter AutoModCounter) {
+	s.counter = counter
+}
+
+// SetTimeoutRepository enables the timeout action. Without it timeout
+// actions are skipped.
+func (s *AutoModService) SetTimeoutRepository(timeouts MemberTimeoutRepository) {
+	s.timeouts = timeouts
+}
+
+// GetRules returns a server's rules. The requester needs MANAGE_SERVER.
+func (s *AutoModService) GetRules(ctx context.Context, serverID, requesterID uuid.UUID) ([]*models.AutoModRule, error) {
+	if err := s.authorize(ctx, serverID, requesterID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByServerID(ctx, serverID)
+}
+
+// GetRule returns one of a server's rules
+func (s *AutoModService) GetRule(ctx context.Context, serverID, ruleID, requesterID uuid.UUID) (*models.AutoModRule, error) {
+	if err := s.authorize(ctx, serverID, requesterID); err != nil {
+		return nil, err
+	}
+	return s.getRule(ctx, serverID, ruleID)
+}
+
+// CreateRule adds a rule to a server
+func (s *AutoModService) CreateRule(ctx context.Context, serverID, requesterID uuid.UUID, req *models.CreateAutoModRuleRequest) (*models.AutoModRule, error) {
+	if err := s.authorize(ctx, serverID, requesterID); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.GetByServerID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= MaxAutoModRules {
+		return nil, ErrTooManyAutoModRules
+	}
+
+	now := time.Now()
+	rule := &models.AutoModRule{
+		ID:              uuid.New(),
+		ServerID:        serverID,
+		CreatorID:       requesterID,
+		Name:            strings.TrimSpace(req.Name),
+		TriggerType:     req.TriggerType,
+		TriggerMetadata: req.TriggerMetadata,
+		Actions:         req.Actions,
+		Enabled:         req.Enabled == nil || *req.Enabled,
+		ExemptRoles:     req.ExemptRoles,
+		ExemptChannels:  req.ExemptChannels,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := s.validate(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, rule); err != nil {
+		return nil, err
+	}
+	s.invalidate(serverID)
+
+	return rule, nil
+}
+
+// UpdateRule changes a rule
+func (s *AutoModService) UpdateRule(ctx context.Context, serverID, ruleID, requesterID uuid.UUID, req *models.UpdateAutoModRuleRequest) (*models.AutoModRule, error) {
+	if err := s.authorize(ctx, serverID, requesterID); err != nil {
+		return nil, err
+	}
+	rule, err := s.getRule(ctx, serverID, ruleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		rule.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.TriggerMetadata != nil {
+		rule.TriggerMetadata = *req.TriggerMetadata
+	}
+	if req.Actions != nil {
+		rule.Actions = *req.Actions
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if req.ExemptRoles != nil {
+		rule.ExemptRoles = *req.ExemptRoles
+	}
+	if req.ExemptChannels != nil {
+		rule.ExemptChannels = *req.ExemptChannels
+	}
+	if err := s.validate(ctx, rule); err != nil {
+		return nil, err
+	}
+	rule.UpdatedAt = time.Now()
+
+	if err := s.repo.Update(ctx, rule); err != nil {
+		return nil, err
+	}
+	s.invalidate(serverID)
+
+	return rule, nil
+}
+
+// DeleteRule removes a rule
+func (s *AutoModService) DeleteRule(ctx context.Context, serverID, ruleID, requesterID uuid.UUID) error {
+	if err := s.authorize(ctx, serverID, requesterID); err != nil {
+		return err
+	}
+	if _, err := s.getRule(ctx, serverID, ruleID); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, ruleID); err != nil {
+		return err
+	}
+	s.invalidate(serverID)
+
+	return nil
+}
+
+func (s *AutoModService) authorize(ctx context.Context, serverID, userID uuid.UUID) error {
+	server, err := s.serverRepo.GetByID(ctx, serverID)
+	if err != nil {
+		return err
+	}
+	if server == nil {
+		return ErrServerNotFound
+	}
+	if !memberHasPermission(ctx, s.serverRepo, s.roleRepo, server, userID, models.PermManageServer) {
+		return ErrCannotManageAutoMod
+	}
+	return nil
+}
+
+func (s *AutoModService) getRule(ctx context.Context, serverID, ruleID uuid.UUID) (*models.AutoModRule, error) {
+	rule, err := s.repo.GetByID(ctx, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	if rule == nil || rule.ServerID != serverID {
+		return nil, ErrAutoModRuleNotFound
+	}
+	return rule, nil
+}
+
+func invalidAutoModRule(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidAutoModRule, fmt.Sprintf(format, args...))
+}
+
+// validate checks a rule before it's saved, and normalizes its lists
+func (s *AutoModService) validate(ctx context.Context, rule *models.AutoModRule) error {
+	if rule.Name == "" || len(rule.Name) > maxAutoModRuleName {
+		return invalidAutoModRule("name must be between 1 and %d characters", maxAutoModRuleName)
+	}
+	if !rule.TriggerType.Valid() {
+		return invalidAutoModRule("unknown trigger type %q", rule.TriggerType)
+	}
+
+	meta := &rule.TriggerMetadata
+	if len(meta.AllowList) > maxAutoModAllowList {
+		return invalidAutoModRule("allow list can have at most %d entries", maxAutoModAllowList)
+	}
+	switch rule.TriggerType {
+	case models.AutoModTriggerKeyword:
+		if len(meta.Keywords) == 0 || len(meta.Keywords) > maxAutoModKeywords {
+			return invalidAutoModRule("keyword rules need between 1 and %d keywords", maxAutoModKeywords)
+		}
+		for i, keyword := range meta.Keywords {
+			keyword = strings.TrimSpace(keyword)
+			if strings.Trim(keyword, "*") == "" || len(keyword) > maxAutoModKeywordLength {
+				return invalidAutoModRule("keywords must be between 1 and %d characters", maxAutoModKeywordLength)
+			}
+			meta.Keywords[i] = keyword
+		}
+	case models.AutoModTriggerRegex:
+		if len(meta.Patterns) == 0 || len(meta.Patterns) > maxAutoModPatterns {
+			return invalidAutoModRule("regex rules need between 1 and %d patterns", maxAutoModPatterns)
+		}
+		for _, pattern := range meta.Patterns {
+			if pattern == "" || len(pattern) > maxAutoModPatternLength {
+				return invalidAutoModRule("patterns must be between 1 and %d characters", maxAutoModPatternLength)
+			}
+			if _, err := regexp.Compile(pattern); err != nil {
+				return invalidAutoModRule("pattern %q: %v", pattern, err)
+			}
+		}
+	case models.AutoModTriggerMentionSpam:
+		// parseMentions stops counting at maxMentionsPerMessage
+		if meta.MentionLimit < 1 || meta.MentionLimit >= maxMentionsPerMessage {
+			return invalidAutoModRule("mention_limit must be between 1 and %d", maxMentionsPerMessage-1)
+		}
+	case models.AutoModTriggerMessageRate:
+		if meta.MessageLimit < 1 || meta.MessageLimit > maxAutoModMessageLimit {
+			return invalidAutoModRule("message_limit must be between 1 and %d", maxAutoModMessageLimit)
+		}
+		if meta.IntervalSeconds < 1 || time.Duration(meta.IntervalSeconds)*time.Second > maxAutoModInterval {
+			return invalidAutoModRule("interval_seconds must be between 1 and %d", int(maxAutoModInterval.Seconds()))
+		}
+	}
+
+	if len(rule.Actions) == 0 {
+		return invalidAutoModRule("rules need at least one action")
+	}
+	seen := make(map[models.AutoModActionType]bool)
+	for _, action := range rule.Actions {
+		if !action.Type.Valid() {
+			return invalidAutoModRule("unknown action type %q", action.Type)
+		}
+		if seen[action.Type] {
+			return invalidAutoModRule("action %q is listed twice", action.Type)
+		}
+		seen[action.Type] = true
+
+		switch action.Type {
+		case models.AutoModActionTimeout:
+			d := time.Duration(action.DurationSeconds) * time.Second
+			if d <= 0 || d > MaxTimeoutDuration {
+				return invalidAutoModRule("timeout duration_seconds must be between 1 and %d", int(MaxTimeoutDuration.Seconds()))
+			}
+		case models.AutoModActionAlert:
+			if action.ChannelID == nil {
+				return invalidAutoModRule("send_alert needs a channel_id")
+			}
+			channel, err := s.channelRepo.GetByID(ctx, *action.ChannelID)
+			if err != nil {
+				return err
+			}
+			if channel == nil || channel.ServerID == nil || *channel.ServerID != rule.ServerID {
+				return invalidAutoModRule("alert channel must be a channel in this server")
+			}
+		}
+	}
+
+	if len(rule.ExemptRoles) > maxAutoModExemptions || len(rule.ExemptChannels) > maxAutoModExemptions {
+		return invalidAutoModRule("rules can exempt at most %d roles and %d channels", maxAutoModExemptions, maxAutoModExemptions)
+	}
+	// Stored as empty arrays rather than NULL
+	if rule.ExemptRoles == nil {
+		rule.ExemptRoles = []uuid.UUID{}
+	}
+	if rule.ExemptChannels == nil {
+		rule.ExemptChannels = []uuid.UUID{}
+	}
+
+	if _, err := compileAutoModRule(rule); err != nil {
+		return invalidAutoModRule("%v", err)
+	}
+	return nil
+}
+
+// invalidate drops a server's compiled rules so the next message reloads
+// them
+func (s *AutoModService) invalidate(serverID uuid.UUID) {
+	s.mu.Lock()
+	delete(s.cache, serverID)
+	s.mu.Unlock()
+}
+
+// serverRules returns a server's enabled rules, compiled
+func (s *AutoModService) serverRules(ctx context.Context, serverID uuid.UUID) ([]*compiledAutoModRule, error) {
+	now := time.Now()
+	s.mu.Lock()
+	set, ok := s.cache[serverID]
+	s.mu.Unlock()
+	if ok && now.Before(set.expires) {
+		return set.rules, nil
+	}
+
+	rules, err := s.repo.GetByServerID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	var compiled []*compiledAutoModRule
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		c, err := compileAutoModRule(rule)
+		if err != nil {
+			log.Printf("[AutoMod] Skipping rule %s: %v", rule.ID, err)
+			continue
+		}
+		compiled = append(compiled, c)
+	}
+
+	s.mu.Lock()
+	if len(s.cache) >= autoModCacheSize {
+		for id, set := range s.cache {
+			if !now.Before(set.expires) {
+				delete(s.cache, id)
+			}
+		}
+	}
+	s.cache[serverID] = autoModRuleSet{rules: compiled, expires: now.Add(autoModCacheTTL)}
+	s.mu.Unlock()
+
+	return compiled, nil
+}
+
+// Moderate checks a message about to be stored against its server's rules
+// and carries out the actions of every rule it breaks. Edits are only
+// checked against content rules, and can't be dropped, only blocked.
+// AutoMod fails open: if the rules can't be loaded the message goes
+// through.
+func (s *AutoModService) Moderate(ctx context.Context, message *models.Message, member *models.Member, edit bool) AutoModVerdict {
+	var verdict AutoModVerdict
+	if message.ServerID == nil {
+		return verdict
+	}
+	serverID := *message.ServerID
+
+	rules, err := s.serverRules(ctx, serverID)
+	if err != nil {
+		log.Printf("[AutoMod] Failed to load rules for server %s: %v", serverID, err)
+		return verdict
+	}
+
+	type match struct {
+		rule    *compiledAutoModRule
+		content string
+	}
+	var matches []match
+	for _, rule := range rules {
+		if rule.exempt(member, message.ChannelID) {
+			continue
+		}
+		var matched string
+		var ok bool
+		if rule.TriggerType == models.AutoModTriggerMessageRate {
+			ok = !edit && s.overRate(ctx, rule, message.AuthorID)
+		} else if message.EncryptedContent != "" {
+			// Content rules can't see into encrypted messages
+			continue
+		} else {
+			matched, ok = rule.match(message.Content)
+		}
+		if ok {
+			matches = append(matches, match{rule: rule, content: matched})
+		}
+	}
+	if len(matches) == 0 || s.exemptMember(ctx, serverID, message.AuthorID) {
+		return verdict
+	}
+
+	var timeout time.Duration
+	for _, m := range matches {
+		for _, action := range m.rule.Actions {
+			switch action.Type {
+			case models.AutoModActionBlock:
+				verdict.Blocked = true
+			case models.AutoModActionDelete:
+				verdict.Dropped = true
+			case models.AutoModActionTimeout:
+				if d := time.Duration(action.DurationSeconds) * time.Second; d > timeout {
+					timeout = d
+				}
+			}
+		}
+	}
+	// Blocking tells the author, which makes silently dropping moot
+	if verdict.Blocked || (edit && verdict.Dropped) {
+		verdict.Blocked, verdict.Dropped = true, false
+	}
+
+	var messageID *uuid.UUID
+	if !verdict.Blocked && !verdict.Dropped {
+		messageID = &message.ID
+	}
+	for _, m := range matches {
+		for _, action := range m.rule.Actions {
+			if action.Type == models.AutoModActionAlert {
+				s.sendAlert(ctx, message, m.rule, m.content, verdict, *action.ChannelID)
+			}
+			s.eventBus.Publish("automod.action_executed", &AutoModActionEvent{
+				ServerID:       serverID,
+				ChannelID:      message.ChannelID,
+				UserID:         message.AuthorID,
+				RuleID:         m.rule.ID,
+				RuleName:       m.rule.Name,
+				TriggerType:    m.rule.TriggerType,
+				Action:         action,
+				MessageID:      messageID,
+				Content:        message.Content,
+				MatchedContent: m.content,
+			})
+		}
+	}
+	if timeout > 0 {
+		s.timeoutMember(ctx, member, timeout)
+	}
+
+	return verdict
+}
+
+// exemptMember reports whether a member is above AutoMod: the owner and
+// anyone who can manage the server
+func (s *AutoModService) exemptMember(ctx context.Context, serverID, userID uuid.UUID) bool {
+	server, err := s.serverRepo.GetByID(ctx, serverID)
+	if err != nil || server == nil {
+		return false
+	}
+	return memberHasPermission(ctx, s.serverRepo, s.roleRepo, server, userID, models.PermManageServer)
+}
+
+// overRate counts a message towards a message_rate rule and reports
+// whether the author has gone over its limit
+func (s *AutoModService) overRate(ctx context.Context, rule *compiledAutoModRule, userID uuid.UUID) bool {
+	interval := time.Duration(rule.TriggerMetadata.IntervalSeconds) * time.Second
+	count, err := s.counter.IncrementWithExpiry(ctx, fmt.Sprintf("automod:rate:%s:%s", rule.ID, userID), interval)
+	if err != nil {
+		log.Printf("[AutoMod] Failed to count messages for rule %s: %v", rule.ID, err)
+		return false
+	}
+	return count > int64(rule.TriggerMetadata.MessageLimit)
+}
+
+// timeoutMember times a member out unless they're already timed out for
+// longer
+func (s *AutoModService) timeoutMember(ctx context.Context, member *models.Member, d time.Duration) {
+	if s.timeouts == nil {
+		log.Printf("[AutoMod] Not timing out %s: timeouts aren't configured", member.UserID)
+		return
+	}
+	until := time.Now().Add(d)
+	if member.CommunicationDisabledUntil != nil && member.CommunicationDisabledUntil.After(until) {
+		return
+	}
+	if err := s.timeouts.SetMemberTimeout(ctx, member.ServerID, member.UserID, &until); err != nil {
+		log.Printf("[AutoMod] Failed to time out %s: %v", member.UserID, err)
+		return
+	}
+
+	updated := *member
+	updated.CommunicationDisabledUntil = &until
+	s.eventBus.Publish("server.member_updated", &MemberUpdatedEvent{
+		ServerID: member.ServerID,
+		UserID:   member.UserID,
+		Member:   &updated,
+	})
+}
+
+// sendAlert posts what AutoMod did to a rule's alert channel
+func (s *AutoModService) sendAlert(ctx context.Context, message *models.Message, rule *compiledAutoModRule, matched string, verdict AutoModVerdict, channelID uuid.UUID) {
+	alert := &models.Message{
+		ID:        uuid.New(),
+		ChannelID: channelID,
+		ServerID:  message.ServerID,
+		AuthorID:  message.AuthorID,
+		Content:   autoModAlertContent(message, rule.Name, matched, verdict),
+		Type:      models.MessageTypeAutoModAction,
+		CreatedAt: time.Now(),
+	}
+	if err := s.messages.Create(ctx, alert); err != nil {
+		log.Printf("[AutoMod] Failed to send alert to channel %s: %v", channelID, err)
+		return
+	}
+	_ = s.channelRepo.UpdateLastMessage(ctx, channelID, alert.ID, alert.CreatedAt)
+
+	s.eventBus.Publish("message.created", &MessageCreatedEvent{
+		Message:   alert,
+		ChannelID: channelID,
+		ServerID:  alert.ServerID,
+	})
+}
+
+func autoModAlertContent(message *models.Message, ruleName, matched string, verdict AutoModVerdict) string {
+	outcome := "flagged"
+	if verdict.Blocked {
+		outcome = "blocked"
+	} else if verdict.Dropped {
+		outcome = "deleted"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "AutoMod %s a message from <@%s> in <#%s> (rule: %s", outcome, message.AuthorID, message.ChannelID, ruleName)
+	if matched != "" {
+		fmt.Fprintf(&b, ", matched: %q", matched)
+	}
+	b.WriteString(")")
+
+	quote := message.Content
+	if runes := []rune(quote); len(runes) > autoModAlertQuote {
+		quote = string(runes[:autoModAlertQuote]) + "…"
+	}
+	if quote != "" {
+		for _, line := range strings.Split(quote, "\n") {
+			b.WriteString("\n> ")
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
+
+// compiledAutoModRule is a rule ready to match messages
+type compiledAutoModRule struct {
+	*models.AutoModRule
+	pattern        *regexp.Regexp
+	allow          map[string]bool
+	exemptRoles    map[uuid.UUID]bool
+	exemptChannels map[uuid.UUID]bool
+}
+
+func compileAutoModRule(rule *models.AutoModRule) (*compiledAutoModRule, error) {
+	c := &compiledAutoModRule{
+		AutoModRule:    rule,
+		allow:          make(map[string]bool, len(rule.TriggerMetadata.AllowList)),
+		exemptRoles:    make(map[uuid.UUID]bool, len(rule.ExemptRoles)),
+		exemptChannels: make(map[uuid.UUID]bool, len(rule.ExemptChannels)),
+	}
+	for _, id := range rule.ExemptRoles {
+		c.exemptRoles[id] = true
+	}
+	for _, id := range rule.ExemptChannels {
+		c.exemptChannels[id] = true
+	}
+	for _, allowed := range rule.TriggerMetadata.AllowList {
+		// Invite codes are case sensitive; words aren't
+		if rule.TriggerType != models.AutoModTriggerInviteLink {
+			allowed = strings.ToLower(allowed)
+		}
+		c.allow[allowed] = true
+	}
+
+	var err error
+	switch rule.TriggerType {
+	case models.AutoModTriggerKeyword:
+		parts := make([]string, len(rule.TriggerMetadata.Keywords))
+		for i, keyword := range rule.TriggerMetadata.Keywords {
+			parts[i] = keywordPattern(keyword)
+		}
+		c.pattern, err = regexp.Compile(`(?i)` + strings.Join(parts, "|"))
+	case models.AutoModTriggerRegex:
+		parts := make([]string, len(rule.TriggerMetadata.Patterns))
+		for i, pattern := range rule.TriggerMetadata.Patterns {
+			parts[i] = "(?:" + pattern + ")"
+		}
+		c.pattern, err = regexp.Compile(strings.Join(parts, "|"))
+	case models.AutoModTriggerInviteLink:
+		c.pattern = inviteLinkPattern
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// keywordPattern matches a keyword as a whole word. A leading or trailing *
+// lets it match inside longer words, and the whole word is matched so it
+// can be allow-listed.
+func keywordPattern(keyword string) string {
+	start, end := `\b`, `\b`
+	if strings.HasPrefix(keyword, "*") {
+		start = `\w*`
+	}
+	if strings.HasSuffix(keyword, "*") {
+		end = `\w*`
+	}
+	return start + regexp.QuoteMeta(strings.Trim(keyword, "*")) + end
+}
+
+// exempt reports whether the rule skips this member or channel
+func (r *compiledAutoModRule) exempt(member *models.Member, channelID uuid.UUID) bool {
+	if r.exemptChannels[channelID] {
+		return true
+	}
+	for _, roleID := range member.Roles {
+		if r.exemptRoles[roleID] {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether content breaks a content rule, and the text that
+// broke it
+func (r *compiledAutoModRule) match(content string) (string, bool) {
+	switch r.TriggerType {
+	case models.AutoModTriggerKeyword, models.AutoModTriggerRegex:
+		for _, m := range r.pattern.FindAllString(content, -1) {
+			if m != "" && !r.allow[strings.ToLower(m)] {
+				return m, true
+			}
+		}
+	case models.AutoModTriggerInviteLink:
+		for _, m := range r.pattern.FindAllStringSubmatch(content, -1) {
+			if !r.allow[m[1]] {
+				return m[0], true
+			}
+		}
+	case models.AutoModTriggerMentionSpam:
+		parsed := parseMentions(content)
+		if len(parsed.Users)+len(parsed.Roles) > r.TriggerMetadata.MentionLimit {
+			return "", true
+		}
+	}
+	return "", false
+}
+
+// memoryCounter counts in fixed windows for a single instance
+type memoryCounter struct {
+	mu      sync.Mutex
+	windows map[string]memoryWindow
+}
+
+type memoryWindow struct {
+	count   int64
+	expires time.Time
+}
+
+func newMemoryCounter() *memoryCounter {
+	return &memoryCounter{windows: make(map[string]memoryWindow)}
+}
+
+func (c *memoryCounter) IncrementWithExpiry(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w, ok := c.windows[key]
+	if !ok || !now.Before(w.expires) {
+		if len(c.windows) >= autoModCacheSize {
+			for k, w := range c.windows {
+				if !now.Before(w.expires) {
+					delete(c.windows, k)
+				}
+			}
+		}
+		w = memoryWindow{expires: now.Add(ttl)}
+	}
+	w.count++
+	c.windows[key] = w
+	return w.count, nil
+}
+
+// Get returns the key's count as a decimal string, like a Redis counter,
+// or nil if the key has no live window
+func (c *memoryCounter) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w, ok := c.windows[key]
+	if !ok || !time.Now().Before(w.expires) {
+		return nil, nil
+	}
+	return strconv.AppendInt(nil, w.count, 10), nil
+}
@@ -0,0 +1,427 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeAutoModRuleRepository struct {
+	rules map[uuid.UUID]*models.AutoModRule
+	loads int
+}
+
+func (f *fakeAutoModRuleRepository) Create(ctx context.Context, rule *models.AutoModRule) error {
+	copied := *rule
+	f.rules[rule.ID] = &copied
+	return nil
+}
+
+func (f *fakeAutoModRuleRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.AutoModRule, error) {
+	rule, ok := f.rules[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *rule
+	return &copied, nil
+}
+
+func (f *fakeAutoModRuleRepository) GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*models.AutoModRule, error) {
+	f.loads++
+	var rules []*models.AutoModRule
+	for _, rule := range f.rules {
+		if rule.ServerID == serverID {
+			copied := *rule
+			rules = append(rules, &copied)
+		}
+	}
+	return rules, nil
+}
+
+func (f *fakeAutoModRuleRepository) Update(ctx context.Context, rule *models.AutoModRule) error {
+	copied := *rule
+	f.rules[rule.ID] = &copied
+	return nil
+}
+
+func (f *fakeAutoModRuleRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	delete(f.rules, id)
+	return nil
+}
+
+type autoModTest struct {
+	service     *AutoModService
+	repo        *fakeAutoModRuleRepository
+	serverRepo  *MockServerRepository
+	channelRepo *MockChannelRepository
+	messages    *MockMessageRepository
+	eventBus    *MockEventBus
+	timeouts    *fakeModerationExpiryRepository
+
+	serverID  uuid.UUID
+	ownerID   uuid.UUID
+	channelID uuid.UUID
+}
+
+func newAutoModTest() *autoModTest {
+	a := &autoModTest{
+		repo:        &fakeAutoModRuleRepository{rules: make(map[uuid.UUID]*models.AutoModRule)},
+		serverRepo:  new(MockServerRepository),
+		channelRepo: new(MockChannelRepository),
+		messages:    new(MockMessageRepository),
+		eventBus:    new(MockEventBus),
+		timeouts:    &fakeModerationExpiryRepository{},
+		serverID:    uuid.New(),
+		ownerID:     uuid.New(),
+		channelID:   uuid.New(),
+	}
+	// Without a role repository only the owner manages or bypasses rules
+	a.service = NewAutoModService(a.repo, a.serverRepo, a.channelRepo, nil, a.messages, a.eventBus)
+	a.service.SetTimeoutRepository(a.timeouts)
+	a.serverRepo.On("GetByID", mock.Anything, a.serverID).Return(&models.Server{ID: a.serverID, OwnerID: a.ownerID}, nil)
+	return a
+}
+
+func (a *autoModTest) createRule(t *testing.T, req *models.CreateAutoModRuleRequest) *models.AutoModRule {
+	t.Helper()
+	if req.Name == "" {
+		req.Name = "rule"
+	}
+	if req.Actions == nil {
+		req.Actions = []models.AutoModAction{{Type: models.AutoModActionBlock}}
+	}
+	rule, err := a.service.CreateRule(context.Background(), a.serverID, a.ownerID, req)
+	require.NoError(t, err)
+	return rule
+}
+
+func (a *autoModTest) message(authorID uuid.UUID, content string) *models.Message {
+	return &models.Message{
+		ID:        uuid.New(),
+		ChannelID: a.channelID,
+		ServerID:  &a.serverID,
+		AuthorID:  authorID,
+		Content:   content,
+	}
+}
+
+func TestCreateAutoModRule(t *testing.T) {
+	a := newAutoModTest()
+
+	rule := a.createRule(t, &models.CreateAutoModRuleRequest{
+		Name:            "  No slurs  ",
+		TriggerType:     models.AutoModTriggerKeyword,
+		TriggerMetadata: models.AutoModTriggerMetadata{Keywords: []string{" badword ", "spam*"}},
+	})
+
+	assert.Equal(t, "No slurs", rule.Name)
+	assert.Equal(t, a.ownerID, rule.CreatorID)
+	assert.True(t, rule.Enabled)
+	assert.Equal(t, []string{"badword", "spam*"}, rule.TriggerMetadata.Keywords)
+	assert.NotNil(t, rule.ExemptRoles)
+	assert.NotNil(t, rule.ExemptChannels)
+	assert.Contains(t, a.repo.rules, rule.ID)
+}
+
+func TestCreateAutoModRule_Invalid(t *testing.T) {
+	a := newAutoModTest()
+	otherServer := uuid.New()
+	otherChannel := uuid.New()
+	a.channelRepo.On("GetByID", mock.Anything, otherChannel).Return(&models.Channel{ID: otherChannel, ServerID: &otherServer}, nil)
+
+	block := []models.AutoModAction{{Type: models.AutoModActionBlock}}
+	keywords := models.AutoModTriggerMetadata{Keywords: []string{"bad"}}
+	tests := map[string]models.CreateAutoModRuleRequest{
+		"no name":            {TriggerType: models.AutoModTriggerKeyword, TriggerMetadata: keywords, Actions: block},
+		"unknown trigger":    {Name: "r", TriggerType: "vibes", Actions: block},
+		"no keywords":        {Name: "r", TriggerType: models.AutoModTriggerKeyword, Actions: block},
+		"wildcard only":      {Name: "r", TriggerType: models.AutoModTriggerKeyword, TriggerMetadata: models.AutoModTriggerMetadata{Keywords: []string{"**"}}, Actions: block},
+		"bad regex":          {Name: "r", TriggerType: models.AutoModTriggerRegex, TriggerMetadata: models.AutoModTriggerMetadata{Patterns: []string{"(unclosed"}}, Actions: block},
+		"mention limit":      {Name: "r", TriggerType: models.AutoModTriggerMentionSpam, Actions: block},
+		"rate interval":      {Name: "r", TriggerType: models.AutoModTriggerMessageRate, TriggerMetadata: models.AutoModTriggerMetadata{MessageLimit: 5, IntervalSeconds: 3600}, Actions: block},
+		"no actions":         {Name: "r", TriggerType: models.AutoModTriggerKeyword, TriggerMetadata: keywords},
+		"unknown action":     {Name: "r", TriggerType: models.AutoModTriggerKeyword, TriggerMetadata: keywords, Actions: []models.AutoModAction{{Type: "shame"}}},
+		"duplicate action":   {Name: "r", TriggerType: models.AutoModTriggerKeyword, TriggerMetadata: keywords, Actions: append(block, block...)},
+		"timeout duration":   {Name: "r", TriggerType: models.AutoModTriggerKeyword, TriggerMetadata: keywords, Actions: []models.AutoModAction{{Type: models.AutoModActionTimeout}}},
+		"alert no channel":   {Name: "r", TriggerType: models.AutoModTriggerKeyword, TriggerMetadata: keywords, Actions: []models.AutoModAction{{Type: models.AutoModActionAlert}}},
+		"alert other server": {Name: "r", TriggerType: models.AutoModTriggerKeyword, TriggerMetadata: keywords, Actions: []models.AutoModAction{{Type: models.AutoModActionAlert, ChannelID: &otherChannel}}},
+	}
+	for name, req := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := a.service.CreateRule(context.Background(), a.serverID, a.ownerID, &req)
+			assert.ErrorIs(t, err, ErrInvalidAutoModRule)
+		})
+	}
+	assert.Empty(t, a.repo.rules)
+}
+
+func TestCreateAutoModRule_RequiresManageServer(t *testing.T) {
+	a := newAutoModTest()
+
+	_, err := a.service.CreateRule(context.Background(), a.serverID, uuid.New(), &models.CreateAutoModRuleRequest{
+		Name:        "r",
+		TriggerType: models.AutoModTriggerInviteLink,
+		Actions:     []models.AutoModAction{{Type: models.AutoModActionBlock}},
+	})
+	assert.Equal(t, ErrCannotManageAutoMod, err)
+}
+
+func TestCreateAutoModRule_Limit(t *testing.T) {
+	a := newAutoModTest()
+	for i := 0; i < MaxAutoModRules; i++ {
+		a.createRule(t, &models.CreateAutoModRuleRequest{TriggerType: models.AutoModTriggerInviteLink})
+	}
+
+	_, err := a.service.CreateRule(context.Background(), a.serverID, a.ownerID, &models.CreateAutoModRuleRequest{
+		Name:        "one too many",
+		TriggerType: models.AutoModTriggerInviteLink,
+		Actions:     []models.AutoModAction{{Type: models.AutoModActionBlock}},
+	})
+	assert.Equal(t, ErrTooManyAutoModRules, err)
+}
+
+func TestUpdateAutoModRule_OtherServer(t *testing.T) {
+	a := newAutoModTest()
+	rule := a.createRule(t, &models.CreateAutoModRuleRequest{TriggerType: models.AutoModTriggerInviteLink})
+	rule.ServerID = uuid.New()
+	a.repo.rules[rule.ID] = rule
+
+	_, err := a.service.UpdateRule(context.Background(), a.serverID, rule.ID, a.ownerID, &models.UpdateAutoModRuleRequest{})
+	assert.Equal(t, ErrAutoModRuleNotFound, err)
+}
+
+func TestCompiledAutoModRule_Match(t *testing.T) {
+	userMentions := func(n int) string {
+		var b strings.Builder
+		for i := 0; i < n; i++ {
+			b.WriteString("<@" + uuid.NewString() + "> ")
+		}
+		return b.String()
+	}
+
+	tests := []struct {
+		name    string
+		trigger models.AutoModTriggerType
+		meta    models.AutoModTriggerMetadata
+		content string
+		matched string
+		ok      bool
+	}{
+		{"keyword whole word", models.AutoModTriggerKeyword, models.AutoModTriggerMetadata{Keywords: []string{"cat"}}, "my CAT sat", "CAT", true},
+		{"keyword inside word", models.AutoModTriggerKeyword, models.AutoModTriggerMetadata{Keywords: []string{"cat"}}, "concatenate", "", false},
+		{"keyword prefix", models.AutoModTriggerKeyword, models.AutoModTriggerMetadata{Keywords: []string{"cat*"}}, "catalog", "catalog", true},
+		{"keyword suffix", models.AutoModTriggerKeyword, models.AutoModTriggerMetadata{Keywords: []string{"*cat"}}, "bobcat", "bobcat", true},
+		{"keyword anywhere", models.AutoModTriggerKeyword, models.AutoModTriggerMetadata{Keywords: []string{"*cat*"}}, "concatenate", "concatenate", true},
+		{"keyword allowed", models.AutoModTriggerKeyword, models.AutoModTriggerMetadata{Keywords: []string{"*cat*"}, AllowList: []string{"Concatenate"}}, "concatenate", "", false},
+		{"keyword metacharacters", models.AutoModTriggerKeyword, models.AutoModTriggerMetadata{Keywords: []string{"a.b"}}, "axb", "", false},
+		{"regex", models.AutoModTriggerRegex, models.AutoModTriggerMetadata{Patterns: []string{`\d{3}-\d{4}`}}, "call 555-1234", "555-1234", true},
+		{"regex no match", models.AutoModTriggerRegex, models.AutoModTriggerMetadata{Patterns: []string{`^spam`}}, "not spam", "", false},
+		{"invite", models.AutoModTriggerInviteLink, models.AutoModTriggerMetadata{}, "join https://chat.example.com/invite/abc123 now", "https://chat.example.com/invite/abc123", true},
+		{"discord invite", models.AutoModTriggerInviteLink, models.AutoModTriggerMetadata{}, "discord.gg/xyz", "discord.gg/xyz", true},
+		{"invite allowed", models.AutoModTriggerInviteLink, models.AutoModTriggerMetadata{AllowList: []string{"abc123"}}, "chat.example.com/invite/abc123", "", false},
+		{"invite path", models.AutoModTriggerInviteLink, models.AutoModTriggerMetadata{}, "see docs/invite/setup", "", false},
+		{"mentions under limit", models.AutoModTriggerMentionSpam, models.AutoModTriggerMetadata{MentionLimit: 3}, userMentions(3), "", false},
+		{"mentions over limit", models.AutoModTriggerMentionSpam, models.AutoModTriggerMetadata{MentionLimit: 3}, userMentions(4), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := compileAutoModRule(&models.AutoModRule{TriggerType: tt.trigger, TriggerMetadata: tt.meta})
+			require.NoError(t, err)
+
+			matched, ok := rule.match(tt.content)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.matched, matched)
+		})
+	}
+}
+
+func TestModerate_Block(t *testing.T) {
+	a := newAutoModTest()
+	rule := a.createRule(t, &models.CreateAutoModRuleRequest{
+		TriggerType:     models.AutoModTriggerKeyword,
+		TriggerMetadata: models.AutoModTriggerMetadata{Keywords: []string{"badword"}},
+	})
+	authorID := uuid.New()
+	member := &models.Member{ServerID: a.serverID, UserID: authorID}
+
+	a.eventBus.On("Publish", "automod.action_executed", mock.MatchedBy(func(e *AutoModActionEvent) bool {
+		return e.RuleID == rule.ID && e.UserID == authorID && e.MatchedContent == "badword" &&
+			e.Action.Type == models.AutoModActionBlock && e.MessageID == nil
+	})).Return().Once()
+
+	verdict := a.service.Moderate(context.Background(), a.message(authorID, "what a badword"), member, false)
+	assert.True(t, verdict.Blocked)
+	assert.False(t, verdict.Dropped)
+
+	verdict = a.service.Moderate(context.Background(), a.message(authorID, "all good"), member, false)
+	assert.Equal(t, AutoModVerdict{}, verdict)
+	a.eventBus.AssertExpectations(t)
+}
+
+func TestModerate_Exempt(t *testing.T) {
+	a := newAutoModTest()
+	roleID := uuid.New()
+	otherChannel := uuid.New()
+	a.createRule(t, &models.CreateAutoModRuleRequest{
+		TriggerType:     models.AutoModTriggerKeyword,
+		TriggerMetadata: models.AutoModTriggerMetadata{Keywords: []string{"badword"}},
+		ExemptRoles:     []uuid.UUID{roleID},
+		ExemptChannels:  []uuid.UUID{otherChannel},
+	})
+	ctx := context.Background()
+
+	// The owner is above AutoMod
+	owner := &models.Member{ServerID: a.serverID, UserID: a.ownerID}
+	assert.False(t, a.service.Moderate(ctx, a.message(a.ownerID, "badword"), owner, false).Blocked)
+
+	userID := uuid.New()
+	trusted := &models.Member{ServerID: a.serverID, UserID: userID, Roles: []uuid.UUID{roleID}}
+	assert.False(t, a.service.Moderate(ctx, a.message(userID, "badword"), trusted, false).Blocked)
+
+	member := &models.Member{ServerID: a.serverID, UserID: userID}
+	message := a.message(userID, "badword")
+	message.ChannelID = otherChannel
+	assert.False(t, a.service.Moderate(ctx, message, member, false).Blocked)
+}
+
+func TestModerate_MessageRate(t *testing.T) {
+	a := newAutoModTest()
+	a.createRule(t, &models.CreateAutoModRuleRequest{
+		TriggerType:     models.AutoModTriggerMessageRate,
+		TriggerMetadata: models.AutoModTriggerMetadata{MessageLimit: 2, IntervalSeconds: 60},
+		Actions: []models.AutoModAction{
+			{Type: models.AutoModActionDelete},
+			{Type: models.AutoModActionTimeout, DurationSeconds: 300},
+		},
+	})
+	ctx := context.Background()
+	authorID := uuid.New()
+	member := &models.Member{ServerID: a.serverID, UserID: authorID}
+
+	assert.False(t, a.service.Moderate(ctx, a.message(authorID, "one"), member, false).Dropped)
+	assert.False(t, a.service.Moderate(ctx, a.message(authorID, "two"), member, false).Dropped)
+	// Edits don't count towards the rate
+	assert.Equal(t, AutoModVerdict{}, a.service.Moderate(ctx, a.message(authorID, "edit"), member, true))
+
+	a.eventBus.On("Publish", "automod.action_executed", mock.Anything).Return().Twice()
+	a.eventBus.On("Publish", "server.member_updated", mock.MatchedBy(func(e *MemberUpdatedEvent) bool {
+		return e.UserID == authorID && e.Member.TimedOut(time.Now())
+	})).Return().Once()
+
+	verdict := a.service.Moderate(ctx, a.message(authorID, "three"), member, false)
+	assert.True(t, verdict.Dropped)
+	assert.False(t, verdict.Blocked)
+	require.NotNil(t, a.timeouts.timeouts[authorID])
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), *a.timeouts.timeouts[authorID], time.Minute)
+	a.eventBus.AssertExpectations(t)
+}
+
+func TestModerate_Alert(t *testing.T) {
+	a := newAutoModTest()
+	alertChannel := uuid.New()
+	a.channelRepo.On("GetByID", mock.Anything, alertChannel).Return(&models.Channel{ID: alertChannel, ServerID: &a.serverID}, nil)
+	a.createRule(t, &models.CreateAutoModRuleRequest{
+		Name:        "No invites",
+		TriggerType: models.AutoModTriggerInviteLink,
+		Actions:     []models.AutoModAction{{Type: models.AutoModActionAlert, ChannelID: &alertChannel}},
+	})
+	authorID := uuid.New()
+	member := &models.Member{ServerID: a.serverID, UserID: authorID}
+	message := a.message(authorID, "join discord.gg/abc")
+
+	a.messages.On("Create", mock.Anything, mock.MatchedBy(func(m *models.Message) bool {
+		return m.ChannelID == alertChannel && m.Type == models.MessageTypeAutoModAction &&
+			strings.HasPrefix(m.Content, "AutoMod flagged a message from <@"+authorID.String()+">") &&
+			strings.Contains(m.Content, `rule: No invites, matched: "discord.gg/abc"`) &&
+			strings.HasSuffix(m.Content, "\n> join discord.gg/abc")
+	})).Return(nil).Once()
+	a.channelRepo.On("UpdateLastMessage", mock.Anything, alertChannel, mock.Anything, mock.Anything).Return(nil)
+	a.eventBus.On("Publish", "message.created", mock.MatchedBy(func(e *MessageCreatedEvent) bool {
+		return e.ChannelID == alertChannel
+	})).Return().Once()
+	a.eventBus.On("Publish", "automod.action_executed", mock.MatchedBy(func(e *AutoModActionEvent) bool {
+		// Alerts alone let the message through
+		return e.MessageID != nil && *e.MessageID == message.ID
+	})).Return().Once()
+
+	verdict := a.service.Moderate(context.Background(), message, member, false)
+	assert.Equal(t, AutoModVerdict{}, verdict)
+	a.messages.AssertExpectations(t)
+	a.eventBus.AssertExpectations(t)
+}
+
+func TestModerate_EditCannotBeDropped(t *testing.T) {
+	a := newAutoModTest()
+	a.createRule(t, &models.CreateAutoModRuleRequest{
+		TriggerType:     models.AutoModTriggerRegex,
+		TriggerMetadata: models.AutoModTriggerMetadata{Patterns: []string{"(?i)free nitro"}},
+		Actions:         []models.AutoModAction{{Type: models.AutoModActionDelete}},
+	})
+	authorID := uuid.New()
+	a.eventBus.On("Publish", "automod.action_executed", mock.Anything).Return()
+
+	verdict := a.service.Moderate(context.Background(), a.message(authorID, "FREE NITRO"), &models.Member{ServerID: a.serverID, UserID: authorID}, true)
+	assert.Equal(t, AutoModVerdict{Blocked: true}, verdict)
+}
+
+func TestModerate_RuleChangesApply(t *testing.T) {
+	a := newAutoModTest()
+	rule := a.createRule(t, &models.CreateAutoModRuleRequest{
+		TriggerType:     models.AutoModTriggerKeyword,
+		TriggerMetadata: models.AutoModTriggerMetadata{Keywords: []string{"badword"}},
+	})
+	ctx := context.Background()
+	authorID := uuid.New()
+	member := &models.Member{ServerID: a.serverID, UserID: authorID}
+	a.eventBus.On("Publish", "automod.action_executed", mock.Anything).Return()
+
+	assert.True(t, a.service.Moderate(ctx, a.message(authorID, "badword"), member, false).Blocked)
+	assert.True(t, a.service.Moderate(ctx, a.message(authorID, "badword"), member, false).Blocked)
+	assert.Equal(t, 2, a.repo.loads, "rules should be loaded once for creating and once for checking")
+
+	disabled := false
+	_, err := a.service.UpdateRule(ctx, a.serverID, rule.ID, a.ownerID, &models.UpdateAutoModRuleRequest{Enabled: &disabled})
+	require.NoError(t, err)
+	assert.False(t, a.service.Moderate(ctx, a.message(authorID, "badword"), member, false).Blocked)
+}
+
+func TestSendMessage_AutoMod(t *testing.T) {
+	service, msgRepo, channelRepo, serverRepo, _, rateLimiter, _, _, eventBus := setupMessageService()
+	a := newAutoModTest()
+	service.SetAutoModService(a.service)
+	a.createRule(t, &models.CreateAutoModRuleRequest{
+		TriggerType:     models.AutoModTriggerKeyword,
+		TriggerMetadata: models.AutoModTriggerMetadata{Keywords: []string{"badword"}},
+	})
+	a.createRule(t, &models.CreateAutoModRuleRequest{
+		TriggerType:     models.AutoModTriggerKeyword,
+		TriggerMetadata: models.AutoModTriggerMetadata{Keywords: []string{"spam"}},
+		Actions:         []models.AutoModAction{{Type: models.AutoModActionDelete}},
+	})
+	a.eventBus.On("Publish", "automod.action_executed", mock.Anything).Return()
+	ctx := context.Background()
+	authorID := uuid.New()
+
+	channelRepo.On("GetByID", ctx, a.channelID).Return(&models.Channel{ID: a.channelID, ServerID: &a.serverID, Type: models.ChannelTypeText}, nil)
+	serverRepo.On("GetMember", ctx, a.serverID, authorID).Return(&models.Member{ServerID: a.serverID, UserID: authorID}, nil)
+	rateLimiter.On("Check", ctx, authorID, a.channelID).Return(nil)
+
+	message, err := service.SendMessage(ctx, authorID, a.channelID, "a badword", nil, nil)
+	assert.Equal(t, ErrAutoModBlocked, err)
+	assert.Nil(t, message)
+
+	// Dropped messages come back to the author but are never stored
+	message, err = service.SendMessage(ctx, authorID, a.channelID, "buy spam", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "buy spam", message.Content)
+	msgRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	eventBus.AssertNotCalled(t, "Publish", "message.created", mock.Anything)
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// Ban import limits
+const (
+	MaxBanImportEntries = 10000
+	maxBanReasonLength  = 512
+
+	// banImportBatch is how many entries are applied at a time
+	banImportBatch = 100
+	// banImportLease is how long an instance holds an import it's working
+	// on before another may pick it up
+	banImportLease = time.Minute
+)
+
+// BanImportRepository queues ban list imports and applies them batch by
+// batch
+type BanImportRepository interface {
+	// CreateBanImport returns ErrBanImportInProgress if the server already
+	// has an unfinished import
+	CreateBanImport(ctx context.Context, job *models.BanImport, entries []models.BanListEntry) error
+	// GetBanImport returns nil if the server has no import with that ID
+	GetBanImport(ctx context.Context, serverID, id uuid.UUID) (*models.BanImport, error)
+	// ClaimBanImport holds the oldest unfinished import nobody else holds
+	// until until and returns it with its next limit entries, or nil if
+	// there's nothing to do
+	ClaimBanImport(ctx context.Context, now, until time.Time, limit int) (*models.BanImport, []models.BanListEntry, error)
+	// AddBans bans the users in entries who exist and aren't banned yet,
+	// removing them from the server, and returns the ones it banned
+	AddBans(ctx context.Context, serverID, bannedBy uuid.UUID, entries []models.BanListEntry, now time.Time) ([]uuid.UUID, error)
+	// SaveBanImportProgress stores the import's status and counters and
+	// releases it
+	SaveBanImportProgress(ctx context.Context, job *models.BanImport) error
+}
+
+// SetBanImportRepository enables ban list imports
+func (s *ServerService) SetBanImportRepository(repo BanImportRepository) {
+	s.banImports = repo
+}
+
+var errBanImportsNotConfigured = errors.New("ban imports are not configured")
+
+// ExportBans returns the server's ban list in the form ImportBans takes.
+// Timed bans that have run out are left out. Requires BAN_MEMBERS.
+func (s *ServerService) ExportBans(ctx context.Context, serverID, requesterID uuid.UUID) (*models.BanList, error) {
+	if _, err := s.banModerator(ctx, serverID, requesterID); err != nil {
+		return nil, err
+	}
+
+	bans, err := s.repo.GetBans(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	list := &models.BanList{ServerID: serverID, ExportedAt: now, Bans: []models.BanListEntry{}}
+	for _, ban := range bans {
+		if ban.Active(now) {
+			list.Bans = append(list.Bans, models.BanListEntry{UserID: ban.UserID, Reason: ban.Reason})
+		}
+	}
+	return list, nil
+}
+
+// ImportBans queues a ban list to be applied to the server in the
+// background and returns the queued import. Entries are applied with the
+// requester as the moderator, so users they don't outrank are skipped.
+// Requires BAN_MEMBERS.
+func (s *ServerService) ImportBans(ctx context.Context, serverID, requesterID uuid.UUID, req *models.BanImportRequest) (*models.BanImport, error) {
+	if _, err := s.banModerator(ctx, serverID, requesterID); err != nil {
+		return nil, err
+	}
+	entries, err := normalizeBanList(req.Bans, req.Reason, MaxBanImportEntries, invalidBanList)
+	if err != nil {
+		return nil, err
+	}
+	if s.banImports == nil {
+		return nil, errBanImportsNotConfigured
+	}
+
+	now := time.Now()
+	job := &models.BanImport{
+		ID:          uuid.New(),
+		ServerID:    serverID,
+		RequestedBy: requesterID,
+		Status:      models.BanImportQueued,
+		Total:       len(entries),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.banImports.CreateBanImport(ctx, job, entries); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// GetBanImport returns an import's progress. Requires BAN_MEMBERS.
+func (s *ServerService) GetBanImport(ctx context.Context, serverID, requesterID, importID uuid.UUID) (*models.BanImport, error) {
+	if _, err := s.banModerator(ctx, serverID, requesterID); err != nil {
+		return nil, err
+	}
+	if s.banImports == nil {
+		return nil, ErrBanImportNotFound
+	}
+
+	job, err := s.banImports.GetBanImport(ctx, serverID, importID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrBanImportNotFound
+	}
+	return job, nil
+}
+
+// ProcessBanImports applies the next batch of the oldest unfinished import.
+// It reports whether there was one to work on.
+func (s *ServerService) ProcessBanImports(ctx context.Context, now time.Time) (bool, error) {
+	if s.banImports == nil {
+		return false, nil
+	}
+	job, entries, err := s.banImports.ClaimBanImport(ctx, now, now.Add(banImportLease), banImportBatch)
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	server, err := s.repo.GetByID(ctx, job.ServerID)
+	if err != nil {
+		return true, err
+	}
+	if server == nil || !s.hasServerPermission(ctx, server, job.RequestedBy, models.PermBanMembers) {
+		reason := "the member who started the import can no longer ban members"
+		job.Status = models.BanImportFailed
+		job.Error = &reason
+		return true, s.finishBanImport(ctx, job, now)
+	}
+
+	// Bans go through as the importer would make them by hand
+	eligible := make([]models.BanListEntry, 0, len(entries))
+	for _, entry := range entries {
+		err := checkOutranks(ctx, s.repo, s.roleRepo, server, job.RequestedBy, entry.UserID)
+		if errors.Is(err, ErrMemberHierarchy) {
+			continue
+		}
+		if err != nil {
+			return true, err
+		}
+		eligible = append(eligible, entry)
+	}
+
+	banned, err := s.banImports.AddBans(ctx, server.ID, job.RequestedBy, eligible, now)
+	if err != nil {
+		return true, err
+	}
+	reasons := make(map[uuid.UUID]string, len(eligible))
+	for _, entry := range eligible {
+		if entry.Reason != nil {
+			reasons[entry.UserID] = *entry.Reason
+		}
+	}
+	for _, userID := range banned {
+		s.eventBus.Publish("server.member_banned", &MemberBannedEvent{
+			ServerID:    server.ID,
+			UserID:      userID,
+			ModeratorID: job.RequestedBy,
+			Reason:      reasons[userID],
+		})
+	}
+
+	job.Processed += len(entries)
+	job.Banned += len(banned)
+	job.Skipped += len(entries) - len(banned)
+	if len(entries) < banImportBatch || job.Processed >= job.Total {
+		job.Status = models.BanImportCompleted
+		return true, s.finishBanImport(ctx, job, now)
+	}
+	job.UpdatedAt = now
+	return true, s.banImports.SaveBanImportProgress(ctx, job)
+}
+
+// RunBanImports works through queued ban imports every interval until ctx
+// is done
+func (s *ServerService) RunBanImports(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				more, err := s.ProcessBanImports(ctx, time.Now())
+				if err != nil {
+					log.Printf("[ServerService] failed to apply ban import: %v", err)
+					break
+				}
+				if !more {
+					break
+				}
+			}
+		}
+	}
+}
+
+func (s *ServerService) finishBanImport(ctx context.Context, job *models.BanImport, now time.Time) error {
+	job.UpdatedAt = now
+	job.CompletedAt = &now
+	return s.banImports.SaveBanImportProgress(ctx, job)
+}
+
+// banModerator loads the server, checking the requester has BAN_MEMBERS
+func (s *ServerService) banModerator(ctx context.Context, serverID, requesterID uuid.UUID) (*models.Server, error) {
+	server, err := s.repo.GetByID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if server == nil {
+		return nil, ErrServerNotFound
+	}
+	if !s.hasServerPermission(ctx, server, requesterID, models.PermBanMembers) {
+		return nil, ErrCannotBan
+	}
+	return server, nil
+}
+
+// normalizeBanList checks a list of bans, trimming reasons, filling in
+// defaultReason and dropping repeated users. Problems are reported through
+// invalid.
+func normalizeBanList(bans []models.BanListEntry, defaultReason string, limit int, invalid func(format string, args ...interface{}) error) ([]models.BanListEntry, error) {
+	if len(bans) == 0 || len(bans) > limit {
+		return nil, invalid("lists must have 1-%d bans", limit)
+	}
+	defaultReason = strings.TrimSpace(defaultReason)
+	if utf8.RuneCountInString(defaultReason) > maxBanReasonLength {
+		return nil, invalid("reasons must be at most %d characters", maxBanReasonLength)
+	}
+
+	seen := make(map[uuid.UUID]bool, len(bans))
+	entries := make([]models.BanListEntry, 0, len(bans))
+	for _, entry := range bans {
+		if entry.UserID == uuid.Nil {
+			return nil, invalid("every ban needs a user_id")
+		}
+		if seen[entry.UserID] {
+			continue
+		}
+		seen[entry.UserID] = true
+
+		reason := defaultReason
+		if entry.Reason != nil {
+			reason = strings.TrimSpace(*entry.Reason)
+		}
+		if utf8.RuneCountInString(reason) > maxBanReasonLength {
+			return nil, invalid("reasons must be at most %d characters", maxBanReasonLength)
+		}
+		entry.Reason = nil
+		if reason != "" {
+			entry.Reason = &reason
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func invalidBanList(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidBanList, fmt.Sprintf(format, args...))
+}
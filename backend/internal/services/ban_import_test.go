@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeBanImportRepository struct {
+	jobs    map[uuid.UUID]*models.BanImport
+	entries map[uuid.UUID][]models.BanListEntry
+	// users exist on this instance; bans are the users banned so far
+	users map[uuid.UUID]bool
+	bans  map[uuid.UUID]*string
+}
+
+func newFakeBanImportRepository() *fakeBanImportRepository {
+	return &fakeBanImportRepository{
+		jobs:    make(map[uuid.UUID]*models.BanImport),
+		entries: make(map[uuid.UUID][]models.BanListEntry),
+		users:   make(map[uuid.UUID]bool),
+		bans:    make(map[uuid.UUID]*string),
+	}
+}
+
+func (f *fakeBanImportRepository) CreateBanImport(ctx context.Context, job *models.BanImport, entries []models.BanListEntry) error {
+	for _, other := range f.jobs {
+		if other.ServerID == job.ServerID && !other.Status.Finished() {
+			return ErrBanImportInProgress
+		}
+	}
+	copied := *job
+	f.jobs[job.ID] = &copied
+	f.entries[job.ID] = entries
+	return nil
+}
+
+func (f *fakeBanImportRepository) GetBanImport(ctx context.Context, serverID, id uuid.UUID) (*models.BanImport, error) {
+	job, ok := f.jobs[id]
+	if !ok || job.ServerID != serverID {
+		return nil, nil
+	}
+	copied := *job
+	return &copied, nil
+}
+
+func (f *fakeBanImportRepository) ClaimBanImport(ctx context.Context, now, until time.Time, limit int) (*models.BanImport, []models.BanListEntry, error) {
+	for id, job := range f.jobs {
+		if job.Status.Finished() {
+			continue
+		}
+		job.Status = models.BanImportRunning
+		entries := f.entries[id][job.Processed:]
+		if len(entries) > limit {
+			entries = entries[:limit]
+		}
+		copied := *job
+		return &copied, entries, nil
+	}
+	return nil, nil, nil
+}
+
+func (f *fakeBanImportRepository) AddBans(ctx context.Context, serverID, bannedBy uuid.UUID, entries []models.BanListEntry, now time.Time) ([]uuid.UUID, error) {
+	var banned []uuid.UUID
+	for _, entry := range entries {
+		if _, ok := f.bans[entry.UserID]; ok || !f.users[entry.UserID] {
+			continue
+		}
+		f.bans[entry.UserID] = entry.Reason
+		banned = append(banned, entry.UserID)
+	}
+	return banned, nil
+}
+
+func (f *fakeBanImportRepository) SaveBanImportProgress(ctx context.Context, job *models.BanImport) error {
+	f.jobs[job.ID] = job
+	return nil
+}
+
+type banImportTest struct {
+	service    *ServerService
+	serverRepo *MockServerRepository
+	roleRepo   *MockRoleRepository
+	eventBus   *MockEventBus
+	repo       *fakeBanImportRepository
+	server     *models.Server
+}
+
+func newBanImportTest() *banImportTest {
+	service, serverRepo, _, roleRepo, _, eventBus := newTestServerService()
+	f := &banImportTest{
+		service:    service,
+		serverRepo: serverRepo,
+		roleRepo:   roleRepo,
+		eventBus:   eventBus,
+		repo:       newFakeBanImportRepository(),
+		server:     &models.Server{ID: uuid.New(), OwnerID: uuid.New()},
+	}
+	service.SetBanImportRepository(f.repo)
+	serverRepo.On("GetByID", mock.Anything, f.server.ID).Return(f.server, nil)
+	return f
+}
+
+// users returns n users who exist and aren't in the server
+func (f *banImportTest) users(n int) []uuid.UUID {
+	ids := make([]uuid.UUID, n)
+	for i := range ids {
+		ids[i] = uuid.New()
+		f.repo.users[ids[i]] = true
+		f.serverRepo.On("GetMember", mock.Anything, f.server.ID, ids[i]).Return(nil, nil)
+	}
+	return ids
+}
+
+// drain applies every batch of queued imports
+func (f *banImportTest) drain(t *testing.T) {
+	for {
+		more, err := f.service.ProcessBanImports(context.Background(), time.Now())
+		require.NoError(t, err)
+		if !more {
+			return
+		}
+	}
+}
+
+func TestExportBans(t *testing.T) {
+	f := newBanImportTest()
+	reason := "spam"
+	expired := time.Now().Add(-time.Hour)
+	kept, lifted := uuid.New(), uuid.New()
+	f.serverRepo.On("GetBans", mock.Anything, f.server.ID).Return([]*models.Ban{
+		{ServerID: f.server.ID, UserID: kept, Reason: &reason},
+		{ServerID: f.server.ID, UserID: lifted, ExpiresAt: &expired},
+	}, nil)
+
+	list, err := f.service.ExportBans(context.Background(), f.server.ID, f.server.OwnerID)
+	require.NoError(t, err)
+	assert.Equal(t, []models.BanListEntry{{UserID: kept, Reason: &reason}}, list.Bans, "bans that ran out aren't exported")
+
+	outsider := uuid.New()
+	f.serverRepo.On("GetMember", mock.Anything, f.server.ID, outsider).Return(nil, nil)
+	_, err = f.service.ExportBans(context.Background(), f.server.ID, outsider)
+	assert.ErrorIs(t, err, ErrCannotBan)
+}
+
+func TestImportBans(t *testing.T) {
+	f := newBanImportTest()
+	ctx := context.Background()
+	users := f.users(banImportBatch + 20)
+	unknown := uuid.New()
+	own := " raided us "
+
+	req := &models.BanImportRequest{Reason: "shared blocklist", Bans: []models.BanListEntry{
+		{UserID: users[0], Reason: &own},
+		{UserID: users[0]},
+		{UserID: unknown},
+		{UserID: f.server.OwnerID},
+	}}
+	for _, userID := range users[1:] {
+		req.Bans = append(req.Bans, models.BanListEntry{UserID: userID})
+	}
+	f.serverRepo.On("GetMember", mock.Anything, f.server.ID, unknown).Return(nil, nil)
+	f.eventBus.On("Publish", "server.member_banned", mock.Anything).Return().Times(len(users))
+
+	job, err := f.service.ImportBans(ctx, f.server.ID, f.server.OwnerID, req)
+	require.NoError(t, err)
+	assert.Equal(t, models.BanImportQueued, job.Status)
+	assert.Equal(t, len(users)+2, job.Total, "repeated users are dropped")
+
+	_, err = f.service.ImportBans(ctx, f.server.ID, f.server.OwnerID, req)
+	assert.ErrorIs(t, err, ErrBanImportInProgress)
+
+	_, err = f.service.ProcessBanImports(ctx, time.Now())
+	require.NoError(t, err)
+	progress, err := f.service.GetBanImport(ctx, f.server.ID, f.server.OwnerID, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.BanImportRunning, progress.Status)
+	assert.Equal(t, banImportBatch, progress.Processed)
+
+	f.drain(t)
+	progress, err = f.service.GetBanImport(ctx, f.server.ID, f.server.OwnerID, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.BanImportCompleted, progress.Status)
+	assert.NotNil(t, progress.CompletedAt)
+	assert.Equal(t, len(users)+2, progress.Processed)
+	assert.Equal(t, len(users), progress.Banned)
+	assert.Equal(t, 2, progress.Skipped, "unknown users and the owner are skipped")
+	assert.Equal(t, "raided us", *f.repo.bans[users[0]])
+	assert.Equal(t, "shared blocklist", *f.repo.bans[users[1]])
+	f.eventBus.AssertExpectations(t)
+
+	_, err = f.service.GetBanImport(ctx, f.server.ID, f.server.OwnerID, uuid.New())
+	assert.ErrorIs(t, err, ErrBanImportNotFound)
+}
+
+func TestImportBans_Validation(t *testing.T) {
+	f := newBanImportTest()
+	long := strings.Repeat("x", maxBanReasonLength+1)
+
+	for name, req := range map[string]*models.BanImportRequest{
+		"empty":       {},
+		"too many":    {Bans: make([]models.BanListEntry, MaxBanImportEntries+1)},
+		"no user":     {Bans: []models.BanListEntry{{}}},
+		"long reason": {Bans: []models.BanListEntry{{UserID: uuid.New(), Reason: &long}}},
+	} {
+		_, err := f.service.ImportBans(context.Background(), f.server.ID, f.server.OwnerID, req)
+		assert.ErrorIs(t, err, ErrInvalidBanList, name)
+	}
+	assert.Empty(t, f.repo.jobs)
+}
+
+func TestProcessBanImports_Hierarchy(t *testing.T) {
+	f := newBanImportTest()
+	ctx := context.Background()
+	moderatorID, adminID := uuid.New(), uuid.New()
+	moderator := &models.Role{ID: uuid.New(), ServerID: f.server.ID, Position: 2, Permissions: models.PermBanMembers}
+	admin := &models.Role{ID: uuid.New(), ServerID: f.server.ID, Position: 3}
+	for userID, role := range map[uuid.UUID]*models.Role{moderatorID: moderator, adminID: admin} {
+		f.repo.users[userID] = true
+		f.serverRepo.On("GetMember", mock.Anything, f.server.ID, userID).Return(&models.Member{ServerID: f.server.ID, UserID: userID}, nil)
+		f.roleRepo.On("GetMemberRoles", mock.Anything, f.server.ID, userID).Return([]*models.Role{role}, nil)
+	}
+	f.roleRepo.On("GetByServerID", mock.Anything, f.server.ID).Return([]*models.Role{moderator, admin}, nil)
+	stranger := f.users(1)[0]
+	f.eventBus.On("Publish", "server.member_banned", &MemberBannedEvent{ServerID: f.server.ID, UserID: stranger, ModeratorID: moderatorID}).Return().Once()
+
+	job, err := f.service.ImportBans(ctx, f.server.ID, moderatorID, &models.BanImportRequest{
+		Bans: []models.BanListEntry{{UserID: adminID}, {UserID: stranger}},
+	})
+	require.NoError(t, err)
+	f.drain(t)
+
+	assert.Equal(t, 1, f.repo.jobs[job.ID].Banned)
+	assert.Equal(t, 1, f.repo.jobs[job.ID].Skipped, "members above the importer are skipped")
+	assert.NotContains(t, f.repo.bans, adminID)
+	f.eventBus.AssertExpectations(t)
+}
+
+func TestProcessBanImports_ImporterLostPermission(t *testing.T) {
+	f := newBanImportTest()
+	importerID := uuid.New()
+	jobID := uuid.New()
+	f.repo.jobs[jobID] = &models.BanImport{ID: jobID, ServerID: f.server.ID, RequestedBy: importerID, Status: models.BanImportQueued, Total: 1}
+	f.serverRepo.On("GetMember", mock.Anything, f.server.ID, importerID).Return(nil, nil)
+
+	f.drain(t)
+	job := f.repo.jobs[jobID]
+	assert.Equal(t, models.BanImportFailed, job.Status)
+	assert.NotNil(t, job.Error)
+	assert.Zero(t, job.Banned)
+}
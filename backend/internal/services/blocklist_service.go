@@ -0,0 +1,577 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// Blocklist limits
+const (
+	minBlocklistName          = 2
+	maxBlocklistName          = 100
+	maxBlocklistDescription   = 300
+	MaxBlocklistEntries       = 1000 // Per request
+	maxBlocklistSubscriptions = 20   // Per server
+
+	// blocklistSyncBatch is how many stale subscriptions are synced at a
+	// time
+	blocklistSyncBatch = 50
+)
+
+// BlocklistRepository stores blocklists, the servers following them and
+// the users each server has exempted. The Postgres BlocklistRepository
+// implements it.
+type BlocklistRepository interface {
+	CreateBlocklist(ctx context.Context, list *models.Blocklist) error
+	// GetBlocklist returns nil if there's no such list
+	GetBlocklist(ctx context.Context, id uuid.UUID) (*models.Blocklist, error)
+	ListPublicBlocklists(ctx context.Context) ([]*models.Blocklist, error)
+	ListServerBlocklists(ctx context.Context, serverID uuid.UUID) ([]*models.Blocklist, error)
+	UpdateBlocklist(ctx context.Context, list *models.Blocklist) error
+	DeleteBlocklist(ctx context.Context, id uuid.UUID) error
+
+	GetEntries(ctx context.Context, blocklistID uuid.UUID) ([]*models.BlocklistEntry, error)
+	// AddEntries adds users to the list, updating the reason of any
+	// already on it, and bumps the list's UpdatedAt
+	AddEntries(ctx context.Context, blocklistID, addedBy uuid.UUID, entries []models.BanListEntry, now time.Time) error
+	// RemoveEntry reports false if the user wasn't on the list
+	RemoveEntry(ctx context.Context, blocklistID, userID uuid.UUID) (bool, error)
+
+	// GetSubscriptions returns the server's subscriptions with their lists
+	GetSubscriptions(ctx context.Context, serverID uuid.UUID) ([]*models.BlocklistSubscription, error)
+	// Subscribe does nothing if the server already follows the list
+	Subscribe(ctx context.Context, subscription *models.BlocklistSubscription) error
+	// Unsubscribe reports false if the server didn't follow the list
+	Unsubscribe(ctx context.Context, serverID, blocklistID uuid.UUID) (bool, error)
+
+	GetExemptions(ctx context.Context, serverID, blocklistID uuid.UUID) ([]*models.BlocklistExemption, error)
+	// AddExemption does nothing if the user is already exempt
+	AddExemption(ctx context.Context, exemption *models.BlocklistExemption) error
+	// RemoveExemption reports false if the user wasn't exempt
+	RemoveExemption(ctx context.Context, serverID, blocklistID, userID uuid.UUID) (bool, error)
+
+	// FindMatch returns the oldest list the server follows that the user
+	// is on and not exempt from, or nil if there isn't one
+	FindMatch(ctx context.Context, serverID, userID uuid.UUID) (*models.BlocklistMatch, error)
+	// MatchMembers returns the server's members who are on the list and
+	// not exempt from it
+	MatchMembers(ctx context.Context, serverID, blocklistID uuid.UUID) ([]*models.BlocklistMatch, error)
+	// StaleSubscriptions returns up to limit subscriptions, with their
+	// lists, whose list changed since they were last synced
+	StaleSubscriptions(ctx context.Context, limit int) ([]*models.BlocklistSubscription, error)
+	// MarkSynced records the list's UpdatedAt a subscription was synced
+	// against
+	MarkSynced(ctx context.Context, serverID, blocklistID uuid.UUID, version time.Time) error
+	// BanMember bans the user and removes them from the server. It reports
+	// false if they were already banned.
+	BanMember(ctx context.Context, ban *models.Ban) (bool, error)
+}
+
+// Blocklists lets the ServerService turn away blocklisted users as they
+// join
+type Blocklists interface {
+	// BanIfBlocklisted bans the user if they're on a list the server
+	// follows, reporting whether they're banned
+	BanIfBlocklisted(ctx context.Context, server *models.Server, userID uuid.UUID) (bool, error)
+}
+
+// SetBlocklists bans users on the blocklists a server follows as they
+// join
+func (s *ServerService) SetBlocklists(blocklists Blocklists) {
+	s.blocklists = blocklists
+}
+
+// BlocklistService handles shared blocklists. A server's moderators, or
+// instance staff, maintain a list of known bad actors; servers that
+// subscribe to it ban the users on it as they join, and members already
+// in the server when they're added. Servers can exempt users from a list
+// they follow.
+type BlocklistService struct {
+	repo       BlocklistRepository
+	serverRepo ServerRepository
+	roleRepo   RoleRepository
+	userRepo   UserRepository
+	eventBus   EventBus
+}
+
+// NewBlocklistService creates a new blocklist service
+func NewBlocklistService(
+	repo BlocklistRepository,
+	serverRepo ServerRepository,
+	roleRepo RoleRepository,
+	userRepo UserRepository,
+	eventBus EventBus,
+) *BlocklistService {
+	return &BlocklistService{
+		repo:       repo,
+		serverRepo: serverRepo,
+		roleRepo:   roleRepo,
+		userRepo:   userRepo,
+		eventBus:   eventBus,
+	}
+}
+
+// ListBlocklists returns the public blocklists, or with a server ID every
+// list that server maintains, which needs BAN_MEMBERS there
+func (s *BlocklistService) ListBlocklists(ctx context.Context, requesterID uuid.UUID, serverID *uuid.UUID) ([]*models.Blocklist, error) {
+	if serverID == nil {
+		return s.repo.ListPublicBlocklists(ctx)
+	}
+	if _, err := s.moderator(ctx, *serverID, requesterID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListServerBlocklists(ctx, *serverID)
+}
+
+// CreateBlocklist creates a list maintained by the given server, which
+// needs BAN_MEMBERS there, or by the instance, which needs staff. Instance
+// lists are always public.
+func (s *BlocklistService) CreateBlocklist(ctx context.Context, requesterID uuid.UUID, req *models.CreateBlocklistRequest) (*models.Blocklist, error) {
+	now := time.Now()
+	list := &models.Blocklist{
+		ID:          uuid.New(),
+		ServerID:    req.ServerID,
+		Name:        req.Name,
+		Description: req.Description,
+		Public:      req.Public || req.ServerID == nil,
+		CreatedBy:   requesterID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.canMaintain(ctx, list, requesterID); err != nil {
+		return nil, err
+	}
+	if err := normalizeBlocklist(list); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateBlocklist(ctx, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetBlocklist returns a public list, or a private one to its maintainers
+func (s *BlocklistService) GetBlocklist(ctx context.Context, id, requesterID uuid.UUID) (*models.Blocklist, error) {
+	return s.visible(ctx, id, requesterID)
+}
+
+// UpdateBlocklist changes a list's details. Only its maintainers can.
+func (s *BlocklistService) UpdateBlocklist(ctx context.Context, id, requesterID uuid.UUID, req *models.UpdateBlocklistRequest) (*models.Blocklist, error) {
+	list, err := s.maintained(ctx, id, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name != nil {
+		list.Name = *req.Name
+	}
+	if req.Description != nil {
+		list.Description = req.Description
+	}
+	if req.Public != nil && list.ServerID != nil {
+		list.Public = *req.Public
+	}
+	if err := normalizeBlocklist(list); err != nil {
+		return nil, err
+	}
+
+	list.UpdatedAt = time.Now()
+	if err := s.repo.UpdateBlocklist(ctx, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// DeleteBlocklist deletes a list and every subscription to it. Bans it
+// already made are kept.
+func (s *BlocklistService) DeleteBlocklist(ctx context.Context, id, requesterID uuid.UUID) error {
+	if _, err := s.maintained(ctx, id, requesterID); err != nil {
+		return err
+	}
+	return s.repo.DeleteBlocklist(ctx, id)
+}
+
+// GetEntries returns the users on a list to anyone who can see it
+func (s *BlocklistService) GetEntries(ctx context.Context, id, requesterID uuid.UUID) ([]*models.BlocklistEntry, error) {
+	if _, err := s.visible(ctx, id, requesterID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetEntries(ctx, id)
+}
+
+// AddEntries adds users to a list, or changes their reason if they're on
+// it already. Servers following the list ban them at their next sync.
+func (s *BlocklistService) AddEntries(ctx context.Context, id, requesterID uuid.UUID, req *models.AddBlocklistEntriesRequest) error {
+	if _, err := s.maintained(ctx, id, requesterID); err != nil {
+		return err
+	}
+	entries, err := normalizeBanList(req.Entries, "", MaxBlocklistEntries, invalidBlocklist)
+	if err != nil {
+		return err
+	}
+	return s.repo.AddEntries(ctx, id, requesterID, entries, time.Now())
+}
+
+// RemoveEntry takes a user off a list. Bans it already made are kept.
+func (s *BlocklistService) RemoveEntry(ctx context.Context, id, requesterID, userID uuid.UUID) error {
+	if _, err := s.maintained(ctx, id, requesterID); err != nil {
+		return err
+	}
+	removed, err := s.repo.RemoveEntry(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrBlocklistNotFound
+	}
+	return nil
+}
+
+// GetSubscriptions returns the lists a server follows. Requires
+// BAN_MEMBERS.
+func (s *BlocklistService) GetSubscriptions(ctx context.Context, serverID, requesterID uuid.UUID) ([]*models.BlocklistSubscription, error) {
+	if _, err := s.moderator(ctx, serverID, requesterID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetSubscriptions(ctx, serverID)
+}
+
+// Subscribe has a server follow a public list, or a private one it
+// maintains. Members already on the list are banned at the next sync, as
+// the requester; they're the moderator every ban the subscription makes
+// is credited to. Requires BAN_MEMBERS.
+func (s *BlocklistService) Subscribe(ctx context.Context, serverID, blocklistID, requesterID uuid.UUID) (*models.BlocklistSubscription, error) {
+	if _, err := s.moderator(ctx, serverID, requesterID); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.GetBlocklist(ctx, blocklistID)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil || !list.Public && (list.ServerID == nil || *list.ServerID != serverID) {
+		return nil, ErrBlocklistNotFound
+	}
+
+	subscriptions, err := s.repo.GetSubscriptions(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	for _, subscription := range subscriptions {
+		if subscription.BlocklistID == blocklistID {
+			return subscription, nil
+		}
+	}
+	if len(subscriptions) >= maxBlocklistSubscriptions {
+		return nil, ErrTooManyBlocklistSubscriptions
+	}
+
+	subscription := &models.BlocklistSubscription{
+		ServerID:     serverID,
+		BlocklistID:  blocklistID,
+		SubscribedBy: requesterID,
+		CreatedAt:    time.Now(),
+		Blocklist:    list,
+	}
+	if err := s.repo.Subscribe(ctx, subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// Unsubscribe stops a server following a list, dropping its exemptions.
+// Bans the list already made are kept. Requires BAN_MEMBERS.
+func (s *BlocklistService) Unsubscribe(ctx context.Context, serverID, blocklistID, requesterID uuid.UUID) error {
+	if _, err := s.moderator(ctx, serverID, requesterID); err != nil {
+		return err
+	}
+	removed, err := s.repo.Unsubscribe(ctx, serverID, blocklistID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrNotSubscribedToBlocklist
+	}
+	return nil
+}
+
+// GetExemptions returns the users a server has exempted from a list it
+// follows. Requires BAN_MEMBERS.
+func (s *BlocklistService) GetExemptions(ctx context.Context, serverID, blocklistID, requesterID uuid.UUID) ([]*models.BlocklistExemption, error) {
+	if err := s.subscribed(ctx, serverID, blocklistID, requesterID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetExemptions(ctx, serverID, blocklistID)
+}
+
+// Exempt stops a list the server follows banning a user. It doesn't lift
+// a ban the list has already made. Requires BAN_MEMBERS.
+func (s *BlocklistService) Exempt(ctx context.Context, serverID, blocklistID, requesterID, userID uuid.UUID) (*models.BlocklistExemption, error) {
+	if err := s.subscribed(ctx, serverID, blocklistID, requesterID); err != nil {
+		return nil, err
+	}
+	exemption := &models.BlocklistExemption{
+		ServerID:    serverID,
+		BlocklistID: blocklistID,
+		UserID:      userID,
+		CreatedBy:   requesterID,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.repo.AddExemption(ctx, exemption); err != nil {
+		return nil, err
+	}
+	return exemption, nil
+}
+
+// Unexempt lets a list the server follows ban a user again, from its
+// next change on. Requires BAN_MEMBERS.
+func (s *BlocklistService) Unexempt(ctx context.Context, serverID, blocklistID, requesterID, userID uuid.UUID) error {
+	if err := s.subscribed(ctx, serverID, blocklistID, requesterID); err != nil {
+		return err
+	}
+	removed, err := s.repo.RemoveExemption(ctx, serverID, blocklistID, userID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrBlocklistNotFound
+	}
+	return nil
+}
+
+// BanIfBlocklisted bans a user joining the server if they're on a list it
+// follows and haven't been exempted
+func (s *BlocklistService) BanIfBlocklisted(ctx context.Context, server *models.Server, userID uuid.UUID) (bool, error) {
+	match, err := s.repo.FindMatch(ctx, server.ID, userID)
+	if err != nil || match == nil {
+		return false, err
+	}
+	if _, err := s.ban(ctx, server.ID, match); err != nil {
+		return false, err
+	}
+	// Even if they were banned already, they're banned now
+	return true, nil
+}
+
+// SyncBlocklists bans the members of servers following a list that
+// changed since it was last synced. Members the subscribing moderator
+// doesn't outrank are left alone, as they would be by hand. It returns how
+// many subscriptions it synced.
+func (s *BlocklistService) SyncBlocklists(ctx context.Context) (int, error) {
+	subscriptions, err := s.repo.StaleSubscriptions(ctx, blocklistSyncBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, subscription := range subscriptions {
+		if err := s.sync(ctx, subscription); err != nil {
+			return i, err
+		}
+	}
+	return len(subscriptions), nil
+}
+
+// RunBlocklistSync syncs blocklist subscriptions every interval until ctx
+// is done
+func (s *BlocklistService) RunBlocklistSync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				synced, err := s.SyncBlocklists(ctx)
+				if err != nil {
+					log.Printf("[BlocklistService] failed to sync blocklists: %v", err)
+					break
+				}
+				if synced < blocklistSyncBatch {
+					break
+				}
+			}
+		}
+	}
+}
+
+func (s *BlocklistService) sync(ctx context.Context, subscription *models.BlocklistSubscription) error {
+	if subscription.Blocklist == nil {
+		return nil
+	}
+	server, err := s.serverRepo.GetByID(ctx, subscription.ServerID)
+	if err != nil {
+		return err
+	}
+
+	// A subscriber who can no longer ban members bans nobody, and this
+	// version of the list is passed over
+	if server != nil && memberHasPermission(ctx, s.serverRepo, s.roleRepo, server, subscription.SubscribedBy, models.PermBanMembers) {
+		matches, err := s.repo.MatchMembers(ctx, server.ID, subscription.BlocklistID)
+		if err != nil {
+			return err
+		}
+		for _, match := range matches {
+			err := checkOutranks(ctx, s.serverRepo, s.roleRepo, server, match.SubscribedBy, match.UserID)
+			if errors.Is(err, ErrMemberHierarchy) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if _, err := s.ban(ctx, server.ID, match); err != nil {
+				return err
+			}
+		}
+	}
+	return s.repo.MarkSynced(ctx, subscription.ServerID, subscription.BlocklistID, subscription.Blocklist.UpdatedAt)
+}
+
+// ban bans a matched user, publishing the ban if it's new
+func (s *BlocklistService) ban(ctx context.Context, serverID uuid.UUID, match *models.BlocklistMatch) (bool, error) {
+	reason := "On blocklist " + match.BlocklistName
+	if match.Reason != nil {
+		reason += ": " + *match.Reason
+	}
+	moderatorID := match.SubscribedBy
+	banned, err := s.repo.BanMember(ctx, &models.Ban{
+		ServerID:  serverID,
+		UserID:    match.UserID,
+		Reason:    &reason,
+		BannedBy:  &moderatorID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil || !banned {
+		return false, err
+	}
+
+	s.eventBus.Publish("server.member_banned", &MemberBannedEvent{
+		ServerID:    serverID,
+		UserID:      match.UserID,
+		ModeratorID: moderatorID,
+		Reason:      reason,
+	})
+	return true, nil
+}
+
+// visible returns the list if it's public or the requester maintains it
+func (s *BlocklistService) visible(ctx context.Context, id, requesterID uuid.UUID) (*models.Blocklist, error) {
+	list, err := s.repo.GetBlocklist(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		return nil, ErrBlocklistNotFound
+	}
+	if !list.Public {
+		// Private lists are hidden from everyone else
+		if err := s.canMaintain(ctx, list, requesterID); errors.Is(err, ErrCannotManageBlocklist) {
+			return nil, ErrBlocklistNotFound
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// maintained returns the list if the requester maintains it
+func (s *BlocklistService) maintained(ctx context.Context, id, requesterID uuid.UUID) (*models.Blocklist, error) {
+	list, err := s.visible(ctx, id, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.canMaintain(ctx, list, requesterID); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// canMaintain checks the requester has BAN_MEMBERS in the server that
+// maintains the list, or is staff for instance lists
+func (s *BlocklistService) canMaintain(ctx context.Context, list *models.Blocklist, requesterID uuid.UUID) error {
+	if list.ServerID == nil {
+		user, err := s.userRepo.GetByID(ctx, requesterID)
+		if err != nil {
+			return err
+		}
+		if user == nil || user.Flags&models.UserFlagStaff == 0 {
+			return ErrCannotManageBlocklist
+		}
+		return nil
+	}
+
+	server, err := s.serverRepo.GetByID(ctx, *list.ServerID)
+	if err != nil {
+		return err
+	}
+	if server == nil || !memberHasPermission(ctx, s.serverRepo, s.roleRepo, server, requesterID, models.PermBanMembers) {
+		return ErrCannotManageBlocklist
+	}
+	return nil
+}
+
+// moderator returns the server if the requester has BAN_MEMBERS there
+func (s *BlocklistService) moderator(ctx context.Context, serverID, requesterID uuid.UUID) (*models.Server, error) {
+	server, err := s.serverRepo.GetByID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if server == nil {
+		return nil, ErrServerNotFound
+	}
+	if !memberHasPermission(ctx, s.serverRepo, s.roleRepo, server, requesterID, models.PermBanMembers) {
+		return nil, ErrCannotBan
+	}
+	return server, nil
+}
+
+// subscribed checks the requester has BAN_MEMBERS and the server follows
+// the list
+func (s *BlocklistService) subscribed(ctx context.Context, serverID, blocklistID, requesterID uuid.UUID) error {
+	if _, err := s.moderator(ctx, serverID, requesterID); err != nil {
+		return err
+	}
+	subscriptions, err := s.repo.GetSubscriptions(ctx, serverID)
+	if err != nil {
+		return err
+	}
+	for _, subscription := range subscriptions {
+		if subscription.BlocklistID == blocklistID {
+			return nil
+		}
+	}
+	return ErrNotSubscribedToBlocklist
+}
+
+// normalizeBlocklist trims the list's name and description and checks
+// them
+func normalizeBlocklist(list *models.Blocklist) error {
+	list.Name = strings.TrimSpace(list.Name)
+	if n := utf8.RuneCountInString(list.Name); n < minBlocklistName || n > maxBlocklistName {
+		return invalidBlocklist("names must be %d-%d characters", minBlocklistName, maxBlocklistName)
+	}
+	if list.Description != nil {
+		description := strings.TrimSpace(*list.Description)
+		list.Description = nil
+		if description != "" {
+			list.Description = &description
+		}
+		if utf8.RuneCountInString(description) > maxBlocklistDescription {
+			return invalidBlocklist("descriptions must be at most %d characters", maxBlocklistDescription)
+		}
+	}
+	return nil
+}
+
+func invalidBlocklist(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidBlocklist, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,458 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type blocklistKey struct {
+	serverID, blocklistID uuid.UUID
+}
+
+type fakeBlocklistRepository struct {
+	lists         map[uuid.UUID]*models.Blocklist
+	entries       map[uuid.UUID][]models.BanListEntry
+	subscriptions map[blocklistKey]*models.BlocklistSubscription
+	exemptions    map[blocklistKey][]uuid.UUID
+	// members are in the server; bans are the users banned so far
+	members map[uuid.UUID]bool
+	bans    map[uuid.UUID]*models.Ban
+}
+
+func newFakeBlocklistRepository() *fakeBlocklistRepository {
+	return &fakeBlocklistRepository{
+		lists:         make(map[uuid.UUID]*models.Blocklist),
+		entries:       make(map[uuid.UUID][]models.BanListEntry),
+		subscriptions: make(map[blocklistKey]*models.BlocklistSubscription),
+		exemptions:    make(map[blocklistKey][]uuid.UUID),
+		members:       make(map[uuid.UUID]bool),
+		bans:          make(map[uuid.UUID]*models.Ban),
+	}
+}
+
+func (f *fakeBlocklistRepository) CreateBlocklist(ctx context.Context, list *models.Blocklist) error {
+	copied := *list
+	f.lists[list.ID] = &copied
+	return nil
+}
+
+func (f *fakeBlocklistRepository) GetBlocklist(ctx context.Context, id uuid.UUID) (*models.Blocklist, error) {
+	list, ok := f.lists[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *list
+	copied.EntryCount = len(f.entries[id])
+	return &copied, nil
+}
+
+func (f *fakeBlocklistRepository) ListPublicBlocklists(ctx context.Context) ([]*models.Blocklist, error) {
+	lists := []*models.Blocklist{}
+	for _, list := range f.lists {
+		if list.Public {
+			lists = append(lists, list)
+		}
+	}
+	return lists, nil
+}
+
+func (f *fakeBlocklistRepository) ListServerBlocklists(ctx context.Context, serverID uuid.UUID) ([]*models.Blocklist, error) {
+	lists := []*models.Blocklist{}
+	for _, list := range f.lists {
+		if list.ServerID != nil && *list.ServerID == serverID {
+			lists = append(lists, list)
+		}
+	}
+	return lists, nil
+}
+
+func (f *fakeBlocklistRepository) UpdateBlocklist(ctx context.Context, list *models.Blocklist) error {
+	return f.CreateBlocklist(ctx, list)
+}
+
+func (f *fakeBlocklistRepository) DeleteBlocklist(ctx context.Context, id uuid.UUID) error {
+	delete(f.lists, id)
+	return nil
+}
+
+func (f *fakeBlocklistRepository) GetEntries(ctx context.Context, blocklistID uuid.UUID) ([]*models.BlocklistEntry, error) {
+	entries := []*models.BlocklistEntry{}
+	for _, entry := range f.entries[blocklistID] {
+		entries = append(entries, &models.BlocklistEntry{BlocklistID: blocklistID, UserID: entry.UserID, Reason: entry.Reason})
+	}
+	return entries, nil
+}
+
+func (f *fakeBlocklistRepository) AddEntries(ctx context.Context, blocklistID, addedBy uuid.UUID, entries []models.BanListEntry, now time.Time) error {
+	f.entries[blocklistID] = append(f.entries[blocklistID], entries...)
+	f.lists[blocklistID].UpdatedAt = now
+	return nil
+}
+
+func (f *fakeBlocklistRepository) RemoveEntry(ctx context.Context, blocklistID, userID uuid.UUID) (bool, error) {
+	for i, entry := range f.entries[blocklistID] {
+		if entry.UserID == userID {
+			f.entries[blocklistID] = append(f.entries[blocklistID][:i], f.entries[blocklistID][i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeBlocklistRepository) GetSubscriptions(ctx context.Context, serverID uuid.UUID) ([]*models.BlocklistSubscription, error) {
+	subscriptions := []*models.BlocklistSubscription{}
+	for key, subscription := range f.subscriptions {
+		if key.serverID == serverID {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (f *fakeBlocklistRepository) Subscribe(ctx context.Context, subscription *models.BlocklistSubscription) error {
+	key := blocklistKey{subscription.ServerID, subscription.BlocklistID}
+	if _, ok := f.subscriptions[key]; !ok {
+		f.subscriptions[key] = subscription
+	}
+	return nil
+}
+
+func (f *fakeBlocklistRepository) Unsubscribe(ctx context.Context, serverID, blocklistID uuid.UUID) (bool, error) {
+	key := blocklistKey{serverID, blocklistID}
+	_, ok := f.subscriptions[key]
+	delete(f.subscriptions, key)
+	delete(f.exemptions, key)
+	return ok, nil
+}
+
+func (f *fakeBlocklistRepository) GetExemptions(ctx context.Context, serverID, blocklistID uuid.UUID) ([]*models.BlocklistExemption, error) {
+	exemptions := []*models.BlocklistExemption{}
+	for _, userID := range f.exemptions[blocklistKey{serverID, blocklistID}] {
+		exemptions = append(exemptions, &models.BlocklistExemption{ServerID: serverID, BlocklistID: blocklistID, UserID: userID})
+	}
+	return exemptions, nil
+}
+
+func (f *fakeBlocklistRepository) AddExemption(ctx context.Context, exemption *models.BlocklistExemption) error {
+	key := blocklistKey{exemption.ServerID, exemption.BlocklistID}
+	f.exemptions[key] = append(f.exemptions[key], exemption.UserID)
+	return nil
+}
+
+func (f *fakeBlocklistRepository) RemoveExemption(ctx context.Context, serverID, blocklistID, userID uuid.UUID) (bool, error) {
+	key := blocklistKey{serverID, blocklistID}
+	f.exemptions[key] = dropIDs(f.exemptions[key], []uuid.UUID{userID})
+	return true, nil
+}
+
+func (f *fakeBlocklistRepository) matches(serverID, blocklistID uuid.UUID) []*models.BlocklistMatch {
+	key := blocklistKey{serverID, blocklistID}
+	subscription, ok := f.subscriptions[key]
+	if !ok {
+		return nil
+	}
+	var matches []*models.BlocklistMatch
+	for _, entry := range f.entries[blocklistID] {
+		if len(keepIDs(f.exemptions[key], []uuid.UUID{entry.UserID})) > 0 {
+			continue
+		}
+		matches = append(matches, &models.BlocklistMatch{
+			BlocklistID:   blocklistID,
+			BlocklistName: f.lists[blocklistID].Name,
+			UserID:        entry.UserID,
+			Reason:        entry.Reason,
+			SubscribedBy:  subscription.SubscribedBy,
+		})
+	}
+	return matches
+}
+
+func (f *fakeBlocklistRepository) FindMatch(ctx context.Context, serverID, userID uuid.UUID) (*models.BlocklistMatch, error) {
+	for key := range f.subscriptions {
+		if key.serverID != serverID {
+			continue
+		}
+		for _, match := range f.matches(serverID, key.blocklistID) {
+			if match.UserID == userID {
+				return match, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeBlocklistRepository) MatchMembers(ctx context.Context, serverID, blocklistID uuid.UUID) ([]*models.BlocklistMatch, error) {
+	var matches []*models.BlocklistMatch
+	for _, match := range f.matches(serverID, blocklistID) {
+		if f.members[match.UserID] {
+			matches = append(matches, match)
+		}
+	}
+	return matches, nil
+}
+
+func (f *fakeBlocklistRepository) StaleSubscriptions(ctx context.Context, limit int) ([]*models.BlocklistSubscription, error) {
+	var stale []*models.BlocklistSubscription
+	for _, subscription := range f.subscriptions {
+		list := f.lists[subscription.BlocklistID]
+		if subscription.SyncedAt == nil || subscription.SyncedAt.Before(list.UpdatedAt) {
+			copied := *subscription
+			copied.Blocklist = list
+			stale = append(stale, &copied)
+		}
+	}
+	if len(stale) > limit {
+		stale = stale[:limit]
+	}
+	return stale, nil
+}
+
+func (f *fakeBlocklistRepository) MarkSynced(ctx context.Context, serverID, blocklistID uuid.UUID, version time.Time) error {
+	f.subscriptions[blocklistKey{serverID, blocklistID}].SyncedAt = &version
+	return nil
+}
+
+func (f *fakeBlocklistRepository) BanMember(ctx context.Context, ban *models.Ban) (bool, error) {
+	if _, ok := f.bans[ban.UserID]; ok {
+		return false, nil
+	}
+	f.bans[ban.UserID] = ban
+	delete(f.members, ban.UserID)
+	return true, nil
+}
+
+type blocklistTest struct {
+	service    *BlocklistService
+	serverRepo *MockServerRepository
+	roleRepo   *MockRoleRepository
+	userRepo   *MockUserRepository
+	eventBus   *MockEventBus
+	repo       *fakeBlocklistRepository
+	server     *models.Server
+}
+
+func newBlocklistTest() *blocklistTest {
+	f := &blocklistTest{
+		serverRepo: new(MockServerRepository),
+		roleRepo:   new(MockRoleRepository),
+		userRepo:   new(MockUserRepository),
+		eventBus:   new(MockEventBus),
+		repo:       newFakeBlocklistRepository(),
+		server:     &models.Server{ID: uuid.New(), OwnerID: uuid.New()},
+	}
+	f.service = NewBlocklistService(f.repo, f.serverRepo, f.roleRepo, f.userRepo, f.eventBus)
+	f.serverRepo.On("GetByID", mock.Anything, f.server.ID).Return(f.server, nil)
+	return f
+}
+
+// outsider returns a user who isn't in the server
+func (f *blocklistTest) outsider() uuid.UUID {
+	userID := uuid.New()
+	f.serverRepo.On("GetMember", mock.Anything, f.server.ID, userID).Return(nil, nil)
+	return userID
+}
+
+// list adds a list maintained by another server
+func (f *blocklistTest) list(public bool, users ...uuid.UUID) *models.Blocklist {
+	otherID := uuid.New()
+	list := &models.Blocklist{ID: uuid.New(), ServerID: &otherID, Name: "Raiders", Public: public, UpdatedAt: time.Now()}
+	f.repo.lists[list.ID] = list
+	for _, userID := range users {
+		f.repo.entries[list.ID] = append(f.repo.entries[list.ID], models.BanListEntry{UserID: userID})
+	}
+	return list
+}
+
+func TestCreateBlocklist(t *testing.T) {
+	f := newBlocklistTest()
+	ctx := context.Background()
+	description := "  Known raid accounts  "
+
+	list, err := f.service.CreateBlocklist(ctx, f.server.OwnerID, &models.CreateBlocklistRequest{
+		ServerID:    &f.server.ID,
+		Name:        " Raiders ",
+		Description: &description,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Raiders", list.Name)
+	assert.Equal(t, "Known raid accounts", *list.Description)
+	assert.False(t, list.Public)
+	assert.Contains(t, f.repo.lists, list.ID)
+
+	_, err = f.service.CreateBlocklist(ctx, f.server.OwnerID, &models.CreateBlocklistRequest{ServerID: &f.server.ID, Name: "x"})
+	assert.ErrorIs(t, err, ErrInvalidBlocklist)
+
+	_, err = f.service.CreateBlocklist(ctx, f.outsider(), &models.CreateBlocklistRequest{ServerID: &f.server.ID, Name: "Raiders"})
+	assert.ErrorIs(t, err, ErrCannotManageBlocklist)
+}
+
+func TestCreateBlocklist_Instance(t *testing.T) {
+	f := newBlocklistTest()
+	ctx := context.Background()
+	staff := &models.User{ID: uuid.New(), Flags: models.UserFlagStaff}
+	user := &models.User{ID: uuid.New()}
+	f.userRepo.On("GetByID", mock.Anything, staff.ID).Return(staff, nil)
+	f.userRepo.On("GetByID", mock.Anything, user.ID).Return(user, nil)
+
+	list, err := f.service.CreateBlocklist(ctx, staff.ID, &models.CreateBlocklistRequest{Name: "Spam networks"})
+	require.NoError(t, err)
+	assert.Nil(t, list.ServerID)
+	assert.True(t, list.Public, "instance lists are always public")
+
+	_, err = f.service.CreateBlocklist(ctx, user.ID, &models.CreateBlocklistRequest{Name: "Spam networks"})
+	assert.ErrorIs(t, err, ErrCannotManageBlocklist)
+}
+
+func TestGetBlocklist_Private(t *testing.T) {
+	f := newBlocklistTest()
+	ctx := context.Background()
+	private := f.list(false)
+	f.serverRepo.On("GetByID", mock.Anything, *private.ServerID).Return(&models.Server{ID: *private.ServerID, OwnerID: uuid.New()}, nil)
+	stranger := uuid.New()
+	f.serverRepo.On("GetMember", mock.Anything, *private.ServerID, stranger).Return(nil, nil)
+
+	_, err := f.service.GetBlocklist(ctx, private.ID, stranger)
+	assert.ErrorIs(t, err, ErrBlocklistNotFound, "private lists are hidden from other servers")
+	_, err = f.service.Subscribe(ctx, f.server.ID, private.ID, f.server.OwnerID)
+	assert.ErrorIs(t, err, ErrBlocklistNotFound)
+}
+
+func TestSubscribe(t *testing.T) {
+	f := newBlocklistTest()
+	ctx := context.Background()
+	list := f.list(true)
+
+	subscription, err := f.service.Subscribe(ctx, f.server.ID, list.ID, f.server.OwnerID)
+	require.NoError(t, err)
+	assert.Equal(t, f.server.OwnerID, subscription.SubscribedBy)
+	again, err := f.service.Subscribe(ctx, f.server.ID, list.ID, f.server.OwnerID)
+	require.NoError(t, err)
+	assert.Equal(t, subscription, again, "subscribing twice does nothing")
+
+	for i := 1; i < maxBlocklistSubscriptions; i++ {
+		_, err := f.service.Subscribe(ctx, f.server.ID, f.list(true).ID, f.server.OwnerID)
+		require.NoError(t, err)
+	}
+	_, err = f.service.Subscribe(ctx, f.server.ID, f.list(true).ID, f.server.OwnerID)
+	assert.ErrorIs(t, err, ErrTooManyBlocklistSubscriptions)
+
+	_, err = f.service.Subscribe(ctx, f.server.ID, list.ID, f.outsider())
+	assert.ErrorIs(t, err, ErrCannotBan)
+
+	require.NoError(t, f.service.Unsubscribe(ctx, f.server.ID, list.ID, f.server.OwnerID))
+	assert.ErrorIs(t, f.service.Unsubscribe(ctx, f.server.ID, list.ID, f.server.OwnerID), ErrNotSubscribedToBlocklist)
+	_, err = f.service.Exempt(ctx, f.server.ID, list.ID, f.server.OwnerID, uuid.New())
+	assert.ErrorIs(t, err, ErrNotSubscribedToBlocklist)
+}
+
+func TestBanIfBlocklisted(t *testing.T) {
+	f := newBlocklistTest()
+	ctx := context.Background()
+	raider, exempt, stranger := uuid.New(), uuid.New(), uuid.New()
+	list := f.list(true, raider, exempt)
+	_, err := f.service.Subscribe(ctx, f.server.ID, list.ID, f.server.OwnerID)
+	require.NoError(t, err)
+	_, err = f.service.Exempt(ctx, f.server.ID, list.ID, f.server.OwnerID, exempt)
+	require.NoError(t, err)
+	f.eventBus.On("Publish", "server.member_banned", &MemberBannedEvent{
+		ServerID:    f.server.ID,
+		UserID:      raider,
+		ModeratorID: f.server.OwnerID,
+		Reason:      "On blocklist Raiders",
+	}).Return().Once()
+
+	banned, err := f.service.BanIfBlocklisted(ctx, f.server, raider)
+	require.NoError(t, err)
+	assert.True(t, banned)
+	assert.Equal(t, f.server.OwnerID, *f.repo.bans[raider].BannedBy, "bans are credited to whoever subscribed")
+
+	banned, err = f.service.BanIfBlocklisted(ctx, f.server, raider)
+	require.NoError(t, err)
+	assert.True(t, banned, "users banned already stay turned away")
+
+	for _, userID := range []uuid.UUID{exempt, stranger} {
+		banned, err := f.service.BanIfBlocklisted(ctx, f.server, userID)
+		require.NoError(t, err)
+		assert.False(t, banned)
+	}
+	f.eventBus.AssertExpectations(t)
+}
+
+func TestSyncBlocklists(t *testing.T) {
+	f := newBlocklistTest()
+	ctx := context.Background()
+	moderatorID, adminID, raider := uuid.New(), uuid.New(), uuid.New()
+	moderator := &models.Role{ID: uuid.New(), ServerID: f.server.ID, Position: 2, Permissions: models.PermBanMembers}
+	admin := &models.Role{ID: uuid.New(), ServerID: f.server.ID, Position: 3}
+	for userID, role := range map[uuid.UUID]*models.Role{moderatorID: moderator, adminID: admin} {
+		f.serverRepo.On("GetMember", mock.Anything, f.server.ID, userID).Return(&models.Member{ServerID: f.server.ID, UserID: userID}, nil)
+		f.roleRepo.On("GetMemberRoles", mock.Anything, f.server.ID, userID).Return([]*models.Role{role}, nil)
+	}
+	f.serverRepo.On("GetMember", mock.Anything, f.server.ID, raider).Return(&models.Member{ServerID: f.server.ID, UserID: raider}, nil)
+	f.roleRepo.On("GetMemberRoles", mock.Anything, f.server.ID, raider).Return([]*models.Role{}, nil)
+	f.roleRepo.On("GetByServerID", mock.Anything, f.server.ID).Return([]*models.Role{moderator, admin}, nil)
+	f.repo.members[raider] = true
+	f.repo.members[adminID] = true
+
+	list := f.list(true, raider, adminID)
+	_, err := f.service.Subscribe(ctx, f.server.ID, list.ID, moderatorID)
+	require.NoError(t, err)
+	f.eventBus.On("Publish", "server.member_banned", mock.MatchedBy(func(event *MemberBannedEvent) bool {
+		return event.UserID == raider && event.ModeratorID == moderatorID
+	})).Return().Once()
+
+	synced, err := f.service.SyncBlocklists(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, synced)
+	assert.Contains(t, f.repo.bans, raider)
+	assert.NotContains(t, f.repo.bans, adminID, "members above the subscriber are left alone")
+	f.eventBus.AssertExpectations(t)
+
+	synced, err = f.service.SyncBlocklists(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, synced, "lists are only synced again once they change")
+
+	newcomer := uuid.New()
+	f.repo.members[newcomer] = true
+	f.serverRepo.On("GetMember", mock.Anything, f.server.ID, newcomer).Return(&models.Member{ServerID: f.server.ID, UserID: newcomer}, nil)
+	f.roleRepo.On("GetMemberRoles", mock.Anything, f.server.ID, newcomer).Return([]*models.Role{}, nil)
+	f.eventBus.On("Publish", "server.member_banned", mock.Anything).Return().Once()
+	require.NoError(t, f.repo.AddEntries(ctx, list.ID, uuid.New(), []models.BanListEntry{{UserID: newcomer}}, time.Now().Add(time.Second)))
+
+	synced, err = f.service.SyncBlocklists(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, synced)
+	assert.Contains(t, f.repo.bans, newcomer)
+}
+
+func TestJoinServer_Blocklisted(t *testing.T) {
+	service, serverRepo, _, _, _, _ := newTestServerService()
+	ctx := context.Background()
+	userID := uuid.New()
+	server := &models.Server{ID: uuid.New(), OwnerID: uuid.New()}
+	invite := &models.Invite{Code: "abc123", ServerID: server.ID}
+
+	f := newBlocklistTest()
+	list := f.list(true, userID)
+	f.repo.subscriptions[blocklistKey{server.ID, list.ID}] = &models.BlocklistSubscription{ServerID: server.ID, BlocklistID: list.ID, SubscribedBy: server.OwnerID}
+	f.eventBus.On("Publish", "server.member_banned", mock.Anything).Return()
+	service.SetBlocklists(f.service)
+
+	serverRepo.On("GetInvite", ctx, invite.Code).Return(invite, nil)
+	serverRepo.On("GetByID", ctx, server.ID).Return(server, nil)
+	serverRepo.On("GetBan", ctx, server.ID, userID).Return(nil, nil)
+	serverRepo.On("GetMember", ctx, server.ID, userID).Return(nil, nil)
+
+	_, err := service.JoinServer(ctx, userID, invite.Code)
+	assert.ErrorIs(t, err, ErrBannedFromServer)
+	assert.Contains(t, f.repo.bans, userID)
+	serverRepo.AssertNotCalled(t, "AddMember", mock.Anything, mock.Anything)
+}
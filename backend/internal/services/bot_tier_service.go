@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	// botTierCacheTTL bounds how long another instance's tier changes take
+	// to apply here. Changes made on this instance apply straight away.
+	botTierCacheTTL = time.Minute
+	// botTierCacheSize caps the cached lookups; the cache starts over once
+	// it is full
+	botTierCacheSize = 10000
+)
+
+// BotTierRepository stores the tiers instance admins gave bot accounts.
+// The Postgres BotTierRepository implements it.
+type BotTierRepository interface {
+	// Get returns nil if the bot was never given a tier
+	Get(ctx context.Context, userID uuid.UUID) (*models.BotVerification, error)
+	Save(ctx context.Context, verification *models.BotVerification) error
+	// List returns the bots given a tier, or any tier if it's empty,
+	// most recently changed first
+	List(ctx context.Context, tier models.BotTier) ([]*models.BotVerification, error)
+}
+
+// BotRequestLimiter counts a bot's API requests against a per-minute and
+// a per-second limit. ratelimit.Limiter implements it.
+type BotRequestLimiter interface {
+	CheckBotRequest(ctx context.Context, userID uuid.UUID, perMinute, burst int) error
+}
+
+// BotTiers looks up what a bot's tier allows it. The BotTierService
+// implements it.
+type BotTiers interface {
+	// Policy returns nil for accounts that aren't bots
+	Policy(ctx context.Context, userID uuid.UUID) (*models.BotTierPolicy, error)
+}
+
+// SetBotTiers caps the servers bots can join by their tier
+func (s *ServerService) SetBotTiers(tiers BotTiers) {
+	s.botTiers = tiers
+}
+
+// BotTierService manages bot verification tiers and applies their rate
+// limits and server caps
+type BotTierService struct {
+	repo    BotTierRepository
+	users   UserRepository
+	api     models.APIQuotaConfig
+	limiter BotRequestLimiter
+	now     func() time.Time
+
+	mu    sync.Mutex
+	tiers map[uuid.UUID]cachedBotTier
+}
+
+// cachedBotTier is a looked up account. tier is nil for accounts that
+// aren't bots.
+type cachedBotTier struct {
+	tier    *models.BotTier
+	expires time.Time
+}
+
+// NewBotTierService creates a new bot tier service. api holds the bot
+// rate limits tiers multiply.
+func NewBotTierService(repo BotTierRepository, users UserRepository, api models.APIQuotaConfig) *BotTierService {
+	return &BotTierService{
+		repo:  repo,
+		users: users,
+		api:   api,
+		now:   time.Now,
+		tiers: make(map[uuid.UUID]cachedBotTier),
+	}
+}
+
+// SetRequestLimiter enables per-bot API rate limits
+func (s *BotTierService) SetRequestLimiter(limiter BotRequestLimiter) {
+	s.limiter = limiter
+}
+
+// ListBots returns the bots given a tier, or any tier if tier is empty.
+// Bots that were never given one are unverified and not listed.
+func (s *BotTierService) ListBots(ctx context.Context, tier models.BotTier) ([]*models.BotVerification, error) {
+	if tier != "" && !tier.Valid() {
+		return nil, ErrInvalidBotTier
+	}
+	verifications, err := s.repo.List(ctx, tier)
+	if err != nil {
+		return nil, err
+	}
+	for _, verification := range verifications {
+		verification.Policy = verification.Tier.Policy()
+	}
+	return verifications, nil
+}
+
+// GetBot returns a bot's tier
+func (s *BotTierService) GetBot(ctx context.Context, botID uuid.UUID) (*models.BotVerification, error) {
+	if err := s.checkBot(ctx, botID); err != nil {
+		return nil, err
+	}
+	verification, err := s.repo.Get(ctx, botID)
+	if err != nil {
+		return nil, err
+	}
+	if verification == nil {
+		verification = &models.BotVerification{UserID: botID, Tier: models.BotTierUnverified}
+	}
+	verification.Policy = verification.Tier.Policy()
+	return verification, nil
+}
+
+// SetTier moves a bot to another tier
+func (s *BotTierService) SetTier(ctx context.Context, adminID, botID uuid.UUID, req *models.SetBotTierRequest) (*models.BotVerification, error) {
+	if !req.Tier.Valid() {
+		return nil, ErrInvalidBotTier
+	}
+	if err := s.checkBot(ctx, botID); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	verification := &models.BotVerification{
+		UserID:    botID,
+		Tier:      req.Tier,
+		Policy:    req.Tier.Policy(),
+		UpdatedBy: &adminID,
+		UpdatedAt: &now,
+	}
+	if err := s.repo.Save(ctx, verification); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	delete(s.tiers, botID)
+	s.mu.Unlock()
+	return verification, nil
+}
+
+// Policy returns what a bot's tier allows it, or nil for accounts that
+// aren't bots
+func (s *BotTierService) Policy(ctx context.Context, userID uuid.UUID) (*models.BotTierPolicy, error) {
+	tier, err := s.tier(ctx, userID)
+	if err != nil || tier == nil {
+		return nil, err
+	}
+	policy := tier.Policy()
+	return &policy, nil
+}
+
+// CheckBotRequest counts an API request made with a bot token against the
+// instance's bot rate limits, multiplied by the bot's tier. It returns
+// ErrBotRateLimited once they are used up. Lookups that fail are logged
+// and the bot is limited as unverified.
+func (s *BotTierService) CheckBotRequest(ctx context.Context, userID uuid.UUID) error {
+	if s.limiter == nil {
+		return nil
+	}
+	tier := models.BotTierUnverified
+	if found, err := s.tier(ctx, userID); err != nil {
+		log.Printf("[BotTiers] failed to look up the tier of %s: %v", userID, err)
+	} else if found != nil {
+		tier = *found
+	}
+
+	multiplier := tier.Policy().RateLimitMultiplier
+	perMinute := s.api.BotRequestsPerMinute * multiplier
+	burst := s.api.BotBurstLimit * multiplier
+	if err := s.limiter.CheckBotRequest(ctx, userID, perMinute, burst); err != nil {
+		return ErrBotRateLimited
+	}
+	return nil
+}
+
+// checkBot returns ErrUserNotFound or ErrNotABot unless botID is a bot
+func (s *BotTierService) checkBot(ctx context.Context, botID uuid.UUID) error {
+	user, err := s.users.GetByID(ctx, botID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if !user.IsBot() {
+		return ErrNotABot
+	}
+	return nil
+}
+
+// tier returns a bot's tier, or nil if the account isn't a bot
+func (s *BotTierService) tier(ctx context.Context, userID uuid.UUID) (*models.BotTier, error) {
+	now := s.now()
+	s.mu.Lock()
+	cached, ok := s.tiers[userID]
+	s.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.tier, nil
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var tier *models.BotTier
+	if user != nil && user.IsBot() {
+		verification, err := s.repo.Get(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		found := models.BotTierUnverified
+		if verification != nil {
+			found = verification.Tier
+		}
+		tier = &found
+	}
+
+	s.mu.Lock()
+	if len(s.tiers) >= botTierCacheSize {
+		s.tiers = make(map[uuid.UUID]cachedBotTier)
+	}
+	s.tiers[userID] = cachedBotTier{tier: tier, expires: now.Add(botTierCacheTTL)}
+	s.mu.Unlock()
+	return tier, nil
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeBotTierRepo struct {
+	tiers map[uuid.UUID]*models.BotVerification
+	gets  int
+}
+
+func (r *fakeBotTierRepo) Get(ctx context.Context, userID uuid.UUID) (*models.BotVerification, error) {
+	r.gets++
+	if v, ok := r.tiers[userID]; ok {
+		copied := *v
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeBotTierRepo) Save(ctx context.Context, verification *models.BotVerification) error {
+	copied := *verification
+	r.tiers[verification.UserID] = &copied
+	return nil
+}
+
+func (r *fakeBotTierRepo) List(ctx context.Context, tier models.BotTier) ([]*models.BotVerification, error) {
+	var result []*models.BotVerification
+	for _, v := range r.tiers {
+		if tier == "" || v.Tier == tier {
+			copied := *v
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+type recordedBotLimits struct {
+	perMinute, burst int
+	err              error
+}
+
+func (l *recordedBotLimits) CheckBotRequest(ctx context.Context, userID uuid.UUID, perMinute, burst int) error {
+	l.perMinute, l.burst = perMinute, burst
+	return l.err
+}
+
+type botTierTest struct {
+	service *BotTierService
+	repo    *fakeBotTierRepo
+	users   *MockUserRepository
+	limits  *recordedBotLimits
+	botID   uuid.UUID
+	userID  uuid.UUID
+}
+
+func newBotTierTest() *botTierTest {
+	f := &botTierTest{
+		repo:   &fakeBotTierRepo{tiers: make(map[uuid.UUID]*models.BotVerification)},
+		users:  new(MockUserRepository),
+		limits: &recordedBotLimits{},
+		botID:  uuid.New(),
+		userID: uuid.New(),
+	}
+	f.users.On("GetByID", mock.Anything, f.botID).Return(&models.User{ID: f.botID, Flags: models.UserFlagBot}, nil)
+	f.users.On("GetByID", mock.Anything, f.userID).Return(&models.User{ID: f.userID}, nil)
+	f.users.On("GetByID", mock.Anything, mock.Anything).Return(nil, nil)
+
+	f.service = NewBotTierService(f.repo, f.users, models.APIQuotaConfig{BotRequestsPerMinute: 100, BotBurstLimit: 10})
+	f.service.SetRequestLimiter(f.limits)
+	return f
+}
+
+func TestBotTierService_SetTier(t *testing.T) {
+	f := newBotTierTest()
+	ctx := context.Background()
+	adminID := uuid.New()
+
+	bot, err := f.service.GetBot(ctx, f.botID)
+	require.NoError(t, err)
+	assert.Equal(t, models.BotTierUnverified, bot.Tier, "bots start out unverified")
+	assert.Equal(t, 100, bot.Policy.MaxServers)
+
+	bot, err = f.service.SetTier(ctx, adminID, f.botID, &models.SetBotTierRequest{Tier: models.BotTierPartner})
+	require.NoError(t, err)
+	assert.Equal(t, models.BotTierPartner, bot.Tier)
+	assert.Equal(t, adminID, *bot.UpdatedBy)
+	assert.Equal(t, 0, bot.Policy.MaxServers)
+
+	bots, err := f.service.ListBots(ctx, models.BotTierPartner)
+	require.NoError(t, err)
+	assert.Len(t, bots, 1)
+	assert.Equal(t, 5, bots[0].Policy.RateLimitMultiplier)
+
+	_, err = f.service.SetTier(ctx, adminID, f.botID, &models.SetBotTierRequest{Tier: "gold"})
+	assert.ErrorIs(t, err, ErrInvalidBotTier)
+	_, err = f.service.ListBots(ctx, "gold")
+	assert.ErrorIs(t, err, ErrInvalidBotTier)
+	_, err = f.service.SetTier(ctx, adminID, f.userID, &models.SetBotTierRequest{Tier: models.BotTierVerified})
+	assert.ErrorIs(t, err, ErrNotABot)
+	_, err = f.service.GetBot(ctx, uuid.New())
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestBotTierService_CheckBotRequest(t *testing.T) {
+	f := newBotTierTest()
+	ctx := context.Background()
+
+	require.NoError(t, f.service.CheckBotRequest(ctx, f.botID))
+	assert.Equal(t, 100, f.limits.perMinute)
+	assert.Equal(t, 10, f.limits.burst)
+
+	// Cached until the tier changes
+	gets := f.repo.gets
+	require.NoError(t, f.service.CheckBotRequest(ctx, f.botID))
+	assert.Equal(t, gets, f.repo.gets)
+
+	_, err := f.service.SetTier(ctx, uuid.New(), f.botID, &models.SetBotTierRequest{Tier: models.BotTierVerified})
+	require.NoError(t, err)
+	require.NoError(t, f.service.CheckBotRequest(ctx, f.botID))
+	assert.Equal(t, 200, f.limits.perMinute)
+	assert.Equal(t, 20, f.limits.burst)
+
+	f.limits.err = errors.New("over the limit")
+	assert.ErrorIs(t, f.service.CheckBotRequest(ctx, f.botID), ErrBotRateLimited)
+
+	policy, err := f.service.Policy(ctx, f.userID)
+	require.NoError(t, err)
+	assert.Nil(t, policy, "users aren't bots")
+}
+
+func TestJoinServer_BotTierCap(t *testing.T) {
+	service, serverRepo, _, _, _, _ := newTestServerService()
+	ctx := context.Background()
+	f := newBotTierTest()
+	service.SetBotTiers(f.service)
+	invite := &models.Invite{Code: "abc123", ServerID: uuid.New()}
+
+	joined := make([]*models.Server, 100)
+	serverRepo.On("GetInvite", ctx, invite.Code).Return(invite, nil)
+	serverRepo.On("GetByID", ctx, invite.ServerID).Return(&models.Server{ID: invite.ServerID}, nil)
+	serverRepo.On("GetBan", ctx, invite.ServerID, f.botID).Return(nil, nil)
+	serverRepo.On("GetMember", ctx, invite.ServerID, f.botID).Return(nil, nil)
+	serverRepo.On("GetUserServers", ctx, f.botID).Return(joined, nil)
+
+	_, err := service.JoinServer(ctx, f.botID, invite.Code)
+	assert.ErrorIs(t, err, ErrMaxServersReached, "unverified bots are capped at 100 servers")
+	serverRepo.AssertNotCalled(t, "AddMember", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// Bulk delete limits
+const (
+	MinBulkDelete    = 2
+	MaxBulkDelete    = 100
+	BulkDeleteMaxAge = 14 * 24 * time.Hour
+)
+
+// BulkDeleteRepository deletes many messages at once
+type BulkDeleteRepository interface {
+	// BulkDelete deletes messages from a channel atomically, refusing with
+	// ErrMessagesTooOld if any was created before notBefore
+	BulkDelete(ctx context.Context, channelID uuid.UUID, messageIDs []uuid.UUID, notBefore time.Time, deletedBy uuid.UUID, tombstone bool) ([]*models.Message, error)
+}
+
+// AuditLogger records moderator actions
+type AuditLogger interface {
+	Log(ctx context.Context, serverID, userID uuid.UUID, action string, targetID *uuid.UUID, changes []models.Change, reason string) error
+}
+
+// SetBulkDeleteRepository enables bulk message deletion
+func (s *MessageService) SetBulkDeleteRepository(repo BulkDeleteRepository) {
+	s.bulkDeleter = repo
+}
+
+// SetAuditLogger sets where moderator message actions are recorded
+func (s *MessageService) SetAuditLogger(logger AuditLogger) {
+	s.auditLog = logger
+}
+
+// MessagesBulkDeletedEvent is published once for a bulk delete
+type MessagesBulkDeletedEvent struct {
+	MessageIDs []uuid.UUID
+	ChannelID  uuid.UUID
+	ServerID   *uuid.UUID
+	DeletedBy  uuid.UUID
+}
+
+// BulkDeleteMessages deletes 2-100 messages from a server channel in one
+// go. All of them must be under 14 days old, and the requester needs
+// MANAGE_MESSAGES. IDs that aren't in the channel are ignored. It returns
+// the IDs that were deleted.
+func (s *MessageService) BulkDeleteMessages(ctx context.Context, channelID, requesterID uuid.UUID, messageIDs []uuid.UUID, reason string) ([]uuid.UUID, error) {
+	messageIDs = uniqueIDs(messageIDs)
+	if len(messageIDs) < MinBulkDelete || len(messageIDs) > MaxBulkDelete {
+		return nil, ErrBulkDeleteCount
+	}
+
+	channel, err := cachedChannel(ctx, s.cache, s.channelRepo, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if channel == nil {
+		return nil, ErrChannelNotFound
+	}
+	if channel.ServerID == nil {
+		return nil, ErrCannotModerate
+	}
+	if !s.canManageMessages(ctx, *channel.ServerID, requesterID) {
+		return nil, ErrCannotModerate
+	}
+	if s.bulkDeleter == nil {
+		return nil, errors.New("bulk delete is not configured")
+	}
+
+	deleted, err := s.bulkDeleter.BulkDelete(ctx, channelID, messageIDs, time.Now().Add(-BulkDeleteMaxAge), requesterID, s.tombstones != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(deleted))
+	for i, m := range deleted {
+		ids[i] = m.ID
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	if s.auditLog != nil {
+		changes := []models.Change{{Key: "count", NewValue: len(ids)}}
+		if err := s.auditLog.Log(ctx, *channel.ServerID, requesterID, models.AuditLogMessageBulkDelete, &channelID, changes, reason); err != nil {
+			log.Printf("[MessageService] failed to record bulk delete in audit log: %v", err)
+		}
+	}
+
+	s.eventBus.Publish("message.deleted_bulk", &MessagesBulkDeletedEvent{
+		MessageIDs: ids,
+		ChannelID:  channelID,
+		ServerID:   channel.ServerID,
+		DeletedBy:  requesterID,
+	})
+
+	return ids, nil
+}
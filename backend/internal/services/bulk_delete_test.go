@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeBulkDeleteRepository struct {
+	messageIDs []uuid.UUID
+	notBefore  time.Time
+	deletedBy  uuid.UUID
+	tombstone  bool
+	err        error
+}
+
+func (f *fakeBulkDeleteRepository) BulkDelete(ctx context.Context, channelID uuid.UUID, messageIDs []uuid.UUID, notBefore time.Time, deletedBy uuid.UUID, tombstone bool) ([]*models.Message, error) {
+	f.messageIDs = messageIDs
+	f.notBefore = notBefore
+	f.deletedBy = deletedBy
+	f.tombstone = tombstone
+	if f.err != nil {
+		return nil, f.err
+	}
+	messages := make([]*models.Message, len(messageIDs))
+	for i, id := range messageIDs {
+		messages[i] = &models.Message{ID: id, ChannelID: channelID}
+	}
+	return messages, nil
+}
+
+func TestBulkDeleteMessages_Count(t *testing.T) {
+	service, _, _, _, _, _, _, _, _ := setupMessageService()
+	service.SetBulkDeleteRepository(&fakeBulkDeleteRepository{})
+	ctx := context.Background()
+	id := uuid.New()
+
+	// Duplicates collapse, so this is one message
+	_, err := service.BulkDeleteMessages(ctx, uuid.New(), uuid.New(), []uuid.UUID{id, id}, "")
+	assert.Equal(t, ErrBulkDeleteCount, err)
+
+	tooMany := make([]uuid.UUID, MaxBulkDelete+1)
+	for i := range tooMany {
+		tooMany[i] = uuid.New()
+	}
+	_, err = service.BulkDeleteMessages(ctx, uuid.New(), uuid.New(), tooMany, "")
+	assert.Equal(t, ErrBulkDeleteCount, err)
+}
+
+func TestBulkDeleteMessages_RequiresManageMessages(t *testing.T) {
+	service, _, channelRepo, serverRepo, _, _, _, _, _ := setupMessageService()
+	repo := &fakeBulkDeleteRepository{}
+	service.SetBulkDeleteRepository(repo)
+	ctx := context.Background()
+	serverID := uuid.New()
+	channelID := uuid.New()
+	dmID := uuid.New()
+
+	channelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, ServerID: &serverID}, nil)
+	channelRepo.On("GetByID", ctx, dmID).Return(&models.Channel{ID: dmID, Type: models.ChannelTypeDM}, nil)
+	serverRepo.On("GetByID", ctx, serverID).Return(&models.Server{ID: serverID, OwnerID: uuid.New()}, nil)
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+
+	_, err := service.BulkDeleteMessages(ctx, channelID, uuid.New(), ids, "")
+	assert.Equal(t, ErrCannotModerate, err)
+
+	_, err = service.BulkDeleteMessages(ctx, dmID, uuid.New(), ids, "")
+	assert.Equal(t, ErrCannotModerate, err)
+
+	assert.Nil(t, repo.messageIDs)
+}
+
+func TestBulkDeleteMessages_Success(t *testing.T) {
+	service, _, channelRepo, serverRepo, _, _, _, _, eventBus := setupMessageService()
+	repo := &fakeBulkDeleteRepository{}
+	service.SetBulkDeleteRepository(repo)
+	auditLog := NewAuditLogService()
+	service.SetAuditLogger(auditLog)
+	ctx := context.Background()
+	ownerID := uuid.New()
+	serverID := uuid.New()
+	channelID := uuid.New()
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+
+	channelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, ServerID: &serverID}, nil)
+	serverRepo.On("GetByID", ctx, serverID).Return(&models.Server{ID: serverID, OwnerID: ownerID}, nil)
+	eventBus.On("Publish", "message.deleted_bulk", &MessagesBulkDeletedEvent{
+		MessageIDs: ids,
+		ChannelID:  channelID,
+		ServerID:   &serverID,
+		DeletedBy:  ownerID,
+	}).Return()
+
+	before := time.Now()
+	deleted, err := service.BulkDeleteMessages(ctx, channelID, ownerID, ids, "raid cleanup")
+
+	require.NoError(t, err)
+	assert.Equal(t, ids, deleted)
+	assert.Equal(t, ownerID, repo.deletedBy)
+	assert.False(t, repo.tombstone)
+	assert.WithinDuration(t, before.Add(-BulkDeleteMaxAge), repo.notBefore, time.Second)
+	eventBus.AssertExpectations(t)
+
+	entries, _, err := auditLog.GetLogs(ctx, serverID, AuditLogFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, models.AuditLogMessageBulkDelete, entries[0].ActionType)
+	assert.Equal(t, &channelID, entries[0].TargetID)
+	assert.Equal(t, "raid cleanup", entries[0].Reason)
+}
+
+func TestBulkDeleteMessages_TooOld(t *testing.T) {
+	service, _, channelRepo, serverRepo, _, _, _, _, eventBus := setupMessageService()
+	service.SetBulkDeleteRepository(&fakeBulkDeleteRepository{err: ErrMessagesTooOld})
+	ctx := context.Background()
+	ownerID := uuid.New()
+	serverID := uuid.New()
+	channelID := uuid.New()
+
+	channelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, ServerID: &serverID}, nil)
+	serverRepo.On("GetByID", ctx, serverID).Return(&models.Server{ID: serverID, OwnerID: ownerID}, nil)
+
+	_, err := service.BulkDeleteMessages(ctx, channelID, ownerID, []uuid.UUID{uuid.New(), uuid.New()}, "")
+
+	assert.Equal(t, ErrMessagesTooOld, err)
+	eventBus.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// warmupTTL matches what the services cache servers and channels for
+const warmupTTL = 5 * time.Minute
+
+// HotServerRepository finds the servers worth warming the cache for. The
+// Postgres ServerRepository implements it.
+type HotServerRepository interface {
+	GetLargestServers(ctx context.Context, limit int) ([]*models.Server, error)
+}
+
+// CacheWarmerConfig bounds how much a warmup caches
+type CacheWarmerConfig struct {
+	Servers int           // Largest servers to warm
+	Members int           // Most recently joined members per server whose permissions are warmed
+	Timeout time.Duration // Give up after this long (0 = no limit)
+}
+
+// WarmupStats says what a warmup cached
+type WarmupStats struct {
+	Servers     int
+	Channels    int
+	Permissions int
+	Failed      int
+	Duration    time.Duration
+}
+
+// CacheWarmer fills the cache with the largest servers' channel lists and
+// members' permissions after boot, so the first requests after a deploy
+// don't all miss. Permissions are computed from each server's role set,
+// read once per server.
+type CacheWarmer struct {
+	hotServers  HotServerRepository
+	serverRepo  ServerRepository
+	channelRepo ChannelRepository
+	permissions *PermissionService
+	cache       CacheService
+	config      CacheWarmerConfig
+	warmed      atomic.Bool
+}
+
+// NewCacheWarmer creates a new cache warmer. permissions may be nil, in
+// which case only servers and channels are warmed.
+func NewCacheWarmer(
+	hotServers HotServerRepository,
+	serverRepo ServerRepository,
+	channelRepo ChannelRepository,
+	permissions *PermissionService,
+	cache CacheService,
+	config CacheWarmerConfig,
+) *CacheWarmer {
+	return &CacheWarmer{
+		hotServers:  hotServers,
+		serverRepo:  serverRepo,
+		channelRepo: channelRepo,
+		permissions: permissions,
+		cache:       cache,
+		config:      config,
+	}
+}
+
+// Warmed reports whether Run has finished, whether or not it got through
+// everything
+func (w *CacheWarmer) Warmed() bool {
+	return w.warmed.Load()
+}
+
+// Run warms the cache and then marks the warmer done. Warming only saves
+// latency, so failures are logged and counted rather than returned, and
+// the warmup stops where it is once the timeout passes.
+func (w *CacheWarmer) Run(ctx context.Context) WarmupStats {
+	defer w.warmed.Store(true)
+
+	start := time.Now()
+	if w.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.config.Timeout)
+		defer cancel()
+	}
+
+	var stats WarmupStats
+	servers, err := w.hotServers.GetLargestServers(ctx, w.config.Servers)
+	if err != nil {
+		log.Printf("[CacheWarmer] failed to find servers to warm: %v", err)
+		stats.Duration = time.Since(start)
+		return stats
+	}
+
+	for i, server := range servers {
+		if err := ctx.Err(); err != nil {
+			log.Printf("[CacheWarmer] stopped after %d of %d servers: %v", i, len(servers), err)
+			break
+		}
+		channels, permissions, err := w.warmServer(ctx, server)
+		stats.Channels += channels
+		stats.Permissions += permissions
+		if err != nil {
+			log.Printf("[CacheWarmer] failed to warm server %s: %v", server.ID, err)
+			stats.Failed++
+			continue
+		}
+		stats.Servers++
+	}
+
+	stats.Duration = time.Since(start)
+	return stats
+}
+
+// warmServer caches a server, its channels and its members' permissions in
+// them, and says how many channels and permission entries it cached
+func (w *CacheWarmer) warmServer(ctx context.Context, server *models.Server) (int, int, error) {
+	channels, err := w.channelRepo.GetByServerID(ctx, server.ID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if w.cache != nil {
+		if err := w.cache.SetServer(ctx, server, warmupTTL); err != nil {
+			return 0, 0, err
+		}
+		for _, channel := range channels {
+			if err := w.cache.SetChannel(ctx, channel, warmupTTL); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+
+	if w.permissions == nil || w.config.Members <= 0 {
+		return len(channels), 0, nil
+	}
+	members, err := w.serverRepo.GetMembers(ctx, server.ID, w.config.Members, 0)
+	if err != nil {
+		return len(channels), 0, err
+	}
+	if !hasMember(members, server.OwnerID) {
+		members = append(members, &models.Member{ServerID: server.ID, UserID: server.OwnerID})
+	}
+	permissions, err := w.permissions.WarmServer(ctx, server, channels, members)
+	return len(channels), permissions, err
+}
+
+func hasMember(members []*models.Member, userID uuid.UUID) bool {
+	for _, m := range members {
+		if m.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeHotServers struct {
+	servers []*models.Server
+	err     error
+}
+
+func (f *fakeHotServers) GetLargestServers(ctx context.Context, limit int) ([]*models.Server, error) {
+	if len(f.servers) > limit {
+		return f.servers[:limit], f.err
+	}
+	return f.servers, f.err
+}
+
+func TestCacheWarmer_Run(t *testing.T) {
+	f := newPermissionFixture()
+	member, moderator := uuid.New(), uuid.New()
+	f.addMember(member)
+	f.addMember(moderator, f.moderator)
+	f.roleRepo.On("GetMemberRoles", mock.Anything, f.server.ID, f.server.OwnerID).Return([]*models.Role{}, nil)
+	f.channelRepo.On("GetByServerID", mock.Anything, f.server.ID).Return([]*models.Channel{f.channel}, nil)
+	f.serverRepo.On("GetMembers", mock.Anything, f.server.ID, 10, 0).Return([]*models.Member{
+		{ServerID: f.server.ID, UserID: member},
+		{ServerID: f.server.ID, UserID: moderator},
+	}, nil)
+	f.cache.On("SetServer", mock.Anything, f.server, warmupTTL).Return(nil)
+	f.cache.On("SetChannel", mock.Anything, f.channel, warmupTTL).Return(nil)
+
+	warmer := NewCacheWarmer(&fakeHotServers{servers: []*models.Server{f.server}}, f.serverRepo, f.channelRepo, f.service, f.cache,
+		CacheWarmerConfig{Servers: 5, Members: 10})
+	assert.False(t, warmer.Warmed())
+
+	stats := warmer.Run(context.Background())
+	assert.True(t, warmer.Warmed())
+	assert.Equal(t, 1, stats.Servers)
+	assert.Equal(t, 1, stats.Channels)
+	assert.Equal(t, 3, stats.Permissions, "both members and the owner")
+	assert.Zero(t, stats.Failed)
+	f.cache.AssertExpectations(t)
+
+	// Warmed entries are served without reading roles again
+	perms, err := f.service.ChannelPermissions(context.Background(), f.channel.ID, moderator)
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultPermissions|models.PermManageMessages, perms)
+	perms, err = f.service.ChannelPermissions(context.Background(), f.channel.ID, f.server.OwnerID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PermissionAll|models.PermAdministrator, perms)
+	f.roleRepo.AssertNumberOfCalls(t, "GetByServerID", 1)
+	f.roleRepo.AssertNumberOfCalls(t, "GetMemberRoles", 3)
+	f.serverRepo.AssertNotCalled(t, "GetMember", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCacheWarmer_Run_Failures(t *testing.T) {
+	f := newPermissionFixture()
+	broken := &models.Server{ID: uuid.New(), OwnerID: uuid.New()}
+	f.channelRepo.On("GetByServerID", mock.Anything, broken.ID).Return(nil, errors.New("database down"))
+	f.channelRepo.On("GetByServerID", mock.Anything, f.server.ID).Return([]*models.Channel{f.channel}, nil)
+	f.cache.On("SetServer", mock.Anything, mock.Anything, warmupTTL).Return(nil)
+	f.cache.On("SetChannel", mock.Anything, mock.Anything, warmupTTL).Return(nil)
+
+	// Without permissions only servers and channels are warmed; one
+	// broken server doesn't stop the rest
+	warmer := NewCacheWarmer(&fakeHotServers{servers: []*models.Server{broken, f.server}}, f.serverRepo, f.channelRepo, nil, f.cache,
+		CacheWarmerConfig{Servers: 5, Members: 10})
+	stats := warmer.Run(context.Background())
+	assert.True(t, warmer.Warmed())
+	assert.Equal(t, 1, stats.Servers)
+	assert.Equal(t, 1, stats.Failed)
+	assert.Zero(t, stats.Permissions)
+
+	// Instances still become ready when nothing can be warmed
+	warmer = NewCacheWarmer(&fakeHotServers{err: errors.New("database down")}, f.serverRepo, f.channelRepo, f.service, f.cache,
+		CacheWarmerConfig{Servers: 5})
+	stats = warmer.Run(context.Background())
+	assert.True(t, warmer.Warmed())
+	assert.Zero(t, stats.Servers)
+}
+
+func TestCacheWarmer_Run_StopsWhenCancelled(t *testing.T) {
+	f := newPermissionFixture()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	warmer := NewCacheWarmer(&fakeHotServers{servers: []*models.Server{f.server}}, f.serverRepo, f.channelRepo, f.service, f.cache,
+		CacheWarmerConfig{Servers: 5, Members: 10})
+	stats := warmer.Run(ctx)
+	assert.True(t, warmer.Warmed())
+	assert.Zero(t, stats.Servers)
+	f.channelRepo.AssertNotCalled(t, "GetByServerID", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// channelFeedEntries is how many recent messages a feed shows
+const channelFeedEntries = 50
+
+// ChannelFeedRepository stores which channels have Atom feeds
+type ChannelFeedRepository interface {
+	Get(ctx context.Context, channelID uuid.UUID) (*models.ChannelFeed, error)
+	// Save creates the feed or replaces its token
+	Save(ctx context.Context, feed *models.ChannelFeed) error
+	Delete(ctx context.Context, channelID uuid.UUID) error
+}
+
+// ChannelFeedService publishes channels as Atom feeds for readers outside
+// Hearth, such as changelog or announcement channels. A feed is read with
+// a secret token rather than a user's credentials.
+type ChannelFeedService struct {
+	repo        ChannelFeedRepository
+	channelRepo ChannelRepository
+	serverRepo  ServerRepository
+	roleRepo    RoleRepository
+	messages    MessageRepository
+	users       UserBatchRepository
+}
+
+// NewChannelFeedService creates a new channel feed service. Without a role
+// repository only server owners can manage feeds.
+func NewChannelFeedService(
+	repo ChannelFeedRepository,
+	channelRepo ChannelRepository,
+	serverRepo ServerRepository,
+	roleRepo RoleRepository,
+	messages MessageRepository,
+	users UserBatchRepository,
+) *ChannelFeedService {
+	return &ChannelFeedService{
+		repo:        repo,
+		channelRepo: channelRepo,
+		serverRepo:  serverRepo,
+		roleRepo:    roleRepo,
+		messages:    messages,
+		users:       users,
+	}
+}
+
+// GetSettings returns whether the channel has a feed, and its token.
+// Requires PermManageChannels since the token is the feed's only secret.
+func (s *ChannelFeedService) GetSettings(ctx context.Context, channelID, requesterID uuid.UUID) (*models.ChannelFeedSettings, error) {
+	if _, err := s.authorize(ctx, channelID, requesterID); err != nil {
+		return nil, err
+	}
+	feed, err := s.repo.Get(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	return channelFeedSettings(channelID, feed), nil
+}
+
+// UpdateSettings turns the channel's feed on or off, or gives it a new
+// token. Turning a feed off and on again also gives it a new token.
+func (s *ChannelFeedService) UpdateSettings(ctx context.Context, channelID, requesterID uuid.UUID, req *models.UpdateChannelFeedRequest) (*models.ChannelFeedSettings, error) {
+	channel, err := s.authorize(ctx, channelID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !req.Enabled {
+		if err := s.repo.Delete(ctx, channelID); err != nil {
+			return nil, err
+		}
+		return channelFeedSettings(channelID, nil), nil
+	}
+
+	if !channelFeedSupported(channel) {
+		return nil, ErrChannelFeedUnsupported
+	}
+	feed, err := s.repo.Get(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if feed != nil && !req.RotateToken {
+		return channelFeedSettings(channelID, feed), nil
+	}
+	if feed == nil {
+		feed = &models.ChannelFeed{ChannelID: channelID, CreatorID: &requesterID, CreatedAt: time.Now()}
+	}
+	if feed.Token, err = generateFeedToken(); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Save(ctx, feed); err != nil {
+		return nil, err
+	}
+	return channelFeedSettings(channelID, feed), nil
+}
+
+// Feed returns the channel's recent messages for its Atom feed. A wrong
+// token looks the same as a channel without a feed.
+func (s *ChannelFeedService) Feed(ctx context.Context, channelID uuid.UUID, token string) (*models.ChannelFeedContent, error) {
+	feed, err := s.repo.Get(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if feed == nil || token == "" || subtle.ConstantTimeCompare([]byte(feed.Token), []byte(token)) != 1 {
+		return nil, ErrChannelFeedNotFound
+	}
+
+	channel, err := s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	// The channel may have been encrypted since the feed was turned on
+	if channel == nil || !channelFeedSupported(channel) {
+		return nil, ErrChannelFeedNotFound
+	}
+	server, err := s.serverRepo.GetByID(ctx, *channel.ServerID)
+	if err != nil {
+		return nil, err
+	}
+	if server == nil {
+		return nil, ErrChannelFeedNotFound
+	}
+
+	recent, err := s.messages.GetChannelMessages(ctx, channelID, nil, nil, channelFeedEntries)
+	if err != nil {
+		return nil, err
+	}
+	content := &models.ChannelFeedContent{Channel: channel, Server: server, Messages: []*models.Message{}, Updated: feed.CreatedAt}
+	var authorIDs []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, message := range recent {
+		if message.EncryptedContent != "" || (message.Content == "" && len(message.Attachments) == 0) {
+			continue
+		}
+		content.Messages = append(content.Messages, message)
+		if updated := messageUpdatedAt(message); updated.After(content.Updated) {
+			content.Updated = updated
+		}
+		if !seen[message.AuthorID] {
+			seen[message.AuthorID] = true
+			authorIDs = append(authorIDs, message.AuthorID)
+		}
+	}
+
+	if len(authorIDs) > 0 {
+		authors, err := s.users.GetByIDs(ctx, authorIDs)
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[uuid.UUID]*models.User, len(authors))
+		for _, author := range authors {
+			byID[author.ID] = author
+		}
+		for _, message := range content.Messages {
+			if author, ok := byID[message.AuthorID]; ok {
+				public := author.ToPublic()
+				message.Author = &public
+			}
+		}
+	}
+	return content, nil
+}
+
+// authorize returns the channel if the requester can manage its feed
+func (s *ChannelFeedService) authorize(ctx context.Context, channelID, requesterID uuid.UUID) (*models.Channel, error) {
+	channel, err := s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if channel == nil {
+		return nil, ErrChannelNotFound
+	}
+	if channel.ServerID == nil {
+		return nil, ErrChannelFeedUnsupported
+	}
+	server, err := s.serverRepo.GetByID(ctx, *channel.ServerID)
+	if err != nil {
+		return nil, err
+	}
+	if server == nil {
+		return nil, ErrServerNotFound
+	}
+	if !memberHasPermission(ctx, s.serverRepo, s.roleRepo, server, requesterID, models.PermManageChannels) {
+		return nil, ErrCannotManageChannelFeed
+	}
+	return channel, nil
+}
+
+func channelFeedSupported(channel *models.Channel) bool {
+	if channel.ServerID == nil || channel.E2EEEnabled {
+		return false
+	}
+	return channel.Type == models.ChannelTypeText || channel.Type == models.ChannelTypeAnnouncement
+}
+
+func channelFeedSettings(channelID uuid.UUID, feed *models.ChannelFeed) *models.ChannelFeedSettings {
+	settings := &models.ChannelFeedSettings{ChannelID: channelID}
+	if feed != nil {
+		settings.Enabled = true
+		settings.Token = feed.Token
+		settings.EnabledAt = &feed.CreatedAt
+	}
+	return settings
+}
+
+// messageUpdatedAt is when a message was last sent or edited
+func messageUpdatedAt(message *models.Message) time.Time {
+	if message.EditedAt != nil {
+		return *message.EditedAt
+	}
+	return message.CreatedAt
+}
+
+func generateFeedToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeChannelFeedRepository struct {
+	feeds map[uuid.UUID]*models.ChannelFeed
+}
+
+func (f *fakeChannelFeedRepository) Get(ctx context.Context, channelID uuid.UUID) (*models.ChannelFeed, error) {
+	feed, ok := f.feeds[channelID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *feed
+	return &copied, nil
+}
+
+func (f *fakeChannelFeedRepository) Save(ctx context.Context, feed *models.ChannelFeed) error {
+	copied := *feed
+	f.feeds[feed.ChannelID] = &copied
+	return nil
+}
+
+func (f *fakeChannelFeedRepository) Delete(ctx context.Context, channelID uuid.UUID) error {
+	delete(f.feeds, channelID)
+	return nil
+}
+
+type channelFeedTest struct {
+	service  *ChannelFeedService
+	repo     *fakeChannelFeedRepository
+	messages *MockMessageRepository
+	users    *MockUserBatchRepository
+
+	serverID    uuid.UUID
+	textID      uuid.UUID
+	encryptedID uuid.UUID
+	voiceID     uuid.UUID
+	ownerID     uuid.UUID
+}
+
+func newChannelFeedTest() *channelFeedTest {
+	f := &channelFeedTest{
+		repo:        &fakeChannelFeedRepository{feeds: make(map[uuid.UUID]*models.ChannelFeed)},
+		messages:    new(MockMessageRepository),
+		users:       new(MockUserBatchRepository),
+		serverID:    uuid.New(),
+		textID:      uuid.New(),
+		encryptedID: uuid.New(),
+		voiceID:     uuid.New(),
+		ownerID:     uuid.New(),
+	}
+	channelRepo := new(MockChannelRepository)
+	serverRepo := new(MockServerRepository)
+	// Without a role repository only the owner manages feeds
+	f.service = NewChannelFeedService(f.repo, channelRepo, serverRepo, nil, f.messages, f.users)
+
+	channelRepo.On("GetByID", mock.Anything, f.textID).Return(&models.Channel{ID: f.textID, ServerID: &f.serverID, Type: models.ChannelTypeAnnouncement, Name: "changelog"}, nil)
+	channelRepo.On("GetByID", mock.Anything, f.encryptedID).Return(&models.Channel{ID: f.encryptedID, ServerID: &f.serverID, Type: models.ChannelTypeText, E2EEEnabled: true}, nil)
+	channelRepo.On("GetByID", mock.Anything, f.voiceID).Return(&models.Channel{ID: f.voiceID, ServerID: &f.serverID, Type: models.ChannelTypeVoice}, nil)
+	serverRepo.On("GetByID", mock.Anything, f.serverID).Return(&models.Server{ID: f.serverID, OwnerID: f.ownerID, Name: "Hearth"}, nil)
+	return f
+}
+
+func TestChannelFeedUpdateSettings(t *testing.T) {
+	f := newChannelFeedTest()
+	ctx := context.Background()
+
+	settings, err := f.service.GetSettings(ctx, f.textID, f.ownerID)
+	require.NoError(t, err)
+	assert.False(t, settings.Enabled)
+
+	settings, err = f.service.UpdateSettings(ctx, f.textID, f.ownerID, &models.UpdateChannelFeedRequest{Enabled: true})
+	require.NoError(t, err)
+	assert.True(t, settings.Enabled)
+	assert.Len(t, settings.Token, 64)
+	token := settings.Token
+
+	settings, err = f.service.UpdateSettings(ctx, f.textID, f.ownerID, &models.UpdateChannelFeedRequest{Enabled: true})
+	require.NoError(t, err)
+	assert.Equal(t, token, settings.Token, "enabling again keeps the token")
+
+	settings, err = f.service.UpdateSettings(ctx, f.textID, f.ownerID, &models.UpdateChannelFeedRequest{Enabled: true, RotateToken: true})
+	require.NoError(t, err)
+	assert.NotEqual(t, token, settings.Token)
+	assert.Equal(t, settings.Token, f.repo.feeds[f.textID].Token)
+
+	settings, err = f.service.UpdateSettings(ctx, f.textID, f.ownerID, &models.UpdateChannelFeedRequest{Enabled: false})
+	require.NoError(t, err)
+	assert.False(t, settings.Enabled)
+	assert.Empty(t, settings.Token)
+	assert.Empty(t, f.repo.feeds)
+}
+
+func TestChannelFeedUpdateSettings_Validation(t *testing.T) {
+	f := newChannelFeedTest()
+	ctx := context.Background()
+	enable := &models.UpdateChannelFeedRequest{Enabled: true}
+
+	_, err := f.service.UpdateSettings(ctx, f.textID, uuid.New(), enable)
+	assert.ErrorIs(t, err, ErrCannotManageChannelFeed)
+	_, err = f.service.GetSettings(ctx, f.textID, uuid.New())
+	assert.ErrorIs(t, err, ErrCannotManageChannelFeed, "only managers see the token")
+
+	_, err = f.service.UpdateSettings(ctx, f.encryptedID, f.ownerID, enable)
+	assert.ErrorIs(t, err, ErrChannelFeedUnsupported)
+	_, err = f.service.UpdateSettings(ctx, f.voiceID, f.ownerID, enable)
+	assert.ErrorIs(t, err, ErrChannelFeedUnsupported)
+	assert.Empty(t, f.repo.feeds)
+}
+
+func TestChannelFeed(t *testing.T) {
+	f := newChannelFeedTest()
+	ctx := context.Background()
+	settings, err := f.service.UpdateSettings(ctx, f.textID, f.ownerID, &models.UpdateChannelFeedRequest{Enabled: true})
+	require.NoError(t, err)
+
+	authorID := uuid.New()
+	now := time.Now()
+	edited := now.Add(time.Minute)
+	newest := &models.Message{ID: uuid.New(), ChannelID: f.textID, AuthorID: authorID, Content: "v1.2 is out", CreatedAt: now, EditedAt: &edited}
+	f.messages.On("GetChannelMessages", mock.Anything, f.textID, (*uuid.UUID)(nil), (*uuid.UUID)(nil), channelFeedEntries).Return([]*models.Message{
+		newest,
+		{ID: uuid.New(), ChannelID: f.textID, AuthorID: authorID, EncryptedContent: "ciphertext", CreatedAt: now.Add(-time.Minute)},
+		{ID: uuid.New(), ChannelID: f.textID, AuthorID: authorID, Content: "v1.1 is out", CreatedAt: now.Add(-time.Hour)},
+	}, nil)
+	f.users.On("GetByIDs", mock.Anything, []uuid.UUID{authorID}).Return([]*models.User{{ID: authorID, Username: "release_bot"}}, nil)
+
+	content, err := f.service.Feed(ctx, f.textID, settings.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "changelog", content.Channel.Name)
+	assert.Equal(t, "Hearth", content.Server.Name)
+	require.Len(t, content.Messages, 2, "encrypted messages are left out")
+	assert.Equal(t, "release_bot", content.Messages[0].Author.Username)
+	assert.True(t, content.Updated.Equal(edited))
+
+	_, err = f.service.Feed(ctx, f.textID, "wrong")
+	assert.ErrorIs(t, err, ErrChannelFeedNotFound)
+	_, err = f.service.Feed(ctx, f.textID, "")
+	assert.ErrorIs(t, err, ErrChannelFeedNotFound)
+	_, err = f.service.Feed(ctx, f.voiceID, settings.Token)
+	assert.ErrorIs(t, err, ErrChannelFeedNotFound)
+}
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	maxChannelHours         = 28 // Four windows a day
+	maxChannelClosures      = 25
+	maxChannelClosureReason = 200
+)
+
+// closedChannelPermissions are what a closed channel takes away from
+// members who can't manage it
+const closedChannelPermissions int64 = models.PermSendMessages | models.PermSendMessagesInThreads |
+	models.PermCreatePublicThreads | models.PermCreatePrivateThreads | models.PermAddReactions
+
+// ChannelScheduleRepository stores channels' office hours. The Postgres
+// ChannelScheduleRepository implements it.
+type ChannelScheduleRepository interface {
+	// Get returns nil for channels without a schedule
+	Get(ctx context.Context, channelID uuid.UUID) (*models.ChannelSchedule, error)
+	// Save creates or replaces the channel's schedule
+	Save(ctx context.Context, schedule *models.ChannelSchedule) error
+	Delete(ctx context.Context, channelID uuid.UUID) error
+}
+
+// SetChannelSchedules closes channels outside their office hours, taking
+// away closedChannelPermissions from members who can't manage them
+func (s *PermissionService) SetChannelSchedules(schedules ChannelScheduleRepository) {
+	s.schedules = schedules
+}
+
+// SetChannelSchedules refuses messages and reactions in channels that are
+// closed by their schedule
+func (s *MessageService) SetChannelSchedules(schedules ChannelScheduleRepository) {
+	s.schedules = schedules
+}
+
+// checkChannelOpen returns ErrChannelClosed if the channel's schedule has
+// it closed and userID can't manage channels
+func (s *MessageService) checkChannelOpen(ctx context.Context, serverID, channelID, userID uuid.UUID) error {
+	if s.schedules == nil {
+		return nil
+	}
+	schedule, err := s.schedules.Get(ctx, channelID)
+	if err != nil {
+		return err
+	}
+	if schedule == nil || schedule.OpenAt(time.Now()) {
+		return nil
+	}
+
+	server, err := s.serverRepo.GetByID(ctx, serverID)
+	if err != nil {
+		return err
+	}
+	if server == nil {
+		return ErrServerNotFound
+	}
+	if memberHasPermission(ctx, s.serverRepo, s.roleRepo, server, userID, models.PermManageChannels) {
+		return nil
+	}
+	return ErrChannelClosed
+}
+
+// ChannelScheduleService manages channels' office hours: weekly hours when
+// a channel is open and closures, such as holidays, when it isn't. Members
+// can read a closed channel but not send to it.
+type ChannelScheduleService struct {
+	repo        ChannelScheduleRepository
+	channelRepo ChannelRepository
+	serverRepo  ServerRepository
+	roleRepo    RoleRepository
+	eventBus    EventBus
+	now         func() time.Time
+}
+
+// NewChannelScheduleService creates a new channel schedule service. Without
+// a role repository only server owners can manage schedules.
+func NewChannelScheduleService(
+	repo ChannelScheduleRepository,
+	channelRepo ChannelRepository,
+	serverRepo ServerRepository,
+	roleRepo RoleRepository,
+	eventBus EventBus,
+) *ChannelScheduleService {
+	return &ChannelScheduleService{
+		repo:        repo,
+		channelRepo: channelRepo,
+		serverRepo:  serverRepo,
+		roleRepo:    roleRepo,
+		eventBus:    eventBus,
+		now:         time.Now,
+	}
+}
+
+// Get returns a channel's schedule with whether it's open and when that
+// next changes, so clients can count down. Any member can read it.
+func (s *ChannelScheduleService) Get(ctx context.Context, channelID, requesterID uuid.UUID) (*models.ChannelSchedule, error) {
+	channel, err := s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if channel == nil {
+		return nil, ErrChannelNotFound
+	}
+	if channel.ServerID == nil {
+		return nil, ErrChannelScheduleNotFound
+	}
+	member, err := s.serverRepo.GetMember(ctx, *channel.ServerID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrNotServerMember
+	}
+
+	schedule, err := s.repo.Get(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if schedule == nil {
+		return nil, ErrChannelScheduleNotFound
+	}
+	schedule.State(s.now())
+	return schedule, nil
+}
+
+// Update replaces a channel's schedule. Requires PermManageChannels.
+// Closures that have already ended are dropped.
+func (s *ChannelScheduleService) Update(ctx context.Context, channelID, requesterID uuid.UUID, req *models.UpdateChannelScheduleRequest) (*models.ChannelSchedule, error) {
+	channel, err := s.authorize(ctx, channelID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	if channel.Type != models.ChannelTypeText && channel.Type != models.ChannelTypeAnnouncement {
+		return nil, ErrChannelScheduleUnsupported
+	}
+
+	now := s.now()
+	schedule := &models.ChannelSchedule{
+		ChannelID: channelID,
+		Timezone:  strings.TrimSpace(req.Timezone),
+		Hours:     req.Hours,
+		UpdatedAt: now,
+	}
+	if err := normalizeChannelSchedule(schedule, req.Closures, now); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Save(ctx, schedule); err != nil {
+		return nil, err
+	}
+
+	s.publishUpdated(channel)
+	schedule.State(now)
+	return schedule, nil
+}
+
+// Delete removes a channel's schedule, leaving it open. Requires
+// PermManageChannels.
+func (s *ChannelScheduleService) Delete(ctx context.Context, channelID, requesterID uuid.UUID) error {
+	channel, err := s.authorize(ctx, channelID, requesterID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, channelID); err != nil {
+		return err
+	}
+	s.publishUpdated(channel)
+	return nil
+}
+
+// authorize returns the channel if the requester can manage its schedule
+func (s *ChannelScheduleService) authorize(ctx context.Context, channelID, requesterID uuid.UUID) (*models.Channel, error) {
+	channel, err := s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if channel == nil {
+		return nil, ErrChannelNotFound
+	}
+	if channel.ServerID == nil {
+		return nil, ErrChannelScheduleUnsupported
+	}
+	server, err := s.serverRepo.GetByID(ctx, *channel.ServerID)
+	if err != nil {
+		return nil, err
+	}
+	if server == nil {
+		return nil, ErrServerNotFound
+	}
+	if !memberHasPermission(ctx, s.serverRepo, s.roleRepo, server, requesterID, models.PermManageChannels) {
+		return nil, ErrCannotManageChannelSchedule
+	}
+	return channel, nil
+}
+
+// publishUpdated announces the change as a channel update, which also
+// drops members' cached permissions in the server
+func (s *ChannelScheduleService) publishUpdated(channel *models.Channel) {
+	s.eventBus.Publish("channel.updated", &ChannelUpdatedEvent{Channel: channel})
+}
+
+func invalidChannelSchedule(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidChannelSchedule, fmt.Sprintf(format, args...))
+}
+
+// normalizeChannelSchedule checks a schedule's timezone and hours and keeps
+// the closures that haven't ended yet, in order
+func normalizeChannelSchedule(schedule *models.ChannelSchedule, closures []models.ChannelClosure, now time.Time) error {
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+		return invalidChannelSchedule("unknown timezone %q", schedule.Timezone)
+	}
+
+	if len(schedule.Hours) > maxChannelHours {
+		return invalidChannelSchedule("at most %d opening hours", maxChannelHours)
+	}
+	if schedule.Hours == nil {
+		schedule.Hours = []models.ChannelHours{}
+	}
+	for _, hours := range schedule.Hours {
+		if hours.Day < time.Sunday || hours.Day > time.Saturday {
+			return invalidChannelSchedule("day must be 0 (Sunday) to 6 (Saturday)")
+		}
+		opens, okOpen := models.ParseClock(hours.Open)
+		closes, okClose := models.ParseClock(hours.Close)
+		if !okOpen || !okClose {
+			return invalidChannelSchedule("open and close must be HH:MM")
+		}
+		if opens >= closes {
+			return invalidChannelSchedule("hours must close after they open; split hours past midnight across two days")
+		}
+	}
+
+	schedule.Closures = []models.ChannelClosure{}
+	for _, closure := range closures {
+		if !closure.EndsAt.After(closure.StartsAt) {
+			return invalidChannelSchedule("closures must end after they start")
+		}
+		closure.Reason = strings.TrimSpace(closure.Reason)
+		if utf8.RuneCountInString(closure.Reason) > maxChannelClosureReason {
+			return invalidChannelSchedule("closure reasons can be at most %d characters", maxChannelClosureReason)
+		}
+		if closure.EndsAt.After(now) {
+			schedule.Closures = append(schedule.Closures, closure)
+		}
+	}
+	if len(schedule.Closures) > maxChannelClosures {
+		return invalidChannelSchedule("at most %d upcoming closures", maxChannelClosures)
+	}
+	sort.Slice(schedule.Closures, func(i, j int) bool {
+		return schedule.Closures[i].StartsAt.Before(schedule.Closures[j].StartsAt)
+	})
+
+	if len(schedule.Hours) == 0 && len(schedule.Closures) == 0 {
+		return invalidChannelSchedule("a schedule needs opening hours or a closure")
+	}
+	return nil
+}
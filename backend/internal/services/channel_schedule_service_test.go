@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeChannelScheduleRepository struct {
+	schedules map[uuid.UUID]*models.ChannelSchedule
+}
+
+func newFakeChannelScheduleRepository() *fakeChannelScheduleRepository {
+	return &fakeChannelScheduleRepository{schedules: make(map[uuid.UUID]*models.ChannelSchedule)}
+}
+
+func (f *fakeChannelScheduleRepository) Get(ctx context.Context, channelID uuid.UUID) (*models.ChannelSchedule, error) {
+	schedule, ok := f.schedules[channelID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *schedule
+	return &copied, nil
+}
+
+func (f *fakeChannelScheduleRepository) Save(ctx context.Context, schedule *models.ChannelSchedule) error {
+	copied := *schedule
+	f.schedules[schedule.ChannelID] = &copied
+	return nil
+}
+
+func (f *fakeChannelScheduleRepository) Delete(ctx context.Context, channelID uuid.UUID) error {
+	delete(f.schedules, channelID)
+	return nil
+}
+
+// closedNow is a schedule that has a channel closed for the next hour
+func closedNow(channelID uuid.UUID) *models.ChannelSchedule {
+	now := time.Now()
+	return &models.ChannelSchedule{
+		ChannelID: channelID,
+		Timezone:  "UTC",
+		Hours:     []models.ChannelHours{},
+		Closures:  []models.ChannelClosure{{StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)}},
+	}
+}
+
+type channelScheduleTest struct {
+	service    *ChannelScheduleService
+	repo       *fakeChannelScheduleRepository
+	serverRepo *MockServerRepository
+	eventBus   *MockEventBus
+	now        time.Time
+
+	server  *models.Server
+	textID  uuid.UUID
+	voiceID uuid.UUID
+}
+
+func newChannelScheduleTest() *channelScheduleTest {
+	serverID := uuid.New()
+	f := &channelScheduleTest{
+		repo:       newFakeChannelScheduleRepository(),
+		serverRepo: new(MockServerRepository),
+		eventBus:   new(MockEventBus),
+		// A Monday
+		now:     time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC),
+		server:  &models.Server{ID: serverID, OwnerID: uuid.New()},
+		textID:  uuid.New(),
+		voiceID: uuid.New(),
+	}
+	channelRepo := new(MockChannelRepository)
+	// Without a role repository only the owner manages schedules
+	f.service = NewChannelScheduleService(f.repo, channelRepo, f.serverRepo, nil, f.eventBus)
+	f.service.now = func() time.Time { return f.now }
+
+	channelRepo.On("GetByID", mock.Anything, f.textID).Return(&models.Channel{ID: f.textID, ServerID: &serverID, Type: models.ChannelTypeText}, nil)
+	channelRepo.On("GetByID", mock.Anything, f.voiceID).Return(&models.Channel{ID: f.voiceID, ServerID: &serverID, Type: models.ChannelTypeVoice}, nil)
+	f.serverRepo.On("GetByID", mock.Anything, serverID).Return(f.server, nil)
+	return f
+}
+
+func TestChannelScheduleUpdate(t *testing.T) {
+	f := newChannelScheduleTest()
+	ctx := context.Background()
+	f.eventBus.On("Publish", "channel.updated", mock.MatchedBy(func(e *ChannelUpdatedEvent) bool {
+		return e.Channel.ID == f.textID
+	})).Return().Twice()
+
+	holiday := f.now.Add(48 * time.Hour)
+	schedule, err := f.service.Update(ctx, f.textID, f.server.OwnerID, &models.UpdateChannelScheduleRequest{
+		Hours: []models.ChannelHours{{Day: time.Monday, Open: "09:00", Close: "17:00"}},
+		Closures: []models.ChannelClosure{
+			{StartsAt: holiday, EndsAt: holiday.Add(24 * time.Hour), Reason: " Holiday "},
+			{StartsAt: f.now.Add(-48 * time.Hour), EndsAt: f.now.Add(-24 * time.Hour)},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "UTC", schedule.Timezone)
+	assert.Equal(t, []models.ChannelClosure{{StartsAt: holiday, EndsAt: holiday.Add(24 * time.Hour), Reason: "Holiday"}}, schedule.Closures, "past closures are dropped")
+	assert.True(t, schedule.Open)
+	require.NotNil(t, schedule.NextChangeAt)
+	assert.Equal(t, f.now.Add(7*time.Hour), *schedule.NextChangeAt)
+
+	_, err = f.service.Update(ctx, f.textID, uuid.New(), &models.UpdateChannelScheduleRequest{Hours: schedule.Hours})
+	assert.ErrorIs(t, err, ErrCannotManageChannelSchedule)
+	_, err = f.service.Update(ctx, f.voiceID, f.server.OwnerID, &models.UpdateChannelScheduleRequest{Hours: schedule.Hours})
+	assert.ErrorIs(t, err, ErrChannelScheduleUnsupported)
+
+	require.NoError(t, f.service.Delete(ctx, f.textID, f.server.OwnerID))
+	assert.Empty(t, f.repo.schedules)
+	f.eventBus.AssertExpectations(t)
+}
+
+func TestChannelScheduleUpdate_Validation(t *testing.T) {
+	f := newChannelScheduleTest()
+	ctx := context.Background()
+	monday := models.ChannelHours{Day: time.Monday, Open: "09:00", Close: "17:00"}
+
+	for name, req := range map[string]*models.UpdateChannelScheduleRequest{
+		"empty":              {},
+		"unknown timezone":   {Timezone: "Mars/Olympus_Mons", Hours: []models.ChannelHours{monday}},
+		"bad day":            {Hours: []models.ChannelHours{{Day: 7, Open: "09:00", Close: "17:00"}}},
+		"bad time":           {Hours: []models.ChannelHours{{Day: time.Monday, Open: "9am", Close: "17:00"}}},
+		"closes first":       {Hours: []models.ChannelHours{{Day: time.Monday, Open: "22:00", Close: "02:00"}}},
+		"backwards closure":  {Closures: []models.ChannelClosure{{StartsAt: f.now.Add(time.Hour), EndsAt: f.now}}},
+		"only past closures": {Closures: []models.ChannelClosure{{StartsAt: f.now.Add(-2 * time.Hour), EndsAt: f.now.Add(-time.Hour)}}},
+	} {
+		_, err := f.service.Update(ctx, f.textID, f.server.OwnerID, req)
+		assert.ErrorIs(t, err, ErrInvalidChannelSchedule, name)
+	}
+	assert.Empty(t, f.repo.schedules)
+}
+
+func TestChannelScheduleGet(t *testing.T) {
+	f := newChannelScheduleTest()
+	ctx := context.Background()
+	memberID, outsiderID := uuid.New(), uuid.New()
+	f.serverRepo.On("GetMember", mock.Anything, f.server.ID, memberID).Return(&models.Member{ServerID: f.server.ID, UserID: memberID}, nil)
+	f.serverRepo.On("GetMember", mock.Anything, f.server.ID, outsiderID).Return(nil, nil)
+
+	_, err := f.service.Get(ctx, f.textID, memberID)
+	assert.ErrorIs(t, err, ErrChannelScheduleNotFound)
+
+	f.repo.schedules[f.textID] = &models.ChannelSchedule{
+		ChannelID: f.textID,
+		Timezone:  "UTC",
+		Hours:     []models.ChannelHours{{Day: time.Monday, Open: "12:00", Close: "13:00"}},
+	}
+	schedule, err := f.service.Get(ctx, f.textID, memberID)
+	require.NoError(t, err)
+	assert.False(t, schedule.Open)
+	require.NotNil(t, schedule.NextChangeAt)
+	assert.Equal(t, f.now.Add(2*time.Hour), *schedule.NextChangeAt, "clients count down to the opening")
+
+	_, err = f.service.Get(ctx, f.textID, outsiderID)
+	assert.ErrorIs(t, err, ErrNotServerMember)
+}
+
+func TestPermissionService_ChannelPermissions_Closed(t *testing.T) {
+	f := newPermissionFixture()
+	now := time.Date(2026, 3, 3, 8, 58, 0, 0, time.UTC)
+	f.service.now = func() time.Time { return now }
+	schedules := newFakeChannelScheduleRepository()
+	schedules.schedules[f.channel.ID] = &models.ChannelSchedule{
+		ChannelID: f.channel.ID,
+		Timezone:  "UTC",
+		Hours:     []models.ChannelHours{{Day: time.Tuesday, Open: "09:00", Close: "17:00"}},
+	}
+	f.service.SetChannelSchedules(schedules)
+
+	member, manager := uuid.New(), uuid.New()
+	f.addMember(member)
+	f.addMember(manager)
+	f.overwrites[f.channel.ID] = []models.PermissionOverride{{TargetType: "user", TargetID: manager, Allow: models.PermManageChannels}}
+
+	perms, err := f.service.ChannelPermissions(context.Background(), f.channel.ID, member)
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultPermissions&^closedChannelPermissions, perms)
+	assert.True(t, models.HasPermission(perms, models.PermReadMessageHistory), "closed channels can still be read")
+	assert.Equal(t, 2*time.Minute, f.cache.ttls[permissionKey(f.channel.ID, member)], "entry expires when the channel opens")
+
+	perms, err = f.service.ChannelPermissions(context.Background(), f.channel.ID, manager)
+	require.NoError(t, err)
+	assert.True(t, models.HasPermission(perms, models.PermSendMessages), "channel managers can post while closed")
+}
+
+func TestSendMessage_ChannelClosed(t *testing.T) {
+	service, _, channelRepo, serverRepo, _, _, _, _, _ := setupMessageService()
+	ctx := context.Background()
+	authorID := uuid.New()
+	channelID := uuid.New()
+	server := &models.Server{ID: uuid.New(), OwnerID: uuid.New()}
+
+	schedules := newFakeChannelScheduleRepository()
+	schedules.schedules[channelID] = closedNow(channelID)
+	service.SetChannelSchedules(schedules)
+
+	channelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, ServerID: &server.ID, Type: models.ChannelTypeText}, nil)
+	serverRepo.On("GetByID", ctx, server.ID).Return(server, nil)
+	serverRepo.On("GetMember", ctx, server.ID, authorID).Return(&models.Member{ServerID: server.ID, UserID: authorID}, nil)
+
+	message, err := service.SendMessage(ctx, authorID, channelID, "Hello!", nil, nil)
+	assert.Equal(t, ErrChannelClosed, err)
+	assert.Nil(t, message)
+}
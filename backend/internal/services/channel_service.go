@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,6 +15,8 @@ type ChannelService struct {
 	serverRepo  ServerRepository
 	cache       CacheService
 	eventBus    EventBus
+	blocks      BlockRepository
+	dmPrivacy   DMPrivacy
 }
 
 // NewChannelService creates a new channel service
@@ -31,6 +34,18 @@ func NewChannelService(
 	}
 }
 
+// SetBlockRepository stops new DMs being opened between users when either
+// has blocked the other
+func (s *ChannelService) SetBlockRepository(blocks BlockRepository) {
+	s.blocks = blocks
+}
+
+// SetDMPrivacy stops DMs and group DMs being opened with users who don't
+// accept direct messages from the requester
+func (s *ChannelService) SetDMPrivacy(privacy DMPrivacy) {
+	s.dmPrivacy = privacy
+}
+
 // GetChannel retrieves a channel by ID
 func (s *ChannelService) GetChannel(ctx context.Context, id uuid.UUID) (*models.Channel, error) {
 	// Try cache first
@@ -131,6 +146,10 @@ func (s *ChannelService) UpdateChannel(
 		// TODO: Check MANAGE_CHANNELS permission
 	}
 
+	if updates.Version != nil && *updates.Version != channel.Version {
+		return nil, &VersionConflictError{Current: channel, Version: channel.Version}
+	}
+
 	// Apply updates
 	if updates.Name != nil {
 		channel.Name = *updates.Name
@@ -147,11 +166,31 @@ func (s *ChannelService) UpdateChannel(
 	if updates.NSFW != nil {
 		channel.NSFW = *updates.NSFW
 	}
-	if updates.E2EEEnabled != nil {
-		channel.E2EEEnabled = *updates.E2EEEnabled
+	if updates.E2EEEnabled != nil && *updates.E2EEEnabled != channel.E2EEEnabled {
+		// History is sealed with members' keys, so there's no going back
+		if channel.E2EEEnabled {
+			return nil, ErrCannotDisableE2EE
+		}
+		switch channel.Type {
+		case models.ChannelTypeText, models.ChannelTypeAnnouncement, models.ChannelTypeGroupDM:
+		default:
+			return nil, ErrE2EEUnsupported
+		}
+		channel.E2EEEnabled = true
 	}
 
 	if err := s.channelRepo.Update(ctx, channel); err != nil {
+		if errors.Is(err, ErrVersionConflict) {
+			// Someone saved between our read and write
+			current, getErr := s.channelRepo.GetByID(ctx, id)
+			if getErr != nil {
+				return nil, getErr
+			}
+			if current == nil {
+				return nil, ErrChannelNotFound
+			}
+			return nil, &VersionConflictError{Current: current, Version: current.Version}
+		}
 		return nil, err
 	}
 
@@ -219,6 +258,21 @@ func (s *ChannelService) GetOrCreateDM(ctx context.Context, user1ID, user2ID uui
 		return channel, nil
 	}
 
+	if s.blocks != nil {
+		blocked, err := isBlockedEitherWay(ctx, s.blocks, user1ID, user2ID)
+		if err != nil {
+			return nil, err
+		}
+		if blocked {
+			return nil, ErrUserBlocked
+		}
+	}
+	if s.dmPrivacy != nil {
+		if err := s.dmPrivacy.CanDM(ctx, user1ID, user2ID); err != nil {
+			return nil, err
+		}
+	}
+
 	// Create new DM channel
 	channel = &models.Channel{
 		ID:          uuid.New(),
@@ -242,6 +296,14 @@ func (s *ChannelService) CreateGroupDM(
 	name string,
 	recipientIDs []uuid.UUID,
 ) (*models.Channel, error) {
+	if s.dmPrivacy != nil {
+		for _, recipientID := range recipientIDs {
+			if err := s.dmPrivacy.CanDM(ctx, ownerID, recipientID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// Include owner in recipients
 	allRecipients := append([]uuid.UUID{ownerID}, recipientIDs...)
 
@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"hearth/internal/models"
 )
 
@@ -330,6 +331,47 @@ func TestCreateChannel_NotMember(t *testing.T) {
 	assert.Nil(t, channel)
 }
 
+func TestUpdateChannel_StaleVersion(t *testing.T) {
+	service, channelRepo, _, _, _ := setupChannelService()
+	ctx := context.Background()
+	channelID := uuid.New()
+
+	current := &models.Channel{ID: channelID, Type: models.ChannelTypeGroupDM, Name: "theirs", Version: 4}
+	stale := 3
+	newName := "mine"
+
+	channelRepo.On("GetByID", ctx, channelID).Return(current, nil)
+
+	channel, err := service.UpdateChannel(ctx, channelID, uuid.New(), &models.ChannelUpdate{Name: &newName, Version: &stale})
+
+	assert.Nil(t, channel)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	var conflict *VersionConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, 4, conflict.Version)
+	assert.Equal(t, "theirs", conflict.Current.(*models.Channel).Name)
+	channelRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestUpdateChannel_ConcurrentWrite(t *testing.T) {
+	service, channelRepo, _, _, _ := setupChannelService()
+	ctx := context.Background()
+	channelID := uuid.New()
+	newName := "mine"
+
+	// Another admin saves between our read and our write
+	channelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, Name: "old", Version: 1}, nil).Once()
+	channelRepo.On("Update", ctx, mock.AnythingOfType("*models.Channel")).Return(ErrVersionConflict)
+	channelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, Name: "theirs", Version: 2}, nil).Once()
+
+	_, err := service.UpdateChannel(ctx, channelID, uuid.New(), &models.ChannelUpdate{Name: &newName})
+
+	var conflict *VersionConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, 2, conflict.Version)
+	assert.Equal(t, "theirs", conflict.Current.(*models.Channel).Name)
+}
+
 func TestUpdateChannel_Success(t *testing.T) {
 	service, channelRepo, serverRepo, cache, eventBus := setupChannelService()
 	ctx := context.Background()
@@ -369,6 +411,33 @@ func TestUpdateChannel_Success(t *testing.T) {
 	assert.Equal(t, "new topic", channel.Topic)
 }
 
+func TestUpdateChannel_E2EE(t *testing.T) {
+	service, channelRepo, serverRepo, cache, eventBus := setupChannelService()
+	ctx := context.Background()
+	serverID := uuid.New()
+	requesterID := uuid.New()
+	text := &models.Channel{ID: uuid.New(), ServerID: &serverID, Type: models.ChannelTypeText}
+	voice := &models.Channel{ID: uuid.New(), ServerID: &serverID, Type: models.ChannelTypeVoice}
+
+	channelRepo.On("GetByID", ctx, text.ID).Return(text, nil)
+	channelRepo.On("GetByID", ctx, voice.ID).Return(voice, nil)
+	serverRepo.On("GetMember", ctx, serverID, requesterID).Return(&models.Member{UserID: requesterID, ServerID: serverID}, nil)
+	channelRepo.On("Update", ctx, mock.AnythingOfType("*models.Channel")).Return(nil)
+	cache.On("DeleteChannel", ctx, mock.Anything).Return(nil)
+	eventBus.On("Publish", "channel.updated", mock.AnythingOfType("*services.ChannelUpdatedEvent")).Return()
+
+	enable, disable := true, false
+	_, err := service.UpdateChannel(ctx, voice.ID, requesterID, &models.ChannelUpdate{E2EEEnabled: &enable})
+	assert.ErrorIs(t, err, ErrE2EEUnsupported)
+
+	channel, err := service.UpdateChannel(ctx, text.ID, requesterID, &models.ChannelUpdate{E2EEEnabled: &enable})
+	require.NoError(t, err)
+	assert.True(t, channel.E2EEEnabled)
+
+	_, err = service.UpdateChannel(ctx, text.ID, requesterID, &models.ChannelUpdate{E2EEEnabled: &disable})
+	assert.ErrorIs(t, err, ErrCannotDisableE2EE)
+}
+
 func TestDeleteChannel_Success(t *testing.T) {
 	service, channelRepo, serverRepo, cache, eventBus := setupChannelService()
 	ctx := context.Background()
@@ -456,6 +525,22 @@ func TestGetOrCreateDM_CreateNew(t *testing.T) {
 	channelRepo.AssertExpectations(t)
 }
 
+func TestGetOrCreateDM_NotAccepted(t *testing.T) {
+	service, channelRepo, _, _, _ := setupChannelService()
+	ctx := context.Background()
+	user1ID := uuid.New()
+	user2ID := uuid.New()
+	privacy, _ := newPrivacyTest()
+	service.SetDMPrivacy(privacy)
+
+	channelRepo.On("GetDMChannel", ctx, user1ID, user2ID).Return(nil, nil)
+
+	_, err := service.GetOrCreateDM(ctx, user1ID, user2ID)
+
+	assert.ErrorIs(t, err, ErrDMNotAllowed)
+	channelRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
 func TestCreateGroupDM_Success(t *testing.T) {
 	service, channelRepo, _, _, _ := setupChannelService()
 	ctx := context.Background()
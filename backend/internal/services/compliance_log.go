@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	complianceQueueSize     = 4096
+	complianceBatchSize     = 100
+	complianceFlushInterval = time.Second
+	complianceWriteTimeout  = 5 * time.Second
+)
+
+// ComplianceLogStore keeps compliance entries append-only. The Postgres
+// ComplianceLogRepository implements it.
+type ComplianceLogStore interface {
+	Append(ctx context.Context, entries []*models.ComplianceLogEntry) error
+	Export(ctx context.Context, filter models.ComplianceLogFilter, fn func(*models.ComplianceLogEntry) error) error
+	Purge(ctx context.Context, cutoff time.Time) (int, error)
+}
+
+// ComplianceLogger records mutating API requests for compliance. Entries
+// are queued and written in batches so requests don't wait on the store;
+// when the queue is full, or once the writer has stopped, an entry is
+// written straight away instead of being dropped.
+type ComplianceLogger struct {
+	store ComplianceLogStore
+	queue chan *models.ComplianceLogEntry
+	now   func() time.Time
+
+	mu       sync.RWMutex
+	stopped  bool
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewComplianceLogger creates a compliance logger writing to store. Run
+// must be started for queued entries to be written.
+func NewComplianceLogger(store ComplianceLogStore) *ComplianceLogger {
+	return &ComplianceLogger{
+		store: store,
+		queue: make(chan *models.ComplianceLogEntry, complianceQueueSize),
+		now:   time.Now,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// Record queues an entry, filling in its ID and time if they're unset
+func (l *ComplianceLogger) Record(entry *models.ComplianceLogEntry) {
+	if entry.ID == uuid.Nil {
+		entry.ID = uuid.New()
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = l.now()
+	}
+	if entry.Targets == nil {
+		entry.Targets = map[string]string{}
+	}
+
+	l.mu.RLock()
+	if !l.stopped {
+		select {
+		case l.queue <- entry:
+			l.mu.RUnlock()
+			return
+		default:
+		}
+	}
+	l.mu.RUnlock()
+	l.write([]*models.ComplianceLogEntry{entry})
+}
+
+// Run writes queued entries until Close is called
+func (l *ComplianceLogger) Run() {
+	defer close(l.done)
+	ticker := time.NewTicker(complianceFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*models.ComplianceLogEntry, 0, complianceBatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			l.write(batch)
+			batch = make([]*models.ComplianceLogEntry, 0, complianceBatchSize)
+		}
+	}
+
+	for {
+		select {
+		case <-l.stop:
+			l.mu.Lock()
+			l.stopped = true
+			l.mu.Unlock()
+			for {
+				select {
+				case entry := <-l.queue:
+					batch = append(batch, entry)
+					if len(batch) == complianceBatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		case entry := <-l.queue:
+			batch = append(batch, entry)
+			if len(batch) == complianceBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// Close stops Run and waits for it to write everything still queued.
+// Entries recorded afterwards are written straight away.
+func (l *ComplianceLogger) Close() {
+	l.stopOnce.Do(func() { close(l.stop) })
+	<-l.done
+}
+
+func (l *ComplianceLogger) write(entries []*models.ComplianceLogEntry) {
+	ctx, cancel := context.WithTimeout(context.Background(), complianceWriteTimeout)
+	defer cancel()
+	if err := l.store.Append(ctx, entries); err != nil {
+		log.Printf("[ComplianceLog] failed to write %d entries: %v", len(entries), err)
+	}
+}
+
+// Export calls fn for each stored entry matching filter, oldest first
+func (l *ComplianceLogger) Export(ctx context.Context, filter models.ComplianceLogFilter, fn func(*models.ComplianceLogEntry) error) error {
+	return l.store.Export(ctx, filter, fn)
+}
+
+// Purge removes entries older than retention
+func (l *ComplianceLogger) Purge(ctx context.Context, now time.Time, retention time.Duration) (int, error) {
+	return l.store.Purge(ctx, now.Add(-retention))
+}
+
+// RunPurge purges expired entries every interval until ctx is cancelled
+func (l *ComplianceLogger) RunPurge(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			purged, err := l.Purge(ctx, now, retention)
+			if err != nil {
+				log.Printf("[ComplianceLog] failed to purge compliance log: %v", err)
+			} else if purged > 0 {
+				log.Printf("[ComplianceLog] purged %d compliance log entries", purged)
+			}
+		}
+	}
+}
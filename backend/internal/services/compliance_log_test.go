@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+// fakeComplianceStore keeps appended entries in memory
+type fakeComplianceStore struct {
+	mu      sync.Mutex
+	entries []*models.ComplianceLogEntry
+	batches int
+	cutoff  time.Time
+}
+
+func (s *fakeComplianceStore) Append(ctx context.Context, entries []*models.ComplianceLogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entries...)
+	s.batches++
+	return nil
+}
+
+func (s *fakeComplianceStore) Export(ctx context.Context, filter models.ComplianceLogFilter, fn func(*models.ComplianceLogEntry) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, entry := range s.entries {
+		if filter.ActorID != nil && (entry.ActorID == nil || *entry.ActorID != *filter.ActorID) {
+			continue
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *fakeComplianceStore) Purge(ctx context.Context, cutoff time.Time) (int, error) {
+	s.cutoff = cutoff
+	return 0, nil
+}
+
+func (s *fakeComplianceStore) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+func TestComplianceLogger_CloseWritesQueuedEntries(t *testing.T) {
+	store := &fakeComplianceStore{}
+	logger := NewComplianceLogger(store)
+	go logger.Run()
+
+	for i := 0; i < 250; i++ {
+		logger.Record(&models.ComplianceLogEntry{Method: "POST", Route: "/api/v1/servers"})
+	}
+	logger.Close()
+
+	require.Equal(t, 250, store.count())
+	assert.LessOrEqual(t, store.batches, 250/complianceBatchSize+1, "entries are written in batches")
+	first := store.entries[0]
+	assert.NotEqual(t, uuid.Nil, first.ID)
+	assert.False(t, first.CreatedAt.IsZero())
+	assert.NotNil(t, first.Targets)
+
+	// Once closed, entries are written straight away
+	logger.Record(&models.ComplianceLogEntry{Method: "DELETE", Route: "/api/v1/servers/:id"})
+	assert.Equal(t, 251, store.count())
+}
+
+func TestComplianceLogger_FullQueueWritesDirectly(t *testing.T) {
+	store := &fakeComplianceStore{}
+	logger := NewComplianceLogger(store)
+	logger.queue = make(chan *models.ComplianceLogEntry, 1)
+
+	// Nothing drains the queue, so the second entry can't wait
+	logger.Record(&models.ComplianceLogEntry{Method: "POST"})
+	logger.Record(&models.ComplianceLogEntry{Method: "PATCH"})
+	require.Equal(t, 1, store.count())
+	assert.Equal(t, "PATCH", store.entries[0].Method)
+
+	go logger.Run()
+	logger.Close()
+	assert.Equal(t, 2, store.count())
+}
+
+func TestComplianceLogger_ExportAndPurge(t *testing.T) {
+	store := &fakeComplianceStore{}
+	logger := NewComplianceLogger(store)
+	go logger.Run()
+	actor := uuid.New()
+	logger.Record(&models.ComplianceLogEntry{Method: "POST", ActorID: &actor})
+	logger.Record(&models.ComplianceLogEntry{Method: "POST"})
+	logger.Close()
+
+	var exported int
+	require.NoError(t, logger.Export(context.Background(), models.ComplianceLogFilter{ActorID: &actor}, func(*models.ComplianceLogEntry) error {
+		exported++
+		return nil
+	}))
+	assert.Equal(t, 1, exported)
+
+	stop := errors.New("stop")
+	assert.ErrorIs(t, logger.Export(context.Background(), models.ComplianceLogFilter{}, func(*models.ComplianceLogEntry) error {
+		return stop
+	}), stop)
+
+	now := time.Now()
+	_, err := logger.Purge(context.Background(), now, 30*24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-30*24*time.Hour), store.cutoff)
+}
+
+func TestComplianceOutcomeFor(t *testing.T) {
+	assert.Equal(t, models.ComplianceOutcomeSuccess, models.ComplianceOutcomeFor(204))
+	assert.Equal(t, models.ComplianceOutcomeDenied, models.ComplianceOutcomeFor(401))
+	assert.Equal(t, models.ComplianceOutcomeDenied, models.ComplianceOutcomeFor(403))
+	assert.Equal(t, models.ComplianceOutcomeInvalid, models.ComplianceOutcomeFor(404))
+	assert.Equal(t, models.ComplianceOutcomeError, models.ComplianceOutcomeFor(503))
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	// contentErasureBatch is how many messages are scrubbed at a time
+	contentErasureBatch = 500
+	// contentErasureLease is how long an instance holds an erasure it's
+	// working on before another may pick it up
+	contentErasureLease = time.Minute
+)
+
+// ContentErasureRepository queues content erasures and scrubs users'
+// content batch by batch
+type ContentErasureRepository interface {
+	// CreateContentErasure returns ErrContentErasureInProgress if the user
+	// already has an unfinished erasure
+	CreateContentErasure(ctx context.Context, job *models.ContentErasure) error
+	// GetContentErasure returns nil if there's no erasure with that ID
+	GetContentErasure(ctx context.Context, id uuid.UUID) (*models.ContentErasure, error)
+	// ListContentErasures returns the user's erasures, newest first
+	ListContentErasures(ctx context.Context, userID uuid.UUID) ([]*models.ContentErasure, error)
+	// ClaimContentErasure holds the oldest unfinished erasure nobody else
+	// holds until until and returns it, or nil if there's nothing to do
+	ClaimContentErasure(ctx context.Context, now, until time.Time) (*models.ContentErasure, error)
+	// ScrubUserContent clears up to limit of the user's messages, thread
+	// replies and forwarded copies of their messages, removing attachments
+	ScrubUserContent(ctx context.Context, userID uuid.UUID, limit int) (models.ContentScrubCounts, error)
+	// CountUserContent counts the user's content scrubbing would still clear
+	CountUserContent(ctx context.Context, userID uuid.UUID) (int, error)
+	// SaveContentErasureProgress stores the erasure's status and counters
+	// and releases it
+	SaveContentErasureProgress(ctx context.Context, job *models.ContentErasure) error
+}
+
+// ContentErasureService erases everything a user wrote for users who asked
+// to be forgotten. Message content is cleared rather than the messages
+// deleted, so replies and threads around them stay intact. Search reads
+// message content directly, so erased messages stop matching as soon as
+// they're scrubbed.
+type ContentErasureService struct {
+	repo ContentErasureRepository
+}
+
+// NewContentErasureService creates a new content erasure service
+func NewContentErasureService(repo ContentErasureRepository) *ContentErasureService {
+	return &ContentErasureService{repo: repo}
+}
+
+// RequestErasure queues erasing the user's content and returns the queued
+// erasure. The user needn't still exist.
+func (s *ContentErasureService) RequestErasure(ctx context.Context, userID, requestedBy uuid.UUID) (*models.ContentErasure, error) {
+	now := time.Now()
+	job := &models.ContentErasure{
+		ID:          uuid.New(),
+		UserID:      userID,
+		RequestedBy: requestedBy,
+		Status:      models.ContentErasureQueued,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.repo.CreateContentErasure(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// GetErasure returns an erasure's progress and verification
+func (s *ContentErasureService) GetErasure(ctx context.Context, id uuid.UUID) (*models.ContentErasure, error) {
+	job, err := s.repo.GetContentErasure(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrContentErasureNotFound
+	}
+	return job, nil
+}
+
+// ListErasures returns every erasure of the user's content, newest first
+func (s *ContentErasureService) ListErasures(ctx context.Context, userID uuid.UUID) ([]*models.ContentErasure, error) {
+	return s.repo.ListContentErasures(ctx, userID)
+}
+
+// ProcessErasures scrubs the next batch of the oldest unfinished erasure.
+// Once a batch comes up short it checks nothing is left: the erasure is
+// verified if so, and fails if what's left can't be scrubbed. It reports
+// whether there was an erasure to work on.
+func (s *ContentErasureService) ProcessErasures(ctx context.Context, now time.Time) (bool, error) {
+	job, err := s.repo.ClaimContentErasure(ctx, now, now.Add(contentErasureLease))
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	counts, err := s.repo.ScrubUserContent(ctx, job.UserID, contentErasureBatch)
+	if err != nil {
+		return true, err
+	}
+	job.MessagesScrubbed += counts.Messages
+	job.ForwardsScrubbed += counts.Forwards
+	job.AttachmentsRemoved += counts.Attachments
+	job.UpdatedAt = now
+	if counts.Total() >= contentErasureBatch {
+		return true, s.repo.SaveContentErasureProgress(ctx, job)
+	}
+
+	remaining, err := s.repo.CountUserContent(ctx, job.UserID)
+	if err != nil {
+		return true, err
+	}
+	job.Remaining = remaining
+	switch {
+	case remaining == 0:
+		job.Status = models.ContentErasureVerified
+		job.VerifiedAt = &now
+	case counts.Total() == 0:
+		reason := fmt.Sprintf("%d messages could not be scrubbed", remaining)
+		job.Status = models.ContentErasureFailed
+		job.Error = &reason
+	default:
+		// The user wrote more while the batch ran; keep going
+		return true, s.repo.SaveContentErasureProgress(ctx, job)
+	}
+	job.CompletedAt = &now
+	return true, s.repo.SaveContentErasureProgress(ctx, job)
+}
+
+// RunErasures works through queued erasures every interval until ctx is
+// done
+func (s *ContentErasureService) RunErasures(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				more, err := s.ProcessErasures(ctx, time.Now())
+				if err != nil {
+					log.Printf("[ContentErasure] failed to erase content: %v", err)
+					break
+				}
+				if !more {
+					break
+				}
+			}
+		}
+	}
+}
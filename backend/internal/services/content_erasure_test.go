@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+// fakeContentErasureRepo holds one user's content as counts of what's left
+// to scrub
+type fakeContentErasureRepo struct {
+	jobs        map[uuid.UUID]*models.ContentErasure
+	messages    int
+	forwards    int
+	stuck       int // Content that scrubbing can't clear
+	attachments int
+}
+
+func newFakeContentErasureRepo() *fakeContentErasureRepo {
+	return &fakeContentErasureRepo{jobs: make(map[uuid.UUID]*models.ContentErasure)}
+}
+
+func (r *fakeContentErasureRepo) CreateContentErasure(ctx context.Context, job *models.ContentErasure) error {
+	for _, existing := range r.jobs {
+		if existing.UserID == job.UserID && !existing.Status.Finished() {
+			return ErrContentErasureInProgress
+		}
+	}
+	copied := *job
+	r.jobs[job.ID] = &copied
+	return nil
+}
+
+func (r *fakeContentErasureRepo) GetContentErasure(ctx context.Context, id uuid.UUID) (*models.ContentErasure, error) {
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *job
+	return &copied, nil
+}
+
+func (r *fakeContentErasureRepo) ListContentErasures(ctx context.Context, userID uuid.UUID) ([]*models.ContentErasure, error) {
+	var jobs []*models.ContentErasure
+	for _, job := range r.jobs {
+		if job.UserID == userID {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func (r *fakeContentErasureRepo) ClaimContentErasure(ctx context.Context, now, until time.Time) (*models.ContentErasure, error) {
+	for _, job := range r.jobs {
+		if !job.Status.Finished() {
+			job.Status = models.ContentErasureRunning
+			copied := *job
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeContentErasureRepo) ScrubUserContent(ctx context.Context, userID uuid.UUID, limit int) (models.ContentScrubCounts, error) {
+	var counts models.ContentScrubCounts
+	counts.Messages = min(r.messages, limit)
+	counts.Forwards = min(r.forwards, limit-counts.Messages)
+	counts.Attachments = min(r.attachments, counts.Messages)
+	r.messages -= counts.Messages
+	r.forwards -= counts.Forwards
+	r.attachments -= counts.Attachments
+	return counts, nil
+}
+
+func (r *fakeContentErasureRepo) CountUserContent(ctx context.Context, userID uuid.UUID) (int, error) {
+	return r.messages + r.forwards + r.stuck, nil
+}
+
+func (r *fakeContentErasureRepo) SaveContentErasureProgress(ctx context.Context, job *models.ContentErasure) error {
+	copied := *job
+	r.jobs[job.ID] = &copied
+	return nil
+}
+
+func TestContentErasureService_ScrubsAndVerifies(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeContentErasureRepo()
+	repo.messages = contentErasureBatch + 20
+	repo.forwards = 5
+	repo.attachments = 3
+	svc := NewContentErasureService(repo)
+	userID, adminID := uuid.New(), uuid.New()
+
+	job, err := svc.RequestErasure(ctx, userID, adminID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ContentErasureQueued, job.Status)
+	_, err = svc.RequestErasure(ctx, userID, adminID)
+	assert.ErrorIs(t, err, ErrContentErasureInProgress)
+
+	now := time.Now()
+	more, err := svc.ProcessErasures(ctx, now)
+	require.NoError(t, err)
+	assert.True(t, more)
+	job, err = svc.GetErasure(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ContentErasureRunning, job.Status, "a full batch means there may be more")
+
+	_, err = svc.ProcessErasures(ctx, now)
+	require.NoError(t, err)
+	job, err = svc.GetErasure(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ContentErasureVerified, job.Status)
+	assert.Equal(t, contentErasureBatch+20, job.MessagesScrubbed)
+	assert.Equal(t, 5, job.ForwardsScrubbed)
+	assert.Equal(t, 3, job.AttachmentsRemoved)
+	assert.Zero(t, job.Remaining)
+	assert.NotNil(t, job.VerifiedAt)
+	assert.NotNil(t, job.CompletedAt)
+
+	more, err = svc.ProcessErasures(ctx, now)
+	require.NoError(t, err)
+	assert.False(t, more)
+
+	// Finished erasures don't stop the user being erased again
+	_, err = svc.RequestErasure(ctx, userID, adminID)
+	assert.NoError(t, err)
+}
+
+func TestContentErasureService_FailsVerification(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeContentErasureRepo()
+	repo.messages = 2
+	repo.stuck = 1
+	svc := NewContentErasureService(repo)
+
+	job, err := svc.RequestErasure(ctx, uuid.New(), uuid.New())
+	require.NoError(t, err)
+
+	// The first pass scrubs what it can, the second finds nothing to
+	// scrub but content left
+	for i := 0; i < 2; i++ {
+		_, err = svc.ProcessErasures(ctx, time.Now())
+		require.NoError(t, err)
+	}
+	job, err = svc.GetErasure(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ContentErasureFailed, job.Status)
+	assert.Equal(t, 1, job.Remaining)
+	require.NotNil(t, job.Error)
+	assert.Nil(t, job.VerifiedAt)
+
+	_, err = svc.GetErasure(ctx, uuid.New())
+	assert.ErrorIs(t, err, ErrContentErasureNotFound)
+}
@@ -0,0 +1,572 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	// MaxE2EEDevices is how many devices a user can register keys for
+	MaxE2EEDevices = 10
+	// MaxOneTimePreKeys is how many unclaimed one-time pre-keys a device
+	// can hold
+	MaxOneTimePreKeys = 100
+	// MaxSenderKeysPerRequest is how many recipient devices one sender key
+	// upload can address; bigger channels are sent in batches
+	MaxSenderKeysPerRequest = 1000
+
+	maxE2EEKeyLength   = 1024
+	maxSenderKeyLength = 4096
+	// encryptedLengthFactor allows for an envelope's base64 and framing
+	// on top of the plaintext length limit
+	encryptedLengthFactor = 2
+	// e2eeRotateTimeout bounds rotations started by gateway events
+	e2eeRotateTimeout = 10 * time.Second
+)
+
+// Reasons a channel's keys were rotated
+const (
+	KeyRotationMemberJoined  = "member_joined"
+	KeyRotationMemberLeft    = "member_left"
+	KeyRotationDeviceRemoved = "device_removed"
+)
+
+// E2EERepository stores device keys, key epochs and sender keys. The
+// Postgres E2EERepository implements it.
+type E2EERepository interface {
+	// GetDevices returns a user's devices, oldest first
+	GetDevices(ctx context.Context, userID uuid.UUID) ([]*models.E2EEDevice, error)
+	// GetDevice returns nil if the user has no such device
+	GetDevice(ctx context.Context, userID uuid.UUID, deviceID string) (*models.E2EEDevice, error)
+	// SaveDevice creates a device or updates its signed pre-key
+	SaveDevice(ctx context.Context, device *models.E2EEDevice) error
+	// DeleteDevice removes a device with its one-time pre-keys and the
+	// sender keys sent to it, and reports whether the user had it
+	DeleteDevice(ctx context.Context, userID uuid.UUID, deviceID string) (bool, error)
+	// AddPreKeys stores one-time pre-keys, skipping IDs the device has
+	AddPreKeys(ctx context.Context, userID uuid.UUID, deviceID string, keys []models.OneTimePreKey) error
+	// ClaimPreKey removes and returns one of a device's one-time pre-keys,
+	// or nil if it has none left
+	ClaimPreKey(ctx context.Context, userID uuid.UUID, deviceID string) (*models.OneTimePreKey, error)
+
+	// GetEpoch returns a channel's key epoch, 0 if it was never rotated
+	GetEpoch(ctx context.Context, channelID uuid.UUID) (int64, error)
+	// RotateEpoch moves a channel's key epoch on and returns the new one
+	RotateEpoch(ctx context.Context, channelID uuid.UUID, at time.Time) (int64, error)
+	// SaveSenderKeys stores sender keys, replacing what the same sender
+	// device sent the same recipient device for the epoch
+	SaveSenderKeys(ctx context.Context, keys []*models.SenderKey) error
+	// GetSenderKeys returns the sender keys sent to a device in a channel,
+	// oldest epoch first
+	GetSenderKeys(ctx context.Context, channelID, recipientID uuid.UUID, recipientDeviceID string) ([]*models.SenderKey, error)
+}
+
+// E2EEKeyService relays the keys end-to-end encrypted channels need
+// without being able to read them. Devices publish identity and pre-keys
+// so others can open pairwise sessions with them, then hand their sender
+// key for each channel to every member device over those sessions.
+// Whenever a channel's membership changes its key epoch moves on, and
+// members must send new sender keys before they can post again. It
+// implements E2EEService.
+type E2EEKeyService struct {
+	repo        E2EERepository
+	channelRepo ChannelRepository
+	serverRepo  ServerRepository
+	eventBus    EventBus
+	now         func() time.Time
+
+	channelPermissions ChannelPermissionChecker
+}
+
+// NewE2EEKeyService creates a new E2EE key service
+func NewE2EEKeyService(repo E2EERepository, channelRepo ChannelRepository, serverRepo ServerRepository, eventBus EventBus) *E2EEKeyService {
+	return &E2EEKeyService{
+		repo:        repo,
+		channelRepo: channelRepo,
+		serverRepo:  serverRepo,
+		eventBus:    eventBus,
+		now:         time.Now,
+	}
+}
+
+// SetChannelPermissions limits sender keys to members with VIEW_CHANNELS.
+// Without it any server member can send and receive them.
+func (s *E2EEKeyService) SetChannelPermissions(permissions ChannelPermissionChecker) {
+	s.channelPermissions = permissions
+}
+
+// Start rotates the keys of a server's encrypted channels whenever someone
+// joins or leaves it
+func (s *E2EEKeyService) Start(eventBus EventBus) {
+	eventBus.Subscribe("server.member_joined", s.handleMembershipChanged)
+	eventBus.Subscribe("server.member_left", s.handleMembershipChanged)
+	eventBus.Subscribe("server.member_kicked", s.handleMembershipChanged)
+	eventBus.Subscribe("server.member_banned", s.handleMembershipChanged)
+}
+
+// ListDevices returns a user's devices
+func (s *E2EEKeyService) ListDevices(ctx context.Context, userID uuid.UUID) ([]*models.E2EEDevice, error) {
+	return s.repo.GetDevices(ctx, userID)
+}
+
+// RegisterDevice publishes a device's keys. Re-registering with the same
+// identity key replaces its signed pre-key; a new identity key makes it a
+// new device, so its old pre-keys and sender keys are dropped and the
+// owner's channels rotated.
+func (s *E2EEKeyService) RegisterDevice(ctx context.Context, userID uuid.UUID, deviceID string, req *models.RegisterDeviceKeysRequest) (*models.E2EEDevice, error) {
+	if !models.ValidDeviceID(deviceID) {
+		return nil, invalidDeviceKeys("device_id must be 1-64 letters, numbers, underscores or dashes")
+	}
+	if !validE2EEKey(req.IdentityKey) || !validE2EEKey(req.SignedPreKey) || !validE2EEKey(req.SignedKeySign) {
+		return nil, invalidDeviceKeys("keys and signatures must be base64, at most %d characters", maxE2EEKeyLength)
+	}
+	if req.SignedPreKeyID < 0 {
+		return nil, invalidDeviceKeys("key ids can't be negative")
+	}
+	if err := validatePreKeys(req.PreKeys, 0); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.GetDevice(ctx, userID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	device := &models.E2EEDevice{
+		UserID:         userID,
+		DeviceID:       deviceID,
+		IdentityKey:    req.IdentityKey,
+		SignedPreKeyID: req.SignedPreKeyID,
+		SignedPreKey:   req.SignedPreKey,
+		SignedKeySign:  req.SignedKeySign,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	replaced := existing != nil && existing.IdentityKey != req.IdentityKey
+	switch {
+	case existing == nil:
+		devices, err := s.repo.GetDevices(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if len(devices) >= MaxE2EEDevices {
+			return nil, ErrTooManyE2EEDevices
+		}
+	case replaced:
+		if _, err := s.repo.DeleteDevice(ctx, userID, deviceID); err != nil {
+			return nil, err
+		}
+	default:
+		device.CreatedAt = existing.CreatedAt
+		device.PreKeyCount = existing.PreKeyCount
+		if err := validatePreKeys(req.PreKeys, existing.PreKeyCount); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.SaveDevice(ctx, device); err != nil {
+		return nil, err
+	}
+	if len(req.PreKeys) > 0 {
+		if err := s.repo.AddPreKeys(ctx, userID, deviceID, req.PreKeys); err != nil {
+			return nil, err
+		}
+	}
+	if replaced {
+		s.rotateUserChannels(ctx, userID, KeyRotationDeviceRemoved)
+	}
+	return s.device(ctx, userID, deviceID)
+}
+
+// UploadPreKeys adds one-time pre-keys to one of the user's devices
+func (s *E2EEKeyService) UploadPreKeys(ctx context.Context, userID uuid.UUID, deviceID string, req *models.UploadPreKeysRequest) (*models.E2EEDevice, error) {
+	device, err := s.device(ctx, userID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.PreKeys) == 0 {
+		return nil, invalidDeviceKeys("pre_keys can't be empty")
+	}
+	if err := validatePreKeys(req.PreKeys, device.PreKeyCount); err != nil {
+		return nil, err
+	}
+	if err := s.repo.AddPreKeys(ctx, userID, deviceID, req.PreKeys); err != nil {
+		return nil, err
+	}
+	return s.device(ctx, userID, deviceID)
+}
+
+// RemoveDevice deletes one of the user's devices. Its sender keys may
+// have leaked with it, so every encrypted channel the user is in is
+// rotated.
+func (s *E2EEKeyService) RemoveDevice(ctx context.Context, userID uuid.UUID, deviceID string) error {
+	deleted, err := s.repo.DeleteDevice(ctx, userID, deviceID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrE2EEDeviceNotFound
+	}
+	s.rotateUserChannels(ctx, userID, KeyRotationDeviceRemoved)
+	return nil
+}
+
+// ClaimPreKeyBundles returns a bundle for each of a user's devices to open
+// sessions with. Each bundle uses up one of its device's one-time
+// pre-keys while they last.
+func (s *E2EEKeyService) ClaimPreKeyBundles(ctx context.Context, userID uuid.UUID) ([]*models.PreKeyBundle, error) {
+	devices, err := s.repo.GetDevices(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	bundles := make([]*models.PreKeyBundle, 0, len(devices))
+	for _, device := range devices {
+		bundle := &models.PreKeyBundle{
+			UserID:         device.UserID,
+			DeviceID:       device.DeviceID,
+			IdentityKey:    device.IdentityKey,
+			SignedPreKeyID: device.SignedPreKeyID,
+			SignedPreKey:   device.SignedPreKey,
+			SignedKeySign:  device.SignedKeySign,
+		}
+		preKey, err := s.repo.ClaimPreKey(ctx, device.UserID, device.DeviceID)
+		if err != nil {
+			return nil, err
+		}
+		if preKey != nil {
+			bundle.PreKeyID = &preKey.KeyID
+			bundle.PreKey = preKey.PublicKey
+		}
+		bundles = append(bundles, bundle)
+	}
+	return bundles, nil
+}
+
+// GetChannelKeys returns an encrypted channel's current epoch and the
+// sender keys sent to one of the requester's devices
+func (s *E2EEKeyService) GetChannelKeys(ctx context.Context, channelID, requesterID uuid.UUID, deviceID string) (*models.ChannelKeys, error) {
+	if _, err := s.encryptedChannel(ctx, channelID, requesterID); err != nil {
+		return nil, err
+	}
+	if _, err := s.device(ctx, requesterID, deviceID); err != nil {
+		return nil, err
+	}
+	epoch, err := s.repo.GetEpoch(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := s.repo.GetSenderKeys(ctx, channelID, requesterID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		keys = []*models.SenderKey{}
+	}
+	return &models.ChannelKeys{Epoch: epoch, SenderKeys: keys}, nil
+}
+
+// DistributeSenderKeys stores the requester's sender key for the channel's
+// current epoch, encrypted for each recipient device. Every recipient
+// must be able to read the channel.
+func (s *E2EEKeyService) DistributeSenderKeys(ctx context.Context, channelID, requesterID uuid.UUID, req *models.DistributeSenderKeysRequest) error {
+	channel, err := s.encryptedChannel(ctx, channelID, requesterID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.device(ctx, requesterID, req.DeviceID); err != nil {
+		return err
+	}
+	if len(req.Keys) == 0 || len(req.Keys) > MaxSenderKeysPerRequest {
+		return invalidSenderKeys("keys must have 1-%d entries", MaxSenderKeysPerRequest)
+	}
+	epoch, err := s.repo.GetEpoch(ctx, channelID)
+	if err != nil {
+		return err
+	}
+	if req.Epoch != epoch {
+		return ErrKeyEpochStale
+	}
+
+	now := s.now()
+	devices := make(map[uuid.UUID]map[string]bool)
+	keys := make([]*models.SenderKey, 0, len(req.Keys))
+	var recipients []uuid.UUID
+	for _, envelope := range req.Keys {
+		if !models.ValidBase64(envelope.Ciphertext) || len(envelope.Ciphertext) > maxSenderKeyLength {
+			return invalidSenderKeys("ciphertext must be base64, at most %d characters", maxSenderKeyLength)
+		}
+		owned, ok := devices[envelope.UserID]
+		if !ok {
+			if owned, err = s.recipientDevices(ctx, channel, envelope.UserID); err != nil {
+				return err
+			}
+			devices[envelope.UserID] = owned
+			recipients = append(recipients, envelope.UserID)
+		}
+		if !owned[envelope.DeviceID] {
+			return invalidSenderKeys("%s has no device %q that can read this channel", envelope.UserID, envelope.DeviceID)
+		}
+		keys = append(keys, &models.SenderKey{
+			ChannelID:         channelID,
+			Epoch:             epoch,
+			SenderID:          requesterID,
+			SenderDeviceID:    req.DeviceID,
+			RecipientID:       envelope.UserID,
+			RecipientDeviceID: envelope.DeviceID,
+			Ciphertext:        envelope.Ciphertext,
+			CreatedAt:         now,
+		})
+	}
+
+	if err := s.repo.SaveSenderKeys(ctx, keys); err != nil {
+		return err
+	}
+	s.eventBus.Publish("channel.sender_keys_distributed", &SenderKeysDistributedEvent{
+		ChannelID:      channelID,
+		Epoch:          epoch,
+		SenderID:       requesterID,
+		SenderDeviceID: req.DeviceID,
+		RecipientIDs:   recipients,
+	})
+	return nil
+}
+
+// CheckPayload checks that payload is an encrypted envelope sealed with
+// the channel's current key epoch
+func (s *E2EEKeyService) CheckPayload(ctx context.Context, channelID uuid.UUID, payload string) error {
+	envelope, err := models.ParseEncryptedPayload(payload)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidEncryptedPayload, err.Error())
+	}
+	epoch, err := s.repo.GetEpoch(ctx, channelID)
+	if err != nil {
+		return err
+	}
+	if envelope.Epoch != epoch {
+		return ErrKeyEpochStale
+	}
+	return nil
+}
+
+// checkEncrypted checks content sent to an encrypted channel. Without an
+// E2EE service only the envelope's format is checked.
+func (s *MessageService) checkEncrypted(ctx context.Context, channelID uuid.UUID, content string) error {
+	if s.e2eeService != nil {
+		return s.e2eeService.CheckPayload(ctx, channelID, content)
+	}
+	if _, err := models.ParseEncryptedPayload(content); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidEncryptedPayload, err.Error())
+	}
+	return nil
+}
+
+// rotate moves a channel's key epoch on and tells its members to send new
+// sender keys
+func (s *E2EEKeyService) rotate(ctx context.Context, channel *models.Channel, reason string) error {
+	epoch, err := s.repo.RotateEpoch(ctx, channel.ID, s.now())
+	if err != nil {
+		return err
+	}
+	s.eventBus.Publish("channel.keys_rotated", &ChannelKeysRotatedEvent{
+		ChannelID: channel.ID,
+		ServerID:  channel.ServerID,
+		Epoch:     epoch,
+		Reason:    reason,
+	})
+	return nil
+}
+
+// rotateServer rotates every encrypted channel in a server
+func (s *E2EEKeyService) rotateServer(ctx context.Context, serverID uuid.UUID, reason string) error {
+	channels, err := s.channelRepo.GetByServerID(ctx, serverID)
+	if err != nil {
+		return err
+	}
+	for _, channel := range channels {
+		if !channel.E2EEEnabled {
+			continue
+		}
+		if err := s.rotate(ctx, channel, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rotateUserChannels rotates every encrypted channel a user is in. It
+// logs rather than fails, since the change that called for it has already
+// been made.
+func (s *E2EEKeyService) rotateUserChannels(ctx context.Context, userID uuid.UUID, reason string) {
+	servers, err := s.serverRepo.GetUserServers(ctx, userID)
+	if err != nil {
+		log.Printf("[E2EE] failed to list servers of %s to rotate: %v", userID, err)
+	}
+	for _, server := range servers {
+		if err := s.rotateServer(ctx, server.ID, reason); err != nil {
+			log.Printf("[E2EE] failed to rotate keys in server %s: %v", server.ID, err)
+		}
+	}
+
+	dms, err := s.channelRepo.GetUserDMs(ctx, userID)
+	if err != nil {
+		log.Printf("[E2EE] failed to list DMs of %s to rotate: %v", userID, err)
+	}
+	for _, channel := range dms {
+		if !channel.E2EEEnabled {
+			continue
+		}
+		if err := s.rotate(ctx, channel, reason); err != nil {
+			log.Printf("[E2EE] failed to rotate keys in channel %s: %v", channel.ID, err)
+		}
+	}
+}
+
+func (s *E2EEKeyService) handleMembershipChanged(data interface{}) {
+	var serverID uuid.UUID
+	reason := KeyRotationMemberLeft
+	switch event := data.(type) {
+	case *MemberJoinedEvent:
+		serverID, reason = event.ServerID, KeyRotationMemberJoined
+	case *MemberLeftEvent:
+		serverID = event.ServerID
+	case *MemberKickedEvent:
+		serverID = event.ServerID
+	case *MemberBannedEvent:
+		serverID = event.ServerID
+	}
+	if serverID == uuid.Nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e2eeRotateTimeout)
+	defer cancel()
+	if err := s.rotateServer(ctx, serverID, reason); err != nil {
+		log.Printf("[E2EE] failed to rotate keys in server %s: %v", serverID, err)
+	}
+}
+
+// encryptedChannel returns an encrypted channel the user can read
+func (s *E2EEKeyService) encryptedChannel(ctx context.Context, channelID, userID uuid.UUID) (*models.Channel, error) {
+	channel, err := s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if channel == nil {
+		return nil, ErrChannelNotFound
+	}
+	canRead, err := s.canRead(ctx, channel, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !canRead {
+		return nil, ErrNotChannelMember
+	}
+	if !channel.E2EEEnabled {
+		return nil, ErrChannelNotEncrypted
+	}
+	return channel, nil
+}
+
+// canRead reports whether a user can read a channel: DMs are limited to
+// their recipients and server channels to members who can view them
+func (s *E2EEKeyService) canRead(ctx context.Context, channel *models.Channel, userID uuid.UUID) (bool, error) {
+	if channel.ServerID == nil {
+		return isChannelParticipant(channel, userID), nil
+	}
+	member, err := s.serverRepo.GetMember(ctx, *channel.ServerID, userID)
+	if err != nil {
+		return false, err
+	}
+	if member == nil {
+		return false, nil
+	}
+	if s.channelPermissions == nil {
+		return true, nil
+	}
+	return s.channelPermissions.HasChannelPermission(ctx, channel.ID, userID, models.PermViewChannels)
+}
+
+// recipientDevices returns the devices of a user who can read the channel,
+// or none if they can't
+func (s *E2EEKeyService) recipientDevices(ctx context.Context, channel *models.Channel, userID uuid.UUID) (map[string]bool, error) {
+	owned := make(map[string]bool)
+	canRead, err := s.canRead(ctx, channel, userID)
+	if err != nil || !canRead {
+		return owned, err
+	}
+	devices, err := s.repo.GetDevices(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, device := range devices {
+		owned[device.DeviceID] = true
+	}
+	return owned, nil
+}
+
+// device returns one of the user's devices
+func (s *E2EEKeyService) device(ctx context.Context, userID uuid.UUID, deviceID string) (*models.E2EEDevice, error) {
+	device, err := s.repo.GetDevice(ctx, userID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if device == nil {
+		return nil, ErrE2EEDeviceNotFound
+	}
+	return device, nil
+}
+
+func validE2EEKey(key string) bool {
+	return len(key) <= maxE2EEKeyLength && models.ValidBase64(key)
+}
+
+// validatePreKeys checks one-time pre-keys being added to a device that
+// already holds held of them
+func validatePreKeys(keys []models.OneTimePreKey, held int) error {
+	if held+len(keys) > MaxOneTimePreKeys {
+		return invalidDeviceKeys("a device can hold at most %d one-time pre-keys", MaxOneTimePreKeys)
+	}
+	seen := make(map[int]bool, len(keys))
+	for _, key := range keys {
+		if key.KeyID < 0 || seen[key.KeyID] {
+			return invalidDeviceKeys("pre-key ids must be unique and not negative")
+		}
+		if !validE2EEKey(key.PublicKey) {
+			return invalidDeviceKeys("pre-keys must be base64, at most %d characters", maxE2EEKeyLength)
+		}
+		seen[key.KeyID] = true
+	}
+	return nil
+}
+
+func invalidDeviceKeys(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidDeviceKeys, fmt.Sprintf(format, args...))
+}
+
+func invalidSenderKeys(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidSenderKeys, fmt.Sprintf(format, args...))
+}
+
+// ChannelKeysRotatedEvent tells a channel's members its key epoch moved on
+type ChannelKeysRotatedEvent struct {
+	ChannelID uuid.UUID
+	ServerID  *uuid.UUID
+	Epoch     int64
+	Reason    string
+}
+
+// SenderKeysDistributedEvent tells recipients a device sent them its
+// sender key for a channel
+type SenderKeysDistributedEvent struct {
+	ChannelID      uuid.UUID
+	Epoch          int64
+	SenderID       uuid.UUID
+	SenderDeviceID string
+	RecipientIDs   []uuid.UUID
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeE2EERepository struct {
+	devices    map[string]*models.E2EEDevice
+	preKeys    map[string][]models.OneTimePreKey
+	epochs     map[uuid.UUID]int64
+	senderKeys []*models.SenderKey
+}
+
+func newFakeE2EERepository() *fakeE2EERepository {
+	return &fakeE2EERepository{
+		devices: make(map[string]*models.E2EEDevice),
+		preKeys: make(map[string][]models.OneTimePreKey),
+		epochs:  make(map[uuid.UUID]int64),
+	}
+}
+
+func e2eeDeviceKey(userID uuid.UUID, deviceID string) string {
+	return userID.String() + "/" + deviceID
+}
+
+func (f *fakeE2EERepository) GetDevices(ctx context.Context, userID uuid.UUID) ([]*models.E2EEDevice, error) {
+	devices := []*models.E2EEDevice{}
+	for _, device := range f.devices {
+		if device.UserID == userID {
+			copied := *device
+			copied.PreKeyCount = len(f.preKeys[e2eeDeviceKey(userID, device.DeviceID)])
+			devices = append(devices, &copied)
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].DeviceID < devices[j].DeviceID })
+	return devices, nil
+}
+
+func (f *fakeE2EERepository) GetDevice(ctx context.Context, userID uuid.UUID, deviceID string) (*models.E2EEDevice, error) {
+	device, ok := f.devices[e2eeDeviceKey(userID, deviceID)]
+	if !ok {
+		return nil, nil
+	}
+	copied := *device
+	copied.PreKeyCount = len(f.preKeys[e2eeDeviceKey(userID, deviceID)])
+	return &copied, nil
+}
+
+func (f *fakeE2EERepository) SaveDevice(ctx context.Context, device *models.E2EEDevice) error {
+	copied := *device
+	f.devices[e2eeDeviceKey(device.UserID, device.DeviceID)] = &copied
+	return nil
+}
+
+func (f *fakeE2EERepository) DeleteDevice(ctx context.Context, userID uuid.UUID, deviceID string) (bool, error) {
+	key := e2eeDeviceKey(userID, deviceID)
+	if _, ok := f.devices[key]; !ok {
+		return false, nil
+	}
+	delete(f.devices, key)
+	delete(f.preKeys, key)
+	kept := f.senderKeys[:0]
+	for _, senderKey := range f.senderKeys {
+		if senderKey.RecipientID != userID || senderKey.RecipientDeviceID != deviceID {
+			kept = append(kept, senderKey)
+		}
+	}
+	f.senderKeys = kept
+	return true, nil
+}
+
+func (f *fakeE2EERepository) AddPreKeys(ctx context.Context, userID uuid.UUID, deviceID string, keys []models.OneTimePreKey) error {
+	key := e2eeDeviceKey(userID, deviceID)
+	f.preKeys[key] = append(f.preKeys[key], keys...)
+	sort.Slice(f.preKeys[key], func(i, j int) bool { return f.preKeys[key][i].KeyID < f.preKeys[key][j].KeyID })
+	return nil
+}
+
+func (f *fakeE2EERepository) ClaimPreKey(ctx context.Context, userID uuid.UUID, deviceID string) (*models.OneTimePreKey, error) {
+	key := e2eeDeviceKey(userID, deviceID)
+	if len(f.preKeys[key]) == 0 {
+		return nil, nil
+	}
+	claimed := f.preKeys[key][0]
+	f.preKeys[key] = f.preKeys[key][1:]
+	return &claimed, nil
+}
+
+func (f *fakeE2EERepository) GetEpoch(ctx context.Context, channelID uuid.UUID) (int64, error) {
+	return f.epochs[channelID], nil
+}
+
+func (f *fakeE2EERepository) RotateEpoch(ctx context.Context, channelID uuid.UUID, at time.Time) (int64, error) {
+	f.epochs[channelID]++
+	return f.epochs[channelID], nil
+}
+
+func (f *fakeE2EERepository) SaveSenderKeys(ctx context.Context, keys []*models.SenderKey) error {
+	f.senderKeys = append(f.senderKeys, keys...)
+	return nil
+}
+
+func (f *fakeE2EERepository) GetSenderKeys(ctx context.Context, channelID, recipientID uuid.UUID, recipientDeviceID string) ([]*models.SenderKey, error) {
+	var keys []*models.SenderKey
+	for _, key := range f.senderKeys {
+		if key.ChannelID == channelID && key.RecipientID == recipientID && key.RecipientDeviceID == recipientDeviceID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+type e2eeTest struct {
+	service     *E2EEKeyService
+	repo        *fakeE2EERepository
+	channelRepo *MockChannelRepository
+	serverRepo  *MockServerRepository
+	eventBus    *MockEventBus
+	server      *models.Server
+	channel     *models.Channel
+	plain       *models.Channel
+	alice       uuid.UUID
+	bob         uuid.UUID
+	outsider    uuid.UUID
+}
+
+func newE2EETest() *e2eeTest {
+	serverID := uuid.New()
+	f := &e2eeTest{
+		repo:        newFakeE2EERepository(),
+		channelRepo: new(MockChannelRepository),
+		serverRepo:  new(MockServerRepository),
+		eventBus:    new(MockEventBus),
+		server:      &models.Server{ID: serverID},
+		channel:     &models.Channel{ID: uuid.New(), ServerID: &serverID, Type: models.ChannelTypeText, E2EEEnabled: true},
+		plain:       &models.Channel{ID: uuid.New(), ServerID: &serverID, Type: models.ChannelTypeText},
+		alice:       uuid.New(),
+		bob:         uuid.New(),
+		outsider:    uuid.New(),
+	}
+	f.channelRepo.On("GetByID", mock.Anything, f.channel.ID).Return(f.channel, nil)
+	f.channelRepo.On("GetByID", mock.Anything, f.plain.ID).Return(f.plain, nil)
+	f.channelRepo.On("GetByServerID", mock.Anything, serverID).Return([]*models.Channel{f.channel, f.plain}, nil)
+	f.channelRepo.On("GetUserDMs", mock.Anything, mock.Anything).Return([]*models.Channel{}, nil)
+	f.serverRepo.On("GetMember", mock.Anything, serverID, f.alice).Return(&models.Member{ServerID: serverID, UserID: f.alice}, nil)
+	f.serverRepo.On("GetMember", mock.Anything, serverID, f.bob).Return(&models.Member{ServerID: serverID, UserID: f.bob}, nil)
+	f.serverRepo.On("GetMember", mock.Anything, serverID, f.outsider).Return(nil, nil)
+	f.serverRepo.On("GetUserServers", mock.Anything, mock.Anything).Return([]*models.Server{f.server}, nil)
+	f.eventBus.On("Publish", mock.Anything, mock.Anything).Return()
+
+	f.service = NewE2EEKeyService(f.repo, f.channelRepo, f.serverRepo, f.eventBus)
+	return f
+}
+
+func e2eeKey(seed string) string {
+	return base64.StdEncoding.EncodeToString([]byte(seed))
+}
+
+func (f *e2eeTest) register(t *testing.T, userID uuid.UUID, deviceID string, preKeys ...int) *models.E2EEDevice {
+	req := &models.RegisterDeviceKeysRequest{
+		IdentityKey:    e2eeKey("identity-" + deviceID),
+		SignedPreKeyID: 1,
+		SignedPreKey:   e2eeKey("signed-" + deviceID),
+		SignedKeySign:  e2eeKey("signature-" + deviceID),
+	}
+	for _, keyID := range preKeys {
+		req.PreKeys = append(req.PreKeys, models.OneTimePreKey{KeyID: keyID, PublicKey: e2eeKey("pre-key")})
+	}
+	device, err := f.service.RegisterDevice(context.Background(), userID, deviceID, req)
+	require.NoError(t, err)
+	return device
+}
+
+func encryptedEnvelope(t *testing.T, epoch int64) string {
+	raw, err := json.Marshal(models.EncryptedPayload{
+		Version:    models.EncryptedPayloadVersion,
+		Epoch:      epoch,
+		DeviceID:   "laptop",
+		IV:         e2eeKey("iv"),
+		Ciphertext: e2eeKey("ciphertext"),
+	})
+	require.NoError(t, err)
+	return string(raw)
+}
+
+func TestE2EEKeyService_RegisterDevice(t *testing.T) {
+	f := newE2EETest()
+	ctx := context.Background()
+
+	device := f.register(t, f.alice, "laptop", 1, 2)
+	assert.Equal(t, 2, device.PreKeyCount)
+
+	// Same identity key: the signed pre-key is replaced and pre-keys kept
+	device = f.register(t, f.alice, "laptop", 3)
+	assert.Equal(t, 3, device.PreKeyCount)
+	f.eventBus.AssertNotCalled(t, "Publish", "channel.keys_rotated", mock.Anything)
+
+	// A new identity key is a new device
+	device, err := f.service.RegisterDevice(ctx, f.alice, "laptop", &models.RegisterDeviceKeysRequest{
+		IdentityKey:   e2eeKey("reinstalled"),
+		SignedPreKey:  e2eeKey("signed"),
+		SignedKeySign: e2eeKey("signature"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, device.PreKeyCount)
+	f.eventBus.AssertCalled(t, "Publish", "channel.keys_rotated", &ChannelKeysRotatedEvent{
+		ChannelID: f.channel.ID,
+		ServerID:  f.channel.ServerID,
+		Epoch:     1,
+		Reason:    KeyRotationDeviceRemoved,
+	})
+
+	for name, req := range map[string]*models.RegisterDeviceKeysRequest{
+		"not base64":       {IdentityKey: "not base64!", SignedPreKey: e2eeKey("s"), SignedKeySign: e2eeKey("s")},
+		"missing key":      {IdentityKey: e2eeKey("i"), SignedKeySign: e2eeKey("s")},
+		"duplicate prekey": {IdentityKey: e2eeKey("i"), SignedPreKey: e2eeKey("s"), SignedKeySign: e2eeKey("s"), PreKeys: []models.OneTimePreKey{{KeyID: 1, PublicKey: e2eeKey("p")}, {KeyID: 1, PublicKey: e2eeKey("p")}}},
+	} {
+		_, err := f.service.RegisterDevice(ctx, f.bob, "phone", req)
+		assert.ErrorIs(t, err, ErrInvalidDeviceKeys, name)
+	}
+	_, err = f.service.RegisterDevice(ctx, f.bob, "bad device!", &models.RegisterDeviceKeysRequest{})
+	assert.ErrorIs(t, err, ErrInvalidDeviceKeys)
+
+	for i := 0; i < MaxE2EEDevices; i++ {
+		f.register(t, f.bob, "device-"+string(rune('a'+i)))
+	}
+	_, err = f.service.RegisterDevice(ctx, f.bob, "one-too-many", &models.RegisterDeviceKeysRequest{
+		IdentityKey:   e2eeKey("i"),
+		SignedPreKey:  e2eeKey("s"),
+		SignedKeySign: e2eeKey("s"),
+	})
+	assert.ErrorIs(t, err, ErrTooManyE2EEDevices)
+}
+
+func TestE2EEKeyService_ClaimPreKeyBundles(t *testing.T) {
+	f := newE2EETest()
+	ctx := context.Background()
+	f.register(t, f.alice, "laptop", 7)
+	f.register(t, f.alice, "phone")
+
+	bundles, err := f.service.ClaimPreKeyBundles(ctx, f.alice)
+	require.NoError(t, err)
+	require.Len(t, bundles, 2)
+	assert.Equal(t, "laptop", bundles[0].DeviceID)
+	require.NotNil(t, bundles[0].PreKeyID)
+	assert.Equal(t, 7, *bundles[0].PreKeyID)
+	assert.Nil(t, bundles[1].PreKeyID, "the phone has no one-time pre-keys")
+
+	bundles, err = f.service.ClaimPreKeyBundles(ctx, f.alice)
+	require.NoError(t, err)
+	assert.Nil(t, bundles[0].PreKeyID, "one-time pre-keys are only handed out once")
+	assert.Equal(t, e2eeKey("signed-laptop"), bundles[0].SignedPreKey)
+}
+
+func TestE2EEKeyService_DistributeSenderKeys(t *testing.T) {
+	f := newE2EETest()
+	ctx := context.Background()
+	f.register(t, f.alice, "laptop")
+	f.register(t, f.bob, "phone")
+	f.register(t, f.outsider, "desktop")
+
+	send := func(epoch int64, keys ...models.SenderKeyEnvelope) error {
+		return f.service.DistributeSenderKeys(ctx, f.channel.ID, f.alice, &models.DistributeSenderKeysRequest{
+			DeviceID: "laptop",
+			Epoch:    epoch,
+			Keys:     keys,
+		})
+	}
+	toBob := models.SenderKeyEnvelope{UserID: f.bob, DeviceID: "phone", Ciphertext: e2eeKey("sender key")}
+
+	assert.ErrorIs(t, send(1, toBob), ErrKeyEpochStale)
+	assert.ErrorIs(t, send(0), ErrInvalidSenderKeys)
+	assert.ErrorIs(t, send(0, models.SenderKeyEnvelope{UserID: f.bob, DeviceID: "tablet", Ciphertext: e2eeKey("k")}), ErrInvalidSenderKeys)
+	assert.ErrorIs(t, send(0, models.SenderKeyEnvelope{UserID: f.outsider, DeviceID: "desktop", Ciphertext: e2eeKey("k")}), ErrInvalidSenderKeys,
+		"keys can't be sent to someone who can't read the channel")
+	assert.Empty(t, f.repo.senderKeys)
+
+	require.NoError(t, send(0, toBob))
+	f.eventBus.AssertCalled(t, "Publish", "channel.sender_keys_distributed", &SenderKeysDistributedEvent{
+		ChannelID:      f.channel.ID,
+		SenderID:       f.alice,
+		SenderDeviceID: "laptop",
+		RecipientIDs:   []uuid.UUID{f.bob},
+	})
+
+	keys, err := f.service.GetChannelKeys(ctx, f.channel.ID, f.bob, "phone")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), keys.Epoch)
+	require.Len(t, keys.SenderKeys, 1)
+	assert.Equal(t, f.alice, keys.SenderKeys[0].SenderID)
+	assert.Equal(t, e2eeKey("sender key"), keys.SenderKeys[0].Ciphertext)
+
+	_, err = f.service.GetChannelKeys(ctx, f.channel.ID, f.outsider, "desktop")
+	assert.ErrorIs(t, err, ErrNotChannelMember)
+	_, err = f.service.GetChannelKeys(ctx, f.plain.ID, f.bob, "phone")
+	assert.ErrorIs(t, err, ErrChannelNotEncrypted)
+	_, err = f.service.GetChannelKeys(ctx, f.channel.ID, f.bob, "tablet")
+	assert.ErrorIs(t, err, ErrE2EEDeviceNotFound)
+}
+
+func TestE2EEKeyService_RotatesOnMembershipChange(t *testing.T) {
+	f := newE2EETest()
+	ctx := context.Background()
+	assert.NoError(t, f.service.CheckPayload(ctx, f.channel.ID, encryptedEnvelope(t, 0)))
+
+	f.service.handleMembershipChanged(&MemberLeftEvent{ServerID: f.server.ID, UserID: f.bob})
+	assert.Equal(t, int64(1), f.repo.epochs[f.channel.ID])
+	assert.Zero(t, f.repo.epochs[f.plain.ID], "plain channels have no keys to rotate")
+	f.eventBus.AssertCalled(t, "Publish", "channel.keys_rotated", &ChannelKeysRotatedEvent{
+		ChannelID: f.channel.ID,
+		ServerID:  f.channel.ServerID,
+		Epoch:     1,
+		Reason:    KeyRotationMemberLeft,
+	})
+
+	assert.ErrorIs(t, f.service.CheckPayload(ctx, f.channel.ID, encryptedEnvelope(t, 0)), ErrKeyEpochStale)
+	assert.NoError(t, f.service.CheckPayload(ctx, f.channel.ID, encryptedEnvelope(t, 1)))
+	assert.ErrorIs(t, f.service.CheckPayload(ctx, f.channel.ID, "hello"), ErrInvalidEncryptedPayload)
+}
+
+func TestE2EEKeyService_RemoveDevice(t *testing.T) {
+	f := newE2EETest()
+	ctx := context.Background()
+	f.register(t, f.bob, "phone")
+
+	assert.ErrorIs(t, f.service.RemoveDevice(ctx, f.bob, "tablet"), ErrE2EEDeviceNotFound)
+	require.NoError(t, f.service.RemoveDevice(ctx, f.bob, "phone"))
+	assert.Equal(t, int64(1), f.repo.epochs[f.channel.ID])
+
+	devices, err := f.service.ListDevices(ctx, f.bob)
+	require.NoError(t, err)
+	assert.Empty(t, devices)
+}
@@ -0,0 +1,513 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"hearth/internal/models"
+)
+
+var (
+	ErrEmbedBlockedAddress = errors.New("embed target resolves to a disallowed address")
+	ErrEmbedUnsupported    = errors.New("embed target has no previewable content")
+	ErrEmbedTooManyHops    = errors.New("embed target redirected too many times")
+)
+
+// EmbedConfig controls link unfurling
+type EmbedConfig struct {
+	FetchTimeout     time.Duration // Per-URL fetch budget
+	CacheTTL         time.Duration // How long unfurled metadata is cached
+	NegativeCacheTTL time.Duration // How long "nothing to show" results are cached
+	MaxURLs          int           // URLs unfurled per message
+	MaxBodyBytes     int64         // Bytes of HTML read looking for metadata
+	MaxRedirects     int
+	UserAgent        string
+}
+
+// DefaultEmbedConfig returns the default unfurling configuration
+func DefaultEmbedConfig() *EmbedConfig {
+	return &EmbedConfig{
+		FetchTimeout:     3 * time.Second,
+		CacheTTL:         6 * time.Hour,
+		NegativeCacheTTL: 15 * time.Minute,
+		MaxURLs:          5,
+		MaxBodyBytes:     512 * 1024,
+		MaxRedirects:     3,
+		UserAgent:        "Mozilla/5.0 (compatible; HearthBot/1.0; +https://github.com/ghndrx/hearth)",
+	}
+}
+
+// EmbedService unfurls links in message content into embeds using
+// OpenGraph and Twitter card metadata
+type EmbedService struct {
+	cache  CacheService
+	client *http.Client
+	config *EmbedConfig
+}
+
+// NewEmbedService creates a new embed service. cache may be nil.
+func NewEmbedService(cache CacheService, config *EmbedConfig) *EmbedService {
+	if config == nil {
+		config = DefaultEmbedConfig()
+	}
+	return &EmbedService{
+		cache:  cache,
+		client: newSafeHTTPClient(config),
+		config: config,
+	}
+}
+
+// newSafeHTTPClient builds a client that refuses to connect to private,
+// loopback or link-local addresses. The check runs on the resolved IP at
+// dial time, so DNS rebinding and redirects to internal hosts are covered.
+func newSafeHTTPClient(config *EmbedConfig) *http.Client {
+	dialer := publicDialer(config.FetchTimeout, ErrEmbedBlockedAddress)
+
+	return &http.Client{
+		Timeout: config.FetchTimeout,
+		Transport: &http.Transport{
+			Proxy:                 nil, // Never route through an env proxy
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   config.FetchTimeout,
+			ResponseHeaderTimeout: config.FetchTimeout,
+			MaxIdleConns:          20,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > config.MaxRedirects {
+				return ErrEmbedTooManyHops
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return ErrEmbedBlockedAddress
+			}
+			return nil
+		},
+	}
+}
+
+// publicDialer returns a dialer that fails with blocked instead of
+// connecting to an address that isn't public
+func publicDialer(timeout time.Duration, blocked error) *net.Dialer {
+	return &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return blocked
+			}
+			return nil
+		},
+	}
+}
+
+// isPublicIP reports whether ip is routable on the public internet
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		switch {
+		case ip4[0] == 0: // "this" network
+			return false
+		case ip4[0] == 100 && ip4[1]&0xc0 == 64: // 100.64.0.0/10 carrier-grade NAT
+			return false
+		case ip4[0] == 192 && ip4[1] == 0 && ip4[2] == 0: // 192.0.0.0/24 IETF assignments
+			return false
+		case ip4[0] == 198 && ip4[1]&0xfe == 18: // 198.18.0.0/15 benchmarking
+			return false
+		case ip4[0] >= 240: // reserved and broadcast
+			return false
+		}
+	}
+	return true
+}
+
+// urlPattern matches http(s) links in message content
+var urlPattern = regexp.MustCompile(`<?https?://[^\s<>]+>?`)
+
+// ExtractURLs returns the distinct unfurlable URLs in content, in order.
+// Links wrapped in <angle brackets> are suppressed, as in Discord.
+func (s *EmbedService) ExtractURLs(content string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+
+	for _, match := range urlPattern.FindAllString(content, -1) {
+		if strings.HasPrefix(match, "<") && strings.HasSuffix(match, ">") {
+			continue
+		}
+		match = strings.TrimPrefix(match, "<")
+		match = strings.TrimSuffix(match, ">")
+		match = trimURLPunctuation(match)
+
+		u, err := url.Parse(match)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		normalized := u.String()
+		if seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+		urls = append(urls, normalized)
+
+		if len(urls) >= s.config.MaxURLs {
+			break
+		}
+	}
+
+	return urls
+}
+
+// trimURLPunctuation strips trailing sentence punctuation, keeping a closing
+// paren when the URL itself contains an opening one (e.g. Wikipedia links)
+func trimURLPunctuation(u string) string {
+	for len(u) > 0 {
+		last := u[len(u)-1]
+		switch {
+		case strings.IndexByte(".,;:!?'\"*_~", last) >= 0:
+			u = u[:len(u)-1]
+		case last == ')' && strings.Count(u, "(") < strings.Count(u, ")"):
+			u = u[:len(u)-1]
+		default:
+			return u
+		}
+	}
+	return u
+}
+
+// EmbedsForContent unfurls every URL in content concurrently. URLs that
+// fail or have nothing to show are skipped; order follows the content.
+func (s *EmbedService) EmbedsForContent(ctx context.Context, content string) []models.Embed {
+	urls := s.ExtractURLs(content)
+	if len(urls) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.FetchTimeout)
+	defer cancel()
+
+	results := make([]*models.Embed, len(urls))
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			if embed, err := s.Unfurl(ctx, u); err == nil {
+				results[i] = embed
+			}
+		}(i, u)
+	}
+	wg.Wait()
+
+	var embeds []models.Embed
+	for _, embed := range results {
+		if embed != nil {
+			embeds = append(embeds, *embed)
+		}
+	}
+	return embeds
+}
+
+// CachedEmbeds returns the embeds of the URLs in content that were unfurled
+// before, without fetching anything. complete reports whether every URL was
+// in the cache, counting those cached as having nothing to show.
+func (s *EmbedService) CachedEmbeds(ctx context.Context, content string) (embeds []models.Embed, complete bool) {
+	complete = true
+	for _, u := range s.ExtractURLs(content) {
+		embed, ok := s.cached(ctx, u)
+		if !ok {
+			complete = false
+			continue
+		}
+		if embed != nil {
+			embeds = append(embeds, *embed)
+		}
+	}
+	return embeds, complete
+}
+
+// Unfurl returns the embed for a single URL, consulting the cache first
+func (s *EmbedService) Unfurl(ctx context.Context, rawURL string) (*models.Embed, error) {
+	if embed, ok := s.cached(ctx, rawURL); ok {
+		if embed == nil {
+			return nil, ErrEmbedUnsupported
+		}
+		return embed, nil
+	}
+
+	key := embedCacheKey(rawURL)
+	embed, err := s.fetch(ctx, rawURL)
+
+	if s.cache != nil {
+		switch {
+		case err == nil:
+			if data, mErr := json.Marshal(embed); mErr == nil {
+				_ = s.cache.Set(ctx, key, data, s.config.CacheTTL)
+			}
+		case errors.Is(err, ErrEmbedUnsupported), errors.Is(err, ErrEmbedBlockedAddress):
+			// Remember misses so every message linking here doesn't refetch
+			_ = s.cache.Set(ctx, key, []byte("null"), s.config.NegativeCacheTTL)
+		}
+	}
+
+	return embed, err
+}
+
+// cached looks a URL up in the cache. ok is false on a miss; a nil embed
+// with ok set means the URL has nothing to show.
+func (s *EmbedService) cached(ctx context.Context, rawURL string) (*models.Embed, bool) {
+	if s.cache == nil {
+		return nil, false
+	}
+	data, err := s.cache.Get(ctx, embedCacheKey(rawURL))
+	if err != nil || len(data) == 0 {
+		return nil, false
+	}
+	var embed *models.Embed
+	if err := json.Unmarshal(data, &embed); err != nil {
+		return nil, false
+	}
+	return embed, true
+}
+
+func embedCacheKey(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return "embed:" + hex.EncodeToString(sum[:])
+}
+
+func (s *EmbedService) fetch(ctx context.Context, rawURL string) (*models.Embed, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", s.config.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,image/*;q=0.8")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrEmbedBlockedAddress) {
+			return nil, ErrEmbedBlockedAddress
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("embed fetch: unexpected status %d", resp.StatusCode)
+	}
+
+	finalURL := resp.Request.URL
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+
+	switch {
+	case strings.HasPrefix(mediaType, "image/"):
+		link := finalURL.String()
+		return &models.Embed{
+			Type:      "image",
+			URL:       &link,
+			Thumbnail: &models.EmbedMedia{URL: link},
+		}, nil
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		meta := parseEmbedMeta(io.LimitReader(resp.Body, s.config.MaxBodyBytes))
+		return meta.toEmbed(finalURL)
+	default:
+		return nil, ErrEmbedUnsupported
+	}
+}
+
+// embedMeta collects the metadata tags relevant to an embed
+type embedMeta struct {
+	title      string
+	props      map[string]string
+	themeColor string
+}
+
+// parseEmbedMeta scans the document head for <title> and <meta> tags,
+// stopping at <body> so large pages aren't tokenized in full
+func parseEmbedMeta(r io.Reader) *embedMeta {
+	meta := &embedMeta{props: make(map[string]string)}
+	z := html.NewTokenizer(r)
+	inTitle := false
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return meta
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "body":
+				return meta
+			case "title":
+				inTitle = meta.title == ""
+			case "meta":
+				if !hasAttr {
+					continue
+				}
+				var key, value string
+				for {
+					k, v, more := z.TagAttr()
+					switch string(k) {
+					case "property", "name":
+						if key == "" {
+							key = strings.ToLower(string(v))
+						}
+					case "content":
+						value = strings.TrimSpace(string(v))
+					}
+					if !more {
+						break
+					}
+				}
+				if key == "theme-color" {
+					meta.themeColor = value
+				} else if key != "" && value != "" {
+					if _, exists := meta.props[key]; !exists {
+						meta.props[key] = value
+					}
+				}
+			}
+		case html.TextToken:
+			if inTitle {
+				meta.title = strings.TrimSpace(string(z.Text()))
+				inTitle = false
+			}
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "head" {
+				return meta
+			}
+		}
+	}
+}
+
+// first returns the first non-empty property among keys
+func (m *embedMeta) first(keys ...string) string {
+	for _, key := range keys {
+		if v := m.props[key]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+const (
+	maxEmbedTitleLength       = 256
+	maxEmbedDescriptionLength = 350
+)
+
+func (m *embedMeta) toEmbed(pageURL *url.URL) (*models.Embed, error) {
+	title := truncateRunes(m.first("og:title", "twitter:title"), maxEmbedTitleLength)
+	if title == "" {
+		title = truncateRunes(m.title, maxEmbedTitleLength)
+	}
+	description := truncateRunes(m.first("og:description", "twitter:description", "description"), maxEmbedDescriptionLength)
+	image := resolveEmbedURL(pageURL, m.first("og:image:secure_url", "og:image", "twitter:image", "twitter:image:src"))
+
+	if title == "" && description == "" && image == "" {
+		return nil, ErrEmbedUnsupported
+	}
+
+	link := pageURL.String()
+	if canonical := resolveEmbedURL(pageURL, m.props["og:url"]); canonical != "" {
+		link = canonical
+	}
+
+	embed := &models.Embed{
+		Type: "link",
+		URL:  &link,
+	}
+	if title != "" {
+		embed.Title = &title
+	}
+	if description != "" {
+		embed.Description = &description
+	}
+	if siteName := m.first("og:site_name", "application-name"); siteName != "" {
+		embed.Provider = &models.EmbedProvider{Name: &siteName}
+	}
+	if color, ok := parseHexColor(m.themeColor); ok {
+		embed.Color = &color
+	}
+
+	switch ogType := m.props["og:type"]; {
+	case strings.HasPrefix(ogType, "video"):
+		embed.Type = "video"
+	case ogType == "article":
+		embed.Type = "article"
+	}
+
+	if image != "" {
+		media := &models.EmbedMedia{URL: image}
+		if w, err := strconv.Atoi(m.props["og:image:width"]); err == nil && w > 0 {
+			media.Width = &w
+		}
+		if h, err := strconv.Atoi(m.props["og:image:height"]); err == nil && h > 0 {
+			media.Height = &h
+		}
+		// Large cards render full-width; everything else as a thumbnail
+		if m.props["twitter:card"] == "summary_large_image" {
+			embed.Image = media
+		} else {
+			embed.Thumbnail = media
+		}
+	}
+
+	return embed, nil
+}
+
+// resolveEmbedURL resolves ref against the page URL, keeping only http(s)
+func resolveEmbedURL(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
+
+func parseHexColor(s string) (int, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	return int(v), true
+}
+
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:max-1])) + "…"
+}
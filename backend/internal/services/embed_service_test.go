@@ -0,0 +1,394 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+const testOGPage = `<!doctype html>
+<html><head>
+<title>Fallback title</title>
+<meta property="og:title" content="Hearth 1.0 released">
+<meta property="og:description" content="Self-hosted chat, now stable.">
+<meta property="og:image" content="/static/card.png">
+<meta property="og:image:width" content="1200">
+<meta property="og:site_name" content="Hearth Blog">
+<meta property="og:type" content="article">
+<meta name="twitter:card" content="summary_large_image">
+<meta name="theme-color" content="#ff6600">
+</head><body><meta property="og:title" content="ignored"></body></html>`
+
+// newTestEmbedService returns an embed service whose client can reach
+// httptest servers on loopback, bypassing the SSRF guard
+func newTestEmbedService(cache CacheService, srv *httptest.Server) *EmbedService {
+	s := NewEmbedService(cache, nil)
+	s.client = srv.Client()
+	return s
+}
+
+func TestEmbedService_ExtractURLs(t *testing.T) {
+	s := NewEmbedService(nil, nil)
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"none", "hello there", nil},
+		{"single", "see https://example.com/a", []string{"https://example.com/a"}},
+		{"trailing punctuation", "read https://example.com/post.", []string{"https://example.com/post"}},
+		{"balanced parens", "https://en.wikipedia.org/wiki/Go_(programming_language)", []string{"https://en.wikipedia.org/wiki/Go_(programming_language)"}},
+		{"wrapped in parens", "(https://example.com)", []string{"https://example.com"}},
+		{"suppressed", "no preview <https://example.com>", nil},
+		{"deduplicated", "https://example.com https://example.com", []string{"https://example.com"}},
+		{"non http scheme", "ftp://example.com javascript:alert(1)", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.ExtractURLs(tt.content))
+		})
+	}
+}
+
+func TestEmbedService_ExtractURLs_Limit(t *testing.T) {
+	s := NewEmbedService(nil, nil)
+
+	content := "https://a.example https://b.example https://c.example https://d.example https://e.example https://f.example"
+
+	assert.Len(t, s.ExtractURLs(content), 5)
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:4700::1111", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false}, // cloud metadata
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"255.255.255.255", false},
+		{"::1", false},
+		{"fc00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.public, isPublicIP(net.ParseIP(tt.ip)))
+		})
+	}
+}
+
+func TestEmbedService_Unfurl_OpenGraph(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(testOGPage))
+	}))
+	defer srv.Close()
+
+	s := newTestEmbedService(nil, srv)
+
+	embed, err := s.Unfurl(context.Background(), srv.URL+"/posts/1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "article", embed.Type)
+	assert.Equal(t, "Hearth 1.0 released", *embed.Title)
+	assert.Equal(t, "Self-hosted chat, now stable.", *embed.Description)
+	assert.Equal(t, srv.URL+"/posts/1", *embed.URL)
+	assert.Equal(t, "Hearth Blog", *embed.Provider.Name)
+	assert.Equal(t, 0xff6600, *embed.Color)
+	require.NotNil(t, embed.Image)
+	assert.Equal(t, srv.URL+"/static/card.png", embed.Image.URL)
+	assert.Equal(t, 1200, *embed.Image.Width)
+	assert.Nil(t, embed.Thumbnail)
+}
+
+func TestEmbedService_Unfurl_Image(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+	}))
+	defer srv.Close()
+
+	s := newTestEmbedService(nil, srv)
+
+	embed, err := s.Unfurl(context.Background(), srv.URL+"/cat.png")
+
+	require.NoError(t, err)
+	assert.Equal(t, "image", embed.Type)
+	assert.Equal(t, srv.URL+"/cat.png", embed.Thumbnail.URL)
+}
+
+func TestEmbedService_Unfurl_NoMetadata(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	cache := new(MockCacheService)
+	cache.On("Get", mock.Anything, mock.Anything).Return(nil, assert.AnError)
+	cache.On("Set", mock.Anything, mock.Anything, []byte("null"), DefaultEmbedConfig().NegativeCacheTTL).Return(nil)
+
+	s := newTestEmbedService(cache, srv)
+
+	embed, err := s.Unfurl(context.Background(), srv.URL)
+
+	assert.ErrorIs(t, err, ErrEmbedUnsupported)
+	assert.Nil(t, embed)
+	cache.AssertExpectations(t)
+}
+
+func TestEmbedService_Unfurl_BlocksPrivateAddresses(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer srv.Close()
+
+	// The default client must refuse loopback targets
+	s := NewEmbedService(nil, nil)
+
+	embed, err := s.Unfurl(context.Background(), srv.URL)
+
+	assert.ErrorIs(t, err, ErrEmbedBlockedAddress)
+	assert.Nil(t, embed)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&hits))
+}
+
+func TestEmbedService_Unfurl_CacheHit(t *testing.T) {
+	title := "Cached"
+	link := "https://example.com"
+	cached, _ := json.Marshal(&models.Embed{Type: "link", Title: &title, URL: &link})
+
+	cache := new(MockCacheService)
+	cache.On("Get", mock.Anything, embedCacheKey(link)).Return(cached, nil)
+
+	s := NewEmbedService(cache, nil)
+
+	embed, err := s.Unfurl(context.Background(), link)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Cached", *embed.Title)
+	cache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestEmbedService_CachedEmbeds(t *testing.T) {
+	title := "Cached"
+	hit, blank, miss := "https://example.com/hit", "https://example.com/blank", "https://example.com/miss"
+	cached, _ := json.Marshal(&models.Embed{Type: "link", Title: &title, URL: &hit})
+
+	cache := new(MockCacheService)
+	cache.On("Get", mock.Anything, embedCacheKey(hit)).Return(cached, nil)
+	cache.On("Get", mock.Anything, embedCacheKey(blank)).Return([]byte("null"), nil)
+	cache.On("Get", mock.Anything, embedCacheKey(miss)).Return(nil, nil)
+
+	s := NewEmbedService(cache, nil)
+
+	embeds, complete := s.CachedEmbeds(context.Background(), hit+" "+blank)
+	assert.True(t, complete, "URLs with nothing to show count as cached")
+	require.Len(t, embeds, 1)
+	assert.Equal(t, "Cached", *embeds[0].Title)
+
+	embeds, complete = s.CachedEmbeds(context.Background(), hit+" "+miss)
+	assert.False(t, complete)
+	assert.Len(t, embeds, 1)
+
+	_, complete = NewEmbedService(nil, nil).CachedEmbeds(context.Background(), hit)
+	assert.False(t, complete)
+}
+
+func TestEmbedService_EmbedsForContent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(testOGPage))
+	}))
+	defer srv.Close()
+
+	s := newTestEmbedService(nil, srv)
+
+	embeds := s.EmbedsForContent(context.Background(), "new post "+srv.URL+"/a and "+srv.URL+"/missing")
+
+	require.Len(t, embeds, 1)
+	assert.Equal(t, srv.URL+"/a", *embeds[0].URL)
+}
+
+func TestEditMessage_UnfurlsLinks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(testOGPage))
+	}))
+	defer srv.Close()
+
+	service, msgRepo, _, _, _, _, _, _, eventBus := setupMessageService()
+	service.SetEmbedService(newTestEmbedService(nil, srv))
+	ctx := context.Background()
+	authorID := uuid.New()
+	messageID := uuid.New()
+
+	existingMessage := &models.Message{
+		ID:       messageID,
+		AuthorID: authorID,
+		Content:  "Original content",
+	}
+
+	unfurled := &models.Message{
+		ID:       messageID,
+		AuthorID: authorID,
+		Content:  "check " + srv.URL,
+		Embeds:   []models.Embed{{Title: strPtr("Hearth 1.0 released")}},
+	}
+	msgRepo.On("GetByID", ctx, messageID).Return(existingMessage, nil).Once()
+	msgRepo.On("GetByID", mock.Anything, messageID).Return(unfurled, nil)
+	msgRepo.On("Update", ctx, mock.AnythingOfType("*models.Message")).Return(nil)
+	msgRepo.On("UpdateEmbeds", mock.Anything, messageID, "check "+srv.URL, mock.MatchedBy(func(embeds []models.Embed) bool {
+		return len(embeds) == 1 && *embeds[0].Title == "Hearth 1.0 released"
+	})).Return(true, nil)
+
+	updates := make(chan *models.Message, 2)
+	eventBus.On("Publish", "message.updated", mock.AnythingOfType("*services.MessageUpdatedEvent")).
+		Run(func(args mock.Arguments) {
+			updates <- args.Get(1).(*MessageUpdatedEvent).Message
+		}).Return()
+
+	message, err := service.EditMessage(ctx, messageID, authorID, "check "+srv.URL)
+
+	// The edit returns at once; its embeds follow in a second update
+	require.NoError(t, err)
+	assert.Empty(t, message.Embeds)
+	assert.Empty(t, (<-updates).Embeds)
+
+	select {
+	case updated := <-updates:
+		require.Len(t, updated.Embeds, 1)
+		assert.Equal(t, "Hearth 1.0 released", *updated.Embeds[0].Title)
+	case <-time.After(5 * time.Second):
+		t.Fatal("embeds were never sent")
+	}
+}
+
+func TestSendMessage_AttachesCachedEmbeds(t *testing.T) {
+	service, msgRepo, channelRepo, serverRepo, _, rateLimiter, _, _, eventBus := setupMessageService()
+	title := "Hearth 1.0 released"
+	link := "https://example.com/post"
+	cached, _ := json.Marshal(&models.Embed{Type: "link", Title: &title, URL: &link})
+	embedCache := new(MockCacheService)
+	embedCache.On("Get", mock.Anything, embedCacheKey(link)).Return(cached, nil)
+	service.SetEmbedService(NewEmbedService(embedCache, nil))
+
+	ctx := context.Background()
+	authorID := uuid.New()
+	channelID := uuid.New()
+	serverID := uuid.New()
+
+	channelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, ServerID: &serverID, Type: models.ChannelTypeText}, nil)
+	channelRepo.On("UpdateLastMessage", ctx, channelID, mock.Anything, mock.Anything).Return(nil)
+	serverRepo.On("GetMember", ctx, serverID, authorID).Return(&models.Member{ServerID: serverID, UserID: authorID}, nil)
+	rateLimiter.On("Check", ctx, authorID, channelID).Return(nil)
+	msgRepo.On("Create", ctx, mock.MatchedBy(func(m *models.Message) bool {
+		return len(m.Embeds) == 1 && *m.Embeds[0].Title == title
+	})).Return(nil)
+	var created *models.Message
+	eventBus.On("Publish", "message.created", mock.Anything).Run(func(args mock.Arguments) {
+		created = args.Get(1).(*MessageCreatedEvent).Message
+	}).Return()
+
+	message, err := service.SendMessage(ctx, authorID, channelID, "new post "+link, nil, nil)
+
+	// Every link was cached, so nothing is left to fetch in the background
+	require.NoError(t, err)
+	require.Len(t, message.Embeds, 1)
+	require.NotNil(t, created)
+	assert.Len(t, created.Embeds, 1)
+	msgRepo.AssertNotCalled(t, "UpdateEmbeds", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestEditMessage_SuppressedEmbeds(t *testing.T) {
+	service, msgRepo, _, _, _, _, _, _, eventBus := setupMessageService()
+	service.SetEmbedService(NewEmbedService(nil, nil))
+	ctx := context.Background()
+	authorID := uuid.New()
+	messageID := uuid.New()
+
+	existingMessage := &models.Message{
+		ID:       messageID,
+		AuthorID: authorID,
+		Content:  "Original content",
+		Flags:    models.MessageFlagSuppressEmbeds,
+	}
+
+	msgRepo.On("GetByID", ctx, messageID).Return(existingMessage, nil)
+	msgRepo.On("Update", ctx, mock.AnythingOfType("*models.Message")).Return(nil)
+	eventBus.On("Publish", "message.updated", mock.AnythingOfType("*services.MessageUpdatedEvent")).Return()
+
+	message, err := service.EditMessage(ctx, messageID, authorID, "https://example.com")
+
+	require.NoError(t, err)
+	assert.Empty(t, message.Embeds)
+}
+
+// FuzzParseEmbedMeta feeds arbitrary pages through metadata extraction.
+// Unfurled pages are untrusted, so nothing may panic and the embed must
+// stay within its limits.
+func FuzzParseEmbedMeta(f *testing.F) {
+	f.Add(testOGPage)
+	for _, seed := range []string{
+		`<html><head><title>Plain</title><meta name="theme-color" content="#abc"></head></html>`,
+		`<meta property="og:image" content="javascript:alert(1)"><meta property="og:url" content="//evil.example">`,
+		`<meta property="og:image" content="/a.png"><meta property="og:image:width" content="-5">`,
+		`<title><title><meta content="x" name="description"`,
+		`<meta property="og:title" content="` + strings.Repeat("é", 300) + `">`,
+		`<head><meta name="theme-color" content="#+ff"></head><body><title>late</title>`,
+	} {
+		f.Add(seed)
+	}
+	page, _ := url.Parse("https://example.com/post")
+
+	f.Fuzz(func(t *testing.T, doc string) {
+		embed, err := parseEmbedMeta(strings.NewReader(doc)).toEmbed(page)
+		if err != nil {
+			return
+		}
+		if embed.Title != nil && utf8.RuneCountInString(*embed.Title) > maxEmbedTitleLength {
+			t.Fatalf("title has %d runes", utf8.RuneCountInString(*embed.Title))
+		}
+		if embed.Description != nil && utf8.RuneCountInString(*embed.Description) > maxEmbedDescriptionLength {
+			t.Fatalf("description has %d runes", utf8.RuneCountInString(*embed.Description))
+		}
+		if embed.Color != nil && (*embed.Color < 0 || *embed.Color > 0xFFFFFF) {
+			t.Fatalf("color %d out of range", *embed.Color)
+		}
+		for _, media := range []*models.EmbedMedia{embed.Image, embed.Thumbnail} {
+			if media != nil && !strings.HasPrefix(media.URL, "http://") && !strings.HasPrefix(media.URL, "https://") {
+				t.Fatalf("media URL %q is not http(s)", media.URL)
+			}
+		}
+	})
+}
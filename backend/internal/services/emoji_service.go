@@ -2,59 +2,302 @@ package services
 
 import (
 	"context"
-	"errors"
-	"sync"
+	"log"
+	"mime/multipart"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+
+	"hearth/internal/models"
+	"hearth/internal/storage"
 )
 
-var ErrEmojiNotFound = errors.New("emoji not found")
+// emojiNamePattern is what custom emoji can be called; names are unique
+// per server regardless of case
+var emojiNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{2,32}$`)
+
+// emojiImageTypes are the image types emoji can be uploaded as, and
+// whether each is animated
+var emojiImageTypes = map[string]bool{
+	"image/png":  false,
+	"image/jpeg": false,
+	"image/webp": false,
+	"image/gif":  true,
+}
+
+// EmojiRepository stores custom emoji
+type EmojiRepository interface {
+	Create(ctx context.Context, emoji *models.Emoji) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Emoji, error)
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Emoji, error)
+	GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*models.Emoji, error)
+	Update(ctx context.Context, emoji *models.Emoji) error
+	// Delete removes the emoji and every reaction made with it
+	Delete(ctx context.Context, id uuid.UUID) error
+}
 
-type CustomEmoji struct {
-	ID       uuid.UUID
+// EmojiStorage keeps emoji images. storage.Service implements it.
+type EmojiStorage interface {
+	UploadFile(ctx context.Context, file *multipart.FileHeader, uploaderID uuid.UUID, category string) (*storage.FileInfo, error)
+	DeleteFile(ctx context.Context, path string) error
+}
+
+// EmojisUpdatedEvent is published with a server's full emoji list whenever
+// one is added, renamed or deleted
+type EmojisUpdatedEvent struct {
 	ServerID uuid.UUID
-	Name     string
-	URL      string
-	Animated bool
+	Emojis   []*models.Emoji
 }
 
+// EmojiService manages custom server emoji
 type EmojiService struct {
-	mu     sync.RWMutex
-	emojis map[uuid.UUID]*CustomEmoji
+	repo       EmojiRepository
+	serverRepo ServerRepository
+	roleRepo   RoleRepository
+	storage    EmojiStorage
+	quotas     *QuotaService
+	eventBus   EventBus
+}
+
+// NewEmojiService creates a new emoji service. Uploads fail with
+// ErrEmojiStorageDisabled when storage is nil, and aren't limited when
+// quotas is nil.
+func NewEmojiService(
+	repo EmojiRepository,
+	serverRepo ServerRepository,
+	roleRepo RoleRepository,
+	storage EmojiStorage,
+	quotas *QuotaService,
+	eventBus EventBus,
+) *EmojiService {
+	return &EmojiService{
+		repo:       repo,
+		serverRepo: serverRepo,
+		roleRepo:   roleRepo,
+		storage:    storage,
+		quotas:     quotas,
+		eventBus:   eventBus,
+	}
+}
+
+// GetServerEmojis returns a server's emoji, oldest first. Any member can
+// list them.
+func (s *EmojiService) GetServerEmojis(ctx context.Context, serverID, requesterID uuid.UUID) ([]*models.Emoji, error) {
+	if _, err := s.getServer(ctx, serverID); err != nil {
+		return nil, err
+	}
+	member, err := s.serverRepo.GetMember(ctx, serverID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrNotServerMember
+	}
+	return s.repo.GetByServerID(ctx, serverID)
+}
+
+// CreateEmoji uploads image as a new emoji. GIFs are animated, and static
+// and animated emoji count towards separate quotas.
+func (s *EmojiService) CreateEmoji(ctx context.Context, serverID, requesterID uuid.UUID, name string, image *multipart.FileHeader) (*models.Emoji, error) {
+	if err := s.authorize(ctx, serverID, requesterID); err != nil {
+		return nil, err
+	}
+	if s.storage == nil {
+		return nil, ErrEmojiStorageDisabled
+	}
+	if !emojiNamePattern.MatchString(name) {
+		return nil, ErrInvalidEmojiName
+	}
+	animated, ok := emojiImageTypes[strings.ToLower(image.Header.Get("Content-Type"))]
+	if !ok {
+		return nil, ErrInvalidEmojiImage
+	}
+
+	existing, err := s.repo.GetByServerID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if nameTaken(existing, name, uuid.Nil) {
+		return nil, ErrEmojiNameTaken
+	}
+	if err := s.checkQuota(ctx, serverID, requesterID, existing, animated, image.Size); err != nil {
+		return nil, err
+	}
+
+	file, err := s.storage.UploadFile(ctx, image, requesterID, "emojis")
+	if err != nil {
+		return nil, err
+	}
+	emoji := &models.Emoji{
+		ID:        file.ID,
+		ServerID:  serverID,
+		CreatorID: requesterID,
+		Name:      name,
+		Animated:  animated,
+		URL:       file.URL,
+		Path:      file.Path,
+		Size:      file.Size,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.Create(ctx, emoji); err != nil {
+		s.deleteImage(ctx, emoji)
+		return nil, err
+	}
+
+	s.publish(ctx, serverID)
+	return emoji, nil
 }
 
-func NewEmojiService() *EmojiService {
-	return &EmojiService{emojis: make(map[uuid.UUID]*CustomEmoji)}
+// RenameEmoji changes an emoji's name
+func (s *EmojiService) RenameEmoji(ctx context.Context, serverID, emojiID, requesterID uuid.UUID, name string) (*models.Emoji, error) {
+	if err := s.authorize(ctx, serverID, requesterID); err != nil {
+		return nil, err
+	}
+	emoji, err := s.getEmoji(ctx, serverID, emojiID)
+	if err != nil {
+		return nil, err
+	}
+	if !emojiNamePattern.MatchString(name) {
+		return nil, ErrInvalidEmojiName
+	}
+	if name == emoji.Name {
+		return emoji, nil
+	}
+
+	existing, err := s.repo.GetByServerID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if nameTaken(existing, name, emoji.ID) {
+		return nil, ErrEmojiNameTaken
+	}
+
+	emoji.Name = name
+	if err := s.repo.Update(ctx, emoji); err != nil {
+		return nil, err
+	}
+
+	s.publish(ctx, serverID)
+	return emoji, nil
 }
 
-func (s *EmojiService) Create(ctx context.Context, serverID uuid.UUID, name, url string, animated bool) (*CustomEmoji, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// DeleteEmoji removes an emoji, its image and every reaction made with it
+func (s *EmojiService) DeleteEmoji(ctx context.Context, serverID, emojiID, requesterID uuid.UUID) error {
+	if err := s.authorize(ctx, serverID, requesterID); err != nil {
+		return err
+	}
+	emoji, err := s.getEmoji(ctx, serverID, emojiID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, emoji.ID); err != nil {
+		return err
+	}
+	s.deleteImage(ctx, emoji)
 
-	e := &CustomEmoji{ID: uuid.New(), ServerID: serverID, Name: name, URL: url, Animated: animated}
-	s.emojis[e.ID] = e
-	return e, nil
+	s.publish(ctx, serverID)
+	return nil
 }
 
-func (s *EmojiService) GetServerEmojis(ctx context.Context, serverID uuid.UUID) ([]*CustomEmoji, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// checkQuota enforces the per-server emoji counts and the image size limit
+func (s *EmojiService) checkQuota(ctx context.Context, serverID, requesterID uuid.UUID, existing []*models.Emoji, animated bool, size int64) error {
+	if s.quotas == nil {
+		return nil
+	}
+	limits, err := s.quotas.GetEffectiveLimits(ctx, requesterID, &serverID)
+	if err != nil {
+		return err
+	}
+	if limits.MaxEmojiSizeMB > 0 && size > limits.MaxEmojiSizeMB*1024*1024 {
+		return ErrEmojiTooLarge
+	}
 
-	var emojis []*CustomEmoji
-	for _, e := range s.emojis {
-		if e.ServerID == serverID {
-			emojis = append(emojis, e)
+	limit := limits.MaxEmoji
+	if animated {
+		limit = limits.MaxEmojiAnimated
+	}
+	if limit <= 0 {
+		return nil
+	}
+	count := 0
+	for _, e := range existing {
+		if e.Animated == animated {
+			count++
 		}
 	}
-	return emojis, nil
+	if count >= limit {
+		return ErrTooManyEmoji
+	}
+	return nil
 }
 
-func (s *EmojiService) Delete(ctx context.Context, emojiID uuid.UUID) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	if _, ok := s.emojis[emojiID]; !ok {
-		return ErrEmojiNotFound
+func (s *EmojiService) authorize(ctx context.Context, serverID, userID uuid.UUID) error {
+	server, err := s.getServer(ctx, serverID)
+	if err != nil {
+		return err
+	}
+	if !memberHasPermission(ctx, s.serverRepo, s.roleRepo, server, userID, models.PermManageEmoji) {
+		return ErrCannotManageEmoji
 	}
-	delete(s.emojis, emojiID)
 	return nil
 }
+
+func (s *EmojiService) getServer(ctx context.Context, serverID uuid.UUID) (*models.Server, error) {
+	server, err := s.serverRepo.GetByID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if server == nil {
+		return nil, ErrServerNotFound
+	}
+	return server, nil
+}
+
+func (s *EmojiService) getEmoji(ctx context.Context, serverID, emojiID uuid.UUID) (*models.Emoji, error) {
+	emoji, err := s.repo.GetByID(ctx, emojiID)
+	if err != nil {
+		return nil, err
+	}
+	if emoji == nil || emoji.ServerID != serverID {
+		return nil, ErrEmojiNotFound
+	}
+	return emoji, nil
+}
+
+// deleteImage removes an emoji's image from storage. A leftover image only
+// wastes space, so failures are logged rather than returned.
+func (s *EmojiService) deleteImage(ctx context.Context, emoji *models.Emoji) {
+	if s.storage == nil {
+		return
+	}
+	if err := s.storage.DeleteFile(ctx, emoji.Path); err != nil {
+		log.Printf("[Emoji] failed to delete image %s of emoji %s: %v", emoji.Path, emoji.ID, err)
+	}
+}
+
+// publish sends the server's emoji list to its members
+func (s *EmojiService) publish(ctx context.Context, serverID uuid.UUID) {
+	emojis, err := s.repo.GetByServerID(ctx, serverID)
+	if err != nil {
+		log.Printf("[Emoji] failed to list emoji of server %s for update: %v", serverID, err)
+		return
+	}
+	s.eventBus.Publish("server.emojis_updated", &EmojisUpdatedEvent{
+		ServerID: serverID,
+		Emojis:   emojis,
+	})
+}
+
+// nameTaken reports whether an emoji other than except is called name,
+// ignoring case
+func nameTaken(emojis []*models.Emoji, name string, except uuid.UUID) bool {
+	for _, e := range emojis {
+		if e.ID != except && strings.EqualFold(e.Name, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,42 +2,278 @@ package services
 
 import (
 	"context"
+	"errors"
+	"mime/multipart"
+	"net/textproto"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+	"hearth/internal/storage"
 )
 
-func TestEmojiService_Create(t *testing.T) {
-	svc := NewEmojiService()
+type fakeEmojiRepository struct {
+	emojis    map[uuid.UUID]*models.Emoji
+	createErr error
+}
+
+func (f *fakeEmojiRepository) Create(ctx context.Context, emoji *models.Emoji) error {
+	if f.createErr != nil {
+		return f.createErr
+	}
+	copied := *emoji
+	f.emojis[emoji.ID] = &copied
+	return nil
+}
+
+func (f *fakeEmojiRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Emoji, error) {
+	emoji, ok := f.emojis[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *emoji
+	return &copied, nil
+}
+
+func (f *fakeEmojiRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Emoji, error) {
+	var emojis []*models.Emoji
+	for _, id := range ids {
+		if emoji, ok := f.emojis[id]; ok {
+			copied := *emoji
+			emojis = append(emojis, &copied)
+		}
+	}
+	return emojis, nil
+}
+
+func (f *fakeEmojiRepository) GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*models.Emoji, error) {
+	var emojis []*models.Emoji
+	for _, emoji := range f.emojis {
+		if emoji.ServerID == serverID {
+			copied := *emoji
+			emojis = append(emojis, &copied)
+		}
+	}
+	return emojis, nil
+}
+
+func (f *fakeEmojiRepository) Update(ctx context.Context, emoji *models.Emoji) error {
+	copied := *emoji
+	f.emojis[emoji.ID] = &copied
+	return nil
+}
+
+func (f *fakeEmojiRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	delete(f.emojis, id)
+	return nil
+}
+
+type fakeEmojiStorage struct {
+	uploads []string
+	deleted []string
+}
+
+func (f *fakeEmojiStorage) UploadFile(ctx context.Context, file *multipart.FileHeader, uploaderID uuid.UUID, category string) (*storage.FileInfo, error) {
+	id := uuid.New()
+	path := category + "/" + id.String()
+	f.uploads = append(f.uploads, path)
+	return &storage.FileInfo{
+		ID:         id,
+		Path:       path,
+		URL:        "https://cdn.example.com/" + path,
+		Filename:   file.Filename,
+		Size:       file.Size,
+		UploadedBy: uploaderID,
+		UploadedAt: time.Now(),
+	}, nil
+}
+
+func (f *fakeEmojiStorage) DeleteFile(ctx context.Context, path string) error {
+	f.deleted = append(f.deleted, path)
+	return nil
+}
+
+type emojiTest struct {
+	service    *EmojiService
+	repo       *fakeEmojiRepository
+	storage    *fakeEmojiStorage
+	serverRepo *MockServerRepository
+	eventBus   *MockEventBus
+
+	serverID uuid.UUID
+	ownerID  uuid.UUID
+}
+
+func newEmojiTest(quotas *models.QuotaConfig) *emojiTest {
+	e := &emojiTest{
+		repo:       &fakeEmojiRepository{emojis: make(map[uuid.UUID]*models.Emoji)},
+		storage:    &fakeEmojiStorage{},
+		serverRepo: new(MockServerRepository),
+		eventBus:   new(MockEventBus),
+		serverID:   uuid.New(),
+		ownerID:    uuid.New(),
+	}
+	var quotaService *QuotaService
+	if quotas != nil {
+		quotaService = NewQuotaService(quotas, nil, nil, nil)
+	}
+	// Without a role repository only the owner manages emoji
+	e.service = NewEmojiService(e.repo, e.serverRepo, nil, e.storage, quotaService, e.eventBus)
+	e.serverRepo.On("GetByID", mock.Anything, e.serverID).Return(&models.Server{ID: e.serverID, OwnerID: e.ownerID}, nil)
+	e.eventBus.On("Publish", "server.emojis_updated", mock.Anything).Return()
+	return e
+}
+
+func emojiImage(contentType string, size int64) *multipart.FileHeader {
+	return &multipart.FileHeader{
+		Filename: "emoji",
+		Header:   textproto.MIMEHeader{"Content-Type": {contentType}},
+		Size:     size,
+	}
+}
+
+func TestCreateEmoji(t *testing.T) {
+	e := newEmojiTest(nil)
+
+	emoji, err := e.service.CreateEmoji(context.Background(), e.serverID, e.ownerID, "party_parrot", emojiImage("image/gif", 2048))
+	require.NoError(t, err)
+	assert.Equal(t, "party_parrot", emoji.Name)
+	assert.True(t, emoji.Animated)
+	assert.Equal(t, e.ownerID, emoji.CreatorID)
+	assert.Equal(t, "https://cdn.example.com/"+emoji.Path, emoji.URL)
+	assert.Len(t, e.storage.uploads, 1)
+	assert.Contains(t, e.repo.emojis, emoji.ID)
+
+	e.eventBus.AssertCalled(t, "Publish", "server.emojis_updated", mock.MatchedBy(func(ev *EmojisUpdatedEvent) bool {
+		return ev.ServerID == e.serverID && len(ev.Emojis) == 1 && ev.Emojis[0].ID == emoji.ID
+	}))
+}
+
+func TestCreateEmoji_Validation(t *testing.T) {
+	e := newEmojiTest(nil)
 	ctx := context.Background()
-	serverID := uuid.New()
 
-	e, err := svc.Create(ctx, serverID, "pepe", "/emojis/pepe.png", false)
-	assert.NoError(t, err)
-	assert.Equal(t, "pepe", e.Name)
+	_, err := e.service.CreateEmoji(ctx, e.serverID, e.ownerID, "a", emojiImage("image/png", 10))
+	assert.ErrorIs(t, err, ErrInvalidEmojiName)
+	_, err = e.service.CreateEmoji(ctx, e.serverID, e.ownerID, "no spaces", emojiImage("image/png", 10))
+	assert.ErrorIs(t, err, ErrInvalidEmojiName)
+	_, err = e.service.CreateEmoji(ctx, e.serverID, e.ownerID, "svg", emojiImage("image/svg+xml", 10))
+	assert.ErrorIs(t, err, ErrInvalidEmojiImage)
 
-	emojis, _ := svc.GetServerEmojis(ctx, serverID)
-	assert.Len(t, emojis, 1)
+	_, err = e.service.CreateEmoji(ctx, e.serverID, e.ownerID, "Blob", emojiImage("image/png", 10))
+	require.NoError(t, err)
+	_, err = e.service.CreateEmoji(ctx, e.serverID, e.ownerID, "blob", emojiImage("image/png", 10))
+	assert.ErrorIs(t, err, ErrEmojiNameTaken)
+
+	assert.Len(t, e.storage.uploads, 1)
 }
 
-func TestEmojiService_Delete(t *testing.T) {
-	svc := NewEmojiService()
+func TestCreateEmoji_Quota(t *testing.T) {
+	e := newEmojiTest(&models.QuotaConfig{
+		Servers: models.ServerQuotaConfig{MaxEmoji: 1, MaxEmojiAnimated: 1},
+		Storage: models.StorageQuotaConfig{MaxEmojiSizeMB: 1},
+	})
 	ctx := context.Background()
-	serverID := uuid.New()
 
-	e, _ := svc.Create(ctx, serverID, "kek", "/emojis/kek.png", false)
-	err := svc.Delete(ctx, e.ID)
-	assert.NoError(t, err)
+	_, err := e.service.CreateEmoji(ctx, e.serverID, e.ownerID, "huge", emojiImage("image/png", 2*1024*1024))
+	assert.ErrorIs(t, err, ErrEmojiTooLarge)
+
+	_, err = e.service.CreateEmoji(ctx, e.serverID, e.ownerID, "first", emojiImage("image/png", 10))
+	require.NoError(t, err)
+	_, err = e.service.CreateEmoji(ctx, e.serverID, e.ownerID, "second", emojiImage("image/webp", 10))
+	assert.ErrorIs(t, err, ErrTooManyEmoji)
+
+	// Animated emoji have their own quota
+	_, err = e.service.CreateEmoji(ctx, e.serverID, e.ownerID, "spinning", emojiImage("image/gif", 10))
+	require.NoError(t, err)
+	_, err = e.service.CreateEmoji(ctx, e.serverID, e.ownerID, "dancing", emojiImage("image/gif", 10))
+	assert.ErrorIs(t, err, ErrTooManyEmoji)
+}
+
+func TestCreateEmoji_RequiresPermission(t *testing.T) {
+	e := newEmojiTest(nil)
+
+	_, err := e.service.CreateEmoji(context.Background(), e.serverID, uuid.New(), "blob", emojiImage("image/png", 10))
+	assert.ErrorIs(t, err, ErrCannotManageEmoji)
+	assert.Empty(t, e.storage.uploads)
+}
+
+func TestCreateEmoji_StorageDisabled(t *testing.T) {
+	e := newEmojiTest(nil)
+	e.service.storage = nil
+
+	_, err := e.service.CreateEmoji(context.Background(), e.serverID, e.ownerID, "blob", emojiImage("image/png", 10))
+	assert.ErrorIs(t, err, ErrEmojiStorageDisabled)
+}
 
-	emojis, _ := svc.GetServerEmojis(ctx, serverID)
-	assert.Len(t, emojis, 0)
+func TestCreateEmoji_RemovesImageWhenSaveFails(t *testing.T) {
+	e := newEmojiTest(nil)
+	e.repo.createErr = errors.New("db down")
+
+	_, err := e.service.CreateEmoji(context.Background(), e.serverID, e.ownerID, "blob", emojiImage("image/png", 10))
+	assert.Error(t, err)
+	assert.Equal(t, e.storage.uploads, e.storage.deleted)
 }
 
-func TestEmojiService_Delete_NotFound(t *testing.T) {
-	svc := NewEmojiService()
+func TestRenameEmoji(t *testing.T) {
+	e := newEmojiTest(nil)
 	ctx := context.Background()
 
-	err := svc.Delete(ctx, uuid.New())
+	blob, err := e.service.CreateEmoji(ctx, e.serverID, e.ownerID, "blob", emojiImage("image/png", 10))
+	require.NoError(t, err)
+	_, err = e.service.CreateEmoji(ctx, e.serverID, e.ownerID, "cat", emojiImage("image/png", 10))
+	require.NoError(t, err)
+
+	_, err = e.service.RenameEmoji(ctx, e.serverID, blob.ID, e.ownerID, "CAT")
+	assert.ErrorIs(t, err, ErrEmojiNameTaken)
+
+	renamed, err := e.service.RenameEmoji(ctx, e.serverID, blob.ID, e.ownerID, "blobby")
+	require.NoError(t, err)
+	assert.Equal(t, "blobby", renamed.Name)
+	assert.Equal(t, "blobby", e.repo.emojis[blob.ID].Name)
+
+	// An emoji is only found through its own server
+	_, err = e.service.RenameEmoji(ctx, e.serverID, uuid.New(), e.ownerID, "gone")
 	assert.ErrorIs(t, err, ErrEmojiNotFound)
 }
+
+func TestDeleteEmoji(t *testing.T) {
+	e := newEmojiTest(nil)
+	ctx := context.Background()
+
+	emoji, err := e.service.CreateEmoji(ctx, e.serverID, e.ownerID, "blob", emojiImage("image/png", 10))
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, e.service.DeleteEmoji(ctx, e.serverID, emoji.ID, uuid.New()), ErrCannotManageEmoji)
+
+	require.NoError(t, e.service.DeleteEmoji(ctx, e.serverID, emoji.ID, e.ownerID))
+	assert.Empty(t, e.repo.emojis)
+	assert.Equal(t, []string{emoji.Path}, e.storage.deleted)
+
+	assert.ErrorIs(t, e.service.DeleteEmoji(ctx, e.serverID, emoji.ID, e.ownerID), ErrEmojiNotFound)
+}
+
+func TestGetServerEmojis_RequiresMembership(t *testing.T) {
+	e := newEmojiTest(nil)
+	ctx := context.Background()
+	memberID := uuid.New()
+	strangerID := uuid.New()
+
+	_, err := e.service.CreateEmoji(ctx, e.serverID, e.ownerID, "blob", emojiImage("image/png", 10))
+	require.NoError(t, err)
+
+	e.serverRepo.On("GetMember", mock.Anything, e.serverID, memberID).Return(&models.Member{UserID: memberID, ServerID: e.serverID}, nil)
+	e.serverRepo.On("GetMember", mock.Anything, e.serverID, strangerID).Return(nil, nil)
+
+	emojis, err := e.service.GetServerEmojis(ctx, e.serverID, memberID)
+	require.NoError(t, err)
+	assert.Len(t, emojis, 1)
+
+	_, err = e.service.GetServerEmojis(ctx, e.serverID, strangerID)
+	assert.ErrorIs(t, err, ErrNotServerMember)
+}
@@ -1,6 +1,9 @@
 package services
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 // Shared errors used across multiple services
 var (
@@ -12,6 +15,21 @@ var (
 	ErrPasswordTooShort   = errors.New("password must be at least 8 characters")
 	ErrPasswordTooLong    = errors.New("password must be at most 72 characters")
 	ErrPasswordWeak       = errors.New("password must contain at least one uppercase, lowercase, and number")
+	// ErrAuthUnavailable means passwords can't be checked or hashed right
+	// now because every password worker is busy
+	ErrAuthUnavailable = errors.New("authentication is at capacity, try again shortly")
+	// ErrCaptchaRequired means the client's address has failed too often
+	// and must pass a captcha before trying again
+	ErrCaptchaRequired    = errors.New("a captcha is required")
+	ErrCaptchaInvalid     = errors.New("captcha was not solved")
+	ErrCaptchaUnavailable = errors.New("captcha could not be verified")
+	// ErrAccountLocked means the account failed to log in too often and
+	// refuses passwords until its lockout ends or the password is reset
+	ErrAccountLocked     = errors.New("account is locked after too many failed logins")
+	ErrInvalidResetToken = errors.New("password reset link is invalid or has expired")
+	// ErrAccountSuspended means an instance admin suspended the account, so
+	// it can't log in or use the API
+	ErrAccountSuspended = errors.New("account suspended")
 
 	// Channel errors
 	ErrChannelNotFound  = errors.New("channel not found")
@@ -25,6 +43,14 @@ var (
 	ErrMessageTooLong   = errors.New("message exceeds maximum length")
 	ErrRateLimited      = errors.New("you are sending messages too quickly")
 	ErrEmptyMessage     = errors.New("message cannot be empty")
+	ErrCannotModerate   = errors.New("missing permission to manage messages")
+	ErrBulkDeleteCount  = errors.New("bulk delete takes between 2 and 100 message ids")
+	ErrMessagesTooOld   = errors.New("cannot bulk delete messages older than 14 days")
+	ErrCannotForward    = errors.New("encrypted messages can't be forwarded")
+
+	// Reaction errors
+	ErrReactionRateLimited = errors.New("you are reacting too quickly")
+	ErrTooManyReactions    = errors.New("message has reached the maximum number of reactions")
 
 	// Server errors
 	ErrServerNotFound   = errors.New("server not found")
@@ -32,6 +58,13 @@ var (
 	ErrNotServerOwner   = errors.New("not the server owner")
 	ErrAlreadyMember    = errors.New("already a member of this server")
 	ErrBannedFromServer = errors.New("you are banned from this server")
+	ErrBanDuration      = errors.New("ban duration cannot be negative")
+	ErrTimeoutDuration  = errors.New("timeout must be between 1 second and 28 days")
+	ErrCannotTimeout    = errors.New("missing permission to time out this member")
+	ErrCannotKick       = errors.New("missing permission to kick members")
+	ErrCannotBan        = errors.New("missing permission to ban members")
+	ErrMemberHierarchy  = errors.New("cannot moderate a member with an equal or higher role")
+	ErrMemberTimedOut   = errors.New("you are timed out in this server")
 
 	// Invite errors
 	ErrInviteNotFound = errors.New("invite not found")
@@ -43,24 +76,292 @@ var (
 	ErrCannotDeleteRole    = errors.New("cannot delete this role")
 	ErrCannotDeleteDefault = errors.New("cannot delete the default role")
 	ErrRoleHierarchy       = errors.New("cannot modify role with higher position")
+	ErrCannotManageRoles   = errors.New("missing permission to manage roles")
+	ErrInvalidRolePosition = errors.New("@everyone stays at position 0 and other roles must be above it")
 
 	// User errors
-	ErrUserNotFound  = errors.New("user not found")
-	ErrUsernameTaken = errors.New("username already taken")
-	ErrSelfAction    = errors.New("cannot perform this action on yourself")
+	ErrUserNotFound     = errors.New("user not found")
+	ErrUsernameTaken    = errors.New("username already taken")
+	ErrInvalidUsername  = errors.New("usernames must be 2-32 letters, numbers, underscores or periods")
+	ErrUsernameCooldown = errors.New("username was changed too recently")
+	ErrUsernameReserved = errors.New("username is reserved")
+	ErrUsernameBanned   = errors.New("username is not allowed")
+	ErrSelfAction       = errors.New("cannot perform this action on yourself")
+	ErrUserBlocked      = errors.New("cannot message this user")
+	ErrDMNotAllowed     = errors.New("this user isn't accepting direct messages from you")
+	ErrTooManyUsers     = errors.New("too many users requested")
+
+	// Username rule errors
+	ErrUsernameRuleNotFound = errors.New("username rule not found")
+	ErrInvalidUsernameRule  = errors.New("invalid username rule")
+	ErrUsernameRuleExists   = errors.New("username rule already exists")
+
+	// Channel feed errors
+	ErrChannelFeedNotFound     = errors.New("channel feed not found")
+	ErrChannelFeedUnsupported  = errors.New("feeds are only available for unencrypted text and announcement channels")
+	ErrCannotManageChannelFeed = errors.New("missing permission to manage this channel's feed")
+
+	// Channel schedule errors
+	ErrChannelClosed               = errors.New("this channel is closed right now")
+	ErrChannelScheduleNotFound     = errors.New("channel has no schedule")
+	ErrChannelScheduleUnsupported  = errors.New("schedules are only available for text and announcement channels")
+	ErrInvalidChannelSchedule      = errors.New("invalid channel schedule")
+	ErrCannotManageChannelSchedule = errors.New("missing permission to manage this channel's schedule")
+
+	// Ownership transfer errors
+	ErrOwnershipTransferNotFound = errors.New("no pending ownership transfer")
+	ErrOwnershipTransferStale    = errors.New("the server changed owner since this transfer was started")
+	ErrNotCoOwner                = errors.New("member is not a co-owner")
+
+	// Ban import errors
+	ErrInvalidBanList      = errors.New("invalid ban list")
+	ErrBanImportInProgress = errors.New("a ban import is already running for this server")
+	ErrBanImportNotFound   = errors.New("ban import not found")
+
+	// Blocklist errors
+	ErrBlocklistNotFound             = errors.New("blocklist not found")
+	ErrInvalidBlocklist              = errors.New("invalid blocklist")
+	ErrCannotManageBlocklist         = errors.New("missing permission to manage this blocklist")
+	ErrNotSubscribedToBlocklist      = errors.New("this server doesn't follow that blocklist")
+	ErrTooManyBlocklistSubscriptions = errors.New("this server follows too many blocklists")
+
+	// Server token errors
+	ErrServerTokenNotFound       = errors.New("server token not found")
+	ErrInvalidServerToken        = errors.New("invalid server token")
+	ErrServerTokenExpired        = errors.New("server token expired")
+	ErrInvalidServerTokenRequest = errors.New("invalid server token request")
+	ErrTooManyServerTokens       = errors.New("this server has too many tokens")
+
+	// E2EE errors
+	ErrInvalidEncryptedPayload = errors.New("invalid encrypted message")
+	ErrKeyEpochStale           = errors.New("the channel's keys have been rotated")
+	ErrChannelNotEncrypted     = errors.New("channel is not end-to-end encrypted")
+	ErrE2EEUnsupported         = errors.New("only text and announcement channels and group DMs can be encrypted")
+	ErrCannotDisableE2EE       = errors.New("encryption can't be turned off once it's on")
+	ErrE2EEDeviceNotFound      = errors.New("device not found")
+	ErrInvalidDeviceKeys       = errors.New("invalid device keys")
+	ErrTooManyE2EEDevices      = errors.New("too many devices")
+	ErrInvalidSenderKeys       = errors.New("invalid sender keys")
+
+	// Bot tier errors
+	ErrInvalidBotTier = errors.New("tier must be unverified, verified or partner")
+	ErrNotABot        = errors.New("user is not a bot")
+	ErrBotRateLimited = errors.New("too many requests")
+
+	// Session errors
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrSessionRevoked     = errors.New("session has been revoked")
+	ErrRefreshTokenReused = errors.New("refresh token was already used")
+
+	// Content erasure errors
+	ErrContentErasureInProgress = errors.New("this user's content is already being erased")
+	ErrContentErasureNotFound   = errors.New("content erasure not found")
+
+	// OAuth login errors
+	ErrOAuthEmailUnverified = errors.New("the login provider hasn't verified this account's email")
+	ErrOAuthIdentityLinked  = errors.New("this login is already linked to an account")
+
+	// Server throttling errors
+	ErrInvalidServerThrottle = errors.New("limits must be 0 or more")
+	ErrServerRateLimited     = errors.New("this server is too busy right now, try again shortly")
+
+	// Quota errors
+	ErrInvalidQuotaTier = errors.New("tier must be default, premium or bot")
+	ErrInvalidQuota     = errors.New("quotas must be 0 or more")
+
+	// IP filter errors
+	ErrInvalidIPRule  = errors.New("invalid ip rule")
+	ErrIPRuleExists   = errors.New("the network already has a rule with this action")
+	ErrIPRuleNotFound = errors.New("ip rule not found")
+
+	// Instance admin errors
+	ErrSuspensionNotFound   = errors.New("user is not suspended")
+	ErrCannotSuspendAdmin   = errors.New("instance admins can't be suspended")
+	ErrInvalidSuspension    = errors.New("invalid suspension")
+	ErrAnnouncementNotFound = errors.New("announcement not found")
+	ErrInvalidAnnouncement  = errors.New("invalid announcement")
+	ErrAdminRateLimited     = errors.New("too many admin requests, try again shortly")
+	ErrInvalidReadOnlyMode  = errors.New("invalid read-only mode")
+
+	// Report errors
+	ErrReportNotFound          = errors.New("report not found")
+	ErrInvalidReport           = errors.New("invalid report")
+	ErrReportExists            = errors.New("you've already reported this and it's still being reviewed")
+	ErrTooManyReports          = errors.New("you have too many reports waiting for review")
+	ErrInvalidReportTransition = errors.New("the report can't move to that status")
+	ErrReportConflict          = errors.New("the report was changed by someone else; reload it and try again")
+	ErrInvalidReportAssignee   = errors.New("reports can only be assigned to instance admins")
+
+	// OAuth application errors
+	ErrOAuthAppNotFound          = errors.New("application not found")
+	ErrInvalidOAuthApp           = errors.New("invalid application")
+	ErrTooManyOAuthApps          = errors.New("you have too many applications")
+	ErrInvalidOAuthClient        = errors.New("unknown client or wrong client secret")
+	ErrInvalidOAuthRequest       = errors.New("invalid authorization request")
+	ErrInvalidOAuthScope         = errors.New("invalid scope")
+	ErrInvalidOAuthGrant         = errors.New("the authorization code or refresh token is invalid or expired")
+	ErrUnsupportedOAuthGrantType = errors.New("grant_type must be authorization_code or refresh_token")
+	ErrInvalidOAuthToken         = errors.New("invalid access token")
+	ErrOAuthTokenExpired         = errors.New("access token expired")
+
+	// Onboarding errors
+	ErrInvalidWelcomeScreen      = errors.New("invalid welcome screen")
+	ErrInvalidOnboarding         = errors.New("invalid onboarding")
+	ErrInvalidOnboardingResponse = errors.New("invalid onboarding response")
+	ErrOnboardingDisabled        = errors.New("this server doesn't have onboarding")
+	ErrCannotManageOnboarding    = errors.New("missing permission to manage onboarding")
 
 	// Webhook errors
-	ErrWebhookNotFound     = errors.New("webhook not found")
-	ErrInvalidWebhookToken = errors.New("invalid webhook token")
-	ErrWebhookNameTooLong  = errors.New("webhook name cannot exceed 80 characters")
-	ErrTooManyWebhooks     = errors.New("maximum number of webhooks reached for this channel")
+	ErrWebhookNotFound           = errors.New("webhook not found")
+	ErrInvalidWebhookToken       = errors.New("invalid webhook token")
+	ErrWebhookNameTooLong        = errors.New("webhook name cannot exceed 80 characters")
+	ErrTooManyWebhooks           = errors.New("maximum number of webhooks reached for this channel")
+	ErrDuplicateWebhookMessage   = errors.New("webhook message already delivered")
+	ErrWebhookCreatorNotAllowed  = errors.New("your roles can't create webhooks here")
+	ErrWebhookURLNotAllowed      = errors.New("webhook URLs can't point at that domain here")
+	ErrWebhookPolicyNotFound     = errors.New("no webhook policy")
+	ErrInvalidWebhookPolicy      = errors.New("invalid webhook policy")
+	ErrCannotManageWebhookPolicy = errors.New("missing permission to manage the webhook policy")
+
+	// Update errors
+	ErrVersionConflict = errors.New("resource was modified since it was read")
 
 	// Cache errors
 	ErrCacheNotFound = errors.New("key not found in cache")
 
 	// Notification errors
-	ErrNotificationNotFound = errors.New("notification not found")
+	ErrNotificationNotFound     = errors.New("notification not found")
+	ErrInvalidPushPlatform      = errors.New("platform must be fcm, apns or webpush")
+	ErrInvalidPushToken         = errors.New("invalid push token")
+	ErrPushDeviceNotFound       = errors.New("push device not found")
+	ErrInvalidNotificationLevel = errors.New("level must be default, all, mentions or none")
 
 	// Audit log errors
 	ErrAuditLogNotFound = errors.New("audit log entry not found")
+
+	// AutoMod errors
+	ErrAutoModBlocked      = errors.New("message blocked by AutoMod")
+	ErrAutoModRuleNotFound = errors.New("automod rule not found")
+	ErrInvalidAutoModRule  = errors.New("invalid automod rule")
+	ErrTooManyAutoModRules = errors.New("maximum number of automod rules reached for this server")
+	ErrCannotManageAutoMod = errors.New("missing permission to manage automod rules")
+
+	// Emoji errors
+	ErrEmojiNotFound        = errors.New("emoji not found")
+	ErrInvalidEmojiName     = errors.New("emoji names must be 2-32 letters, numbers or underscores")
+	ErrInvalidEmojiImage    = errors.New("emoji must be a PNG, JPEG, GIF or WebP image")
+	ErrEmojiTooLarge        = errors.New("emoji image is too large")
+	ErrEmojiNameTaken       = errors.New("this server already has an emoji with that name")
+	ErrTooManyEmoji         = errors.New("maximum number of emoji reached for this server")
+	ErrCannotManageEmoji    = errors.New("missing permission to manage emoji")
+	ErrEmojiStorageDisabled = errors.New("file storage not configured")
+
+	// Forum errors
+	ErrNotForumChannel       = errors.New("channel is not a forum")
+	ErrForumChannel          = errors.New("forum channels only take posts")
+	ErrForumTagRequired      = errors.New("this forum requires posts to have a tag")
+	ErrInvalidForumTag       = errors.New("tag does not belong to this forum")
+	ErrInvalidForumTagName   = errors.New("tag names must be 1-20 characters")
+	ErrTooManyForumTags      = errors.New("too many tags")
+	ErrInvalidForumSortOrder = errors.New("sort order must be latest_activity or creation_date")
+	ErrCannotManageForum     = errors.New("missing permission to manage this forum")
+
+	// Membership screening errors
+	ErrMembershipPending                 = errors.New("you must accept this server's rules first")
+	ErrInvalidMemberVerification         = errors.New("invalid membership screening form")
+	ErrMemberVerificationOutdated        = errors.New("the server's rules have changed, review them again")
+	ErrMembershipNotPending              = errors.New("membership is not pending")
+	ErrMemberVerificationResponseMissing = errors.New("answer every required question")
+	ErrMemberVerificationNotFound        = errors.New("member has not submitted membership screening")
+	ErrCannotManageMemberVerification    = errors.New("missing permission to manage membership screening")
 )
+
+// VersionConflictError is returned when an update was based on a stale
+// version. Current is the latest saved resource, for the client to merge
+// its edit into. It matches ErrVersionConflict with errors.Is.
+type VersionConflictError struct {
+	Current interface{}
+	Version int
+}
+
+func (e *VersionConflictError) Error() string {
+	return ErrVersionConflict.Error()
+}
+
+func (e *VersionConflictError) Unwrap() error {
+	return ErrVersionConflict
+}
+
+// UsernameCooldownError is returned when a username change comes before
+// the cooldown since the last one has passed. It matches
+// ErrUsernameCooldown with errors.Is.
+type UsernameCooldownError struct {
+	RetryAt time.Time
+}
+
+func (e *UsernameCooldownError) Error() string {
+	return ErrUsernameCooldown.Error()
+}
+
+func (e *UsernameCooldownError) Unwrap() error {
+	return ErrUsernameCooldown
+}
+
+// ServerRateLimitedError is returned when a server has used up its share of
+// the instance for now. It matches ErrServerRateLimited with errors.Is.
+type ServerRateLimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ServerRateLimitedError) Error() string {
+	return ErrServerRateLimited.Error()
+}
+
+func (e *ServerRateLimitedError) Unwrap() error {
+	return ErrServerRateLimited
+}
+
+// AccountLockedError is returned for logins to a locked account. It
+// matches ErrAccountLocked with errors.Is.
+type AccountLockedError struct {
+	Until time.Time
+}
+
+func (e *AccountLockedError) Error() string {
+	return ErrAccountLocked.Error()
+}
+
+func (e *AccountLockedError) Unwrap() error {
+	return ErrAccountLocked
+}
+
+// AccountSuspendedError is returned for suspended accounts. Until is nil
+// if the suspension doesn't expire. It matches ErrAccountSuspended with
+// errors.Is.
+type AccountSuspendedError struct {
+	Reason *string
+	Until  *time.Time
+}
+
+func (e *AccountSuspendedError) Error() string {
+	return ErrAccountSuspended.Error()
+}
+
+func (e *AccountSuspendedError) Unwrap() error {
+	return ErrAccountSuspended
+}
+
+// AdminRateLimitedError is returned when an instance admin has used up
+// their admin API requests for now. It matches ErrAdminRateLimited with
+// errors.Is.
+type AdminRateLimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *AdminRateLimitedError) Error() string {
+	return ErrAdminRateLimited.Error()
+}
+
+func (e *AdminRateLimitedError) Unwrap() error {
+	return ErrAdminRateLimited
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	maxForumTags          = 20
+	maxForumTagNameLength = 20
+	maxAppliedForumTags   = 5
+	defaultForumPostLimit = 25
+	maxForumPostLimit     = 100
+)
+
+// ForumRepository stores forum settings, tags and posts. Posts are threads
+// whose parent is the forum channel.
+type ForumRepository interface {
+	GetSettings(ctx context.Context, channelID uuid.UUID) (*models.ForumSettings, error)
+	// SaveSettings replaces the forum's tags with settings.Tags
+	SaveSettings(ctx context.Context, settings *models.ForumSettings) error
+	// CreatePost stores the thread, its first message and thread.AppliedTags together
+	CreatePost(ctx context.Context, thread *models.Thread, message *models.ThreadMessage) error
+	ListPosts(ctx context.Context, channelID uuid.UUID, query models.ForumPostQuery) ([]*models.ForumPost, error)
+}
+
+// ForumService handles forum channels, whose top-level posts are threads
+type ForumService struct {
+	repo        ForumRepository
+	channelRepo ChannelRepository
+	serverRepo  ServerRepository
+	roleRepo    RoleRepository
+	eventBus    EventBus
+}
+
+// NewForumService creates a new forum service. Without a role repository
+// only server owners can configure forums.
+func NewForumService(
+	repo ForumRepository,
+	channelRepo ChannelRepository,
+	serverRepo ServerRepository,
+	roleRepo RoleRepository,
+	eventBus EventBus,
+) *ForumService {
+	return &ForumService{
+		repo:        repo,
+		channelRepo: channelRepo,
+		serverRepo:  serverRepo,
+		roleRepo:    roleRepo,
+		eventBus:    eventBus,
+	}
+}
+
+// GetSettings returns a forum's settings and tags. Any server member can
+// read them.
+func (s *ForumService) GetSettings(ctx context.Context, channelID, requesterID uuid.UUID) (*models.ForumSettings, error) {
+	if _, err := s.getForum(ctx, channelID, requesterID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetSettings(ctx, channelID)
+}
+
+// UpdateSettings changes a forum's sort order, tag requirement and tags.
+// Requires MANAGE_CHANNELS.
+func (s *ForumService) UpdateSettings(ctx context.Context, channelID, requesterID uuid.UUID, req *models.UpdateForumSettingsRequest) (*models.ForumSettings, error) {
+	channel, err := s.getForum(ctx, channelID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	server, err := s.serverRepo.GetByID(ctx, *channel.ServerID)
+	if err != nil {
+		return nil, err
+	}
+	if server == nil {
+		return nil, ErrServerNotFound
+	}
+	if !memberHasPermission(ctx, s.serverRepo, s.roleRepo, server, requesterID, models.PermManageChannels) {
+		return nil, ErrCannotManageForum
+	}
+
+	settings, err := s.repo.GetSettings(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.DefaultSortOrder != nil {
+		if !req.DefaultSortOrder.Valid() {
+			return nil, ErrInvalidForumSortOrder
+		}
+		settings.DefaultSortOrder = *req.DefaultSortOrder
+	}
+	if req.RequireTag != nil {
+		settings.RequireTag = *req.RequireTag
+	}
+	if req.AvailableTags != nil {
+		tags, err := forumTags(channelID, settings.Tags, *req.AvailableTags)
+		if err != nil {
+			return nil, err
+		}
+		settings.Tags = tags
+	}
+
+	if err := s.repo.SaveSettings(ctx, settings); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// forumTags turns the requested tag list into the forum's new tags, in the
+// order given. Inputs with an ID must name one of the current tags.
+func forumTags(channelID uuid.UUID, current []models.ForumTag, inputs []models.ForumTagInput) ([]models.ForumTag, error) {
+	if len(inputs) > maxForumTags {
+		return nil, ErrTooManyForumTags
+	}
+
+	existing := make(map[uuid.UUID]bool, len(current))
+	for _, tag := range current {
+		existing[tag.ID] = true
+	}
+
+	tags := make([]models.ForumTag, 0, len(inputs))
+	seen := make(map[uuid.UUID]bool, len(inputs))
+	for i, input := range inputs {
+		name := strings.TrimSpace(input.Name)
+		if name == "" || utf8.RuneCountInString(name) > maxForumTagNameLength {
+			return nil, ErrInvalidForumTagName
+		}
+
+		id := uuid.New()
+		if input.ID != nil {
+			if !existing[*input.ID] || seen[*input.ID] {
+				return nil, ErrInvalidForumTag
+			}
+			id = *input.ID
+		}
+		seen[id] = true
+
+		tags = append(tags, models.ForumTag{
+			ID:        id,
+			ChannelID: channelID,
+			Name:      name,
+			Emoji:     input.Emoji,
+			Position:  i,
+		})
+	}
+	return tags, nil
+}
+
+// CreatePost starts a thread in a forum with its first message and tags
+func (s *ForumService) CreatePost(ctx context.Context, channelID, authorID uuid.UUID, req *models.CreateForumPostRequest) (*models.ForumPost, error) {
+	if _, err := s.getForum(ctx, channelID, authorID); err != nil {
+		return nil, err
+	}
+
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		return nil, ErrEmptyMessage
+	}
+
+	archiveDuration := models.AutoArchive24Hour
+	if req.AutoArchive != nil {
+		switch *req.AutoArchive {
+		case models.AutoArchive1Hour, models.AutoArchive24Hour, models.AutoArchive3Day, models.AutoArchive1Week:
+			archiveDuration = *req.AutoArchive
+		default:
+			return nil, ErrInvalidAutoArchive
+		}
+	}
+
+	settings, err := s.repo.GetSettings(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedForumTags(settings, req.AppliedTags)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	thread := &models.Thread{
+		ID:              uuid.New(),
+		ParentChannelID: channelID,
+		OwnerID:         authorID,
+		Name:            req.Name,
+		MessageCount:    0,
+		MemberCount:     1,
+		AutoArchive:     archiveDuration,
+		CreatedAt:       now,
+		AppliedTags:     applied,
+	}
+	message := &models.ThreadMessage{
+		ID:        uuid.New(),
+		ThreadID:  thread.ID,
+		AuthorID:  authorID,
+		Content:   content,
+		CreatedAt: now,
+	}
+
+	if err := s.repo.CreatePost(ctx, thread, message); err != nil {
+		return nil, err
+	}
+	thread.MessageCount = 1
+
+	s.eventBus.Publish("thread.created", &ThreadCreatedEvent{
+		Thread:    thread,
+		ChannelID: channelID,
+	})
+	s.eventBus.Publish("thread.message_created", &ThreadMessageCreatedEvent{
+		Message:   message,
+		ThreadID:  thread.ID,
+		ChannelID: channelID,
+	})
+
+	return &models.ForumPost{
+		Thread:         *thread,
+		LastActivityAt: now,
+		FirstMessage:   message,
+	}, nil
+}
+
+// appliedForumTags checks a new post's tags against the forum, dropping
+// duplicates
+func appliedForumTags(settings *models.ForumSettings, requested []uuid.UUID) ([]uuid.UUID, error) {
+	available := make(map[uuid.UUID]bool, len(settings.Tags))
+	for _, tag := range settings.Tags {
+		available[tag.ID] = true
+	}
+
+	applied := make([]uuid.UUID, 0, len(requested))
+	seen := make(map[uuid.UUID]bool, len(requested))
+	for _, id := range requested {
+		if seen[id] {
+			continue
+		}
+		if !available[id] {
+			return nil, ErrInvalidForumTag
+		}
+		seen[id] = true
+		applied = append(applied, id)
+	}
+
+	if len(applied) > maxAppliedForumTags {
+		return nil, ErrTooManyForumTags
+	}
+	if settings.RequireTag && len(applied) == 0 {
+		return nil, ErrForumTagRequired
+	}
+	return applied, nil
+}
+
+// ListPosts returns a page of a forum's posts. Without a sort order the
+// forum's default is used.
+func (s *ForumService) ListPosts(ctx context.Context, channelID, requesterID uuid.UUID, query models.ForumPostQuery) ([]*models.ForumPost, error) {
+	if _, err := s.getForum(ctx, channelID, requesterID); err != nil {
+		return nil, err
+	}
+
+	if query.Sort == "" {
+		settings, err := s.repo.GetSettings(ctx, channelID)
+		if err != nil {
+			return nil, err
+		}
+		query.Sort = settings.DefaultSortOrder
+	} else if !query.Sort.Valid() {
+		return nil, ErrInvalidForumSortOrder
+	}
+	if query.Limit <= 0 || query.Limit > maxForumPostLimit {
+		query.Limit = defaultForumPostLimit
+	}
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+
+	return s.repo.ListPosts(ctx, channelID, query)
+}
+
+// getForum loads a forum channel the requester belongs to
+func (s *ForumService) getForum(ctx context.Context, channelID, requesterID uuid.UUID) (*models.Channel, error) {
+	channel, err := s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if channel == nil {
+		return nil, ErrChannelNotFound
+	}
+	if channel.Type != models.ChannelTypeForum || channel.ServerID == nil {
+		return nil, ErrNotForumChannel
+	}
+
+	member, err := s.serverRepo.GetMember(ctx, *channel.ServerID, requesterID)
+	if err != nil || member == nil {
+		return nil, ErrNotServerMember
+	}
+	return channel, nil
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeForumRepository struct {
+	settings map[uuid.UUID]*models.ForumSettings
+	posts    []*models.Thread
+	messages []*models.ThreadMessage
+	queries  []models.ForumPostQuery
+}
+
+func (f *fakeForumRepository) GetSettings(ctx context.Context, channelID uuid.UUID) (*models.ForumSettings, error) {
+	settings, ok := f.settings[channelID]
+	if !ok {
+		return models.DefaultForumSettings(channelID), nil
+	}
+	copied := *settings
+	copied.Tags = append([]models.ForumTag{}, settings.Tags...)
+	return &copied, nil
+}
+
+func (f *fakeForumRepository) SaveSettings(ctx context.Context, settings *models.ForumSettings) error {
+	copied := *settings
+	f.settings[settings.ChannelID] = &copied
+	return nil
+}
+
+func (f *fakeForumRepository) CreatePost(ctx context.Context, thread *models.Thread, message *models.ThreadMessage) error {
+	f.posts = append(f.posts, thread)
+	f.messages = append(f.messages, message)
+	return nil
+}
+
+func (f *fakeForumRepository) ListPosts(ctx context.Context, channelID uuid.UUID, query models.ForumPostQuery) ([]*models.ForumPost, error) {
+	f.queries = append(f.queries, query)
+	return []*models.ForumPost{}, nil
+}
+
+type forumTest struct {
+	service     *ForumService
+	repo        *fakeForumRepository
+	channelRepo *MockChannelRepository
+	serverRepo  *MockServerRepository
+	eventBus    *MockEventBus
+
+	forumID  uuid.UUID
+	textID   uuid.UUID
+	ownerID  uuid.UUID
+	memberID uuid.UUID
+}
+
+func newForumTest() *forumTest {
+	serverID := uuid.New()
+	f := &forumTest{
+		repo:        &fakeForumRepository{settings: make(map[uuid.UUID]*models.ForumSettings)},
+		channelRepo: new(MockChannelRepository),
+		serverRepo:  new(MockServerRepository),
+		eventBus:    new(MockEventBus),
+		forumID:     uuid.New(),
+		textID:      uuid.New(),
+		ownerID:     uuid.New(),
+		memberID:    uuid.New(),
+	}
+	// Without a role repository only the owner manages forums
+	f.service = NewForumService(f.repo, f.channelRepo, f.serverRepo, nil, f.eventBus)
+
+	f.channelRepo.On("GetByID", mock.Anything, f.forumID).Return(&models.Channel{ID: f.forumID, ServerID: &serverID, Type: models.ChannelTypeForum}, nil)
+	f.channelRepo.On("GetByID", mock.Anything, f.textID).Return(&models.Channel{ID: f.textID, ServerID: &serverID, Type: models.ChannelTypeText}, nil)
+	f.serverRepo.On("GetByID", mock.Anything, serverID).Return(&models.Server{ID: serverID, OwnerID: f.ownerID}, nil)
+	f.serverRepo.On("GetMember", mock.Anything, serverID, f.ownerID).Return(&models.Member{ServerID: serverID, UserID: f.ownerID}, nil)
+	f.serverRepo.On("GetMember", mock.Anything, serverID, f.memberID).Return(&models.Member{ServerID: serverID, UserID: f.memberID}, nil)
+	f.serverRepo.On("GetMember", mock.Anything, serverID, mock.Anything).Return(nil, nil)
+	f.eventBus.On("Publish", mock.Anything, mock.Anything).Return()
+	return f
+}
+
+func (f *forumTest) setTags(t *testing.T, requireTag bool, names ...string) []models.ForumTag {
+	inputs := make([]models.ForumTagInput, len(names))
+	for i, name := range names {
+		inputs[i] = models.ForumTagInput{Name: name}
+	}
+	settings, err := f.service.UpdateSettings(context.Background(), f.forumID, f.ownerID, &models.UpdateForumSettingsRequest{
+		RequireTag:    &requireTag,
+		AvailableTags: &inputs,
+	})
+	require.NoError(t, err)
+	return settings.Tags
+}
+
+func TestForumUpdateSettings(t *testing.T) {
+	f := newForumTest()
+	ctx := context.Background()
+
+	tags := f.setTags(t, true, "bug", " question ")
+	require.Len(t, tags, 2)
+	assert.Equal(t, "question", tags[1].Name)
+	assert.Equal(t, 1, tags[1].Position)
+
+	// Reordering keeps the IDs of existing tags
+	sort := models.ForumSortCreationDate
+	settings, err := f.service.UpdateSettings(ctx, f.forumID, f.ownerID, &models.UpdateForumSettingsRequest{
+		DefaultSortOrder: &sort,
+		AvailableTags: &[]models.ForumTagInput{
+			{ID: &tags[1].ID, Name: "question"},
+			{Name: "idea"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.ForumSortCreationDate, settings.DefaultSortOrder)
+	assert.True(t, settings.RequireTag, "unset fields are left alone")
+	require.Len(t, settings.Tags, 2)
+	assert.Equal(t, tags[1].ID, settings.Tags[0].ID)
+	assert.Equal(t, 0, settings.Tags[0].Position)
+	assert.Equal(t, "idea", settings.Tags[1].Name)
+}
+
+func TestForumUpdateSettings_Validation(t *testing.T) {
+	f := newForumTest()
+	ctx := context.Background()
+
+	bad := models.ForumSortOrder("random")
+	_, err := f.service.UpdateSettings(ctx, f.forumID, f.ownerID, &models.UpdateForumSettingsRequest{DefaultSortOrder: &bad})
+	assert.ErrorIs(t, err, ErrInvalidForumSortOrder)
+
+	_, err = f.service.UpdateSettings(ctx, f.forumID, f.ownerID, &models.UpdateForumSettingsRequest{
+		AvailableTags: &[]models.ForumTagInput{{Name: "   "}},
+	})
+	assert.ErrorIs(t, err, ErrInvalidForumTagName)
+
+	unknown := uuid.New()
+	_, err = f.service.UpdateSettings(ctx, f.forumID, f.ownerID, &models.UpdateForumSettingsRequest{
+		AvailableTags: &[]models.ForumTagInput{{ID: &unknown, Name: "stolen"}},
+	})
+	assert.ErrorIs(t, err, ErrInvalidForumTag, "tags can't be taken from other forums")
+
+	tooMany := make([]models.ForumTagInput, maxForumTags+1)
+	for i := range tooMany {
+		tooMany[i] = models.ForumTagInput{Name: "tag"}
+	}
+	_, err = f.service.UpdateSettings(ctx, f.forumID, f.ownerID, &models.UpdateForumSettingsRequest{AvailableTags: &tooMany})
+	assert.ErrorIs(t, err, ErrTooManyForumTags)
+
+	_, err = f.service.UpdateSettings(ctx, f.forumID, f.memberID, &models.UpdateForumSettingsRequest{})
+	assert.ErrorIs(t, err, ErrCannotManageForum)
+
+	_, err = f.service.UpdateSettings(ctx, f.textID, f.ownerID, &models.UpdateForumSettingsRequest{})
+	assert.ErrorIs(t, err, ErrNotForumChannel)
+}
+
+func TestForumCreatePost(t *testing.T) {
+	f := newForumTest()
+	tags := f.setTags(t, false, "bug", "question")
+
+	post, err := f.service.CreatePost(context.Background(), f.forumID, f.memberID, &models.CreateForumPostRequest{
+		Name:        "Crash on startup",
+		Content:     "  It crashes.  ",
+		AppliedTags: []uuid.UUID{tags[0].ID, tags[0].ID},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, f.forumID, post.ParentChannelID)
+	assert.Equal(t, f.memberID, post.OwnerID)
+	assert.Equal(t, 1, post.MessageCount)
+	assert.Equal(t, []uuid.UUID{tags[0].ID}, post.AppliedTags, "duplicates are dropped")
+	assert.Equal(t, "It crashes.", post.FirstMessage.Content)
+	assert.Equal(t, post.ID, post.FirstMessage.ThreadID)
+
+	require.Len(t, f.repo.posts, 1)
+	assert.Equal(t, post.ID, f.repo.messages[0].ThreadID)
+	f.eventBus.AssertCalled(t, "Publish", "thread.created", mock.MatchedBy(func(ev *ThreadCreatedEvent) bool {
+		return ev.Thread.ID == post.ID && ev.ChannelID == f.forumID && len(ev.Thread.AppliedTags) == 1
+	}))
+}
+
+func TestForumCreatePost_Tags(t *testing.T) {
+	f := newForumTest()
+	ctx := context.Background()
+	f.setTags(t, true, "bug")
+
+	_, err := f.service.CreatePost(ctx, f.forumID, f.memberID, &models.CreateForumPostRequest{Name: "Untagged", Content: "hi"})
+	assert.ErrorIs(t, err, ErrForumTagRequired)
+
+	_, err = f.service.CreatePost(ctx, f.forumID, f.memberID, &models.CreateForumPostRequest{
+		Name: "Foreign", Content: "hi", AppliedTags: []uuid.UUID{uuid.New()},
+	})
+	assert.ErrorIs(t, err, ErrInvalidForumTag)
+	assert.Empty(t, f.repo.posts)
+}
+
+func TestForumCreatePost_Validation(t *testing.T) {
+	f := newForumTest()
+	ctx := context.Background()
+
+	_, err := f.service.CreatePost(ctx, f.textID, f.memberID, &models.CreateForumPostRequest{Name: "Post", Content: "hi"})
+	assert.ErrorIs(t, err, ErrNotForumChannel)
+
+	_, err = f.service.CreatePost(ctx, f.forumID, uuid.New(), &models.CreateForumPostRequest{Name: "Post", Content: "hi"})
+	assert.ErrorIs(t, err, ErrNotServerMember)
+
+	_, err = f.service.CreatePost(ctx, f.forumID, f.memberID, &models.CreateForumPostRequest{Name: "Post", Content: " "})
+	assert.ErrorIs(t, err, ErrEmptyMessage)
+
+	archive := 5
+	_, err = f.service.CreatePost(ctx, f.forumID, f.memberID, &models.CreateForumPostRequest{Name: "Post", Content: "hi", AutoArchive: &archive})
+	assert.ErrorIs(t, err, ErrInvalidAutoArchive)
+}
+
+func TestForumListPosts(t *testing.T) {
+	f := newForumTest()
+	ctx := context.Background()
+
+	_, err := f.service.ListPosts(ctx, f.forumID, f.memberID, models.ForumPostQuery{Limit: 1000})
+	require.NoError(t, err)
+	assert.Equal(t, models.ForumSortLatestActivity, f.repo.queries[0].Sort, "the forum's default order is used")
+	assert.Equal(t, defaultForumPostLimit, f.repo.queries[0].Limit)
+
+	_, err = f.service.ListPosts(ctx, f.forumID, f.memberID, models.ForumPostQuery{Sort: models.ForumSortCreationDate, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, models.ForumSortCreationDate, f.repo.queries[1].Sort)
+	assert.Equal(t, 10, f.repo.queries[1].Limit)
+
+	_, err = f.service.ListPosts(ctx, f.forumID, f.memberID, models.ForumPostQuery{Sort: "hot"})
+	assert.ErrorIs(t, err, ErrInvalidForumSortOrder)
+
+	_, err = f.service.ListPosts(ctx, f.forumID, uuid.New(), models.ForumPostQuery{})
+	assert.ErrorIs(t, err, ErrNotServerMember)
+}
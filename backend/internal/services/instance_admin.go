@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// InstanceAdminUserRepository is what InstanceAdminService needs from the
+// users table. The Postgres UserRepository implements it.
+type InstanceAdminUserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	// GetByID and GetByUsername return nil if there's no such user
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	// GetByEmail returns ErrUserNotFound if there's no such user
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	// List returns users oldest first. A non-empty query matches the start
+	// of usernames and emails, ignoring case.
+	List(ctx context.Context, query string, after *uuid.UUID, limit int) ([]*models.User, error)
+}
+
+// InstanceAdminServerRepository is what InstanceAdminService needs from
+// the servers table. The Postgres ServerRepository implements it.
+type InstanceAdminServerRepository interface {
+	// GetByID returns nil if there's no such server
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Server, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// InstanceStatsRepository counts what the instance holds. The Postgres
+// InstanceStatsRepository implements it.
+type InstanceStatsRepository interface {
+	Stats(ctx context.Context) (*models.InstanceStats, error)
+}
+
+// SuspensionLookup finds which users are suspended. The
+// UserSuspensionService implements it.
+type SuspensionLookup interface {
+	ActiveSuspensions(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*models.UserSuspension, error)
+}
+
+const (
+	defaultAdminUserPage = 50
+	maxAdminUserPage     = 100
+)
+
+// InstanceAdminService runs the operations behind `hearth admin` and the
+// admin API, for operators with access to the database or a staff
+// account. Nothing here checks who is asking.
+type InstanceAdminService struct {
+	users       InstanceAdminUserRepository
+	servers     InstanceAdminServerRepository
+	stats       InstanceStatsRepository
+	sessions    *SessionService
+	suspensions SuspensionLookup
+	eventBus    EventBus
+	now         func() time.Time
+}
+
+// NewInstanceAdminService creates an instance admin service. Without
+// sessions, resetting a password leaves the user's sessions logged in.
+func NewInstanceAdminService(
+	users InstanceAdminUserRepository,
+	servers InstanceAdminServerRepository,
+	stats InstanceStatsRepository,
+	sessions *SessionService,
+) *InstanceAdminService {
+	return &InstanceAdminService{
+		users:    users,
+		servers:  servers,
+		stats:    stats,
+		sessions: sessions,
+		now:      time.Now,
+	}
+}
+
+// SetSuspensions shows which listed users are suspended
+func (s *InstanceAdminService) SetSuspensions(suspensions SuspensionLookup) {
+	s.suspensions = suspensions
+}
+
+// SetEventBus tells connected members about servers deleted here
+func (s *InstanceAdminService) SetEventBus(eventBus EventBus) {
+	s.eventBus = eventBus
+}
+
+// ListUsers returns a page of users oldest first, with their suspensions.
+// A non-empty query matches the start of usernames and emails. limit
+// defaults to 50 and is capped at 100.
+func (s *InstanceAdminService) ListUsers(ctx context.Context, query string, after *uuid.UUID, limit int) ([]*models.AdminUser, error) {
+	if limit <= 0 {
+		limit = defaultAdminUserPage
+	}
+	if limit > maxAdminUserPage {
+		limit = maxAdminUserPage
+	}
+	users, err := s.users.List(ctx, strings.TrimSpace(query), after, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var suspensions map[uuid.UUID]*models.UserSuspension
+	if s.suspensions != nil && len(users) > 0 {
+		ids := make([]uuid.UUID, len(users))
+		for i, user := range users {
+			ids[i] = user.ID
+		}
+		if suspensions, err = s.suspensions.ActiveSuspensions(ctx, ids); err != nil {
+			return nil, err
+		}
+	}
+
+	listed := make([]*models.AdminUser, len(users))
+	for i, user := range users {
+		listed[i] = &models.AdminUser{User: user, Suspension: suspensions[user.ID]}
+	}
+	return listed, nil
+}
+
+// FindUser looks a user up by ID, email or username, whichever ref looks
+// like
+func (s *InstanceAdminService) FindUser(ctx context.Context, ref string) (*models.User, error) {
+	var user *models.User
+	var err error
+	if id, parseErr := uuid.Parse(ref); parseErr == nil {
+		user, err = s.users.GetByID(ctx, id)
+	} else if strings.Contains(ref, "@") {
+		user, err = s.users.GetByEmail(ctx, ref)
+	} else {
+		user, err = s.users.GetByUsername(ctx, ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// CreateUser creates an account as registering would, optionally as an
+// instance admin. Reserved usernames are allowed, so operators can claim
+// them for staff.
+func (s *InstanceAdminService) CreateUser(ctx context.Context, email, username, password string, instanceAdmin bool) (*models.User, error) {
+	if err := validateUsername(username); err != nil {
+		return nil, err
+	}
+	if _, err := s.users.GetByEmail(ctx, email); err == nil {
+		return nil, ErrEmailTaken
+	} else if !errors.Is(err, ErrUserNotFound) {
+		return nil, err
+	}
+
+	hashedPassword, err := hashPassword(ctx, password)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	user := &models.User{
+		ID:            uuid.New(),
+		Email:         email,
+		Username:      username,
+		Discriminator: models.LegacyDiscriminator,
+		PasswordHash:  hashedPassword,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if instanceAdmin {
+		user.Flags |= models.UserFlagStaff
+	}
+	if err := s.users.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetInstanceAdmin grants or revokes instance admin, which is the staff
+// flag the admin API checks
+func (s *InstanceAdminService) SetInstanceAdmin(ctx context.Context, userID uuid.UUID, instanceAdmin bool) (*models.User, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	flags := user.Flags &^ models.UserFlagStaff
+	if instanceAdmin {
+		flags |= models.UserFlagStaff
+	}
+	if flags == user.Flags {
+		return user, nil
+	}
+	user.Flags = flags
+	user.UpdatedAt = s.now()
+	if err := s.users.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ResetPassword sets a new password for the user and logs them out
+// everywhere
+func (s *InstanceAdminService) ResetPassword(ctx context.Context, userID uuid.UUID, password string) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	hashedPassword, err := hashPassword(ctx, password)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = hashedPassword
+	user.UpdatedAt = s.now()
+	if err := s.users.Update(ctx, user); err != nil {
+		return err
+	}
+
+	if s.sessions != nil {
+		return s.sessions.RevokeAllSessions(ctx, user.ID)
+	}
+	return nil
+}
+
+// DeleteServer deletes a server whoever owns it and returns what was
+// deleted. Without an event bus, as from `hearth admin`, running instances
+// don't hear about it, so connected members keep seeing the server until
+// they reconnect.
+func (s *InstanceAdminService) DeleteServer(ctx context.Context, serverID uuid.UUID) (*models.Server, error) {
+	server, err := s.servers.GetByID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if server == nil {
+		return nil, ErrServerNotFound
+	}
+	if err := s.servers.Delete(ctx, serverID); err != nil {
+		return nil, err
+	}
+	if s.eventBus != nil {
+		s.eventBus.Publish("server.deleted", &ServerDeletedEvent{
+			ServerID: server.ID,
+			OwnerID:  server.OwnerID,
+		})
+	}
+	return server, nil
+}
+
+// Stats counts what the instance holds
+func (s *InstanceAdminService) Stats(ctx context.Context) (*models.InstanceStats, error) {
+	return s.stats.Stats(ctx)
+}
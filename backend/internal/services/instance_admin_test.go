@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/auth"
+	"hearth/internal/models"
+)
+
+// fakeInstanceAdminRepo stores users and servers in memory
+type fakeInstanceAdminRepo struct {
+	users   map[uuid.UUID]*models.User
+	servers map[uuid.UUID]*models.Server
+}
+
+func (r *fakeInstanceAdminRepo) Create(ctx context.Context, user *models.User) error {
+	for _, u := range r.users {
+		if strings.EqualFold(u.Username, user.Username) {
+			return ErrUsernameTaken
+		}
+	}
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+func (r *fakeInstanceAdminRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *fakeInstanceAdminRepo) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	for _, user := range r.users {
+		if strings.EqualFold(user.Username, username) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeInstanceAdminRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+func (r *fakeInstanceAdminRepo) Update(ctx context.Context, user *models.User) error {
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+func (r *fakeInstanceAdminRepo) List(ctx context.Context, query string, after *uuid.UUID, limit int) ([]*models.User, error) {
+	var users []*models.User
+	for _, user := range r.users {
+		if strings.HasPrefix(strings.ToLower(user.Username), strings.ToLower(query)) ||
+			strings.HasPrefix(strings.ToLower(user.Email), strings.ToLower(query)) {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].CreatedAt.Before(users[j].CreatedAt) })
+	if after != nil {
+		for i, user := range users {
+			if user.ID == *after {
+				users = users[i+1:]
+				break
+			}
+		}
+	}
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+// fakeInstanceAdminServers adapts the fake's servers to
+// InstanceAdminServerRepository
+type fakeInstanceAdminServers struct{ *fakeInstanceAdminRepo }
+
+func (r fakeInstanceAdminServers) GetByID(ctx context.Context, id uuid.UUID) (*models.Server, error) {
+	return r.servers[id], nil
+}
+
+func (r fakeInstanceAdminServers) Delete(ctx context.Context, id uuid.UUID) error {
+	delete(r.servers, id)
+	return nil
+}
+
+type fakeInstanceStats models.InstanceStats
+
+func (s fakeInstanceStats) Stats(ctx context.Context) (*models.InstanceStats, error) {
+	stats := models.InstanceStats(s)
+	return &stats, nil
+}
+
+func newInstanceAdminTest() (*InstanceAdminService, *fakeInstanceAdminRepo, *fakeSessionRepo) {
+	repo := &fakeInstanceAdminRepo{
+		users:   make(map[uuid.UUID]*models.User),
+		servers: make(map[uuid.UUID]*models.Server),
+	}
+	sessions := newFakeSessionRepo()
+	svc := NewInstanceAdminService(repo, fakeInstanceAdminServers{repo}, fakeInstanceStats{Users: 3}, NewSessionService(sessions, testJWTService()))
+	return svc, repo, sessions
+}
+
+func TestInstanceAdminService_CreateUser(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, _ := newInstanceAdminTest()
+
+	user, err := svc.CreateUser(ctx, "root@example.com", "admin", "Sup3rSecret", true)
+	require.NoError(t, err)
+	assert.NotZero(t, user.Flags&models.UserFlagStaff)
+	assert.Equal(t, models.LegacyDiscriminator, user.Discriminator)
+	assert.False(t, user.CreatedAt.IsZero())
+	require.NoError(t, auth.CheckPassword("Sup3rSecret", repo.users[user.ID].PasswordHash))
+
+	plain, err := svc.CreateUser(ctx, "bob@example.com", "bob", "Sup3rSecret", false)
+	require.NoError(t, err)
+	assert.Zero(t, plain.Flags&models.UserFlagStaff)
+
+	_, err = svc.CreateUser(ctx, "root@example.com", "other", "Sup3rSecret", false)
+	assert.ErrorIs(t, err, ErrEmailTaken)
+	_, err = svc.CreateUser(ctx, "new@example.com", "has space", "Sup3rSecret", false)
+	assert.ErrorIs(t, err, ErrInvalidUsername)
+	_, err = svc.CreateUser(ctx, "new@example.com", "newbie", "weak", false)
+	assert.ErrorIs(t, err, ErrPasswordTooShort)
+	assert.Len(t, repo.users, 2)
+}
+
+func TestInstanceAdminService_FindUser(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newInstanceAdminTest()
+	user, err := svc.CreateUser(ctx, "alice@example.com", "alice", "Sup3rSecret", false)
+	require.NoError(t, err)
+
+	for _, ref := range []string{user.ID.String(), "alice@example.com", "Alice"} {
+		found, err := svc.FindUser(ctx, ref)
+		require.NoError(t, err, ref)
+		assert.Equal(t, user.ID, found.ID, ref)
+	}
+
+	for _, ref := range []string{uuid.NewString(), "nobody@example.com", "nobody"} {
+		_, err := svc.FindUser(ctx, ref)
+		assert.ErrorIs(t, err, ErrUserNotFound, ref)
+	}
+}
+
+func TestInstanceAdminService_SetInstanceAdmin(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, _ := newInstanceAdminTest()
+	user, err := svc.CreateUser(ctx, "alice@example.com", "alice", "Sup3rSecret", false)
+	require.NoError(t, err)
+	repo.users[user.ID].Flags |= models.UserFlagPremium
+
+	granted, err := svc.SetInstanceAdmin(ctx, user.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, models.UserFlagStaff|models.UserFlagPremium, granted.Flags)
+	assert.Equal(t, granted.Flags, repo.users[user.ID].Flags)
+
+	revoked, err := svc.SetInstanceAdmin(ctx, user.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, models.UserFlagPremium, revoked.Flags, "other flags are kept")
+
+	_, err = svc.SetInstanceAdmin(ctx, uuid.New(), true)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestInstanceAdminService_ResetPassword(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, sessions := newInstanceAdminTest()
+	user, err := svc.CreateUser(ctx, "alice@example.com", "alice", "Sup3rSecret", false)
+	require.NoError(t, err)
+	sessions.sessions[uuid.New()] = &models.Session{UserID: user.ID}
+
+	require.NoError(t, svc.ResetPassword(ctx, user.ID, "N3wPassword"))
+	assert.NoError(t, auth.CheckPassword("N3wPassword", repo.users[user.ID].PasswordHash))
+	assert.Empty(t, sessions.sessions, "the user is logged out everywhere")
+	assert.Equal(t, 1, sessions.versions[user.ID], "older refresh tokens are refused")
+
+	assert.ErrorIs(t, svc.ResetPassword(ctx, user.ID, "weakpassword"), ErrPasswordWeak)
+	assert.ErrorIs(t, svc.ResetPassword(ctx, uuid.New(), "N3wPassword"), ErrUserNotFound)
+}
+
+func TestInstanceAdminService_DeleteServer(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, _ := newInstanceAdminTest()
+	server := &models.Server{ID: uuid.New(), Name: "Spam", OwnerID: uuid.New()}
+	repo.servers[server.ID] = server
+
+	deleted, err := svc.DeleteServer(ctx, server.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Spam", deleted.Name)
+	assert.Empty(t, repo.servers)
+
+	_, err = svc.DeleteServer(ctx, server.ID)
+	assert.ErrorIs(t, err, ErrServerNotFound)
+}
+
+func TestInstanceAdminService_ListUsers(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, _ := newInstanceAdminTest()
+	start := time.Now()
+	var ids []uuid.UUID
+	for i, name := range []string{"alice", "albert", "bob"} {
+		user := &models.User{ID: uuid.New(), Username: name, Email: name + "@example.com", CreatedAt: start.Add(time.Duration(i) * time.Second)}
+		repo.users[user.ID] = user
+		ids = append(ids, user.ID)
+	}
+	suspensions := &fakeSuspensionRepo{suspensions: map[uuid.UUID]*models.UserSuspension{
+		ids[1]: {UserID: ids[1]},
+	}}
+	svc.SetSuspensions(NewUserSuspensionService(suspensions, repo, nil))
+
+	users, err := svc.ListUsers(ctx, "AL", nil, 0)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, ids[0], users[0].ID)
+	assert.Nil(t, users[0].Suspension)
+	assert.Equal(t, ids[1], users[1].Suspension.UserID)
+
+	users, err = svc.ListUsers(ctx, "", &ids[0], 1)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, ids[1], users[0].ID, "pages continue after the last user")
+}
+
+func TestInstanceAdminService_DeleteServerPublishes(t *testing.T) {
+	svc, repo, _ := newInstanceAdminTest()
+	bus := new(MockEventBus)
+	svc.SetEventBus(bus)
+	server := &models.Server{ID: uuid.New(), Name: "Spam", OwnerID: uuid.New()}
+	repo.servers[server.ID] = server
+
+	bus.On("Publish", "server.deleted", &ServerDeletedEvent{ServerID: server.ID, OwnerID: server.OwnerID}).Return().Once()
+	_, err := svc.DeleteServer(context.Background(), server.ID)
+	require.NoError(t, err)
+	bus.AssertExpectations(t)
+}
+
+func TestInstanceAdminService_Stats(t *testing.T) {
+	svc, _, _ := newInstanceAdminTest()
+	stats, err := svc.Stats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.Users)
+}
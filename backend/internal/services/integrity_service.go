@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrIntegrityCheckRunning = errors.New("integrity check already running")
+
+// Integrity checks
+const (
+	IntegrityOrphanedChannels = "orphaned_channels"
+	IntegrityOrphanedMembers  = "orphaned_members"
+	IntegrityChannelPositions = "channel_positions"
+	IntegrityRolePositions    = "role_positions"
+)
+
+// IntegrityRepository finds and repairs inconsistent rows
+type IntegrityRepository interface {
+	CountOrphanedChannels(ctx context.Context) (int, error)
+	DeleteOrphanedChannels(ctx context.Context) (int, error)
+	CountOrphanedMembers(ctx context.Context) (int, error)
+	DeleteOrphanedMembers(ctx context.Context) (int, error)
+	ServersWithChannelPositionDrift(ctx context.Context) ([]uuid.UUID, error)
+	ServersWithRolePositionDrift(ctx context.Context) ([]uuid.UUID, error)
+	CompactChannelPositions(ctx context.Context, serverID uuid.UUID) (int, error)
+	CompactRolePositions(ctx context.Context, serverID uuid.UUID) (int, error)
+}
+
+// IntegrityRecorder reports check results, e.g. as metrics
+type IntegrityRecorder interface {
+	CheckCompleted(check string, found, repaired int)
+	RunCompleted(at time.Time, failed bool)
+}
+
+// IntegrityFinding is the result of one check. For position checks Found
+// counts affected servers.
+type IntegrityFinding struct {
+	Check     string      `json:"check"`
+	Found     int         `json:"found"`
+	Repaired  int         `json:"repaired"`
+	ServerIDs []uuid.UUID `json:"server_ids,omitempty"`
+}
+
+// IntegrityReport is the result of an integrity run
+type IntegrityReport struct {
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt time.Time          `json:"finished_at"`
+	Repair     bool               `json:"repair"`
+	Findings   []IntegrityFinding `json:"findings"`
+}
+
+// IntegrityService detects and repairs inconsistent position sequences and
+// orphaned rows
+type IntegrityService struct {
+	repo     IntegrityRepository
+	recorder IntegrityRecorder
+
+	running sync.Mutex
+}
+
+func NewIntegrityService(repo IntegrityRepository) *IntegrityService {
+	return &IntegrityService{repo: repo}
+}
+
+// SetRecorder sets where check results are reported
+func (s *IntegrityService) SetRecorder(recorder IntegrityRecorder) {
+	s.recorder = recorder
+}
+
+// Check runs every integrity check, repairing what it finds if repair is
+// set. Orphans are removed before positions are compacted so deleted rows
+// don't leave new gaps.
+func (s *IntegrityService) Check(ctx context.Context, repair bool) (*IntegrityReport, error) {
+	if !s.running.TryLock() {
+		return nil, ErrIntegrityCheckRunning
+	}
+	defer s.running.Unlock()
+
+	report := &IntegrityReport{StartedAt: time.Now(), Repair: repair}
+	checks := []func(context.Context, bool) (IntegrityFinding, error){
+		s.orphans(IntegrityOrphanedChannels, s.repo.CountOrphanedChannels, s.repo.DeleteOrphanedChannels),
+		s.orphans(IntegrityOrphanedMembers, s.repo.CountOrphanedMembers, s.repo.DeleteOrphanedMembers),
+		s.positions(IntegrityChannelPositions, s.repo.ServersWithChannelPositionDrift, s.repo.CompactChannelPositions),
+		s.positions(IntegrityRolePositions, s.repo.ServersWithRolePositionDrift, s.repo.CompactRolePositions),
+	}
+
+	for _, check := range checks {
+		finding, err := check(ctx, repair)
+		if err != nil {
+			if s.recorder != nil {
+				s.recorder.RunCompleted(time.Now(), true)
+			}
+			return nil, err
+		}
+		if s.recorder != nil {
+			s.recorder.CheckCompleted(finding.Check, finding.Found, finding.Repaired)
+		}
+		report.Findings = append(report.Findings, finding)
+	}
+
+	report.FinishedAt = time.Now()
+	if s.recorder != nil {
+		s.recorder.RunCompleted(report.FinishedAt, false)
+	}
+	return report, nil
+}
+
+func (s *IntegrityService) orphans(
+	name string,
+	count func(context.Context) (int, error),
+	remove func(context.Context) (int, error),
+) func(context.Context, bool) (IntegrityFinding, error) {
+	return func(ctx context.Context, repair bool) (IntegrityFinding, error) {
+		finding := IntegrityFinding{Check: name}
+		found, err := count(ctx)
+		if err != nil {
+			return finding, err
+		}
+		finding.Found = found
+		if repair && found > 0 {
+			if finding.Repaired, err = remove(ctx); err != nil {
+				return finding, err
+			}
+		}
+		return finding, nil
+	}
+}
+
+func (s *IntegrityService) positions(
+	name string,
+	find func(context.Context) ([]uuid.UUID, error),
+	compact func(context.Context, uuid.UUID) (int, error),
+) func(context.Context, bool) (IntegrityFinding, error) {
+	return func(ctx context.Context, repair bool) (IntegrityFinding, error) {
+		finding := IntegrityFinding{Check: name}
+		serverIDs, err := find(ctx)
+		if err != nil {
+			return finding, err
+		}
+		finding.Found = len(serverIDs)
+		finding.ServerIDs = serverIDs
+		if !repair {
+			return finding, nil
+		}
+		for _, serverID := range serverIDs {
+			if _, err := compact(ctx, serverID); err != nil {
+				return finding, err
+			}
+			finding.Repaired++
+		}
+		return finding, nil
+	}
+}
+
+// RunIntegrityChecks checks, and optionally repairs, the database every
+// interval until ctx is cancelled
+func (s *IntegrityService) RunIntegrityChecks(ctx context.Context, interval time.Duration, repair bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := s.Check(ctx, repair)
+			if err != nil {
+				if !errors.Is(err, ErrIntegrityCheckRunning) {
+					log.Printf("[IntegrityService] check failed: %v", err)
+				}
+				continue
+			}
+			for _, f := range report.Findings {
+				if f.Found > 0 {
+					log.Printf("[IntegrityService] %s: found %d, repaired %d", f.Check, f.Found, f.Repaired)
+				}
+			}
+		}
+	}
+}
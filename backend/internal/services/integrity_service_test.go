@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeIntegrityRepository struct {
+	orphanedChannels int
+	orphanedMembers  int
+	channelDrift     []uuid.UUID
+	roleDrift        []uuid.UUID
+	compacted        []uuid.UUID
+	err              error
+}
+
+func (r *fakeIntegrityRepository) CountOrphanedChannels(ctx context.Context) (int, error) {
+	return r.orphanedChannels, r.err
+}
+
+func (r *fakeIntegrityRepository) DeleteOrphanedChannels(ctx context.Context) (int, error) {
+	n := r.orphanedChannels
+	r.orphanedChannels = 0
+	return n, nil
+}
+
+func (r *fakeIntegrityRepository) CountOrphanedMembers(ctx context.Context) (int, error) {
+	return r.orphanedMembers, nil
+}
+
+func (r *fakeIntegrityRepository) DeleteOrphanedMembers(ctx context.Context) (int, error) {
+	n := r.orphanedMembers
+	r.orphanedMembers = 0
+	return n, nil
+}
+
+func (r *fakeIntegrityRepository) ServersWithChannelPositionDrift(ctx context.Context) ([]uuid.UUID, error) {
+	return r.channelDrift, nil
+}
+
+func (r *fakeIntegrityRepository) ServersWithRolePositionDrift(ctx context.Context) ([]uuid.UUID, error) {
+	return r.roleDrift, nil
+}
+
+func (r *fakeIntegrityRepository) CompactChannelPositions(ctx context.Context, serverID uuid.UUID) (int, error) {
+	r.compacted = append(r.compacted, serverID)
+	return 2, nil
+}
+
+func (r *fakeIntegrityRepository) CompactRolePositions(ctx context.Context, serverID uuid.UUID) (int, error) {
+	r.compacted = append(r.compacted, serverID)
+	return 1, nil
+}
+
+type recordedCheck struct {
+	check           string
+	found, repaired int
+}
+
+type fakeIntegrityRecorder struct {
+	checks []recordedCheck
+	failed []bool
+}
+
+func (r *fakeIntegrityRecorder) CheckCompleted(check string, found, repaired int) {
+	r.checks = append(r.checks, recordedCheck{check, found, repaired})
+}
+
+func (r *fakeIntegrityRecorder) RunCompleted(at time.Time, failed bool) {
+	r.failed = append(r.failed, failed)
+}
+
+func TestIntegrityService_CheckOnly(t *testing.T) {
+	serverID := uuid.New()
+	repo := &fakeIntegrityRepository{orphanedMembers: 3, channelDrift: []uuid.UUID{serverID}}
+	recorder := &fakeIntegrityRecorder{}
+	svc := NewIntegrityService(repo)
+	svc.SetRecorder(recorder)
+
+	report, err := svc.Check(context.Background(), false)
+	require.NoError(t, err)
+	assert.False(t, report.Repair)
+	require.Len(t, report.Findings, 4)
+	assert.Equal(t, IntegrityFinding{Check: IntegrityOrphanedMembers, Found: 3}, report.Findings[1])
+	assert.Equal(t, IntegrityFinding{Check: IntegrityChannelPositions, Found: 1, ServerIDs: []uuid.UUID{serverID}}, report.Findings[2])
+
+	// Nothing changed
+	assert.Equal(t, 3, repo.orphanedMembers)
+	assert.Empty(t, repo.compacted)
+
+	assert.Len(t, recorder.checks, 4)
+	assert.Equal(t, []bool{false}, recorder.failed)
+}
+
+func TestIntegrityService_Repair(t *testing.T) {
+	first := uuid.New()
+	second := uuid.New()
+	repo := &fakeIntegrityRepository{
+		orphanedChannels: 2,
+		channelDrift:     []uuid.UUID{first},
+		roleDrift:        []uuid.UUID{second},
+	}
+	recorder := &fakeIntegrityRecorder{}
+	svc := NewIntegrityService(repo)
+	svc.SetRecorder(recorder)
+
+	report, err := svc.Check(context.Background(), true)
+	require.NoError(t, err)
+	assert.Equal(t, IntegrityFinding{Check: IntegrityOrphanedChannels, Found: 2, Repaired: 2}, report.Findings[0])
+	assert.Equal(t, 1, report.Findings[2].Repaired)
+	assert.Equal(t, 1, report.Findings[3].Repaired)
+	assert.Equal(t, []uuid.UUID{first, second}, repo.compacted)
+	assert.Equal(t, 0, repo.orphanedChannels)
+	assert.Contains(t, recorder.checks, recordedCheck{IntegrityOrphanedChannels, 2, 2})
+}
+
+func TestIntegrityService_Error(t *testing.T) {
+	recorder := &fakeIntegrityRecorder{}
+	svc := NewIntegrityService(&fakeIntegrityRepository{err: errors.New("db down")})
+	svc.SetRecorder(recorder)
+
+	_, err := svc.Check(context.Background(), true)
+	assert.Error(t, err)
+	assert.Equal(t, []bool{true}, recorder.failed)
+}
+
+func TestIntegrityService_OneRunAtATime(t *testing.T) {
+	svc := NewIntegrityService(&fakeIntegrityRepository{})
+	svc.running.Lock()
+	defer svc.running.Unlock()
+
+	_, err := svc.Check(context.Background(), false)
+	assert.ErrorIs(t, err, ErrIntegrityCheckRunning)
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -45,19 +46,12 @@ type RateLimiter interface {
 	Reset(ctx context.Context, userID, channelID uuid.UUID) error
 }
 
-// E2EEService defines E2EE operations
+// E2EEService checks messages sent to end-to-end encrypted channels
 type E2EEService interface {
-	// Validate that a payload is properly formatted encrypted content
-	ValidateEncryptedPayload(payload string) bool
-
-	// Key management for DMs
-	GetPreKeys(ctx context.Context, userID uuid.UUID) (*models.PreKeyBundle, error)
-	UploadPreKeys(ctx context.Context, userID uuid.UUID, bundle *models.PreKeyBundle) error
-
-	// Group key management (MLS)
-	CreateGroup(ctx context.Context, channelID uuid.UUID, memberIDs []uuid.UUID) error
-	AddGroupMember(ctx context.Context, channelID, userID uuid.UUID) error
-	RemoveGroupMember(ctx context.Context, channelID, userID uuid.UUID) error
+	// CheckPayload returns ErrInvalidEncryptedPayload unless payload is an
+	// encrypted envelope, and ErrKeyEpochStale unless it was sealed with
+	// the channel's current key epoch
+	CheckPayload(ctx context.Context, channelID uuid.UUID, payload string) error
 }
 
 // ChannelRepository defines channel data access
@@ -106,6 +100,13 @@ type QuotaService struct {
 	serverRepo ServerRepository
 	userRepo   UserRepository
 	roleRepo   RoleRepository
+	overrides  QuotaOverrideRepository
+	now        func() time.Time
+
+	mu              sync.Mutex
+	tierOverrides   map[models.QuotaTier]*models.QuotaOverride
+	overridesExpire time.Time
+	userTiers       map[uuid.UUID]cachedQuotaTier
 }
 
 // NewQuotaService creates a new quota service
@@ -115,30 +116,43 @@ func NewQuotaService(config *models.QuotaConfig, serverRepo ServerRepository, us
 		serverRepo: serverRepo,
 		userRepo:   userRepo,
 		roleRepo:   roleRepo,
+		now:        time.Now,
+		userTiers:  make(map[uuid.UUID]cachedQuotaTier),
 	}
 }
 
 // EffectiveLimits for quota checks
 type EffectiveLimits struct {
-	MaxMessageLength int
-	MaxServersOwned  int
-	MaxServersJoined int
-	StorageMB        int64
-	MaxFileSizeMB    int64
+	MaxMessageLength       int
+	MaxReactionsPerMessage int // Distinct emoji; 0 = unlimited
+	MaxServersOwned        int
+	MaxServersJoined       int
+	StorageMB              int64
+	MaxFileSizeMB          int64
+	MaxEmoji               int // Static emoji per server; 0 = unlimited
+	MaxEmojiAnimated       int // Animated emoji per server; 0 = unlimited
+	MaxEmojiSizeMB         int64
 }
 
 // GetEffectiveLimits calculates effective limits for a user
 func (s *QuotaService) GetEffectiveLimits(ctx context.Context, userID uuid.UUID, serverID *uuid.UUID) (*EffectiveLimits, error) {
 	// Start with instance defaults
 	limits := &EffectiveLimits{
-		MaxMessageLength: s.config.Messages.MaxMessageLength,
-		MaxServersOwned:  s.config.Servers.MaxServersOwned,
-		MaxServersJoined: s.config.Servers.MaxServersJoined,
-		StorageMB:        s.config.Storage.UserStorageMB,
-		MaxFileSizeMB:    s.config.Storage.MaxFileSizeMB,
+		MaxMessageLength:       s.config.Messages.MaxMessageLength,
+		MaxReactionsPerMessage: s.config.Messages.MaxReactionsPerMessage,
+		MaxServersOwned:        s.config.Servers.MaxServersOwned,
+		MaxServersJoined:       s.config.Servers.MaxServersJoined,
+		StorageMB:              s.config.Storage.UserStorageMB,
+		MaxFileSizeMB:          s.config.Storage.MaxFileSizeMB,
+		MaxEmoji:               s.config.Servers.MaxEmoji,
+		MaxEmojiAnimated:       s.config.Servers.MaxEmojiAnimated,
+		MaxEmojiSizeMB:         s.config.Storage.MaxEmojiSizeMB,
 	}
 
-	// TODO: Apply server, role, and user overrides
+	// Apply the overrides instance admins set for the user's tier
+	s.applyOverrides(ctx, userID, limits)
+
+	// TODO: Apply server and role overrides
 
 	return limits, nil
 }
@@ -178,6 +178,7 @@ func (s *InviteService) UseInvite(ctx context.Context, code string, userID uuid.
 		ServerID:   invite.ServerID,
 		UserID:     userID,
 		InviteCode: code,
+		Member:     member,
 	})
 
 	return server, nil
@@ -314,6 +315,7 @@ type MemberJoinedEvent struct {
 	ServerID   uuid.UUID
 	UserID     uuid.UUID
 	InviteCode string
+	Member     *models.Member
 }
 
 type MemberBannedEvent struct {
@@ -323,6 +325,7 @@ type MemberBannedEvent struct {
 	Reason      string
 }
 
+// MemberUnbannedEvent has a nil ModeratorID when a timed ban ran out
 type MemberUnbannedEvent struct {
 	ServerID    uuid.UUID
 	UserID      uuid.UUID
@@ -0,0 +1,476 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	maxIPRules      = 10000
+	maxIPRuleReason = 255
+
+	// ipRuleCacheTTL bounds how long another instance's rule changes take
+	// to apply here. Changes made on this instance apply straight away.
+	ipRuleCacheTTL = time.Minute
+	// ipLookupTimeout bounds an ASN or reputation lookup, which the
+	// request waits on the first time its address is seen
+	ipLookupTimeout = 2 * time.Second
+	// ipLookupCacheSize caps the cached lookups; the cache starts over once
+	// it is full
+	ipLookupCacheSize = 10000
+)
+
+// IPRuleRepository stores the networks instance admins block or allow. The
+// Postgres IPRuleRepository implements it.
+type IPRuleRepository interface {
+	List(ctx context.Context) ([]*models.IPRule, error)
+	Create(ctx context.Context, rule *models.IPRule) error
+	Delete(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+// IPReputationProvider reports whether an outside source, such as a DNS
+// blocklist, lists an address as abusive. ipreputation.DNSBL implements it.
+type IPReputationProvider interface {
+	Name() string
+	Listed(ctx context.Context, ip net.IP) (bool, error)
+}
+
+// ASNResolver finds the autonomous system announcing an address, or 0 if
+// none does. ipreputation.CymruASN implements it.
+type ASNResolver interface {
+	ASN(ctx context.Context, ip net.IP) (int64, error)
+}
+
+// IPFilterConfig holds the networks the instance's config blocks and
+// allows. Admins' rules apply on top of them.
+type IPFilterConfig struct {
+	// Blocklist and Allowlist are CIDR ranges or single addresses
+	Blocklist []string
+	Allowlist []string
+	// LookupTTL is how long ASN and reputation lookups are remembered.
+	// Defaults to 10 minutes.
+	LookupTTL time.Duration
+}
+
+// IPFilterService decides which client addresses the instance serves. It
+// checks them against CIDR and ASN rules from the config and from instance
+// admins, then against reputation providers. Allow rules win over
+// everything else.
+type IPFilterService struct {
+	repo      IPRuleRepository
+	lookupTTL time.Duration
+	providers []IPReputationProvider
+	asns      ASNResolver
+	now       func() time.Time
+
+	mu          sync.Mutex
+	blocklist   []*net.IPNet
+	allowlist   []*net.IPNet
+	rules       []*compiledIPRule
+	rulesExpire time.Time
+	lookups     map[string]cachedIPLookup
+}
+
+type compiledIPRule struct {
+	rule    *models.IPRule
+	network *net.IPNet
+}
+
+// cachedIPLookup is what the ASN resolver and reputation providers said
+// about an address
+type cachedIPLookup struct {
+	asn      int64
+	asnDone  bool
+	listedBy string
+	repDone  bool
+	expires  time.Time
+}
+
+// NewIPFilterService creates an IP filter. It fails if the config lists
+// something that isn't a CIDR range or an address.
+func NewIPFilterService(repo IPRuleRepository, config IPFilterConfig) (*IPFilterService, error) {
+	if config.LookupTTL <= 0 {
+		config.LookupTTL = 10 * time.Minute
+	}
+	s := &IPFilterService{
+		repo:      repo,
+		lookupTTL: config.LookupTTL,
+		now:       time.Now,
+		lookups:   make(map[string]cachedIPLookup),
+	}
+	if err := s.SetLists(config.Blocklist, config.Allowlist); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetLists replaces the networks blocked and allowed by the config, e.g.
+// when the config is reloaded. It keeps the old lists if either doesn't
+// parse.
+func (s *IPFilterService) SetLists(block, allow []string) error {
+	blocklist, err := parseNetworks(block)
+	if err != nil {
+		return fmt.Errorf("blocklist: %w", err)
+	}
+	allowlist, err := parseNetworks(allow)
+	if err != nil {
+		return fmt.Errorf("allowlist: %w", err)
+	}
+	s.mu.Lock()
+	s.blocklist = blocklist
+	s.allowlist = allowlist
+	s.mu.Unlock()
+	return nil
+}
+
+// SetReputationProviders blocks addresses any of the providers list.
+// Private and loopback addresses aren't looked up.
+func (s *IPFilterService) SetReputationProviders(providers ...IPReputationProvider) {
+	s.providers = providers
+}
+
+// SetASNResolver enables rules by autonomous system number. Without one,
+// ASN rules are kept but don't match.
+func (s *IPFilterService) SetASNResolver(resolver ASNResolver) {
+	s.asns = resolver
+}
+
+// ListRules returns every admin rule, including expired ones, oldest first
+func (s *IPFilterService) ListRules(ctx context.Context) ([]*models.IPRule, error) {
+	return s.repo.List(ctx)
+}
+
+func invalidIPRule(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidIPRule, fmt.Sprintf(format, args...))
+}
+
+// CreateRule adds a rule. CIDR ranges are stored in canonical form.
+func (s *IPFilterService) CreateRule(ctx context.Context, creatorID uuid.UUID, req *models.CreateIPRuleRequest) (*models.IPRule, error) {
+	if !req.Action.Valid() {
+		return nil, invalidIPRule("action must be block or allow")
+	}
+	if (req.CIDR == nil) == (req.ASN == nil) {
+		return nil, invalidIPRule("give either a cidr or an asn")
+	}
+	rule := &models.IPRule{
+		ID:        uuid.New(),
+		Action:    req.Action,
+		CreatorID: &creatorID,
+		CreatedAt: s.now(),
+	}
+	if req.CIDR != nil {
+		network, err := parseNetwork(*req.CIDR)
+		if err != nil {
+			return nil, invalidIPRule("%v", err)
+		}
+		cidr := network.String()
+		rule.CIDR = &cidr
+	}
+	if req.ASN != nil {
+		if *req.ASN <= 0 || *req.ASN > 1<<32-1 {
+			return nil, invalidIPRule("asn must be between 1 and 4294967295")
+		}
+		asn := *req.ASN
+		rule.ASN = &asn
+	}
+	if req.Reason != nil {
+		if trimmed := strings.TrimSpace(*req.Reason); trimmed != "" {
+			if len(trimmed) > maxIPRuleReason {
+				return nil, invalidIPRule("reason must be at most %d characters", maxIPRuleReason)
+			}
+			rule.Reason = &trimmed
+		}
+	}
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(rule.CreatedAt) {
+			return nil, invalidIPRule("expires_at must be in the future")
+		}
+		expiresAt := *req.ExpiresAt
+		rule.ExpiresAt = &expiresAt
+	}
+
+	existing, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxIPRules {
+		return nil, invalidIPRule("an instance can have at most %d ip rules", maxIPRules)
+	}
+	if err := s.repo.Create(ctx, rule); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return rule, nil
+}
+
+// DeleteRule removes a rule
+func (s *IPFilterService) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	deleted, err := s.repo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrIPRuleNotFound
+	}
+	s.invalidate()
+	return nil
+}
+
+// Check decides whether to serve requests from addr. It fails open:
+// addresses that don't parse are served, and lookups that fail are logged
+// and count as no match.
+func (s *IPFilterService) Check(ctx context.Context, addr string) *models.IPVerdict {
+	verdict := &models.IPVerdict{IP: addr}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return verdict
+	}
+
+	s.mu.Lock()
+	blocklist, allowlist := s.blocklist, s.allowlist
+	s.mu.Unlock()
+	if containsIP(allowlist, ip) {
+		verdict.Allowed = true
+		return verdict
+	}
+	blockedByConfig := containsIP(blocklist, ip)
+
+	// Any allow rule wins, so every rule is checked before blocking
+	rules := s.activeRules(ctx)
+	var block *compiledIPRule
+	needASN := false
+	for _, rule := range rules {
+		if rule.network == nil {
+			needASN = true
+			continue
+		}
+		if !rule.network.Contains(ip) {
+			continue
+		}
+		if rule.rule.Action == models.IPRuleAllow {
+			return allowedBy(verdict, rule)
+		}
+		if block == nil {
+			block = rule
+		}
+	}
+
+	if needASN && s.asns != nil {
+		if asn := s.lookupASN(ctx, ip); asn != 0 {
+			verdict.ASN = &asn
+			for _, rule := range rules {
+				if rule.rule.ASN == nil || *rule.rule.ASN != asn {
+					continue
+				}
+				if rule.rule.Action == models.IPRuleAllow {
+					return allowedBy(verdict, rule)
+				}
+				if block == nil {
+					block = rule
+				}
+			}
+		}
+	}
+
+	switch {
+	case blockedByConfig:
+		verdict.Blocked, verdict.Reason = true, models.IPBlockedCIDR
+	case block != nil:
+		verdict.Blocked, verdict.RuleID = true, &block.rule.ID
+		verdict.Reason = models.IPBlockedCIDR
+		if block.rule.ASN != nil {
+			verdict.Reason = models.IPBlockedASN
+		}
+	default:
+		if provider := s.lookupReputation(ctx, ip); provider != "" {
+			verdict.Blocked, verdict.Reason, verdict.Provider = true, models.IPBlockedReputation, provider
+		}
+	}
+	return verdict
+}
+
+func allowedBy(verdict *models.IPVerdict, rule *compiledIPRule) *models.IPVerdict {
+	verdict.Allowed = true
+	verdict.RuleID = &rule.rule.ID
+	return verdict
+}
+
+// activeRules returns the admin rules that haven't expired. Lookups that
+// fail are logged and the rules loaded last keep applying.
+func (s *IPFilterService) activeRules(ctx context.Context) []*compiledIPRule {
+	now := s.now()
+	s.mu.Lock()
+	rules, expires := s.rules, s.rulesExpire
+	s.mu.Unlock()
+	if now.Before(expires) {
+		return unexpiredIPRules(rules, now)
+	}
+
+	stored, err := s.repo.List(ctx)
+	if err != nil {
+		log.Printf("[IPFilter] failed to look up ip rules: %v", err)
+		return unexpiredIPRules(rules, now)
+	}
+	compiled := make([]*compiledIPRule, 0, len(stored))
+	for _, rule := range stored {
+		c := &compiledIPRule{rule: rule}
+		if rule.CIDR != nil {
+			network, err := parseNetwork(*rule.CIDR)
+			if err != nil {
+				log.Printf("[IPFilter] Skipping rule %s: %v", rule.ID, err)
+				continue
+			}
+			c.network = network
+		} else if rule.ASN == nil {
+			continue
+		}
+		compiled = append(compiled, c)
+	}
+
+	s.mu.Lock()
+	s.rules = compiled
+	s.rulesExpire = now.Add(ipRuleCacheTTL)
+	s.mu.Unlock()
+	return unexpiredIPRules(compiled, now)
+}
+
+func unexpiredIPRules(rules []*compiledIPRule, now time.Time) []*compiledIPRule {
+	active := rules[:0:0]
+	for _, rule := range rules {
+		if rule.rule.ExpiresAt == nil || now.Before(*rule.rule.ExpiresAt) {
+			active = append(active, rule)
+		}
+	}
+	return active
+}
+
+// lookupASN returns the autonomous system announcing ip, or 0 if it's
+// unknown or the lookup failed
+func (s *IPFilterService) lookupASN(ctx context.Context, ip net.IP) int64 {
+	if cached, ok := s.cachedLookup(ip); ok && cached.asnDone {
+		return cached.asn
+	}
+	ctx, cancel := context.WithTimeout(ctx, ipLookupTimeout)
+	defer cancel()
+	asn, err := s.asns.ASN(ctx, ip)
+	if err != nil {
+		log.Printf("[IPFilter] failed to look up the ASN of %s: %v", ip, err)
+	}
+	s.updateLookup(ip, func(l *cachedIPLookup) { l.asn, l.asnDone = asn, true })
+	return asn
+}
+
+// lookupReputation returns the first provider that lists ip, or "" if
+// none does. Providers that fail are logged and skipped.
+func (s *IPFilterService) lookupReputation(ctx context.Context, ip net.IP) string {
+	if len(s.providers) == 0 || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+		return ""
+	}
+	if cached, ok := s.cachedLookup(ip); ok && cached.repDone {
+		return cached.listedBy
+	}
+	ctx, cancel := context.WithTimeout(ctx, ipLookupTimeout)
+	defer cancel()
+	listedBy := ""
+	for _, provider := range s.providers {
+		listed, err := provider.Listed(ctx, ip)
+		if err != nil {
+			log.Printf("[IPFilter] %s failed to look up %s: %v", provider.Name(), ip, err)
+			continue
+		}
+		if listed {
+			listedBy = provider.Name()
+			break
+		}
+	}
+	s.updateLookup(ip, func(l *cachedIPLookup) { l.listedBy, l.repDone = listedBy, true })
+	return listedBy
+}
+
+func (s *IPFilterService) cachedLookup(ip net.IP) (cachedIPLookup, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cached, ok := s.lookups[ip.String()]
+	if !ok || !s.now().Before(cached.expires) {
+		return cachedIPLookup{}, false
+	}
+	return cached, true
+}
+
+func (s *IPFilterService) updateLookup(ip net.IP, update func(*cachedIPLookup)) {
+	now := s.now()
+	key := ip.String()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cached, ok := s.lookups[key]
+	if !ok || !now.Before(cached.expires) {
+		if len(s.lookups) >= ipLookupCacheSize {
+			s.lookups = make(map[string]cachedIPLookup)
+		}
+		cached = cachedIPLookup{expires: now.Add(s.lookupTTL)}
+	}
+	update(&cached)
+	s.lookups[key] = cached
+}
+
+// invalidate drops the loaded rules so the next check reloads them
+func (s *IPFilterService) invalidate() {
+	s.mu.Lock()
+	s.rules = nil
+	s.rulesExpire = time.Time{}
+	s.mu.Unlock()
+}
+
+// parseNetwork parses a CIDR range, taking a single address as a range of
+// one
+func parseNetwork(value string) (*net.IPNet, error) {
+	value = strings.TrimSpace(value)
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("%q is not an address or a cidr range", value)
+		}
+		if v4 := ip.To4(); v4 != nil {
+			return &net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}, nil
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not an address or a cidr range", value)
+	}
+	return network, nil
+}
+
+func parseNetworks(values []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		network, err := parseNetwork(value)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
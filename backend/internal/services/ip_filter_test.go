@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeIPRuleRepo struct {
+	rules []*models.IPRule
+	lists int
+	err   error
+}
+
+func (r *fakeIPRuleRepo) List(ctx context.Context) ([]*models.IPRule, error) {
+	r.lists++
+	if r.err != nil {
+		return nil, r.err
+	}
+	return append([]*models.IPRule(nil), r.rules...), nil
+}
+
+func (r *fakeIPRuleRepo) Create(ctx context.Context, rule *models.IPRule) error {
+	for _, existing := range r.rules {
+		if existing.Action == rule.Action && (existing.CIDR != nil && rule.CIDR != nil && *existing.CIDR == *rule.CIDR ||
+			existing.ASN != nil && rule.ASN != nil && *existing.ASN == *rule.ASN) {
+			return ErrIPRuleExists
+		}
+	}
+	r.rules = append(r.rules, rule)
+	return nil
+}
+
+func (r *fakeIPRuleRepo) Delete(ctx context.Context, id uuid.UUID) (bool, error) {
+	for i, rule := range r.rules {
+		if rule.ID == id {
+			r.rules = append(r.rules[:i], r.rules[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fakeIPLookups answers ASN and reputation lookups from maps
+type fakeIPLookups struct {
+	name    string
+	asns    map[string]int64
+	listed  map[string]bool
+	err     error
+	lookups int
+}
+
+func (f *fakeIPLookups) Name() string { return f.name }
+
+func (f *fakeIPLookups) Listed(ctx context.Context, ip net.IP) (bool, error) {
+	f.lookups++
+	return f.listed[ip.String()], f.err
+}
+
+func (f *fakeIPLookups) ASN(ctx context.Context, ip net.IP) (int64, error) {
+	f.lookups++
+	return f.asns[ip.String()], f.err
+}
+
+func strPtr(v string) *string { return &v }
+
+func newIPFilterTest(t *testing.T, config IPFilterConfig) (*IPFilterService, *fakeIPRuleRepo) {
+	repo := &fakeIPRuleRepo{}
+	svc, err := NewIPFilterService(repo, config)
+	require.NoError(t, err)
+	return svc, repo
+}
+
+func cidrRule(action models.IPRuleAction, cidr string) *models.CreateIPRuleRequest {
+	return &models.CreateIPRuleRequest{Action: action, CIDR: &cidr}
+}
+
+func asnRule(action models.IPRuleAction, asn int64) *models.CreateIPRuleRequest {
+	return &models.CreateIPRuleRequest{Action: action, ASN: &asn}
+}
+
+func TestNewIPFilterService_InvalidConfig(t *testing.T) {
+	_, err := NewIPFilterService(&fakeIPRuleRepo{}, IPFilterConfig{Blocklist: []string{"10.0.0.0/8", "nope"}})
+	assert.Error(t, err)
+	_, err = NewIPFilterService(&fakeIPRuleRepo{}, IPFilterConfig{Allowlist: []string{"10.0.0.0/33"}})
+	assert.Error(t, err)
+}
+
+func TestIPFilterService_CIDRRules(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newIPFilterTest(t, IPFilterConfig{
+		Blocklist: []string{"198.51.100.0/24", " "},
+		Allowlist: []string{"198.51.100.7"},
+	})
+
+	assert.True(t, svc.Check(ctx, "198.51.100.1").Blocked)
+	assert.Equal(t, models.IPBlockedCIDR, svc.Check(ctx, "198.51.100.1").Reason)
+	assert.True(t, svc.Check(ctx, "198.51.100.7").Allowed, "the allowlist wins")
+	assert.False(t, svc.Check(ctx, "203.0.113.5").Blocked)
+	assert.False(t, svc.Check(ctx, "not an ip").Blocked)
+
+	rule, err := svc.CreateRule(ctx, uuid.New(), cidrRule(models.IPRuleBlock, "203.0.113.99/24"))
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.0/24", *rule.CIDR, "ranges are stored in canonical form")
+	verdict := svc.Check(ctx, "203.0.113.5")
+	assert.True(t, verdict.Blocked)
+	assert.Equal(t, rule.ID, *verdict.RuleID)
+
+	allow, err := svc.CreateRule(ctx, uuid.New(), cidrRule(models.IPRuleAllow, "198.51.100.1"))
+	require.NoError(t, err)
+	assert.Equal(t, "198.51.100.1/32", *allow.CIDR)
+	verdict = svc.Check(ctx, "198.51.100.1")
+	assert.False(t, verdict.Blocked, "allow rules win over the blocklist")
+	assert.True(t, verdict.Allowed)
+
+	require.NoError(t, svc.DeleteRule(ctx, rule.ID))
+	assert.False(t, svc.Check(ctx, "203.0.113.5").Blocked, "deleting applies straight away")
+	assert.ErrorIs(t, svc.DeleteRule(ctx, rule.ID), ErrIPRuleNotFound)
+
+	_, err = svc.CreateRule(ctx, uuid.New(), cidrRule(models.IPRuleBlock, "2001:db8::/32"))
+	require.NoError(t, err)
+	assert.True(t, svc.Check(ctx, "2001:db8::1").Blocked)
+}
+
+func TestIPFilterService_ASNRules(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newIPFilterTest(t, IPFilterConfig{})
+	asns := &fakeIPLookups{asns: map[string]int64{"192.0.2.1": 64500, "192.0.2.2": 64501}}
+
+	_, err := svc.CreateRule(ctx, uuid.New(), asnRule(models.IPRuleBlock, 64500))
+	require.NoError(t, err)
+	assert.False(t, svc.Check(ctx, "192.0.2.1").Blocked, "ASN rules need a resolver")
+
+	svc.SetASNResolver(asns)
+	verdict := svc.Check(ctx, "192.0.2.1")
+	assert.True(t, verdict.Blocked)
+	assert.Equal(t, models.IPBlockedASN, verdict.Reason)
+	assert.Equal(t, int64(64500), *verdict.ASN)
+	assert.False(t, svc.Check(ctx, "192.0.2.2").Blocked)
+
+	lookups := asns.lookups
+	svc.Check(ctx, "192.0.2.1")
+	assert.Equal(t, lookups, asns.lookups, "lookups are cached")
+
+	_, err = svc.CreateRule(ctx, uuid.New(), cidrRule(models.IPRuleAllow, "192.0.2.1"))
+	require.NoError(t, err)
+	assert.False(t, svc.Check(ctx, "192.0.2.1").Blocked)
+}
+
+func TestIPFilterService_ReputationProviders(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newIPFilterTest(t, IPFilterConfig{LookupTTL: time.Minute})
+	now := time.Unix(1700000000, 0)
+	svc.now = func() time.Time { return now }
+	broken := &fakeIPLookups{name: "broken.example", err: errors.New("timeout")}
+	dnsbl := &fakeIPLookups{name: "zen.example", listed: map[string]bool{"192.0.2.66": true, "10.0.0.66": true}}
+	svc.SetReputationProviders(broken, dnsbl)
+
+	verdict := svc.Check(ctx, "192.0.2.66")
+	assert.True(t, verdict.Blocked, "failing providers are skipped")
+	assert.Equal(t, models.IPBlockedReputation, verdict.Reason)
+	assert.Equal(t, "zen.example", verdict.Provider)
+	assert.False(t, svc.Check(ctx, "192.0.2.1").Blocked)
+	assert.False(t, svc.Check(ctx, "10.0.0.66").Blocked, "private addresses aren't looked up")
+
+	lookups := dnsbl.lookups
+	svc.Check(ctx, "192.0.2.66")
+	assert.Equal(t, lookups, dnsbl.lookups)
+	now = now.Add(time.Minute)
+	svc.Check(ctx, "192.0.2.66")
+	assert.Equal(t, lookups+1, dnsbl.lookups, "lookups expire")
+
+	_, err := svc.CreateRule(ctx, uuid.New(), cidrRule(models.IPRuleAllow, "192.0.2.0/24"))
+	require.NoError(t, err)
+	assert.False(t, svc.Check(ctx, "192.0.2.66").Blocked, "allow rules win over providers")
+}
+
+func TestIPFilterService_RulesExpireAndCache(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newIPFilterTest(t, IPFilterConfig{})
+	now := time.Unix(1700000000, 0)
+	svc.now = func() time.Time { return now }
+
+	expiresAt := now.Add(time.Hour)
+	req := cidrRule(models.IPRuleBlock, "192.0.2.0/24")
+	req.ExpiresAt = &expiresAt
+	_, err := svc.CreateRule(ctx, uuid.New(), req)
+	require.NoError(t, err)
+
+	assert.True(t, svc.Check(ctx, "192.0.2.1").Blocked)
+	assert.True(t, svc.Check(ctx, "192.0.2.1").Blocked)
+	lists := repo.lists
+	now = now.Add(time.Hour)
+	assert.False(t, svc.Check(ctx, "192.0.2.1").Blocked, "expired rules stop applying")
+
+	// Another instance adds a rule, then the database goes away
+	repo.rules = append(repo.rules, &models.IPRule{ID: uuid.New(), Action: models.IPRuleBlock, CIDR: strPtr("203.0.113.0/24")})
+	assert.False(t, svc.Check(ctx, "203.0.113.1").Blocked)
+	now = now.Add(ipRuleCacheTTL)
+	assert.True(t, svc.Check(ctx, "203.0.113.1").Blocked)
+	assert.Greater(t, repo.lists, lists)
+
+	repo.err = errors.New("database down")
+	now = now.Add(ipRuleCacheTTL)
+	assert.True(t, svc.Check(ctx, "203.0.113.1").Blocked, "failed lookups keep the rules loaded last")
+}
+
+func TestIPFilterService_CreateRuleValidates(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newIPFilterTest(t, IPFilterConfig{})
+	past := time.Now().Add(-time.Hour)
+	long := string(make([]byte, maxIPRuleReason+1))
+
+	for _, req := range []*models.CreateIPRuleRequest{
+		{Action: "deny", CIDR: strPtr("192.0.2.0/24")},
+		{Action: models.IPRuleBlock},
+		{Action: models.IPRuleBlock, CIDR: strPtr("192.0.2.0/24"), ASN: int64Ptr(64500)},
+		{Action: models.IPRuleBlock, CIDR: strPtr("192.0.2.0/40")},
+		{Action: models.IPRuleBlock, ASN: int64Ptr(0)},
+		{Action: models.IPRuleBlock, ASN: int64Ptr(1 << 32)},
+		{Action: models.IPRuleBlock, CIDR: strPtr("192.0.2.0/24"), ExpiresAt: &past},
+		{Action: models.IPRuleBlock, CIDR: strPtr("192.0.2.0/24"), Reason: &long},
+	} {
+		_, err := svc.CreateRule(ctx, uuid.New(), req)
+		assert.ErrorIs(t, err, ErrInvalidIPRule)
+	}
+	assert.Empty(t, repo.rules)
+
+	_, err := svc.CreateRule(ctx, uuid.New(), cidrRule(models.IPRuleBlock, "192.0.2.0/24"))
+	require.NoError(t, err)
+	_, err = svc.CreateRule(ctx, uuid.New(), cidrRule(models.IPRuleBlock, "192.0.2.1/24"))
+	assert.ErrorIs(t, err, ErrIPRuleExists)
+}
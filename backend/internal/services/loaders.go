@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// UserBatchRepository loads many users in one query
+type UserBatchRepository interface {
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.User, error)
+}
+
+// MemberBatchRepository loads many members of a server in one query
+type MemberBatchRepository interface {
+	GetMembersByUserIDs(ctx context.Context, serverID uuid.UUID, userIDs []uuid.UUID) ([]*models.Member, error)
+}
+
+type memberKey struct {
+	serverID uuid.UUID
+	userID   uuid.UUID
+}
+
+// Loaders batch and cache user and member lookups for one request. Resolving
+// the authors of a page of messages costs one query per kind rather than one
+// per message, and later lookups in the same request hit the cache. Loaders
+// are safe for concurrent use.
+type Loaders struct {
+	users   UserBatchRepository
+	members MemberBatchRepository
+
+	mu sync.Mutex
+	// A nil entry records an ID that was looked up and not found
+	userCache   map[uuid.UUID]*models.User
+	memberCache map[memberKey]*models.Member
+}
+
+// NewLoaders creates request-scoped loaders. members may be nil, in which
+// case only users are resolved.
+func NewLoaders(users UserBatchRepository, members MemberBatchRepository) *Loaders {
+	return &Loaders{
+		users:       users,
+		members:     members,
+		userCache:   make(map[uuid.UUID]*models.User),
+		memberCache: make(map[memberKey]*models.Member),
+	}
+}
+
+type loadersKey struct{}
+
+// WithLoaders attaches loaders to a request context
+func WithLoaders(ctx context.Context, loaders *Loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, loaders)
+}
+
+// LoadersFrom returns the loaders attached to ctx, or nil
+func LoadersFrom(ctx context.Context) *Loaders {
+	loaders, _ := ctx.Value(loadersKey{}).(*Loaders)
+	return loaders
+}
+
+type skipAuthorsKey struct{}
+
+// WithoutAuthors marks a request as not wanting message authors attached,
+// for clients that resolve them from their own cache
+func WithoutAuthors(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipAuthorsKey{}, true)
+}
+
+func skipAuthors(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipAuthorsKey{}).(bool)
+	return skip
+}
+
+// Users resolves ids, querying only those not already cached. IDs that don't
+// exist are absent from the result.
+func (l *Loaders) Users(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*models.User, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var missing []uuid.UUID
+	for _, id := range uniqueIDs(ids) {
+		if _, ok := l.userCache[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		users, err := l.users.GetByIDs(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range missing {
+			l.userCache[id] = nil
+		}
+		for _, user := range users {
+			l.userCache[user.ID] = user
+		}
+	}
+
+	result := make(map[uuid.UUID]*models.User, len(ids))
+	for _, id := range ids {
+		if user := l.userCache[id]; user != nil {
+			result[id] = user
+		}
+	}
+	return result, nil
+}
+
+// Members resolves userIDs' memberships in a server, querying only those not
+// already cached. Users who aren't members are absent from the result.
+func (l *Loaders) Members(ctx context.Context, serverID uuid.UUID, userIDs []uuid.UUID) (map[uuid.UUID]*models.Member, error) {
+	result := make(map[uuid.UUID]*models.Member, len(userIDs))
+	if l.members == nil {
+		return result, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var missing []uuid.UUID
+	for _, id := range uniqueIDs(userIDs) {
+		if _, ok := l.memberCache[memberKey{serverID, id}]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		members, err := l.members.GetMembersByUserIDs(ctx, serverID, missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range missing {
+			l.memberCache[memberKey{serverID, id}] = nil
+		}
+		for _, member := range members {
+			l.memberCache[memberKey{serverID, member.UserID}] = member
+		}
+	}
+
+	for _, id := range userIDs {
+		if member := l.memberCache[memberKey{serverID, id}]; member != nil {
+			result[id] = member
+		}
+	}
+	return result, nil
+}
+
+// AttachAuthors sets Author on messages and their referenced messages, and
+// Member on those sent in servers. It issues at most one user query plus one
+// member query per server involved.
+func (l *Loaders) AttachAuthors(ctx context.Context, messages []*models.Message) error {
+	all := make([]*models.Message, 0, len(messages))
+	for _, message := range messages {
+		if message == nil {
+			continue
+		}
+		all = append(all, message)
+		if message.ReferencedMsg != nil {
+			all = append(all, message.ReferencedMsg)
+		}
+	}
+	if len(all) == 0 {
+		return nil
+	}
+
+	authorIDs := make([]uuid.UUID, 0, len(all))
+	byServer := make(map[uuid.UUID][]uuid.UUID)
+	for _, message := range all {
+		authorIDs = append(authorIDs, message.AuthorID)
+		if message.ServerID != nil {
+			byServer[*message.ServerID] = append(byServer[*message.ServerID], message.AuthorID)
+		}
+	}
+
+	users, err := l.Users(ctx, authorIDs)
+	if err != nil {
+		return err
+	}
+	members := make(map[uuid.UUID]map[uuid.UUID]*models.Member, len(byServer))
+	for serverID, ids := range byServer {
+		serverMembers, err := l.Members(ctx, serverID, ids)
+		if err != nil {
+			return err
+		}
+		members[serverID] = serverMembers
+	}
+
+	for _, message := range all {
+		if user, ok := users[message.AuthorID]; ok {
+			author := user.ToPublic()
+			message.Author = &author
+		}
+		if message.ServerID != nil {
+			message.Member = members[*message.ServerID][message.AuthorID]
+		}
+	}
+	return nil
+}
+
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]struct{}, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		unique = append(unique, id)
+	}
+	return unique
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+// MockUserBatchRepository mocks UserBatchRepository
+type MockUserBatchRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserBatchRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.User, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+// MockMemberBatchRepository mocks MemberBatchRepository
+type MockMemberBatchRepository struct {
+	mock.Mock
+}
+
+func (m *MockMemberBatchRepository) GetMembersByUserIDs(ctx context.Context, serverID uuid.UUID, userIDs []uuid.UUID) ([]*models.Member, error) {
+	args := m.Called(ctx, serverID, userIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Member), args.Error(1)
+}
+
+// idsMatch matches an ID slice regardless of order
+func idsMatch(want ...uuid.UUID) interface{} {
+	return mock.MatchedBy(func(got []uuid.UUID) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		seen := make(map[uuid.UUID]bool, len(got))
+		for _, id := range got {
+			seen[id] = true
+		}
+		for _, id := range want {
+			if !seen[id] {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func TestLoaders_AttachAuthors_BatchesLookups(t *testing.T) {
+	users := new(MockUserBatchRepository)
+	members := new(MockMemberBatchRepository)
+	loaders := NewLoaders(users, members)
+	ctx := context.Background()
+
+	serverID := uuid.New()
+	alice := &models.User{ID: uuid.New(), Username: "alice"}
+	bob := &models.User{ID: uuid.New(), Username: "bob"}
+	nickname := "Bobby"
+
+	// A page of 50 messages from two authors
+	var messages []*models.Message
+	for i := 0; i < 50; i++ {
+		author := alice
+		if i%2 == 1 {
+			author = bob
+		}
+		messages = append(messages, &models.Message{ID: uuid.New(), ServerID: &serverID, AuthorID: author.ID})
+	}
+
+	users.On("GetByIDs", ctx, idsMatch(alice.ID, bob.ID)).Return([]*models.User{alice, bob}, nil).Once()
+	members.On("GetMembersByUserIDs", ctx, serverID, idsMatch(alice.ID, bob.ID)).Return([]*models.Member{
+		{ServerID: serverID, UserID: alice.ID},
+		{ServerID: serverID, UserID: bob.ID, Nickname: &nickname},
+	}, nil).Once()
+
+	require.NoError(t, loaders.AttachAuthors(ctx, messages))
+
+	for i, message := range messages {
+		require.NotNil(t, message.Author, "message %d", i)
+		require.NotNil(t, message.Member, "message %d", i)
+		assert.Equal(t, message.AuthorID, message.Author.ID)
+	}
+	assert.Equal(t, "bob", messages[1].Author.Username)
+	assert.Equal(t, "Bobby", *messages[1].Member.Nickname)
+
+	users.AssertNumberOfCalls(t, "GetByIDs", 1)
+	members.AssertNumberOfCalls(t, "GetMembersByUserIDs", 1)
+}
+
+func TestLoaders_CachesAcrossCalls(t *testing.T) {
+	users := new(MockUserBatchRepository)
+	loaders := NewLoaders(users, nil)
+	ctx := context.Background()
+
+	alice := &models.User{ID: uuid.New(), Username: "alice"}
+	bob := &models.User{ID: uuid.New(), Username: "bob"}
+	deleted := uuid.New()
+
+	users.On("GetByIDs", ctx, idsMatch(alice.ID, deleted)).Return([]*models.User{alice}, nil).Once()
+	users.On("GetByIDs", ctx, []uuid.UUID{bob.ID}).Return([]*models.User{bob}, nil).Once()
+
+	found, err := loaders.Users(ctx, []uuid.UUID{alice.ID, deleted, alice.ID})
+	require.NoError(t, err)
+	assert.Len(t, found, 1)
+	assert.NotContains(t, found, deleted)
+
+	// Only bob is new; alice is cached and the deleted user isn't retried
+	found, err = loaders.Users(ctx, []uuid.UUID{alice.ID, bob.ID, deleted})
+	require.NoError(t, err)
+	assert.Len(t, found, 2)
+
+	users.AssertExpectations(t)
+}
+
+func TestLoaders_AttachAuthors_DMWithoutMembers(t *testing.T) {
+	users := new(MockUserBatchRepository)
+	members := new(MockMemberBatchRepository)
+	loaders := NewLoaders(users, members)
+	ctx := context.Background()
+
+	author := &models.User{ID: uuid.New(), Username: "alice"}
+	replyAuthor := &models.User{ID: uuid.New(), Username: "bob"}
+	message := &models.Message{
+		ID:            uuid.New(),
+		AuthorID:      author.ID,
+		ReferencedMsg: &models.Message{ID: uuid.New(), AuthorID: replyAuthor.ID},
+	}
+
+	users.On("GetByIDs", ctx, idsMatch(author.ID, replyAuthor.ID)).Return([]*models.User{author, replyAuthor}, nil).Once()
+
+	require.NoError(t, loaders.AttachAuthors(ctx, []*models.Message{message}))
+
+	assert.Equal(t, "alice", message.Author.Username)
+	assert.Equal(t, "bob", message.ReferencedMsg.Author.Username)
+	assert.Nil(t, message.Member)
+	members.AssertNotCalled(t, "GetMembersByUserIDs", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLoaders_AttachAuthors_Error(t *testing.T) {
+	users := new(MockUserBatchRepository)
+	loaders := NewLoaders(users, nil)
+	ctx := context.Background()
+
+	users.On("GetByIDs", ctx, mock.Anything).Return(nil, errors.New("db down"))
+
+	err := loaders.AttachAuthors(ctx, []*models.Message{{ID: uuid.New(), AuthorID: uuid.New()}})
+	assert.Error(t, err)
+}
+
+func TestGetMessages_AttachesAuthors(t *testing.T) {
+	service, msgRepo, channelRepo, _, _, _, _, _, _ := setupMessageService()
+	users := new(MockUserBatchRepository)
+	service.SetAuthorRepositories(users, nil)
+	ctx := context.Background()
+
+	requesterID := uuid.New()
+	channelID := uuid.New()
+	channel := &models.Channel{
+		ID:         channelID,
+		Type:       models.ChannelTypeDM,
+		Recipients: []uuid.UUID{requesterID},
+	}
+	requester := &models.User{ID: requesterID, Username: "alice"}
+	messages := []*models.Message{
+		{ID: uuid.New(), AuthorID: requesterID},
+		{ID: uuid.New(), AuthorID: requesterID},
+	}
+
+	channelRepo.On("GetByID", ctx, channelID).Return(channel, nil)
+	msgRepo.On("GetChannelMessages", ctx, channelID, (*uuid.UUID)(nil), (*uuid.UUID)(nil), 50).Return(messages, nil)
+	users.On("GetByIDs", ctx, []uuid.UUID{requesterID}).Return([]*models.User{requester}, nil).Once()
+
+	result, err := service.GetMessages(ctx, channelID, requesterID, nil, nil, 50)
+
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	for _, message := range result {
+		require.NotNil(t, message.Author)
+		assert.Equal(t, "alice", message.Author.Username)
+	}
+	users.AssertNumberOfCalls(t, "GetByIDs", 1)
+}
+
+func TestGetMessages_UsesRequestLoaders(t *testing.T) {
+	service, msgRepo, channelRepo, _, _, _, _, _, _ := setupMessageService()
+	users := new(MockUserBatchRepository)
+	ctx := WithLoaders(context.Background(), NewLoaders(users, nil))
+
+	requesterID := uuid.New()
+	channelID := uuid.New()
+	channel := &models.Channel{
+		ID:         channelID,
+		Type:       models.ChannelTypeDM,
+		Recipients: []uuid.UUID{requesterID},
+	}
+
+	channelRepo.On("GetByID", ctx, channelID).Return(channel, nil)
+	msgRepo.On("GetChannelMessages", ctx, channelID, (*uuid.UUID)(nil), (*uuid.UUID)(nil), 50).Return([]*models.Message{{ID: uuid.New(), AuthorID: requesterID}}, nil)
+	msgRepo.On("GetPinnedMessages", ctx, channelID).Return([]*models.Message{{ID: uuid.New(), AuthorID: requesterID}}, nil)
+	users.On("GetByIDs", ctx, []uuid.UUID{requesterID}).Return([]*models.User{{ID: requesterID}}, nil).Once()
+
+	_, err := service.GetMessages(ctx, channelID, requesterID, nil, nil, 50)
+	require.NoError(t, err)
+	pinned, err := service.GetPinnedMessages(ctx, channelID, requesterID)
+	require.NoError(t, err)
+
+	assert.NotNil(t, pinned[0].Author)
+	users.AssertNumberOfCalls(t, "GetByIDs", 1)
+}
+
+func TestGetMessages_WithoutAuthors(t *testing.T) {
+	service, msgRepo, channelRepo, _, _, _, _, _, _ := setupMessageService()
+	users := new(MockUserBatchRepository)
+	service.SetAuthorRepositories(users, nil)
+	ctx := WithoutAuthors(context.Background())
+
+	requesterID := uuid.New()
+	channelID := uuid.New()
+	channel := &models.Channel{
+		ID:         channelID,
+		Type:       models.ChannelTypeDM,
+		Recipients: []uuid.UUID{requesterID},
+	}
+
+	channelRepo.On("GetByID", ctx, channelID).Return(channel, nil)
+	msgRepo.On("GetChannelMessages", ctx, channelID, (*uuid.UUID)(nil), (*uuid.UUID)(nil), 50).Return([]*models.Message{{ID: uuid.New(), AuthorID: requesterID}}, nil)
+
+	result, err := service.GetMessages(ctx, channelID, requesterID, nil, nil, 50)
+
+	require.NoError(t, err)
+	assert.Nil(t, result[0].Author)
+	users.AssertNotCalled(t, "GetByIDs", mock.Anything, mock.Anything)
+}
+
+func TestEditMessage_PublishesHydratedMessage(t *testing.T) {
+	service, msgRepo, _, _, _, _, _, _, eventBus := setupMessageService()
+	users := new(MockUserBatchRepository)
+	members := new(MockMemberBatchRepository)
+	service.SetAuthorRepositories(users, members)
+	ctx := context.Background()
+
+	serverID := uuid.New()
+	author := &models.User{ID: uuid.New(), Username: "alice"}
+	roleID := uuid.New()
+	existing := &models.Message{ID: uuid.New(), ChannelID: uuid.New(), ServerID: &serverID, AuthorID: author.ID}
+
+	msgRepo.On("GetByID", ctx, existing.ID).Return(existing, nil)
+	msgRepo.On("Update", ctx, existing).Return(nil)
+	users.On("GetByIDs", ctx, []uuid.UUID{author.ID}).Return([]*models.User{author}, nil)
+	members.On("GetMembersByUserIDs", ctx, serverID, []uuid.UUID{author.ID}).Return([]*models.Member{
+		{ServerID: serverID, UserID: author.ID, Roles: []uuid.UUID{roleID}},
+	}, nil)
+	eventBus.On("Publish", "message.updated", mock.MatchedBy(func(e *MessageUpdatedEvent) bool {
+		return e.Message.Author != nil && e.Message.Member != nil && e.Message.Member.Roles[0] == roleID
+	})).Return()
+
+	_, err := service.EditMessage(ctx, existing.ID, author.ID, "edited")
+
+	require.NoError(t, err)
+	eventBus.AssertExpectations(t)
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// mailSendTimeout bounds sending one email in the background
+const mailSendTimeout = time.Minute
+
+// Mailer sends email. mail.SMTP implements it.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// LoginSecurityRepository stores failed login counts, lockouts and the
+// devices accounts log in from. The Postgres LoginSecurityRepository
+// implements it.
+type LoginSecurityRepository interface {
+	// GetLockout returns nil if the account has no failures or lockouts
+	GetLockout(ctx context.Context, userID uuid.UUID) (*models.AccountLockout, error)
+	// RecordFailure counts a failed login, starting again from one if the
+	// last failure was before windowStart, and returns the new state
+	RecordFailure(ctx context.Context, userID uuid.UUID, at, windowStart time.Time) (*models.AccountLockout, error)
+	// Lock locks the account until the given time, clearing its failures
+	// and counting the lockout
+	Lock(ctx context.Context, userID uuid.UUID, until time.Time) error
+	// ClearLockout forgets the account's failures and lockouts
+	ClearLockout(ctx context.Context, userID uuid.UUID) error
+	// HasDevices reports whether the account has logged in from any device
+	HasDevices(ctx context.Context, userID uuid.UUID) (bool, error)
+	// TouchDevice records a login from the device and reports whether it
+	// was already known
+	TouchDevice(ctx context.Context, device *models.LoginDevice) (bool, error)
+}
+
+// LoginSecurityConfig configures account lockouts
+type LoginSecurityConfig struct {
+	// LockoutThreshold is how many failed logins in a row lock an account.
+	// 0 never locks.
+	LockoutThreshold int
+	// LockoutDuration is how long the first lockout lasts. Each one after
+	// it, until the next successful login, lasts twice as long, up to
+	// MaxLockoutDuration.
+	LockoutDuration    time.Duration
+	MaxLockoutDuration time.Duration
+	// FailureWindow is how long failures count. A failure further than
+	// this from the one before starts the count again.
+	FailureWindow time.Duration
+}
+
+// LoginSecurityService protects password logins. Accounts that fail to
+// log in too often are locked for a while, which stops guessing against
+// one account from many addresses where captchas count per address. Users
+// are emailed when they log in from a device they haven't used before.
+type LoginSecurityService struct {
+	repo LoginSecurityRepository
+	now  func() time.Time
+
+	mu     sync.RWMutex
+	config LoginSecurityConfig
+
+	mailer   Mailer
+	resetURL string
+}
+
+// NewLoginSecurityService creates a login security service. New device
+// alerts are off until SetMailer.
+func NewLoginSecurityService(repo LoginSecurityRepository, config LoginSecurityConfig) *LoginSecurityService {
+	return &LoginSecurityService{
+		repo:   repo,
+		config: normalizeLoginSecurityConfig(config),
+		now:    time.Now,
+	}
+}
+
+// normalizeLoginSecurityConfig fills in defaults for unset durations
+func normalizeLoginSecurityConfig(config LoginSecurityConfig) LoginSecurityConfig {
+	if config.LockoutDuration <= 0 {
+		config.LockoutDuration = 5 * time.Minute
+	}
+	if config.MaxLockoutDuration <= 0 {
+		config.MaxLockoutDuration = 24 * time.Hour
+	}
+	if config.MaxLockoutDuration < config.LockoutDuration {
+		config.MaxLockoutDuration = config.LockoutDuration
+	}
+	if config.FailureWindow <= 0 {
+		config.FailureWindow = time.Hour
+	}
+	return config
+}
+
+// SetConfig changes the lockout settings, e.g. when the config is
+// reloaded. Accounts already locked stay locked until their lockout ends.
+func (s *LoginSecurityService) SetConfig(config LoginSecurityConfig) {
+	config = normalizeLoginSecurityConfig(config)
+	s.mu.Lock()
+	s.config = config
+	s.mu.Unlock()
+}
+
+func (s *LoginSecurityService) settings() LoginSecurityConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config
+}
+
+// SetMailer emails users when they log in from a new device. The email
+// points them to resetURL in case it wasn't them.
+func (s *LoginSecurityService) SetMailer(mailer Mailer, resetURL string) {
+	s.mailer = mailer
+	s.resetURL = resetURL
+}
+
+// CheckLocked returns an *AccountLockedError while the account is locked
+func (s *LoginSecurityService) CheckLocked(ctx context.Context, userID uuid.UUID) error {
+	lockout, err := s.repo.GetLockout(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if lockout != nil && lockout.LockedUntil != nil && s.now().Before(*lockout.LockedUntil) {
+		return &AccountLockedError{Until: *lockout.LockedUntil}
+	}
+	return nil
+}
+
+// LoginFailed counts a wrong password against the account. It returns an
+// *AccountLockedError if that locked the account. A failure that can't be
+// counted is logged, and the login fails as usual.
+func (s *LoginSecurityService) LoginFailed(ctx context.Context, userID uuid.UUID) error {
+	config := s.settings()
+	if config.LockoutThreshold <= 0 {
+		return nil
+	}
+	now := s.now()
+	lockout, err := s.repo.RecordFailure(ctx, userID, now, now.Add(-config.FailureWindow))
+	if err != nil {
+		log.Printf("[LoginSecurity] failed to count failed login for %s: %v", userID, err)
+		return nil
+	}
+	if lockout.Failures < config.LockoutThreshold {
+		return nil
+	}
+
+	until := now.Add(config.lockoutDuration(lockout.Lockouts))
+	if err := s.repo.Lock(ctx, userID, until); err != nil {
+		log.Printf("[LoginSecurity] failed to lock %s: %v", userID, err)
+		return nil
+	}
+	log.Printf("[LoginSecurity] locked account %s until %s after %d failed logins", userID, until.Format(time.RFC3339), lockout.Failures)
+	return &AccountLockedError{Until: until}
+}
+
+// lockoutDuration doubles the lockout for each one before it
+func (c LoginSecurityConfig) lockoutDuration(previous int) time.Duration {
+	duration := c.LockoutDuration
+	for i := 0; i < previous && duration < c.MaxLockoutDuration; i++ {
+		duration *= 2
+	}
+	if duration > c.MaxLockoutDuration {
+		duration = c.MaxLockoutDuration
+	}
+	return duration
+}
+
+// LoginSucceeded clears the account's failures and remembers the device
+// from the client info in ctx, emailing the user if it's new. The
+// account's first device isn't alerted about. Failures are logged rather
+// than failing the login.
+func (s *LoginSecurityService) LoginSucceeded(ctx context.Context, user *models.User) {
+	if s.settings().LockoutThreshold > 0 {
+		if err := s.repo.ClearLockout(ctx, user.ID); err != nil {
+			log.Printf("[LoginSecurity] failed to clear failed logins for %s: %v", user.ID, err)
+		}
+	}
+
+	client := ClientInfoFrom(ctx)
+	now := s.now()
+	device := &models.LoginDevice{
+		UserID:      user.ID,
+		DeviceHash:  deviceHash(client.UserAgent),
+		FirstSeenAt: now,
+		LastSeenAt:  now,
+	}
+	if client.UserAgent != "" {
+		userAgent := truncateRunes(client.UserAgent, maxSessionUserAgentLength)
+		device.UserAgent = &userAgent
+	}
+	if client.IPAddress != "" {
+		device.IPAddress = &client.IPAddress
+	}
+
+	hasDevices, err := s.repo.HasDevices(ctx, user.ID)
+	if err != nil {
+		log.Printf("[LoginSecurity] failed to look up devices for %s: %v", user.ID, err)
+		return
+	}
+	known, err := s.repo.TouchDevice(ctx, device)
+	if err != nil {
+		log.Printf("[LoginSecurity] failed to record device for %s: %v", user.ID, err)
+		return
+	}
+	if known || !hasDevices || s.mailer == nil || user.Email == "" {
+		return
+	}
+
+	subject, body := s.newDeviceEmail(user, device)
+	to := user.Email
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+		defer cancel()
+		if err := s.mailer.Send(ctx, to, subject, body); err != nil {
+			log.Printf("[LoginSecurity] failed to send new device alert to %s: %v", user.ID, err)
+		}
+	}()
+}
+
+// Unlock ends the account's lockout and forgets its failures, for when the
+// user proves who they are another way
+func (s *LoginSecurityService) Unlock(ctx context.Context, userID uuid.UUID) error {
+	return s.repo.ClearLockout(ctx, userID)
+}
+
+func (s *LoginSecurityService) newDeviceEmail(user *models.User, device *models.LoginDevice) (string, string) {
+	userAgent, ip := "unknown", "unknown"
+	if device.UserAgent != nil {
+		userAgent = *device.UserAgent
+	}
+	if device.IPAddress != nil {
+		ip = *device.IPAddress
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hi %s,\n\n", user.Username)
+	body.WriteString("Your Hearth account was just logged in to from a device it hasn't been used on before.\n\n")
+	fmt.Fprintf(&body, "Device: %s\n", userAgent)
+	fmt.Fprintf(&body, "IP address: %s\n", ip)
+	fmt.Fprintf(&body, "Time: %s\n\n", device.LastSeenAt.UTC().Format("2 January 2006 15:04 MST"))
+	body.WriteString("If this was you, there's nothing to do.\n\n")
+	body.WriteString("If it wasn't, someone knows your password. Reset it")
+	if s.resetURL != "" {
+		fmt.Fprintf(&body, " at %s", s.resetURL)
+	}
+	body.WriteString(" to log them out.\n")
+	return "New login to your Hearth account", body.String()
+}
+
+// deviceVersions matches the version numbers in user agents, which change
+// with every browser update
+var deviceVersions = regexp.MustCompile(`[0-9]+([._][0-9]+)*`)
+
+// deviceHash identifies a client by its user agent with versions left out,
+// so updating a browser doesn't make it a new device
+func deviceHash(userAgent string) string {
+	normalized := deviceVersions.ReplaceAllString(strings.ToLower(userAgent), "")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/auth"
+	"hearth/internal/models"
+)
+
+type fakeLoginSecurityRepo struct {
+	lockouts map[uuid.UUID]*models.AccountLockout
+	devices  map[uuid.UUID]map[string]*models.LoginDevice
+	err      error
+}
+
+func newFakeLoginSecurityRepo() *fakeLoginSecurityRepo {
+	return &fakeLoginSecurityRepo{
+		lockouts: make(map[uuid.UUID]*models.AccountLockout),
+		devices:  make(map[uuid.UUID]map[string]*models.LoginDevice),
+	}
+}
+
+func (r *fakeLoginSecurityRepo) GetLockout(ctx context.Context, userID uuid.UUID) (*models.AccountLockout, error) {
+	if lockout, ok := r.lockouts[userID]; ok {
+		copied := *lockout
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeLoginSecurityRepo) RecordFailure(ctx context.Context, userID uuid.UUID, at, windowStart time.Time) (*models.AccountLockout, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	lockout, ok := r.lockouts[userID]
+	if !ok {
+		lockout = &models.AccountLockout{UserID: userID}
+		r.lockouts[userID] = lockout
+	}
+	if lockout.LastFailureAt == nil || lockout.LastFailureAt.Before(windowStart) {
+		lockout.Failures = 0
+	}
+	lockout.Failures++
+	lockout.LastFailureAt = &at
+	copied := *lockout
+	return &copied, nil
+}
+
+func (r *fakeLoginSecurityRepo) Lock(ctx context.Context, userID uuid.UUID, until time.Time) error {
+	lockout := r.lockouts[userID]
+	lockout.Failures = 0
+	lockout.Lockouts++
+	lockout.LockedUntil = &until
+	return nil
+}
+
+func (r *fakeLoginSecurityRepo) ClearLockout(ctx context.Context, userID uuid.UUID) error {
+	delete(r.lockouts, userID)
+	return nil
+}
+
+func (r *fakeLoginSecurityRepo) HasDevices(ctx context.Context, userID uuid.UUID) (bool, error) {
+	return len(r.devices[userID]) > 0, nil
+}
+
+func (r *fakeLoginSecurityRepo) TouchDevice(ctx context.Context, device *models.LoginDevice) (bool, error) {
+	if r.devices[device.UserID] == nil {
+		r.devices[device.UserID] = make(map[string]*models.LoginDevice)
+	}
+	_, known := r.devices[device.UserID][device.DeviceHash]
+	r.devices[device.UserID][device.DeviceHash] = device
+	return known, nil
+}
+
+type sentMail struct {
+	to, subject, body string
+}
+
+// fakeMailer hands sent mail to the test, which may be waiting on another
+// goroutine
+type fakeMailer struct {
+	sent chan sentMail
+}
+
+func newFakeMailer() *fakeMailer {
+	return &fakeMailer{sent: make(chan sentMail, 10)}
+}
+
+func (m *fakeMailer) Send(ctx context.Context, to, subject, body string) error {
+	m.sent <- sentMail{to: to, subject: subject, body: body}
+	return nil
+}
+
+func (m *fakeMailer) next(t *testing.T) sentMail {
+	select {
+	case mail := <-m.sent:
+		return mail
+	case <-time.After(5 * time.Second):
+		t.Fatal("no email was sent")
+		return sentMail{}
+	}
+}
+
+func (m *fakeMailer) none(t *testing.T) {
+	select {
+	case mail := <-m.sent:
+		t.Fatalf("unexpected email %q to %s", mail.subject, mail.to)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func newLoginSecurityTest(config LoginSecurityConfig) (*LoginSecurityService, *fakeLoginSecurityRepo, *time.Time) {
+	repo := newFakeLoginSecurityRepo()
+	svc := NewLoginSecurityService(repo, config)
+	now := time.Unix(1700000000, 0)
+	svc.now = func() time.Time { return now }
+	return svc, repo, &now
+}
+
+func TestLoginSecurityService_Lockout(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, now := newLoginSecurityTest(LoginSecurityConfig{
+		LockoutThreshold:   3,
+		LockoutDuration:    time.Minute,
+		MaxLockoutDuration: 3 * time.Minute,
+		FailureWindow:      time.Hour,
+	})
+	userID := uuid.New()
+
+	require.NoError(t, svc.LoginFailed(ctx, userID))
+	require.NoError(t, svc.LoginFailed(ctx, userID))
+	require.NoError(t, svc.CheckLocked(ctx, userID))
+
+	err := svc.LoginFailed(ctx, userID)
+	var locked *AccountLockedError
+	require.ErrorAs(t, err, &locked)
+	assert.ErrorIs(t, err, ErrAccountLocked)
+	assert.Equal(t, now.Add(time.Minute), locked.Until)
+	require.ErrorAs(t, svc.CheckLocked(ctx, userID), &locked)
+
+	// Each lockout lasts twice as long as the one before, up to the cap
+	for _, want := range []time.Duration{2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		*now = locked.Until
+		require.NoError(t, svc.CheckLocked(ctx, userID), "lockouts end")
+		svc.LoginFailed(ctx, userID)
+		svc.LoginFailed(ctx, userID)
+		require.ErrorAs(t, svc.LoginFailed(ctx, userID), &locked)
+		assert.Equal(t, now.Add(want), locked.Until)
+	}
+
+	// A successful login starts over
+	*now = locked.Until
+	svc.LoginSucceeded(ctx, &models.User{ID: userID})
+	assert.Empty(t, repo.lockouts)
+	svc.LoginFailed(ctx, userID)
+	svc.LoginFailed(ctx, userID)
+	require.ErrorAs(t, svc.LoginFailed(ctx, userID), &locked)
+	assert.Equal(t, now.Add(time.Minute), locked.Until)
+
+	require.NoError(t, svc.Unlock(ctx, userID))
+	assert.NoError(t, svc.CheckLocked(ctx, userID))
+}
+
+func TestLoginSecurityService_FailureWindow(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, now := newLoginSecurityTest(LoginSecurityConfig{LockoutThreshold: 2, FailureWindow: 10 * time.Minute})
+	userID := uuid.New()
+
+	require.NoError(t, svc.LoginFailed(ctx, userID))
+	*now = now.Add(11 * time.Minute)
+	require.NoError(t, svc.LoginFailed(ctx, userID), "old failures stop counting")
+	require.ErrorIs(t, svc.LoginFailed(ctx, userID), ErrAccountLocked)
+
+	// Counting failures that go wrong doesn't break logins
+	other := uuid.New()
+	repo.err = errors.New("database down")
+	assert.NoError(t, svc.LoginFailed(ctx, other))
+}
+
+func TestLoginSecurityService_NoThreshold(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, _ := newLoginSecurityTest(LoginSecurityConfig{})
+	userID := uuid.New()
+	for i := 0; i < 50; i++ {
+		require.NoError(t, svc.LoginFailed(ctx, userID))
+	}
+	assert.Empty(t, repo.lockouts)
+}
+
+func TestLoginSecurityService_NewDeviceAlerts(t *testing.T) {
+	svc, repo, _ := newLoginSecurityTest(LoginSecurityConfig{LockoutThreshold: 5})
+	mailer := newFakeMailer()
+	svc.SetMailer(mailer, "https://chat.example.com/forgot-password")
+	user := &models.User{ID: uuid.New(), Username: "ada", Email: "ada@example.com"}
+	login := func(userAgent, ip string) {
+		svc.LoginSucceeded(WithClientInfo(context.Background(), ClientInfo{UserAgent: userAgent, IPAddress: ip}), user)
+	}
+
+	login("Mozilla/5.0 (X11; Linux x86_64) Firefox/120.0", "198.51.100.1")
+	mailer.none(t)
+	assert.Len(t, repo.devices[user.ID], 1, "the first device is remembered without an alert")
+
+	login("Mozilla/5.0 (X11; Linux x86_64) Firefox/121.0", "198.51.100.2")
+	mailer.none(t)
+	assert.Len(t, repo.devices[user.ID], 1, "browser updates aren't new devices")
+
+	login("hearth-ios/2.3 (iPhone; iOS 17.1)", "203.0.113.9")
+	mail := mailer.next(t)
+	assert.Equal(t, "ada@example.com", mail.to)
+	assert.Equal(t, "New login to your Hearth account", mail.subject)
+	assert.Contains(t, mail.body, "Hi ada,")
+	assert.Contains(t, mail.body, "hearth-ios/2.3 (iPhone; iOS 17.1)")
+	assert.Contains(t, mail.body, "203.0.113.9")
+	assert.Contains(t, mail.body, "https://chat.example.com/forgot-password")
+
+	login("hearth-ios/2.4 (iPhone; iOS 17.2)", "203.0.113.9")
+	mailer.none(t)
+}
+
+func TestDeviceHash(t *testing.T) {
+	assert.Equal(t,
+		deviceHash("Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0.6099.109 Safari/537.36"),
+		deviceHash("Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/121.0.6167.85 Safari/537.36"))
+	assert.NotEqual(t,
+		deviceHash("Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0 Safari/537.36"),
+		deviceHash("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) Chrome/120.0 Safari/537.36"))
+}
+
+func TestAuthService_Login_Lockout(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockAuthRepository)
+	security, repo, now := newLoginSecurityTest(LoginSecurityConfig{LockoutThreshold: 2, LockoutDuration: time.Minute})
+	service := NewAuthServiceWithLoginSecurity(mockRepo, testJWTService(), nil, nil, security)
+
+	hash, err := auth.HashPassword("Password123")
+	require.NoError(t, err)
+	user := &models.User{ID: uuid.New(), Email: "ada@example.com", Username: "ada", PasswordHash: hash}
+	mockRepo.On("GetByEmail", mock.Anything, "ada@example.com").Return(user, nil)
+
+	_, _, err = service.Login(ctx, "ada@example.com", "Wrong12345")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, _, err = service.Login(ctx, "ada@example.com", "Wrong12345")
+	var locked *AccountLockedError
+	require.ErrorAs(t, err, &locked, "the failure that locks the account says so")
+	assert.Equal(t, now.Add(time.Minute), locked.Until)
+
+	_, _, err = service.Login(ctx, "ada@example.com", "Password123")
+	assert.ErrorIs(t, err, ErrAccountLocked, "the right password doesn't get in while locked")
+
+	*now = now.Add(time.Minute)
+	_, tokens, err := service.Login(ctx, "ada@example.com", "Password123")
+	require.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.Empty(t, repo.lockouts, "logging in clears the failures")
+}
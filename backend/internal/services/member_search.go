@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// memberSearchScanBatch is how many members a search reads at a time when
+// there is no MemberSearchRepository to do it in the database
+const memberSearchScanBatch = 1000
+
+// MemberSearchRepository finds a server's members by name or ID, with
+// their users and role IDs. The Postgres ServerRepository implements it.
+type MemberSearchRepository interface {
+	// SearchMembers returns up to limit members whose username or nickname
+	// starts with prefix, ignoring case, ordered by user ID after after
+	SearchMembers(ctx context.Context, serverID uuid.UUID, prefix string, after *uuid.UUID, limit int) ([]*models.Member, error)
+	GetMembersWithUsers(ctx context.Context, serverID uuid.UUID, userIDs []uuid.UUID) ([]*models.Member, error)
+}
+
+// SetMemberSearchRepository lets members be searched in the database.
+// Without one a search reads through every member.
+func (s *ServerService) SetMemberSearchRepository(search MemberSearchRepository) {
+	s.search = search
+}
+
+// SearchMembers returns up to limit of the server's members whose username
+// or nickname starts with prefix, ignoring case. An empty prefix matches
+// everyone. Members are ordered by user ID and after is the last member of
+// the previous page, so a whole server can be read a page at a time.
+func (s *ServerService) SearchMembers(ctx context.Context, serverID uuid.UUID, prefix string, after *uuid.UUID, limit int) ([]*models.Member, error) {
+	if s.search != nil {
+		return s.search.SearchMembers(ctx, serverID, prefix, after, limit)
+	}
+
+	prefix = strings.ToLower(prefix)
+	var matches []*models.Member
+	for offset := 0; ; offset += memberSearchScanBatch {
+		batch, err := s.repo.GetMembers(ctx, serverID, memberSearchScanBatch, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range batch {
+			if (after == nil || member.UserID.String() > after.String()) && memberHasPrefix(member, prefix) {
+				matches = append(matches, member)
+			}
+		}
+		if len(batch) < memberSearchScanBatch {
+			break
+		}
+	}
+
+	slices.SortFunc(matches, func(a, b *models.Member) int {
+		return strings.Compare(a.UserID.String(), b.UserID.String())
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// GetMembersByIDs returns the server's members among userIDs. Users who
+// aren't members are left out.
+func (s *ServerService) GetMembersByIDs(ctx context.Context, serverID uuid.UUID, userIDs []uuid.UUID) ([]*models.Member, error) {
+	if s.search != nil {
+		return s.search.GetMembersWithUsers(ctx, serverID, userIDs)
+	}
+
+	var members []*models.Member
+	for _, userID := range userIDs {
+		member, err := s.repo.GetMember(ctx, serverID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if member != nil {
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+func memberHasPrefix(member *models.Member, prefix string) bool {
+	if prefix == "" {
+		return true
+	}
+	if member.Nickname != nil && strings.HasPrefix(strings.ToLower(*member.Nickname), prefix) {
+		return true
+	}
+	return member.User != nil && strings.HasPrefix(strings.ToLower(member.User.Username), prefix)
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+func TestServerService_SearchMembersWithoutRepository(t *testing.T) {
+	ctx := context.Background()
+	serverID := uuid.New()
+	nick := "Alfred"
+	members := []*models.Member{
+		{UserID: uuid.New(), User: &models.PublicUser{Username: "alice"}},
+		{UserID: uuid.New(), User: &models.PublicUser{Username: "bob"}, Nickname: &nick},
+		{UserID: uuid.New(), User: &models.PublicUser{Username: "carol"}},
+		{UserID: uuid.New(), User: &models.PublicUser{Username: "Alan"}},
+	}
+
+	repo := new(MockServerRepository)
+	repo.On("GetMembers", ctx, serverID, memberSearchScanBatch, 0).Return(members, nil)
+	service := NewServerService(repo, nil, nil, nil, nil, nil)
+
+	found, err := service.SearchMembers(ctx, serverID, "AL", nil, 10)
+	require.NoError(t, err)
+	require.Len(t, found, 3)
+	for i := 1; i < len(found); i++ {
+		assert.Less(t, found[i-1].UserID.String(), found[i].UserID.String())
+	}
+	assert.NotContains(t, found, members[2])
+
+	// The next page starts after the last member of this one
+	next, err := service.SearchMembers(ctx, serverID, "al", &found[0].UserID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []*models.Member{found[1]}, next)
+}
+
+func TestServerService_GetMembersByIDsSkipsNonMembers(t *testing.T) {
+	ctx := context.Background()
+	serverID := uuid.New()
+	member := &models.Member{UserID: uuid.New()}
+	stranger := uuid.New()
+
+	repo := new(MockServerRepository)
+	repo.On("GetMember", ctx, serverID, member.UserID).Return(member, nil)
+	repo.On("GetMember", ctx, serverID, stranger).Return(nil, nil)
+	service := NewServerService(repo, nil, nil, nil, nil, nil)
+
+	found, err := service.GetMembersByIDs(ctx, serverID, []uuid.UUID{member.UserID, stranger})
+	require.NoError(t, err)
+	assert.Equal(t, []*models.Member{member}, found)
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	maxMemberVerificationFields      = 5
+	maxMemberVerificationRules       = 16
+	maxMemberVerificationTextLength  = 300 // Descriptions, labels and rules
+	maxMemberVerificationShortAnswer = 150
+	maxMemberVerificationParagraph   = 1000
+)
+
+// MemberVerificationRepository stores membership screening forms and
+// answers. The Postgres MemberVerificationRepository implements it.
+type MemberVerificationRepository interface {
+	// GetSettings returns the default form for servers without one
+	GetSettings(ctx context.Context, serverID uuid.UUID) (*models.MemberVerification, error)
+	SaveSettings(ctx context.Context, settings *models.MemberVerification) error
+	// ClearPending lets every pending member in and returns their IDs
+	ClearPending(ctx context.Context, serverID uuid.UUID) ([]uuid.UUID, error)
+	// Accept clears the member's pending flag and stores their answers,
+	// reporting false if they weren't pending
+	Accept(ctx context.Context, response *models.MemberVerificationResponse) (bool, error)
+	GetResponse(ctx context.Context, serverID, userID uuid.UUID) (*models.MemberVerificationResponse, error)
+}
+
+// MembershipScreening tells the ServerService whether new members of a
+// server start out pending
+type MembershipScreening interface {
+	ScreeningEnabled(ctx context.Context, serverID uuid.UUID) (bool, error)
+}
+
+// SetMembershipScreening makes members who join a server with screening
+// turned on pending until they accept its rules
+func (s *ServerService) SetMembershipScreening(screening MembershipScreening) {
+	s.screening = screening
+}
+
+// MemberVerificationService handles membership screening: a server's rules
+// and questions, which members who join while screening is on must accept
+// before they can send messages or react.
+type MemberVerificationService struct {
+	repo       MemberVerificationRepository
+	serverRepo ServerRepository
+	roleRepo   RoleRepository
+	eventBus   EventBus
+}
+
+// NewMemberVerificationService creates a new membership screening service.
+// Without a role repository only server owners can manage screening.
+func NewMemberVerificationService(
+	repo MemberVerificationRepository,
+	serverRepo ServerRepository,
+	roleRepo RoleRepository,
+	eventBus EventBus,
+) *MemberVerificationService {
+	return &MemberVerificationService{
+		repo:       repo,
+		serverRepo: serverRepo,
+		roleRepo:   roleRepo,
+		eventBus:   eventBus,
+	}
+}
+
+// GetSettings returns a server's screening form. Any member can read it,
+// including pending members who still have to fill it in.
+func (s *MemberVerificationService) GetSettings(ctx context.Context, serverID, requesterID uuid.UUID) (*models.MemberVerification, error) {
+	member, err := s.serverRepo.GetMember(ctx, serverID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrNotServerMember
+	}
+	return s.repo.GetSettings(ctx, serverID)
+}
+
+// UpdateSettings changes a server's screening form. Requires MANAGE_SERVER.
+// Any change gives the form a new version. Turning screening off lets
+// everyone still pending in.
+func (s *MemberVerificationService) UpdateSettings(ctx context.Context, serverID, requesterID uuid.UUID, req *models.UpdateMemberVerificationRequest) (*models.MemberVerification, error) {
+	server, err := s.serverRepo.GetByID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if server == nil {
+		return nil, ErrServerNotFound
+	}
+	if !memberHasPermission(ctx, s.serverRepo, s.roleRepo, server, requesterID, models.PermManageServer) {
+		return nil, ErrCannotManageMemberVerification
+	}
+
+	settings, err := s.repo.GetSettings(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	wasEnabled := settings.Enabled
+	if req.Enabled != nil {
+		settings.Enabled = *req.Enabled
+	}
+	if req.Description != nil {
+		settings.Description = nil
+		if description := strings.TrimSpace(*req.Description); description != "" {
+			settings.Description = &description
+		}
+	}
+	if req.FormFields != nil {
+		settings.FormFields = *req.FormFields
+	}
+	if err := normalizeMemberVerification(settings); err != nil {
+		return nil, err
+	}
+
+	// Versions round-trip through Postgres, which keeps microseconds
+	settings.Version = time.Now().UTC().Truncate(time.Microsecond)
+	if err := s.repo.SaveSettings(ctx, settings); err != nil {
+		return nil, err
+	}
+
+	if wasEnabled && !settings.Enabled {
+		userIDs, err := s.repo.ClearPending(ctx, serverID)
+		if err != nil {
+			return nil, err
+		}
+		for _, userID := range userIDs {
+			s.publishAccepted(ctx, serverID, userID)
+		}
+	}
+	return settings, nil
+}
+
+// Submit accepts a server's rules for a pending member and records their
+// answers. req.Version must be the version of the form they were shown.
+func (s *MemberVerificationService) Submit(ctx context.Context, serverID, userID uuid.UUID, req *models.SubmitMemberVerificationRequest) (*models.Member, error) {
+	member, err := s.serverRepo.GetMember(ctx, serverID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrNotServerMember
+	}
+	if !member.Pending {
+		return nil, ErrMembershipNotPending
+	}
+
+	settings, err := s.repo.GetSettings(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if !req.Version.Equal(settings.Version) {
+		return nil, ErrMemberVerificationOutdated
+	}
+	answers, err := memberVerificationAnswers(settings.FormFields, req.Responses)
+	if err != nil {
+		return nil, err
+	}
+
+	accepted, err := s.repo.Accept(ctx, &models.MemberVerificationResponse{
+		ServerID:    serverID,
+		UserID:      userID,
+		Answers:     answers,
+		SubmittedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !accepted {
+		return nil, ErrMembershipNotPending
+	}
+
+	member.Pending = false
+	s.eventBus.Publish("server.member_updated", &MemberUpdatedEvent{
+		ServerID: serverID,
+		UserID:   userID,
+		Member:   member,
+	})
+	return member, nil
+}
+
+// GetResponse returns what a member answered when they joined. Requires
+// KICK_MEMBERS.
+func (s *MemberVerificationService) GetResponse(ctx context.Context, serverID, requesterID, userID uuid.UUID) (*models.MemberVerificationResponse, error) {
+	server, err := s.serverRepo.GetByID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if server == nil {
+		return nil, ErrServerNotFound
+	}
+	if !memberHasPermission(ctx, s.serverRepo, s.roleRepo, server, requesterID, models.PermKickMembers) {
+		return nil, ErrCannotManageMemberVerification
+	}
+
+	response, err := s.repo.GetResponse(ctx, serverID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if response == nil {
+		return nil, ErrMemberVerificationNotFound
+	}
+	return response, nil
+}
+
+// ScreeningEnabled reports whether new members of the server start out
+// pending
+func (s *MemberVerificationService) ScreeningEnabled(ctx context.Context, serverID uuid.UUID) (bool, error) {
+	settings, err := s.repo.GetSettings(ctx, serverID)
+	if err != nil {
+		return false, err
+	}
+	return settings.Enabled, nil
+}
+
+// publishAccepted tells the server a member is no longer pending
+func (s *MemberVerificationService) publishAccepted(ctx context.Context, serverID, userID uuid.UUID) {
+	member, err := s.serverRepo.GetMember(ctx, serverID, userID)
+	if err != nil || member == nil {
+		return
+	}
+	s.eventBus.Publish("server.member_updated", &MemberUpdatedEvent{
+		ServerID: serverID,
+		UserID:   userID,
+		Member:   member,
+	})
+}
+
+func invalidMemberVerification(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidMemberVerification, fmt.Sprintf(format, args...))
+}
+
+// normalizeMemberVerification trims and checks a form before it's saved
+func normalizeMemberVerification(settings *models.MemberVerification) error {
+	if settings.Description != nil && utf8.RuneCountInString(*settings.Description) > maxMemberVerificationTextLength {
+		return invalidMemberVerification("description can be at most %d characters", maxMemberVerificationTextLength)
+	}
+	if len(settings.FormFields) > maxMemberVerificationFields {
+		return invalidMemberVerification("at most %d fields", maxMemberVerificationFields)
+	}
+	if settings.Enabled && len(settings.FormFields) == 0 {
+		return invalidMemberVerification("screening needs rules or a question")
+	}
+
+	terms := 0
+	for i := range settings.FormFields {
+		field := &settings.FormFields[i]
+		if !field.FieldType.Valid() {
+			return invalidMemberVerification("field_type must be terms, text_input or paragraph")
+		}
+		field.Label = strings.TrimSpace(field.Label)
+		if field.Label == "" || utf8.RuneCountInString(field.Label) > maxMemberVerificationTextLength {
+			return invalidMemberVerification("labels must be 1-%d characters", maxMemberVerificationTextLength)
+		}
+
+		if field.FieldType != models.MemberVerificationTerms {
+			if len(field.Values) > 0 {
+				return invalidMemberVerification("only terms fields have values")
+			}
+			continue
+		}
+		if terms++; terms > 1 {
+			return invalidMemberVerification("only one terms field")
+		}
+		// Submitting the form is what accepts the rules
+		field.Required = true
+		if len(field.Values) == 0 || len(field.Values) > maxMemberVerificationRules {
+			return invalidMemberVerification("terms fields need 1-%d rules", maxMemberVerificationRules)
+		}
+		for j, rule := range field.Values {
+			field.Values[j] = strings.TrimSpace(rule)
+			if field.Values[j] == "" || utf8.RuneCountInString(field.Values[j]) > maxMemberVerificationTextLength {
+				return invalidMemberVerification("rules must be 1-%d characters", maxMemberVerificationTextLength)
+			}
+		}
+	}
+	return nil
+}
+
+// memberVerificationAnswers pairs a member's responses with the form's
+// questions. Responses line up with the fields; terms fields take none.
+func memberVerificationAnswers(fields []models.MemberVerificationField, responses []string) ([]models.MemberVerificationAnswer, error) {
+	if len(responses) > len(fields) {
+		return nil, invalidMemberVerification("more responses than fields")
+	}
+
+	answers := []models.MemberVerificationAnswer{}
+	for i, field := range fields {
+		if field.FieldType == models.MemberVerificationTerms {
+			continue
+		}
+		var response string
+		if i < len(responses) {
+			response = strings.TrimSpace(responses[i])
+		}
+		if response == "" {
+			if field.Required {
+				return nil, ErrMemberVerificationResponseMissing
+			}
+			continue
+		}
+		limit := maxMemberVerificationShortAnswer
+		if field.FieldType == models.MemberVerificationParagraph {
+			limit = maxMemberVerificationParagraph
+		}
+		if utf8.RuneCountInString(response) > limit {
+			return nil, invalidMemberVerification("answers to %q can be at most %d characters", field.Label, limit)
+		}
+		answers = append(answers, models.MemberVerificationAnswer{Label: field.Label, Response: response})
+	}
+	return answers, nil
+}
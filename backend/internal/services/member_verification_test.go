@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeMemberVerificationRepository struct {
+	settings  *models.MemberVerification
+	pending   map[uuid.UUID]bool
+	responses map[uuid.UUID]*models.MemberVerificationResponse
+}
+
+func (f *fakeMemberVerificationRepository) GetSettings(ctx context.Context, serverID uuid.UUID) (*models.MemberVerification, error) {
+	if f.settings == nil {
+		return models.DefaultMemberVerification(serverID), nil
+	}
+	copied := *f.settings
+	copied.FormFields = append([]models.MemberVerificationField{}, f.settings.FormFields...)
+	return &copied, nil
+}
+
+func (f *fakeMemberVerificationRepository) SaveSettings(ctx context.Context, settings *models.MemberVerification) error {
+	copied := *settings
+	f.settings = &copied
+	return nil
+}
+
+func (f *fakeMemberVerificationRepository) ClearPending(ctx context.Context, serverID uuid.UUID) ([]uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	for userID, pending := range f.pending {
+		if pending {
+			userIDs = append(userIDs, userID)
+			f.pending[userID] = false
+		}
+	}
+	return userIDs, nil
+}
+
+func (f *fakeMemberVerificationRepository) Accept(ctx context.Context, response *models.MemberVerificationResponse) (bool, error) {
+	if !f.pending[response.UserID] {
+		return false, nil
+	}
+	f.pending[response.UserID] = false
+	f.responses[response.UserID] = response
+	return true, nil
+}
+
+func (f *fakeMemberVerificationRepository) GetResponse(ctx context.Context, serverID, userID uuid.UUID) (*models.MemberVerificationResponse, error) {
+	return f.responses[userID], nil
+}
+
+type memberVerificationTest struct {
+	service    *MemberVerificationService
+	repo       *fakeMemberVerificationRepository
+	serverRepo *MockServerRepository
+	eventBus   *MockEventBus
+	server     *models.Server
+}
+
+func newMemberVerificationTest() *memberVerificationTest {
+	f := &memberVerificationTest{
+		repo: &fakeMemberVerificationRepository{
+			pending:   make(map[uuid.UUID]bool),
+			responses: make(map[uuid.UUID]*models.MemberVerificationResponse),
+		},
+		serverRepo: new(MockServerRepository),
+		eventBus:   new(MockEventBus),
+		server:     &models.Server{ID: uuid.New(), OwnerID: uuid.New()},
+	}
+	// Without a role repository only the owner manages screening
+	f.service = NewMemberVerificationService(f.repo, f.serverRepo, nil, f.eventBus)
+	f.serverRepo.On("GetByID", mock.Anything, f.server.ID).Return(f.server, nil)
+	return f
+}
+
+// addMember makes userID a member, pending if pending is set
+func (f *memberVerificationTest) addMember(userID uuid.UUID, pending bool) *models.Member {
+	member := &models.Member{ServerID: f.server.ID, UserID: userID, Pending: pending}
+	f.repo.pending[userID] = pending
+	f.serverRepo.On("GetMember", mock.Anything, f.server.ID, userID).Return(member, nil)
+	return member
+}
+
+func (f *memberVerificationTest) enable(t *testing.T) *models.MemberVerification {
+	enabled := true
+	fields := []models.MemberVerificationField{
+		{FieldType: models.MemberVerificationTerms, Label: "Read the rules", Values: []string{" Be kind ", "No spam"}},
+		{FieldType: models.MemberVerificationTextInput, Label: "How did you find us?", Required: true},
+		{FieldType: models.MemberVerificationParagraph, Label: "Anything else?"},
+	}
+	settings, err := f.service.UpdateSettings(context.Background(), f.server.ID, f.server.OwnerID, &models.UpdateMemberVerificationRequest{
+		Enabled:    &enabled,
+		FormFields: &fields,
+	})
+	require.NoError(t, err)
+	return settings
+}
+
+func TestMemberVerificationUpdateSettings(t *testing.T) {
+	f := newMemberVerificationTest()
+	ctx := context.Background()
+
+	settings := f.enable(t)
+	assert.True(t, settings.Enabled)
+	assert.False(t, settings.Version.IsZero())
+	assert.Equal(t, []string{"Be kind", "No spam"}, settings.FormFields[0].Values)
+	assert.True(t, settings.FormFields[0].Required, "rules always have to be accepted")
+
+	enabled, err := f.service.ScreeningEnabled(ctx, f.server.ID)
+	require.NoError(t, err)
+	assert.True(t, enabled)
+
+	description := "  Welcome!  "
+	updated, err := f.service.UpdateSettings(ctx, f.server.ID, f.server.OwnerID, &models.UpdateMemberVerificationRequest{Description: &description})
+	require.NoError(t, err)
+	assert.Equal(t, "Welcome!", *updated.Description)
+	assert.Len(t, updated.FormFields, 3, "omitted fields are left alone")
+	assert.False(t, updated.Version.Before(settings.Version), "every change gets a new version")
+
+	_, err = f.service.UpdateSettings(ctx, f.server.ID, uuid.New(), &models.UpdateMemberVerificationRequest{Description: &description})
+	assert.ErrorIs(t, err, ErrCannotManageMemberVerification)
+}
+
+func TestMemberVerificationUpdateSettings_Validation(t *testing.T) {
+	f := newMemberVerificationTest()
+	ctx := context.Background()
+	enabled := true
+	terms := models.MemberVerificationField{FieldType: models.MemberVerificationTerms, Label: "Rules", Values: []string{"Be kind"}}
+
+	for name, fields := range map[string][]models.MemberVerificationField{
+		"no fields":            {},
+		"unknown type":         {{FieldType: "checkbox", Label: "Agree?"}},
+		"empty label":          {{FieldType: models.MemberVerificationTextInput, Label: "  "}},
+		"two terms fields":     {terms, terms},
+		"terms without rules":  {{FieldType: models.MemberVerificationTerms, Label: "Rules"}},
+		"empty rule":           {{FieldType: models.MemberVerificationTerms, Label: "Rules", Values: []string{""}}},
+		"question with values": {{FieldType: models.MemberVerificationTextInput, Label: "Why?", Values: []string{"a"}}},
+		"too many fields":      {terms, {FieldType: "text_input", Label: "1"}, {FieldType: "text_input", Label: "2"}, {FieldType: "text_input", Label: "3"}, {FieldType: "text_input", Label: "4"}, {FieldType: "text_input", Label: "5"}},
+	} {
+		fields := fields
+		_, err := f.service.UpdateSettings(ctx, f.server.ID, f.server.OwnerID, &models.UpdateMemberVerificationRequest{Enabled: &enabled, FormFields: &fields})
+		assert.ErrorIs(t, err, ErrInvalidMemberVerification, name)
+	}
+	assert.Nil(t, f.repo.settings)
+}
+
+func TestMemberVerificationSubmit(t *testing.T) {
+	f := newMemberVerificationTest()
+	ctx := context.Background()
+	settings := f.enable(t)
+	userID := uuid.New()
+	f.addMember(userID, true)
+	member := f.addMember(uuid.New(), false)
+
+	_, err := f.service.Submit(ctx, f.server.ID, userID, &models.SubmitMemberVerificationRequest{Version: settings.Version.Add(-time.Minute), Responses: []string{"", "A friend"}})
+	assert.ErrorIs(t, err, ErrMemberVerificationOutdated)
+	_, err = f.service.Submit(ctx, f.server.ID, userID, &models.SubmitMemberVerificationRequest{Version: settings.Version})
+	assert.ErrorIs(t, err, ErrMemberVerificationResponseMissing)
+	_, err = f.service.Submit(ctx, f.server.ID, member.UserID, &models.SubmitMemberVerificationRequest{Version: settings.Version, Responses: []string{"", "A friend"}})
+	assert.ErrorIs(t, err, ErrMembershipNotPending)
+
+	f.eventBus.On("Publish", "server.member_updated", mock.MatchedBy(func(e *MemberUpdatedEvent) bool {
+		return e.UserID == userID && !e.Member.Pending
+	})).Return().Once()
+	accepted, err := f.service.Submit(ctx, f.server.ID, userID, &models.SubmitMemberVerificationRequest{Version: settings.Version, Responses: []string{"", " A friend "}})
+	require.NoError(t, err)
+	assert.False(t, accepted.Pending)
+	f.eventBus.AssertExpectations(t)
+
+	response, err := f.service.GetResponse(ctx, f.server.ID, f.server.OwnerID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, []models.MemberVerificationAnswer{{Label: "How did you find us?", Response: "A friend"}}, response.Answers)
+
+	_, err = f.service.GetResponse(ctx, f.server.ID, userID, userID)
+	assert.ErrorIs(t, err, ErrCannotManageMemberVerification)
+	_, err = f.service.GetResponse(ctx, f.server.ID, f.server.OwnerID, member.UserID)
+	assert.ErrorIs(t, err, ErrMemberVerificationNotFound)
+}
+
+func TestMemberVerificationDisable_ClearsPending(t *testing.T) {
+	f := newMemberVerificationTest()
+	ctx := context.Background()
+	f.enable(t)
+	pendingID := uuid.New()
+	f.addMember(pendingID, true)
+	f.addMember(uuid.New(), false)
+
+	f.eventBus.On("Publish", "server.member_updated", mock.MatchedBy(func(e *MemberUpdatedEvent) bool {
+		return e.UserID == pendingID
+	})).Return().Once()
+	disabled := false
+	settings, err := f.service.UpdateSettings(ctx, f.server.ID, f.server.OwnerID, &models.UpdateMemberVerificationRequest{Enabled: &disabled})
+	require.NoError(t, err)
+	assert.False(t, settings.Enabled)
+	assert.False(t, f.repo.pending[pendingID])
+	f.eventBus.AssertExpectations(t)
+}
+
+func TestJoinServer_MembershipScreening(t *testing.T) {
+	service, serverRepo, _, roleRepo, _, eventBus := newTestServerService()
+	ctx := context.Background()
+	userID := uuid.New()
+	server := &models.Server{ID: uuid.New(), Name: "Screened"}
+	invite := &models.Invite{Code: "screened", ServerID: server.ID}
+
+	screening := newMemberVerificationTest()
+	screening.enable(t)
+	service.SetMembershipScreening(screening.service)
+
+	serverRepo.On("GetInvite", ctx, invite.Code).Return(invite, nil)
+	serverRepo.On("GetByID", ctx, server.ID).Return(server, nil)
+	serverRepo.On("GetBan", ctx, server.ID, userID).Return(nil, nil)
+	serverRepo.On("GetMember", ctx, server.ID, userID).Return(nil, nil)
+	serverRepo.On("GetUserServers", ctx, userID).Return([]*models.Server{}, nil)
+	roleRepo.On("GetByServerID", ctx, server.ID).Return([]*models.Role{{ID: uuid.New(), IsDefault: true}}, nil)
+	serverRepo.On("AddMember", ctx, mock.MatchedBy(func(m *models.Member) bool { return m.Pending })).Return(nil)
+	serverRepo.On("IncrementInviteUses", ctx, invite.Code).Return(nil)
+	eventBus.On("Publish", "server.member_joined", mock.MatchedBy(func(e *MemberJoinedEvent) bool {
+		return e.Member != nil && e.Member.Pending
+	})).Return()
+
+	_, err := service.JoinServer(ctx, userID, invite.Code)
+	require.NoError(t, err)
+	serverRepo.AssertExpectations(t)
+	eventBus.AssertExpectations(t)
+}
+
+func TestSendMessage_MembershipPending(t *testing.T) {
+	service, _, channelRepo, serverRepo, _, _, _, _, _ := setupMessageService()
+	ctx := context.Background()
+	authorID := uuid.New()
+	channelID := uuid.New()
+	serverID := uuid.New()
+
+	channelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, ServerID: &serverID, Type: models.ChannelTypeText}, nil)
+	serverRepo.On("GetMember", ctx, serverID, authorID).Return(&models.Member{ServerID: serverID, UserID: authorID, Pending: true}, nil)
+
+	message, err := service.SendMessage(ctx, authorID, channelID, "Hello!", nil, nil)
+	assert.Equal(t, ErrMembershipPending, err)
+	assert.Nil(t, message)
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	// maxMentionsPerMessage caps the user and role mentions honored per
+	// message
+	maxMentionsPerMessage = 50
+	mentionMemberPageSize = 1000
+	// mentionNotifyTimeout bounds counting and announcing one message's
+	// mentions, which run after the send returns
+	mentionNotifyTimeout = 30 * time.Second
+)
+
+// Mention syntax, as rendered by the client: <@user>, <@!user>, <@&role>,
+// @everyone and @here
+var (
+	userMentionPattern     = regexp.MustCompile(`<@!?([0-9a-fA-F-]{36})>`)
+	roleMentionPattern     = regexp.MustCompile(`<@&([0-9a-fA-F-]{36})>`)
+	everyoneMentionPattern = regexp.MustCompile(`(?:^|[^\w<@])@(everyone|here)\b`)
+	codeSpanPattern        = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+)
+
+// MentionCounter tracks unread mentions per user and channel.
+// ReadStateService implements it.
+type MentionCounter interface {
+	IncrementMentions(ctx context.Context, channelID uuid.UUID, userIDs []uuid.UUID) error
+}
+
+// MentionPresence reports who is online, for @here. The gateway hub
+// implements it.
+type MentionPresence interface {
+	GetPresences(userIDs []uuid.UUID) map[uuid.UUID]*models.Presence
+}
+
+// parsedMentions is what a message's content asks to mention, before
+// permissions are applied
+type parsedMentions struct {
+	Users    []uuid.UUID
+	Roles    []uuid.UUID
+	Everyone bool // @everyone or @here
+	Here     bool // @here without @everyone, which only notifies online members
+}
+
+func (p parsedMentions) empty() bool {
+	return len(p.Users) == 0 && len(p.Roles) == 0 && !p.Everyone
+}
+
+// parseMentions extracts mentions from content. Mentions inside code spans
+// and blocks are ignored.
+func parseMentions(content string) parsedMentions {
+	content = codeSpanPattern.ReplaceAllString(content, "")
+
+	parsed := parsedMentions{
+		Users: matchIDs(userMentionPattern, content),
+		Roles: matchIDs(roleMentionPattern, content),
+	}
+	for _, m := range everyoneMentionPattern.FindAllStringSubmatch(content, -1) {
+		parsed.Everyone = true
+		parsed.Here = m[1] == "here"
+		if !parsed.Here {
+			break
+		}
+	}
+	return parsed
+}
+
+// matchIDs returns the unique IDs captured by pattern, in order of first
+// appearance
+func matchIDs(pattern *regexp.Regexp, content string) []uuid.UUID {
+	var ids []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, m := range pattern.FindAllStringSubmatch(content, -1) {
+		id, err := uuid.Parse(m[1])
+		if err != nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+		if len(ids) == maxMentionsPerMessage {
+			break
+		}
+	}
+	return ids
+}
+
+// applyMentions sets the message's mentions to those the author may make.
+// Mentioning @everyone, @here or a role that isn't mentionable needs
+// MENTION_EVERYONE; without it the text is kept but nobody is notified.
+func (s *MessageService) applyMentions(ctx context.Context, message *models.Message, channel *models.Channel, parsed parsedMentions) {
+	message.Mentions = nil
+	message.MentionRoles = nil
+	message.MentionEveryone = false
+
+	if channel.ServerID == nil {
+		// DMs only mention their recipients
+		for _, id := range parsed.Users {
+			if id != message.AuthorID && isChannelParticipant(channel, id) {
+				message.Mentions = append(message.Mentions, id)
+			}
+		}
+		return
+	}
+	serverID := *channel.ServerID
+
+	for _, id := range parsed.Users {
+		if member, err := cachedMember(ctx, s.cache, s.serverRepo, serverID, id); err == nil && member != nil {
+			message.Mentions = append(message.Mentions, id)
+		}
+	}
+
+	if len(parsed.Roles) == 0 && !parsed.Everyone {
+		return
+	}
+
+	roles, canMentionEveryone := s.mentionPermissions(ctx, serverID, message.AuthorID)
+	message.MentionEveryone = parsed.Everyone && canMentionEveryone
+
+	byID := make(map[uuid.UUID]*models.Role, len(roles))
+	for _, r := range roles {
+		byID[r.ID] = r
+	}
+	for _, id := range parsed.Roles {
+		role, ok := byID[id]
+		// The @everyone role shares the server's ID and is mentioned by name
+		if !ok || role.ID == serverID {
+			continue
+		}
+		if role.Mentionable || canMentionEveryone {
+			message.MentionRoles = append(message.MentionRoles, id)
+		}
+	}
+}
+
+// mentionPermissions returns the server's roles and whether the author has
+// MENTION_EVERYONE. Without a role repository only the owner has it.
+func (s *MessageService) mentionPermissions(ctx context.Context, serverID, authorID uuid.UUID) ([]*models.Role, bool) {
+	server, err := s.serverRepo.GetByID(ctx, serverID)
+	if err != nil || server == nil {
+		return nil, false
+	}
+	if s.roleRepo == nil {
+		return nil, server.OwnerID == authorID
+	}
+
+	roles, err := s.roleRepo.GetByServerID(ctx, serverID)
+	if err != nil {
+		return nil, server.OwnerID == authorID
+	}
+	member, err := cachedMember(ctx, s.cache, s.serverRepo, serverID, authorID)
+	if err != nil || member == nil {
+		return roles, false
+	}
+
+	perms := models.CalculatePermissions(member, roles, server, nil, nil)
+	return roles, models.HasPermission(perms, models.PermMentionEveryone)
+}
+
+// mentionedUsers returns everyone a message notifies, excluding its author.
+// here narrows the message's @everyone to online members.
+func (s *MessageService) mentionedUsers(ctx context.Context, message *models.Message, here bool) []uuid.UUID {
+	notify := make(map[uuid.UUID]bool)
+	for _, id := range message.Mentions {
+		notify[id] = true
+	}
+
+	if message.ServerID != nil && (message.MentionEveryone || len(message.MentionRoles) > 0) {
+		roles := make(map[uuid.UUID]bool, len(message.MentionRoles))
+		for _, id := range message.MentionRoles {
+			roles[id] = true
+		}
+
+		for offset := 0; ; offset += mentionMemberPageSize {
+			members, err := s.serverRepo.GetMembers(ctx, *message.ServerID, mentionMemberPageSize, offset)
+			if err != nil {
+				break
+			}
+			var online map[uuid.UUID]bool
+			if message.MentionEveryone && here {
+				online = s.onlineMembers(members)
+			}
+			for _, m := range members {
+				switch {
+				case hasAnyRole(m, roles):
+					notify[m.UserID] = true
+				case message.MentionEveryone && (!here || online[m.UserID]):
+					notify[m.UserID] = true
+				}
+			}
+			if len(members) < mentionMemberPageSize {
+				break
+			}
+		}
+	}
+
+	delete(notify, message.AuthorID)
+
+	users := make([]uuid.UUID, 0, len(notify))
+	for id := range notify {
+		users = append(users, id)
+	}
+	return users
+}
+
+// onlineMembers returns which of members are online. Without a presence
+// source nobody is.
+func (s *MessageService) onlineMembers(members []*models.Member) map[uuid.UUID]bool {
+	online := make(map[uuid.UUID]bool)
+	if s.presence == nil {
+		return online
+	}
+	ids := make([]uuid.UUID, len(members))
+	for i, m := range members {
+		ids[i] = m.UserID
+	}
+	for id, p := range s.presence.GetPresences(ids) {
+		if p != nil && p.Status != models.StatusOffline && p.Status != models.StatusInvisible {
+			online[id] = true
+		}
+	}
+	return online
+}
+
+// notifyMentionsLater notifies a sent message's mentions in the background.
+// @everyone and role mentions read the whole member list, which a send
+// shouldn't wait on.
+func (s *MessageService) notifyMentionsLater(message *models.Message, here bool) {
+	if len(message.Mentions) == 0 && len(message.MentionRoles) == 0 && !message.MentionEveryone {
+		return
+	}
+	sent := *message
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mentionNotifyTimeout)
+		defer cancel()
+		s.notifyMentions(ctx, &sent, here)
+	}()
+}
+
+// notifyMentions bumps mention counters and announces the mentions so push
+// notifications can be sent
+func (s *MessageService) notifyMentions(ctx context.Context, message *models.Message, here bool) {
+	users := s.mentionedUsers(ctx, message, here)
+	if len(users) == 0 {
+		return
+	}
+
+	if s.mentionCounter != nil {
+		if err := s.mentionCounter.IncrementMentions(ctx, message.ChannelID, users); err != nil {
+			log.Printf("[MessageService] failed to count mentions of message %s: %v", message.ID, err)
+		}
+	}
+
+	s.eventBus.Publish("message.mentioned", &MessageMentionedEvent{
+		MessageID: message.ID,
+		ChannelID: message.ChannelID,
+		ServerID:  message.ServerID,
+		AuthorID:  message.AuthorID,
+		UserIDs:   users,
+		Message:   message,
+	})
+}
+
+func hasAnyRole(member *models.Member, roles map[uuid.UUID]bool) bool {
+	for _, id := range member.Roles {
+		if roles[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// MessageMentionedEvent lists the users a new message mentioned
+type MessageMentionedEvent struct {
+	MessageID uuid.UUID
+	ChannelID uuid.UUID
+	ServerID  *uuid.UUID
+	AuthorID  uuid.UUID
+	UserIDs   []uuid.UUID
+	Message   *models.Message
+}
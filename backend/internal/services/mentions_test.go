@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"hearth/internal/models"
+)
+
+type countingMentionCounter struct {
+	counts map[uuid.UUID]int
+	calls  int
+}
+
+func (c *countingMentionCounter) IncrementMentions(ctx context.Context, channelID uuid.UUID, userIDs []uuid.UUID) error {
+	if c.counts == nil {
+		c.counts = make(map[uuid.UUID]int)
+	}
+	c.calls++
+	for _, id := range userIDs {
+		c.counts[id]++
+	}
+	return nil
+}
+
+// fixedPresence reports the listed users online and everyone else offline
+type fixedPresence map[uuid.UUID]models.PresenceStatus
+
+func (f fixedPresence) GetPresences(userIDs []uuid.UUID) map[uuid.UUID]*models.Presence {
+	result := make(map[uuid.UUID]*models.Presence, len(userIDs))
+	for _, id := range userIDs {
+		status, ok := f[id]
+		if !ok {
+			status = models.StatusOffline
+		}
+		result[id] = &models.Presence{UserID: id, Status: status}
+	}
+	return result
+}
+
+func TestParseMentions(t *testing.T) {
+	userID := uuid.New()
+	nickID := uuid.New()
+	roleID := uuid.New()
+
+	t.Run("users and roles", func(t *testing.T) {
+		parsed := parseMentions("hi <@" + userID.String() + "> and <@!" + nickID.String() + "> ping <@&" + roleID.String() + "> <@" + userID.String() + ">")
+
+		assert.Equal(t, []uuid.UUID{userID, nickID}, parsed.Users)
+		assert.Equal(t, []uuid.UUID{roleID}, parsed.Roles)
+		assert.False(t, parsed.Everyone)
+	})
+
+	t.Run("everyone and here", func(t *testing.T) {
+		assert.True(t, parseMentions("@everyone look").Everyone)
+		assert.False(t, parseMentions("@everyone look").Here)
+		assert.True(t, parseMentions("hey @here").Everyone)
+		assert.True(t, parseMentions("hey @here").Here)
+		assert.False(t, parseMentions("@here and @everyone").Here, "@everyone wins")
+		assert.False(t, parseMentions("@everyone and @here").Here, "@everyone wins")
+		assert.False(t, parseMentions("mail me at a@everyone.com").Everyone)
+		assert.False(t, parseMentions("@everyones").Everyone)
+	})
+
+	t.Run("ignores code", func(t *testing.T) {
+		parsed := parseMentions("`<@" + userID.String() + ">` and ```\n@everyone\n```")
+		assert.True(t, parsed.empty())
+	})
+
+	t.Run("ignores invalid ids", func(t *testing.T) {
+		assert.True(t, parseMentions("Hello <@123456> and <@789012>!").empty())
+	})
+
+	t.Run("caps mentions", func(t *testing.T) {
+		content := ""
+		for i := 0; i < maxMentionsPerMessage+10; i++ {
+			content += "<@" + uuid.New().String() + ">"
+		}
+		assert.Len(t, parseMentions(content).Users, maxMentionsPerMessage)
+	})
+}
+
+func TestApplyMentions_DMOnlyMentionsRecipients(t *testing.T) {
+	service, _, _, _, _, _, _, _, _ := setupMessageService()
+	authorID := uuid.New()
+	recipientID := uuid.New()
+	strangerID := uuid.New()
+
+	channel := &models.Channel{Type: models.ChannelTypeDM, Recipients: []uuid.UUID{authorID, recipientID}}
+	message := &models.Message{AuthorID: authorID}
+
+	service.applyMentions(context.Background(), message, channel, parsedMentions{
+		Users:    []uuid.UUID{recipientID, strangerID, authorID},
+		Everyone: true,
+	})
+
+	assert.Equal(t, []uuid.UUID{recipientID}, message.Mentions)
+	assert.False(t, message.MentionEveryone)
+}
+
+func TestApplyMentions_RequiresMentionEveryone(t *testing.T) {
+	ctx := context.Background()
+	serverID := uuid.New()
+	authorID := uuid.New()
+	mentionable := &models.Role{ID: uuid.New(), ServerID: serverID, Mentionable: true}
+	locked := &models.Role{ID: uuid.New(), ServerID: serverID}
+	everyoneRole := &models.Role{ID: serverID, ServerID: serverID, Permissions: models.PermSendMessages}
+	server := &models.Server{ID: serverID, OwnerID: uuid.New()}
+	channel := &models.Channel{ServerID: &serverID}
+	parsed := parsedMentions{Roles: []uuid.UUID{mentionable.ID, locked.ID, serverID}, Everyone: true}
+
+	t.Run("without permission", func(t *testing.T) {
+		service, _, _, serverRepo, _, _, _, _, _ := setupMessageService()
+		roleRepo := new(MockRoleRepository)
+		service.SetRoleRepository(roleRepo)
+
+		serverRepo.On("GetByID", ctx, serverID).Return(server, nil)
+		serverRepo.On("GetMember", ctx, serverID, authorID).Return(&models.Member{UserID: authorID, ServerID: serverID}, nil)
+		roleRepo.On("GetByServerID", ctx, serverID).Return([]*models.Role{everyoneRole, mentionable, locked}, nil)
+
+		message := &models.Message{AuthorID: authorID}
+		service.applyMentions(ctx, message, channel, parsed)
+
+		assert.Equal(t, []uuid.UUID{mentionable.ID}, message.MentionRoles)
+		assert.False(t, message.MentionEveryone)
+	})
+
+	t.Run("with permission", func(t *testing.T) {
+		service, _, _, serverRepo, _, _, _, _, _ := setupMessageService()
+		roleRepo := new(MockRoleRepository)
+		service.SetRoleRepository(roleRepo)
+
+		announcer := &models.Role{ID: uuid.New(), ServerID: serverID, Permissions: models.PermMentionEveryone}
+		serverRepo.On("GetByID", ctx, serverID).Return(server, nil)
+		serverRepo.On("GetMember", ctx, serverID, authorID).Return(&models.Member{UserID: authorID, ServerID: serverID, Roles: []uuid.UUID{announcer.ID}}, nil)
+		roleRepo.On("GetByServerID", ctx, serverID).Return([]*models.Role{everyoneRole, mentionable, locked, announcer}, nil)
+
+		message := &models.Message{AuthorID: authorID}
+		service.applyMentions(ctx, message, channel, parsed)
+
+		assert.Equal(t, []uuid.UUID{mentionable.ID, locked.ID}, message.MentionRoles)
+		assert.True(t, message.MentionEveryone)
+	})
+}
+
+func TestApplyMentions_DropsNonMembers(t *testing.T) {
+	ctx := context.Background()
+	service, _, _, serverRepo, _, _, _, _, _ := setupMessageService()
+	serverID := uuid.New()
+	memberID := uuid.New()
+	outsiderID := uuid.New()
+
+	serverRepo.On("GetMember", ctx, serverID, memberID).Return(&models.Member{UserID: memberID}, nil)
+	serverRepo.On("GetMember", ctx, serverID, outsiderID).Return(nil, nil)
+
+	message := &models.Message{AuthorID: uuid.New()}
+	service.applyMentions(ctx, message, &models.Channel{ServerID: &serverID}, parsedMentions{Users: []uuid.UUID{memberID, outsiderID}})
+
+	assert.Equal(t, []uuid.UUID{memberID}, message.Mentions)
+}
+
+func TestNotifyMentions(t *testing.T) {
+	ctx := context.Background()
+	service, _, _, serverRepo, _, _, _, _, eventBus := setupMessageService()
+	counter := &countingMentionCounter{}
+	service.SetMentionCounter(counter)
+
+	serverID := uuid.New()
+	authorID := uuid.New()
+	directID := uuid.New()
+	roleID := uuid.New()
+	roleMemberID := uuid.New()
+	otherID := uuid.New()
+
+	serverRepo.On("GetMembers", ctx, serverID, mentionMemberPageSize, 0).Return([]*models.Member{
+		{UserID: authorID, Roles: []uuid.UUID{roleID}},
+		{UserID: directID},
+		{UserID: roleMemberID, Roles: []uuid.UUID{roleID}},
+		{UserID: otherID},
+	}, nil)
+	eventBus.On("Publish", "message.mentioned", mock.AnythingOfType("*services.MessageMentionedEvent")).Return()
+
+	message := &models.Message{
+		ID:           uuid.New(),
+		ChannelID:    uuid.New(),
+		ServerID:     &serverID,
+		AuthorID:     authorID,
+		Mentions:     []uuid.UUID{directID, authorID},
+		MentionRoles: []uuid.UUID{roleID},
+	}
+	service.notifyMentions(ctx, message, false)
+
+	assert.Equal(t, map[uuid.UUID]int{directID: 1, roleMemberID: 1}, counter.counts)
+	assert.Equal(t, 1, counter.calls, "counted in one batch")
+
+	event := eventBus.Calls[0].Arguments.Get(1).(*MessageMentionedEvent)
+	assert.ElementsMatch(t, []uuid.UUID{directID, roleMemberID}, event.UserIDs)
+}
+
+func TestNotifyMentions_Everyone(t *testing.T) {
+	ctx := context.Background()
+	service, _, _, serverRepo, _, _, _, _, eventBus := setupMessageService()
+	counter := &countingMentionCounter{}
+	service.SetMentionCounter(counter)
+
+	serverID := uuid.New()
+	authorID := uuid.New()
+	members := []*models.Member{{UserID: authorID}, {UserID: uuid.New()}, {UserID: uuid.New()}}
+
+	serverRepo.On("GetMembers", ctx, serverID, mentionMemberPageSize, 0).Return(members, nil)
+	eventBus.On("Publish", "message.mentioned", mock.AnythingOfType("*services.MessageMentionedEvent")).Return()
+
+	service.notifyMentions(ctx, &models.Message{ServerID: &serverID, AuthorID: authorID, MentionEveryone: true}, false)
+
+	assert.Len(t, counter.counts, 2)
+	assert.NotContains(t, counter.counts, authorID)
+}
+
+func TestNotifyMentions_HereOnlyNotifiesOnlineMembers(t *testing.T) {
+	ctx := context.Background()
+	service, _, _, serverRepo, _, _, _, _, eventBus := setupMessageService()
+	counter := &countingMentionCounter{}
+	service.SetMentionCounter(counter)
+
+	serverID := uuid.New()
+	authorID := uuid.New()
+	roleID := uuid.New()
+	onlineID, idleID, invisibleID, offlineID, roleMemberID := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	service.SetMentionPresence(fixedPresence{
+		authorID:    models.StatusOnline,
+		onlineID:    models.StatusOnline,
+		idleID:      models.StatusIdle,
+		invisibleID: models.StatusInvisible,
+	})
+
+	serverRepo.On("GetMembers", ctx, serverID, mentionMemberPageSize, 0).Return([]*models.Member{
+		{UserID: authorID}, {UserID: onlineID}, {UserID: idleID}, {UserID: invisibleID}, {UserID: offlineID},
+		{UserID: roleMemberID, Roles: []uuid.UUID{roleID}},
+	}, nil)
+	eventBus.On("Publish", "message.mentioned", mock.AnythingOfType("*services.MessageMentionedEvent")).Return()
+
+	// Role mentions still reach offline members
+	service.notifyMentions(ctx, &models.Message{ServerID: &serverID, AuthorID: authorID, MentionEveryone: true, MentionRoles: []uuid.UUID{roleID}}, true)
+
+	assert.Equal(t, map[uuid.UUID]int{onlineID: 1, idleID: 1, roleMemberID: 1}, counter.counts)
+}
+
+func TestNotifyMentions_NoMentions(t *testing.T) {
+	service, _, _, _, _, _, _, _, eventBus := setupMessageService()
+	counter := &countingMentionCounter{}
+	service.SetMentionCounter(counter)
+
+	service.notifyMentions(context.Background(), &models.Message{AuthorID: uuid.New()}, false)
+
+	assert.Empty(t, counter.counts)
+	eventBus.AssertNotCalled(t, "Publish", "message.mentioned", mock.Anything)
+}
+
+// FuzzParseMentions checks that mention parsing never panics and honors
+// its limits whatever the message content
+func FuzzParseMentions(f *testing.F) {
+	id := "7f8a5e0e-5f43-4bf4-8c2b-2a0b3b5f1a11"
+	for _, seed := range []string{
+		"hello <@" + id + ">",
+		"<@!" + id + "> <@&" + id + "> @everyone",
+		"`<@" + id + ">` ```@here```",
+		"```unterminated <@" + id + ">",
+		"email@everyone.com <@@here",
+		"<@7F8A5E0E-5F43-4BF4-8C2B-2A0B3B5F1A11>",
+		"<@------------------------------------>",
+		strings.Repeat("<@"+id+">", 60),
+		strings.Repeat("`", 1000),
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, content string) {
+		parsed := parseMentions(content)
+		lower := strings.ToLower(content)
+
+		for _, ids := range [][]uuid.UUID{parsed.Users, parsed.Roles} {
+			if len(ids) > maxMentionsPerMessage {
+				t.Fatalf("parsed %d mentions, limit is %d", len(ids), maxMentionsPerMessage)
+			}
+			seen := make(map[uuid.UUID]bool)
+			for _, id := range ids {
+				if seen[id] {
+					t.Fatalf("duplicate mention %s", id)
+				}
+				seen[id] = true
+				if !strings.Contains(lower, id.String()) {
+					t.Fatalf("mention %s does not appear in content", id)
+				}
+			}
+		}
+	})
+}
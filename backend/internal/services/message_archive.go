@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// MessageArchiveRepository moves old messages out of the messages table
+// into cold storage, where they stay readable
+type MessageArchiveRepository interface {
+	LargeServers(ctx context.Context, minMembers int) ([]uuid.UUID, error)
+	// ArchiveServerMessages moves up to limit of the server's messages sent
+	// before cutoff and returns how many it moved
+	ArchiveServerMessages(ctx context.Context, serverID uuid.UUID, cutoff time.Time, limit int) (int, error)
+	GetChannelMessages(ctx context.Context, channelID uuid.UUID, before *uuid.UUID, limit int) ([]*models.Message, error)
+}
+
+// MessageArchivePolicy decides which messages are archived: those older
+// than After in servers with at least MinMembers members. Messages are
+// moved BatchSize at a time.
+type MessageArchivePolicy struct {
+	After      time.Duration
+	MinMembers int
+	BatchSize  int
+}
+
+// SetArchiveRepository makes archived messages readable. Archiving itself
+// is done by RunMessageArchiving.
+func (s *MessageService) SetArchiveRepository(archive MessageArchiveRepository) {
+	s.archive = archive
+}
+
+// GetArchivedMessages returns a page of a channel's archived messages,
+// newest first, to whoever may read the channel. Pass the oldest message
+// GetMessages returned as before to carry on from where it stops.
+func (s *MessageService) GetArchivedMessages(ctx context.Context, channelID uuid.UUID, requesterID uuid.UUID, before *uuid.UUID, limit int) ([]*models.Message, error) {
+	channel, err := cachedChannel(ctx, s.cache, s.channelRepo, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if channel == nil {
+		return nil, ErrChannelNotFound
+	}
+
+	if channel.ServerID != nil {
+		member, err := cachedMember(ctx, s.cache, s.serverRepo, *channel.ServerID, requesterID)
+		if err != nil || member == nil {
+			return nil, ErrNotServerMember
+		}
+	} else {
+		if !isChannelParticipant(channel, requesterID) {
+			return nil, ErrNoPermission
+		}
+	}
+
+	if s.archive == nil {
+		return []*models.Message{}, nil
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	messages, err := s.archive.GetChannelMessages(ctx, channelID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	messages, err = s.hideBlockedAuthors(ctx, requesterID, limit, messages, func(cursor uuid.UUID) ([]*models.Message, error) {
+		return s.archive.GetChannelMessages(ctx, channelID, &cursor, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.attachAuthors(ctx, messages...)
+	return messages, nil
+}
+
+// ArchiveMessages moves the messages policy covers into the archive and
+// returns how many it moved. Each server's messages are moved batch by
+// batch until none are left or ctx is cancelled.
+func (s *MessageService) ArchiveMessages(ctx context.Context, now time.Time, policy MessageArchivePolicy) (int, error) {
+	if s.archive == nil || policy.After <= 0 {
+		return 0, nil
+	}
+	batchSize := policy.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	servers, err := s.archive.LargeServers(ctx, policy.MinMembers)
+	if err != nil {
+		return 0, err
+	}
+	cutoff := now.Add(-policy.After)
+	archived := 0
+	for _, serverID := range servers {
+		for {
+			if err := ctx.Err(); err != nil {
+				return archived, err
+			}
+			moved, err := s.archive.ArchiveServerMessages(ctx, serverID, cutoff, batchSize)
+			if err != nil {
+				return archived, err
+			}
+			archived += moved
+			if moved < batchSize {
+				break
+			}
+		}
+	}
+	return archived, nil
+}
+
+// RunMessageArchiving archives messages every interval until ctx is
+// cancelled
+func (s *MessageService) RunMessageArchiving(ctx context.Context, interval time.Duration, policy MessageArchivePolicy) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			archived, err := s.ArchiveMessages(ctx, now, policy)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("[MessageService] failed to archive messages: %v", err)
+			}
+			if archived > 0 {
+				log.Printf("[MessageService] archived %d messages", archived)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+// fakeMessageArchive holds how many messages each server has left to
+// archive
+type fakeMessageArchive struct {
+	servers  []uuid.UUID
+	pending  map[uuid.UUID]int
+	cutoff   time.Time
+	messages []*models.Message
+	before   *uuid.UUID
+}
+
+func (f *fakeMessageArchive) LargeServers(ctx context.Context, minMembers int) ([]uuid.UUID, error) {
+	return f.servers, nil
+}
+
+func (f *fakeMessageArchive) ArchiveServerMessages(ctx context.Context, serverID uuid.UUID, cutoff time.Time, limit int) (int, error) {
+	f.cutoff = cutoff
+	moved := f.pending[serverID]
+	if moved > limit {
+		moved = limit
+	}
+	f.pending[serverID] -= moved
+	return moved, nil
+}
+
+func (f *fakeMessageArchive) GetChannelMessages(ctx context.Context, channelID uuid.UUID, before *uuid.UUID, limit int) ([]*models.Message, error) {
+	f.before = before
+	return f.messages, nil
+}
+
+func TestArchiveMessages_MovesEveryBatch(t *testing.T) {
+	service, _, _, _, _, _, _, _, _ := setupMessageService()
+	busy, quiet := uuid.New(), uuid.New()
+	archive := &fakeMessageArchive{
+		servers: []uuid.UUID{busy, quiet},
+		pending: map[uuid.UUID]int{busy: 25, quiet: 3},
+	}
+	service.SetArchiveRepository(archive)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	archived, err := service.ArchiveMessages(context.Background(), now, MessageArchivePolicy{After: 90 * 24 * time.Hour, BatchSize: 10})
+
+	require.NoError(t, err)
+	assert.Equal(t, 28, archived)
+	assert.Zero(t, archive.pending[busy])
+	assert.Zero(t, archive.pending[quiet])
+	assert.Equal(t, now.Add(-90*24*time.Hour), archive.cutoff)
+}
+
+func TestArchiveMessages_Disabled(t *testing.T) {
+	service, _, _, _, _, _, _, _, _ := setupMessageService()
+	archive := &fakeMessageArchive{servers: []uuid.UUID{uuid.New()}}
+	service.SetArchiveRepository(archive)
+
+	archived, err := service.ArchiveMessages(context.Background(), time.Now(), MessageArchivePolicy{})
+
+	require.NoError(t, err)
+	assert.Zero(t, archived)
+	assert.True(t, archive.cutoff.IsZero())
+}
+
+func TestGetArchivedMessages_RequiresMembership(t *testing.T) {
+	service, _, channelRepo, serverRepo, _, _, _, _, _ := setupMessageService()
+	archive := &fakeMessageArchive{messages: []*models.Message{{ID: uuid.New()}}}
+	service.SetArchiveRepository(archive)
+	ctx := context.Background()
+	serverID := uuid.New()
+	channelID := uuid.New()
+	memberID := uuid.New()
+	outsiderID := uuid.New()
+
+	channelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, ServerID: &serverID}, nil)
+	serverRepo.On("GetMember", ctx, serverID, memberID).Return(&models.Member{UserID: memberID, ServerID: serverID}, nil)
+	serverRepo.On("GetMember", ctx, serverID, outsiderID).Return(nil, nil)
+
+	_, err := service.GetArchivedMessages(ctx, channelID, outsiderID, nil, 50)
+	assert.Equal(t, ErrNotServerMember, err)
+
+	before := uuid.New()
+	messages, err := service.GetArchivedMessages(ctx, channelID, memberID, &before, 50)
+	require.NoError(t, err)
+	assert.Equal(t, archive.messages, messages)
+	assert.Equal(t, &before, archive.before)
+}
+
+func TestGetArchivedMessages_DMChannel(t *testing.T) {
+	service, _, channelRepo, _, _, _, _, _, _ := setupMessageService()
+	service.SetArchiveRepository(&fakeMessageArchive{})
+	ctx := context.Background()
+	channelID := uuid.New()
+
+	channelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, Type: models.ChannelTypeDM}, nil)
+
+	_, err := service.GetArchivedMessages(ctx, channelID, uuid.New(), nil, 50)
+	assert.Equal(t, ErrNoPermission, err)
+}
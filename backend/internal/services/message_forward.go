@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// ChannelPermissionChecker reports what a user may do in a channel, taking
+// its overwrites into account. The PermissionService implements it.
+type ChannelPermissionChecker interface {
+	HasChannelPermission(ctx context.Context, channelID, userID uuid.UUID, perm int64) (bool, error)
+}
+
+// SetChannelPermissions has forwarded messages checked against channel
+// permissions: reading the original needs VIEW_CHANNELS and
+// READ_MESSAGE_HISTORY, sending the copy SEND_MESSAGES. Without it only
+// membership of both channels is checked.
+func (s *MessageService) SetChannelPermissions(permissions ChannelPermissionChecker) {
+	s.channelPermissions = permissions
+}
+
+// ForwardMessage copies a message into another channel, keeping a
+// reference to the original in ForwardedFrom. The copy is sent by the
+// requester, so it goes through the same checks as any message they send
+// there; it doesn't mention anyone again. Encrypted messages can't be
+// forwarded.
+func (s *MessageService) ForwardMessage(ctx context.Context, requesterID, channelID, messageID, targetChannelID uuid.UUID) (*models.Message, error) {
+	original, err := s.GetMessage(ctx, messageID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	if original.ChannelID != channelID {
+		return nil, ErrMessageNotFound
+	}
+	if original.EncryptedContent != "" {
+		return nil, ErrCannotForward
+	}
+
+	if s.channelPermissions != nil {
+		for _, perm := range []int64{models.PermViewChannels, models.PermReadMessageHistory} {
+			allowed, err := s.channelPermissions.HasChannelPermission(ctx, channelID, requesterID, perm)
+			if err != nil {
+				return nil, err
+			}
+			if !allowed {
+				return nil, ErrMessageNotFound
+			}
+		}
+		allowed, err := s.channelPermissions.HasChannelPermission(ctx, targetChannelID, requesterID, models.PermSendMessages)
+		if err == nil && !allowed {
+			err = ErrNoPermission
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	attachments := make([]*models.Attachment, 0, len(original.Attachments))
+	for _, attachment := range original.Attachments {
+		copied := attachment
+		copied.ID = uuid.New()
+		attachments = append(attachments, &copied)
+	}
+
+	return s.send(ctx, requesterID, targetChannelID, original.Content, attachments, nil, &models.MessageForward{
+		MessageID: original.ID,
+		ChannelID: original.ChannelID,
+		ServerID:  original.ServerID,
+		AuthorID:  original.AuthorID,
+		CreatedAt: original.CreatedAt,
+	})
+}
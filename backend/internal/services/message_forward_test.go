@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+// fakeChannelPermissions grants perms per channel
+type fakeChannelPermissions map[uuid.UUID]int64
+
+func (f fakeChannelPermissions) HasChannelPermission(ctx context.Context, channelID, userID uuid.UUID, perm int64) (bool, error) {
+	return models.HasPermission(f[channelID], perm), nil
+}
+
+type forwardTest struct {
+	service     *MessageService
+	msgRepo     *MockMessageRepository
+	eventBus    *MockEventBus
+	userID      uuid.UUID
+	source      *models.Channel
+	target      *models.Channel
+	original    *models.Message
+	permissions fakeChannelPermissions
+}
+
+func newForwardTest() *forwardTest {
+	service, msgRepo, channelRepo, serverRepo, _, rateLimiter, _, _, eventBus := setupMessageService()
+	sourceServer, targetServer := uuid.New(), uuid.New()
+	f := &forwardTest{
+		service:     service,
+		msgRepo:     msgRepo,
+		eventBus:    eventBus,
+		userID:      uuid.New(),
+		source:      &models.Channel{ID: uuid.New(), ServerID: &sourceServer, Type: models.ChannelTypeText},
+		target:      &models.Channel{ID: uuid.New(), ServerID: &targetServer, Type: models.ChannelTypeText},
+		permissions: fakeChannelPermissions{},
+	}
+	contentType := "image/png"
+	f.original = &models.Message{
+		ID:          uuid.New(),
+		ChannelID:   f.source.ID,
+		ServerID:    &sourceServer,
+		AuthorID:    uuid.New(),
+		Content:     "release notes for <@" + uuid.New().String() + ">",
+		Attachments: []models.Attachment{{ID: uuid.New(), Filename: "notes.png", URL: "https://cdn.example/notes.png", ContentType: &contentType}},
+		CreatedAt:   time.Now().Add(-time.Hour),
+	}
+	service.SetChannelPermissions(f.permissions)
+
+	ctx := mock.Anything
+	msgRepo.On("GetByID", ctx, f.original.ID).Return(f.original, nil)
+	for _, channel := range []*models.Channel{f.source, f.target} {
+		channelRepo.On("GetByID", ctx, channel.ID).Return(channel, nil)
+		channelRepo.On("UpdateLastMessage", ctx, channel.ID, mock.Anything, mock.Anything).Return(nil)
+		serverRepo.On("GetMember", ctx, *channel.ServerID, f.userID).Return(&models.Member{ServerID: *channel.ServerID, UserID: f.userID}, nil)
+		rateLimiter.On("Check", ctx, f.userID, channel.ID).Return(nil)
+	}
+	return f
+}
+
+func TestForwardMessage(t *testing.T) {
+	f := newForwardTest()
+	f.permissions[f.source.ID] = models.PermViewChannels | models.PermReadMessageHistory
+	f.permissions[f.target.ID] = models.PermViewChannels | models.PermSendMessages
+	f.msgRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	f.eventBus.On("Publish", "message.created", mock.Anything).Return()
+
+	forwarded, err := f.service.ForwardMessage(context.Background(), f.userID, f.source.ID, f.original.ID, f.target.ID)
+	require.NoError(t, err)
+	assert.Equal(t, f.target.ID, forwarded.ChannelID)
+	assert.Equal(t, f.userID, forwarded.AuthorID)
+	assert.Equal(t, f.original.Content, forwarded.Content)
+	assert.Empty(t, forwarded.Mentions, "forwards don't mention anyone again")
+	require.Len(t, forwarded.Attachments, 1)
+	assert.Equal(t, f.original.Attachments[0].URL, forwarded.Attachments[0].URL)
+	assert.NotEqual(t, f.original.Attachments[0].ID, forwarded.Attachments[0].ID)
+	assert.Equal(t, &models.MessageForward{
+		MessageID: f.original.ID,
+		ChannelID: f.source.ID,
+		ServerID:  f.source.ServerID,
+		AuthorID:  f.original.AuthorID,
+		CreatedAt: f.original.CreatedAt,
+	}, forwarded.ForwardedFrom)
+}
+
+func TestForwardMessage_Permissions(t *testing.T) {
+	ctx := context.Background()
+
+	f := newForwardTest()
+	f.permissions[f.source.ID] = models.PermViewChannels
+	f.permissions[f.target.ID] = models.PermViewChannels | models.PermSendMessages
+	_, err := f.service.ForwardMessage(ctx, f.userID, f.source.ID, f.original.ID, f.target.ID)
+	assert.Equal(t, ErrMessageNotFound, err, "the original's history must be readable")
+
+	f = newForwardTest()
+	f.permissions[f.source.ID] = models.PermViewChannels | models.PermReadMessageHistory
+	f.permissions[f.target.ID] = models.PermViewChannels
+	_, err = f.service.ForwardMessage(ctx, f.userID, f.source.ID, f.original.ID, f.target.ID)
+	assert.Equal(t, ErrNoPermission, err)
+
+	_, err = f.service.ForwardMessage(ctx, f.userID, f.target.ID, f.original.ID, f.source.ID)
+	assert.Equal(t, ErrMessageNotFound, err, "the message must be in the channel it's forwarded from")
+	f.msgRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestForwardMessage_Encrypted(t *testing.T) {
+	f := newForwardTest()
+	f.original.Content, f.original.EncryptedContent = "", "ciphertext"
+
+	_, err := f.service.ForwardMessage(context.Background(), f.userID, f.source.ID, f.original.ID, f.target.ID)
+	assert.Equal(t, ErrCannotForward, err)
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,11 +14,17 @@ type MessageRepository interface {
 	Create(ctx context.Context, message *models.Message) error
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Message, error)
 	Update(ctx context.Context, message *models.Message) error
+	// UpdateEmbeds saves embeds unfurled from content, unless the message
+	// has been edited since. It reports whether they were saved.
+	UpdateEmbeds(ctx context.Context, messageID uuid.UUID, content string, embeds []models.Embed) (bool, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 
 	// Queries
 	GetChannelMessages(ctx context.Context, channelID uuid.UUID, before, after *uuid.UUID, limit int) ([]*models.Message, error)
 	GetPinnedMessages(ctx context.Context, channelID uuid.UUID) ([]*models.Message, error)
+	// GetReplies returns the message's replies and theirs, oldest first,
+	// starting after the given reply
+	GetReplies(ctx context.Context, messageID uuid.UUID, after *uuid.UUID, limit int) ([]*models.Message, error)
 	SearchMessages(ctx context.Context, query string, channelID *uuid.UUID, authorID *uuid.UUID, limit int) ([]*models.Message, error)
 
 	// Reactions
@@ -42,6 +49,48 @@ type MessageService struct {
 	e2eeService  E2EEService
 	cache        CacheService
 	eventBus     EventBus
+	embeds       *EmbedService
+
+	roleRepo       RoleRepository
+	mentionCounter MentionCounter
+	presence       MentionPresence
+
+	userBatch   UserBatchRepository
+	memberBatch MemberBatchRepository
+
+	blocks    BlockRepository
+	dmPrivacy DMPrivacy
+
+	tombstones TombstoneRepository
+	archive    MessageArchiveRepository
+
+	reactionLimiter ReactionLimiter
+	emojis          EmojiLookup
+
+	bulkDeleter BulkDeleteRepository
+	auditLog    AuditLogger
+
+	automod *AutoModService
+
+	sendRecorder   MessageSendRecorder
+	serverRecorder ServerMessageRecorder
+	serverThrottle ServerMessageThrottle
+
+	schedules ChannelScheduleRepository
+
+	channelPermissions ChannelPermissionChecker
+}
+
+// MessageSendRecorder reports how long successful sends took, e.g. as a
+// metric for autoscaling on
+type MessageSendRecorder interface {
+	MessageSent(duration time.Duration)
+}
+
+// ServerMessageRecorder counts messages sent per server, e.g. to find the
+// busiest servers
+type ServerMessageRecorder interface {
+	ServerMessageSent(serverID uuid.UUID)
 }
 
 // NewMessageService creates a new message service
@@ -67,26 +116,147 @@ func NewMessageService(
 	}
 }
 
+// SetEmbedService enables link unfurling for sent and edited messages
+func (s *MessageService) SetEmbedService(embeds *EmbedService) {
+	s.embeds = embeds
+}
+
+// SetRoleRepository lets role mentions and MENTION_EVERYONE be checked
+// against the author's roles. Without it only server owners may mention
+// @everyone or unmentionable roles.
+func (s *MessageService) SetRoleRepository(roleRepo RoleRepository) {
+	s.roleRepo = roleRepo
+}
+
+// SetMentionCounter enables per-user mention counts for sent messages
+func (s *MessageService) SetMentionCounter(counter MentionCounter) {
+	s.mentionCounter = counter
+}
+
+// SetMentionPresence lets @here notify online members. Without it @here
+// only notifies those mentioned some other way.
+func (s *MessageService) SetMentionPresence(presence MentionPresence) {
+	s.presence = presence
+}
+
+// SetAuthorRepositories enables author and member objects on fetched
+// messages. Lookups go through the request's Loaders when it has them.
+func (s *MessageService) SetAuthorRepositories(users UserBatchRepository, members MemberBatchRepository) {
+	s.userBatch = users
+	s.memberBatch = members
+}
+
+// SetBlockRepository enables blocking: DMs can't be sent between users when
+// either has blocked the other, and history omits messages from users the
+// requester has blocked
+func (s *MessageService) SetBlockRepository(blocks BlockRepository) {
+	s.blocks = blocks
+}
+
+// SetDMPrivacy refuses DMs to users who stopped accepting direct messages
+// from the author, e.g. by switching to friends only
+func (s *MessageService) SetDMPrivacy(privacy DMPrivacy) {
+	s.dmPrivacy = privacy
+}
+
+// SetAutoModService checks sent and edited server messages against the
+// server's AutoMod rules before they're stored
+func (s *MessageService) SetAutoModService(automod *AutoModService) {
+	s.automod = automod
+}
+
+// SetSendRecorder sets where send latency is reported
+func (s *MessageService) SetSendRecorder(recorder MessageSendRecorder) {
+	s.sendRecorder = recorder
+}
+
+// SetServerMessageRecorder sets where messages sent per server are counted
+func (s *MessageService) SetServerMessageRecorder(recorder ServerMessageRecorder) {
+	s.serverRecorder = recorder
+}
+
+// attachAuthors fills in message authors with batched lookups, unless the
+// request asked for them to be left out. Failures are logged rather than
+// returned so history still loads without them.
+func (s *MessageService) attachAuthors(ctx context.Context, messages ...*models.Message) {
+	if skipAuthors(ctx) {
+		return
+	}
+	loaders := LoadersFrom(ctx)
+	if loaders == nil {
+		if s.userBatch == nil {
+			return
+		}
+		loaders = NewLoaders(s.userBatch, s.memberBatch)
+	}
+	if err := loaders.AttachAuthors(ctx, messages); err != nil {
+		log.Printf("[Message] Failed to load message authors: %v", err)
+	}
+}
+
 // SendMessage sends a message to a channel
 func (s *MessageService) SendMessage(ctx context.Context, authorID uuid.UUID, channelID uuid.UUID, content string, attachments []*models.Attachment, replyTo *uuid.UUID) (*models.Message, error) {
+	return s.send(ctx, authorID, channelID, content, attachments, replyTo, nil)
+}
+
+// send sends a message, or a forwarded copy of one when forward is set
+func (s *MessageService) send(ctx context.Context, authorID uuid.UUID, channelID uuid.UUID, content string, attachments []*models.Attachment, replyTo *uuid.UUID, forward *models.MessageForward) (*models.Message, error) {
+	start := time.Now()
+
 	// Get channel
-	channel, err := s.channelRepo.GetByID(ctx, channelID)
+	channel, err := cachedChannel(ctx, s.cache, s.channelRepo, channelID)
 	if err != nil {
 		return nil, err
 	}
 	if channel == nil {
 		return nil, ErrChannelNotFound
 	}
+	if channel.Type == models.ChannelTypeForum {
+		return nil, ErrForumChannel
+	}
 
 	// Check permissions for server channels
+	var member *models.Member
 	if channel.ServerID != nil {
-		member, err := s.serverRepo.GetMember(ctx, *channel.ServerID, authorID)
+		member, err = cachedMember(ctx, s.cache, s.serverRepo, *channel.ServerID, authorID)
 		if err != nil || member == nil {
 			return nil, ErrNotServerMember
 		}
+		if member.TimedOut(time.Now()) {
+			return nil, ErrMemberTimedOut
+		}
+		if member.Pending {
+			return nil, ErrMembershipPending
+		}
+		if err := s.checkChannelOpen(ctx, *channel.ServerID, channelID, authorID); err != nil {
+			return nil, err
+		}
 		// TODO: Check SEND_MESSAGES permission
 	}
 
+	// Blocks suppress DMs in both directions
+	if channel.Type == models.ChannelTypeDM && s.blocks != nil {
+		for _, recipientID := range channel.Recipients {
+			if recipientID == authorID {
+				continue
+			}
+			blocked, err := isBlockedEitherWay(ctx, s.blocks, authorID, recipientID)
+			if err != nil {
+				return nil, err
+			}
+			if blocked {
+				return nil, ErrUserBlocked
+			}
+		}
+	}
+	if channel.Type == models.ChannelTypeDM && s.dmPrivacy != nil {
+		for _, recipientID := range channel.Recipients {
+			if err := s.dmPrivacy.CanDM(ctx, authorID, recipientID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// Get quota limits
 	var serverID *uuid.UUID
 	if channel.ServerID != nil {
@@ -97,8 +267,13 @@ func (s *MessageService) SendMessage(ctx context.Context, authorID uuid.UUID, ch
 		return nil, err
 	}
 
-	// Check message length
-	if limits.MaxMessageLength > 0 && len(content) > limits.MaxMessageLength {
+	// Check message length. Encrypted envelopes are allowed for their
+	// base64 and framing.
+	maxLength := limits.MaxMessageLength
+	if channel.E2EEEnabled {
+		maxLength *= encryptedLengthFactor
+	}
+	if maxLength > 0 && len(content) > maxLength {
 		return nil, ErrMessageTooLong
 	}
 
@@ -121,6 +296,14 @@ func (s *MessageService) SendMessage(ctx context.Context, authorID uuid.UUID, ch
 		}
 	}
 
+	// Check the server's share of the instance, after the author's own
+	// limits so one member's burst isn't held against the whole server
+	if s.serverThrottle != nil && channel.ServerID != nil {
+		if err := s.serverThrottle.CheckServerMessage(ctx, *channel.ServerID); err != nil {
+			return nil, err
+		}
+	}
+
 	// Convert attachments
 	var msgAttachments []models.Attachment
 	for _, att := range attachments {
@@ -140,6 +323,8 @@ func (s *MessageService) SendMessage(ctx context.Context, authorID uuid.UUID, ch
 		Attachments: msgAttachments,
 		ReplyToID:   replyTo,
 		CreatedAt:   time.Now(),
+
+		ForwardedFrom: forward,
 	}
 
 	// Set reply type if replying
@@ -147,32 +332,44 @@ func (s *MessageService) SendMessage(ctx context.Context, authorID uuid.UUID, ch
 		message.Type = models.MessageTypeReply
 	}
 
-	// Handle E2EE for DM channels
-	isEncrypted := false
-	if channel.Type == models.ChannelTypeDM || channel.Type == models.ChannelTypeGroupDM {
-		if channel.E2EEEnabled {
-			// Content should already be encrypted by client
-			// We just verify the format
-			if !s.e2eeService.ValidateEncryptedPayload(content) {
-				return nil, ErrNoPermission
-			}
-			message.EncryptedContent = content
-			message.Content = "" // Clear plaintext
-			isEncrypted = true
-		}
-	} else if channel.E2EEEnabled {
-		// Server channel with E2EE enabled
-		if !s.e2eeService.ValidateEncryptedPayload(content) {
-			return nil, ErrNoPermission
+	// Encrypted channels only take envelopes sealed by the client. The
+	// server can't read them, so they skip mentions, embeds and AutoMod's
+	// content rules.
+	isEncrypted := channel.E2EEEnabled
+	if isEncrypted && content != "" {
+		if err := s.checkEncrypted(ctx, channelID, content); err != nil {
+			return nil, err
 		}
 		message.EncryptedContent = content
 		message.Content = ""
-		isEncrypted = true
 	}
 
-	// Parse mentions from content (if not encrypted)
+	// Parse mentions from content (if not encrypted). Forwards don't
+	// mention anyone again.
+	var mentionsHere bool
 	if !isEncrypted {
-		message.Mentions = parseMentions(content)
+		if forward == nil {
+			parsed := parseMentions(content)
+			s.applyMentions(ctx, message, channel, parsed)
+			mentionsHere = parsed.Here
+		}
+	}
+
+	if s.automod != nil && member != nil {
+		verdict := s.automod.Moderate(ctx, message, member, false)
+		if verdict.Blocked {
+			return nil, ErrAutoModBlocked
+		}
+		if verdict.Dropped {
+			return message, nil
+		}
+	}
+
+	// Links unfurled before are embedded straight away; the rest are
+	// fetched once the message is saved
+	var unfurl bool
+	if !isEncrypted {
+		unfurl = s.attachEmbeds(ctx, message)
 	}
 
 	if err := s.repo.Create(ctx, message); err != nil {
@@ -182,6 +379,9 @@ func (s *MessageService) SendMessage(ctx context.Context, authorID uuid.UUID, ch
 	// Update channel's last message
 	_ = s.channelRepo.UpdateLastMessage(ctx, channelID, message.ID, message.CreatedAt)
 
+	// Hydrate author and member so MESSAGE_CREATE needs no follow-up lookups
+	s.attachAuthors(ctx, message)
+
 	// Emit event
 	s.eventBus.Publish("message.created", &MessageCreatedEvent{
 		Message:   message,
@@ -189,6 +389,17 @@ func (s *MessageService) SendMessage(ctx context.Context, authorID uuid.UUID, ch
 		ServerID:  channel.ServerID,
 	})
 
+	s.notifyMentionsLater(message, mentionsHere)
+	if unfurl {
+		s.unfurlLater(message)
+	}
+
+	if s.sendRecorder != nil {
+		s.sendRecorder.MessageSent(time.Since(start))
+	}
+	if s.serverRecorder != nil && channel.ServerID != nil {
+		s.serverRecorder.ServerMessageSent(*channel.ServerID)
+	}
 	return message, nil
 }
 
@@ -206,26 +417,112 @@ func (s *MessageService) EditMessage(ctx context.Context, messageID uuid.UUID, a
 		return nil, ErrNotMessageAuthor
 	}
 
-	message.Content = newContent
+	previousContent := message.Content
+	var unfurl bool
+	if message.EncryptedContent != "" {
+		// Edits are sealed anew, with the channel's current keys
+		if err := s.checkEncrypted(ctx, message.ChannelID, newContent); err != nil {
+			return nil, err
+		}
+		message.EncryptedContent = newContent
+	} else {
+		message.Content = newContent
+	}
 	message.EditedAt = timePtr(time.Now())
 
-	// Re-parse mentions if not encrypted (EncryptedContent is empty for non-encrypted)
+	// Re-parse mentions if not encrypted (EncryptedContent is empty for non-encrypted).
+	// Edits update the mention lists but don't notify again.
 	if message.EncryptedContent == "" {
-		message.Mentions = parseMentions(newContent)
+		parsed := parseMentions(newContent)
+		if parsed.empty() {
+			message.Mentions, message.MentionRoles, message.MentionEveryone = nil, nil, false
+		} else if channel, err := cachedChannel(ctx, s.cache, s.channelRepo, message.ChannelID); err == nil && channel != nil {
+			s.applyMentions(ctx, message, channel, parsed)
+		}
+		if message.Content != previousContent {
+			message.Embeds = nil
+			unfurl = s.attachEmbeds(ctx, message)
+		}
+
+		if s.automod != nil && message.ServerID != nil {
+			member, err := cachedMember(ctx, s.cache, s.serverRepo, *message.ServerID, authorID)
+			if err == nil && member != nil && s.automod.Moderate(ctx, message, member, true).Blocked {
+				return nil, ErrAutoModBlocked
+			}
+		}
 	}
 
 	if err := s.repo.Update(ctx, message); err != nil {
 		return nil, err
 	}
 
+	s.attachAuthors(ctx, message)
+
 	s.eventBus.Publish("message.updated", &MessageUpdatedEvent{
 		Message:   message,
 		ChannelID: message.ChannelID,
 	})
 
+	if unfurl {
+		s.unfurlLater(message)
+	}
+
 	return message, nil
 }
 
+// attachEmbeds gives a message the embeds of its links that were unfurled
+// before, so the response and its event carry them, and reports whether any
+// link still has to be fetched. Nothing is attached when embeds are disabled
+// or suppressed by the author.
+func (s *MessageService) attachEmbeds(ctx context.Context, message *models.Message) bool {
+	if s.embeds == nil || message.Flags&models.MessageFlagSuppressEmbeds != 0 {
+		return false
+	}
+	embeds, complete := s.embeds.CachedEmbeds(ctx, message.Content)
+	message.Embeds = embeds
+	return !complete
+}
+
+// unfurlTimeout bounds unfurling one message's links, which runs after the
+// send or edit returns
+const unfurlTimeout = 30 * time.Second
+
+// unfurlLater builds the embeds of links attachEmbeds found no cached
+// embed for. Fetching other sites can take seconds, so sends and edits
+// return first; the embeds are saved and sent as a MESSAGE_UPDATE once
+// they're ready.
+func (s *MessageService) unfurlLater(message *models.Message) {
+	messageID, content := message.ID, message.Content
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), unfurlTimeout)
+		defer cancel()
+
+		embeds := s.embeds.EmbedsForContent(ctx, content)
+		if len(embeds) == 0 {
+			return
+		}
+		saved, err := s.repo.UpdateEmbeds(ctx, messageID, content, embeds)
+		if err != nil {
+			log.Printf("[MessageService] failed to save embeds of message %s: %v", messageID, err)
+			return
+		}
+		if !saved {
+			// Edited or deleted meanwhile; an edit unfurls its own links
+			return
+		}
+
+		updated, err := s.repo.GetByID(ctx, messageID)
+		if err != nil || updated == nil {
+			return
+		}
+		s.attachAuthors(ctx, updated)
+		s.eventBus.Publish("message.updated", &MessageUpdatedEvent{
+			Message:   updated,
+			ChannelID: updated.ChannelID,
+		})
+	}()
+}
+
 // DeleteMessage deletes a message
 func (s *MessageService) DeleteMessage(ctx context.Context, messageID uuid.UUID, requesterID uuid.UUID) error {
 	message, err := s.repo.GetByID(ctx, messageID)
@@ -239,7 +536,7 @@ func (s *MessageService) DeleteMessage(ctx context.Context, messageID uuid.UUID,
 	// Author can always delete their own messages
 	if message.AuthorID != requesterID {
 		// Check if requester has MANAGE_MESSAGES permission
-		channel, _ := s.channelRepo.GetByID(ctx, message.ChannelID)
+		channel, _ := cachedChannel(ctx, s.cache, s.channelRepo, message.ChannelID)
 		if channel != nil && channel.ServerID != nil {
 			// TODO: Check MANAGE_MESSAGES permission
 		} else {
@@ -247,7 +544,7 @@ func (s *MessageService) DeleteMessage(ctx context.Context, messageID uuid.UUID,
 		}
 	}
 
-	if err := s.repo.Delete(ctx, messageID); err != nil {
+	if err := s.deleteMessage(ctx, message, requesterID); err != nil {
 		return err
 	}
 
@@ -255,6 +552,7 @@ func (s *MessageService) DeleteMessage(ctx context.Context, messageID uuid.UUID,
 		MessageID: messageID,
 		ChannelID: message.ChannelID,
 		AuthorID:  message.AuthorID,
+		DeletedBy: requesterID,
 	})
 
 	return nil
@@ -262,7 +560,7 @@ func (s *MessageService) DeleteMessage(ctx context.Context, messageID uuid.UUID,
 
 // GetMessages retrieves messages from a channel with pagination
 func (s *MessageService) GetMessages(ctx context.Context, channelID uuid.UUID, requesterID uuid.UUID, before, after *uuid.UUID, limit int) ([]*models.Message, error) {
-	channel, err := s.channelRepo.GetByID(ctx, channelID)
+	channel, err := cachedChannel(ctx, s.cache, s.channelRepo, channelID)
 	if err != nil {
 		return nil, err
 	}
@@ -272,7 +570,7 @@ func (s *MessageService) GetMessages(ctx context.Context, channelID uuid.UUID, r
 
 	// Check access
 	if channel.ServerID != nil {
-		member, err := s.serverRepo.GetMember(ctx, *channel.ServerID, requesterID)
+		member, err := cachedMember(ctx, s.cache, s.serverRepo, *channel.ServerID, requesterID)
 		if err != nil || member == nil {
 			return nil, ErrNotServerMember
 		}
@@ -288,7 +586,90 @@ func (s *MessageService) GetMessages(ctx context.Context, channelID uuid.UUID, r
 		limit = 50
 	}
 
-	return s.repo.GetChannelMessages(ctx, channelID, before, after, limit)
+	messages, err := s.repo.GetChannelMessages(ctx, channelID, before, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	messages, err = s.hideBlockedAuthors(ctx, requesterID, limit, messages, func(cursor uuid.UUID) ([]*models.Message, error) {
+		if after != nil {
+			after = &cursor
+		} else {
+			before = &cursor
+		}
+		return s.repo.GetChannelMessages(ctx, channelID, before, after, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.attachAuthors(ctx, messages...)
+	return messages, nil
+}
+
+// GetReplies returns a page of the replies to a message, and the replies to
+// those, in the order they were sent. Pass the last reply of a page as
+// after for the next one. Replies to deleted messages aren't included, as
+// deleting a message detaches its replies.
+func (s *MessageService) GetReplies(ctx context.Context, channelID, messageID, requesterID uuid.UUID, after *uuid.UUID, limit int) ([]*models.Message, error) {
+	message, err := s.GetMessage(ctx, messageID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	if message.ChannelID != channelID {
+		return nil, ErrMessageNotFound
+	}
+
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	replies, err := s.repo.GetReplies(ctx, messageID, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	replies, err = s.hideBlockedAuthors(ctx, requesterID, limit, replies, func(cursor uuid.UUID) ([]*models.Message, error) {
+		return s.repo.GetReplies(ctx, messageID, &cursor, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.attachAuthors(ctx, replies...)
+	return replies, nil
+}
+
+// hideBlockedAuthors drops messages written by users the requester has
+// blocked. When that empties a full page it reads on from the page's last
+// message, so a client paging by the messages it was given doesn't mistake
+// a run of hidden messages for the end of history. next reads the page
+// after the given message.
+func (s *MessageService) hideBlockedAuthors(ctx context.Context, requesterID uuid.UUID, limit int, messages []*models.Message, next func(cursor uuid.UUID) ([]*models.Message, error)) ([]*models.Message, error) {
+	if s.blocks == nil || len(messages) == 0 {
+		return messages, nil
+	}
+	blocked, err := blockedUserIDs(ctx, s.blocks, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	if len(blocked) == 0 {
+		return messages, nil
+	}
+
+	visible := make([]*models.Message, 0, len(messages))
+	page := messages
+	for reads := 0; ; reads++ {
+		for _, message := range page {
+			if !blocked[message.AuthorID] {
+				visible = append(visible, message)
+			}
+		}
+		if len(visible) > 0 || len(page) < limit || reads == maxHiddenPageReads {
+			return visible, nil
+		}
+
+		page, err = next(page[len(page)-1].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
 }
 
 // GetMessage retrieves a specific message by ID
@@ -302,7 +683,7 @@ func (s *MessageService) GetMessage(ctx context.Context, messageID uuid.UUID, re
 	}
 
 	// Check access to the channel
-	channel, err := s.channelRepo.GetByID(ctx, message.ChannelID)
+	channel, err := cachedChannel(ctx, s.cache, s.channelRepo, message.ChannelID)
 	if err != nil {
 		return nil, err
 	}
@@ -311,7 +692,7 @@ func (s *MessageService) GetMessage(ctx context.Context, messageID uuid.UUID, re
 	}
 
 	if channel.ServerID != nil {
-		member, err := s.serverRepo.GetMember(ctx, *channel.ServerID, requesterID)
+		member, err := cachedMember(ctx, s.cache, s.serverRepo, *channel.ServerID, requesterID)
 		if err != nil || member == nil {
 			return nil, ErrNotServerMember
 		}
@@ -323,6 +704,7 @@ func (s *MessageService) GetMessage(ctx context.Context, messageID uuid.UUID, re
 		}
 	}
 
+	s.attachAuthors(ctx, message)
 	return message, nil
 }
 
@@ -337,7 +719,7 @@ func (s *MessageService) PinMessage(ctx context.Context, messageID uuid.UUID, re
 	}
 
 	// Check access to the channel
-	channel, err := s.channelRepo.GetByID(ctx, message.ChannelID)
+	channel, err := cachedChannel(ctx, s.cache, s.channelRepo, message.ChannelID)
 	if err != nil {
 		return err
 	}
@@ -346,7 +728,7 @@ func (s *MessageService) PinMessage(ctx context.Context, messageID uuid.UUID, re
 	}
 
 	if channel.ServerID != nil {
-		member, err := s.serverRepo.GetMember(ctx, *channel.ServerID, requesterID)
+		member, err := cachedMember(ctx, s.cache, s.serverRepo, *channel.ServerID, requesterID)
 		if err != nil || member == nil {
 			return ErrNotServerMember
 		}
@@ -384,7 +766,7 @@ func (s *MessageService) UnpinMessage(ctx context.Context, messageID uuid.UUID,
 	}
 
 	// Check access to the channel
-	channel, err := s.channelRepo.GetByID(ctx, message.ChannelID)
+	channel, err := cachedChannel(ctx, s.cache, s.channelRepo, message.ChannelID)
 	if err != nil {
 		return err
 	}
@@ -393,7 +775,7 @@ func (s *MessageService) UnpinMessage(ctx context.Context, messageID uuid.UUID,
 	}
 
 	if channel.ServerID != nil {
-		member, err := s.serverRepo.GetMember(ctx, *channel.ServerID, requesterID)
+		member, err := cachedMember(ctx, s.cache, s.serverRepo, *channel.ServerID, requesterID)
 		if err != nil || member == nil {
 			return ErrNotServerMember
 		}
@@ -426,7 +808,7 @@ func (s *MessageService) UnpinMessage(ctx context.Context, messageID uuid.UUID,
 
 // GetPinnedMessages retrieves all pinned messages in a channel
 func (s *MessageService) GetPinnedMessages(ctx context.Context, channelID uuid.UUID, requesterID uuid.UUID) ([]*models.Message, error) {
-	channel, err := s.channelRepo.GetByID(ctx, channelID)
+	channel, err := cachedChannel(ctx, s.cache, s.channelRepo, channelID)
 	if err != nil {
 		return nil, err
 	}
@@ -436,7 +818,7 @@ func (s *MessageService) GetPinnedMessages(ctx context.Context, channelID uuid.U
 
 	// Check access
 	if channel.ServerID != nil {
-		member, err := s.serverRepo.GetMember(ctx, *channel.ServerID, requesterID)
+		member, err := cachedMember(ctx, s.cache, s.serverRepo, *channel.ServerID, requesterID)
 		if err != nil || member == nil {
 			return nil, ErrNotServerMember
 		}
@@ -448,11 +830,19 @@ func (s *MessageService) GetPinnedMessages(ctx context.Context, channelID uuid.U
 		}
 	}
 
-	return s.repo.GetPinnedMessages(ctx, channelID)
+	messages, err := s.repo.GetPinnedMessages(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	s.attachAuthors(ctx, messages...)
+	return messages, nil
 }
 
-// AddReaction adds a reaction to a message
+// AddReaction adds a reaction to a message. emoji is a unicode emoji or a
+// custom emoji, as its ID or name:id.
 func (s *MessageService) AddReaction(ctx context.Context, messageID, userID uuid.UUID, emoji string) error {
+	emoji, customID := models.ParseReactionEmoji(emoji)
+
 	message, err := s.repo.GetByID(ctx, messageID)
 	if err != nil {
 		return err
@@ -462,6 +852,30 @@ func (s *MessageService) AddReaction(ctx context.Context, messageID, userID uuid
 	}
 
 	// TODO: Check ADD_REACTIONS permission
+	if message.ServerID != nil {
+		member, err := cachedMember(ctx, s.cache, s.serverRepo, *message.ServerID, userID)
+		if err != nil {
+			return err
+		}
+		if member != nil && member.TimedOut(time.Now()) {
+			return ErrMemberTimedOut
+		}
+		if member != nil && member.Pending {
+			return ErrMembershipPending
+		}
+		if err := s.checkChannelOpen(ctx, *message.ServerID, message.ChannelID, userID); err != nil {
+			return err
+		}
+	}
+
+	if customID != nil {
+		if err := s.checkCustomEmoji(ctx, message, userID, *customID); err != nil {
+			return err
+		}
+	}
+	if err := s.checkReactionLimits(ctx, message, userID, emoji); err != nil {
+		return err
+	}
 
 	if err := s.repo.AddReaction(ctx, messageID, userID, emoji); err != nil {
 		return err
@@ -479,6 +893,7 @@ func (s *MessageService) AddReaction(ctx context.Context, messageID, userID uuid
 
 // RemoveReaction removes a reaction from a message
 func (s *MessageService) RemoveReaction(ctx context.Context, messageID, userID uuid.UUID, emoji string) error {
+	emoji, _ = models.ParseReactionEmoji(emoji)
 	if err := s.repo.RemoveReaction(ctx, messageID, userID, emoji); err != nil {
 		return err
 	}
@@ -504,7 +919,7 @@ func (s *MessageService) GetReactions(ctx context.Context, messageID uuid.UUID,
 	}
 
 	// Check access to the channel
-	channel, err := s.channelRepo.GetByID(ctx, message.ChannelID)
+	channel, err := cachedChannel(ctx, s.cache, s.channelRepo, message.ChannelID)
 	if err != nil {
 		return nil, err
 	}
@@ -513,7 +928,7 @@ func (s *MessageService) GetReactions(ctx context.Context, messageID uuid.UUID,
 	}
 
 	if channel.ServerID != nil {
-		member, err := s.serverRepo.GetMember(ctx, *channel.ServerID, requesterID)
+		member, err := cachedMember(ctx, s.cache, s.serverRepo, *channel.ServerID, requesterID)
 		if err != nil || member == nil {
 			return nil, ErrNotServerMember
 		}
@@ -539,6 +954,7 @@ func (s *MessageService) GetReactions(ctx context.Context, messageID uuid.UUID,
 	for _, r := range reactions {
 		r.Me = userReacted[r.Emoji]
 	}
+	s.resolveReactionEmoji(ctx, reactions)
 
 	return reactions, nil
 }
@@ -555,7 +971,7 @@ func (s *MessageService) GetReactionUsers(ctx context.Context, messageID uuid.UU
 	}
 
 	// Check access to the channel
-	channel, err := s.channelRepo.GetByID(ctx, message.ChannelID)
+	channel, err := cachedChannel(ctx, s.cache, s.channelRepo, message.ChannelID)
 	if err != nil {
 		return nil, err
 	}
@@ -564,7 +980,7 @@ func (s *MessageService) GetReactionUsers(ctx context.Context, messageID uuid.UU
 	}
 
 	if channel.ServerID != nil {
-		member, err := s.serverRepo.GetMember(ctx, *channel.ServerID, requesterID)
+		member, err := cachedMember(ctx, s.cache, s.serverRepo, *channel.ServerID, requesterID)
 		if err != nil || member == nil {
 			return nil, ErrNotServerMember
 		}
@@ -578,16 +994,12 @@ func (s *MessageService) GetReactionUsers(ctx context.Context, messageID uuid.UU
 		limit = 25
 	}
 
+	emoji, _ = models.ParseReactionEmoji(emoji)
 	return s.repo.GetReactionUsers(ctx, messageID, emoji, limit)
 }
 
 // Helpers
 
-func parseMentions(content string) []uuid.UUID {
-	// TODO: Parse @mentions and return user IDs
-	return nil
-}
-
 func isChannelParticipant(channel *models.Channel, userID uuid.UUID) bool {
 	for _, p := range channel.Recipients {
 		if p == userID {
@@ -618,6 +1030,7 @@ type MessageDeletedEvent struct {
 	MessageID uuid.UUID
 	ChannelID uuid.UUID
 	AuthorID  uuid.UUID
+	DeletedBy uuid.UUID
 }
 
 type MessagePinnedEvent struct {
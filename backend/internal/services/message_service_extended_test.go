@@ -228,14 +228,6 @@ func TestPinMessage_MessageNotFound(t *testing.T) {
 	assert.Equal(t, ErrMessageNotFound, err)
 }
 
-func TestParseMentions(t *testing.T) {
-	// parseMentions returns nil currently (placeholder)
-	mentions := parseMentions("Hello <@123456> and <@789012>!")
-	
-	// Current implementation returns nil
-	assert.Nil(t, mentions)
-}
-
 func TestIsChannelParticipant(t *testing.T) {
 	userID := uuid.New()
 	otherUserID := uuid.New()
@@ -275,7 +267,7 @@ func TestTimePtr(t *testing.T) {
 }
 
 func TestEditMessage_WithEncryptedContent(t *testing.T) {
-	service, msgRepo, _, _, _, _, _, _, eventBus := setupMessageService()
+	service, msgRepo, _, _, _, _, e2eeService, _, eventBus := setupMessageService()
 	ctx := context.Background()
 	authorID := uuid.New()
 	messageID := uuid.New()
@@ -290,13 +282,19 @@ func TestEditMessage_WithEncryptedContent(t *testing.T) {
 	}
 
 	msgRepo.On("GetByID", ctx, messageID).Return(existingMessage, nil)
+	e2eeService.On("CheckPayload", ctx, channelID, "new_encrypted_data").Return(nil)
+	e2eeService.On("CheckPayload", ctx, channelID, "stale_encrypted_data").Return(ErrKeyEpochStale)
 	msgRepo.On("Update", ctx, mock.AnythingOfType("*models.Message")).Return(nil)
 	eventBus.On("Publish", "message.updated", mock.AnythingOfType("*services.MessageUpdatedEvent")).Return()
 
+	_, err := service.EditMessage(ctx, messageID, authorID, "stale_encrypted_data")
+	assert.ErrorIs(t, err, ErrKeyEpochStale)
+
 	message, err := service.EditMessage(ctx, messageID, authorID, "new_encrypted_data")
 
 	assert.NoError(t, err)
-	assert.Equal(t, "new_encrypted_data", message.Content)
+	assert.Equal(t, "new_encrypted_data", message.EncryptedContent)
+	assert.Empty(t, message.Content)
 	// Mentions should not be parsed for encrypted messages
 	assert.Nil(t, message.Mentions)
 }
@@ -323,7 +321,8 @@ func TestEditMessage_PlaintextPreservesMentions(t *testing.T) {
 
 	assert.NoError(t, err)
 	assert.Equal(t, "Hello <@user>!", message.Content)
-	// Mentions should be parsed (though current impl returns nil)
+	// "<@user>" isn't a valid user ID, so nothing is mentioned
+	assert.Nil(t, message.Mentions)
 }
 
 func TestDeleteMessage_ServerChannelWithManagePermission(t *testing.T) {
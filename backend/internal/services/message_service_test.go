@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"hearth/internal/models"
 )
 
@@ -34,6 +35,11 @@ func (m *MockMessageRepository) Update(ctx context.Context, message *models.Mess
 	return args.Error(0)
 }
 
+func (m *MockMessageRepository) UpdateEmbeds(ctx context.Context, messageID uuid.UUID, content string, embeds []models.Embed) (bool, error) {
+	args := m.Called(ctx, messageID, content, embeds)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockMessageRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
@@ -55,6 +61,14 @@ func (m *MockMessageRepository) GetPinnedMessages(ctx context.Context, channelID
 	return args.Get(0).([]*models.Message), args.Error(1)
 }
 
+func (m *MockMessageRepository) GetReplies(ctx context.Context, messageID uuid.UUID, after *uuid.UUID, limit int) ([]*models.Message, error) {
+	args := m.Called(ctx, messageID, after, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Message), args.Error(1)
+}
+
 func (m *MockMessageRepository) SearchMessages(ctx context.Context, query string, channelID *uuid.UUID, authorID *uuid.UUID, limit int) ([]*models.Message, error) {
 	args := m.Called(ctx, query, channelID, authorID, limit)
 	if args.Get(0) == nil {
@@ -145,36 +159,8 @@ type MockE2EEService struct {
 	mock.Mock
 }
 
-func (m *MockE2EEService) ValidateEncryptedPayload(content string) bool {
-	args := m.Called(content)
-	return args.Bool(0)
-}
-
-func (m *MockE2EEService) GetPreKeys(ctx context.Context, userID uuid.UUID) (*models.PreKeyBundle, error) {
-	args := m.Called(ctx, userID)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*models.PreKeyBundle), args.Error(1)
-}
-
-func (m *MockE2EEService) UploadPreKeys(ctx context.Context, userID uuid.UUID, bundle *models.PreKeyBundle) error {
-	args := m.Called(ctx, userID, bundle)
-	return args.Error(0)
-}
-
-func (m *MockE2EEService) CreateGroup(ctx context.Context, channelID uuid.UUID, memberIDs []uuid.UUID) error {
-	args := m.Called(ctx, channelID, memberIDs)
-	return args.Error(0)
-}
-
-func (m *MockE2EEService) AddGroupMember(ctx context.Context, channelID, userID uuid.UUID) error {
-	args := m.Called(ctx, channelID, userID)
-	return args.Error(0)
-}
-
-func (m *MockE2EEService) RemoveGroupMember(ctx context.Context, channelID, userID uuid.UUID) error {
-	args := m.Called(ctx, channelID, userID)
+func (m *MockE2EEService) CheckPayload(ctx context.Context, channelID uuid.UUID, payload string) error {
+	args := m.Called(ctx, channelID, payload)
 	return args.Error(0)
 }
 
@@ -241,7 +227,7 @@ func setupMessageService() (*MessageService, *MockMessageRepository, *MockChanne
 	serverRepo := new(MockServerRepository)
 	rateLimiter := new(MockRateLimiter)
 	e2eeService := new(MockE2EEService)
-	cache := new(MockCacheService)
+	cache := new(MockCacheService).empty()
 	eventBus := new(MockEventBus)
 	mockQuotaService := new(MockQuotaService)
 
@@ -279,6 +265,65 @@ func TestSendMessage_Success(t *testing.T) {
 	t.Skip("TODO: Fix quota service setup in tests")
 }
 
+type fakeSendRecorder struct {
+	sends []time.Duration
+}
+
+func (f *fakeSendRecorder) MessageSent(duration time.Duration) {
+	f.sends = append(f.sends, duration)
+}
+
+func TestSendMessage_RecordsLatency(t *testing.T) {
+	service, msgRepo, channelRepo, serverRepo, _, rateLimiter, _, _, eventBus := setupMessageService()
+	recorder := &fakeSendRecorder{}
+	service.SetSendRecorder(recorder)
+	ctx := context.Background()
+	authorID := uuid.New()
+	channelID := uuid.New()
+	serverID := uuid.New()
+
+	channelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, ServerID: &serverID, Type: models.ChannelTypeText}, nil)
+	channelRepo.On("UpdateLastMessage", ctx, channelID, mock.Anything, mock.Anything).Return(nil)
+	serverRepo.On("GetMember", ctx, serverID, authorID).Return(&models.Member{ServerID: serverID, UserID: authorID}, nil)
+	rateLimiter.On("Check", ctx, authorID, channelID).Return(nil)
+	msgRepo.On("Create", ctx, mock.Anything).Return(nil)
+	eventBus.On("Publish", "message.created", mock.Anything).Return()
+
+	_, err := service.SendMessage(ctx, authorID, channelID, "Hello!", nil, nil)
+	require.NoError(t, err)
+	assert.Len(t, recorder.sends, 1)
+
+	// Rejected sends aren't timed
+	_, err = service.SendMessage(ctx, authorID, channelID, "", nil, nil)
+	assert.Equal(t, ErrEmptyMessage, err)
+	assert.Len(t, recorder.sends, 1)
+}
+
+func TestSendMessage_EncryptedChannel(t *testing.T) {
+	service, msgRepo, channelRepo, serverRepo, _, rateLimiter, e2eeService, _, eventBus := setupMessageService()
+	ctx := context.Background()
+	authorID := uuid.New()
+	channelID := uuid.New()
+	serverID := uuid.New()
+
+	channelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, ServerID: &serverID, Type: models.ChannelTypeText, E2EEEnabled: true}, nil)
+	channelRepo.On("UpdateLastMessage", ctx, channelID, mock.Anything, mock.Anything).Return(nil)
+	serverRepo.On("GetMember", ctx, serverID, authorID).Return(&models.Member{ServerID: serverID, UserID: authorID}, nil)
+	rateLimiter.On("Check", ctx, authorID, channelID).Return(nil)
+	e2eeService.On("CheckPayload", ctx, channelID, "sealed").Return(nil)
+	e2eeService.On("CheckPayload", ctx, channelID, "stale").Return(ErrKeyEpochStale)
+	msgRepo.On("Create", ctx, mock.Anything).Return(nil)
+	eventBus.On("Publish", "message.created", mock.Anything).Return()
+
+	_, err := service.SendMessage(ctx, authorID, channelID, "stale", nil, nil)
+	assert.ErrorIs(t, err, ErrKeyEpochStale)
+
+	message, err := service.SendMessage(ctx, authorID, channelID, "sealed", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "sealed", message.EncryptedContent)
+	assert.Empty(t, message.Content, "the server never stores plaintext for encrypted channels")
+}
+
 func TestSendMessage_ChannelNotFound(t *testing.T) {
 	service, _, channelRepo, _, _, _, _, _, _ := setupMessageService()
 	ctx := context.Background()
@@ -478,6 +523,90 @@ func TestGetMessages_Success(t *testing.T) {
 	assert.Len(t, messages, 2)
 }
 
+func TestGetReplies_Success(t *testing.T) {
+	service, msgRepo, channelRepo, serverRepo, _, _, _, _, _ := setupMessageService()
+	blocks := new(MockUserRepository)
+	service.SetBlockRepository(blocks)
+	ctx := context.Background()
+	requesterID := uuid.New()
+	blockedID := uuid.New()
+	channelID := uuid.New()
+	serverID := uuid.New()
+	messageID := uuid.New()
+
+	reply := &models.Message{ID: uuid.New(), ChannelID: channelID, ReplyToID: &messageID}
+	nested := &models.Message{ID: uuid.New(), ChannelID: channelID, ReplyToID: &reply.ID}
+	hidden := &models.Message{ID: uuid.New(), ChannelID: channelID, AuthorID: blockedID, ReplyToID: &messageID}
+
+	msgRepo.On("GetByID", ctx, messageID).Return(&models.Message{ID: messageID, ChannelID: channelID}, nil)
+	channelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, ServerID: &serverID}, nil)
+	serverRepo.On("GetMember", ctx, serverID, requesterID).Return(&models.Member{UserID: requesterID}, nil)
+	msgRepo.On("GetReplies", ctx, messageID, &reply.ID, 50).Return([]*models.Message{hidden, nested}, nil)
+	blocks.On("GetBlockedUsers", ctx, requesterID).Return([]*models.User{{ID: blockedID}}, nil)
+
+	replies, err := service.GetReplies(ctx, channelID, messageID, requesterID, &reply.ID, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.Message{nested}, replies)
+}
+
+func TestGetReplies_ReadsPastHiddenPage(t *testing.T) {
+	service, msgRepo, channelRepo, serverRepo, _, _, _, _, _ := setupMessageService()
+	blocks := new(MockUserRepository)
+	service.SetBlockRepository(blocks)
+	ctx := context.Background()
+	requesterID := uuid.New()
+	blockedID := uuid.New()
+	channelID := uuid.New()
+	serverID := uuid.New()
+	messageID := uuid.New()
+
+	hidden := []*models.Message{
+		{ID: uuid.New(), AuthorID: blockedID},
+		{ID: uuid.New(), AuthorID: blockedID},
+	}
+	later := &models.Message{ID: uuid.New(), AuthorID: uuid.New()}
+	cursor := hidden[1].ID
+
+	msgRepo.On("GetByID", ctx, messageID).Return(&models.Message{ID: messageID, ChannelID: channelID}, nil)
+	channelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, ServerID: &serverID}, nil)
+	serverRepo.On("GetMember", ctx, serverID, requesterID).Return(&models.Member{UserID: requesterID}, nil)
+	msgRepo.On("GetReplies", ctx, messageID, (*uuid.UUID)(nil), 2).Return(hidden, nil).Once()
+	msgRepo.On("GetReplies", ctx, messageID, &cursor, 2).Return([]*models.Message{later}, nil).Once()
+	blocks.On("GetBlockedUsers", ctx, requesterID).Return([]*models.User{{ID: blockedID}}, nil)
+
+	replies, err := service.GetReplies(ctx, channelID, messageID, requesterID, nil, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.Message{later}, replies)
+	msgRepo.AssertExpectations(t)
+}
+
+func TestGetReplies_Denied(t *testing.T) {
+	service, msgRepo, channelRepo, serverRepo, _, _, _, _, _ := setupMessageService()
+	ctx := context.Background()
+	requesterID := uuid.New()
+	channelID := uuid.New()
+	serverID := uuid.New()
+	messageID := uuid.New()
+	otherID := uuid.New()
+	memberID := uuid.New()
+
+	msgRepo.On("GetByID", ctx, messageID).Return(&models.Message{ID: messageID, ChannelID: channelID}, nil)
+	msgRepo.On("GetByID", ctx, otherID).Return(nil, nil)
+	channelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, ServerID: &serverID}, nil)
+	serverRepo.On("GetMember", ctx, serverID, requesterID).Return(nil, nil)
+	serverRepo.On("GetMember", ctx, serverID, memberID).Return(&models.Member{UserID: memberID}, nil)
+
+	_, err := service.GetReplies(ctx, channelID, messageID, requesterID, nil, 50)
+	assert.Equal(t, ErrNotServerMember, err)
+	_, err = service.GetReplies(ctx, channelID, otherID, requesterID, nil, 50)
+	assert.Equal(t, ErrMessageNotFound, err)
+	_, err = service.GetReplies(ctx, uuid.New(), messageID, memberID, nil, 50)
+	assert.Equal(t, ErrMessageNotFound, err, "messages are only found in their own channel")
+	msgRepo.AssertNotCalled(t, "GetReplies", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestAddReaction_Success(t *testing.T) {
 	service, msgRepo, _, _, _, _, _, _, eventBus := setupMessageService()
 	ctx := context.Background()
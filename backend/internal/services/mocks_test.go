@@ -86,6 +86,22 @@ func (m *MockCacheService) Delete(ctx context.Context, key string) error {
 	return args.Error(0)
 }
 
+// empty makes every lookup miss and every write succeed, for tests that
+// don't care about caching
+func (m *MockCacheService) empty() *MockCacheService {
+	for _, method := range []string{"GetUser", "GetServer", "GetChannel", "Get"} {
+		m.On(method, mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+	}
+	for _, method := range []string{"SetUser", "SetServer", "SetChannel"} {
+		m.On(method, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	}
+	m.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	for _, method := range []string{"DeleteUser", "DeleteServer", "DeleteChannel", "Delete"} {
+		m.On(method, mock.Anything, mock.Anything).Return(nil).Maybe()
+	}
+	return m
+}
+
 // MockEventBus is a mock implementation of EventBus
 type MockEventBus struct {
 	mock.Mock
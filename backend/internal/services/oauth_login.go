@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/auth"
+	"hearth/internal/auth/oauth"
+	"hearth/internal/models"
+)
+
+// oauthUsernameAttempts is how many usernames are tried for a new account
+// before giving up
+const oauthUsernameAttempts = 5
+
+// OAuthIdentityRepository stores which login provider accounts log in as
+// which users
+type OAuthIdentityRepository interface {
+	// GetOAuthIdentity returns nil if the provider account isn't linked
+	GetOAuthIdentity(ctx context.Context, provider, subject string) (*models.OAuthIdentity, error)
+	// CreateOAuthIdentity returns ErrOAuthIdentityLinked if the provider
+	// account is already linked
+	CreateOAuthIdentity(ctx context.Context, identity *models.OAuthIdentity) error
+	RecordOAuthLogin(ctx context.Context, provider, subject string, at time.Time) error
+}
+
+// oauthUserRepository is the part of UserRepository OAuth logins need
+type oauthUserRepository interface {
+	authRepository
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+}
+
+// OAuthLoginService logs users in with accounts at external providers. The
+// first login links the provider account to the user with the same
+// verified email, or creates a user for it if provisioning is enabled.
+type OAuthLoginService struct {
+	identities OAuthIdentityRepository
+	users      oauthUserRepository
+	settings   SettingsRepository
+	jwtService *auth.JWTService
+	sessions   *SessionService
+	usernames  UsernamePolicy
+	security   *LoginSecurityService
+
+	provision bool
+	defaults  models.UserSettings
+}
+
+// NewOAuthLoginService creates an OAuth login service that links existing
+// users but doesn't create new ones. sessions may be nil, in which case
+// tokens aren't tracked.
+func NewOAuthLoginService(identities OAuthIdentityRepository, users oauthUserRepository, settings SettingsRepository, jwtService *auth.JWTService, sessions *SessionService) *OAuthLoginService {
+	return &OAuthLoginService{
+		identities: identities,
+		users:      users,
+		settings:   settings,
+		jwtService: jwtService,
+		sessions:   sessions,
+		defaults:   *models.DefaultUserSettings(uuid.Nil),
+	}
+}
+
+// SetLoginSecurity emails users when they log in from a new device.
+// Provider logins aren't refused while the account is locked: the lockout
+// is against guessing its password.
+func (s *OAuthLoginService) SetLoginSecurity(security *LoginSecurityService) {
+	s.security = security
+}
+
+// SetProvisioning creates users on their first login with a provider
+// account whose email matches nobody, starting with the given settings.
+// defaults may be nil to use the usual defaults.
+func (s *OAuthLoginService) SetProvisioning(enabled bool, defaults *models.UserSettings) {
+	s.provision = enabled
+	if defaults != nil {
+		s.defaults = *defaults
+	}
+}
+
+// SetUsernamePolicy keeps created users from taking reserved or banned
+// usernames
+func (s *OAuthLoginService) SetUsernamePolicy(policy UsernamePolicy) {
+	s.usernames = policy
+}
+
+// Login logs in as the user the provider account is linked to, linking or
+// creating one on its first login. Linking to an existing user needs an
+// email the provider verified, so nobody can take over an account by
+// claiming its email elsewhere.
+func (s *OAuthLoginService) Login(ctx context.Context, identity *oauth.Identity) (*models.User, *AuthTokens, error) {
+	now := time.Now()
+	linked, err := s.identities.GetOAuthIdentity(ctx, identity.Provider, identity.Subject)
+	if err != nil {
+		return nil, nil, err
+	}
+	if linked != nil {
+		user, err := s.users.GetByID(ctx, linked.UserID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if user == nil {
+			return nil, nil, ErrInvalidCredentials
+		}
+		if err := s.identities.RecordOAuthLogin(ctx, identity.Provider, identity.Subject, now); err != nil {
+			return nil, nil, err
+		}
+		return s.issue(ctx, user)
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, nil, ErrOAuthEmailUnverified
+	}
+	user, err := s.users.GetByEmail(ctx, identity.Email)
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		if !s.provision {
+			return nil, nil, ErrRegistrationClosed
+		}
+		if user, err = s.provisionUser(ctx, identity, now); err != nil {
+			return nil, nil, err
+		}
+	case err != nil:
+		return nil, nil, err
+	case !canLoginWithOAuth(user):
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	email := identity.Email
+	err = s.identities.CreateOAuthIdentity(ctx, &models.OAuthIdentity{
+		Provider:    identity.Provider,
+		Subject:     identity.Subject,
+		UserID:      user.ID,
+		Email:       &email,
+		CreatedAt:   now,
+		LastLoginAt: &now,
+	})
+	if errors.Is(err, ErrOAuthIdentityLinked) {
+		// Another callback for the same account linked it first
+		return s.Login(ctx, identity)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.issue(ctx, user)
+}
+
+// canLoginWithOAuth reports whether the user may log in with a provider.
+// Bots log in with their tokens and deleted accounts not at all.
+func canLoginWithOAuth(user *models.User) bool {
+	return !user.IsBot() && user.Flags&models.UserFlagDeletedUser == 0
+}
+
+func (s *OAuthLoginService) issue(ctx context.Context, user *models.User) (*models.User, *AuthTokens, error) {
+	if !canLoginWithOAuth(user) {
+		return nil, nil, ErrInvalidCredentials
+	}
+	tokens, err := issueTokens(ctx, s.jwtService, s.sessions, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.security != nil {
+		s.security.LoginSucceeded(ctx, user)
+	}
+	return user, tokens, nil
+}
+
+// provisionUser creates a user for a provider account on its first login.
+// The user has no password; they log in through the provider.
+func (s *OAuthLoginService) provisionUser(ctx context.Context, identity *oauth.Identity, now time.Time) (*models.User, error) {
+	user := &models.User{
+		ID:            uuid.New(),
+		Email:         identity.Email,
+		Discriminator: models.LegacyDiscriminator,
+		Status:        models.StatusOffline,
+		Verified:      true,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if identity.AvatarURL != "" {
+		avatar := identity.AvatarURL
+		user.AvatarURL = &avatar
+	}
+
+	base := oauthUsernameBase(identity)
+	for attempt := 0; ; attempt++ {
+		if attempt == oauthUsernameAttempts {
+			return nil, ErrUsernameTaken
+		}
+		user.Username = base
+		if attempt > 0 {
+			suffix := strconv.Itoa(rand.Intn(9999) + 1)
+			user.Username = base[:min(len(base), maxUsernameLength-len(suffix))] + suffix
+		}
+		if s.usernames != nil {
+			err := s.usernames.Check(ctx, user.Username, false)
+			if errors.Is(err, ErrUsernameReserved) || errors.Is(err, ErrUsernameBanned) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		err := s.users.Create(ctx, user)
+		if errors.Is(err, ErrUsernameTaken) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+
+	settings := s.defaults
+	settings.UserID = user.ID
+	settings.UpdatedAt = now
+	if err := s.settings.Create(ctx, &settings); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// oauthUsernameBase picks a username for a new user from their provider
+// handle, name or email
+func oauthUsernameBase(identity *oauth.Identity) string {
+	local, _, _ := strings.Cut(identity.Email, "@")
+	for _, name := range []string{identity.Username, identity.Name, local} {
+		if base := usernameBase(strings.ReplaceAll(name, " ", "")); base != "" {
+			return base
+		}
+	}
+	return "user"
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/auth/oauth"
+	"hearth/internal/models"
+)
+
+type fakeOAuthRepos struct {
+	identities map[string]*models.OAuthIdentity
+	users      map[uuid.UUID]*models.User
+}
+
+func newFakeOAuthRepos() *fakeOAuthRepos {
+	return &fakeOAuthRepos{
+		identities: make(map[string]*models.OAuthIdentity),
+		users:      make(map[uuid.UUID]*models.User),
+	}
+}
+
+func (r *fakeOAuthRepos) GetOAuthIdentity(ctx context.Context, provider, subject string) (*models.OAuthIdentity, error) {
+	return r.identities[provider+"/"+subject], nil
+}
+
+func (r *fakeOAuthRepos) CreateOAuthIdentity(ctx context.Context, identity *models.OAuthIdentity) error {
+	key := identity.Provider + "/" + identity.Subject
+	if _, ok := r.identities[key]; ok {
+		return ErrOAuthIdentityLinked
+	}
+	r.identities[key] = identity
+	return nil
+}
+
+func (r *fakeOAuthRepos) RecordOAuthLogin(ctx context.Context, provider, subject string, at time.Time) error {
+	r.identities[provider+"/"+subject].LastLoginAt = &at
+	return nil
+}
+
+func (r *fakeOAuthRepos) Create(ctx context.Context, user *models.User) error {
+	for _, existing := range r.users {
+		if strings.EqualFold(existing.Username, user.Username) {
+			return ErrUsernameTaken
+		}
+	}
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+func (r *fakeOAuthRepos) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+func (r *fakeOAuthRepos) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	return r.users[id], nil
+}
+
+type oauthLoginTest struct {
+	service  *OAuthLoginService
+	repos    *fakeOAuthRepos
+	settings *fakePrivacyRepos
+}
+
+func newOAuthLoginTest() *oauthLoginTest {
+	f := &oauthLoginTest{repos: newFakeOAuthRepos(), settings: newFakePrivacyRepos()}
+	f.service = NewOAuthLoginService(f.repos, f.repos, f.settings, testJWTService(), nil)
+	return f
+}
+
+func (f *oauthLoginTest) addUser(email, username string) *models.User {
+	user := &models.User{ID: uuid.New(), Email: email, Username: username}
+	f.repos.users[user.ID] = user
+	return user
+}
+
+func googleIdentity(subject, email string, verified bool) *oauth.Identity {
+	return &oauth.Identity{Provider: "google", Subject: subject, Email: email, EmailVerified: verified, Name: "Alice Liddell"}
+}
+
+func TestOAuthLoginService_LinksByVerifiedEmail(t *testing.T) {
+	f := newOAuthLoginTest()
+	ctx := context.Background()
+	alice := f.addUser("alice@example.com", "alice")
+
+	_, _, err := f.service.Login(ctx, googleIdentity("g-1", "alice@example.com", false))
+	assert.ErrorIs(t, err, ErrOAuthEmailUnverified, "an unverified email can't claim an account")
+	assert.Empty(t, f.repos.identities)
+
+	user, tokens, err := f.service.Login(ctx, googleIdentity("g-1", "alice@example.com", true))
+	require.NoError(t, err)
+	assert.Equal(t, alice.ID, user.ID)
+	assert.NotEmpty(t, tokens.AccessToken)
+	require.Contains(t, f.repos.identities, "google/g-1")
+	assert.Equal(t, alice.ID, f.repos.identities["google/g-1"].UserID)
+	assert.Len(t, f.repos.users, 1, "no account is created")
+
+	// Once linked, the identity logs in even if the email changes
+	user, _, err = f.service.Login(ctx, googleIdentity("g-1", "alice@elsewhere.example", false))
+	require.NoError(t, err)
+	assert.Equal(t, alice.ID, user.ID)
+	assert.NotNil(t, f.repos.identities["google/g-1"].LastLoginAt)
+}
+
+func TestOAuthLoginService_Provisioning(t *testing.T) {
+	f := newOAuthLoginTest()
+	ctx := context.Background()
+	identity := googleIdentity("g-2", "bob@example.com", true)
+	identity.Username = "bob"
+
+	_, _, err := f.service.Login(ctx, identity)
+	assert.ErrorIs(t, err, ErrRegistrationClosed, "accounts aren't created unless provisioning is on")
+
+	defaults := models.DefaultUserSettings(uuid.Nil)
+	defaults.Theme = "light"
+	defaults.PrivacyDMFromServers = false
+	f.service.SetProvisioning(true, defaults)
+	f.addUser("someone@example.com", "bob")
+
+	user, tokens, err := f.service.Login(ctx, identity)
+	require.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.Equal(t, "bob@example.com", user.Email)
+	assert.True(t, user.Verified)
+	assert.True(t, strings.HasPrefix(user.Username, "bob") && user.Username != "bob", "a taken username gets a suffix, got %q", user.Username)
+	assert.Equal(t, user.ID, f.repos.identities["google/g-2"].UserID)
+
+	settings := f.settings.settings[user.ID]
+	require.NotNil(t, settings, "created users start with the configured settings")
+	assert.Equal(t, "light", settings.Theme)
+	assert.False(t, settings.PrivacyDMFromServers)
+	assert.Equal(t, "cozy", settings.MessageDisplay)
+
+	again, _, err := f.service.Login(ctx, identity)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, again.ID)
+	assert.Len(t, f.repos.users, 2)
+}
+
+func TestOAuthLoginService_RefusesBots(t *testing.T) {
+	f := newOAuthLoginTest()
+	bot := f.addUser("bot@example.com", "helper")
+	bot.Flags = models.UserFlagBot
+
+	_, _, err := f.service.Login(context.Background(), googleIdentity("g-3", "bot@example.com", true))
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	assert.Empty(t, f.repos.identities, "bots aren't linked")
+}
+
+func TestOAuthUsernameBase(t *testing.T) {
+	assert.Equal(t, "octocat", oauthUsernameBase(&oauth.Identity{Username: "octocat", Name: "The Octocat"}))
+	assert.Equal(t, "AliceLiddell", oauthUsernameBase(&oauth.Identity{Name: "Alice Liddell"}))
+	assert.Equal(t, "j.doe", oauthUsernameBase(&oauth.Identity{Name: "李", Email: "j.doe@example.com"}))
+	assert.Equal(t, "user", oauthUsernameBase(&oauth.Identity{}))
+}
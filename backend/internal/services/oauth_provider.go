@@ -0,0 +1,472 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/auth/oauth"
+	"hearth/internal/models"
+)
+
+const (
+	// MaxOAuthApplications is how many applications a user can register
+	MaxOAuthApplications = 25
+	// OAuthCodeLifetime is how long an application has to exchange an
+	// authorization code
+	OAuthCodeLifetime = 10 * time.Minute
+	// OAuthAccessTokenLifetime is how long access tokens last before the
+	// application has to refresh them
+	OAuthAccessTokenLifetime = 7 * 24 * time.Hour
+
+	maxOAuthAppName      = 100
+	maxOAuthRedirectURIs = 10
+)
+
+// OAuthAppRepository stores OAuth applications and what users granted them,
+// by the hash of each secret. The Postgres OAuthAppRepository implements it.
+type OAuthAppRepository interface {
+	CreateApplication(ctx context.Context, app *models.OAuthApplication) error
+	// GetApplication returns nil if there's no such application
+	GetApplication(ctx context.Context, id uuid.UUID) (*models.OAuthApplication, error)
+	// GetApplicationsByOwner returns the user's applications, newest first
+	GetApplicationsByOwner(ctx context.Context, ownerID uuid.UUID) ([]*models.OAuthApplication, error)
+	SetClientSecretHash(ctx context.Context, id uuid.UUID, secretHash string) error
+	// DeleteApplication reports whether the owner had the application
+	DeleteApplication(ctx context.Context, ownerID, id uuid.UUID) (bool, error)
+
+	CreateAuthorizationCode(ctx context.Context, code *models.OAuthAuthorizationCode) error
+	// TakeAuthorizationCode deletes and returns the code with the hash, or
+	// nil if there's none, so each code is used once
+	TakeAuthorizationCode(ctx context.Context, codeHash string) (*models.OAuthAuthorizationCode, error)
+
+	CreateToken(ctx context.Context, token *models.OAuthToken) error
+	// GetTokenByAccessHash returns nil if no token has the hash
+	GetTokenByAccessHash(ctx context.Context, accessHash string) (*models.OAuthToken, error)
+	// TakeTokenByRefreshHash deletes and returns the token with the refresh
+	// token hash, or nil if there's none
+	TakeTokenByRefreshHash(ctx context.Context, refreshHash string) (*models.OAuthToken, error)
+	// GetAuthorizedApplications returns the applications the user granted
+	// access, most recently granted first
+	GetAuthorizedApplications(ctx context.Context, userID uuid.UUID) ([]*models.AuthorizedOAuthApplication, error)
+	// DeleteTokens revokes what the user granted the application and
+	// reports whether there was anything
+	DeleteTokens(ctx context.Context, userID, applicationID uuid.UUID) (bool, error)
+	// DeleteUserGrants revokes the user's tokens and authorization codes
+	// for every application
+	DeleteUserGrants(ctx context.Context, userID uuid.UUID) error
+}
+
+// OAuthProviderService makes Hearth an OAuth2 authorization server, so
+// third-party apps can act for users with the scopes they approve instead
+// of asking for their password. It implements the authorization code grant,
+// with optional PKCE, and refresh tokens.
+type OAuthProviderService struct {
+	repo OAuthAppRepository
+	now  func() time.Time
+}
+
+// NewOAuthProviderService creates a new OAuth provider service
+func NewOAuthProviderService(repo OAuthAppRepository) *OAuthProviderService {
+	return &OAuthProviderService{
+		repo: repo,
+		now:  time.Now,
+	}
+}
+
+// ListApplications returns the user's applications, without their secrets
+func (s *OAuthProviderService) ListApplications(ctx context.Context, ownerID uuid.UUID) ([]*models.OAuthApplication, error) {
+	return s.repo.GetApplicationsByOwner(ctx, ownerID)
+}
+
+// CreateApplication registers an application. The returned application
+// carries its client secret, which isn't kept and can't be shown again.
+func (s *OAuthProviderService) CreateApplication(ctx context.Context, ownerID uuid.UUID, req *models.CreateOAuthApplicationRequest) (*models.OAuthApplication, error) {
+	app := &models.OAuthApplication{
+		ID:        uuid.New(),
+		OwnerID:   ownerID,
+		Name:      strings.TrimSpace(req.Name),
+		CreatedAt: s.now(),
+	}
+	if err := normalizeOAuthApp(app, req.RedirectURIs); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.GetApplicationsByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= MaxOAuthApplications {
+		return nil, ErrTooManyOAuthApps
+	}
+
+	if app.ClientSecret, err = newOAuthSecret(""); err != nil {
+		return nil, err
+	}
+	app.ClientSecretHash = hashOAuthSecret(app.ClientSecret)
+	if err := s.repo.CreateApplication(ctx, app); err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// ResetClientSecret gives an application a new client secret, returned
+// this once. The old one stops working at once; tokens already issued
+// keep working.
+func (s *OAuthProviderService) ResetClientSecret(ctx context.Context, ownerID, appID uuid.UUID) (*models.OAuthApplication, error) {
+	app, err := s.ownApplication(ctx, ownerID, appID)
+	if err != nil {
+		return nil, err
+	}
+	if app.ClientSecret, err = newOAuthSecret(""); err != nil {
+		return nil, err
+	}
+	app.ClientSecretHash = hashOAuthSecret(app.ClientSecret)
+	if err := s.repo.SetClientSecretHash(ctx, app.ID, app.ClientSecretHash); err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// DeleteApplication deletes an application and every token users granted it
+func (s *OAuthProviderService) DeleteApplication(ctx context.Context, ownerID, appID uuid.UUID) error {
+	deleted, err := s.repo.DeleteApplication(ctx, ownerID, appID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrOAuthAppNotFound
+	}
+	return nil
+}
+
+// GetAuthorization checks an authorization request and returns what the
+// user is asked to approve. It returns ErrInvalidOAuthClient for unknown
+// clients and unregistered redirect URIs, which must not be redirected to.
+func (s *OAuthProviderService) GetAuthorization(ctx context.Context, req *models.OAuthAuthorizeRequest) (*models.OAuthAuthorization, error) {
+	appID, err := uuid.Parse(req.ClientID)
+	if err != nil {
+		return nil, ErrInvalidOAuthClient
+	}
+	app, err := s.repo.GetApplication(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	if app == nil {
+		return nil, ErrInvalidOAuthClient
+	}
+
+	redirectURI := req.RedirectURI
+	if redirectURI == "" && len(app.RedirectURIs) == 1 {
+		redirectURI = app.RedirectURIs[0]
+	}
+	if !slices.Contains(app.RedirectURIs, redirectURI) {
+		return nil, ErrInvalidOAuthClient
+	}
+
+	if req.ResponseType != "code" {
+		return nil, invalidOAuthRequest("response_type must be code")
+	}
+	if req.CodeChallenge != "" && req.CodeChallengeMethod != "S256" {
+		return nil, invalidOAuthRequest("code_challenge_method must be S256")
+	}
+	scopes, err := parseOAuthScopes(req.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	app.ClientSecretHash = ""
+	return &models.OAuthAuthorization{
+		Application: app,
+		Scopes:      scopes,
+		RedirectURI: redirectURI,
+	}, nil
+}
+
+// Authorize records that the user approved an authorization request and
+// returns the URL to send them back to the application with, carrying an
+// authorization code and the request's state
+func (s *OAuthProviderService) Authorize(ctx context.Context, userID uuid.UUID, req *models.OAuthAuthorizeRequest) (string, error) {
+	authorization, err := s.GetAuthorization(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	raw, err := newOAuthSecret("")
+	if err != nil {
+		return "", err
+	}
+	now := s.now()
+	code := &models.OAuthAuthorizationCode{
+		CodeHash:      hashOAuthSecret(raw),
+		ApplicationID: authorization.Application.ID,
+		UserID:        userID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        authorization.Scopes,
+		ExpiresAt:     now.Add(OAuthCodeLifetime),
+		CreatedAt:     now,
+	}
+	if req.CodeChallenge != "" {
+		code.CodeChallenge = &req.CodeChallenge
+	}
+	if err := s.repo.CreateAuthorizationCode(ctx, code); err != nil {
+		return "", err
+	}
+
+	location, err := url.Parse(authorization.RedirectURI)
+	if err != nil {
+		return "", err
+	}
+	query := location.Query()
+	query.Set("code", raw)
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+	location.RawQuery = query.Encode()
+	return location.String(), nil
+}
+
+// Exchange answers the token endpoint: it trades an authorization code or
+// a refresh token for a new access token and refresh token. Refresh tokens
+// are used once; each exchange replaces the token it refreshed.
+func (s *OAuthProviderService) Exchange(ctx context.Context, req *models.OAuthTokenRequest) (*models.OAuthTokenResponse, error) {
+	app, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	var userID uuid.UUID
+	var scopes []string
+	switch req.GrantType {
+	case "authorization_code":
+		code, err := s.repo.TakeAuthorizationCode(ctx, hashOAuthSecret(req.Code))
+		if err != nil {
+			return nil, err
+		}
+		// A redirect_uri named when authorizing must be repeated exactly
+		// (RFC 6749 section 4.1.3)
+		if code == nil || code.ApplicationID != app.ID || !now.Before(code.ExpiresAt) ||
+			(code.RedirectURI != "" && req.RedirectURI != code.RedirectURI) {
+			return nil, ErrInvalidOAuthGrant
+		}
+		if code.CodeChallenge != nil && oauth.Challenge(req.CodeVerifier) != *code.CodeChallenge {
+			return nil, ErrInvalidOAuthGrant
+		}
+		userID, scopes = code.UserID, code.Scopes
+	case "refresh_token":
+		if !strings.HasPrefix(req.RefreshToken, models.OAuthRefreshTokenPrefix) {
+			return nil, ErrInvalidOAuthGrant
+		}
+		previous, err := s.repo.TakeTokenByRefreshHash(ctx, hashOAuthSecret(req.RefreshToken))
+		if err != nil {
+			return nil, err
+		}
+		if previous == nil || previous.ApplicationID != app.ID {
+			return nil, ErrInvalidOAuthGrant
+		}
+		userID, scopes = previous.UserID, previous.Scopes
+	default:
+		return nil, ErrUnsupportedOAuthGrantType
+	}
+
+	access, err := newOAuthSecret(models.OAuthAccessTokenPrefix)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := newOAuthSecret(models.OAuthRefreshTokenPrefix)
+	if err != nil {
+		return nil, err
+	}
+	token := &models.OAuthToken{
+		ID:               uuid.New(),
+		ApplicationID:    app.ID,
+		UserID:           userID,
+		Scopes:           scopes,
+		AccessTokenHash:  hashOAuthSecret(access),
+		RefreshTokenHash: hashOAuthSecret(refresh),
+		ExpiresAt:        now.Add(OAuthAccessTokenLifetime),
+		CreatedAt:        now,
+	}
+	if err := s.repo.CreateToken(ctx, token); err != nil {
+		return nil, err
+	}
+	return &models.OAuthTokenResponse{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(OAuthAccessTokenLifetime.Seconds()),
+		RefreshToken: refresh,
+		Scope:        strings.Join(scopes, " "),
+	}, nil
+}
+
+// AuthenticateOAuthToken returns the token raw is the access token of. It
+// returns ErrInvalidOAuthToken for unknown tokens, including those of
+// deleted applications and revoked grants, and ErrOAuthTokenExpired for
+// expired ones.
+func (s *OAuthProviderService) AuthenticateOAuthToken(ctx context.Context, raw string) (*models.OAuthToken, error) {
+	if !strings.HasPrefix(raw, models.OAuthAccessTokenPrefix) {
+		return nil, ErrInvalidOAuthToken
+	}
+	token, err := s.repo.GetTokenByAccessHash(ctx, hashOAuthSecret(raw))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, ErrInvalidOAuthToken
+	}
+	if !s.now().Before(token.ExpiresAt) {
+		return nil, ErrOAuthTokenExpired
+	}
+	return token, nil
+}
+
+// ListAuthorizedApplications returns the applications the user granted
+// access, without their secrets
+func (s *OAuthProviderService) ListAuthorizedApplications(ctx context.Context, userID uuid.UUID) ([]*models.AuthorizedOAuthApplication, error) {
+	return s.repo.GetAuthorizedApplications(ctx, userID)
+}
+
+// RevokeAuthorization takes back everything the user granted an
+// application. Its tokens stop working at once.
+func (s *OAuthProviderService) RevokeAuthorization(ctx context.Context, userID, appID uuid.UUID) error {
+	deleted, err := s.repo.DeleteTokens(ctx, userID, appID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrOAuthAppNotFound
+	}
+	return nil
+}
+
+// RevokeAllAuthorizations takes back everything the user granted every
+// application, including codes not yet exchanged. It's part of logging the
+// user out everywhere.
+func (s *OAuthProviderService) RevokeAllAuthorizations(ctx context.Context, userID uuid.UUID) error {
+	return s.repo.DeleteUserGrants(ctx, userID)
+}
+
+// ownApplication returns the owner's application
+func (s *OAuthProviderService) ownApplication(ctx context.Context, ownerID, appID uuid.UUID) (*models.OAuthApplication, error) {
+	app, err := s.repo.GetApplication(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	if app == nil || app.OwnerID != ownerID {
+		return nil, ErrOAuthAppNotFound
+	}
+	return app, nil
+}
+
+// authenticateClient returns the application with the client ID if secret
+// is its client secret
+func (s *OAuthProviderService) authenticateClient(ctx context.Context, clientID, secret string) (*models.OAuthApplication, error) {
+	appID, err := uuid.Parse(clientID)
+	if err != nil {
+		return nil, ErrInvalidOAuthClient
+	}
+	app, err := s.repo.GetApplication(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	if app == nil || subtle.ConstantTimeCompare([]byte(hashOAuthSecret(secret)), []byte(app.ClientSecretHash)) != 1 {
+		return nil, ErrInvalidOAuthClient
+	}
+	return app, nil
+}
+
+func newOAuthSecret(prefix string) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(secret), nil
+}
+
+func hashOAuthSecret(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func invalidOAuthApp(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidOAuthApp, fmt.Sprintf(format, args...))
+}
+
+func invalidOAuthRequest(message string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidOAuthRequest, message)
+}
+
+// normalizeOAuthApp checks a new application's name and keeps each of its
+// redirect URIs once. Redirect URIs must be absolute https URLs, or http
+// ones on the loopback interface for apps running on the user's machine.
+func normalizeOAuthApp(app *models.OAuthApplication, redirectURIs []string) error {
+	if app.Name == "" || utf8.RuneCountInString(app.Name) > maxOAuthAppName {
+		return invalidOAuthApp("name must be 1-%d characters", maxOAuthAppName)
+	}
+
+	app.RedirectURIs = []string{}
+	for _, raw := range redirectURIs {
+		raw = strings.TrimSpace(raw)
+		if slices.Contains(app.RedirectURIs, raw) {
+			continue
+		}
+		uri, err := url.Parse(raw)
+		if err != nil || uri.Host == "" || uri.Fragment != "" || uri.User != nil {
+			return invalidOAuthApp("redirect URI %q must be an absolute URL without a fragment", raw)
+		}
+		switch uri.Scheme {
+		case "https":
+		case "http":
+			if host := uri.Hostname(); host != "localhost" && !isLoopbackIP(host) {
+				return invalidOAuthApp("redirect URI %q must use https", raw)
+			}
+		default:
+			return invalidOAuthApp("redirect URI %q must use https", raw)
+		}
+		app.RedirectURIs = append(app.RedirectURIs, raw)
+	}
+	if len(app.RedirectURIs) == 0 || len(app.RedirectURIs) > maxOAuthRedirectURIs {
+		return invalidOAuthApp("an application needs 1-%d redirect URIs", maxOAuthRedirectURIs)
+	}
+	return nil
+}
+
+// parseOAuthScopes splits a space separated scope parameter, keeping each
+// scope once in the order of models.OAuthScopes
+func parseOAuthScopes(scope string) ([]string, error) {
+	requested := make(map[string]bool)
+	for _, s := range strings.Fields(scope) {
+		requested[s] = true
+	}
+	scopes := []string{}
+	for _, s := range models.OAuthScopes {
+		if requested[s] {
+			scopes = append(scopes, s)
+			delete(requested, s)
+		}
+	}
+	for s := range requested {
+		return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidOAuthScope, s)
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: ask for at least one scope", ErrInvalidOAuthScope)
+	}
+	return scopes, nil
+}
+
+func isLoopbackIP(host string) bool {
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,431 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/auth/oauth"
+	"hearth/internal/models"
+)
+
+type fakeOAuthAppRepository struct {
+	apps   map[uuid.UUID]*models.OAuthApplication
+	codes  map[string]*models.OAuthAuthorizationCode
+	tokens map[uuid.UUID]*models.OAuthToken
+}
+
+func newFakeOAuthAppRepository() *fakeOAuthAppRepository {
+	return &fakeOAuthAppRepository{
+		apps:   make(map[uuid.UUID]*models.OAuthApplication),
+		codes:  make(map[string]*models.OAuthAuthorizationCode),
+		tokens: make(map[uuid.UUID]*models.OAuthToken),
+	}
+}
+
+func (f *fakeOAuthAppRepository) CreateApplication(ctx context.Context, app *models.OAuthApplication) error {
+	copied := *app
+	copied.ClientSecret = ""
+	f.apps[app.ID] = &copied
+	return nil
+}
+
+func (f *fakeOAuthAppRepository) GetApplication(ctx context.Context, id uuid.UUID) (*models.OAuthApplication, error) {
+	if app, ok := f.apps[id]; ok {
+		copied := *app
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (f *fakeOAuthAppRepository) GetApplicationsByOwner(ctx context.Context, ownerID uuid.UUID) ([]*models.OAuthApplication, error) {
+	var apps []*models.OAuthApplication
+	for _, app := range f.apps {
+		if app.OwnerID == ownerID {
+			copied := *app
+			apps = append(apps, &copied)
+		}
+	}
+	return apps, nil
+}
+
+func (f *fakeOAuthAppRepository) SetClientSecretHash(ctx context.Context, id uuid.UUID, secretHash string) error {
+	f.apps[id].ClientSecretHash = secretHash
+	return nil
+}
+
+func (f *fakeOAuthAppRepository) DeleteApplication(ctx context.Context, ownerID, id uuid.UUID) (bool, error) {
+	app, ok := f.apps[id]
+	if !ok || app.OwnerID != ownerID {
+		return false, nil
+	}
+	delete(f.apps, id)
+	for tokenID, token := range f.tokens {
+		if token.ApplicationID == id {
+			delete(f.tokens, tokenID)
+		}
+	}
+	return true, nil
+}
+
+func (f *fakeOAuthAppRepository) CreateAuthorizationCode(ctx context.Context, code *models.OAuthAuthorizationCode) error {
+	copied := *code
+	f.codes[code.CodeHash] = &copied
+	return nil
+}
+
+func (f *fakeOAuthAppRepository) TakeAuthorizationCode(ctx context.Context, codeHash string) (*models.OAuthAuthorizationCode, error) {
+	code, ok := f.codes[codeHash]
+	if !ok {
+		return nil, nil
+	}
+	delete(f.codes, codeHash)
+	return code, nil
+}
+
+func (f *fakeOAuthAppRepository) CreateToken(ctx context.Context, token *models.OAuthToken) error {
+	copied := *token
+	f.tokens[token.ID] = &copied
+	return nil
+}
+
+func (f *fakeOAuthAppRepository) GetTokenByAccessHash(ctx context.Context, accessHash string) (*models.OAuthToken, error) {
+	for _, token := range f.tokens {
+		if token.AccessTokenHash == accessHash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeOAuthAppRepository) TakeTokenByRefreshHash(ctx context.Context, refreshHash string) (*models.OAuthToken, error) {
+	for id, token := range f.tokens {
+		if token.RefreshTokenHash == refreshHash {
+			delete(f.tokens, id)
+			return token, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeOAuthAppRepository) GetAuthorizedApplications(ctx context.Context, userID uuid.UUID) ([]*models.AuthorizedOAuthApplication, error) {
+	var apps []*models.AuthorizedOAuthApplication
+	for _, token := range f.tokens {
+		if token.UserID == userID {
+			apps = append(apps, &models.AuthorizedOAuthApplication{Application: f.apps[token.ApplicationID], Scopes: token.Scopes})
+		}
+	}
+	return apps, nil
+}
+
+func (f *fakeOAuthAppRepository) DeleteTokens(ctx context.Context, userID, applicationID uuid.UUID) (bool, error) {
+	deleted := false
+	for id, token := range f.tokens {
+		if token.UserID == userID && token.ApplicationID == applicationID {
+			delete(f.tokens, id)
+			deleted = true
+		}
+	}
+	return deleted, nil
+}
+
+func (f *fakeOAuthAppRepository) DeleteUserGrants(ctx context.Context, userID uuid.UUID) error {
+	for hash, code := range f.codes {
+		if code.UserID == userID {
+			delete(f.codes, hash)
+		}
+	}
+	for id, token := range f.tokens {
+		if token.UserID == userID {
+			delete(f.tokens, id)
+		}
+	}
+	return nil
+}
+
+type oauthProviderTest struct {
+	service *OAuthProviderService
+	repo    *fakeOAuthAppRepository
+	app     *models.OAuthApplication
+	ownerID uuid.UUID
+	userID  uuid.UUID
+	now     time.Time
+}
+
+func newOAuthProviderTest(t *testing.T) *oauthProviderTest {
+	f := &oauthProviderTest{
+		repo:    newFakeOAuthAppRepository(),
+		ownerID: uuid.New(),
+		userID:  uuid.New(),
+		now:     time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	f.service = NewOAuthProviderService(f.repo)
+	f.service.now = func() time.Time { return f.now }
+
+	app, err := f.service.CreateApplication(context.Background(), f.ownerID, &models.CreateOAuthApplicationRequest{
+		Name:         " Release bot ",
+		RedirectURIs: []string{"https://bot.example.com/callback", "http://127.0.0.1:8080/cb"},
+	})
+	require.NoError(t, err)
+	f.app = app
+	return f
+}
+
+// authorize approves a request for scope and returns the code in the
+// redirect
+func (f *oauthProviderTest) authorize(t *testing.T, scope, challenge string) string {
+	location, err := f.service.Authorize(context.Background(), f.userID, &models.OAuthAuthorizeRequest{
+		ClientID:            f.app.ID.String(),
+		RedirectURI:         "https://bot.example.com/callback",
+		ResponseType:        "code",
+		Scope:               scope,
+		State:               "xyz",
+		CodeChallenge:       challenge,
+		CodeChallengeMethod: "S256",
+	})
+	require.NoError(t, err)
+	redirect, err := url.Parse(location)
+	require.NoError(t, err)
+	assert.Equal(t, "bot.example.com", redirect.Host)
+	assert.Equal(t, "xyz", redirect.Query().Get("state"))
+	return redirect.Query().Get("code")
+}
+
+func TestOAuthProviderService_CreateApplication(t *testing.T) {
+	f := newOAuthProviderTest(t)
+	ctx := context.Background()
+
+	assert.Equal(t, "Release bot", f.app.Name)
+	assert.NotEmpty(t, f.app.ClientSecret)
+	assert.NotContains(t, f.repo.apps[f.app.ID].ClientSecretHash, f.app.ClientSecret, "only the hash is kept")
+
+	for _, uris := range [][]string{
+		nil,
+		{"http://bot.example.com/callback"},
+		{"javascript:alert(1)"},
+		{"https://bot.example.com/callback#frag"},
+		{"/callback"},
+	} {
+		_, err := f.service.CreateApplication(ctx, f.ownerID, &models.CreateOAuthApplicationRequest{Name: "Bot", RedirectURIs: uris})
+		assert.ErrorIs(t, err, ErrInvalidOAuthApp, "%v", uris)
+	}
+
+	app, err := f.service.ResetClientSecret(ctx, f.ownerID, f.app.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, f.app.ClientSecret, app.ClientSecret)
+	_, err = f.service.ResetClientSecret(ctx, uuid.New(), f.app.ID)
+	assert.ErrorIs(t, err, ErrOAuthAppNotFound, "only the owner can reset the secret")
+}
+
+func TestOAuthProviderService_GetAuthorization(t *testing.T) {
+	f := newOAuthProviderTest(t)
+	ctx := context.Background()
+	valid := models.OAuthAuthorizeRequest{
+		ClientID:     f.app.ID.String(),
+		RedirectURI:  "https://bot.example.com/callback",
+		ResponseType: "code",
+		Scope:        "messages.write identify identify",
+	}
+
+	authorization, err := f.service.GetAuthorization(ctx, &valid)
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.OAuthScopeIdentify, models.OAuthScopeMessagesWrite}, authorization.Scopes)
+	assert.Empty(t, authorization.Application.ClientSecretHash)
+
+	tests := []struct {
+		name   string
+		modify func(req *models.OAuthAuthorizeRequest)
+		want   error
+	}{
+		{"unknown client", func(req *models.OAuthAuthorizeRequest) { req.ClientID = uuid.NewString() }, ErrInvalidOAuthClient},
+		{"unregistered redirect", func(req *models.OAuthAuthorizeRequest) { req.RedirectURI = "https://evil.example.com/" }, ErrInvalidOAuthClient},
+		{"implicit grant", func(req *models.OAuthAuthorizeRequest) { req.ResponseType = "token" }, ErrInvalidOAuthRequest},
+		{"plain PKCE", func(req *models.OAuthAuthorizeRequest) { req.CodeChallenge, req.CodeChallengeMethod = "abc", "plain" }, ErrInvalidOAuthRequest},
+		{"unknown scope", func(req *models.OAuthAuthorizeRequest) { req.Scope = "identify admin" }, ErrInvalidOAuthScope},
+		{"no scope", func(req *models.OAuthAuthorizeRequest) { req.Scope = "" }, ErrInvalidOAuthScope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+			_, err := f.service.GetAuthorization(ctx, &req)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestOAuthProviderService_Exchange(t *testing.T) {
+	f := newOAuthProviderTest(t)
+	ctx := context.Background()
+	verifier := "a-verifier-long-enough-for-pkce-0123456789"
+	code := f.authorize(t, "identify servers.read", oauth.Challenge(verifier))
+
+	exchange := models.OAuthTokenRequest{
+		GrantType:    "authorization_code",
+		Code:         code,
+		RedirectURI:  "https://bot.example.com/callback",
+		CodeVerifier: verifier,
+		ClientID:     f.app.ID.String(),
+		ClientSecret: f.app.ClientSecret,
+	}
+
+	wrongSecret := exchange
+	wrongSecret.ClientSecret = "nope"
+	_, err := f.service.Exchange(ctx, &wrongSecret)
+	assert.ErrorIs(t, err, ErrInvalidOAuthClient)
+
+	tokens, err := f.service.Exchange(ctx, &exchange)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer", tokens.TokenType)
+	assert.Equal(t, "identify servers.read", tokens.Scope)
+	assert.Equal(t, int(OAuthAccessTokenLifetime.Seconds()), tokens.ExpiresIn)
+
+	token, err := f.service.AuthenticateOAuthToken(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, f.userID, token.UserID)
+	assert.True(t, token.HasScope(models.OAuthScopeServersRead))
+
+	_, err = f.service.Exchange(ctx, &exchange)
+	assert.ErrorIs(t, err, ErrInvalidOAuthGrant, "codes are used once")
+
+	refresh := models.OAuthTokenRequest{
+		GrantType:    "refresh_token",
+		RefreshToken: tokens.RefreshToken,
+		ClientID:     f.app.ID.String(),
+		ClientSecret: f.app.ClientSecret,
+	}
+	refreshed, err := f.service.Exchange(ctx, &refresh)
+	require.NoError(t, err)
+	assert.Equal(t, tokens.Scope, refreshed.Scope)
+	_, err = f.service.AuthenticateOAuthToken(ctx, tokens.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidOAuthToken, "refreshing replaces the old token")
+	_, err = f.service.Exchange(ctx, &refresh)
+	assert.ErrorIs(t, err, ErrInvalidOAuthGrant, "refresh tokens are used once")
+
+	f.now = f.now.Add(OAuthAccessTokenLifetime)
+	_, err = f.service.AuthenticateOAuthToken(ctx, refreshed.AccessToken)
+	assert.ErrorIs(t, err, ErrOAuthTokenExpired)
+
+	_, err = f.service.Exchange(ctx, &models.OAuthTokenRequest{GrantType: "password", ClientID: f.app.ID.String(), ClientSecret: f.app.ClientSecret})
+	assert.ErrorIs(t, err, ErrUnsupportedOAuthGrantType)
+}
+
+func TestOAuthProviderService_ExchangeRejectsBadCodes(t *testing.T) {
+	f := newOAuthProviderTest(t)
+	ctx := context.Background()
+	other, err := f.service.CreateApplication(ctx, f.ownerID, &models.CreateOAuthApplicationRequest{
+		Name: "Other", RedirectURIs: []string{"https://bot.example.com/callback"},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		modify func(req *models.OAuthTokenRequest)
+	}{
+		{"wrong verifier", func(req *models.OAuthTokenRequest) { req.CodeVerifier = "guess" }},
+		{"another redirect", func(req *models.OAuthTokenRequest) { req.RedirectURI = "http://127.0.0.1:8080/cb" }},
+		{"missing redirect", func(req *models.OAuthTokenRequest) { req.RedirectURI = "" }},
+		{"another application", func(req *models.OAuthTokenRequest) {
+			req.ClientID, req.ClientSecret = other.ID.String(), other.ClientSecret
+		}},
+		{"expired", func(req *models.OAuthTokenRequest) { f.now = f.now.Add(OAuthCodeLifetime) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := "a-verifier-long-enough-for-pkce-0123456789"
+			req := models.OAuthTokenRequest{
+				GrantType:    "authorization_code",
+				Code:         f.authorize(t, "identify", oauth.Challenge(verifier)),
+				RedirectURI:  "https://bot.example.com/callback",
+				CodeVerifier: verifier,
+				ClientID:     f.app.ID.String(),
+				ClientSecret: f.app.ClientSecret,
+			}
+			tt.modify(&req)
+			_, err := f.service.Exchange(ctx, &req)
+			assert.ErrorIs(t, err, ErrInvalidOAuthGrant)
+		})
+	}
+}
+
+func TestOAuthProviderService_RevokeAuthorization(t *testing.T) {
+	f := newOAuthProviderTest(t)
+	ctx := context.Background()
+	tokens, err := f.service.Exchange(ctx, &models.OAuthTokenRequest{
+		GrantType:    "authorization_code",
+		Code:         f.authorize(t, "identify", ""),
+		RedirectURI:  "https://bot.example.com/callback",
+		ClientID:     f.app.ID.String(),
+		ClientSecret: f.app.ClientSecret,
+	})
+	require.NoError(t, err, "PKCE is optional for confidential clients")
+
+	apps, err := f.service.ListAuthorizedApplications(ctx, f.userID)
+	require.NoError(t, err)
+	require.Len(t, apps, 1)
+	assert.Equal(t, f.app.ID, apps[0].Application.ID)
+
+	require.NoError(t, f.service.RevokeAuthorization(ctx, f.userID, f.app.ID))
+	_, err = f.service.AuthenticateOAuthToken(ctx, tokens.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidOAuthToken)
+	assert.ErrorIs(t, f.service.RevokeAuthorization(ctx, f.userID, f.app.ID), ErrOAuthAppNotFound)
+}
+
+func TestOAuthProviderService_ExchangeWithDefaultRedirect(t *testing.T) {
+	f := newOAuthProviderTest(t)
+	ctx := context.Background()
+	app, err := f.service.CreateApplication(ctx, f.ownerID, &models.CreateOAuthApplicationRequest{
+		Name: "Single", RedirectURIs: []string{"https://single.example.com/callback"},
+	})
+	require.NoError(t, err)
+
+	location, err := f.service.Authorize(ctx, f.userID, &models.OAuthAuthorizeRequest{
+		ClientID: app.ID.String(), ResponseType: "code", Scope: "identify",
+	})
+	require.NoError(t, err)
+	redirect, err := url.Parse(location)
+	require.NoError(t, err)
+	assert.Equal(t, "single.example.com", redirect.Host)
+
+	// Authorizing without redirect_uri means the exchange needn't send one
+	_, err = f.service.Exchange(ctx, &models.OAuthTokenRequest{
+		GrantType:    "authorization_code",
+		Code:         redirect.Query().Get("code"),
+		ClientID:     app.ID.String(),
+		ClientSecret: app.ClientSecret,
+	})
+	assert.NoError(t, err)
+}
+
+func TestOAuthProviderService_RevokeAllAuthorizations(t *testing.T) {
+	f := newOAuthProviderTest(t)
+	ctx := context.Background()
+	tokens, err := f.service.Exchange(ctx, &models.OAuthTokenRequest{
+		GrantType:    "authorization_code",
+		Code:         f.authorize(t, "identify", ""),
+		RedirectURI:  "https://bot.example.com/callback",
+		ClientID:     f.app.ID.String(),
+		ClientSecret: f.app.ClientSecret,
+	})
+	require.NoError(t, err)
+	pending := f.authorize(t, "identify", "")
+
+	require.NoError(t, f.service.RevokeAllAuthorizations(ctx, f.userID))
+
+	_, err = f.service.AuthenticateOAuthToken(ctx, tokens.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidOAuthToken)
+	_, err = f.service.Exchange(ctx, &models.OAuthTokenRequest{
+		GrantType:    "authorization_code",
+		Code:         pending,
+		RedirectURI:  "https://bot.example.com/callback",
+		ClientID:     f.app.ID.String(),
+		ClientSecret: f.app.ClientSecret,
+	})
+	assert.ErrorIs(t, err, ErrInvalidOAuthGrant, "codes not yet exchanged are revoked too")
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// objectCacheTTL bounds how stale a cached server, channel, member or role
+// list can get if an invalidation is missed
+const objectCacheTTL = 5 * time.Minute
+
+// CacheRecorder counts read-through cache lookups by the kind of object
+// looked up
+type CacheRecorder interface {
+	CacheLookup(object string, hit bool)
+}
+
+// ObservedCache is a CacheService that records whether each lookup hit
+type ObservedCache struct {
+	CacheService
+	recorder CacheRecorder
+}
+
+// NewObservedCache wraps cache so its lookups are recorded
+func NewObservedCache(cache CacheService, recorder CacheRecorder) *ObservedCache {
+	return &ObservedCache{CacheService: cache, recorder: recorder}
+}
+
+func (c *ObservedCache) GetUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	user, err := c.CacheService.GetUser(ctx, id)
+	c.recorder.CacheLookup("user", err == nil && user != nil)
+	return user, err
+}
+
+func (c *ObservedCache) GetServer(ctx context.Context, id uuid.UUID) (*models.Server, error) {
+	server, err := c.CacheService.GetServer(ctx, id)
+	c.recorder.CacheLookup("server", err == nil && server != nil)
+	return server, err
+}
+
+func (c *ObservedCache) GetChannel(ctx context.Context, id uuid.UUID) (*models.Channel, error) {
+	channel, err := c.CacheService.GetChannel(ctx, id)
+	c.recorder.CacheLookup("channel", err == nil && channel != nil)
+	return channel, err
+}
+
+// Get records lookups by the key's first segment, e.g. "member" for
+// "member:<server>:<user>"
+func (c *ObservedCache) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := c.CacheService.Get(ctx, key)
+	object, _, _ := strings.Cut(key, ":")
+	c.recorder.CacheLookup(object, err == nil && data != nil)
+	return data, err
+}
+
+// cachedServer reads a server through the cache. Cache errors are treated
+// as misses.
+func cachedServer(ctx context.Context, cache CacheService, repo ServerRepository, id uuid.UUID) (*models.Server, error) {
+	if cache != nil {
+		if server, err := cache.GetServer(ctx, id); err == nil && server != nil {
+			return server, nil
+		}
+	}
+	server, err := repo.GetByID(ctx, id)
+	if err != nil || server == nil {
+		return server, err
+	}
+	if cache != nil {
+		_ = cache.SetServer(ctx, server, objectCacheTTL)
+	}
+	return server, nil
+}
+
+// cachedChannel reads a channel through the cache
+func cachedChannel(ctx context.Context, cache CacheService, repo interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Channel, error)
+}, id uuid.UUID) (*models.Channel, error) {
+	if cache != nil {
+		if channel, err := cache.GetChannel(ctx, id); err == nil && channel != nil {
+			return channel, nil
+		}
+	}
+	channel, err := repo.GetByID(ctx, id)
+	if err != nil || channel == nil {
+		return channel, err
+	}
+	if cache != nil {
+		_ = cache.SetChannel(ctx, channel, objectCacheTTL)
+	}
+	return channel, nil
+}
+
+// cachedMember reads a server member through the cache. Users who aren't
+// members aren't cached, so joining takes effect straight away.
+func cachedMember(ctx context.Context, cache CacheService, repo ServerRepository, serverID, userID uuid.UUID) (*models.Member, error) {
+	key := memberCacheKey(serverID, userID)
+	var member *models.Member
+	if readCached(ctx, cache, key, &member) && member != nil {
+		return member, nil
+	}
+	member, err := repo.GetMember(ctx, serverID, userID)
+	if err != nil || member == nil {
+		return member, err
+	}
+	writeCached(ctx, cache, key, member)
+	return member, nil
+}
+
+// cachedRoles reads a server's roles through the cache
+func cachedRoles(ctx context.Context, cache CacheService, repo RoleRepository, serverID uuid.UUID) ([]*models.Role, error) {
+	key := rolesCacheKey(serverID)
+	var roles []*models.Role
+	if readCached(ctx, cache, key, &roles) {
+		return roles, nil
+	}
+	roles, err := repo.GetByServerID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	writeCached(ctx, cache, key, roles)
+	return roles, nil
+}
+
+func readCached(ctx context.Context, cache CacheService, key string, v interface{}) bool {
+	if cache == nil {
+		return false
+	}
+	data, err := cache.Get(ctx, key)
+	if err != nil || data == nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+func writeCached(ctx context.Context, cache CacheService, key string, v interface{}) {
+	if cache == nil {
+		return
+	}
+	if data, err := json.Marshal(v); err == nil {
+		_ = cache.Set(ctx, key, data, objectCacheTTL)
+	}
+}
+
+func forgetServer(ctx context.Context, cache CacheService, id uuid.UUID) {
+	if cache != nil {
+		_ = cache.DeleteServer(ctx, id)
+	}
+}
+
+func forgetMember(ctx context.Context, cache CacheService, serverID, userID uuid.UUID) {
+	if cache != nil {
+		_ = cache.Delete(ctx, memberCacheKey(serverID, userID))
+	}
+}
+
+func forgetRoles(ctx context.Context, cache CacheService, serverID uuid.UUID) {
+	if cache != nil {
+		_ = cache.Delete(ctx, rolesCacheKey(serverID))
+	}
+}
+
+func memberCacheKey(serverID, userID uuid.UUID) string {
+	return "member:" + serverID.String() + ":" + userID.String()
+}
+
+func rolesCacheKey(serverID uuid.UUID) string {
+	return "roles:" + serverID.String()
+}
+
+// cacheInvalidateTimeout bounds each cache delete made for an event
+const cacheInvalidateTimeout = 2 * time.Second
+
+// CacheInvalidator drops cached objects when events say they've changed.
+// Services drop what they change themselves; this catches changes made
+// elsewhere, e.g. by moderation, onboarding or ownership transfers.
+type CacheInvalidator struct {
+	cache CacheService
+}
+
+// NewCacheInvalidator creates an invalidator for cache
+func NewCacheInvalidator(cache CacheService) *CacheInvalidator {
+	return &CacheInvalidator{cache: cache}
+}
+
+// Start subscribes the invalidator to the events that change cached
+// objects
+func (i *CacheInvalidator) Start(eventBus EventBus) {
+	eventBus.Subscribe("user.updated", i.handleUserChanged)
+
+	eventBus.Subscribe("server.updated", i.handleServerChanged)
+	eventBus.Subscribe("server.deleted", i.handleServerChanged)
+	eventBus.Subscribe("server.ownership_transferred", i.handleServerChanged)
+
+	eventBus.Subscribe("channel.updated", i.handleChannelChanged)
+	eventBus.Subscribe("channel.deleted", i.handleChannelChanged)
+
+	eventBus.Subscribe("role.created", i.handleRolesChanged)
+	eventBus.Subscribe("role.updated", i.handleRolesChanged)
+	eventBus.Subscribe("role.deleted", i.handleRolesChanged)
+
+	eventBus.Subscribe("member.role_added", i.handleMemberChanged)
+	eventBus.Subscribe("member.role_removed", i.handleMemberChanged)
+	eventBus.Subscribe("server.member_left", i.handleMemberChanged)
+	eventBus.Subscribe("server.member_kicked", i.handleMemberChanged)
+	eventBus.Subscribe("server.member_banned", i.handleMemberChanged)
+	eventBus.Subscribe("server.member_updated", i.handleMemberChanged)
+}
+
+func (i *CacheInvalidator) handleUserChanged(data interface{}) {
+	if event, ok := data.(*UserUpdatedEvent); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), cacheInvalidateTimeout)
+		defer cancel()
+		_ = i.cache.DeleteUser(ctx, event.UserID)
+	}
+}
+
+func (i *CacheInvalidator) handleServerChanged(data interface{}) {
+	var serverID uuid.UUID
+	switch event := data.(type) {
+	case *ServerUpdatedEvent:
+		if event.Server != nil {
+			serverID = event.Server.ID
+		}
+	case *ServerDeletedEvent:
+		serverID = event.ServerID
+	case *OwnershipTransferredEvent:
+		serverID = event.ServerID
+	}
+	if serverID != uuid.Nil {
+		ctx, cancel := context.WithTimeout(context.Background(), cacheInvalidateTimeout)
+		defer cancel()
+		forgetServer(ctx, i.cache, serverID)
+		forgetRoles(ctx, i.cache, serverID)
+	}
+}
+
+func (i *CacheInvalidator) handleChannelChanged(data interface{}) {
+	var channelID uuid.UUID
+	switch event := data.(type) {
+	case *ChannelUpdatedEvent:
+		if event.Channel != nil {
+			channelID = event.Channel.ID
+		}
+	case *ChannelDeletedEvent:
+		channelID = event.ChannelID
+	}
+	if channelID != uuid.Nil {
+		ctx, cancel := context.WithTimeout(context.Background(), cacheInvalidateTimeout)
+		defer cancel()
+		_ = i.cache.DeleteChannel(ctx, channelID)
+	}
+}
+
+func (i *CacheInvalidator) handleRolesChanged(data interface{}) {
+	var serverID uuid.UUID
+	switch event := data.(type) {
+	case *RoleCreatedEvent:
+		serverID = event.ServerID
+	case *RoleUpdatedEvent:
+		if event.Role != nil {
+			serverID = event.Role.ServerID
+		}
+	case *RoleDeletedEvent:
+		serverID = event.ServerID
+	}
+	if serverID != uuid.Nil {
+		ctx, cancel := context.WithTimeout(context.Background(), cacheInvalidateTimeout)
+		defer cancel()
+		forgetRoles(ctx, i.cache, serverID)
+	}
+}
+
+func (i *CacheInvalidator) handleMemberChanged(data interface{}) {
+	var serverID, userID uuid.UUID
+	switch event := data.(type) {
+	case *MemberRoleAddedEvent:
+		serverID, userID = event.ServerID, event.UserID
+	case *MemberRoleRemovedEvent:
+		serverID, userID = event.ServerID, event.UserID
+	case *MemberLeftEvent:
+		serverID, userID = event.ServerID, event.UserID
+	case *MemberKickedEvent:
+		serverID, userID = event.ServerID, event.UserID
+	case *MemberBannedEvent:
+		serverID, userID = event.ServerID, event.UserID
+	case *MemberUpdatedEvent:
+		serverID, userID = event.ServerID, event.UserID
+	}
+	if serverID != uuid.Nil && userID != uuid.Nil {
+		ctx, cancel := context.WithTimeout(context.Background(), cacheInvalidateTimeout)
+		defer cancel()
+		forgetMember(ctx, i.cache, serverID, userID)
+	}
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+// lookupCounts records cache lookups by object
+type lookupCounts map[string][2]int
+
+func (c lookupCounts) CacheLookup(object string, hit bool) {
+	counts := c[object]
+	if hit {
+		counts[0]++
+	} else {
+		counts[1]++
+	}
+	c[object] = counts
+}
+
+func TestGetServer_ReadsThroughCache(t *testing.T) {
+	service, serverRepo, _, _, _, _ := newTestServerService()
+	cache := new(MockCacheService)
+	service.cache = cache
+	ctx := context.Background()
+	server := &models.Server{ID: uuid.New(), Name: "cached"}
+
+	cache.On("GetServer", ctx, server.ID).Return(nil, nil).Once()
+	serverRepo.On("GetByID", ctx, server.ID).Return(server, nil).Once()
+	cache.On("SetServer", ctx, server, objectCacheTTL).Return(nil).Once()
+
+	got, err := service.GetServer(ctx, server.ID)
+	require.NoError(t, err)
+	assert.Equal(t, server, got)
+
+	cache.On("GetServer", ctx, server.ID).Return(server, nil).Once()
+	got, err = service.GetServer(ctx, server.ID)
+	require.NoError(t, err)
+	assert.Equal(t, server, got)
+
+	serverRepo.AssertNumberOfCalls(t, "GetByID", 1)
+	cache.AssertExpectations(t)
+}
+
+func TestGetMember_ReadsThroughCache(t *testing.T) {
+	service, serverRepo, _, _, _, _ := newTestServerService()
+	cache := new(MockCacheService)
+	service.cache = cache
+	ctx := context.Background()
+	serverID, userID := uuid.New(), uuid.New()
+	member := &models.Member{ServerID: serverID, UserID: userID, Roles: []uuid.UUID{uuid.New()}}
+	data, err := json.Marshal(member)
+	require.NoError(t, err)
+	key := "member:" + serverID.String() + ":" + userID.String()
+
+	cache.On("Get", ctx, key).Return(data, nil).Once()
+	got, err := service.GetMember(ctx, serverID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, member.Roles, got.Roles)
+	serverRepo.AssertNotCalled(t, "GetMember", ctx, serverID, userID)
+
+	// Users who aren't members aren't cached
+	cache.On("Get", ctx, key).Return(nil, nil).Once()
+	serverRepo.On("GetMember", ctx, serverID, userID).Return(nil, nil).Once()
+	_, err = service.GetMember(ctx, serverID, userID)
+	assert.Equal(t, ErrNotServerMember, err)
+	cache.AssertNotCalled(t, "Set", ctx, key, mock.Anything, objectCacheTTL)
+}
+
+func TestRoleChanges_DropCachedRoles(t *testing.T) {
+	service, roleRepo, serverRepo, _, eventBus := newTestRoleService()
+	cache := new(MockCacheService)
+	service.cache = cache
+	ctx := context.Background()
+	serverID, userID := uuid.New(), uuid.New()
+
+	serverRepo.On("GetMember", ctx, serverID, userID).Return(&models.Member{ServerID: serverID, UserID: userID}, nil)
+	roleRepo.On("GetByServerID", ctx, serverID).Return([]*models.Role{}, nil)
+	roleRepo.On("Create", ctx, mock.Anything).Return(nil)
+	eventBus.On("Publish", "role.created", mock.Anything).Return()
+	cache.On("Delete", ctx, "roles:"+serverID.String()).Return(nil).Once()
+
+	_, err := service.CreateRole(ctx, serverID, userID, "mods", 0, 0)
+	require.NoError(t, err)
+	cache.AssertExpectations(t)
+}
+
+func TestObservedCache_RecordsLookups(t *testing.T) {
+	cache := new(MockCacheService)
+	counts := lookupCounts{}
+	observed := NewObservedCache(cache, counts)
+	ctx := context.Background()
+	hit, miss := uuid.New(), uuid.New()
+
+	cache.On("GetChannel", ctx, hit).Return(&models.Channel{ID: hit}, nil)
+	cache.On("GetChannel", ctx, miss).Return(nil, assert.AnError)
+	cache.On("Get", ctx, "roles:"+hit.String()).Return([]byte("[]"), nil)
+
+	_, _ = observed.GetChannel(ctx, hit)
+	_, _ = observed.GetChannel(ctx, miss)
+	_, _ = observed.Get(ctx, "roles:"+hit.String())
+
+	assert.Equal(t, [2]int{1, 1}, counts["channel"])
+	assert.Equal(t, [2]int{1, 0}, counts["roles"])
+}
+
+func TestCacheInvalidator(t *testing.T) {
+	cache := new(MockCacheService)
+	invalidator := NewCacheInvalidator(cache)
+	serverID, userID, channelID := uuid.New(), uuid.New(), uuid.New()
+
+	cache.On("DeleteServer", mock.Anything, serverID).Return(nil).Once()
+	cache.On("Delete", mock.Anything, "roles:"+serverID.String()).Return(nil).Twice()
+	cache.On("Delete", mock.Anything, "member:"+serverID.String()+":"+userID.String()).Return(nil).Once()
+	cache.On("DeleteChannel", mock.Anything, channelID).Return(nil).Once()
+	cache.On("DeleteUser", mock.Anything, userID).Return(nil).Once()
+
+	invalidator.handleServerChanged(&OwnershipTransferredEvent{ServerID: serverID, NewOwnerID: userID})
+	invalidator.handleRolesChanged(&RoleUpdatedEvent{Role: &models.Role{ServerID: serverID}})
+	invalidator.handleMemberChanged(&MemberUpdatedEvent{ServerID: serverID, UserID: userID, Member: &models.Member{}})
+	invalidator.handleChannelChanged(&ChannelDeletedEvent{ChannelID: channelID})
+	invalidator.handleUserChanged(&UserUpdatedEvent{UserID: userID, UpdatedAt: time.Now()})
+
+	cache.AssertExpectations(t)
+}
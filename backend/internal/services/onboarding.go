@@ -0,0 +1,519 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	maxWelcomeChannels           = 5
+	maxWelcomeDescription        = 140
+	maxWelcomeChannelDescription = 50
+	maxOnboardingPrompts         = 15
+	maxOnboardingOptions         = 50 // Per prompt
+	maxOnboardingTitle           = 100
+	maxOnboardingDescription     = 100
+)
+
+// OnboardingRepository stores welcome screens, onboarding prompts and the
+// options members picked. The Postgres OnboardingRepository implements it.
+type OnboardingRepository interface {
+	// GetWelcomeScreen returns the default screen for servers without one
+	GetWelcomeScreen(ctx context.Context, serverID uuid.UUID) (*models.WelcomeScreen, error)
+	SaveWelcomeScreen(ctx context.Context, screen *models.WelcomeScreen) error
+	// GetOnboarding returns the default onboarding for servers without one
+	GetOnboarding(ctx context.Context, serverID uuid.UUID) (*models.Onboarding, error)
+	SaveOnboarding(ctx context.Context, onboarding *models.Onboarding) error
+	// GetResponses returns nil for members who never went through onboarding
+	GetResponses(ctx context.Context, serverID, userID uuid.UUID) (*models.OnboardingResponses, error)
+	// SaveResponses stores the member's picks, taking away the roles in
+	// remove and giving them responses.RoleIDs in one go. It reports false
+	// if they aren't a member.
+	SaveResponses(ctx context.Context, responses *models.OnboardingResponses, remove []uuid.UUID) (bool, error)
+}
+
+// WelcomeScreens gives the ServerService the welcome screen to show
+// members who join a server
+type WelcomeScreens interface {
+	// JoinWelcomeScreen returns nil if the server's welcome screen is off
+	JoinWelcomeScreen(ctx context.Context, serverID uuid.UUID) (*models.WelcomeScreen, error)
+}
+
+// SetWelcomeScreens returns servers' welcome screens to members as they
+// join
+func (s *ServerService) SetWelcomeScreens(screens WelcomeScreens) {
+	s.welcome = screens
+}
+
+// OnboardingService handles what members see as they join a server: its
+// welcome screen, which points them to a few channels, and onboarding
+// prompts, which let them pick roles and channels for themselves.
+type OnboardingService struct {
+	repo        OnboardingRepository
+	serverRepo  ServerRepository
+	channelRepo ChannelRepository
+	roleRepo    RoleRepository
+	eventBus    EventBus
+}
+
+// NewOnboardingService creates a new onboarding service
+func NewOnboardingService(
+	repo OnboardingRepository,
+	serverRepo ServerRepository,
+	channelRepo ChannelRepository,
+	roleRepo RoleRepository,
+	eventBus EventBus,
+) *OnboardingService {
+	return &OnboardingService{
+		repo:        repo,
+		serverRepo:  serverRepo,
+		channelRepo: channelRepo,
+		roleRepo:    roleRepo,
+		eventBus:    eventBus,
+	}
+}
+
+// GetWelcomeScreen returns a server's welcome screen. Any member can read
+// it.
+func (s *OnboardingService) GetWelcomeScreen(ctx context.Context, serverID, requesterID uuid.UUID) (*models.WelcomeScreen, error) {
+	if err := s.checkMember(ctx, serverID, requesterID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetWelcomeScreen(ctx, serverID)
+}
+
+// UpdateWelcomeScreen changes a server's welcome screen. Requires
+// MANAGE_SERVER.
+func (s *OnboardingService) UpdateWelcomeScreen(ctx context.Context, serverID, requesterID uuid.UUID, req *models.UpdateWelcomeScreenRequest) (*models.WelcomeScreen, error) {
+	server, err := s.authorize(ctx, serverID, requesterID, models.PermManageServer)
+	if err != nil {
+		return nil, err
+	}
+
+	screen, err := s.repo.GetWelcomeScreen(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Enabled != nil {
+		screen.Enabled = *req.Enabled
+	}
+	if req.Description != nil {
+		screen.Description = nil
+		if description := strings.TrimSpace(*req.Description); description != "" {
+			screen.Description = &description
+		}
+	}
+	if req.WelcomeChannels != nil {
+		screen.WelcomeChannels = *req.WelcomeChannels
+	}
+	channels, err := s.serverChannels(ctx, server.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := normalizeWelcomeScreen(screen, channels); err != nil {
+		return nil, err
+	}
+
+	screen.UpdatedAt = time.Now()
+	if err := s.repo.SaveWelcomeScreen(ctx, screen); err != nil {
+		return nil, err
+	}
+	return screen, nil
+}
+
+// JoinWelcomeScreen returns the welcome screen for a member who just
+// joined, or nil if the server's is off
+func (s *OnboardingService) JoinWelcomeScreen(ctx context.Context, serverID uuid.UUID) (*models.WelcomeScreen, error) {
+	screen, err := s.repo.GetWelcomeScreen(ctx, serverID)
+	if err != nil || !screen.Enabled {
+		return nil, err
+	}
+	return screen, nil
+}
+
+// GetOnboarding returns a server's onboarding prompts. Any member can read
+// them.
+func (s *OnboardingService) GetOnboarding(ctx context.Context, serverID, requesterID uuid.UUID) (*models.Onboarding, error) {
+	if err := s.checkMember(ctx, serverID, requesterID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetOnboarding(ctx, serverID)
+}
+
+// UpdateOnboarding changes a server's onboarding prompts. Requires
+// MANAGE_SERVER and MANAGE_ROLES, and every role offered must sit below
+// the requester's highest role. Members keep the roles they already
+// picked.
+func (s *OnboardingService) UpdateOnboarding(ctx context.Context, serverID, requesterID uuid.UUID, req *models.UpdateOnboardingRequest) (*models.Onboarding, error) {
+	server, err := s.authorize(ctx, serverID, requesterID, models.PermManageServer, models.PermManageRoles)
+	if err != nil {
+		return nil, err
+	}
+
+	onboarding, err := s.repo.GetOnboarding(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Enabled != nil {
+		onboarding.Enabled = *req.Enabled
+	}
+	if req.Prompts != nil {
+		onboarding.Prompts = *req.Prompts
+	}
+
+	channels, err := s.serverChannels(ctx, server.ID)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.assignableRoles(ctx, server, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	if err := normalizeOnboarding(onboarding, roles, channels); err != nil {
+		return nil, err
+	}
+
+	onboarding.UpdatedAt = time.Now()
+	if err := s.repo.SaveOnboarding(ctx, onboarding); err != nil {
+		return nil, err
+	}
+	return onboarding, nil
+}
+
+// GetResponses returns the options the requester picked, which are empty
+// if they never went through onboarding
+func (s *OnboardingService) GetResponses(ctx context.Context, serverID, userID uuid.UUID) (*models.OnboardingResponses, error) {
+	if err := s.checkMember(ctx, serverID, userID); err != nil {
+		return nil, err
+	}
+	responses, err := s.repo.GetResponses(ctx, serverID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if responses == nil {
+		responses = &models.OnboardingResponses{
+			ServerID:   serverID,
+			UserID:     userID,
+			OptionIDs:  []uuid.UUID{},
+			RoleIDs:    []uuid.UUID{},
+			ChannelIDs: []uuid.UUID{},
+		}
+	}
+	return responses, nil
+}
+
+// Submit records the options a member picked and gives them those
+// options' roles, taking away roles from options they picked before and
+// no longer do. Roles and picks change together or not at all.
+func (s *OnboardingService) Submit(ctx context.Context, serverID, userID uuid.UUID, req *models.SubmitOnboardingRequest) (*models.Member, error) {
+	member, err := s.serverRepo.GetMember(ctx, serverID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrNotServerMember
+	}
+
+	onboarding, err := s.repo.GetOnboarding(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if !onboarding.Enabled {
+		return nil, ErrOnboardingDisabled
+	}
+	responses, err := onboardingResponses(onboarding, req.OptionIDs)
+	if err != nil {
+		return nil, err
+	}
+	responses.ServerID, responses.UserID = serverID, userID
+	responses.UpdatedAt = time.Now()
+
+	// Roles deleted since onboarding was set up can't be given out
+	serverRoles, err := s.roleRepo.GetByServerID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	responses.RoleIDs = keepIDs(responses.RoleIDs, roleIDs(serverRoles))
+
+	previous, err := s.repo.GetResponses(ctx, serverID, userID)
+	if err != nil {
+		return nil, err
+	}
+	var remove []uuid.UUID
+	if previous != nil {
+		remove = dropIDs(previous.RoleIDs, responses.RoleIDs)
+	}
+
+	saved, err := s.repo.SaveResponses(ctx, responses, remove)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, ErrNotServerMember
+	}
+
+	memberRoles, err := s.roleRepo.GetMemberRoles(ctx, serverID, userID)
+	if err != nil {
+		return nil, err
+	}
+	member.Roles = roleIDs(memberRoles)
+	s.eventBus.Publish("server.member_updated", &MemberUpdatedEvent{
+		ServerID: serverID,
+		UserID:   userID,
+		Member:   member,
+	})
+	return member, nil
+}
+
+func (s *OnboardingService) checkMember(ctx context.Context, serverID, userID uuid.UUID) error {
+	member, err := s.serverRepo.GetMember(ctx, serverID, userID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return ErrNotServerMember
+	}
+	return nil
+}
+
+// authorize returns the server if the requester has every one of perms
+func (s *OnboardingService) authorize(ctx context.Context, serverID, requesterID uuid.UUID, perms ...int64) (*models.Server, error) {
+	server, err := s.serverRepo.GetByID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if server == nil {
+		return nil, ErrServerNotFound
+	}
+	for _, perm := range perms {
+		if !memberHasPermission(ctx, s.serverRepo, s.roleRepo, server, requesterID, perm) {
+			return nil, ErrCannotManageOnboarding
+		}
+	}
+	return server, nil
+}
+
+func (s *OnboardingService) serverChannels(ctx context.Context, serverID uuid.UUID) (map[uuid.UUID]bool, error) {
+	channels, err := s.channelRepo.GetByServerID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[uuid.UUID]bool, len(channels))
+	for _, channel := range channels {
+		ids[channel.ID] = true
+	}
+	return ids, nil
+}
+
+// assignableRoles returns the server's roles, other than @everyone, that
+// sit below the requester's highest role
+func (s *OnboardingService) assignableRoles(ctx context.Context, server *models.Server, requesterID uuid.UUID) (map[uuid.UUID]bool, error) {
+	roles, err := s.roleRepo.GetByServerID(ctx, server.ID)
+	if err != nil {
+		return nil, err
+	}
+	top, err := memberPosition(ctx, s.serverRepo, s.roleRepo, server, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[uuid.UUID]bool, len(roles))
+	for _, role := range roles {
+		if role.IsDefault || role.ID == server.ID {
+			continue
+		}
+		ids[role.ID] = role.Position < top
+	}
+	return ids, nil
+}
+
+func invalidWelcomeScreen(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidWelcomeScreen, fmt.Sprintf(format, args...))
+}
+
+func invalidOnboarding(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidOnboarding, fmt.Sprintf(format, args...))
+}
+
+// normalizeWelcomeScreen trims and checks a welcome screen before it's
+// saved. channels are the server's channels.
+func normalizeWelcomeScreen(screen *models.WelcomeScreen, channels map[uuid.UUID]bool) error {
+	if screen.Description != nil && utf8.RuneCountInString(*screen.Description) > maxWelcomeDescription {
+		return invalidWelcomeScreen("description can be at most %d characters", maxWelcomeDescription)
+	}
+	if len(screen.WelcomeChannels) > maxWelcomeChannels {
+		return invalidWelcomeScreen("at most %d welcome channels", maxWelcomeChannels)
+	}
+	if screen.WelcomeChannels == nil {
+		screen.WelcomeChannels = []models.WelcomeChannel{}
+	}
+
+	seen := make(map[uuid.UUID]bool, len(screen.WelcomeChannels))
+	for i := range screen.WelcomeChannels {
+		channel := &screen.WelcomeChannels[i]
+		if !channels[channel.ChannelID] {
+			return invalidWelcomeScreen("channel %s isn't in this server", channel.ChannelID)
+		}
+		if seen[channel.ChannelID] {
+			return invalidWelcomeScreen("channel %s is listed twice", channel.ChannelID)
+		}
+		seen[channel.ChannelID] = true
+		channel.Description = strings.TrimSpace(channel.Description)
+		if channel.Description == "" || utf8.RuneCountInString(channel.Description) > maxWelcomeChannelDescription {
+			return invalidWelcomeScreen("channel descriptions must be 1-%d characters", maxWelcomeChannelDescription)
+		}
+	}
+	if screen.Enabled && screen.Description == nil && len(screen.WelcomeChannels) == 0 {
+		return invalidWelcomeScreen("a welcome screen needs a description or channels")
+	}
+	return nil
+}
+
+// normalizeOnboarding trims and checks prompts before they're saved and
+// gives new prompts and options IDs. roles maps the server's roles to
+// whether the requester may hand them out; channels are the server's
+// channels.
+func normalizeOnboarding(onboarding *models.Onboarding, roles, channels map[uuid.UUID]bool) error {
+	if len(onboarding.Prompts) > maxOnboardingPrompts {
+		return invalidOnboarding("at most %d prompts", maxOnboardingPrompts)
+	}
+	if onboarding.Prompts == nil {
+		onboarding.Prompts = []models.OnboardingPrompt{}
+	}
+	if onboarding.Enabled && len(onboarding.Prompts) == 0 {
+		return invalidOnboarding("onboarding needs a prompt")
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	newID := func(id *uuid.UUID) error {
+		if *id == uuid.Nil {
+			*id = uuid.New()
+		}
+		if seen[*id] {
+			return invalidOnboarding("id %s is used twice", *id)
+		}
+		seen[*id] = true
+		return nil
+	}
+	for i := range onboarding.Prompts {
+		prompt := &onboarding.Prompts[i]
+		if err := newID(&prompt.ID); err != nil {
+			return err
+		}
+		prompt.Title = strings.TrimSpace(prompt.Title)
+		if prompt.Title == "" || utf8.RuneCountInString(prompt.Title) > maxOnboardingTitle {
+			return invalidOnboarding("titles must be 1-%d characters", maxOnboardingTitle)
+		}
+		if len(prompt.Options) == 0 || len(prompt.Options) > maxOnboardingOptions {
+			return invalidOnboarding("prompts need 1-%d options", maxOnboardingOptions)
+		}
+
+		for j := range prompt.Options {
+			option := &prompt.Options[j]
+			if err := newID(&option.ID); err != nil {
+				return err
+			}
+			option.Title = strings.TrimSpace(option.Title)
+			if option.Title == "" || utf8.RuneCountInString(option.Title) > maxOnboardingTitle {
+				return invalidOnboarding("titles must be 1-%d characters", maxOnboardingTitle)
+			}
+			if option.Description != nil {
+				description := strings.TrimSpace(*option.Description)
+				option.Description = nil
+				if utf8.RuneCountInString(description) > maxOnboardingDescription {
+					return invalidOnboarding("option descriptions can be at most %d characters", maxOnboardingDescription)
+				}
+				if description != "" {
+					option.Description = &description
+				}
+			}
+
+			option.RoleIDs = uniqueIDs(option.RoleIDs)
+			for _, roleID := range option.RoleIDs {
+				assignable, ok := roles[roleID]
+				if !ok {
+					return invalidOnboarding("role %s isn't in this server", roleID)
+				}
+				if !assignable {
+					return fmt.Errorf("%w: role %s", ErrCannotManageOnboarding, roleID)
+				}
+			}
+			option.ChannelIDs = uniqueIDs(option.ChannelIDs)
+			for _, channelID := range option.ChannelIDs {
+				if !channels[channelID] {
+					return invalidOnboarding("channel %s isn't in this server", channelID)
+				}
+			}
+			if len(option.RoleIDs) == 0 && len(option.ChannelIDs) == 0 {
+				return invalidOnboarding("options need a role or a channel")
+			}
+		}
+	}
+	return nil
+}
+
+// onboardingResponses checks a member's picks against the prompts and
+// collects the roles and channels they lead to
+func onboardingResponses(onboarding *models.Onboarding, optionIDs []uuid.UUID) (*models.OnboardingResponses, error) {
+	responses := &models.OnboardingResponses{
+		OptionIDs:  uniqueIDs(optionIDs),
+		RoleIDs:    []uuid.UUID{},
+		ChannelIDs: []uuid.UUID{},
+	}
+	picked := make(map[uuid.UUID]int)
+	for _, optionID := range responses.OptionIDs {
+		prompt, option := onboarding.Option(optionID)
+		if option == nil {
+			return nil, fmt.Errorf("%w: unknown option %s", ErrInvalidOnboardingResponse, optionID)
+		}
+		if picked[prompt.ID]++; prompt.SingleSelect && picked[prompt.ID] > 1 {
+			return nil, fmt.Errorf("%w: pick one option for %q", ErrInvalidOnboardingResponse, prompt.Title)
+		}
+		responses.RoleIDs = append(responses.RoleIDs, option.RoleIDs...)
+		responses.ChannelIDs = append(responses.ChannelIDs, option.ChannelIDs...)
+	}
+	for _, prompt := range onboarding.Prompts {
+		if prompt.Required && picked[prompt.ID] == 0 {
+			return nil, fmt.Errorf("%w: %q needs an answer", ErrInvalidOnboardingResponse, prompt.Title)
+		}
+	}
+	responses.RoleIDs = uniqueIDs(responses.RoleIDs)
+	responses.ChannelIDs = uniqueIDs(responses.ChannelIDs)
+	return responses, nil
+}
+
+// keepIDs returns the ids that are also in allowed
+func keepIDs(ids, allowed []uuid.UUID) []uuid.UUID {
+	set := make(map[uuid.UUID]bool, len(allowed))
+	for _, id := range allowed {
+		set[id] = true
+	}
+	kept := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if set[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// dropIDs returns the ids that aren't in drop
+func dropIDs(ids, drop []uuid.UUID) []uuid.UUID {
+	set := make(map[uuid.UUID]bool, len(drop))
+	for _, id := range drop {
+		set[id] = true
+	}
+	var kept []uuid.UUID
+	for _, id := range ids {
+		if !set[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeOnboardingRepository struct {
+	screens     map[uuid.UUID]*models.WelcomeScreen
+	onboardings map[uuid.UUID]*models.Onboarding
+	responses   map[uuid.UUID]*models.OnboardingResponses
+	// memberRoles are every member's roles, as SaveResponses leaves them
+	memberRoles map[uuid.UUID][]uuid.UUID
+}
+
+func newFakeOnboardingRepository() *fakeOnboardingRepository {
+	return &fakeOnboardingRepository{
+		screens:     make(map[uuid.UUID]*models.WelcomeScreen),
+		onboardings: make(map[uuid.UUID]*models.Onboarding),
+		responses:   make(map[uuid.UUID]*models.OnboardingResponses),
+		memberRoles: make(map[uuid.UUID][]uuid.UUID),
+	}
+}
+
+func (f *fakeOnboardingRepository) GetWelcomeScreen(ctx context.Context, serverID uuid.UUID) (*models.WelcomeScreen, error) {
+	if screen, ok := f.screens[serverID]; ok {
+		copied := *screen
+		return &copied, nil
+	}
+	return models.DefaultWelcomeScreen(serverID), nil
+}
+
+func (f *fakeOnboardingRepository) SaveWelcomeScreen(ctx context.Context, screen *models.WelcomeScreen) error {
+	f.screens[screen.ServerID] = screen
+	return nil
+}
+
+func (f *fakeOnboardingRepository) GetOnboarding(ctx context.Context, serverID uuid.UUID) (*models.Onboarding, error) {
+	if onboarding, ok := f.onboardings[serverID]; ok {
+		copied := *onboarding
+		return &copied, nil
+	}
+	return models.DefaultOnboarding(serverID), nil
+}
+
+func (f *fakeOnboardingRepository) SaveOnboarding(ctx context.Context, onboarding *models.Onboarding) error {
+	f.onboardings[onboarding.ServerID] = onboarding
+	return nil
+}
+
+func (f *fakeOnboardingRepository) GetResponses(ctx context.Context, serverID, userID uuid.UUID) (*models.OnboardingResponses, error) {
+	return f.responses[userID], nil
+}
+
+func (f *fakeOnboardingRepository) SaveResponses(ctx context.Context, responses *models.OnboardingResponses, remove []uuid.UUID) (bool, error) {
+	roles := append(dropIDs(f.memberRoles[responses.UserID], remove), responses.RoleIDs...)
+	f.memberRoles[responses.UserID] = uniqueIDs(roles)
+	f.responses[responses.UserID] = responses
+	return true, nil
+}
+
+// onboardingRoles serves the server's roles from roles, and members' roles
+// from what the fake onboarding repository left them with
+type onboardingRoles struct {
+	*MockRoleRepository
+	roles       []*models.Role
+	memberRoles map[uuid.UUID][]uuid.UUID
+}
+
+func (r *onboardingRoles) GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*models.Role, error) {
+	return r.roles, nil
+}
+
+func (r *onboardingRoles) GetMemberRoles(ctx context.Context, serverID, userID uuid.UUID) ([]*models.Role, error) {
+	var roles []*models.Role
+	for _, id := range r.memberRoles[userID] {
+		role := &models.Role{ID: id}
+		for _, known := range r.roles {
+			if known.ID == id {
+				role = known
+			}
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+type onboardingTest struct {
+	service    *OnboardingService
+	repo       *fakeOnboardingRepository
+	serverRepo *MockServerRepository
+	roleRepo   *onboardingRoles
+	eventBus   *MockEventBus
+
+	server    *models.Server
+	memberID  uuid.UUID
+	channelID uuid.UUID
+	// Roles at positions 1 and 2
+	artists, gamers *models.Role
+}
+
+func newOnboardingTest() *onboardingTest {
+	serverID := uuid.New()
+	f := &onboardingTest{
+		repo:       newFakeOnboardingRepository(),
+		serverRepo: new(MockServerRepository),
+		eventBus:   new(MockEventBus),
+		server:     &models.Server{ID: serverID, OwnerID: uuid.New()},
+		memberID:   uuid.New(),
+		channelID:  uuid.New(),
+		artists:    &models.Role{ID: uuid.New(), ServerID: serverID, Position: 1},
+		gamers:     &models.Role{ID: uuid.New(), ServerID: serverID, Position: 2},
+	}
+	everyone := &models.Role{ID: serverID, ServerID: serverID, IsDefault: true}
+	f.roleRepo = &onboardingRoles{roles: []*models.Role{everyone, f.artists, f.gamers}, memberRoles: f.repo.memberRoles}
+	channelRepo := new(MockChannelRepository)
+	f.service = NewOnboardingService(f.repo, f.serverRepo, channelRepo, f.roleRepo, f.eventBus)
+
+	channelRepo.On("GetByServerID", mock.Anything, serverID).Return([]*models.Channel{{ID: f.channelID, ServerID: &serverID}}, nil)
+	f.serverRepo.On("GetByID", mock.Anything, serverID).Return(f.server, nil)
+	f.serverRepo.On("GetMember", mock.Anything, serverID, f.memberID).Return(&models.Member{ServerID: serverID, UserID: f.memberID}, nil)
+	return f
+}
+
+// prompts sets up one single-select, required prompt offering each role
+func (f *onboardingTest) prompts(t *testing.T) *models.Onboarding {
+	enabled := true
+	onboarding, err := f.service.UpdateOnboarding(context.Background(), f.server.ID, f.server.OwnerID, &models.UpdateOnboardingRequest{
+		Enabled: &enabled,
+		Prompts: &[]models.OnboardingPrompt{{
+			Title:        " What brings you here? ",
+			SingleSelect: true,
+			Required:     true,
+			Options: []models.OnboardingOption{
+				{Title: "Art", RoleIDs: []uuid.UUID{f.artists.ID}, ChannelIDs: []uuid.UUID{f.channelID}},
+				{Title: "Games", RoleIDs: []uuid.UUID{f.gamers.ID, f.gamers.ID}},
+			},
+		}},
+	})
+	require.NoError(t, err)
+	return onboarding
+}
+
+func TestUpdateOnboarding(t *testing.T) {
+	f := newOnboardingTest()
+	onboarding := f.prompts(t)
+
+	prompt := onboarding.Prompts[0]
+	assert.Equal(t, "What brings you here?", prompt.Title)
+	assert.NotEqual(t, uuid.Nil, prompt.ID)
+	assert.NotEqual(t, uuid.Nil, prompt.Options[0].ID, "options get IDs")
+	assert.Equal(t, []uuid.UUID{f.gamers.ID}, prompt.Options[1].RoleIDs)
+
+	_, err := f.service.UpdateOnboarding(context.Background(), f.server.ID, f.memberID, &models.UpdateOnboardingRequest{})
+	assert.ErrorIs(t, err, ErrCannotManageOnboarding)
+}
+
+func TestUpdateOnboarding_Validation(t *testing.T) {
+	f := newOnboardingTest()
+	ctx := context.Background()
+	enabled := true
+	option := func(roles ...uuid.UUID) []models.OnboardingOption {
+		return []models.OnboardingOption{{Title: "Option", RoleIDs: roles}}
+	}
+
+	for name, prompts := range map[string][]models.OnboardingPrompt{
+		"no prompts":      {},
+		"no title":        {{Options: option(f.artists.ID)}},
+		"no options":      {{Title: "Pick"}},
+		"empty option":    {{Title: "Pick", Options: option()}},
+		"@everyone":       {{Title: "Pick", Options: option(f.server.ID)}},
+		"unknown role":    {{Title: "Pick", Options: option(uuid.New())}},
+		"repeated prompt": {{ID: f.artists.ID, Title: "Pick", Options: option(f.artists.ID)}, {ID: f.artists.ID, Title: "Again", Options: option(f.gamers.ID)}},
+	} {
+		prompts := prompts
+		_, err := f.service.UpdateOnboarding(ctx, f.server.ID, f.server.OwnerID, &models.UpdateOnboardingRequest{Enabled: &enabled, Prompts: &prompts})
+		assert.ErrorIs(t, err, ErrInvalidOnboarding, name)
+	}
+	assert.Empty(t, f.repo.onboardings)
+}
+
+func TestUpdateOnboarding_RoleHierarchy(t *testing.T) {
+	f := newOnboardingTest()
+	managerID := uuid.New()
+	manager := &models.Role{ID: uuid.New(), ServerID: f.server.ID, Position: 2, Permissions: models.PermManageServer | models.PermManageRoles}
+	f.serverRepo.On("GetMember", mock.Anything, f.server.ID, managerID).Return(&models.Member{ServerID: f.server.ID, UserID: managerID}, nil)
+	f.roleRepo.roles = append(f.roleRepo.roles, manager)
+	f.repo.memberRoles[managerID] = []uuid.UUID{manager.ID}
+
+	prompts := []models.OnboardingPrompt{{Title: "Pick", Options: []models.OnboardingOption{{Title: "Games", RoleIDs: []uuid.UUID{f.gamers.ID}}}}}
+	_, err := f.service.UpdateOnboarding(context.Background(), f.server.ID, managerID, &models.UpdateOnboardingRequest{Prompts: &prompts})
+	assert.ErrorIs(t, err, ErrCannotManageOnboarding, "roles level with the manager's can't be offered")
+
+	prompts[0].Options[0].RoleIDs = []uuid.UUID{f.artists.ID}
+	_, err = f.service.UpdateOnboarding(context.Background(), f.server.ID, managerID, &models.UpdateOnboardingRequest{Prompts: &prompts})
+	assert.NoError(t, err)
+}
+
+func TestSubmitOnboarding(t *testing.T) {
+	f := newOnboardingTest()
+	ctx := context.Background()
+	prompt := f.prompts(t).Prompts[0]
+	art, games := prompt.Options[0].ID, prompt.Options[1].ID
+	f.eventBus.On("Publish", "server.member_updated", mock.Anything).Return().Twice()
+	// Given by a moderator, so switching options leaves it alone
+	moderatorGiven := uuid.New()
+	f.repo.memberRoles[f.memberID] = []uuid.UUID{moderatorGiven}
+
+	member, err := f.service.Submit(ctx, f.server.ID, f.memberID, &models.SubmitOnboardingRequest{OptionIDs: []uuid.UUID{art}})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{moderatorGiven, f.artists.ID}, member.Roles)
+	responses, err := f.service.GetResponses(ctx, f.server.ID, f.memberID)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{f.channelID}, responses.ChannelIDs)
+
+	member, err = f.service.Submit(ctx, f.server.ID, f.memberID, &models.SubmitOnboardingRequest{OptionIDs: []uuid.UUID{games}})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{moderatorGiven, f.gamers.ID}, member.Roles, "the old pick's role is taken away")
+
+	for name, picks := range map[string][]uuid.UUID{
+		"unknown option":   {uuid.New()},
+		"two picks":        {art, games},
+		"missing required": {},
+	} {
+		_, err := f.service.Submit(ctx, f.server.ID, f.memberID, &models.SubmitOnboardingRequest{OptionIDs: picks})
+		assert.ErrorIs(t, err, ErrInvalidOnboardingResponse, name)
+	}
+	assert.ElementsMatch(t, []uuid.UUID{moderatorGiven, f.gamers.ID}, f.repo.memberRoles[f.memberID])
+	f.eventBus.AssertExpectations(t)
+}
+
+func TestSubmitOnboarding_Disabled(t *testing.T) {
+	f := newOnboardingTest()
+	_, err := f.service.Submit(context.Background(), f.server.ID, f.memberID, &models.SubmitOnboardingRequest{})
+	assert.ErrorIs(t, err, ErrOnboardingDisabled)
+}
+
+func TestUpdateWelcomeScreen(t *testing.T) {
+	f := newOnboardingTest()
+	ctx := context.Background()
+	enabled := true
+	description := " Say hi! "
+
+	_, err := f.service.UpdateWelcomeScreen(ctx, f.server.ID, f.server.OwnerID, &models.UpdateWelcomeScreenRequest{
+		Enabled:         &enabled,
+		WelcomeChannels: &[]models.WelcomeChannel{{ChannelID: uuid.New(), Description: "Elsewhere"}},
+	})
+	assert.ErrorIs(t, err, ErrInvalidWelcomeScreen, "channels must be in the server")
+
+	screen, err := f.service.UpdateWelcomeScreen(ctx, f.server.ID, f.server.OwnerID, &models.UpdateWelcomeScreenRequest{
+		Enabled:         &enabled,
+		Description:     &description,
+		WelcomeChannels: &[]models.WelcomeChannel{{ChannelID: f.channelID, Description: " Start here "}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Say hi!", *screen.Description)
+	assert.Equal(t, "Start here", screen.WelcomeChannels[0].Description)
+
+	joined, err := f.service.JoinWelcomeScreen(ctx, f.server.ID)
+	require.NoError(t, err)
+	require.NotNil(t, joined)
+	assert.Equal(t, screen.WelcomeChannels, joined.WelcomeChannels)
+
+	joined, err = f.service.JoinWelcomeScreen(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Nil(t, joined, "servers without a welcome screen show none")
+}
+
+func TestJoinServer_WelcomeScreen(t *testing.T) {
+	service, serverRepo, _, roleRepo, _, eventBus := newTestServerService()
+	ctx := context.Background()
+	userID := uuid.New()
+	server := &models.Server{ID: uuid.New(), Name: "Test Server"}
+	invite := &models.Invite{Code: "abc123", ServerID: server.ID}
+
+	repo := newFakeOnboardingRepository()
+	repo.screens[server.ID] = &models.WelcomeScreen{ServerID: server.ID, Enabled: true, WelcomeChannels: []models.WelcomeChannel{}, UpdatedAt: time.Now()}
+	service.SetWelcomeScreens(NewOnboardingService(repo, serverRepo, nil, roleRepo, eventBus))
+
+	serverRepo.On("GetInvite", ctx, invite.Code).Return(invite, nil)
+	serverRepo.On("GetByID", ctx, server.ID).Return(server, nil)
+	serverRepo.On("GetBan", ctx, server.ID, userID).Return(nil, nil)
+	serverRepo.On("GetMember", ctx, server.ID, userID).Return(nil, nil)
+	serverRepo.On("GetUserServers", ctx, userID).Return([]*models.Server{}, nil)
+	roleRepo.On("GetByServerID", ctx, server.ID).Return([]*models.Role{}, nil)
+	serverRepo.On("AddMember", ctx, mock.AnythingOfType("*models.Member")).Return(nil)
+	serverRepo.On("IncrementInviteUses", ctx, invite.Code).Return(nil)
+	eventBus.On("Publish", "server.member_joined", mock.Anything).Return()
+
+	joined, err := service.JoinServer(ctx, userID, invite.Code)
+	require.NoError(t, err)
+	require.NotNil(t, joined.WelcomeScreen)
+	assert.True(t, joined.WelcomeScreen.Enabled)
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// OwnershipTransferWindow is how long the new owner has to accept a transfer
+const OwnershipTransferWindow = 48 * time.Hour
+
+// OwnershipTransferRepository stores pending ownership transfers and
+// co-owners. The Postgres ServerRepository implements it.
+type OwnershipTransferRepository interface {
+	// SaveOwnershipTransfer replaces any transfer pending for the server
+	SaveOwnershipTransfer(ctx context.Context, transfer *models.OwnershipTransfer) error
+	// GetOwnershipTransfer returns nil if the server has no pending transfer
+	GetOwnershipTransfer(ctx context.Context, serverID uuid.UUID) (*models.OwnershipTransfer, error)
+	DeleteOwnershipTransfer(ctx context.Context, serverID uuid.UUID) error
+	// CompleteOwnershipTransfer hands the server over and settles both
+	// owners' co-owner flags in one go. It reports false, changing
+	// nothing, if the transfer is no longer pending or had expired by now,
+	// or the server's owner has changed.
+	CompleteOwnershipTransfer(ctx context.Context, transfer *models.OwnershipTransfer, now time.Time) (bool, error)
+	SetCoOwner(ctx context.Context, serverID, userID uuid.UUID, coOwner bool) error
+}
+
+// SetOwnershipTransferRepository enables ownership transfers and co-owners
+func (s *ServerService) SetOwnershipTransferRepository(repo OwnershipTransferRepository) {
+	s.transfers = repo
+}
+
+var errOwnershipTransfersNotConfigured = errors.New("ownership transfers are not configured")
+
+// RequestOwnershipTransfer offers the server to another member, who has
+// OwnershipTransferWindow to accept it. Nothing changes until they do. It
+// replaces any transfer already pending. Owner only.
+func (s *ServerService) RequestOwnershipTransfer(ctx context.Context, serverID, requesterID uuid.UUID, req *models.OwnershipTransferRequest) (*models.OwnershipTransfer, error) {
+	server, err := s.repo.GetByID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if server == nil {
+		return nil, ErrServerNotFound
+	}
+	if server.OwnerID != requesterID {
+		return nil, ErrNotServerOwner
+	}
+	if req.NewOwnerID == requesterID {
+		return nil, ErrSelfAction
+	}
+
+	member, err := s.repo.GetMember(ctx, serverID, req.NewOwnerID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrNotServerMember
+	}
+	if s.transfers == nil {
+		return nil, errOwnershipTransfersNotConfigured
+	}
+
+	now := time.Now()
+	transfer := &models.OwnershipTransfer{
+		ServerID:    serverID,
+		FromUserID:  requesterID,
+		ToUserID:    req.NewOwnerID,
+		KeepCoOwner: req.KeepCoOwner,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(OwnershipTransferWindow),
+	}
+	if err := s.transfers.SaveOwnershipTransfer(ctx, transfer); err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// GetOwnershipTransfer returns the server's pending transfer. Only the
+// owner and the member it's offered to can see it.
+func (s *ServerService) GetOwnershipTransfer(ctx context.Context, serverID, requesterID uuid.UUID) (*models.OwnershipTransfer, error) {
+	server, err := s.repo.GetByID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if server == nil {
+		return nil, ErrServerNotFound
+	}
+	return s.pendingTransfer(ctx, server, requesterID)
+}
+
+// AcceptOwnershipTransfer makes the requester, who the transfer was offered
+// to, the server's owner. If the transfer asked for it the outgoing owner
+// stays on as a co-owner.
+func (s *ServerService) AcceptOwnershipTransfer(ctx context.Context, serverID, requesterID uuid.UUID) (*models.Server, error) {
+	server, err := s.repo.GetByID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if server == nil {
+		return nil, ErrServerNotFound
+	}
+	transfer, err := s.pendingTransfer(ctx, server, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.ToUserID != requesterID {
+		return nil, ErrOwnershipTransferNotFound
+	}
+	if transfer.FromUserID != server.OwnerID {
+		return nil, ErrOwnershipTransferStale
+	}
+
+	// They may have left since it was offered
+	member, err := s.repo.GetMember(ctx, serverID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrNotServerMember
+	}
+
+	completed, err := s.transfers.CompleteOwnershipTransfer(ctx, transfer, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !completed {
+		return nil, ErrOwnershipTransferStale
+	}
+
+	server.OwnerID = requesterID
+	server.UpdatedAt = time.Now()
+	forgetServer(ctx, s.cache, serverID)
+
+	s.eventBus.Publish("server.ownership_transferred", &OwnershipTransferredEvent{
+		ServerID:   serverID,
+		OldOwnerID: transfer.FromUserID,
+		NewOwnerID: requesterID,
+	})
+	if transfer.KeepCoOwner {
+		s.publishCoOwner(ctx, serverID, transfer.FromUserID)
+	}
+
+	return server, nil
+}
+
+// CancelOwnershipTransfer withdraws the server's pending transfer. The
+// owner can cancel it and the member it's offered to can decline it.
+func (s *ServerService) CancelOwnershipTransfer(ctx context.Context, serverID, requesterID uuid.UUID) error {
+	server, err := s.repo.GetByID(ctx, serverID)
+	if err != nil {
+		return err
+	}
+	if server == nil {
+		return ErrServerNotFound
+	}
+	if _, err := s.pendingTransfer(ctx, server, requesterID); err != nil {
+		return err
+	}
+	return s.transfers.DeleteOwnershipTransfer(ctx, serverID)
+}
+
+// pendingTransfer returns the server's unexpired transfer if the requester
+// is its owner or the member it's offered to
+func (s *ServerService) pendingTransfer(ctx context.Context, server *models.Server, requesterID uuid.UUID) (*models.OwnershipTransfer, error) {
+	if s.transfers == nil {
+		return nil, ErrOwnershipTransferNotFound
+	}
+	transfer, err := s.transfers.GetOwnershipTransfer(ctx, server.ID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer == nil || transfer.Expired(time.Now()) {
+		return nil, ErrOwnershipTransferNotFound
+	}
+	if requesterID != server.OwnerID && requesterID != transfer.ToUserID {
+		return nil, ErrOwnershipTransferNotFound
+	}
+	return transfer, nil
+}
+
+// SetCoOwner makes a member a co-owner, with every permission and a place
+// above every role. Deleting the server, transferring it and appointing
+// co-owners stay with the owner. Owner only.
+func (s *ServerService) SetCoOwner(ctx context.Context, serverID, requesterID, targetID uuid.UUID) (*models.Member, error) {
+	return s.setCoOwner(ctx, serverID, requesterID, targetID, true)
+}
+
+// RemoveCoOwner takes a member's co-ownership away. The owner can remove
+// any co-owner and co-owners can step down.
+func (s *ServerService) RemoveCoOwner(ctx context.Context, serverID, requesterID, targetID uuid.UUID) (*models.Member, error) {
+	return s.setCoOwner(ctx, serverID, requesterID, targetID, false)
+}
+
+func (s *ServerService) setCoOwner(ctx context.Context, serverID, requesterID, targetID uuid.UUID, coOwner bool) (*models.Member, error) {
+	server, err := s.repo.GetByID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if server == nil {
+		return nil, ErrServerNotFound
+	}
+	stepDown := !coOwner && requesterID == targetID
+	if server.OwnerID != requesterID && !stepDown {
+		return nil, ErrNotServerOwner
+	}
+	if server.OwnerID == targetID {
+		return nil, ErrSelfAction
+	}
+	if s.transfers == nil {
+		return nil, errOwnershipTransfersNotConfigured
+	}
+
+	member, err := s.repo.GetMember(ctx, serverID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrNotServerMember
+	}
+	if !coOwner && !member.CoOwner {
+		return nil, ErrNotCoOwner
+	}
+	if member.CoOwner == coOwner {
+		return member, nil
+	}
+
+	if err := s.transfers.SetCoOwner(ctx, serverID, targetID, coOwner); err != nil {
+		return nil, err
+	}
+	member.CoOwner = coOwner
+	forgetMember(ctx, s.cache, serverID, targetID)
+
+	s.eventBus.Publish("server.member_updated", &MemberUpdatedEvent{
+		ServerID: serverID,
+		UserID:   targetID,
+		Member:   member,
+	})
+
+	return member, nil
+}
+
+// publishCoOwner announces that a member became a co-owner other than
+// through SetCoOwner
+func (s *ServerService) publishCoOwner(ctx context.Context, serverID, userID uuid.UUID) {
+	member, err := s.repo.GetMember(ctx, serverID, userID)
+	if err != nil || member == nil {
+		return
+	}
+	member.CoOwner = true
+	s.eventBus.Publish("server.member_updated", &MemberUpdatedEvent{
+		ServerID: serverID,
+		UserID:   userID,
+		Member:   member,
+	})
+}
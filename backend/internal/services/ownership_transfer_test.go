@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeOwnershipTransferRepository struct {
+	transfers map[uuid.UUID]*models.OwnershipTransfer
+	servers   map[uuid.UUID]*models.Server
+	members   map[uuid.UUID]*models.Member
+	err       error
+}
+
+func newFakeOwnershipTransferRepository() *fakeOwnershipTransferRepository {
+	return &fakeOwnershipTransferRepository{
+		transfers: make(map[uuid.UUID]*models.OwnershipTransfer),
+		servers:   make(map[uuid.UUID]*models.Server),
+		members:   make(map[uuid.UUID]*models.Member),
+	}
+}
+
+func (f *fakeOwnershipTransferRepository) SaveOwnershipTransfer(ctx context.Context, transfer *models.OwnershipTransfer) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.transfers[transfer.ServerID] = transfer
+	return nil
+}
+
+func (f *fakeOwnershipTransferRepository) GetOwnershipTransfer(ctx context.Context, serverID uuid.UUID) (*models.OwnershipTransfer, error) {
+	return f.transfers[serverID], f.err
+}
+
+func (f *fakeOwnershipTransferRepository) DeleteOwnershipTransfer(ctx context.Context, serverID uuid.UUID) error {
+	delete(f.transfers, serverID)
+	return f.err
+}
+
+func (f *fakeOwnershipTransferRepository) CompleteOwnershipTransfer(ctx context.Context, transfer *models.OwnershipTransfer, now time.Time) (bool, error) {
+	pending, ok := f.transfers[transfer.ServerID]
+	if !ok || pending.ToUserID != transfer.ToUserID || pending.Expired(now) {
+		return false, f.err
+	}
+	server := f.servers[transfer.ServerID]
+	if server.OwnerID != transfer.FromUserID {
+		return false, f.err
+	}
+	delete(f.transfers, transfer.ServerID)
+	server.OwnerID = transfer.ToUserID
+	f.members[transfer.ToUserID].CoOwner = false
+	f.members[transfer.FromUserID].CoOwner = transfer.KeepCoOwner
+	return true, f.err
+}
+
+func (f *fakeOwnershipTransferRepository) SetCoOwner(ctx context.Context, serverID, userID uuid.UUID, coOwner bool) error {
+	f.members[userID].CoOwner = coOwner
+	return f.err
+}
+
+type ownershipTest struct {
+	service    *ServerService
+	serverRepo *MockServerRepository
+	eventBus   *MockEventBus
+	transfers  *fakeOwnershipTransferRepository
+
+	server  *models.Server
+	ownerID uuid.UUID
+	heirID  uuid.UUID
+}
+
+func newOwnershipTest() *ownershipTest {
+	service, serverRepo, _, _, _, eventBus := newTestServerService()
+	f := &ownershipTest{
+		service:    service,
+		serverRepo: serverRepo,
+		eventBus:   eventBus,
+		transfers:  newFakeOwnershipTransferRepository(),
+		ownerID:    uuid.New(),
+		heirID:     uuid.New(),
+	}
+	f.server = &models.Server{ID: uuid.New(), OwnerID: f.ownerID}
+	f.transfers.servers[f.server.ID] = f.server
+	service.SetOwnershipTransferRepository(f.transfers)
+
+	serverRepo.On("GetByID", mock.Anything, f.server.ID).Return(f.server, nil)
+	for _, userID := range []uuid.UUID{f.ownerID, f.heirID} {
+		member := &models.Member{ServerID: f.server.ID, UserID: userID}
+		f.transfers.members[userID] = member
+		serverRepo.On("GetMember", mock.Anything, f.server.ID, userID).Return(member, nil)
+	}
+	return f
+}
+
+func (f *ownershipTest) request(t *testing.T, keepCoOwner bool) *models.OwnershipTransfer {
+	transfer, err := f.service.RequestOwnershipTransfer(context.Background(), f.server.ID, f.ownerID, &models.OwnershipTransferRequest{
+		NewOwnerID:  f.heirID,
+		KeepCoOwner: keepCoOwner,
+	})
+	require.NoError(t, err)
+	return transfer
+}
+
+func TestAcceptOwnershipTransfer(t *testing.T) {
+	f := newOwnershipTest()
+	ctx := context.Background()
+	f.eventBus.On("Publish", "server.ownership_transferred", &OwnershipTransferredEvent{
+		ServerID:   f.server.ID,
+		OldOwnerID: f.ownerID,
+		NewOwnerID: f.heirID,
+	}).Return().Once()
+	f.eventBus.On("Publish", "server.member_updated", mock.MatchedBy(func(e *MemberUpdatedEvent) bool {
+		return e.UserID == f.ownerID && e.Member.CoOwner
+	})).Return().Once()
+	f.request(t, true)
+
+	_, err := f.service.AcceptOwnershipTransfer(ctx, f.server.ID, f.ownerID)
+	assert.ErrorIs(t, err, ErrOwnershipTransferNotFound, "only the new owner can accept")
+
+	transfer, err := f.service.GetOwnershipTransfer(ctx, f.server.ID, f.heirID)
+	require.NoError(t, err)
+	assert.True(t, transfer.KeepCoOwner)
+
+	server, err := f.service.AcceptOwnershipTransfer(ctx, f.server.ID, f.heirID)
+	require.NoError(t, err)
+	assert.Equal(t, f.heirID, server.OwnerID)
+	assert.Equal(t, f.heirID, f.server.OwnerID)
+	assert.True(t, f.transfers.members[f.ownerID].CoOwner, "the old owner stays on as a co-owner")
+	assert.Empty(t, f.transfers.transfers)
+
+	_, err = f.service.AcceptOwnershipTransfer(ctx, f.server.ID, f.heirID)
+	assert.ErrorIs(t, err, ErrOwnershipTransferNotFound)
+	f.eventBus.AssertExpectations(t)
+}
+
+func TestAcceptOwnershipTransfer_Expired(t *testing.T) {
+	f := newOwnershipTest()
+	ctx := context.Background()
+	transfer := f.request(t, false)
+	transfer.ExpiresAt = time.Now().Add(-time.Minute)
+
+	_, err := f.service.AcceptOwnershipTransfer(ctx, f.server.ID, f.heirID)
+	assert.ErrorIs(t, err, ErrOwnershipTransferNotFound)
+	_, err = f.service.GetOwnershipTransfer(ctx, f.server.ID, f.ownerID)
+	assert.ErrorIs(t, err, ErrOwnershipTransferNotFound)
+	assert.Equal(t, f.ownerID, f.server.OwnerID)
+}
+
+func TestAcceptOwnershipTransfer_OwnerChanged(t *testing.T) {
+	f := newOwnershipTest()
+	f.request(t, false)
+	// Handed over some other way since
+	f.server.OwnerID = uuid.New()
+
+	_, err := f.service.AcceptOwnershipTransfer(context.Background(), f.server.ID, f.heirID)
+	assert.ErrorIs(t, err, ErrOwnershipTransferStale)
+	f.eventBus.AssertNotCalled(t, "Publish", "server.ownership_transferred", mock.Anything)
+}
+
+func TestCancelOwnershipTransfer(t *testing.T) {
+	f := newOwnershipTest()
+	ctx := context.Background()
+
+	f.request(t, false)
+	assert.ErrorIs(t, f.service.CancelOwnershipTransfer(ctx, f.server.ID, uuid.New()), ErrOwnershipTransferNotFound)
+	require.NoError(t, f.service.CancelOwnershipTransfer(ctx, f.server.ID, f.heirID), "the new owner can decline")
+	assert.Empty(t, f.transfers.transfers)
+
+	f.request(t, false)
+	require.NoError(t, f.service.CancelOwnershipTransfer(ctx, f.server.ID, f.ownerID))
+	assert.ErrorIs(t, f.service.CancelOwnershipTransfer(ctx, f.server.ID, f.ownerID), ErrOwnershipTransferNotFound)
+}
+
+func TestSetCoOwner(t *testing.T) {
+	f := newOwnershipTest()
+	ctx := context.Background()
+	f.eventBus.On("Publish", "server.member_updated", mock.Anything).Return().Twice()
+
+	_, err := f.service.SetCoOwner(ctx, f.server.ID, f.heirID, f.heirID)
+	assert.ErrorIs(t, err, ErrNotServerOwner, "only the owner appoints co-owners")
+	_, err = f.service.SetCoOwner(ctx, f.server.ID, f.ownerID, f.ownerID)
+	assert.ErrorIs(t, err, ErrSelfAction)
+
+	member, err := f.service.SetCoOwner(ctx, f.server.ID, f.ownerID, f.heirID)
+	require.NoError(t, err)
+	assert.True(t, member.CoOwner)
+	assert.True(t, f.transfers.members[f.heirID].CoOwner)
+
+	_, err = f.service.SetCoOwner(ctx, f.server.ID, f.ownerID, f.heirID)
+	require.NoError(t, err, "appointing a co-owner again is a no-op")
+
+	member, err = f.service.RemoveCoOwner(ctx, f.server.ID, f.heirID, f.heirID)
+	require.NoError(t, err, "co-owners can step down")
+	assert.False(t, member.CoOwner)
+	_, err = f.service.RemoveCoOwner(ctx, f.server.ID, f.ownerID, f.heirID)
+	assert.ErrorIs(t, err, ErrNotCoOwner)
+	f.eventBus.AssertExpectations(t)
+}
+
+func TestCoOwnerHierarchy(t *testing.T) {
+	f := newOwnershipTest()
+	ctx := context.Background()
+	f.transfers.members[f.heirID].CoOwner = true
+	modID := uuid.New()
+	roleRepo := new(MockRoleRepository)
+	f.serverRepo.On("GetMember", mock.Anything, f.server.ID, modID).Return(&models.Member{ServerID: f.server.ID, UserID: modID}, nil)
+	roleRepo.On("GetMemberRoles", mock.Anything, f.server.ID, modID).Return([]*models.Role{{ID: uuid.New(), Position: 100}}, nil)
+
+	assert.NoError(t, checkOutranks(ctx, f.serverRepo, roleRepo, f.server, f.heirID, modID), "co-owners outrank every role")
+	assert.ErrorIs(t, checkOutranks(ctx, f.serverRepo, roleRepo, f.server, modID, f.heirID), ErrMemberHierarchy)
+	assert.ErrorIs(t, checkOutranks(ctx, f.serverRepo, roleRepo, f.server, f.heirID, f.ownerID), ErrMemberHierarchy)
+
+	perms := models.CalculatePermissions(&models.Member{UserID: f.heirID, CoOwner: true}, nil, f.server, nil, nil)
+	assert.True(t, models.HasPermission(perms, models.PermAdministrator))
+}
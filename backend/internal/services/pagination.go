@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// ServerPageRepository reads a server's members, bans and invites a page at
+// a time. Each method returns up to limit rows past cursor, nearest first.
+type ServerPageRepository interface {
+	PageMembers(ctx context.Context, serverID uuid.UUID, cursor *models.PageCursor, limit int) ([]*models.Member, error)
+	PageBans(ctx context.Context, serverID uuid.UUID, cursor *models.PageCursor, limit int) ([]*models.Ban, error)
+	PageInvites(ctx context.Context, serverID uuid.UUID, cursor *models.PageCursor, limit int) ([]*models.Invite, error)
+}
+
+// RolePageRepository reads a server's roles a page at a time
+type RolePageRepository interface {
+	PageRoles(ctx context.Context, serverID uuid.UUID, cursor *models.PageCursor, limit int) ([]*models.Role, error)
+}
+
+// SetPageRepository lets members, bans and invites be listed a page at a
+// time. Without one each list is returned whole, as the first page.
+func (s *ServerService) SetPageRepository(pages ServerPageRepository) {
+	s.pages = pages
+}
+
+// ListMembers returns a page of the server's members, newest first
+func (s *ServerService) ListMembers(ctx context.Context, serverID uuid.UUID, req models.PageRequest) (*models.Page[*models.Member], error) {
+	if s.pages == nil {
+		members, err := s.repo.GetMembers(ctx, serverID, req.Limit, 0)
+		return wholePage(members), err
+	}
+	members, err := s.pages.PageMembers(ctx, serverID, req.Cursor, req.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+	return pageOf(members, req, func(member *models.Member) models.PageCursor {
+		return models.PageCursor{List: models.PageMembers, Time: &member.JoinedAt, Key: member.UserID.String()}
+	}), nil
+}
+
+// ListBans returns a page of the server's bans, newest first
+func (s *ServerService) ListBans(ctx context.Context, serverID uuid.UUID, req models.PageRequest) (*models.Page[*models.Ban], error) {
+	if s.pages == nil {
+		bans, err := s.repo.GetBans(ctx, serverID)
+		return wholePage(bans), err
+	}
+	bans, err := s.pages.PageBans(ctx, serverID, req.Cursor, req.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+	return pageOf(bans, req, func(ban *models.Ban) models.PageCursor {
+		return models.PageCursor{List: models.PageBans, Time: &ban.CreatedAt, Key: ban.UserID.String()}
+	}), nil
+}
+
+// ListInvites returns a page of the server's invites, newest first
+func (s *ServerService) ListInvites(ctx context.Context, serverID uuid.UUID, req models.PageRequest) (*models.Page[*models.Invite], error) {
+	if s.pages == nil {
+		invites, err := s.repo.GetInvites(ctx, serverID)
+		return wholePage(invites), err
+	}
+	invites, err := s.pages.PageInvites(ctx, serverID, req.Cursor, req.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+	return pageOf(invites, req, func(invite *models.Invite) models.PageCursor {
+		return models.PageCursor{List: models.PageInvites, Time: &invite.CreatedAt, Key: invite.Code}
+	}), nil
+}
+
+// SetPageRepository lets roles be listed a page at a time. Without one
+// they're returned whole, as the first page.
+func (s *RoleService) SetPageRepository(pages RolePageRepository) {
+	s.pages = pages
+}
+
+// ListServerRoles returns a page of the server's roles, highest first, to
+// its members
+func (s *RoleService) ListServerRoles(ctx context.Context, serverID, requesterID uuid.UUID, req models.PageRequest) (*models.Page[*models.Role], error) {
+	member, err := cachedMember(ctx, s.cache, s.serverRepo, serverID, requesterID)
+	if err != nil || member == nil {
+		return nil, ErrNotServerMember
+	}
+
+	if s.pages == nil {
+		roles, err := cachedRoles(ctx, s.cache, s.roleRepo, serverID)
+		return wholePage(roles), err
+	}
+	roles, err := s.pages.PageRoles(ctx, serverID, req.Cursor, req.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+	return pageOf(roles, req, func(role *models.Role) models.PageCursor {
+		return models.PageCursor{List: models.PageRoles, Position: &role.Position, Key: role.ID.String()}
+	}), nil
+}
+
+// pageOf makes a page of rows read for req: up to req.Limit+1 of them,
+// nearest the cursor first. Rows read backward are put back in list order.
+func pageOf[T any](rows []T, req models.PageRequest, cursorOf func(T) models.PageCursor) *models.Page[T] {
+	more := len(rows) > req.Limit
+	if more {
+		rows = rows[:req.Limit]
+	}
+	if req.Cursor != nil && req.Cursor.Backward {
+		slices.Reverse(rows)
+	}
+
+	page := wholePage(rows)
+	if len(rows) > 0 {
+		page.NextCursor, page.PrevCursor = pageCursors(cursorOf(rows[0]), cursorOf(rows[len(rows)-1]), req.Cursor, more)
+	}
+	return page
+}
+
+// pageCursors returns the tokens for the pages either side of one running
+// from first to last that was read at cursor. more says rows were left
+// over past the page in the direction it was read.
+func pageCursors(first, last models.PageCursor, cursor *models.PageCursor, more bool) (next, prev string) {
+	first.Backward = true
+	last.Backward = false
+	if cursor != nil && cursor.Backward {
+		next = last.Encode()
+		if more {
+			prev = first.Encode()
+		}
+		return next, prev
+	}
+
+	if more {
+		next = last.Encode()
+	}
+	if cursor != nil {
+		prev = first.Encode()
+	}
+	return next, prev
+}
+
+func wholePage[T any](rows []T) *models.Page[T] {
+	if rows == nil {
+		rows = []T{}
+	}
+	return &models.Page[T]{Items: rows}
+}
+
+// searchCursor is where a message search result sits in the results
+func searchCursor(message *models.Message) models.PageCursor {
+	return models.PageCursor{List: models.PageSearch, Time: &message.CreatedAt, Key: message.ID.String()}
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+// fakeServerPages hands back members as PageMembers would, and remembers
+// what it was asked for
+type fakeServerPages struct {
+	members []*models.Member
+	cursor  *models.PageCursor
+	limit   int
+}
+
+func (f *fakeServerPages) PageMembers(ctx context.Context, serverID uuid.UUID, cursor *models.PageCursor, limit int) ([]*models.Member, error) {
+	f.cursor, f.limit = cursor, limit
+	if len(f.members) > limit {
+		return f.members[:limit], nil
+	}
+	return f.members, nil
+}
+
+func (f *fakeServerPages) PageBans(ctx context.Context, serverID uuid.UUID, cursor *models.PageCursor, limit int) ([]*models.Ban, error) {
+	return nil, nil
+}
+
+func (f *fakeServerPages) PageInvites(ctx context.Context, serverID uuid.UUID, cursor *models.PageCursor, limit int) ([]*models.Invite, error) {
+	return nil, nil
+}
+
+func pageMembers(n int) []*models.Member {
+	start := time.Date(2026, 2, 14, 12, 0, 0, 0, time.UTC)
+	members := make([]*models.Member, n)
+	for i := range members {
+		members[i] = &models.Member{UserID: uuid.New(), JoinedAt: start.Add(-time.Duration(i) * time.Minute)}
+	}
+	return members
+}
+
+func TestListMembers_Pages(t *testing.T) {
+	members := pageMembers(3)
+	pages := &fakeServerPages{members: members}
+	service := &ServerService{}
+	service.SetPageRepository(pages)
+	ctx := context.Background()
+
+	page, err := service.ListMembers(ctx, uuid.New(), models.PageRequest{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, members[:2], page.Items)
+	assert.Equal(t, 3, pages.limit, "one extra row is read to see if there's more")
+	assert.Empty(t, page.PrevCursor, "nothing before the first page")
+	require.NotEmpty(t, page.NextCursor)
+
+	next, err := models.DecodePageCursor(page.NextCursor, models.PageMembers)
+	require.NoError(t, err)
+	assert.Equal(t, members[1].UserID.String(), next.Key)
+	assert.False(t, next.Backward)
+
+	pages.members = members[2:]
+	page, err = service.ListMembers(ctx, uuid.New(), models.PageRequest{Cursor: next, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, next, pages.cursor)
+	assert.Equal(t, members[2:], page.Items)
+	assert.Empty(t, page.NextCursor)
+	require.NotEmpty(t, page.PrevCursor)
+
+	prev, err := models.DecodePageCursor(page.PrevCursor, models.PageMembers)
+	require.NoError(t, err)
+	assert.Equal(t, members[2].UserID.String(), prev.Key)
+	assert.True(t, prev.Backward)
+}
+
+func TestPageOf_Backward(t *testing.T) {
+	members := pageMembers(4)
+	cursorOf := func(member *models.Member) models.PageCursor {
+		return models.PageCursor{List: models.PageMembers, Time: &member.JoinedAt, Key: member.UserID.String()}
+	}
+	at := cursorOf(members[3])
+	at.Backward = true
+
+	// Read backward from the last member: nearest first, with one to spare
+	page := pageOf([]*models.Member{members[2], members[1], members[0]}, models.PageRequest{Cursor: &at, Limit: 2}, cursorOf)
+
+	assert.Equal(t, []*models.Member{members[1], members[2]}, page.Items, "back in list order")
+	assert.NotEmpty(t, page.NextCursor)
+	assert.NotEmpty(t, page.PrevCursor, "the first member is still before this page")
+}
+
+func TestListMembers_WithoutPageRepository(t *testing.T) {
+	serverRepo := new(MockServerRepository)
+	service := &ServerService{repo: serverRepo}
+	ctx := context.Background()
+	serverID := uuid.New()
+	members := pageMembers(2)
+
+	serverRepo.On("GetMembers", ctx, serverID, 100, 0).Return(members, nil)
+
+	page, err := service.ListMembers(ctx, serverID, models.PageRequest{Limit: 100})
+	require.NoError(t, err)
+	assert.Equal(t, members, page.Items)
+	assert.Empty(t, page.NextCursor)
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	defaultPasswordResetTTL = time.Hour
+	// passwordResetCooldown is how soon after one reset email another can
+	// be sent to the same account
+	passwordResetCooldown = time.Minute
+)
+
+// PasswordResetRepository stores outstanding password reset links. The
+// Postgres PasswordResetRepository implements it.
+type PasswordResetRepository interface {
+	// Create replaces the user's reset link, unless the current one was
+	// made after cooldownStart, in which case it returns false
+	Create(ctx context.Context, reset *models.PasswordReset, cooldownStart time.Time) (bool, error)
+	// Consume deletes the reset with the token hash and returns its user,
+	// or uuid.Nil if there's none or it expired before now
+	Consume(ctx context.Context, tokenHash string, now time.Time) (uuid.UUID, error)
+}
+
+// passwordResetUsers is the part of UserRepository password resets need
+type passwordResetUsers interface {
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string, at time.Time) error
+}
+
+// PasswordResetService lets users who forgot their password set a new one
+// through a link emailed to them. Resetting also unlocks the account and
+// logs it out everywhere.
+type PasswordResetService struct {
+	repo     PasswordResetRepository
+	users    passwordResetUsers
+	mailer   Mailer
+	resetURL string
+	ttl      time.Duration
+	now      func() time.Time
+
+	sessions *SessionService
+	security *LoginSecurityService
+}
+
+// NewPasswordResetService creates a password reset service. Emails link to
+// resetURL with the token in its token query parameter. Links work once,
+// for ttl (default an hour).
+func NewPasswordResetService(repo PasswordResetRepository, users passwordResetUsers, mailer Mailer, resetURL string, ttl time.Duration) *PasswordResetService {
+	if ttl <= 0 {
+		ttl = defaultPasswordResetTTL
+	}
+	return &PasswordResetService{
+		repo:     repo,
+		users:    users,
+		mailer:   mailer,
+		resetURL: resetURL,
+		ttl:      ttl,
+		now:      time.Now,
+	}
+}
+
+// SetSessions logs users out everywhere when they reset their password
+func (s *PasswordResetService) SetSessions(sessions *SessionService) {
+	s.sessions = sessions
+}
+
+// SetLoginSecurity unlocks accounts when their password is reset
+func (s *PasswordResetService) SetLoginSecurity(security *LoginSecurityService) {
+	s.security = security
+}
+
+// Request emails a reset link to the account with the email, if there is
+// one. It returns nil either way, and sends in the background, so callers
+// can't tell which emails are registered.
+func (s *PasswordResetService) Request(ctx context.Context, email string) error {
+	user, err := s.users.GetByEmail(ctx, email)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Bots don't log in with passwords, and erased accounts not at all
+	if user.IsBot() || user.Flags&models.UserFlagDeletedUser != 0 || user.Email == "" {
+		return nil
+	}
+
+	token, err := generateResetToken()
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	created, err := s.repo.Create(ctx, &models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hashResetToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.ttl),
+	}, now.Add(-passwordResetCooldown))
+	if err != nil {
+		return err
+	}
+	if !created {
+		// The last email was only just sent
+		return nil
+	}
+
+	subject, body := s.resetEmail(user, token)
+	to := user.Email
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+		defer cancel()
+		if err := s.mailer.Send(ctx, to, subject, body); err != nil {
+			log.Printf("[PasswordReset] failed to send reset email to %s: %v", user.ID, err)
+		}
+	}()
+	return nil
+}
+
+// Confirm sets a new password with a reset token. The token works once;
+// ErrInvalidResetToken means it was used, expired or never existed. The
+// new password is checked before the token is used up, so a weak one can
+// be fixed and retried.
+func (s *PasswordResetService) Confirm(ctx context.Context, token, password string) error {
+	if token == "" {
+		return ErrInvalidResetToken
+	}
+	passwordHash, err := hashPassword(ctx, password)
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	userID, err := s.repo.Consume(ctx, hashResetToken(token), now)
+	if err != nil {
+		return err
+	}
+	if userID == uuid.Nil {
+		return ErrInvalidResetToken
+	}
+	if err := s.users.UpdatePassword(ctx, userID, passwordHash, now); err != nil {
+		return err
+	}
+
+	// The password is changed by now, so what's left is logged rather
+	// than failing the reset
+	if s.security != nil {
+		if err := s.security.Unlock(ctx, userID); err != nil {
+			log.Printf("[PasswordReset] failed to unlock %s: %v", userID, err)
+		}
+	}
+	if s.sessions != nil {
+		if err := s.sessions.RevokeAllSessions(ctx, userID); err != nil {
+			log.Printf("[PasswordReset] failed to log %s out: %v", userID, err)
+		}
+	}
+	return nil
+}
+
+func (s *PasswordResetService) resetEmail(user *models.User, token string) (string, string) {
+	link := s.resetURL
+	if u, err := url.Parse(s.resetURL); err == nil {
+		query := u.Query()
+		query.Set("token", token)
+		u.RawQuery = query.Encode()
+		link = u.String()
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hi %s,\n\n", user.Username)
+	body.WriteString("Someone asked to reset the password of your Hearth account. To choose a new one, open this link:\n\n")
+	fmt.Fprintf(&body, "%s\n\n", link)
+	fmt.Fprintf(&body, "The link works once and expires in %s. Resetting your password unlocks your account if too many failed logins locked it, and logs you out everywhere.\n\n", formatResetTTL(s.ttl))
+	body.WriteString("If you didn't ask for this, you can ignore this email; your password stays as it is.\n")
+	return "Reset your Hearth password", body.String()
+}
+
+// formatResetTTL writes whole hours and minutes as words, e.g. "1 hour"
+func formatResetTTL(ttl time.Duration) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s", unit)
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+	if ttl >= time.Hour && ttl%time.Hour == 0 {
+		return plural(int(ttl/time.Hour), "hour")
+	}
+	return plural(int(ttl/time.Minute), "minute")
+}
+
+func generateResetToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/auth"
+	"hearth/internal/models"
+)
+
+type fakePasswordResetRepo struct {
+	resets map[uuid.UUID]*models.PasswordReset
+}
+
+func (r *fakePasswordResetRepo) Create(ctx context.Context, reset *models.PasswordReset, cooldownStart time.Time) (bool, error) {
+	if existing, ok := r.resets[reset.UserID]; ok && !existing.CreatedAt.Before(cooldownStart) {
+		return false, nil
+	}
+	copied := *reset
+	r.resets[reset.UserID] = &copied
+	return true, nil
+}
+
+func (r *fakePasswordResetRepo) Consume(ctx context.Context, tokenHash string, now time.Time) (uuid.UUID, error) {
+	for userID, reset := range r.resets {
+		if reset.TokenHash == tokenHash {
+			delete(r.resets, userID)
+			if !reset.ExpiresAt.After(now) {
+				return uuid.Nil, nil
+			}
+			return userID, nil
+		}
+	}
+	return uuid.Nil, nil
+}
+
+type fakePasswordResetUsers struct {
+	users map[string]*models.User
+}
+
+func (u *fakePasswordResetUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	if user, ok := u.users[email]; ok {
+		return user, nil
+	}
+	return nil, ErrUserNotFound
+}
+
+func (u *fakePasswordResetUsers) UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string, at time.Time) error {
+	for _, user := range u.users {
+		if user.ID == userID {
+			user.PasswordHash = passwordHash
+			return nil
+		}
+	}
+	return ErrUserNotFound
+}
+
+type passwordResetTest struct {
+	svc    *PasswordResetService
+	repo   *fakePasswordResetRepo
+	users  *fakePasswordResetUsers
+	mailer *fakeMailer
+	now    *time.Time
+}
+
+func newPasswordResetTest(users ...*models.User) *passwordResetTest {
+	test := &passwordResetTest{
+		repo:   &fakePasswordResetRepo{resets: make(map[uuid.UUID]*models.PasswordReset)},
+		users:  &fakePasswordResetUsers{users: make(map[string]*models.User)},
+		mailer: newFakeMailer(),
+	}
+	for _, user := range users {
+		test.users.users[user.Email] = user
+	}
+	test.svc = NewPasswordResetService(test.repo, test.users, test.mailer, "https://chat.example.com/reset-password?from=email", 0)
+	now := time.Unix(1700000000, 0)
+	test.now = &now
+	test.svc.now = func() time.Time { return now }
+	return test
+}
+
+var resetLink = regexp.MustCompile(`https://\S+`)
+
+// token pulls the token out of the link in the next reset email
+func (test *passwordResetTest) token(t *testing.T) string {
+	mail := test.mailer.next(t)
+	link, err := url.Parse(resetLink.FindString(mail.body))
+	require.NoError(t, err)
+	return link.Query().Get("token")
+}
+
+func TestPasswordResetService_Request(t *testing.T) {
+	ctx := context.Background()
+	user := &models.User{ID: uuid.New(), Username: "ada", Email: "ada@example.com"}
+	test := newPasswordResetTest(user)
+
+	require.NoError(t, test.svc.Request(ctx, "ada@example.com"))
+	mail := test.mailer.next(t)
+	assert.Equal(t, "ada@example.com", mail.to)
+	assert.Equal(t, "Reset your Hearth password", mail.subject)
+	assert.Contains(t, mail.body, "Hi ada,")
+	assert.Contains(t, mail.body, "expires in 1 hour")
+	link, err := url.Parse(resetLink.FindString(mail.body))
+	require.NoError(t, err)
+	assert.Equal(t, "/reset-password", link.Path)
+	assert.Equal(t, "email", link.Query().Get("from"))
+	assert.Len(t, link.Query().Get("token"), 64)
+	assert.NotEqual(t, link.Query().Get("token"), test.repo.resets[user.ID].TokenHash, "only the hash is stored")
+
+	require.NoError(t, test.svc.Request(ctx, "ada@example.com"))
+	test.mailer.none(t)
+	*test.now = test.now.Add(passwordResetCooldown + time.Second)
+	require.NoError(t, test.svc.Request(ctx, "ada@example.com"))
+	test.mailer.next(t)
+}
+
+func TestPasswordResetService_RequestIsSilent(t *testing.T) {
+	ctx := context.Background()
+	test := newPasswordResetTest(
+		&models.User{ID: uuid.New(), Username: "bot", Email: "bot@example.com", Flags: models.UserFlagBot},
+		&models.User{ID: uuid.New(), Username: "gone", Email: "gone@example.com", Flags: models.UserFlagDeletedUser},
+	)
+
+	for _, email := range []string{"nobody@example.com", "bot@example.com", "gone@example.com"} {
+		assert.NoError(t, test.svc.Request(ctx, email))
+	}
+	test.mailer.none(t)
+	assert.Empty(t, test.repo.resets)
+}
+
+func TestPasswordResetService_Confirm(t *testing.T) {
+	ctx := context.Background()
+	user := &models.User{ID: uuid.New(), Username: "ada", Email: "ada@example.com", PasswordHash: "old"}
+	test := newPasswordResetTest(user)
+
+	security, lockouts, _ := newLoginSecurityTest(LoginSecurityConfig{LockoutThreshold: 1})
+	require.ErrorIs(t, security.LoginFailed(ctx, user.ID), ErrAccountLocked)
+	test.svc.SetLoginSecurity(security)
+
+	sessionRepo := newFakeSessionRepo()
+	sessions := NewSessionService(sessionRepo, testJWTService())
+	_, err := sessions.Start(ctx, user.ID, user.Username, false)
+	require.NoError(t, err)
+	test.svc.SetSessions(sessions)
+
+	require.NoError(t, test.svc.Request(ctx, "ada@example.com"))
+	token := test.token(t)
+
+	assert.ErrorIs(t, test.svc.Confirm(ctx, token, "weakpassword"), ErrPasswordWeak)
+	assert.Equal(t, "old", user.PasswordHash)
+	assert.ErrorIs(t, test.svc.Confirm(ctx, "not-the-token", "NewPassword1"), ErrInvalidResetToken)
+
+	require.NoError(t, test.svc.Confirm(ctx, token, "NewPassword1"), "a weak password doesn't use the token up")
+	assert.NoError(t, auth.CheckPassword("NewPassword1", user.PasswordHash))
+	assert.NoError(t, security.CheckLocked(ctx, user.ID), "resetting unlocks the account")
+	assert.Empty(t, lockouts.lockouts)
+	assert.Empty(t, sessionRepo.sessions, "resetting logs out everywhere")
+	assert.Equal(t, 1, sessionRepo.versions[user.ID])
+
+	assert.ErrorIs(t, test.svc.Confirm(ctx, token, "OtherPassword1"), ErrInvalidResetToken, "tokens work once")
+	assert.ErrorIs(t, test.svc.Confirm(ctx, "", "OtherPassword1"), ErrInvalidResetToken)
+}
+
+func TestPasswordResetService_ConfirmExpired(t *testing.T) {
+	ctx := context.Background()
+	user := &models.User{ID: uuid.New(), Username: "ada", Email: "ada@example.com"}
+	test := newPasswordResetTest(user)
+
+	require.NoError(t, test.svc.Request(ctx, "ada@example.com"))
+	token := test.token(t)
+	*test.now = test.now.Add(defaultPasswordResetTTL)
+	assert.ErrorIs(t, test.svc.Confirm(ctx, token, "NewPassword1"), ErrInvalidResetToken)
+}
+
+func TestFormatResetTTL(t *testing.T) {
+	assert.Equal(t, "1 hour", formatResetTTL(time.Hour))
+	assert.Equal(t, "2 hours", formatResetTTL(2*time.Hour))
+	assert.Equal(t, "90 minutes", formatResetTTL(90*time.Minute))
+	assert.Equal(t, "1 minute", formatResetTTL(time.Minute))
+}
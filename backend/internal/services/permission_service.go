@@ -0,0 +1,449 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	// permissionCacheTTL bounds how long a computed bitmask is served
+	// should an invalidation event be missed
+	permissionCacheTTL = 5 * time.Minute
+
+	// permissionEpochTTL outlives every entry written under an epoch, so
+	// an epoch that expires can't bring stale entries back
+	permissionEpochTTL = time.Hour
+
+	// permissionInvalidateTimeout bounds the cache write made for an event
+	permissionInvalidateTimeout = 2 * time.Second
+)
+
+// DMPermissions are what every recipient of a DM or group DM may do
+const DMPermissions int64 = models.PermViewChannels | models.PermSendMessages |
+	models.PermEmbedLinks | models.PermAttachFiles | models.PermReadMessageHistory |
+	models.PermAddReactions | models.PermUseExternalEmoji | models.PermUseExternalStickers |
+	models.PermConnect | models.PermSpeak | models.PermVideo | models.PermUseVoiceActivity
+
+// timedOutPermissions are all a timed out or pending member keeps: they
+// can still read the channel but not take part in it
+const timedOutPermissions int64 = models.PermViewChannels | models.PermReadMessageHistory
+
+// PermissionOverwriteRepository reads channel permission overwrites. The
+// Postgres PermissionOverwriteRepository implements it.
+type PermissionOverwriteRepository interface {
+	GetByChannelID(ctx context.Context, channelID uuid.UUID) ([]models.PermissionOverride, error)
+}
+
+// PermissionService computes a user's effective permissions in a channel
+// from the server's roles, the member's roles, the channel's overwrites
+// and schedule, and any timeout or pending screening. Results are cached;
+// role, member and channel events bump a per-server or per-member epoch
+// that is part of every cache entry, so a change invalidates all of the
+// affected entries without scanning for them.
+type PermissionService struct {
+	channelRepo ChannelRepository
+	serverRepo  ServerRepository
+	roleRepo    RoleRepository
+	overwrites  PermissionOverwriteRepository
+	schedules   ChannelScheduleRepository
+	cache       CacheService
+	now         func() time.Time
+}
+
+// NewPermissionService creates a new permission service. cache may be nil,
+// in which case permissions are computed on every call.
+func NewPermissionService(
+	channelRepo ChannelRepository,
+	serverRepo ServerRepository,
+	roleRepo RoleRepository,
+	overwrites PermissionOverwriteRepository,
+	cache CacheService,
+) *PermissionService {
+	return &PermissionService{
+		channelRepo: channelRepo,
+		serverRepo:  serverRepo,
+		roleRepo:    roleRepo,
+		overwrites:  overwrites,
+		cache:       cache,
+		now:         time.Now,
+	}
+}
+
+// cachedPermissions is a cache entry, along with the epochs it was
+// computed under
+type cachedPermissions struct {
+	ServerID    uuid.UUID `json:"server_id"`
+	ServerEpoch string    `json:"server_epoch"`
+	MemberEpoch string    `json:"member_epoch"`
+	Permissions int64     `json:"permissions"`
+}
+
+// ChannelPermissions returns userID's effective permission bitmask in a
+// channel. Users who can't see the channel get 0; users outside the server
+// or DM get ErrNotServerMember or ErrNotChannelMember.
+func (s *PermissionService) ChannelPermissions(ctx context.Context, channelID, userID uuid.UUID) (int64, error) {
+	if cached, ok := s.lookup(ctx, channelID, userID); ok {
+		return cached, nil
+	}
+
+	channel, err := s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return 0, err
+	}
+	if channel == nil {
+		return 0, ErrChannelNotFound
+	}
+	if channel.ServerID == nil {
+		for _, recipient := range channel.Recipients {
+			if recipient == userID {
+				return DMPermissions, nil
+			}
+		}
+		return 0, ErrNotChannelMember
+	}
+
+	// Read the epochs before anything the result depends on, so a change
+	// made while computing leaves the entry under an epoch nobody reads
+	serverID := *channel.ServerID
+	entry := cachedPermissions{
+		ServerID:    serverID,
+		ServerEpoch: s.epoch(ctx, serverEpochKey(serverID)),
+		MemberEpoch: s.epoch(ctx, memberEpochKey(serverID, userID)),
+	}
+
+	permissions, expires, err := s.compute(ctx, channel, userID)
+	if err != nil {
+		return 0, err
+	}
+	entry.Permissions = permissions
+	s.store(ctx, channelID, userID, &entry, expires)
+	return permissions, nil
+}
+
+// HasChannelPermission reports whether userID holds perm in a channel
+func (s *PermissionService) HasChannelPermission(ctx context.Context, channelID, userID uuid.UUID, perm int64) (bool, error) {
+	permissions, err := s.ChannelPermissions(ctx, channelID, userID)
+	if err != nil {
+		return false, err
+	}
+	return models.HasPermission(permissions, perm), nil
+}
+
+// compute works out a member's permissions in a server channel. expires is
+// set when the result changes by itself, at the end of a timeout or when
+// the channel opens or closes.
+func (s *PermissionService) compute(ctx context.Context, channel *models.Channel, userID uuid.UUID) (int64, *time.Time, error) {
+	server, err := s.serverRepo.GetByID(ctx, *channel.ServerID)
+	if err != nil {
+		return 0, nil, err
+	}
+	if server == nil {
+		return 0, nil, ErrServerNotFound
+	}
+	if server.OwnerID == userID {
+		return models.PermissionAll | models.PermAdministrator, nil, nil
+	}
+	member, err := s.serverRepo.GetMember(ctx, server.ID, userID)
+	if err != nil {
+		return 0, nil, err
+	}
+	if member == nil {
+		return 0, nil, ErrNotServerMember
+	}
+
+	memberRoles, err := s.roleRepo.GetMemberRoles(ctx, server.ID, userID)
+	if err != nil {
+		return 0, nil, err
+	}
+	member.Roles = make([]uuid.UUID, 0, len(memberRoles))
+	for _, role := range memberRoles {
+		member.Roles = append(member.Roles, role.ID)
+	}
+	roles, err := s.roleRepo.GetByServerID(ctx, server.ID)
+	if err != nil {
+		return 0, nil, err
+	}
+	var overwrites []models.PermissionOverride
+	if s.overwrites != nil {
+		overwrites, err = s.overwrites.GetByChannelID(ctx, channel.ID)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+	schedule, err := s.schedule(ctx, channel.ID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	permissions, expires := s.effective(member, roles, server, channel, overwrites, schedule)
+	return permissions, expires, nil
+}
+
+// schedule returns the channel's schedule with its state worked out, or
+// nil if it has none
+func (s *PermissionService) schedule(ctx context.Context, channelID uuid.UUID) (*models.ChannelSchedule, error) {
+	if s.schedules == nil {
+		return nil, nil
+	}
+	schedule, err := s.schedules.Get(ctx, channelID)
+	if err != nil || schedule == nil {
+		return nil, err
+	}
+	schedule.State(s.now())
+	return schedule, nil
+}
+
+// effective applies a server's roles, a channel's overwrites and schedule
+// and any timeout or pending screening to a member, whose Roles must be
+// filled in. Members who can manage channels aren't held to schedules.
+func (s *PermissionService) effective(member *models.Member, roles []*models.Role, server *models.Server, channel *models.Channel, overwrites []models.PermissionOverride, schedule *models.ChannelSchedule) (int64, *time.Time) {
+	permissions := models.CalculatePermissions(member, roles, server, channel, overwrites)
+	if !models.HasPermission(permissions, models.PermViewChannels) {
+		return 0, nil
+	}
+	if member.Pending && !models.HasPermission(permissions, models.PermAdministrator) {
+		return permissions & timedOutPermissions, nil
+	}
+	now := s.now()
+	if member.TimedOut(now) && !models.HasPermission(permissions, models.PermAdministrator) {
+		return permissions & timedOutPermissions, member.CommunicationDisabledUntil
+	}
+	if schedule != nil && !models.HasPermission(permissions, models.PermManageChannels) {
+		if !schedule.Open {
+			permissions &^= closedChannelPermissions
+		}
+		return permissions, schedule.NextChangeAt
+	}
+	return permissions, nil
+}
+
+// WarmServer computes and caches members' permissions in each of a
+// server's channels. The server's roles and each channel's overwrites and
+// schedule are read once rather than per member. It returns how many
+// entries were cached.
+func (s *PermissionService) WarmServer(ctx context.Context, server *models.Server, channels []*models.Channel, members []*models.Member) (int, error) {
+	if s.cache == nil {
+		return 0, nil
+	}
+
+	// As in ChannelPermissions, epochs are read before what they guard
+	serverEpoch := s.epoch(ctx, serverEpochKey(server.ID))
+	roles, err := s.roleRepo.GetByServerID(ctx, server.ID)
+	if err != nil {
+		return 0, err
+	}
+	overwrites := make(map[uuid.UUID][]models.PermissionOverride, len(channels))
+	if s.overwrites != nil {
+		for _, channel := range channels {
+			if overwrites[channel.ID], err = s.overwrites.GetByChannelID(ctx, channel.ID); err != nil {
+				return 0, err
+			}
+		}
+	}
+	schedules := make(map[uuid.UUID]*models.ChannelSchedule, len(channels))
+	for _, channel := range channels {
+		if schedules[channel.ID], err = s.schedule(ctx, channel.ID); err != nil {
+			return 0, err
+		}
+	}
+
+	warmed := 0
+	for _, member := range members {
+		if err := ctx.Err(); err != nil {
+			return warmed, err
+		}
+		entry := cachedPermissions{
+			ServerID:    server.ID,
+			ServerEpoch: serverEpoch,
+			MemberEpoch: s.epoch(ctx, memberEpochKey(server.ID, member.UserID)),
+		}
+		memberRoles, err := s.roleRepo.GetMemberRoles(ctx, server.ID, member.UserID)
+		if err != nil {
+			return warmed, err
+		}
+		member.Roles = roleIDs(memberRoles)
+
+		for _, channel := range channels {
+			var expires *time.Time
+			if member.UserID == server.OwnerID {
+				entry.Permissions = models.PermissionAll | models.PermAdministrator
+			} else {
+				entry.Permissions, expires = s.effective(member, roles, server, channel, overwrites[channel.ID], schedules[channel.ID])
+			}
+			s.store(ctx, channel.ID, member.UserID, &entry, expires)
+			warmed++
+		}
+	}
+	return warmed, nil
+}
+
+// lookup returns a cached bitmask if its epochs are still current
+func (s *PermissionService) lookup(ctx context.Context, channelID, userID uuid.UUID) (int64, bool) {
+	if s.cache == nil {
+		return 0, false
+	}
+	data, err := s.cache.Get(ctx, permissionKey(channelID, userID))
+	if err != nil || len(data) == 0 {
+		return 0, false
+	}
+	var entry cachedPermissions
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return 0, false
+	}
+	if entry.ServerEpoch != s.epoch(ctx, serverEpochKey(entry.ServerID)) ||
+		entry.MemberEpoch != s.epoch(ctx, memberEpochKey(entry.ServerID, userID)) {
+		return 0, false
+	}
+	return entry.Permissions, true
+}
+
+func (s *PermissionService) store(ctx context.Context, channelID, userID uuid.UUID, entry *cachedPermissions, expires *time.Time) {
+	if s.cache == nil {
+		return
+	}
+	ttl := permissionCacheTTL
+	if expires != nil {
+		if until := expires.Sub(s.now()); until < ttl {
+			ttl = until
+		}
+	}
+	if ttl <= 0 {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := s.cache.Set(ctx, permissionKey(channelID, userID), data, ttl); err != nil {
+		log.Printf("[Permissions] failed to cache permissions for %s in %s: %v", userID, channelID, err)
+	}
+}
+
+// epoch returns the current value of an epoch key; a missing key reads as
+// an empty epoch
+func (s *PermissionService) epoch(ctx context.Context, key string) string {
+	if s.cache == nil {
+		return ""
+	}
+	data, err := s.cache.Get(ctx, key)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// bump moves an epoch on, orphaning every entry computed under the old one
+func (s *PermissionService) bump(key string) {
+	if s.cache == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), permissionInvalidateTimeout)
+	defer cancel()
+	if err := s.cache.Set(ctx, key, []byte(uuid.NewString()), permissionEpochTTL); err != nil {
+		log.Printf("[Permissions] failed to invalidate %s: %v", key, err)
+	}
+}
+
+// InvalidateServer drops every cached permission in a server
+func (s *PermissionService) InvalidateServer(serverID uuid.UUID) {
+	s.bump(serverEpochKey(serverID))
+}
+
+// InvalidateMember drops a member's cached permissions in a server
+func (s *PermissionService) InvalidateMember(serverID, userID uuid.UUID) {
+	s.bump(memberEpochKey(serverID, userID))
+}
+
+// Start subscribes the cache to the events that change permissions. Role
+// and channel changes (including overwrites) invalidate the whole server;
+// membership and member role changes invalidate just that member.
+func (s *PermissionService) Start(eventBus EventBus) {
+	eventBus.Subscribe("role.created", s.handleServerChanged)
+	eventBus.Subscribe("role.updated", s.handleServerChanged)
+	eventBus.Subscribe("role.deleted", s.handleServerChanged)
+	eventBus.Subscribe("channel.updated", s.handleServerChanged)
+	eventBus.Subscribe("channel.deleted", s.handleServerChanged)
+	eventBus.Subscribe("server.ownership_transferred", s.handleServerChanged)
+	eventBus.Subscribe("server.deleted", s.handleServerChanged)
+
+	eventBus.Subscribe("member.role_added", s.handleMemberChanged)
+	eventBus.Subscribe("member.role_removed", s.handleMemberChanged)
+	eventBus.Subscribe("server.member_joined", s.handleMemberChanged)
+	eventBus.Subscribe("server.member_left", s.handleMemberChanged)
+	eventBus.Subscribe("server.member_kicked", s.handleMemberChanged)
+	eventBus.Subscribe("server.member_banned", s.handleMemberChanged)
+	eventBus.Subscribe("server.member_unbanned", s.handleMemberChanged)
+	eventBus.Subscribe("server.member_updated", s.handleMemberChanged)
+}
+
+func (s *PermissionService) handleServerChanged(data interface{}) {
+	var serverID uuid.UUID
+	switch event := data.(type) {
+	case *RoleCreatedEvent:
+		serverID = event.ServerID
+	case *RoleUpdatedEvent:
+		if event.Role != nil {
+			serverID = event.Role.ServerID
+		}
+	case *RoleDeletedEvent:
+		serverID = event.ServerID
+	case *ChannelUpdatedEvent:
+		if event.Channel != nil && event.Channel.ServerID != nil {
+			serverID = *event.Channel.ServerID
+		}
+	case *ChannelDeletedEvent:
+		if event.ServerID != nil {
+			serverID = *event.ServerID
+		}
+	case *OwnershipTransferredEvent:
+		serverID = event.ServerID
+	case *ServerDeletedEvent:
+		serverID = event.ServerID
+	}
+	if serverID != uuid.Nil {
+		s.InvalidateServer(serverID)
+	}
+}
+
+func (s *PermissionService) handleMemberChanged(data interface{}) {
+	var serverID, userID uuid.UUID
+	switch event := data.(type) {
+	case *MemberRoleAddedEvent:
+		serverID, userID = event.ServerID, event.UserID
+	case *MemberRoleRemovedEvent:
+		serverID, userID = event.ServerID, event.UserID
+	case *MemberJoinedEvent:
+		serverID, userID = event.ServerID, event.UserID
+	case *MemberLeftEvent:
+		serverID, userID = event.ServerID, event.UserID
+	case *MemberKickedEvent:
+		serverID, userID = event.ServerID, event.UserID
+	case *MemberBannedEvent:
+		serverID, userID = event.ServerID, event.UserID
+	case *MemberUnbannedEvent:
+		serverID, userID = event.ServerID, event.UserID
+	case *MemberUpdatedEvent:
+		serverID, userID = event.ServerID, event.UserID
+	}
+	if serverID != uuid.Nil && userID != uuid.Nil {
+		s.InvalidateMember(serverID, userID)
+	}
+}
+
+func permissionKey(channelID, userID uuid.UUID) string {
+	return "permissions:" + channelID.String() + ":" + userID.String()
+}
+
+func serverEpochKey(serverID uuid.UUID) string {
+	return "permissions:epoch:" + serverID.String()
+}
+
+func memberEpochKey(serverID, userID uuid.UUID) string {
+	return "permissions:epoch:" + serverID.String() + ":" + userID.String()
+}
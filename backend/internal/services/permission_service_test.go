@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+// fakePermissionCache keeps generic cache entries in a map
+type fakePermissionCache struct {
+	MockCacheService
+	data map[string][]byte
+	ttls map[string]time.Duration
+}
+
+func newFakePermissionCache() *fakePermissionCache {
+	return &fakePermissionCache{data: map[string][]byte{}, ttls: map[string]time.Duration{}}
+}
+
+func (c *fakePermissionCache) Get(ctx context.Context, key string) ([]byte, error) {
+	data, ok := c.data[key]
+	if !ok {
+		return nil, ErrCacheNotFound
+	}
+	return data, nil
+}
+
+func (c *fakePermissionCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.data[key] = value
+	c.ttls[key] = ttl
+	return nil
+}
+
+func (c *fakePermissionCache) Delete(ctx context.Context, key string) error {
+	delete(c.data, key)
+	return nil
+}
+
+// fakeOverwriteRepository returns fixed overwrites per channel
+type fakeOverwriteRepository map[uuid.UUID][]models.PermissionOverride
+
+func (r fakeOverwriteRepository) GetByChannelID(ctx context.Context, channelID uuid.UUID) ([]models.PermissionOverride, error) {
+	return r[channelID], nil
+}
+
+type permissionFixture struct {
+	service     *PermissionService
+	channelRepo *MockChannelRepository
+	serverRepo  *MockServerRepository
+	roleRepo    *MockRoleRepository
+	cache       *fakePermissionCache
+	overwrites  fakeOverwriteRepository
+
+	server    *models.Server
+	channel   *models.Channel
+	everyone  *models.Role
+	moderator *models.Role
+}
+
+// newPermissionFixture sets up a server whose @everyone role has the
+// default permissions and whose moderator role can manage messages
+func newPermissionFixture() *permissionFixture {
+	serverID := uuid.New()
+	f := &permissionFixture{
+		channelRepo: new(MockChannelRepository),
+		serverRepo:  new(MockServerRepository),
+		roleRepo:    new(MockRoleRepository),
+		cache:       newFakePermissionCache(),
+		overwrites:  fakeOverwriteRepository{},
+		server:      &models.Server{ID: serverID, OwnerID: uuid.New()},
+		channel:     &models.Channel{ID: uuid.New(), ServerID: &serverID, Type: models.ChannelTypeText},
+		everyone:    &models.Role{ID: uuid.New(), ServerID: serverID, Name: "@everyone", Permissions: models.DefaultPermissions, IsDefault: true},
+		moderator:   &models.Role{ID: uuid.New(), ServerID: serverID, Name: "Moderator", Permissions: models.PermManageMessages, Position: 1},
+	}
+	f.service = NewPermissionService(f.channelRepo, f.serverRepo, f.roleRepo, f.overwrites, f.cache)
+
+	f.channelRepo.On("GetByID", mock.Anything, f.channel.ID).Return(f.channel, nil)
+	f.serverRepo.On("GetByID", mock.Anything, serverID).Return(f.server, nil)
+	f.roleRepo.On("GetByServerID", mock.Anything, serverID).Return([]*models.Role{f.moderator, f.everyone}, nil)
+	return f
+}
+
+// addMember makes userID a member holding roles
+func (f *permissionFixture) addMember(userID uuid.UUID, roles ...*models.Role) *models.Member {
+	member := &models.Member{ServerID: f.server.ID, UserID: userID}
+	f.serverRepo.On("GetMember", mock.Anything, f.server.ID, userID).Return(member, nil)
+	f.roleRepo.On("GetMemberRoles", mock.Anything, f.server.ID, userID).Return(roles, nil)
+	return member
+}
+
+func TestPermissionService_ChannelPermissions(t *testing.T) {
+	f := newPermissionFixture()
+	member, moderator := uuid.New(), uuid.New()
+	f.addMember(member)
+	f.addMember(moderator, f.moderator)
+
+	perms, err := f.service.ChannelPermissions(context.Background(), f.channel.ID, member)
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultPermissions, perms, "@everyone applies to members with no roles")
+
+	perms, err = f.service.ChannelPermissions(context.Background(), f.channel.ID, moderator)
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultPermissions|models.PermManageMessages, perms)
+
+	perms, err = f.service.ChannelPermissions(context.Background(), f.channel.ID, f.server.OwnerID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PermissionAll|models.PermAdministrator, perms)
+}
+
+func TestPermissionService_ChannelPermissions_Overwrites(t *testing.T) {
+	f := newPermissionFixture()
+	member, moderator, muted := uuid.New(), uuid.New(), uuid.New()
+	f.addMember(member)
+	f.addMember(moderator, f.moderator)
+	f.addMember(muted, f.moderator)
+	f.overwrites[f.channel.ID] = []models.PermissionOverride{
+		{TargetType: "role", TargetID: f.everyone.ID, Deny: models.PermSendMessages},
+		{TargetType: "role", TargetID: f.moderator.ID, Allow: models.PermSendMessages},
+		{TargetType: "user", TargetID: muted, Deny: models.PermSendMessages},
+	}
+
+	ctx := context.Background()
+	perms, err := f.service.ChannelPermissions(ctx, f.channel.ID, member)
+	require.NoError(t, err)
+	assert.False(t, models.HasPermission(perms, models.PermSendMessages), "@everyone deny")
+
+	perms, err = f.service.ChannelPermissions(ctx, f.channel.ID, moderator)
+	require.NoError(t, err)
+	assert.True(t, models.HasPermission(perms, models.PermSendMessages), "role allow beats @everyone deny")
+
+	perms, err = f.service.ChannelPermissions(ctx, f.channel.ID, muted)
+	require.NoError(t, err)
+	assert.False(t, models.HasPermission(perms, models.PermSendMessages), "user deny beats role allow")
+}
+
+func TestPermissionService_ChannelPermissions_HiddenChannel(t *testing.T) {
+	f := newPermissionFixture()
+	member := uuid.New()
+	f.addMember(member)
+	f.overwrites[f.channel.ID] = []models.PermissionOverride{
+		{TargetType: "role", TargetID: f.everyone.ID, Deny: models.PermViewChannels},
+	}
+
+	perms, err := f.service.ChannelPermissions(context.Background(), f.channel.ID, member)
+	require.NoError(t, err)
+	assert.Zero(t, perms, "nothing applies in a channel the member can't see")
+}
+
+func TestPermissionService_ChannelPermissions_TimedOut(t *testing.T) {
+	f := newPermissionFixture()
+	now := time.Now()
+	f.service.now = func() time.Time { return now }
+	userID := uuid.New()
+	until := now.Add(time.Minute)
+	f.addMember(userID).CommunicationDisabledUntil = &until
+
+	perms, err := f.service.ChannelPermissions(context.Background(), f.channel.ID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PermViewChannels|models.PermReadMessageHistory, perms)
+	assert.Equal(t, time.Minute, f.cache.ttls[permissionKey(f.channel.ID, userID)], "entry expires with the timeout")
+}
+
+func TestPermissionService_ChannelPermissions_Pending(t *testing.T) {
+	f := newPermissionFixture()
+	userID := uuid.New()
+	f.addMember(userID).Pending = true
+
+	perms, err := f.service.ChannelPermissions(context.Background(), f.channel.ID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PermViewChannels|models.PermReadMessageHistory, perms, "pending members can read until they pass screening")
+}
+
+func TestPermissionService_ChannelPermissions_Errors(t *testing.T) {
+	f := newPermissionFixture()
+	outsider := uuid.New()
+	f.serverRepo.On("GetMember", mock.Anything, f.server.ID, outsider).Return(nil, nil)
+	missing := uuid.New()
+	f.channelRepo.On("GetByID", mock.Anything, missing).Return(nil, nil)
+
+	_, err := f.service.ChannelPermissions(context.Background(), f.channel.ID, outsider)
+	assert.ErrorIs(t, err, ErrNotServerMember)
+	_, err = f.service.ChannelPermissions(context.Background(), missing, outsider)
+	assert.ErrorIs(t, err, ErrChannelNotFound)
+}
+
+func TestPermissionService_ChannelPermissions_DM(t *testing.T) {
+	f := newPermissionFixture()
+	alice, bob := uuid.New(), uuid.New()
+	dm := &models.Channel{ID: uuid.New(), Type: models.ChannelTypeDM, Recipients: []uuid.UUID{alice, bob}}
+	f.channelRepo.On("GetByID", mock.Anything, dm.ID).Return(dm, nil)
+
+	perms, err := f.service.ChannelPermissions(context.Background(), dm.ID, alice)
+	require.NoError(t, err)
+	assert.Equal(t, DMPermissions, perms)
+
+	_, err = f.service.ChannelPermissions(context.Background(), dm.ID, uuid.New())
+	assert.ErrorIs(t, err, ErrNotChannelMember)
+}
+
+func TestPermissionService_Caching(t *testing.T) {
+	f := newPermissionFixture()
+	alice, bob := uuid.New(), uuid.New()
+	f.addMember(alice)
+	f.addMember(bob)
+	ctx := context.Background()
+	lookup := func(userID uuid.UUID) int64 {
+		perms, err := f.service.ChannelPermissions(ctx, f.channel.ID, userID)
+		require.NoError(t, err)
+		return perms
+	}
+
+	lookup(alice)
+	lookup(alice)
+	f.roleRepo.AssertNumberOfCalls(t, "GetMemberRoles", 1)
+
+	// Role changes invalidate everyone in the server
+	lookup(bob)
+	f.everyone.Permissions &^= models.PermSendMessages
+	f.service.handleServerChanged(&RoleUpdatedEvent{Role: f.everyone})
+	assert.False(t, models.HasPermission(lookup(alice), models.PermSendMessages))
+	assert.False(t, models.HasPermission(lookup(bob), models.PermSendMessages))
+	f.roleRepo.AssertNumberOfCalls(t, "GetMemberRoles", 4)
+
+	// Member changes invalidate just that member
+	f.service.handleMemberChanged(&MemberRoleAddedEvent{ServerID: f.server.ID, UserID: alice, RoleID: f.moderator.ID})
+	lookup(alice)
+	lookup(bob)
+	f.roleRepo.AssertNumberOfCalls(t, "GetMemberRoles", 5)
+
+	// Overwrites arrive with channel updates
+	f.service.handleServerChanged(&ChannelUpdatedEvent{Channel: f.channel})
+	lookup(bob)
+	f.roleRepo.AssertNumberOfCalls(t, "GetMemberRoles", 6)
+}
+
+func TestPermissionService_WithoutCache(t *testing.T) {
+	f := newPermissionFixture()
+	f.service.cache = nil
+	userID := uuid.New()
+	f.addMember(userID)
+
+	for i := 0; i < 2; i++ {
+		perms, err := f.service.ChannelPermissions(context.Background(), f.channel.ID, userID)
+		require.NoError(t, err)
+		assert.Equal(t, models.DefaultPermissions, perms)
+	}
+	f.roleRepo.AssertNumberOfCalls(t, "GetMemberRoles", 2)
+	f.service.InvalidateServer(f.server.ID)
+}
+
+func TestPermissionService_Start(t *testing.T) {
+	f := newPermissionFixture()
+	bus := new(MockEventBus)
+	bus.On("Subscribe", mock.Anything, mock.Anything).Return()
+
+	f.service.Start(bus)
+
+	for _, event := range []string{"role.updated", "channel.updated", "member.role_added", "server.member_updated"} {
+		bus.AssertCalled(t, "Subscribe", event, mock.Anything)
+	}
+}
@@ -2,6 +2,8 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,6 +14,18 @@ const (
 	presenceTTL     = 2 * time.Minute
 	idleTimeout     = 5 * time.Minute
 	heartbeatInterval = 30 * time.Second
+
+	// Bot presence outlives connections so it can be restored on reconnect
+	botPresenceTTL      = 30 * 24 * time.Hour
+	maxBotActivities    = 5
+	maxActivityNameLen  = 128
+	maxActivityStateLen = 128
+)
+
+var (
+	ErrInvalidPresenceStatus = errors.New("invalid presence status")
+	ErrInvalidActivity       = errors.New("invalid activity")
+	ErrTooManyActivities     = errors.New("too many activities")
 )
 
 // PresenceService handles user presence tracking
@@ -19,6 +33,8 @@ type PresenceService struct {
 	cache     CacheService
 	eventBus  EventBus
 	serverRepo ServerRepository
+	users      UserRepository
+	privacy    PresencePrivacy
 }
 
 // NewPresenceService creates a new presence service
@@ -34,6 +50,16 @@ func NewPresenceService(
 	}
 }
 
+// SetUserRepository lets broadcasts carry the user's custom status
+func (s *PresenceService) SetUserRepository(users UserRepository) {
+	s.users = users
+}
+
+// SetPrivacy lets users show their presence to friends only
+func (s *PresenceService) SetPrivacy(privacy PresencePrivacy) {
+	s.privacy = privacy
+}
+
 // UpdatePresence updates a user's presence
 func (s *PresenceService) UpdatePresence(
 	ctx context.Context,
@@ -147,8 +173,129 @@ func (s *PresenceService) TypingStart(ctx context.Context, userID, channelID uui
 	return nil
 }
 
+// BroadcastPresence publishes a presence change to every server the user
+// is in. The gateway hub calls it when a user connects, disconnects or
+// changes status.
+func (s *PresenceService) BroadcastPresence(ctx context.Context, presence *models.Presence) {
+	s.broadcastPresenceUpdate(ctx, presence.UserID, s.withCustomStatus(ctx, presence))
+}
+
+// withCustomStatus returns a copy of presence carrying the user's active
+// custom status. Offline users don't show one.
+func (s *PresenceService) withCustomStatus(ctx context.Context, presence *models.Presence) *models.Presence {
+	if s.users == nil || presence.Status == models.StatusOffline {
+		return presence
+	}
+	user, err := s.users.GetByID(ctx, presence.UserID)
+	if err != nil || user == nil {
+		return presence
+	}
+
+	enriched := *presence
+	if cs := user.ActiveCustomStatus(time.Now()); cs != nil {
+		enriched.CustomStatus = cs.Text
+		enriched.CustomStatusEmoji = cs.Emoji
+		enriched.CustomStatusExpiresAt = cs.ExpiresAt
+	}
+	return &enriched
+}
+
+// ValidateBotPresence checks a status and activities a bot chose. Offline
+// must already have been turned into invisible.
+func ValidateBotPresence(status models.PresenceStatus, activities []models.Activity) error {
+	switch status {
+	case models.StatusOnline, models.StatusIdle, models.StatusDND, models.StatusInvisible:
+	default:
+		return ErrInvalidPresenceStatus
+	}
+	if len(activities) > maxBotActivities {
+		return ErrTooManyActivities
+	}
+	for _, a := range activities {
+		if a.Type < models.ActivityTypePlaying || a.Type > models.ActivityTypeCompeting {
+			return ErrInvalidActivity
+		}
+		// Custom activities show their state; every other type needs a name
+		if a.Type == models.ActivityTypeCustom {
+			if a.State == "" || len(a.State) > maxActivityStateLen {
+				return ErrInvalidActivity
+			}
+		} else if a.Name == "" || len(a.Name) > maxActivityNameLen {
+			return ErrInvalidActivity
+		}
+	}
+	return nil
+}
+
+// SetBotPresence stores the status and activities a bot chose over the
+// gateway. Fan-out happens through the gateway hub like any other presence.
+func (s *PresenceService) SetBotPresence(
+	ctx context.Context,
+	botID uuid.UUID,
+	status models.PresenceStatus,
+	activities []models.Activity,
+) error {
+	if err := ValidateBotPresence(status, activities); err != nil {
+		return err
+	}
+
+	if s.cache == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(&models.Presence{
+		UserID:     botID,
+		Status:     status,
+		Activities: activities,
+		UpdatedAt:  time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	return s.cache.Set(ctx, botPresenceKey(botID), data, botPresenceTTL)
+}
+
+// GetBotPresence returns a bot's stored presence, or nil if it never set one
+func (s *PresenceService) GetBotPresence(ctx context.Context, botID uuid.UUID) (*models.Presence, error) {
+	if s.cache == nil {
+		return nil, nil
+	}
+
+	data, err := s.cache.Get(ctx, botPresenceKey(botID))
+	if err != nil || data == nil {
+		return nil, nil
+	}
+
+	var presence models.Presence
+	if err := json.Unmarshal(data, &presence); err != nil {
+		return nil, err
+	}
+	return &presence, nil
+}
+
+func botPresenceKey(botID uuid.UUID) string {
+	return "presence:bot:" + botID.String()
+}
+
 // broadcastPresenceUpdate sends presence updates to relevant users
 func (s *PresenceService) broadcastPresenceUpdate(ctx context.Context, userID uuid.UUID, presence *models.Presence) {
+	if s.privacy != nil {
+		friends, restricted, err := s.privacy.PresenceAudience(ctx, userID)
+		if err != nil {
+			// Don't risk showing a hidden presence to everyone
+			return
+		}
+		// Friends-only presence goes straight to each friend, not to servers
+		if restricted {
+			s.eventBus.Publish("presence.updated", &PresenceUpdateEvent{
+				UserID:       userID,
+				Presence:     presence,
+				RecipientIDs: friends,
+			})
+			return
+		}
+	}
+
 	// Get all servers the user is in
 	servers, err := s.serverRepo.GetUserServers(ctx, userID)
 	if err != nil {
@@ -187,4 +334,7 @@ type PresenceUpdateEvent struct {
 	UserID   uuid.UUID
 	ServerID uuid.UUID
 	Presence *models.Presence
+	// RecipientIDs is set instead of ServerID when only these users may
+	// see the presence
+	RecipientIDs []uuid.UUID
 }
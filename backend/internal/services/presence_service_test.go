@@ -196,6 +196,33 @@ func TestPresenceService_UpdatePresence_Success(t *testing.T) {
 	mockEventBus.AssertExpectations(t)
 }
 
+func TestPresenceService_UpdatePresence_FriendsOnly(t *testing.T) {
+	ctx := context.Background()
+	userID, friendID := uuid.New(), uuid.New()
+
+	privacy, repos := newPrivacyTest()
+	repos.befriend(userID, friendID)
+	repos.configure(userID, func(s *models.UserSettings) { s.PrivacyPresenceFriendsOnly = true })
+
+	mockCache := new(MockCacheService)
+	mockEventBus := new(MockEventBus)
+	mockServerRepo := new(MockServerRepositoryForPresence)
+
+	service := NewPresenceService(mockCache, mockEventBus, mockServerRepo)
+	service.SetPrivacy(privacy)
+
+	mockCache.On("Set", ctx, "presence:"+userID.String(), []byte(models.StatusOnline), presenceTTL).Return(nil)
+	mockEventBus.On("Publish", "presence.updated", mock.MatchedBy(func(event *PresenceUpdateEvent) bool {
+		return event.ServerID == uuid.Nil && len(event.RecipientIDs) == 1 && event.RecipientIDs[0] == friendID
+	})).Return().Once()
+
+	err := service.UpdatePresence(ctx, userID, models.StatusOnline, nil, "desktop")
+
+	assert.NoError(t, err)
+	mockEventBus.AssertExpectations(t)
+	mockServerRepo.AssertNotCalled(t, "GetUserServers", ctx, userID)
+}
+
 func TestPresenceService_UpdatePresence_WithCustomStatus(t *testing.T) {
 	ctx := context.Background()
 	userID := uuid.New()
@@ -744,6 +771,92 @@ func TestPresenceService_BroadcastPresenceUpdate_ServerRepoError(t *testing.T) {
 	mockEventBus.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
 }
 
+func TestPresenceService_BroadcastPresence(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	serverID := uuid.New()
+
+	mockEventBus := new(MockEventBus)
+	mockServerRepo := new(MockServerRepositoryForPresence)
+
+	service := NewPresenceService(nil, mockEventBus, mockServerRepo)
+
+	presence := &models.Presence{UserID: userID, Status: models.StatusIdle}
+
+	mockServerRepo.On("GetUserServers", ctx, userID).Return([]*models.Server{{ID: serverID}}, nil)
+	mockEventBus.On("Publish", "presence.updated", &PresenceUpdateEvent{
+		UserID:   userID,
+		ServerID: serverID,
+		Presence: presence,
+	}).Return()
+
+	service.BroadcastPresence(ctx, presence)
+
+	mockEventBus.AssertExpectations(t)
+}
+
+func TestPresenceService_BroadcastPresence_CustomStatus(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	serverID := uuid.New()
+
+	mockEventBus := new(MockEventBus)
+	mockServerRepo := new(MockServerRepositoryForPresence)
+	mockUsers := new(MockUserRepository)
+
+	service := NewPresenceService(nil, mockEventBus, mockServerRepo)
+	service.SetUserRepository(mockUsers)
+
+	text := "Focusing"
+	emoji := "🎧"
+	expiresAt := time.Now().Add(time.Hour)
+	mockUsers.On("GetByID", ctx, userID).Return(&models.User{
+		ID:                    userID,
+		CustomStatus:          &text,
+		CustomStatusEmoji:     &emoji,
+		CustomStatusExpiresAt: &expiresAt,
+	}, nil)
+	mockServerRepo.On("GetUserServers", ctx, userID).Return([]*models.Server{{ID: serverID}}, nil)
+
+	var published *PresenceUpdateEvent
+	mockEventBus.On("Publish", "presence.updated", mock.AnythingOfType("*services.PresenceUpdateEvent")).
+		Run(func(args mock.Arguments) { published = args.Get(1).(*PresenceUpdateEvent) }).Return()
+
+	presence := &models.Presence{UserID: userID, Status: models.StatusDND}
+	service.BroadcastPresence(ctx, presence)
+
+	if !assert.NotNil(t, published) {
+		return
+	}
+	assert.Equal(t, &text, published.Presence.CustomStatus)
+	assert.Equal(t, &emoji, published.Presence.CustomStatusEmoji)
+	assert.Equal(t, &expiresAt, published.Presence.CustomStatusExpiresAt)
+	assert.Nil(t, presence.CustomStatus, "caller's presence must not be modified")
+}
+
+func TestPresenceService_BroadcastPresence_OfflineHidesCustomStatus(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	mockEventBus := new(MockEventBus)
+	mockServerRepo := new(MockServerRepositoryForPresence)
+	mockUsers := new(MockUserRepository)
+
+	service := NewPresenceService(nil, mockEventBus, mockServerRepo)
+	service.SetUserRepository(mockUsers)
+
+	presence := &models.Presence{UserID: userID, Status: models.StatusOffline}
+	mockServerRepo.On("GetUserServers", ctx, userID).Return([]*models.Server{{ID: uuid.New()}}, nil)
+	mockEventBus.On("Publish", "presence.updated", mock.MatchedBy(func(e *PresenceUpdateEvent) bool {
+		return e.Presence == presence
+	})).Return()
+
+	service.BroadcastPresence(ctx, presence)
+
+	mockUsers.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	mockEventBus.AssertExpectations(t)
+}
+
 // ========== PresenceUpdateEvent Tests ==========
 
 func TestPresenceUpdateEvent_Structure(t *testing.T) {
@@ -812,3 +925,74 @@ func TestPresenceService_FullLifecycle(t *testing.T) {
 	err = service.SetOffline(ctx, userID)
 	assert.NoError(t, err)
 }
+
+// ========== Bot Presence Tests ==========
+
+func TestPresenceService_SetBotPresence_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	botID := uuid.New()
+
+	mockCache := new(MockCacheService)
+	service := NewPresenceService(mockCache, new(MockEventBus), new(MockServerRepositoryForPresence))
+
+	var stored []byte
+	mockCache.On("Set", ctx, "presence:bot:"+botID.String(), mock.Anything, botPresenceTTL).
+		Run(func(args mock.Arguments) { stored = args.Get(2).([]byte) }).
+		Return(nil)
+
+	activities := []models.Activity{{Name: "the logs", Type: models.ActivityTypeWatching}}
+	err := service.SetBotPresence(ctx, botID, models.StatusDND, activities)
+	assert.NoError(t, err)
+
+	mockCache.On("Get", ctx, "presence:bot:"+botID.String()).Return(stored, nil)
+
+	presence, err := service.GetBotPresence(ctx, botID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusDND, presence.Status)
+	assert.Equal(t, "the logs", presence.Activities[0].Name)
+	assert.Equal(t, models.ActivityTypeWatching, presence.Activities[0].Type)
+}
+
+func TestPresenceService_SetBotPresence_Validation(t *testing.T) {
+	ctx := context.Background()
+	service := NewPresenceService(nil, nil, nil)
+
+	tests := []struct {
+		name       string
+		status     models.PresenceStatus
+		activities []models.Activity
+		err        error
+	}{
+		{"offline status", models.StatusOffline, nil, ErrInvalidPresenceStatus},
+		{"unknown status", "away", nil, ErrInvalidPresenceStatus},
+		{"missing name", models.StatusOnline, []models.Activity{{Type: models.ActivityTypePlaying}}, ErrInvalidActivity},
+		{"unknown type", models.StatusOnline, []models.Activity{{Name: "x", Type: 9}}, ErrInvalidActivity},
+		{"custom without state", models.StatusOnline, []models.Activity{{Name: "Custom Status", Type: models.ActivityTypeCustom}}, ErrInvalidActivity},
+		{"too many", models.StatusOnline, make([]models.Activity, maxBotActivities+1), ErrTooManyActivities},
+		{"custom with state", models.StatusOnline, []models.Activity{{Type: models.ActivityTypeCustom, State: "Serving 12 servers"}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.SetBotPresence(ctx, uuid.New(), tt.status, tt.activities)
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestPresenceService_GetBotPresence_NotSet(t *testing.T) {
+	ctx := context.Background()
+	botID := uuid.New()
+
+	mockCache := new(MockCacheService)
+	mockCache.On("Get", ctx, "presence:bot:"+botID.String()).Return(nil, ErrCacheNotFound)
+	service := NewPresenceService(mockCache, nil, nil)
+
+	presence, err := service.GetBotPresence(ctx, botID)
+	assert.NoError(t, err)
+	assert.Nil(t, presence)
+}
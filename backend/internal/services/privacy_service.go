@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// PrivacyRelationships is the part of UserRepository privacy checks need
+type PrivacyRelationships interface {
+	GetRelationship(ctx context.Context, userID, targetID uuid.UUID) (int, error)
+	GetFriends(ctx context.Context, userID uuid.UUID) ([]*models.User, error)
+}
+
+// MutualServerRepository finds the servers two users are both in
+type MutualServerRepository interface {
+	GetMutualServers(ctx context.Context, userID1, userID2 uuid.UUID) ([]*models.Server, error)
+}
+
+// DMPrivacy decides who may direct message whom
+type DMPrivacy interface {
+	CanDM(ctx context.Context, senderID, recipientID uuid.UUID) error
+}
+
+// PresencePrivacy decides who sees a user's presence
+type PresencePrivacy interface {
+	// PresenceAudience returns restricted=true and the user's friends if
+	// only friends may see their presence
+	PresenceAudience(ctx context.Context, userID uuid.UUID) (friends []uuid.UUID, restricted bool, err error)
+}
+
+// ReadReceiptPrivacy decides whose reads are shared with DM partners
+type ReadReceiptPrivacy interface {
+	SharesReadReceipts(ctx context.Context, userID uuid.UUID) (bool, error)
+}
+
+// PrivacyService enforces the privacy settings users choose: who may DM
+// them, who sees their presence and whether DM partners see their reads
+type PrivacyService struct {
+	settings      SettingsRepository
+	relationships PrivacyRelationships
+	servers       MutualServerRepository
+}
+
+// NewPrivacyService creates a new privacy service
+func NewPrivacyService(settings SettingsRepository, relationships PrivacyRelationships, servers MutualServerRepository) *PrivacyService {
+	return &PrivacyService{
+		settings:      settings,
+		relationships: relationships,
+		servers:       servers,
+	}
+}
+
+// CanDM returns ErrDMNotAllowed unless the recipient accepts direct
+// messages from the sender. Friends always may; otherwise the recipient has
+// to allow DMs from server members and share a server with the sender.
+func (s *PrivacyService) CanDM(ctx context.Context, senderID, recipientID uuid.UUID) error {
+	if senderID == recipientID {
+		return nil
+	}
+	friends, err := s.areFriends(ctx, recipientID, senderID)
+	if err != nil {
+		return err
+	}
+	if friends {
+		return nil
+	}
+
+	settings, err := s.settingsFor(ctx, recipientID)
+	if err != nil {
+		return err
+	}
+	if settings.PrivacyDMFromFriendsOnly || !settings.PrivacyDMFromServers {
+		return ErrDMNotAllowed
+	}
+	mutual, err := s.servers.GetMutualServers(ctx, senderID, recipientID)
+	if err != nil {
+		return err
+	}
+	if len(mutual) == 0 {
+		return ErrDMNotAllowed
+	}
+	return nil
+}
+
+// PresenceAudience implements PresencePrivacy
+func (s *PrivacyService) PresenceAudience(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, bool, error) {
+	settings, err := s.settingsFor(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !settings.PrivacyPresenceFriendsOnly {
+		return nil, false, nil
+	}
+
+	friends, err := s.relationships.GetFriends(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	ids := make([]uuid.UUID, len(friends))
+	for i, friend := range friends {
+		ids[i] = friend.ID
+	}
+	return ids, true, nil
+}
+
+// FilterPresences replaces the presence of users who hide it from viewerID
+// with offline, in place
+func (s *PrivacyService) FilterPresences(ctx context.Context, viewerID uuid.UUID, presences map[uuid.UUID]*models.Presence) error {
+	for userID, presence := range presences {
+		if presence == nil || presence.Status == models.StatusOffline || userID == viewerID {
+			continue
+		}
+		settings, err := s.settingsFor(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if !settings.PrivacyPresenceFriendsOnly {
+			continue
+		}
+		friends, err := s.areFriends(ctx, userID, viewerID)
+		if err != nil {
+			return err
+		}
+		if !friends {
+			presences[userID] = &models.Presence{UserID: userID, Status: models.StatusOffline}
+		}
+	}
+	return nil
+}
+
+// SharesReadReceipts implements ReadReceiptPrivacy
+func (s *PrivacyService) SharesReadReceipts(ctx context.Context, userID uuid.UUID) (bool, error) {
+	settings, err := s.settingsFor(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return settings.PrivacyReadReceipts, nil
+}
+
+// settingsFor returns the user's settings, or the defaults if they never
+// saved any
+func (s *PrivacyService) settingsFor(ctx context.Context, userID uuid.UUID) (*models.UserSettings, error) {
+	settings, err := s.settings.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		return models.DefaultUserSettings(userID), nil
+	}
+	return settings, nil
+}
+
+func (s *PrivacyService) areFriends(ctx context.Context, userID, otherID uuid.UUID) (bool, error) {
+	relType, err := s.relationships.GetRelationship(ctx, userID, otherID)
+	if err != nil {
+		return false, err
+	}
+	return relType == RelationshipFriend, nil
+}
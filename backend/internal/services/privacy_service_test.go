@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakePrivacyRepos struct {
+	settings map[uuid.UUID]*models.UserSettings
+	friends  map[uuid.UUID][]uuid.UUID
+	mutual   map[[2]uuid.UUID]bool
+}
+
+func newFakePrivacyRepos() *fakePrivacyRepos {
+	return &fakePrivacyRepos{
+		settings: make(map[uuid.UUID]*models.UserSettings),
+		friends:  make(map[uuid.UUID][]uuid.UUID),
+		mutual:   make(map[[2]uuid.UUID]bool),
+	}
+}
+
+func (r *fakePrivacyRepos) befriend(a, b uuid.UUID) {
+	r.friends[a] = append(r.friends[a], b)
+	r.friends[b] = append(r.friends[b], a)
+}
+
+func (r *fakePrivacyRepos) shareServer(a, b uuid.UUID) {
+	r.mutual[[2]uuid.UUID{a, b}] = true
+	r.mutual[[2]uuid.UUID{b, a}] = true
+}
+
+func (r *fakePrivacyRepos) configure(userID uuid.UUID, change func(*models.UserSettings)) {
+	settings := models.DefaultUserSettings(userID)
+	change(settings)
+	r.settings[userID] = settings
+}
+
+func (r *fakePrivacyRepos) Get(ctx context.Context, userID uuid.UUID) (*models.UserSettings, error) {
+	return r.settings[userID], nil
+}
+
+func (r *fakePrivacyRepos) Create(ctx context.Context, settings *models.UserSettings) error {
+	r.settings[settings.UserID] = settings
+	return nil
+}
+
+func (r *fakePrivacyRepos) Update(ctx context.Context, settings *models.UserSettings) error {
+	r.settings[settings.UserID] = settings
+	return nil
+}
+
+func (r *fakePrivacyRepos) Upsert(ctx context.Context, settings *models.UserSettings) error {
+	r.settings[settings.UserID] = settings
+	return nil
+}
+
+func (r *fakePrivacyRepos) Delete(ctx context.Context, userID uuid.UUID) error {
+	delete(r.settings, userID)
+	return nil
+}
+
+func (r *fakePrivacyRepos) GetRelationship(ctx context.Context, userID, targetID uuid.UUID) (int, error) {
+	for _, id := range r.friends[userID] {
+		if id == targetID {
+			return RelationshipFriend, nil
+		}
+	}
+	return 0, nil
+}
+
+func (r *fakePrivacyRepos) GetFriends(ctx context.Context, userID uuid.UUID) ([]*models.User, error) {
+	var friends []*models.User
+	for _, id := range r.friends[userID] {
+		friends = append(friends, &models.User{ID: id})
+	}
+	return friends, nil
+}
+
+func (r *fakePrivacyRepos) GetMutualServers(ctx context.Context, userID1, userID2 uuid.UUID) ([]*models.Server, error) {
+	if r.mutual[[2]uuid.UUID{userID1, userID2}] {
+		return []*models.Server{{ID: uuid.New()}}, nil
+	}
+	return nil, nil
+}
+
+func newPrivacyTest() (*PrivacyService, *fakePrivacyRepos) {
+	repos := newFakePrivacyRepos()
+	return NewPrivacyService(repos, repos, repos), repos
+}
+
+func TestPrivacyService_CanDM(t *testing.T) {
+	ctx := context.Background()
+	svc, repos := newPrivacyTest()
+	sender, recipient := uuid.New(), uuid.New()
+
+	assert.ErrorIs(t, svc.CanDM(ctx, sender, recipient), ErrDMNotAllowed, "strangers need a shared server")
+
+	repos.shareServer(sender, recipient)
+	assert.NoError(t, svc.CanDM(ctx, sender, recipient))
+
+	repos.configure(recipient, func(s *models.UserSettings) { s.PrivacyDMFromServers = false })
+	assert.ErrorIs(t, svc.CanDM(ctx, sender, recipient), ErrDMNotAllowed)
+
+	repos.configure(recipient, func(s *models.UserSettings) { s.PrivacyDMFromFriendsOnly = true })
+	assert.ErrorIs(t, svc.CanDM(ctx, sender, recipient), ErrDMNotAllowed)
+
+	repos.befriend(sender, recipient)
+	assert.NoError(t, svc.CanDM(ctx, sender, recipient), "friends may always DM")
+	assert.NoError(t, svc.CanDM(ctx, sender, sender))
+}
+
+func TestPrivacyService_Presence(t *testing.T) {
+	ctx := context.Background()
+	svc, repos := newPrivacyTest()
+	userID, friendID, viewerID := uuid.New(), uuid.New(), uuid.New()
+	repos.befriend(userID, friendID)
+
+	_, restricted, err := svc.PresenceAudience(ctx, userID)
+	require.NoError(t, err)
+	assert.False(t, restricted)
+
+	repos.configure(userID, func(s *models.UserSettings) { s.PrivacyPresenceFriendsOnly = true })
+	friends, restricted, err := svc.PresenceAudience(ctx, userID)
+	require.NoError(t, err)
+	assert.True(t, restricted)
+	assert.Equal(t, []uuid.UUID{friendID}, friends)
+
+	presences := func() map[uuid.UUID]*models.Presence {
+		return map[uuid.UUID]*models.Presence{
+			userID:   {UserID: userID, Status: models.StatusOnline},
+			viewerID: {UserID: viewerID, Status: models.StatusIdle},
+		}
+	}
+	seenByStranger := presences()
+	require.NoError(t, svc.FilterPresences(ctx, viewerID, seenByStranger))
+	assert.Equal(t, models.StatusOffline, seenByStranger[userID].Status)
+	assert.Equal(t, models.StatusIdle, seenByStranger[viewerID].Status)
+
+	seenByFriend := presences()
+	require.NoError(t, svc.FilterPresences(ctx, friendID, seenByFriend))
+	assert.Equal(t, models.StatusOnline, seenByFriend[userID].Status)
+}
+
+func TestPrivacyService_SharesReadReceipts(t *testing.T) {
+	ctx := context.Background()
+	svc, repos := newPrivacyTest()
+	userID := uuid.New()
+
+	shares, err := svc.SharesReadReceipts(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultUserSettings(userID).PrivacyReadReceipts, shares)
+
+	repos.configure(userID, func(s *models.UserSettings) { s.PrivacyReadReceipts = false })
+	shares, err = svc.SharesReadReceipts(ctx, userID)
+	require.NoError(t, err)
+	assert.False(t, shares)
+}
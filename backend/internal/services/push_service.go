@@ -0,0 +1,488 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+	"hearth/internal/push"
+)
+
+const (
+	maxPushTokenLength = 4096
+	maxPushBodyLength  = 200
+	maxPushAttempts    = 3
+
+	defaultPushRetryBackoff = 500 * time.Millisecond
+	pushDispatchTimeout     = 30 * time.Second
+	webPushSendTimeout      = 10 * time.Second
+	// pushDedupeWindow is how long a delivered (message, user) pair is
+	// remembered, so a user isn't notified twice for the same message
+	pushDedupeWindow = 5 * time.Minute
+)
+
+// PushDeviceRepository defines the interface for push device data access
+type PushDeviceRepository interface {
+	// Upsert registers a device for a user. A token may be registered by
+	// every account signed in on the device.
+	Upsert(ctx context.Context, device *models.PushDevice) error
+	GetByUserID(ctx context.Context, userID uuid.UUID) ([]*models.PushDevice, error)
+	Delete(ctx context.Context, id, userID uuid.UUID) error
+	DeleteByToken(ctx context.Context, token string) error
+}
+
+// ChannelNotificationSettingsRepository defines the interface for per-channel
+// notification overrides
+type ChannelNotificationSettingsRepository interface {
+	Get(ctx context.Context, userID, channelID uuid.UUID) (*models.ChannelNotificationSettings, error)
+	Upsert(ctx context.Context, settings *models.ChannelNotificationSettings) error
+	// GetUserIDsWithLevel lists the users who set a channel to level
+	GetUserIDsWithLevel(ctx context.Context, channelID uuid.UUID, level models.NotificationLevel) ([]uuid.UUID, error)
+}
+
+// pushKind is why a user is being notified about a message
+type pushKind string
+
+const (
+	pushKindMessage       pushKind = "message"
+	pushKindMention       pushKind = "mention"
+	pushKindDirectMessage pushKind = "direct_message"
+)
+
+type pushKey struct {
+	messageID uuid.UUID
+	userID    uuid.UUID
+}
+
+// PushService registers devices and delivers push notifications for new
+// messages and mentions according to each user's notification settings
+type PushService struct {
+	devices         PushDeviceRepository
+	channelSettings ChannelNotificationSettingsRepository
+	settings        SettingsRepository
+	channels        ChannelRepository
+	users           UserRepository
+	eventBus        EventBus
+
+	drivers      map[models.PushPlatform]push.Driver
+	retryBackoff time.Duration
+
+	mu        sync.Mutex
+	delivered map[pushKey]time.Time
+}
+
+// NewPushService creates a new push service. Drivers are added with
+// RegisterDriver; devices on platforms without one are skipped.
+func NewPushService(
+	devices PushDeviceRepository,
+	channelSettings ChannelNotificationSettingsRepository,
+	settings SettingsRepository,
+	channels ChannelRepository,
+	eventBus EventBus,
+) *PushService {
+	return &PushService{
+		devices:         devices,
+		channelSettings: channelSettings,
+		settings:        settings,
+		channels:        channels,
+		eventBus:        eventBus,
+		drivers:         make(map[models.PushPlatform]push.Driver),
+		retryBackoff:    defaultPushRetryBackoff,
+		delivered:       make(map[pushKey]time.Time),
+	}
+}
+
+// NewWebPushClient returns the client WebPush delivers with. Subscription
+// endpoints are URLs users hand us, so like link unfurling it can only
+// reach public addresses. Endpoints that resolve elsewhere fail with
+// push.ErrInvalidToken, so their devices are dropped.
+func NewWebPushClient() *http.Client {
+	return &http.Client{
+		Timeout: webPushSendTimeout,
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         publicDialer(webPushSendTimeout, push.ErrInvalidToken).DialContext,
+			TLSHandshakeTimeout: webPushSendTimeout,
+			MaxIdleConns:        20,
+			IdleConnTimeout:     30 * time.Second,
+		},
+		// Push services answer directly; a redirect is somewhere else
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// RegisterDriver enables delivery for the driver's platform
+func (s *PushService) RegisterDriver(driver push.Driver) {
+	s.drivers[driver.Platform()] = driver
+}
+
+// SetUserRepository lets notifications name the message author
+func (s *PushService) SetUserRepository(users UserRepository) {
+	s.users = users
+}
+
+// Start subscribes the dispatcher to message and mention events
+func (s *PushService) Start() {
+	s.eventBus.Subscribe("message.created", s.handleMessageCreated)
+	s.eventBus.Subscribe("message.mentioned", s.handleMessageMentioned)
+}
+
+func (s *PushService) handleMessageCreated(data interface{}) {
+	event, ok := data.(*MessageCreatedEvent)
+	if !ok || event.Message == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), pushDispatchTimeout)
+		defer cancel()
+		s.pushMessage(ctx, event.Message)
+	}()
+}
+
+func (s *PushService) handleMessageMentioned(data interface{}) {
+	event, ok := data.(*MessageMentionedEvent)
+	if !ok {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), pushDispatchTimeout)
+		defer cancel()
+		s.pushMentions(ctx, event)
+	}()
+}
+
+// RegisterDevice registers a device token for the user
+func (s *PushService) RegisterDevice(ctx context.Context, userID uuid.UUID, req *models.RegisterDeviceRequest) (*models.PushDevice, error) {
+	if !req.Platform.Valid() {
+		return nil, ErrInvalidPushPlatform
+	}
+	if req.Token == "" || len(req.Token) > maxPushTokenLength {
+		return nil, ErrInvalidPushToken
+	}
+
+	now := time.Now()
+	device := &models.PushDevice{
+		ID:         uuid.New(),
+		UserID:     userID,
+		Platform:   req.Platform,
+		Token:      req.Token,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+
+	// WebPush subscriptions are an endpoint URL plus the browser's keys
+	if req.Platform == models.PushPlatformWebPush {
+		endpoint, err := url.Parse(req.Token)
+		if err != nil || endpoint.Scheme != "https" || !isPublicPushHost(endpoint.Hostname()) {
+			return nil, ErrInvalidPushToken
+		}
+		if req.Keys == nil || req.Keys.P256dh == "" || req.Keys.Auth == "" {
+			return nil, ErrInvalidPushToken
+		}
+		device.P256dh = &req.Keys.P256dh
+		device.Auth = &req.Keys.Auth
+	}
+
+	if err := s.devices.Upsert(ctx, device); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// isPublicPushHost reports whether host could be a push service. Push
+// services are reached by name, so IP literals and local names are refused.
+func isPublicPushHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" || net.ParseIP(host) != nil || !strings.Contains(host, ".") {
+		return false
+	}
+	for _, suffix := range []string{".localhost", ".local", ".internal", ".lan", ".home.arpa"} {
+		if strings.HasSuffix(host, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// ListDevices returns the user's registered devices
+func (s *PushService) ListDevices(ctx context.Context, userID uuid.UUID) ([]*models.PushDevice, error) {
+	return s.devices.GetByUserID(ctx, userID)
+}
+
+// UnregisterDevice removes one of the user's devices
+func (s *PushService) UnregisterDevice(ctx context.Context, userID, deviceID uuid.UUID) error {
+	err := s.devices.Delete(ctx, deviceID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPushDeviceNotFound
+	}
+	return err
+}
+
+// GetChannelSettings returns the user's notification overrides for a channel
+func (s *PushService) GetChannelSettings(ctx context.Context, userID, channelID uuid.UUID) (*models.ChannelNotificationSettings, error) {
+	settings, err := s.channelSettings.Get(ctx, userID, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		settings = &models.ChannelNotificationSettings{
+			UserID:    userID,
+			ChannelID: channelID,
+			Level:     models.NotificationLevelDefault,
+		}
+	}
+	return settings, nil
+}
+
+// UpdateChannelSettings replaces the user's notification overrides for a channel
+func (s *PushService) UpdateChannelSettings(ctx context.Context, userID, channelID uuid.UUID, req *models.UpdateChannelNotificationSettingsRequest) (*models.ChannelNotificationSettings, error) {
+	level := req.Level
+	if level == "" {
+		level = models.NotificationLevelDefault
+	}
+	if !level.Valid() {
+		return nil, ErrInvalidNotificationLevel
+	}
+
+	channel, err := s.channels.GetByID(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if channel == nil {
+		return nil, ErrChannelNotFound
+	}
+
+	settings := &models.ChannelNotificationSettings{
+		UserID:     userID,
+		ChannelID:  channelID,
+		Level:      level,
+		MutedUntil: req.MutedUntil,
+		UpdatedAt:  time.Now(),
+	}
+	if err := s.channelSettings.Upsert(ctx, settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// pushMessage notifies DM recipients and users following every message in
+// the channel. Mentioned users are left to pushMentions.
+func (s *PushService) pushMessage(ctx context.Context, message *models.Message) {
+	channel, err := s.channels.GetByID(ctx, message.ChannelID)
+	if err != nil || channel == nil {
+		return
+	}
+
+	kind := pushKindDirectMessage
+	var recipients []uuid.UUID
+	if channel.ServerID == nil {
+		recipients = channel.Recipients
+	} else {
+		if message.MentionEveryone {
+			return
+		}
+		kind = pushKindMessage
+		recipients, err = s.channelSettings.GetUserIDsWithLevel(ctx, channel.ID, models.NotificationLevelAll)
+		if err != nil {
+			log.Printf("[Push] Failed to list subscribers for channel %s: %v", channel.ID, err)
+			return
+		}
+	}
+
+	mentioned := make(map[uuid.UUID]bool, len(message.Mentions))
+	for _, id := range message.Mentions {
+		mentioned[id] = true
+	}
+
+	var users []uuid.UUID
+	for _, id := range recipients {
+		if id != message.AuthorID && !mentioned[id] {
+			users = append(users, id)
+		}
+	}
+	s.deliver(ctx, message, channel, users, kind)
+}
+
+// pushMentions notifies the users a message mentioned
+func (s *PushService) pushMentions(ctx context.Context, event *MessageMentionedEvent) {
+	channel, err := s.channels.GetByID(ctx, event.ChannelID)
+	if err != nil || channel == nil {
+		return
+	}
+	message := event.Message
+	if message == nil {
+		message = &models.Message{
+			ID:        event.MessageID,
+			ChannelID: event.ChannelID,
+			ServerID:  event.ServerID,
+			AuthorID:  event.AuthorID,
+		}
+	}
+	s.deliver(ctx, message, channel, event.UserIDs, pushKindMention)
+}
+
+func (s *PushService) deliver(ctx context.Context, message *models.Message, channel *models.Channel, users []uuid.UUID, kind pushKind) {
+	if len(users) == 0 || len(s.drivers) == 0 {
+		return
+	}
+
+	notification := s.buildNotification(ctx, message, channel, kind)
+	now := time.Now()
+	for _, userID := range users {
+		if !s.shouldNotify(ctx, userID, channel.ID, kind, now) || !s.claim(message.ID, userID, now) {
+			continue
+		}
+
+		devices, err := s.devices.GetByUserID(ctx, userID)
+		if err != nil {
+			log.Printf("[Push] Failed to load devices for user %s: %v", userID, err)
+			continue
+		}
+		for _, device := range devices {
+			if driver, ok := s.drivers[device.Platform]; ok {
+				s.send(ctx, driver, device, notification)
+			}
+		}
+	}
+}
+
+// shouldNotify applies the channel's overrides, then the user's settings
+func (s *PushService) shouldNotify(ctx context.Context, userID, channelID uuid.UUID, kind pushKind, now time.Time) bool {
+	settings, err := s.settings.Get(ctx, userID)
+	if err != nil {
+		return false
+	}
+	if settings == nil {
+		settings = models.DefaultUserSettings(userID)
+	}
+	if !settings.NotificationsEnabled {
+		return false
+	}
+	if kind == pushKindDirectMessage && !settings.NotificationsDM {
+		return false
+	}
+
+	override, err := s.channelSettings.Get(ctx, userID, channelID)
+	if err != nil {
+		return false
+	}
+	if override != nil {
+		if override.Muted(now) {
+			return false
+		}
+		switch override.Level {
+		case models.NotificationLevelNone:
+			return false
+		case models.NotificationLevelMentions:
+			return kind == pushKindMention
+		case models.NotificationLevelAll:
+			return true
+		}
+	}
+
+	// Plain server messages only reach users who asked for every message
+	return kind != pushKindMessage
+}
+
+// claim records a delivery, reporting false if the user was already
+// notified about the message
+func (s *PushService) claim(messageID, userID uuid.UUID, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.delivered) > 1024 {
+		for k, at := range s.delivered {
+			if now.Sub(at) > pushDedupeWindow {
+				delete(s.delivered, k)
+			}
+		}
+	}
+
+	key := pushKey{messageID: messageID, userID: userID}
+	if at, ok := s.delivered[key]; ok && now.Sub(at) <= pushDedupeWindow {
+		return false
+	}
+	s.delivered[key] = now
+	return true
+}
+
+// send delivers to one device, retrying transient failures and forgetting
+// tokens the push service no longer recognises
+func (s *PushService) send(ctx context.Context, driver push.Driver, device *models.PushDevice, n *push.Notification) {
+	backoff := s.retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := driver.Send(ctx, device, n)
+		switch {
+		case err == nil:
+			return
+		case errors.Is(err, push.ErrInvalidToken):
+			if err := s.devices.DeleteByToken(ctx, device.Token); err != nil {
+				log.Printf("[Push] Failed to prune device %s: %v", device.ID, err)
+			}
+			return
+		case errors.Is(err, push.ErrRejected), attempt == maxPushAttempts:
+			log.Printf("[Push] Delivery to %s device %s failed: %v", device.Platform, device.ID, err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (s *PushService) buildNotification(ctx context.Context, message *models.Message, channel *models.Channel, kind pushKind) *push.Notification {
+	author := "Someone"
+	if s.users != nil {
+		if user, err := s.users.GetByID(ctx, message.AuthorID); err == nil && user != nil {
+			author = user.Username
+		}
+	}
+
+	title := author
+	if channel.ServerID != nil && channel.Name != "" {
+		title = author + " (#" + channel.Name + ")"
+	}
+
+	body := message.Content
+	switch {
+	case message.EncryptedContent != "":
+		body = "Sent an encrypted message"
+	case body == "" && len(message.Attachments) > 0:
+		body = "Sent an attachment"
+	case utf8.RuneCountInString(body) > maxPushBodyLength:
+		body = string([]rune(body)[:maxPushBodyLength-1]) + "…"
+	}
+
+	data := map[string]string{
+		"type":       string(kind),
+		"channel_id": message.ChannelID.String(),
+		"message_id": message.ID.String(),
+	}
+	if message.ServerID != nil {
+		data["server_id"] = message.ServerID.String()
+	}
+
+	return &push.Notification{
+		Title: title,
+		Body:  body,
+		Tag:   message.ChannelID.String(),
+		Data:  data,
+	}
+}
@@ -0,0 +1,446 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"hearth/internal/models"
+	"hearth/internal/push"
+)
+
+type MockPushDeviceRepository struct {
+	mock.Mock
+}
+
+func (m *MockPushDeviceRepository) Upsert(ctx context.Context, device *models.PushDevice) error {
+	args := m.Called(ctx, device)
+	return args.Error(0)
+}
+
+func (m *MockPushDeviceRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*models.PushDevice, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.PushDevice), args.Error(1)
+}
+
+func (m *MockPushDeviceRepository) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	args := m.Called(ctx, id, userID)
+	return args.Error(0)
+}
+
+func (m *MockPushDeviceRepository) DeleteByToken(ctx context.Context, token string) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+type MockChannelNotificationSettingsRepository struct {
+	mock.Mock
+}
+
+func (m *MockChannelNotificationSettingsRepository) Get(ctx context.Context, userID, channelID uuid.UUID) (*models.ChannelNotificationSettings, error) {
+	args := m.Called(ctx, userID, channelID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChannelNotificationSettings), args.Error(1)
+}
+
+func (m *MockChannelNotificationSettingsRepository) Upsert(ctx context.Context, settings *models.ChannelNotificationSettings) error {
+	args := m.Called(ctx, settings)
+	return args.Error(0)
+}
+
+func (m *MockChannelNotificationSettingsRepository) GetUserIDsWithLevel(ctx context.Context, channelID uuid.UUID, level models.NotificationLevel) ([]uuid.UUID, error) {
+	args := m.Called(ctx, channelID, level)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+// fakePushDriver records deliveries and fails with the scripted errors first
+type fakePushDriver struct {
+	platform models.PushPlatform
+	errs     []error
+
+	mu    sync.Mutex
+	sent  []*push.Notification
+	calls int
+}
+
+func (d *fakePushDriver) Platform() models.PushPlatform {
+	return d.platform
+}
+
+func (d *fakePushDriver) Send(ctx context.Context, device *models.PushDevice, n *push.Notification) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls++
+	if len(d.errs) > 0 {
+		err := d.errs[0]
+		d.errs = d.errs[1:]
+		return err
+	}
+	d.sent = append(d.sent, n)
+	return nil
+}
+
+func setupPushService() (*PushService, *MockPushDeviceRepository, *MockChannelNotificationSettingsRepository, *MockSettingsRepository, *MockChannelRepository, *fakePushDriver) {
+	devices := new(MockPushDeviceRepository)
+	channelSettings := new(MockChannelNotificationSettingsRepository)
+	settings := new(MockSettingsRepository)
+	channels := new(MockChannelRepository)
+	eventBus := new(MockEventBus)
+
+	service := NewPushService(devices, channelSettings, settings, channels, eventBus)
+	service.retryBackoff = time.Millisecond
+
+	driver := &fakePushDriver{platform: models.PushPlatformFCM}
+	service.RegisterDriver(driver)
+
+	return service, devices, channelSettings, settings, channels, driver
+}
+
+func fcmDevice(userID uuid.UUID) *models.PushDevice {
+	return &models.PushDevice{ID: uuid.New(), UserID: userID, Platform: models.PushPlatformFCM, Token: "token-" + userID.String()}
+}
+
+func TestPushService_RegisterDevice(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	t.Run("fcm", func(t *testing.T) {
+		service, devices, _, _, _, _ := setupPushService()
+		devices.On("Upsert", ctx, mock.AnythingOfType("*models.PushDevice")).Return(nil)
+
+		device, err := service.RegisterDevice(ctx, userID, &models.RegisterDeviceRequest{Platform: models.PushPlatformFCM, Token: "abc"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, userID, device.UserID)
+		assert.Equal(t, "abc", device.Token)
+		assert.Nil(t, device.P256dh)
+	})
+
+	t.Run("webpush", func(t *testing.T) {
+		service, devices, _, _, _, _ := setupPushService()
+		devices.On("Upsert", ctx, mock.AnythingOfType("*models.PushDevice")).Return(nil)
+
+		req := &models.RegisterDeviceRequest{Platform: models.PushPlatformWebPush, Token: "https://push.example.com/sub/1"}
+		_, err := service.RegisterDevice(ctx, userID, req)
+		assert.ErrorIs(t, err, ErrInvalidPushToken, "keys are required")
+
+		req.Keys = &struct {
+			P256dh string `json:"p256dh"`
+			Auth   string `json:"auth"`
+		}{P256dh: "key", Auth: "secret"}
+		device, err := service.RegisterDevice(ctx, userID, req)
+		assert.NoError(t, err)
+		assert.Equal(t, "key", *device.P256dh)
+		assert.Equal(t, "secret", *device.Auth)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		service, _, _, _, _, _ := setupPushService()
+
+		_, err := service.RegisterDevice(ctx, userID, &models.RegisterDeviceRequest{Platform: "sms", Token: "abc"})
+		assert.ErrorIs(t, err, ErrInvalidPushPlatform)
+
+		_, err = service.RegisterDevice(ctx, userID, &models.RegisterDeviceRequest{Platform: models.PushPlatformAPNs})
+		assert.ErrorIs(t, err, ErrInvalidPushToken)
+
+		_, err = service.RegisterDevice(ctx, userID, &models.RegisterDeviceRequest{Platform: models.PushPlatformAPNs, Token: strings.Repeat("a", maxPushTokenLength+1)})
+		assert.ErrorIs(t, err, ErrInvalidPushToken)
+
+		keys := &struct {
+			P256dh string `json:"p256dh"`
+			Auth   string `json:"auth"`
+		}{P256dh: "key", Auth: "secret"}
+		for _, endpoint := range []string{
+			"http://insecure.example.com",
+			"https://127.0.0.1/sub",
+			"https://[::1]:8443/sub",
+			"https://169.254.169.254/latest",
+			"https://localhost/sub",
+			"https://metadata.google.internal/sub",
+			"https://printer.local/sub",
+		} {
+			_, err = service.RegisterDevice(ctx, userID, &models.RegisterDeviceRequest{Platform: models.PushPlatformWebPush, Token: endpoint, Keys: keys})
+			assert.ErrorIs(t, err, ErrInvalidPushToken, endpoint)
+		}
+	})
+}
+
+func TestNewWebPushClient_RefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	resp, err := NewWebPushClient().Post(server.URL, "application/octet-stream", nil)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	assert.ErrorIs(t, err, push.ErrInvalidToken)
+}
+
+func TestPushService_UnregisterDevice(t *testing.T) {
+	ctx := context.Background()
+	service, devices, _, _, _, _ := setupPushService()
+	userID := uuid.New()
+	deviceID := uuid.New()
+	missingID := uuid.New()
+
+	devices.On("Delete", ctx, deviceID, userID).Return(nil)
+	devices.On("Delete", ctx, missingID, userID).Return(sql.ErrNoRows)
+
+	assert.NoError(t, service.UnregisterDevice(ctx, userID, deviceID))
+	assert.ErrorIs(t, service.UnregisterDevice(ctx, userID, missingID), ErrPushDeviceNotFound)
+}
+
+func TestPushService_ChannelSettings(t *testing.T) {
+	ctx := context.Background()
+	service, _, channelSettings, _, channels, _ := setupPushService()
+	userID := uuid.New()
+	channelID := uuid.New()
+
+	channelSettings.On("Get", ctx, userID, channelID).Return(nil, nil)
+	settings, err := service.GetChannelSettings(ctx, userID, channelID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.NotificationLevelDefault, settings.Level)
+
+	_, err = service.UpdateChannelSettings(ctx, userID, channelID, &models.UpdateChannelNotificationSettingsRequest{Level: "loud"})
+	assert.ErrorIs(t, err, ErrInvalidNotificationLevel)
+
+	channels.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID}, nil)
+	channelSettings.On("Upsert", ctx, mock.AnythingOfType("*models.ChannelNotificationSettings")).Return(nil)
+
+	until := time.Now().Add(time.Hour)
+	settings, err = service.UpdateChannelSettings(ctx, userID, channelID, &models.UpdateChannelNotificationSettingsRequest{
+		Level:      models.NotificationLevelMentions,
+		MutedUntil: &until,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, models.NotificationLevelMentions, settings.Level)
+	assert.True(t, settings.Muted(time.Now()))
+}
+
+func TestPushService_UpdateChannelSettings_ChannelNotFound(t *testing.T) {
+	ctx := context.Background()
+	service, _, _, _, channels, _ := setupPushService()
+	channelID := uuid.New()
+
+	channels.On("GetByID", ctx, channelID).Return(nil, nil)
+
+	_, err := service.UpdateChannelSettings(ctx, uuid.New(), channelID, &models.UpdateChannelNotificationSettingsRequest{})
+	assert.ErrorIs(t, err, ErrChannelNotFound)
+}
+
+func TestPushService_PushMessage_DM(t *testing.T) {
+	ctx := context.Background()
+	service, devices, channelSettings, settings, channels, driver := setupPushService()
+	authorID := uuid.New()
+	recipientID := uuid.New()
+	channelID := uuid.New()
+
+	channels.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, Type: models.ChannelTypeDM, Recipients: []uuid.UUID{authorID, recipientID}}, nil)
+	settings.On("Get", ctx, recipientID).Return(nil, nil)
+	channelSettings.On("Get", ctx, recipientID, channelID).Return(nil, nil)
+	devices.On("GetByUserID", ctx, recipientID).Return([]*models.PushDevice{fcmDevice(recipientID)}, nil)
+
+	message := &models.Message{ID: uuid.New(), ChannelID: channelID, AuthorID: authorID, Content: "hey there"}
+	service.pushMessage(ctx, message)
+
+	if assert.Len(t, driver.sent, 1) {
+		assert.Equal(t, "hey there", driver.sent[0].Body)
+		assert.Equal(t, "direct_message", driver.sent[0].Data["type"])
+		assert.Equal(t, channelID.String(), driver.sent[0].Tag)
+	}
+	devices.AssertNotCalled(t, "GetByUserID", ctx, authorID)
+
+	// The same message is only delivered once
+	service.pushMessage(ctx, message)
+	assert.Len(t, driver.sent, 1)
+}
+
+func TestPushService_PushMessage_DMsDisabled(t *testing.T) {
+	ctx := context.Background()
+	service, devices, _, settings, channels, driver := setupPushService()
+	authorID := uuid.New()
+	recipientID := uuid.New()
+	channelID := uuid.New()
+
+	userSettings := models.DefaultUserSettings(recipientID)
+	userSettings.NotificationsDM = false
+
+	channels.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, Recipients: []uuid.UUID{authorID, recipientID}}, nil)
+	settings.On("Get", ctx, recipientID).Return(userSettings, nil)
+
+	service.pushMessage(ctx, &models.Message{ID: uuid.New(), ChannelID: channelID, AuthorID: authorID, Content: "hi"})
+
+	assert.Empty(t, driver.sent)
+	devices.AssertNotCalled(t, "GetByUserID", mock.Anything, mock.Anything)
+}
+
+func TestPushService_PushMessage_ServerChannelSubscribers(t *testing.T) {
+	ctx := context.Background()
+	service, devices, channelSettings, settings, channels, driver := setupPushService()
+	serverID := uuid.New()
+	channelID := uuid.New()
+	authorID := uuid.New()
+	followerID := uuid.New()
+	mentionedID := uuid.New()
+
+	users := new(MockUserRepository)
+	service.SetUserRepository(users)
+	users.On("GetByID", ctx, authorID).Return(&models.User{ID: authorID, Username: "alice"}, nil)
+
+	channels.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, ServerID: &serverID, Name: "general"}, nil)
+	channelSettings.On("GetUserIDsWithLevel", ctx, channelID, models.NotificationLevelAll).Return([]uuid.UUID{followerID, mentionedID, authorID}, nil)
+	channelSettings.On("Get", ctx, followerID, channelID).Return(&models.ChannelNotificationSettings{Level: models.NotificationLevelAll}, nil)
+	settings.On("Get", ctx, followerID).Return(nil, nil)
+	devices.On("GetByUserID", ctx, followerID).Return([]*models.PushDevice{fcmDevice(followerID)}, nil)
+
+	service.pushMessage(ctx, &models.Message{
+		ID:        uuid.New(),
+		ChannelID: channelID,
+		ServerID:  &serverID,
+		AuthorID:  authorID,
+		Content:   strings.Repeat("x", maxPushBodyLength+50),
+		Mentions:  []uuid.UUID{mentionedID},
+	})
+
+	if assert.Len(t, driver.sent, 1) {
+		assert.Equal(t, "alice (#general)", driver.sent[0].Title)
+		assert.Equal(t, maxPushBodyLength, len([]rune(driver.sent[0].Body)))
+		assert.Equal(t, serverID.String(), driver.sent[0].Data["server_id"])
+	}
+	devices.AssertNotCalled(t, "GetByUserID", ctx, mentionedID)
+}
+
+func TestPushService_PushMentions_RespectsChannelSettings(t *testing.T) {
+	ctx := context.Background()
+	service, devices, channelSettings, settings, channels, driver := setupPushService()
+	serverID := uuid.New()
+	channelID := uuid.New()
+	defaultID := uuid.New()
+	mentionsOnlyID := uuid.New()
+	silencedID := uuid.New()
+	mutedID := uuid.New()
+	disabledID := uuid.New()
+
+	until := time.Now().Add(time.Hour)
+	disabled := models.DefaultUserSettings(disabledID)
+	disabled.NotificationsEnabled = false
+
+	channels.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, ServerID: &serverID}, nil)
+	for _, id := range []uuid.UUID{defaultID, mentionsOnlyID, silencedID, mutedID} {
+		settings.On("Get", ctx, id).Return(nil, nil)
+	}
+	settings.On("Get", ctx, disabledID).Return(disabled, nil)
+	channelSettings.On("Get", ctx, defaultID, channelID).Return(nil, nil)
+	channelSettings.On("Get", ctx, mentionsOnlyID, channelID).Return(&models.ChannelNotificationSettings{Level: models.NotificationLevelMentions}, nil)
+	channelSettings.On("Get", ctx, silencedID, channelID).Return(&models.ChannelNotificationSettings{Level: models.NotificationLevelNone}, nil)
+	channelSettings.On("Get", ctx, mutedID, channelID).Return(&models.ChannelNotificationSettings{Level: models.NotificationLevelAll, MutedUntil: &until}, nil)
+	devices.On("GetByUserID", ctx, defaultID).Return([]*models.PushDevice{fcmDevice(defaultID)}, nil)
+	devices.On("GetByUserID", ctx, mentionsOnlyID).Return([]*models.PushDevice{fcmDevice(mentionsOnlyID)}, nil)
+
+	service.pushMentions(ctx, &MessageMentionedEvent{
+		MessageID: uuid.New(),
+		ChannelID: channelID,
+		ServerID:  &serverID,
+		AuthorID:  uuid.New(),
+		UserIDs:   []uuid.UUID{defaultID, mentionsOnlyID, silencedID, mutedID, disabledID},
+	})
+
+	assert.Len(t, driver.sent, 2)
+	for _, n := range driver.sent {
+		assert.Equal(t, "mention", n.Data["type"])
+	}
+}
+
+func TestPushService_Send_RetriesAndPrunes(t *testing.T) {
+	ctx := context.Background()
+	device := fcmDevice(uuid.New())
+	notification := &push.Notification{Title: "t", Body: "b"}
+
+	t.Run("retries transient failures", func(t *testing.T) {
+		service, _, _, _, _, _ := setupPushService()
+		driver := &fakePushDriver{platform: models.PushPlatformFCM, errs: []error{errors.New("unavailable"), errors.New("unavailable")}}
+
+		service.send(ctx, driver, device, notification)
+
+		assert.Equal(t, 3, driver.calls)
+		assert.Len(t, driver.sent, 1)
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		service, _, _, _, _, _ := setupPushService()
+		fail := errors.New("unavailable")
+		driver := &fakePushDriver{platform: models.PushPlatformFCM, errs: []error{fail, fail, fail, fail}}
+
+		service.send(ctx, driver, device, notification)
+
+		assert.Equal(t, maxPushAttempts, driver.calls)
+		assert.Empty(t, driver.sent)
+	})
+
+	t.Run("does not retry rejected notifications", func(t *testing.T) {
+		service, _, _, _, _, _ := setupPushService()
+		driver := &fakePushDriver{platform: models.PushPlatformFCM, errs: []error{push.ErrRejected}}
+
+		service.send(ctx, driver, device, notification)
+
+		assert.Equal(t, 1, driver.calls)
+	})
+
+	t.Run("prunes invalid tokens", func(t *testing.T) {
+		service, devices, _, _, _, _ := setupPushService()
+		driver := &fakePushDriver{platform: models.PushPlatformFCM, errs: []error{push.ErrInvalidToken}}
+		devices.On("DeleteByToken", ctx, device.Token).Return(nil)
+
+		service.send(ctx, driver, device, notification)
+
+		assert.Equal(t, 1, driver.calls)
+		devices.AssertCalled(t, "DeleteByToken", ctx, device.Token)
+	})
+}
+
+func TestPushService_SkipsPlatformsWithoutDriver(t *testing.T) {
+	ctx := context.Background()
+	service, devices, channelSettings, settings, channels, driver := setupPushService()
+	channelID := uuid.New()
+	userID := uuid.New()
+
+	channels.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, Recipients: []uuid.UUID{userID}}, nil)
+	settings.On("Get", ctx, userID).Return(nil, nil)
+	channelSettings.On("Get", ctx, userID, channelID).Return(nil, nil)
+	devices.On("GetByUserID", ctx, userID).Return([]*models.PushDevice{{ID: uuid.New(), Platform: models.PushPlatformAPNs, Token: "apns"}}, nil)
+
+	service.pushMessage(ctx, &models.Message{ID: uuid.New(), ChannelID: channelID, AuthorID: uuid.New(), Content: "hi"})
+
+	assert.Zero(t, driver.calls)
+}
+
+func TestPushService_Start(t *testing.T) {
+	devices := new(MockPushDeviceRepository)
+	eventBus := new(MockEventBus)
+	eventBus.On("Subscribe", "message.created", mock.Anything).Return()
+	eventBus.On("Subscribe", "message.mentioned", mock.Anything).Return()
+
+	NewPushService(devices, nil, nil, nil, eventBus).Start()
+
+	eventBus.AssertExpectations(t)
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	// quotaOverrideCacheTTL bounds how long another instance's quota
+	// changes take to apply here, and how long a user's premium or bot
+	// flag is remembered. Changes made on this instance apply straight away.
+	quotaOverrideCacheTTL = time.Minute
+	// quotaTierCacheSize caps the cached user tiers; the cache starts over
+	// once it is full
+	quotaTierCacheSize = 10000
+)
+
+// QuotaOverrideRepository stores the quotas instance admins set for each
+// tier. The Postgres QuotaOverrideRepository implements it.
+type QuotaOverrideRepository interface {
+	// List returns only the tiers that have overrides
+	List(ctx context.Context) ([]*models.QuotaOverride, error)
+	Save(ctx context.Context, override *models.QuotaOverride) error
+	Delete(ctx context.Context, tier models.QuotaTier) error
+}
+
+type cachedQuotaTier struct {
+	tier    models.QuotaTier
+	expires time.Time
+}
+
+// SetOverrides lets instance admins change quotas per tier at runtime, in
+// place of the static config. Premium and bot tiers are only looked up
+// with a user repository.
+func (s *QuotaService) SetOverrides(repo QuotaOverrideRepository) {
+	s.overrides = repo
+}
+
+// GetQuotas returns the static config and every tier with its overrides
+// and the limits that apply to it
+func (s *QuotaService) GetQuotas(ctx context.Context) (*models.QuotaSettings, error) {
+	overrides, err := s.listOverrides(ctx)
+	if err != nil {
+		return nil, err
+	}
+	settings := &models.QuotaSettings{Config: s.config.Limits()}
+	for _, tier := range models.QuotaTiers {
+		settings.Tiers = append(settings.Tiers, s.tierQuotas(overrides, tier))
+	}
+	return settings, nil
+}
+
+// GetTierQuotas returns a tier's overrides and the limits that apply to it
+func (s *QuotaService) GetTierQuotas(ctx context.Context, tier models.QuotaTier) (*models.QuotaOverride, error) {
+	if !tier.Valid() {
+		return nil, ErrInvalidQuotaTier
+	}
+	overrides, err := s.listOverrides(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.tierQuotas(overrides, tier), nil
+}
+
+// SetTierQuotas overrides a tier's quotas
+func (s *QuotaService) SetTierQuotas(ctx context.Context, adminID uuid.UUID, tier models.QuotaTier, req *models.SetQuotaOverrideRequest) (*models.QuotaOverride, error) {
+	if !tier.Valid() {
+		return nil, ErrInvalidQuotaTier
+	}
+	for _, limit := range []*int{req.MaxServersOwned, req.MaxServersJoined, req.MaxMessageLength} {
+		if limit != nil && *limit < 0 {
+			return nil, ErrInvalidQuota
+		}
+	}
+	for _, limit := range []*int64{req.StorageMB, req.MaxFileSizeMB} {
+		if limit != nil && *limit < 0 {
+			return nil, ErrInvalidQuota
+		}
+	}
+
+	now := s.now()
+	override := &models.QuotaOverride{
+		Tier:             tier,
+		MaxServersOwned:  req.MaxServersOwned,
+		MaxServersJoined: req.MaxServersJoined,
+		MaxMessageLength: req.MaxMessageLength,
+		StorageMB:        req.StorageMB,
+		MaxFileSizeMB:    req.MaxFileSizeMB,
+		UpdatedBy:        &adminID,
+		UpdatedAt:        &now,
+	}
+	if err := s.overrides.Save(ctx, override); err != nil {
+		return nil, err
+	}
+	s.forgetOverrides()
+	return s.GetTierQuotas(ctx, tier)
+}
+
+// ResetTierQuotas removes a tier's overrides so the tiers below apply
+// again
+func (s *QuotaService) ResetTierQuotas(ctx context.Context, tier models.QuotaTier) error {
+	if !tier.Valid() {
+		return ErrInvalidQuotaTier
+	}
+	if err := s.overrides.Delete(ctx, tier); err != nil {
+		return err
+	}
+	s.forgetOverrides()
+	return nil
+}
+
+// applyOverrides puts the overrides of the user's tier in place in limits
+func (s *QuotaService) applyOverrides(ctx context.Context, userID uuid.UUID, limits *EffectiveLimits) {
+	if s.overrides == nil {
+		return
+	}
+	overrides := s.cachedOverrides(ctx)
+	if len(overrides) == 0 {
+		return
+	}
+
+	tier := models.QuotaTierDefault
+	if overrides[models.QuotaTierPremium] != nil || overrides[models.QuotaTierBot] != nil {
+		tier = s.userTier(ctx, userID)
+	}
+	applied := applyQuotaTier(models.QuotaLimits{
+		MaxServersOwned:  limits.MaxServersOwned,
+		MaxServersJoined: limits.MaxServersJoined,
+		MaxMessageLength: limits.MaxMessageLength,
+		StorageMB:        limits.StorageMB,
+		MaxFileSizeMB:    limits.MaxFileSizeMB,
+	}, overrides, tier)
+
+	limits.MaxServersOwned = applied.MaxServersOwned
+	limits.MaxServersJoined = applied.MaxServersJoined
+	limits.MaxMessageLength = applied.MaxMessageLength
+	limits.StorageMB = applied.StorageMB
+	limits.MaxFileSizeMB = applied.MaxFileSizeMB
+}
+
+// applyQuotaTier returns base with the default tier's overrides in place,
+// then tier's
+func applyQuotaTier(base models.QuotaLimits, overrides map[models.QuotaTier]*models.QuotaOverride, tier models.QuotaTier) models.QuotaLimits {
+	limits := overrides[models.QuotaTierDefault].Apply(base)
+	if tier != models.QuotaTierDefault {
+		limits = overrides[tier].Apply(limits)
+	}
+	return limits
+}
+
+func (s *QuotaService) tierQuotas(overrides map[models.QuotaTier]*models.QuotaOverride, tier models.QuotaTier) *models.QuotaOverride {
+	override := overrides[tier]
+	if override == nil {
+		override = &models.QuotaOverride{Tier: tier}
+	}
+	override.Limits = applyQuotaTier(s.config.Limits(), overrides, tier)
+	return override
+}
+
+func (s *QuotaService) listOverrides(ctx context.Context) (map[models.QuotaTier]*models.QuotaOverride, error) {
+	list, err := s.overrides.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	overrides := make(map[models.QuotaTier]*models.QuotaOverride, len(list))
+	for _, override := range list {
+		overrides[override.Tier] = override
+	}
+	return overrides, nil
+}
+
+// cachedOverrides returns every tier's overrides. Lookups that fail are
+// logged and the overrides loaded last keep applying, so premium accounts
+// don't drop to the static config while the database is unreachable.
+func (s *QuotaService) cachedOverrides(ctx context.Context) map[models.QuotaTier]*models.QuotaOverride {
+	now := s.now()
+	s.mu.Lock()
+	if now.Before(s.overridesExpire) {
+		overrides := s.tierOverrides
+		s.mu.Unlock()
+		return overrides
+	}
+	s.mu.Unlock()
+
+	overrides, err := s.listOverrides(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		log.Printf("[Quota] failed to look up quota overrides: %v", err)
+		return s.tierOverrides
+	}
+	s.tierOverrides = overrides
+	s.overridesExpire = now.Add(quotaOverrideCacheTTL)
+	return overrides
+}
+
+// userTier returns the tier a user's quotas come from. Lookups that fail
+// are logged and the user gets the default tier.
+func (s *QuotaService) userTier(ctx context.Context, userID uuid.UUID) models.QuotaTier {
+	if s.userRepo == nil {
+		return models.QuotaTierDefault
+	}
+	now := s.now()
+	s.mu.Lock()
+	cached, ok := s.userTiers[userID]
+	s.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.tier
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		log.Printf("[Quota] failed to look up the tier of %s: %v", userID, err)
+		return models.QuotaTierDefault
+	}
+	tier := models.QuotaTierDefault
+	if user != nil {
+		tier = models.QuotaTierOf(user)
+	}
+
+	s.mu.Lock()
+	if len(s.userTiers) >= quotaTierCacheSize {
+		s.userTiers = make(map[uuid.UUID]cachedQuotaTier)
+	}
+	s.userTiers[userID] = cachedQuotaTier{tier: tier, expires: now.Add(quotaOverrideCacheTTL)}
+	s.mu.Unlock()
+	return tier
+}
+
+func (s *QuotaService) forgetOverrides() {
+	s.mu.Lock()
+	s.overridesExpire = time.Time{}
+	s.mu.Unlock()
+}
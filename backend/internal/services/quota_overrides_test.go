@@ -0,0 +1,208 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+	"hearth/internal/storage"
+)
+
+type fakeQuotaOverrideRepo struct {
+	overrides map[models.QuotaTier]*models.QuotaOverride
+	lists     int
+	err       error
+}
+
+func (r *fakeQuotaOverrideRepo) List(ctx context.Context) ([]*models.QuotaOverride, error) {
+	r.lists++
+	if r.err != nil {
+		return nil, r.err
+	}
+	var result []*models.QuotaOverride
+	for _, o := range r.overrides {
+		copied := *o
+		result = append(result, &copied)
+	}
+	return result, nil
+}
+
+func (r *fakeQuotaOverrideRepo) Save(ctx context.Context, override *models.QuotaOverride) error {
+	copied := *override
+	r.overrides[override.Tier] = &copied
+	return nil
+}
+
+func (r *fakeQuotaOverrideRepo) Delete(ctx context.Context, tier models.QuotaTier) error {
+	delete(r.overrides, tier)
+	return nil
+}
+
+type quotaOverrideTest struct {
+	service *QuotaService
+	repo    *fakeQuotaOverrideRepo
+	users   *MockUserRepository
+	now     time.Time
+}
+
+func newQuotaOverrideTest() *quotaOverrideTest {
+	config := &models.QuotaConfig{
+		Messages: models.MessageQuotaConfig{MaxMessageLength: 2000},
+		Servers:  models.ServerQuotaConfig{MaxServersOwned: 10, MaxServersJoined: 100},
+		Storage:  models.StorageQuotaConfig{UserStorageMB: 500, MaxFileSizeMB: 25},
+	}
+	tt := &quotaOverrideTest{
+		repo:  &fakeQuotaOverrideRepo{overrides: make(map[models.QuotaTier]*models.QuotaOverride)},
+		users: new(MockUserRepository),
+		now:   time.Unix(1700000000, 0),
+	}
+	tt.service = NewQuotaService(config, nil, tt.users, nil)
+	tt.service.SetOverrides(tt.repo)
+	tt.service.now = func() time.Time { return tt.now }
+	return tt
+}
+
+func int64Ptr(v int64) *int64 { return &v }
+
+func TestQuotaService_TierOverrides(t *testing.T) {
+	ctx := context.Background()
+	tt := newQuotaOverrideTest()
+	adminID, userID, premiumID, botID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	tt.users.On("GetByID", mock.Anything, userID).Return(&models.User{ID: userID}, nil)
+	tt.users.On("GetByID", mock.Anything, premiumID).Return(&models.User{ID: premiumID, Flags: models.UserFlagPremium}, nil)
+	tt.users.On("GetByID", mock.Anything, botID).Return(&models.User{ID: botID, Flags: models.UserFlagBot | models.UserFlagPremium}, nil)
+
+	_, err := tt.service.SetTierQuotas(ctx, adminID, models.QuotaTierDefault, &models.SetQuotaOverrideRequest{
+		MaxMessageLength: intPtr(4000),
+	})
+	require.NoError(t, err)
+	limits, err := tt.service.GetEffectiveLimits(ctx, userID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 4000, limits.MaxMessageLength)
+	assert.Equal(t, 10, limits.MaxServersOwned, "limits left out use the static config")
+	tt.users.AssertNotCalled(t, "GetByID", mock.Anything, userID)
+
+	premium, err := tt.service.SetTierQuotas(ctx, adminID, models.QuotaTierPremium, &models.SetQuotaOverrideRequest{
+		MaxServersOwned: intPtr(50),
+		MaxFileSizeMB:   int64Ptr(500),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, adminID, *premium.UpdatedBy)
+	assert.Equal(t, models.QuotaLimits{MaxServersOwned: 50, MaxServersJoined: 100, MaxMessageLength: 4000, StorageMB: 500, MaxFileSizeMB: 500}, premium.Limits)
+
+	limits, err = tt.service.GetEffectiveLimits(ctx, premiumID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 50, limits.MaxServersOwned)
+	assert.Equal(t, int64(500), limits.MaxFileSizeMB)
+	assert.Equal(t, 4000, limits.MaxMessageLength, "the default tier applies below premium")
+
+	limits, err = tt.service.GetEffectiveLimits(ctx, userID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 10, limits.MaxServersOwned)
+
+	limits, err = tt.service.GetEffectiveLimits(ctx, botID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 10, limits.MaxServersOwned, "bots are in the bot tier even with the premium flag")
+
+	require.NoError(t, tt.service.ResetTierQuotas(ctx, models.QuotaTierPremium))
+	limits, err = tt.service.GetEffectiveLimits(ctx, premiumID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 10, limits.MaxServersOwned, "resetting applies straight away")
+}
+
+func TestQuotaService_GetQuotas(t *testing.T) {
+	ctx := context.Background()
+	tt := newQuotaOverrideTest()
+	_, err := tt.service.SetTierQuotas(ctx, uuid.New(), models.QuotaTierBot, &models.SetQuotaOverrideRequest{MaxServersJoined: intPtr(0)})
+	require.NoError(t, err)
+
+	settings, err := tt.service.GetQuotas(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 100, settings.Config.MaxServersJoined)
+	require.Len(t, settings.Tiers, 3)
+	for i, tier := range models.QuotaTiers {
+		assert.Equal(t, tier, settings.Tiers[i].Tier)
+	}
+	assert.Nil(t, settings.Tiers[0].MaxServersJoined)
+	assert.Equal(t, 100, settings.Tiers[0].Limits.MaxServersJoined)
+	assert.Equal(t, 0, settings.Tiers[2].Limits.MaxServersJoined)
+}
+
+func TestQuotaService_SetTierQuotasValidates(t *testing.T) {
+	ctx := context.Background()
+	tt := newQuotaOverrideTest()
+
+	_, err := tt.service.SetTierQuotas(ctx, uuid.New(), "gold", &models.SetQuotaOverrideRequest{})
+	assert.ErrorIs(t, err, ErrInvalidQuotaTier)
+	_, err = tt.service.SetTierQuotas(ctx, uuid.New(), models.QuotaTierDefault, &models.SetQuotaOverrideRequest{MaxMessageLength: intPtr(-1)})
+	assert.ErrorIs(t, err, ErrInvalidQuota)
+	_, err = tt.service.SetTierQuotas(ctx, uuid.New(), models.QuotaTierDefault, &models.SetQuotaOverrideRequest{StorageMB: int64Ptr(-1)})
+	assert.ErrorIs(t, err, ErrInvalidQuota)
+	assert.ErrorIs(t, tt.service.ResetTierQuotas(ctx, "gold"), ErrInvalidQuotaTier)
+	assert.Empty(t, tt.repo.overrides)
+}
+
+func TestQuotaService_OverridesCached(t *testing.T) {
+	ctx := context.Background()
+	tt := newQuotaOverrideTest()
+	userID := uuid.New()
+	tt.repo.overrides[models.QuotaTierDefault] = &models.QuotaOverride{Tier: models.QuotaTierDefault, MaxServersOwned: intPtr(3)}
+
+	for i := 0; i < 3; i++ {
+		limits, err := tt.service.GetEffectiveLimits(ctx, userID, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 3, limits.MaxServersOwned)
+	}
+	assert.Equal(t, 1, tt.repo.lists)
+
+	// Another instance changes the default tier
+	tt.repo.overrides[models.QuotaTierDefault] = &models.QuotaOverride{Tier: models.QuotaTierDefault, MaxServersOwned: intPtr(5)}
+	tt.now = tt.now.Add(quotaOverrideCacheTTL)
+	limits, err := tt.service.GetEffectiveLimits(ctx, userID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 5, limits.MaxServersOwned)
+
+	tt.repo.err = errors.New("database down")
+	tt.now = tt.now.Add(quotaOverrideCacheTTL)
+	limits, err = tt.service.GetEffectiveLimits(ctx, userID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 5, limits.MaxServersOwned, "failed lookups keep the overrides loaded last")
+}
+
+func TestQuotaService_UserLookupFails(t *testing.T) {
+	ctx := context.Background()
+	tt := newQuotaOverrideTest()
+	userID := uuid.New()
+	tt.users.On("GetByID", mock.Anything, userID).Return(nil, errors.New("database down"))
+	tt.repo.overrides[models.QuotaTierPremium] = &models.QuotaOverride{Tier: models.QuotaTierPremium, MaxServersOwned: intPtr(50)}
+
+	limits, err := tt.service.GetEffectiveLimits(ctx, userID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 10, limits.MaxServersOwned, "users that can't be looked up get the default tier")
+}
+
+func TestAttachmentService_Quotas(t *testing.T) {
+	ctx := context.Background()
+	tt := newQuotaOverrideTest()
+	uploaderID, channelID := uuid.New(), uuid.New()
+	tt.repo.overrides[models.QuotaTierDefault] = &models.QuotaOverride{Tier: models.QuotaTierDefault, MaxFileSizeMB: int64Ptr(1)}
+
+	svc := NewAttachmentService(storage.NewService(&mockPresignBackend{newMockStorageBackend()}, 10, nil))
+	svc.SetQuotas(tt.service)
+
+	_, err := svc.Upload(ctx, createTestFileHeader("big.bin", "application/octet-stream", bytes.Repeat([]byte("a"), 2<<20)), uploaderID, channelID)
+	assert.ErrorIs(t, err, ErrFileTooLarge)
+	_, _, err = svc.CreatePendingUpload(ctx, uploaderID, channelID, "big.bin", "application/octet-stream", 2<<20, "")
+	assert.ErrorIs(t, err, ErrFileTooLarge)
+
+	_, err = svc.Upload(ctx, createTestFileHeader("small.txt", "text/plain", []byte("hello")), uploaderID, channelID)
+	assert.NoError(t, err)
+}
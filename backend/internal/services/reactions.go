@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// ReactionLimiter throttles how fast a user can add reactions
+type ReactionLimiter interface {
+	CheckReaction(ctx context.Context, userID uuid.UUID) error
+}
+
+// SetReactionLimiter enables per-user reaction rate limits
+func (s *MessageService) SetReactionLimiter(limiter ReactionLimiter) {
+	s.reactionLimiter = limiter
+}
+
+// checkReactionLimits stops reaction spam before it reaches the gateway:
+// users are rate limited, and a message can only collect so many distinct
+// emoji. Adding to an existing reaction doesn't count towards the cap.
+func (s *MessageService) checkReactionLimits(ctx context.Context, message *models.Message, userID uuid.UUID, emoji string) error {
+	if s.reactionLimiter != nil {
+		if err := s.reactionLimiter.CheckReaction(ctx, userID); err != nil {
+			return ErrReactionRateLimited
+		}
+	}
+
+	if s.quotaService == nil {
+		return nil
+	}
+	limits, err := s.quotaService.GetEffectiveLimits(ctx, userID, message.ServerID)
+	if err != nil {
+		return err
+	}
+	if limits.MaxReactionsPerMessage <= 0 {
+		return nil
+	}
+
+	reactions, err := s.repo.GetReactions(ctx, message.ID)
+	if err != nil {
+		return err
+	}
+	for _, r := range reactions {
+		if r.Emoji == emoji {
+			return nil
+		}
+	}
+	if len(reactions) >= limits.MaxReactionsPerMessage {
+		return ErrTooManyReactions
+	}
+	return nil
+}
+
+// EmojiLookup finds the custom emoji used in reactions. EmojiRepository
+// implements it.
+type EmojiLookup interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Emoji, error)
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Emoji, error)
+}
+
+// SetEmojiRepository lets reactions use custom emoji. Without it only
+// unicode emoji can be added.
+func (s *MessageService) SetEmojiRepository(emojis EmojiLookup) {
+	s.emojis = emojis
+}
+
+// checkCustomEmoji allows reacting with a custom emoji from the message's
+// server, or from any server the user is in
+func (s *MessageService) checkCustomEmoji(ctx context.Context, message *models.Message, userID, emojiID uuid.UUID) error {
+	if s.emojis == nil {
+		return ErrEmojiNotFound
+	}
+	emoji, err := s.emojis.GetByID(ctx, emojiID)
+	if err != nil {
+		return err
+	}
+	if emoji == nil {
+		return ErrEmojiNotFound
+	}
+	if message.ServerID != nil && *message.ServerID == emoji.ServerID {
+		return nil
+	}
+
+	member, err := cachedMember(ctx, s.cache, s.serverRepo, emoji.ServerID, userID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return ErrEmojiNotFound
+	}
+	return nil
+}
+
+// resolveReactionEmoji fills in the emoji of each reaction. Custom emoji
+// that can't be looked up keep just their ID.
+func (s *MessageService) resolveReactionEmoji(ctx context.Context, reactions []*models.Reaction) {
+	var ids []uuid.UUID
+	for _, r := range reactions {
+		if _, id := models.ParseReactionEmoji(r.Emoji); id != nil {
+			r.EmojiInfo = models.ReactionEmoji{ID: id}
+			ids = append(ids, *id)
+		} else {
+			r.EmojiInfo = models.ReactionEmoji{Name: r.Emoji}
+		}
+	}
+	if len(ids) == 0 || s.emojis == nil {
+		return
+	}
+
+	emojis, err := s.emojis.GetByIDs(ctx, ids)
+	if err != nil {
+		log.Printf("[Reactions] failed to look up custom emoji: %v", err)
+		return
+	}
+	byID := make(map[uuid.UUID]*models.Emoji, len(emojis))
+	for _, e := range emojis {
+		byID[e.ID] = e
+	}
+	for _, r := range reactions {
+		if r.EmojiInfo.ID == nil {
+			continue
+		}
+		if e := byID[*r.EmojiInfo.ID]; e != nil {
+			r.EmojiInfo.Name = e.Name
+			r.EmojiInfo.Animated = e.Animated
+			r.EmojiInfo.URL = e.URL
+		}
+	}
+}
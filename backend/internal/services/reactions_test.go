@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"hearth/internal/models"
+)
+
+type stubReactionLimiter struct {
+	err error
+}
+
+func (s *stubReactionLimiter) CheckReaction(ctx context.Context, userID uuid.UUID) error {
+	return s.err
+}
+
+func setupReactionLimits(maxReactions int) (*MessageService, *MockMessageRepository, *MockEventBus) {
+	service, msgRepo, _, _, _, _, _, _, eventBus := setupMessageService()
+	service.quotaService = NewQuotaService(&models.QuotaConfig{
+		Messages: models.MessageQuotaConfig{MaxReactionsPerMessage: maxReactions},
+	}, nil, nil, nil)
+	return service, msgRepo, eventBus
+}
+
+func TestAddReaction_RateLimited(t *testing.T) {
+	service, msgRepo, _ := setupReactionLimits(0)
+	service.SetReactionLimiter(&stubReactionLimiter{err: errors.New("rate limited")})
+	ctx := context.Background()
+	messageID := uuid.New()
+
+	msgRepo.On("GetByID", ctx, messageID).Return(&models.Message{ID: messageID}, nil)
+
+	err := service.AddReaction(ctx, messageID, uuid.New(), "👍")
+
+	assert.Equal(t, ErrReactionRateLimited, err)
+	msgRepo.AssertNotCalled(t, "AddReaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAddReaction_DistinctEmojiCap(t *testing.T) {
+	service, msgRepo, eventBus := setupReactionLimits(2)
+	ctx := context.Background()
+	userID := uuid.New()
+	messageID := uuid.New()
+
+	msgRepo.On("GetByID", ctx, messageID).Return(&models.Message{ID: messageID}, nil)
+	msgRepo.On("GetReactions", ctx, messageID).Return([]*models.Reaction{
+		{Emoji: "👍", Count: 3},
+		{Emoji: "🎉", Count: 1},
+	}, nil)
+
+	// A new emoji would be the third distinct one
+	err := service.AddReaction(ctx, messageID, userID, "🔥")
+	assert.Equal(t, ErrTooManyReactions, err)
+	msgRepo.AssertNotCalled(t, "AddReaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	// Joining an existing reaction is still allowed
+	msgRepo.On("AddReaction", ctx, messageID, userID, "👍").Return(nil)
+	eventBus.On("Publish", "reaction.added", mock.AnythingOfType("*services.ReactionAddedEvent")).Return()
+
+	err = service.AddReaction(ctx, messageID, userID, "👍")
+	assert.NoError(t, err)
+	msgRepo.AssertExpectations(t)
+}
+
+func TestAddReaction_CustomEmoji(t *testing.T) {
+	service, msgRepo, _, serverRepo, _, _, _, _, eventBus := setupMessageService()
+	emojis := &fakeEmojiRepository{emojis: make(map[uuid.UUID]*models.Emoji)}
+	service.SetEmojiRepository(emojis)
+	ctx := context.Background()
+	userID := uuid.New()
+	serverID := uuid.New()
+	otherServerID := uuid.New()
+	messageID := uuid.New()
+
+	local := &models.Emoji{ID: uuid.New(), ServerID: serverID, Name: "blob"}
+	foreign := &models.Emoji{ID: uuid.New(), ServerID: otherServerID, Name: "cat"}
+	emojis.emojis[local.ID] = local
+	emojis.emojis[foreign.ID] = foreign
+
+	msgRepo.On("GetByID", ctx, messageID).Return(&models.Message{ID: messageID, ServerID: &serverID}, nil)
+	serverRepo.On("GetMember", ctx, serverID, userID).Return(&models.Member{UserID: userID, ServerID: serverID}, nil)
+	serverRepo.On("GetMember", ctx, otherServerID, userID).Return(nil, nil).Once()
+	eventBus.On("Publish", "reaction.added", mock.AnythingOfType("*services.ReactionAddedEvent")).Return()
+
+	// The message's own server emoji, referenced as name:id, is stored by ID
+	msgRepo.On("AddReaction", ctx, messageID, userID, local.ID.String()).Return(nil).Once()
+	assert.NoError(t, service.AddReaction(ctx, messageID, userID, "blob:"+local.ID.String()))
+
+	// Another server's emoji needs membership there
+	err := service.AddReaction(ctx, messageID, userID, foreign.ID.String())
+	assert.Equal(t, ErrEmojiNotFound, err)
+
+	serverRepo.On("GetMember", ctx, otherServerID, userID).Return(&models.Member{UserID: userID, ServerID: otherServerID}, nil)
+	msgRepo.On("AddReaction", ctx, messageID, userID, foreign.ID.String()).Return(nil).Once()
+	assert.NoError(t, service.AddReaction(ctx, messageID, userID, foreign.ID.String()))
+
+	// Unknown custom emoji are rejected
+	err = service.AddReaction(ctx, messageID, userID, uuid.New().String())
+	assert.Equal(t, ErrEmojiNotFound, err)
+	msgRepo.AssertExpectations(t)
+}
+
+func TestGetReactions_ResolvesCustomEmoji(t *testing.T) {
+	service, msgRepo, channelRepo, serverRepo, _, _, _, _, _ := setupMessageService()
+	emojis := &fakeEmojiRepository{emojis: make(map[uuid.UUID]*models.Emoji)}
+	service.SetEmojiRepository(emojis)
+	ctx := context.Background()
+	userID := uuid.New()
+	serverID := uuid.New()
+	channelID := uuid.New()
+	messageID := uuid.New()
+
+	blob := &models.Emoji{ID: uuid.New(), ServerID: serverID, Name: "blob", Animated: true, URL: "https://cdn.example.com/blob.gif"}
+	emojis.emojis[blob.ID] = blob
+	deletedID := uuid.New()
+
+	msgRepo.On("GetByID", ctx, messageID).Return(&models.Message{ID: messageID, ChannelID: channelID, ServerID: &serverID}, nil)
+	channelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, ServerID: &serverID}, nil)
+	serverRepo.On("GetMember", ctx, serverID, userID).Return(&models.Member{UserID: userID, ServerID: serverID}, nil)
+	msgRepo.On("GetReactions", ctx, messageID).Return([]*models.Reaction{
+		{MessageID: messageID, Emoji: "👍", Count: 2},
+		{MessageID: messageID, Emoji: blob.ID.String(), Count: 1},
+		{MessageID: messageID, Emoji: deletedID.String(), Count: 1},
+	}, nil)
+	msgRepo.On("GetUserReactions", ctx, messageID, userID).Return([]string{blob.ID.String()}, nil)
+
+	reactions, err := service.GetReactions(ctx, messageID, userID)
+	assert.NoError(t, err)
+	assert.Len(t, reactions, 3)
+	assert.Equal(t, models.ReactionEmoji{Name: "👍"}, reactions[0].EmojiInfo)
+	assert.Equal(t, models.ReactionEmoji{ID: &blob.ID, Name: "blob", Animated: true, URL: blob.URL}, reactions[1].EmojiInfo)
+	assert.True(t, reactions[1].Me)
+	assert.Equal(t, models.ReactionEmoji{ID: &deletedID}, reactions[2].EmojiInfo)
+}
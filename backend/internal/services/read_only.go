@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	// readOnlyCacheTTL is how long an instance goes without checking
+	// whether another one changed the mode
+	readOnlyCacheTTL     = 5 * time.Second
+	maxReadOnlyReason    = 512
+	readOnlyStoreTimeout = 2 * time.Second
+)
+
+// ReadOnlyStore shares the read-only mode between instances. The
+// cache.RedisCache implements it.
+type ReadOnlyStore interface {
+	// GetReadOnlyMode returns nil if the instance is writable
+	GetReadOnlyMode(ctx context.Context) (*models.ReadOnlyMode, error)
+	SetReadOnlyMode(ctx context.Context, mode *models.ReadOnlyMode) error
+	ClearReadOnlyMode(ctx context.Context) error
+}
+
+// ReadOnlyModeUpdatedEvent is published when read-only mode is turned on or
+// off, or its reason changes
+type ReadOnlyModeUpdatedEvent struct {
+	Mode *models.ReadOnlyMode
+}
+
+// ReadOnlyService keeps the instance's read-only mode, in which the API
+// serves reads and refuses writes so operators can fail over or repair
+// data without users changing it underneath them.
+type ReadOnlyService struct {
+	eventBus EventBus
+	store    ReadOnlyStore
+	now      func() time.Time
+
+	mu      sync.Mutex
+	mode    *models.ReadOnlyMode
+	checked time.Time
+}
+
+// NewReadOnlyService creates a read-only mode that applies to this instance
+// only, and is lost when it restarts
+func NewReadOnlyService(eventBus EventBus) *ReadOnlyService {
+	return &ReadOnlyService{
+		eventBus: eventBus,
+		now:      time.Now,
+		mode:     &models.ReadOnlyMode{},
+	}
+}
+
+// SetStore shares the mode between instances, and keeps it across
+// restarts. Other instances pick changes up within 5 seconds.
+func (s *ReadOnlyService) SetStore(store ReadOnlyStore) {
+	s.store = store
+}
+
+// Mode returns the read-only mode. It never returns nil. If the store
+// can't be reached, the last mode seen stays in force.
+func (s *ReadOnlyService) Mode(ctx context.Context) *models.ReadOnlyMode {
+	now := s.now()
+	s.mu.Lock()
+	mode := s.mode
+	if s.store == nil || now.Sub(s.checked) < readOnlyCacheTTL {
+		s.mu.Unlock()
+		return mode
+	}
+	// Requests arriving while this one looks it up use the last mode
+	s.checked = now
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, readOnlyStoreTimeout)
+	defer cancel()
+	stored, err := s.store.GetReadOnlyMode(ctx)
+	if err != nil {
+		log.Printf("[ReadOnly] failed to look up the read-only mode: %v", err)
+		return mode
+	}
+	if stored == nil {
+		stored = &models.ReadOnlyMode{}
+	}
+
+	s.mu.Lock()
+	s.mode = stored
+	s.mu.Unlock()
+	return stored
+}
+
+// SetMode turns read-only mode on or off and tells connected clients, so
+// they can show a banner
+func (s *ReadOnlyService) SetMode(ctx context.Context, adminID uuid.UUID, req *models.SetReadOnlyModeRequest) (*models.ReadOnlyMode, error) {
+	mode := &models.ReadOnlyMode{}
+	if req.Enabled {
+		if req.Reason != nil {
+			reason := strings.TrimSpace(*req.Reason)
+			if utf8.RuneCountInString(reason) > maxReadOnlyReason {
+				return nil, fmt.Errorf("%w: reason can be at most %d characters", ErrInvalidReadOnlyMode, maxReadOnlyReason)
+			}
+			if reason != "" {
+				mode.Reason = &reason
+			}
+		}
+		now := s.now()
+		mode.Enabled = true
+		mode.EnabledBy = &adminID
+		mode.Since = &now
+	}
+
+	if s.store != nil {
+		var err error
+		if mode.Enabled {
+			err = s.store.SetReadOnlyMode(ctx, mode)
+		} else {
+			err = s.store.ClearReadOnlyMode(ctx)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	s.mode = mode
+	s.checked = s.now()
+	s.mu.Unlock()
+
+	if s.eventBus != nil {
+		s.eventBus.Publish("instance.read_only_updated", &ReadOnlyModeUpdatedEvent{Mode: mode})
+	}
+	return mode, nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+// fakeReadOnlyStore stands in for Redis, shared by every instance
+type fakeReadOnlyStore struct {
+	mode *models.ReadOnlyMode
+	gets int
+	err  error
+}
+
+func (s *fakeReadOnlyStore) GetReadOnlyMode(ctx context.Context) (*models.ReadOnlyMode, error) {
+	s.gets++
+	return s.mode, s.err
+}
+
+func (s *fakeReadOnlyStore) SetReadOnlyMode(ctx context.Context, mode *models.ReadOnlyMode) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.mode = mode
+	return nil
+}
+
+func (s *fakeReadOnlyStore) ClearReadOnlyMode(ctx context.Context) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.mode = nil
+	return nil
+}
+
+func TestReadOnlyService_SetMode(t *testing.T) {
+	ctx := context.Background()
+	bus := new(MockEventBus)
+	svc := NewReadOnlyService(bus)
+	adminID := uuid.New()
+	assert.False(t, svc.Mode(ctx).Enabled, "instances start writable")
+
+	bus.On("Publish", "instance.read_only_updated", mock.MatchedBy(func(e *ReadOnlyModeUpdatedEvent) bool {
+		return e.Mode.Enabled
+	})).Return().Once()
+	reason := " failing over to the new primary "
+	mode, err := svc.SetMode(ctx, adminID, &models.SetReadOnlyModeRequest{Enabled: true, Reason: &reason})
+	require.NoError(t, err)
+	assert.Equal(t, "failing over to the new primary", *mode.Reason)
+	assert.Equal(t, adminID, *mode.EnabledBy)
+	assert.NotNil(t, mode.Since)
+	assert.True(t, svc.Mode(ctx).Enabled)
+
+	bus.On("Publish", "instance.read_only_updated", &ReadOnlyModeUpdatedEvent{Mode: &models.ReadOnlyMode{}}).Return().Once()
+	mode, err = svc.SetMode(ctx, adminID, &models.SetReadOnlyModeRequest{Reason: &reason})
+	require.NoError(t, err)
+	assert.Equal(t, &models.ReadOnlyMode{}, mode, "turning it off drops the reason")
+	bus.AssertExpectations(t)
+
+	long := strings.Repeat("a", maxReadOnlyReason+1)
+	_, err = svc.SetMode(ctx, adminID, &models.SetReadOnlyModeRequest{Enabled: true, Reason: &long})
+	assert.ErrorIs(t, err, ErrInvalidReadOnlyMode)
+	assert.False(t, svc.Mode(ctx).Enabled)
+}
+
+func TestReadOnlyService_Store(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeReadOnlyStore{}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	newInstance := func() *ReadOnlyService {
+		svc := NewReadOnlyService(nil)
+		svc.SetStore(store)
+		svc.now = func() time.Time { return now }
+		return svc
+	}
+	first, second := newInstance(), newInstance()
+
+	assert.False(t, second.Mode(ctx).Enabled)
+	_, err := first.SetMode(ctx, uuid.New(), &models.SetReadOnlyModeRequest{Enabled: true})
+	require.NoError(t, err)
+	assert.True(t, first.Mode(ctx).Enabled, "the instance that changed it knows straight away")
+	assert.False(t, second.Mode(ctx).Enabled, "others see it once their cache expires")
+	assert.Equal(t, 1, store.gets)
+
+	now = now.Add(readOnlyCacheTTL)
+	assert.True(t, second.Mode(ctx).Enabled)
+
+	now = now.Add(readOnlyCacheTTL)
+	store.err = errors.New("connection refused")
+	assert.True(t, second.Mode(ctx).Enabled, "the last mode seen stays in force")
+	_, err = first.SetMode(ctx, uuid.New(), &models.SetReadOnlyModeRequest{})
+	assert.Error(t, err)
+	assert.True(t, first.Mode(ctx).Enabled, "a change that wasn't stored isn't applied")
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 
 	"github.com/google/uuid"
 
@@ -12,20 +13,31 @@ import (
 type ReadStateRepository interface {
 	GetReadState(ctx context.Context, userID, channelID uuid.UUID) (*models.ReadState, error)
 	SetReadState(ctx context.Context, userID, channelID uuid.UUID, lastMessageID *uuid.UUID) error
-	IncrementMentionCount(ctx context.Context, userID, channelID uuid.UUID) error
+	IncrementMentionCounts(ctx context.Context, channelID uuid.UUID, userIDs []uuid.UUID) error
 	GetUnreadCount(ctx context.Context, userID, channelID uuid.UUID) (int, error)
 	GetUnreadInfo(ctx context.Context, userID, channelID uuid.UUID) (*models.ChannelUnreadInfo, error)
 	GetUserUnreadSummary(ctx context.Context, userID uuid.UUID) (*models.UnreadSummary, error)
+	GetUserReadStates(ctx context.Context, userID uuid.UUID) ([]models.ReadStateWithUnread, error)
 	GetServerUnreadSummary(ctx context.Context, userID, serverID uuid.UUID) (*models.UnreadSummary, error)
 	MarkServerAsRead(ctx context.Context, userID, serverID uuid.UUID) error
 	DeleteByChannel(ctx context.Context, channelID uuid.UUID) error
 	DeleteByUser(ctx context.Context, userID uuid.UUID) error
 }
 
+// ReadStateMessages looks up the messages users ack. MessageRepository
+// implements it.
+type ReadStateMessages interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Message, error)
+}
+
 // ReadStateService handles read state operations
 type ReadStateService struct {
 	repo        ReadStateRepository
 	channelRepo ChannelRepository
+	eventBus    EventBus
+	receipts    ReadReceiptPrivacy
+	messages    ReadStateMessages
+	permissions ChannelPermissionChecker
 }
 
 // NewReadStateService creates a new read state service
@@ -36,6 +48,29 @@ func NewReadStateService(repo ReadStateRepository, channelRepo ChannelRepository
 	}
 }
 
+// SetEventBus enables MESSAGE_ACK events so a user's other devices see acks
+func (s *ReadStateService) SetEventBus(eventBus EventBus) {
+	s.eventBus = eventBus
+}
+
+// SetMessages makes acks of a specific message check that it's in the
+// acked channel
+func (s *ReadStateService) SetMessages(messages ReadStateMessages) {
+	s.messages = messages
+}
+
+// SetChannelPermissions limits acks to users with VIEW_CHANNELS. Without it
+// anyone can ack any channel.
+func (s *ReadStateService) SetChannelPermissions(permissions ChannelPermissionChecker) {
+	s.permissions = permissions
+}
+
+// SetReadReceiptPrivacy enables read receipts in DMs and group DMs. They're
+// only exchanged between participants who both share theirs.
+func (s *ReadStateService) SetReadReceiptPrivacy(receipts ReadReceiptPrivacy) {
+	s.receipts = receipts
+}
+
 // MarkChannelAsRead marks a channel as read for a user
 func (s *ReadStateService) MarkChannelAsRead(ctx context.Context, userID, channelID uuid.UUID, messageID *uuid.UUID) (*models.AckResponse, error) {
 	// Get the channel to check it exists and get last message
@@ -47,6 +82,10 @@ func (s *ReadStateService) MarkChannelAsRead(ctx context.Context, userID, channe
 		return nil, ErrChannelNotFound
 	}
 
+	if err := s.checkAck(ctx, userID, channel, messageID); err != nil {
+		return nil, err
+	}
+
 	// If no specific message provided, use the channel's last message
 	lastMsgID := messageID
 	if lastMsgID == nil {
@@ -58,14 +97,96 @@ func (s *ReadStateService) MarkChannelAsRead(ctx context.Context, userID, channe
 	if err != nil {
 		return nil, err
 	}
+	// Mentions may have been counted since the reset
+	mentionCount := 0
+	if state, err := s.repo.GetReadState(ctx, userID, channelID); err != nil {
+		return nil, err
+	} else if state != nil {
+		mentionCount = state.MentionCount
+	}
+
+	if s.eventBus != nil {
+		receiptRecipients, err := s.receiptRecipients(ctx, userID, channel)
+		if err != nil {
+			return nil, err
+		}
+		s.eventBus.Publish("message.acked", &MessageAckEvent{
+			UserID:            userID,
+			ChannelID:         channelID,
+			MessageID:         lastMsgID,
+			MentionCount:      mentionCount,
+			ReceiptRecipients: receiptRecipients,
+		})
+	}
 
 	return &models.AckResponse{
 		ChannelID:     channelID,
 		LastMessageID: lastMsgID,
-		MentionCount:  0,
+		MentionCount:  mentionCount,
 	}, nil
 }
 
+// checkAck returns ErrNotChannelMember if the user can't view the channel,
+// and ErrMessageNotFound if the acked message isn't in it
+func (s *ReadStateService) checkAck(ctx context.Context, userID uuid.UUID, channel *models.Channel, messageID *uuid.UUID) error {
+	if s.permissions != nil {
+		canView, err := s.permissions.HasChannelPermission(ctx, channel.ID, userID, models.PermViewChannels)
+		if errors.Is(err, ErrNotServerMember) || errors.Is(err, ErrNotChannelMember) {
+			return ErrNotChannelMember
+		}
+		if err != nil {
+			return err
+		}
+		if !canView {
+			return ErrNotChannelMember
+		}
+	}
+
+	if messageID == nil || s.messages == nil {
+		return nil
+	}
+	message, err := s.messages.GetByID(ctx, *messageID)
+	if err != nil {
+		return err
+	}
+	if message == nil || message.ChannelID != channel.ID {
+		return ErrMessageNotFound
+	}
+	return nil
+}
+
+// receiptRecipients returns the DM participants who should see that userID
+// read the channel
+func (s *ReadStateService) receiptRecipients(ctx context.Context, userID uuid.UUID, channel *models.Channel) ([]uuid.UUID, error) {
+	if s.receipts == nil || (channel.Type != models.ChannelTypeDM && channel.Type != models.ChannelTypeGroupDM) {
+		return nil, nil
+	}
+	shares, err := s.receipts.SharesReadReceipts(ctx, userID)
+	if err != nil || !shares {
+		return nil, err
+	}
+
+	var recipients []uuid.UUID
+	for _, recipientID := range channel.Recipients {
+		if recipientID == userID {
+			continue
+		}
+		shares, err := s.receipts.SharesReadReceipts(ctx, recipientID)
+		if err != nil {
+			return nil, err
+		}
+		if shares {
+			recipients = append(recipients, recipientID)
+		}
+	}
+	return recipients, nil
+}
+
+// GetReadStates returns every read state a user has, with unread counts
+func (s *ReadStateService) GetReadStates(ctx context.Context, userID uuid.UUID) ([]models.ReadStateWithUnread, error) {
+	return s.repo.GetUserReadStates(ctx, userID)
+}
+
 // GetChannelReadState gets the read state for a user in a channel
 func (s *ReadStateService) GetChannelReadState(ctx context.Context, userID, channelID uuid.UUID) (*models.ReadState, error) {
 	return s.repo.GetReadState(ctx, userID, channelID)
@@ -100,9 +221,12 @@ func (s *ReadStateService) MarkServerAsRead(ctx context.Context, userID, serverI
 	return s.repo.MarkServerAsRead(ctx, userID, serverID)
 }
 
-// IncrementMention increments the mention count for a user in a channel
-func (s *ReadStateService) IncrementMention(ctx context.Context, userID, channelID uuid.UUID) error {
-	return s.repo.IncrementMentionCount(ctx, userID, channelID)
+// IncrementMentions increments the mention count of each user in a channel
+func (s *ReadStateService) IncrementMentions(ctx context.Context, channelID uuid.UUID, userIDs []uuid.UUID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return s.repo.IncrementMentionCounts(ctx, channelID, userIDs)
 }
 
 // OnChannelDeleted cleans up read states when a channel is deleted
@@ -114,3 +238,14 @@ func (s *ReadStateService) OnChannelDeleted(ctx context.Context, channelID uuid.
 func (s *ReadStateService) OnUserDeleted(ctx context.Context, userID uuid.UUID) error {
 	return s.repo.DeleteByUser(ctx, userID)
 }
+
+// MessageAckEvent is published when a user marks a channel read
+type MessageAckEvent struct {
+	UserID    uuid.UUID
+	ChannelID uuid.UUID
+	MessageID *uuid.UUID
+	// MentionCount is what's left of the user's mentions in the channel
+	MentionCount int
+	// ReceiptRecipients are the DM participants shown a read receipt
+	ReceiptRecipients []uuid.UUID
+}
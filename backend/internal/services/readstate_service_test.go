@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -30,8 +31,8 @@ func (m *MockReadStateRepository) SetReadState(ctx context.Context, userID, chan
 	return args.Error(0)
 }
 
-func (m *MockReadStateRepository) IncrementMentionCount(ctx context.Context, userID, channelID uuid.UUID) error {
-	args := m.Called(ctx, userID, channelID)
+func (m *MockReadStateRepository) IncrementMentionCounts(ctx context.Context, channelID uuid.UUID, userIDs []uuid.UUID) error {
+	args := m.Called(ctx, channelID, userIDs)
 	return args.Error(0)
 }
 
@@ -56,6 +57,14 @@ func (m *MockReadStateRepository) GetUserUnreadSummary(ctx context.Context, user
 	return args.Get(0).(*models.UnreadSummary), args.Error(1)
 }
 
+func (m *MockReadStateRepository) GetUserReadStates(ctx context.Context, userID uuid.UUID) ([]models.ReadStateWithUnread, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ReadStateWithUnread), args.Error(1)
+}
+
 func (m *MockReadStateRepository) GetServerUnreadSummary(ctx context.Context, userID, serverID uuid.UUID) (*models.UnreadSummary, error) {
 	args := m.Called(ctx, userID, serverID)
 	if args.Get(0) == nil {
@@ -147,6 +156,7 @@ func TestReadStateService_MarkChannelAsRead(t *testing.T) {
 		}
 		mockChannelRepo.On("GetByID", ctx, channelID).Return(channel, nil)
 		mockRepo.On("SetReadState", ctx, userID, channelID, &lastMsgID).Return(nil)
+		mockRepo.On("GetReadState", ctx, userID, channelID).Return(nil, nil)
 
 		ack, err := svc.MarkChannelAsRead(ctx, userID, channelID, nil)
 
@@ -171,6 +181,7 @@ func TestReadStateService_MarkChannelAsRead(t *testing.T) {
 		}
 		mockChannelRepo.On("GetByID", ctx, channelID).Return(channel, nil)
 		mockRepo.On("SetReadState", ctx, userID, channelID, &specificMsgID).Return(nil)
+		mockRepo.On("GetReadState", ctx, userID, channelID).Return(nil, nil)
 
 		ack, err := svc.MarkChannelAsRead(ctx, userID, channelID, &specificMsgID)
 
@@ -197,6 +208,162 @@ func TestReadStateService_MarkChannelAsRead(t *testing.T) {
 	})
 }
 
+func TestReadStateService_MarkChannelAsRead_PublishesAck(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	channelID := uuid.New()
+	messageID := uuid.New()
+
+	mockRepo := new(MockReadStateRepository)
+	mockChannelRepo := new(MockChannelRepositoryForReadState)
+	mockEventBus := new(MockEventBus)
+	svc := NewReadStateService(mockRepo, mockChannelRepo)
+	svc.SetEventBus(mockEventBus)
+
+	mockChannelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID}, nil)
+	mockRepo.On("SetReadState", ctx, userID, channelID, &messageID).Return(nil)
+	mockRepo.On("GetReadState", ctx, userID, channelID).Return(nil, nil)
+	mockEventBus.On("Publish", "message.acked", &MessageAckEvent{
+		UserID:    userID,
+		ChannelID: channelID,
+		MessageID: &messageID,
+	}).Return()
+
+	_, err := svc.MarkChannelAsRead(ctx, userID, channelID, &messageID)
+
+	assert.NoError(t, err)
+	mockEventBus.AssertExpectations(t)
+}
+
+func TestReadStateService_MarkChannelAsRead_ReadReceipts(t *testing.T) {
+	ctx := context.Background()
+	userID, sharingID, hidingID := uuid.New(), uuid.New(), uuid.New()
+	channelID := uuid.New()
+	messageID := uuid.New()
+
+	privacy, repos := newPrivacyTest()
+	repos.configure(hidingID, func(s *models.UserSettings) { s.PrivacyReadReceipts = false })
+
+	mockRepo := new(MockReadStateRepository)
+	mockChannelRepo := new(MockChannelRepositoryForReadState)
+	mockEventBus := new(MockEventBus)
+	svc := NewReadStateService(mockRepo, mockChannelRepo)
+	svc.SetEventBus(mockEventBus)
+	svc.SetReadReceiptPrivacy(privacy)
+
+	mockChannelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{
+		ID:         channelID,
+		Type:       models.ChannelTypeGroupDM,
+		Recipients: []uuid.UUID{userID, sharingID, hidingID},
+	}, nil)
+	mockRepo.On("SetReadState", ctx, userID, channelID, &messageID).Return(nil)
+	mockRepo.On("GetReadState", ctx, userID, channelID).Return(nil, nil)
+	mockEventBus.On("Publish", "message.acked", &MessageAckEvent{
+		UserID:            userID,
+		ChannelID:         channelID,
+		MessageID:         &messageID,
+		ReceiptRecipients: []uuid.UUID{sharingID},
+	}).Return()
+
+	_, err := svc.MarkChannelAsRead(ctx, userID, channelID, &messageID)
+
+	assert.NoError(t, err)
+	mockEventBus.AssertExpectations(t)
+}
+
+func TestReadStateService_MarkChannelAsRead_ChecksAccess(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	channelID := uuid.New()
+	otherChannelID := uuid.New()
+
+	newService := func(perms int64) (*ReadStateService, *MockReadStateRepository, *MockMessageRepository) {
+		mockRepo := new(MockReadStateRepository)
+		mockChannelRepo := new(MockChannelRepositoryForReadState)
+		msgRepo := new(MockMessageRepository)
+		svc := NewReadStateService(mockRepo, mockChannelRepo)
+		svc.SetMessages(msgRepo)
+		svc.SetChannelPermissions(fakeChannelPermissions{channelID: perms})
+		mockChannelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID}, nil)
+		return svc, mockRepo, msgRepo
+	}
+
+	t.Run("needs VIEW_CHANNELS", func(t *testing.T) {
+		svc, mockRepo, msgRepo := newService(models.PermSendMessages)
+
+		_, err := svc.MarkChannelAsRead(ctx, userID, channelID, nil)
+
+		assert.ErrorIs(t, err, ErrNotChannelMember)
+		mockRepo.AssertNotCalled(t, "SetReadState", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		msgRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("message from another channel", func(t *testing.T) {
+		svc, mockRepo, msgRepo := newService(models.PermViewChannels)
+		messageID := uuid.New()
+		msgRepo.On("GetByID", ctx, messageID).Return(&models.Message{ID: messageID, ChannelID: otherChannelID}, nil)
+
+		_, err := svc.MarkChannelAsRead(ctx, userID, channelID, &messageID)
+
+		assert.ErrorIs(t, err, ErrMessageNotFound)
+		mockRepo.AssertNotCalled(t, "SetReadState", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("message in the channel", func(t *testing.T) {
+		svc, mockRepo, msgRepo := newService(models.PermViewChannels)
+		messageID := uuid.New()
+		msgRepo.On("GetByID", ctx, messageID).Return(&models.Message{ID: messageID, ChannelID: channelID}, nil)
+		mockRepo.On("SetReadState", ctx, userID, channelID, &messageID).Return(nil)
+		mockRepo.On("GetReadState", ctx, userID, channelID).Return(&models.ReadState{MentionCount: 1}, nil)
+
+		ack, err := svc.MarkChannelAsRead(ctx, userID, channelID, &messageID)
+
+		assert.NoError(t, err)
+		// A mention counted just after the reset is kept
+		assert.Equal(t, 1, ack.MentionCount)
+	})
+}
+
+func TestReadStateService_MarkChannelAsRead_NoAckOnError(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	channelID := uuid.New()
+
+	mockRepo := new(MockReadStateRepository)
+	mockChannelRepo := new(MockChannelRepositoryForReadState)
+	mockEventBus := new(MockEventBus)
+	svc := NewReadStateService(mockRepo, mockChannelRepo)
+	svc.SetEventBus(mockEventBus)
+
+	mockChannelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID}, nil)
+	mockRepo.On("SetReadState", ctx, userID, channelID, (*uuid.UUID)(nil)).Return(errors.New("db error"))
+
+	_, err := svc.MarkChannelAsRead(ctx, userID, channelID, nil)
+
+	assert.Error(t, err)
+	mockEventBus.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+}
+
+func TestReadStateService_GetReadStates(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	mockRepo := new(MockReadStateRepository)
+	svc := NewReadStateService(mockRepo, new(MockChannelRepositoryForReadState))
+
+	states := []models.ReadStateWithUnread{
+		{ReadState: models.ReadState{UserID: userID, ChannelID: uuid.New(), MentionCount: 2}, UnreadCount: 5},
+		{ReadState: models.ReadState{UserID: userID, ChannelID: uuid.New()}},
+	}
+	mockRepo.On("GetUserReadStates", ctx, userID).Return(states, nil)
+
+	result, err := svc.GetReadStates(ctx, userID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, states, result)
+	mockRepo.AssertExpectations(t)
+}
+
 func TestReadStateService_GetChannelUnreadInfo(t *testing.T) {
 	ctx := context.Background()
 	userID := uuid.New()
@@ -327,23 +494,31 @@ func TestReadStateService_MarkServerAsRead(t *testing.T) {
 	})
 }
 
-func TestReadStateService_IncrementMention(t *testing.T) {
+func TestReadStateService_IncrementMentions(t *testing.T) {
 	ctx := context.Background()
-	userID := uuid.New()
+	userIDs := []uuid.UUID{uuid.New(), uuid.New()}
 	channelID := uuid.New()
 
-	t.Run("increments mention count", func(t *testing.T) {
+	t.Run("increments mention counts together", func(t *testing.T) {
 		mockRepo := new(MockReadStateRepository)
 		mockChannelRepo := new(MockChannelRepositoryForReadState)
 		svc := NewReadStateService(mockRepo, mockChannelRepo)
 
-		mockRepo.On("IncrementMentionCount", ctx, userID, channelID).Return(nil)
+		mockRepo.On("IncrementMentionCounts", ctx, channelID, userIDs).Return(nil)
 
-		err := svc.IncrementMention(ctx, userID, channelID)
+		err := svc.IncrementMentions(ctx, channelID, userIDs)
 
 		assert.NoError(t, err)
 		mockRepo.AssertExpectations(t)
 	})
+
+	t.Run("skips the query for nobody", func(t *testing.T) {
+		mockRepo := new(MockReadStateRepository)
+		svc := NewReadStateService(mockRepo, new(MockChannelRepositoryForReadState))
+
+		assert.NoError(t, svc.IncrementMentions(ctx, channelID, nil))
+		mockRepo.AssertNotCalled(t, "IncrementMentionCounts", mock.Anything, mock.Anything, mock.Anything)
+	})
 }
 
 func TestReadStateService_OnChannelDeleted(t *testing.T) {
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// ReadyUsers loads the user and their relationships. The UserService
+// implements it, reading the user through the cache.
+type ReadyUsers interface {
+	GetUser(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetFriends(ctx context.Context, userID uuid.UUID) ([]*models.User, error)
+	GetIncomingFriendRequests(ctx context.Context, userID uuid.UUID) ([]*models.User, error)
+	GetOutgoingFriendRequests(ctx context.Context, userID uuid.UUID) ([]*models.User, error)
+	GetBlockedUsers(ctx context.Context, userID uuid.UUID) ([]*models.User, error)
+}
+
+// ReadyServers loads the user's servers. The ServerService implements it,
+// reading roles and memberships through the cache.
+type ReadyServers interface {
+	GetUserServers(ctx context.Context, userID uuid.UUID) ([]*models.Server, error)
+	GetChannels(ctx context.Context, serverID uuid.UUID) ([]*models.Channel, error)
+	GetRoles(ctx context.Context, serverID uuid.UUID) ([]*models.Role, error)
+	GetMember(ctx context.Context, serverID, userID uuid.UUID) (*models.Member, error)
+}
+
+// ReadyDMs loads the user's DM channels
+type ReadyDMs interface {
+	GetUserDMs(ctx context.Context, userID uuid.UUID) ([]*models.Channel, error)
+}
+
+// ReadyReadStates loads the user's read states
+type ReadyReadStates interface {
+	GetReadStates(ctx context.Context, userID uuid.UUID) ([]models.ReadStateWithUnread, error)
+}
+
+// ReadyStateService builds the snapshot the gateway sends in READY
+type ReadyStateService struct {
+	users      ReadyUsers
+	servers    ReadyServers
+	dms        ReadyDMs
+	readStates ReadyReadStates
+}
+
+// NewReadyStateService creates a ready state service
+func NewReadyStateService(users ReadyUsers, servers ReadyServers, dms ReadyDMs, readStates ReadyReadStates) *ReadyStateService {
+	return &ReadyStateService{
+		users:      users,
+		servers:    servers,
+		dms:        dms,
+		readStates: readStates,
+	}
+}
+
+// ReadyState returns everything userID's client needs once connected. Any
+// failure fails the whole snapshot so clients never mistake a missing
+// list for an empty one.
+func (s *ReadyStateService) ReadyState(ctx context.Context, userID uuid.UUID) (*models.ReadyState, error) {
+	user, err := s.users.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	servers, err := s.servers.GetUserServers(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	guilds := make([]*models.ReadyGuild, 0, len(servers))
+	for _, server := range servers {
+		guild, err := s.readyGuild(ctx, server, userID)
+		if err != nil {
+			return nil, err
+		}
+		guilds = append(guilds, guild)
+	}
+
+	dms, err := s.dms.GetUserDMs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	readStates, err := s.readStates.GetReadStates(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	relationships, err := s.relationships(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &models.ReadyState{
+		User:            user,
+		Guilds:          guilds,
+		PrivateChannels: dms,
+		ReadStates:      readStates,
+		Relationships:   relationships,
+	}
+	if state.PrivateChannels == nil {
+		state.PrivateChannels = []*models.Channel{}
+	}
+	if state.ReadStates == nil {
+		state.ReadStates = []models.ReadStateWithUnread{}
+	}
+	return state, nil
+}
+
+func (s *ReadyStateService) readyGuild(ctx context.Context, server *models.Server, userID uuid.UUID) (*models.ReadyGuild, error) {
+	guild := &models.ReadyGuild{
+		Server:   server,
+		Channels: []*models.Channel{},
+		Roles:    []*models.Role{},
+	}
+	channels, err := s.servers.GetChannels(ctx, server.ID)
+	if err != nil {
+		return nil, err
+	}
+	if channels != nil {
+		guild.Channels = channels
+	}
+	roles, err := s.servers.GetRoles(ctx, server.ID)
+	if err != nil {
+		return nil, err
+	}
+	if roles != nil {
+		guild.Roles = roles
+	}
+	// The user may have left since the server list was read
+	member, err := s.servers.GetMember(ctx, server.ID, userID)
+	if err != nil && !errors.Is(err, ErrNotServerMember) {
+		return nil, err
+	}
+	guild.Member = member
+	return guild, nil
+}
+
+func (s *ReadyStateService) relationships(ctx context.Context, userID uuid.UUID) ([]models.Relationship, error) {
+	lists := []struct {
+		kind int
+		load func(ctx context.Context, userID uuid.UUID) ([]*models.User, error)
+	}{
+		{models.RelationshipFriend, s.users.GetFriends},
+		{models.RelationshipPendingIn, s.users.GetIncomingFriendRequests},
+		{models.RelationshipPendingOut, s.users.GetOutgoingFriendRequests},
+		{models.RelationshipBlocked, s.users.GetBlockedUsers},
+	}
+
+	relationships := []models.Relationship{}
+	for _, list := range lists {
+		users, err := list.load(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			relationships = append(relationships, models.Relationship{
+				ID:   user.ID,
+				Type: list.kind,
+				User: user.ToPublic(),
+			})
+		}
+	}
+	return relationships, nil
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+// fakeReadySources serves every READY lookup from memory
+type fakeReadySources struct {
+	user     *models.User
+	servers  []*models.Server
+	channels map[uuid.UUID][]*models.Channel
+	roles    map[uuid.UUID][]*models.Role
+	members  map[uuid.UUID]*models.Member
+	friends  []*models.User
+	blocked  []*models.User
+	dms      []*models.Channel
+	states   []models.ReadStateWithUnread
+	rolesErr error
+}
+
+func (f *fakeReadySources) GetUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	return f.user, nil
+}
+
+func (f *fakeReadySources) GetFriends(ctx context.Context, userID uuid.UUID) ([]*models.User, error) {
+	return f.friends, nil
+}
+
+func (f *fakeReadySources) GetIncomingFriendRequests(ctx context.Context, userID uuid.UUID) ([]*models.User, error) {
+	return nil, nil
+}
+
+func (f *fakeReadySources) GetOutgoingFriendRequests(ctx context.Context, userID uuid.UUID) ([]*models.User, error) {
+	return nil, nil
+}
+
+func (f *fakeReadySources) GetBlockedUsers(ctx context.Context, userID uuid.UUID) ([]*models.User, error) {
+	return f.blocked, nil
+}
+
+func (f *fakeReadySources) GetUserServers(ctx context.Context, userID uuid.UUID) ([]*models.Server, error) {
+	return f.servers, nil
+}
+
+func (f *fakeReadySources) GetChannels(ctx context.Context, serverID uuid.UUID) ([]*models.Channel, error) {
+	return f.channels[serverID], nil
+}
+
+func (f *fakeReadySources) GetRoles(ctx context.Context, serverID uuid.UUID) ([]*models.Role, error) {
+	return f.roles[serverID], f.rolesErr
+}
+
+func (f *fakeReadySources) GetMember(ctx context.Context, serverID, userID uuid.UUID) (*models.Member, error) {
+	if member, ok := f.members[serverID]; ok {
+		return member, nil
+	}
+	return nil, ErrNotServerMember
+}
+
+func (f *fakeReadySources) GetUserDMs(ctx context.Context, userID uuid.UUID) ([]*models.Channel, error) {
+	return f.dms, nil
+}
+
+func (f *fakeReadySources) GetReadStates(ctx context.Context, userID uuid.UUID) ([]models.ReadStateWithUnread, error) {
+	return f.states, nil
+}
+
+func newFakeReadyState(sources *fakeReadySources) *ReadyStateService {
+	return NewReadyStateService(sources, sources, sources, sources)
+}
+
+func TestReadyState(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Username: "alice"}
+	joined := &models.Server{ID: uuid.New(), Name: "joined"}
+	left := &models.Server{ID: uuid.New(), Name: "left meanwhile"}
+	channel := &models.Channel{ID: uuid.New(), ServerID: &joined.ID, Name: "general"}
+	role := &models.Role{ID: joined.ID, ServerID: joined.ID, Name: "@everyone"}
+	friend := &models.User{ID: uuid.New(), Username: "bob"}
+	blocked := &models.User{ID: uuid.New(), Username: "mallory"}
+	sources := &fakeReadySources{
+		user:     user,
+		servers:  []*models.Server{joined, left},
+		channels: map[uuid.UUID][]*models.Channel{joined.ID: {channel}},
+		roles:    map[uuid.UUID][]*models.Role{joined.ID: {role}},
+		members:  map[uuid.UUID]*models.Member{joined.ID: {ServerID: joined.ID, UserID: user.ID}},
+		friends:  []*models.User{friend},
+		blocked:  []*models.User{blocked},
+	}
+
+	state, err := newFakeReadyState(sources).ReadyState(context.Background(), user.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, user, state.User)
+	require.Len(t, state.Guilds, 2)
+	assert.Equal(t, []*models.Channel{channel}, state.Guilds[0].Channels)
+	assert.Equal(t, []*models.Role{role}, state.Guilds[0].Roles)
+	assert.Equal(t, user.ID, state.Guilds[0].Member.UserID)
+	assert.Nil(t, state.Guilds[1].Member)
+	assert.NotNil(t, state.Guilds[1].Channels)
+	assert.NotNil(t, state.PrivateChannels)
+	assert.NotNil(t, state.ReadStates)
+	assert.Equal(t, []models.Relationship{
+		{ID: friend.ID, Type: models.RelationshipFriend, User: friend.ToPublic()},
+		{ID: blocked.ID, Type: models.RelationshipBlocked, User: blocked.ToPublic()},
+	}, state.Relationships)
+}
+
+func TestReadyState_FailsWhole(t *testing.T) {
+	server := &models.Server{ID: uuid.New()}
+	sources := &fakeReadySources{
+		user:     &models.User{ID: uuid.New()},
+		servers:  []*models.Server{server},
+		rolesErr: errors.New("database down"),
+	}
+
+	state, err := newFakeReadyState(sources).ReadyState(context.Background(), sources.user.ID)
+	assert.Error(t, err)
+	assert.Nil(t, state)
+}
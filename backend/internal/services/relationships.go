@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// Relationship types as stored in the relationships table. Each row is one
+// user's view of another: friendships and pending requests are written on
+// both sides, blocks only on the blocker's.
+const (
+	RelationshipNone       = 0
+	RelationshipFriend     = 1
+	RelationshipBlocked    = 2
+	RelationshipPendingIn  = 3
+	RelationshipPendingOut = 4
+)
+
+// maxHiddenPageReads bounds how many extra pages GetMessages and GetReplies
+// read when every message on a page was written by a blocked user
+const maxHiddenPageReads = 3
+
+// BlockRepository is the part of UserRepository needed to enforce blocks
+type BlockRepository interface {
+	GetRelationship(ctx context.Context, userID, targetID uuid.UUID) (int, error)
+	GetBlockedUsers(ctx context.Context, userID uuid.UUID) ([]*models.User, error)
+}
+
+// isBlockedEitherWay reports whether either user has blocked the other
+func isBlockedEitherWay(ctx context.Context, repo BlockRepository, a, b uuid.UUID) (bool, error) {
+	relType, err := repo.GetRelationship(ctx, a, b)
+	if err != nil {
+		return false, err
+	}
+	if relType == RelationshipBlocked {
+		return true, nil
+	}
+	relType, err = repo.GetRelationship(ctx, b, a)
+	if err != nil {
+		return false, err
+	}
+	return relType == RelationshipBlocked, nil
+}
+
+// blockedUserIDs returns the set of users userID has blocked
+func blockedUserIDs(ctx context.Context, repo BlockRepository, userID uuid.UUID) (map[uuid.UUID]bool, error) {
+	users, err := repo.GetBlockedUsers(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	blocked := make(map[uuid.UUID]bool, len(users))
+	for _, user := range users {
+		blocked[user.ID] = true
+	}
+	return blocked, nil
+}
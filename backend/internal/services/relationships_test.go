@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+func TestSendMessage_DMBlocked(t *testing.T) {
+	service, msgRepo, channelRepo, _, _, _, _, _, _ := setupMessageService()
+	blocks := new(MockUserRepository)
+	service.SetBlockRepository(blocks)
+	ctx := context.Background()
+
+	authorID := uuid.New()
+	recipientID := uuid.New()
+	channelID := uuid.New()
+	channel := &models.Channel{
+		ID:         channelID,
+		Type:       models.ChannelTypeDM,
+		Recipients: []uuid.UUID{authorID, recipientID},
+	}
+
+	// The recipient blocked the author
+	channelRepo.On("GetByID", ctx, channelID).Return(channel, nil)
+	blocks.On("GetRelationship", ctx, authorID, recipientID).Return(RelationshipNone, nil)
+	blocks.On("GetRelationship", ctx, recipientID, authorID).Return(RelationshipBlocked, nil)
+
+	message, err := service.SendMessage(ctx, authorID, channelID, "Hello!", nil, nil)
+
+	assert.Equal(t, ErrUserBlocked, err)
+	assert.Nil(t, message)
+	msgRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestGetMessages_HidesBlockedAuthors(t *testing.T) {
+	service, msgRepo, channelRepo, serverRepo, _, _, _, _, _ := setupMessageService()
+	blocks := new(MockUserRepository)
+	service.SetBlockRepository(blocks)
+	ctx := context.Background()
+
+	requesterID := uuid.New()
+	blockedID := uuid.New()
+	friendID := uuid.New()
+	serverID := uuid.New()
+	channelID := uuid.New()
+	visible := &models.Message{ID: uuid.New(), AuthorID: friendID}
+
+	channelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, ServerID: &serverID}, nil)
+	serverRepo.On("GetMember", ctx, serverID, requesterID).Return(&models.Member{UserID: requesterID}, nil)
+	msgRepo.On("GetChannelMessages", ctx, channelID, (*uuid.UUID)(nil), (*uuid.UUID)(nil), 50).Return([]*models.Message{
+		{ID: uuid.New(), AuthorID: blockedID},
+		visible,
+		{ID: uuid.New(), AuthorID: blockedID},
+	}, nil)
+	blocks.On("GetBlockedUsers", ctx, requesterID).Return([]*models.User{{ID: blockedID}}, nil)
+
+	messages, err := service.GetMessages(ctx, channelID, requesterID, nil, nil, 50)
+
+	require.NoError(t, err)
+	assert.Equal(t, []*models.Message{visible}, messages)
+}
+
+func TestGetMessages_ReadsPastHiddenPage(t *testing.T) {
+	service, msgRepo, channelRepo, serverRepo, _, _, _, _, _ := setupMessageService()
+	blocks := new(MockUserRepository)
+	service.SetBlockRepository(blocks)
+	ctx := context.Background()
+
+	requesterID := uuid.New()
+	blockedID := uuid.New()
+	serverID := uuid.New()
+	channelID := uuid.New()
+	hidden := []*models.Message{
+		{ID: uuid.New(), AuthorID: blockedID},
+		{ID: uuid.New(), AuthorID: blockedID},
+	}
+	older := &models.Message{ID: uuid.New(), AuthorID: uuid.New()}
+	cursor := hidden[1].ID
+
+	channelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, ServerID: &serverID}, nil)
+	serverRepo.On("GetMember", ctx, serverID, requesterID).Return(&models.Member{UserID: requesterID}, nil)
+	msgRepo.On("GetChannelMessages", ctx, channelID, (*uuid.UUID)(nil), (*uuid.UUID)(nil), 2).Return(hidden, nil).Once()
+	msgRepo.On("GetChannelMessages", ctx, channelID, &cursor, (*uuid.UUID)(nil), 2).Return([]*models.Message{older}, nil).Once()
+	blocks.On("GetBlockedUsers", ctx, requesterID).Return([]*models.User{{ID: blockedID}}, nil)
+
+	messages, err := service.GetMessages(ctx, channelID, requesterID, nil, nil, 2)
+
+	require.NoError(t, err)
+	assert.Equal(t, []*models.Message{older}, messages)
+	msgRepo.AssertExpectations(t)
+}
+
+func TestGetOrCreateDM_Blocked(t *testing.T) {
+	service, channelRepo, _, _, _ := setupChannelService()
+	blocks := new(MockUserRepository)
+	service.SetBlockRepository(blocks)
+	ctx := context.Background()
+
+	user1ID := uuid.New()
+	user2ID := uuid.New()
+
+	channelRepo.On("GetDMChannel", ctx, user1ID, user2ID).Return(nil, nil)
+	blocks.On("GetRelationship", ctx, user1ID, user2ID).Return(RelationshipBlocked, nil)
+
+	channel, err := service.GetOrCreateDM(ctx, user1ID, user2ID)
+
+	assert.Equal(t, ErrUserBlocked, err)
+	assert.Nil(t, channel)
+	channelRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
@@ -3,72 +3,407 @@ package services
 import (
 	"context"
 	"errors"
-	"sync"
+	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	maxReportDetails = 1000
+	maxReportNote    = 2000
+	// maxUnresolvedReports caps how many of a user's reports can wait for
+	// review at once, so one account can't flood the queue
+	maxUnresolvedReports = 20
+	defaultReportPage    = 50
+	maxReportPage        = 100
 )
 
-type Report struct {
-	ID         uuid.UUID
-	ReporterID uuid.UUID
-	TargetID   uuid.UUID
-	TargetType string
-	Reason     string
-	Status     string
-	CreatedAt  time.Time
+// ReportRepository stores reports and their history. The Postgres
+// ReportRepository implements it.
+type ReportRepository interface {
+	Create(ctx context.Context, report *models.Report, event *models.ReportEvent) error
+	// Get returns nil if there's no such report
+	Get(ctx context.Context, id uuid.UUID) (*models.Report, error)
+	Events(ctx context.Context, reportID uuid.UUID) ([]*models.ReportEvent, error)
+	List(ctx context.Context, filter models.ReportFilter) ([]*models.Report, error)
+	// FindUnresolved returns nil if the reporter has no open or reviewing
+	// report of the target
+	FindUnresolved(ctx context.Context, reporterID uuid.UUID, targetType models.ReportTargetType, targetID uuid.UUID) (*models.Report, error)
+	CountUnresolved(ctx context.Context, reporterID uuid.UUID) (int, error)
+	// Update reports false, changing nothing, if the report's status is no
+	// longer from
+	Update(ctx context.Context, report *models.Report, from models.ReportStatus, event *models.ReportEvent) (bool, error)
+	AddEvent(ctx context.Context, event *models.ReportEvent) error
+}
+
+// ReportMessageLookup finds a message as the reporter sees it. The
+// MessageService implements it.
+type ReportMessageLookup interface {
+	GetMessage(ctx context.Context, messageID, requesterID uuid.UUID) (*models.Message, error)
+}
+
+// ReportServerLookup finds reported servers. The ServerService implements
+// it.
+type ReportServerLookup interface {
+	GetServer(ctx context.Context, id uuid.UUID) (*models.Server, error)
+}
+
+// ReportUserLookup finds reported users and assignees. The UserService
+// implements it.
+type ReportUserLookup interface {
+	GetUser(ctx context.Context, id uuid.UUID) (*models.User, error)
+}
+
+// reportTransitions are the statuses each status can move to. Resolved
+// reports can be picked up again if they need another look.
+var reportTransitions = map[models.ReportStatus][]models.ReportStatus{
+	models.ReportOpen:      {models.ReportReviewing, models.ReportDismissed},
+	models.ReportReviewing: {models.ReportOpen, models.ReportActioned, models.ReportDismissed},
+	models.ReportActioned:  {models.ReportReviewing},
+	models.ReportDismissed: {models.ReportReviewing},
+}
+
+func canMoveReport(from, to models.ReportStatus) bool {
+	for _, status := range reportTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
 }
 
+// ReportService runs the trust and safety queue: users report messages,
+// users and servers, and instance admins review the reports. Every change
+// to a report is kept in its history.
 type ReportService struct {
-	mu      sync.RWMutex
-	reports []Report
+	repo     ReportRepository
+	messages ReportMessageLookup
+	servers  ReportServerLookup
+	users    ReportUserLookup
+	now      func() time.Time
 }
 
-func NewReportService() *ReportService {
-	return &ReportService{}
+func NewReportService(repo ReportRepository, messages ReportMessageLookup, servers ReportServerLookup, users ReportUserLookup) *ReportService {
+	return &ReportService{
+		repo:     repo,
+		messages: messages,
+		servers:  servers,
+		users:    users,
+		now:      time.Now,
+	}
 }
 
-func (s *ReportService) Create(ctx context.Context, reporterID, targetID uuid.UUID, targetType, reason string) (*Report, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+func invalidReport(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidReport, fmt.Sprintf(format, args...))
+}
 
-	r := Report{
+// Create files a report. Reporters can only report messages they can see,
+// and the message is kept as it was in case it's edited or deleted.
+func (s *ReportService) Create(ctx context.Context, reporterID uuid.UUID, req *models.CreateReportRequest) (*models.Report, error) {
+	if !req.TargetType.Valid() {
+		return nil, invalidReport("target_type must be message, user or server")
+	}
+	if req.TargetID == uuid.Nil {
+		return nil, invalidReport("target_id is required")
+	}
+	if !req.Category.Valid() {
+		return nil, invalidReport("unknown category %q", req.Category)
+	}
+	now := s.now()
+	report := &models.Report{
 		ID:         uuid.New(),
-		ReporterID: reporterID,
-		TargetID:   targetID,
-		TargetType: targetType,
-		Reason:     reason,
-		Status:     "pending",
-		CreatedAt:  time.Now(),
+		ReporterID: &reporterID,
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		Category:   req.Category,
+		Status:     models.ReportOpen,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if req.Details != nil {
+		details := strings.TrimSpace(*req.Details)
+		if utf8.RuneCountInString(details) > maxReportDetails {
+			return nil, invalidReport("details must be at most %d characters", maxReportDetails)
+		}
+		if details != "" {
+			report.Details = &details
+		}
+	}
+	if err := s.snapshotTarget(ctx, reporterID, report); err != nil {
+		return nil, err
 	}
-	s.reports = append(s.reports, r)
-	return &r, nil
-}
 
-var ErrReportNotFound = errors.New("report not found")
+	existing, err := s.repo.FindUnresolved(ctx, reporterID, report.TargetType, report.TargetID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrReportExists
+	}
+	count, err := s.repo.CountUnresolved(ctx, reporterID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxUnresolvedReports {
+		return nil, ErrTooManyReports
+	}
 
-func (s *ReportService) UpdateStatus(ctx context.Context, reportID uuid.UUID, status string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	event := &models.ReportEvent{
+		ID:        uuid.New(),
+		ReportID:  report.ID,
+		ActorID:   &reporterID,
+		Action:    models.ReportActionCreated,
+		Status:    &report.Status,
+		CreatedAt: now,
+	}
+	if err := s.repo.Create(ctx, report, event); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
 
-	for i := range s.reports {
-		if s.reports[i].ID == reportID {
-			s.reports[i].Status = status
-			return nil
+// snapshotTarget checks the reported thing exists and the reporter may
+// see it, and records what the reviewers need to know about it
+func (s *ReportService) snapshotTarget(ctx context.Context, reporterID uuid.UUID, report *models.Report) error {
+	switch report.TargetType {
+	case models.ReportTargetMessage:
+		message, err := s.messages.GetMessage(ctx, report.TargetID, reporterID)
+		if errors.Is(err, ErrMessageNotFound) || errors.Is(err, ErrChannelNotFound) ||
+			errors.Is(err, ErrNotServerMember) || errors.Is(err, ErrNoPermission) {
+			// Messages the reporter can't see look the same as ones that
+			// don't exist
+			return ErrMessageNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if message.AuthorID == reporterID {
+			return invalidReport("you can't report your own message")
+		}
+		content := message.Content
+		report.TargetUserID = &message.AuthorID
+		report.ServerID = message.ServerID
+		report.ChannelID = &message.ChannelID
+		report.MessageContent = &content
+	case models.ReportTargetUser:
+		if report.TargetID == reporterID {
+			return invalidReport("you can't report yourself")
+		}
+		if _, err := s.users.GetUser(ctx, report.TargetID); err != nil {
+			return err
 		}
+		report.TargetUserID = &report.TargetID
+	case models.ReportTargetServer:
+		if _, err := s.servers.GetServer(ctx, report.TargetID); err != nil {
+			return err
+		}
+		report.ServerID = &report.TargetID
+	}
+	return nil
+}
+
+// List returns the reports in the queue matching filter, oldest first
+func (s *ReportService) List(ctx context.Context, filter models.ReportFilter) ([]*models.Report, error) {
+	if filter.Status != "" && !filter.Status.Valid() {
+		return nil, invalidReport("status must be open, reviewing, actioned or dismissed")
+	}
+	if filter.TargetType != "" && !filter.TargetType.Valid() {
+		return nil, invalidReport("target_type must be message, user or server")
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultReportPage
 	}
-	return ErrReportNotFound
+	if filter.Limit > maxReportPage {
+		filter.Limit = maxReportPage
+	}
+	return s.repo.List(ctx, filter)
 }
 
-func (s *ReportService) GetPending(ctx context.Context) ([]Report, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+func (s *ReportService) get(ctx context.Context, id uuid.UUID) (*models.Report, error) {
+	report, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if report == nil {
+		return nil, ErrReportNotFound
+	}
+	return report, nil
+}
 
-	var pending []Report
-	for _, r := range s.reports {
-		if r.Status == "pending" {
-			pending = append(pending, r)
-		}
+// Get returns a report with its history
+func (s *ReportService) Get(ctx context.Context, id uuid.UUID) (*models.ReportDetail, error) {
+	report, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.repo.Events(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ReportDetail{Report: report, Events: events}, nil
+}
+
+func reportNote(note *string) (*string, error) {
+	if note == nil {
+		return nil, nil
+	}
+	trimmed := strings.TrimSpace(*note)
+	if utf8.RuneCountInString(trimmed) > maxReportNote {
+		return nil, invalidReport("note must be at most %d characters", maxReportNote)
+	}
+	if trimmed == "" {
+		return nil, nil
+	}
+	return &trimmed, nil
+}
+
+// update saves a change to a report unless another admin changed its
+// status first
+func (s *ReportService) update(ctx context.Context, report *models.Report, from models.ReportStatus, event *models.ReportEvent) error {
+	report.UpdatedAt = event.CreatedAt
+	saved, err := s.repo.Update(ctx, report, from, event)
+	if err != nil {
+		return err
+	}
+	if !saved {
+		return ErrReportConflict
+	}
+	return nil
+}
+
+// UpdateStatus moves a report along the queue. Picking a report up
+// assigns it to the admin if nobody has it, and handing it back to the
+// queue unassigns it.
+func (s *ReportService) UpdateStatus(ctx context.Context, adminID, id uuid.UUID, req *models.UpdateReportStatusRequest) (*models.Report, error) {
+	if !req.Status.Valid() {
+		return nil, invalidReport("status must be open, reviewing, actioned or dismissed")
+	}
+	note, err := reportNote(req.Note)
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	from := report.Status
+	if !canMoveReport(from, req.Status) {
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidReportTransition, from, req.Status)
+	}
+
+	now := s.now()
+	report.Status = req.Status
+	switch {
+	case req.Status == models.ReportOpen:
+		report.AssigneeID = nil
+	case req.Status == models.ReportReviewing && report.AssigneeID == nil:
+		report.AssigneeID = &adminID
+	}
+	report.ResolvedAt = nil
+	if req.Status.Resolved() {
+		report.ResolvedAt = &now
+	}
+
+	event := &models.ReportEvent{
+		ID:         uuid.New(),
+		ReportID:   report.ID,
+		ActorID:    &adminID,
+		Action:     models.ReportActionStatus,
+		Status:     &report.Status,
+		AssigneeID: report.AssigneeID,
+		Note:       note,
+		CreatedAt:  now,
+	}
+	if err := s.update(ctx, report, from, event); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Assign gives a report to an instance admin
+func (s *ReportService) Assign(ctx context.Context, adminID, id, assigneeID uuid.UUID) (*models.Report, error) {
+	assignee, err := s.users.GetUser(ctx, assigneeID)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, ErrInvalidReportAssignee
+	}
+	if err != nil {
+		return nil, err
+	}
+	if assignee.Flags&models.UserFlagStaff == 0 {
+		return nil, ErrInvalidReportAssignee
+	}
+	report, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	report.AssigneeID = &assigneeID
+	event := &models.ReportEvent{
+		ID:         uuid.New(),
+		ReportID:   report.ID,
+		ActorID:    &adminID,
+		Action:     models.ReportActionAssigned,
+		AssigneeID: &assigneeID,
+		CreatedAt:  s.now(),
+	}
+	if err := s.update(ctx, report, report.Status, event); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Unassign takes a report away from whoever has it
+func (s *ReportService) Unassign(ctx context.Context, adminID, id uuid.UUID) (*models.Report, error) {
+	report, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if report.AssigneeID == nil {
+		return report, nil
+	}
+
+	report.AssigneeID = nil
+	event := &models.ReportEvent{
+		ID:        uuid.New(),
+		ReportID:  report.ID,
+		ActorID:   &adminID,
+		Action:    models.ReportActionUnassigned,
+		CreatedAt: s.now(),
+	}
+	if err := s.update(ctx, report, report.Status, event); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// AddNote adds a note to a report's history without changing it
+func (s *ReportService) AddNote(ctx context.Context, adminID, id uuid.UUID, note string) (*models.ReportEvent, error) {
+	trimmed, err := reportNote(&note)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed == nil {
+		return nil, invalidReport("note is required")
+	}
+	if _, err := s.get(ctx, id); err != nil {
+		return nil, err
+	}
+
+	event := &models.ReportEvent{
+		ID:        uuid.New(),
+		ReportID:  id,
+		ActorID:   &adminID,
+		Action:    models.ReportActionNote,
+		Note:      trimmed,
+		CreatedAt: s.now(),
+	}
+	if err := s.repo.AddEvent(ctx, event); err != nil {
+		return nil, err
 	}
-	return pending, nil
+	return event, nil
 }
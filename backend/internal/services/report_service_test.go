@@ -2,37 +2,329 @@ package services
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
 )
 
+// fakeReportRepo stores reports in memory
+type fakeReportRepo struct {
+	reports map[uuid.UUID]*models.Report
+	events  []*models.ReportEvent
+}
+
+func (r *fakeReportRepo) Create(ctx context.Context, report *models.Report, event *models.ReportEvent) error {
+	copied := *report
+	r.reports[report.ID] = &copied
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *fakeReportRepo) Get(ctx context.Context, id uuid.UUID) (*models.Report, error) {
+	report, ok := r.reports[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *report
+	return &copied, nil
+}
+
+func (r *fakeReportRepo) Events(ctx context.Context, reportID uuid.UUID) ([]*models.ReportEvent, error) {
+	var events []*models.ReportEvent
+	for _, event := range r.events {
+		if event.ReportID == reportID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (r *fakeReportRepo) List(ctx context.Context, filter models.ReportFilter) ([]*models.Report, error) {
+	var reports []*models.Report
+	for _, report := range r.reports {
+		if filter.Status == "" || report.Status == filter.Status {
+			reports = append(reports, report)
+		}
+	}
+	return reports, nil
+}
+
+func (r *fakeReportRepo) FindUnresolved(ctx context.Context, reporterID uuid.UUID, targetType models.ReportTargetType, targetID uuid.UUID) (*models.Report, error) {
+	for _, report := range r.reports {
+		if *report.ReporterID == reporterID && report.TargetType == targetType && report.TargetID == targetID && !report.Status.Resolved() {
+			return report, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeReportRepo) CountUnresolved(ctx context.Context, reporterID uuid.UUID) (int, error) {
+	count := 0
+	for _, report := range r.reports {
+		if *report.ReporterID == reporterID && !report.Status.Resolved() {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *fakeReportRepo) Update(ctx context.Context, report *models.Report, from models.ReportStatus, event *models.ReportEvent) (bool, error) {
+	if r.reports[report.ID].Status != from {
+		return false, nil
+	}
+	copied := *report
+	r.reports[report.ID] = &copied
+	r.events = append(r.events, event)
+	return true, nil
+}
+
+func (r *fakeReportRepo) AddEvent(ctx context.Context, event *models.ReportEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+type stubReportTargets struct {
+	messages map[uuid.UUID]*models.Message
+	users    map[uuid.UUID]*models.User
+	servers  map[uuid.UUID]*models.Server
+}
+
+func (s *stubReportTargets) GetMessage(ctx context.Context, messageID, requesterID uuid.UUID) (*models.Message, error) {
+	message, ok := s.messages[messageID]
+	if !ok {
+		return nil, ErrMessageNotFound
+	}
+	if message.ServerID != nil && *message.ServerID != s.serverOf(requesterID) {
+		return nil, ErrNotServerMember
+	}
+	return message, nil
+}
+
+// serverOf is the one server every stub user is a member of
+func (s *stubReportTargets) serverOf(userID uuid.UUID) uuid.UUID {
+	for id := range s.servers {
+		return id
+	}
+	return uuid.Nil
+}
+
+func (s *stubReportTargets) GetUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	user, ok := s.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *stubReportTargets) GetServer(ctx context.Context, id uuid.UUID) (*models.Server, error) {
+	server, ok := s.servers[id]
+	if !ok {
+		return nil, ErrServerNotFound
+	}
+	return server, nil
+}
+
+type reportTest struct {
+	svc     *ReportService
+	repo    *fakeReportRepo
+	targets *stubReportTargets
+	server  *models.Server
+	author  *models.User
+	admin   *models.User
+	message *models.Message
+}
+
+func newReportTest() *reportTest {
+	tt := &reportTest{
+		repo:    &fakeReportRepo{reports: make(map[uuid.UUID]*models.Report)},
+		server:  &models.Server{ID: uuid.New(), Name: "Hearth"},
+		author:  &models.User{ID: uuid.New(), Username: "author"},
+		admin:   &models.User{ID: uuid.New(), Username: "admin", Flags: models.UserFlagStaff},
+		targets: &stubReportTargets{},
+	}
+	tt.message = &models.Message{ID: uuid.New(), ChannelID: uuid.New(), ServerID: &tt.server.ID, AuthorID: tt.author.ID, Content: "buy cheap followers"}
+	tt.targets.messages = map[uuid.UUID]*models.Message{tt.message.ID: tt.message}
+	tt.targets.users = map[uuid.UUID]*models.User{tt.author.ID: tt.author, tt.admin.ID: tt.admin}
+	tt.targets.servers = map[uuid.UUID]*models.Server{tt.server.ID: tt.server}
+	tt.svc = NewReportService(tt.repo, tt.targets, tt.targets, tt.targets)
+	tt.svc.now = func() time.Time { return time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC) }
+	return tt
+}
+
+func (tt *reportTest) reportMessage(t *testing.T) *models.Report {
+	report, err := tt.svc.Create(context.Background(), uuid.New(), &models.CreateReportRequest{
+		TargetType: models.ReportTargetMessage,
+		TargetID:   tt.message.ID,
+		Category:   models.ReportSpam,
+	})
+	require.NoError(t, err)
+	return report
+}
+
 func TestReportService_Create(t *testing.T) {
-	svc := NewReportService()
 	ctx := context.Background()
+	tt := newReportTest()
+	reporterID := uuid.New()
+
+	details := "  keeps posting this in every channel "
+	report, err := tt.svc.Create(ctx, reporterID, &models.CreateReportRequest{
+		TargetType: models.ReportTargetMessage,
+		TargetID:   tt.message.ID,
+		Category:   models.ReportSpam,
+		Details:    &details,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.ReportOpen, report.Status)
+	assert.Equal(t, "keeps posting this in every channel", *report.Details)
+	assert.Equal(t, tt.author.ID, *report.TargetUserID, "the author is recorded")
+	assert.Equal(t, tt.server.ID, *report.ServerID)
+	assert.Equal(t, "buy cheap followers", *report.MessageContent, "the message is kept as reported")
+	require.Len(t, tt.repo.events, 1)
+	assert.Equal(t, models.ReportActionCreated, tt.repo.events[0].Action)
+
+	_, err = tt.svc.Create(ctx, reporterID, &models.CreateReportRequest{TargetType: models.ReportTargetMessage, TargetID: tt.message.ID, Category: models.ReportHarassment})
+	assert.ErrorIs(t, err, ErrReportExists)
+
+	report, err = tt.svc.Create(ctx, reporterID, &models.CreateReportRequest{TargetType: models.ReportTargetUser, TargetID: tt.author.ID, Category: models.ReportImpersonation})
+	require.NoError(t, err)
+	assert.Equal(t, tt.author.ID, *report.TargetUserID)
+
+	report, err = tt.svc.Create(ctx, reporterID, &models.CreateReportRequest{TargetType: models.ReportTargetServer, TargetID: tt.server.ID, Category: models.ReportIllegal})
+	require.NoError(t, err)
+	assert.Equal(t, tt.server.ID, *report.ServerID)
+}
+
+func TestReportService_CreateInvalid(t *testing.T) {
+	ctx := context.Background()
+	tt := newReportTest()
+	long := strings.Repeat("a", maxReportDetails+1)
+
+	for name, req := range map[string]*models.CreateReportRequest{
+		"unknown target":   {TargetType: "channel", TargetID: uuid.New(), Category: models.ReportSpam},
+		"missing target":   {TargetType: models.ReportTargetUser, Category: models.ReportSpam},
+		"unknown category": {TargetType: models.ReportTargetUser, TargetID: tt.author.ID, Category: "rude"},
+		"long details":     {TargetType: models.ReportTargetUser, TargetID: tt.author.ID, Category: models.ReportSpam, Details: &long},
+		"own message":      {TargetType: models.ReportTargetMessage, TargetID: tt.message.ID, Category: models.ReportSpam},
+		"self":             {TargetType: models.ReportTargetUser, TargetID: tt.author.ID, Category: models.ReportSpam},
+	} {
+		_, err := tt.svc.Create(ctx, tt.author.ID, req)
+		assert.ErrorIs(t, err, ErrInvalidReport, name)
+	}
+
+	_, err := tt.svc.Create(ctx, uuid.New(), &models.CreateReportRequest{TargetType: models.ReportTargetUser, TargetID: uuid.New(), Category: models.ReportSpam})
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	_, err = tt.svc.Create(ctx, uuid.New(), &models.CreateReportRequest{TargetType: models.ReportTargetServer, TargetID: uuid.New(), Category: models.ReportSpam})
+	assert.ErrorIs(t, err, ErrServerNotFound)
+
+	tt.targets.servers = map[uuid.UUID]*models.Server{uuid.New(): {}}
+	_, err = tt.svc.Create(ctx, uuid.New(), &models.CreateReportRequest{TargetType: models.ReportTargetMessage, TargetID: tt.message.ID, Category: models.ReportSpam})
+	assert.ErrorIs(t, err, ErrMessageNotFound, "messages the reporter can't see can't be reported")
+	assert.Empty(t, tt.repo.reports)
+}
+
+func TestReportService_CreateLimit(t *testing.T) {
+	ctx := context.Background()
+	tt := newReportTest()
+	reporterID := uuid.New()
 
-	r, err := svc.Create(ctx, uuid.New(), uuid.New(), "message", "Spam")
-	assert.NoError(t, err)
-	assert.Equal(t, "pending", r.Status)
+	for i := 0; i < maxUnresolvedReports; i++ {
+		user := &models.User{ID: uuid.New()}
+		tt.targets.users[user.ID] = user
+		_, err := tt.svc.Create(ctx, reporterID, &models.CreateReportRequest{TargetType: models.ReportTargetUser, TargetID: user.ID, Category: models.ReportSpam})
+		require.NoError(t, err)
+	}
+	_, err := tt.svc.Create(ctx, reporterID, &models.CreateReportRequest{TargetType: models.ReportTargetUser, TargetID: tt.author.ID, Category: models.ReportSpam})
+	assert.ErrorIs(t, err, ErrTooManyReports)
 }
 
 func TestReportService_UpdateStatus(t *testing.T) {
-	svc := NewReportService()
 	ctx := context.Background()
+	tt := newReportTest()
+	report := tt.reportMessage(t)
 
-	r, _ := svc.Create(ctx, uuid.New(), uuid.New(), "user", "Harassment")
-	err := svc.UpdateStatus(ctx, r.ID, "resolved")
-	assert.NoError(t, err)
+	_, err := tt.svc.UpdateStatus(ctx, tt.admin.ID, report.ID, &models.UpdateReportStatusRequest{Status: models.ReportActioned})
+	assert.ErrorIs(t, err, ErrInvalidReportTransition, "open reports are reviewed before they're actioned")
 
-	pending, _ := svc.GetPending(ctx)
-	assert.Len(t, pending, 0)
+	report, err = tt.svc.UpdateStatus(ctx, tt.admin.ID, report.ID, &models.UpdateReportStatusRequest{Status: models.ReportReviewing})
+	require.NoError(t, err)
+	assert.Equal(t, tt.admin.ID, *report.AssigneeID, "picking a report up assigns it")
+
+	note := "removed the message and warned the author"
+	report, err = tt.svc.UpdateStatus(ctx, tt.admin.ID, report.ID, &models.UpdateReportStatusRequest{Status: models.ReportActioned, Note: &note})
+	require.NoError(t, err)
+	assert.Equal(t, models.ReportActioned, report.Status)
+	assert.NotNil(t, report.ResolvedAt)
+
+	detail, err := tt.svc.Get(ctx, report.ID)
+	require.NoError(t, err)
+	require.Len(t, detail.Events, 3)
+	assert.Equal(t, note, *detail.Events[2].Note)
+	assert.Equal(t, tt.admin.ID, *detail.Events[2].ActorID)
+
+	report, err = tt.svc.UpdateStatus(ctx, tt.admin.ID, report.ID, &models.UpdateReportStatusRequest{Status: models.ReportReviewing})
+	require.NoError(t, err)
+	assert.Nil(t, report.ResolvedAt, "reopening clears the resolution")
+	report, err = tt.svc.UpdateStatus(ctx, tt.admin.ID, report.ID, &models.UpdateReportStatusRequest{Status: models.ReportOpen})
+	require.NoError(t, err)
+	assert.Nil(t, report.AssigneeID, "handing a report back unassigns it")
+
+	_, err = tt.svc.UpdateStatus(ctx, tt.admin.ID, report.ID, &models.UpdateReportStatusRequest{Status: "resolved"})
+	assert.ErrorIs(t, err, ErrInvalidReport)
 }
 
 func TestReportService_UpdateStatus_NotFound(t *testing.T) {
-	svc := NewReportService()
+	tt := newReportTest()
+	_, err := tt.svc.UpdateStatus(context.Background(), tt.admin.ID, uuid.New(), &models.UpdateReportStatusRequest{Status: models.ReportReviewing})
+	assert.ErrorIs(t, err, ErrReportNotFound)
+}
+
+func TestReportService_UpdateStatus_Conflict(t *testing.T) {
 	ctx := context.Background()
+	tt := newReportTest()
+	report := tt.reportMessage(t)
 
-	err := svc.UpdateStatus(ctx, uuid.New(), "resolved")
-	assert.ErrorIs(t, err, ErrReportNotFound)
+	stale := *tt.repo.reports[report.ID]
+	_, err := tt.svc.UpdateStatus(ctx, tt.admin.ID, report.ID, &models.UpdateReportStatusRequest{Status: models.ReportDismissed})
+	require.NoError(t, err)
+	assert.ErrorIs(t, tt.svc.update(ctx, &stale, models.ReportOpen, &models.ReportEvent{}), ErrReportConflict)
+}
+
+func TestReportService_Assign(t *testing.T) {
+	ctx := context.Background()
+	tt := newReportTest()
+	report := tt.reportMessage(t)
+
+	_, err := tt.svc.Assign(ctx, tt.admin.ID, report.ID, tt.author.ID)
+	assert.ErrorIs(t, err, ErrInvalidReportAssignee, "only instance admins take reports")
+	_, err = tt.svc.Assign(ctx, tt.admin.ID, report.ID, uuid.New())
+	assert.ErrorIs(t, err, ErrInvalidReportAssignee)
+
+	report, err = tt.svc.Assign(ctx, tt.admin.ID, report.ID, tt.admin.ID)
+	require.NoError(t, err)
+	assert.Equal(t, tt.admin.ID, *report.AssigneeID)
+	assert.Equal(t, models.ReportOpen, report.Status, "assigning doesn't change the status")
+
+	report, err = tt.svc.Unassign(ctx, tt.admin.ID, report.ID)
+	require.NoError(t, err)
+	assert.Nil(t, report.AssigneeID)
+
+	event, err := tt.svc.AddNote(ctx, tt.admin.ID, report.ID, " asked the server's owner ")
+	require.NoError(t, err)
+	assert.Equal(t, "asked the server's owner", *event.Note)
+	_, err = tt.svc.AddNote(ctx, tt.admin.ID, report.ID, "  ")
+	assert.ErrorIs(t, err, ErrInvalidReport)
+
+	detail, err := tt.svc.Get(ctx, report.ID)
+	require.NoError(t, err)
+	var actions []models.ReportAction
+	for _, event := range detail.Events {
+		actions = append(actions, event.Action)
+	}
+	assert.Equal(t, []models.ReportAction{models.ReportActionCreated, models.ReportActionAssigned, models.ReportActionUnassigned, models.ReportActionNote}, actions)
 }
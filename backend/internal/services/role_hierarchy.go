@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"math"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// ownerPosition ranks the server owner above every role
+const ownerPosition = math.MaxInt
+
+// memberPosition returns the position of the highest role userID holds in
+// server. The owner ranks above every role, co-owners rank above every
+// role but below the owner and members with just @everyone rank at 0. Users who aren't members rank below everyone, so they can
+// still be banned. Without a role repository every member ranks at 0.
+func memberPosition(ctx context.Context, servers ServerRepository, roleRepo RoleRepository, server *models.Server, userID uuid.UUID) (int, error) {
+	if server.OwnerID == userID {
+		return ownerPosition, nil
+	}
+
+	member, err := servers.GetMember(ctx, server.ID, userID)
+	if err != nil {
+		return 0, err
+	}
+	if member == nil {
+		return -1, nil
+	}
+	if member.CoOwner {
+		return ownerPosition - 1, nil
+	}
+	if roleRepo == nil {
+		return 0, nil
+	}
+
+	roles, err := roleRepo.GetMemberRoles(ctx, server.ID, userID)
+	if err != nil {
+		return 0, err
+	}
+	top := 0
+	for _, role := range roles {
+		if role.Position > top {
+			top = role.Position
+		}
+	}
+	return top, nil
+}
+
+// checkOutranks returns ErrMemberHierarchy unless actorID's highest role
+// sits strictly above targetID's. The owner outranks everyone and nobody
+// outranks the owner.
+func checkOutranks(ctx context.Context, servers ServerRepository, roleRepo RoleRepository, server *models.Server, actorID, targetID uuid.UUID) error {
+	if server.OwnerID == targetID {
+		return ErrMemberHierarchy
+	}
+	if server.OwnerID == actorID {
+		return nil
+	}
+
+	actor, err := memberPosition(ctx, servers, roleRepo, server, actorID)
+	if err != nil {
+		return err
+	}
+	target, err := memberPosition(ctx, servers, roleRepo, server, targetID)
+	if err != nil {
+		return err
+	}
+	if actor <= target {
+		return ErrMemberHierarchy
+	}
+	return nil
+}
+
+// roleIDs returns the IDs of roles
+func roleIDs(roles []*models.Role) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(roles))
+	for _, role := range roles {
+		ids = append(ids, role.ID)
+	}
+	return ids
+}
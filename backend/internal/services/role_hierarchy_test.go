@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type hierarchyFixture struct {
+	service    *ServerService
+	roles      *RoleService
+	serverRepo *MockServerRepository
+	roleRepo   *MockRoleRepository
+	eventBus   *MockEventBus
+
+	server    *models.Server
+	everyone  *models.Role
+	helper    *models.Role
+	moderator *models.Role
+	admin     *models.Role
+}
+
+// newHierarchyFixture sets up a server with a helper role at position 1
+// that can kick, a moderator role at 2 that can kick, ban and manage roles,
+// and an administrator role at 3
+func newHierarchyFixture() *hierarchyFixture {
+	service, serverRepo, _, roleRepo, cache, eventBus := newTestServerService()
+	serverID := uuid.New()
+	f := &hierarchyFixture{
+		service:    service,
+		roles:      NewRoleService(roleRepo, serverRepo, cache, eventBus),
+		serverRepo: serverRepo,
+		roleRepo:   roleRepo,
+		eventBus:   eventBus,
+		server:     &models.Server{ID: serverID, OwnerID: uuid.New()},
+		everyone:   &models.Role{ID: serverID, ServerID: serverID, IsDefault: true, Permissions: models.DefaultPermissions},
+		helper:     &models.Role{ID: uuid.New(), ServerID: serverID, Position: 1, Permissions: models.PermKickMembers},
+		moderator:  &models.Role{ID: uuid.New(), ServerID: serverID, Position: 2, Permissions: models.PermKickMembers | models.PermBanMembers | models.PermManageRoles},
+		admin:      &models.Role{ID: uuid.New(), ServerID: serverID, Position: 3, Permissions: models.PermAdministrator},
+	}
+
+	serverRepo.On("GetByID", mock.Anything, serverID).Return(f.server, nil)
+	serverRepo.On("GetMember", mock.Anything, serverID, f.server.OwnerID).Return(&models.Member{ServerID: serverID, UserID: f.server.OwnerID}, nil)
+	roleRepo.On("GetByServerID", mock.Anything, serverID).Return([]*models.Role{f.admin, f.moderator, f.helper, f.everyone}, nil)
+	serverRepo.On("RemoveMember", mock.Anything, serverID, mock.Anything).Return(nil)
+	serverRepo.On("AddBan", mock.Anything, mock.Anything).Return(nil)
+	serverRepo.On("UpdateMember", mock.Anything, mock.Anything).Return(nil)
+	roleRepo.On("UpdatePositions", mock.Anything, serverID, mock.Anything).Return(nil)
+	eventBus.On("Publish", mock.Anything, mock.Anything).Return()
+	return f
+}
+
+// addMember makes a new member holding roles and returns their ID
+func (f *hierarchyFixture) addMember(roles ...*models.Role) uuid.UUID {
+	userID := uuid.New()
+	f.serverRepo.On("GetMember", mock.Anything, f.server.ID, userID).Return(&models.Member{ServerID: f.server.ID, UserID: userID}, nil)
+	f.roleRepo.On("GetMemberRoles", mock.Anything, f.server.ID, userID).Return(roles, nil)
+	return userID
+}
+
+func TestKickMember_Hierarchy(t *testing.T) {
+	f := newHierarchyFixture()
+	ctx := context.Background()
+	moderator := f.addMember(f.moderator)
+	otherModerator := f.addMember(f.moderator)
+	helper := f.addMember(f.helper)
+	admin := f.addMember(f.admin)
+	plain := f.addMember()
+
+	assert.NoError(t, f.service.KickMember(ctx, f.server.ID, moderator, helper, ""))
+	assert.NoError(t, f.service.KickMember(ctx, f.server.ID, helper, plain, ""))
+	assert.NoError(t, f.service.KickMember(ctx, f.server.ID, f.server.OwnerID, admin, ""), "the owner outranks everyone")
+
+	assert.ErrorIs(t, f.service.KickMember(ctx, f.server.ID, moderator, otherModerator, ""), ErrMemberHierarchy, "equal positions")
+	assert.ErrorIs(t, f.service.KickMember(ctx, f.server.ID, helper, moderator, ""), ErrMemberHierarchy)
+	assert.ErrorIs(t, f.service.KickMember(ctx, f.server.ID, moderator, admin, ""), ErrMemberHierarchy)
+	assert.ErrorIs(t, f.service.KickMember(ctx, f.server.ID, plain, plain, ""), ErrCannotKick)
+}
+
+func TestBanMember_Hierarchy(t *testing.T) {
+	f := newHierarchyFixture()
+	ctx := context.Background()
+	moderator := f.addMember(f.moderator)
+	helper := f.addMember(f.helper)
+	admin := f.addMember(f.admin)
+	outsider := uuid.New()
+	f.serverRepo.On("GetMember", mock.Anything, f.server.ID, outsider).Return(nil, nil)
+
+	assert.NoError(t, f.service.BanMember(ctx, f.server.ID, moderator, helper, "", 0, 0))
+	assert.NoError(t, f.service.BanMember(ctx, f.server.ID, moderator, outsider, "", 0, 0), "non-members rank below everyone")
+	assert.NoError(t, f.service.BanMember(ctx, f.server.ID, admin, moderator, "", 0, 0))
+
+	assert.ErrorIs(t, f.service.BanMember(ctx, f.server.ID, moderator, admin, "", 0, 0), ErrMemberHierarchy)
+	assert.ErrorIs(t, f.service.BanMember(ctx, f.server.ID, helper, outsider, "", 0, 0), ErrCannotBan)
+	f.serverRepo.AssertNumberOfCalls(t, "AddBan", 3)
+}
+
+func TestTimeoutMember_Hierarchy(t *testing.T) {
+	f := newHierarchyFixture()
+	f.service.SetModerationExpiryRepository(&fakeModerationExpiryRepository{})
+	admin := f.addMember(f.admin)
+	otherAdmin := f.addMember(f.admin)
+
+	_, err := f.service.TimeoutMember(context.Background(), f.server.ID, admin, otherAdmin, time.Hour)
+	assert.ErrorIs(t, err, ErrMemberHierarchy)
+}
+
+func TestUpdateMember_RoleHierarchy(t *testing.T) {
+	f := newHierarchyFixture()
+	ctx := context.Background()
+	moderator := f.addMember(f.moderator)
+	otherModerator := f.addMember(f.moderator)
+	helper := f.addMember(f.helper)
+	plain := f.addMember()
+
+	_, err := f.service.UpdateMember(ctx, f.server.ID, moderator, plain, nil, []uuid.UUID{f.helper.ID})
+	assert.NoError(t, err, "roles below the requester's can be given")
+	_, err = f.service.UpdateMember(ctx, f.server.ID, moderator, helper, nil, []uuid.UUID{})
+	assert.NoError(t, err, "and taken away")
+	_, err = f.service.UpdateMember(ctx, f.server.ID, moderator, moderator, nil, []uuid.UUID{f.moderator.ID, f.helper.ID})
+	assert.NoError(t, err, "members can change their own lower roles")
+
+	_, err = f.service.UpdateMember(ctx, f.server.ID, moderator, plain, nil, []uuid.UUID{f.moderator.ID})
+	assert.ErrorIs(t, err, ErrRoleHierarchy, "can't give out their own role")
+	_, err = f.service.UpdateMember(ctx, f.server.ID, moderator, moderator, nil, []uuid.UUID{f.admin.ID})
+	assert.ErrorIs(t, err, ErrRoleHierarchy)
+	_, err = f.service.UpdateMember(ctx, f.server.ID, moderator, otherModerator, nil, []uuid.UUID{})
+	assert.ErrorIs(t, err, ErrMemberHierarchy)
+	_, err = f.service.UpdateMember(ctx, f.server.ID, moderator, plain, nil, []uuid.UUID{uuid.New()})
+	assert.ErrorIs(t, err, ErrRoleNotFound)
+	_, err = f.service.UpdateMember(ctx, f.server.ID, helper, plain, nil, []uuid.UUID{})
+	assert.ErrorIs(t, err, ErrCannotManageRoles)
+}
+
+func TestUpdateRolePositions_Hierarchy(t *testing.T) {
+	f := newHierarchyFixture()
+	ctx := context.Background()
+	moderator := f.addMember(f.moderator)
+	helper := f.addMember(f.helper)
+	admin := f.addMember(f.admin)
+
+	require.NoError(t, f.roles.UpdateRolePositions(ctx, f.server.ID, admin, map[uuid.UUID]int{f.moderator.ID: 1, f.helper.ID: 2}))
+	assert.Equal(t, 1, f.moderator.Position)
+	assert.Equal(t, 2, f.helper.Position)
+	require.NoError(t, f.roles.UpdateRolePositions(ctx, f.server.ID, f.server.OwnerID, map[uuid.UUID]int{f.moderator.ID: 2, f.helper.ID: 1}))
+
+	tests := []struct {
+		name      string
+		requester uuid.UUID
+		positions map[uuid.UUID]int
+		err       error
+	}{
+		{"own role", moderator, map[uuid.UUID]int{f.moderator.ID: 1}, ErrRoleHierarchy},
+		{"above own role", moderator, map[uuid.UUID]int{f.helper.ID: 3}, ErrRoleHierarchy},
+		{"higher role", admin, map[uuid.UUID]int{f.admin.ID: 4}, ErrRoleHierarchy},
+		{"@everyone", f.server.OwnerID, map[uuid.UUID]int{f.everyone.ID: 1}, ErrInvalidRolePosition},
+		{"below @everyone", f.server.OwnerID, map[uuid.UUID]int{f.helper.ID: 0}, ErrInvalidRolePosition},
+		{"unknown role", f.server.OwnerID, map[uuid.UUID]int{uuid.New(): 1}, ErrRoleNotFound},
+		{"no permission", helper, map[uuid.UUID]int{}, ErrCannotManageRoles},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, f.roles.UpdateRolePositions(ctx, f.server.ID, tt.requester, tt.positions), tt.err)
+		})
+	}
+	f.roleRepo.AssertNumberOfCalls(t, "UpdatePositions", 2)
+}
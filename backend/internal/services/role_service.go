@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,6 +15,7 @@ type RoleService struct {
 	serverRepo ServerRepository
 	cache      CacheService
 	eventBus   EventBus
+	pages      RolePageRepository
 }
 
 // NewRoleService creates a new role service
@@ -66,6 +68,7 @@ func (s *RoleService) CreateRole(
 	if err := s.roleRepo.Create(ctx, role); err != nil {
 		return nil, err
 	}
+	forgetRoles(ctx, s.cache, serverID)
 
 	s.eventBus.Publish("role.created", &RoleCreatedEvent{
 		Role:     role,
@@ -97,6 +100,10 @@ func (s *RoleService) UpdateRole(
 	}
 	// TODO: Check MANAGE_ROLES permission and role hierarchy
 
+	if updates.Version != nil && *updates.Version != role.Version {
+		return nil, &VersionConflictError{Current: role, Version: role.Version}
+	}
+
 	// Apply updates
 	if updates.Name != nil {
 		role.Name = *updates.Name
@@ -115,8 +122,20 @@ func (s *RoleService) UpdateRole(
 	}
 
 	if err := s.roleRepo.Update(ctx, role); err != nil {
+		if errors.Is(err, ErrVersionConflict) {
+			// Someone saved between our read and write
+			current, getErr := s.roleRepo.GetByID(ctx, roleID)
+			if getErr != nil {
+				return nil, getErr
+			}
+			if current == nil {
+				return nil, ErrRoleNotFound
+			}
+			return nil, &VersionConflictError{Current: current, Version: current.Version}
+		}
 		return nil, err
 	}
+	forgetRoles(ctx, s.cache, role.ServerID)
 
 	s.eventBus.Publish("role.updated", &RoleUpdatedEvent{
 		Role: role,
@@ -150,6 +169,7 @@ func (s *RoleService) DeleteRole(ctx context.Context, roleID, requesterID uuid.U
 	if err := s.roleRepo.Delete(ctx, roleID); err != nil {
 		return err
 	}
+	forgetRoles(ctx, s.cache, role.ServerID)
 
 	s.eventBus.Publish("role.deleted", &RoleDeletedEvent{
 		RoleID:   roleID,
@@ -159,18 +179,38 @@ func (s *RoleService) DeleteRole(ctx context.Context, roleID, requesterID uuid.U
 	return nil
 }
 
-// GetServerRoles gets all roles in a server
+// GetServerRoles gets all roles in a server, through the cache
 func (s *RoleService) GetServerRoles(ctx context.Context, serverID, requesterID uuid.UUID) ([]*models.Role, error) {
 	// Verify requester is a member
-	member, err := s.serverRepo.GetMember(ctx, serverID, requesterID)
+	member, err := cachedMember(ctx, s.cache, s.serverRepo, serverID, requesterID)
+	if err != nil || member == nil {
+		return nil, ErrNotServerMember
+	}
+
+	return cachedRoles(ctx, s.cache, s.roleRepo, serverID)
+}
+
+// GetRole gets one of a server's roles, for its members
+func (s *RoleService) GetRole(ctx context.Context, serverID, roleID, requesterID uuid.UUID) (*models.Role, error) {
+	member, err := cachedMember(ctx, s.cache, s.serverRepo, serverID, requesterID)
 	if err != nil || member == nil {
 		return nil, ErrNotServerMember
 	}
 
-	return s.roleRepo.GetByServerID(ctx, serverID)
+	role, err := s.roleRepo.GetByID(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil || role.ServerID != serverID {
+		return nil, ErrRoleNotFound
+	}
+	return role, nil
 }
 
-// UpdateRolePositions updates the positions of multiple roles
+// UpdateRolePositions moves several roles at once, in one transaction. The
+// requester needs MANAGE_ROLES and, unless they own the server, can only
+// move roles below their highest role to positions still below it.
+// @everyone stays at position 0 beneath every other role.
 func (s *RoleService) UpdateRolePositions(
 	ctx context.Context,
 	serverID uuid.UUID,
@@ -182,9 +222,61 @@ func (s *RoleService) UpdateRolePositions(
 	if err != nil || member == nil {
 		return ErrNotServerMember
 	}
-	// TODO: Check MANAGE_ROLES permission
 
-	return s.roleRepo.UpdatePositions(ctx, serverID, positions)
+	server, err := s.serverRepo.GetByID(ctx, serverID)
+	if err != nil {
+		return err
+	}
+	if server == nil {
+		return ErrServerNotFound
+	}
+	if !memberHasPermission(ctx, s.serverRepo, s.roleRepo, server, requesterID, models.PermManageRoles) {
+		return ErrCannotManageRoles
+	}
+	top, err := memberPosition(ctx, s.serverRepo, s.roleRepo, server, requesterID)
+	if err != nil {
+		return err
+	}
+
+	roles, err := s.roleRepo.GetByServerID(ctx, serverID)
+	if err != nil {
+		return err
+	}
+	byID := make(map[uuid.UUID]*models.Role, len(roles))
+	for _, role := range roles {
+		byID[role.ID] = role
+	}
+	for roleID, position := range positions {
+		role := byID[roleID]
+		if role == nil {
+			return ErrRoleNotFound
+		}
+		if role.IsDefault || role.ID == serverID || position < 1 {
+			return ErrInvalidRolePosition
+		}
+		if role.Position >= top || position >= top {
+			return ErrRoleHierarchy
+		}
+	}
+
+	if err := s.roleRepo.UpdatePositions(ctx, serverID, positions); err != nil {
+		return err
+	}
+	forgetRoles(ctx, s.cache, serverID)
+
+	for roleID, position := range positions {
+		role := byID[roleID]
+		if role.Position == position {
+			continue
+		}
+		role.Position = position
+		role.Version++
+		s.eventBus.Publish("role.updated", &RoleUpdatedEvent{
+			Role: role,
+		})
+	}
+
+	return nil
 }
 
 // AddRoleToMember assigns a role to a member
@@ -218,6 +310,7 @@ func (s *RoleService) AddRoleToMember(
 	if err := s.roleRepo.AddRoleToMember(ctx, serverID, userID, roleID); err != nil {
 		return err
 	}
+	forgetMember(ctx, s.cache, serverID, userID)
 
 	s.eventBus.Publish("member.role_added", &MemberRoleAddedEvent{
 		ServerID: serverID,
@@ -244,6 +337,7 @@ func (s *RoleService) RemoveRoleFromMember(
 	if err := s.roleRepo.RemoveRoleFromMember(ctx, serverID, userID, roleID); err != nil {
 		return err
 	}
+	forgetMember(ctx, s.cache, serverID, userID)
 
 	s.eventBus.Publish("member.role_removed", &MemberRoleRemovedEvent{
 		ServerID: serverID,
@@ -262,7 +356,7 @@ func (s *RoleService) GetMemberRoles(ctx context.Context, serverID, userID uuid.
 // ComputeMemberPermissions computes effective permissions for a member
 func (s *RoleService) ComputeMemberPermissions(ctx context.Context, serverID, userID uuid.UUID) (int64, error) {
 	// Get server to check ownership
-	server, err := s.serverRepo.GetByID(ctx, serverID)
+	server, err := cachedServer(ctx, s.cache, s.serverRepo, serverID)
 	if err != nil {
 		return 0, err
 	}
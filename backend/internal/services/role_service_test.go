@@ -17,7 +17,7 @@ import (
 func newTestRoleService() (*RoleService, *MockRoleRepository, *MockServerRepository, *MockCacheService, *MockEventBus) {
 	roleRepo := new(MockRoleRepository)
 	serverRepo := new(MockServerRepository)
-	cache := new(MockCacheService)
+	cache := new(MockCacheService).empty()
 	eventBus := new(MockEventBus)
 
 	service := NewRoleService(roleRepo, serverRepo, cache, eventBus)
@@ -320,6 +320,116 @@ func TestUpdateRole_UpdateError(t *testing.T) {
 	assert.Equal(t, dbErr, err)
 }
 
+func TestUpdateRole_StaleVersion(t *testing.T) {
+	service, roleRepo, serverRepo, _, _ := newTestRoleService()
+	ctx := context.Background()
+	roleID := uuid.New()
+	serverID := uuid.New()
+	requesterID := uuid.New()
+
+	current := &models.Role{ID: roleID, ServerID: serverID, Name: "Theirs", Version: 3}
+	member := &models.Member{UserID: requesterID, ServerID: serverID}
+	stale := 2
+	name := "Mine"
+
+	roleRepo.On("GetByID", ctx, roleID).Return(current, nil)
+	serverRepo.On("GetMember", ctx, serverID, requesterID).Return(member, nil)
+
+	role, err := service.UpdateRole(ctx, roleID, requesterID, &models.RoleUpdate{Name: &name, Version: &stale})
+
+	assert.Nil(t, role)
+	var conflict *VersionConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, 3, conflict.Version)
+	assert.Same(t, current, conflict.Current)
+	roleRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestUpdateRole_ConcurrentSave(t *testing.T) {
+	service, roleRepo, serverRepo, _, _ := newTestRoleService()
+	ctx := context.Background()
+	roleID := uuid.New()
+	serverID := uuid.New()
+	requesterID := uuid.New()
+
+	read := &models.Role{ID: roleID, ServerID: serverID, Name: "Old", Version: 3}
+	saved := &models.Role{ID: roleID, ServerID: serverID, Name: "Theirs", Version: 4}
+	member := &models.Member{UserID: requesterID, ServerID: serverID}
+	version := 3
+	name := "Mine"
+
+	// Someone else saves between our read and write
+	roleRepo.On("GetByID", ctx, roleID).Return(read, nil).Once()
+	roleRepo.On("GetByID", ctx, roleID).Return(saved, nil).Once()
+	serverRepo.On("GetMember", ctx, serverID, requesterID).Return(member, nil)
+	roleRepo.On("Update", ctx, mock.Anything).Return(ErrVersionConflict)
+
+	role, err := service.UpdateRole(ctx, roleID, requesterID, &models.RoleUpdate{Name: &name, Version: &version})
+
+	assert.Nil(t, role)
+	var conflict *VersionConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, 4, conflict.Version)
+	assert.Same(t, saved, conflict.Current)
+}
+
+// ============================================
+// GetRole Tests
+// ============================================
+
+func TestGetRole_Success(t *testing.T) {
+	service, roleRepo, serverRepo, _, _ := newTestRoleService()
+	ctx := context.Background()
+	roleID := uuid.New()
+	serverID := uuid.New()
+	requesterID := uuid.New()
+
+	existing := &models.Role{ID: roleID, ServerID: serverID, Name: "Mods", Version: 2}
+	member := &models.Member{UserID: requesterID, ServerID: serverID}
+
+	serverRepo.On("GetMember", ctx, serverID, requesterID).Return(member, nil)
+	roleRepo.On("GetByID", ctx, roleID).Return(existing, nil)
+
+	role, err := service.GetRole(ctx, serverID, roleID, requesterID)
+
+	require.NoError(t, err)
+	assert.Same(t, existing, role)
+}
+
+func TestGetRole_OtherServer(t *testing.T) {
+	service, roleRepo, serverRepo, _, _ := newTestRoleService()
+	ctx := context.Background()
+	roleID := uuid.New()
+	serverID := uuid.New()
+	requesterID := uuid.New()
+
+	member := &models.Member{UserID: requesterID, ServerID: serverID}
+
+	serverRepo.On("GetMember", ctx, serverID, requesterID).Return(member, nil)
+	roleRepo.On("GetByID", ctx, roleID).Return(&models.Role{ID: roleID, ServerID: uuid.New()}, nil)
+
+	role, err := service.GetRole(ctx, serverID, roleID, requesterID)
+
+	assert.Nil(t, role)
+	assert.Equal(t, ErrRoleNotFound, err)
+}
+
+func TestGetRole_NotServerMember(t *testing.T) {
+	service, roleRepo, serverRepo, _, _ := newTestRoleService()
+	ctx := context.Background()
+	roleID := uuid.New()
+	serverID := uuid.New()
+	requesterID := uuid.New()
+
+	serverRepo.On("GetMember", ctx, serverID, requesterID).Return(nil, nil)
+
+	role, err := service.GetRole(ctx, serverID, roleID, requesterID)
+
+	assert.Nil(t, role)
+	assert.Equal(t, ErrNotServerMember, err)
+	roleRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+}
+
 // ============================================
 // DeleteRole Tests
 // ============================================
@@ -494,25 +604,33 @@ func TestGetServerRoles_GetMemberError(t *testing.T) {
 // ============================================
 
 func TestUpdateRolePositions_Success(t *testing.T) {
-	service, roleRepo, serverRepo, _, _ := newTestRoleService()
+	service, roleRepo, serverRepo, _, eventBus := newTestRoleService()
 	ctx := context.Background()
 	serverID := uuid.New()
 	requesterID := uuid.New()
 
 	member := &models.Member{UserID: requesterID, ServerID: serverID}
+	everyone := &models.Role{ID: serverID, ServerID: serverID, IsDefault: true}
+	first := &models.Role{ID: uuid.New(), ServerID: serverID, Position: 1}
+	second := &models.Role{ID: uuid.New(), ServerID: serverID, Position: 2}
 	positions := map[uuid.UUID]int{
-		uuid.New(): 0,
-		uuid.New(): 1,
-		uuid.New(): 2,
+		first.ID:  2,
+		second.ID: 1,
 	}
 
 	serverRepo.On("GetMember", ctx, serverID, requesterID).Return(member, nil)
+	serverRepo.On("GetByID", ctx, serverID).Return(&models.Server{ID: serverID, OwnerID: requesterID}, nil)
+	roleRepo.On("GetByServerID", ctx, serverID).Return([]*models.Role{second, first, everyone}, nil)
 	roleRepo.On("UpdatePositions", ctx, serverID, positions).Return(nil)
+	eventBus.On("Publish", "role.updated", mock.Anything).Return()
 
 	err := service.UpdateRolePositions(ctx, serverID, requesterID, positions)
 
 	require.NoError(t, err)
 	roleRepo.AssertExpectations(t)
+	eventBus.AssertNumberOfCalls(t, "Publish", 2)
+	assert.Equal(t, 2, first.Position)
+	assert.Equal(t, 1, second.Position)
 }
 
 func TestUpdateRolePositions_NotServerMember(t *testing.T) {
@@ -538,6 +656,8 @@ func TestUpdateRolePositions_UpdateError(t *testing.T) {
 	member := &models.Member{UserID: requesterID, ServerID: serverID}
 
 	serverRepo.On("GetMember", ctx, serverID, requesterID).Return(member, nil)
+	serverRepo.On("GetByID", ctx, serverID).Return(&models.Server{ID: serverID, OwnerID: requesterID}, nil)
+	roleRepo.On("GetByServerID", ctx, serverID).Return([]*models.Role{}, nil)
 	roleRepo.On("UpdatePositions", ctx, serverID, mock.Anything).Return(dbErr)
 
 	err := service.UpdateRolePositions(ctx, serverID, requesterID, map[uuid.UUID]int{})
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -285,3 +286,41 @@ func findToken(tokens []SearchQueryToken, tokenType string) *SearchQueryToken {
 func boolPtr(b bool) *bool {
 	return &b
 }
+
+// FuzzParseSearchQueryString checks that any query parses without
+// panicking and that every token and the free text come from the query
+func FuzzParseSearchQueryString(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"hello world",
+		"from:@alice in:#general has:image before:2024-01-01 after:2023-06-01T10:00:00Z pinned:yes bug",
+		"from:<@7f8a5e0e-5f43-4bf4-8c2b-2a0b3b5f1a11> mentions:<@!bob>",
+		"before:2024-13-45 after:9999-99-99T99:99:99+99:99",
+		"has:has:has: from:from: in:",
+		"FROM:alice PINNED:FALSE HAS:Links",
+		"\x00from:\xff",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, query string) {
+		parsed := ParseSearchQueryString(query)
+
+		if len(parsed.FreeText) > len(query) {
+			t.Fatalf("free text %q is longer than query %q", parsed.FreeText, query)
+		}
+		for _, token := range parsed.Tokens {
+			if !strings.Contains(query, token.Raw) {
+				t.Fatalf("token %q does not appear in query", token.Raw)
+			}
+		}
+		if parsed.Pinned != nil && !strings.Contains(strings.ToLower(query), "pinned:") {
+			t.Fatal("pinned set without a pinned: filter")
+		}
+		for _, has := range parsed.Has {
+			if has != strings.ToLower(has) {
+				t.Fatalf("has value %q not normalized", has)
+			}
+		}
+	})
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
@@ -42,9 +43,10 @@ type SearchMessageOptions struct {
 	Pinned         *bool
 	Mentions       []uuid.UUID
 
-	// Pagination
+	// Pagination. Cursor takes over from Offset when set.
 	Limit  int
 	Offset int
+	Cursor *models.PageCursor
 
 	// Requester for permission checks
 	RequesterID uuid.UUID
@@ -57,6 +59,10 @@ type SearchResult struct {
 	Channels []*models.Channel
 	Total    int
 	HasMore  bool
+
+	// Tokens for the pages of messages either side of this one
+	NextCursor string
+	PrevCursor string
 }
 
 // SearchService handles search-related business logic
@@ -107,13 +113,22 @@ func (s *SearchService) SearchMessages(ctx context.Context, opts SearchMessageOp
 			if err != nil {
 				return nil, err
 			}
+			if len(channels) == 0 {
+				return &SearchResult{}, nil
+			}
 			opts.ChannelIDs = channels
 		}
 	} else if opts.ChannelID != nil {
 		// Validate channel access
-		if err := s.validateChannelAccess(ctx, *opts.ChannelID, opts.RequesterID); err != nil {
+		encrypted, err := s.validateChannelAccess(ctx, *opts.ChannelID, opts.RequesterID)
+		if err != nil {
 			return nil, err
 		}
+		// The server can't read encrypted channels, so there's nothing to
+		// search
+		if encrypted {
+			return &SearchResult{}, nil
+		}
 	}
 
 	// Perform search
@@ -127,6 +142,8 @@ func (s *SearchService) SearchMessages(ctx context.Context, opts SearchMessageOp
 		if err := s.enrichMessages(ctx, result.Messages); err != nil {
 			return nil, err
 		}
+		first, last := result.Messages[0], result.Messages[len(result.Messages)-1]
+		result.NextCursor, result.PrevCursor = pageCursors(searchCursor(first), searchCursor(last), opts.Cursor, result.HasMore)
 	}
 
 	return result, nil
@@ -180,19 +197,20 @@ func (s *SearchService) validateServerAccess(ctx context.Context, serverID *uuid
 	return nil
 }
 
-// validateChannelAccess checks if user can access the channel
-func (s *SearchService) validateChannelAccess(ctx context.Context, channelID uuid.UUID, requesterID uuid.UUID) error {
+// validateChannelAccess checks if user can access the channel and reports
+// whether it's end-to-end encrypted
+func (s *SearchService) validateChannelAccess(ctx context.Context, channelID uuid.UUID, requesterID uuid.UUID) (bool, error) {
 	channel, err := s.channelRepo.GetByID(ctx, channelID)
 	if err != nil {
-		return err
+		return false, err
 	}
 	if channel == nil {
-		return ErrChannelNotFound
+		return false, ErrChannelNotFound
 	}
 
 	// Check server membership for server channels
 	if channel.ServerID != nil {
-		return s.validateServerAccess(ctx, channel.ServerID, &requesterID)
+		return channel.E2EEEnabled, s.validateServerAccess(ctx, channel.ServerID, &requesterID)
 	}
 
 	// For DM channels, check if user is a participant
@@ -204,10 +222,10 @@ func (s *SearchService) validateChannelAccess(ctx context.Context, channelID uui
 		}
 	}
 	if !isParticipant {
-		return ErrNoPermission
+		return false, ErrNoPermission
 	}
 
-	return nil
+	return channel.E2EEEnabled, nil
 }
 
 // getAccessibleChannels returns all channels the user can access in a server
@@ -219,18 +237,35 @@ func (s *SearchService) getAccessibleChannels(ctx context.Context, serverID uuid
 
 	var accessible []uuid.UUID
 	for _, ch := range channels {
-		// Skip non-text channels
+		// Skip non-text channels, and encrypted ones the server can't read
 		if ch.Type != models.ChannelTypeText && ch.Type != models.ChannelTypeAnnouncement {
 			continue
 		}
+		if ch.E2EEEnabled {
+			continue
+		}
 		accessible = append(accessible, ch.ID)
 	}
 
 	return accessible, nil
 }
 
-// enrichMessages adds author information to messages
+// enrichMessages adds author information to messages, in one query when
+// the request has Loaders or the user repository supports batching
 func (s *SearchService) enrichMessages(ctx context.Context, messages []*models.Message) error {
+	loaders := LoadersFrom(ctx)
+	if loaders == nil {
+		if batch, ok := s.userRepo.(UserBatchRepository); ok {
+			loaders = NewLoaders(batch, nil)
+		}
+	}
+	if loaders != nil {
+		if err := loaders.AttachAuthors(ctx, messages); err != nil {
+			log.Printf("[Search] Failed to load message authors: %v", err)
+		}
+		return nil
+	}
+
 	// Collect unique author IDs
 	authorIDs := make(map[uuid.UUID]bool)
 	for _, msg := range messages {
@@ -133,6 +133,14 @@ func (m *MockUserRepositoryForSearch) GetPresence(ctx context.Context, userID uu
 	return args.Get(0).(*models.Presence), args.Error(1)
 }
 
+func (m *MockUserRepositoryForSearch) ClearExpiredCustomStatuses(ctx context.Context, before time.Time) ([]uuid.UUID, error) {
+	args := m.Called(ctx, before)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
 func (m *MockUserRepositoryForSearch) GetPresenceBulk(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*models.Presence, error) {
 	args := m.Called(ctx, userIDs)
 	if args.Get(0) == nil {
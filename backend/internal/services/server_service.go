@@ -55,6 +55,15 @@ type ServerService struct {
 	quotaService *QuotaService
 	cache        CacheService
 	eventBus     EventBus
+	expiry       ModerationExpiryRepository
+	screening    MembershipScreening
+	transfers    OwnershipTransferRepository
+	welcome      WelcomeScreens
+	banImports   BanImportRepository
+	blocklists   Blocklists
+	botTiers     BotTiers
+	pages        ServerPageRepository
+	search       MemberSearchRepository
 }
 
 // NewServerService creates a new server service
@@ -173,9 +182,9 @@ func (s *ServerService) CreateServer(ctx context.Context, ownerID uuid.UUID, nam
 	return server, nil
 }
 
-// GetServer retrieves a server by ID
+// GetServer retrieves a server by ID, through the cache
 func (s *ServerService) GetServer(ctx context.Context, id uuid.UUID) (*models.Server, error) {
-	server, err := s.repo.GetByID(ctx, id)
+	server, err := cachedServer(ctx, s.cache, s.repo, id)
 	if err != nil {
 		return nil, err
 	}
@@ -204,6 +213,10 @@ func (s *ServerService) UpdateServer(ctx context.Context, id uuid.UUID, requeste
 		// TODO: Check admin permission via roles
 	}
 
+	if updates.Version != nil && *updates.Version != server.Version {
+		return nil, &VersionConflictError{Current: server, Version: server.Version}
+	}
+
 	// Apply updates
 	if updates.Name != nil {
 		server.Name = *updates.Name
@@ -221,8 +234,20 @@ func (s *ServerService) UpdateServer(ctx context.Context, id uuid.UUID, requeste
 	server.UpdatedAt = time.Now()
 
 	if err := s.repo.Update(ctx, server); err != nil {
+		if errors.Is(err, ErrVersionConflict) {
+			// Someone saved between our read and write
+			current, getErr := s.repo.GetByID(ctx, id)
+			if getErr != nil {
+				return nil, getErr
+			}
+			if current == nil {
+				return nil, ErrServerNotFound
+			}
+			return nil, &VersionConflictError{Current: current, Version: current.Version}
+		}
 		return nil, err
 	}
+	forgetServer(ctx, s.cache, id)
 
 	s.eventBus.Publish("server.updated", &ServerUpdatedEvent{
 		Server: server,
@@ -248,6 +273,8 @@ func (s *ServerService) DeleteServer(ctx context.Context, id uuid.UUID, requeste
 	if err := s.repo.Delete(ctx, id); err != nil {
 		return err
 	}
+	forgetServer(ctx, s.cache, id)
+	forgetRoles(ctx, s.cache, id)
 
 	s.eventBus.Publish("server.deleted", &ServerDeletedEvent{
 		ServerID: id,
@@ -257,53 +284,6 @@ func (s *ServerService) DeleteServer(ctx context.Context, id uuid.UUID, requeste
 	return nil
 }
 
-// TransferOwnership transfers server ownership to a new owner (owner only)
-func (s *ServerService) TransferOwnership(ctx context.Context, serverID, requesterID, newOwnerID uuid.UUID) (*models.Server, error) {
-	server, err := s.repo.GetByID(ctx, serverID)
-	if err != nil {
-		return nil, err
-	}
-	if server == nil {
-		return nil, ErrServerNotFound
-	}
-
-	// Only owner can transfer
-	if server.OwnerID != requesterID {
-		return nil, ErrNotServerOwner
-	}
-
-	// Cannot transfer to self
-	if requesterID == newOwnerID {
-		return nil, ErrSelfAction
-	}
-
-	// New owner must be a member
-	member, err := s.repo.GetMember(ctx, serverID, newOwnerID)
-	if err != nil {
-		return nil, err
-	}
-	if member == nil {
-		return nil, ErrNotServerMember
-	}
-
-	// Transfer ownership
-	if err := s.repo.TransferOwnership(ctx, serverID, newOwnerID); err != nil {
-		return nil, err
-	}
-
-	// Update local server object
-	server.OwnerID = newOwnerID
-	server.UpdatedAt = time.Now()
-
-	s.eventBus.Publish("server.ownership_transferred", &OwnershipTransferredEvent{
-		ServerID:    serverID,
-		OldOwnerID:  requesterID,
-		NewOwnerID:  newOwnerID,
-	})
-
-	return server, nil
-}
-
 // JoinServer joins a server via invite
 func (s *ServerService) JoinServer(ctx context.Context, userID uuid.UUID, inviteCode string) (*models.Server, error) {
 	invite, err := s.repo.GetInvite(ctx, inviteCode)
@@ -334,7 +314,7 @@ func (s *ServerService) JoinServer(ctx context.Context, userID uuid.UUID, invite
 
 	// Check if banned
 	ban, _ := s.repo.GetBan(ctx, invite.ServerID, userID)
-	if ban != nil {
+	if ban != nil && ban.Active(time.Now()) {
 		return nil, ErrBannedFromServer
 	}
 
@@ -344,18 +324,39 @@ func (s *ServerService) JoinServer(ctx context.Context, userID uuid.UUID, invite
 		return nil, ErrAlreadyMember
 	}
 
+	if s.blocklists != nil {
+		banned, err := s.blocklists.BanIfBlocklisted(ctx, server, userID)
+		if err != nil {
+			return nil, err
+		}
+		if banned {
+			return nil, ErrBannedFromServer
+		}
+	}
+
 	// Check quota
 	limits, err := s.quotaService.GetEffectiveLimits(ctx, userID, nil)
 	if err != nil {
 		return nil, err
 	}
+	maxJoined := limits.MaxServersJoined
+	if s.botTiers != nil {
+		policy, err := s.botTiers.Policy(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if policy != nil {
+			// Bots are capped by their tier instead
+			maxJoined = policy.MaxServers
+		}
+	}
 
 	userServers, err := s.repo.GetUserServers(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	if limits.MaxServersJoined > 0 && len(userServers) >= limits.MaxServersJoined {
+	if maxJoined > 0 && len(userServers) >= maxJoined {
 		return nil, ErrMaxServersReached
 	}
 
@@ -379,6 +380,11 @@ func (s *ServerService) JoinServer(ctx context.Context, userID uuid.UUID, invite
 		JoinedAt: time.Now(),
 		Roles:    []uuid.UUID{everyoneRoleID},
 	}
+	if s.screening != nil {
+		if member.Pending, err = s.screening.ScreeningEnabled(ctx, invite.ServerID); err != nil {
+			return nil, err
+		}
+	}
 
 	if err := s.repo.AddMember(ctx, member); err != nil {
 		return nil, err
@@ -391,8 +397,14 @@ func (s *ServerService) JoinServer(ctx context.Context, userID uuid.UUID, invite
 		ServerID:   invite.ServerID,
 		UserID:     userID,
 		InviteCode: inviteCode,
+		Member:     member,
 	})
 
+	// The member has joined by now, so a missing welcome screen isn't fatal
+	if s.welcome != nil {
+		server.WelcomeScreen, _ = s.welcome.JoinWelcomeScreen(ctx, invite.ServerID)
+	}
+
 	return server, nil
 }
 
@@ -414,6 +426,7 @@ func (s *ServerService) LeaveServer(ctx context.Context, serverID, userID uuid.U
 	if err := s.repo.RemoveMember(ctx, serverID, userID); err != nil {
 		return err
 	}
+	forgetMember(ctx, s.cache, serverID, userID)
 
 	s.eventBus.Publish("server.member_left", &MemberLeftEvent{
 		ServerID: serverID,
@@ -423,7 +436,8 @@ func (s *ServerService) LeaveServer(ctx context.Context, serverID, userID uuid.U
 	return nil
 }
 
-// KickMember kicks a member from server
+// KickMember kicks a member from server. The requester needs KICK_MEMBERS
+// and a higher role than the target.
 func (s *ServerService) KickMember(ctx context.Context, serverID, requesterID, targetID uuid.UUID, reason string) error {
 	server, err := s.repo.GetByID(ctx, serverID)
 	if err != nil {
@@ -438,11 +452,17 @@ func (s *ServerService) KickMember(ctx context.Context, serverID, requesterID, t
 		return errors.New("cannot kick server owner")
 	}
 
-	// TODO: Check requester has KICK_MEMBERS permission
+	if !s.hasServerPermission(ctx, server, requesterID, models.PermKickMembers) {
+		return ErrCannotKick
+	}
+	if err := checkOutranks(ctx, s.repo, s.roleRepo, server, requesterID, targetID); err != nil {
+		return err
+	}
 
 	if err := s.repo.RemoveMember(ctx, serverID, targetID); err != nil {
 		return err
 	}
+	forgetMember(ctx, s.cache, serverID, targetID)
 
 	s.eventBus.Publish("server.member_kicked", &MemberKickedEvent{
 		ServerID: serverID,
@@ -454,8 +474,14 @@ func (s *ServerService) KickMember(ctx context.Context, serverID, requesterID, t
 	return nil
 }
 
-// BanMember bans a member from server
-func (s *ServerService) BanMember(ctx context.Context, serverID, requesterID, targetID uuid.UUID, reason string, deleteDays int) error {
+// BanMember bans a member from server. A positive duration makes the ban
+// lift itself after that long; zero bans permanently. The requester needs
+// BAN_MEMBERS and a higher role than the target.
+func (s *ServerService) BanMember(ctx context.Context, serverID, requesterID, targetID uuid.UUID, reason string, deleteDays int, duration time.Duration) error {
+	if duration < 0 {
+		return ErrBanDuration
+	}
+
 	server, err := s.repo.GetByID(ctx, serverID)
 	if err != nil {
 		return err
@@ -469,10 +495,16 @@ func (s *ServerService) BanMember(ctx context.Context, serverID, requesterID, ta
 		return errors.New("cannot ban server owner")
 	}
 
-	// TODO: Check requester has BAN_MEMBERS permission
+	if !s.hasServerPermission(ctx, server, requesterID, models.PermBanMembers) {
+		return ErrCannotBan
+	}
+	if err := checkOutranks(ctx, s.repo, s.roleRepo, server, requesterID, targetID); err != nil {
+		return err
+	}
 
 	// Remove member first
 	_ = s.repo.RemoveMember(ctx, serverID, targetID)
+	forgetMember(ctx, s.cache, serverID, targetID)
 
 	// Add ban
 	var banReason *string
@@ -486,6 +518,10 @@ func (s *ServerService) BanMember(ctx context.Context, serverID, requesterID, ta
 		BannedBy:  &requesterID,
 		CreatedAt: time.Now(),
 	}
+	if duration > 0 {
+		expiresAt := ban.CreatedAt.Add(duration)
+		ban.ExpiresAt = &expiresAt
+	}
 
 	if err := s.repo.AddBan(ctx, ban); err != nil {
 		return err
@@ -589,9 +625,9 @@ func (s *ServerService) GetMembers(ctx context.Context, serverID uuid.UUID, limi
 	return s.repo.GetMembers(ctx, serverID, limit, offset)
 }
 
-// GetMember retrieves a specific member
+// GetMember retrieves a specific member, through the cache
 func (s *ServerService) GetMember(ctx context.Context, serverID, userID uuid.UUID) (*models.Member, error) {
-	member, err := s.repo.GetMember(ctx, serverID, userID)
+	member, err := cachedMember(ctx, s.cache, s.repo, serverID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -614,16 +650,86 @@ func (s *ServerService) UpdateMember(ctx context.Context, serverID, requesterID,
 		member.Nickname = nickname
 	}
 	if roles != nil {
+		if err := s.checkRoleChange(ctx, serverID, requesterID, targetID, roles); err != nil {
+			return nil, err
+		}
 		member.Roles = roles
 	}
 
 	if err := s.repo.UpdateMember(ctx, member); err != nil {
 		return nil, err
 	}
+	forgetMember(ctx, s.cache, serverID, targetID)
 
 	return member, nil
 }
 
+// checkRoleChange makes sure requesterID may set targetID's roles to roles.
+// They need MANAGE_ROLES and a higher role than the target, unless it's
+// their own roles they're changing, and every role added or removed must
+// sit below their highest role.
+func (s *ServerService) checkRoleChange(ctx context.Context, serverID, requesterID, targetID uuid.UUID, roles []uuid.UUID) error {
+	server, err := s.repo.GetByID(ctx, serverID)
+	if err != nil {
+		return err
+	}
+	if server == nil {
+		return ErrServerNotFound
+	}
+	if server.OwnerID == requesterID {
+		return nil
+	}
+	if !s.hasServerPermission(ctx, server, requesterID, models.PermManageRoles) {
+		return ErrCannotManageRoles
+	}
+	if requesterID != targetID {
+		if err := checkOutranks(ctx, s.repo, s.roleRepo, server, requesterID, targetID); err != nil {
+			return err
+		}
+	}
+
+	top, err := memberPosition(ctx, s.repo, s.roleRepo, server, requesterID)
+	if err != nil {
+		return err
+	}
+	serverRoles, err := s.roleRepo.GetByServerID(ctx, serverID)
+	if err != nil {
+		return err
+	}
+	current, err := s.roleRepo.GetMemberRoles(ctx, serverID, targetID)
+	if err != nil {
+		return err
+	}
+
+	// Roles on only one side of the change are being added or removed
+	changed := make(map[uuid.UUID]bool, len(roles))
+	for _, id := range roles {
+		changed[id] = true
+	}
+	for _, role := range current {
+		if changed[role.ID] {
+			delete(changed, role.ID)
+		} else {
+			changed[role.ID] = true
+		}
+	}
+
+	byID := make(map[uuid.UUID]*models.Role, len(serverRoles))
+	for _, role := range serverRoles {
+		byID[role.ID] = role
+	}
+	for id := range changed {
+		role := byID[id]
+		if role == nil {
+			return ErrRoleNotFound
+		}
+		if role.Position >= top {
+			return ErrRoleHierarchy
+		}
+	}
+	return nil
+}
+
 // GetBans retrieves all bans for a server
 func (s *ServerService) GetBans(ctx context.Context, serverID uuid.UUID) ([]*models.Ban, error) {
 	return s.repo.GetBans(ctx, serverID)
@@ -632,7 +738,17 @@ func (s *ServerService) GetBans(ctx context.Context, serverID uuid.UUID) ([]*mod
 // UnbanMember removes a ban
 func (s *ServerService) UnbanMember(ctx context.Context, serverID, requesterID, targetID uuid.UUID) error {
 	// TODO: Check permissions
-	return s.repo.RemoveBan(ctx, serverID, targetID)
+	if err := s.repo.RemoveBan(ctx, serverID, targetID); err != nil {
+		return err
+	}
+
+	s.eventBus.Publish("server.member_unbanned", &MemberUnbannedEvent{
+		ServerID:    serverID,
+		UserID:      targetID,
+		ModeratorID: requesterID,
+	})
+
+	return nil
 }
 
 // GetInvites retrieves all invites for a server
@@ -696,7 +812,7 @@ func (s *ServerService) GetChannels(ctx context.Context, serverID uuid.UUID) ([]
 	return s.channelRepo.GetByServerID(ctx, serverID)
 }
 
-// GetRoles retrieves all roles for a server
+// GetRoles retrieves all roles for a server, through the cache
 func (s *ServerService) GetRoles(ctx context.Context, serverID uuid.UUID) ([]*models.Role, error) {
-	return s.roleRepo.GetByServerID(ctx, serverID)
+	return cachedRoles(ctx, s.cache, s.roleRepo, serverID)
 }
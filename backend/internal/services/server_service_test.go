@@ -77,7 +77,7 @@ func newTestServerService() (*ServerService, *MockServerRepository, *MockChannel
 	serverRepo := new(MockServerRepository)
 	channelRepo := new(MockChannelRepository)
 	roleRepo := new(MockRoleRepository)
-	cache := new(MockCacheService)
+	cache := new(MockCacheService).empty()
 	eventBus := new(MockEventBus)
 
 	quotaConfig := &models.QuotaConfig{
@@ -279,6 +279,28 @@ func TestUpdateServer_Success_ByOwner(t *testing.T) {
 	assert.Equal(t, newName, server.Name)
 }
 
+func TestUpdateServer_StaleVersion(t *testing.T) {
+	service, serverRepo, _, _, _, _ := newTestServerService()
+	ctx := context.Background()
+	serverID := uuid.New()
+	ownerID := uuid.New()
+
+	current := &models.Server{ID: serverID, Name: "Theirs", OwnerID: ownerID, Version: 7}
+	stale := 6
+	newName := "Mine"
+
+	serverRepo.On("GetByID", ctx, serverID).Return(current, nil)
+
+	server, err := service.UpdateServer(ctx, serverID, ownerID, &models.ServerUpdate{Name: &newName, Version: &stale})
+
+	assert.Nil(t, server)
+	var conflict *VersionConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, 7, conflict.Version)
+	assert.Same(t, current, conflict.Current)
+	serverRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
 func TestUpdateServer_NotFound(t *testing.T) {
 	service, serverRepo, _, _, _, _ := newTestServerService()
 	ctx := context.Background()
@@ -375,10 +397,10 @@ func TestDeleteServer_NotFound(t *testing.T) {
 }
 
 // ============================================
-// TransferOwnership Tests
+// RequestOwnershipTransfer Tests
 // ============================================
 
-func TestTransferOwnership_Success(t *testing.T) {
+func TestRequestOwnershipTransfer_Success(t *testing.T) {
 	service, serverRepo, _, _, _, eventBus := newTestServerService()
 	ctx := context.Background()
 	serverID := uuid.New()
@@ -396,20 +418,25 @@ func TestTransferOwnership_Success(t *testing.T) {
 		ServerID: serverID,
 	}
 
+	transfers := newFakeOwnershipTransferRepository()
+	service.SetOwnershipTransferRepository(transfers)
 	serverRepo.On("GetByID", ctx, serverID).Return(existingServer, nil)
 	serverRepo.On("GetMember", ctx, serverID, newOwnerID).Return(newOwnerMember, nil)
-	serverRepo.On("TransferOwnership", ctx, serverID, newOwnerID).Return(nil)
-	eventBus.On("Publish", "server.ownership_transferred", mock.Anything).Return()
 
-	server, err := service.TransferOwnership(ctx, serverID, ownerID, newOwnerID)
+	transfer, err := service.RequestOwnershipTransfer(ctx, serverID, ownerID, &models.OwnershipTransferRequest{NewOwnerID: newOwnerID})
 
 	require.NoError(t, err)
-	assert.Equal(t, newOwnerID, server.OwnerID)
+	assert.Equal(t, ownerID, transfer.FromUserID)
+	assert.Equal(t, newOwnerID, transfer.ToUserID)
+	assert.Equal(t, OwnershipTransferWindow, transfer.ExpiresAt.Sub(transfer.CreatedAt))
+	assert.Equal(t, transfer, transfers.transfers[serverID])
+	// Nothing changes until the new owner accepts
+	assert.Equal(t, ownerID, existingServer.OwnerID)
 	serverRepo.AssertExpectations(t)
-	eventBus.AssertExpectations(t)
+	eventBus.AssertNotCalled(t, "Publish", "server.ownership_transferred", mock.Anything)
 }
 
-func TestTransferOwnership_NotOwner(t *testing.T) {
+func TestRequestOwnershipTransfer_NotOwner(t *testing.T) {
 	service, serverRepo, _, _, _, _ := newTestServerService()
 	ctx := context.Background()
 	serverID := uuid.New()
@@ -425,13 +452,13 @@ func TestTransferOwnership_NotOwner(t *testing.T) {
 
 	serverRepo.On("GetByID", ctx, serverID).Return(existingServer, nil)
 
-	server, err := service.TransferOwnership(ctx, serverID, requesterID, newOwnerID)
+	server, err := service.RequestOwnershipTransfer(ctx, serverID, requesterID, &models.OwnershipTransferRequest{NewOwnerID: newOwnerID})
 
 	assert.Nil(t, server)
 	assert.ErrorIs(t, err, ErrNotServerOwner)
 }
 
-func TestTransferOwnership_ServerNotFound(t *testing.T) {
+func TestRequestOwnershipTransfer_ServerNotFound(t *testing.T) {
 	service, serverRepo, _, _, _, _ := newTestServerService()
 	ctx := context.Background()
 	serverID := uuid.New()
@@ -440,13 +467,13 @@ func TestTransferOwnership_ServerNotFound(t *testing.T) {
 
 	serverRepo.On("GetByID", ctx, serverID).Return(nil, nil)
 
-	server, err := service.TransferOwnership(ctx, serverID, requesterID, newOwnerID)
+	server, err := service.RequestOwnershipTransfer(ctx, serverID, requesterID, &models.OwnershipTransferRequest{NewOwnerID: newOwnerID})
 
 	assert.Nil(t, server)
 	assert.ErrorIs(t, err, ErrServerNotFound)
 }
 
-func TestTransferOwnership_SelfAction(t *testing.T) {
+func TestRequestOwnershipTransfer_SelfAction(t *testing.T) {
 	service, serverRepo, _, _, _, _ := newTestServerService()
 	ctx := context.Background()
 	serverID := uuid.New()
@@ -461,13 +488,13 @@ func TestTransferOwnership_SelfAction(t *testing.T) {
 	serverRepo.On("GetByID", ctx, serverID).Return(existingServer, nil)
 
 	// Try to transfer to self
-	server, err := service.TransferOwnership(ctx, serverID, ownerID, ownerID)
+	server, err := service.RequestOwnershipTransfer(ctx, serverID, ownerID, &models.OwnershipTransferRequest{NewOwnerID: ownerID})
 
 	assert.Nil(t, server)
 	assert.ErrorIs(t, err, ErrSelfAction)
 }
 
-func TestTransferOwnership_NewOwnerNotMember(t *testing.T) {
+func TestRequestOwnershipTransfer_NewOwnerNotMember(t *testing.T) {
 	service, serverRepo, _, _, _, _ := newTestServerService()
 	ctx := context.Background()
 	serverID := uuid.New()
@@ -483,13 +510,13 @@ func TestTransferOwnership_NewOwnerNotMember(t *testing.T) {
 	serverRepo.On("GetByID", ctx, serverID).Return(existingServer, nil)
 	serverRepo.On("GetMember", ctx, serverID, newOwnerID).Return(nil, nil)
 
-	server, err := service.TransferOwnership(ctx, serverID, ownerID, newOwnerID)
+	server, err := service.RequestOwnershipTransfer(ctx, serverID, ownerID, &models.OwnershipTransferRequest{NewOwnerID: newOwnerID})
 
 	assert.Nil(t, server)
 	assert.ErrorIs(t, err, ErrNotServerMember)
 }
 
-func TestTransferOwnership_DatabaseError(t *testing.T) {
+func TestRequestOwnershipTransfer_DatabaseError(t *testing.T) {
 	service, serverRepo, _, _, _, _ := newTestServerService()
 	ctx := context.Background()
 	serverID := uuid.New()
@@ -508,11 +535,13 @@ func TestTransferOwnership_DatabaseError(t *testing.T) {
 		ServerID: serverID,
 	}
 
+	transfers := newFakeOwnershipTransferRepository()
+	transfers.err = dbErr
+	service.SetOwnershipTransferRepository(transfers)
 	serverRepo.On("GetByID", ctx, serverID).Return(existingServer, nil)
 	serverRepo.On("GetMember", ctx, serverID, newOwnerID).Return(newOwnerMember, nil)
-	serverRepo.On("TransferOwnership", ctx, serverID, newOwnerID).Return(dbErr)
 
-	server, err := service.TransferOwnership(ctx, serverID, ownerID, newOwnerID)
+	server, err := service.RequestOwnershipTransfer(ctx, serverID, ownerID, &models.OwnershipTransferRequest{NewOwnerID: newOwnerID})
 
 	assert.Nil(t, server)
 	assert.Error(t, err)
@@ -811,7 +840,7 @@ func TestBanMember_Success(t *testing.T) {
 	})).Return(nil)
 	eventBus.On("Publish", "server.member_banned", mock.Anything).Return()
 
-	err := service.BanMember(ctx, serverID, ownerID, targetID, "spamming", 0)
+	err := service.BanMember(ctx, serverID, ownerID, targetID, "spamming", 0, 0)
 
 	require.NoError(t, err)
 }
@@ -830,7 +859,7 @@ func TestBanMember_CannotBanOwner(t *testing.T) {
 
 	serverRepo.On("GetByID", ctx, serverID).Return(server, nil)
 
-	err := service.BanMember(ctx, serverID, adminID, ownerID, "trying to ban owner", 0)
+	err := service.BanMember(ctx, serverID, adminID, ownerID, "trying to ban owner", 0, 0)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "cannot ban server owner")
@@ -1100,6 +1129,7 @@ func TestUpdateMember_Success_Roles(t *testing.T) {
 	}
 
 	serverRepo.On("GetMember", ctx, serverID, targetID).Return(existingMember, nil)
+	serverRepo.On("GetByID", ctx, serverID).Return(&models.Server{ID: serverID, OwnerID: requesterID}, nil)
 	serverRepo.On("UpdateMember", ctx, mock.MatchedBy(func(m *models.Member) bool {
 		return len(m.Roles) == 2
 	})).Return(nil)
@@ -1190,17 +1220,21 @@ func TestGetBans_Empty(t *testing.T) {
 // ============================================
 
 func TestUnbanMember_Success(t *testing.T) {
-	service, serverRepo, _, _, _, _ := newTestServerService()
+	service, serverRepo, _, _, _, eventBus := newTestServerService()
 	ctx := context.Background()
 	serverID := uuid.New()
 	requesterID := uuid.New()
 	targetID := uuid.New()
 
 	serverRepo.On("RemoveBan", ctx, serverID, targetID).Return(nil)
+	eventBus.On("Publish", "server.member_unbanned", mock.MatchedBy(func(e *MemberUnbannedEvent) bool {
+		return e.UserID == targetID && e.ModeratorID == requesterID
+	})).Return()
 
 	err := service.UnbanMember(ctx, serverID, requesterID, targetID)
 
 	require.NoError(t, err)
+	eventBus.AssertExpectations(t)
 }
 
 func TestUnbanMember_Error(t *testing.T) {
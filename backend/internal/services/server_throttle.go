@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	// serverThrottleCacheTTL bounds how long another instance's overrides
+	// take to apply here. Changes made on this instance apply straight away.
+	serverThrottleCacheTTL = time.Minute
+	// serverThrottleCacheSize caps the cached lookups; the cache starts over
+	// once it is full
+	serverThrottleCacheSize = 10000
+)
+
+// ServerThrottleRepository stores the limits instance admins set for
+// servers. The Postgres ServerThrottleRepository implements it.
+type ServerThrottleRepository interface {
+	// Get returns nil if the server has no overrides
+	Get(ctx context.Context, serverID uuid.UUID) (*models.ServerThrottle, error)
+	Save(ctx context.Context, throttle *models.ServerThrottle) error
+	Delete(ctx context.Context, serverID uuid.UUID) error
+}
+
+// ServerThrottleCounter counts a server's traffic in fixed windows.
+// RedisCache implements it.
+type ServerThrottleCounter interface {
+	IncrementWithExpiry(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// ServerMessageThrottle caps the messages sent in a server's channels. The
+// ServerThrottleService implements it.
+type ServerMessageThrottle interface {
+	// CheckServerMessage returns a *ServerRateLimitedError once the server
+	// has sent as many messages as it may this second
+	CheckServerMessage(ctx context.Context, serverID uuid.UUID) error
+}
+
+// SetServerThrottle caps the messages each server sends per second, so one
+// busy or abusive server can't starve the rest of the instance
+func (s *MessageService) SetServerThrottle(throttle ServerMessageThrottle) {
+	s.serverThrottle = throttle
+}
+
+// ServerThrottleService applies per-server rate limits: how many messages a
+// server's members may send and how many gateway events they may cause each
+// second, all members together. Servers use the instance's limits unless
+// instance admins override them.
+type ServerThrottleService struct {
+	repo    ServerThrottleRepository
+	servers ServerRepository
+	counter ServerThrottleCounter
+	now     func() time.Time
+
+	mu       sync.Mutex
+	defaults models.ServerThrottleLimits
+	limits   map[uuid.UUID]cachedServerThrottle
+}
+
+type cachedServerThrottle struct {
+	limits  models.ServerThrottleLimits
+	expires time.Time
+}
+
+// NewServerThrottleService creates a new server throttle service that
+// counts on this instance only
+func NewServerThrottleService(repo ServerThrottleRepository, servers ServerRepository, defaults models.ServerThrottleLimits) *ServerThrottleService {
+	return &ServerThrottleService{
+		repo:     repo,
+		servers:  servers,
+		defaults: defaults,
+		counter:  newMemoryCounter(),
+		now:      time.Now,
+		limits:   make(map[uuid.UUID]cachedServerThrottle),
+	}
+}
+
+// SetCounter shares the counts between instances, so limits apply to the
+// whole deployment rather than to each instance
+func (s *ServerThrottleService) SetCounter(counter ServerThrottleCounter) {
+	s.counter = counter
+}
+
+// SetDefaults changes the instance's limits, e.g. when the config is
+// reloaded. Servers with overrides keep them.
+func (s *ServerThrottleService) SetDefaults(defaults models.ServerThrottleLimits) {
+	s.mu.Lock()
+	s.defaults = defaults
+	s.limits = make(map[uuid.UUID]cachedServerThrottle)
+	s.mu.Unlock()
+}
+
+// Defaults returns the instance's limits
+func (s *ServerThrottleService) Defaults() models.ServerThrottleLimits {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.defaults
+}
+
+// GetThrottle returns a server's overrides and the limits that apply
+func (s *ServerThrottleService) GetThrottle(ctx context.Context, serverID uuid.UUID) (*models.ServerThrottle, error) {
+	if err := s.checkServer(ctx, serverID); err != nil {
+		return nil, err
+	}
+	throttle, err := s.repo.Get(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if throttle == nil {
+		throttle = &models.ServerThrottle{ServerID: serverID}
+	}
+	throttle.Limits = throttle.Apply(s.Defaults())
+	return throttle, nil
+}
+
+// SetThrottle overrides a server's limits
+func (s *ServerThrottleService) SetThrottle(ctx context.Context, adminID, serverID uuid.UUID, req *models.SetServerThrottleRequest) (*models.ServerThrottle, error) {
+	for _, limit := range []*int{req.MessagesPerSecond, req.EventsPerSecond} {
+		if limit != nil && *limit < 0 {
+			return nil, ErrInvalidServerThrottle
+		}
+	}
+	if err := s.checkServer(ctx, serverID); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	throttle := &models.ServerThrottle{
+		ServerID:          serverID,
+		MessagesPerSecond: req.MessagesPerSecond,
+		EventsPerSecond:   req.EventsPerSecond,
+		Reason:            req.Reason,
+		UpdatedBy:         &adminID,
+		UpdatedAt:         &now,
+	}
+	if err := s.repo.Save(ctx, throttle); err != nil {
+		return nil, err
+	}
+	throttle.Limits = throttle.Apply(s.Defaults())
+
+	s.forget(serverID)
+	return throttle, nil
+}
+
+// ResetThrottle removes a server's overrides so the instance limits apply
+// again
+func (s *ServerThrottleService) ResetThrottle(ctx context.Context, serverID uuid.UUID) error {
+	if err := s.checkServer(ctx, serverID); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, serverID); err != nil {
+		return err
+	}
+	s.forget(serverID)
+	return nil
+}
+
+// CheckServerMessage counts a message sent in one of the server's channels
+// and returns a *ServerRateLimitedError if the server is over its limit
+func (s *ServerThrottleService) CheckServerMessage(ctx context.Context, serverID uuid.UUID) error {
+	limit := s.serverLimits(ctx, serverID).MessagesPerSecond
+	if retryAfter, ok := s.take(ctx, "messages", serverID, limit); !ok {
+		return &ServerRateLimitedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// CheckServerEvent counts a gateway event for the server, such as a typing
+// indicator, and returns a *ServerRateLimitedError if the server is over its
+// limit
+func (s *ServerThrottleService) CheckServerEvent(ctx context.Context, serverID uuid.UUID) error {
+	limit := s.serverLimits(ctx, serverID).EventsPerSecond
+	if retryAfter, ok := s.take(ctx, "events", serverID, limit); !ok {
+		return &ServerRateLimitedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// AllowServerEvent counts a gateway event for the server and reports
+// whether it is within the server's limit
+func (s *ServerThrottleService) AllowServerEvent(serverID uuid.UUID) bool {
+	return s.CheckServerEvent(context.Background(), serverID) == nil
+}
+
+// take counts one unit of traffic in the current second and reports whether
+// it is within limit, and if not how long until the next second starts.
+// Counting fails open so an unreachable Redis doesn't stop every server.
+func (s *ServerThrottleService) take(ctx context.Context, kind string, serverID uuid.UUID, limit int) (time.Duration, bool) {
+	if limit <= 0 {
+		return 0, true
+	}
+	now := s.now()
+	key := fmt.Sprintf("server_throttle:%s:%s:%d", kind, serverID, now.Unix())
+	count, err := s.counter.IncrementWithExpiry(ctx, key, 2*time.Second)
+	if err != nil {
+		log.Printf("[ServerThrottle] failed to count %s for %s: %v", kind, serverID, err)
+		return 0, true
+	}
+	if count <= int64(limit) {
+		return 0, true
+	}
+	return now.Truncate(time.Second).Add(time.Second).Sub(now), false
+}
+
+// serverLimits returns the limits that apply to a server. Lookups that fail
+// are logged and the server gets the instance limits.
+func (s *ServerThrottleService) serverLimits(ctx context.Context, serverID uuid.UUID) models.ServerThrottleLimits {
+	now := s.now()
+	s.mu.Lock()
+	cached, ok := s.limits[serverID]
+	defaults := s.defaults
+	s.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.limits
+	}
+
+	throttle, err := s.repo.Get(ctx, serverID)
+	if err != nil {
+		log.Printf("[ServerThrottle] failed to look up the limits of %s: %v", serverID, err)
+		return defaults
+	}
+	limits := throttle.Apply(defaults)
+
+	s.mu.Lock()
+	if len(s.limits) >= serverThrottleCacheSize {
+		s.limits = make(map[uuid.UUID]cachedServerThrottle)
+	}
+	s.limits[serverID] = cachedServerThrottle{limits: limits, expires: now.Add(serverThrottleCacheTTL)}
+	s.mu.Unlock()
+	return limits
+}
+
+func (s *ServerThrottleService) forget(serverID uuid.UUID) {
+	s.mu.Lock()
+	delete(s.limits, serverID)
+	s.mu.Unlock()
+}
+
+// checkServer returns ErrServerNotFound unless the server exists
+func (s *ServerThrottleService) checkServer(ctx context.Context, serverID uuid.UUID) error {
+	server, err := s.servers.GetByID(ctx, serverID)
+	if err != nil {
+		return err
+	}
+	if server == nil {
+		return ErrServerNotFound
+	}
+	return nil
+}
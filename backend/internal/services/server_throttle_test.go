@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeServerThrottleRepo struct {
+	throttles map[uuid.UUID]*models.ServerThrottle
+	gets      int
+}
+
+func (r *fakeServerThrottleRepo) Get(ctx context.Context, serverID uuid.UUID) (*models.ServerThrottle, error) {
+	r.gets++
+	if t, ok := r.throttles[serverID]; ok {
+		copied := *t
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeServerThrottleRepo) Save(ctx context.Context, throttle *models.ServerThrottle) error {
+	copied := *throttle
+	r.throttles[throttle.ServerID] = &copied
+	return nil
+}
+
+func (r *fakeServerThrottleRepo) Delete(ctx context.Context, serverID uuid.UUID) error {
+	delete(r.throttles, serverID)
+	return nil
+}
+
+type failingCounter struct{}
+
+func (failingCounter) IncrementWithExpiry(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return 0, errors.New("redis is down")
+}
+
+type serverThrottleTest struct {
+	service  *ServerThrottleService
+	repo     *fakeServerThrottleRepo
+	now      time.Time
+	serverID uuid.UUID
+	adminID  uuid.UUID
+}
+
+func newServerThrottleTest() *serverThrottleTest {
+	f := &serverThrottleTest{
+		repo:     &fakeServerThrottleRepo{throttles: make(map[uuid.UUID]*models.ServerThrottle)},
+		now:      time.Unix(1700000000, 250*int64(time.Millisecond)),
+		serverID: uuid.New(),
+		adminID:  uuid.New(),
+	}
+	servers := new(MockServerRepository)
+	servers.On("GetByID", mock.Anything, f.serverID).Return(&models.Server{ID: f.serverID}, nil)
+	servers.On("GetByID", mock.Anything, mock.Anything).Return(nil, nil)
+
+	f.service = NewServerThrottleService(f.repo, servers, models.ServerThrottleLimits{MessagesPerSecond: 3, EventsPerSecond: 2})
+	f.service.now = func() time.Time { return f.now }
+	return f
+}
+
+func TestServerThrottleService_CheckServerMessage(t *testing.T) {
+	f := newServerThrottleTest()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, f.service.CheckServerMessage(ctx, f.serverID))
+	}
+	err := f.service.CheckServerMessage(ctx, f.serverID)
+	require.ErrorIs(t, err, ErrServerRateLimited)
+	var limited *ServerRateLimitedError
+	require.True(t, errors.As(err, &limited))
+	assert.Equal(t, 750*time.Millisecond, limited.RetryAfter, "servers retry when the next second starts")
+
+	assert.NoError(t, f.service.CheckServerMessage(ctx, uuid.New()), "other servers have their own share")
+	assert.NoError(t, f.service.CheckServerEvent(ctx, f.serverID), "events are counted apart from messages")
+
+	f.now = f.now.Add(time.Second)
+	assert.NoError(t, f.service.CheckServerMessage(ctx, f.serverID))
+}
+
+func TestServerThrottleService_Overrides(t *testing.T) {
+	f := newServerThrottleTest()
+	ctx := context.Background()
+
+	throttle, err := f.service.GetThrottle(ctx, f.serverID)
+	require.NoError(t, err)
+	assert.Nil(t, throttle.MessagesPerSecond)
+	assert.Equal(t, models.ServerThrottleLimits{MessagesPerSecond: 3, EventsPerSecond: 2}, throttle.Limits)
+
+	// Caches the instance limits before the override
+	assert.True(t, f.service.AllowServerEvent(f.serverID))
+
+	throttle, err = f.service.SetThrottle(ctx, f.adminID, f.serverID, &models.SetServerThrottleRequest{EventsPerSecond: intPtr(0)})
+	require.NoError(t, err)
+	assert.Equal(t, models.ServerThrottleLimits{MessagesPerSecond: 3, EventsPerSecond: 0}, throttle.Limits)
+	assert.Equal(t, &f.adminID, throttle.UpdatedBy)
+	for i := 0; i < 10; i++ {
+		assert.True(t, f.service.AllowServerEvent(f.serverID), "0 is unlimited and applies straight away")
+	}
+
+	_, err = f.service.SetThrottle(ctx, f.adminID, f.serverID, &models.SetServerThrottleRequest{MessagesPerSecond: intPtr(-1)})
+	assert.ErrorIs(t, err, ErrInvalidServerThrottle)
+	_, err = f.service.SetThrottle(ctx, f.adminID, uuid.New(), &models.SetServerThrottleRequest{})
+	assert.ErrorIs(t, err, ErrServerNotFound)
+
+	require.NoError(t, f.service.ResetThrottle(ctx, f.serverID))
+	assert.Empty(t, f.repo.throttles)
+	assert.True(t, f.service.AllowServerEvent(f.serverID))
+	assert.False(t, f.service.AllowServerEvent(f.serverID), "the instance limits apply again")
+}
+
+func TestServerThrottleService_CachesLimits(t *testing.T) {
+	f := newServerThrottleTest()
+	ctx := context.Background()
+
+	f.service.CheckServerMessage(ctx, f.serverID)
+	f.service.CheckServerMessage(ctx, f.serverID)
+	assert.Equal(t, 1, f.repo.gets)
+
+	f.now = f.now.Add(serverThrottleCacheTTL)
+	f.service.CheckServerMessage(ctx, f.serverID)
+	assert.Equal(t, 2, f.repo.gets)
+}
+
+func TestServerThrottleService_FailsOpen(t *testing.T) {
+	f := newServerThrottleTest()
+	f.service.SetCounter(failingCounter{})
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, f.service.CheckServerMessage(context.Background(), f.serverID))
+	}
+}
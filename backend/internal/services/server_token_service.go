@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	// MaxServerTokens is how many tokens a server can have at once
+	MaxServerTokens = 25
+	// MaxServerTokenLifetime is how far ahead a token can expire
+	MaxServerTokenLifetime = 365 * 24 * time.Hour
+
+	maxServerTokenName = 100
+	// serverTokenTouchInterval is how stale a token's last use may get
+	// before it's written again
+	serverTokenTouchInterval = time.Minute
+)
+
+// ServerTokenRepository stores server tokens by the hash of their secret.
+// The Postgres ServerTokenRepository implements it.
+type ServerTokenRepository interface {
+	Create(ctx context.Context, token *models.ServerToken) error
+	// GetByHash returns nil if no token has the hash
+	GetByHash(ctx context.Context, tokenHash string) (*models.ServerToken, error)
+	// GetByServerID returns the server's tokens, newest first
+	GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*models.ServerToken, error)
+	// Delete reports whether the server had the token
+	Delete(ctx context.Context, serverID, tokenID uuid.UUID) (bool, error)
+	// Touch records that the token was used at at
+	Touch(ctx context.Context, tokenID uuid.UUID, at time.Time) error
+}
+
+// ServerTokenService manages server tokens, which let automation such as
+// CI jobs call a server's endpoints without a user's session. Only the
+// owner and co-owners can manage them.
+type ServerTokenService struct {
+	repo        ServerTokenRepository
+	serverRepo  ServerRepository
+	channelRepo ChannelRepository
+	now         func() time.Time
+}
+
+// NewServerTokenService creates a new server token service
+func NewServerTokenService(repo ServerTokenRepository, serverRepo ServerRepository, channelRepo ChannelRepository) *ServerTokenService {
+	return &ServerTokenService{
+		repo:        repo,
+		serverRepo:  serverRepo,
+		channelRepo: channelRepo,
+		now:         time.Now,
+	}
+}
+
+// ListTokens returns a server's tokens, without their secrets
+func (s *ServerTokenService) ListTokens(ctx context.Context, serverID, requesterID uuid.UUID) ([]*models.ServerToken, error) {
+	if err := s.authorize(ctx, serverID, requesterID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByServerID(ctx, serverID)
+}
+
+// CreateToken creates a token with the given scopes that acts as the
+// requester in serverID until it expires. The returned token carries its
+// secret, which isn't kept and can't be shown again.
+func (s *ServerTokenService) CreateToken(ctx context.Context, serverID, requesterID uuid.UUID, req *models.CreateServerTokenRequest) (*models.ServerToken, error) {
+	if err := s.authorize(ctx, serverID, requesterID); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	token := &models.ServerToken{
+		ID:        uuid.New(),
+		ServerID:  serverID,
+		CreatorID: requesterID,
+		Name:      strings.TrimSpace(req.Name),
+		ExpiresAt: req.ExpiresAt.UTC(),
+		CreatedAt: now,
+	}
+	if err := normalizeServerToken(token, req.Scopes, now); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.GetByServerID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= MaxServerTokens {
+		return nil, ErrTooManyServerTokens
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	token.Token = models.ServerTokenPrefix + hex.EncodeToString(secret)
+	token.TokenHash = hashServerToken(token.Token)
+
+	if err := s.repo.Create(ctx, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// RevokeToken deletes a server's token, which stops working at once
+func (s *ServerTokenService) RevokeToken(ctx context.Context, serverID, tokenID, requesterID uuid.UUID) error {
+	if err := s.authorize(ctx, serverID, requesterID); err != nil {
+		return err
+	}
+	deleted, err := s.repo.Delete(ctx, serverID, tokenID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrServerTokenNotFound
+	}
+	return nil
+}
+
+// AuthenticateServerToken returns the token raw is the secret of. It
+// returns ErrInvalidServerToken for unknown tokens and tokens whose
+// creator no longer owns the server, and ErrServerTokenExpired for
+// expired ones.
+func (s *ServerTokenService) AuthenticateServerToken(ctx context.Context, raw string) (*models.ServerToken, error) {
+	if !strings.HasPrefix(raw, models.ServerTokenPrefix) {
+		return nil, ErrInvalidServerToken
+	}
+	token, err := s.repo.GetByHash(ctx, hashServerToken(raw))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, ErrInvalidServerToken
+	}
+	now := s.now()
+	if !now.Before(token.ExpiresAt) {
+		return nil, ErrServerTokenExpired
+	}
+	if err := s.authorize(ctx, token.ServerID, token.CreatorID); err != nil {
+		if err == ErrNotServerOwner || err == ErrServerNotFound {
+			return nil, ErrInvalidServerToken
+		}
+		return nil, err
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= serverTokenTouchInterval {
+		if err := s.repo.Touch(ctx, token.ID, now); err != nil {
+			return nil, err
+		}
+		token.LastUsedAt = &now
+	}
+	return token, nil
+}
+
+// ChannelServerID returns the server a channel is in, or uuid.Nil for DMs
+// and channels that don't exist
+func (s *ServerTokenService) ChannelServerID(ctx context.Context, channelID uuid.UUID) (uuid.UUID, error) {
+	channel, err := s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		if err == ErrChannelNotFound {
+			return uuid.Nil, nil
+		}
+		return uuid.Nil, err
+	}
+	if channel == nil || channel.ServerID == nil {
+		return uuid.Nil, nil
+	}
+	return *channel.ServerID, nil
+}
+
+// authorize checks that userID is the server's owner or a co-owner
+func (s *ServerTokenService) authorize(ctx context.Context, serverID, userID uuid.UUID) error {
+	server, err := s.serverRepo.GetByID(ctx, serverID)
+	if err != nil {
+		return err
+	}
+	if server == nil {
+		return ErrServerNotFound
+	}
+	if server.OwnerID == userID {
+		return nil
+	}
+	member, err := s.serverRepo.GetMember(ctx, serverID, userID)
+	if err != nil {
+		return err
+	}
+	if member == nil || !member.CoOwner {
+		return ErrNotServerOwner
+	}
+	return nil
+}
+
+func hashServerToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func invalidServerToken(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidServerTokenRequest, fmt.Sprintf(format, args...))
+}
+
+// normalizeServerToken checks a new token's name and expiry and keeps each
+// of its scopes once, in the order of models.ServerTokenScopes
+func normalizeServerToken(token *models.ServerToken, scopes []string, now time.Time) error {
+	if token.Name == "" || utf8.RuneCountInString(token.Name) > maxServerTokenName {
+		return invalidServerToken("name must be 1-%d characters", maxServerTokenName)
+	}
+	if !token.ExpiresAt.After(now) {
+		return invalidServerToken("expires_at must be in the future")
+	}
+	if token.ExpiresAt.After(now.Add(MaxServerTokenLifetime)) {
+		return invalidServerToken("tokens can last at most %d days", int(MaxServerTokenLifetime.Hours()/24))
+	}
+
+	requested := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		requested[scope] = true
+	}
+	token.Scopes = []string{}
+	for _, scope := range models.ServerTokenScopes {
+		if requested[scope] {
+			token.Scopes = append(token.Scopes, scope)
+			delete(requested, scope)
+		}
+	}
+	for scope := range requested {
+		return invalidServerToken("unknown scope %q", scope)
+	}
+	if len(token.Scopes) == 0 {
+		return invalidServerToken("a token needs at least one scope")
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeServerTokenRepository struct {
+	tokens  map[uuid.UUID]*models.ServerToken
+	touches int
+}
+
+func (f *fakeServerTokenRepository) Create(ctx context.Context, token *models.ServerToken) error {
+	copied := *token
+	copied.Token = ""
+	f.tokens[token.ID] = &copied
+	return nil
+}
+
+func (f *fakeServerTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.ServerToken, error) {
+	for _, token := range f.tokens {
+		if token.TokenHash == tokenHash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeServerTokenRepository) GetByServerID(ctx context.Context, serverID uuid.UUID) ([]*models.ServerToken, error) {
+	var tokens []*models.ServerToken
+	for _, token := range f.tokens {
+		if token.ServerID == serverID {
+			copied := *token
+			tokens = append(tokens, &copied)
+		}
+	}
+	return tokens, nil
+}
+
+func (f *fakeServerTokenRepository) Delete(ctx context.Context, serverID, tokenID uuid.UUID) (bool, error) {
+	token, ok := f.tokens[tokenID]
+	if !ok || token.ServerID != serverID {
+		return false, nil
+	}
+	delete(f.tokens, tokenID)
+	return true, nil
+}
+
+func (f *fakeServerTokenRepository) Touch(ctx context.Context, tokenID uuid.UUID, at time.Time) error {
+	f.touches++
+	f.tokens[tokenID].LastUsedAt = &at
+	return nil
+}
+
+type serverTokenTest struct {
+	service    *ServerTokenService
+	repo       *fakeServerTokenRepository
+	serverRepo *MockServerRepository
+	server     *models.Server
+	coOwner    uuid.UUID
+	member     uuid.UUID
+	now        time.Time
+}
+
+func newServerTokenTest() *serverTokenTest {
+	f := &serverTokenTest{
+		repo:       &fakeServerTokenRepository{tokens: make(map[uuid.UUID]*models.ServerToken)},
+		serverRepo: new(MockServerRepository),
+		server:     &models.Server{ID: uuid.New(), OwnerID: uuid.New()},
+		coOwner:    uuid.New(),
+		member:     uuid.New(),
+		now:        time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC),
+	}
+	f.serverRepo.On("GetByID", mock.Anything, f.server.ID).Return(f.server, nil)
+	f.serverRepo.On("GetMember", mock.Anything, f.server.ID, f.coOwner).Return(&models.Member{ServerID: f.server.ID, UserID: f.coOwner, CoOwner: true}, nil)
+	f.serverRepo.On("GetMember", mock.Anything, f.server.ID, f.member).Return(&models.Member{ServerID: f.server.ID, UserID: f.member}, nil)
+
+	f.service = NewServerTokenService(f.repo, f.serverRepo, new(MockChannelRepository))
+	f.service.now = func() time.Time { return f.now }
+	return f
+}
+
+func (f *serverTokenTest) create(t *testing.T, creatorID uuid.UUID) *models.ServerToken {
+	token, err := f.service.CreateToken(context.Background(), f.server.ID, creatorID, &models.CreateServerTokenRequest{
+		Name:      " Release notes ",
+		Scopes:    []string{models.ServerTokenScopeMessagesWrite, models.ServerTokenScopeServerRead, models.ServerTokenScopeMessagesWrite},
+		ExpiresAt: f.now.Add(30 * 24 * time.Hour),
+	})
+	require.NoError(t, err)
+	return token
+}
+
+func TestServerTokenService_CreateToken(t *testing.T) {
+	f := newServerTokenTest()
+	ctx := context.Background()
+
+	token := f.create(t, f.coOwner)
+	assert.Equal(t, "Release notes", token.Name)
+	assert.Equal(t, []string{models.ServerTokenScopeServerRead, models.ServerTokenScopeMessagesWrite}, token.Scopes)
+	assert.True(t, strings.HasPrefix(token.Token, models.ServerTokenPrefix))
+	assert.Equal(t, hashServerToken(token.Token), token.TokenHash)
+
+	listed, err := f.service.ListTokens(ctx, f.server.ID, f.server.OwnerID)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Empty(t, listed[0].Token, "secrets are only shown once")
+
+	for _, req := range []*models.CreateServerTokenRequest{
+		{Name: "", Scopes: []string{models.ServerTokenScopeServerRead}, ExpiresAt: f.now.Add(time.Hour)},
+		{Name: "CI", Scopes: []string{}, ExpiresAt: f.now.Add(time.Hour)},
+		{Name: "CI", Scopes: []string{"admin"}, ExpiresAt: f.now.Add(time.Hour)},
+		{Name: "CI", Scopes: []string{models.ServerTokenScopeServerRead}, ExpiresAt: f.now},
+		{Name: "CI", Scopes: []string{models.ServerTokenScopeServerRead}, ExpiresAt: f.now.Add(MaxServerTokenLifetime + time.Hour)},
+	} {
+		_, err := f.service.CreateToken(ctx, f.server.ID, f.server.OwnerID, req)
+		assert.ErrorIs(t, err, ErrInvalidServerTokenRequest)
+	}
+
+	_, err = f.service.CreateToken(ctx, f.server.ID, f.member, &models.CreateServerTokenRequest{})
+	assert.ErrorIs(t, err, ErrNotServerOwner)
+	_, err = f.service.ListTokens(ctx, f.server.ID, f.member)
+	assert.ErrorIs(t, err, ErrNotServerOwner)
+}
+
+func TestServerTokenService_AuthenticateServerToken(t *testing.T) {
+	f := newServerTokenTest()
+	ctx := context.Background()
+	token := f.create(t, f.server.OwnerID)
+
+	authenticated, err := f.service.AuthenticateServerToken(ctx, token.Token)
+	require.NoError(t, err)
+	assert.Equal(t, token.ID, authenticated.ID)
+	assert.Equal(t, f.server.OwnerID, authenticated.CreatorID)
+	_, err = f.service.AuthenticateServerToken(ctx, token.Token)
+	require.NoError(t, err)
+	assert.Equal(t, 1, f.repo.touches, "last use is written at most once a minute")
+
+	_, err = f.service.AuthenticateServerToken(ctx, token.Token+"0")
+	assert.ErrorIs(t, err, ErrInvalidServerToken)
+	_, err = f.service.AuthenticateServerToken(ctx, "eyJhbGciOi")
+	assert.ErrorIs(t, err, ErrInvalidServerToken)
+
+	f.now = token.ExpiresAt
+	_, err = f.service.AuthenticateServerToken(ctx, token.Token)
+	assert.ErrorIs(t, err, ErrServerTokenExpired)
+}
+
+func TestServerTokenService_CreatorLosesOwnership(t *testing.T) {
+	f := newServerTokenTest()
+	ctx := context.Background()
+	token := f.create(t, f.coOwner)
+
+	// The co-owner stepped down
+	f.serverRepo.ExpectedCalls = nil
+	f.serverRepo.On("GetByID", mock.Anything, f.server.ID).Return(f.server, nil)
+	f.serverRepo.On("GetMember", mock.Anything, f.server.ID, f.coOwner).Return(&models.Member{ServerID: f.server.ID, UserID: f.coOwner}, nil)
+
+	_, err := f.service.AuthenticateServerToken(ctx, token.Token)
+	assert.ErrorIs(t, err, ErrInvalidServerToken)
+}
+
+func TestServerTokenService_RevokeToken(t *testing.T) {
+	f := newServerTokenTest()
+	ctx := context.Background()
+	token := f.create(t, f.server.OwnerID)
+
+	assert.ErrorIs(t, f.service.RevokeToken(ctx, f.server.ID, token.ID, f.member), ErrNotServerOwner)
+	require.NoError(t, f.service.RevokeToken(ctx, f.server.ID, token.ID, f.coOwner))
+	assert.ErrorIs(t, f.service.RevokeToken(ctx, f.server.ID, token.ID, f.coOwner), ErrServerTokenNotFound)
+
+	_, err := f.service.AuthenticateServerToken(ctx, token.Token)
+	assert.ErrorIs(t, err, ErrInvalidServerToken)
+}
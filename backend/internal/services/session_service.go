@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/auth"
+	"hearth/internal/models"
+)
+
+// maxSessionUserAgentLength caps the user agent stored with a session
+const maxSessionUserAgentLength = 512
+
+// SessionRepository stores login sessions and the token version each user
+// is on. The Postgres SessionRepository implements it.
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	// Get returns nil if there's no such session
+	Get(ctx context.Context, id uuid.UUID) (*models.Session, error)
+	// Rotate stores the session's new refresh token hash and client
+	// details if its hash is still oldHash, and returns false otherwise
+	Rotate(ctx context.Context, session *models.Session, oldHash string) (bool, error)
+	ListByUser(ctx context.Context, userID uuid.UUID, now time.Time) ([]*models.Session, error)
+	// Delete returns false if the user has no such session
+	Delete(ctx context.Context, userID, id uuid.UUID) (bool, error)
+	DeleteByUser(ctx context.Context, userID uuid.UUID) error
+	TokenVersion(ctx context.Context, userID uuid.UUID) (int, error)
+	BumpTokenVersion(ctx context.Context, userID uuid.UUID) (int, error)
+}
+
+// ClientInfo describes the client a request came from
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+type clientInfoKey struct{}
+
+// WithClientInfo attaches the requesting client to a context
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// ClientInfoFrom returns the client attached to ctx, if any
+func ClientInfoFrom(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
+
+// SessionService tracks the login sessions refresh tokens belong to, so
+// users can see where they're logged in and revoke sessions
+type SessionService struct {
+	repo        SessionRepository
+	jwt         *auth.JWTService
+	suspensions AccountSuspensionChecker
+	oauthGrants OAuthGrantRevoker
+	now         func() time.Time
+}
+
+// AccountSuspensionChecker refuses suspended accounts. The
+// UserSuspensionService implements it.
+type AccountSuspensionChecker interface {
+	// Check returns an *AccountSuspendedError if the user is suspended
+	Check(ctx context.Context, userID uuid.UUID) error
+}
+
+// OAuthGrantRevoker takes back what a user granted OAuth applications. The
+// OAuthProviderService implements it.
+type OAuthGrantRevoker interface {
+	RevokeAllAuthorizations(ctx context.Context, userID uuid.UUID) error
+}
+
+// NewSessionService creates a new session service
+func NewSessionService(repo SessionRepository, jwtService *auth.JWTService) *SessionService {
+	return &SessionService{
+		repo: repo,
+		jwt:  jwtService,
+		now:  time.Now,
+	}
+}
+
+// SetSuspensions refuses to start, refresh or validate sessions of
+// suspended accounts
+func (s *SessionService) SetSuspensions(suspensions AccountSuspensionChecker) {
+	s.suspensions = suspensions
+}
+
+// SetOAuthGrants revokes the user's OAuth grants whenever they're logged
+// out everywhere, so applications lose access along with their sessions
+func (s *SessionService) SetOAuthGrants(grants OAuthGrantRevoker) {
+	s.oauthGrants = grants
+}
+
+// checkSuspension returns an *AccountSuspendedError if the user is
+// suspended
+func (s *SessionService) checkSuspension(ctx context.Context, userID uuid.UUID) error {
+	if s.suspensions == nil {
+		return nil
+	}
+	return s.suspensions.Check(ctx, userID)
+}
+
+// Start opens a session for a login and returns its first token pair
+func (s *SessionService) Start(ctx context.Context, userID uuid.UUID, username string, bot bool) (*AuthTokens, error) {
+	if err := s.checkSuspension(ctx, userID); err != nil {
+		return nil, err
+	}
+	version, err := s.repo.TokenVersion(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	session := &models.Session{
+		ID:         uuid.New(),
+		UserID:     userID,
+		CreatedAt:  now,
+		LastUsedAt: &now,
+	}
+	tokens, err := s.issue(ctx, session, username, bot, version)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Refresh swaps a validated refresh token for a new pair in the same
+// session. Refresh tokens work once: presenting one that was already
+// swapped means it leaked, so the whole session is revoked and
+// ErrRefreshTokenReused returned. Tokens issued before sessions were
+// tracked are moved into a new session.
+func (s *SessionService) Refresh(ctx context.Context, claims *auth.Claims, refreshToken string) (*AuthTokens, error) {
+	if err := s.checkSuspension(ctx, claims.UserID); err != nil {
+		return nil, err
+	}
+	version, err := s.repo.TokenVersion(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.jwt.CheckTokenVersion(claims, version); err != nil {
+		return nil, err
+	}
+	if claims.SessionID == nil {
+		return s.Start(ctx, claims.UserID, claims.Username, claims.Bot)
+	}
+
+	session, err := s.repo.Get(ctx, *claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || session.UserID != claims.UserID || !s.now().Before(session.ExpiresAt) {
+		return nil, ErrSessionRevoked
+	}
+
+	oldHash := session.RefreshToken
+	if hashRefreshToken(refreshToken) != oldHash {
+		if _, err := s.repo.Delete(ctx, session.UserID, session.ID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	now := s.now()
+	session.LastUsedAt = &now
+	tokens, err := s.issue(ctx, session, claims.Username, claims.Bot, version)
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := s.repo.Rotate(ctx, session, oldHash)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// The same token was refreshed twice at once
+		if _, err := s.repo.Delete(ctx, session.UserID, session.ID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	return tokens, nil
+}
+
+// Validate checks an access token against the sessions store, for
+// long-lived uses such as gateway connections. It returns ErrSessionRevoked
+// once the token's session is logged out or has expired,
+// auth.ErrRevokedToken once the user has logged out everywhere, and an
+// *AccountSuspendedError once the account is suspended.
+func (s *SessionService) Validate(ctx context.Context, claims *auth.Claims) error {
+	if err := s.checkSuspension(ctx, claims.UserID); err != nil {
+		return err
+	}
+	version, err := s.repo.TokenVersion(ctx, claims.UserID)
+	if err != nil {
+		return err
+	}
+	if err := s.jwt.CheckTokenVersion(claims, version); err != nil {
+		return err
+	}
+	if claims.SessionID == nil {
+		return nil
+	}
+
+	session, err := s.repo.Get(ctx, *claims.SessionID)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.UserID != claims.UserID || !s.now().Before(session.ExpiresAt) {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+// ListSessions returns the user's active sessions, most recently used
+// first. currentID marks the session the request was made with.
+func (s *SessionService) ListSessions(ctx context.Context, userID, currentID uuid.UUID) ([]*models.Session, error) {
+	sessions, err := s.repo.ListByUser(ctx, userID, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if sessions == nil {
+		sessions = []*models.Session{}
+	}
+	for _, session := range sessions {
+		session.Current = session.ID == currentID
+	}
+	return sessions, nil
+}
+
+// RevokeSession logs one of the user's sessions out. Its access tokens
+// stay valid until they expire.
+func (s *SessionService) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	deleted, err := s.repo.Delete(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeAllSessions logs the user out everywhere, OAuth applications
+// included. Moving to a new token version also refuses refresh tokens
+// issued before sessions were tracked.
+func (s *SessionService) RevokeAllSessions(ctx context.Context, userID uuid.UUID) error {
+	if _, err := s.repo.BumpTokenVersion(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteByUser(ctx, userID); err != nil {
+		return err
+	}
+	if s.oauthGrants != nil {
+		return s.oauthGrants.RevokeAllAuthorizations(ctx, userID)
+	}
+	return nil
+}
+
+// issue generates a token pair for the session and records the refresh
+// token, client and expiry on it
+func (s *SessionService) issue(ctx context.Context, session *models.Session, username string, bot bool, version int) (*AuthTokens, error) {
+	accessToken, refreshToken, err := s.jwt.GenerateSessionTokenPair(session.UserID, username, bot, auth.TokenFamily{
+		SessionID: session.ID,
+		Version:   version,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client := ClientInfoFrom(ctx)
+	session.RefreshToken = hashRefreshToken(refreshToken)
+	session.ExpiresAt = s.now().Add(s.jwt.GetRefreshExpiry())
+	session.IPAddress, session.UserAgent = nil, nil
+	if client.IPAddress != "" {
+		session.IPAddress = &client.IPAddress
+	}
+	if client.UserAgent != "" {
+		userAgent := truncateRunes(client.UserAgent, maxSessionUserAgentLength)
+		session.UserAgent = &userAgent
+	}
+
+	return &AuthTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    s.jwt.GetExpirySeconds(),
+	}, nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
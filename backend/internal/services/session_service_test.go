@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"hearth/internal/auth"
+	"hearth/internal/models"
+)
+
+type fakeSessionRepo struct {
+	sessions map[uuid.UUID]*models.Session
+	versions map[uuid.UUID]int
+}
+
+func newFakeSessionRepo() *fakeSessionRepo {
+	return &fakeSessionRepo{
+		sessions: make(map[uuid.UUID]*models.Session),
+		versions: make(map[uuid.UUID]int),
+	}
+}
+
+func (r *fakeSessionRepo) Create(ctx context.Context, session *models.Session) error {
+	copied := *session
+	r.sessions[session.ID] = &copied
+	return nil
+}
+
+func (r *fakeSessionRepo) Get(ctx context.Context, id uuid.UUID) (*models.Session, error) {
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (r *fakeSessionRepo) Rotate(ctx context.Context, session *models.Session, oldHash string) (bool, error) {
+	stored, ok := r.sessions[session.ID]
+	if !ok || stored.RefreshToken != oldHash {
+		return false, nil
+	}
+	copied := *session
+	r.sessions[session.ID] = &copied
+	return true, nil
+}
+
+func (r *fakeSessionRepo) ListByUser(ctx context.Context, userID uuid.UUID, now time.Time) ([]*models.Session, error) {
+	var result []*models.Session
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.ExpiresAt.After(now) {
+			copied := *session
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeSessionRepo) Delete(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	session, ok := r.sessions[id]
+	if !ok || session.UserID != userID {
+		return false, nil
+	}
+	delete(r.sessions, id)
+	return true, nil
+}
+
+func (r *fakeSessionRepo) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	for id, session := range r.sessions {
+		if session.UserID == userID {
+			delete(r.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (r *fakeSessionRepo) TokenVersion(ctx context.Context, userID uuid.UUID) (int, error) {
+	return r.versions[userID], nil
+}
+
+func (r *fakeSessionRepo) BumpTokenVersion(ctx context.Context, userID uuid.UUID) (int, error) {
+	r.versions[userID]++
+	return r.versions[userID], nil
+}
+
+type sessionTest struct {
+	service *SessionService
+	repo    *fakeSessionRepo
+	jwt     *auth.JWTService
+	userID  uuid.UUID
+}
+
+func newSessionTest() *sessionTest {
+	f := &sessionTest{repo: newFakeSessionRepo(), jwt: testJWTService(), userID: uuid.New()}
+	f.service = NewSessionService(f.repo, f.jwt)
+	return f
+}
+
+func (f *sessionTest) refresh(t *testing.T, refreshToken string) (*AuthTokens, error) {
+	claims, err := f.jwt.ValidateRefreshToken(refreshToken)
+	require.NoError(t, err)
+	return f.service.Refresh(context.Background(), claims, refreshToken)
+}
+
+func TestSessionService_StartAndRefresh(t *testing.T) {
+	f := newSessionTest()
+	ctx := WithClientInfo(context.Background(), ClientInfo{IPAddress: "203.0.113.7", UserAgent: "hearth-desktop/1.0"})
+
+	tokens, err := f.service.Start(ctx, f.userID, "alice", false)
+	require.NoError(t, err)
+	require.Len(t, f.repo.sessions, 1)
+	claims, err := f.jwt.ValidateAccessToken(tokens.AccessToken)
+	require.NoError(t, err)
+	require.NotNil(t, claims.SessionID)
+
+	session := f.repo.sessions[*claims.SessionID]
+	assert.Equal(t, "203.0.113.7", *session.IPAddress)
+	assert.Equal(t, "hearth-desktop/1.0", *session.UserAgent)
+	assert.NotContains(t, session.RefreshToken, tokens.RefreshToken, "only a hash is stored")
+
+	refreshed, err := f.refresh(t, tokens.RefreshToken)
+	require.NoError(t, err)
+	assert.Len(t, f.repo.sessions, 1, "refreshing stays in the same session")
+	refreshedClaims, err := f.jwt.ValidateRefreshToken(refreshed.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, *claims.SessionID, *refreshedClaims.SessionID)
+	assert.Equal(t, "alice", refreshedClaims.Username)
+
+	// Reusing the old refresh token means it leaked
+	_, err = f.refresh(t, tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+	assert.Empty(t, f.repo.sessions, "the whole session is revoked")
+	_, err = f.refresh(t, refreshed.RefreshToken)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+}
+
+func TestSessionService_Revoke(t *testing.T) {
+	f := newSessionTest()
+	ctx := context.Background()
+
+	first, err := f.service.Start(ctx, f.userID, "alice", false)
+	require.NoError(t, err)
+	second, err := f.service.Start(ctx, f.userID, "alice", false)
+	require.NoError(t, err)
+	_, err = f.service.Start(ctx, uuid.New(), "bob", false)
+	require.NoError(t, err)
+
+	firstClaims, err := f.jwt.ValidateRefreshToken(first.RefreshToken)
+	require.NoError(t, err)
+	sessions, err := f.service.ListSessions(ctx, f.userID, *firstClaims.SessionID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	for _, session := range sessions {
+		assert.Equal(t, session.ID == *firstClaims.SessionID, session.Current)
+	}
+
+	assert.ErrorIs(t, f.service.RevokeSession(ctx, f.userID, uuid.New()), ErrSessionNotFound)
+	require.NoError(t, f.service.RevokeSession(ctx, f.userID, *firstClaims.SessionID))
+	_, err = f.refresh(t, first.RefreshToken)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+
+	// Logging out everywhere also refuses tokens from before sessions
+	legacy, err := f.jwt.GenerateRefreshToken(f.userID)
+	require.NoError(t, err)
+	require.NoError(t, f.service.RevokeAllSessions(ctx, f.userID))
+	_, err = f.refresh(t, second.RefreshToken)
+	assert.ErrorIs(t, err, auth.ErrRevokedToken)
+	_, err = f.refresh(t, legacy)
+	assert.ErrorIs(t, err, auth.ErrRevokedToken)
+	assert.Len(t, f.repo.sessions, 1, "other users stay logged in")
+}
+
+type fakeOAuthGrantRevoker struct {
+	revoked []uuid.UUID
+}
+
+func (f *fakeOAuthGrantRevoker) RevokeAllAuthorizations(ctx context.Context, userID uuid.UUID) error {
+	f.revoked = append(f.revoked, userID)
+	return nil
+}
+
+func TestSessionService_RevokeAllRevokesOAuthGrants(t *testing.T) {
+	f := newSessionTest()
+	grants := &fakeOAuthGrantRevoker{}
+	f.service.SetOAuthGrants(grants)
+
+	require.NoError(t, f.service.RevokeAllSessions(context.Background(), f.userID))
+	assert.Equal(t, []uuid.UUID{f.userID}, grants.revoked)
+}
+
+func TestSessionService_LegacyRefreshToken(t *testing.T) {
+	f := newSessionTest()
+
+	legacy, err := f.jwt.GenerateRefreshToken(f.userID)
+	require.NoError(t, err)
+	tokens, err := f.refresh(t, legacy)
+	require.NoError(t, err)
+	assert.Len(t, f.repo.sessions, 1, "tokens from before sessions move into one")
+
+	claims, err := f.jwt.ValidateRefreshToken(tokens.RefreshToken)
+	require.NoError(t, err)
+	assert.NotNil(t, claims.SessionID)
+}
+
+func TestSessionService_Validate(t *testing.T) {
+	f := newSessionTest()
+	ctx := context.Background()
+
+	tokens, err := f.service.Start(ctx, f.userID, "alice", false)
+	require.NoError(t, err)
+	claims, err := f.jwt.ValidateAccessToken(tokens.AccessToken)
+	require.NoError(t, err)
+	assert.NoError(t, f.service.Validate(ctx, claims))
+
+	legacy, err := f.jwt.GenerateAccessToken(f.userID, "alice")
+	require.NoError(t, err)
+	legacyClaims, err := f.jwt.ValidateAccessToken(legacy)
+	require.NoError(t, err)
+	assert.NoError(t, f.service.Validate(ctx, legacyClaims), "tokens from before sessions have none to check")
+
+	require.NoError(t, f.service.RevokeSession(ctx, f.userID, *claims.SessionID))
+	assert.ErrorIs(t, f.service.Validate(ctx, claims), ErrSessionRevoked)
+
+	require.NoError(t, f.service.RevokeAllSessions(ctx, f.userID))
+	assert.ErrorIs(t, f.service.Validate(ctx, legacyClaims), auth.ErrRevokedToken)
+}
+
+func TestAuthService_Login_OpensSession(t *testing.T) {
+	mockRepo := new(MockAuthRepository)
+	f := newSessionTest()
+	service := NewAuthServiceWithSessions(mockRepo, f.jwt, nil, f.service)
+	ctx := context.Background()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("Password123"), bcrypt.MinCost)
+	user := &models.User{ID: f.userID, Email: "test@example.com", Username: "alice", PasswordHash: string(hashedPassword)}
+	mockRepo.On("GetByEmail", mock.Anything, "test@example.com").Return(user, nil)
+
+	_, tokens, err := service.Login(ctx, "test@example.com", "Password123")
+	require.NoError(t, err)
+	assert.Len(t, f.repo.sessions, 1)
+
+	refreshed, err := service.RefreshTokens(ctx, tokens.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, tokens.RefreshToken, refreshed.RefreshToken)
+}
@@ -136,6 +136,9 @@ func (s *SettingsService) UpdateSettings(ctx context.Context, userID uuid.UUID,
 	if updates.PrivacyReadReceipts != nil {
 		settings.PrivacyReadReceipts = *updates.PrivacyReadReceipts
 	}
+	if updates.PrivacyPresenceFriendsOnly != nil {
+		settings.PrivacyPresenceFriendsOnly = *updates.PrivacyPresenceFriendsOnly
+	}
 
 	// Apply locale update
 	if updates.Locale != nil {
@@ -77,6 +77,10 @@ func (s *ThreadService) CreateThread(
 	if channel == nil {
 		return nil, ErrChannelNotFound
 	}
+	// Forum posts come with a first message and tags; see ForumService
+	if channel.Type == models.ChannelTypeForum {
+		return nil, ErrForumChannel
+	}
 
 	// For server channels, verify membership
 	if channel.ServerID != nil {
@@ -226,8 +230,9 @@ func (s *ThreadService) SendThreadMessage(
 	}
 
 	s.eventBus.Publish("thread.message_created", &ThreadMessageCreatedEvent{
-		Message:  msg,
-		ThreadID: threadID,
+		Message:   msg,
+		ThreadID:  threadID,
+		ChannelID: thread.ParentChannelID,
 	})
 
 	return msg, nil
@@ -265,8 +270,11 @@ func (s *ThreadService) ArchiveThread(ctx context.Context, threadID uuid.UUID, r
 		return err
 	}
 
-	s.eventBus.Publish("thread.archived", &ThreadArchivedEvent{
-		ThreadID:  threadID,
+	now := time.Now()
+	thread.Archived = true
+	thread.ArchiveTimestamp = &now
+	s.eventBus.Publish("thread.updated", &ThreadUpdatedEvent{
+		Thread:    thread,
 		ChannelID: thread.ParentChannelID,
 	})
 
@@ -303,8 +311,10 @@ func (s *ThreadService) UnarchiveThread(ctx context.Context, threadID uuid.UUID,
 		return err
 	}
 
-	s.eventBus.Publish("thread.unarchived", &ThreadUnarchivedEvent{
-		ThreadID:  threadID,
+	thread.Archived = false
+	thread.ArchiveTimestamp = nil
+	s.eventBus.Publish("thread.updated", &ThreadUpdatedEvent{
+		Thread:    thread,
 		ChannelID: thread.ParentChannelID,
 	})
 
@@ -413,13 +423,9 @@ type ThreadCreatedEvent struct {
 	ChannelID uuid.UUID
 }
 
-type ThreadArchivedEvent struct {
-	ThreadID  uuid.UUID
-	ChannelID uuid.UUID
-}
-
-type ThreadUnarchivedEvent struct {
-	ThreadID  uuid.UUID
+// ThreadUpdatedEvent carries a thread after it was archived or unarchived
+type ThreadUpdatedEvent struct {
+	Thread    *models.Thread
 	ChannelID uuid.UUID
 }
 
@@ -429,6 +435,7 @@ type ThreadDeletedEvent struct {
 }
 
 type ThreadMessageCreatedEvent struct {
-	Message  *models.ThreadMessage
-	ThreadID uuid.UUID
+	Message   *models.ThreadMessage
+	ThreadID  uuid.UUID
+	ChannelID uuid.UUID // The thread's parent channel
 }
@@ -280,10 +280,12 @@ func (m *mockServerRepoForThread) IncrementInviteUses(ctx context.Context, code
 // mockEventBusForThread mocks EventBus for thread service tests
 type mockEventBusForThread struct {
 	events []string
+	data   []interface{}
 }
 
 func (m *mockEventBusForThread) Publish(event string, data interface{}) {
 	m.events = append(m.events, event)
+	m.data = append(m.data, data)
 }
 
 func (m *mockEventBusForThread) Subscribe(event string, handler func(data interface{})) {}
@@ -396,6 +398,19 @@ func TestThreadService_CreateThread(t *testing.T) {
 			},
 			wantErr: ErrInvalidAutoArchive,
 		},
+		{
+			name:        "forum channel",
+			channelID:   channelID,
+			creatorID:   userID,
+			threadName:  "Test Thread",
+			autoArchive: nil,
+			setupMocks: func(tr *mockThreadRepository, cr *mockChannelRepoForThread, sr *mockServerRepoForThread) {
+				cr.getByIDFunc = func(ctx context.Context, id uuid.UUID) (*models.Channel, error) {
+					return &models.Channel{ID: id, ServerID: &serverID, Type: models.ChannelTypeForum}, nil
+				}
+			},
+			wantErr: ErrForumChannel,
+		},
 	}
 
 	for _, tt := range tests {
@@ -746,6 +761,38 @@ func TestThreadService_ArchiveThread(t *testing.T) {
 	}
 }
 
+func TestThreadService_ArchivePublishesUpdate(t *testing.T) {
+	ctx := context.Background()
+	ownerID := uuid.New()
+	channelID := uuid.New()
+	threadRepo := &mockThreadRepository{
+		getByIDFunc: func(ctx context.Context, id uuid.UUID) (*models.Thread, error) {
+			return &models.Thread{ID: id, OwnerID: ownerID, ParentChannelID: channelID}, nil
+		},
+	}
+	eventBus := &mockEventBusForThread{}
+	svc := NewThreadService(threadRepo, &mockChannelRepoForThread{}, &mockServerRepoForThread{}, eventBus)
+	threadID := uuid.New()
+
+	if err := svc.ArchiveThread(ctx, threadID, ownerID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.UnarchiveThread(ctx, threadID, ownerID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(eventBus.events) != 2 || eventBus.events[0] != "thread.updated" || eventBus.events[1] != "thread.updated" {
+		t.Fatalf("expected two thread.updated events, got %v", eventBus.events)
+	}
+	archived := eventBus.data[0].(*ThreadUpdatedEvent)
+	if !archived.Thread.Archived || archived.Thread.ArchiveTimestamp == nil || archived.ChannelID != channelID {
+		t.Errorf("expected the archived thread in its parent channel, got %+v", archived)
+	}
+	if unarchived := eventBus.data[1].(*ThreadUpdatedEvent); unarchived.Thread.Archived {
+		t.Errorf("expected the unarchived thread, got %+v", unarchived.Thread)
+	}
+}
+
 func TestThreadService_GetChannelThreads(t *testing.T) {
 	ctx := context.Background()
 	channelID := uuid.New()
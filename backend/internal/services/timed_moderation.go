@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// MaxTimeoutDuration is the longest a member can be timed out for
+const MaxTimeoutDuration = 28 * 24 * time.Hour
+
+// ModerationExpiryRepository stores timeouts and lifts timed bans and
+// timeouts once they run out. The Postgres ServerRepository implements it.
+type ModerationExpiryRepository interface {
+	SetMemberTimeout(ctx context.Context, serverID, userID uuid.UUID, until *time.Time) error
+	RemoveExpiredBans(ctx context.Context, before time.Time) ([]*models.Ban, error)
+	ClearExpiredTimeouts(ctx context.Context, before time.Time) ([]*models.Member, error)
+}
+
+// SetModerationExpiryRepository enables member timeouts and the expiry of
+// timed bans
+func (s *ServerService) SetModerationExpiryRepository(repo ModerationExpiryRepository) {
+	s.expiry = repo
+}
+
+// MemberUpdatedEvent is published when a member's server profile changes
+type MemberUpdatedEvent struct {
+	ServerID uuid.UUID
+	UserID   uuid.UUID
+	Member   *models.Member
+}
+
+// TimeoutMember stops a member from sending messages or reacting for
+// duration, replacing any timeout they already have. The requester needs
+// TIMEOUT_MEMBERS and a higher role than the target, and the owner can't
+// be timed out.
+func (s *ServerService) TimeoutMember(ctx context.Context, serverID, requesterID, targetID uuid.UUID, duration time.Duration) (*models.Member, error) {
+	if duration <= 0 || duration > MaxTimeoutDuration {
+		return nil, ErrTimeoutDuration
+	}
+	until := time.Now().Add(duration)
+	return s.setTimeout(ctx, serverID, requesterID, targetID, &until)
+}
+
+// RemoveTimeout lifts a member's timeout early
+func (s *ServerService) RemoveTimeout(ctx context.Context, serverID, requesterID, targetID uuid.UUID) (*models.Member, error) {
+	return s.setTimeout(ctx, serverID, requesterID, targetID, nil)
+}
+
+func (s *ServerService) setTimeout(ctx context.Context, serverID, requesterID, targetID uuid.UUID, until *time.Time) (*models.Member, error) {
+	server, err := s.repo.GetByID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if server == nil {
+		return nil, ErrServerNotFound
+	}
+	if server.OwnerID == targetID || !s.hasServerPermission(ctx, server, requesterID, models.PermTimeoutMembers) {
+		return nil, ErrCannotTimeout
+	}
+	if err := checkOutranks(ctx, s.repo, s.roleRepo, server, requesterID, targetID); err != nil {
+		return nil, err
+	}
+	if s.expiry == nil {
+		return nil, errors.New("member timeouts are not configured")
+	}
+
+	member, err := s.repo.GetMember(ctx, serverID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrNotServerMember
+	}
+
+	if err := s.expiry.SetMemberTimeout(ctx, serverID, targetID, until); err != nil {
+		return nil, err
+	}
+	member.CommunicationDisabledUntil = until
+	forgetMember(ctx, s.cache, serverID, targetID)
+
+	s.eventBus.Publish("server.member_updated", &MemberUpdatedEvent{
+		ServerID: serverID,
+		UserID:   targetID,
+		Member:   member,
+	})
+
+	return member, nil
+}
+
+// hasServerPermission reports whether a member has perm. Without a role
+// repository only the owner does.
+func (s *ServerService) hasServerPermission(ctx context.Context, server *models.Server, userID uuid.UUID, perm int64) bool {
+	return memberHasPermission(ctx, s.repo, s.roleRepo, server, userID, perm)
+}
+
+func memberHasPermission(ctx context.Context, servers ServerRepository, roleRepo RoleRepository, server *models.Server, userID uuid.UUID, perm int64) bool {
+	if server.OwnerID == userID {
+		return true
+	}
+	if roleRepo == nil {
+		return false
+	}
+
+	member, err := servers.GetMember(ctx, server.ID, userID)
+	if err != nil || member == nil {
+		return false
+	}
+	memberRoles, err := roleRepo.GetMemberRoles(ctx, server.ID, userID)
+	if err != nil {
+		return false
+	}
+	member.Roles = roleIDs(memberRoles)
+	roles, err := roleRepo.GetByServerID(ctx, server.ID)
+	if err != nil {
+		return false
+	}
+	perms := models.CalculatePermissions(member, roles, server, nil, nil)
+	return models.HasPermission(perms, perm)
+}
+
+// ExpireModeration lifts every timed ban and timeout that has run out and
+// returns how many of each were lifted
+func (s *ServerService) ExpireModeration(ctx context.Context, now time.Time) (bans, timeouts int, err error) {
+	if s.expiry == nil {
+		return 0, 0, nil
+	}
+
+	expiredBans, err := s.expiry.RemoveExpiredBans(ctx, now)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, ban := range expiredBans {
+		s.eventBus.Publish("server.member_unbanned", &MemberUnbannedEvent{
+			ServerID: ban.ServerID,
+			UserID:   ban.UserID,
+		})
+	}
+
+	expiredTimeouts, err := s.expiry.ClearExpiredTimeouts(ctx, now)
+	if err != nil {
+		return len(expiredBans), 0, err
+	}
+	for _, member := range expiredTimeouts {
+		s.eventBus.Publish("server.member_updated", &MemberUpdatedEvent{
+			ServerID: member.ServerID,
+			UserID:   member.UserID,
+			Member:   member,
+		})
+	}
+
+	return len(expiredBans), len(expiredTimeouts), nil
+}
+
+// RunModerationExpiry lifts expired bans and timeouts every interval until
+// ctx is done
+func (s *ServerService) RunModerationExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, _, err := s.ExpireModeration(ctx, now); err != nil {
+				log.Printf("[ServerService] failed to expire bans and timeouts: %v", err)
+			}
+		}
+	}
+}
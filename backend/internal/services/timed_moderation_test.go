@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeModerationExpiryRepository struct {
+	timeouts map[uuid.UUID]*time.Time
+	bans     []*models.Ban
+	members  []*models.Member
+	before   time.Time
+}
+
+func (f *fakeModerationExpiryRepository) SetMemberTimeout(ctx context.Context, serverID, userID uuid.UUID, until *time.Time) error {
+	if f.timeouts == nil {
+		f.timeouts = make(map[uuid.UUID]*time.Time)
+	}
+	f.timeouts[userID] = until
+	return nil
+}
+
+func (f *fakeModerationExpiryRepository) RemoveExpiredBans(ctx context.Context, before time.Time) ([]*models.Ban, error) {
+	f.before = before
+	return f.bans, nil
+}
+
+func (f *fakeModerationExpiryRepository) ClearExpiredTimeouts(ctx context.Context, before time.Time) ([]*models.Member, error) {
+	return f.members, nil
+}
+
+func TestTimeoutMember_Success(t *testing.T) {
+	service, serverRepo, _, _, _, eventBus := newTestServerService()
+	repo := &fakeModerationExpiryRepository{}
+	service.SetModerationExpiryRepository(repo)
+	ctx := context.Background()
+	serverID := uuid.New()
+	ownerID := uuid.New()
+	targetID := uuid.New()
+
+	serverRepo.On("GetByID", ctx, serverID).Return(&models.Server{ID: serverID, OwnerID: ownerID}, nil)
+	serverRepo.On("GetMember", ctx, serverID, targetID).Return(&models.Member{ServerID: serverID, UserID: targetID}, nil)
+	eventBus.On("Publish", "server.member_updated", mock.MatchedBy(func(e *MemberUpdatedEvent) bool {
+		return e.UserID == targetID && e.Member.CommunicationDisabledUntil != nil
+	})).Return()
+
+	member, err := service.TimeoutMember(ctx, serverID, ownerID, targetID, time.Hour)
+	require.NoError(t, err)
+
+	require.NotNil(t, member.CommunicationDisabledUntil)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *member.CommunicationDisabledUntil, time.Minute)
+	assert.Equal(t, member.CommunicationDisabledUntil, repo.timeouts[targetID])
+	assert.True(t, member.TimedOut(time.Now()))
+	eventBus.AssertExpectations(t)
+}
+
+func TestTimeoutMember_Duration(t *testing.T) {
+	service, _, _, _, _, _ := newTestServerService()
+	service.SetModerationExpiryRepository(&fakeModerationExpiryRepository{})
+	ctx := context.Background()
+
+	for _, d := range []time.Duration{0, -time.Minute, MaxTimeoutDuration + time.Second} {
+		_, err := service.TimeoutMember(ctx, uuid.New(), uuid.New(), uuid.New(), d)
+		assert.Equal(t, ErrTimeoutDuration, err, "duration %s", d)
+	}
+}
+
+func TestTimeoutMember_RequiresPermission(t *testing.T) {
+	service, serverRepo, _, roleRepo, _, _ := newTestServerService()
+	repo := &fakeModerationExpiryRepository{}
+	service.SetModerationExpiryRepository(repo)
+	ctx := context.Background()
+	serverID := uuid.New()
+	ownerID := uuid.New()
+	requesterID := uuid.New()
+	targetID := uuid.New()
+
+	serverRepo.On("GetByID", ctx, serverID).Return(&models.Server{ID: serverID, OwnerID: ownerID}, nil)
+	serverRepo.On("GetMember", ctx, serverID, requesterID).Return(&models.Member{ServerID: serverID, UserID: requesterID}, nil)
+	roleRepo.On("GetMemberRoles", ctx, serverID, requesterID).Return([]*models.Role{}, nil)
+	roleRepo.On("GetByServerID", ctx, serverID).Return([]*models.Role{
+		{ID: serverID, ServerID: serverID, IsDefault: true, Permissions: models.DefaultPermissions},
+	}, nil)
+
+	_, err := service.TimeoutMember(ctx, serverID, requesterID, targetID, time.Hour)
+	assert.Equal(t, ErrCannotTimeout, err)
+	assert.Empty(t, repo.timeouts)
+
+	// Nobody can time out the owner
+	_, err = service.TimeoutMember(ctx, serverID, ownerID, ownerID, time.Hour)
+	assert.Equal(t, ErrCannotTimeout, err)
+}
+
+func TestRemoveTimeout(t *testing.T) {
+	service, serverRepo, _, _, _, eventBus := newTestServerService()
+	repo := &fakeModerationExpiryRepository{}
+	service.SetModerationExpiryRepository(repo)
+	ctx := context.Background()
+	serverID := uuid.New()
+	ownerID := uuid.New()
+	targetID := uuid.New()
+	until := time.Now().Add(time.Hour)
+
+	serverRepo.On("GetByID", ctx, serverID).Return(&models.Server{ID: serverID, OwnerID: ownerID}, nil)
+	serverRepo.On("GetMember", ctx, serverID, targetID).Return(&models.Member{
+		ServerID: serverID, UserID: targetID, CommunicationDisabledUntil: &until,
+	}, nil)
+	eventBus.On("Publish", "server.member_updated", mock.Anything).Return()
+
+	member, err := service.RemoveTimeout(ctx, serverID, ownerID, targetID)
+	require.NoError(t, err)
+	assert.Nil(t, member.CommunicationDisabledUntil)
+	assert.Contains(t, repo.timeouts, targetID)
+	assert.Nil(t, repo.timeouts[targetID])
+}
+
+func TestBanMember_Timed(t *testing.T) {
+	service, serverRepo, _, _, _, eventBus := newTestServerService()
+	ctx := context.Background()
+	serverID := uuid.New()
+	ownerID := uuid.New()
+	targetID := uuid.New()
+
+	serverRepo.On("GetByID", ctx, serverID).Return(&models.Server{ID: serverID, OwnerID: ownerID}, nil)
+	serverRepo.On("RemoveMember", ctx, serverID, targetID).Return(nil)
+	serverRepo.On("AddBan", ctx, mock.MatchedBy(func(b *models.Ban) bool {
+		return b.ExpiresAt != nil && b.ExpiresAt.Sub(b.CreatedAt) == 24*time.Hour
+	})).Return(nil)
+	eventBus.On("Publish", "server.member_banned", mock.Anything).Return()
+
+	require.NoError(t, service.BanMember(ctx, serverID, ownerID, targetID, "cool off", 0, 24*time.Hour))
+	serverRepo.AssertExpectations(t)
+
+	assert.Equal(t, ErrBanDuration, service.BanMember(ctx, serverID, ownerID, targetID, "", 0, -time.Hour))
+}
+
+func TestExpireModeration(t *testing.T) {
+	service, _, _, _, _, eventBus := newTestServerService()
+	serverID := uuid.New()
+	unbanned := uuid.New()
+	released := uuid.New()
+	repo := &fakeModerationExpiryRepository{
+		bans:    []*models.Ban{{ServerID: serverID, UserID: unbanned}},
+		members: []*models.Member{{ServerID: serverID, UserID: released}},
+	}
+	service.SetModerationExpiryRepository(repo)
+	now := time.Now()
+
+	eventBus.On("Publish", "server.member_unbanned", mock.MatchedBy(func(e *MemberUnbannedEvent) bool {
+		return e.ServerID == serverID && e.UserID == unbanned && e.ModeratorID == uuid.Nil
+	})).Return().Once()
+	eventBus.On("Publish", "server.member_updated", mock.MatchedBy(func(e *MemberUpdatedEvent) bool {
+		return e.UserID == released && e.Member.CommunicationDisabledUntil == nil
+	})).Return().Once()
+
+	bans, timeouts, err := service.ExpireModeration(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, bans)
+	assert.Equal(t, 1, timeouts)
+	assert.Equal(t, now, repo.before)
+	eventBus.AssertExpectations(t)
+}
+
+func TestExpireModeration_NotConfigured(t *testing.T) {
+	service, _, _, _, _, _ := newTestServerService()
+
+	bans, timeouts, err := service.ExpireModeration(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, bans)
+	assert.Zero(t, timeouts)
+}
+
+func TestSendMessage_MemberTimedOut(t *testing.T) {
+	service, _, channelRepo, serverRepo, _, _, _, _, _ := setupMessageService()
+	ctx := context.Background()
+	authorID := uuid.New()
+	channelID := uuid.New()
+	serverID := uuid.New()
+	until := time.Now().Add(time.Hour)
+
+	channelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, ServerID: &serverID, Type: models.ChannelTypeText}, nil)
+	serverRepo.On("GetMember", ctx, serverID, authorID).Return(&models.Member{ServerID: serverID, UserID: authorID, CommunicationDisabledUntil: &until}, nil)
+
+	message, err := service.SendMessage(ctx, authorID, channelID, "Hello!", nil, nil)
+	assert.Equal(t, ErrMemberTimedOut, err)
+	assert.Nil(t, message)
+}
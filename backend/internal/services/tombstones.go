@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// TombstoneRepository keeps a record of deleted messages
+type TombstoneRepository interface {
+	// DeleteWithTombstone deletes message and records who deleted it,
+	// atomically
+	DeleteWithTombstone(ctx context.Context, message *models.Message, deletedBy uuid.UUID) error
+	GetChannelTombstones(ctx context.Context, channelID uuid.UUID, limit int) ([]*models.MessageTombstone, error)
+	PurgeTombstones(ctx context.Context, cutoff time.Time) (int, error)
+}
+
+// SetTombstoneRepository makes deleting a message leave a tombstone for
+// moderators instead of removing every trace of it
+func (s *MessageService) SetTombstoneRepository(tombstones TombstoneRepository) {
+	s.tombstones = tombstones
+}
+
+func (s *MessageService) deleteMessage(ctx context.Context, message *models.Message, deletedBy uuid.UUID) error {
+	if s.tombstones == nil {
+		return s.repo.Delete(ctx, message.ID)
+	}
+	return s.tombstones.DeleteWithTombstone(ctx, message, deletedBy)
+}
+
+// GetDeletedMessages lists tombstones for a server channel's recently
+// deleted messages. Only moderators with MANAGE_MESSAGES may see them.
+func (s *MessageService) GetDeletedMessages(ctx context.Context, channelID, requesterID uuid.UUID, limit int) ([]*models.MessageTombstone, error) {
+	channel, err := cachedChannel(ctx, s.cache, s.channelRepo, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if channel == nil {
+		return nil, ErrChannelNotFound
+	}
+	if channel.ServerID == nil {
+		return nil, ErrCannotModerate
+	}
+	if !s.canManageMessages(ctx, *channel.ServerID, requesterID) {
+		return nil, ErrCannotModerate
+	}
+
+	if s.tombstones == nil {
+		return []*models.MessageTombstone{}, nil
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	return s.tombstones.GetChannelTombstones(ctx, channelID, limit)
+}
+
+// canManageMessages reports whether a member has MANAGE_MESSAGES. Without a
+// role repository only the owner does.
+func (s *MessageService) canManageMessages(ctx context.Context, serverID, userID uuid.UUID) bool {
+	server, err := s.serverRepo.GetByID(ctx, serverID)
+	if err != nil || server == nil {
+		return false
+	}
+	if server.OwnerID == userID {
+		return true
+	}
+	if s.roleRepo == nil {
+		return false
+	}
+
+	member, err := cachedMember(ctx, s.cache, s.serverRepo, serverID, userID)
+	if err != nil || member == nil {
+		return false
+	}
+	roles, err := s.roleRepo.GetByServerID(ctx, serverID)
+	if err != nil {
+		return false
+	}
+	perms := models.CalculatePermissions(member, roles, server, nil, nil)
+	return models.HasPermission(perms, models.PermManageMessages)
+}
+
+// PurgeTombstones removes tombstones older than retention
+func (s *MessageService) PurgeTombstones(ctx context.Context, now time.Time, retention time.Duration) (int, error) {
+	if s.tombstones == nil {
+		return 0, nil
+	}
+	return s.tombstones.PurgeTombstones(ctx, now.Add(-retention))
+}
+
+// RunTombstonePurge purges expired tombstones every interval until ctx is
+// cancelled
+func (s *MessageService) RunTombstonePurge(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			purged, err := s.PurgeTombstones(ctx, now, retention)
+			if err != nil {
+				log.Printf("[MessageService] failed to purge message tombstones: %v", err)
+			} else if purged > 0 {
+				log.Printf("[MessageService] purged %d message tombstones", purged)
+			}
+		}
+	}
+}
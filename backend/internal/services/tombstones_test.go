@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeTombstoneRepository struct {
+	deleted    map[uuid.UUID]uuid.UUID // messageID -> deletedBy
+	tombstones []*models.MessageTombstone
+	cutoff     time.Time
+}
+
+func (f *fakeTombstoneRepository) DeleteWithTombstone(ctx context.Context, message *models.Message, deletedBy uuid.UUID) error {
+	if f.deleted == nil {
+		f.deleted = make(map[uuid.UUID]uuid.UUID)
+	}
+	f.deleted[message.ID] = deletedBy
+	return nil
+}
+
+func (f *fakeTombstoneRepository) GetChannelTombstones(ctx context.Context, channelID uuid.UUID, limit int) ([]*models.MessageTombstone, error) {
+	return f.tombstones, nil
+}
+
+func (f *fakeTombstoneRepository) PurgeTombstones(ctx context.Context, cutoff time.Time) (int, error) {
+	f.cutoff = cutoff
+	return len(f.tombstones), nil
+}
+
+func TestDeleteMessage_WritesTombstone(t *testing.T) {
+	service, msgRepo, channelRepo, _, _, _, _, _, eventBus := setupMessageService()
+	tombstones := &fakeTombstoneRepository{}
+	service.SetTombstoneRepository(tombstones)
+	ctx := context.Background()
+	authorID := uuid.New()
+	messageID := uuid.New()
+	channelID := uuid.New()
+
+	msgRepo.On("GetByID", ctx, messageID).Return(&models.Message{ID: messageID, ChannelID: channelID, AuthorID: authorID}, nil)
+	channelRepo.On("GetByID", ctx, channelID).Return(nil, nil)
+	eventBus.On("Publish", "message.deleted", &MessageDeletedEvent{
+		MessageID: messageID,
+		ChannelID: channelID,
+		AuthorID:  authorID,
+		DeletedBy: authorID,
+	}).Return()
+
+	err := service.DeleteMessage(ctx, messageID, authorID)
+
+	require.NoError(t, err)
+	assert.Equal(t, authorID, tombstones.deleted[messageID])
+	msgRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	eventBus.AssertExpectations(t)
+}
+
+func TestGetDeletedMessages_RequiresManageMessages(t *testing.T) {
+	service, _, channelRepo, serverRepo, _, _, _, _, _ := setupMessageService()
+	tombstones := &fakeTombstoneRepository{
+		tombstones: []*models.MessageTombstone{{MessageID: uuid.New()}},
+	}
+	service.SetTombstoneRepository(tombstones)
+	ctx := context.Background()
+	ownerID := uuid.New()
+	memberID := uuid.New()
+	serverID := uuid.New()
+	channelID := uuid.New()
+
+	channelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, ServerID: &serverID}, nil)
+	serverRepo.On("GetByID", ctx, serverID).Return(&models.Server{ID: serverID, OwnerID: ownerID}, nil)
+
+	// Without a role repository only the owner can moderate
+	_, err := service.GetDeletedMessages(ctx, channelID, memberID, 50)
+	assert.Equal(t, ErrCannotModerate, err)
+
+	result, err := service.GetDeletedMessages(ctx, channelID, ownerID, 50)
+	require.NoError(t, err)
+	assert.Equal(t, tombstones.tombstones, result)
+}
+
+func TestGetDeletedMessages_DMChannel(t *testing.T) {
+	service, _, channelRepo, _, _, _, _, _, _ := setupMessageService()
+	service.SetTombstoneRepository(&fakeTombstoneRepository{})
+	ctx := context.Background()
+	channelID := uuid.New()
+
+	channelRepo.On("GetByID", ctx, channelID).Return(&models.Channel{ID: channelID, Type: models.ChannelTypeDM}, nil)
+
+	_, err := service.GetDeletedMessages(ctx, channelID, uuid.New(), 50)
+	assert.Equal(t, ErrCannotModerate, err)
+}
+
+func TestPurgeTombstones_UsesRetention(t *testing.T) {
+	service, _, _, _, _, _, _, _, _ := setupMessageService()
+	tombstones := &fakeTombstoneRepository{tombstones: []*models.MessageTombstone{{}, {}}}
+	service.SetTombstoneRepository(tombstones)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	purged, err := service.PurgeTombstones(context.Background(), now, 72*time.Hour)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, purged)
+	assert.Equal(t, now.Add(-72*time.Hour), tombstones.cutoff)
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"log"
 	"sync"
 	"time"
 
@@ -12,63 +13,99 @@ import (
 const (
 	// TypingTTL is how long a typing indicator lasts
 	TypingTTL = 10 * time.Second
+
+	// typingSweepInterval is how often lapsed indicators are looked for, so
+	// TYPING_STOP goes out at most this long after an indicator expires
+	typingSweepInterval = time.Second
 )
 
-// TypingService manages typing indicators
+// TypingStore keeps typing indicators until they expire. The default store
+// only covers this instance; RedisCache implements it for the deployment.
+type TypingStore interface {
+	// StartTyping records that a user is typing in a channel until expires
+	StartTyping(ctx context.Context, channelID, userID uuid.UUID, expires time.Time) error
+	// StopTyping removes an indicator and reports whether there was one
+	StopTyping(ctx context.Context, channelID, userID uuid.UUID) (bool, error)
+	// TypingUsers returns when each user typing in a channel expires,
+	// leaving out those that expired by now
+	TypingUsers(ctx context.Context, channelID uuid.UUID, now time.Time) (map[uuid.UUID]time.Time, error)
+	// ExpireTyping removes the indicators that expired by now and returns
+	// them, stamped with when they expired. Each is returned once, however
+	// many instances sweep.
+	ExpireTyping(ctx context.Context, now time.Time) ([]models.TypingIndicator, error)
+	// ClearTyping removes all of a channel's indicators
+	ClearTyping(ctx context.Context, channelID uuid.UUID) error
+}
+
+// TypingService manages typing indicators. Starting and stopping publish
+// typing.started and typing.stopped, which the gateway sends to the
+// channel on every node; indicators that lapse are stopped automatically.
 type TypingService struct {
 	mu       sync.RWMutex
-	typing   map[uuid.UUID]map[uuid.UUID]time.Time // channelID -> userID -> timestamp
+	store    TypingStore
 	eventBus EventBus
+	now      func() time.Time
 }
 
-// NewTypingService creates a new typing service
+// NewTypingService creates a new typing service that keeps indicators on
+// this instance only
 func NewTypingService(eventBus EventBus) *TypingService {
 	svc := &TypingService{
-		typing:   make(map[uuid.UUID]map[uuid.UUID]time.Time),
+		store:    newMemoryTypingStore(),
 		eventBus: eventBus,
+		now:      time.Now,
 	}
 
-	// Start background cleanup goroutine
-	go svc.cleanupLoop()
+	// Start background sweep for lapsed indicators
+	go svc.sweepLoop()
 
 	return svc
 }
 
-// StartTyping records that a user started typing in a channel
-func (s *TypingService) StartTyping(ctx context.Context, channelID, userID uuid.UUID) error {
+// SetStore shares indicators between instances, so every instance sees who
+// is typing and each lapsed indicator is stopped once
+func (s *TypingService) SetStore(store TypingStore) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.store = store
+}
 
-	if s.typing[channelID] == nil {
-		s.typing[channelID] = make(map[uuid.UUID]time.Time)
-	}
+func (s *TypingService) typingStore() TypingStore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.store
+}
 
-	now := time.Now()
-	s.typing[channelID][userID] = now
+// StartTyping records that a user started typing in a channel
+func (s *TypingService) StartTyping(ctx context.Context, channelID, userID uuid.UUID) error {
+	now := s.now()
+	if err := s.typingStore().StartTyping(ctx, channelID, userID, now.Add(TypingTTL)); err != nil {
+		return err
+	}
 
 	// Publish typing event for WebSocket broadcast
-	if s.eventBus != nil {
-		indicator := &models.TypingIndicator{
-			ChannelID: channelID,
-			UserID:    userID,
-			Timestamp: now,
-		}
-		s.eventBus.Publish("typing.start", indicator)
-	}
+	s.publish("typing.started", &models.TypingIndicator{
+		ChannelID: channelID,
+		UserID:    userID,
+		Timestamp: now,
+	})
 
 	return nil
 }
 
 // StopTyping removes a user's typing indicator (e.g., when they send a message)
 func (s *TypingService) StopTyping(ctx context.Context, channelID, userID uuid.UUID) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	stopped, err := s.typingStore().StopTyping(ctx, channelID, userID)
+	if err != nil {
+		return err
+	}
 
-	if s.typing[channelID] != nil {
-		delete(s.typing[channelID], userID)
-		if len(s.typing[channelID]) == 0 {
-			delete(s.typing, channelID)
-		}
+	if stopped {
+		s.publish("typing.stopped", &models.TypingIndicator{
+			ChannelID: channelID,
+			UserID:    userID,
+			Timestamp: s.now(),
+		})
 	}
 
 	return nil
@@ -76,22 +113,18 @@ func (s *TypingService) StopTyping(ctx context.Context, channelID, userID uuid.U
 
 // GetTypingUsers returns a list of users currently typing in a channel
 func (s *TypingService) GetTypingUsers(ctx context.Context, channelID uuid.UUID) ([]models.TypingIndicator, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	typing, err := s.typingStore().TypingUsers(ctx, channelID, s.now())
+	if err != nil {
+		return nil, err
+	}
 
 	var indicators []models.TypingIndicator
-	now := time.Now()
-
-	if channelUsers, ok := s.typing[channelID]; ok {
-		for userID, ts := range channelUsers {
-			if now.Sub(ts) < TypingTTL {
-				indicators = append(indicators, models.TypingIndicator{
-					ChannelID: channelID,
-					UserID:    userID,
-					Timestamp: ts,
-				})
-			}
-		}
+	for userID, expires := range typing {
+		indicators = append(indicators, models.TypingIndicator{
+			ChannelID: channelID,
+			UserID:    userID,
+			Timestamp: expires.Add(-TypingTTL),
+		})
 	}
 
 	return indicators, nil
@@ -99,44 +132,40 @@ func (s *TypingService) GetTypingUsers(ctx context.Context, channelID uuid.UUID)
 
 // IsTyping checks if a specific user is currently typing in a channel
 func (s *TypingService) IsTyping(ctx context.Context, channelID, userID uuid.UUID) (bool, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	if channelUsers, ok := s.typing[channelID]; ok {
-		if ts, exists := channelUsers[userID]; exists {
-			return time.Since(ts) < TypingTTL, nil
-		}
+	typing, err := s.typingStore().TypingUsers(ctx, channelID, s.now())
+	if err != nil {
+		return false, err
 	}
-
-	return false, nil
+	_, ok := typing[userID]
+	return ok, nil
 }
 
-// cleanupLoop periodically cleans up expired typing indicators
-func (s *TypingService) cleanupLoop() {
-	ticker := time.NewTicker(5 * time.Second)
+// sweepLoop periodically stops lapsed typing indicators
+func (s *TypingService) sweepLoop() {
+	ticker := time.NewTicker(typingSweepInterval)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		s.cleanup()
+		s.sweep(context.Background())
 	}
 }
 
-// cleanup removes expired typing indicators
-func (s *TypingService) cleanup() {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// sweep removes lapsed typing indicators and publishes typing.stopped for
+// each
+func (s *TypingService) sweep(ctx context.Context) {
+	expired, err := s.typingStore().ExpireTyping(ctx, s.now())
+	if err != nil {
+		log.Printf("[Typing] Failed to expire typing indicators: %v", err)
+	}
 
-	now := time.Now()
+	for i := range expired {
+		s.publish("typing.stopped", &expired[i])
+	}
+}
 
-	for channelID, channelUsers := range s.typing {
-		for userID, ts := range channelUsers {
-			if now.Sub(ts) >= TypingTTL {
-				delete(channelUsers, userID)
-			}
-		}
-		if len(channelUsers) == 0 {
-			delete(s.typing, channelID)
-		}
+func (s *TypingService) publish(event string, indicator *models.TypingIndicator) {
+	if s.eventBus != nil {
+		s.eventBus.Publish(event, indicator)
 	}
 }
 
@@ -157,9 +186,83 @@ func (s *TypingService) GetTypingUserIDs(ctx context.Context, channelID uuid.UUI
 
 // ClearChannel removes all typing indicators for a channel (e.g., when channel is deleted)
 func (s *TypingService) ClearChannel(ctx context.Context, channelID uuid.UUID) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	return s.typingStore().ClearTyping(ctx, channelID)
+}
+
+// memoryTypingStore keeps typing indicators in this process
+type memoryTypingStore struct {
+	mu     sync.Mutex
+	typing map[uuid.UUID]map[uuid.UUID]time.Time // channelID -> userID -> expiry
+}
+
+func newMemoryTypingStore() *memoryTypingStore {
+	return &memoryTypingStore{typing: make(map[uuid.UUID]map[uuid.UUID]time.Time)}
+}
+
+func (m *memoryTypingStore) StartTyping(ctx context.Context, channelID, userID uuid.UUID, expires time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.typing[channelID] == nil {
+		m.typing[channelID] = make(map[uuid.UUID]time.Time)
+	}
+	m.typing[channelID][userID] = expires
+	return nil
+}
+
+func (m *memoryTypingStore) StopTyping(ctx context.Context, channelID, userID uuid.UUID) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.typing[channelID][userID]; !ok {
+		return false, nil
+	}
+	delete(m.typing[channelID], userID)
+	if len(m.typing[channelID]) == 0 {
+		delete(m.typing, channelID)
+	}
+	return true, nil
+}
+
+func (m *memoryTypingStore) TypingUsers(ctx context.Context, channelID uuid.UUID, now time.Time) (map[uuid.UUID]time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	typing := make(map[uuid.UUID]time.Time)
+	for userID, expires := range m.typing[channelID] {
+		if now.Before(expires) {
+			typing[userID] = expires
+		}
+	}
+	return typing, nil
+}
+
+func (m *memoryTypingStore) ExpireTyping(ctx context.Context, now time.Time) ([]models.TypingIndicator, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var expired []models.TypingIndicator
+	for channelID, channelUsers := range m.typing {
+		for userID, expires := range channelUsers {
+			if !now.Before(expires) {
+				delete(channelUsers, userID)
+				expired = append(expired, models.TypingIndicator{
+					ChannelID: channelID,
+					UserID:    userID,
+					Timestamp: expires,
+				})
+			}
+		}
+		if len(channelUsers) == 0 {
+			delete(m.typing, channelID)
+		}
+	}
+	return expired, nil
+}
 
-	delete(s.typing, channelID)
+func (m *memoryTypingStore) ClearTyping(ctx context.Context, channelID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.typing, channelID)
 	return nil
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"hearth/internal/models"
 )
 
@@ -48,7 +49,7 @@ func (m *MockEventBusForTyping) GetEvents() []interface{} {
 
 func TestTypingService_StartTyping(t *testing.T) {
 	mockEventBus := NewMockEventBusForTyping()
-	mockEventBus.On("Publish", "typing.start", mock.AnythingOfType("*models.TypingIndicator")).Return()
+	mockEventBus.On("Publish", "typing.started", mock.AnythingOfType("*models.TypingIndicator")).Return()
 
 	svc := NewTypingService(mockEventBus)
 	ctx := context.Background()
@@ -67,12 +68,12 @@ func TestTypingService_StartTyping(t *testing.T) {
 	assert.Equal(t, channelID, indicators[0].ChannelID)
 
 	// Verify event was published
-	mockEventBus.AssertCalled(t, "Publish", "typing.start", mock.AnythingOfType("*models.TypingIndicator"))
+	mockEventBus.AssertCalled(t, "Publish", "typing.started", mock.AnythingOfType("*models.TypingIndicator"))
 }
 
 func TestTypingService_StartTyping_MultipleUsers(t *testing.T) {
 	mockEventBus := NewMockEventBusForTyping()
-	mockEventBus.On("Publish", "typing.start", mock.AnythingOfType("*models.TypingIndicator")).Return()
+	mockEventBus.On("Publish", "typing.started", mock.AnythingOfType("*models.TypingIndicator")).Return()
 
 	svc := NewTypingService(mockEventBus)
 	ctx := context.Background()
@@ -102,7 +103,8 @@ func TestTypingService_StartTyping_MultipleUsers(t *testing.T) {
 
 func TestTypingService_StopTyping(t *testing.T) {
 	mockEventBus := NewMockEventBusForTyping()
-	mockEventBus.On("Publish", "typing.start", mock.AnythingOfType("*models.TypingIndicator")).Return()
+	mockEventBus.On("Publish", "typing.started", mock.AnythingOfType("*models.TypingIndicator")).Return()
+	mockEventBus.On("Publish", "typing.stopped", mock.AnythingOfType("*models.TypingIndicator")).Return()
 
 	svc := NewTypingService(mockEventBus)
 	ctx := context.Background()
@@ -116,6 +118,11 @@ func TestTypingService_StopTyping(t *testing.T) {
 
 	indicators, _ := svc.GetTypingUsers(ctx, channelID)
 	assert.Len(t, indicators, 0)
+	mockEventBus.AssertNumberOfCalls(t, "Publish", 2)
+
+	// Stopping again has nothing to stop
+	require.NoError(t, svc.StopTyping(ctx, channelID, userID))
+	mockEventBus.AssertNumberOfCalls(t, "Publish", 2)
 }
 
 func TestTypingService_StopTyping_NonExistent(t *testing.T) {
@@ -143,7 +150,7 @@ func TestTypingService_GetTypingUsers_EmptyChannel(t *testing.T) {
 
 func TestTypingService_IsTyping(t *testing.T) {
 	mockEventBus := NewMockEventBusForTyping()
-	mockEventBus.On("Publish", "typing.start", mock.AnythingOfType("*models.TypingIndicator")).Return()
+	mockEventBus.On("Publish", "typing.started", mock.AnythingOfType("*models.TypingIndicator")).Return()
 
 	svc := NewTypingService(mockEventBus)
 	ctx := context.Background()
@@ -165,7 +172,7 @@ func TestTypingService_IsTyping(t *testing.T) {
 
 func TestTypingService_GetTypingUserIDs(t *testing.T) {
 	mockEventBus := NewMockEventBusForTyping()
-	mockEventBus.On("Publish", "typing.start", mock.AnythingOfType("*models.TypingIndicator")).Return()
+	mockEventBus.On("Publish", "typing.started", mock.AnythingOfType("*models.TypingIndicator")).Return()
 
 	svc := NewTypingService(mockEventBus)
 	ctx := context.Background()
@@ -186,7 +193,7 @@ func TestTypingService_GetTypingUserIDs(t *testing.T) {
 
 func TestTypingService_ClearChannel(t *testing.T) {
 	mockEventBus := NewMockEventBusForTyping()
-	mockEventBus.On("Publish", "typing.start", mock.AnythingOfType("*models.TypingIndicator")).Return()
+	mockEventBus.On("Publish", "typing.started", mock.AnythingOfType("*models.TypingIndicator")).Return()
 
 	svc := NewTypingService(mockEventBus)
 	ctx := context.Background()
@@ -205,7 +212,7 @@ func TestTypingService_ClearChannel(t *testing.T) {
 
 func TestTypingService_MultipleChannels(t *testing.T) {
 	mockEventBus := NewMockEventBusForTyping()
-	mockEventBus.On("Publish", "typing.start", mock.AnythingOfType("*models.TypingIndicator")).Return()
+	mockEventBus.On("Publish", "typing.started", mock.AnythingOfType("*models.TypingIndicator")).Return()
 
 	svc := NewTypingService(mockEventBus)
 	ctx := context.Background()
@@ -229,7 +236,7 @@ func TestTypingService_MultipleChannels(t *testing.T) {
 
 func TestTypingService_RefreshTyping(t *testing.T) {
 	mockEventBus := NewMockEventBusForTyping()
-	mockEventBus.On("Publish", "typing.start", mock.AnythingOfType("*models.TypingIndicator")).Return()
+	mockEventBus.On("Publish", "typing.started", mock.AnythingOfType("*models.TypingIndicator")).Return()
 
 	svc := NewTypingService(mockEventBus)
 	ctx := context.Background()
@@ -268,7 +275,8 @@ func TestTypingService_NilEventBus(t *testing.T) {
 
 func TestTypingService_ConcurrentAccess(t *testing.T) {
 	mockEventBus := NewMockEventBusForTyping()
-	mockEventBus.On("Publish", "typing.start", mock.AnythingOfType("*models.TypingIndicator")).Return()
+	mockEventBus.On("Publish", "typing.started", mock.AnythingOfType("*models.TypingIndicator")).Return()
+	mockEventBus.On("Publish", "typing.stopped", mock.AnythingOfType("*models.TypingIndicator")).Return()
 
 	svc := NewTypingService(mockEventBus)
 	ctx := context.Background()
@@ -302,7 +310,7 @@ func TestTypingService_EventBusReceivesIndicator(t *testing.T) {
 	channelID := uuid.New()
 	userID := uuid.New()
 	
-	mockEventBus.On("Publish", "typing.start", mock.MatchedBy(func(indicator *models.TypingIndicator) bool {
+	mockEventBus.On("Publish", "typing.started", mock.MatchedBy(func(indicator *models.TypingIndicator) bool {
 		return indicator.ChannelID == channelID && indicator.UserID == userID
 	})).Return()
 
@@ -316,40 +324,43 @@ func TestTypingService_EventBusReceivesIndicator(t *testing.T) {
 	mockEventBus.AssertExpectations(t)
 }
 
-func TestTypingService_CleanupExpired(t *testing.T) {
-	// Create a service with very short TTL for testing
-	svc := &TypingService{
-		typing:   make(map[uuid.UUID]map[uuid.UUID]time.Time),
-		eventBus: nil,
-	}
-
-	ctx := context.Background()
+func TestTypingService_SweepStopsExpired(t *testing.T) {
 	channelID := uuid.New()
 	userID := uuid.New()
+	mockEventBus := NewMockEventBusForTyping()
+	mockEventBus.On("Publish", "typing.started", mock.AnythingOfType("*models.TypingIndicator")).Return()
+	mockEventBus.On("Publish", "typing.stopped", mock.MatchedBy(func(indicator *models.TypingIndicator) bool {
+		return indicator.ChannelID == channelID && indicator.UserID == userID
+	})).Return()
 
-	// Manually set an old timestamp (expired)
-	svc.typing[channelID] = make(map[uuid.UUID]time.Time)
-	svc.typing[channelID][userID] = time.Now().Add(-15 * time.Second)
+	now := time.Now()
+	svc := &TypingService{
+		store:    newMemoryTypingStore(),
+		eventBus: mockEventBus,
+		now:      func() time.Time { return now },
+	}
+	ctx := context.Background()
 
-	// Run cleanup
-	svc.cleanup()
+	require.NoError(t, svc.StartTyping(ctx, channelID, userID))
+	svc.sweep(ctx)
+	mockEventBus.AssertNotCalled(t, "Publish", "typing.stopped", mock.Anything)
 
-	// Verify expired indicator was removed
-	svc.mu.RLock()
-	_, channelExists := svc.typing[channelID]
-	svc.mu.RUnlock()
-	assert.False(t, channelExists)
+	// Once the TTL lapses the indicator is stopped, once
+	now = now.Add(TypingTTL)
+	svc.sweep(ctx)
+	svc.sweep(ctx)
+	mockEventBus.AssertNumberOfCalls(t, "Publish", 2)
 
-	// GetTypingUsers should return empty
 	indicators, err := svc.GetTypingUsers(ctx, channelID)
 	assert.NoError(t, err)
 	assert.Len(t, indicators, 0)
 }
 
 func TestTypingService_GetTypingUsersFiltersExpired(t *testing.T) {
+	now := time.Now()
 	svc := &TypingService{
-		typing:   make(map[uuid.UUID]map[uuid.UUID]time.Time),
-		eventBus: nil,
+		store: newMemoryTypingStore(),
+		now:   func() time.Time { return now },
 	}
 
 	ctx := context.Background()
@@ -358,12 +369,13 @@ func TestTypingService_GetTypingUsersFiltersExpired(t *testing.T) {
 	expiredUser := uuid.New()
 
 	// Set up one active and one expired user
-	svc.typing[channelID] = make(map[uuid.UUID]time.Time)
-	svc.typing[channelID][activeUser] = time.Now()
-	svc.typing[channelID][expiredUser] = time.Now().Add(-15 * time.Second)
+	require.NoError(t, svc.StartTyping(ctx, channelID, expiredUser))
+	now = now.Add(TypingTTL)
+	require.NoError(t, svc.StartTyping(ctx, channelID, activeUser))
 
 	indicators, err := svc.GetTypingUsers(ctx, channelID)
 	assert.NoError(t, err)
 	assert.Len(t, indicators, 1)
 	assert.Equal(t, activeUser, indicators[0].UserID)
+	assert.Equal(t, now, indicators[0].Timestamp)
 }
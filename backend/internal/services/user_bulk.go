@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// MaxBulkUsers caps how many users GetUsers resolves at once
+const MaxBulkUsers = 100
+
+// SetUserBatchRepository lets GetUsers resolve many users in one query.
+// Without it each is looked up on its own.
+func (s *UserService) SetUserBatchRepository(repo UserBatchRepository) {
+	s.userBatch = repo
+}
+
+// GetUsers resolves up to MaxBulkUsers users, in the order asked for.
+// Repeated IDs are resolved once and users that don't exist are left out.
+// Lookups go through the request's Loaders when it has them.
+func (s *UserService) GetUsers(ctx context.Context, ids []uuid.UUID) ([]*models.User, error) {
+	ids = uniqueIDs(ids)
+	if len(ids) > MaxBulkUsers {
+		return nil, ErrTooManyUsers
+	}
+
+	loaders := LoadersFrom(ctx)
+	if loaders == nil && s.userBatch != nil {
+		loaders = NewLoaders(s.userBatch, nil)
+	}
+	if loaders == nil {
+		users := make([]*models.User, 0, len(ids))
+		for _, id := range ids {
+			user, err := s.GetUser(ctx, id)
+			if err == ErrUserNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			users = append(users, user)
+		}
+		return users, nil
+	}
+
+	found, err := loaders.Users(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]*models.User, 0, len(found))
+	for _, id := range ids {
+		if user, ok := found[id]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
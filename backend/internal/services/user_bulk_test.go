@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+func TestGetUsers_OneQueryInRequestOrder(t *testing.T) {
+	service, repo, _, _ := setupUserService()
+	users := new(MockUserBatchRepository)
+	service.SetUserBatchRepository(users)
+	ctx := context.Background()
+
+	alice := &models.User{ID: uuid.New(), Username: "alice"}
+	bob := &models.User{ID: uuid.New(), Username: "bob"}
+	deleted := uuid.New()
+
+	users.On("GetByIDs", ctx, []uuid.UUID{bob.ID, deleted, alice.ID}).Return([]*models.User{alice, bob}, nil).Once()
+
+	result, err := service.GetUsers(ctx, []uuid.UUID{bob.ID, deleted, alice.ID, bob.ID})
+
+	require.NoError(t, err)
+	assert.Equal(t, []*models.User{bob, alice}, result)
+	repo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+}
+
+func TestGetUsers_WithoutBatchRepository(t *testing.T) {
+	service, repo, cache, _ := setupUserService()
+	ctx := context.Background()
+
+	alice := &models.User{ID: uuid.New(), Username: "alice"}
+	deleted := uuid.New()
+
+	cache.On("GetUser", ctx, mock.Anything).Return(nil, nil)
+	cache.On("SetUser", ctx, alice, mock.Anything).Return(nil)
+	repo.On("GetByID", ctx, alice.ID).Return(alice, nil)
+	repo.On("GetByID", ctx, deleted).Return(nil, nil)
+
+	result, err := service.GetUsers(ctx, []uuid.UUID{deleted, alice.ID})
+
+	require.NoError(t, err)
+	assert.Equal(t, []*models.User{alice}, result)
+}
+
+func TestGetUsers_TooMany(t *testing.T) {
+	service, _, _, _ := setupUserService()
+	service.SetUserBatchRepository(new(MockUserBatchRepository))
+
+	ids := make([]uuid.UUID, MaxBulkUsers+1)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+
+	_, err := service.GetUsers(context.Background(), ids)
+	assert.Equal(t, ErrTooManyUsers, err)
+}
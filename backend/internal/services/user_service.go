@@ -3,6 +3,8 @@ package services
 import (
 	"context"
 	"errors"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -38,6 +40,9 @@ type UserRepository interface {
 	UpdatePresence(ctx context.Context, userID uuid.UUID, status models.PresenceStatus) error
 	GetPresence(ctx context.Context, userID uuid.UUID) (*models.Presence, error)
 	GetPresenceBulk(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*models.Presence, error)
+
+	// Custom status
+	ClearExpiredCustomStatuses(ctx context.Context, before time.Time) ([]uuid.UUID, error)
 }
 
 // UserService handles user-related business logic
@@ -45,6 +50,12 @@ type UserService struct {
 	repo     UserRepository
 	cache    CacheService
 	eventBus EventBus
+
+	usernames        UsernameRepository
+	usernameCooldown time.Duration
+	usernamePolicy   UsernamePolicy
+
+	userBatch UserBatchRepository
 }
 
 // NewUserService creates a new user service
@@ -81,8 +92,13 @@ func (s *UserService) GetUser(ctx context.Context, id uuid.UUID) (*models.User,
 	return user, nil
 }
 
-// GetUserByUsername retrieves a user by username
+// GetUserByUsername retrieves a user by username, ignoring case. A legacy
+// "name#1234" tag is looked up by its name, since usernames are unique
+// on their own.
 func (s *UserService) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	if i := strings.LastIndexByte(username, '#'); i > 0 {
+		username = username[:i]
+	}
 	user, err := s.repo.GetByUsername(ctx, username)
 	if err != nil {
 		return nil, err
@@ -103,13 +119,11 @@ func (s *UserService) UpdateUser(ctx context.Context, id uuid.UUID, updates *mod
 		return nil, ErrUserNotFound
 	}
 	
-	// Check username uniqueness if changing
+	// Check the naming rules, uniqueness and cooldown if changing
 	if updates.Username != nil && *updates.Username != user.Username {
-		existing, _ := s.repo.GetByUsername(ctx, *updates.Username)
-		if existing != nil {
-			return nil, ErrUsernameTaken
+		if err := s.changeUsername(ctx, user, *updates.Username); err != nil {
+			return nil, err
 		}
-		user.Username = *updates.Username
 	}
 	
 	// Apply updates
@@ -169,6 +183,122 @@ func (s *UserService) UpdatePresence(ctx context.Context, userID uuid.UUID, stat
 	return nil
 }
 
+const (
+	maxCustomStatusText  = 128
+	maxCustomStatusEmoji = 64
+)
+
+var (
+	ErrCustomStatusEmpty   = errors.New("custom status needs text or an emoji")
+	ErrCustomStatusTooLong = errors.New("custom status text or emoji is too long")
+	ErrCustomStatusExpired = errors.New("custom status expiry must be in the future")
+)
+
+// SetCustomStatus sets the user's custom status. A nil ExpiresAt keeps it
+// until the user clears it.
+func (s *UserService) SetCustomStatus(ctx context.Context, userID uuid.UUID, status *models.CustomStatus) (*models.User, error) {
+	text := trimmedOrNil(status.Text)
+	emoji := trimmedOrNil(status.Emoji)
+	if text == nil && emoji == nil {
+		return nil, ErrCustomStatusEmpty
+	}
+	if (text != nil && len(*text) > maxCustomStatusText) || (emoji != nil && len(*emoji) > maxCustomStatusEmoji) {
+		return nil, ErrCustomStatusTooLong
+	}
+	if status.ExpiresAt != nil && !status.ExpiresAt.After(time.Now()) {
+		return nil, ErrCustomStatusExpired
+	}
+
+	return s.saveCustomStatus(ctx, userID, text, emoji, status.ExpiresAt)
+}
+
+// ClearCustomStatus removes the user's custom status
+func (s *UserService) ClearCustomStatus(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	return s.saveCustomStatus(ctx, userID, nil, nil, nil)
+}
+
+func (s *UserService) saveCustomStatus(ctx context.Context, userID uuid.UUID, text, emoji *string, expiresAt *time.Time) (*models.User, error) {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	user.CustomStatus = text
+	user.CustomStatusEmoji = emoji
+	user.CustomStatusExpiresAt = expiresAt
+	user.UpdatedAt = time.Now()
+
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if s.cache != nil {
+		_ = s.cache.DeleteUser(ctx, userID)
+	}
+
+	s.eventBus.Publish("user.updated", &UserUpdatedEvent{
+		UserID:    userID,
+		User:      user,
+		UpdatedAt: user.UpdatedAt,
+	})
+	s.eventBus.Publish("user.custom_status_updated", &CustomStatusUpdatedEvent{
+		UserID:       userID,
+		CustomStatus: user.ActiveCustomStatus(user.UpdatedAt),
+	})
+
+	return user, nil
+}
+
+// ExpireCustomStatuses clears every custom status whose expiry has passed
+// and returns how many were cleared
+func (s *UserService) ExpireCustomStatuses(ctx context.Context, now time.Time) (int, error) {
+	ids, err := s.repo.ClearExpiredCustomStatuses(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if s.cache != nil {
+			_ = s.cache.DeleteUser(ctx, id)
+		}
+		s.eventBus.Publish("user.custom_status_updated", &CustomStatusUpdatedEvent{UserID: id})
+	}
+
+	return len(ids), nil
+}
+
+// RunCustomStatusExpiry clears expired custom statuses every interval until
+// ctx is done
+func (s *UserService) RunCustomStatusExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := s.ExpireCustomStatuses(ctx, now); err != nil {
+				log.Printf("[UserService] failed to expire custom statuses: %v", err)
+			}
+		}
+	}
+}
+
+func trimmedOrNil(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	v := strings.TrimSpace(*s)
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
 // GetFriends retrieves user's friend list
 func (s *UserService) GetFriends(ctx context.Context, userID uuid.UUID) ([]*models.User, error) {
 	return s.repo.GetFriends(ctx, userID)
@@ -212,8 +342,19 @@ func (s *UserService) BlockUser(ctx context.Context, userID, blockedID uuid.UUID
 		return errors.New("cannot block yourself")
 	}
 	
-	// Remove friend relationship if exists
-	_ = s.repo.RemoveFriend(ctx, userID, blockedID)
+	// Blocking ends any friendship or pending request. RemoveFriend clears
+	// both sides, so skip it when there is none: a block the other user
+	// holds against us has to survive.
+	relType, err := s.repo.GetRelationship(ctx, userID, blockedID)
+	if err != nil {
+		return err
+	}
+	severed := relType == RelationshipFriend || relType == RelationshipPendingIn || relType == RelationshipPendingOut
+	if severed {
+		if err := s.repo.RemoveFriend(ctx, userID, blockedID); err != nil {
+			return err
+		}
+	}
 	
 	if err := s.repo.BlockUser(ctx, userID, blockedID); err != nil {
 		return err
@@ -222,11 +363,17 @@ func (s *UserService) BlockUser(ctx context.Context, userID, blockedID uuid.UUID
 	s.eventBus.Publish("user.blocked", &UserBlockedEvent{
 		UserID:    userID,
 		BlockedID: blockedID,
+		Severed:   severed,
 	})
 	
 	return nil
 }
 
+// GetBlockedUsers returns the users userID has blocked
+func (s *UserService) GetBlockedUsers(ctx context.Context, userID uuid.UUID) ([]*models.User, error) {
+	return s.repo.GetBlockedUsers(ctx, userID)
+}
+
 // UnblockUser unblocks a user
 func (s *UserService) UnblockUser(ctx context.Context, userID, blockedID uuid.UUID) error {
 	if err := s.repo.UnblockUser(ctx, userID, blockedID); err != nil {
@@ -374,6 +521,13 @@ type PresenceUpdatedEvent struct {
 	Presence *models.Presence
 }
 
+// CustomStatusUpdatedEvent carries the user's new custom status; nil means
+// it was cleared or expired
+type CustomStatusUpdatedEvent struct {
+	UserID       uuid.UUID
+	CustomStatus *models.CustomStatus
+}
+
 type FriendAddedEvent struct {
 	UserID   uuid.UUID
 	FriendID uuid.UUID
@@ -384,9 +538,12 @@ type FriendRemovedEvent struct {
 	FriendID uuid.UUID
 }
 
+// UserBlockedEvent is published when UserID blocks BlockedID. Severed is
+// set when the block ended a friendship or pending request between them.
 type UserBlockedEvent struct {
 	UserID    uuid.UUID
 	BlockedID uuid.UUID
+	Severed   bool
 }
 
 type UserUnblockedEvent struct {
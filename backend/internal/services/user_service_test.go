@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -104,6 +105,14 @@ func (m *MockUserRepository) GetPresence(ctx context.Context, userID uuid.UUID)
 	return args.Get(0).(*models.Presence), args.Error(1)
 }
 
+func (m *MockUserRepository) ClearExpiredCustomStatuses(ctx context.Context, before time.Time) ([]uuid.UUID, error) {
+	args := m.Called(ctx, before)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
 func (m *MockUserRepository) GetPresenceBulk(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*models.Presence, error) {
 	args := m.Called(ctx, userIDs)
 	if args.Get(0) == nil {
@@ -314,9 +323,12 @@ func TestBlockUser_Success(t *testing.T) {
 	userID := uuid.New()
 	blockedID := uuid.New()
 
+	repo.On("GetRelationship", ctx, userID, blockedID).Return(RelationshipFriend, nil)
 	repo.On("RemoveFriend", ctx, userID, blockedID).Return(nil)
 	repo.On("BlockUser", ctx, userID, blockedID).Return(nil)
-	eventBus.On("Publish", "user.blocked", mock.AnythingOfType("*services.UserBlockedEvent")).Return()
+	eventBus.On("Publish", "user.blocked", mock.MatchedBy(func(e *UserBlockedEvent) bool {
+		return e.UserID == userID && e.BlockedID == blockedID && e.Severed
+	})).Return()
 
 	err := service.BlockUser(ctx, userID, blockedID)
 
@@ -325,6 +337,26 @@ func TestBlockUser_Success(t *testing.T) {
 	eventBus.AssertExpectations(t)
 }
 
+func TestBlockUser_KeepsOtherUsersBlock(t *testing.T) {
+	service, repo, _, eventBus := setupUserService()
+	ctx := context.Background()
+	userID := uuid.New()
+	blockedID := uuid.New()
+
+	// blockedID already blocked userID, so userID has no row of its own
+	repo.On("GetRelationship", ctx, userID, blockedID).Return(RelationshipNone, nil)
+	repo.On("BlockUser", ctx, userID, blockedID).Return(nil)
+	eventBus.On("Publish", "user.blocked", mock.MatchedBy(func(e *UserBlockedEvent) bool {
+		return !e.Severed
+	})).Return()
+
+	err := service.BlockUser(ctx, userID, blockedID)
+
+	assert.NoError(t, err)
+	repo.AssertNotCalled(t, "RemoveFriend", mock.Anything, mock.Anything, mock.Anything)
+	eventBus.AssertExpectations(t)
+}
+
 func TestBlockUser_CannotBlockSelf(t *testing.T) {
 	service, _, _, _ := setupUserService()
 	ctx := context.Background()
@@ -720,3 +752,97 @@ func TestGetRelationship_Success(t *testing.T) {
 	assert.Equal(t, 1, relType)
 	repo.AssertExpectations(t)
 }
+
+func TestSetCustomStatus_Success(t *testing.T) {
+	service, repo, cache, eventBus := setupUserService()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	text := "  Out for lunch  "
+	emoji := "🍜"
+	expiresAt := time.Now().Add(time.Hour)
+
+	repo.On("GetByID", ctx, userID).Return(&models.User{ID: userID}, nil)
+	repo.On("Update", ctx, mock.AnythingOfType("*models.User")).Return(nil)
+	cache.On("DeleteUser", ctx, userID).Return(nil)
+	eventBus.On("Publish", "user.updated", mock.AnythingOfType("*services.UserUpdatedEvent")).Return()
+	eventBus.On("Publish", "user.custom_status_updated", mock.MatchedBy(func(e *CustomStatusUpdatedEvent) bool {
+		return e.UserID == userID && e.CustomStatus != nil && *e.CustomStatus.Text == "Out for lunch"
+	})).Return()
+
+	user, err := service.SetCustomStatus(ctx, userID, &models.CustomStatus{
+		Text:      &text,
+		Emoji:     &emoji,
+		ExpiresAt: &expiresAt,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Out for lunch", *user.CustomStatus)
+	assert.Equal(t, emoji, *user.CustomStatusEmoji)
+	assert.Equal(t, &expiresAt, user.CustomStatusExpiresAt)
+	repo.AssertExpectations(t)
+	eventBus.AssertExpectations(t)
+}
+
+func TestSetCustomStatus_Validation(t *testing.T) {
+	service, repo, _, _ := setupUserService()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	blank := "   "
+	long := strings.Repeat("a", maxCustomStatusText+1)
+	text := "Busy"
+	past := time.Now().Add(-time.Minute)
+
+	_, err := service.SetCustomStatus(ctx, userID, &models.CustomStatus{Text: &blank})
+	assert.ErrorIs(t, err, ErrCustomStatusEmpty)
+
+	_, err = service.SetCustomStatus(ctx, userID, &models.CustomStatus{Text: &long})
+	assert.ErrorIs(t, err, ErrCustomStatusTooLong)
+
+	_, err = service.SetCustomStatus(ctx, userID, &models.CustomStatus{Text: &text, ExpiresAt: &past})
+	assert.ErrorIs(t, err, ErrCustomStatusExpired)
+
+	repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestClearCustomStatus_Success(t *testing.T) {
+	service, repo, cache, eventBus := setupUserService()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	text := "Busy"
+	repo.On("GetByID", ctx, userID).Return(&models.User{ID: userID, CustomStatus: &text}, nil)
+	repo.On("Update", ctx, mock.MatchedBy(func(u *models.User) bool {
+		return u.CustomStatus == nil && u.CustomStatusEmoji == nil && u.CustomStatusExpiresAt == nil
+	})).Return(nil)
+	cache.On("DeleteUser", ctx, userID).Return(nil)
+	eventBus.On("Publish", "user.updated", mock.AnythingOfType("*services.UserUpdatedEvent")).Return()
+	eventBus.On("Publish", "user.custom_status_updated", &CustomStatusUpdatedEvent{UserID: userID}).Return()
+
+	_, err := service.ClearCustomStatus(ctx, userID)
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+	eventBus.AssertExpectations(t)
+}
+
+func TestExpireCustomStatuses(t *testing.T) {
+	service, repo, cache, eventBus := setupUserService()
+	ctx := context.Background()
+	now := time.Now()
+	expired := []uuid.UUID{uuid.New(), uuid.New()}
+
+	repo.On("ClearExpiredCustomStatuses", ctx, now).Return(expired, nil)
+	for _, id := range expired {
+		cache.On("DeleteUser", ctx, id).Return(nil)
+		eventBus.On("Publish", "user.custom_status_updated", &CustomStatusUpdatedEvent{UserID: id}).Return()
+	}
+
+	n, err := service.ExpireCustomStatuses(ctx, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	cache.AssertExpectations(t)
+	eventBus.AssertExpectations(t)
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	maxSuspensionReason = 512
+
+	// suspensionCacheTTL bounds how long a suspension made on another
+	// instance takes to apply here. Suspensions made on this instance apply
+	// straight away.
+	suspensionCacheTTL = 30 * time.Second
+	// suspensionCacheSize caps the cached lookups; the cache starts over
+	// once it is full
+	suspensionCacheSize = 10000
+)
+
+// UserSuspensionRepository stores the accounts instance admins suspended.
+// The Postgres UserSuspensionRepository implements it.
+type UserSuspensionRepository interface {
+	// Get returns nil if the user was never suspended
+	Get(ctx context.Context, userID uuid.UUID) (*models.UserSuspension, error)
+	GetMany(ctx context.Context, userIDs []uuid.UUID) ([]*models.UserSuspension, error)
+	Save(ctx context.Context, suspension *models.UserSuspension) error
+	Delete(ctx context.Context, userID uuid.UUID) (bool, error)
+}
+
+// SuspensionUserRepository looks up the users being suspended. The
+// Postgres UserRepository implements it.
+type SuspensionUserRepository interface {
+	// GetByID returns nil if there's no such user
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+}
+
+// UserDisconnector closes a suspended user's gateway connections. The
+// websocket Gateway implements it.
+type UserDisconnector interface {
+	DisconnectSuspendedUser(userID uuid.UUID) int
+}
+
+// UserSuspensionService suspends accounts for instance admins. Suspended
+// accounts are logged out, can't log in or refresh tokens, and are refused
+// by the API and the gateway until the suspension is lifted or expires.
+type UserSuspensionService struct {
+	repo         UserSuspensionRepository
+	users        SuspensionUserRepository
+	sessions     *SessionService
+	disconnector UserDisconnector
+	now          func() time.Time
+
+	mu    sync.Mutex
+	cache map[uuid.UUID]cachedSuspension
+}
+
+type cachedSuspension struct {
+	suspension *models.UserSuspension
+	expires    time.Time
+}
+
+// NewUserSuspensionService creates a suspension service. Without
+// sessions, suspending a user leaves their refresh tokens working until
+// they're used.
+func NewUserSuspensionService(repo UserSuspensionRepository, users SuspensionUserRepository, sessions *SessionService) *UserSuspensionService {
+	return &UserSuspensionService{
+		repo:     repo,
+		users:    users,
+		sessions: sessions,
+		now:      time.Now,
+		cache:    make(map[uuid.UUID]cachedSuspension),
+	}
+}
+
+// SetDisconnector closes suspended users' gateway connections on this
+// instance. Connections to other instances close the next time they
+// refresh their token.
+func (s *UserSuspensionService) SetDisconnector(disconnector UserDisconnector) {
+	s.disconnector = disconnector
+}
+
+func invalidSuspension(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidSuspension, fmt.Sprintf(format, args...))
+}
+
+// Suspend suspends a user, replacing any suspension they already have, and
+// logs them out everywhere. Instance admins can't be suspended; revoke
+// their admin first.
+func (s *UserSuspensionService) Suspend(ctx context.Context, adminID, userID uuid.UUID, req *models.SuspendUserRequest) (*models.UserSuspension, error) {
+	now := s.now()
+	suspension := &models.UserSuspension{
+		UserID:      userID,
+		SuspendedBy: &adminID,
+		CreatedAt:   now,
+	}
+	if req.Reason != nil {
+		if trimmed := strings.TrimSpace(*req.Reason); trimmed != "" {
+			if len(trimmed) > maxSuspensionReason {
+				return nil, invalidSuspension("reason must be at most %d characters", maxSuspensionReason)
+			}
+			suspension.Reason = &trimmed
+		}
+	}
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(now) {
+			return nil, invalidSuspension("expires_at must be in the future")
+		}
+		expiresAt := *req.ExpiresAt
+		suspension.ExpiresAt = &expiresAt
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if user.Flags&models.UserFlagStaff != 0 {
+		return nil, ErrCannotSuspendAdmin
+	}
+
+	if err := s.repo.Save(ctx, suspension); err != nil {
+		return nil, err
+	}
+	s.store(userID, suspension)
+
+	if s.sessions != nil {
+		if err := s.sessions.RevokeAllSessions(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	if s.disconnector != nil {
+		s.disconnector.DisconnectSuspendedUser(userID)
+	}
+	return suspension, nil
+}
+
+// Unsuspend lifts a user's suspension. They have to log in again.
+func (s *UserSuspensionService) Unsuspend(ctx context.Context, userID uuid.UUID) error {
+	deleted, err := s.repo.Delete(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrSuspensionNotFound
+	}
+	s.store(userID, nil)
+	return nil
+}
+
+// ActiveSuspension returns the user's suspension if it still applies. It
+// fails open: lookups that fail are logged and the user is let through.
+func (s *UserSuspensionService) ActiveSuspension(ctx context.Context, userID uuid.UUID) *models.UserSuspension {
+	now := s.now()
+	s.mu.Lock()
+	cached, ok := s.cache[userID]
+	s.mu.Unlock()
+
+	suspension := cached.suspension
+	if !ok || !now.Before(cached.expires) {
+		var err error
+		suspension, err = s.repo.Get(ctx, userID)
+		if err != nil {
+			log.Printf("[Suspensions] failed to look up suspension of %s: %v", userID, err)
+			return nil
+		}
+		s.store(userID, suspension)
+	}
+
+	if suspension == nil || !suspension.Active(now) {
+		return nil
+	}
+	return suspension
+}
+
+// Check returns an *AccountSuspendedError if the user is suspended
+func (s *UserSuspensionService) Check(ctx context.Context, userID uuid.UUID) error {
+	suspension := s.ActiveSuspension(ctx, userID)
+	if suspension == nil {
+		return nil
+	}
+	return &AccountSuspendedError{Reason: suspension.Reason, Until: suspension.ExpiresAt}
+}
+
+// ActiveSuspensions returns the suspensions that still apply to the users
+// given, by user
+func (s *UserSuspensionService) ActiveSuspensions(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*models.UserSuspension, error) {
+	suspensions, err := s.repo.GetMany(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	active := make(map[uuid.UUID]*models.UserSuspension, len(suspensions))
+	for _, suspension := range suspensions {
+		if suspension.Active(now) {
+			active[suspension.UserID] = suspension
+		}
+	}
+	return active, nil
+}
+
+// store caches a lookup, nil meaning the user isn't suspended
+func (s *UserSuspensionService) store(userID uuid.UUID, suspension *models.UserSuspension) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.cache) >= suspensionCacheSize {
+		s.cache = make(map[uuid.UUID]cachedSuspension)
+	}
+	s.cache[userID] = cachedSuspension{suspension: suspension, expires: s.now().Add(suspensionCacheTTL)}
+}
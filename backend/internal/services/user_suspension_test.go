@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/auth"
+	"hearth/internal/models"
+)
+
+// fakeSuspensionRepo stores suspensions in memory
+type fakeSuspensionRepo struct {
+	suspensions map[uuid.UUID]*models.UserSuspension
+	gets        int
+	err         error
+}
+
+func (r *fakeSuspensionRepo) Get(ctx context.Context, userID uuid.UUID) (*models.UserSuspension, error) {
+	r.gets++
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.suspensions[userID], nil
+}
+
+func (r *fakeSuspensionRepo) GetMany(ctx context.Context, userIDs []uuid.UUID) ([]*models.UserSuspension, error) {
+	var suspensions []*models.UserSuspension
+	for _, id := range userIDs {
+		if suspension, ok := r.suspensions[id]; ok {
+			suspensions = append(suspensions, suspension)
+		}
+	}
+	return suspensions, nil
+}
+
+func (r *fakeSuspensionRepo) Save(ctx context.Context, suspension *models.UserSuspension) error {
+	copied := *suspension
+	r.suspensions[suspension.UserID] = &copied
+	return nil
+}
+
+func (r *fakeSuspensionRepo) Delete(ctx context.Context, userID uuid.UUID) (bool, error) {
+	_, ok := r.suspensions[userID]
+	delete(r.suspensions, userID)
+	return ok, nil
+}
+
+type recordedDisconnects []uuid.UUID
+
+func (r *recordedDisconnects) DisconnectSuspendedUser(userID uuid.UUID) int {
+	*r = append(*r, userID)
+	return 1
+}
+
+type suspensionTest struct {
+	svc         *UserSuspensionService
+	repo        *fakeSuspensionRepo
+	users       *fakeInstanceAdminRepo
+	sessions    *fakeSessionRepo
+	disconnects *recordedDisconnects
+	now         time.Time
+}
+
+func newSuspensionTest() *suspensionTest {
+	tt := &suspensionTest{
+		repo:        &fakeSuspensionRepo{suspensions: make(map[uuid.UUID]*models.UserSuspension)},
+		users:       &fakeInstanceAdminRepo{users: make(map[uuid.UUID]*models.User)},
+		sessions:    newFakeSessionRepo(),
+		disconnects: &recordedDisconnects{},
+		now:         time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	tt.svc = NewUserSuspensionService(tt.repo, tt.users, NewSessionService(tt.sessions, testJWTService()))
+	tt.svc.SetDisconnector(tt.disconnects)
+	tt.svc.now = func() time.Time { return tt.now }
+	return tt
+}
+
+func (tt *suspensionTest) addUser(flags int64) *models.User {
+	user := &models.User{ID: uuid.New(), Username: "user", Flags: flags}
+	tt.users.users[user.ID] = user
+	return user
+}
+
+func TestUserSuspensionService_Suspend(t *testing.T) {
+	ctx := context.Background()
+	tt := newSuspensionTest()
+	user := tt.addUser(0)
+	adminID := uuid.New()
+	tt.sessions.sessions[uuid.New()] = &models.Session{UserID: user.ID}
+
+	reason := "  spam  "
+	until := tt.now.Add(24 * time.Hour)
+	suspension, err := tt.svc.Suspend(ctx, adminID, user.ID, &models.SuspendUserRequest{Reason: &reason, ExpiresAt: &until})
+	require.NoError(t, err)
+	assert.Equal(t, "spam", *suspension.Reason)
+	assert.Equal(t, adminID, *suspension.SuspendedBy)
+	assert.Contains(t, tt.repo.suspensions, user.ID)
+	assert.Empty(t, tt.sessions.sessions, "the user is logged out everywhere")
+	assert.Equal(t, recordedDisconnects{user.ID}, *tt.disconnects)
+
+	var suspended *AccountSuspendedError
+	require.ErrorAs(t, tt.svc.Check(ctx, user.ID), &suspended)
+	assert.Equal(t, "spam", *suspended.Reason)
+	assert.Equal(t, until, *suspended.Until)
+	assert.Zero(t, tt.repo.gets, "suspending caches the suspension")
+
+	tt.now = until
+	tt.repo.suspensions[user.ID].ExpiresAt = &until
+	assert.NoError(t, tt.svc.Check(ctx, user.ID), "suspensions end by themselves")
+}
+
+func TestUserSuspensionService_SuspendInvalid(t *testing.T) {
+	ctx := context.Background()
+	tt := newSuspensionTest()
+	user := tt.addUser(0)
+	admin := tt.addUser(models.UserFlagStaff)
+
+	past := tt.now.Add(-time.Minute)
+	_, err := tt.svc.Suspend(ctx, admin.ID, user.ID, &models.SuspendUserRequest{ExpiresAt: &past})
+	assert.ErrorIs(t, err, ErrInvalidSuspension)
+
+	long := strings.Repeat("a", maxSuspensionReason+1)
+	_, err = tt.svc.Suspend(ctx, admin.ID, user.ID, &models.SuspendUserRequest{Reason: &long})
+	assert.ErrorIs(t, err, ErrInvalidSuspension)
+
+	_, err = tt.svc.Suspend(ctx, admin.ID, uuid.New(), &models.SuspendUserRequest{})
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	_, err = tt.svc.Suspend(ctx, user.ID, admin.ID, &models.SuspendUserRequest{})
+	assert.ErrorIs(t, err, ErrCannotSuspendAdmin)
+	assert.Empty(t, tt.repo.suspensions)
+}
+
+func TestUserSuspensionService_Unsuspend(t *testing.T) {
+	ctx := context.Background()
+	tt := newSuspensionTest()
+	user := tt.addUser(0)
+
+	_, err := tt.svc.Suspend(ctx, uuid.New(), user.ID, &models.SuspendUserRequest{})
+	require.NoError(t, err)
+	assert.ErrorIs(t, tt.svc.Check(ctx, user.ID), ErrAccountSuspended)
+
+	require.NoError(t, tt.svc.Unsuspend(ctx, user.ID))
+	assert.NoError(t, tt.svc.Check(ctx, user.ID))
+	assert.ErrorIs(t, tt.svc.Unsuspend(ctx, user.ID), ErrSuspensionNotFound)
+}
+
+func TestUserSuspensionService_Cache(t *testing.T) {
+	ctx := context.Background()
+	tt := newSuspensionTest()
+	userID := uuid.New()
+
+	assert.Nil(t, tt.svc.ActiveSuspension(ctx, userID))
+	// Suspended on another instance
+	tt.repo.suspensions[userID] = &models.UserSuspension{UserID: userID}
+	assert.Nil(t, tt.svc.ActiveSuspension(ctx, userID))
+	assert.Equal(t, 1, tt.repo.gets)
+
+	tt.now = tt.now.Add(suspensionCacheTTL)
+	assert.NotNil(t, tt.svc.ActiveSuspension(ctx, userID))
+	assert.Equal(t, 2, tt.repo.gets)
+
+	tt.now = tt.now.Add(suspensionCacheTTL)
+	tt.repo.err = errors.New("connection refused")
+	assert.Nil(t, tt.svc.ActiveSuspension(ctx, userID), "lookups that fail let the user through")
+}
+
+func TestUserSuspensionService_ActiveSuspensions(t *testing.T) {
+	tt := newSuspensionTest()
+	active, expired, never := uuid.New(), uuid.New(), uuid.New()
+	past := tt.now.Add(-time.Hour)
+	tt.repo.suspensions[active] = &models.UserSuspension{UserID: active}
+	tt.repo.suspensions[expired] = &models.UserSuspension{UserID: expired, ExpiresAt: &past}
+
+	suspensions, err := tt.svc.ActiveSuspensions(context.Background(), []uuid.UUID{active, expired, never})
+	require.NoError(t, err)
+	assert.Len(t, suspensions, 1)
+	assert.Contains(t, suspensions, active)
+}
+
+func TestSessionService_Suspended(t *testing.T) {
+	ctx := context.Background()
+	tt := newSuspensionTest()
+	user := tt.addUser(0)
+	sessions := NewSessionService(tt.sessions, testJWTService())
+	sessions.SetSuspensions(tt.svc)
+
+	tokens, err := sessions.Start(ctx, user.ID, user.Username, false)
+	require.NoError(t, err)
+	claims, err := testJWTService().ValidateRefreshToken(tokens.RefreshToken)
+	require.NoError(t, err)
+
+	_, err = tt.svc.Suspend(ctx, uuid.New(), user.ID, &models.SuspendUserRequest{})
+	require.NoError(t, err)
+
+	_, err = sessions.Start(ctx, user.ID, user.Username, false)
+	assert.ErrorIs(t, err, ErrAccountSuspended)
+	_, err = sessions.Refresh(ctx, claims, tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrAccountSuspended)
+	assert.ErrorIs(t, sessions.Validate(ctx, &auth.Claims{UserID: user.ID}), ErrAccountSuspended)
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// Username rule limits
+const (
+	maxUsernameRules       = 1000
+	maxUsernameRulePattern = 260
+	maxUsernameRuleReason  = 255
+
+	// usernameRuleCacheTTL bounds how long another instance's edits take to
+	// apply here. Edits made on this instance apply straight away.
+	usernameRuleCacheTTL = time.Minute
+)
+
+// UsernameRuleRepository stores reserved and banned usernames
+type UsernameRuleRepository interface {
+	List(ctx context.Context) ([]*models.UsernameRule, error)
+	Create(ctx context.Context, rule *models.UsernameRule) error
+	Delete(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+// UsernameRuleService manages the instance's reserved and banned usernames
+// and checks new usernames against them
+type UsernameRuleService struct {
+	repo UsernameRuleRepository
+
+	mu      sync.Mutex
+	rules   []*compiledUsernameRule
+	expires time.Time
+}
+
+func NewUsernameRuleService(repo UsernameRuleRepository) *UsernameRuleService {
+	return &UsernameRuleService{repo: repo}
+}
+
+// ListRules returns every rule, oldest first
+func (s *UsernameRuleService) ListRules(ctx context.Context) ([]*models.UsernameRule, error) {
+	return s.repo.List(ctx)
+}
+
+func invalidUsernameRule(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidUsernameRule, fmt.Sprintf(format, args...))
+}
+
+// CreateRule adds a rule. Accounts that already have a matching username
+// keep it.
+func (s *UsernameRuleService) CreateRule(ctx context.Context, creatorID uuid.UUID, req *models.CreateUsernameRuleRequest) (*models.UsernameRule, error) {
+	if !req.Kind.Valid() {
+		return nil, invalidUsernameRule("kind must be reserved or banned")
+	}
+	pattern := strings.TrimSpace(req.Pattern)
+	if pattern == "" || len(pattern) > maxUsernameRulePattern {
+		return nil, invalidUsernameRule("pattern must be between 1 and %d characters", maxUsernameRulePattern)
+	}
+	if !req.Regex && validateUsername(pattern) != nil {
+		return nil, invalidUsernameRule("plain patterns must be usernames; use a regex to match anything else")
+	}
+	var reason *string
+	if req.Reason != nil {
+		if trimmed := strings.TrimSpace(*req.Reason); trimmed != "" {
+			if len(trimmed) > maxUsernameRuleReason {
+				return nil, invalidUsernameRule("reason must be at most %d characters", maxUsernameRuleReason)
+			}
+			reason = &trimmed
+		}
+	}
+
+	rule := &models.UsernameRule{
+		ID:        uuid.New(),
+		Kind:      req.Kind,
+		Pattern:   pattern,
+		Regex:     req.Regex,
+		Reason:    reason,
+		CreatorID: &creatorID,
+		CreatedAt: time.Now(),
+	}
+	if _, err := compileUsernameRule(rule); err != nil {
+		return nil, invalidUsernameRule("pattern %q: %v", pattern, err)
+	}
+
+	existing, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxUsernameRules {
+		return nil, invalidUsernameRule("an instance can have at most %d username rules", maxUsernameRules)
+	}
+	if err := s.repo.Create(ctx, rule); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return rule, nil
+}
+
+// DeleteRule removes a rule
+func (s *UsernameRuleService) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	deleted, err := s.repo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrUsernameRuleNotFound
+	}
+	s.invalidate()
+	return nil
+}
+
+// Check returns ErrUsernameBanned or ErrUsernameReserved if a rule stops
+// the username from being claimed. Staff may claim reserved usernames.
+func (s *UsernameRuleService) Check(ctx context.Context, name string, staff bool) error {
+	rules, err := s.compiledRules(ctx)
+	if err != nil {
+		return err
+	}
+	reserved := false
+	for _, rule := range rules {
+		if !rule.match(name) {
+			continue
+		}
+		if rule.kind == models.UsernameRuleBanned {
+			return ErrUsernameBanned
+		}
+		reserved = true
+	}
+	if reserved && !staff {
+		return ErrUsernameReserved
+	}
+	return nil
+}
+
+// invalidate drops the compiled rules so the next check reloads them
+func (s *UsernameRuleService) invalidate() {
+	s.mu.Lock()
+	s.rules = nil
+	s.expires = time.Time{}
+	s.mu.Unlock()
+}
+
+// compiledRules returns every rule, compiled
+func (s *UsernameRuleService) compiledRules(ctx context.Context) ([]*compiledUsernameRule, error) {
+	now := time.Now()
+	s.mu.Lock()
+	rules, expires := s.rules, s.expires
+	s.mu.Unlock()
+	if now.Before(expires) {
+		return rules, nil
+	}
+
+	stored, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	compiled := make([]*compiledUsernameRule, 0, len(stored))
+	for _, rule := range stored {
+		c, err := compileUsernameRule(rule)
+		if err != nil {
+			log.Printf("[UsernameRules] Skipping rule %s: %v", rule.ID, err)
+			continue
+		}
+		compiled = append(compiled, c)
+	}
+
+	s.mu.Lock()
+	s.rules = compiled
+	s.expires = now.Add(usernameRuleCacheTTL)
+	s.mu.Unlock()
+	return compiled, nil
+}
+
+// compiledUsernameRule is a rule ready to match usernames
+type compiledUsernameRule struct {
+	kind    models.UsernameRuleKind
+	literal string
+	pattern *regexp.Regexp
+}
+
+func compileUsernameRule(rule *models.UsernameRule) (*compiledUsernameRule, error) {
+	c := &compiledUsernameRule{kind: rule.Kind}
+	if !rule.Regex {
+		c.literal = strings.ToLower(rule.Pattern)
+		return c, nil
+	}
+	pattern, err := regexp.Compile(`(?i)` + rule.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	c.pattern = pattern
+	return c, nil
+}
+
+func (r *compiledUsernameRule) match(name string) bool {
+	if r.pattern != nil {
+		return r.pattern.MatchString(name)
+	}
+	return strings.ToLower(name) == r.literal
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeUsernameRuleRepository struct {
+	rules []*models.UsernameRule
+	lists int
+}
+
+func (f *fakeUsernameRuleRepository) List(ctx context.Context) ([]*models.UsernameRule, error) {
+	f.lists++
+	return append([]*models.UsernameRule{}, f.rules...), nil
+}
+
+func (f *fakeUsernameRuleRepository) Create(ctx context.Context, rule *models.UsernameRule) error {
+	for _, existing := range f.rules {
+		if existing.Kind == rule.Kind && existing.Regex == rule.Regex && strings.EqualFold(existing.Pattern, rule.Pattern) {
+			return ErrUsernameRuleExists
+		}
+	}
+	f.rules = append(f.rules, rule)
+	return nil
+}
+
+func (f *fakeUsernameRuleRepository) Delete(ctx context.Context, id uuid.UUID) (bool, error) {
+	for i, rule := range f.rules {
+		if rule.ID == id {
+			f.rules = append(f.rules[:i], f.rules[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func newUsernameRuleTest(t *testing.T, rules ...models.CreateUsernameRuleRequest) (*UsernameRuleService, *fakeUsernameRuleRepository) {
+	repo := &fakeUsernameRuleRepository{}
+	service := NewUsernameRuleService(repo)
+	for _, req := range rules {
+		_, err := service.CreateRule(context.Background(), uuid.New(), &req)
+		require.NoError(t, err)
+	}
+	return service, repo
+}
+
+func TestUsernameRuleCheck(t *testing.T) {
+	service, _ := newUsernameRuleTest(t,
+		models.CreateUsernameRuleRequest{Kind: models.UsernameRuleReserved, Pattern: "admin"},
+		models.CreateUsernameRuleRequest{Kind: models.UsernameRuleReserved, Pattern: `^hearth`, Regex: true},
+		models.CreateUsernameRuleRequest{Kind: models.UsernameRuleBanned, Pattern: `badword`, Regex: true},
+	)
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		staff bool
+		err   error
+	}{
+		{"Admin", false, ErrUsernameReserved},
+		{"admin", true, nil},
+		{"admin2", false, nil},
+		{"HearthTeam", false, ErrUsernameReserved},
+		{"my_hearth", false, nil},
+		{"xBadWordx", false, ErrUsernameBanned},
+		{"xbadwordx", true, ErrUsernameBanned},
+		{"alice", false, nil},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.err, service.Check(ctx, tt.name, tt.staff), tt.name)
+	}
+}
+
+func TestUsernameRuleCheck_Cache(t *testing.T) {
+	service, repo := newUsernameRuleTest(t)
+	ctx := context.Background()
+
+	require.NoError(t, service.Check(ctx, "admin", false))
+	require.NoError(t, service.Check(ctx, "admin", false))
+	assert.Equal(t, 1, repo.lists, "the second check is cached")
+
+	rule, err := service.CreateRule(ctx, uuid.New(), &models.CreateUsernameRuleRequest{Kind: models.UsernameRuleReserved, Pattern: "admin"})
+	require.NoError(t, err)
+	assert.Equal(t, ErrUsernameReserved, service.Check(ctx, "admin", false), "new rules apply straight away")
+
+	require.NoError(t, service.DeleteRule(ctx, rule.ID))
+	assert.NoError(t, service.Check(ctx, "admin", false))
+	assert.ErrorIs(t, service.DeleteRule(ctx, rule.ID), ErrUsernameRuleNotFound)
+}
+
+func TestUsernameRuleCreate_Validation(t *testing.T) {
+	service, repo := newUsernameRuleTest(t)
+	ctx := context.Background()
+
+	for _, req := range []models.CreateUsernameRuleRequest{
+		{Kind: "blocked", Pattern: "admin"},
+		{Kind: models.UsernameRuleBanned, Pattern: "  "},
+		{Kind: models.UsernameRuleBanned, Pattern: "no spaces"},
+		{Kind: models.UsernameRuleBanned, Pattern: "(unclosed", Regex: true},
+		{Kind: models.UsernameRuleBanned, Pattern: strings.Repeat("a", maxUsernameRulePattern+1), Regex: true},
+	} {
+		_, err := service.CreateRule(ctx, uuid.New(), &req)
+		assert.ErrorIs(t, err, ErrInvalidUsernameRule, req.Pattern)
+	}
+	assert.Empty(t, repo.rules)
+
+	reason := "  trademark  "
+	creatorID := uuid.New()
+	rule, err := service.CreateRule(ctx, creatorID, &models.CreateUsernameRuleRequest{Kind: models.UsernameRuleReserved, Pattern: " Hearth ", Reason: &reason})
+	require.NoError(t, err)
+	assert.Equal(t, "Hearth", rule.Pattern)
+	assert.Equal(t, "trademark", *rule.Reason)
+	assert.Equal(t, creatorID, *rule.CreatorID)
+
+	_, err = service.CreateRule(ctx, creatorID, &models.CreateUsernameRuleRequest{Kind: models.UsernameRuleReserved, Pattern: "hearth"})
+	assert.ErrorIs(t, err, ErrUsernameRuleExists)
+}
+
+func TestUpdateUser_UsernameRules(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Username: "Admin_old"}
+	service, _, usernames := setupUsernameTest(user)
+	rules, _ := newUsernameRuleTest(t,
+		models.CreateUsernameRuleRequest{Kind: models.UsernameRuleReserved, Pattern: "admin"},
+		models.CreateUsernameRuleRequest{Kind: models.UsernameRuleBanned, Pattern: `badword`, Regex: true},
+		models.CreateUsernameRuleRequest{Kind: models.UsernameRuleBanned, Pattern: `old$`, Regex: true},
+	)
+	service.SetUsernamePolicy(rules)
+	ctx := context.Background()
+
+	reserved, banned := "admin", "badword1"
+	_, err := service.UpdateUser(ctx, user.ID, &models.UserUpdate{Username: &reserved})
+	assert.ErrorIs(t, err, ErrUsernameReserved)
+	_, err = service.UpdateUser(ctx, user.ID, &models.UserUpdate{Username: &banned})
+	assert.ErrorIs(t, err, ErrUsernameBanned)
+	assert.Empty(t, usernames.renamed)
+
+	// Users keep names that were allowed when they took them
+	recased := "admin_old"
+	_, err = service.UpdateUser(ctx, user.ID, &models.UserUpdate{Username: &recased})
+	assert.NoError(t, err)
+
+	user.Flags = models.UserFlagStaff
+	_, err = service.UpdateUser(ctx, user.ID, &models.UserUpdate{Username: &reserved})
+	assert.NoError(t, err, "staff can take reserved usernames")
+	assert.Equal(t, []string{"admin"}, usernames.renamed)
+}
+
+func TestCheckUsername_UsernameRules(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Username: "alice"}
+	service, repo, _ := setupUsernameTest(user)
+	rules, _ := newUsernameRuleTest(t,
+		models.CreateUsernameRuleRequest{Kind: models.UsernameRuleReserved, Pattern: "admin"},
+		models.CreateUsernameRuleRequest{Kind: models.UsernameRuleReserved, Pattern: "admin_"},
+		models.CreateUsernameRuleRequest{Kind: models.UsernameRuleBanned, Pattern: `badword`, Regex: true},
+	)
+	service.SetUsernamePolicy(rules)
+	ctx := context.Background()
+	repo.On("GetByUsername", ctx, "admin").Return(nil, nil)
+	repo.On("GetByUsername", ctx, "badword").Return(nil, nil)
+
+	result, err := service.CheckUsername(ctx, user.ID, "admin")
+	require.NoError(t, err)
+	assert.False(t, result.Available)
+	assert.Equal(t, "reserved", result.Reason)
+	assert.NotEmpty(t, result.Suggestions)
+	assert.NotContains(t, result.Suggestions, "admin_", "suggestions follow the rules too")
+
+	result, err = service.CheckUsername(ctx, user.ID, "badword")
+	require.NoError(t, err)
+	assert.Equal(t, "banned", result.Reason)
+	assert.Empty(t, result.Suggestions)
+}
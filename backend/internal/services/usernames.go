@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// usernamePattern is what usernames may look like. They are unique
+// regardless of case.
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.]{2,32}$`)
+
+const (
+	maxUsernameLength      = 32
+	maxUsernameSuggestions = 5
+	usernameHistoryLimit   = 20
+)
+
+// UsernameRepository keeps usernames and their history.
+// postgres.UserRepository implements it.
+type UsernameRepository interface {
+	// ChangeUsername renames the user, records the old name and clears
+	// UserFlagUsernameReset. It returns ErrUsernameTaken if another account
+	// has the new name.
+	ChangeUsername(ctx context.Context, userID uuid.UUID, oldName, newName string, at time.Time) error
+	GetUsernameHistory(ctx context.Context, userID uuid.UUID, limit int) ([]*models.UsernameChange, error)
+	// TakenUsernames returns which of the names an account already has, lowercased
+	TakenUsernames(ctx context.Context, names []string) ([]string, error)
+}
+
+// UsernamePolicy decides which valid usernames may be claimed.
+// UsernameRuleService implements it.
+type UsernamePolicy interface {
+	// Check returns ErrUsernameReserved or ErrUsernameBanned if the
+	// username can't be claimed
+	Check(ctx context.Context, name string, staff bool) error
+}
+
+// validateUsername checks a username against the naming rules
+func validateUsername(name string) error {
+	if !usernamePattern.MatchString(name) {
+		return ErrInvalidUsername
+	}
+	return nil
+}
+
+// SetUsernameRepository enables username history, change cooldowns and
+// availability checks. A cooldown of 0 lets users rename at any time.
+func (s *UserService) SetUsernameRepository(repo UsernameRepository, cooldown time.Duration) {
+	s.usernames = repo
+	s.usernameCooldown = cooldown
+}
+
+// SetUsernamePolicy enforces reserved and banned usernames on renames
+func (s *UserService) SetUsernamePolicy(policy UsernamePolicy) {
+	s.usernamePolicy = policy
+}
+
+// usernameRetryAt returns when the user may next change their username, or
+// the zero time if they may now
+func (s *UserService) usernameRetryAt(user *models.User, now time.Time) time.Time {
+	if s.usernameCooldown <= 0 || user.UsernameChangedAt == nil || user.Flags&models.UserFlagUsernameReset != 0 {
+		return time.Time{}
+	}
+	retryAt := user.UsernameChangedAt.Add(s.usernameCooldown)
+	if !retryAt.After(now) {
+		return time.Time{}
+	}
+	return retryAt
+}
+
+// changeUsername checks and applies a username change for UpdateUser
+func (s *UserService) changeUsername(ctx context.Context, user *models.User, name string) error {
+	if err := validateUsername(name); err != nil {
+		return err
+	}
+	// Fixing the case of a username isn't a new handle, so it skips the
+	// rules, cooldown and history
+	if strings.EqualFold(name, user.Username) {
+		user.Username = name
+		return nil
+	}
+	if s.usernamePolicy != nil {
+		if err := s.usernamePolicy.Check(ctx, name, user.Flags&models.UserFlagStaff != 0); err != nil {
+			return err
+		}
+	}
+
+	if s.usernames == nil {
+		existing, _ := s.repo.GetByUsername(ctx, name)
+		if existing != nil && existing.ID != user.ID {
+			return ErrUsernameTaken
+		}
+		user.Username = name
+		return nil
+	}
+
+	now := time.Now()
+	if retryAt := s.usernameRetryAt(user, now); !retryAt.IsZero() {
+		return &UsernameCooldownError{RetryAt: retryAt}
+	}
+	if err := s.usernames.ChangeUsername(ctx, user.ID, user.Username, name, now); err != nil {
+		return err
+	}
+	user.Username = name
+	user.UsernameChangedAt = &now
+	user.Flags &^= models.UserFlagUsernameReset
+	return nil
+}
+
+// CheckUsername says whether the requester could take a username, and
+// suggests free ones when they can't. Their own username counts as free.
+func (s *UserService) CheckUsername(ctx context.Context, requesterID uuid.UUID, name string) (*models.UsernameAvailability, error) {
+	staff := false
+	if s.usernamePolicy != nil {
+		requester, err := s.GetUser(ctx, requesterID)
+		if err != nil {
+			return nil, err
+		}
+		staff = requester.Flags&models.UserFlagStaff != 0
+	}
+
+	result := &models.UsernameAvailability{Username: name}
+	if err := validateUsername(name); err != nil {
+		result.Reason = "invalid"
+		name = usernameBase(name)
+	} else {
+		existing, err := s.repo.GetByUsername(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil && existing.ID == requesterID {
+			result.Available = true
+			return result, nil
+		}
+		reason, err := s.usernameRuleReason(ctx, name, staff)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case reason != "":
+			result.Reason = reason
+		case existing != nil:
+			result.Reason = "taken"
+		default:
+			result.Available = true
+			return result, nil
+		}
+	}
+
+	if s.usernames == nil || name == "" {
+		return result, nil
+	}
+	suggestions, err := s.suggestUsernames(ctx, name, staff)
+	if err != nil {
+		return nil, err
+	}
+	result.Suggestions = suggestions
+	return result, nil
+}
+
+// usernameRuleReason returns "reserved" or "banned" if the username policy
+// stops the name being claimed
+func (s *UserService) usernameRuleReason(ctx context.Context, name string, staff bool) (string, error) {
+	if s.usernamePolicy == nil {
+		return "", nil
+	}
+	switch err := s.usernamePolicy.Check(ctx, name, staff); err {
+	case nil:
+		return "", nil
+	case ErrUsernameReserved:
+		return "reserved", nil
+	case ErrUsernameBanned:
+		return "banned", nil
+	default:
+		return "", err
+	}
+}
+
+// suggestUsernames returns free variations of a username
+func (s *UserService) suggestUsernames(ctx context.Context, base string, staff bool) ([]string, error) {
+	candidates := []string{}
+	seen := map[string]bool{}
+	add := func(suffix string) {
+		trimmed := base
+		if len(trimmed)+len(suffix) > maxUsernameLength {
+			trimmed = trimmed[:maxUsernameLength-len(suffix)]
+		}
+		candidate := trimmed + suffix
+		if !seen[strings.ToLower(candidate)] && validateUsername(candidate) == nil {
+			seen[strings.ToLower(candidate)] = true
+			candidates = append(candidates, candidate)
+		}
+	}
+	add("_")
+	add(".")
+	for i := 0; i < 8; i++ {
+		add(strconv.Itoa(rand.Intn(9999) + 1))
+	}
+
+	taken, err := s.usernames.TakenUsernames(ctx, candidates)
+	if err != nil {
+		return nil, err
+	}
+	takenSet := make(map[string]bool, len(taken))
+	for _, name := range taken {
+		takenSet[name] = true
+	}
+
+	suggestions := []string{}
+	for _, candidate := range candidates {
+		if takenSet[strings.ToLower(candidate)] {
+			continue
+		}
+		reason, err := s.usernameRuleReason(ctx, candidate, staff)
+		if err != nil {
+			return nil, err
+		}
+		if reason == "" {
+			suggestions = append(suggestions, candidate)
+		}
+		if len(suggestions) == maxUsernameSuggestions {
+			break
+		}
+	}
+	return suggestions, nil
+}
+
+// usernameBase turns an invalid username into a valid starting point for
+// suggestions, the way existing accounts were migrated
+func usernameBase(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if b.Len() == maxUsernameLength {
+			break
+		}
+		if r < 128 && (r == '_' || r == '.' || r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z') {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	base := b.String()
+	if strings.Trim(base, "_") == "" {
+		return ""
+	}
+	for len(base) < 2 {
+		base += "_"
+	}
+	return base
+}
+
+// GetUsernameHistory returns the user's previous usernames, newest first,
+// and when they may next change it. The retry time is zero if they may now.
+func (s *UserService) GetUsernameHistory(ctx context.Context, userID uuid.UUID) ([]*models.UsernameChange, time.Time, error) {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if s.usernames == nil {
+		return []*models.UsernameChange{}, time.Time{}, nil
+	}
+	history, err := s.usernames.GetUsernameHistory(ctx, userID, usernameHistoryLimit)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return history, s.usernameRetryAt(user, time.Now()), nil
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeUsernameRepository struct {
+	taken   map[string]bool
+	changes []models.UsernameChange
+	renamed []string
+}
+
+func (f *fakeUsernameRepository) ChangeUsername(ctx context.Context, userID uuid.UUID, oldName, newName string, at time.Time) error {
+	if f.taken[strings.ToLower(newName)] {
+		return ErrUsernameTaken
+	}
+	f.renamed = append(f.renamed, newName)
+	f.changes = append([]models.UsernameChange{{Username: oldName, ChangedAt: at}}, f.changes...)
+	return nil
+}
+
+func (f *fakeUsernameRepository) GetUsernameHistory(ctx context.Context, userID uuid.UUID, limit int) ([]*models.UsernameChange, error) {
+	history := []*models.UsernameChange{}
+	for i := range f.changes {
+		history = append(history, &f.changes[i])
+	}
+	return history, nil
+}
+
+func (f *fakeUsernameRepository) TakenUsernames(ctx context.Context, names []string) ([]string, error) {
+	taken := []string{}
+	for _, name := range names {
+		if f.taken[strings.ToLower(name)] {
+			taken = append(taken, strings.ToLower(name))
+		}
+	}
+	return taken, nil
+}
+
+func setupUsernameTest(user *models.User) (*UserService, *MockUserRepository, *fakeUsernameRepository) {
+	service, repo, cache, eventBus := setupUserService()
+	usernames := &fakeUsernameRepository{taken: map[string]bool{}}
+	service.SetUsernameRepository(usernames, 7*24*time.Hour)
+
+	repo.On("GetByID", mock.Anything, user.ID).Return(user, nil)
+	repo.On("Update", mock.Anything, mock.Anything).Return(nil)
+	cache.On("GetUser", mock.Anything, user.ID).Return(nil, nil)
+	cache.On("SetUser", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	cache.On("DeleteUser", mock.Anything, user.ID).Return(nil)
+	eventBus.On("Publish", mock.Anything, mock.Anything).Return()
+	return service, repo, usernames
+}
+
+func TestUpdateUser_ChangesUsername(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Username: "alice"}
+	service, _, usernames := setupUsernameTest(user)
+
+	name := "Alice.Smith"
+	updated, err := service.UpdateUser(context.Background(), user.ID, &models.UserUpdate{Username: &name})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Alice.Smith", updated.Username)
+	require.NotNil(t, updated.UsernameChangedAt)
+	assert.Equal(t, []string{"Alice.Smith"}, usernames.renamed)
+	assert.Equal(t, "alice", usernames.changes[0].Username)
+}
+
+func TestUpdateUser_InvalidUsername(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Username: "alice"}
+	service, _, usernames := setupUsernameTest(user)
+
+	for _, name := range []string{"a", "has space", "émile", strings.Repeat("a", 33), "al#1234"} {
+		_, err := service.UpdateUser(context.Background(), user.ID, &models.UserUpdate{Username: &name})
+		assert.ErrorIs(t, err, ErrInvalidUsername, name)
+	}
+	assert.Empty(t, usernames.renamed)
+}
+
+func TestUpdateUser_UsernameTakenIgnoresCase(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Username: "alice"}
+	service, _, usernames := setupUsernameTest(user)
+	usernames.taken["bob"] = true
+
+	name := "BOB"
+	_, err := service.UpdateUser(context.Background(), user.ID, &models.UserUpdate{Username: &name})
+
+	assert.ErrorIs(t, err, ErrUsernameTaken)
+}
+
+func TestUpdateUser_UsernameCooldown(t *testing.T) {
+	changedAt := time.Now().Add(-24 * time.Hour)
+	user := &models.User{ID: uuid.New(), Username: "alice", UsernameChangedAt: &changedAt}
+	service, _, usernames := setupUsernameTest(user)
+
+	name := "alice2"
+	_, err := service.UpdateUser(context.Background(), user.ID, &models.UserUpdate{Username: &name})
+
+	var cooldown *UsernameCooldownError
+	require.True(t, errors.As(err, &cooldown))
+	assert.ErrorIs(t, err, ErrUsernameCooldown)
+	assert.WithinDuration(t, changedAt.Add(7*24*time.Hour), cooldown.RetryAt, time.Second)
+	assert.Empty(t, usernames.renamed)
+
+	// Other fields can still be changed without touching the username
+	bio := "hi"
+	same := "alice"
+	_, err = service.UpdateUser(context.Background(), user.ID, &models.UserUpdate{Username: &same, Bio: &bio})
+	assert.NoError(t, err)
+
+	// and so can the case of the username
+	recased := "Alice"
+	updated, err := service.UpdateUser(context.Background(), user.ID, &models.UserUpdate{Username: &recased})
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", updated.Username)
+	assert.Empty(t, usernames.renamed)
+}
+
+func TestUpdateUser_UsernameResetSkipsCooldown(t *testing.T) {
+	changedAt := time.Now().Add(-time.Hour)
+	user := &models.User{ID: uuid.New(), Username: "alice_1a2b3c4d", UsernameChangedAt: &changedAt, Flags: models.UserFlagUsernameReset}
+	service, _, usernames := setupUsernameTest(user)
+
+	name := "alice_again"
+	updated, err := service.UpdateUser(context.Background(), user.ID, &models.UserUpdate{Username: &name})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice_again"}, usernames.renamed)
+	assert.Zero(t, updated.Flags&models.UserFlagUsernameReset)
+}
+
+func TestCheckUsername(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Username: "alice"}
+	service, repo, usernames := setupUsernameTest(user)
+	ctx := context.Background()
+	usernames.taken["bob"] = true
+	usernames.taken["bob_"] = true
+
+	repo.On("GetByUsername", mock.Anything, "bob").Return(&models.User{ID: uuid.New(), Username: "bob"}, nil)
+	repo.On("GetByUsername", mock.Anything, "Alice").Return(user, nil)
+	repo.On("GetByUsername", mock.Anything, "carol").Return(nil, nil)
+
+	result, err := service.CheckUsername(ctx, user.ID, "bob")
+	require.NoError(t, err)
+	assert.False(t, result.Available)
+	assert.Equal(t, "taken", result.Reason)
+	assert.NotEmpty(t, result.Suggestions)
+	assert.LessOrEqual(t, len(result.Suggestions), maxUsernameSuggestions)
+	assert.NotContains(t, result.Suggestions, "bob_")
+	assert.Contains(t, result.Suggestions, "bob.")
+
+	result, err = service.CheckUsername(ctx, user.ID, "Alice")
+	require.NoError(t, err)
+	assert.True(t, result.Available, "the requester's own username is free to them")
+
+	result, err = service.CheckUsername(ctx, user.ID, "carol")
+	require.NoError(t, err)
+	assert.True(t, result.Available)
+	assert.Empty(t, result.Suggestions)
+
+	result, err = service.CheckUsername(ctx, user.ID, "carol smith")
+	require.NoError(t, err)
+	assert.False(t, result.Available)
+	assert.Equal(t, "invalid", result.Reason)
+	for _, suggestion := range result.Suggestions {
+		assert.True(t, strings.HasPrefix(suggestion, "carol_smith"), suggestion)
+		assert.NoError(t, validateUsername(suggestion))
+	}
+}
+
+func TestGetUsernameHistory(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Username: "alice"}
+	service, _, _ := setupUsernameTest(user)
+	ctx := context.Background()
+
+	history, retryAt, err := service.GetUsernameHistory(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+	assert.True(t, retryAt.IsZero())
+
+	name := "alice2"
+	_, err = service.UpdateUser(ctx, user.ID, &models.UserUpdate{Username: &name})
+	require.NoError(t, err)
+
+	history, retryAt, err = service.GetUsernameHistory(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "alice", history[0].Username)
+	assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), retryAt, time.Minute)
+}
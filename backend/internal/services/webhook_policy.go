@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	maxWebhookPolicyRoles   = 25
+	maxWebhookPolicyDomains = 50
+)
+
+// WebhookPolicyRepository stores servers' and channels' webhook policies.
+// The Postgres WebhookPolicyRepository implements it.
+type WebhookPolicyRepository interface {
+	// Get returns nil if there's no policy. A nil channelID is the server's
+	// own policy.
+	Get(ctx context.Context, serverID uuid.UUID, channelID *uuid.UUID) (*models.WebhookPolicy, error)
+	// Save creates or replaces the policy
+	Save(ctx context.Context, policy *models.WebhookPolicy) error
+	Delete(ctx context.Context, serverID uuid.UUID, channelID *uuid.UUID) error
+}
+
+// WebhookPolicies checks webhooks against their channel's webhook policy.
+// The WebhookPolicyService implements it.
+type WebhookPolicies interface {
+	CheckCreator(ctx context.Context, serverID, channelID, userID uuid.UUID) error
+	CheckURL(ctx context.Context, serverID, channelID uuid.UUID, rawURL string) error
+}
+
+// SetPolicies checks webhooks against their channel's webhook policy when
+// they're created, changed and executed, so a webhook whose creator lost
+// the roles allowed to create it stops posting
+func (s *WebhookService) SetPolicies(policies WebhookPolicies) {
+	s.policies = policies
+}
+
+// checkPolicy checks that creatorID may have a webhook in the channel and
+// that the webhook's URLs point at allowed domains
+func (s *WebhookService) checkPolicy(ctx context.Context, serverID, channelID, creatorID uuid.UUID, urls ...*string) error {
+	if s.policies == nil {
+		return nil
+	}
+	if err := s.policies.CheckCreator(ctx, serverID, channelID, creatorID); err != nil {
+		return err
+	}
+	for _, u := range urls {
+		if u == nil || *u == "" {
+			continue
+		}
+		if err := s.policies.CheckURL(ctx, serverID, channelID, *u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WebhookPolicyService manages which roles can create a server's webhooks
+// and which domains they may point at. A channel's policy replaces the
+// server's for webhooks in that channel.
+type WebhookPolicyService struct {
+	repo        WebhookPolicyRepository
+	channelRepo ChannelRepository
+	serverRepo  ServerRepository
+	roleRepo    RoleRepository
+	now         func() time.Time
+}
+
+// NewWebhookPolicyService creates a new webhook policy service
+func NewWebhookPolicyService(
+	repo WebhookPolicyRepository,
+	channelRepo ChannelRepository,
+	serverRepo ServerRepository,
+	roleRepo RoleRepository,
+) *WebhookPolicyService {
+	return &WebhookPolicyService{
+		repo:        repo,
+		channelRepo: channelRepo,
+		serverRepo:  serverRepo,
+		roleRepo:    roleRepo,
+		now:         time.Now,
+	}
+}
+
+// GetServerPolicy returns a server's webhook policy. Requires
+// PermManageWebhooks or PermManageServer.
+func (s *WebhookPolicyService) GetServerPolicy(ctx context.Context, serverID, requesterID uuid.UUID) (*models.WebhookPolicy, error) {
+	if err := s.authorize(ctx, serverID, requesterID, models.PermManageWebhooks|models.PermManageServer); err != nil {
+		return nil, err
+	}
+	return s.get(ctx, serverID, nil)
+}
+
+// UpdateServerPolicy replaces a server's webhook policy. Requires
+// PermManageServer.
+func (s *WebhookPolicyService) UpdateServerPolicy(ctx context.Context, serverID, requesterID uuid.UUID, req *models.UpdateWebhookPolicyRequest) (*models.WebhookPolicy, error) {
+	if err := s.authorize(ctx, serverID, requesterID, models.PermManageServer); err != nil {
+		return nil, err
+	}
+	return s.save(ctx, serverID, nil, requesterID, req)
+}
+
+// DeleteServerPolicy lifts a server's webhook policy. Channels keep their
+// own. Requires PermManageServer.
+func (s *WebhookPolicyService) DeleteServerPolicy(ctx context.Context, serverID, requesterID uuid.UUID) error {
+	if err := s.authorize(ctx, serverID, requesterID, models.PermManageServer); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, serverID, nil)
+}
+
+// GetChannelPolicy returns a channel's own webhook policy. Requires
+// PermManageWebhooks or PermManageServer.
+func (s *WebhookPolicyService) GetChannelPolicy(ctx context.Context, channelID, requesterID uuid.UUID) (*models.WebhookPolicy, error) {
+	serverID, err := s.channelServer(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, serverID, requesterID, models.PermManageWebhooks|models.PermManageServer); err != nil {
+		return nil, err
+	}
+	return s.get(ctx, serverID, &channelID)
+}
+
+// UpdateChannelPolicy replaces a channel's webhook policy, which replaces
+// the server's for webhooks in the channel. Requires PermManageServer.
+func (s *WebhookPolicyService) UpdateChannelPolicy(ctx context.Context, channelID, requesterID uuid.UUID, req *models.UpdateWebhookPolicyRequest) (*models.WebhookPolicy, error) {
+	serverID, err := s.channelServer(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, serverID, requesterID, models.PermManageServer); err != nil {
+		return nil, err
+	}
+	return s.save(ctx, serverID, &channelID, requesterID, req)
+}
+
+// DeleteChannelPolicy drops a channel's webhook policy, leaving the
+// server's to apply. Requires PermManageServer.
+func (s *WebhookPolicyService) DeleteChannelPolicy(ctx context.Context, channelID, requesterID uuid.UUID) error {
+	serverID, err := s.channelServer(ctx, channelID)
+	if err != nil {
+		return err
+	}
+	if err := s.authorize(ctx, serverID, requesterID, models.PermManageServer); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, serverID, &channelID)
+}
+
+// CheckCreator returns ErrWebhookCreatorNotAllowed unless userID has one
+// of the roles the channel's policy lets create webhooks. Owners and
+// administrators always may.
+func (s *WebhookPolicyService) CheckCreator(ctx context.Context, serverID, channelID, userID uuid.UUID) error {
+	policy, err := s.effective(ctx, serverID, channelID)
+	if err != nil || policy == nil || len(policy.CreatorRoleIDs) == 0 {
+		return err
+	}
+
+	server, err := s.serverRepo.GetByID(ctx, serverID)
+	if err != nil {
+		return err
+	}
+	if server == nil {
+		return ErrServerNotFound
+	}
+	if memberHasPermission(ctx, s.serverRepo, s.roleRepo, server, userID, models.PermAdministrator) {
+		return nil
+	}
+	roles, err := s.roleRepo.GetMemberRoles(ctx, serverID, userID)
+	if err != nil {
+		return err
+	}
+	if !policy.AllowsCreator(roleIDs(roles)) {
+		return ErrWebhookCreatorNotAllowed
+	}
+	return nil
+}
+
+// CheckURL returns ErrWebhookURLNotAllowed if the channel's policy doesn't
+// allow rawURL's domain
+func (s *WebhookPolicyService) CheckURL(ctx context.Context, serverID, channelID uuid.UUID, rawURL string) error {
+	policy, err := s.effective(ctx, serverID, channelID)
+	if err != nil || policy == nil {
+		return err
+	}
+	if !policy.AllowsURL(rawURL) {
+		return ErrWebhookURLNotAllowed
+	}
+	return nil
+}
+
+// effective returns the policy webhooks in the channel follow: its own,
+// else the server's, else nil
+func (s *WebhookPolicyService) effective(ctx context.Context, serverID, channelID uuid.UUID) (*models.WebhookPolicy, error) {
+	policy, err := s.repo.Get(ctx, serverID, &channelID)
+	if err != nil || policy != nil {
+		return policy, err
+	}
+	return s.repo.Get(ctx, serverID, nil)
+}
+
+func (s *WebhookPolicyService) get(ctx context.Context, serverID uuid.UUID, channelID *uuid.UUID) (*models.WebhookPolicy, error) {
+	policy, err := s.repo.Get(ctx, serverID, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, ErrWebhookPolicyNotFound
+	}
+	return policy, nil
+}
+
+func (s *WebhookPolicyService) save(ctx context.Context, serverID uuid.UUID, channelID *uuid.UUID, requesterID uuid.UUID, req *models.UpdateWebhookPolicyRequest) (*models.WebhookPolicy, error) {
+	policy := &models.WebhookPolicy{
+		ServerID:  serverID,
+		ChannelID: channelID,
+		UpdatedBy: requesterID,
+		UpdatedAt: s.now(),
+	}
+	if err := s.normalize(ctx, policy, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Save(ctx, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// channelServer returns the server a channel belongs to. DMs have no
+// webhook policies.
+func (s *WebhookPolicyService) channelServer(ctx context.Context, channelID uuid.UUID) (uuid.UUID, error) {
+	channel, err := s.channelRepo.GetByID(ctx, channelID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if channel == nil || channel.ServerID == nil {
+		return uuid.Nil, ErrChannelNotFound
+	}
+	return *channel.ServerID, nil
+}
+
+// authorize checks that the requester has any of perm in the server
+func (s *WebhookPolicyService) authorize(ctx context.Context, serverID, requesterID uuid.UUID, perm int64) error {
+	server, err := s.serverRepo.GetByID(ctx, serverID)
+	if err != nil {
+		return err
+	}
+	if server == nil {
+		return ErrServerNotFound
+	}
+	if !memberHasPermission(ctx, s.serverRepo, s.roleRepo, server, requesterID, perm) {
+		return ErrCannotManageWebhookPolicy
+	}
+	return nil
+}
+
+func invalidWebhookPolicy(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidWebhookPolicy, fmt.Sprintf(format, args...))
+}
+
+// normalize checks that the policy's roles belong to its server and keeps
+// each domain once, lowercased, without a leading "*." or trailing dot
+func (s *WebhookPolicyService) normalize(ctx context.Context, policy *models.WebhookPolicy, req *models.UpdateWebhookPolicyRequest) error {
+	if len(req.CreatorRoleIDs) > maxWebhookPolicyRoles {
+		return invalidWebhookPolicy("at most %d creator roles", maxWebhookPolicyRoles)
+	}
+	if len(req.AllowedDomains) > maxWebhookPolicyDomains {
+		return invalidWebhookPolicy("at most %d allowed domains", maxWebhookPolicyDomains)
+	}
+
+	policy.CreatorRoleIDs = []uuid.UUID{}
+	if len(req.CreatorRoleIDs) > 0 {
+		roles, err := s.roleRepo.GetByServerID(ctx, policy.ServerID)
+		if err != nil {
+			return err
+		}
+		serverRoles := make(map[uuid.UUID]bool, len(roles))
+		for _, role := range roles {
+			serverRoles[role.ID] = true
+		}
+		seen := make(map[uuid.UUID]bool, len(req.CreatorRoleIDs))
+		for _, id := range req.CreatorRoleIDs {
+			if !serverRoles[id] {
+				return invalidWebhookPolicy("role %s isn't in this server", id)
+			}
+			if !seen[id] {
+				seen[id] = true
+				policy.CreatorRoleIDs = append(policy.CreatorRoleIDs, id)
+			}
+		}
+	}
+
+	policy.AllowedDomains = []string{}
+	seen := make(map[string]bool, len(req.AllowedDomains))
+	for _, raw := range req.AllowedDomains {
+		domain := strings.TrimSuffix(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(raw)), "*."), ".")
+		if !validWebhookDomain(domain) {
+			return invalidWebhookPolicy("%q isn't a domain", raw)
+		}
+		if !seen[domain] {
+			seen[domain] = true
+			policy.AllowedDomains = append(policy.AllowedDomains, domain)
+		}
+	}
+	return nil
+}
+
+// validWebhookDomain reports whether domain is a hostname such as
+// "hooks.example.com", without a scheme, port or path
+func validWebhookDomain(domain string) bool {
+	if domain == "" || len(domain) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeWebhookPolicyRepository struct {
+	policies map[[2]uuid.UUID]*models.WebhookPolicy
+}
+
+func webhookPolicyKey(serverID uuid.UUID, channelID *uuid.UUID) [2]uuid.UUID {
+	if channelID == nil {
+		return [2]uuid.UUID{serverID, uuid.Nil}
+	}
+	return [2]uuid.UUID{serverID, *channelID}
+}
+
+func (f *fakeWebhookPolicyRepository) Get(ctx context.Context, serverID uuid.UUID, channelID *uuid.UUID) (*models.WebhookPolicy, error) {
+	policy, ok := f.policies[webhookPolicyKey(serverID, channelID)]
+	if !ok {
+		return nil, nil
+	}
+	copied := *policy
+	return &copied, nil
+}
+
+func (f *fakeWebhookPolicyRepository) Save(ctx context.Context, policy *models.WebhookPolicy) error {
+	copied := *policy
+	f.policies[webhookPolicyKey(policy.ServerID, policy.ChannelID)] = &copied
+	return nil
+}
+
+func (f *fakeWebhookPolicyRepository) Delete(ctx context.Context, serverID uuid.UUID, channelID *uuid.UUID) error {
+	delete(f.policies, webhookPolicyKey(serverID, channelID))
+	return nil
+}
+
+type webhookPolicyTest struct {
+	service *WebhookPolicyService
+	repo    *fakeWebhookPolicyRepository
+	server  *models.Server
+	channel *models.Channel
+
+	hooksRole *models.Role
+	builder   uuid.UUID // Has hooksRole
+	admin     uuid.UUID // Has an administrator role
+	member    uuid.UUID // Has no roles
+}
+
+func newWebhookPolicyTest() *webhookPolicyTest {
+	serverID := uuid.New()
+	f := &webhookPolicyTest{
+		repo:      &fakeWebhookPolicyRepository{policies: make(map[[2]uuid.UUID]*models.WebhookPolicy)},
+		server:    &models.Server{ID: serverID, OwnerID: uuid.New()},
+		channel:   &models.Channel{ID: uuid.New(), ServerID: &serverID, Type: models.ChannelTypeText},
+		hooksRole: &models.Role{ID: uuid.New(), ServerID: serverID, Name: "Integrations"},
+		builder:   uuid.New(),
+		admin:     uuid.New(),
+		member:    uuid.New(),
+	}
+	adminRole := &models.Role{ID: uuid.New(), ServerID: serverID, Name: "Admin", Permissions: models.PermAdministrator, Position: 1}
+
+	serverRepo := new(MockServerRepository)
+	channelRepo := new(MockChannelRepository)
+	roleRepo := new(MockRoleRepository)
+	serverRepo.On("GetByID", mock.Anything, serverID).Return(f.server, nil)
+	channelRepo.On("GetByID", mock.Anything, f.channel.ID).Return(f.channel, nil)
+	roleRepo.On("GetByServerID", mock.Anything, serverID).Return([]*models.Role{f.hooksRole, adminRole}, nil)
+	for userID, roles := range map[uuid.UUID][]*models.Role{
+		f.builder: {f.hooksRole},
+		f.admin:   {adminRole},
+		f.member:  {},
+	} {
+		serverRepo.On("GetMember", mock.Anything, serverID, userID).Return(&models.Member{ServerID: serverID, UserID: userID}, nil)
+		roleRepo.On("GetMemberRoles", mock.Anything, serverID, userID).Return(roles, nil)
+	}
+
+	f.service = NewWebhookPolicyService(f.repo, channelRepo, serverRepo, roleRepo)
+	return f
+}
+
+func TestWebhookPolicyService_UpdateServerPolicy(t *testing.T) {
+	f := newWebhookPolicyTest()
+	ctx := context.Background()
+
+	policy, err := f.service.UpdateServerPolicy(ctx, f.server.ID, f.server.OwnerID, &models.UpdateWebhookPolicyRequest{
+		CreatorRoleIDs: []uuid.UUID{f.hooksRole.ID, f.hooksRole.ID},
+		AllowedDomains: []string{" *.Example.COM. ", "example.com", "hooks.slack.com"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{f.hooksRole.ID}, policy.CreatorRoleIDs)
+	assert.Equal(t, []string{"example.com", "hooks.slack.com"}, policy.AllowedDomains)
+	assert.Equal(t, f.server.OwnerID, policy.UpdatedBy)
+
+	stored, err := f.service.GetServerPolicy(ctx, f.server.ID, f.admin)
+	require.NoError(t, err)
+	assert.Equal(t, policy.AllowedDomains, stored.AllowedDomains)
+
+	for _, req := range []*models.UpdateWebhookPolicyRequest{
+		{CreatorRoleIDs: []uuid.UUID{uuid.New()}},
+		{AllowedDomains: []string{"https://example.com"}},
+		{AllowedDomains: []string{"example.com:8080"}},
+		{AllowedDomains: []string{"-bad.example.com"}},
+	} {
+		_, err := f.service.UpdateServerPolicy(ctx, f.server.ID, f.server.OwnerID, req)
+		assert.ErrorIs(t, err, ErrInvalidWebhookPolicy)
+	}
+
+	_, err = f.service.UpdateServerPolicy(ctx, f.server.ID, f.builder, &models.UpdateWebhookPolicyRequest{})
+	assert.ErrorIs(t, err, ErrCannotManageWebhookPolicy)
+	_, err = f.service.GetServerPolicy(ctx, f.server.ID, f.member)
+	assert.ErrorIs(t, err, ErrCannotManageWebhookPolicy)
+}
+
+func TestWebhookPolicyService_CheckCreator(t *testing.T) {
+	f := newWebhookPolicyTest()
+	ctx := context.Background()
+
+	assert.NoError(t, f.service.CheckCreator(ctx, f.server.ID, f.channel.ID, f.member), "no policy lets anyone")
+
+	_, err := f.service.UpdateServerPolicy(ctx, f.server.ID, f.server.OwnerID, &models.UpdateWebhookPolicyRequest{
+		CreatorRoleIDs: []uuid.UUID{f.hooksRole.ID},
+	})
+	require.NoError(t, err)
+	assert.NoError(t, f.service.CheckCreator(ctx, f.server.ID, f.channel.ID, f.builder))
+	assert.NoError(t, f.service.CheckCreator(ctx, f.server.ID, f.channel.ID, f.admin))
+	assert.NoError(t, f.service.CheckCreator(ctx, f.server.ID, f.channel.ID, f.server.OwnerID))
+	assert.ErrorIs(t, f.service.CheckCreator(ctx, f.server.ID, f.channel.ID, f.member), ErrWebhookCreatorNotAllowed)
+	assert.ErrorIs(t, f.service.CheckCreator(ctx, f.server.ID, uuid.New(), f.member), ErrWebhookCreatorNotAllowed)
+
+	// The channel's policy replaces the server's there
+	_, err = f.service.UpdateChannelPolicy(ctx, f.channel.ID, f.server.OwnerID, &models.UpdateWebhookPolicyRequest{})
+	require.NoError(t, err)
+	assert.NoError(t, f.service.CheckCreator(ctx, f.server.ID, f.channel.ID, f.member))
+	assert.ErrorIs(t, f.service.CheckCreator(ctx, f.server.ID, uuid.New(), f.member), ErrWebhookCreatorNotAllowed)
+
+	require.NoError(t, f.service.DeleteChannelPolicy(ctx, f.channel.ID, f.server.OwnerID))
+	assert.ErrorIs(t, f.service.CheckCreator(ctx, f.server.ID, f.channel.ID, f.member), ErrWebhookCreatorNotAllowed)
+	_, err = f.service.GetChannelPolicy(ctx, f.channel.ID, f.server.OwnerID)
+	assert.ErrorIs(t, err, ErrWebhookPolicyNotFound)
+}
+
+func TestWebhookPolicyService_CheckURL(t *testing.T) {
+	f := newWebhookPolicyTest()
+	ctx := context.Background()
+	_, err := f.service.UpdateChannelPolicy(ctx, f.channel.ID, f.server.OwnerID, &models.UpdateWebhookPolicyRequest{
+		AllowedDomains: []string{"example.com"},
+	})
+	require.NoError(t, err)
+
+	for rawURL, allowed := range map[string]bool{
+		"https://example.com/a.png":         true,
+		"https://cdn.EXAMPLE.com/a.png":     true,
+		"a1b2c3":                            true,
+		"https://example.com.evil.net/x":    false,
+		"https://notexample.com/a.png":      false,
+		"//evil.net/a.png":                  false,
+		"javascript:alert(1)":               false,
+		"ftp://example.com/a.png":           false,
+		"https://user@evil.net/example.com": false,
+	} {
+		err := f.service.CheckURL(ctx, f.server.ID, f.channel.ID, rawURL)
+		if allowed {
+			assert.NoError(t, err, rawURL)
+		} else {
+			assert.ErrorIs(t, err, ErrWebhookURLNotAllowed, rawURL)
+		}
+	}
+	assert.NoError(t, f.service.CheckURL(ctx, f.server.ID, uuid.New(), "https://evil.net/x"), "other channels have no policy")
+}
+
+// fakeWebhookPolicies lets the allowed users create webhooks that point
+// at example.com
+type fakeWebhookPolicies map[uuid.UUID]bool
+
+func (f fakeWebhookPolicies) CheckCreator(ctx context.Context, serverID, channelID, userID uuid.UUID) error {
+	if !f[userID] {
+		return ErrWebhookCreatorNotAllowed
+	}
+	return nil
+}
+
+func (f fakeWebhookPolicies) CheckURL(ctx context.Context, serverID, channelID uuid.UUID, rawURL string) error {
+	if !(&models.WebhookPolicy{AllowedDomains: []string{"example.com"}}).AllowsURL(rawURL) {
+		return ErrWebhookURLNotAllowed
+	}
+	return nil
+}
+
+func TestWebhookService_CreateWebhook_Policy(t *testing.T) {
+	service, webhookRepo, channelRepo, serverRepo, _ := newTestWebhookService()
+	ctx := context.Background()
+	serverID := uuid.New()
+	channel := &models.Channel{ID: uuid.New(), ServerID: &serverID}
+	creatorID := uuid.New()
+	service.SetPolicies(fakeWebhookPolicies{})
+
+	channelRepo.On("GetByID", ctx, channel.ID).Return(channel, nil)
+	serverRepo.On("GetMember", ctx, serverID, creatorID).Return(&models.Member{ServerID: serverID, UserID: creatorID}, nil)
+
+	_, err := service.CreateWebhook(ctx, &CreateWebhookRequest{ChannelID: channel.ID, CreatorID: creatorID, Name: "CI"})
+	assert.Equal(t, ErrWebhookCreatorNotAllowed, err)
+
+	service.SetPolicies(fakeWebhookPolicies{creatorID: true})
+	avatar := "https://evil.net/pixel.png"
+	_, err = service.CreateWebhook(ctx, &CreateWebhookRequest{ChannelID: channel.ID, CreatorID: creatorID, Name: "CI", Avatar: &avatar})
+	assert.Equal(t, ErrWebhookURLNotAllowed, err)
+	webhookRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestWebhookService_ExecuteWebhook_Policy(t *testing.T) {
+	service, webhookRepo, _, _, eventBus := newTestWebhookService()
+	ctx := context.Background()
+	serverID := uuid.New()
+	creatorID := uuid.New()
+	token, _ := generateWebhookToken()
+	webhook := &models.Webhook{ID: uuid.New(), ChannelID: uuid.New(), ServerID: &serverID, CreatorID: &creatorID, Name: "CI", Token: token}
+	webhookRepo.On("GetByID", ctx, webhook.ID).Return(webhook, nil)
+	eventBus.On("Publish", "webhook.executed", mock.Anything).Return()
+
+	service.SetPolicies(fakeWebhookPolicies{creatorID: true})
+	_, err := service.ExecuteWebhook(ctx, webhook.ID, token, &ExecuteWebhookRequest{Content: "build passed"})
+	require.NoError(t, err)
+
+	avatarURL := "https://evil.net/pixel.png"
+	_, err = service.ExecuteWebhook(ctx, webhook.ID, token, &ExecuteWebhookRequest{Content: "build passed", AvatarURL: &avatarURL})
+	assert.Equal(t, ErrWebhookURLNotAllowed, err)
+
+	// The creator lost the roles allowed to create webhooks
+	service.SetPolicies(fakeWebhookPolicies{})
+	_, err = service.ExecuteWebhook(ctx, webhook.ID, token, &ExecuteWebhookRequest{Content: "build passed"})
+	assert.Equal(t, ErrWebhookCreatorNotAllowed, err)
+	eventBus.AssertNumberOfCalls(t, "Publish", 1)
+}
@@ -3,20 +3,37 @@ package services
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/hex"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
 	"hearth/internal/models"
 )
 
+// DefaultWebhookDedupeWindow is how long a provider message ID is
+// remembered. It covers the retry schedules of common webhook senders.
+const DefaultWebhookDedupeWindow = 24 * time.Hour
+
+// WebhookDedupeStore remembers which provider message IDs a webhook has
+// already delivered. RedisCache implements it.
+type WebhookDedupeStore interface {
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
 // WebhookService handles webhook-related business logic
 type WebhookService struct {
 	webhookRepo WebhookRepository
 	channelRepo ChannelRepository
 	serverRepo  ServerRepository
 	eventBus    EventBus
+
+	dedupe       WebhookDedupeStore
+	dedupeWindow time.Duration
+	policies     WebhookPolicies
 }
 
 // NewWebhookService creates a new webhook service
@@ -34,6 +51,16 @@ func NewWebhookService(
 	}
 }
 
+// SetDedupeStore enables dropping sender retries that carry a provider
+// message ID already delivered within window
+func (s *WebhookService) SetDedupeStore(store WebhookDedupeStore, window time.Duration) {
+	if window <= 0 {
+		window = DefaultWebhookDedupeWindow
+	}
+	s.dedupe = store
+	s.dedupeWindow = window
+}
+
 // CreateWebhookRequest represents a webhook creation request
 type CreateWebhookRequest struct {
 	ChannelID uuid.UUID
@@ -58,6 +85,9 @@ func (s *WebhookService) CreateWebhook(ctx context.Context, req *CreateWebhookRe
 			return nil, ErrNotServerMember
 		}
 		req.ServerID = *channel.ServerID
+		if err := s.checkPolicy(ctx, req.ServerID, req.ChannelID, req.CreatorID, req.Avatar); err != nil {
+			return nil, err
+		}
 	}
 
 	// TODO: Check MANAGE_WEBHOOKS permission
@@ -218,6 +248,13 @@ func (s *WebhookService) UpdateWebhook(ctx context.Context, webhookID uuid.UUID,
 		webhook.ChannelID = *req.ChannelID
 	}
 
+	// Moving a webhook or changing its avatar takes what creating it would
+	if channel.ServerID != nil && (req.ChannelID != nil || req.Avatar != nil) {
+		if err := s.checkPolicy(ctx, *channel.ServerID, webhook.ChannelID, requesterID, webhook.Avatar); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := s.webhookRepo.Update(ctx, webhook); err != nil {
 		return nil, err
 	}
@@ -272,12 +309,18 @@ type ExecuteWebhookRequest struct {
 	Username  *string
 	AvatarURL *string
 	TTS       bool
+
+	// ProviderMessageID is the sender's ID for this delivery. Retries with
+	// the same ID within the dedupe window are not posted again.
+	ProviderMessageID string
 }
 
-// ExecuteWebhook executes a webhook by sending a message
+// ExecuteWebhook executes a webhook by sending a message. A retried delivery
+// returns ErrDuplicateWebhookMessage along with a message carrying only the
+// ID and channel of the original post.
 func (s *WebhookService) ExecuteWebhook(ctx context.Context, webhookID uuid.UUID, token string, req *ExecuteWebhookRequest) (*models.Message, error) {
 	webhook, err := s.webhookRepo.GetByID(ctx, webhookID)
-	if err != nil {
+	if err != nil || webhook == nil {
 		return nil, ErrWebhookNotFound
 	}
 
@@ -291,6 +334,18 @@ func (s *WebhookService) ExecuteWebhook(ctx context.Context, webhookID uuid.UUID
 		return nil, ErrEmptyMessage
 	}
 
+	// The policy may have changed since the webhook was made. Webhooks
+	// whose creator is gone only deliver where anyone may create them.
+	if webhook.ServerID != nil {
+		creatorID := uuid.Nil
+		if webhook.CreatorID != nil {
+			creatorID = *webhook.CreatorID
+		}
+		if err := s.checkPolicy(ctx, *webhook.ServerID, webhook.ChannelID, creatorID, webhook.Avatar, req.AvatarURL); err != nil {
+			return nil, err
+		}
+	}
+
 	// TODO: Create actual message via MessageService
 	// For now, return a mock message
 	author := models.PublicUser{
@@ -311,6 +366,10 @@ func (s *WebhookService) ExecuteWebhook(ctx context.Context, webhookID uuid.UUID
 		CreatedAt: time.Now(),
 	}
 
+	if originalID, dup := s.claimDelivery(ctx, webhook.ID, req.ProviderMessageID, message.ID); dup {
+		return &models.Message{ID: originalID, ChannelID: webhook.ChannelID}, ErrDuplicateWebhookMessage
+	}
+
 	s.eventBus.Publish("webhook.executed", &WebhookExecutedEvent{
 		WebhookID: webhook.ID,
 		ChannelID: webhook.ChannelID,
@@ -322,6 +381,39 @@ func (s *WebhookService) ExecuteWebhook(ctx context.Context, webhookID uuid.UUID
 
 // Helper functions
 
+// claimDelivery records that messageID delivers providerID for the webhook.
+// If the delivery was already claimed it returns the original message ID.
+// Store errors let the message through: a rare double post beats a lost one.
+func (s *WebhookService) claimDelivery(ctx context.Context, webhookID uuid.UUID, providerID string, messageID uuid.UUID) (uuid.UUID, bool) {
+	if s.dedupe == nil || providerID == "" {
+		return uuid.Nil, false
+	}
+
+	key := webhookDedupeKey(webhookID, providerID)
+	claimed, err := s.dedupe.SetNX(ctx, key, []byte(messageID.String()), s.dedupeWindow)
+	if err != nil {
+		log.Printf("[WebhookService] dedupe store unavailable for webhook %s: %v", webhookID, err)
+		return uuid.Nil, false
+	}
+	if claimed {
+		return uuid.Nil, false
+	}
+
+	// The original ID is informational; a missing one still means duplicate
+	var originalID uuid.UUID
+	if data, err := s.dedupe.Get(ctx, key); err == nil {
+		originalID, _ = uuid.ParseBytes(data)
+	}
+	return originalID, true
+}
+
+// webhookDedupeKey hashes the provider ID so senders can't pick key contents
+// or length
+func webhookDedupeKey(webhookID uuid.UUID, providerID string) string {
+	sum := sha256.Sum256([]byte(providerID))
+	return "webhook:dedupe:" + webhookID.String() + ":" + hex.EncodeToString(sum[:])
+}
+
 func generateWebhookToken() (string, error) {
 	bytes := make([]byte, 32)
 	if _, err := rand.Read(bytes); err != nil {
@@ -1135,6 +1135,116 @@ func TestWebhookService_ExecuteWebhook_WithCustomUsername(t *testing.T) {
 	assert.Equal(t, customUsername, message.Author.Username)
 }
 
+// memoryDedupeStore is an in-memory WebhookDedupeStore
+type memoryDedupeStore struct {
+	entries map[string][]byte
+	ttls    map[string]time.Duration
+	err     error
+}
+
+func newMemoryDedupeStore() *memoryDedupeStore {
+	return &memoryDedupeStore{entries: make(map[string][]byte), ttls: make(map[string]time.Duration)}
+}
+
+func (s *memoryDedupeStore) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	if _, ok := s.entries[key]; ok {
+		return false, nil
+	}
+	s.entries[key] = value
+	s.ttls[key] = ttl
+	return true, nil
+}
+
+func (s *memoryDedupeStore) Get(ctx context.Context, key string) ([]byte, error) {
+	if v, ok := s.entries[key]; ok {
+		return v, nil
+	}
+	return nil, ErrCacheNotFound
+}
+
+func setupDedupedWebhook() (*WebhookService, *memoryDedupeStore, *MockEventBus, *models.Webhook) {
+	service, webhookRepo, _, _, eventBus := newTestWebhookService()
+	store := newMemoryDedupeStore()
+	service.SetDedupeStore(store, time.Hour)
+
+	token, _ := generateWebhookToken()
+	webhook := &models.Webhook{
+		ID:        uuid.New(),
+		ChannelID: uuid.New(),
+		Name:      "CI",
+		Token:     token,
+	}
+	webhookRepo.On("GetByID", mock.Anything, webhook.ID).Return(webhook, nil)
+	return service, store, eventBus, webhook
+}
+
+func TestWebhookService_ExecuteWebhook_DedupesRetries(t *testing.T) {
+	service, store, eventBus, webhook := setupDedupedWebhook()
+	ctx := context.Background()
+
+	eventBus.On("Publish", "webhook.executed", mock.AnythingOfType("*services.WebhookExecutedEvent")).Return().Once()
+
+	req := &ExecuteWebhookRequest{Content: "Build passed", ProviderMessageID: "delivery-1"}
+
+	first, err := service.ExecuteWebhook(ctx, webhook.ID, webhook.Token, req)
+	assert.NoError(t, err)
+
+	retry, err := service.ExecuteWebhook(ctx, webhook.ID, webhook.Token, req)
+	assert.ErrorIs(t, err, ErrDuplicateWebhookMessage)
+	assert.Equal(t, first.ID, retry.ID)
+	assert.Equal(t, webhook.ChannelID, retry.ChannelID)
+
+	eventBus.AssertNumberOfCalls(t, "Publish", 1)
+	for _, ttl := range store.ttls {
+		assert.Equal(t, time.Hour, ttl)
+	}
+}
+
+func TestWebhookService_ExecuteWebhook_DistinctDeliveriesPost(t *testing.T) {
+	service, _, eventBus, webhook := setupDedupedWebhook()
+	ctx := context.Background()
+
+	eventBus.On("Publish", "webhook.executed", mock.AnythingOfType("*services.WebhookExecutedEvent")).Return()
+
+	// Deliveries without a provider ID can't be deduped
+	for _, id := range []string{"delivery-1", "delivery-2", "", ""} {
+		_, err := service.ExecuteWebhook(ctx, webhook.ID, webhook.Token, &ExecuteWebhookRequest{
+			Content:           "Build passed",
+			ProviderMessageID: id,
+		})
+		assert.NoError(t, err)
+	}
+
+	eventBus.AssertNumberOfCalls(t, "Publish", 4)
+}
+
+func TestWebhookService_ExecuteWebhook_DedupeStoreDown(t *testing.T) {
+	service, store, eventBus, webhook := setupDedupedWebhook()
+	ctx := context.Background()
+	store.err = errors.New("redis down")
+
+	eventBus.On("Publish", "webhook.executed", mock.AnythingOfType("*services.WebhookExecutedEvent")).Return()
+
+	req := &ExecuteWebhookRequest{Content: "Build passed", ProviderMessageID: "delivery-1"}
+	for i := 0; i < 2; i++ {
+		_, err := service.ExecuteWebhook(ctx, webhook.ID, webhook.Token, req)
+		assert.NoError(t, err)
+	}
+	eventBus.AssertNumberOfCalls(t, "Publish", 2)
+}
+
+func TestWebhookDedupeKey(t *testing.T) {
+	webhookA, webhookB := uuid.New(), uuid.New()
+
+	assert.Equal(t, webhookDedupeKey(webhookA, "x"), webhookDedupeKey(webhookA, "x"))
+	assert.NotEqual(t, webhookDedupeKey(webhookA, "x"), webhookDedupeKey(webhookB, "x"))
+	assert.NotEqual(t, webhookDedupeKey(webhookA, "x"), webhookDedupeKey(webhookA, "y"))
+	assert.NotContains(t, webhookDedupeKey(webhookA, "secret-id"), "secret-id")
+}
+
 // ============================================================================
 // Helper Function Tests
 // ============================================================================
@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrPresignNotSupported = errors.New("storage backend does not support presigned uploads")
+	ErrObjectNotFound      = errors.New("object not found")
+)
+
+// PresignedUploader is implemented by backends that let clients upload
+// directly to object storage instead of streaming through the API.
+type PresignedUploader interface {
+	// GetPresignedUploadURL returns a URL the client can PUT the object to
+	GetPresignedUploadURL(ctx context.Context, path, contentType string, expiry time.Duration) (string, error)
+
+	// StatObject returns the stored size of an object, or ErrObjectNotFound
+	StatObject(ctx context.Context, path string) (int64, error)
+}
+
+// presignV4 builds an AWS Signature Version 4 query-signed URL.
+// Only UNSIGNED-PAYLOAD requests are supported, which is what S3 expects
+// for presigned uploads where the body is not known up front.
+func (b *S3Backend) presignV4(method, path string, expiry time.Duration, signedHeaders map[string]string, now time.Time) (string, error) {
+	if b.accessKey == "" || b.secretKey == "" {
+		return "", errors.New("s3 credentials not configured")
+	}
+
+	endpoint, err := url.Parse(b.endpoint)
+	if err != nil || endpoint.Host == "" {
+		return "", fmt.Errorf("invalid s3 endpoint: %q", b.endpoint)
+	}
+
+	host := endpoint.Host
+	canonicalURI := "/" + encodePath(path)
+	if b.forcePathStyle {
+		canonicalURI = "/" + b.bucket + canonicalURI
+	} else {
+		host = b.bucket + "." + host
+	}
+
+	amzDate := now.UTC().Format("20060102T150405Z")
+	dateStamp := now.UTC().Format("20060102")
+	scope := dateStamp + "/" + b.region + "/s3/aws4_request"
+
+	headers := map[string]string{"host": host}
+	for k, v := range signedHeaders {
+		headers[strings.ToLower(k)] = strings.TrimSpace(v)
+	}
+	headerNames := make([]string, 0, len(headers))
+	for k := range headers {
+		headerNames = append(headerNames, k)
+	}
+	sort.Strings(headerNames)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range headerNames {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaderList := strings.Join(headerNames, ";")
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", b.accessKey+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", fmt.Sprintf("%d", int(expiry.Seconds())))
+	query.Set("X-Amz-SignedHeaders", signedHeaderList)
+	canonicalQuery := strings.ReplaceAll(query.Encode(), "+", "%20")
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		canonicalURI,
+		canonicalQuery,
+		canonicalHeaders.String(),
+		signedHeaderList,
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(hashed[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+b.secretKey), dateStamp)
+	key = hmacSHA256(key, b.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return fmt.Sprintf("%s://%s%s?%s&X-Amz-Signature=%s",
+		endpoint.Scheme, host, canonicalURI, canonicalQuery, signature), nil
+}
+
+// GetPresignedUploadURL returns a presigned PUT URL for direct uploads
+func (b *S3Backend) GetPresignedUploadURL(ctx context.Context, path, contentType string, expiry time.Duration) (string, error) {
+	headers := map[string]string{}
+	if contentType != "" {
+		headers["content-type"] = contentType
+	}
+	return b.presignV4(http.MethodPut, path, expiry, headers, time.Now())
+}
+
+// StatObject issues a presigned HEAD request to check an uploaded object
+func (b *S3Backend) StatObject(ctx context.Context, path string) (int64, error) {
+	signed, err := b.presignV4(http.MethodHead, path, time.Minute, nil, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, signed, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat object: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.ContentLength, nil
+	case http.StatusNotFound:
+		return 0, ErrObjectNotFound
+	default:
+		return 0, fmt.Errorf("failed to stat object: unexpected status %d", resp.StatusCode)
+	}
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// encodePath URI-encodes each path segment as required by SigV4
+func encodePath(path string) string {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, s := range segments {
+		segments[i] = strings.ReplaceAll(url.PathEscape(s), "+", "%2B")
+	}
+	return strings.Join(segments, "/")
+}
+
+// Ensure S3Backend supports presigned uploads
+var _ PresignedUploader = (*S3Backend)(nil)
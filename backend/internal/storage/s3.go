@@ -4,15 +4,21 @@ import (
 	"context"
 	"errors"
 	"io"
+	"net/http"
 	"time"
 )
 
 // S3Backend implements StorageBackend for S3-compatible storage
 // This is a stub - implement with AWS SDK when Go 1.23+ is available
 type S3Backend struct {
-	bucket    string
-	endpoint  string
-	publicURL string
+	bucket         string
+	endpoint       string
+	region         string
+	accessKey      string
+	secretKey      string
+	publicURL      string
+	forcePathStyle bool
+	httpClient     *http.Client
 }
 
 // S3Config holds S3 configuration
@@ -32,10 +38,20 @@ var ErrS3NotImplemented = errors.New("S3 storage requires Go 1.23+, use local st
 func NewS3Backend(cfg S3Config) (*S3Backend, error) {
 	// Return a stub that will error on use
 	// Full implementation requires Go 1.23+ for AWS SDK v2
+	// Presigned uploads are implemented natively with SigV4 (see presign.go)
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
 	return &S3Backend{
-		bucket:    cfg.Bucket,
-		endpoint:  cfg.Endpoint,
-		publicURL: cfg.PublicURL,
+		bucket:         cfg.Bucket,
+		endpoint:       cfg.Endpoint,
+		region:         region,
+		accessKey:      cfg.AccessKey,
+		secretKey:      cfg.SecretKey,
+		publicURL:      cfg.PublicURL,
+		forcePathStyle: cfg.ForcePathStyle,
+		httpClient:     &http.Client{Timeout: 10 * time.Second},
 	}, nil
 }
 
@@ -61,5 +77,5 @@ func (b *S3Backend) GetURL(path string) string {
 
 // GetSignedURL returns a presigned URL for temporary access
 func (b *S3Backend) GetSignedURL(ctx context.Context, path string, expiry time.Duration) (string, error) {
-	return "", ErrS3NotImplemented
+	return b.presignV4(http.MethodGet, path, expiry, nil, time.Now())
 }
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -12,6 +13,13 @@ import (
 	"github.com/google/uuid"
 )
 
+// Validation errors, for files the service refuses to store
+var (
+	ErrFileTooLarge          = errors.New("file too large")
+	ErrFileTypeNotAllowed    = errors.New("file type not allowed")
+	ErrContentTypeNotAllowed = errors.New("content type not allowed")
+)
+
 // StorageBackend defines the storage interface
 type StorageBackend interface {
 	// Upload uploads a file and returns its URL
@@ -70,28 +78,70 @@ func NewService(backend StorageBackend, maxFileSizeMB int64, blockedExts []strin
 	}
 }
 
-// UploadFile handles file upload with validation
-func (s *Service) UploadFile(
-	ctx context.Context,
-	file *multipart.FileHeader,
-	uploaderID uuid.UUID,
-	category string, // "attachments", "avatars", "icons", etc.
-) (*FileInfo, error) {
+// PresignedUpload describes a direct-to-storage upload the client must complete
+type PresignedUpload struct {
+	FileInfo
+	UploadURL string            `json:"upload_url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// validate checks size, extension and content type against service policy
+func (s *Service) validate(filename, contentType string, size int64) error {
 	// Validate size
-	if s.maxFileSize > 0 && file.Size > s.maxFileSize {
-		return nil, fmt.Errorf("file too large: %d bytes (max: %d)", file.Size, s.maxFileSize)
+	if s.maxFileSize > 0 && size > s.maxFileSize {
+		return fmt.Errorf("%w: %d bytes (max: %d)", ErrFileTooLarge, size, s.maxFileSize)
 	}
 
 	// Validate extension
-	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(file.Filename), "."))
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
 	if s.blockedExts[ext] {
-		return nil, fmt.Errorf("file type not allowed: %s", ext)
+		return fmt.Errorf("%w: %s", ErrFileTypeNotAllowed, ext)
 	}
 
 	// Validate content type
-	contentType := file.Header.Get("Content-Type")
 	if s.blockedTypes[contentType] {
-		return nil, fmt.Errorf("content type not allowed: %s", contentType)
+		return fmt.Errorf("%w: %s", ErrContentTypeNotAllowed, contentType)
+	}
+
+	return nil
+}
+
+// objectPath generates the storage path for a new file
+func objectPath(category string, uploaderID, fileID uuid.UUID, filename string) string {
+	return fmt.Sprintf("%s/%s/%s/%s%s",
+		category,
+		uploaderID.String()[:8],
+		time.Now().Format("2006/01"),
+		fileID.String(),
+		filepath.Ext(filename),
+	)
+}
+
+// UploadFile handles file upload with validation
+func (s *Service) UploadFile(
+	ctx context.Context,
+	file *multipart.FileHeader,
+	uploaderID uuid.UUID,
+	category string, // "attachments", "avatars", "icons", etc.
+) (*FileInfo, error) {
+	return s.UploadFileWithProgress(ctx, file, uploaderID, category, nil)
+}
+
+// UploadFileWithProgress uploads like UploadFile and calls progress with
+// the bytes handed to the backend so far as the file is written. progress
+// may be nil.
+func (s *Service) UploadFileWithProgress(
+	ctx context.Context,
+	file *multipart.FileHeader,
+	uploaderID uuid.UUID,
+	category string,
+	progress func(written int64),
+) (*FileInfo, error) {
+	contentType := file.Header.Get("Content-Type")
+	if err := s.validate(file.Filename, contentType, file.Size); err != nil {
+		return nil, err
 	}
 
 	// Open file
@@ -103,16 +153,14 @@ func (s *Service) UploadFile(
 
 	// Generate path
 	fileID := uuid.New()
-	path := fmt.Sprintf("%s/%s/%s/%s%s",
-		category,
-		uploaderID.String()[:8],
-		time.Now().Format("2006/01"),
-		fileID.String(),
-		filepath.Ext(file.Filename),
-	)
+	path := objectPath(category, uploaderID, fileID, file.Filename)
 
 	// Upload
-	url, err := s.backend.Upload(ctx, path, src, contentType, file.Size)
+	var content io.Reader = src
+	if progress != nil {
+		content = &progressReader{r: src, progress: progress}
+	}
+	url, err := s.backend.Upload(ctx, path, content, contentType, file.Size)
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload file: %w", err)
 	}
@@ -129,6 +177,65 @@ func (s *Service) UploadFile(
 	}, nil
 }
 
+// PresignUpload validates a pending upload and returns a URL the client can
+// PUT the file to directly, bypassing the API's request body limit
+func (s *Service) PresignUpload(
+	ctx context.Context,
+	filename, contentType string,
+	size int64,
+	uploaderID uuid.UUID,
+	category string,
+	expiry time.Duration,
+) (*PresignedUpload, error) {
+	uploader, ok := s.backend.(PresignedUploader)
+	if !ok {
+		return nil, ErrPresignNotSupported
+	}
+
+	if err := s.validate(filename, contentType, size); err != nil {
+		return nil, err
+	}
+
+	fileID := uuid.New()
+	path := objectPath(category, uploaderID, fileID, filename)
+
+	uploadURL, err := uploader.GetPresignedUploadURL(ctx, path, contentType, expiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign upload: %w", err)
+	}
+
+	headers := map[string]string{}
+	if contentType != "" {
+		headers["Content-Type"] = contentType
+	}
+
+	return &PresignedUpload{
+		FileInfo: FileInfo{
+			ID:          fileID,
+			Path:        path,
+			URL:         s.backend.GetURL(path),
+			Filename:    filename,
+			ContentType: contentType,
+			Size:        size,
+			UploadedBy:  uploaderID,
+			UploadedAt:  time.Now(),
+		},
+		UploadURL: uploadURL,
+		Method:    "PUT",
+		Headers:   headers,
+		ExpiresAt: time.Now().Add(expiry),
+	}, nil
+}
+
+// StatObject returns the stored size of an object uploaded via a presigned URL
+func (s *Service) StatObject(ctx context.Context, path string) (int64, error) {
+	uploader, ok := s.backend.(PresignedUploader)
+	if !ok {
+		return 0, ErrPresignNotSupported
+	}
+	return uploader.StatObject(ctx, path)
+}
+
 // DeleteFile deletes a file
 func (s *Service) DeleteFile(ctx context.Context, path string) error {
 	return s.backend.Delete(ctx, path)
@@ -148,3 +255,19 @@ func (s *Service) GetSignedURL(ctx context.Context, path string, expiry time.Dur
 func (s *Service) Download(ctx context.Context, path string) (io.ReadCloser, error) {
 	return s.backend.Download(ctx, path)
 }
+
+// progressReader reports how much of a file has been read as it's read
+type progressReader struct {
+	r        io.Reader
+	read     int64
+	progress func(read int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.read += int64(n)
+		p.progress(p.read)
+	}
+	return n, err
+}
@@ -0,0 +1,253 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	defaultTokenTTL     = time.Hour
+	defaultEmptyTimeout = 5 * time.Minute
+	defaultTimeout      = 10 * time.Second
+)
+
+// LiveKitConfig configures a LiveKit server
+type LiveKitConfig struct {
+	// URL is the ws:// or wss:// address clients connect to
+	URL       string
+	APIKey    string
+	APISecret string
+
+	// APIURL overrides where the room API is called, e.g. an internal
+	// address. Defaults to URL with an http(s) scheme.
+	APIURL string
+	// TokenTTL bounds how long a join token can be used to connect
+	TokenTTL time.Duration
+	// EmptyTimeout is how long LiveKit keeps a room nobody joined or
+	// everyone left, for clients that disconnect without telling us
+	EmptyTimeout time.Duration
+	Client       *http.Client
+}
+
+// LiveKit provisions rooms through LiveKit's RoomService API
+type LiveKit struct {
+	url          string
+	apiURL       string
+	apiKey       string
+	apiSecret    []byte
+	tokenTTL     time.Duration
+	emptyTimeout time.Duration
+	client       *http.Client
+}
+
+// NewLiveKit creates a LiveKit SFU client
+func NewLiveKit(cfg LiveKitConfig) (*LiveKit, error) {
+	if cfg.URL == "" || cfg.APIKey == "" || cfg.APISecret == "" {
+		return nil, errors.New("livekit: URL, API key and API secret are required")
+	}
+
+	apiURL := cfg.APIURL
+	if apiURL == "" {
+		apiURL = httpURL(cfg.URL)
+	}
+	tokenTTL := cfg.TokenTTL
+	if tokenTTL <= 0 {
+		tokenTTL = defaultTokenTTL
+	}
+	emptyTimeout := cfg.EmptyTimeout
+	if emptyTimeout <= 0 {
+		emptyTimeout = defaultEmptyTimeout
+	}
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+
+	return &LiveKit{
+		url:          cfg.URL,
+		apiURL:       strings.TrimRight(apiURL, "/"),
+		apiKey:       cfg.APIKey,
+		apiSecret:    []byte(cfg.APISecret),
+		tokenTTL:     tokenTTL,
+		emptyTimeout: emptyTimeout,
+		client:       client,
+	}, nil
+}
+
+// httpURL turns a client WebSocket URL into the matching API URL
+func httpURL(u string) string {
+	switch {
+	case strings.HasPrefix(u, "wss://"):
+		return "https://" + strings.TrimPrefix(u, "wss://")
+	case strings.HasPrefix(u, "ws://"):
+		return "http://" + strings.TrimPrefix(u, "ws://")
+	}
+	return u
+}
+
+// videoGrant is LiveKit's "video" token claim. The Can* flags are
+// pointers so false is still sent: LiveKit treats a missing flag as true.
+type videoGrant struct {
+	RoomCreate        bool     `json:"roomCreate,omitempty"`
+	RoomJoin          bool     `json:"roomJoin,omitempty"`
+	Room              string   `json:"room,omitempty"`
+	CanPublish        *bool    `json:"canPublish,omitempty"`
+	CanSubscribe      *bool    `json:"canSubscribe,omitempty"`
+	CanPublishData    *bool    `json:"canPublishData,omitempty"`
+	CanPublishSources []string `json:"canPublishSources,omitempty"`
+}
+
+type liveKitClaims struct {
+	jwt.RegisteredClaims
+	Name   string      `json:"name,omitempty"`
+	Video  *videoGrant `json:"video,omitempty"`
+	SHA256 string      `json:"sha256,omitempty"`
+}
+
+// URL implements SFU
+func (l *LiveKit) URL() string {
+	return l.url
+}
+
+// JoinToken implements SFU
+func (l *LiveKit) JoinToken(p Participant, room string, grant Grant) (string, error) {
+	canPublish := len(grant.Sources) > 0
+	identity := p.UserID.String()
+
+	return l.sign(liveKitClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: identity,
+			ID:      identity,
+		},
+		Name: p.Name,
+		Video: &videoGrant{
+			RoomJoin:          true,
+			Room:              room,
+			CanPublish:        &canPublish,
+			CanSubscribe:      &grant.Subscribe,
+			CanPublishData:    &grant.Data,
+			CanPublishSources: grant.Sources,
+		},
+	}, l.tokenTTL)
+}
+
+func (l *LiveKit) sign(claims liveKitClaims, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims.Issuer = l.apiKey
+	claims.NotBefore = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(ttl))
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(l.apiSecret)
+	if err != nil {
+		return "", fmt.Errorf("livekit: sign token: %w", err)
+	}
+	return token, nil
+}
+
+// CreateRoom implements SFU. LiveKit returns the existing room if there
+// is one.
+func (l *LiveKit) CreateRoom(ctx context.Context, room string) error {
+	return l.call(ctx, "CreateRoom", map[string]interface{}{
+		"name":          room,
+		"empty_timeout": int(l.emptyTimeout / time.Second),
+	})
+}
+
+// DeleteRoom implements SFU
+func (l *LiveKit) DeleteRoom(ctx context.Context, room string) error {
+	err := l.call(ctx, "DeleteRoom", map[string]string{"room": room})
+	if errors.Is(err, errTwirpNotFound) {
+		return nil
+	}
+	return err
+}
+
+var errTwirpNotFound = errors.New("livekit: not found")
+
+// call invokes a RoomService method over LiveKit's Twirp JSON API
+func (l *LiveKit) call(ctx context.Context, method string, in interface{}) error {
+	token, err := l.sign(liveKitClaims{Video: &videoGrant{RoomCreate: true}}, time.Minute)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.apiURL+"/twirp/livekit.RoomService/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("livekit: %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	var twirpErr struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	json.Unmarshal(raw, &twirpErr)
+	if twirpErr.Code == "not_found" {
+		return errTwirpNotFound
+	}
+	return fmt.Errorf("livekit: %s returned %d: %s", method, resp.StatusCode, raw)
+}
+
+// ParseWebhook verifies a LiveKit webhook and decodes it. LiveKit signs
+// each delivery with the API secret and puts the body's SHA-256 in the
+// token.
+func (l *LiveKit) ParseWebhook(authorization string, body []byte) (*WebhookEvent, error) {
+	var claims liveKitClaims
+	_, err := jwt.ParseWithClaims(strings.TrimPrefix(authorization, "Bearer "), &claims, func(t *jwt.Token) (interface{}, error) {
+		return l.apiSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithIssuer(l.apiKey))
+	if err != nil {
+		return nil, ErrInvalidWebhook
+	}
+
+	sum := sha256.Sum256(body)
+	if claims.SHA256 != base64.StdEncoding.EncodeToString(sum[:]) {
+		return nil, ErrInvalidWebhook
+	}
+
+	var payload struct {
+		Event string `json:"event"`
+		Room  struct {
+			Name string `json:"name"`
+		} `json:"room"`
+		Participant struct {
+			Identity string `json:"identity"`
+		} `json:"participant"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("livekit: decode webhook: %w", err)
+	}
+	return &WebhookEvent{
+		Event:       payload.Event,
+		Room:        payload.Room.Name,
+		Participant: payload.Participant.Identity,
+	}, nil
+}
@@ -0,0 +1,330 @@
+package voice
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/events"
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// ChannelGetter looks up channels
+type ChannelGetter interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Channel, error)
+}
+
+// PermissionResolver computes a member's permissions in a channel, with
+// its overwrites and schedule applied. The PermissionService implements it.
+type PermissionResolver interface {
+	ChannelPermissions(ctx context.Context, channelID, userID uuid.UUID) (int64, error)
+}
+
+// EventPublisher publishes voice state and stream changes
+type EventPublisher interface {
+	Publish(eventType string, data interface{})
+}
+
+// Manager tracks who is in which voice channel, creates each channel's SFU
+// room on first join and deletes it when the last participant leaves.
+// A user is in at most one voice channel at a time.
+type Manager struct {
+	sfu      SFU
+	channels ChannelGetter
+	perms    PermissionResolver
+	events   EventPublisher
+
+	mu           sync.Mutex
+	participants map[uuid.UUID]map[uuid.UUID]struct{} // channelID -> userIDs
+	joined       map[uuid.UUID]*member                // userID -> where they are
+	streams      map[uuid.UUID]*Stream                // streamer userID -> stream
+	roomLocks    map[uuid.UUID]*sync.Mutex            // channelID -> lock
+}
+
+// member is a user's place in voice
+type member struct {
+	channelID uuid.UUID
+	serverID  *uuid.UUID
+	grant     Grant
+}
+
+// NewManager creates a voice manager backed by sfu
+func NewManager(sfu SFU, channels ChannelGetter, perms PermissionResolver) *Manager {
+	return &Manager{
+		sfu:          sfu,
+		channels:     channels,
+		perms:        perms,
+		participants: make(map[uuid.UUID]map[uuid.UUID]struct{}),
+		joined:       make(map[uuid.UUID]*member),
+		streams:      make(map[uuid.UUID]*Stream),
+		roomLocks:    make(map[uuid.UUID]*sync.Mutex),
+	}
+}
+
+// SetEventBus sets where voice state and stream changes are published
+func (m *Manager) SetEventBus(bus EventPublisher) {
+	m.events = bus
+}
+
+// Join puts p in a voice channel, leaving any other one first, and returns
+// a token granting what their permissions allow there
+func (m *Manager) Join(ctx context.Context, p Participant, channelID uuid.UUID) (*Session, error) {
+	channel, grant, err := m.grantFor(ctx, p.UserID, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	current, inVoice := m.joined[p.UserID]
+	m.mu.Unlock()
+	if inVoice && current.channelID != channelID {
+		if err := m.leave(ctx, p.UserID, current.channelID); err != nil {
+			return nil, err
+		}
+	}
+
+	room := RoomName(channelID)
+	token, err := m.sfu.JoinToken(p, room, grant)
+	if err != nil {
+		return nil, err
+	}
+
+	// Hold the room lock across the SFU call so a concurrent last leave
+	// can't delete the room we just made sure exists
+	lock := m.roomLock(channelID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := m.sfu.CreateRoom(ctx, room); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	if m.participants[channelID] == nil {
+		m.participants[channelID] = make(map[uuid.UUID]struct{})
+	}
+	m.participants[channelID][p.UserID] = struct{}{}
+	m.joined[p.UserID] = &member{channelID: channelID, serverID: channel.ServerID, grant: grant}
+	state := &StateEvent{ChannelID: channelID, State: m.voiceStateLocked(p.UserID)}
+	m.mu.Unlock()
+
+	m.publish(event{events.VoiceStateUpdated, state})
+
+	return &Session{
+		ChannelID: channelID,
+		URL:       m.sfu.URL(),
+		Room:      room,
+		Token:     token,
+	}, nil
+}
+
+// Leave takes a user out of their voice channel. It does nothing if they
+// aren't in one.
+func (m *Manager) Leave(ctx context.Context, userID uuid.UUID) error {
+	m.mu.Lock()
+	current, ok := m.joined[userID]
+	m.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return m.leave(ctx, userID, current.channelID)
+}
+
+// Participants returns the users in a voice channel
+func (m *Manager) Participants(channelID uuid.UUID) []uuid.UUID {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	users := make([]uuid.UUID, 0, len(m.participants[channelID]))
+	for userID := range m.participants[channelID] {
+		users = append(users, userID)
+	}
+	return users
+}
+
+// HandleWebhook applies a room or participant change reported by the SFU,
+// so clients that drop without leaving don't keep a room alive
+func (m *Manager) HandleWebhook(ctx context.Context, authorization string, body []byte) error {
+	receiver, ok := m.sfu.(WebhookReceiver)
+	if !ok {
+		return ErrWebhooksUnsupported
+	}
+	event, err := receiver.ParseWebhook(authorization, body)
+	if err != nil {
+		return err
+	}
+
+	channelID, ok := channelFromRoom(event.Room)
+	if !ok {
+		return nil // Not one of ours
+	}
+
+	switch event.Event {
+	case EventParticipantLeft:
+		userID, err := uuid.Parse(event.Participant)
+		if err != nil {
+			return nil
+		}
+		return m.leave(ctx, userID, channelID)
+	case EventRoomFinished:
+		m.forgetRoom(channelID)
+	}
+	return nil
+}
+
+// leave removes a user from channelID, deleting the room if they were the
+// last one in it. It's a no-op if they have since moved elsewhere.
+func (m *Manager) leave(ctx context.Context, userID, channelID uuid.UUID) error {
+	lock := m.roomLock(channelID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	m.mu.Lock()
+	current, ok := m.joined[userID]
+	if !ok || current.channelID != channelID {
+		m.mu.Unlock()
+		return nil
+	}
+	pending := m.dropStreamsLocked(userID)
+	pending = append(pending, event{events.VoiceStateUpdated, &StateEvent{
+		ChannelID: channelID,
+		State:     &models.VoiceState{UserID: userID, ServerID: current.serverID},
+	}})
+	delete(m.joined, userID)
+	delete(m.participants[channelID], userID)
+	empty := len(m.participants[channelID]) == 0
+	if empty {
+		delete(m.participants, channelID)
+	}
+	m.mu.Unlock()
+
+	m.publish(pending...)
+	if !empty {
+		return nil
+	}
+	if err := m.sfu.DeleteRoom(ctx, RoomName(channelID)); err != nil {
+		// Not fatal: the SFU closes empty rooms itself after a timeout
+		log.Printf("[Voice] failed to delete room for channel %s: %v", channelID, err)
+	}
+	return nil
+}
+
+// forgetRoom drops everyone from a room the SFU has closed
+func (m *Manager) forgetRoom(channelID uuid.UUID) {
+	lock := m.roomLock(channelID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	m.mu.Lock()
+	var pending []event
+	for userID := range m.participants[channelID] {
+		current, ok := m.joined[userID]
+		if !ok || current.channelID != channelID {
+			continue
+		}
+		pending = append(pending, m.dropStreamsLocked(userID)...)
+		pending = append(pending, event{events.VoiceStateUpdated, &StateEvent{
+			ChannelID: channelID,
+			State:     &models.VoiceState{UserID: userID, ServerID: current.serverID},
+		}})
+		delete(m.joined, userID)
+	}
+	delete(m.participants, channelID)
+	m.mu.Unlock()
+
+	m.publish(pending...)
+}
+
+// voiceStateLocked builds a user's current voice state. m.mu must be held.
+func (m *Manager) voiceStateLocked(userID uuid.UUID) *models.VoiceState {
+	current := m.joined[userID]
+	channelID := current.channelID
+	return &models.VoiceState{
+		UserID:     userID,
+		ServerID:   current.serverID,
+		ChannelID:  &channelID,
+		SelfStream: m.streams[userID] != nil,
+	}
+}
+
+// event is a change to publish once the manager's lock is released
+type event struct {
+	eventType string
+	data      interface{}
+}
+
+func (m *Manager) publish(pending ...event) {
+	if m.events == nil {
+		return
+	}
+	for _, e := range pending {
+		m.events.Publish(e.eventType, e.data)
+	}
+}
+
+func (m *Manager) roomLock(channelID uuid.UUID) *sync.Mutex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lock, ok := m.roomLocks[channelID]
+	if !ok {
+		lock = &sync.Mutex{}
+		m.roomLocks[channelID] = lock
+	}
+	return lock
+}
+
+// grantFor checks a user may connect to a channel and works out what they
+// may publish there. DM and group DM voice is open to all recipients.
+func (m *Manager) grantFor(ctx context.Context, userID, channelID uuid.UUID) (*models.Channel, Grant, error) {
+	channel, err := m.channels.GetByID(ctx, channelID)
+	if err != nil {
+		return nil, Grant{}, err
+	}
+	if channel == nil {
+		return nil, Grant{}, ErrChannelNotFound
+	}
+
+	switch channel.Type {
+	case models.ChannelTypeDM, models.ChannelTypeGroupDM:
+		if !slices.Contains(channel.Recipients, userID) {
+			return nil, Grant{}, ErrNoConnectPermission
+		}
+		return channel, grantFromPermissions(models.PermConnect | models.PermSpeak | models.PermVideo), nil
+	case models.ChannelTypeVoice, models.ChannelTypeStage:
+	default:
+		return nil, Grant{}, ErrNotVoiceChannel
+	}
+	if channel.ServerID == nil {
+		return nil, Grant{}, ErrNotVoiceChannel
+	}
+
+	perms, err := m.perms.ChannelPermissions(ctx, channel.ID, userID)
+	if errors.Is(err, services.ErrNotServerMember) {
+		return nil, Grant{}, ErrNoConnectPermission
+	}
+	if err != nil {
+		return nil, Grant{}, err
+	}
+	if !models.HasPermission(perms, models.PermConnect) {
+		return nil, Grant{}, ErrNoConnectPermission
+	}
+	return channel, grantFromPermissions(perms), nil
+}
+
+// grantFromPermissions maps SPEAK to the microphone and VIDEO to the camera
+// and screen share
+func grantFromPermissions(perms int64) Grant {
+	grant := Grant{Subscribe: true, Data: true}
+	if models.HasPermission(perms, models.PermSpeak) {
+		grant.Sources = append(grant.Sources, SourceMicrophone)
+	}
+	if models.HasPermission(perms, models.PermVideo) {
+		grant.Sources = append(grant.Sources, SourceCamera, SourceScreenShare, SourceScreenShareAudio)
+	}
+	return grant
+}
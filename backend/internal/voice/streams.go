@@ -0,0 +1,197 @@
+package voice
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/events"
+)
+
+// MaxApplicationNameLength bounds the application name a streamer reports
+const MaxApplicationNameLength = 100
+
+var (
+	// ErrNotInVoice means the user isn't in a voice channel
+	ErrNotInVoice = errors.New("voice: not in a voice channel")
+
+	// ErrNoStreamPermission means the user may not share their screen
+	ErrNoStreamPermission = errors.New("voice: missing permission to stream")
+
+	// ErrStreamNotFound means nobody is streaming with that key
+	ErrStreamNotFound = errors.New("voice: stream not found")
+)
+
+// Stream is a screen share ("Go Live") in a voice channel
+type Stream struct {
+	Key             string      `json:"stream_key"`
+	UserID          uuid.UUID   `json:"user_id"`
+	ChannelID       uuid.UUID   `json:"channel_id"`
+	ServerID        *uuid.UUID  `json:"server_id,omitempty"`
+	ApplicationName string      `json:"application_name,omitempty"`
+	Viewers         []uuid.UUID `json:"viewer_ids"`
+	StartedAt       time.Time   `json:"started_at"`
+}
+
+// StreamKey identifies a user's stream in a channel
+func StreamKey(channelID, userID uuid.UUID) string {
+	return channelID.String() + ":" + userID.String()
+}
+
+func (s *Stream) clone() *Stream {
+	c := *s
+	c.Viewers = slices.Clone(s.Viewers)
+	return &c
+}
+
+// StartStream marks a user as streaming in their voice channel. Calling it
+// again while streaming updates the application name.
+func (m *Manager) StartStream(userID uuid.UUID, applicationName string) (*Stream, error) {
+	applicationName = strings.TrimSpace(applicationName)
+	if len(applicationName) > MaxApplicationNameLength {
+		applicationName = applicationName[:MaxApplicationNameLength]
+	}
+
+	m.mu.Lock()
+	current, ok := m.joined[userID]
+	if !ok {
+		m.mu.Unlock()
+		return nil, ErrNotInVoice
+	}
+	if !slices.Contains(current.grant.Sources, SourceScreenShare) {
+		m.mu.Unlock()
+		return nil, ErrNoStreamPermission
+	}
+
+	var pending []event
+	stream, streaming := m.streams[userID]
+	if streaming {
+		stream.ApplicationName = applicationName
+		pending = append(pending, event{events.StreamUpdated, stream.clone()})
+	} else {
+		stream = &Stream{
+			Key:             StreamKey(current.channelID, userID),
+			UserID:          userID,
+			ChannelID:       current.channelID,
+			ServerID:        current.serverID,
+			ApplicationName: applicationName,
+			Viewers:         []uuid.UUID{},
+			StartedAt:       time.Now(),
+		}
+		m.streams[userID] = stream
+		pending = append(pending,
+			event{events.StreamCreated, stream.clone()},
+			event{events.VoiceStateUpdated, &StateEvent{ChannelID: current.channelID, State: m.voiceStateLocked(userID)}},
+		)
+	}
+	result := stream.clone()
+	m.mu.Unlock()
+
+	m.publish(pending...)
+	return result, nil
+}
+
+// StopStream ends a user's stream. It does nothing if they aren't streaming.
+func (m *Manager) StopStream(userID uuid.UUID) {
+	m.mu.Lock()
+	stream, ok := m.streams[userID]
+	if !ok {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.streams, userID)
+	pending := []event{
+		{events.StreamDeleted, stream.clone()},
+		{events.VoiceStateUpdated, &StateEvent{ChannelID: stream.ChannelID, State: m.voiceStateLocked(userID)}},
+	}
+	m.mu.Unlock()
+
+	m.publish(pending...)
+}
+
+// WatchStream adds a viewer to a stream in the channel they are in
+func (m *Manager) WatchStream(viewerID, channelID, streamerID uuid.UUID) (*Stream, error) {
+	return m.updateViewers(viewerID, channelID, streamerID, func(s *Stream) bool {
+		if viewerID == streamerID || slices.Contains(s.Viewers, viewerID) {
+			return false
+		}
+		s.Viewers = append(s.Viewers, viewerID)
+		return true
+	})
+}
+
+// UnwatchStream removes a viewer from a stream
+func (m *Manager) UnwatchStream(viewerID, channelID, streamerID uuid.UUID) (*Stream, error) {
+	return m.updateViewers(viewerID, channelID, streamerID, func(s *Stream) bool {
+		n := len(s.Viewers)
+		s.Viewers = slices.DeleteFunc(s.Viewers, func(id uuid.UUID) bool { return id == viewerID })
+		return len(s.Viewers) != n
+	})
+}
+
+func (m *Manager) updateViewers(viewerID, channelID, streamerID uuid.UUID, update func(*Stream) bool) (*Stream, error) {
+	m.mu.Lock()
+	if current, ok := m.joined[viewerID]; !ok || current.channelID != channelID {
+		m.mu.Unlock()
+		return nil, ErrNotInVoice
+	}
+	stream, ok := m.streams[streamerID]
+	if !ok || stream.ChannelID != channelID {
+		m.mu.Unlock()
+		return nil, ErrStreamNotFound
+	}
+
+	var pending []event
+	if update(stream) {
+		pending = append(pending, event{events.StreamUpdated, stream.clone()})
+	}
+	result := stream.clone()
+	m.mu.Unlock()
+
+	m.publish(pending...)
+	return result, nil
+}
+
+// Streams lists the active streams in a voice channel the requester may
+// connect to
+func (m *Manager) Streams(ctx context.Context, requesterID, channelID uuid.UUID) ([]*Stream, error) {
+	if _, _, err := m.grantFor(ctx, requesterID, channelID); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	streams := []*Stream{}
+	for _, stream := range m.streams {
+		if stream.ChannelID == channelID {
+			streams = append(streams, stream.clone())
+		}
+	}
+	slices.SortFunc(streams, func(a, b *Stream) int {
+		return a.StartedAt.Compare(b.StartedAt)
+	})
+	return streams, nil
+}
+
+// dropStreamsLocked ends userID's stream and stops them watching any
+// others, returning the events to publish. m.mu must be held.
+func (m *Manager) dropStreamsLocked(userID uuid.UUID) []event {
+	var pending []event
+	if stream, ok := m.streams[userID]; ok {
+		delete(m.streams, userID)
+		pending = append(pending, event{events.StreamDeleted, stream.clone()})
+	}
+	for _, stream := range m.streams {
+		n := len(stream.Viewers)
+		stream.Viewers = slices.DeleteFunc(stream.Viewers, func(id uuid.UUID) bool { return id == userID })
+		if len(stream.Viewers) != n {
+			pending = append(pending, event{events.StreamUpdated, stream.clone()})
+		}
+	}
+	return pending
+}
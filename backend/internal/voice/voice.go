@@ -0,0 +1,117 @@
+// Package voice provisions rooms on a selective forwarding unit (SFU) for
+// voice channels and hands out join tokens scoped to what each member may
+// do in the channel.
+package voice
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+var (
+	// ErrNotVoiceChannel means the channel can't be joined for voice
+	ErrNotVoiceChannel = errors.New("voice: not a voice channel")
+
+	// ErrChannelNotFound means the channel doesn't exist
+	ErrChannelNotFound = errors.New("voice: channel not found")
+
+	// ErrNoConnectPermission means the user lacks CONNECT in the channel
+	ErrNoConnectPermission = errors.New("voice: missing connect permission")
+
+	// ErrWebhooksUnsupported means the SFU doesn't send webhooks
+	ErrWebhooksUnsupported = errors.New("voice: SFU does not support webhooks")
+
+	// ErrInvalidWebhook means a webhook wasn't signed by the SFU
+	ErrInvalidWebhook = errors.New("voice: invalid webhook signature")
+)
+
+// Track sources a participant may publish
+const (
+	SourceMicrophone       = "microphone"
+	SourceCamera           = "camera"
+	SourceScreenShare      = "screen_share"
+	SourceScreenShareAudio = "screen_share_audio"
+)
+
+// Grant is what a participant may do once in the room
+type Grant struct {
+	// Sources lists the track sources the participant may publish. Empty
+	// means listen only.
+	Sources   []string
+	Subscribe bool
+	Data      bool
+}
+
+// Participant identifies who a join token is for
+type Participant struct {
+	UserID uuid.UUID
+	Name   string
+}
+
+// SFU is a media server that hosts rooms
+type SFU interface {
+	// URL is where clients connect with a join token
+	URL() string
+	// CreateRoom makes sure a room exists. It succeeds if it already does.
+	CreateRoom(ctx context.Context, room string) error
+	// DeleteRoom closes a room and disconnects anyone still in it. It
+	// succeeds if the room is already gone.
+	DeleteRoom(ctx context.Context, room string) error
+	// JoinToken mints a token that lets p join room with grant
+	JoinToken(p Participant, room string, grant Grant) (string, error)
+}
+
+// WebhookReceiver is implemented by SFUs that report room changes back to
+// us, so rooms are torn down even when clients vanish without leaving
+type WebhookReceiver interface {
+	// ParseWebhook verifies a delivery and decodes it
+	ParseWebhook(authorization string, body []byte) (*WebhookEvent, error)
+}
+
+// WebhookEvent is a room or participant change reported by the SFU
+type WebhookEvent struct {
+	Event       string
+	Room        string
+	Participant string // Identity, for participant events
+}
+
+// SFU webhook event names
+const (
+	EventParticipantLeft = "participant_left"
+	EventRoomFinished    = "room_finished"
+)
+
+// Session is what a client needs to connect to a voice channel
+type Session struct {
+	ChannelID uuid.UUID `json:"channel_id"`
+	URL       string    `json:"url"`
+	Room      string    `json:"room"`
+	Token     string    `json:"token"`
+}
+
+// StateEvent is published when a user joins, leaves or changes their state
+// in a voice channel. State.ChannelID is nil once they've left; ChannelID
+// is always the channel the change happened in.
+type StateEvent struct {
+	ChannelID uuid.UUID
+	State     *models.VoiceState
+}
+
+// RoomName is the SFU room that hosts a channel
+func RoomName(channelID uuid.UUID) string {
+	return "channel-" + channelID.String()
+}
+
+// channelFromRoom reverses RoomName
+func channelFromRoom(room string) (uuid.UUID, bool) {
+	const prefix = "channel-"
+	if len(room) <= len(prefix) || room[:len(prefix)] != prefix {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(room[len(prefix):])
+	return id, err == nil
+}
@@ -0,0 +1,477 @@
+package voice
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/events"
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+const (
+	testAPIKey    = "APItest"
+	testAPISecret = "test-secret-that-is-long-enough"
+)
+
+// fakeLiveKit records RoomService calls
+type fakeLiveKit struct {
+	mu    sync.Mutex
+	calls []string
+	rooms map[string]bool
+}
+
+func newFakeLiveKit(t *testing.T) (*fakeLiveKit, *LiveKit) {
+	fake := &fakeLiveKit{rooms: make(map[string]bool)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims := &liveKitClaims{}
+		_, err := jwt.ParseWithClaims(r.Header.Get("Authorization")[len("Bearer "):], claims, func(*jwt.Token) (interface{}, error) {
+			return []byte(testAPISecret), nil
+		})
+		if err != nil || claims.Issuer != testAPIKey || claims.Video == nil || !claims.Video.RoomCreate {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var body struct {
+			Name string `json:"name"`
+			Room string `json:"room"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		switch r.URL.Path {
+		case "/twirp/livekit.RoomService/CreateRoom":
+			fake.calls = append(fake.calls, "create "+body.Name)
+			fake.rooms[body.Name] = true
+		case "/twirp/livekit.RoomService/DeleteRoom":
+			fake.calls = append(fake.calls, "delete "+body.Room)
+			if !fake.rooms[body.Room] {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"code":"not_found","msg":"room not found"}`))
+				return
+			}
+			delete(fake.rooms, body.Room)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	lk, err := NewLiveKit(LiveKitConfig{
+		URL:       "wss://voice.example.com",
+		APIURL:    srv.URL,
+		APIKey:    testAPIKey,
+		APISecret: testAPISecret,
+	})
+	require.NoError(t, err)
+	return fake, lk
+}
+
+func (f *fakeLiveKit) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+type fakeChannels map[uuid.UUID]*models.Channel
+
+func (f fakeChannels) GetByID(ctx context.Context, id uuid.UUID) (*models.Channel, error) {
+	return f[id], nil
+}
+
+type fakePerms map[uuid.UUID]int64
+
+func (f fakePerms) ChannelPermissions(ctx context.Context, channelID, userID uuid.UUID) (int64, error) {
+	perms, ok := f[userID]
+	if !ok {
+		return 0, services.ErrNotServerMember
+	}
+	return perms, nil
+}
+
+func parseJoinToken(t *testing.T, token string) *liveKitClaims {
+	claims := &liveKitClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return []byte(testAPISecret), nil
+	})
+	require.NoError(t, err)
+	return claims
+}
+
+func TestManager_JoinGrantsFollowPermissions(t *testing.T) {
+	_, lk := newFakeLiveKit(t)
+	serverID := uuid.New()
+	channelID := uuid.New()
+	speaker := uuid.New()
+	listener := uuid.New()
+
+	m := NewManager(lk,
+		fakeChannels{channelID: {ID: channelID, ServerID: &serverID, Type: models.ChannelTypeVoice}},
+		fakePerms{
+			speaker:  models.PermConnect | models.PermSpeak,
+			listener: models.PermConnect,
+		},
+	)
+
+	session, err := m.Join(context.Background(), Participant{UserID: speaker, Name: "alice"}, channelID)
+	require.NoError(t, err)
+	assert.Equal(t, "wss://voice.example.com", session.URL)
+	assert.Equal(t, RoomName(channelID), session.Room)
+
+	claims := parseJoinToken(t, session.Token)
+	assert.Equal(t, testAPIKey, claims.Issuer)
+	assert.Equal(t, speaker.String(), claims.Subject)
+	assert.Equal(t, "alice", claims.Name)
+	require.NotNil(t, claims.Video)
+	assert.True(t, claims.Video.RoomJoin)
+	assert.Equal(t, RoomName(channelID), claims.Video.Room)
+	assert.True(t, *claims.Video.CanPublish)
+	assert.Equal(t, []string{SourceMicrophone}, claims.Video.CanPublishSources)
+
+	session, err = m.Join(context.Background(), Participant{UserID: listener}, channelID)
+	require.NoError(t, err)
+	claims = parseJoinToken(t, session.Token)
+	// LiveKit defaults a missing canPublish to true, so it must be sent
+	require.NotNil(t, claims.Video.CanPublish)
+	assert.False(t, *claims.Video.CanPublish)
+	assert.True(t, *claims.Video.CanSubscribe)
+}
+
+func TestManager_JoinRequiresConnect(t *testing.T) {
+	fake, lk := newFakeLiveKit(t)
+	serverID := uuid.New()
+	voiceID := uuid.New()
+	textID := uuid.New()
+	userID := uuid.New()
+
+	m := NewManager(lk,
+		fakeChannels{
+			voiceID: {ID: voiceID, ServerID: &serverID, Type: models.ChannelTypeVoice},
+			textID:  {ID: textID, ServerID: &serverID, Type: models.ChannelTypeText},
+		},
+		fakePerms{userID: models.PermSpeak},
+	)
+
+	_, err := m.Join(context.Background(), Participant{UserID: userID}, voiceID)
+	assert.ErrorIs(t, err, ErrNoConnectPermission)
+
+	_, err = m.Join(context.Background(), Participant{UserID: userID}, textID)
+	assert.ErrorIs(t, err, ErrNotVoiceChannel)
+
+	_, err = m.Join(context.Background(), Participant{UserID: userID}, uuid.New())
+	assert.ErrorIs(t, err, ErrChannelNotFound)
+
+	assert.Empty(t, fake.Calls())
+}
+
+// channelPerms resolves permissions per channel, as overwrites do
+type channelPerms map[uuid.UUID]fakePerms
+
+func (f channelPerms) ChannelPermissions(ctx context.Context, channelID, userID uuid.UUID) (int64, error) {
+	return f[channelID].ChannelPermissions(ctx, channelID, userID)
+}
+
+func TestManager_JoinUsesChannelPermissions(t *testing.T) {
+	fake, lk := newFakeLiveKit(t)
+	serverID := uuid.New()
+	lobbyID := uuid.New()
+	stageID := uuid.New()
+	userID := uuid.New()
+
+	// The user's roles grant SPEAK, but the stage's overwrites deny it and
+	// a closed channel denies CONNECT
+	m := NewManager(lk,
+		fakeChannels{
+			lobbyID: {ID: lobbyID, ServerID: &serverID, Type: models.ChannelTypeVoice},
+			stageID: {ID: stageID, ServerID: &serverID, Type: models.ChannelTypeStage},
+		},
+		channelPerms{
+			lobbyID: {userID: models.PermViewChannels},
+			stageID: {userID: models.PermViewChannels | models.PermConnect},
+		},
+	)
+
+	_, err := m.Join(context.Background(), Participant{UserID: userID}, lobbyID)
+	assert.ErrorIs(t, err, ErrNoConnectPermission)
+	assert.Empty(t, fake.Calls())
+
+	session, err := m.Join(context.Background(), Participant{UserID: userID}, stageID)
+	require.NoError(t, err)
+	assert.False(t, *parseJoinToken(t, session.Token).Video.CanPublish)
+
+	_, err = m.Join(context.Background(), Participant{UserID: uuid.New()}, stageID)
+	assert.ErrorIs(t, err, ErrNoConnectPermission)
+}
+
+func TestManager_JoinDMRequiresRecipient(t *testing.T) {
+	_, lk := newFakeLiveKit(t)
+	channelID := uuid.New()
+	recipient := uuid.New()
+
+	m := NewManager(lk,
+		fakeChannels{channelID: {ID: channelID, Type: models.ChannelTypeDM, Recipients: []uuid.UUID{recipient, uuid.New()}}},
+		fakePerms{},
+	)
+
+	session, err := m.Join(context.Background(), Participant{UserID: recipient}, channelID)
+	require.NoError(t, err)
+	assert.Contains(t, parseJoinToken(t, session.Token).Video.CanPublishSources, SourceCamera)
+
+	_, err = m.Join(context.Background(), Participant{UserID: uuid.New()}, channelID)
+	assert.ErrorIs(t, err, ErrNoConnectPermission)
+}
+
+func TestManager_DeletesRoomWhenLastParticipantLeaves(t *testing.T) {
+	fake, lk := newFakeLiveKit(t)
+	serverID := uuid.New()
+	channelID := uuid.New()
+	alice := uuid.New()
+	bob := uuid.New()
+	room := RoomName(channelID)
+
+	m := NewManager(lk,
+		fakeChannels{channelID: {ID: channelID, ServerID: &serverID, Type: models.ChannelTypeVoice}},
+		fakePerms{alice: models.PermConnect, bob: models.PermConnect},
+	)
+	ctx := context.Background()
+
+	_, err := m.Join(ctx, Participant{UserID: alice}, channelID)
+	require.NoError(t, err)
+	_, err = m.Join(ctx, Participant{UserID: bob}, channelID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{alice, bob}, m.Participants(channelID))
+
+	require.NoError(t, m.Leave(ctx, alice))
+	assert.Equal(t, []string{"create " + room, "create " + room}, fake.Calls())
+
+	require.NoError(t, m.Leave(ctx, bob))
+	assert.Equal(t, "delete "+room, fake.Calls()[2])
+	assert.Empty(t, m.Participants(channelID))
+
+	// Leaving again is a no-op
+	require.NoError(t, m.Leave(ctx, bob))
+	assert.Len(t, fake.Calls(), 3)
+}
+
+func TestManager_JoinMovesBetweenChannels(t *testing.T) {
+	fake, lk := newFakeLiveKit(t)
+	serverID := uuid.New()
+	first := uuid.New()
+	second := uuid.New()
+	userID := uuid.New()
+
+	m := NewManager(lk,
+		fakeChannels{
+			first:  {ID: first, ServerID: &serverID, Type: models.ChannelTypeVoice},
+			second: {ID: second, ServerID: &serverID, Type: models.ChannelTypeVoice},
+		},
+		fakePerms{userID: models.PermConnect},
+	)
+	ctx := context.Background()
+
+	_, err := m.Join(ctx, Participant{UserID: userID}, first)
+	require.NoError(t, err)
+	_, err = m.Join(ctx, Participant{UserID: userID}, second)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"create " + RoomName(first),
+		"delete " + RoomName(first),
+		"create " + RoomName(second),
+	}, fake.Calls())
+	assert.Empty(t, m.Participants(first))
+	assert.Equal(t, []uuid.UUID{userID}, m.Participants(second))
+}
+
+func signWebhook(t *testing.T, body []byte) string {
+	sum := sha256.Sum256(body)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, liveKitClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    testAPIKey,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+		SHA256: base64.StdEncoding.EncodeToString(sum[:]),
+	}).SignedString([]byte(testAPISecret))
+	require.NoError(t, err)
+	return token
+}
+
+func TestManager_WebhookParticipantLeft(t *testing.T) {
+	fake, lk := newFakeLiveKit(t)
+	serverID := uuid.New()
+	channelID := uuid.New()
+	userID := uuid.New()
+
+	m := NewManager(lk,
+		fakeChannels{channelID: {ID: channelID, ServerID: &serverID, Type: models.ChannelTypeVoice}},
+		fakePerms{userID: models.PermConnect},
+	)
+	ctx := context.Background()
+
+	_, err := m.Join(ctx, Participant{UserID: userID}, channelID)
+	require.NoError(t, err)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"event":       EventParticipantLeft,
+		"room":        map[string]string{"name": RoomName(channelID)},
+		"participant": map[string]string{"identity": userID.String()},
+	})
+
+	// Unsigned and tampered deliveries are rejected
+	assert.ErrorIs(t, m.HandleWebhook(ctx, "", body), ErrInvalidWebhook)
+	assert.ErrorIs(t, m.HandleWebhook(ctx, signWebhook(t, []byte(`{}`)), body), ErrInvalidWebhook)
+	assert.Len(t, m.Participants(channelID), 1)
+
+	require.NoError(t, m.HandleWebhook(ctx, signWebhook(t, body), body))
+	assert.Empty(t, m.Participants(channelID))
+	assert.Equal(t, "delete "+RoomName(channelID), fake.Calls()[len(fake.Calls())-1])
+}
+
+func TestLiveKit_DeleteMissingRoom(t *testing.T) {
+	_, lk := newFakeLiveKit(t)
+	assert.NoError(t, lk.DeleteRoom(context.Background(), "channel-gone"))
+}
+
+func TestNewLiveKit_RequiresCredentials(t *testing.T) {
+	_, err := NewLiveKit(LiveKitConfig{URL: "wss://voice.example.com"})
+	assert.Error(t, err)
+}
+
+func TestHTTPURL(t *testing.T) {
+	assert.Equal(t, "https://voice.example.com", httpURL("wss://voice.example.com"))
+	assert.Equal(t, "http://localhost:7880", httpURL("ws://localhost:7880"))
+}
+
+// recordingBus collects published event types
+type recordingBus struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (b *recordingBus) Publish(eventType string, data interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, eventType)
+}
+
+func (b *recordingBus) Take() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	taken := b.events
+	b.events = nil
+	return taken
+}
+
+func TestManager_StreamLifecycle(t *testing.T) {
+	_, lk := newFakeLiveKit(t)
+	serverID := uuid.New()
+	channelID := uuid.New()
+	streamer := uuid.New()
+	viewer := uuid.New()
+	listener := uuid.New()
+
+	m := NewManager(lk,
+		fakeChannels{channelID: {ID: channelID, ServerID: &serverID, Type: models.ChannelTypeVoice}},
+		fakePerms{
+			streamer: models.PermConnect | models.PermVideo,
+			viewer:   models.PermConnect,
+			listener: models.PermConnect,
+		},
+	)
+	bus := &recordingBus{}
+	m.SetEventBus(bus)
+	ctx := context.Background()
+
+	_, err := m.StartStream(streamer, "Factorio")
+	assert.ErrorIs(t, err, ErrNotInVoice)
+
+	for _, userID := range []uuid.UUID{streamer, viewer, listener} {
+		_, err := m.Join(ctx, Participant{UserID: userID}, channelID)
+		require.NoError(t, err)
+	}
+	bus.Take()
+
+	// Screen share needs VIDEO
+	_, err = m.StartStream(viewer, "")
+	assert.ErrorIs(t, err, ErrNoStreamPermission)
+
+	stream, err := m.StartStream(streamer, "  Factorio  ")
+	require.NoError(t, err)
+	assert.Equal(t, StreamKey(channelID, streamer), stream.Key)
+	assert.Equal(t, "Factorio", stream.ApplicationName)
+	assert.Equal(t, &serverID, stream.ServerID)
+	assert.Equal(t, []string{events.StreamCreated, events.VoiceStateUpdated}, bus.Take())
+
+	stream, err = m.WatchStream(viewer, channelID, streamer)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{viewer}, stream.Viewers)
+	// Watching twice changes nothing
+	_, err = m.WatchStream(viewer, channelID, streamer)
+	require.NoError(t, err)
+	assert.Equal(t, []string{events.StreamUpdated}, bus.Take())
+
+	_, err = m.WatchStream(uuid.New(), channelID, streamer)
+	assert.ErrorIs(t, err, ErrNotInVoice)
+	_, err = m.WatchStream(viewer, channelID, listener)
+	assert.ErrorIs(t, err, ErrStreamNotFound)
+
+	streams, err := m.Streams(ctx, listener, channelID)
+	require.NoError(t, err)
+	require.Len(t, streams, 1)
+	assert.Equal(t, []uuid.UUID{viewer}, streams[0].Viewers)
+
+	// A viewer leaving drops off the stream
+	require.NoError(t, m.Leave(ctx, viewer))
+	assert.Equal(t, []string{events.StreamUpdated, events.VoiceStateUpdated}, bus.Take())
+	streams, _ = m.Streams(ctx, listener, channelID)
+	assert.Empty(t, streams[0].Viewers)
+
+	// The streamer leaving ends the stream
+	require.NoError(t, m.Leave(ctx, streamer))
+	assert.Equal(t, []string{events.StreamDeleted, events.VoiceStateUpdated}, bus.Take())
+	streams, _ = m.Streams(ctx, listener, channelID)
+	assert.Empty(t, streams)
+}
+
+func TestManager_StopStream(t *testing.T) {
+	_, lk := newFakeLiveKit(t)
+	channelID := uuid.New()
+	userID := uuid.New()
+
+	m := NewManager(lk,
+		fakeChannels{channelID: {ID: channelID, Type: models.ChannelTypeDM, Recipients: []uuid.UUID{userID}}},
+		fakePerms{},
+	)
+	bus := &recordingBus{}
+	m.SetEventBus(bus)
+	ctx := context.Background()
+
+	_, err := m.Join(ctx, Participant{UserID: userID}, channelID)
+	require.NoError(t, err)
+	_, err = m.StartStream(userID, "")
+	require.NoError(t, err)
+	bus.Take()
+
+	m.StopStream(userID)
+	assert.Equal(t, []string{events.StreamDeleted, events.VoiceStateUpdated}, bus.Take())
+
+	// Stopping again is a no-op
+	m.StopStream(userID)
+	assert.Empty(t, bus.Take())
+}
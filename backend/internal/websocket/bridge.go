@@ -3,18 +3,21 @@ package websocket
 import (
 	"encoding/json"
 	"log"
+	"time"
 
 	"github.com/google/uuid"
 
 	"hearth/internal/events"
 	"hearth/internal/models"
 	"hearth/internal/services"
+	"hearth/internal/voice"
 )
 
 // EventBridge connects the domain event bus to the WebSocket hub
 type EventBridge struct {
-	hub *Hub
-	bus *events.Bus
+	hub      *Hub
+	bus      *events.Bus
+	throttle ServerEventThrottle
 }
 
 // NewEventBridge creates a new event bridge
@@ -75,7 +78,9 @@ func (b *EventBridge) registerHandlers() {
 	b.bus.Subscribe(events.MessageCreated, b.onMessageCreated)
 	b.bus.Subscribe(events.MessageUpdated, b.onMessageUpdated)
 	b.bus.Subscribe(events.MessageDeleted, b.onMessageDeleted)
+	b.bus.Subscribe(events.MessagesBulkDeleted, b.onMessagesBulkDeleted)
 	b.bus.Subscribe(events.MessagePinned, b.onMessagePinned)
+	b.bus.Subscribe(events.MessageAcked, b.onMessageAcked)
 
 	// Reaction events
 	b.bus.Subscribe(events.ReactionAdded, b.onReactionAdded)
@@ -97,13 +102,47 @@ func (b *EventBridge) registerHandlers() {
 	b.bus.Subscribe(events.MemberUpdated, b.onMemberUpdated)
 	b.bus.Subscribe(events.MemberKicked, b.onMemberKicked)
 	b.bus.Subscribe(events.MemberBanned, b.onMemberBanned)
+	b.bus.Subscribe(events.MemberUnbanned, b.onMemberUnbanned)
+
+	// Emoji events
+	b.bus.Subscribe(events.EmojisUpdated, b.onEmojisUpdated)
+
+	// Encrypted channel key events
+	b.bus.Subscribe(events.ChannelKeysRotated, b.onChannelKeysRotated)
+	b.bus.Subscribe(events.SenderKeysDistributed, b.onSenderKeysDistributed)
+
+	// Attachment events
+	b.bus.Subscribe(events.AttachmentUpdated, b.onAttachmentUpdated)
+	b.bus.Subscribe(events.AttachmentUploadProgress, b.onAttachmentUploadProgress)
 
 	// User events
 	b.bus.Subscribe(events.UserUpdated, b.onUserUpdated)
 	b.bus.Subscribe(events.PresenceUpdate, b.onPresenceUpdate)
+	b.bus.Subscribe(events.CustomStatusUpdated, b.onCustomStatusUpdated)
+
+	// Relationship events
+	for _, eventType := range relationshipEventTypes {
+		b.bus.Subscribe(eventType, b.onRelationshipChanged)
+	}
 
 	// Typing events
 	b.bus.Subscribe(events.TypingStarted, b.onTypingStarted)
+	b.bus.Subscribe(events.TypingStopped, b.onTypingStopped)
+
+	// Voice events
+	for _, eventType := range voiceEventTypes {
+		b.bus.Subscribe(eventType, b.onVoiceChanged)
+	}
+
+	// Thread events
+	for _, eventType := range threadEventTypes {
+		b.bus.Subscribe(eventType, b.onThreadChanged)
+	}
+
+	// Instance events
+	for _, eventType := range announcementEventTypes {
+		b.bus.Subscribe(eventType, b.onAnnouncementChanged)
+	}
 }
 
 // Message event handlers
@@ -141,6 +180,16 @@ func (b *EventBridge) onMessageDeleted(event events.Event) {
 	})
 }
 
+func (b *EventBridge) onMessagesBulkDeleted(event events.Event) {
+	data, ok := event.Data.(*services.MessagesBulkDeletedEvent)
+	if !ok {
+		log.Printf("[EventBridge] onMessagesBulkDeleted: wrong type %T", event.Data)
+		return
+	}
+	log.Printf("[EventBridge] Broadcasting MESSAGE_DELETE_BULK (%d) to channel %s", len(data.MessageIDs), data.ChannelID)
+	b.sendToChannel(data.ChannelID, EventMessageDeleteBulk, MessagesBulkDeletedToWS(data))
+}
+
 func (b *EventBridge) onMessagePinned(event events.Event) {
 	data, ok := event.Data.(*services.MessagePinnedEvent)
 	if !ok {
@@ -237,6 +286,12 @@ func (b *EventBridge) onServerUpdated(event events.Event) {
 }
 
 func (b *EventBridge) onServerDeleted(event events.Event) {
+	if deleted, ok := event.Data.(*services.ServerDeletedEvent); ok {
+		b.sendToServer(deleted.ServerID, EventTypeServerDelete, map[string]interface{}{
+			"id": deleted.ServerID.String(),
+		})
+		return
+	}
 	data, ok := event.Data.(*ServerEventData)
 	if !ok {
 		return
@@ -257,6 +312,10 @@ type MemberEventData struct {
 }
 
 func (b *EventBridge) onMemberJoined(event events.Event) {
+	if joined, ok := event.Data.(*services.MemberJoinedEvent); ok {
+		b.sendToServer(joined.ServerID, EventTypeMemberJoin, MemberJoinedToWS(joined))
+		return
+	}
 	data, ok := event.Data.(*MemberEventData)
 	if !ok {
 		return
@@ -273,6 +332,10 @@ func (b *EventBridge) onMemberLeft(event events.Event) {
 }
 
 func (b *EventBridge) onMemberUpdated(event events.Event) {
+	if updated, ok := event.Data.(*services.MemberUpdatedEvent); ok {
+		b.sendToServer(updated.ServerID, EventTypeMemberUpdate, MemberUpdatedToWS(updated))
+		return
+	}
 	data, ok := event.Data.(*MemberEventData)
 	if !ok {
 		return
@@ -298,6 +361,57 @@ func (b *EventBridge) onMemberBanned(event events.Event) {
 	b.sendToServer(data.ServerID, EventTypeBanAdd, b.buildMemberData(data, false))
 }
 
+func (b *EventBridge) onMemberUnbanned(event events.Event) {
+	data, ok := event.Data.(*services.MemberUnbannedEvent)
+	if !ok {
+		return
+	}
+	b.sendToServer(data.ServerID, EventTypeBanRemove, MemberUnbannedToWS(data))
+}
+
+func (b *EventBridge) onEmojisUpdated(event events.Event) {
+	data, ok := event.Data.(*services.EmojisUpdatedEvent)
+	if !ok {
+		return
+	}
+	b.sendToServer(data.ServerID, EventTypeEmojisUpdate, EmojisUpdatedToWS(data))
+}
+
+func (b *EventBridge) onChannelKeysRotated(event events.Event) {
+	data, ok := event.Data.(*services.ChannelKeysRotatedEvent)
+	if !ok {
+		return
+	}
+	b.sendToChannel(data.ChannelID, EventTypeChannelKeysRotate, ChannelKeysRotatedToWS(data))
+}
+
+func (b *EventBridge) onSenderKeysDistributed(event events.Event) {
+	data, ok := event.Data.(*services.SenderKeysDistributedEvent)
+	if !ok {
+		return
+	}
+	payload := SenderKeysDistributedToWS(data)
+	for _, recipientID := range data.RecipientIDs {
+		b.sendToUser(recipientID, EventTypeChannelSenderKeys, payload)
+	}
+}
+
+func (b *EventBridge) onAttachmentUpdated(event events.Event) {
+	data, ok := event.Data.(*services.AttachmentUpdatedEvent)
+	if !ok || data.ChannelID == uuid.Nil {
+		return
+	}
+	b.sendToChannel(data.ChannelID, EventTypeAttachmentUpdate, AttachmentUpdatedToWS(data))
+}
+
+func (b *EventBridge) onAttachmentUploadProgress(event events.Event) {
+	data, ok := event.Data.(*services.AttachmentUploadProgressEvent)
+	if !ok {
+		return
+	}
+	b.sendToUser(data.UserID, EventTypeAttachmentUploadProgress, UploadProgressToWS(data))
+}
+
 // buildMemberData creates the common member event payload
 func (b *EventBridge) buildMemberData(data *MemberEventData, includeJoinedAt bool) map[string]interface{} {
 	wsData := map[string]interface{}{
@@ -338,6 +452,22 @@ type PresenceEventData struct {
 }
 
 func (b *EventBridge) onPresenceUpdate(event events.Event) {
+	// Presence tracked by the hub arrives once per shared server, or once
+	// for the friends of users who show it to friends only
+	if update, ok := event.Data.(*services.PresenceUpdateEvent); ok {
+		if update.RecipientIDs != nil {
+			payload := PresenceToWS(update.Presence, nil)
+			for _, recipientID := range update.RecipientIDs {
+				b.sendToUser(recipientID, EventTypePresenceUpdate, payload)
+			}
+			return
+		}
+		if allowServerEvent(b.throttle, update.ServerID) {
+			b.sendToServer(update.ServerID, EventTypePresenceUpdate, PresenceToWS(update.Presence, &update.ServerID))
+		}
+		return
+	}
+
 	data, ok := event.Data.(*PresenceEventData)
 	if !ok {
 		return
@@ -354,7 +484,75 @@ func (b *EventBridge) onPresenceUpdate(event events.Event) {
 
 	// Send to all servers the user is in
 	for _, serverID := range data.ServerIDs {
-		b.sendToServer(serverID, EventTypePresenceUpdate, wsData)
+		if allowServerEvent(b.throttle, serverID) {
+			b.sendToServer(serverID, EventTypePresenceUpdate, wsData)
+		}
+	}
+}
+
+func (b *EventBridge) onCustomStatusUpdated(event events.Event) {
+	data, ok := event.Data.(*services.CustomStatusUpdatedEvent)
+	if !ok {
+		return
+	}
+	// The presence listener adds the new custom status when it broadcasts
+	b.hub.RepublishPresence(data.UserID)
+}
+
+func (b *EventBridge) onRelationshipChanged(event events.Event) {
+	for _, update := range RelationshipUpdatesToWS(event.Data) {
+		b.sendToUser(update.UserID, update.Type, update.Data)
+	}
+}
+
+func (b *EventBridge) onThreadChanged(event events.Event) {
+	update := ThreadUpdateToWS(event.Data)
+	if update == nil {
+		return
+	}
+	b.sendToChannel(update.ChannelID, update.Type, update.Data)
+}
+
+func (b *EventBridge) onAnnouncementChanged(event events.Event) {
+	update := AnnouncementUpdateToWS(event.Data)
+	if update == nil {
+		return
+	}
+	jsonData, err := json.Marshal(update.Data)
+	if err != nil {
+		log.Printf("[EventBridge] failed to marshal %s event: %v", update.Type, err)
+		return
+	}
+	b.hub.SendToAll(&Event{
+		Op:   OpDispatch,
+		Type: update.Type,
+		Data: json.RawMessage(jsonData),
+	})
+}
+
+func (b *EventBridge) onVoiceChanged(event events.Event) {
+	update := VoiceUpdateToWS(event.Type, event.Data)
+	if update == nil {
+		return
+	}
+	if update.ServerID != nil {
+		b.sendToServer(*update.ServerID, update.Type, update.Data)
+	} else {
+		b.sendToChannel(update.ChannelID, update.Type, update.Data)
+	}
+}
+
+func (b *EventBridge) onMessageAcked(event events.Event) {
+	data, ok := event.Data.(*services.MessageAckEvent)
+	if !ok {
+		return
+	}
+	b.sendToUser(data.UserID, EventTypeMessageAck, MessageAckToWS(data))
+	if len(data.ReceiptRecipients) > 0 {
+		receipt := ReadReceiptToWS(data)
+		for _, recipientID := range data.ReceiptRecipients {
+			b.sendToUser(recipientID, EventTypeMessageRead, receipt)
+		}
 	}
 }
 
@@ -367,20 +565,40 @@ type TypingEventData struct {
 }
 
 func (b *EventBridge) onTypingStarted(event events.Event) {
-	data, ok := event.Data.(*TypingEventData)
-	if !ok {
-		return
+	if channelID, wsData, ok := typingToWS(event.Data); ok {
+		b.sendToChannel(channelID, EventTypeTypingStart, wsData)
 	}
+}
 
-	wsData := TypingStartData{
-		ChannelID: data.ChannelID.String(),
-		UserID:    data.UserID.String(),
-		Timestamp: 0, // Would use actual timestamp
+func (b *EventBridge) onTypingStopped(event events.Event) {
+	if channelID, wsData, ok := typingToWS(event.Data); ok {
+		b.sendToChannel(channelID, EventTypeTypingStop, wsData)
 	}
-	if data.ServerID != nil {
-		wsData.GuildID = data.ServerID.String()
+}
+
+// typingToWS converts a typing event, given either as TypingEventData or as
+// the models.TypingIndicator the typing service publishes
+func typingToWS(data interface{}) (uuid.UUID, TypingStartData, bool) {
+	switch data := data.(type) {
+	case *TypingEventData:
+		wsData := TypingStartData{
+			ChannelID: data.ChannelID.String(),
+			UserID:    data.UserID.String(),
+			Timestamp: 0, // Would use actual timestamp
+		}
+		if data.ServerID != nil {
+			wsData.GuildID = data.ServerID.String()
+		}
+		return data.ChannelID, wsData, true
+
+	case *models.TypingIndicator:
+		return data.ChannelID, TypingStartData{
+			ChannelID: data.ChannelID.String(),
+			UserID:    data.UserID.String(),
+			Timestamp: data.Timestamp.Unix(),
+		}, true
 	}
-	b.sendToChannel(data.ChannelID, EventTypeTypingStart, wsData)
+	return uuid.Nil, TypingStartData{}, false
 }
 
 // Conversion helpers
@@ -408,6 +626,10 @@ func (b *EventBridge) messageToWS(msg *models.Message) map[string]interface{} {
 		result["guild_id"] = msg.ServerID.String()
 	}
 
+	if len(msg.Embeds) > 0 {
+		result["embeds"] = msg.Embeds
+	}
+
 	if msg.Author != nil {
 		result["author"] = b.publicUserToWS(msg.Author)
 	} else {
@@ -416,6 +638,10 @@ func (b *EventBridge) messageToWS(msg *models.Message) map[string]interface{} {
 		}
 	}
 
+	if msg.Member != nil {
+		result["member"] = MemberToWS(msg.Member)
+	}
+
 	if msg.EditedAt != nil && !msg.EditedAt.IsZero() {
 		ts := msg.EditedAt.Format("2006-01-02T15:04:05.000Z")
 		result["edited_timestamp"] = ts
@@ -424,6 +650,128 @@ func (b *EventBridge) messageToWS(msg *models.Message) map[string]interface{} {
 	return result
 }
 
+// MemberToWS converts the author's server membership embedded in message
+// payloads. The user is omitted because the message already carries it.
+func MemberToWS(member *models.Member) map[string]interface{} {
+	roles := make([]string, len(member.Roles))
+	for i, roleID := range member.Roles {
+		roles[i] = roleID.String()
+	}
+
+	result := map[string]interface{}{
+		"nick":                         nil,
+		"roles":                        roles,
+		"joined_at":                    member.JoinedAt.Format("2006-01-02T15:04:05.000Z"),
+		"deaf":                         member.Deaf,
+		"mute":                         member.Mute,
+		"pending":                      member.Pending,
+		"co_owner":                     member.CoOwner,
+		"communication_disabled_until": nil,
+	}
+	if member.Nickname != nil {
+		result["nick"] = *member.Nickname
+	}
+	if member.CommunicationDisabledUntil != nil {
+		result["communication_disabled_until"] = member.CommunicationDisabledUntil.UTC().Format(time.RFC3339)
+	}
+	return result
+}
+
+// MemberUpdatedToWS converts a member update to its GUILD_MEMBER_UPDATE
+// payload
+func MemberUpdatedToWS(data *services.MemberUpdatedEvent) map[string]interface{} {
+	payload := map[string]interface{}{}
+	if data.Member != nil {
+		payload = MemberToWS(data.Member)
+	}
+	payload["guild_id"] = data.ServerID.String()
+	payload["user"] = map[string]interface{}{"id": data.UserID.String()}
+	return payload
+}
+
+// MemberJoinedToWS converts a join to its GUILD_MEMBER_ADD payload.
+// pending tells clients the member must pass membership screening.
+func MemberJoinedToWS(data *services.MemberJoinedEvent) map[string]interface{} {
+	payload := map[string]interface{}{"pending": false}
+	if data.Member != nil {
+		payload = MemberToWS(data.Member)
+	}
+	payload["guild_id"] = data.ServerID.String()
+	payload["user"] = map[string]interface{}{"id": data.UserID.String()}
+	return payload
+}
+
+// MemberUnbannedToWS converts an unban to its GUILD_BAN_REMOVE payload
+func MemberUnbannedToWS(data *services.MemberUnbannedEvent) map[string]interface{} {
+	return map[string]interface{}{
+		"guild_id": data.ServerID.String(),
+		"user":     map[string]interface{}{"id": data.UserID.String()},
+	}
+}
+
+// EmojisUpdatedToWS converts a server's emoji list to its
+// GUILD_EMOJIS_UPDATE payload
+func EmojisUpdatedToWS(data *services.EmojisUpdatedEvent) map[string]interface{} {
+	emojis := data.Emojis
+	if emojis == nil {
+		emojis = []*models.Emoji{}
+	}
+	return map[string]interface{}{
+		"guild_id": data.ServerID.String(),
+		"emojis":   emojis,
+	}
+}
+
+// ChannelKeysRotatedToWS converts a key rotation to its
+// CHANNEL_KEYS_ROTATE payload
+func ChannelKeysRotatedToWS(data *services.ChannelKeysRotatedEvent) map[string]interface{} {
+	wsData := map[string]interface{}{
+		"channel_id": data.ChannelID.String(),
+		"epoch":      data.Epoch,
+		"reason":     data.Reason,
+	}
+	if data.ServerID != nil {
+		wsData["guild_id"] = data.ServerID.String()
+	}
+	return wsData
+}
+
+// SenderKeysDistributedToWS converts a sender key distribution to the
+// CHANNEL_SENDER_KEYS payload each recipient gets
+func SenderKeysDistributedToWS(data *services.SenderKeysDistributedEvent) map[string]interface{} {
+	return map[string]interface{}{
+		"channel_id":       data.ChannelID.String(),
+		"epoch":            data.Epoch,
+		"sender_id":        data.SenderID.String(),
+		"sender_device_id": data.SenderDeviceID,
+	}
+}
+
+// AttachmentUpdatedToWS converts an attachment scan result to its
+// ATTACHMENT_UPDATE payload
+func AttachmentUpdatedToWS(data *services.AttachmentUpdatedEvent) map[string]interface{} {
+	wsData := map[string]interface{}{
+		"id":          data.AttachmentID.String(),
+		"channel_id":  data.ChannelID.String(),
+		"scan_status": data.ScanStatus,
+	}
+	if data.MessageID != uuid.Nil {
+		wsData["message_id"] = data.MessageID.String()
+	}
+	return wsData
+}
+
+// UploadProgressToWS converts an upload's progress to its
+// ATTACHMENT_UPLOAD_PROGRESS payload
+func UploadProgressToWS(data *services.AttachmentUploadProgressEvent) map[string]interface{} {
+	return map[string]interface{}{
+		"upload_id":      data.UploadID,
+		"channel_id":     data.ChannelID.String(),
+		"bytes_received": data.BytesReceived,
+		"total_bytes":    data.TotalBytes,
+	}
+}
+
 func (b *EventBridge) channelToWS(ch *models.Channel) map[string]interface{} {
 	if ch == nil {
 		return nil
@@ -517,5 +865,272 @@ func (b *EventBridge) publicUserToWS(user *models.PublicUser) map[string]interfa
 const (
 	EventTypeChannelPinsUpdate = "CHANNEL_PINS_UPDATE"
 	EventTypeBanAdd            = "GUILD_BAN_ADD"
+	EventTypeBanRemove         = "GUILD_BAN_REMOVE"
+	EventTypeEmojisUpdate      = "GUILD_EMOJIS_UPDATE"
+	EventTypeChannelKeysRotate = "CHANNEL_KEYS_ROTATE"
+	EventTypeChannelSenderKeys = "CHANNEL_SENDER_KEYS"
+	EventTypeAttachmentUpdate  = "ATTACHMENT_UPDATE"
 	EventTypeUserUpdate        = "USER_UPDATE"
+	EventTypeMessageAck        = "MESSAGE_ACK"
+	EventTypeMessageRead       = "MESSAGE_READ"
+
+	EventTypeAttachmentUploadProgress = "ATTACHMENT_UPLOAD_PROGRESS"
+
+	EventTypeRelationshipAdd    = "RELATIONSHIP_ADD"
+	EventTypeRelationshipRemove = "RELATIONSHIP_REMOVE"
+
+	EventTypeStreamCreate = "STREAM_CREATE"
+	EventTypeStreamUpdate = "STREAM_UPDATE"
+	EventTypeStreamDelete = "STREAM_DELETE"
+
+	EventTypeThreadCreate        = "THREAD_CREATE"
+	EventTypeThreadUpdate        = "THREAD_UPDATE"
+	EventTypeThreadDelete        = "THREAD_DELETE"
+	EventTypeThreadMessageCreate = "THREAD_MESSAGE_CREATE"
+
+	EventTypeAnnouncementCreate = "ANNOUNCEMENT_CREATE"
+	EventTypeAnnouncementDelete = "ANNOUNCEMENT_DELETE"
 )
+
+// relationshipEventTypes are the bus events RelationshipUpdatesToWS handles
+var relationshipEventTypes = []string{
+	events.FriendAdded,
+	events.FriendRemoved,
+	events.FriendRequestSent,
+	events.FriendRequestDeclined,
+	events.UserBlocked,
+	events.UserUnblocked,
+}
+
+// RelationshipUpdate is a RELATIONSHIP_ADD or RELATIONSHIP_REMOVE for one user
+type RelationshipUpdate struct {
+	UserID uuid.UUID
+	Type   string
+	Data   map[string]interface{}
+}
+
+// RelationshipUpdatesToWS converts a friend or block event into the gateway
+// events each side should see. A block is never announced to the blocked
+// user; they only see a friendship or request it ended disappear.
+func RelationshipUpdatesToWS(data interface{}) []RelationshipUpdate {
+	add := func(userID, otherID uuid.UUID, relType int) RelationshipUpdate {
+		return RelationshipUpdate{UserID: userID, Type: EventTypeRelationshipAdd, Data: map[string]interface{}{
+			"id":   otherID.String(),
+			"type": relType,
+		}}
+	}
+	remove := func(userID, otherID uuid.UUID) RelationshipUpdate {
+		return RelationshipUpdate{UserID: userID, Type: EventTypeRelationshipRemove, Data: map[string]interface{}{
+			"id": otherID.String(),
+		}}
+	}
+
+	switch e := data.(type) {
+	case *services.FriendAddedEvent:
+		return []RelationshipUpdate{
+			add(e.UserID, e.FriendID, services.RelationshipFriend),
+			add(e.FriendID, e.UserID, services.RelationshipFriend),
+		}
+	case *services.FriendRemovedEvent:
+		return []RelationshipUpdate{remove(e.UserID, e.FriendID), remove(e.FriendID, e.UserID)}
+	case *services.FriendRequestSentEvent:
+		return []RelationshipUpdate{
+			add(e.SenderID, e.ReceiverID, services.RelationshipPendingOut),
+			add(e.ReceiverID, e.SenderID, services.RelationshipPendingIn),
+		}
+	case *services.FriendRequestDeclinedEvent:
+		return []RelationshipUpdate{remove(e.UserID, e.OtherID), remove(e.OtherID, e.UserID)}
+	case *services.UserBlockedEvent:
+		updates := []RelationshipUpdate{add(e.UserID, e.BlockedID, services.RelationshipBlocked)}
+		if e.Severed {
+			updates = append(updates, remove(e.BlockedID, e.UserID))
+		}
+		return updates
+	case *services.UserUnblockedEvent:
+		return []RelationshipUpdate{remove(e.UserID, e.UnblockedID)}
+	}
+	return nil
+}
+
+// threadEventTypes are the bus events ThreadUpdateToWS handles
+var threadEventTypes = []string{
+	events.ThreadCreated,
+	events.ThreadUpdated,
+	events.ThreadDeleted,
+	events.ThreadMessageCreated,
+}
+
+// ThreadUpdate is a thread gateway event. It goes to the thread's parent
+// channel, so everyone who can see the channel sees its threads.
+type ThreadUpdate struct {
+	ChannelID uuid.UUID
+	Type      string
+	Data      interface{}
+}
+
+// ThreadUpdateToWS converts a thread bus event to its gateway event, or nil
+// for anything else
+func ThreadUpdateToWS(data interface{}) *ThreadUpdate {
+	switch e := data.(type) {
+	case *services.ThreadCreatedEvent:
+		return &ThreadUpdate{ChannelID: e.ChannelID, Type: EventTypeThreadCreate, Data: e.Thread}
+	case *services.ThreadUpdatedEvent:
+		return &ThreadUpdate{ChannelID: e.ChannelID, Type: EventTypeThreadUpdate, Data: e.Thread}
+	case *services.ThreadDeletedEvent:
+		return &ThreadUpdate{ChannelID: e.ChannelID, Type: EventTypeThreadDelete, Data: map[string]interface{}{
+			"id":                e.ThreadID.String(),
+			"parent_channel_id": e.ChannelID.String(),
+		}}
+	case *services.ThreadMessageCreatedEvent:
+		return &ThreadUpdate{ChannelID: e.ChannelID, Type: EventTypeThreadMessageCreate, Data: e.Message}
+	}
+	return nil
+}
+
+// announcementEventTypes are the bus events AnnouncementUpdateToWS handles
+var announcementEventTypes = []string{
+	events.AnnouncementCreated,
+	events.AnnouncementDeleted,
+	events.ReadOnlyModeUpdated,
+}
+
+// AnnouncementUpdate is an instance announcement or read-only mode gateway
+// event. It goes to every connected client.
+type AnnouncementUpdate struct {
+	Type string
+	Data interface{}
+}
+
+// AnnouncementUpdateToWS converts an announcement bus event to its gateway
+// event, or nil for anything else
+func AnnouncementUpdateToWS(data interface{}) *AnnouncementUpdate {
+	switch e := data.(type) {
+	case *services.AnnouncementCreatedEvent:
+		return &AnnouncementUpdate{Type: EventTypeAnnouncementCreate, Data: e.Announcement}
+	case *services.AnnouncementDeletedEvent:
+		return &AnnouncementUpdate{Type: EventTypeAnnouncementDelete, Data: map[string]interface{}{
+			"id": e.ID.String(),
+		}}
+	case *services.ReadOnlyModeUpdatedEvent:
+		return &AnnouncementUpdate{Type: EventTypeReadOnlyModeUpdate, Data: e.Mode}
+	}
+	return nil
+}
+
+// voiceEventTypes are the bus events VoiceUpdateToWS handles
+var voiceEventTypes = []string{
+	events.VoiceStateUpdated,
+	events.StreamCreated,
+	events.StreamUpdated,
+	events.StreamDeleted,
+}
+
+// VoiceUpdate is a voice state or stream gateway event. Server voice goes to
+// the whole server; DM calls only to the channel's recipients.
+type VoiceUpdate struct {
+	ServerID  *uuid.UUID
+	ChannelID uuid.UUID
+	Type      string
+	Data      map[string]interface{}
+}
+
+// VoiceUpdateToWS converts a voice state or stream event to its gateway
+// payload
+func VoiceUpdateToWS(eventType string, data interface{}) *VoiceUpdate {
+	switch e := data.(type) {
+	case *voice.StateEvent:
+		state := e.State
+		payload := map[string]interface{}{
+			"user_id":     state.UserID.String(),
+			"channel_id":  nil,
+			"self_mute":   state.SelfMute,
+			"self_deaf":   state.SelfDeaf,
+			"self_video":  state.SelfVideo,
+			"self_stream": state.SelfStream,
+		}
+		if state.ChannelID != nil {
+			payload["channel_id"] = state.ChannelID.String()
+		}
+		if state.ServerID != nil {
+			payload["guild_id"] = state.ServerID.String()
+		}
+		return &VoiceUpdate{ServerID: state.ServerID, ChannelID: e.ChannelID, Type: EventVoiceStateUpdate, Data: payload}
+
+	case *voice.Stream:
+		payload := map[string]interface{}{
+			"stream_key": e.Key,
+			"user_id":    e.UserID.String(),
+			"channel_id": e.ChannelID.String(),
+		}
+		if e.ServerID != nil {
+			payload["guild_id"] = e.ServerID.String()
+		}
+
+		var wsType string
+		switch eventType {
+		case events.StreamCreated:
+			wsType = EventTypeStreamCreate
+		case events.StreamUpdated:
+			wsType = EventTypeStreamUpdate
+		case events.StreamDeleted:
+			return &VoiceUpdate{ServerID: e.ServerID, ChannelID: e.ChannelID, Type: EventTypeStreamDelete, Data: payload}
+		default:
+			return nil
+		}
+
+		viewers := make([]string, len(e.Viewers))
+		for i, id := range e.Viewers {
+			viewers[i] = id.String()
+		}
+		payload["application_name"] = e.ApplicationName
+		payload["viewer_ids"] = viewers
+		payload["viewer_count"] = len(viewers)
+		payload["started_at"] = e.StartedAt
+		return &VoiceUpdate{ServerID: e.ServerID, ChannelID: e.ChannelID, Type: wsType, Data: payload}
+	}
+	return nil
+}
+
+// MessagesBulkDeletedToWS converts a bulk delete to its MESSAGE_DELETE_BULK
+// payload
+func MessagesBulkDeletedToWS(data *services.MessagesBulkDeletedEvent) map[string]interface{} {
+	ids := make([]string, len(data.MessageIDs))
+	for i, id := range data.MessageIDs {
+		ids[i] = id.String()
+	}
+	payload := map[string]interface{}{
+		"ids":        ids,
+		"channel_id": data.ChannelID.String(),
+	}
+	if data.ServerID != nil {
+		payload["guild_id"] = data.ServerID.String()
+	}
+	return payload
+}
+
+// MessageAckToWS converts an ack to its MESSAGE_ACK payload
+func MessageAckToWS(ack *services.MessageAckEvent) map[string]interface{} {
+	data := map[string]interface{}{
+		"channel_id":    ack.ChannelID.String(),
+		"message_id":    nil,
+		"mention_count": ack.MentionCount,
+	}
+	if ack.MessageID != nil {
+		data["message_id"] = ack.MessageID.String()
+	}
+	return data
+}
+
+// ReadReceiptToWS converts an ack to the MESSAGE_READ payload DM partners
+// get
+func ReadReceiptToWS(ack *services.MessageAckEvent) map[string]interface{} {
+	data := map[string]interface{}{
+		"channel_id": ack.ChannelID.String(),
+		"user_id":    ack.UserID.String(),
+		"message_id": nil,
+	}
+	if ack.MessageID != nil {
+		data["message_id"] = ack.MessageID.String()
+	}
+	return data
+}
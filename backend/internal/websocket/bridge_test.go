@@ -13,6 +13,7 @@ import (
 	"hearth/internal/events"
 	"hearth/internal/models"
 	"hearth/internal/services"
+	"hearth/internal/voice"
 )
 
 func TestNewEventBridge(t *testing.T) {
@@ -579,6 +580,82 @@ func TestEventBridge_onServerDeleted(t *testing.T) {
 	}
 }
 
+func TestEventBridge_onServerDeleted_ServiceEvent(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	bus := events.NewBus()
+	_ = NewEventBridge(hub, bus)
+
+	serverID := uuid.New()
+	client := &Client{
+		ID:       uuid.New().String(),
+		UserID:   uuid.New(),
+		Username: "testuser",
+		hub:      hub,
+		send:     make(chan []byte, 256),
+		servers:  make(map[uuid.UUID]bool),
+		channels: make(map[uuid.UUID]bool),
+	}
+	hub.register <- client
+	time.Sleep(50 * time.Millisecond)
+	hub.SubscribeServer(client, serverID)
+
+	// ServerService and the admin API publish their own event type
+	bus.Publish(events.ServerDeleted, &services.ServerDeletedEvent{ServerID: serverID, OwnerID: uuid.New()})
+
+	select {
+	case data := <-client.send:
+		var event map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &event))
+		assert.Equal(t, EventTypeServerDelete, event["t"])
+		assert.Equal(t, map[string]interface{}{"id": serverID.String()}, event["d"])
+	case <-time.After(time.Second):
+		t.Fatal("Did not receive server delete event")
+	}
+}
+
+func TestEventBridge_onAnnouncementChanged(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	bus := events.NewBus()
+	_ = NewEventBridge(hub, bus)
+
+	// Clients get announcements whatever they're subscribed to
+	client := &Client{
+		ID:       uuid.New().String(),
+		UserID:   uuid.New(),
+		Username: "testuser",
+		hub:      hub,
+		send:     make(chan []byte, 256),
+		servers:  make(map[uuid.UUID]bool),
+		channels: make(map[uuid.UUID]bool),
+	}
+	hub.register <- client
+	time.Sleep(50 * time.Millisecond)
+
+	announcement := &models.Announcement{ID: uuid.New(), Content: "Maintenance tonight", Level: models.AnnouncementWarning}
+	bus.Publish(events.AnnouncementCreated, &services.AnnouncementCreatedEvent{Announcement: announcement})
+
+	select {
+	case data := <-client.send:
+		var event struct {
+			Type string              `json:"t"`
+			Data models.Announcement `json:"d"`
+		}
+		require.NoError(t, json.Unmarshal(data, &event))
+		assert.Equal(t, EventTypeAnnouncementCreate, event.Type)
+		assert.Equal(t, "Maintenance tonight", event.Data.Content)
+	case <-time.After(time.Second):
+		t.Fatal("Did not receive announcement")
+	}
+}
+
 func TestEventBridge_onMemberJoined(t *testing.T) {
 	hub := NewHub()
 	ctx, cancel := context.WithCancel(context.Background())
@@ -763,6 +840,280 @@ func TestEventBridge_onUserUpdated(t *testing.T) {
 	}
 }
 
+func TestEventBridge_onMessageAcked(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	bus := events.NewBus()
+	_ = NewEventBridge(hub, bus)
+
+	userID := uuid.New()
+	channelID := uuid.New()
+	messageID := uuid.New()
+
+	client := &Client{
+		ID:       uuid.New().String(),
+		UserID:   userID,
+		hub:      hub,
+		send:     make(chan []byte, 256),
+		servers:  make(map[uuid.UUID]bool),
+		channels: make(map[uuid.UUID]bool),
+	}
+
+	hub.register <- client
+	time.Sleep(50 * time.Millisecond)
+
+	bus.Publish(events.MessageAcked, &services.MessageAckEvent{
+		UserID:       userID,
+		ChannelID:    channelID,
+		MessageID:    &messageID,
+		MentionCount: 2,
+	})
+
+	select {
+	case data := <-client.send:
+		var event struct {
+			Type string                 `json:"t"`
+			Data map[string]interface{} `json:"d"`
+		}
+		require.NoError(t, json.Unmarshal(data, &event))
+		assert.Equal(t, EventTypeMessageAck, event.Type)
+		assert.Equal(t, channelID.String(), event.Data["channel_id"])
+		assert.Equal(t, messageID.String(), event.Data["message_id"])
+		assert.Equal(t, float64(2), event.Data["mention_count"])
+	case <-time.After(time.Second):
+		t.Fatal("Did not receive message ack event")
+	}
+}
+
+func TestEventBridge_onAttachmentUploadProgress(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	bus := events.NewBus()
+	_ = NewEventBridge(hub, bus)
+
+	userID := uuid.New()
+	channelID := uuid.New()
+
+	client := &Client{
+		ID:       uuid.New().String(),
+		UserID:   userID,
+		hub:      hub,
+		send:     make(chan []byte, 256),
+		servers:  make(map[uuid.UUID]bool),
+		channels: make(map[uuid.UUID]bool),
+	}
+
+	hub.register <- client
+	time.Sleep(50 * time.Millisecond)
+
+	bus.Publish(events.AttachmentUploadProgress, &services.AttachmentUploadProgressEvent{
+		UploadID:      "upload-1",
+		UserID:        userID,
+		ChannelID:     channelID,
+		BytesReceived: 1024,
+		TotalBytes:    4096,
+	})
+
+	select {
+	case data := <-client.send:
+		var event struct {
+			Type string                 `json:"t"`
+			Data map[string]interface{} `json:"d"`
+		}
+		require.NoError(t, json.Unmarshal(data, &event))
+		assert.Equal(t, EventTypeAttachmentUploadProgress, event.Type)
+		assert.Equal(t, "upload-1", event.Data["upload_id"])
+		assert.Equal(t, channelID.String(), event.Data["channel_id"])
+		assert.Equal(t, float64(1024), event.Data["bytes_received"])
+		assert.Equal(t, float64(4096), event.Data["total_bytes"])
+	case <-time.After(time.Second):
+		t.Fatal("Did not receive upload progress event")
+	}
+}
+
+func TestEventBridge_onRelationshipChanged(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	bus := events.NewBus()
+	_ = NewEventBridge(hub, bus)
+
+	senderID := uuid.New()
+	receiverID := uuid.New()
+
+	newClient := func(userID uuid.UUID) *Client {
+		return &Client{
+			ID:       uuid.New().String(),
+			UserID:   userID,
+			hub:      hub,
+			send:     make(chan []byte, 256),
+			servers:  make(map[uuid.UUID]bool),
+			channels: make(map[uuid.UUID]bool),
+		}
+	}
+	sender := newClient(senderID)
+	receiver := newClient(receiverID)
+	hub.register <- sender
+	hub.register <- receiver
+	time.Sleep(50 * time.Millisecond)
+
+	bus.Publish(events.FriendRequestSent, &services.FriendRequestSentEvent{
+		SenderID:   senderID,
+		ReceiverID: receiverID,
+	})
+
+	for _, tc := range []struct {
+		client  *Client
+		otherID uuid.UUID
+		relType float64
+	}{
+		{sender, receiverID, services.RelationshipPendingOut},
+		{receiver, senderID, services.RelationshipPendingIn},
+	} {
+		select {
+		case data := <-tc.client.send:
+			var event struct {
+				Type string                 `json:"t"`
+				Data map[string]interface{} `json:"d"`
+			}
+			require.NoError(t, json.Unmarshal(data, &event))
+			assert.Equal(t, EventTypeRelationshipAdd, event.Type)
+			assert.Equal(t, tc.otherID.String(), event.Data["id"])
+			assert.Equal(t, tc.relType, event.Data["type"])
+		case <-time.After(time.Second):
+			t.Fatal("Did not receive relationship event")
+		}
+	}
+}
+
+func TestRelationshipUpdatesToWS_Block(t *testing.T) {
+	blockerID := uuid.New()
+	blockedID := uuid.New()
+
+	// A bare block is only visible to the blocker
+	updates := RelationshipUpdatesToWS(&services.UserBlockedEvent{UserID: blockerID, BlockedID: blockedID})
+	require.Len(t, updates, 1)
+	assert.Equal(t, blockerID, updates[0].UserID)
+	assert.Equal(t, EventTypeRelationshipAdd, updates[0].Type)
+	assert.Equal(t, services.RelationshipBlocked, updates[0].Data["type"])
+
+	// Blocking a friend removes the friendship on the other side
+	updates = RelationshipUpdatesToWS(&services.UserBlockedEvent{UserID: blockerID, BlockedID: blockedID, Severed: true})
+	require.Len(t, updates, 2)
+	assert.Equal(t, blockedID, updates[1].UserID)
+	assert.Equal(t, EventTypeRelationshipRemove, updates[1].Type)
+	assert.Equal(t, blockerID.String(), updates[1].Data["id"])
+	assert.NotContains(t, updates[1].Data, "type")
+
+	assert.Nil(t, RelationshipUpdatesToWS(&services.MessageAckEvent{}))
+}
+
+func TestVoiceUpdateToWS(t *testing.T) {
+	serverID := uuid.New()
+	channelID := uuid.New()
+	userID := uuid.New()
+	viewerID := uuid.New()
+
+	stream := &voice.Stream{
+		Key:             voice.StreamKey(channelID, userID),
+		UserID:          userID,
+		ChannelID:       channelID,
+		ServerID:        &serverID,
+		ApplicationName: "Factorio",
+		Viewers:         []uuid.UUID{viewerID},
+	}
+
+	update := VoiceUpdateToWS(events.StreamCreated, stream)
+	require.NotNil(t, update)
+	assert.Equal(t, EventTypeStreamCreate, update.Type)
+	assert.Equal(t, &serverID, update.ServerID)
+	assert.Equal(t, "Factorio", update.Data["application_name"])
+	assert.Equal(t, []string{viewerID.String()}, update.Data["viewer_ids"])
+	assert.Equal(t, 1, update.Data["viewer_count"])
+
+	update = VoiceUpdateToWS(events.StreamDeleted, stream)
+	require.NotNil(t, update)
+	assert.Equal(t, EventTypeStreamDelete, update.Type)
+	assert.Equal(t, stream.Key, update.Data["stream_key"])
+	assert.NotContains(t, update.Data, "viewer_ids")
+
+	// Leaving a DM call is still routed to the channel
+	update = VoiceUpdateToWS(events.VoiceStateUpdated, &voice.StateEvent{
+		ChannelID: channelID,
+		State:     &models.VoiceState{UserID: userID},
+	})
+	require.NotNil(t, update)
+	assert.Equal(t, EventVoiceStateUpdate, update.Type)
+	assert.Nil(t, update.ServerID)
+	assert.Equal(t, channelID, update.ChannelID)
+	assert.Nil(t, update.Data["channel_id"])
+	assert.Equal(t, false, update.Data["self_stream"])
+
+	assert.Nil(t, VoiceUpdateToWS(events.StreamCreated, &services.MessageAckEvent{}))
+}
+
+func TestThreadUpdateToWS(t *testing.T) {
+	channelID := uuid.New()
+	thread := &models.Thread{ID: uuid.New(), ParentChannelID: channelID, Name: "release plan"}
+
+	update := ThreadUpdateToWS(&services.ThreadCreatedEvent{Thread: thread, ChannelID: channelID})
+	require.NotNil(t, update)
+	assert.Equal(t, EventTypeThreadCreate, update.Type)
+	assert.Equal(t, channelID, update.ChannelID)
+	assert.Equal(t, thread, update.Data)
+
+	update = ThreadUpdateToWS(&services.ThreadUpdatedEvent{Thread: thread, ChannelID: channelID})
+	require.NotNil(t, update)
+	assert.Equal(t, EventTypeThreadUpdate, update.Type)
+
+	update = ThreadUpdateToWS(&services.ThreadDeletedEvent{ThreadID: thread.ID, ChannelID: channelID})
+	require.NotNil(t, update)
+	assert.Equal(t, EventTypeThreadDelete, update.Type)
+	assert.Equal(t, map[string]interface{}{
+		"id":                thread.ID.String(),
+		"parent_channel_id": channelID.String(),
+	}, update.Data)
+
+	msg := &models.ThreadMessage{ID: uuid.New(), ThreadID: thread.ID, Content: "hi"}
+	update = ThreadUpdateToWS(&services.ThreadMessageCreatedEvent{Message: msg, ThreadID: thread.ID, ChannelID: channelID})
+	require.NotNil(t, update)
+	assert.Equal(t, EventTypeThreadMessageCreate, update.Type)
+	assert.Equal(t, channelID, update.ChannelID)
+	assert.Equal(t, msg, update.Data)
+
+	assert.Nil(t, ThreadUpdateToWS(&services.MessageAckEvent{}))
+}
+
+func TestAnnouncementUpdateToWS(t *testing.T) {
+	announcement := &models.Announcement{ID: uuid.New(), Content: "hi", Level: models.AnnouncementInfo}
+
+	update := AnnouncementUpdateToWS(&services.AnnouncementCreatedEvent{Announcement: announcement})
+	require.NotNil(t, update)
+	assert.Equal(t, EventTypeAnnouncementCreate, update.Type)
+	assert.Equal(t, announcement, update.Data)
+
+	update = AnnouncementUpdateToWS(&services.AnnouncementDeletedEvent{ID: announcement.ID})
+	require.NotNil(t, update)
+	assert.Equal(t, EventTypeAnnouncementDelete, update.Type)
+	assert.Equal(t, map[string]interface{}{"id": announcement.ID.String()}, update.Data)
+
+	mode := &models.ReadOnlyMode{Enabled: true}
+	update = AnnouncementUpdateToWS(&services.ReadOnlyModeUpdatedEvent{Mode: mode})
+	require.NotNil(t, update)
+	assert.Equal(t, EventTypeReadOnlyModeUpdate, update.Type)
+	assert.Equal(t, mode, update.Data)
+
+	assert.Nil(t, AnnouncementUpdateToWS(&services.MessageAckEvent{}))
+}
+
 func TestEventBridge_onPresenceUpdate(t *testing.T) {
 	hub := NewHub()
 	ctx, cancel := context.WithCancel(context.Background())
@@ -851,6 +1202,45 @@ func TestEventBridge_onTypingStarted(t *testing.T) {
 	}
 }
 
+func TestEventBridge_onTypingStopped(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	bus := events.NewBus()
+	_ = NewEventBridge(hub, bus)
+
+	userID := uuid.New()
+	channelID := uuid.New()
+	client := newMockClient(hub, uuid.New())
+	hub.register <- client
+	time.Sleep(50 * time.Millisecond)
+	hub.SubscribeChannel(client, channelID)
+
+	// The typing service publishes its indicators
+	stoppedAt := time.Now()
+	bus.Publish(events.TypingStopped, &models.TypingIndicator{
+		ChannelID: channelID,
+		UserID:    userID,
+		Timestamp: stoppedAt,
+	})
+
+	select {
+	case data := <-client.send:
+		var event struct {
+			Type string          `json:"t"`
+			Data TypingStartData `json:"d"`
+		}
+		require.NoError(t, json.Unmarshal(data, &event))
+		assert.Equal(t, EventTypeTypingStop, event.Type)
+		assert.Equal(t, userID.String(), event.Data.UserID)
+		assert.Equal(t, stoppedAt.Unix(), event.Data.Timestamp)
+	case <-time.After(time.Second):
+		t.Fatal("Did not receive typing stop event")
+	}
+}
+
 func TestEventBridge_onMemberUpdated(t *testing.T) {
 	hub := NewHub()
 	ctx, cancel := context.WithCancel(context.Background())
@@ -1003,6 +1393,51 @@ func TestEventBridge_ConversionHelpers(t *testing.T) {
 		assert.NotNil(t, result["edited_timestamp"])
 	})
 
+	t.Run("messageToWS with embeds", func(t *testing.T) {
+		link := "https://example.com"
+		msg := &models.Message{
+			ID:        uuid.New(),
+			ChannelID: uuid.New(),
+			AuthorID:  uuid.New(),
+			Content:   link,
+			CreatedAt: time.Now(),
+			Embeds:    []models.Embed{{Type: "link", URL: &link}},
+		}
+
+		result := bridge.messageToWS(msg)
+		require.NotNil(t, result)
+		assert.Equal(t, msg.Embeds, result["embeds"])
+	})
+
+	t.Run("messageToWS with member", func(t *testing.T) {
+		serverID := uuid.New()
+		roleID := uuid.New()
+		nickname := "Bobby"
+		msg := &models.Message{
+			ID:        uuid.New(),
+			ChannelID: uuid.New(),
+			ServerID:  &serverID,
+			AuthorID:  uuid.New(),
+			CreatedAt: time.Now(),
+			Member: &models.Member{
+				ServerID: serverID,
+				Nickname: &nickname,
+				Roles:    []uuid.UUID{roleID},
+				JoinedAt: time.Now(),
+			},
+		}
+
+		result := bridge.messageToWS(msg)
+		require.NotNil(t, result)
+		member, ok := result["member"].(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, "Bobby", member["nick"])
+		assert.Equal(t, []string{roleID.String()}, member["roles"])
+
+		msg.Member = nil
+		assert.NotContains(t, bridge.messageToWS(msg), "member")
+	})
+
 	t.Run("channelToWS with valid channel", func(t *testing.T) {
 		serverID := uuid.New()
 		parentID := uuid.New()
@@ -1454,5 +1889,74 @@ func TestEventBridge_buildMemberData(t *testing.T) {
 func TestEventBridge_Constants(t *testing.T) {
 	assert.Equal(t, "CHANNEL_PINS_UPDATE", EventTypeChannelPinsUpdate)
 	assert.Equal(t, "GUILD_BAN_ADD", EventTypeBanAdd)
+	assert.Equal(t, "GUILD_BAN_REMOVE", EventTypeBanRemove)
 	assert.Equal(t, "USER_UPDATE", EventTypeUserUpdate)
 }
+
+func TestMessagesBulkDeletedToWS(t *testing.T) {
+	serverID := uuid.New()
+	channelID := uuid.New()
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+
+	payload := MessagesBulkDeletedToWS(&services.MessagesBulkDeletedEvent{
+		MessageIDs: ids,
+		ChannelID:  channelID,
+		ServerID:   &serverID,
+	})
+
+	assert.Equal(t, []string{ids[0].String(), ids[1].String()}, payload["ids"])
+	assert.Equal(t, channelID.String(), payload["channel_id"])
+	assert.Equal(t, serverID.String(), payload["guild_id"])
+}
+
+func TestMemberUpdatedToWS(t *testing.T) {
+	serverID := uuid.New()
+	userID := uuid.New()
+	until := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	payload := MemberUpdatedToWS(&services.MemberUpdatedEvent{
+		ServerID: serverID,
+		UserID:   userID,
+		Member:   &models.Member{ServerID: serverID, UserID: userID, CommunicationDisabledUntil: &until},
+	})
+
+	assert.Equal(t, serverID.String(), payload["guild_id"])
+	assert.Equal(t, map[string]interface{}{"id": userID.String()}, payload["user"])
+	assert.Equal(t, "2026-03-01T12:00:00Z", payload["communication_disabled_until"])
+
+	// Lifted timeouts are sent as null so clients clear them
+	payload = MemberUpdatedToWS(&services.MemberUpdatedEvent{
+		ServerID: serverID,
+		UserID:   userID,
+		Member:   &models.Member{ServerID: serverID, UserID: userID},
+	})
+	assert.Contains(t, payload, "communication_disabled_until")
+	assert.Nil(t, payload["communication_disabled_until"])
+}
+
+func TestMemberJoinedToWS(t *testing.T) {
+	serverID := uuid.New()
+	userID := uuid.New()
+
+	payload := MemberJoinedToWS(&services.MemberJoinedEvent{
+		ServerID: serverID,
+		UserID:   userID,
+		Member:   &models.Member{ServerID: serverID, UserID: userID, Pending: true},
+	})
+	assert.Equal(t, serverID.String(), payload["guild_id"])
+	assert.Equal(t, map[string]interface{}{"id": userID.String()}, payload["user"])
+	assert.Equal(t, true, payload["pending"])
+
+	payload = MemberJoinedToWS(&services.MemberJoinedEvent{ServerID: serverID, UserID: userID})
+	assert.Equal(t, false, payload["pending"])
+}
+
+func TestMemberUnbannedToWS(t *testing.T) {
+	serverID := uuid.New()
+	userID := uuid.New()
+
+	payload := MemberUnbannedToWS(&services.MemberUnbannedEvent{ServerID: serverID, UserID: userID})
+
+	assert.Equal(t, serverID.String(), payload["guild_id"])
+	assert.Equal(t, map[string]interface{}{"id": userID.String()}, payload["user"])
+}
@@ -0,0 +1,133 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Capabilities is a bitfield sent by the client in IDENTIFY describing the
+// payload features it understands. Clients that send no capabilities get
+// the legacy shapes, so older builds keep working unchanged.
+type Capabilities uint64
+
+const (
+	// CapabilityOmitLegacyFields drops null values and empty arrays from
+	// dispatch payloads. Old clients rely on every key being present.
+	CapabilityOmitLegacyFields Capabilities = 1 << iota
+
+	// CapabilityDeltaSync stamps every dispatch with a per-session sequence
+	// and, on resume, replays only events after the client's last seen seq
+	// instead of the whole buffer.
+	CapabilityDeltaSync
+)
+
+// SupportedCapabilities is the set of capability bits this server honours.
+// Unknown bits sent by newer clients are ignored rather than rejected.
+const SupportedCapabilities = CapabilityOmitLegacyFields | CapabilityDeltaSync
+
+var capabilityNames = []struct {
+	bit  Capabilities
+	name string
+}{
+	{CapabilityOmitLegacyFields, "omit_legacy_fields"},
+	{CapabilityDeltaSync, "delta_sync"},
+}
+
+// Has reports whether every bit in flag is set
+func (c Capabilities) Has(flag Capabilities) bool {
+	return c&flag == flag
+}
+
+// Negotiate returns the subset of the requested capabilities the server supports
+func (c Capabilities) Negotiate() Capabilities {
+	return c & SupportedCapabilities
+}
+
+// String returns the capability names joined with "|"
+func (c Capabilities) String() string {
+	if c == 0 {
+		return "none"
+	}
+	var names []string
+	for _, cn := range capabilityNames {
+		if c.Has(cn.bit) {
+			names = append(names, cn.name)
+			c &^= cn.bit
+		}
+	}
+	if c != 0 {
+		names = append(names, "0x"+strconv.FormatUint(uint64(c), 16))
+	}
+	return strings.Join(names, "|")
+}
+
+// shapeOutbound rewrites a serialized frame for a client with the given
+// capabilities. seq is the session sequence to stamp when delta sync is on.
+// Frames that don't need changing are returned as-is.
+func (c Capabilities) shapeOutbound(data []byte, seq int64) []byte {
+	if c == 0 {
+		return data
+	}
+
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Op != OpDispatch {
+		return data
+	}
+
+	if c.Has(CapabilityDeltaSync) && msg.Sequence == 0 {
+		msg.Sequence = seq
+	}
+	if c.Has(CapabilityOmitLegacyFields) {
+		msg.Data = omitEmptyFields(msg.Data)
+	}
+
+	shaped, err := json.Marshal(&msg)
+	if err != nil {
+		return data
+	}
+	return shaped
+}
+
+// omitEmptyFields drops top-level null and empty-array members from a JSON
+// object. Non-object payloads are returned unchanged.
+func omitEmptyFields(d json.RawMessage) json.RawMessage {
+	if len(d) == 0 || d[0] != '{' {
+		return d
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(d, &fields); err != nil {
+		return d
+	}
+
+	changed := false
+	for key, value := range fields {
+		v := bytes.TrimSpace(value)
+		if bytes.Equal(v, []byte("null")) || bytes.Equal(v, []byte("[]")) {
+			delete(fields, key)
+			changed = true
+		}
+	}
+	if !changed {
+		return d
+	}
+
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return d
+	}
+	return out
+}
+
+// frameSequence extracts the "s" field from a serialized frame
+func frameSequence(data []byte) int64 {
+	var frame struct {
+		Sequence int64 `json:"s"`
+	}
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return 0
+	}
+	return frame.Sequence
+}
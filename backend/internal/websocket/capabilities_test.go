@@ -0,0 +1,67 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapabilities_Negotiate(t *testing.T) {
+	requested := CapabilityOmitLegacyFields | CapabilityDeltaSync | 1<<40
+
+	caps := requested.Negotiate()
+
+	assert.Equal(t, CapabilityOmitLegacyFields|CapabilityDeltaSync, caps)
+	assert.True(t, caps.Has(CapabilityDeltaSync))
+	assert.False(t, Capabilities(0).Has(CapabilityDeltaSync))
+}
+
+func TestCapabilities_String(t *testing.T) {
+	assert.Equal(t, "none", Capabilities(0).String())
+	assert.Equal(t, "omit_legacy_fields|delta_sync", (CapabilityOmitLegacyFields | CapabilityDeltaSync).String())
+	assert.Equal(t, "delta_sync|0x100", (CapabilityDeltaSync | 1<<8).String())
+}
+
+func TestCapabilities_ShapeOutbound_Legacy(t *testing.T) {
+	frame := []byte(`{"op":0,"t":"MESSAGE_CREATE","d":{"id":"1","embeds":[],"edited_timestamp":null}}`)
+
+	// Clients without capabilities get the frame byte-for-byte
+	assert.Equal(t, frame, Capabilities(0).shapeOutbound(frame, 7))
+}
+
+func TestCapabilities_ShapeOutbound_OmitLegacyFields(t *testing.T) {
+	frame := []byte(`{"op":0,"t":"MESSAGE_CREATE","d":{"id":"1","content":"","embeds":[],"edited_timestamp":null}}`)
+
+	shaped := CapabilityOmitLegacyFields.shapeOutbound(frame, 0)
+
+	var msg Message
+	require.NoError(t, json.Unmarshal(shaped, &msg))
+	var d map[string]interface{}
+	require.NoError(t, json.Unmarshal(msg.Data, &d))
+	assert.Equal(t, map[string]interface{}{"id": "1", "content": ""}, d)
+	assert.Equal(t, int64(0), msg.Sequence)
+}
+
+func TestCapabilities_ShapeOutbound_DeltaSync(t *testing.T) {
+	frame := []byte(`{"op":0,"t":"TYPING_START","d":{"user_id":"1"}}`)
+
+	shaped := CapabilityDeltaSync.shapeOutbound(frame, 42)
+
+	assert.Equal(t, int64(42), frameSequence(shaped))
+}
+
+func TestCapabilities_ShapeOutbound_NonDispatch(t *testing.T) {
+	frame := []byte(`{"op":11}`)
+
+	assert.Equal(t, frame, (CapabilityOmitLegacyFields|CapabilityDeltaSync).shapeOutbound(frame, 3))
+}
+
+func TestSession_Capabilities(t *testing.T) {
+	session := &Session{}
+	assert.Equal(t, Capabilities(0), session.Capabilities())
+
+	session.SetCapabilities(CapabilityDeltaSync)
+	assert.Equal(t, CapabilityDeltaSync, session.Capabilities())
+}
@@ -3,6 +3,7 @@ package websocket
 import (
 	"encoding/json"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -31,10 +32,25 @@ type Client struct {
 	channels map[uuid.UUID]bool
 	mu       sync.RWMutex
 
+	// What the hub has the client registered under, so it can be
+	// unregistered without scanning every channel and server
+	hubServers  registrations
+	hubChannels registrations
+
 	// Session info
 	SessionID  string
 	ClientType string // "desktop", "web", "mobile"
 
+	// How hub frames are serialized for this client
+	encoding   Encoding
+	omitLegacy atomic.Bool
+
+	// Event groups the client asked for; 0 means all
+	intents atomic.Uint64
+
+	// What happens once the send queue backs up; nil drops what doesn't fit
+	outbound *outboundQueue
+
 	// Heartbeat
 	lastHeartbeat time.Time
 	sequence      int64
@@ -148,9 +164,9 @@ func (c *Client) Send(msg *Message) {
 
 // handleMessage processes an incoming WebSocket message
 func (c *Client) handleMessage(data []byte) {
-	var msg Message
-	if err := json.Unmarshal(data, &msg); err != nil {
-		c.sendError("Invalid message format")
+	msg, perr := ValidateInbound(data)
+	if perr != nil {
+		c.sendProtocolError(perr)
 		return
 	}
 
@@ -158,15 +174,15 @@ func (c *Client) handleMessage(data []byte) {
 
 	switch msg.Op {
 	case OpHeartbeat:
-		c.handleHeartbeat(&msg)
+		c.handleHeartbeat(msg)
 	case OpIdentify:
-		c.handleIdentify(&msg)
+		c.handleIdentify(msg)
 	case OpResume:
-		c.handleResume(&msg)
+		c.handleResume(msg)
 	case OpPresenceUpdate:
-		c.handlePresenceUpdate(&msg)
+		c.handlePresenceUpdate(msg)
 	default:
-		c.sendError("Unknown opcode")
+		c.sendError("Unsupported opcode")
 	}
 }
 
@@ -224,6 +240,29 @@ func (c *Client) sendError(message string) {
 	})
 }
 
+// sendProtocolError sends a structured ERROR frame and closes the
+// connection if the violation is fatal
+func (c *Client) sendProtocolError(perr *ProtocolError) {
+	data, _ := json.Marshal(perr)
+	c.Send(&Message{
+		Op:   OpDispatch,
+		Type: EventError,
+		Data: data,
+	})
+
+	if perr.Fatal && c.conn != nil {
+		// Close frame reasons are limited to 123 bytes
+		reason := perr.Message
+		if len(reason) > 123 {
+			reason = reason[:123]
+		}
+		c.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(perr.Code, reason), time.Now().Add(writeWait))
+		// ReadPump sees the closed connection and unregisters the client
+		c.conn.Close()
+	}
+}
+
 // Subscription management
 
 func (c *Client) SubscribeServer(serverID uuid.UUID) {
@@ -237,6 +276,7 @@ func (c *Client) UnsubscribeServer(serverID uuid.UUID) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	delete(c.servers, serverID)
+	c.hub.UnsubscribeServer(c, serverID)
 }
 
 func (c *Client) SubscribeChannel(channelID uuid.UUID) {
@@ -264,3 +304,31 @@ func (c *Client) IsSubscribedToChannel(channelID uuid.UUID) bool {
 	defer c.mu.RUnlock()
 	return c.channels[channelID]
 }
+
+// subscriptions returns the servers and channels the client is subscribed to
+func (c *Client) subscriptions() (servers, channels []uuid.UUID) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for id := range c.servers {
+		servers = append(servers, id)
+	}
+	for id := range c.channels {
+		channels = append(channels, id)
+	}
+	return servers, channels
+}
+
+// Intents returns the event groups the client receives; 0 means all
+func (c *Client) Intents() Intents {
+	return Intents(c.intents.Load())
+}
+
+// SetIntents changes the event groups the hub sends the client
+func (c *Client) SetIntents(intents Intents) {
+	c.intents.Store(uint64(intents))
+}
+
+// format is how hub frames are serialized for the client
+func (c *Client) format() frameFormat {
+	return frameFormat{encoding: c.encoding, omitLegacy: c.omitLegacy.Load()}
+}
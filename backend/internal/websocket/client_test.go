@@ -55,9 +55,7 @@ func TestClient_SubscribeServer(t *testing.T) {
 
 	assert.True(t, client.servers[serverID])
 
-	hub.serversMux.RLock()
-	assert.True(t, hub.servers[serverID][client])
-	hub.serversMux.RUnlock()
+	assert.True(t, hub.servers.contains(serverID, client))
 }
 
 func TestClient_UnsubscribeServer(t *testing.T) {
@@ -100,9 +98,7 @@ func TestClient_SubscribeChannel(t *testing.T) {
 
 	assert.True(t, client.channels[channelID])
 
-	hub.channelsMux.RLock()
-	assert.True(t, hub.channels[channelID][client])
-	hub.channelsMux.RUnlock()
+	assert.True(t, hub.channels.contains(channelID, client))
 }
 
 func TestClient_UnsubscribeChannel(t *testing.T) {
@@ -125,9 +121,7 @@ func TestClient_UnsubscribeChannel(t *testing.T) {
 
 	assert.False(t, client.channels[channelID])
 
-	hub.channelsMux.RLock()
-	_, exists := hub.channels[channelID]
-	hub.channelsMux.RUnlock()
+	exists := hub.channels.has(channelID)
 	assert.False(t, exists)
 }
 
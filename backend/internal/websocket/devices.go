@@ -0,0 +1,162 @@
+package websocket
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/google/uuid"
+)
+
+// Device describes a live gateway connection, as shown in the connections API
+type Device struct {
+	ID          string    `json:"id"`
+	SessionID   string    `json:"session_id"`
+	ClientType  string    `json:"client_type"`
+	OS          string    `json:"os,omitempty"`
+	Browser     string    `json:"browser,omitempty"`
+	Device      string    `json:"device,omitempty"`
+	IP          string    `json:"ip,omitempty"`
+	ConnectedAt time.Time `json:"connected_at"`
+}
+
+// liveDevice is a registered connection that can be closed from outside
+// its own read/write pumps
+type liveDevice struct {
+	Device
+	conn      *websocket.Conn
+	closeOnce sync.Once
+}
+
+// close sends a close frame and tears down the connection. WriteControl is
+// safe to call concurrently with the connection's write pump.
+func (d *liveDevice) close(code int, reason string) {
+	d.closeOnce.Do(func() {
+		if d.conn == nil {
+			return
+		}
+		d.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeWait))
+		d.conn.Close()
+	})
+}
+
+// deviceRegistry tracks live connections per user and enforces the
+// concurrent device limit. It only sees this node's connections, so the
+// limit applies per node.
+type deviceRegistry struct {
+	mu     sync.Mutex
+	byUser map[uuid.UUID][]*liveDevice
+	limit  int // 0 = unlimited
+}
+
+func newDeviceRegistry(limit int) *deviceRegistry {
+	return &deviceRegistry{
+		byUser: make(map[uuid.UUID][]*liveDevice),
+		limit:  limit,
+	}
+}
+
+// add registers a device and returns the oldest devices that must be
+// closed to stay within the limit
+func (r *deviceRegistry) add(userID uuid.UUID, d *liveDevice) []*liveDevice {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	devices := append(r.byUser[userID], d)
+
+	var evicted []*liveDevice
+	if r.limit > 0 && len(devices) > r.limit {
+		// Devices are appended in connection order, so the oldest come first
+		n := len(devices) - r.limit
+		evicted = append(evicted, devices[:n]...)
+		devices = append([]*liveDevice(nil), devices[n:]...)
+	}
+
+	r.byUser[userID] = devices
+	return evicted
+}
+
+// remove unregisters a device. Removing an already-evicted device is a no-op.
+func (r *deviceRegistry) remove(userID uuid.UUID, id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	devices := r.byUser[userID]
+	for i, d := range devices {
+		if d.ID == id {
+			devices = append(devices[:i:i], devices[i+1:]...)
+			break
+		}
+	}
+
+	if len(devices) == 0 {
+		delete(r.byUser, userID)
+	} else {
+		r.byUser[userID] = devices
+	}
+}
+
+// get returns a user's device by ID
+func (r *deviceRegistry) get(userID uuid.UUID, id string) *liveDevice {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range r.byUser[userID] {
+		if d.ID == id {
+			return d
+		}
+	}
+	return nil
+}
+
+// userDevices returns a user's live devices, for closing them
+func (r *deviceRegistry) userDevices(userID uuid.UUID) []*liveDevice {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]*liveDevice(nil), r.byUser[userID]...)
+}
+
+// setProperties records the client properties sent in IDENTIFY
+func (r *deviceRegistry) setProperties(userID uuid.UUID, id, os, browser, device string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range r.byUser[userID] {
+		if d.ID == id {
+			d.Device.OS = os
+			d.Device.Browser = browser
+			d.Device.Device = device
+			return
+		}
+	}
+}
+
+// setSessionID records the gateway session a device took over by resuming
+func (r *deviceRegistry) setSessionID(userID uuid.UUID, id, sessionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range r.byUser[userID] {
+		if d.ID == id {
+			d.Device.SessionID = sessionID
+			return
+		}
+	}
+}
+
+// list returns a snapshot of a user's devices, newest first
+func (r *deviceRegistry) list(userID uuid.UUID) []Device {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	devices := make([]Device, 0, len(r.byUser[userID]))
+	for _, d := range r.byUser[userID] {
+		devices = append(devices, d.Device)
+	}
+	sort.SliceStable(devices, func(i, j int) bool {
+		return devices[i].ConnectedAt.After(devices[j].ConnectedAt)
+	})
+	return devices
+}
@@ -0,0 +1,132 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDevice(id string, connectedAt time.Time) *liveDevice {
+	return &liveDevice{Device: Device{ID: id, SessionID: "s-" + id, ClientType: "web", ConnectedAt: connectedAt}}
+}
+
+func TestDeviceRegistry_EvictsOldest(t *testing.T) {
+	r := newDeviceRegistry(2)
+	userID := uuid.New()
+	now := time.Now()
+
+	assert.Empty(t, r.add(userID, newTestDevice("a", now)))
+	assert.Empty(t, r.add(userID, newTestDevice("b", now.Add(time.Second))))
+
+	evicted := r.add(userID, newTestDevice("c", now.Add(2*time.Second)))
+
+	require.Len(t, evicted, 1)
+	assert.Equal(t, "a", evicted[0].ID)
+	assert.Nil(t, r.get(userID, "a"))
+
+	devices := r.list(userID)
+	require.Len(t, devices, 2)
+	assert.Equal(t, "c", devices[0].ID, "newest first")
+	assert.Equal(t, "b", devices[1].ID)
+}
+
+func TestDeviceRegistry_Unlimited(t *testing.T) {
+	r := newDeviceRegistry(0)
+	userID := uuid.New()
+
+	for i := 0; i < 20; i++ {
+		assert.Empty(t, r.add(userID, newTestDevice(uuid.New().String(), time.Now())))
+	}
+	assert.Len(t, r.list(userID), 20)
+}
+
+func TestDeviceRegistry_PerUser(t *testing.T) {
+	r := newDeviceRegistry(1)
+	alice, bob := uuid.New(), uuid.New()
+
+	r.add(alice, newTestDevice("a", time.Now()))
+
+	assert.Empty(t, r.add(bob, newTestDevice("b", time.Now())))
+	assert.Len(t, r.list(alice), 1)
+	assert.Len(t, r.list(bob), 1)
+}
+
+func TestDeviceRegistry_Remove(t *testing.T) {
+	r := newDeviceRegistry(0)
+	userID := uuid.New()
+	r.add(userID, newTestDevice("a", time.Now()))
+	r.add(userID, newTestDevice("b", time.Now()))
+
+	r.remove(userID, "a")
+	r.remove(userID, "missing") // no-op
+
+	devices := r.list(userID)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "b", devices[0].ID)
+
+	r.remove(userID, "b")
+	assert.Empty(t, r.list(userID))
+	assert.NotContains(t, r.byUser, userID)
+}
+
+func TestDeviceRegistry_SetProperties(t *testing.T) {
+	r := newDeviceRegistry(0)
+	userID := uuid.New()
+	r.add(userID, newTestDevice("a", time.Now()))
+
+	r.setProperties(userID, "a", "linux", "firefox", "desktop")
+
+	devices := r.list(userID)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "linux", devices[0].OS)
+	assert.Equal(t, "firefox", devices[0].Browser)
+	assert.Equal(t, "desktop", devices[0].Device)
+}
+
+func TestGateway_UserDevices(t *testing.T) {
+	gateway := NewGateway(NewHub(), nil, nil)
+	userID := uuid.New()
+
+	assert.Empty(t, gateway.UserDevices(userID))
+	assert.False(t, gateway.DisconnectDevice(userID, "missing"))
+
+	gateway.devices.add(userID, newTestDevice("a", time.Now()))
+
+	devices := gateway.UserDevices(userID)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "s-a", devices[0].SessionID)
+
+	// Closing a device without a connection is safe
+	assert.True(t, gateway.DisconnectDevice(userID, "a"))
+}
+
+func TestGateway_UserDevices_NilRegistry(t *testing.T) {
+	gateway := &Gateway{}
+
+	assert.Empty(t, gateway.UserDevices(uuid.New()))
+	assert.False(t, gateway.DisconnectDevice(uuid.New(), "a"))
+}
+
+func TestGateway_DeviceLimitIsPerNode(t *testing.T) {
+	config := DefaultGatewayConfig()
+	config.MaxSessionsPerUser = 1
+	first, second := NewGateway(NewHub(), nil, config), NewGateway(NewHub(), nil, config)
+	userID := uuid.New()
+
+	// Each node only sees and limits its own connections
+	assert.Empty(t, first.devices.add(userID, newTestDevice("a", time.Now())))
+	assert.Empty(t, second.devices.add(userID, newTestDevice("b", time.Now())))
+
+	require.Len(t, first.UserDevices(userID), 1)
+	assert.Equal(t, "a", first.UserDevices(userID)[0].ID)
+	require.Len(t, second.UserDevices(userID), 1)
+	assert.Equal(t, "b", second.UserDevices(userID)[0].ID)
+	assert.False(t, first.DisconnectDevice(userID, "b"), "devices on another node can't be closed from here")
+
+	evicted := first.devices.add(userID, newTestDevice("c", time.Now()))
+	require.Len(t, evicted, 1)
+	assert.Equal(t, "a", evicted[0].ID)
+}
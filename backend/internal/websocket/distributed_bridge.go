@@ -16,9 +16,10 @@ import (
 // DistributedEventBridge connects the domain event bus to the DistributedHub
 // It broadcasts events to local clients AND publishes to Redis for other instances
 type DistributedEventBridge struct {
-	hub *DistributedHub
-	bus *events.Bus
-	ctx context.Context
+	hub      *DistributedHub
+	bus      *events.Bus
+	ctx      context.Context
+	throttle ServerEventThrottle
 }
 
 // NewDistributedEventBridge creates a new distributed event bridge
@@ -68,7 +69,9 @@ func (b *DistributedEventBridge) registerHandlers() {
 	b.bus.Subscribe(events.MessageCreated, b.onMessageCreated)
 	b.bus.Subscribe(events.MessageUpdated, b.onMessageUpdated)
 	b.bus.Subscribe(events.MessageDeleted, b.onMessageDeleted)
+	b.bus.Subscribe(events.MessagesBulkDeleted, b.onMessagesBulkDeleted)
 	b.bus.Subscribe(events.MessagePinned, b.onMessagePinned)
+	b.bus.Subscribe(events.MessageAcked, b.onMessageAcked)
 
 	// Reaction events
 	b.bus.Subscribe(events.ReactionAdded, b.onReactionAdded)
@@ -90,13 +93,47 @@ func (b *DistributedEventBridge) registerHandlers() {
 	b.bus.Subscribe(events.MemberUpdated, b.onMemberUpdated)
 	b.bus.Subscribe(events.MemberKicked, b.onMemberKicked)
 	b.bus.Subscribe(events.MemberBanned, b.onMemberBanned)
+	b.bus.Subscribe(events.MemberUnbanned, b.onMemberUnbanned)
+
+	// Emoji events
+	b.bus.Subscribe(events.EmojisUpdated, b.onEmojisUpdated)
+
+	// Encrypted channel key events
+	b.bus.Subscribe(events.ChannelKeysRotated, b.onChannelKeysRotated)
+	b.bus.Subscribe(events.SenderKeysDistributed, b.onSenderKeysDistributed)
+
+	// Attachment events
+	b.bus.Subscribe(events.AttachmentUpdated, b.onAttachmentUpdated)
+	b.bus.Subscribe(events.AttachmentUploadProgress, b.onAttachmentUploadProgress)
 
 	// User events
 	b.bus.Subscribe(events.UserUpdated, b.onUserUpdated)
 	b.bus.Subscribe(events.PresenceUpdate, b.onPresenceUpdate)
+	b.bus.Subscribe(events.CustomStatusUpdated, b.onCustomStatusUpdated)
+
+	// Relationship events
+	for _, eventType := range relationshipEventTypes {
+		b.bus.Subscribe(eventType, b.onRelationshipChanged)
+	}
 
 	// Typing events
 	b.bus.Subscribe(events.TypingStarted, b.onTypingStarted)
+	b.bus.Subscribe(events.TypingStopped, b.onTypingStopped)
+
+	// Voice events
+	for _, eventType := range voiceEventTypes {
+		b.bus.Subscribe(eventType, b.onVoiceChanged)
+	}
+
+	// Thread events
+	for _, eventType := range threadEventTypes {
+		b.bus.Subscribe(eventType, b.onThreadChanged)
+	}
+
+	// Instance events
+	for _, eventType := range announcementEventTypes {
+		b.bus.Subscribe(eventType, b.onAnnouncementChanged)
+	}
 }
 
 // Message event handlers
@@ -134,6 +171,16 @@ func (b *DistributedEventBridge) onMessageDeleted(event events.Event) {
 	})
 }
 
+func (b *DistributedEventBridge) onMessagesBulkDeleted(event events.Event) {
+	data, ok := event.Data.(*services.MessagesBulkDeletedEvent)
+	if !ok {
+		log.Printf("[DistributedEventBridge] onMessagesBulkDeleted: wrong type %T", event.Data)
+		return
+	}
+	log.Printf("[DistributedEventBridge] Broadcasting MESSAGE_DELETE_BULK (%d) to channel %s (distributed)", len(data.MessageIDs), data.ChannelID)
+	b.sendToChannelDistributed(data.ChannelID, EventMessageDeleteBulk, MessagesBulkDeletedToWS(data))
+}
+
 func (b *DistributedEventBridge) onMessagePinned(event events.Event) {
 	data, ok := event.Data.(*services.MessagePinnedEvent)
 	if !ok {
@@ -213,6 +260,12 @@ func (b *DistributedEventBridge) onServerUpdated(event events.Event) {
 }
 
 func (b *DistributedEventBridge) onServerDeleted(event events.Event) {
+	if deleted, ok := event.Data.(*services.ServerDeletedEvent); ok {
+		b.sendToServerDistributed(deleted.ServerID, EventTypeServerDelete, map[string]interface{}{
+			"id": deleted.ServerID.String(),
+		})
+		return
+	}
 	data, ok := event.Data.(*ServerEventData)
 	if !ok {
 		return
@@ -225,6 +278,10 @@ func (b *DistributedEventBridge) onServerDeleted(event events.Event) {
 // Member event handlers
 
 func (b *DistributedEventBridge) onMemberJoined(event events.Event) {
+	if joined, ok := event.Data.(*services.MemberJoinedEvent); ok {
+		b.sendToServerDistributed(joined.ServerID, EventTypeMemberJoin, MemberJoinedToWS(joined))
+		return
+	}
 	data, ok := event.Data.(*MemberEventData)
 	if !ok {
 		return
@@ -241,6 +298,10 @@ func (b *DistributedEventBridge) onMemberLeft(event events.Event) {
 }
 
 func (b *DistributedEventBridge) onMemberUpdated(event events.Event) {
+	if updated, ok := event.Data.(*services.MemberUpdatedEvent); ok {
+		b.sendToServerDistributed(updated.ServerID, EventTypeMemberUpdate, MemberUpdatedToWS(updated))
+		return
+	}
 	data, ok := event.Data.(*MemberEventData)
 	if !ok {
 		return
@@ -266,6 +327,57 @@ func (b *DistributedEventBridge) onMemberBanned(event events.Event) {
 	b.sendToServerDistributed(data.ServerID, EventTypeBanAdd, b.buildMemberData(data, false))
 }
 
+func (b *DistributedEventBridge) onMemberUnbanned(event events.Event) {
+	data, ok := event.Data.(*services.MemberUnbannedEvent)
+	if !ok {
+		return
+	}
+	b.sendToServerDistributed(data.ServerID, EventTypeBanRemove, MemberUnbannedToWS(data))
+}
+
+func (b *DistributedEventBridge) onEmojisUpdated(event events.Event) {
+	data, ok := event.Data.(*services.EmojisUpdatedEvent)
+	if !ok {
+		return
+	}
+	b.sendToServerDistributed(data.ServerID, EventTypeEmojisUpdate, EmojisUpdatedToWS(data))
+}
+
+func (b *DistributedEventBridge) onChannelKeysRotated(event events.Event) {
+	data, ok := event.Data.(*services.ChannelKeysRotatedEvent)
+	if !ok {
+		return
+	}
+	b.sendToChannelDistributed(data.ChannelID, EventTypeChannelKeysRotate, ChannelKeysRotatedToWS(data))
+}
+
+func (b *DistributedEventBridge) onSenderKeysDistributed(event events.Event) {
+	data, ok := event.Data.(*services.SenderKeysDistributedEvent)
+	if !ok {
+		return
+	}
+	payload := SenderKeysDistributedToWS(data)
+	for _, recipientID := range data.RecipientIDs {
+		b.sendToUserDistributed(recipientID, EventTypeChannelSenderKeys, payload)
+	}
+}
+
+func (b *DistributedEventBridge) onAttachmentUpdated(event events.Event) {
+	data, ok := event.Data.(*services.AttachmentUpdatedEvent)
+	if !ok || data.ChannelID == uuid.Nil {
+		return
+	}
+	b.sendToChannelDistributed(data.ChannelID, EventTypeAttachmentUpdate, AttachmentUpdatedToWS(data))
+}
+
+func (b *DistributedEventBridge) onAttachmentUploadProgress(event events.Event) {
+	data, ok := event.Data.(*services.AttachmentUploadProgressEvent)
+	if !ok {
+		return
+	}
+	b.sendToUserDistributed(data.UserID, EventTypeAttachmentUploadProgress, UploadProgressToWS(data))
+}
+
 // buildMemberData creates the common member event payload
 func (b *DistributedEventBridge) buildMemberData(data *MemberEventData, includeJoinedAt bool) map[string]interface{} {
 	wsData := map[string]interface{}{
@@ -295,6 +407,22 @@ func (b *DistributedEventBridge) onUserUpdated(event events.Event) {
 }
 
 func (b *DistributedEventBridge) onPresenceUpdate(event events.Event) {
+	// Presence tracked by the hub arrives once per shared server, or once
+	// for the friends of users who show it to friends only
+	if update, ok := event.Data.(*services.PresenceUpdateEvent); ok {
+		if update.RecipientIDs != nil {
+			payload := PresenceToWS(update.Presence, nil)
+			for _, recipientID := range update.RecipientIDs {
+				b.sendToUserDistributed(recipientID, EventTypePresenceUpdate, payload)
+			}
+			return
+		}
+		if allowServerEvent(b.throttle, update.ServerID) {
+			b.sendToServerDistributed(update.ServerID, EventTypePresenceUpdate, PresenceToWS(update.Presence, &update.ServerID))
+		}
+		return
+	}
+
 	data, ok := event.Data.(*PresenceEventData)
 	if !ok {
 		return
@@ -311,27 +439,86 @@ func (b *DistributedEventBridge) onPresenceUpdate(event events.Event) {
 
 	// Send to all servers the user is in
 	for _, serverID := range data.ServerIDs {
-		b.sendToServerDistributed(serverID, EventTypePresenceUpdate, wsData)
+		if allowServerEvent(b.throttle, serverID) {
+			b.sendToServerDistributed(serverID, EventTypePresenceUpdate, wsData)
+		}
 	}
 }
 
-// Typing event handler
+func (b *DistributedEventBridge) onCustomStatusUpdated(event events.Event) {
+	data, ok := event.Data.(*services.CustomStatusUpdatedEvent)
+	if !ok {
+		return
+	}
+	// The presence listener adds the new custom status when it broadcasts
+	b.hub.RepublishPresence(data.UserID)
+}
 
-func (b *DistributedEventBridge) onTypingStarted(event events.Event) {
-	data, ok := event.Data.(*TypingEventData)
+func (b *DistributedEventBridge) onRelationshipChanged(event events.Event) {
+	for _, update := range RelationshipUpdatesToWS(event.Data) {
+		b.sendToUserDistributed(update.UserID, update.Type, update.Data)
+	}
+}
+
+func (b *DistributedEventBridge) onAnnouncementChanged(event events.Event) {
+	update := AnnouncementUpdateToWS(event.Data)
+	if update == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(b.ctx, 5*time.Second)
+	defer cancel()
+
+	if err := b.hub.SendToAllDistributed(ctx, update.Type, update.Data); err != nil {
+		log.Printf("[DistributedEventBridge] failed to send %s to everyone: %v", update.Type, err)
+	}
+}
+
+func (b *DistributedEventBridge) onThreadChanged(event events.Event) {
+	update := ThreadUpdateToWS(event.Data)
+	if update == nil {
+		return
+	}
+	b.sendToChannelDistributed(update.ChannelID, update.Type, update.Data)
+}
+
+func (b *DistributedEventBridge) onVoiceChanged(event events.Event) {
+	update := VoiceUpdateToWS(event.Type, event.Data)
+	if update == nil {
+		return
+	}
+	if update.ServerID != nil {
+		b.sendToServerDistributed(*update.ServerID, update.Type, update.Data)
+	} else {
+		b.sendToChannelDistributed(update.ChannelID, update.Type, update.Data)
+	}
+}
+
+func (b *DistributedEventBridge) onMessageAcked(event events.Event) {
+	data, ok := event.Data.(*services.MessageAckEvent)
 	if !ok {
 		return
 	}
+	b.sendToUserDistributed(data.UserID, EventTypeMessageAck, MessageAckToWS(data))
+	if len(data.ReceiptRecipients) > 0 {
+		receipt := ReadReceiptToWS(data)
+		for _, recipientID := range data.ReceiptRecipients {
+			b.sendToUserDistributed(recipientID, EventTypeMessageRead, receipt)
+		}
+	}
+}
 
-	wsData := TypingStartData{
-		ChannelID: data.ChannelID.String(),
-		UserID:    data.UserID.String(),
-		Timestamp: 0, // Would use actual timestamp
+// Typing event handler
+
+func (b *DistributedEventBridge) onTypingStarted(event events.Event) {
+	if channelID, wsData, ok := typingToWS(event.Data); ok {
+		b.sendToChannelDistributed(channelID, EventTypeTypingStart, wsData)
 	}
-	if data.ServerID != nil {
-		wsData.GuildID = data.ServerID.String()
+}
+
+func (b *DistributedEventBridge) onTypingStopped(event events.Event) {
+	if channelID, wsData, ok := typingToWS(event.Data); ok {
+		b.sendToChannelDistributed(channelID, EventTypeTypingStop, wsData)
 	}
-	b.sendToChannelDistributed(data.ChannelID, EventTypeTypingStart, wsData)
 }
 
 // Conversion helpers
@@ -359,6 +546,10 @@ func (b *DistributedEventBridge) messageToWS(msg *models.Message) map[string]int
 		result["guild_id"] = msg.ServerID.String()
 	}
 
+	if len(msg.Embeds) > 0 {
+		result["embeds"] = msg.Embeds
+	}
+
 	if msg.Author != nil {
 		result["author"] = b.publicUserToWS(msg.Author)
 	} else {
@@ -367,6 +558,10 @@ func (b *DistributedEventBridge) messageToWS(msg *models.Message) map[string]int
 		}
 	}
 
+	if msg.Member != nil {
+		result["member"] = MemberToWS(msg.Member)
+	}
+
 	if msg.EditedAt != nil && !msg.EditedAt.IsZero() {
 		ts := msg.EditedAt.Format("2006-01-02T15:04:05.000Z")
 		result["edited_timestamp"] = ts
@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
+	"hearth/internal/models"
 	"hearth/internal/pubsub"
 )
 
@@ -22,6 +24,16 @@ type DistributedHub struct {
 	localServerSubs  map[uuid.UUID]int
 	localUserSubs    map[uuid.UUID]int
 	localSubsMux     sync.RWMutex
+
+	// Users coming online and going offline here, for their routes
+	userRoutes      chan userRoute
+	userRouteWorker sync.Once
+}
+
+// userRoute is a user whose route to this node is to be added or removed
+type userRoute struct {
+	userID uuid.UUID
+	online bool
 }
 
 // NewDistributedHub creates a hub with Redis pub/sub support
@@ -32,6 +44,16 @@ func NewDistributedHub(ps *pubsub.PubSub) *DistributedHub {
 		localChannelSubs: make(map[uuid.UUID]int),
 		localServerSubs:  make(map[uuid.UUID]int),
 		localUserSubs:    make(map[uuid.UUID]int),
+		userRoutes:       make(chan userRoute, 1024),
+	}
+
+	// Share presence with the other instances
+	dh.Hub.presenceStore = &redisPresenceStore{ps: ps}
+
+	// With node routing, events for a user reach this node only while it
+	// has told the others it hosts them
+	if ps.NodeRouting() {
+		dh.Hub.userListener = dh.routeUser
 	}
 
 	// Register handler for incoming pub/sub messages
@@ -48,6 +70,16 @@ func NewDistributedHubWithDrainConfig(ps *pubsub.PubSub, drainConfig *DrainConfi
 		localChannelSubs: make(map[uuid.UUID]int),
 		localServerSubs:  make(map[uuid.UUID]int),
 		localUserSubs:    make(map[uuid.UUID]int),
+		userRoutes:       make(chan userRoute, 1024),
+	}
+
+	// Share presence with the other instances
+	dh.Hub.presenceStore = &redisPresenceStore{ps: ps}
+
+	// With node routing, events for a user reach this node only while it
+	// has told the others it hosts them
+	if ps.NodeRouting() {
+		dh.Hub.userListener = dh.routeUser
 	}
 
 	// Register handler for incoming pub/sub messages
@@ -63,6 +95,9 @@ func (dh *DistributedHub) Run(ctx context.Context) {
 		log.Printf("Failed to subscribe to global pub/sub: %v", err)
 	}
 
+	// Keep this instance's presence entries from expiring
+	go dh.Hub.runPresenceRefresh(ctx)
+
 	// Run the base hub
 	dh.Hub.Run(ctx)
 }
@@ -86,6 +121,8 @@ func (dh *DistributedHub) handlePubSubMessage(msg *pubsub.BroadcastMessage) {
 	if msg.UserID != nil {
 		event.UserID = msg.UserID
 	}
+	// Messages without a target were published to the global channel
+	event.Everyone = msg.ChannelID == nil && msg.ServerID == nil && msg.UserID == nil
 
 	// Use base hub's local broadcast (don't re-publish to Redis)
 	dh.Hub.handleBroadcast(event)
@@ -138,7 +175,8 @@ func (dh *DistributedHub) SubscribeServer(client *Client, serverID uuid.UUID) {
 
 // UnsubscribeServer unsubscribes a client from a server with Redis tracking
 func (dh *DistributedHub) UnsubscribeServer(client *Client, serverID uuid.UUID) {
-	// Note: Base hub doesn't have UnsubscribeServer, so just manage Redis
+	dh.Hub.UnsubscribeServer(client, serverID)
+
 	dh.localSubsMux.Lock()
 	dh.localServerSubs[serverID]--
 	if dh.localServerSubs[serverID] <= 0 {
@@ -162,6 +200,23 @@ func (dh *DistributedHub) SubscribeUser(userID uuid.UUID) {
 	dh.localSubsMux.Unlock()
 }
 
+// routeUser queues the user's route for updating. A single worker updates
+// routes in order, so a quick reconnect can't end with the route removed.
+func (dh *DistributedHub) routeUser(userID uuid.UUID, online bool) {
+	dh.userRouteWorker.Do(func() {
+		go func() {
+			for route := range dh.userRoutes {
+				if route.online {
+					dh.SubscribeUser(route.userID)
+				} else {
+					dh.UnsubscribeUser(route.userID)
+				}
+			}
+		}()
+	})
+	dh.userRoutes <- userRoute{userID: userID, online: online}
+}
+
 // UnsubscribeUser unsubscribes from a user's events
 func (dh *DistributedHub) UnsubscribeUser(userID uuid.UUID) {
 	dh.localSubsMux.Lock()
@@ -237,26 +292,25 @@ func (dh *DistributedHub) SendToUserDistributed(ctx context.Context, userID uuid
 	return dh.BroadcastDistributed(ctx, event)
 }
 
+// SendToAllDistributed sends to every client on every instance
+func (dh *DistributedHub) SendToAllDistributed(ctx context.Context, eventType string, data interface{}) error {
+	event := &Event{
+		Op:       0,
+		Type:     eventType,
+		Data:     data,
+		Everyone: true,
+	}
+	return dh.BroadcastDistributed(ctx, event)
+}
+
 // Stats returns hub statistics including pub/sub info
 func (dh *DistributedHub) Stats() map[string]interface{} {
 	dh.localSubsMux.RLock()
 	defer dh.localSubsMux.RUnlock()
 
-	dh.clientsMux.RLock()
-	clientCount := 0
-	for _, clients := range dh.clients {
-		clientCount += len(clients)
-	}
-	userCount := len(dh.clients)
-	dh.clientsMux.RUnlock()
-
-	dh.channelsMux.RLock()
-	channelSubCount := len(dh.channels)
-	dh.channelsMux.RUnlock()
-
-	dh.serversMux.RLock()
-	serverSubCount := len(dh.servers)
-	dh.serversMux.RUnlock()
+	userCount, clientCount := dh.clients.stats()
+	channelSubCount, _ := dh.channels.stats()
+	serverSubCount, _ := dh.servers.stats()
 
 	return map[string]interface{}{
 		"clients":              clientCount,
@@ -269,3 +323,42 @@ func (dh *DistributedHub) Stats() map[string]interface{} {
 		"pubsub_stats":         dh.pubsub.Stats(),
 	}
 }
+
+// redisPresenceStore keeps each instance's presence view in Redis
+type redisPresenceStore struct {
+	ps *pubsub.PubSub
+}
+
+func (s *redisPresenceStore) Save(ctx context.Context, presence *models.Presence) error {
+	data, err := json.Marshal(presence)
+	if err != nil {
+		return err
+	}
+	return s.ps.SetNodePresence(ctx, presence.UserID, data, presenceTTL)
+}
+
+func (s *redisPresenceStore) Remove(ctx context.Context, userID uuid.UUID) error {
+	return s.ps.ClearNodePresence(ctx, userID)
+}
+
+func (s *redisPresenceStore) Load(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*models.Presence, error) {
+	stored, err := s.ps.GetNodePresences(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := make(map[uuid.UUID]*models.Presence, len(stored))
+	for userID, raw := range stored {
+		views := make([]*models.Presence, 0, len(raw))
+		for _, data := range raw {
+			var p models.Presence
+			if err := json.Unmarshal(data, &p); err != nil {
+				continue
+			}
+			views = append(views, &p)
+		}
+		result[userID] = mergePresences(userID, views, now)
+	}
+	return result, nil
+}
@@ -18,6 +18,18 @@ type DrainConfig struct {
 
 	// GracePeriod is the time between sending reconnect signal and force-closing connections
 	GracePeriod time.Duration
+
+	// ReconnectURL is the gateway URL sent to clients in the RECONNECT payload.
+	// It should point at healthy nodes (usually the load balancer), not this node.
+	ReconnectURL string
+
+	// StaggerWindow spreads reconnect signals over this duration to avoid a
+	// thundering herd on the remaining nodes. Clamped to GracePeriod.
+	StaggerWindow time.Duration
+
+	// ReconnectBatchSize is the number of clients signalled per stagger step.
+	// Zero means all clients are signalled at once.
+	ReconnectBatchSize int
 }
 
 // DefaultDrainConfig returns sensible defaults for connection draining
@@ -54,14 +66,39 @@ func (s DrainState) String() string {
 	}
 }
 
+// DrainProgress is a point-in-time snapshot of a drain, exposed via the admin API
+type DrainProgress struct {
+	State        string     `json:"state"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	ElapsedMs    int64      `json:"elapsed_ms"`
+	TotalClients int        `json:"total_clients"`
+	Notified     int        `json:"notified"`
+	Failed       int        `json:"failed"`
+	Remaining    int        `json:"remaining"`
+	ForceClosed  int        `json:"force_closed"`
+	HandedOff    int        `json:"handed_off"`
+	ReconnectURL string     `json:"reconnect_url,omitempty"`
+}
+
 // DrainManager manages graceful connection draining
 type DrainManager struct {
 	config *DrainConfig
 	state  atomic.Int32
 
+	// Progress tracking
+	startedAt    atomic.Int64 // unix nanos, 0 until draining starts
+	totalClients atomic.Int64
+	notified     atomic.Int64
+	failed       atomic.Int64
+	forceClosed  atomic.Int64
+	handedOff    atomic.Int64
+
 	// Callback to get all active clients
 	getClients func() []*Client
 
+	// Saves each client's session before it's told to reconnect (optional)
+	handoff SessionHandoff
+
 	// Callback when draining is complete
 	onDrainComplete func()
 
@@ -101,6 +138,42 @@ func (dm *DrainManager) IsDraining() bool {
 	return dm.State() == DrainStateDraining
 }
 
+// Progress returns the current drain progress
+func (dm *DrainManager) Progress() DrainProgress {
+	p := DrainProgress{
+		State:        dm.State().String(),
+		TotalClients: int(dm.totalClients.Load()),
+		Notified:     int(dm.notified.Load()),
+		Failed:       int(dm.failed.Load()),
+		ForceClosed:  int(dm.forceClosed.Load()),
+		HandedOff:    int(dm.handedOff.Load()),
+		ReconnectURL: dm.config.ReconnectURL,
+	}
+
+	if started := dm.startedAt.Load(); started != 0 {
+		t := time.Unix(0, started)
+		p.StartedAt = &t
+		p.ElapsedMs = time.Since(t).Milliseconds()
+	}
+
+	if dm.getClients != nil {
+		p.Remaining = len(dm.getClients())
+	}
+
+	return p
+}
+
+// SessionHandoff saves a client's session so it can be resumed on another
+// node, returning the session ID and last sequence to send in its
+// RECONNECT. ok is false if the session can't be handed off.
+type SessionHandoff func(ctx context.Context, client *Client) (sessionID string, seq int64, ok bool)
+
+// SetSessionHandoff makes the drain hand each client's session off before
+// telling it to reconnect (must be called before StartDrain)
+func (dm *DrainManager) SetSessionHandoff(fn SessionHandoff) {
+	dm.handoff = fn
+}
+
 // SetOnDrainComplete sets a callback to be invoked when draining is complete
 func (dm *DrainManager) SetOnDrainComplete(fn func()) {
 	dm.onDrainComplete = fn
@@ -118,21 +191,28 @@ func (dm *DrainManager) StartDrain(ctx context.Context) error {
 
 		// Transition to draining state
 		dm.state.Store(int32(DrainStateDraining))
+		start := time.Now()
+		dm.startedAt.Store(start.UnixNano())
 
 		// Get all active clients
 		clients := dm.getClients()
 		clientCount := len(clients)
+		dm.totalClients.Store(int64(clientCount))
 		log.Printf("[Drain] Broadcasting reconnect to %d clients", clientCount)
 
-		// Send reconnect signal to all clients
-		dm.broadcastReconnect(clients)
-
 		// Create a context with drain timeout
 		drainCtx, cancel := context.WithTimeout(ctx, dm.config.DrainTimeout)
 		defer cancel()
 
-		// Wait for grace period before checking connection counts
-		graceTicker := time.NewTimer(dm.config.GracePeriod)
+		// Send reconnect signal to all clients, staggered across the grace period
+		dm.broadcastReconnect(drainCtx, clients)
+
+		// Wait out the rest of the grace period before checking connection counts
+		graceRemaining := dm.config.GracePeriod - time.Since(start)
+		if graceRemaining < 0 {
+			graceRemaining = 0
+		}
+		graceTicker := time.NewTimer(graceRemaining)
 		defer graceTicker.Stop()
 
 		select {
@@ -171,6 +251,7 @@ func (dm *DrainManager) StartDrain(ctx context.Context) error {
 				if remaining > 0 {
 					log.Printf("[Drain] Drain timeout reached, force-closing %d connections", remaining)
 					closed := dm.ForceCloseClients(remainingClients, CloseGoingAway, "server shutdown")
+					dm.forceClosed.Add(int64(closed))
 					log.Printf("[Drain] Force-closed %d connections", closed)
 				} else {
 					log.Printf("[Drain] All connections drained before timeout")
@@ -201,38 +282,96 @@ const (
 	CloseServiceRestart = 1012
 )
 
-// broadcastReconnect sends a reconnect opcode to all connected clients
-func (dm *DrainManager) broadcastReconnect(clients []*Client) {
-	// Create the reconnect message
-	// OpReconnect (7) tells clients to reconnect to a different gateway
-	reconnectData, _ := json.Marshal(map[string]interface{}{
-		"reason": "server_shutdown",
-	})
+// staggerStep returns the number of clients per batch and the delay between
+// batches so that all reconnect signals go out within the stagger window
+func (dm *DrainManager) staggerStep(clientCount int) (int, time.Duration) {
+	window := dm.config.StaggerWindow
+	if window > dm.config.GracePeriod {
+		window = dm.config.GracePeriod
+	}
 
-	msg := &Message{
-		Op:   OpReconnect,
-		Data: reconnectData,
+	batchSize := dm.config.ReconnectBatchSize
+	if batchSize <= 0 || window <= 0 || clientCount <= batchSize {
+		return clientCount, 0
 	}
 
-	msgBytes, err := json.Marshal(msg)
+	batches := (clientCount + batchSize - 1) / batchSize
+	return batchSize, window / time.Duration(batches-1)
+}
+
+// broadcastReconnect sends a reconnect opcode to all connected clients.
+// Clients are signalled in batches spread over the stagger window; the
+// payload points them at ReconnectURL so they land on a healthy node. With
+// a session handoff each client's session is saved first and its payload
+// says which session to resume.
+func (dm *DrainManager) broadcastReconnect(ctx context.Context, clients []*Client) {
+	frames, err := dm.reconnectFrames(ReconnectData{})
 	if err != nil {
 		log.Printf("[Drain] Failed to marshal reconnect message: %v", err)
 		return
 	}
 
+	batchSize, delay := dm.staggerStep(len(clients))
+	if delay > 0 {
+		log.Printf("[Drain] Staggering reconnects: %d clients per batch every %v", batchSize, delay)
+	}
+
 	// Send to all clients (non-blocking)
 	var sent, failed int
-	for _, client := range clients {
+	for i, client := range clients {
+		if delay > 0 && i > 0 && i%batchSize == 0 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				log.Printf("[Drain] Context done while staggering, %d clients not signalled", len(clients)-i)
+				log.Printf("[Drain] Sent reconnect to %d clients (%d failed due to full buffer)", sent, failed)
+				return
+			}
+		}
+
+		clientFrames := frames
+		if dm.handoff != nil {
+			if sessionID, seq, ok := dm.handoff(ctx, client); ok {
+				if handed, err := dm.reconnectFrames(ReconnectData{SessionID: sessionID, Seq: seq}); err == nil {
+					clientFrames = handed
+					dm.handedOff.Add(1)
+				}
+			}
+		}
+
 		select {
-		case client.send <- msgBytes:
+		case client.send <- clientFrames.get(client.format()):
 			sent++
+			dm.notified.Add(1)
 		default:
 			// Client buffer full, skip
 			failed++
+			dm.failed.Add(1)
 		}
 	}
 
-	log.Printf("[Drain] Sent reconnect to %d clients (%d failed due to full buffer)", sent, failed)
+	log.Printf("[Drain] Sent reconnect to %d clients (%d failed due to full buffer, %d sessions handed off)",
+		sent, failed, dm.handedOff.Load())
+}
+
+// reconnectFrames builds the RECONNECT (op 7) telling a client to
+// reconnect to a different gateway, resuming the session in data if set
+func (dm *DrainManager) reconnectFrames(data ReconnectData) (*frameSet, error) {
+	data.Reason = "server_shutdown"
+	data.ResumeURL = dm.config.ReconnectURL
+	reconnectData, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	msgBytes, err := json.Marshal(&Message{
+		Op:   OpReconnect,
+		Data: reconnectData,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newFrameSet(msgBytes), nil
 }
 
 // ForceCloseClients forcefully closes remaining client connections with a close code
@@ -269,4 +408,8 @@ const EventReconnect = "RECONNECT"
 type ReconnectData struct {
 	Reason    string `json:"reason"`
 	ResumeURL string `json:"resume_gateway_url,omitempty"`
+	// SessionID and Seq are set when the session was handed off: the
+	// client should RESUME it at ResumeURL instead of identifying again
+	SessionID string `json:"session_id,omitempty"`
+	Seq       int64  `json:"seq,omitempty"`
 }
@@ -368,3 +368,95 @@ func TestCloseConstants(t *testing.T) {
 	assert.Equal(t, 1001, CloseGoingAway)
 	assert.Equal(t, 1012, CloseServiceRestart)
 }
+
+func TestDrainManager_StaggerStep(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           *DrainConfig
+		clients       int
+		expectedBatch int
+		expectedDelay time.Duration
+	}{
+		{"no stagger configured", &DrainConfig{GracePeriod: time.Second}, 500, 500, 0},
+		{"fewer clients than batch", &DrainConfig{GracePeriod: time.Second, StaggerWindow: time.Second, ReconnectBatchSize: 100}, 50, 50, 0},
+		{"even split", &DrainConfig{GracePeriod: time.Second, StaggerWindow: 900 * time.Millisecond, ReconnectBatchSize: 10}, 40, 10, 300 * time.Millisecond},
+		{"window clamped to grace", &DrainConfig{GracePeriod: 200 * time.Millisecond, StaggerWindow: 10 * time.Second, ReconnectBatchSize: 1}, 3, 1, 100 * time.Millisecond},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dm := NewDrainManager(tc.cfg, func() []*Client { return nil })
+			batch, delay := dm.staggerStep(tc.clients)
+			assert.Equal(t, tc.expectedBatch, batch)
+			assert.Equal(t, tc.expectedDelay, delay)
+		})
+	}
+}
+
+func TestDrainManager_StaggeredReconnect(t *testing.T) {
+	cfg := &DrainConfig{
+		DrainTimeout:       2 * time.Second,
+		GracePeriod:        200 * time.Millisecond,
+		StaggerWindow:      200 * time.Millisecond,
+		ReconnectBatchSize: 1,
+		ReconnectURL:       "wss://chat.example.com/gateway",
+	}
+
+	clients := []*Client{
+		{ID: "a", send: make(chan []byte, 1)},
+		{ID: "b", send: make(chan []byte, 1)},
+		{ID: "c", send: make(chan []byte, 1)},
+	}
+
+	var mu sync.Mutex
+	remaining := clients
+	dm := NewDrainManager(cfg, func() []*Client {
+		mu.Lock()
+		defer mu.Unlock()
+		return remaining
+	})
+
+	go dm.StartDrain(context.Background())
+
+	// First batch goes out immediately, the last one only after the window
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, clients[0].send, 1)
+	assert.Len(t, clients[2].send, 0)
+
+	time.Sleep(250 * time.Millisecond)
+	for _, client := range clients {
+		require.Len(t, client.send, 1, "client %s should have been signalled", client.ID)
+		var parsed Message
+		require.NoError(t, json.Unmarshal(<-client.send, &parsed))
+
+		var data ReconnectData
+		require.NoError(t, json.Unmarshal(parsed.Data, &data))
+		assert.Equal(t, "server_shutdown", data.Reason)
+		assert.Equal(t, "wss://chat.example.com/gateway", data.ResumeURL)
+	}
+
+	progress := dm.Progress()
+	assert.Equal(t, "draining", progress.State)
+	assert.Equal(t, 3, progress.TotalClients)
+	assert.Equal(t, 3, progress.Notified)
+	assert.Equal(t, 3, progress.Remaining)
+	assert.NotNil(t, progress.StartedAt)
+
+	mu.Lock()
+	remaining = nil
+	mu.Unlock()
+
+	dm.WaitForDrain()
+	progress = dm.Progress()
+	assert.Equal(t, "closed", progress.State)
+	assert.Equal(t, 0, progress.Remaining)
+}
+
+func TestDrainManager_Progress_BeforeDrain(t *testing.T) {
+	dm := NewDrainManager(nil, func() []*Client { return nil })
+
+	progress := dm.Progress()
+	assert.Equal(t, "healthy", progress.State)
+	assert.Nil(t, progress.StartedAt)
+	assert.Equal(t, 0, progress.Notified)
+}
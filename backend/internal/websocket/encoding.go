@@ -0,0 +1,366 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/tinylib/msgp/msgp"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Encoding is the wire format a client asks for with ?encoding= when it
+// connects. Frames have the same shape in every encoding.
+type Encoding string
+
+const (
+	EncodingJSON    Encoding = "json"
+	EncodingMsgpack Encoding = "msgpack"
+	// EncodingProtobuf sends each frame as a google.protobuf.Struct
+	EncodingProtobuf Encoding = "protobuf"
+)
+
+var errUnknownEncoding = errors.New("unsupported encoding")
+
+// ParseEncoding returns the encoding named by a connect query parameter;
+// an empty name means JSON
+func ParseEncoding(name string) (Encoding, bool) {
+	switch Encoding(name) {
+	case "", EncodingJSON:
+		return EncodingJSON, true
+	case EncodingMsgpack, EncodingProtobuf:
+		return Encoding(name), true
+	}
+	return "", false
+}
+
+// binary reports whether frames in this encoding are sent as binary
+// WebSocket messages
+func (e Encoding) binary() bool {
+	return e != "" && e != EncodingJSON
+}
+
+// messageType is the WebSocket message type frames are written with
+func (e Encoding) messageType() int {
+	if e.binary() {
+		return websocket.BinaryMessage
+	}
+	return websocket.TextMessage
+}
+
+// encodeFrame converts a serialized JSON frame to the encoding
+func encodeFrame(e Encoding, frame []byte) ([]byte, error) {
+	if !e.binary() {
+		return frame, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(frame))
+	dec.UseNumber()
+	var fields map[string]interface{}
+	if err := dec.Decode(&fields); err != nil {
+		return nil, err
+	}
+	fields = plainNumbers(fields).(map[string]interface{})
+
+	switch e {
+	case EncodingMsgpack:
+		return msgp.AppendIntf(nil, fields)
+	case EncodingProtobuf:
+		s, err := structpb.NewStruct(fields)
+		if err != nil {
+			return nil, err
+		}
+		return proto.Marshal(s)
+	}
+	return nil, errUnknownEncoding
+}
+
+// decodeFrame converts a frame received in the encoding back to JSON for
+// validation
+func decodeFrame(e Encoding, frame []byte) ([]byte, error) {
+	switch e {
+	case EncodingMsgpack:
+		var buf bytes.Buffer
+		if _, err := msgp.UnmarshalAsJSON(&buf, frame); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case EncodingProtobuf:
+		var s structpb.Struct
+		if err := proto.Unmarshal(frame, &s); err != nil {
+			return nil, err
+		}
+		return json.Marshal(s.AsMap())
+	}
+	return frame, nil
+}
+
+// plainNumbers replaces json.Numbers with int64s, or float64s where they
+// aren't integers, so the binary encoders can write them
+func plainNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = plainNumbers(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = plainNumbers(value)
+		}
+	}
+	return v
+}
+
+// jsonDispatchPrefix starts every dispatch the hub serializes
+var jsonDispatchPrefix = []byte(`{"op":0,`)
+
+// stampSequence adds a sequence number to an encoded dispatch frame that
+// doesn't have one, calling next for it. Other frames are returned as-is.
+// Only the envelope is touched, so a broadcast encoded once can be
+// stamped for each connection cheaply.
+func stampSequence(e Encoding, frame []byte, next func() int64) []byte {
+	switch e {
+	case EncodingMsgpack:
+		op, seq, ok := msgpackFields(frame)
+		// Frames the hub encodes have a handful of keys, so a fixmap
+		if !ok || op != OpDispatch || seq != 0 || frame[0]&0xf0 != 0x80 || frame[0] == 0x8f {
+			return frame
+		}
+		stamped := make([]byte, 0, len(frame)+12)
+		stamped = append(stamped, frame[0]+1)
+		stamped = append(stamped, frame[1:]...)
+		stamped = msgp.AppendString(stamped, "s")
+		return msgp.AppendInt64(stamped, next())
+
+	case EncodingProtobuf:
+		op, seq, ok := protobufFields(frame)
+		if !ok || op != OpDispatch || seq != 0 {
+			return frame
+		}
+		// Concatenated messages merge, so this adds the "s" field
+		entry, err := proto.Marshal(&structpb.Struct{Fields: map[string]*structpb.Value{
+			"s": structpb.NewNumberValue(float64(next())),
+		}})
+		if err != nil {
+			return frame
+		}
+		return append(append(make([]byte, 0, len(frame)+len(entry)), frame...), entry...)
+	}
+
+	if bytes.HasPrefix(frame, jsonDispatchPrefix) {
+		seq := strconv.AppendInt(nil, next(), 10)
+		stamped := make([]byte, 0, len(frame)+len(seq)+5)
+		stamped = append(stamped, `{"s":`...)
+		stamped = append(stamped, seq...)
+		stamped = append(stamped, ',')
+		return append(stamped, frame[1:]...)
+	}
+
+	// Frames serialized elsewhere are decoded to check
+	var msg Message
+	if err := json.Unmarshal(frame, &msg); err != nil || msg.Op != OpDispatch || msg.Sequence != 0 {
+		return frame
+	}
+	msg.Sequence = next()
+	stamped, err := json.Marshal(&msg)
+	if err != nil {
+		return frame
+	}
+	return stamped
+}
+
+// encodedSequence extracts the "s" field from a frame in the encoding
+func encodedSequence(e Encoding, frame []byte) int64 {
+	switch e {
+	case EncodingMsgpack:
+		_, seq, _ := msgpackFields(frame)
+		return seq
+	case EncodingProtobuf:
+		_, seq, _ := protobufFields(frame)
+		return seq
+	}
+	return frameSequence(frame)
+}
+
+// encodedOp extracts the opcode from a frame in the encoding, or -1 if it
+// can't be read
+func encodedOp(e Encoding, frame []byte) int {
+	switch e {
+	case EncodingMsgpack:
+		if op, _, ok := msgpackFields(frame); ok {
+			return int(op)
+		}
+		return -1
+	case EncodingProtobuf:
+		if op, _, ok := protobufFields(frame); ok {
+			return int(op)
+		}
+		return -1
+	}
+	var header struct {
+		Op *int `json:"op"`
+	}
+	if err := json.Unmarshal(frame, &header); err != nil || header.Op == nil {
+		return -1
+	}
+	return *header.Op
+}
+
+// msgpackFields reads the op and sequence of a msgpack frame, skipping
+// over its payload
+func msgpackFields(frame []byte) (op, seq int64, ok bool) {
+	n, rest, err := msgp.ReadMapHeaderBytes(frame)
+	if err != nil {
+		return 0, 0, false
+	}
+	op = -1
+	for i := uint32(0); i < n; i++ {
+		var key []byte
+		if key, rest, err = msgp.ReadMapKeyZC(rest); err != nil {
+			return 0, 0, false
+		}
+		switch string(key) {
+		case "op":
+			op, rest, err = msgp.ReadInt64Bytes(rest)
+		case "s":
+			seq, rest, err = msgp.ReadInt64Bytes(rest)
+		default:
+			rest, err = msgp.Skip(rest)
+		}
+		if err != nil {
+			return 0, 0, false
+		}
+	}
+	return op, seq, true
+}
+
+// protobufFields reads the op and sequence of a protobuf Struct frame,
+// skipping over its payload
+func protobufFields(frame []byte) (op, seq int64, ok bool) {
+	op = -1
+	for len(frame) > 0 {
+		num, typ, n := protowire.ConsumeTag(frame)
+		if n < 0 {
+			return 0, 0, false
+		}
+		frame = frame[n:]
+		if num != 1 || typ != protowire.BytesType {
+			if n = protowire.ConsumeFieldValue(num, typ, frame); n < 0 {
+				return 0, 0, false
+			}
+			frame = frame[n:]
+			continue
+		}
+
+		entry, n := protowire.ConsumeBytes(frame)
+		if n < 0 {
+			return 0, 0, false
+		}
+		frame = frame[n:]
+		key, value, ok := protobufEntry(entry)
+		if !ok {
+			return 0, 0, false
+		}
+		if key != "op" && key != "s" {
+			continue
+		}
+		var v structpb.Value
+		if err := proto.Unmarshal(value, &v); err != nil {
+			return 0, 0, false
+		}
+		if key == "op" {
+			op = int64(v.GetNumberValue())
+		} else {
+			seq = int64(v.GetNumberValue())
+		}
+	}
+	return op, seq, true
+}
+
+// protobufEntry splits a serialized Struct.fields map entry
+func protobufEntry(entry []byte) (key string, value []byte, ok bool) {
+	for len(entry) > 0 {
+		num, typ, n := protowire.ConsumeTag(entry)
+		if n < 0 {
+			return "", nil, false
+		}
+		entry = entry[n:]
+		if typ != protowire.BytesType {
+			if n = protowire.ConsumeFieldValue(num, typ, entry); n < 0 {
+				return "", nil, false
+			}
+			entry = entry[n:]
+			continue
+		}
+		b, n := protowire.ConsumeBytes(entry)
+		if n < 0 {
+			return "", nil, false
+		}
+		entry = entry[n:]
+		switch num {
+		case 1:
+			key = string(b)
+		case 2:
+			value = b
+		}
+	}
+	return key, value, true
+}
+
+// frameFormat is how a client's frames are serialized
+type frameFormat struct {
+	encoding   Encoding
+	omitLegacy bool
+}
+
+// frameSet holds one broadcast serialized in each format its recipients
+// use. Each format is encoded once, however many clients share it. Fan-out
+// workers share a set, so it's safe for concurrent use.
+type frameSet struct {
+	json   []byte
+	mu     sync.Mutex
+	frames map[frameFormat][]byte
+}
+
+func newFrameSet(data []byte) *frameSet {
+	return &frameSet{json: data}
+}
+
+// get returns the frame in format, or nil if it couldn't be encoded
+func (f *frameSet) get(format frameFormat) []byte {
+	if !format.encoding.binary() && !format.omitLegacy {
+		return f.json
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if frame, ok := f.frames[format]; ok {
+		return frame
+	}
+
+	frame := f.json
+	if format.omitLegacy {
+		frame = CapabilityOmitLegacyFields.shapeOutbound(frame, 0)
+	}
+	frame, err := encodeFrame(format.encoding, frame)
+	if err != nil {
+		log.Printf("[Hub] Failed to encode frame as %s: %v", format.encoding, err)
+		frame = nil
+	}
+	if f.frames == nil {
+		f.frames = make(map[frameFormat][]byte)
+	}
+	f.frames[format] = frame
+	return frame
+}
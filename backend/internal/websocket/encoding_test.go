@@ -0,0 +1,176 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEventFrame(t testing.TB) []byte {
+	data, err := json.Marshal(&Event{
+		Type: EventMessageCreate,
+		Data: map[string]interface{}{
+			"id":          uuid.NewString(),
+			"content":     "hello",
+			"mentions":    []string{},
+			"attachments": nil,
+			"nonce":       12,
+		},
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func TestParseEncoding(t *testing.T) {
+	for name, want := range map[string]Encoding{"": EncodingJSON, "json": EncodingJSON, "msgpack": EncodingMsgpack, "protobuf": EncodingProtobuf} {
+		got, ok := ParseEncoding(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, want, got)
+	}
+	_, ok := ParseEncoding("etf")
+	assert.False(t, ok)
+}
+
+func TestEncodeFrame_RoundTrip(t *testing.T) {
+	frame := testEventFrame(t)
+
+	for _, encoding := range []Encoding{EncodingMsgpack, EncodingProtobuf} {
+		t.Run(string(encoding), func(t *testing.T) {
+			encoded, err := encodeFrame(encoding, frame)
+			require.NoError(t, err)
+			assert.NotEqual(t, frame, encoded)
+
+			decoded, err := decodeFrame(encoding, encoded)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(frame), string(decoded))
+		})
+	}
+}
+
+func TestStampSequence(t *testing.T) {
+	frame := testEventFrame(t)
+
+	for _, encoding := range []Encoding{EncodingJSON, EncodingMsgpack, EncodingProtobuf} {
+		t.Run(string(encoding), func(t *testing.T) {
+			encoded, err := encodeFrame(encoding, frame)
+			require.NoError(t, err)
+
+			stamped := stampSequence(encoding, encoded, func() int64 { return 42 })
+			assert.Equal(t, int64(42), encodedSequence(encoding, stamped))
+
+			// Already stamped frames keep their sequence
+			again := stampSequence(encoding, stamped, func() int64 { return 43 })
+			assert.Equal(t, int64(42), encodedSequence(encoding, again))
+
+			decoded, err := decodeFrame(encoding, stamped)
+			require.NoError(t, err)
+			var msg Message
+			require.NoError(t, json.Unmarshal(decoded, &msg))
+			assert.Equal(t, EventMessageCreate, msg.Type)
+			assert.Equal(t, int64(42), msg.Sequence)
+		})
+	}
+}
+
+func TestStampSequence_SkipsNonDispatch(t *testing.T) {
+	frame, err := json.Marshal(&Message{Op: OpReconnect})
+	require.NoError(t, err)
+
+	for _, encoding := range []Encoding{EncodingJSON, EncodingMsgpack, EncodingProtobuf} {
+		encoded, err := encodeFrame(encoding, frame)
+		require.NoError(t, err)
+		called := false
+		stamped := stampSequence(encoding, encoded, func() int64 { called = true; return 1 })
+		assert.Equal(t, encoded, stamped, encoding)
+		assert.False(t, called, "no sequence is used up")
+	}
+}
+
+func TestFrameSet_EncodesOncePerFormat(t *testing.T) {
+	frame := testEventFrame(t)
+	frames := newFrameSet(frame)
+
+	assert.Equal(t, frame, frames.get(frameFormat{encoding: EncodingJSON}))
+	msgpack := frames.get(frameFormat{encoding: EncodingMsgpack})
+	assert.Same(t, &msgpack[0], &frames.get(frameFormat{encoding: EncodingMsgpack})[0])
+	assert.Len(t, frames.frames, 1)
+
+	// Legacy fields are dropped once for every client that asked
+	shaped := frames.get(frameFormat{encoding: EncodingJSON, omitLegacy: true})
+	assert.NotContains(t, string(shaped), "mentions")
+	assert.Len(t, frames.frames, 2)
+}
+
+func TestHub_BroadcastInClientEncoding(t *testing.T) {
+	hub := NewHub()
+	channelID := uuid.New()
+	jsonClient := newMockClient(hub, uuid.New())
+	msgpackClient := newMockClient(hub, uuid.New())
+	msgpackClient.encoding = EncodingMsgpack
+	hub.SubscribeChannel(jsonClient, channelID)
+	hub.SubscribeChannel(msgpackClient, channelID)
+
+	hub.handleBroadcast(&Event{Type: EventTypingStart, Data: map[string]string{"user_id": "u"}, ChannelID: &channelID})
+
+	plain := <-jsonClient.send
+	assert.True(t, json.Valid(plain))
+	decoded, err := decodeFrame(EncodingMsgpack, <-msgpackClient.send)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(plain), string(decoded))
+}
+
+// The benchmarks fan one broadcast out to 1000 msgpack clients, encoding
+// it for each connection versus once for the broadcast
+func BenchmarkBroadcast_EncodePerConnection(b *testing.B) {
+	frame := testEventFrame(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for c := 0; c < 1000; c++ {
+			if _, err := encodeFrame(EncodingMsgpack, frame); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkBroadcast_FrameSet(b *testing.B) {
+	frame := testEventFrame(b)
+	format := frameFormat{encoding: EncodingMsgpack}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		frames := newFrameSet(frame)
+		for c := 0; c < 1000; c++ {
+			seq := int64(c)
+			if stampSequence(EncodingMsgpack, frames.get(format), func() int64 { return seq }) == nil {
+				b.Fatal("frame not encoded")
+			}
+		}
+	}
+}
+
+// BenchmarkBroadcast_JSONShapePerConnection is how delta sync clients were
+// served before: each frame decoded and re-marshaled per connection
+func BenchmarkBroadcast_JSONShapePerConnection(b *testing.B) {
+	frame := testEventFrame(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for c := 0; c < 1000; c++ {
+			CapabilityDeltaSync.shapeOutbound(frame, int64(c+1))
+		}
+	}
+}
+
+func BenchmarkBroadcast_JSONStampPerConnection(b *testing.B) {
+	frame := testEventFrame(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		frames := newFrameSet(frame)
+		for c := 0; c < 1000; c++ {
+			seq := int64(c + 1)
+			stampSequence(EncodingJSON, frames.get(frameFormat{}), func() int64 { return seq })
+		}
+	}
+}
@@ -3,6 +3,7 @@ package websocket
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"strconv"
 	"sync"
@@ -14,19 +15,51 @@ import (
 
 	"hearth/internal/auth"
 	"hearth/internal/metrics"
+	"hearth/internal/models"
+	"hearth/internal/services"
 )
 
 // GatewayConfig holds gateway configuration
 type GatewayConfig struct {
 	HeartbeatInterval time.Duration
-	SessionTimeout    time.Duration
+	// MissedHeartbeats is how many heartbeat intervals may pass without a
+	// HEARTBEAT before the connection is closed as a zombie
+	MissedHeartbeats int
+	// SessionTimeout is the replay window: how long a disconnected session
+	// can be resumed for
+	SessionTimeout time.Duration
+	// ResumeBufferSize is how many recent events are kept per session for
+	// replay on resume
+	ResumeBufferSize   int
+	MaxSessionsPerUser int // Concurrent connections per account on this node; 0 = unlimited
+	// ResumeURL is the gateway URL sent in READY for clients to resume on
+	ResumeURL string
+	// EnforceTokenExpiry closes connections whose access token has expired
+	// without a TOKEN_REFRESH
+	EnforceTokenExpiry bool
+	// OutboundQueueSize is how many events are buffered per connection
+	// waiting to be written
+	OutboundQueueSize int
+	// A connection whose queue stays at OutboundLagThreshold or more for
+	// longer than OutboundMaxLag is dealt with by OutboundPolicy
+	OutboundLagThreshold int
+	OutboundMaxLag       time.Duration
+	OutboundPolicy       OutboundPolicy
 }
 
 // DefaultGatewayConfig returns default configuration
 func DefaultGatewayConfig() *GatewayConfig {
 	return &GatewayConfig{
-		HeartbeatInterval: 41250 * time.Millisecond, // ~41 seconds
-		SessionTimeout:    5 * time.Minute,
+		HeartbeatInterval:  41250 * time.Millisecond, // ~41 seconds
+		MissedHeartbeats:   2,
+		SessionTimeout:     5 * time.Minute,
+		ResumeBufferSize:   100,
+		MaxSessionsPerUser: 10,
+
+		OutboundQueueSize:    256,
+		OutboundLagThreshold: 192,
+		OutboundMaxLag:       10 * time.Second,
+		OutboundPolicy:       OutboundClose,
 	}
 }
 
@@ -36,10 +69,47 @@ type Gateway struct {
 	jwtService *auth.JWTService
 	config     *GatewayConfig
 
-	// Session management
+	// Session management: live sessions and disconnected ones still
+	// buffering events on this node, by session ID
 	sessions   map[string]*Session
 	sessionsMu sync.RWMutex
 
+	// Keeps disconnected sessions and their missed events for resume
+	resumes ResumeStore
+
+	// Encodings of connections that didn't ask for JSON
+	encodings sync.Map // *websocket.Conn -> Encoding
+
+	// Live connections per user, for the device limit and connections API
+	devices *deviceRegistry
+
+	// Persists bot presence across reconnects (optional)
+	botPresence BotPresenceStore
+
+	// Redeems invites sent with JOIN_GUILD (optional)
+	guildJoiner GuildJoiner
+
+	// Caps member requests per server (optional)
+	serverThrottle ServerEventThrottle
+
+	// Checks tokens against login sessions and refreshes them (optional)
+	authSessions GatewaySessions
+
+	// Refuses writes during maintenance (optional)
+	readOnly ReadOnlyChecker
+
+	// Fills READY with the user's servers and state (optional)
+	readyState ReadyStateProvider
+
+	// Checks membership for member list subscriptions (optional)
+	memberLists MemberListSource
+
+	// Answers REQUEST_GUILD_MEMBERS (optional)
+	memberRequests MemberRequestSource
+
+	// Hides friends-only presence from member requests (optional)
+	presenceFilter PresenceFilter
+
 	// Metrics (legacy counters for Stats())
 	totalConnections  int64
 	activeConnections int64
@@ -59,14 +129,49 @@ type Session struct {
 	UserID        uuid.UUID
 	Username      string
 	ClientType    string
+	Encoding      Encoding
+	Bot           bool
 	CreatedAt     time.Time
 	LastHeartbeat time.Time
 	Sequence      int64
 
-	// Resume support
-	ResumeKey    string
+	// AuthSessionID is the login session the connection's access token
+	// belongs to; TOKEN_REFRESH keeps it and moves the token's expiry on
+	AuthSessionID *uuid.UUID
+	tokenExpiry   atomic.Int64
+
+	// Resume support: the last events written, saved on disconnect
 	ResumeEvents [][]byte
 	resumeMu     sync.Mutex
+	identified   atomic.Bool
+	timedOut     atomic.Bool
+	lastBeat     atomic.Int64
+	startWrites  func()
+	// Closed when another connection resumes the session
+	stopBuffering chan struct{}
+	stopOnce      sync.Once
+	// Set once the session is saved for resume on disconnect or handed
+	// off by a drain; saveMu orders the two
+	detached  atomic.Bool
+	handedOff atomic.Bool
+	saveMu    sync.Mutex
+
+	// Negotiated at IDENTIFY; read by the write pump
+	capabilities atomic.Uint64
+	dispatchSeq  atomic.Int64
+
+	// Spent by REQUEST_GUILD_MEMBERS
+	memberRequests requestBucket
+}
+
+// Capabilities returns the capabilities negotiated for this session
+func (s *Session) Capabilities() Capabilities {
+	return Capabilities(s.capabilities.Load())
+}
+
+// SetCapabilities records the capabilities negotiated for this session
+func (s *Session) SetCapabilities(c Capabilities) {
+	s.capabilities.Store(uint64(c))
 }
 
 // NewGateway creates a new WebSocket gateway
@@ -80,6 +185,8 @@ func NewGateway(hub HubInterface, jwtService *auth.JWTService, config *GatewayCo
 		jwtService: jwtService,
 		config:     config,
 		sessions:   make(map[string]*Session),
+		resumes:    NewMemoryResumeStore(config.SessionTimeout, config.ResumeBufferSize),
+		devices:    newDeviceRegistry(config.MaxSessionsPerUser),
 		wsMetrics:  metrics.GetMetrics(),
 	}
 }
@@ -100,44 +207,56 @@ func (g *Gateway) HandleConnection(conn *websocket.Conn) {
 	// Validate token
 	claims, err := g.jwtService.ValidateAccessToken(token)
 	if err != nil {
-		g.sendClose(conn, 4001, "authentication failed")
+		g.sendClose(conn, CloseAuthenticationFailed, "authentication failed")
 		return
 	}
-
-	// Get connection metadata
-	sessionID := conn.Query("session_id")
-	if sessionID == "" {
-		sessionID = uuid.New().String()
+	if code := g.validateSession(claims); code != 0 {
+		g.sendClose(conn, code, closeReason(code))
+		return
 	}
 
+	// Get connection metadata
 	clientType := conn.Query("client_type")
 	if clientType == "" {
 		clientType = "web"
 	}
 
-	// Check for session resume
-	resumeKey := conn.Query("resume")
-	if resumeKey != "" {
-		if g.handleResume(conn, resumeKey, claims.UserID) {
-			return // Resume handled separately
-		}
+	encoding, ok := ParseEncoding(conn.Query("encoding"))
+	if !ok {
+		g.sendClose(conn, CloseDecodeError, "unsupported encoding")
+		return
+	}
+	if encoding.binary() {
+		g.encodings.Store(conn, encoding)
+		defer g.encodings.Delete(conn)
+	}
+
+	intents, ok := ParseIntents(conn.Query("intents"))
+	if !ok {
+		g.sendClose(conn, CloseInvalidIntents, "invalid intents")
+		return
 	}
 
 	// Create session
 	session := &Session{
-		ID:            sessionID,
+		ID:            uuid.New().String(),
 		UserID:        claims.UserID,
 		Username:      claims.Username,
 		ClientType:    clientType,
+		Encoding:      encoding,
+		Bot:           claims.Bot,
 		CreatedAt:     time.Now(),
 		LastHeartbeat: time.Now(),
 		Sequence:      0,
-		ResumeKey:     uuid.New().String(),
-		ResumeEvents:  make([][]byte, 0, 100),
+		ResumeEvents:  make([][]byte, 0, g.config.ResumeBufferSize),
+		AuthSessionID: claims.SessionID,
+		stopBuffering: make(chan struct{}),
 	}
+	session.setTokenExpiry(claims)
+	session.heartbeat(session.CreatedAt)
 
 	g.sessionsMu.Lock()
-	g.sessions[session.ResumeKey] = session
+	g.sessions[session.ID] = session
 	g.sessionsMu.Unlock()
 
 	// Track connection
@@ -164,55 +283,116 @@ func (g *Gateway) HandleConnection(conn *websocket.Conn) {
 
 	// Create client for hub
 	client := g.createHubClient(conn, session)
+	client.SetIntents(intents)
+
+	// Register the device, closing the user's oldest connections if this
+	// one takes them over the limit
+	device := &liveDevice{
+		Device: Device{
+			ID:          client.ID,
+			SessionID:   session.ID,
+			ClientType:  clientType,
+			IP:          conn.IP(),
+			ConnectedAt: connectionStart,
+		},
+		conn: conn,
+	}
+	for _, old := range g.devices.add(session.UserID, device) {
+		log.Printf("[Gateway] User %s over session limit, closing device %s", session.UserID, old.ID)
+		old.close(CloseSessionLimit, "session limit reached")
+	}
+	defer g.devices.remove(session.UserID, device.ID)
+
+	client.outbound = newOutboundQueue(g.config, cap(client.send), clientType, g.wsMetrics, func() {
+		device.close(CloseSlowConsumer, "slow consumer")
+	})
+	defer client.outbound.closed()
+
+	heartbeatsDone := make(chan struct{})
+	defer close(heartbeatsDone)
+	go g.watchHeartbeats(device, session, heartbeatsDone)
 
 	// Register with hub
 	g.hub.RegisterClient() <- client
 
+	// Writes start at IDENTIFY or RESUME, so replayed events go out ahead
+	// of anything queued for the client meanwhile
+	stopWrites := make(chan struct{})
+	writesDone := make(chan struct{})
+	var writesOnce sync.Once
+	session.startWrites = func() {
+		writesOnce.Do(func() {
+			go func() {
+				defer close(writesDone)
+				g.writePump(conn, client, session, stopWrites)
+			}()
+		})
+	}
+
 	defer func() {
-		g.hub.UnregisterClient() <- client
+		close(stopWrites)
+		started := true
+		writesOnce.Do(func() { started = false })
+		if started {
+			<-writesDone
+		}
+		g.release(client, session)
 	}()
 
 	// Send HELLO
 	g.sendHello(conn)
 
-	// Start read/write pumps
-	go g.writePump(conn, client, session)
+	// Resume from the query string: ?resume=<session_id>&seq=<last seq>
+	if resumeID := conn.Query("resume"); resumeID != "" {
+		lastSeq, _ := strconv.ParseInt(conn.Query("seq"), 10, 64)
+		if code := g.resume(conn, client, session, resumeID, lastSeq); code != 0 {
+			g.sendClose(conn, code, resumeFailure(code))
+			return
+		}
+	}
+
 	g.readPump(conn, client, session)
 }
 
-func (g *Gateway) createHubClient(conn *websocket.Conn, session *Session) *Client {
-	// Get the underlying Hub from the interface
-	// For DistributedHub, we need access to its embedded Hub
-	var baseHub *Hub
+// baseHub returns the local Hub behind the gateway's hub; for a
+// DistributedHub that's its embedded Hub
+func (g *Gateway) baseHub() *Hub {
 	switch h := g.hub.(type) {
 	case *Hub:
-		baseHub = h
+		return h
 	case *DistributedHub:
-		baseHub = h.Hub
-	default:
-		// Fallback - try type assertion
-		if dh, ok := g.hub.(*DistributedHub); ok {
-			baseHub = dh.Hub
-		}
+		return h.Hub
 	}
+	return nil
+}
 
+func (g *Gateway) createHubClient(conn *websocket.Conn, session *Session) *Client {
 	// Create a wrapper that adapts fiber websocket to our Client
 	return &Client{
 		ID:            uuid.New().String(),
 		UserID:        session.UserID,
 		Username:      session.Username,
-		hub:           baseHub,
+		hub:           g.baseHub(),
 		conn:          nil, // Will use fiber conn directly
-		send:          make(chan []byte, 256),
+		send:          make(chan []byte, g.outboundQueueSize()),
 		servers:       make(map[uuid.UUID]bool),
 		channels:      make(map[uuid.UUID]bool),
 		SessionID:     session.ID,
 		ClientType:    session.ClientType,
+		encoding:      session.Encoding,
 		lastHeartbeat: time.Now(),
 		sequence:      0,
 	}
 }
 
+// outboundQueueSize is how many events each connection buffers
+func (g *Gateway) outboundQueueSize() int {
+	if g.config.OutboundQueueSize > 0 {
+		return g.config.OutboundQueueSize
+	}
+	return DefaultGatewayConfig().OutboundQueueSize
+}
+
 func (g *Gateway) readPump(conn *websocket.Conn, client *Client, session *Session) {
 	conn.SetReadLimit(maxMessageSize)
 	conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -231,20 +411,41 @@ func (g *Gateway) readPump(conn *websocket.Conn, client *Client, session *Sessio
 			break
 		}
 
-		if messageType != websocket.TextMessage {
-			continue
+		if messageType == websocket.BinaryMessage && session.Encoding.binary() {
+			if data, err = decodeFrame(session.Encoding, data); err != nil {
+				g.rejectMessage(conn, &ProtocolError{
+					Code:    CloseDecodeError,
+					Op:      -1,
+					Message: "invalid " + string(session.Encoding) + " frame",
+					Fatal:   true,
+				})
+				break
+			}
+		} else if messageType != websocket.TextMessage {
+			g.rejectMessage(conn, &ProtocolError{
+				Code:    CloseDecodeError,
+				Op:      -1,
+				Message: "only text frames are supported",
+				Fatal:   true,
+			})
+			break
 		}
 
 		g.handleMessage(conn, client, session, data)
 	}
 }
 
-func (g *Gateway) writePump(conn *websocket.Conn, client *Client, session *Session) {
+// writePump writes hub frames to the connection until it fails or stop is
+// closed
+func (g *Gateway) writePump(conn *websocket.Conn, client *Client, session *Session, stop <-chan struct{}) {
 	ticker := time.NewTicker(pingPeriod)
 	defer ticker.Stop()
 
 	for {
 		select {
+		case <-stop:
+			return
+
 		case message, ok := <-client.send:
 			conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
@@ -252,7 +453,12 @@ func (g *Gateway) writePump(conn *websocket.Conn, client *Client, session *Sessi
 				return
 			}
 
-			if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			// Kept for resume before writing, so a frame lost with the
+			// connection is replayed
+			message = session.prepare(message)
+			session.remember(message, g.config.ResumeBufferSize)
+
+			if err := conn.WriteMessage(session.Encoding.messageType(), message); err != nil {
 				return
 			}
 
@@ -260,18 +466,12 @@ func (g *Gateway) writePump(conn *websocket.Conn, client *Client, session *Sessi
 			eventType := g.extractEventType(message)
 			g.wsMetrics.MessageSent(eventType)
 
-			// Store for potential resume
-			session.resumeMu.Lock()
-			if len(session.ResumeEvents) < 100 {
-				session.ResumeEvents = append(session.ResumeEvents, message)
-			} else {
-				// Slide window
-				session.ResumeEvents = append(session.ResumeEvents[1:], message)
-			}
-			session.resumeMu.Unlock()
-
 		case <-ticker.C:
 			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if g.tokenExpired(session, time.Now()) {
+				g.sendClose(conn, CloseTokenExpired, "access token expired")
+				return
+			}
 			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
@@ -282,9 +482,9 @@ func (g *Gateway) writePump(conn *websocket.Conn, client *Client, session *Sessi
 func (g *Gateway) handleMessage(conn *websocket.Conn, client *Client, session *Session, data []byte) {
 	startTime := time.Now()
 
-	var msg Message
-	if err := json.Unmarshal(data, &msg); err != nil {
-		g.sendError(conn, "invalid message format")
+	msg, perr := ValidateInbound(data)
+	if perr != nil {
+		g.rejectMessage(conn, perr)
 		return
 	}
 
@@ -303,28 +503,35 @@ func (g *Gateway) handleMessage(conn *websocket.Conn, client *Client, session *S
 		g.handleHeartbeat(conn, session)
 
 	case OpIdentify:
-		g.handleIdentify(conn, client, session, &msg)
+		g.handleIdentify(conn, client, session, msg)
 
 	case OpPresenceUpdate:
-		g.handlePresenceUpdate(conn, client, session, &msg)
+		g.handlePresenceUpdate(conn, client, session, msg)
 
 	case OpVoiceStateUpdate:
-		g.handleVoiceStateUpdate(conn, client, session, &msg)
+		g.handleVoiceStateUpdate(conn, client, session, msg)
 
 	case OpResume:
-		// Already handled at connection time
-		g.sendError(conn, "resume must be initiated on connect")
+		g.handleResume(conn, client, session, msg)
 
 	case OpRequestGuildMembers:
-		g.handleRequestMembers(conn, client, session, &msg)
+		g.handleRequestMembers(conn, client, session, msg)
+
+	case OpJoinGuild:
+		g.handleJoinGuild(client, session, msg)
+
+	case OpTokenRefresh:
+		g.handleTokenRefresh(client, session, msg)
+
+	case OpUpdateSubscriptions:
+		g.handleUpdateSubscriptions(client, msg)
+
+	case OpMemberListSubscribe:
+		g.handleMemberListSubscribe(conn, client, session, msg)
 
 	case OpDispatch:
 		// Handle client-sent dispatch events (like SUBSCRIBE)
-		g.handleClientDispatch(conn, client, session, &msg)
-
-	default:
-		log.Printf("[Gateway] Unknown opcode: %d from user %s", msg.Op, session.UserID)
-		g.sendError(conn, "unknown opcode")
+		g.handleClientDispatch(conn, client, session, msg)
 	}
 
 	// Record message processing latency
@@ -426,159 +633,162 @@ func (g *Gateway) handleUnsubscribe(conn *websocket.Conn, client *Client, sessio
 
 func (g *Gateway) handleHeartbeat(conn *websocket.Conn, session *Session) {
 	session.LastHeartbeat = time.Now()
+	session.heartbeat(session.LastHeartbeat)
 	g.sendMessage(conn, &Message{Op: OpHeartbeatAck})
 }
 
 func (g *Gateway) handleIdentify(conn *websocket.Conn, client *Client, session *Session, msg *Message) {
 	var data struct {
 		Properties struct {
-			OS      string `json:"$os"`
-			Browser string `json:"$browser"`
-			Device  string `json:"$device"`
+			OS            string `json:"os"`
+			Browser       string `json:"browser"`
+			Device        string `json:"device"`
+			LegacyOS      string `json:"$os"`
+			LegacyBrowser string `json:"$browser"`
+			LegacyDevice  string `json:"$device"`
 		} `json:"properties"`
-		Compress bool `json:"compress"`
+		Compress     bool             `json:"compress"`
+		Capabilities Capabilities     `json:"capabilities"`
+		Intents      Intents          `json:"intents"`
+		Presence     *presencePayload `json:"presence"`
 	}
 
 	if msg.Data != nil {
 		json.Unmarshal(msg.Data, &data)
 	}
 
+	// Intents sent here replace any given on connect
+	if data.Intents != 0 {
+		if !data.Intents.Valid() {
+			g.sendClose(conn, CloseInvalidIntents, "invalid intents")
+			return
+		}
+		client.SetIntents(data.Intents)
+	}
+
+	caps := data.Capabilities.Negotiate()
+	session.SetCapabilities(caps)
+	client.omitLegacy.Store(caps.Has(CapabilityOmitLegacyFields))
+
+	props := data.Properties
+	g.devices.setProperties(session.UserID, client.ID,
+		firstNonEmpty(props.OS, props.LegacyOS),
+		firstNonEmpty(props.Browser, props.LegacyBrowser),
+		firstNonEmpty(props.Device, props.LegacyDevice))
+
+	// Clients may connect with a status other than online; bots that
+	// don't pick one get back the presence they last set
+	if p := data.Presence; p != nil && p.Status != "" {
+		if err := g.setPresence(session, models.PresenceStatus(p.Status), p.toActivities()); err != nil {
+			// The session stays online; only the presence is refused
+			g.rejectMessage(conn, presenceError(msg.Op, "d.presence", err))
+		}
+	} else if session.Bot {
+		g.restoreBotPresence(session)
+	}
+
 	// Send READY event
 	ready := ReadyData{
 		Version:         10,
 		SessionID:       session.ID,
-		ResumeURL:       "",              // Set if using resume URL
+		ResumeURL:       g.config.ResumeURL,
 		Guilds:          []interface{}{}, // Will be populated by services
 		PrivateChannels: []interface{}{},
 		User: map[string]interface{}{
 			"id":       session.UserID.String(),
 			"username": session.Username,
 		},
+		Capabilities: uint64(caps),
+		Intents:      uint64(client.Intents()),
+		ReadOnly:     g.readOnlyMode(context.Background()),
 	}
+	g.fillReady(&ready, session)
 
 	readyData, _ := json.Marshal(ready)
-	g.sendMessage(conn, &Message{
+	readyMsg := &Message{
 		Op:       OpDispatch,
 		Type:     EventReady,
 		Data:     readyData,
-		Sequence: session.Sequence,
-	})
+		Sequence: session.replySequence(),
+	}
+	if data.Compress && !session.Encoding.binary() {
+		g.sendCompressed(conn, readyMsg)
+	} else {
+		g.sendMessage(conn, readyMsg)
+	}
+
+	session.identified.Store(true)
+	session.startWrites()
 }
 
 func (g *Gateway) handlePresenceUpdate(conn *websocket.Conn, client *Client, session *Session, msg *Message) {
-	var data struct {
-		Status     string        `json:"status"`
-		Activities []interface{} `json:"activities"`
-		Since      *int64        `json:"since"`
-		AFK        bool          `json:"afk"`
-	}
-
+	var data presencePayload
 	if msg.Data != nil {
-		json.Unmarshal(msg.Data, &data)
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			return
+		}
 	}
-
-	// Broadcast presence to subscribed servers
-	presence := PresenceUpdateData{
-		Status:     data.Status,
-		Activities: data.Activities,
-		User: map[string]interface{}{
-			"id": session.UserID.String(),
-		},
+	if data.Status == "" {
+		data.Status = string(models.StatusOnline)
 	}
 
-	presenceData, _ := json.Marshal(presence)
-
-	// Broadcast to all servers the user is in
-	client.mu.RLock()
-	servers := make([]uuid.UUID, 0, len(client.servers))
-	for serverID := range client.servers {
-		servers = append(servers, serverID)
-	}
-	client.mu.RUnlock()
-
-	for _, serverID := range servers {
-		g.hub.SendToServer(serverID, &Event{
-			Op:   OpDispatch,
-			Type: EventTypePresenceUpdate,
-			Data: json.RawMessage(presenceData),
-		})
+	if err := g.setPresence(session, models.PresenceStatus(data.Status), data.toActivities()); err != nil {
+		g.rejectMessage(conn, presenceError(msg.Op, "d", err))
 	}
 }
 
-func (g *Gateway) handleVoiceStateUpdate(conn *websocket.Conn, client *Client, session *Session, msg *Message) {
-	// Voice implementation placeholder
-	// Will be implemented with WebRTC integration
-}
-
-func (g *Gateway) handleRequestMembers(conn *websocket.Conn, client *Client, session *Session, msg *Message) {
-	var data struct {
-		GuildID   string   `json:"guild_id"`
-		Query     string   `json:"query"`
-		Limit     int      `json:"limit"`
-		Presences bool     `json:"presences"`
-		UserIDs   []string `json:"user_ids"`
-		Nonce     string   `json:"nonce"`
+// setPresence applies a session's chosen presence; the hub broadcasts
+// PRESENCE_UPDATE to servers the user shares. Bot presence is validated
+// first and refused whole if invalid, then persisted so it survives
+// reconnects.
+func (g *Gateway) setPresence(session *Session, status models.PresenceStatus, activities []models.Activity) error {
+	stored := status
+	if stored == models.StatusOffline {
+		stored = models.StatusInvisible
 	}
-
-	if msg.Data != nil {
-		json.Unmarshal(msg.Data, &data)
+	if session.Bot {
+		if err := services.ValidateBotPresence(stored, activities); err != nil {
+			return err
+		}
 	}
 
-	// This would query the server service for members
-	// For now, send an empty chunk
-	chunk := map[string]interface{}{
-		"guild_id":    data.GuildID,
-		"members":     []interface{}{},
-		"chunk_index": 0,
-		"chunk_count": 1,
-		"nonce":       data.Nonce,
-	}
+	g.hub.UpdatePresence(session.UserID, status, activities)
 
-	chunkData, _ := json.Marshal(chunk)
-	g.sendMessage(conn, &Message{
-		Op:       OpDispatch,
-		Type:     EventGuildMembersChunk,
-		Data:     chunkData,
-		Sequence: session.Sequence,
-	})
+	if !session.Bot || g.botPresence == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), presenceStoreTimeout)
+	defer cancel()
+	if err := g.botPresence.SetBotPresence(ctx, session.UserID, stored, activities); err != nil {
+		log.Printf("[Gateway] failed to store bot presence for %s: %v", session.UserID, err)
+	}
+	return nil
 }
 
-func (g *Gateway) handleResume(conn *websocket.Conn, resumeKey string, userID uuid.UUID) bool {
-	g.sessionsMu.RLock()
-	session, ok := g.sessions[resumeKey]
-	g.sessionsMu.RUnlock()
-
-	if !ok || session.UserID != userID {
-		g.sendClose(conn, 4006, "invalid session")
-		return true
+// presenceError describes a refused presence to the client
+func presenceError(op int, prefix string, err error) *ProtocolError {
+	if errors.Is(err, services.ErrInvalidPresenceStatus) {
+		return invalidPayload(op, prefix+".status", err.Error())
 	}
+	return invalidPayload(op, prefix+".activities", err.Error())
+}
 
-	// Check if session is still valid
-	if time.Since(session.LastHeartbeat) > g.config.SessionTimeout {
-		g.sessionsMu.Lock()
-		delete(g.sessions, resumeKey)
-		g.sessionsMu.Unlock()
-		g.sendClose(conn, 4009, "session timed out")
-		return true
+func (g *Gateway) restoreBotPresence(session *Session) {
+	if g.botPresence == nil {
+		return
 	}
-
-	// Replay missed events
-	session.resumeMu.Lock()
-	events := session.ResumeEvents
-	session.ResumeEvents = make([][]byte, 0, 100)
-	session.resumeMu.Unlock()
-
-	for _, event := range events {
-		conn.WriteMessage(websocket.TextMessage, event)
+	ctx, cancel := context.WithTimeout(context.Background(), presenceStoreTimeout)
+	defer cancel()
+	presence, err := g.botPresence.GetBotPresence(ctx, session.UserID)
+	if err != nil || presence == nil {
+		return
 	}
+	g.hub.UpdatePresence(session.UserID, presence.Status, presence.Activities)
+}
 
-	// Send RESUMED
-	g.sendMessage(conn, &Message{
-		Op:   OpDispatch,
-		Type: EventResumed,
-	})
-
-	return false // Continue with normal handling
+func (g *Gateway) handleVoiceStateUpdate(conn *websocket.Conn, client *Client, session *Session, msg *Message) {
+	// Voice implementation placeholder
+	// Will be implemented with WebRTC integration
 }
 
 func (g *Gateway) sendHello(conn *websocket.Conn) {
@@ -598,7 +808,40 @@ func (g *Gateway) sendMessage(conn *websocket.Conn, msg *Message) {
 	if err != nil {
 		return
 	}
-	conn.WriteMessage(websocket.TextMessage, data)
+	g.writeFrame(conn, data)
+}
+
+// dispatch queues a reply on the client's send queue, where the write pump
+// sequences it and keeps it for resume like any hub event. It reports
+// whether the queue took it.
+func (g *Gateway) dispatch(client *Client, msg *Message) bool {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return false
+	}
+	frame := newFrameSet(data).get(client.format())
+	return frame != nil && client.queue(frame)
+}
+
+// writeFrame writes a serialized JSON frame in the connection's encoding
+func (g *Gateway) writeFrame(conn *websocket.Conn, data []byte) {
+	encoding := g.encodingOf(conn)
+	if encoding.binary() {
+		var err error
+		if data, err = encodeFrame(encoding, data); err != nil {
+			log.Printf("[Gateway] Failed to encode frame as %s: %v", encoding, err)
+			return
+		}
+	}
+	conn.WriteMessage(encoding.messageType(), data)
+}
+
+// encodingOf returns the encoding a connection asked for
+func (g *Gateway) encodingOf(conn *websocket.Conn) Encoding {
+	if encoding, ok := g.encodings.Load(conn); ok {
+		return encoding.(Encoding)
+	}
+	return EncodingJSON
 }
 
 func (g *Gateway) sendError(conn *websocket.Conn, message string) {
@@ -610,12 +853,29 @@ func (g *Gateway) sendError(conn *websocket.Conn, message string) {
 	})
 }
 
-func (g *Gateway) sendClose(conn *websocket.Conn, code int, reason string) {
-	payload, _ := json.Marshal(map[string]interface{}{
-		"code":   code,
-		"reason": reason,
+// rejectMessage sends a structured ERROR frame for a protocol violation and,
+// for fatal violations, closes the connection with the matching close code
+func (g *Gateway) rejectMessage(conn *websocket.Conn, perr *ProtocolError) {
+	g.wsMetrics.ProtocolError(strconv.Itoa(perr.Code))
+
+	errorData, _ := json.Marshal(perr)
+	g.sendMessage(conn, &Message{
+		Op:   OpDispatch,
+		Type: EventError,
+		Data: errorData,
 	})
-	conn.WriteMessage(websocket.CloseMessage, payload)
+
+	if perr.Fatal {
+		g.sendClose(conn, perr.Code, perr.Message)
+	}
+}
+
+func (g *Gateway) sendClose(conn *websocket.Conn, code int, reason string) {
+	// Close frame reasons are limited to 123 bytes
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
 	conn.Close()
 }
 
@@ -705,6 +965,68 @@ func (g *Gateway) DrainState() DrainState {
 	return DrainStateHealthy
 }
 
+// DrainProgress returns drain progress for the admin API
+func (g *Gateway) DrainProgress() DrainProgress {
+	if g.hub != nil {
+		return g.hub.DrainProgress()
+	}
+	return DrainProgress{State: g.DrainState().String()}
+}
+
+// SetBotPresenceStore enables persisting presence set by bot sessions
+func (g *Gateway) SetBotPresenceStore(store BotPresenceStore) {
+	g.botPresence = store
+}
+
+// GetPresences returns the presence of the given users across all nodes
+func (g *Gateway) GetPresences(userIDs []uuid.UUID) map[uuid.UUID]*models.Presence {
+	return g.hub.GetPresences(userIDs)
+}
+
+// UserDevices returns a user's live connections on this node, newest first
+func (g *Gateway) UserDevices(userID uuid.UUID) []Device {
+	if g.devices == nil {
+		return []Device{}
+	}
+	return g.devices.list(userID)
+}
+
+// DisconnectDevice closes one of a user's connections. It returns false if
+// the device isn't connected to this node.
+func (g *Gateway) DisconnectDevice(userID uuid.UUID, deviceID string) bool {
+	if g.devices == nil {
+		return false
+	}
+	device := g.devices.get(userID, deviceID)
+	if device == nil {
+		return false
+	}
+	device.close(CloseSessionRevoked, "session revoked")
+	return true
+}
+
+// DisconnectSuspendedUser closes all of a user's connections to this node
+// with CloseAccountSuspended and returns how many were closed
+func (g *Gateway) DisconnectSuspendedUser(userID uuid.UUID) int {
+	if g.devices == nil {
+		return 0
+	}
+	devices := g.devices.userDevices(userID)
+	for _, device := range devices {
+		device.close(CloseAccountSuspended, "account suspended")
+	}
+	return len(devices)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
 // GetActiveConnections returns the current number of active connections
 func (g *Gateway) GetActiveConnections() int64 {
 	g.connectionsMu.RLock()
@@ -15,6 +15,7 @@ func TestDefaultGatewayConfig(t *testing.T) {
 	
 	assert.Equal(t, 41250*time.Millisecond, cfg.HeartbeatInterval)
 	assert.Equal(t, 5*time.Minute, cfg.SessionTimeout)
+	assert.Equal(t, 100, cfg.ResumeBufferSize)
 }
 
 func TestSession(t *testing.T) {
@@ -26,14 +27,13 @@ func TestSession(t *testing.T) {
 		CreatedAt:     time.Now(),
 		LastHeartbeat: time.Now(),
 		Sequence:      0,
-		ResumeKey:     uuid.New().String(),
 		ResumeEvents:  make([][]byte, 0, 100),
 	}
 
 	assert.NotEmpty(t, session.ID)
 	assert.Equal(t, "testuser", session.Username)
 	assert.Equal(t, "web", session.ClientType)
-	assert.NotEmpty(t, session.ResumeKey)
+	assert.Empty(t, session.ResumeEvents)
 }
 
 func TestHelloData(t *testing.T) {
@@ -217,6 +217,7 @@ func TestMessageOpcodes(t *testing.T) {
 	assert.Equal(t, 9, OpInvalidSession)
 	assert.Equal(t, 10, OpHello)
 	assert.Equal(t, 11, OpHeartbeatAck)
+	assert.Equal(t, 12, OpJoinGuild)
 }
 
 func TestEventTypes(t *testing.T) {
@@ -0,0 +1,149 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// EventGuildJoinError answers a JOIN_GUILD that couldn't be redeemed
+const EventGuildJoinError = "GUILD_JOIN_ERROR"
+
+const guildJoinTimeout = 10 * time.Second
+
+// GuildJoiner redeems invites for the gateway. The ServerService
+// implements it, so joining over the gateway goes through the same checks
+// as POST /invites/:code.
+type GuildJoiner interface {
+	JoinServer(ctx context.Context, userID uuid.UUID, inviteCode string) (*models.Server, error)
+	GetChannels(ctx context.Context, serverID uuid.UUID) ([]*models.Channel, error)
+	GetRoles(ctx context.Context, serverID uuid.UUID) ([]*models.Role, error)
+	GetMember(ctx context.Context, serverID, userID uuid.UUID) (*models.Member, error)
+}
+
+// GuildCreateData is the GUILD_CREATE payload sent after joining a server.
+// Member is the joining user's membership; it is pending while they still
+// have to pass membership screening.
+type GuildCreateData struct {
+	*models.Server
+	Channels []*models.Channel `json:"channels"`
+	Roles    []*models.Role    `json:"roles"`
+	Member   *models.Member    `json:"member,omitempty"`
+	Nonce    string            `json:"nonce,omitempty"`
+}
+
+// GuildJoinErrorData says why a JOIN_GUILD failed. Code is stable for
+// clients to switch on; Message is for display.
+type GuildJoinErrorData struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Nonce   string `json:"nonce,omitempty"`
+}
+
+// guildJoinErrors maps invite failures to the codes clients see
+var guildJoinErrors = []struct {
+	err  error
+	code string
+}{
+	{services.ErrInviteNotFound, "invite_not_found"},
+	{services.ErrInviteExpired, "invite_expired"},
+	{services.ErrInviteMaxUses, "invite_expired"},
+	{services.ErrServerNotFound, "invite_not_found"},
+	{services.ErrBannedFromServer, "banned"},
+	{services.ErrAlreadyMember, "already_member"},
+	{services.ErrMaxServersReached, "max_servers"},
+}
+
+// SetGuildJoiner enables redeeming invites with the JOIN_GUILD op
+func (g *Gateway) SetGuildJoiner(joiner GuildJoiner) {
+	g.guildJoiner = joiner
+}
+
+func (g *Gateway) handleJoinGuild(client *Client, session *Session, msg *Message) {
+	var data joinGuildPayload
+	if msg.Data != nil {
+		json.Unmarshal(msg.Data, &data)
+	}
+
+	reply := g.joinGuild(client, session, data)
+	if reply.Type == EventGuildCreate {
+		g.wsMetrics.ServerSubscribed()
+	}
+	g.dispatch(client, reply)
+}
+
+// joinGuild redeems the invite and subscribes the client to the server it
+// joined. It returns the GUILD_CREATE or GUILD_JOIN_ERROR to send back.
+func (g *Gateway) joinGuild(client *Client, session *Session, data joinGuildPayload) *Message {
+	if g.guildJoiner == nil {
+		return guildJoinError(data.Nonce, "unavailable", "joining over the gateway is not enabled")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), guildJoinTimeout)
+	defer cancel()
+
+	if g.readOnlyMode(ctx) != nil {
+		return guildJoinError(data.Nonce, "read_only", "this instance is read-only for maintenance; try again later")
+	}
+
+	server, err := g.guildJoiner.JoinServer(ctx, session.UserID, data.Code)
+	if err != nil {
+		for _, e := range guildJoinErrors {
+			if errors.Is(err, e.err) {
+				return guildJoinError(data.Nonce, e.code, err.Error())
+			}
+		}
+		log.Printf("[Gateway] Failed to join user %s via invite %s: %v", session.UserID, data.Code, err)
+		return guildJoinError(data.Nonce, "internal_error", "failed to join server")
+	}
+
+	// The membership exists from here on, so a failed lookup only leaves
+	// the lists empty; the client can still fetch them over REST
+	guild := GuildCreateData{
+		Server:   server,
+		Channels: []*models.Channel{},
+		Roles:    []*models.Role{},
+		Nonce:    data.Nonce,
+	}
+	if channels, err := g.guildJoiner.GetChannels(ctx, server.ID); err != nil {
+		log.Printf("[Gateway] Failed to load channels for joined server %s: %v", server.ID, err)
+	} else if channels != nil {
+		guild.Channels = channels
+	}
+	if roles, err := g.guildJoiner.GetRoles(ctx, server.ID); err != nil {
+		log.Printf("[Gateway] Failed to load roles for joined server %s: %v", server.ID, err)
+	} else if roles != nil {
+		guild.Roles = roles
+	}
+	if member, err := g.guildJoiner.GetMember(ctx, server.ID, session.UserID); err != nil {
+		log.Printf("[Gateway] Failed to load membership in joined server %s: %v", server.ID, err)
+	} else {
+		guild.Member = member
+	}
+
+	client.SubscribeServer(server.ID)
+	log.Printf("[Gateway] User %s joined server %s over the gateway", session.UserID, server.ID)
+
+	guildData, _ := json.Marshal(guild)
+	return &Message{
+		Op:   OpDispatch,
+		Type: EventGuildCreate,
+		Data: guildData,
+	}
+}
+
+func guildJoinError(nonce, code, message string) *Message {
+	errorData, _ := json.Marshal(GuildJoinErrorData{Code: code, Message: message, Nonce: nonce})
+	return &Message{
+		Op:   OpDispatch,
+		Type: EventGuildJoinError,
+		Data: errorData,
+	}
+}
@@ -0,0 +1,162 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+type fakeGuildJoiner struct {
+	server   *models.Server
+	channels []*models.Channel
+	member   *models.Member
+	err      error
+	joined   []string
+}
+
+func (f *fakeGuildJoiner) JoinServer(ctx context.Context, userID uuid.UUID, inviteCode string) (*models.Server, error) {
+	f.joined = append(f.joined, inviteCode)
+	return f.server, f.err
+}
+
+func (f *fakeGuildJoiner) GetChannels(ctx context.Context, serverID uuid.UUID) ([]*models.Channel, error) {
+	return f.channels, nil
+}
+
+func (f *fakeGuildJoiner) GetRoles(ctx context.Context, serverID uuid.UUID) ([]*models.Role, error) {
+	return nil, errors.New("database down")
+}
+
+func (f *fakeGuildJoiner) GetMember(ctx context.Context, serverID, userID uuid.UUID) (*models.Member, error) {
+	return f.member, nil
+}
+
+func TestGateway_JoinGuild(t *testing.T) {
+	server := &models.Server{ID: uuid.New(), Name: "Hearth"}
+	channel := &models.Channel{ID: uuid.New(), ServerID: &server.ID, Name: "general"}
+	session := &Session{UserID: uuid.New()}
+	joiner := &fakeGuildJoiner{
+		server:   server,
+		channels: []*models.Channel{channel},
+		member:   &models.Member{ServerID: server.ID, UserID: session.UserID, Pending: true},
+	}
+
+	gateway := NewGateway(NewHub(), nil, nil)
+	gateway.SetGuildJoiner(joiner)
+	client := NewClient(NewHub(), nil, session.UserID, "alice", "session", "mobile")
+
+	reply := gateway.joinGuild(client, session, joinGuildPayload{Code: "aB3dE6gH", Nonce: "join-1"})
+	assert.Equal(t, OpDispatch, reply.Op)
+	assert.Equal(t, EventGuildCreate, reply.Type)
+	assert.Equal(t, []string{"aB3dE6gH"}, joiner.joined)
+	assert.True(t, client.IsSubscribedToServer(server.ID), "events for the new server reach the client")
+
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal(reply.Data, &data))
+	assert.Equal(t, server.ID.String(), data["id"])
+	assert.Equal(t, "Hearth", data["name"])
+	assert.Equal(t, "join-1", data["nonce"])
+	assert.Len(t, data["channels"], 1)
+	assert.Equal(t, []interface{}{}, data["roles"], "lookup failures leave the list empty")
+	assert.Equal(t, true, data["member"].(map[string]interface{})["pending"], "the client learns it must pass screening")
+}
+
+func TestGateway_HandleJoinGuildQueuesReply(t *testing.T) {
+	gateway := NewGateway(NewHub(), nil, nil)
+	session := &Session{UserID: uuid.New(), Sequence: 3}
+	client := NewClient(NewHub(), nil, session.UserID, "alice", "session", "mobile")
+
+	request, _ := json.Marshal(joinGuildPayload{Code: "aB3dE6gH", Nonce: "join-3"})
+	gateway.handleJoinGuild(client, session, &Message{Op: OpJoinGuild, Data: request})
+
+	// The write pump sequences the reply and keeps it for resume
+	require.Len(t, client.send, 1)
+	var reply Message
+	require.NoError(t, json.Unmarshal(<-client.send, &reply))
+	assert.Equal(t, EventGuildJoinError, reply.Type)
+	assert.Zero(t, reply.Sequence)
+}
+
+func TestGateway_JoinGuild_Errors(t *testing.T) {
+	tests := []struct {
+		err  error
+		code string
+	}{
+		{services.ErrInviteNotFound, "invite_not_found"},
+		{services.ErrInviteExpired, "invite_expired"},
+		{services.ErrBannedFromServer, "banned"},
+		{fmt.Errorf("join: %w", services.ErrAlreadyMember), "already_member"},
+		{services.ErrMaxServersReached, "max_servers"},
+		{errors.New("connection refused"), "internal_error"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.code, func(t *testing.T) {
+			gateway := NewGateway(NewHub(), nil, nil)
+			gateway.SetGuildJoiner(&fakeGuildJoiner{err: tc.err})
+			session := &Session{UserID: uuid.New()}
+			client := NewClient(NewHub(), nil, session.UserID, "alice", "session", "mobile")
+
+			reply := gateway.joinGuild(client, session, joinGuildPayload{Code: "nope", Nonce: "join-2"})
+			assert.Equal(t, EventGuildJoinError, reply.Type)
+
+			var data GuildJoinErrorData
+			require.NoError(t, json.Unmarshal(reply.Data, &data))
+			assert.Equal(t, tc.code, data.Code)
+			assert.Equal(t, "join-2", data.Nonce)
+			assert.NotContains(t, data.Message, "connection refused", "internal errors aren't leaked")
+			assert.Empty(t, client.servers)
+		})
+	}
+}
+
+func TestGateway_JoinGuild_NotConfigured(t *testing.T) {
+	gateway := NewGateway(NewHub(), nil, nil)
+	session := &Session{UserID: uuid.New()}
+	client := NewClient(NewHub(), nil, session.UserID, "alice", "session", "mobile")
+
+	reply := gateway.joinGuild(client, session, joinGuildPayload{Code: "aB3dE6gH"})
+	assert.Equal(t, EventGuildJoinError, reply.Type)
+
+	var data GuildJoinErrorData
+	require.NoError(t, json.Unmarshal(reply.Data, &data))
+	assert.Equal(t, "unavailable", data.Code)
+}
+
+type stubReadOnly struct {
+	mode models.ReadOnlyMode
+}
+
+func (s *stubReadOnly) Mode(ctx context.Context) *models.ReadOnlyMode {
+	return &s.mode
+}
+
+func TestGateway_JoinGuild_ReadOnly(t *testing.T) {
+	joiner := &fakeGuildJoiner{server: &models.Server{ID: uuid.New()}}
+	readOnly := &stubReadOnly{mode: models.ReadOnlyMode{Enabled: true}}
+	gateway := NewGateway(NewHub(), nil, nil)
+	gateway.SetGuildJoiner(joiner)
+	gateway.SetReadOnly(readOnly)
+	session := &Session{UserID: uuid.New()}
+	client := NewClient(NewHub(), nil, session.UserID, "alice", "session", "mobile")
+
+	reply := gateway.joinGuild(client, session, joinGuildPayload{Code: "aB3dE6gH"})
+	assert.Equal(t, EventGuildJoinError, reply.Type)
+	var data GuildJoinErrorData
+	require.NoError(t, json.Unmarshal(reply.Data, &data))
+	assert.Equal(t, "read_only", data.Code)
+	assert.Empty(t, joiner.joined)
+
+	readOnly.mode.Enabled = false
+	reply = gateway.joinGuild(client, session, joinGuildPayload{Code: "aB3dE6gH"})
+	assert.Equal(t, EventGuildCreate, reply.Type)
+}
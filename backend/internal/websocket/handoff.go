@@ -0,0 +1,50 @@
+package websocket
+
+import (
+	"context"
+	"log"
+)
+
+// EnableSessionHandoff makes a drain hand live sessions off instead of
+// just dropping them: each session is saved to the resume store before its
+// client is sent RECONNECT, and the RECONNECT carries the session ID and
+// last sequence, so the client resumes on a healthy node with its
+// subscriptions and replay buffer intact. The resume store must be shared
+// between nodes.
+func (g *Gateway) EnableSessionHandoff() {
+	if hub := g.baseHub(); hub != nil {
+		hub.SetSessionHandoff(g.handoff)
+	}
+}
+
+// handoff saves client's session for resume ahead of a drain. Sessions
+// that haven't identified, or are already disconnected and saved, aren't
+// handed off.
+func (g *Gateway) handoff(ctx context.Context, client *Client) (string, int64, bool) {
+	if g.resumes == nil || g.config.SessionTimeout <= 0 {
+		return "", 0, false
+	}
+
+	g.sessionsMu.RLock()
+	session := g.sessions[client.SessionID]
+	g.sessionsMu.RUnlock()
+	if session == nil || !session.identified.Load() || session.timedOut.Load() {
+		return "", 0, false
+	}
+
+	session.saveMu.Lock()
+	defer session.saveMu.Unlock()
+	if session.detached.Load() {
+		return "", 0, false
+	}
+
+	state, events := g.snapshot(client, session)
+	ctx, cancel := context.WithTimeout(ctx, resumeStoreTimeout)
+	defer cancel()
+	if err := g.resumes.Save(ctx, state, events); err != nil {
+		log.Printf("[Gateway] Failed to hand off session %s: %v", session.ID, err)
+		return "", 0, false
+	}
+	session.handedOff.Store(true)
+	return state.SessionID, state.Sequence, true
+}
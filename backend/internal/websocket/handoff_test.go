@@ -0,0 +1,130 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readReconnect(t *testing.T, client *Client) ReconnectData {
+	select {
+	case frame := <-client.send:
+		var msg Message
+		require.NoError(t, json.Unmarshal(frame, &msg))
+		require.Equal(t, OpReconnect, msg.Op)
+		var data ReconnectData
+		require.NoError(t, json.Unmarshal(msg.Data, &data))
+		return data
+	case <-time.After(time.Second):
+		t.Fatal("Did not receive reconnect message")
+	}
+	return ReconnectData{}
+}
+
+func TestGateway_DrainHandsOffSessions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hub := NewHubWithDrainConfig(&DrainConfig{
+		DrainTimeout: 2 * time.Second,
+		GracePeriod:  50 * time.Millisecond,
+		ReconnectURL: "wss://chat.example.com/gateway",
+	})
+	go hub.Run(ctx)
+
+	gateway := NewGateway(hub, nil, nil)
+	gateway.EnableSessionHandoff()
+
+	session := &Session{ID: "s-1", UserID: uuid.New(), stopBuffering: make(chan struct{})}
+	session.SetCapabilities(CapabilityDeltaSync)
+	session.dispatchSeq.Store(3)
+	session.identified.Store(true)
+	session.remember(seqFrame(t, 3), gateway.config.ResumeBufferSize)
+	gateway.sessions[session.ID] = session
+
+	client := newMockClient(hub, session.UserID)
+	client.SessionID = session.ID
+	serverID := uuid.New()
+	client.servers[serverID] = true
+	hub.RegisterClient() <- client
+
+	// Connections that never identified have nothing to hand off
+	unidentified := newMockClient(hub, uuid.New())
+	hub.RegisterClient() <- unidentified
+	require.Eventually(t, func() bool { return hub.GetClientCount() == 2 }, time.Second, 10*time.Millisecond)
+
+	shutdownDone := make(chan error, 1)
+	go func() { shutdownDone <- gateway.Shutdown(context.Background()) }()
+
+	data := readReconnect(t, client)
+	assert.Equal(t, "s-1", data.SessionID)
+	assert.Equal(t, int64(3), data.Seq)
+	assert.Equal(t, "wss://chat.example.com/gateway", data.ResumeURL)
+	assert.Empty(t, readReconnect(t, unidentified).SessionID)
+	assert.Equal(t, 1, gateway.DrainProgress().HandedOff)
+
+	// The session is stored before the client is told to reconnect
+	state, events, err := gateway.resumes.Load(ctx, "s-1")
+	require.NoError(t, err)
+	require.NotNil(t, state)
+	assert.Equal(t, []uuid.UUID{serverID}, state.Servers)
+	assert.Len(t, events, 1)
+
+	// Once the client goes the session is saved again, without the
+	// RECONNECT it was last written
+	reconnect, _ := json.Marshal(&Message{Op: OpReconnect, Data: json.RawMessage(`{}`)})
+	session.remember(seqFrame(t, 4), gateway.config.ResumeBufferSize)
+	session.remember(reconnect, gateway.config.ResumeBufferSize)
+	gateway.release(client, session)
+
+	_, events, err = gateway.resumes.Load(ctx, "s-1")
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{seqFrame(t, 3), seqFrame(t, 4)}, events)
+
+	session.stop()
+	hub.UnregisterClient() <- unidentified
+	select {
+	case err := <-shutdownDone:
+		assert.NoError(t, err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("Shutdown timed out")
+	}
+}
+
+func TestGateway_ReleaseAfterHandoffResumedElsewhere(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hub := NewHub()
+	go hub.Run(ctx)
+
+	gateway := NewGateway(hub, nil, nil)
+	session := &Session{ID: "s-1", UserID: uuid.New(), stopBuffering: make(chan struct{})}
+	session.identified.Store(true)
+	session.handedOff.Store(true)
+	gateway.sessions[session.ID] = session
+	client := newMockClient(hub, session.UserID)
+	hub.RegisterClient() <- client
+
+	// The handed off session was resumed on another node, which deleted it
+	gateway.release(client, session)
+
+	state, _, err := gateway.resumes.Load(ctx, "s-1")
+	require.NoError(t, err)
+	assert.Nil(t, state, "a resumed session isn't saved again")
+	assert.Nil(t, gateway.sessions["s-1"])
+}
+
+func TestEncodedOp(t *testing.T) {
+	assert.Equal(t, OpDispatch, encodedOp(EncodingJSON, seqFrame(t, 1)))
+	assert.Equal(t, OpReconnect, encodedOp(EncodingJSON, []byte(`{"op":7,"d":{}}`)))
+	assert.Equal(t, -1, encodedOp(EncodingJSON, []byte(`{"t":"READY"}`)))
+	assert.Equal(t, -1, encodedOp(EncodingJSON, []byte("not json")))
+
+	frame, err := encodeFrame(EncodingMsgpack, []byte(`{"op":7,"d":{}}`))
+	require.NoError(t, err)
+	assert.Equal(t, OpReconnect, encodedOp(EncodingMsgpack, frame))
+}
@@ -0,0 +1,46 @@
+package websocket
+
+import (
+	"log"
+	"time"
+)
+
+// heartbeat records a HEARTBEAT (op 1) from the client
+func (s *Session) heartbeat(now time.Time) {
+	s.lastBeat.Store(now.UnixNano())
+}
+
+// heartbeatOverdue reports whether more than misses heartbeat intervals
+// have passed since the client's last HEARTBEAT
+func (s *Session) heartbeatOverdue(now time.Time, interval time.Duration, misses int) bool {
+	last := time.Unix(0, s.lastBeat.Load())
+	return now.Sub(last) > interval*time.Duration(misses)
+}
+
+// watchHeartbeats closes the connection with 4009 once the client has
+// missed MissedHeartbeats heartbeats in a row, until done is closed.
+// WebSocket pings keep the read deadline alive even when the client
+// itself has hung, so HEARTBEAT is what tells a zombie apart.
+func (g *Gateway) watchHeartbeats(device *liveDevice, session *Session, done <-chan struct{}) {
+	if g.config.HeartbeatInterval <= 0 || g.config.MissedHeartbeats <= 0 {
+		return
+	}
+	ticker := time.NewTicker(g.config.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			if !session.heartbeatOverdue(now, g.config.HeartbeatInterval, g.config.MissedHeartbeats) {
+				continue
+			}
+			log.Printf("[Gateway] Closing connection %s of user %s after %d missed heartbeats", device.ID, session.UserID, g.config.MissedHeartbeats)
+			session.timedOut.Store(true)
+			g.wsMetrics.ZombieReaped(session.ClientType)
+			device.close(CloseSessionTimeout, "session timed out")
+			return
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession_HeartbeatOverdue(t *testing.T) {
+	session := &Session{}
+	now := time.Now()
+	session.heartbeat(now)
+
+	assert.False(t, session.heartbeatOverdue(now.Add(80*time.Second), 41*time.Second, 2))
+	assert.True(t, session.heartbeatOverdue(now.Add(83*time.Second), 41*time.Second, 2))
+
+	session.heartbeat(now.Add(80 * time.Second))
+	assert.False(t, session.heartbeatOverdue(now.Add(83*time.Second), 41*time.Second, 2))
+}
+
+func TestGateway_WatchHeartbeatsReapsZombies(t *testing.T) {
+	config := DefaultGatewayConfig()
+	config.HeartbeatInterval = 10 * time.Millisecond
+	gateway := NewGateway(NewHub(), nil, config)
+	session := &Session{UserID: uuid.New(), ClientType: "web"}
+	session.heartbeat(time.Now())
+	device := &liveDevice{Device: Device{ID: "d-1"}}
+
+	done := make(chan struct{})
+	returned := make(chan struct{})
+	go func() {
+		gateway.watchHeartbeats(device, session, done)
+		close(returned)
+	}()
+
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("connection without heartbeats was not reaped")
+	}
+	assert.True(t, session.timedOut.Load(), "reaped sessions can't be resumed")
+	close(done)
+}
+
+func TestGateway_WatchHeartbeatsKeepsLiveConnections(t *testing.T) {
+	config := DefaultGatewayConfig()
+	config.HeartbeatInterval = 10 * time.Millisecond
+	config.MissedHeartbeats = 5
+	gateway := NewGateway(NewHub(), nil, config)
+	session := &Session{UserID: uuid.New()}
+	session.heartbeat(time.Now())
+
+	done := make(chan struct{})
+	go gateway.watchHeartbeats(&liveDevice{}, session, done)
+	for i := 0; i < 10; i++ {
+		time.Sleep(5 * time.Millisecond)
+		session.heartbeat(time.Now())
+	}
+	close(done)
+	assert.False(t, session.timedOut.Load())
+}
@@ -3,24 +3,27 @@ package websocket
 import (
 	"context"
 	"encoding/json"
+	"runtime"
 	"sync"
 
 	"github.com/google/uuid"
+
+	"hearth/internal/models"
 )
 
 // Hub manages all WebSocket connections
 type Hub struct {
 	// Registered clients by user ID
-	clients    map[uuid.UUID]map[*Client]bool
-	clientsMux sync.RWMutex
+	clients *clientRegistry
 
 	// Channel subscriptions
-	channels    map[uuid.UUID]map[*Client]bool
-	channelsMux sync.RWMutex
+	channels *clientRegistry
 
 	// Server subscriptions (for presence, typing, etc.)
-	servers    map[uuid.UUID]map[*Client]bool
-	serversMux sync.RWMutex
+	servers *clientRegistry
+
+	// Spreads large broadcasts over CPUs
+	fanout *fanoutPool
 
 	// Incoming events
 	broadcast  chan *Event
@@ -29,37 +32,63 @@ type Hub struct {
 
 	// Graceful shutdown
 	drainManager *DrainManager
+
+	// Presence of locally connected users, optionally shared via a store
+	presence         *presenceTracker
+	presenceStore    PresenceStore
+	presenceListener func(*models.Presence)
+	presenceMu       sync.RWMutex
+	presenceWorker   sync.Once
+	// Users whose presence changed and awaits publishing, oldest first.
+	// A user is queued once however often they change meanwhile.
+	presenceQueueMu sync.Mutex
+	presenceQueue   []uuid.UUID
+	presenceQueued  map[uuid.UUID]bool
+	presenceWake    chan struct{}
+
+	// Member lists clients on this node are watching ranges of
+	memberLists *memberListIndex
+
+	// Told when a user's first connection to this node opens and their
+	// last one closes. Called on the hub's goroutine, so it should return
+	// quickly.
+	userListener func(userID uuid.UUID, online bool)
+}
+
+// recipientBuffers recycles the slices broadcasts collect recipients in.
+// A distributed hub broadcasts from more than one goroutine, so the hub
+// can't keep just one.
+var recipientBuffers = sync.Pool{
+	New: func() any {
+		buf := make([]*Client, 0, fanoutBatch)
+		return &buf
+	},
 }
 
 // NewHub creates a new WebSocket hub
 func NewHub() *Hub {
-	h := &Hub{
-		clients:    make(map[uuid.UUID]map[*Client]bool),
-		channels:   make(map[uuid.UUID]map[*Client]bool),
-		servers:    make(map[uuid.UUID]map[*Client]bool),
-		broadcast:  make(chan *Event, 256),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-	}
-
-	// Initialize drain manager with client getter
-	h.drainManager = NewDrainManager(nil, h.getAllClients)
-
-	return h
+	return NewHubWithDrainConfig(nil)
 }
 
 // NewHubWithDrainConfig creates a new WebSocket hub with custom drain configuration
 func NewHubWithDrainConfig(drainConfig *DrainConfig) *Hub {
 	h := &Hub{
-		clients:    make(map[uuid.UUID]map[*Client]bool),
-		channels:   make(map[uuid.UUID]map[*Client]bool),
-		servers:    make(map[uuid.UUID]map[*Client]bool),
+		clients:    newClientRegistry(),
+		channels:   newClientRegistry(),
+		servers:    newClientRegistry(),
+		fanout:     newFanoutPool(runtime.GOMAXPROCS(0)),
 		broadcast:  make(chan *Event, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+
+		presence:       newPresenceTracker(),
+		presenceQueued: make(map[uuid.UUID]bool),
+		presenceWake:   make(chan struct{}, 1),
 	}
 
-	// Initialize drain manager with custom config
+	h.memberLists = newMemberListIndex(h)
+
+	// Initialize drain manager; a nil config uses the defaults
 	h.drainManager = NewDrainManager(drainConfig, h.getAllClients)
 
 	return h
@@ -85,48 +114,35 @@ func (h *Hub) Run(ctx context.Context) {
 }
 
 func (h *Hub) registerClient(client *Client) {
-	h.clientsMux.Lock()
-	defer h.clientsMux.Unlock()
+	h.clients.add(client.UserID, client)
 
-	if h.clients[client.UserID] == nil {
-		h.clients[client.UserID] = make(map[*Client]bool)
+	if h.presence.connect(client) {
+		h.presenceChanged(client.UserID)
+		if h.userListener != nil {
+			h.userListener(client.UserID, true)
+		}
 	}
-	h.clients[client.UserID][client] = true
 }
 
 func (h *Hub) unregisterClient(client *Client) {
-	h.clientsMux.Lock()
-	if clients, ok := h.clients[client.UserID]; ok {
-		delete(clients, client)
-		if len(clients) == 0 {
-			delete(h.clients, client.UserID)
-		}
+	h.clients.remove(client.UserID, client)
+
+	// Unsubscribe from the client's channels and servers only
+	for _, channelID := range client.hubChannels.take() {
+		h.channels.remove(channelID, client)
 	}
-	h.clientsMux.Unlock()
-
-	// Unsubscribe from all channels
-	h.channelsMux.Lock()
-	for channelID, clients := range h.channels {
-		if _, ok := clients[client]; ok {
-			delete(clients, client)
-			if len(clients) == 0 {
-				delete(h.channels, channelID)
-			}
-		}
+	for _, serverID := range client.hubServers.take() {
+		h.servers.remove(serverID, client)
 	}
-	h.channelsMux.Unlock()
-
-	// Unsubscribe from all servers
-	h.serversMux.Lock()
-	for serverID, clients := range h.servers {
-		if _, ok := clients[client]; ok {
-			delete(clients, client)
-			if len(clients) == 0 {
-				delete(h.servers, serverID)
-			}
+
+	h.memberLists.unsubscribeAll(client)
+
+	if h.presence.disconnect(client) {
+		h.presenceChanged(client.UserID)
+		if h.userListener != nil {
+			h.userListener(client.UserID, false)
 		}
 	}
-	h.serversMux.Unlock()
 
 	close(client.send)
 }
@@ -136,83 +152,72 @@ func (h *Hub) handleBroadcast(event *Event) {
 	if err != nil {
 		return
 	}
+	// Serialized once per format, not per client
+	frames := newFrameSet(data)
+	intent := intentFor(event.Type)
+
+	// Recipients are collected under the shard's lock and sent to after
+	// it's released, so subscribing never waits on a broadcast
+	buf := recipientBuffers.Get().(*[]*Client)
+	clients := (*buf)[:0]
 
 	switch {
 	case event.ChannelID != nil:
 		// Send to all clients subscribed to channel
-		h.channelsMux.RLock()
-		clients := h.channels[*event.ChannelID]
-		h.channelsMux.RUnlock()
-
-		for client := range clients {
-			select {
-			case client.send <- data:
-			default:
-				// Client buffer full, skip
-			}
-		}
+		clients = h.channels.appendClients(clients, *event.ChannelID)
 
 	case event.ServerID != nil:
+		h.memberLists.apply(*event.ServerID, event.Type, data)
+
 		// Send to all clients subscribed to server
-		h.serversMux.RLock()
-		clients := h.servers[*event.ServerID]
-		h.serversMux.RUnlock()
-
-		for client := range clients {
-			select {
-			case client.send <- data:
-			default:
-			}
-		}
+		clients = h.servers.appendClients(clients, *event.ServerID)
 
 	case event.UserID != nil:
 		// Send to specific user (all their connections)
-		h.clientsMux.RLock()
-		clients := h.clients[*event.UserID]
-		h.clientsMux.RUnlock()
-
-		for client := range clients {
-			select {
-			case client.send <- data:
-			default:
-			}
-		}
+		clients = h.clients.appendClients(clients, *event.UserID)
+
+	case event.Everyone:
+		clients = h.clients.appendAll(clients)
+	}
+
+	h.fanout.send(clients, frames, intent)
+
+	clear(clients)
+	*buf = clients[:0]
+	recipientBuffers.Put(buf)
+}
+
+// queueFrame queues a frame in the client's format, subject to its
+// outbound policy
+func queueFrame(client *Client, frames *frameSet) {
+	if frame := frames.get(client.format()); frame != nil {
+		client.queue(frame)
 	}
 }
 
 // SubscribeChannel subscribes a client to a channel
 func (h *Hub) SubscribeChannel(client *Client, channelID uuid.UUID) {
-	h.channelsMux.Lock()
-	defer h.channelsMux.Unlock()
-
-	if h.channels[channelID] == nil {
-		h.channels[channelID] = make(map[*Client]bool)
-	}
-	h.channels[channelID][client] = true
+	h.channels.add(channelID, client)
+	client.hubChannels.add(channelID)
 }
 
 // UnsubscribeChannel unsubscribes a client from a channel
 func (h *Hub) UnsubscribeChannel(client *Client, channelID uuid.UUID) {
-	h.channelsMux.Lock()
-	defer h.channelsMux.Unlock()
-
-	if clients, ok := h.channels[channelID]; ok {
-		delete(clients, client)
-		if len(clients) == 0 {
-			delete(h.channels, channelID)
-		}
-	}
+	h.channels.remove(channelID, client)
+	client.hubChannels.remove(channelID)
 }
 
 // SubscribeServer subscribes a client to a server
 func (h *Hub) SubscribeServer(client *Client, serverID uuid.UUID) {
-	h.serversMux.Lock()
-	defer h.serversMux.Unlock()
+	h.servers.add(serverID, client)
+	client.hubServers.add(serverID)
+}
 
-	if h.servers[serverID] == nil {
-		h.servers[serverID] = make(map[*Client]bool)
-	}
-	h.servers[serverID][client] = true
+// UnsubscribeServer unsubscribes a client from a server
+func (h *Hub) UnsubscribeServer(client *Client, serverID uuid.UUID) {
+	h.servers.remove(serverID, client)
+	client.hubServers.remove(serverID)
+	h.memberLists.unsubscribe(client, serverID)
 }
 
 // Broadcast sends an event to the appropriate recipients
@@ -238,14 +243,18 @@ func (h *Hub) SendToServer(serverID uuid.UUID, event *Event) {
 	h.broadcast <- event
 }
 
+// SendToAll sends an event to every connected client, such as instance
+// announcements
+func (h *Hub) SendToAll(event *Event) {
+	event.Everyone = true
+	h.broadcast <- event
+}
+
 // GetOnlineUsers returns IDs of users who have active connections
 func (h *Hub) GetOnlineUsers(userIDs []uuid.UUID) []uuid.UUID {
-	h.clientsMux.RLock()
-	defer h.clientsMux.RUnlock()
-
 	online := make([]uuid.UUID, 0)
 	for _, id := range userIDs {
-		if _, ok := h.clients[id]; ok {
+		if h.clients.has(id) {
 			online = append(online, id)
 		}
 	}
@@ -254,28 +263,23 @@ func (h *Hub) GetOnlineUsers(userIDs []uuid.UUID) []uuid.UUID {
 
 // getAllClients returns all connected clients (for drain manager)
 func (h *Hub) getAllClients() []*Client {
-	h.clientsMux.RLock()
-	defer h.clientsMux.RUnlock()
-
-	var clients []*Client
-	for _, userClients := range h.clients {
-		for client := range userClients {
-			clients = append(clients, client)
-		}
-	}
-	return clients
+	return h.clients.appendAll(nil)
 }
 
 // GetClientCount returns the number of connected clients
 func (h *Hub) GetClientCount() int {
-	h.clientsMux.RLock()
-	defer h.clientsMux.RUnlock()
+	_, count := h.clients.stats()
+	return count
+}
 
-	count := 0
-	for _, userClients := range h.clients {
-		count += len(userClients)
+// DispatchQueueDepth returns how many events are waiting for the hub to
+// route them and how many are buffered in clients' send queues. Both
+// growing means this instance can't keep up with fan-out.
+func (h *Hub) DispatchQueueDepth() (hub, clients int) {
+	for _, client := range h.getAllClients() {
+		clients += len(client.send)
 	}
-	return count
+	return len(h.broadcast), clients
 }
 
 // Shutdown initiates graceful shutdown of the hub
@@ -289,6 +293,11 @@ func (h *Hub) DrainState() DrainState {
 	return h.drainManager.State()
 }
 
+// DrainProgress returns the progress of an in-flight or completed drain
+func (h *Hub) DrainProgress() DrainProgress {
+	return h.drainManager.Progress()
+}
+
 // IsHealthy returns true if the hub is accepting new connections
 func (h *Hub) IsHealthy() bool {
 	return h.drainManager.IsHealthy()
@@ -301,7 +310,15 @@ func (h *Hub) IsDraining() bool {
 
 // SetDrainConfig sets the drain configuration (must be called before Shutdown)
 func (h *Hub) SetDrainConfig(config *DrainConfig) {
+	handoff := h.drainManager.handoff
 	h.drainManager = NewDrainManager(config, h.getAllClients)
+	h.drainManager.SetSessionHandoff(handoff)
+}
+
+// SetSessionHandoff makes a drain save each client's session before
+// telling it to reconnect, so it can be resumed on another node
+func (h *Hub) SetSessionHandoff(fn SessionHandoff) {
+	h.drainManager.SetSessionHandoff(fn)
 }
 
 // Event types
@@ -311,6 +328,7 @@ const (
 	EventTypeMessageUpdate  = "MESSAGE_UPDATE"
 	EventTypeMessageDelete  = "MESSAGE_DELETE"
 	EventTypeTypingStart    = "TYPING_START"
+	EventTypeTypingStop     = "TYPING_STOP"
 	EventTypePresenceUpdate = "PRESENCE_UPDATE"
 	EventTypeServerCreate   = "SERVER_CREATE"
 	EventTypeServerUpdate   = "SERVER_UPDATE"
@@ -338,6 +356,8 @@ type Event struct {
 	UserID    *uuid.UUID `json:"-"`
 	ChannelID *uuid.UUID `json:"-"`
 	ServerID  *uuid.UUID `json:"-"`
+	// Everyone sends the event to every connected client
+	Everyone bool `json:"-"`
 }
 
 // Event data types
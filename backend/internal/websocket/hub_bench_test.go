@@ -0,0 +1,161 @@
+package websocket
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+const (
+	benchConnections       = 50000
+	benchServers           = 1000
+	benchChannels          = 5000
+	benchServersPerClient  = 5
+	benchChannelsPerClient = 10
+)
+
+// benchHub is a hub with benchConnections clients, each in a few servers
+// and channels, the way a busy node looks
+type benchHub struct {
+	hub      *Hub
+	clients  []*Client
+	servers  []uuid.UUID
+	channels []uuid.UUID
+	rng      *rand.Rand
+}
+
+func newBenchHub(b *testing.B) *benchHub {
+	b.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	b.Cleanup(cancel)
+
+	bh := &benchHub{hub: NewHub(), rng: rand.New(rand.NewSource(1))}
+	// Presence changes from connects and disconnects are queued as
+	// broadcasts; nobody is listening, so they're discarded
+	go func() {
+		for {
+			select {
+			case <-bh.hub.broadcast:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < benchServers; i++ {
+		bh.servers = append(bh.servers, uuid.New())
+	}
+	for i := 0; i < benchChannels; i++ {
+		bh.channels = append(bh.channels, uuid.New())
+	}
+	// Everyone connects before anyone subscribes, so setup doesn't pay
+	// for each connect's presence update reaching more servers
+	for i := 0; i < benchConnections; i++ {
+		client := &Client{ID: uuid.New().String(), UserID: uuid.New(), hub: bh.hub}
+		client.send = make(chan []byte, 8)
+		bh.hub.registerClient(client)
+		bh.clients = append(bh.clients, client)
+	}
+	for _, client := range bh.clients {
+		bh.subscribe(client)
+	}
+	return bh
+}
+
+// connect registers a client and subscribes it the way IDENTIFY does
+func (bh *benchHub) connect(client *Client) {
+	client.send = make(chan []byte, 8)
+	bh.hub.registerClient(client)
+	bh.subscribe(client)
+}
+
+func (bh *benchHub) subscribe(client *Client) {
+	for i := 0; i < benchServersPerClient; i++ {
+		bh.hub.SubscribeServer(client, bh.servers[bh.rng.Intn(len(bh.servers))])
+	}
+	for i := 0; i < benchChannelsPerClient; i++ {
+		bh.hub.SubscribeChannel(client, bh.channels[bh.rng.Intn(len(bh.channels))])
+	}
+}
+
+// drainEvery empties the clients' queues every so many broadcasts, outside
+// the timer, so broadcasts measure delivery rather than dropping
+func (bh *benchHub) drainEvery(b *testing.B, i, every int) {
+	if i%every == every-1 {
+		b.StopTimer()
+		bh.drain()
+		b.StartTimer()
+	}
+}
+
+func (bh *benchHub) drain() {
+	for _, client := range bh.clients {
+		for len(client.send) > 0 {
+			<-client.send
+		}
+	}
+}
+
+func (bh *benchHub) broadcastServer() {
+	serverID := bh.servers[bh.rng.Intn(len(bh.servers))]
+	bh.hub.handleBroadcast(&Event{Op: OpDispatch, Type: EventMessageCreate, ServerID: &serverID, Data: map[string]string{"content": "hello"}})
+}
+
+func BenchmarkHub_BroadcastServer(b *testing.B) {
+	bh := newBenchHub(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		bh.drainEvery(b, i, 256)
+		bh.broadcastServer()
+	}
+}
+
+func BenchmarkHub_BroadcastEveryone(b *testing.B) {
+	bh := newBenchHub(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		bh.drainEvery(b, i, 8)
+		bh.hub.handleBroadcast(&Event{Op: OpDispatch, Type: EventTypeAnnouncementCreate, Everyone: true})
+	}
+}
+
+// BenchmarkHub_BroadcastWithChurn broadcasts to a server while clients
+// disconnect and reconnect, as they do all the time at this size
+func BenchmarkHub_BroadcastWithChurn(b *testing.B) {
+	bh := newBenchHub(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		bh.drainEvery(b, i, 256)
+		bh.broadcastServer()
+
+		client := bh.clients[bh.rng.Intn(len(bh.clients))]
+		bh.hub.unregisterClient(client)
+		bh.connect(client)
+	}
+}
+
+// BenchmarkHub_ParallelSubscribe subscribes and unsubscribes from many
+// goroutines, as IDENTIFY and UPDATE_SUBSCRIPTIONS do on busy nodes
+func BenchmarkHub_ParallelSubscribe(b *testing.B) {
+	bh := newBenchHub(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		rng := rand.New(rand.NewSource(rand.Int63()))
+		for pb.Next() {
+			client := bh.clients[rng.Intn(len(bh.clients))]
+			channelID := bh.channels[rng.Intn(len(bh.channels))]
+			bh.hub.SubscribeChannel(client, channelID)
+			bh.hub.UnsubscribeChannel(client, channelID)
+		}
+	})
+}
@@ -231,21 +231,12 @@ func TestHub_UnsubscribeServerCleanup(t *testing.T) {
 	hub.SubscribeServer(client1, serverID)
 	hub.SubscribeServer(client2, serverID)
 
-	hub.serversMux.RLock()
-	assert.Len(t, hub.servers[serverID], 2)
-	hub.serversMux.RUnlock()
+	assert.Equal(t, 2, hub.servers.count(serverID))
 
 	// Unsubscribe first client
-	hub.serversMux.Lock()
-	delete(hub.servers[serverID], client1)
-	if len(hub.servers[serverID]) == 0 {
-		delete(hub.servers, serverID)
-	}
-	hub.serversMux.Unlock()
+	hub.servers.remove(serverID, client1)
 
-	hub.serversMux.RLock()
-	assert.Len(t, hub.servers[serverID], 1)
-	hub.serversMux.RUnlock()
+	assert.Equal(t, 1, hub.servers.count(serverID))
 }
 
 func TestHub_MultipleUsersPerServer(t *testing.T) {
@@ -443,9 +434,7 @@ func TestHub_UnregisterWithPendingMessages(t *testing.T) {
 	hub.unregister <- client
 	time.Sleep(50 * time.Millisecond)
 
-	hub.clientsMux.RLock()
-	_, exists := hub.clients[client.UserID]
-	hub.clientsMux.RUnlock()
+	exists := hub.clients.has(client.UserID)
 
 	assert.False(t, exists)
 }
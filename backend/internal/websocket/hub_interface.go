@@ -4,6 +4,8 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+
+	"hearth/internal/models"
 )
 
 // HubInterface defines the interface for WebSocket hubs
@@ -22,10 +24,17 @@ type HubInterface interface {
 	SendToUser(userID uuid.UUID, event *Event)
 	SendToChannel(channelID uuid.UUID, event *Event)
 	SendToServer(serverID uuid.UUID, event *Event)
+	SendToAll(event *Event)
 
 	// Queries
 	GetOnlineUsers(userIDs []uuid.UUID) []uuid.UUID
 	GetClientCount() int
+	DispatchQueueDepth() (hub, clients int)
+
+	// Presence
+	UpdatePresence(userID uuid.UUID, status models.PresenceStatus, activities []models.Activity)
+	GetPresences(userIDs []uuid.UUID) map[uuid.UUID]*models.Presence
+	OnPresenceChange(fn func(presence *models.Presence))
 
 	// Registration channels (for Gateway)
 	RegisterClient() chan<- *Client
@@ -36,6 +45,7 @@ type HubInterface interface {
 	IsHealthy() bool
 	IsDraining() bool
 	DrainState() DrainState
+	DrainProgress() DrainProgress
 }
 
 // RegisterClient returns the registration channel
@@ -60,6 +70,7 @@ type DistributedHubInterface interface {
 	SendToChannelDistributed(ctx context.Context, channelID uuid.UUID, eventType string, data interface{}) error
 	SendToServerDistributed(ctx context.Context, serverID uuid.UUID, eventType string, data interface{}) error
 	SendToUserDistributed(ctx context.Context, userID uuid.UUID, eventType string, data interface{}) error
+	SendToAllDistributed(ctx context.Context, eventType string, data interface{}) error
 
 	// User subscription for DMs
 	SubscribeUser(userID uuid.UUID)
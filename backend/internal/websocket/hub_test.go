@@ -38,11 +38,8 @@ func TestHub_RegisterClient(t *testing.T) {
 
 	hub.registerClient(client)
 
-	hub.clientsMux.RLock()
-	defer hub.clientsMux.RUnlock()
-
-	assert.NotNil(t, hub.clients[userID])
-	assert.True(t, hub.clients[userID][client])
+	assert.True(t, hub.clients.has(userID))
+	assert.True(t, hub.clients.contains(userID, client))
 }
 
 func TestHub_RegisterMultipleClientsForSameUser(t *testing.T) {
@@ -72,12 +69,9 @@ func TestHub_RegisterMultipleClientsForSameUser(t *testing.T) {
 	hub.registerClient(client1)
 	hub.registerClient(client2)
 
-	hub.clientsMux.RLock()
-	defer hub.clientsMux.RUnlock()
-
-	assert.Len(t, hub.clients[userID], 2)
-	assert.True(t, hub.clients[userID][client1])
-	assert.True(t, hub.clients[userID][client2])
+	assert.Equal(t, 2, hub.clients.count(userID))
+	assert.True(t, hub.clients.contains(userID, client1))
+	assert.True(t, hub.clients.contains(userID, client2))
 }
 
 func TestHub_UnregisterClient(t *testing.T) {
@@ -97,10 +91,7 @@ func TestHub_UnregisterClient(t *testing.T) {
 	hub.registerClient(client)
 	hub.unregisterClient(client)
 
-	hub.clientsMux.RLock()
-	defer hub.clientsMux.RUnlock()
-
-	_, exists := hub.clients[userID]
+	exists := hub.clients.has(userID)
 	assert.False(t, exists)
 }
 
@@ -122,16 +113,11 @@ func TestHub_UnregisterClientWithChannelSubscriptions(t *testing.T) {
 	hub.registerClient(client)
 	hub.SubscribeChannel(client, channelID)
 
-	hub.channelsMux.RLock()
-	assert.True(t, hub.channels[channelID][client])
-	hub.channelsMux.RUnlock()
+	assert.True(t, hub.channels.contains(channelID, client))
 
 	hub.unregisterClient(client)
 
-	hub.channelsMux.RLock()
-	defer hub.channelsMux.RUnlock()
-
-	_, exists := hub.channels[channelID]
+	exists := hub.channels.has(channelID)
 	assert.False(t, exists)
 }
 
@@ -153,16 +139,11 @@ func TestHub_UnregisterClientWithServerSubscriptions(t *testing.T) {
 	hub.registerClient(client)
 	hub.SubscribeServer(client, serverID)
 
-	hub.serversMux.RLock()
-	assert.True(t, hub.servers[serverID][client])
-	hub.serversMux.RUnlock()
+	assert.True(t, hub.servers.contains(serverID, client))
 
 	hub.unregisterClient(client)
 
-	hub.serversMux.RLock()
-	defer hub.serversMux.RUnlock()
-
-	_, exists := hub.servers[serverID]
+	exists := hub.servers.has(serverID)
 	assert.False(t, exists)
 }
 
@@ -183,11 +164,8 @@ func TestHub_SubscribeChannel(t *testing.T) {
 
 	hub.SubscribeChannel(client, channelID)
 
-	hub.channelsMux.RLock()
-	defer hub.channelsMux.RUnlock()
-
-	assert.NotNil(t, hub.channels[channelID])
-	assert.True(t, hub.channels[channelID][client])
+	assert.True(t, hub.channels.has(channelID))
+	assert.True(t, hub.channels.contains(channelID, client))
 }
 
 func TestHub_UnsubscribeChannel(t *testing.T) {
@@ -208,10 +186,7 @@ func TestHub_UnsubscribeChannel(t *testing.T) {
 	hub.SubscribeChannel(client, channelID)
 	hub.UnsubscribeChannel(client, channelID)
 
-	hub.channelsMux.RLock()
-	defer hub.channelsMux.RUnlock()
-
-	_, exists := hub.channels[channelID]
+	exists := hub.channels.has(channelID)
 	assert.False(t, exists)
 }
 
@@ -232,11 +207,8 @@ func TestHub_SubscribeServer(t *testing.T) {
 
 	hub.SubscribeServer(client, serverID)
 
-	hub.serversMux.RLock()
-	defer hub.serversMux.RUnlock()
-
-	assert.NotNil(t, hub.servers[serverID])
-	assert.True(t, hub.servers[serverID][client])
+	assert.True(t, hub.servers.has(serverID))
+	assert.True(t, hub.servers.contains(serverID, client))
 }
 
 func TestHub_GetOnlineUsers(t *testing.T) {
@@ -287,6 +259,34 @@ func TestHub_GetOnlineUsersEmpty(t *testing.T) {
 	assert.Len(t, online, 0)
 }
 
+func TestHub_DispatchQueueDepth(t *testing.T) {
+	hub := NewHub()
+
+	hubDepth, clientDepth := hub.DispatchQueueDepth()
+	assert.Zero(t, hubDepth)
+	assert.Zero(t, clientDepth)
+
+	client := &Client{
+		ID:       uuid.New().String(),
+		UserID:   uuid.New(),
+		Username: "testuser",
+		hub:      hub,
+		send:     make(chan []byte, 256),
+		servers:  make(map[uuid.UUID]bool),
+		channels: make(map[uuid.UUID]bool),
+	}
+	hub.registerClient(client)
+	client.send <- []byte("one")
+	client.send <- []byte("two")
+
+	// Without Run nothing drains the broadcast queue
+	hub.Broadcast(&Event{Type: EventTypeMessageCreate})
+
+	hubDepth, clientDepth = hub.DispatchQueueDepth()
+	assert.Equal(t, 1, hubDepth)
+	assert.Equal(t, 2, clientDepth)
+}
+
 func TestHub_RunContextCancellation(t *testing.T) {
 	hub := NewHub()
 	ctx, cancel := context.WithCancel(context.Background())
@@ -331,9 +331,7 @@ func TestHub_RunRegisterUnregister(t *testing.T) {
 	// Give it time to process
 	time.Sleep(50 * time.Millisecond)
 
-	hub.clientsMux.RLock()
-	registered := hub.clients[userID][client]
-	hub.clientsMux.RUnlock()
+	registered := hub.clients.contains(userID, client)
 
 	assert.True(t, registered)
 
@@ -343,9 +341,7 @@ func TestHub_RunRegisterUnregister(t *testing.T) {
 	// Give it time to process
 	time.Sleep(50 * time.Millisecond)
 
-	hub.clientsMux.RLock()
-	_, exists := hub.clients[userID]
-	hub.clientsMux.RUnlock()
+	exists := hub.clients.has(userID)
 
 	assert.False(t, exists)
 }
@@ -568,3 +564,27 @@ func TestSubscribeData(t *testing.T) {
 	assert.Equal(t, &channelID, data.ChannelID)
 	assert.Equal(t, &serverID, data.ServerID)
 }
+
+func TestHub_UserListenerSeesFirstAndLastConnection(t *testing.T) {
+	hub := NewHub()
+	userID := uuid.New()
+
+	type change struct {
+		userID uuid.UUID
+		online bool
+	}
+	var changes []change
+	hub.userListener = func(id uuid.UUID, online bool) {
+		changes = append(changes, change{id, online})
+	}
+
+	first := &Client{UserID: userID, send: make(chan []byte, 8)}
+	second := &Client{UserID: userID, send: make(chan []byte, 8)}
+	hub.registerClient(first)
+	hub.registerClient(second)
+	hub.unregisterClient(first)
+	assert.Equal(t, []change{{userID, true}}, changes)
+
+	hub.unregisterClient(second)
+	assert.Equal(t, []change{{userID, true}, {userID, false}}, changes)
+}
@@ -0,0 +1,171 @@
+package websocket
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Intents is a bitfield of the event groups a connection wants, sent with
+// ?intents= on connect or in IDENTIFY. The hub skips connections that
+// didn't ask for an event before it's queued. Connections that send no
+// intents get every event, as before.
+type Intents uint64
+
+const (
+	// IntentServers covers server, role, channel and emoji changes
+	IntentServers Intents = 1 << iota
+	// IntentMembers covers members joining, leaving and being updated
+	IntentMembers
+	// IntentModeration covers bans
+	IntentModeration
+	// IntentInvites covers invites being created and deleted
+	IntentInvites
+	// IntentVoice covers voice state changes
+	IntentVoice
+	// IntentPresence covers presence updates, usually the bulk of a large
+	// server's traffic
+	IntentPresence
+	// IntentMessages covers messages being sent, edited, deleted and pinned
+	IntentMessages
+	// IntentReactions covers reactions
+	IntentReactions
+	// IntentTyping covers typing indicators
+	IntentTyping
+)
+
+// AllIntents is every intent this server knows
+const AllIntents = IntentServers | IntentMembers | IntentModeration | IntentInvites |
+	IntentVoice | IntentPresence | IntentMessages | IntentReactions | IntentTyping
+
+var intentNames = []struct {
+	bit  Intents
+	name string
+}{
+	{IntentServers, "servers"},
+	{IntentMembers, "members"},
+	{IntentModeration, "moderation"},
+	{IntentInvites, "invites"},
+	{IntentVoice, "voice"},
+	{IntentPresence, "presence"},
+	{IntentMessages, "messages"},
+	{IntentReactions, "reactions"},
+	{IntentTyping, "typing"},
+}
+
+// eventIntents maps each filterable event to the intent it needs. Events
+// not listed, such as READY and USER_UPDATE, are always sent.
+var eventIntents = map[string]Intents{
+	EventGuildCreate:              IntentServers,
+	EventGuildUpdate:              IntentServers,
+	EventGuildDelete:              IntentServers,
+	EventGuildRoleCreate:          IntentServers,
+	EventGuildRoleUpdate:          IntentServers,
+	EventGuildRoleDelete:          IntentServers,
+	EventGuildEmojisUpdate:        IntentServers,
+	EventChannelCreate:            IntentServers,
+	EventChannelUpdate:            IntentServers,
+	EventChannelDelete:            IntentServers,
+	EventGuildMemberAdd:           IntentMembers,
+	EventGuildMemberUpdate:        IntentMembers,
+	EventGuildMemberRemove:        IntentMembers,
+	EventGuildBanAdd:              IntentModeration,
+	EventGuildBanRemove:           IntentModeration,
+	EventInviteCreate:             IntentInvites,
+	EventInviteDelete:             IntentInvites,
+	EventVoiceStateUpdate:         IntentVoice,
+	EventPresenceUpdate:           IntentPresence,
+	EventMessageCreate:            IntentMessages,
+	EventMessageUpdate:            IntentMessages,
+	EventMessageDelete:            IntentMessages,
+	EventMessageDeleteBulk:        IntentMessages,
+	EventChannelPinsUpdate:        IntentMessages,
+	EventMessageReactionAdd:       IntentReactions,
+	EventMessageReactionRemove:    IntentReactions,
+	EventMessageReactionRemoveAll: IntentReactions,
+	EventTypingStart:              IntentTyping,
+	EventTypingStop:               IntentTyping,
+
+	// Names the event bridge sends
+	EventTypeServerCreate:        IntentServers,
+	EventTypeServerUpdate:        IntentServers,
+	EventTypeServerDelete:        IntentServers,
+	EventTypeThreadCreate:        IntentServers,
+	EventTypeThreadUpdate:        IntentServers,
+	EventTypeThreadDelete:        IntentServers,
+	EventTypeMemberJoin:          IntentMembers,
+	EventTypeMemberUpdate:        IntentMembers,
+	EventTypeMemberLeave:         IntentMembers,
+	EventTypeStreamCreate:        IntentVoice,
+	EventTypeStreamUpdate:        IntentVoice,
+	EventTypeStreamDelete:        IntentVoice,
+	EventTypeThreadMessageCreate: IntentMessages,
+	EventTypeReactionAdd:         IntentReactions,
+	EventTypeReactionRemove:      IntentReactions,
+}
+
+// intentFor returns the intent needed to receive eventType, or 0 if it's
+// always sent
+func intentFor(eventType string) Intents {
+	return eventIntents[eventType]
+}
+
+// ParseIntents reads a connect query parameter: a decimal bitfield or
+// intent names joined with "|" or ",". Unknown names and bits are refused.
+func ParseIntents(value string) (Intents, bool) {
+	if value == "" {
+		return 0, true
+	}
+	if bits, err := strconv.ParseUint(value, 10, 64); err == nil {
+		return Intents(bits), Intents(bits).Valid()
+	}
+
+	var intents Intents
+	for _, name := range strings.FieldsFunc(value, func(r rune) bool { return r == '|' || r == ',' }) {
+		found := false
+		for _, in := range intentNames {
+			if in.name == strings.TrimSpace(name) {
+				intents |= in.bit
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+	return intents, true
+}
+
+// Has reports whether every bit in flag is set
+func (i Intents) Has(flag Intents) bool {
+	return i&flag == flag
+}
+
+// Valid reports whether every bit set is a known intent
+func (i Intents) Valid() bool {
+	return i&^AllIntents == 0
+}
+
+// String returns the intent names joined with "|"
+func (i Intents) String() string {
+	if i == 0 {
+		return "all"
+	}
+	var names []string
+	for _, in := range intentNames {
+		if i.Has(in.bit) {
+			names = append(names, in.name)
+			i &^= in.bit
+		}
+	}
+	if i != 0 {
+		names = append(names, "0x"+strconv.FormatUint(uint64(i), 16))
+	}
+	return strings.Join(names, "|")
+}
+
+// wants reports whether a client with intents should get an event needing
+// intent. No intents means every event.
+func (i Intents) wants(intent Intents) bool {
+	return i == 0 || intent == 0 || i.Has(intent)
+}
@@ -0,0 +1,90 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIntents(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Intents
+		ok    bool
+	}{
+		{"", 0, true},
+		{"64", IntentMessages, true},
+		{"messages|typing", IntentMessages | IntentTyping, true},
+		{"servers, presence", IntentServers | IntentPresence, true},
+		{"messages|gossip", 0, false},
+		{"1048576", 0, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			got, ok := ParseIntents(tc.value)
+			assert.Equal(t, tc.ok, ok)
+			if ok {
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestIntents_String(t *testing.T) {
+	assert.Equal(t, "all", Intents(0).String())
+	assert.Equal(t, "messages|typing", (IntentMessages | IntentTyping).String())
+}
+
+func TestHub_BroadcastFiltersByIntent(t *testing.T) {
+	hub := NewHub()
+	serverID := uuid.New()
+
+	newClient := func(intents Intents) *Client {
+		c := &Client{
+			ID:       uuid.New().String(),
+			UserID:   uuid.New(),
+			hub:      hub,
+			send:     make(chan []byte, 4),
+			servers:  make(map[uuid.UUID]bool),
+			channels: make(map[uuid.UUID]bool),
+		}
+		c.SetIntents(intents)
+		c.SubscribeServer(serverID)
+		return c
+	}
+	everything := newClient(0)
+	messagesOnly := newClient(IntentMessages)
+
+	hub.handleBroadcast(&Event{Type: EventPresenceUpdate, ServerID: &serverID})
+	hub.handleBroadcast(&Event{Type: EventTypeMemberJoin, ServerID: &serverID})
+	hub.handleBroadcast(&Event{Type: EventMessageCreate, ServerID: &serverID})
+	hub.handleBroadcast(&Event{Type: EventUserUpdate, ServerID: &serverID})
+
+	assert.Len(t, everything.send, 4)
+	require.Len(t, messagesOnly.send, 2)
+	assert.Contains(t, string(<-messagesOnly.send), EventMessageCreate)
+	assert.Contains(t, string(<-messagesOnly.send), EventUserUpdate)
+}
+
+func TestClient_UnsubscribeServerStopsEvents(t *testing.T) {
+	hub := NewHub()
+	serverID := uuid.New()
+	client := &Client{
+		ID:       uuid.New().String(),
+		UserID:   uuid.New(),
+		hub:      hub,
+		send:     make(chan []byte, 4),
+		servers:  make(map[uuid.UUID]bool),
+		channels: make(map[uuid.UUID]bool),
+	}
+
+	client.SubscribeServer(serverID)
+	client.UnsubscribeServer(serverID)
+	hub.handleBroadcast(&Event{Type: EventGuildUpdate, ServerID: &serverID})
+
+	assert.Empty(t, client.send)
+	assert.False(t, hub.servers.has(serverID))
+}
@@ -0,0 +1,857 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+// EventMemberListUpdate carries a member list's SYNC, INSERT, DELETE and
+// UPDATE ops to clients subscribed to ranges of it
+const EventMemberListUpdate = "MEMBER_LIST_UPDATE"
+
+const (
+	// memberListEveryone is the ID of the server-wide list every channel
+	// shows for now
+	memberListEveryone = "everyone"
+
+	memberListGroupOnline  = "online"
+	memberListGroupOffline = "offline"
+
+	maxMemberListRanges     = 5
+	maxMemberListRangeSize  = 100
+	memberListLoadBatch     = 1000
+	memberListLoadTimeout   = 30 * time.Second
+	memberListRevealTimeout = 5 * time.Second
+)
+
+// MemberListSource loads what a server's member list is built from. The
+// ServerService implements it.
+type MemberListSource interface {
+	GetMember(ctx context.Context, serverID, userID uuid.UUID) (*models.Member, error)
+	GetMembers(ctx context.Context, serverID uuid.UUID, limit, offset int) ([]*models.Member, error)
+	GetRoles(ctx context.Context, serverID uuid.UUID) ([]*models.Role, error)
+}
+
+// MemberListOp is one change to a member list. Indexes are rows in the
+// whole list, group headers included; ops apply in order.
+type MemberListOp struct {
+	Op    string            `json:"op"` // SYNC, INSERT, DELETE or UPDATE
+	Range *[2]int           `json:"range,omitempty"`
+	Items []*MemberListItem `json:"items,omitempty"`
+	Index *int              `json:"index,omitempty"`
+	Item  *MemberListItem   `json:"item,omitempty"`
+}
+
+// MemberListItem is a row: either a group header or a member
+type MemberListItem struct {
+	Group  *MemberListGroup  `json:"group,omitempty"`
+	Member *MemberListMember `json:"member,omitempty"`
+}
+
+// MemberListGroup is a hoisted role, "online" or "offline"
+type MemberListGroup struct {
+	ID    string `json:"id"`
+	Count int    `json:"count,omitempty"`
+}
+
+// MemberListMember is a member row
+type MemberListMember struct {
+	User     map[string]interface{} `json:"user"`
+	Nick     *string                `json:"nick"`
+	Roles    []string               `json:"roles"`
+	Presence map[string]string      `json:"presence"`
+}
+
+// MemberListUpdateData is the MEMBER_LIST_UPDATE payload
+type MemberListUpdateData struct {
+	GuildID     string             `json:"guild_id"`
+	ChannelID   string             `json:"channel_id,omitempty"`
+	ID          string             `json:"id"`
+	MemberCount int                `json:"member_count"`
+	OnlineCount int                `json:"online_count"`
+	Groups      []*MemberListGroup `json:"groups"`
+	Ops         []*MemberListOp    `json:"ops"`
+}
+
+// memberListEntry is one member in the index
+type memberListEntry struct {
+	userID   uuid.UUID
+	username string
+	nick     *string
+	roles    []uuid.UUID
+	status   models.PresenceStatus
+	group    string
+	sortKey  string
+}
+
+func (e *memberListEntry) less(other *memberListEntry) bool {
+	if e.sortKey != other.sortKey {
+		return e.sortKey < other.sortKey
+	}
+	return e.userID.String() < other.userID.String()
+}
+
+func (e *memberListEntry) item() *MemberListItem {
+	roles := make([]string, len(e.roles))
+	for i, id := range e.roles {
+		roles[i] = id.String()
+	}
+	user := map[string]interface{}{"id": e.userID.String()}
+	if e.username != "" {
+		user["username"] = e.username
+	}
+	return &MemberListItem{Member: &MemberListMember{
+		User:     user,
+		Nick:     e.nick,
+		Roles:    roles,
+		Presence: map[string]string{"status": string(e.status)},
+	}}
+}
+
+// memberListSub is what one client is watching of a list
+type memberListSub struct {
+	channelID *uuid.UUID
+	ranges    [][2]int
+}
+
+// covers reports whether row i is inside one of the ranges
+func (s *memberListSub) covers(i int) bool {
+	for _, r := range s.ranges {
+		if i >= r[0] && i <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *memberListSub) maxEnd() int {
+	end := -1
+	for _, r := range s.ranges {
+		if r[1] > end {
+			end = r[1]
+		}
+	}
+	return end
+}
+
+// key groups subscribers that are sent the same ops
+func (s *memberListSub) key() string {
+	channel := ""
+	if s.channelID != nil {
+		channel = s.channelID.String()
+	}
+	return fmt.Sprint(channel, s.ranges)
+}
+
+// memberList is a server's member list sorted into groups: hoisted roles
+// by position, then online, then offline. Within a group members sort by
+// display name. Empty groups have no header row.
+type memberList struct {
+	serverID    uuid.UUID
+	hoisted     map[uuid.UUID]*models.Role
+	groupOrder  []string
+	groups      map[string][]*memberListEntry
+	members     map[uuid.UUID]*memberListEntry
+	subscribers map[*Client]*memberListSub
+
+	// Ops for each subscriber key, collected while a change is applied
+	pending map[string][]*MemberListOp
+}
+
+func newMemberList(serverID uuid.UUID, roles []*models.Role) *memberList {
+	l := &memberList{
+		serverID:    serverID,
+		hoisted:     make(map[uuid.UUID]*models.Role),
+		groups:      make(map[string][]*memberListEntry),
+		members:     make(map[uuid.UUID]*memberListEntry),
+		subscribers: make(map[*Client]*memberListSub),
+	}
+
+	var hoisted []*models.Role
+	for _, role := range roles {
+		if role.Hoist && !role.IsDefault {
+			l.hoisted[role.ID] = role
+			hoisted = append(hoisted, role)
+		}
+	}
+	sort.Slice(hoisted, func(i, j int) bool {
+		if hoisted[i].Position != hoisted[j].Position {
+			return hoisted[i].Position > hoisted[j].Position
+		}
+		return hoisted[i].ID.String() < hoisted[j].ID.String()
+	})
+	for _, role := range hoisted {
+		l.groupOrder = append(l.groupOrder, role.ID.String())
+	}
+	l.groupOrder = append(l.groupOrder, memberListGroupOnline, memberListGroupOffline)
+	return l
+}
+
+// place sets the entry's group and sort key from its state
+func (l *memberList) place(e *memberListEntry) {
+	name := e.username
+	if e.nick != nil && *e.nick != "" {
+		name = *e.nick
+	}
+	e.sortKey = strings.ToLower(name)
+
+	if e.status == "" || e.status == models.StatusOffline || e.status == models.StatusInvisible {
+		e.group = memberListGroupOffline
+		return
+	}
+	var top *models.Role
+	for _, id := range e.roles {
+		if role, ok := l.hoisted[id]; ok && (top == nil || role.Position > top.Position) {
+			top = role
+		}
+	}
+	if top != nil {
+		e.group = top.ID.String()
+		return
+	}
+	e.group = memberListGroupOnline
+}
+
+// load adds members without producing ops, while the list is being built
+func (l *memberList) load(e *memberListEntry) {
+	l.place(e)
+	l.members[e.userID] = e
+	l.groups[e.group] = append(l.groups[e.group], e)
+}
+
+func (l *memberList) sortGroups() {
+	for _, entries := range l.groups {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].less(entries[j]) })
+	}
+}
+
+// groupStart returns the header row of a group, where it is or would be
+func (l *memberList) groupStart(group string) int {
+	row := 0
+	for _, id := range l.groupOrder {
+		if id == group {
+			return row
+		}
+		if n := len(l.groups[id]); n > 0 {
+			row += n + 1
+		}
+	}
+	return row
+}
+
+// position returns where e is, or would go, within its group
+func (l *memberList) position(e *memberListEntry) int {
+	entries := l.groups[e.group]
+	return sort.Search(len(entries), func(i int) bool { return !entries[i].less(e) })
+}
+
+func (l *memberList) indexOf(e *memberListEntry) int {
+	return l.groupStart(e.group) + 1 + l.position(e)
+}
+
+// rows returns the number of rows in the list
+func (l *memberList) rows() int {
+	return l.groupStart("")
+}
+
+// row returns the item at row i, or nil past the end
+func (l *memberList) row(i int) *MemberListItem {
+	for _, id := range l.groupOrder {
+		entries := l.groups[id]
+		if len(entries) == 0 {
+			continue
+		}
+		if i == 0 {
+			return &MemberListItem{Group: &MemberListGroup{ID: id, Count: len(entries)}}
+		}
+		if i <= len(entries) {
+			return entries[i-1].item()
+		}
+		i -= len(entries) + 1
+	}
+	return nil
+}
+
+// listEdit is a row inserted, deleted or updated in place
+type listEdit struct {
+	op    string
+	index int
+}
+
+// insert adds e to the list
+func (l *memberList) insert(e *memberListEntry) {
+	l.place(e)
+	l.members[e.userID] = e
+	if len(l.groups[e.group]) == 0 {
+		header := l.groupStart(e.group)
+		l.groups[e.group] = []*memberListEntry{e}
+		l.emit(listEdit{"INSERT", header}, listEdit{"INSERT", header + 1})
+		return
+	}
+	pos := l.position(e)
+	entries := append(l.groups[e.group], nil)
+	copy(entries[pos+1:], entries[pos:])
+	entries[pos] = e
+	l.groups[e.group] = entries
+	l.emit(listEdit{"INSERT", l.indexOf(e)})
+}
+
+// remove takes e out of the list, with its group's header if it was the
+// last member of it
+func (l *memberList) remove(e *memberListEntry) {
+	index := l.indexOf(e)
+	pos := l.position(e)
+	entries := l.groups[e.group]
+	l.groups[e.group] = append(entries[:pos], entries[pos+1:]...)
+	delete(l.members, e.userID)
+	if len(l.groups[e.group]) > 0 {
+		l.emit(listEdit{"DELETE", index})
+		return
+	}
+	delete(l.groups, e.group)
+	l.emit(listEdit{"DELETE", index}, listEdit{"DELETE", index - 1})
+}
+
+// update applies change to a member, moving it if its group or name
+// changed
+func (l *memberList) update(e *memberListEntry, change func(*memberListEntry)) {
+	next := *e
+	change(&next)
+	l.place(&next)
+	if next.group == e.group && next.sortKey == e.sortKey {
+		*e = next
+		l.emit(listEdit{"UPDATE", l.indexOf(e)})
+		return
+	}
+	l.remove(e)
+	l.insert(&next)
+}
+
+// emit translates edits that have just been applied into the ops each
+// subscriber needs to keep its ranges whole. Rows that shift into a range
+// from outside it are sent as UPDATEs.
+func (l *memberList) emit(edits ...listEdit) {
+	if l.pending == nil {
+		return
+	}
+	rows := l.rows()
+	before := rows
+	for _, edit := range edits {
+		switch edit.op {
+		case "INSERT":
+			before--
+		case "DELETE":
+			before++
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, sub := range l.subscribers {
+		key := sub.key()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		end := sub.maxEnd()
+
+		// Rows the client holds, followed through the edits
+		held := make(map[int]bool)
+		for _, r := range sub.ranges {
+			for i := r[0]; i <= r[1] && i < before; i++ {
+				held[i] = true
+			}
+		}
+
+		var ops []*MemberListOp
+		for _, edit := range edits {
+			if edit.index > end {
+				continue
+			}
+			switch edit.op {
+			case "UPDATE":
+				if sub.covers(edit.index) {
+					ops = append(ops, l.rowOp("UPDATE", edit.index))
+				}
+			case "INSERT":
+				ops = append(ops, l.rowOp("INSERT", edit.index))
+				held = shiftRows(held, edit.index, 1)
+				held[edit.index] = true
+			case "DELETE":
+				index := edit.index
+				ops = append(ops, &MemberListOp{Op: "DELETE", Index: &index})
+				delete(held, edit.index)
+				held = shiftRows(held, edit.index, -1)
+			}
+		}
+		for _, r := range sub.ranges {
+			for i := r[0]; i <= r[1] && i < rows; i++ {
+				if !held[i] {
+					ops = append(ops, l.rowOp("UPDATE", i))
+				}
+			}
+		}
+		if len(ops) > 0 {
+			l.pending[key] = append(l.pending[key], ops...)
+		}
+	}
+}
+
+// shiftRows moves held rows at or after index by delta
+func shiftRows(held map[int]bool, index, delta int) map[int]bool {
+	shifted := make(map[int]bool, len(held))
+	for i := range held {
+		if i >= index {
+			shifted[i+delta] = true
+		} else {
+			shifted[i] = true
+		}
+	}
+	return shifted
+}
+
+func (l *memberList) rowOp(op string, index int) *MemberListOp {
+	return &MemberListOp{Op: op, Index: &index, Item: l.row(index)}
+}
+
+// sync returns a SYNC op for each range
+func (l *memberList) sync(ranges [][2]int) []*MemberListOp {
+	ops := make([]*MemberListOp, 0, len(ranges))
+	for _, r := range ranges {
+		r := r
+		items := make([]*MemberListItem, 0, r[1]-r[0]+1)
+		for i := r[0]; i <= r[1]; i++ {
+			item := l.row(i)
+			if item == nil {
+				break
+			}
+			items = append(items, item)
+		}
+		ops = append(ops, &MemberListOp{Op: "SYNC", Range: &r, Items: items})
+	}
+	return ops
+}
+
+// frame builds a MEMBER_LIST_UPDATE frame with the list's counts
+func (l *memberList) frame(sub *memberListSub, ops []*MemberListOp) *frameSet {
+	data := MemberListUpdateData{
+		GuildID:     l.serverID.String(),
+		ID:          memberListEveryone,
+		MemberCount: len(l.members),
+		Groups:      []*MemberListGroup{},
+		Ops:         ops,
+	}
+	if sub.channelID != nil {
+		data.ChannelID = sub.channelID.String()
+	}
+	for _, id := range l.groupOrder {
+		n := len(l.groups[id])
+		if n == 0 {
+			continue
+		}
+		data.Groups = append(data.Groups, &MemberListGroup{ID: id, Count: n})
+		if id != memberListGroupOffline {
+			data.OnlineCount += n
+		}
+	}
+
+	frame, err := json.Marshal(&Event{Op: OpDispatch, Type: EventMemberListUpdate, Data: data})
+	if err != nil {
+		log.Printf("[Hub] Failed to marshal member list update for %s: %v", l.serverID, err)
+		return nil
+	}
+	return newFrameSet(frame)
+}
+
+// change applies fn and sends the resulting ops to subscribers
+func (l *memberList) change(fn func()) {
+	l.pending = make(map[string][]*MemberListOp)
+	fn()
+	pending := l.pending
+	l.pending = nil
+
+	frames := make(map[string]*frameSet)
+	for client, sub := range l.subscribers {
+		key := sub.key()
+		ops := pending[key]
+		if len(ops) == 0 {
+			continue
+		}
+		if frames[key] == nil {
+			frames[key] = l.frame(sub, ops)
+		}
+		queueFrame(client, frames[key])
+	}
+}
+
+// memberListIndex keeps the member lists of servers clients on this node
+// are watching. A list is loaded when its first subscriber arrives, kept
+// up to date from the presence and member events the hub routes, and
+// dropped with its last subscriber.
+type memberListIndex struct {
+	mu     sync.Mutex
+	lists  map[uuid.UUID]*memberList
+	source MemberListSource
+	// Presence of the members when a list is loaded, across every node
+	presences func(userIDs []uuid.UUID) map[uuid.UUID]*models.Presence
+	// Presence of members that join a watched server, from this node
+	localPresence func(userID uuid.UUID) *models.Presence
+	// Hides presence members only show their friends
+	filter PresenceFilter
+	// Serializes loads so a busy server is only read once
+	loadMu sync.Mutex
+}
+
+func newMemberListIndex(h *Hub) *memberListIndex {
+	return &memberListIndex{
+		lists:         make(map[uuid.UUID]*memberList),
+		presences:     h.GetPresences,
+		localPresence: h.presence.get,
+	}
+}
+
+var errMemberListsUnavailable = errors.New("member lists are not enabled")
+
+// memberListViewer is who lists are filtered for. Every subscriber shares
+// a list, so it's nobody in particular, and members who only show their
+// presence to friends are listed offline.
+var memberListViewer = uuid.Nil
+
+// SetMemberListSource enables member list subscriptions on this hub
+func (h *Hub) SetMemberListSource(source MemberListSource) {
+	h.memberLists.mu.Lock()
+	defer h.memberLists.mu.Unlock()
+	h.memberLists.source = source
+}
+
+// SetPresenceFilter shows members who only share their presence with
+// friends as offline in member lists
+func (h *Hub) SetPresenceFilter(filter PresenceFilter) {
+	h.memberLists.mu.Lock()
+	defer h.memberLists.mu.Unlock()
+	h.memberLists.filter = filter
+}
+
+// SubscribeMemberList sets the ranges of a server's member list the client
+// watches and queues a SYNC of them. No ranges ends the subscription.
+func (h *Hub) SubscribeMemberList(ctx context.Context, client *Client, serverID uuid.UUID, channelID *uuid.UUID, ranges [][2]int) error {
+	idx := h.memberLists
+	if len(ranges) == 0 {
+		idx.unsubscribe(client, serverID)
+		return nil
+	}
+
+	list, err := idx.get(ctx, serverID)
+	if err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	// A list dropped while it was loading is kept for this subscriber
+	if idx.lists[serverID] == nil {
+		idx.lists[serverID] = list
+	}
+	list = idx.lists[serverID]
+
+	sub := &memberListSub{channelID: channelID, ranges: ranges}
+	list.subscribers[client] = sub
+	// Queued under the lock so no update can overtake it
+	if frames := list.frame(sub, list.sync(ranges)); frames != nil {
+		queueFrame(client, frames)
+	}
+	return nil
+}
+
+// get returns the server's list, loading it if nobody is watching it yet
+func (idx *memberListIndex) get(ctx context.Context, serverID uuid.UUID) (*memberList, error) {
+	idx.mu.Lock()
+	list, source, filter := idx.lists[serverID], idx.source, idx.filter
+	idx.mu.Unlock()
+	if list != nil {
+		return list, nil
+	}
+	if source == nil {
+		return nil, errMemberListsUnavailable
+	}
+
+	idx.loadMu.Lock()
+	defer idx.loadMu.Unlock()
+	idx.mu.Lock()
+	list = idx.lists[serverID]
+	idx.mu.Unlock()
+	if list != nil {
+		return list, nil
+	}
+
+	roles, err := source.GetRoles(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	list = newMemberList(serverID, roles)
+
+	var userIDs []uuid.UUID
+	var members []*models.Member
+	for offset := 0; ; offset += memberListLoadBatch {
+		batch, err := source.GetMembers(ctx, serverID, memberListLoadBatch, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range batch {
+			members = append(members, m)
+			userIDs = append(userIDs, m.UserID)
+		}
+		if len(batch) < memberListLoadBatch {
+			break
+		}
+	}
+
+	presences := idx.presences(userIDs)
+	if filter != nil {
+		if err := filter.FilterPresences(ctx, memberListViewer, presences); err != nil {
+			return nil, err
+		}
+	}
+	for _, m := range members {
+		entry := &memberListEntry{
+			userID: m.UserID,
+			nick:   m.Nickname,
+			roles:  m.Roles,
+		}
+		if p := presences[m.UserID]; p != nil {
+			entry.status = p.Status
+		}
+		if m.User != nil {
+			entry.username = m.User.Username
+		}
+		list.load(entry)
+	}
+	list.sortGroups()
+	return list, nil
+}
+
+// unsubscribe stops the client watching a server's list
+func (idx *memberListIndex) unsubscribe(client *Client, serverID uuid.UUID) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if list, ok := idx.lists[serverID]; ok {
+		delete(list.subscribers, client)
+		if len(list.subscribers) == 0 {
+			delete(idx.lists, serverID)
+		}
+	}
+}
+
+// unsubscribeAll drops a disconnecting client from every list
+func (idx *memberListIndex) unsubscribeAll(client *Client) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for serverID, list := range idx.lists {
+		delete(list.subscribers, client)
+		if len(list.subscribers) == 0 {
+			delete(idx.lists, serverID)
+		}
+	}
+}
+
+// memberEventFrame is the part of presence and member events the index
+// reads
+type memberEventFrame struct {
+	D struct {
+		User struct {
+			ID       string `json:"id"`
+			Username string `json:"username"`
+		} `json:"user"`
+		Nick   *string   `json:"nick"`
+		Roles  *[]string `json:"roles"`
+		Status string    `json:"status"`
+	} `json:"d"`
+}
+
+// apply updates a watched server's list from an event the hub is routing
+func (idx *memberListIndex) apply(serverID uuid.UUID, eventType string, data []byte) {
+	switch eventType {
+	case EventTypePresenceUpdate, EventTypeMemberJoin, EventGuildMemberAdd,
+		EventTypeMemberUpdate, EventGuildMemberUpdate, EventTypeMemberLeave, EventGuildMemberRemove,
+		EventTypeServerDelete, EventGuildDelete:
+	default:
+		return
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	list, ok := idx.lists[serverID]
+	if !ok {
+		return
+	}
+	if eventType == EventTypeServerDelete || eventType == EventGuildDelete {
+		delete(idx.lists, serverID)
+		return
+	}
+
+	var frame memberEventFrame
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return
+	}
+	d := frame.D
+	userID, err := uuid.Parse(d.User.ID)
+	if err != nil {
+		return
+	}
+	entry := list.members[userID]
+
+	list.change(func() {
+		switch eventType {
+		case EventTypePresenceUpdate:
+			if entry != nil && entry.status != models.PresenceStatus(d.Status) {
+				list.update(entry, func(e *memberListEntry) { e.status = models.PresenceStatus(d.Status) })
+			}
+
+		case EventTypeMemberJoin, EventGuildMemberAdd:
+			if entry == nil {
+				status := idx.localPresence(userID).Status
+				// Checking privacy can't hold up routing, so members join
+				// offline until it's known they may be shown
+				if idx.filter != nil && status != models.StatusOffline {
+					status = models.StatusOffline
+					go idx.reveal(serverID, userID)
+				}
+				list.insert(&memberListEntry{
+					userID:   userID,
+					username: d.User.Username,
+					nick:     d.Nick,
+					roles:    parseRoleIDs(d.Roles),
+					status:   status,
+				})
+			}
+
+		case EventTypeMemberUpdate, EventGuildMemberUpdate:
+			if entry != nil {
+				list.update(entry, func(e *memberListEntry) {
+					e.nick = d.Nick
+					if d.Roles != nil {
+						e.roles = parseRoleIDs(d.Roles)
+					}
+					if d.User.Username != "" {
+						e.username = d.User.Username
+					}
+				})
+			}
+
+		case EventTypeMemberLeave, EventGuildMemberRemove:
+			if entry != nil {
+				list.remove(entry)
+			}
+		}
+	})
+}
+
+// reveal shows the presence of a member who joined a watched server, if
+// they share it with everyone
+func (idx *memberListIndex) reveal(serverID, userID uuid.UUID) {
+	idx.mu.Lock()
+	filter := idx.filter
+	idx.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), memberListRevealTimeout)
+	defer cancel()
+	presences := map[uuid.UUID]*models.Presence{userID: idx.localPresence(userID)}
+	if err := filter.FilterPresences(ctx, memberListViewer, presences); err != nil {
+		log.Printf("[Hub] Failed to check presence privacy of %s: %v", userID, err)
+		return
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	list, ok := idx.lists[serverID]
+	if !ok {
+		return
+	}
+	entry := list.members[userID]
+	// Read again, as they may have gone offline while privacy was checked
+	status := idx.localPresence(userID).Status
+	if entry == nil || entry.status != models.StatusOffline || presences[userID].Status == models.StatusOffline || status == models.StatusOffline {
+		return
+	}
+	list.change(func() {
+		list.update(entry, func(e *memberListEntry) { e.status = status })
+	})
+}
+
+func parseRoleIDs(ids *[]string) []uuid.UUID {
+	if ids == nil {
+		return nil
+	}
+	roles := make([]uuid.UUID, 0, len(*ids))
+	for _, id := range *ids {
+		if roleID, err := uuid.Parse(id); err == nil {
+			roles = append(roles, roleID)
+		}
+	}
+	return roles
+}
+
+// SetMemberListSource enables subscribing to member lists with
+// MEMBER_LIST_SUBSCRIBE
+func (g *Gateway) SetMemberListSource(source MemberListSource) {
+	g.memberLists = source
+	if hub := g.baseHub(); hub != nil {
+		hub.SetMemberListSource(source)
+	}
+}
+
+// SetPresenceFilter keeps presence members only share with friends out of
+// what other members are sent
+func (g *Gateway) SetPresenceFilter(filter PresenceFilter) {
+	g.presenceFilter = filter
+	if hub := g.baseHub(); hub != nil {
+		hub.SetPresenceFilter(filter)
+	}
+}
+
+func (g *Gateway) handleMemberListSubscribe(conn *websocket.Conn, client *Client, session *Session, msg *Message) {
+	var data memberListPayload
+	if msg.Data != nil {
+		json.Unmarshal(msg.Data, &data)
+	}
+	if g.memberLists == nil || client.hub == nil {
+		g.sendError(conn, "member lists are not available")
+		return
+	}
+
+	serverID := uuid.MustParse(data.GuildID)
+	var channelID *uuid.UUID
+	if data.ChannelID != "" {
+		id := uuid.MustParse(data.ChannelID)
+		channelID = &id
+	}
+	ranges := make([][2]int, len(data.Ranges))
+	for i, r := range data.Ranges {
+		ranges[i] = [2]int{r[0], r[1]}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), memberListLoadTimeout)
+	defer cancel()
+
+	if len(ranges) > 0 {
+		if member, err := g.memberLists.GetMember(ctx, serverID, session.UserID); err != nil || member == nil {
+			g.rejectMessage(conn, invalidPayload(msg.Op, "d.guild_id", "not a member of this server"))
+			return
+		}
+	}
+	if err := client.hub.SubscribeMemberList(ctx, client, serverID, channelID, ranges); err != nil {
+		log.Printf("[Gateway] Failed to load member list for server %s: %v", serverID, err)
+		g.sendError(conn, "member list unavailable")
+	}
+}
@@ -0,0 +1,302 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeMemberListSource struct {
+	members []*models.Member
+	roles   []*models.Role
+	loads   int
+}
+
+func (f *fakeMemberListSource) GetMember(ctx context.Context, serverID, userID uuid.UUID) (*models.Member, error) {
+	for _, m := range f.members {
+		if m.UserID == userID {
+			return m, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeMemberListSource) GetMembers(ctx context.Context, serverID uuid.UUID, limit, offset int) ([]*models.Member, error) {
+	if offset == 0 {
+		f.loads++
+	}
+	if offset >= len(f.members) {
+		return nil, nil
+	}
+	end := offset + limit
+	if end > len(f.members) {
+		end = len(f.members)
+	}
+	return f.members[offset:end], nil
+}
+
+func (f *fakeMemberListSource) GetRoles(ctx context.Context, serverID uuid.UUID) ([]*models.Role, error) {
+	return f.roles, nil
+}
+
+// memberListView is a client's copy of its ranges of a member list
+type memberListView map[int]string
+
+func rowName(item *MemberListItem) string {
+	if item.Group != nil {
+		return "group:" + item.Group.ID
+	}
+	return fmt.Sprintf("%s:%s", item.Member.User["id"], item.Member.Presence["status"])
+}
+
+// apply plays MEMBER_LIST_UPDATE frames the way a client would
+func (v memberListView) apply(t *testing.T, frame []byte, ranges [][2]int) {
+	var msg struct {
+		D MemberListUpdateData `json:"d"`
+	}
+	require.NoError(t, json.Unmarshal(frame, &msg))
+
+	for _, op := range msg.D.Ops {
+		switch op.Op {
+		case "SYNC":
+			for i := op.Range[0]; i <= op.Range[1]; i++ {
+				delete(v, i)
+			}
+			for i, item := range op.Items {
+				v[op.Range[0]+i] = rowName(item)
+			}
+		case "UPDATE":
+			v[*op.Index] = rowName(op.Item)
+		case "INSERT":
+			v.shift(*op.Index, 1)
+			v[*op.Index] = rowName(op.Item)
+		case "DELETE":
+			delete(v, *op.Index)
+			v.shift(*op.Index, -1)
+		}
+	}
+	for i := range v {
+		covered := false
+		for _, r := range ranges {
+			covered = covered || (i >= r[0] && i <= r[1])
+		}
+		if !covered {
+			delete(v, i)
+		}
+	}
+}
+
+func (v memberListView) shift(index, delta int) {
+	moved := make(map[int]string)
+	for i, name := range v {
+		if i >= index {
+			moved[i+delta] = name
+			delete(v, i)
+		}
+	}
+	for i, name := range moved {
+		v[i] = name
+	}
+}
+
+func drain(t *testing.T, client *Client, view memberListView, ranges [][2]int) {
+	for {
+		select {
+		case frame := <-client.send:
+			view.apply(t, frame, ranges)
+		default:
+			return
+		}
+	}
+}
+
+func TestHub_MemberListSync(t *testing.T) {
+	hub := NewHub()
+	serverID := uuid.New()
+	mods := &models.Role{ID: uuid.New(), Name: "Mods", Position: 5, Hoist: true}
+	alice := &models.Member{UserID: uuid.New(), User: &models.PublicUser{Username: "alice"}}
+	bob := &models.Member{UserID: uuid.New(), User: &models.PublicUser{Username: "bob"}, Roles: []uuid.UUID{mods.ID}}
+	carol := &models.Member{UserID: uuid.New(), User: &models.PublicUser{Username: "carol"}}
+	source := &fakeMemberListSource{members: []*models.Member{carol, bob, alice}, roles: []*models.Role{mods}}
+	hub.SetMemberListSource(source)
+
+	// Bob and Carol are online, Alice isn't
+	for _, m := range []*models.Member{bob, carol} {
+		hub.registerClient(&Client{UserID: m.UserID, send: make(chan []byte, 8)})
+	}
+
+	client := &Client{UserID: alice.UserID, hub: hub, send: make(chan []byte, 8)}
+	require.NoError(t, hub.SubscribeMemberList(context.Background(), client, serverID, nil, [][2]int{{0, 99}}))
+
+	require.Len(t, client.send, 1)
+	var msg struct {
+		T string               `json:"t"`
+		D MemberListUpdateData `json:"d"`
+	}
+	require.NoError(t, json.Unmarshal(<-client.send, &msg))
+	assert.Equal(t, EventMemberListUpdate, msg.T)
+	assert.Equal(t, 3, msg.D.MemberCount)
+	assert.Equal(t, 2, msg.D.OnlineCount)
+
+	require.Len(t, msg.D.Ops, 1)
+	var rows []string
+	for _, item := range msg.D.Ops[0].Items {
+		rows = append(rows, rowName(item))
+	}
+	assert.Equal(t, []string{
+		"group:" + mods.ID.String(), bob.UserID.String() + ":online",
+		"group:online", carol.UserID.String() + ":online",
+		"group:offline", alice.UserID.String() + ":offline",
+	}, rows)
+
+	// A second subscriber shares the loaded list
+	other := &Client{UserID: bob.UserID, hub: hub, send: make(chan []byte, 8)}
+	require.NoError(t, hub.SubscribeMemberList(context.Background(), other, serverID, nil, [][2]int{{0, 99}}))
+	assert.Equal(t, 1, source.loads)
+
+	// The list goes with its last subscriber
+	hub.memberLists.unsubscribe(client, serverID)
+	hub.memberLists.unsubscribeAll(other)
+	assert.Empty(t, hub.memberLists.lists)
+}
+
+func TestHub_MemberListDeltasKeepRangesWhole(t *testing.T) {
+	hub := NewHub()
+	serverID := uuid.New()
+	roles := []*models.Role{
+		{ID: uuid.New(), Position: 3, Hoist: true},
+		{ID: uuid.New(), Position: 2, Hoist: true},
+	}
+	var members []*models.Member
+	for i := 0; i < 60; i++ {
+		m := &models.Member{UserID: uuid.New(), User: &models.PublicUser{Username: fmt.Sprintf("user%02d", i)}}
+		if i%7 == 0 {
+			m.Roles = []uuid.UUID{roles[i%2].ID}
+		}
+		members = append(members, m)
+	}
+	hub.SetMemberListSource(&fakeMemberListSource{members: members, roles: roles})
+
+	type watcher struct {
+		client *Client
+		ranges [][2]int
+		view   memberListView
+	}
+	var watchers []*watcher
+	for _, ranges := range [][][2]int{{{0, 19}}, {{10, 29}, {40, 49}}, {{50, 69}}} {
+		w := &watcher{client: &Client{UserID: uuid.New(), hub: hub, send: make(chan []byte, 1024)}, ranges: ranges, view: memberListView{}}
+		require.NoError(t, hub.SubscribeMemberList(context.Background(), w.client, serverID, nil, ranges))
+		drain(t, w.client, w.view, ranges)
+		watchers = append(watchers, w)
+	}
+
+	statuses := []string{"online", "idle", "dnd", "offline"}
+	rng := rand.New(rand.NewSource(1))
+	for step := 0; step < 300; step++ {
+		m := members[rng.Intn(len(members))]
+		switch rng.Intn(6) {
+		case 0:
+			hub.handleBroadcast(&Event{Type: EventTypeMemberLeave, ServerID: &serverID,
+				Data: map[string]interface{}{"user": map[string]string{"id": m.UserID.String()}}})
+		case 1:
+			hub.handleBroadcast(&Event{Type: EventTypeMemberJoin, ServerID: &serverID,
+				Data: map[string]interface{}{"user": map[string]string{"id": m.UserID.String(), "username": m.User.Username}}})
+		case 2:
+			nick := fmt.Sprintf("nick%03d", rng.Intn(1000))
+			hub.handleBroadcast(&Event{Type: EventTypeMemberUpdate, ServerID: &serverID,
+				Data: map[string]interface{}{"user": map[string]string{"id": m.UserID.String()}, "nick": nick, "roles": []string{roles[rng.Intn(2)].ID.String()}}})
+		default:
+			hub.handleBroadcast(&Event{Type: EventTypePresenceUpdate, ServerID: &serverID,
+				Data: PresenceUpdateData{User: map[string]string{"id": m.UserID.String()}, Status: statuses[rng.Intn(len(statuses))]}})
+		}
+
+		list := hub.memberLists.lists[serverID]
+		for _, w := range watchers {
+			drain(t, w.client, w.view, w.ranges)
+			want := memberListView{}
+			for _, r := range w.ranges {
+				for i := r[0]; i <= r[1]; i++ {
+					if item := list.row(i); item != nil {
+						want[i] = rowName(item)
+					}
+				}
+			}
+			require.Equal(t, want, w.view, "step %d, ranges %v", step, w.ranges)
+		}
+	}
+}
+
+// fakePresenceFilter hides the presence of users from everyone but their
+// friends
+type fakePresenceFilter struct {
+	hidden  map[uuid.UUID]bool
+	friends map[uuid.UUID]uuid.UUID
+}
+
+func (f *fakePresenceFilter) FilterPresences(ctx context.Context, viewerID uuid.UUID, presences map[uuid.UUID]*models.Presence) error {
+	for userID := range presences {
+		friend, ok := f.friends[userID]
+		if f.hidden[userID] && userID != viewerID && !(ok && friend == viewerID) {
+			presences[userID] = &models.Presence{UserID: userID, Status: models.StatusOffline}
+		}
+	}
+	return nil
+}
+
+func TestHub_MemberListHidesFriendsOnlyPresence(t *testing.T) {
+	hub := NewHub()
+	serverID := uuid.New()
+	alice := &models.Member{UserID: uuid.New(), User: &models.PublicUser{Username: "alice"}}
+	bob := &models.Member{UserID: uuid.New(), User: &models.PublicUser{Username: "bob"}}
+	carol := &models.Member{UserID: uuid.New(), User: &models.PublicUser{Username: "carol"}}
+	dave, erin := uuid.New(), uuid.New()
+	hub.SetMemberListSource(&fakeMemberListSource{members: []*models.Member{alice, bob, carol}})
+	// Bob and Dave only show their presence to friends; Bob is Alice's
+	hub.SetPresenceFilter(&fakePresenceFilter{
+		hidden:  map[uuid.UUID]bool{bob.UserID: true, dave: true},
+		friends: map[uuid.UUID]uuid.UUID{bob.UserID: alice.UserID},
+	})
+	for _, id := range []uuid.UUID{bob.UserID, carol.UserID, dave, erin} {
+		hub.registerClient(&Client{UserID: id, send: make(chan []byte, 8)})
+	}
+
+	// The list is shared, so even Bob's friend sees him offline
+	client := &Client{UserID: alice.UserID, hub: hub, send: make(chan []byte, 64)}
+	require.NoError(t, hub.SubscribeMemberList(context.Background(), client, serverID, nil, [][2]int{{0, 99}}))
+	view := memberListView{}
+	drain(t, client, view, [][2]int{{0, 99}})
+	assert.ElementsMatch(t, []string{
+		"group:online", carol.UserID.String() + ":online",
+		"group:offline", alice.UserID.String() + ":offline", bob.UserID.String() + ":offline",
+	}, values(view))
+
+	// Members who join show online once they're known to allow it
+	for i, id := range []uuid.UUID{dave, erin} {
+		hub.handleBroadcast(&Event{Type: EventTypeMemberJoin, ServerID: &serverID,
+			Data: map[string]interface{}{"user": map[string]string{"id": id.String(), "username": fmt.Sprint("joiner", i)}}})
+	}
+	status := func(userID uuid.UUID) models.PresenceStatus {
+		hub.memberLists.mu.Lock()
+		defer hub.memberLists.mu.Unlock()
+		return hub.memberLists.lists[serverID].members[userID].status
+	}
+	require.Eventually(t, func() bool { return status(erin) == models.StatusOnline }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, models.StatusOffline, status(dave))
+}
+
+func values(view memberListView) []string {
+	rows := make([]string, 0, len(view))
+	for _, row := range view {
+		rows = append(rows, row)
+	}
+	return rows
+}
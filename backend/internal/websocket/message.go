@@ -1,6 +1,10 @@
 package websocket
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"hearth/internal/models"
+)
 
 // Opcodes
 const (
@@ -15,6 +19,10 @@ const (
 	OpInvalidSession      = 9  // Receive: Invalid session
 	OpHello               = 10 // Receive: Hello
 	OpHeartbeatAck        = 11 // Receive: Heartbeat ack
+	OpJoinGuild           = 12 // Send: Join a server with an invite
+	OpTokenRefresh        = 13 // Send: Refresh the access token
+	OpUpdateSubscriptions = 14 // Send: Change intents and server/channel subscriptions
+	OpMemberListSubscribe = 15 // Send: Watch ranges of a server's member list
 )
 
 // Message represents a WebSocket message
@@ -37,6 +45,7 @@ const (
 	EventMessageReactionRemove     = "MESSAGE_REACTION_REMOVE"
 	EventMessageReactionRemoveAll  = "MESSAGE_REACTION_REMOVE_ALL"
 	EventTypingStart               = "TYPING_START"
+	EventTypingStop                = "TYPING_STOP"
 	EventChannelCreate             = "CHANNEL_CREATE"
 	EventChannelUpdate             = "CHANNEL_UPDATE"
 	EventChannelDelete             = "CHANNEL_DELETE"
@@ -90,6 +99,15 @@ type ReadyData struct {
 	PrivateChannels []interface{} `json:"private_channels"`
 	SessionID       string        `json:"session_id"`
 	ResumeURL       string        `json:"resume_gateway_url,omitempty"`
+	Capabilities    uint64        `json:"capabilities,omitempty"`
+	Intents         uint64        `json:"intents,omitempty"`
+
+	// ReadOnly is set while the instance refuses writes
+	ReadOnly *models.ReadOnlyMode `json:"read_only,omitempty"`
+
+	// Set along with the guilds when the user's snapshot was loaded
+	ReadStates    []models.ReadStateWithUnread `json:"read_states,omitempty"`
+	Relationships []models.Relationship        `json:"relationships,omitempty"`
 }
 
 // MessageCreateData represents a new message event
@@ -130,6 +148,7 @@ type PresenceUpdateData struct {
 	Status       string        `json:"status"`
 	Activities   []interface{} `json:"activities"`
 	ClientStatus interface{}   `json:"client_status"`
+	CustomStatus interface{}   `json:"custom_status,omitempty"`
 }
 
 // GuildMemberAddData represents a member join
@@ -0,0 +1,173 @@
+package websocket
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+
+	"hearth/internal/metrics"
+)
+
+// OutboundPolicy is what happens to a connection whose send queue stays
+// backed up. Either way the hub never waits on a slow client.
+type OutboundPolicy string
+
+const (
+	// OutboundDrop drops events for the connection until its queue drains
+	// below the lag threshold. It stays connected but misses them.
+	OutboundDrop OutboundPolicy = "drop"
+	// OutboundClose closes the connection with 4020. Events still queued
+	// are kept for resume, so a client that resumes misses nothing that
+	// fit in its queue.
+	OutboundClose OutboundPolicy = "close"
+)
+
+// ParseOutboundPolicy reads an outbound policy name
+func ParseOutboundPolicy(value string) (OutboundPolicy, bool) {
+	switch policy := OutboundPolicy(value); policy {
+	case OutboundDrop, OutboundClose:
+		return policy, true
+	}
+	return "", false
+}
+
+// outboundQueue watches one connection's send queue. The queue itself is
+// the client's buffered send channel; this decides what happens once it
+// backs up.
+type outboundQueue struct {
+	policy     OutboundPolicy
+	threshold  int
+	maxLag     time.Duration
+	clientType string
+	metrics    *metrics.WebSocketMetrics
+
+	// evict closes the connection; called once, off the hub's goroutine
+	evict func()
+
+	// When the queue last reached the threshold, in Unix nanoseconds; 0
+	// while the client keeps up
+	lagSince atomic.Int64
+	dropped  atomic.Int64
+	evicted  atomic.Bool
+}
+
+// newOutboundQueue applies the config's outbound policy to a connection
+// with a queue of size frames. Settings left unset take their defaults.
+func newOutboundQueue(config *GatewayConfig, size int, clientType string, wsMetrics *metrics.WebSocketMetrics, evict func()) *outboundQueue {
+	defaults := DefaultGatewayConfig()
+	q := &outboundQueue{
+		policy:     config.OutboundPolicy,
+		threshold:  config.OutboundLagThreshold,
+		maxLag:     config.OutboundMaxLag,
+		clientType: clientType,
+		metrics:    wsMetrics,
+		evict:      evict,
+	}
+	if q.policy == "" {
+		q.policy = defaults.OutboundPolicy
+	}
+	if q.threshold <= 0 || q.threshold > size {
+		q.threshold = size
+	}
+	if q.maxLag <= 0 {
+		q.maxLag = defaults.OutboundMaxLag
+	}
+	return q
+}
+
+// lagging reports whether the queue has stayed at or over the threshold for
+// longer than maxLag, given its depth now
+func (q *outboundQueue) lagging(depth int, now time.Time) bool {
+	if depth < q.threshold {
+		q.lagSince.Store(0)
+		return false
+	}
+	since := q.lagSince.Load()
+	if since == 0 {
+		q.lagSince.CompareAndSwap(0, now.UnixNano())
+		return false
+	}
+	return now.Sub(time.Unix(0, since)) > q.maxLag
+}
+
+// admit decides whether a frame may be queued behind depth others
+func (q *outboundQueue) admit(depth int, now time.Time) bool {
+	if !q.lagging(depth, now) {
+		return true
+	}
+	if q.policy == OutboundClose {
+		q.close("lagging")
+		// Queued anyway: the connection is going and what's queued is
+		// kept for resume
+		return true
+	}
+	q.drop("lagging")
+	return false
+}
+
+// queued records the queue's depth after a frame went in
+func (q *outboundQueue) queued(depth int) {
+	if q.metrics != nil {
+		q.metrics.OutboundQueued(depth)
+	}
+}
+
+// full deals with a frame that didn't fit
+func (q *outboundQueue) full() {
+	q.drop("queue_full")
+	if q.policy == OutboundClose {
+		q.close("queue full")
+	}
+}
+
+func (q *outboundQueue) drop(reason string) {
+	q.dropped.Add(1)
+	if q.metrics != nil {
+		q.metrics.OutboundDropped(reason)
+	}
+}
+
+// close evicts the connection once. Closing writes to the connection, so
+// it's done off the caller's goroutine.
+func (q *outboundQueue) close(reason string) {
+	if !q.evicted.CompareAndSwap(false, true) {
+		return
+	}
+	log.Printf("[Gateway] Closing slow %s connection: %s", q.clientType, reason)
+	if q.metrics != nil {
+		q.metrics.SlowConsumerEvicted(q.clientType)
+	}
+	if q.evict != nil {
+		go q.evict()
+	}
+}
+
+// closed records what the connection dropped over its life
+func (q *outboundQueue) closed() {
+	if q.metrics != nil {
+		q.metrics.OutboundClosed(q.clientType, q.dropped.Load())
+	}
+}
+
+// queue adds a frame to the client's send queue without blocking. Without
+// an outbound policy frames that don't fit are dropped.
+func (c *Client) queue(frame []byte) bool {
+	q := c.outbound
+	// Once evicted, frames keep being queued for resume
+	if q != nil && !q.evicted.Load() && !q.admit(len(c.send), time.Now()) {
+		return false
+	}
+
+	select {
+	case c.send <- frame:
+		if q != nil {
+			q.queued(len(c.send))
+		}
+		return true
+	default:
+		if q != nil {
+			q.full()
+		}
+		return false
+	}
+}
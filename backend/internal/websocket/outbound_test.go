@@ -0,0 +1,120 @@
+package websocket
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOutboundClient(policy OutboundPolicy, size, threshold int, maxLag time.Duration, evictions *atomic.Int32) *Client {
+	client := &Client{
+		ID:       uuid.New().String(),
+		UserID:   uuid.New(),
+		send:     make(chan []byte, size),
+		servers:  make(map[uuid.UUID]bool),
+		channels: make(map[uuid.UUID]bool),
+	}
+	config := &GatewayConfig{OutboundLagThreshold: threshold, OutboundMaxLag: maxLag, OutboundPolicy: policy}
+	client.outbound = newOutboundQueue(config, size, "bot", nil, func() { evictions.Add(1) })
+	return client
+}
+
+func TestOutboundQueue_Lagging(t *testing.T) {
+	q := newOutboundQueue(&GatewayConfig{OutboundLagThreshold: 4, OutboundMaxLag: time.Second}, 8, "web", nil, nil)
+	now := time.Now()
+
+	assert.False(t, q.lagging(3, now))
+	assert.False(t, q.lagging(4, now), "lag starts at the threshold")
+	assert.False(t, q.lagging(6, now.Add(time.Second)))
+	assert.True(t, q.lagging(6, now.Add(2*time.Second)))
+
+	// Draining below the threshold starts over
+	assert.False(t, q.lagging(2, now.Add(3*time.Second)))
+	assert.False(t, q.lagging(5, now.Add(3*time.Second)))
+}
+
+func TestOutboundQueue_Defaults(t *testing.T) {
+	q := newOutboundQueue(&GatewayConfig{}, 16, "web", nil, nil)
+	assert.Equal(t, OutboundClose, q.policy)
+	assert.Equal(t, 16, q.threshold)
+	assert.Equal(t, DefaultGatewayConfig().OutboundMaxLag, q.maxLag)
+}
+
+func TestClient_QueueDropPolicy(t *testing.T) {
+	var evictions atomic.Int32
+	client := newOutboundClient(OutboundDrop, 4, 2, time.Nanosecond, &evictions)
+
+	assert.True(t, client.queue([]byte("1")))
+	assert.True(t, client.queue([]byte("2")))
+	assert.True(t, client.queue([]byte("3")), "lag has only just started")
+	time.Sleep(time.Millisecond)
+	assert.False(t, client.queue([]byte("4")), "dropped while lagging")
+	assert.Equal(t, int64(1), client.outbound.dropped.Load())
+
+	// Once the client catches up events flow again
+	<-client.send
+	<-client.send
+	assert.True(t, client.queue([]byte("5")))
+	assert.Zero(t, evictions.Load())
+}
+
+func TestClient_QueueClosePolicy(t *testing.T) {
+	var evictions atomic.Int32
+	client := newOutboundClient(OutboundClose, 2, 2, time.Hour, &evictions)
+
+	assert.True(t, client.queue([]byte("1")))
+	assert.True(t, client.queue([]byte("2")))
+	assert.False(t, client.queue([]byte("3")), "the queue is full")
+	assert.Eventually(t, func() bool { return evictions.Load() == 1 }, time.Second, time.Millisecond)
+
+	// An evicted client keeps queueing for resume and is evicted only once
+	<-client.send
+	assert.True(t, client.queue([]byte("4")))
+	assert.False(t, client.queue([]byte("5")))
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, int32(1), evictions.Load())
+	assert.Equal(t, int64(2), client.outbound.dropped.Load())
+}
+
+func TestHub_SlowClientDoesNotHoldUpOthers(t *testing.T) {
+	hub := NewHub()
+	serverID := uuid.New()
+	var evictions atomic.Int32
+
+	slow := newOutboundClient(OutboundClose, 2, 1, time.Hour, &evictions)
+	slow.hub = hub
+	fast := newOutboundClient(OutboundClose, 16, 12, time.Hour, &evictions)
+	fast.hub = hub
+	slow.SubscribeServer(serverID)
+	fast.SubscribeServer(serverID)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			hub.handleBroadcast(&Event{Type: EventMessageCreate, ServerID: &serverID})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("broadcast blocked on a slow client")
+	}
+
+	assert.Len(t, fast.send, 10)
+	require.Len(t, slow.send, 2)
+	assert.Eventually(t, func() bool { return evictions.Load() == 1 }, time.Second, time.Millisecond)
+}
+
+func TestParseOutboundPolicy(t *testing.T) {
+	policy, ok := ParseOutboundPolicy("drop")
+	assert.True(t, ok)
+	assert.Equal(t, OutboundDrop, policy)
+
+	_, ok = ParseOutboundPolicy("block")
+	assert.False(t, ok)
+}
@@ -0,0 +1,464 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	// presenceTTL bounds how long a node's presence entry survives without
+	// a refresh, so users on a crashed node eventually show offline
+	presenceTTL             = 2 * time.Minute
+	presenceRefreshInterval = presenceTTL / 2
+	presenceStoreTimeout    = 2 * time.Second
+)
+
+// PresenceStore shares presence between gateway nodes. Each node saves its
+// own view of a user; Load merges the views of every node.
+type PresenceStore interface {
+	Save(ctx context.Context, presence *models.Presence) error
+	Remove(ctx context.Context, userID uuid.UUID) error
+	Load(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*models.Presence, error)
+}
+
+// BotPresenceStore persists the presence bots set over the gateway so it
+// can be restored when they reconnect
+type BotPresenceStore interface {
+	SetBotPresence(ctx context.Context, botID uuid.UUID, status models.PresenceStatus, activities []models.Activity) error
+	GetBotPresence(ctx context.Context, botID uuid.UUID) (*models.Presence, error)
+}
+
+// PresenceFilter hides presence from viewers its users don't show it to.
+// The PrivacyService implements it.
+type PresenceFilter interface {
+	// FilterPresences replaces, in place, the presence of users who hide
+	// it from viewerID with offline
+	FilterPresences(ctx context.Context, viewerID uuid.UUID, presences map[uuid.UUID]*models.Presence) error
+}
+
+// userPresence is one user's presence on this node
+type userPresence struct {
+	status     models.PresenceStatus // Chosen by the user; never offline
+	activities []models.Activity
+	clients    map[*Client]bool
+	updatedAt  time.Time
+}
+
+// presenceTracker holds presence for users connected to this node
+type presenceTracker struct {
+	mu    sync.RWMutex
+	users map[uuid.UUID]*userPresence
+}
+
+func newPresenceTracker() *presenceTracker {
+	return &presenceTracker{users: make(map[uuid.UUID]*userPresence)}
+}
+
+// connect adds a client and reports whether the user just came online
+func (t *presenceTracker) connect(client *Client) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.users[client.UserID]
+	if !ok {
+		p = &userPresence{status: models.StatusOnline, clients: make(map[*Client]bool)}
+		t.users[client.UserID] = p
+	}
+	p.clients[client] = true
+	p.updatedAt = time.Now()
+	return !ok
+}
+
+// disconnect removes a client and reports whether the user went offline
+func (t *presenceTracker) disconnect(client *Client) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.users[client.UserID]
+	if !ok || !p.clients[client] {
+		return false
+	}
+	delete(p.clients, client)
+	if len(p.clients) > 0 {
+		return false
+	}
+	delete(t.users, client.UserID)
+	return true
+}
+
+// update sets the user's chosen status and activities. It reports false
+// if the user has no connection on this node or nothing changed.
+func (t *presenceTracker) update(userID uuid.UUID, status models.PresenceStatus, activities []models.Activity) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.users[userID]
+	if !ok {
+		return false
+	}
+	if status == models.StatusOffline {
+		// Clients that want to look offline while connected go invisible
+		status = models.StatusInvisible
+	}
+	if p.status == status && len(p.activities) == 0 && len(activities) == 0 {
+		return false
+	}
+	p.status = status
+	p.activities = activities
+	p.updatedAt = time.Now()
+	return true
+}
+
+// get returns the user's presence as seen by other users
+func (t *presenceTracker) get(userID uuid.UUID) *models.Presence {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	p, ok := t.users[userID]
+	if !ok {
+		return offlinePresence(userID)
+	}
+	return p.snapshot(userID)
+}
+
+// userIDs returns every user with a connection on this node
+func (t *presenceTracker) userIDs() []uuid.UUID {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	ids := make([]uuid.UUID, 0, len(t.users))
+	for id := range t.users {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// snapshot builds the public presence; caller holds the tracker lock
+func (p *userPresence) snapshot(userID uuid.UUID) *models.Presence {
+	status := p.status
+	if status == models.StatusInvisible {
+		return offlinePresence(userID)
+	}
+
+	clientStatus := &models.ClientStatus{}
+	for client := range p.clients {
+		switch client.ClientType {
+		case "desktop":
+			clientStatus.Desktop = status
+		case "mobile":
+			clientStatus.Mobile = status
+		default:
+			clientStatus.Web = status
+		}
+	}
+
+	return &models.Presence{
+		UserID:       userID,
+		Status:       status,
+		Activities:   append([]models.Activity(nil), p.activities...),
+		ClientStatus: clientStatus,
+		UpdatedAt:    p.updatedAt,
+	}
+}
+
+func offlinePresence(userID uuid.UUID) *models.Presence {
+	return &models.Presence{
+		UserID:    userID,
+		Status:    models.StatusOffline,
+		UpdatedAt: time.Now(),
+	}
+}
+
+// mergePresences combines the views of several nodes: the most recently
+// updated view decides the status, and client statuses are unioned.
+// Views older than presenceTTL are treated as stale.
+func mergePresences(userID uuid.UUID, views []*models.Presence, now time.Time) *models.Presence {
+	var latest *models.Presence
+	merged := &models.ClientStatus{}
+
+	for _, v := range views {
+		if v == nil || v.Status == models.StatusOffline || now.Sub(v.UpdatedAt) > presenceTTL {
+			continue
+		}
+		if latest == nil || v.UpdatedAt.After(latest.UpdatedAt) {
+			latest = v
+		}
+		if cs := v.ClientStatus; cs != nil {
+			if cs.Desktop != "" {
+				merged.Desktop = cs.Desktop
+			}
+			if cs.Mobile != "" {
+				merged.Mobile = cs.Mobile
+			}
+			if cs.Web != "" {
+				merged.Web = cs.Web
+			}
+		}
+	}
+
+	if latest == nil {
+		return offlinePresence(userID)
+	}
+
+	// Every connected client reflects the latest chosen status
+	for _, s := range []*models.PresenceStatus{&merged.Desktop, &merged.Mobile, &merged.Web} {
+		if *s != "" {
+			*s = latest.Status
+		}
+	}
+
+	result := *latest
+	result.ClientStatus = merged
+	return &result
+}
+
+// OnPresenceChange registers fn to be called, off the hub loop, whenever a
+// user's presence changes. Without a listener the hub broadcasts
+// PRESENCE_UPDATE to servers the user's local clients are subscribed to.
+func (h *Hub) OnPresenceChange(fn func(presence *models.Presence)) {
+	h.presenceMu.Lock()
+	defer h.presenceMu.Unlock()
+	h.presenceListener = fn
+}
+
+// UpdatePresence sets a connected user's status and activities
+func (h *Hub) UpdatePresence(userID uuid.UUID, status models.PresenceStatus, activities []models.Activity) {
+	if h.presence.update(userID, status, activities) {
+		h.presenceChanged(userID)
+	}
+}
+
+// RepublishPresence broadcasts the user's presence again, e.g. after their
+// custom status changed. Users that are offline everywhere are skipped.
+func (h *Hub) RepublishPresence(userID uuid.UUID) {
+	if h.GetPresences([]uuid.UUID{userID})[userID].Status == models.StatusOffline {
+		return
+	}
+	h.presenceChanged(userID)
+}
+
+// GetPresences returns presence for the given users. Users that aren't
+// connected anywhere are reported offline.
+func (h *Hub) GetPresences(userIDs []uuid.UUID) map[uuid.UUID]*models.Presence {
+	result := make(map[uuid.UUID]*models.Presence, len(userIDs))
+
+	if h.presenceStore != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), presenceStoreTimeout)
+		defer cancel()
+		stored, err := h.presenceStore.Load(ctx, userIDs)
+		if err == nil {
+			for _, id := range userIDs {
+				if p, ok := stored[id]; ok {
+					result[id] = p
+				} else {
+					result[id] = offlinePresence(id)
+				}
+			}
+			return result
+		}
+		log.Printf("[Hub] presence store load failed, using local presence: %v", err)
+	}
+
+	for _, id := range userIDs {
+		result[id] = h.presence.get(id)
+	}
+	return result
+}
+
+// presenceChanged queues the user for publishing without blocking, since
+// it's called from the hub loop. A single worker publishes each queued
+// user's presence as it is when their turn comes, so changes made while
+// they wait are coalesced and a quick connect/disconnect can't leave a
+// stale "online" entry in the store.
+func (h *Hub) presenceChanged(userID uuid.UUID) {
+	h.presenceWorker.Do(func() {
+		go h.publishQueuedPresence()
+	})
+
+	h.presenceQueueMu.Lock()
+	if !h.presenceQueued[userID] {
+		h.presenceQueued[userID] = true
+		h.presenceQueue = append(h.presenceQueue, userID)
+	}
+	h.presenceQueueMu.Unlock()
+
+	select {
+	case h.presenceWake <- struct{}{}:
+	default: // The worker is already due to look
+	}
+}
+
+// publishQueuedPresence is the presence worker: it publishes queued users
+// until the process exits
+func (h *Hub) publishQueuedPresence() {
+	for range h.presenceWake {
+		for {
+			h.presenceQueueMu.Lock()
+			if len(h.presenceQueue) == 0 {
+				h.presenceQueueMu.Unlock()
+				break
+			}
+			userID := h.presenceQueue[0]
+			h.presenceQueue = h.presenceQueue[1:]
+			delete(h.presenceQueued, userID)
+			h.presenceQueueMu.Unlock()
+
+			h.publishPresence(h.presence.get(userID))
+		}
+	}
+}
+
+// publishPresence writes this node's view to the store and notifies the
+// listener with the merged presence
+func (h *Hub) publishPresence(presence *models.Presence) {
+	if h.presenceStore != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), presenceStoreTimeout)
+		defer cancel()
+
+		var err error
+		if presence.Status == models.StatusOffline {
+			err = h.presenceStore.Remove(ctx, presence.UserID)
+		} else {
+			err = h.presenceStore.Save(ctx, presence)
+		}
+		if err != nil {
+			log.Printf("[Hub] failed to store presence for %s: %v", presence.UserID, err)
+		}
+
+		// Going offline here doesn't mean offline everywhere
+		if merged, err := h.presenceStore.Load(ctx, []uuid.UUID{presence.UserID}); err == nil {
+			if p, ok := merged[presence.UserID]; ok {
+				presence = p
+			}
+		}
+	}
+
+	h.presenceMu.RLock()
+	listener := h.presenceListener
+	h.presenceMu.RUnlock()
+
+	if listener != nil {
+		listener(presence)
+	} else {
+		h.broadcastPresenceLocal(presence)
+	}
+}
+
+// broadcastPresenceLocal sends PRESENCE_UPDATE to every server the user's
+// clients on this node are subscribed to
+func (h *Hub) broadcastPresenceLocal(presence *models.Presence) {
+	seen := make(map[uuid.UUID]bool)
+	var servers []uuid.UUID
+	for _, client := range h.clients.appendClients(nil, presence.UserID) {
+		for _, serverID := range client.hubServers.list() {
+			if !seen[serverID] {
+				seen[serverID] = true
+				servers = append(servers, serverID)
+			}
+		}
+	}
+
+	for _, serverID := range servers {
+		h.SendToServer(serverID, &Event{
+			Op:   OpDispatch,
+			Type: EventTypePresenceUpdate,
+			Data: PresenceToWS(presence, &serverID),
+		})
+	}
+}
+
+// refreshPresence re-saves every local user's presence so entries in the
+// shared store don't expire while users stay connected
+func (h *Hub) refreshPresence(ctx context.Context) {
+	if h.presenceStore == nil {
+		return
+	}
+	for _, userID := range h.presence.userIDs() {
+		presence := h.presence.get(userID)
+		if presence.Status == models.StatusOffline {
+			continue // Invisible users have nothing to share
+		}
+		presence.UpdatedAt = time.Now()
+		if err := h.presenceStore.Save(ctx, presence); err != nil {
+			log.Printf("[Hub] failed to refresh presence for %s: %v", userID, err)
+			return
+		}
+	}
+}
+
+// runPresenceRefresh refreshes stored presence until ctx is done
+func (h *Hub) runPresenceRefresh(ctx context.Context) {
+	ticker := time.NewTicker(presenceRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.refreshPresence(ctx)
+		}
+	}
+}
+
+// toActivities converts validated activities to their model form
+func (p *presencePayload) toActivities() []models.Activity {
+	if len(p.Activities) == 0 {
+		return nil
+	}
+	activities := make([]models.Activity, 0, len(p.Activities))
+	for _, a := range p.Activities {
+		activity := models.Activity{
+			Name:      a.Name,
+			Type:      models.ActivityType(a.Type),
+			State:     a.State,
+			Details:   a.Details,
+			CreatedAt: time.Now(),
+		}
+		if a.URL != nil {
+			activity.URL = *a.URL
+		}
+		if a.CreatedAt > 0 {
+			activity.CreatedAt = time.UnixMilli(a.CreatedAt)
+		}
+		activities = append(activities, activity)
+	}
+	return activities
+}
+
+// PresenceToWS converts a presence to its PRESENCE_UPDATE payload
+func PresenceToWS(presence *models.Presence, serverID *uuid.UUID) PresenceUpdateData {
+	activities := make([]interface{}, 0, len(presence.Activities))
+	for _, a := range presence.Activities {
+		activities = append(activities, a)
+	}
+
+	var clientStatus interface{} = map[string]string{}
+	if presence.ClientStatus != nil {
+		clientStatus = presence.ClientStatus
+	}
+
+	data := PresenceUpdateData{
+		User:         map[string]interface{}{"id": presence.UserID.String()},
+		Status:       string(presence.Status),
+		Activities:   activities,
+		ClientStatus: clientStatus,
+	}
+	if presence.CustomStatus != nil || presence.CustomStatusEmoji != nil {
+		data.CustomStatus = &models.CustomStatus{
+			Text:      presence.CustomStatus,
+			Emoji:     presence.CustomStatusEmoji,
+			ExpiresAt: presence.CustomStatusExpiresAt,
+		}
+	}
+	if serverID != nil {
+		data.GuildID = serverID.String()
+	}
+	return data
+}
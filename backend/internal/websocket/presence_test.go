@@ -0,0 +1,548 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/events"
+	"hearth/internal/models"
+	"hearth/internal/services"
+)
+
+// memoryPresenceStore is a single-node PresenceStore for tests
+type memoryPresenceStore struct {
+	mu    sync.Mutex
+	views map[uuid.UUID]*models.Presence
+}
+
+func newMemoryPresenceStore() *memoryPresenceStore {
+	return &memoryPresenceStore{views: make(map[uuid.UUID]*models.Presence)}
+}
+
+func (s *memoryPresenceStore) Save(ctx context.Context, presence *models.Presence) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.views[presence.UserID] = presence
+	return nil
+}
+
+func (s *memoryPresenceStore) Remove(ctx context.Context, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.views, userID)
+	return nil
+}
+
+func (s *memoryPresenceStore) Load(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*models.Presence, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make(map[uuid.UUID]*models.Presence)
+	for _, id := range userIDs {
+		if p, ok := s.views[id]; ok {
+			result[id] = p
+		}
+	}
+	return result, nil
+}
+
+func newPresenceClient(userID uuid.UUID, clientType string) *Client {
+	return &Client{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		ClientType: clientType,
+		send:       make(chan []byte, 256),
+		servers:    make(map[uuid.UUID]bool),
+		channels:   make(map[uuid.UUID]bool),
+	}
+}
+
+func TestPresenceTracker_ConnectDisconnect(t *testing.T) {
+	tracker := newPresenceTracker()
+	userID := uuid.New()
+	desktop := newPresenceClient(userID, "desktop")
+	mobile := newPresenceClient(userID, "mobile")
+
+	assert.True(t, tracker.connect(desktop), "first connection brings the user online")
+	assert.False(t, tracker.connect(mobile))
+
+	p := tracker.get(userID)
+	assert.Equal(t, models.StatusOnline, p.Status)
+	assert.Equal(t, models.StatusOnline, p.ClientStatus.Desktop)
+	assert.Equal(t, models.StatusOnline, p.ClientStatus.Mobile)
+	assert.Empty(t, p.ClientStatus.Web)
+
+	assert.False(t, tracker.disconnect(desktop))
+	assert.True(t, tracker.disconnect(mobile), "last disconnection takes the user offline")
+	assert.False(t, tracker.disconnect(mobile), "disconnecting twice is a no-op")
+
+	assert.Equal(t, models.StatusOffline, tracker.get(userID).Status)
+}
+
+func TestPresenceTracker_Update(t *testing.T) {
+	tracker := newPresenceTracker()
+	userID := uuid.New()
+
+	assert.False(t, tracker.update(userID, models.StatusIdle, nil), "unknown users can't set presence")
+
+	tracker.connect(newPresenceClient(userID, "web"))
+
+	assert.False(t, tracker.update(userID, models.StatusOnline, nil), "unchanged status is not a change")
+	assert.True(t, tracker.update(userID, models.StatusDND, []models.Activity{{Name: "Focus"}}))
+
+	p := tracker.get(userID)
+	assert.Equal(t, models.StatusDND, p.Status)
+	assert.Equal(t, models.StatusDND, p.ClientStatus.Web)
+	require.Len(t, p.Activities, 1)
+	assert.Equal(t, "Focus", p.Activities[0].Name)
+}
+
+func TestPresenceTracker_InvisibleAppearsOffline(t *testing.T) {
+	tracker := newPresenceTracker()
+	userID := uuid.New()
+	tracker.connect(newPresenceClient(userID, "web"))
+
+	assert.True(t, tracker.update(userID, models.StatusOffline, nil))
+
+	p := tracker.get(userID)
+	assert.Equal(t, models.StatusOffline, p.Status)
+	assert.Nil(t, p.ClientStatus)
+	assert.Empty(t, p.Activities)
+}
+
+func TestMergePresences(t *testing.T) {
+	userID := uuid.New()
+	now := time.Now()
+
+	desktop := &models.Presence{
+		UserID:       userID,
+		Status:       models.StatusOnline,
+		ClientStatus: &models.ClientStatus{Desktop: models.StatusOnline},
+		UpdatedAt:    now.Add(-time.Minute),
+	}
+	mobile := &models.Presence{
+		UserID:       userID,
+		Status:       models.StatusIdle,
+		ClientStatus: &models.ClientStatus{Mobile: models.StatusIdle},
+		UpdatedAt:    now,
+	}
+
+	merged := mergePresences(userID, []*models.Presence{desktop, mobile}, now)
+
+	assert.Equal(t, models.StatusIdle, merged.Status, "latest view wins")
+	assert.Equal(t, models.StatusIdle, merged.ClientStatus.Desktop)
+	assert.Equal(t, models.StatusIdle, merged.ClientStatus.Mobile)
+}
+
+func TestMergePresences_IgnoresStaleViews(t *testing.T) {
+	userID := uuid.New()
+	now := time.Now()
+
+	stale := &models.Presence{
+		UserID:    userID,
+		Status:    models.StatusOnline,
+		UpdatedAt: now.Add(-2 * presenceTTL),
+	}
+
+	assert.Equal(t, models.StatusOffline, mergePresences(userID, []*models.Presence{stale}, now).Status)
+	assert.Equal(t, models.StatusOffline, mergePresences(userID, nil, now).Status)
+}
+
+func TestHub_PresenceListener(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	changes := make(chan *models.Presence, 10)
+	hub.OnPresenceChange(func(p *models.Presence) { changes <- p })
+
+	userID := uuid.New()
+	client := newPresenceClient(userID, "desktop")
+	client.hub = hub
+
+	next := func() *models.Presence {
+		select {
+		case p := <-changes:
+			return p
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for presence change")
+			return nil
+		}
+	}
+
+	hub.register <- client
+	assert.Equal(t, models.StatusOnline, next().Status)
+
+	hub.UpdatePresence(userID, models.StatusDND, nil)
+	assert.Equal(t, models.StatusDND, next().Status)
+
+	hub.unregister <- client
+	assert.Equal(t, models.StatusOffline, next().Status)
+}
+
+func TestHub_SlowPresencePublishingDoesNotBlockHub(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	published := make(map[uuid.UUID]int)
+	hub.OnPresenceChange(func(p *models.Presence) {
+		<-release
+		mu.Lock()
+		published[p.UserID]++
+		mu.Unlock()
+	})
+
+	// More changes than any fixed queue would hold, while the publisher is
+	// stuck on the first
+	busyUserID := uuid.New()
+	busy := newPresenceClient(busyUserID, "desktop")
+	busy.hub = hub
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		hub.register <- busy
+		for i := 0; i < 2000; i++ {
+			client := newPresenceClient(uuid.New(), "web")
+			client.hub = hub
+			hub.register <- client
+			hub.UpdatePresence(busyUserID, models.StatusIdle, nil)
+			hub.UpdatePresence(busyUserID, models.StatusDND, nil)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("hub blocked on presence publishing")
+	}
+	close(release)
+
+	// The busy user's changes collapse into few publishes
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(published) == 2001
+	}, 5*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.LessOrEqual(t, published[busyUserID], 3)
+}
+
+func TestHub_PresenceBroadcastsLocally(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	serverID := uuid.New()
+	userID := uuid.New()
+	watcher := newPresenceClient(uuid.New(), "web")
+	user := newPresenceClient(userID, "web")
+	watcher.hub, user.hub = hub, hub
+
+	hub.register <- watcher
+	hub.register <- user
+	time.Sleep(50 * time.Millisecond)
+	hub.SubscribeServer(watcher, serverID)
+	hub.SubscribeServer(user, serverID)
+
+	hub.UpdatePresence(userID, models.StatusIdle, nil)
+
+	select {
+	case data := <-watcher.send:
+		var event struct {
+			Type string             `json:"t"`
+			Data PresenceUpdateData `json:"d"`
+		}
+		require.NoError(t, json.Unmarshal(data, &event))
+		assert.Equal(t, EventTypePresenceUpdate, event.Type)
+		assert.Equal(t, "idle", event.Data.Status)
+		assert.Equal(t, serverID.String(), event.Data.GuildID)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for PRESENCE_UPDATE")
+	}
+}
+
+func TestHub_GetPresences(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	online := uuid.New()
+	offline := uuid.New()
+	hub.register <- newPresenceClient(online, "mobile")
+	time.Sleep(50 * time.Millisecond)
+
+	presences := hub.GetPresences([]uuid.UUID{online, offline})
+
+	require.Len(t, presences, 2)
+	assert.Equal(t, models.StatusOnline, presences[online].Status)
+	assert.Equal(t, models.StatusOnline, presences[online].ClientStatus.Mobile)
+	assert.Equal(t, models.StatusOffline, presences[offline].Status)
+}
+
+func TestHub_PresenceStore(t *testing.T) {
+	hub := NewHub()
+	store := newMemoryPresenceStore()
+	hub.presenceStore = store
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	changes := make(chan *models.Presence, 10)
+	hub.OnPresenceChange(func(p *models.Presence) { changes <- p })
+
+	userID := uuid.New()
+	client := newPresenceClient(userID, "web")
+	client.hub = hub
+
+	hub.register <- client
+	<-changes
+
+	stored, _ := store.Load(ctx, []uuid.UUID{userID})
+	require.Contains(t, stored, userID)
+	assert.Equal(t, models.StatusOnline, hub.GetPresences([]uuid.UUID{userID})[userID].Status)
+
+	hub.unregister <- client
+	<-changes
+
+	stored, _ = store.Load(ctx, []uuid.UUID{userID})
+	assert.NotContains(t, stored, userID)
+}
+
+func TestPresencePayload_ToActivities(t *testing.T) {
+	url := "https://twitch.tv/hearth"
+	p := presencePayload{
+		Status: "online",
+		Activities: []activityPayload{
+			{Name: "Streaming", Type: 1, URL: &url, CreatedAt: 1700000000000},
+		},
+	}
+
+	activities := p.toActivities()
+
+	require.Len(t, activities, 1)
+	assert.Equal(t, models.ActivityType(1), activities[0].Type)
+	assert.Equal(t, url, activities[0].URL)
+	assert.Equal(t, time.UnixMilli(1700000000000), activities[0].CreatedAt)
+	assert.Nil(t, (&presencePayload{}).toActivities())
+}
+
+func TestEventBridge_onPresenceUpdate_HubPresence(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	bus := events.NewBus()
+	_ = NewEventBridge(hub, bus)
+
+	serverID := uuid.New()
+	watcher := newPresenceClient(uuid.New(), "web")
+	watcher.hub = hub
+	hub.register <- watcher
+	time.Sleep(50 * time.Millisecond)
+	hub.SubscribeServer(watcher, serverID)
+
+	userID := uuid.New()
+	bus.Publish(events.PresenceUpdate, &services.PresenceUpdateEvent{
+		UserID:   userID,
+		ServerID: serverID,
+		Presence: &models.Presence{
+			UserID:       userID,
+			Status:       models.StatusDND,
+			ClientStatus: &models.ClientStatus{Desktop: models.StatusDND},
+		},
+	})
+
+	select {
+	case data := <-watcher.send:
+		var event struct {
+			Type string                 `json:"t"`
+			Data map[string]interface{} `json:"d"`
+		}
+		require.NoError(t, json.Unmarshal(data, &event))
+		assert.Equal(t, EventTypePresenceUpdate, event.Type)
+		assert.Equal(t, "dnd", event.Data["status"])
+		assert.Equal(t, serverID.String(), event.Data["guild_id"])
+		assert.Equal(t, map[string]interface{}{"desktop": "dnd"}, event.Data["client_status"])
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for PRESENCE_UPDATE")
+	}
+}
+
+func TestPresenceToWS_CustomStatus(t *testing.T) {
+	text := "Gone fishing"
+	emoji := "🎣"
+	presence := &models.Presence{
+		UserID:            uuid.New(),
+		Status:            models.StatusIdle,
+		CustomStatus:      &text,
+		CustomStatusEmoji: &emoji,
+	}
+
+	data := PresenceToWS(presence, nil)
+	assert.Equal(t, &models.CustomStatus{Text: &text, Emoji: &emoji}, data.CustomStatus)
+
+	data = PresenceToWS(&models.Presence{UserID: presence.UserID, Status: models.StatusIdle}, nil)
+	assert.Nil(t, data.CustomStatus)
+}
+
+func TestEventBridge_onCustomStatusUpdated_Republishes(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	bus := events.NewBus()
+	_ = NewEventBridge(hub, bus)
+
+	changes := make(chan *models.Presence, 10)
+	hub.OnPresenceChange(func(p *models.Presence) { changes <- p })
+
+	userID := uuid.New()
+	client := newPresenceClient(userID, "web")
+	client.hub = hub
+	hub.register <- client
+
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for connect presence")
+	}
+
+	bus.Publish(events.CustomStatusUpdated, &services.CustomStatusUpdatedEvent{UserID: userID})
+
+	select {
+	case p := <-changes:
+		assert.Equal(t, userID, p.UserID)
+		assert.Equal(t, models.StatusOnline, p.Status)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for republished presence")
+	}
+
+	// Users that aren't connected have nothing to republish
+	bus.Publish(events.CustomStatusUpdated, &services.CustomStatusUpdatedEvent{UserID: uuid.New()})
+	select {
+	case p := <-changes:
+		t.Fatalf("unexpected presence change for %s", p.UserID)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// memoryBotPresenceStore records what bots set
+type memoryBotPresenceStore struct {
+	mu        sync.Mutex
+	presences map[uuid.UUID]*models.Presence
+}
+
+func (s *memoryBotPresenceStore) SetBotPresence(ctx context.Context, botID uuid.UUID, status models.PresenceStatus, activities []models.Activity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.presences[botID] = &models.Presence{UserID: botID, Status: status, Activities: activities}
+	return nil
+}
+
+func (s *memoryBotPresenceStore) GetBotPresence(ctx context.Context, botID uuid.UUID) (*models.Presence, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.presences[botID], nil
+}
+
+func TestGateway_BotPresencePersistsAcrossReconnects(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	store := &memoryBotPresenceStore{presences: make(map[uuid.UUID]*models.Presence)}
+	gateway := NewGateway(hub, nil, nil)
+	gateway.SetBotPresenceStore(store)
+
+	botID := uuid.New()
+	session := &Session{UserID: botID, Bot: true}
+	client := newPresenceClient(botID, "web")
+	client.hub = hub
+	hub.register <- client
+	time.Sleep(50 * time.Millisecond)
+
+	gateway.handlePresenceUpdate(nil, client, session, &Message{
+		Op:   OpPresenceUpdate,
+		Data: json.RawMessage(`{"status":"idle","activities":[{"name":"/help","type":0}]}`),
+	})
+
+	require.Contains(t, store.presences, botID)
+	assert.Equal(t, models.StatusIdle, store.presences[botID].Status)
+
+	// Reconnect: the new connection comes up online, then IDENTIFY restores
+	hub.unregister <- client
+	client = newPresenceClient(botID, "web")
+	client.hub = hub
+	hub.register <- client
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, models.StatusOnline, hub.presence.get(botID).Status)
+
+	gateway.restoreBotPresence(session)
+
+	p := hub.presence.get(botID)
+	assert.Equal(t, models.StatusIdle, p.Status)
+	require.Len(t, p.Activities, 1)
+	assert.Equal(t, "/help", p.Activities[0].Name)
+}
+
+func TestGateway_UserPresenceIsNotPersisted(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	store := &memoryBotPresenceStore{presences: make(map[uuid.UUID]*models.Presence)}
+	gateway := NewGateway(hub, nil, nil)
+	gateway.SetBotPresenceStore(store)
+
+	userID := uuid.New()
+	gateway.setPresence(&Session{UserID: userID}, models.StatusDND, nil)
+
+	assert.Empty(t, store.presences)
+}
+
+func TestGateway_InvalidBotPresenceIsRefused(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	store := &memoryBotPresenceStore{presences: make(map[uuid.UUID]*models.Presence)}
+	gateway := NewGateway(hub, nil, nil)
+	gateway.SetBotPresenceStore(store)
+
+	botID := uuid.New()
+	client := newPresenceClient(botID, "web")
+	client.hub = hub
+	hub.register <- client
+	time.Sleep(50 * time.Millisecond)
+
+	// Playing activities need a name
+	err := gateway.setPresence(&Session{UserID: botID, Bot: true}, models.StatusDND,
+		[]models.Activity{{Type: models.ActivityTypePlaying}})
+
+	assert.ErrorIs(t, err, services.ErrInvalidActivity)
+	assert.Equal(t, models.StatusOnline, hub.presence.get(botID).Status)
+	assert.Empty(t, store.presences)
+
+	perr := presenceError(OpPresenceUpdate, "d", err)
+	assert.Equal(t, "d.activities", perr.Field)
+	assert.False(t, perr.Fatal)
+}
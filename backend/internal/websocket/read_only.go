@@ -0,0 +1,34 @@
+package websocket
+
+import (
+	"context"
+
+	"hearth/internal/models"
+)
+
+// EventTypeReadOnlyModeUpdate goes to every client when an instance admin
+// turns read-only mode on or off, so clients can show or hide a banner
+const EventTypeReadOnlyModeUpdate = "READ_ONLY_MODE_UPDATE"
+
+// ReadOnlyChecker reports whether the instance is refusing writes. The
+// ReadOnlyService implements it.
+type ReadOnlyChecker interface {
+	Mode(ctx context.Context) *models.ReadOnlyMode
+}
+
+// SetReadOnly puts read-only mode in READY and refuses JOIN_GUILD while it
+// is on, as the REST API refuses writes
+func (g *Gateway) SetReadOnly(readOnly ReadOnlyChecker) {
+	g.readOnly = readOnly
+}
+
+// readOnlyMode returns the read-only mode when it is on, or nil
+func (g *Gateway) readOnlyMode(ctx context.Context) *models.ReadOnlyMode {
+	if g.readOnly == nil {
+		return nil
+	}
+	if mode := g.readOnly.Mode(ctx); mode.Enabled {
+		return mode
+	}
+	return nil
+}
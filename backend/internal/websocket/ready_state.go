@@ -0,0 +1,87 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const readyStateTimeout = 10 * time.Second
+
+// ReadyStateProvider builds the snapshot sent in READY. The
+// ReadyStateService implements it.
+type ReadyStateProvider interface {
+	ReadyState(ctx context.Context, userID uuid.UUID) (*models.ReadyState, error)
+}
+
+// SetReadyState fills READY with the user's servers, DMs, read states and
+// relationships. Without it READY carries only the session and user ID.
+func (g *Gateway) SetReadyState(provider ReadyStateProvider) {
+	g.readyState = provider
+}
+
+// fillReady adds the user's snapshot to ready. If it can't be loaded READY
+// goes out without it, and read_states and relationships are left out so
+// clients know to fetch over REST.
+func (g *Gateway) fillReady(ready *ReadyData, session *Session) {
+	if g.readyState == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), readyStateTimeout)
+	defer cancel()
+
+	state, err := g.readyState.ReadyState(ctx, session.UserID)
+	if err != nil {
+		log.Printf("[Gateway] Failed to load ready state for %s: %v", session.UserID, err)
+		return
+	}
+
+	ready.User = state.User
+	ready.Guilds = make([]interface{}, len(state.Guilds))
+	for i, guild := range state.Guilds {
+		ready.Guilds[i] = guild
+	}
+	ready.PrivateChannels = make([]interface{}, len(state.PrivateChannels))
+	for i, channel := range state.PrivateChannels {
+		ready.PrivateChannels[i] = channel
+	}
+	ready.ReadStates = state.ReadStates
+	ready.Relationships = state.Relationships
+}
+
+// sendCompressed sends msg as a zlib-compressed binary frame, for clients
+// that asked for compression in IDENTIFY
+func (g *Gateway) sendCompressed(conn *websocket.Conn, msg *Message) {
+	data, err := compressMessage(msg)
+	if err != nil {
+		log.Printf("[Gateway] Failed to compress %s: %v", msg.Type, err)
+		g.sendMessage(conn, msg)
+		return
+	}
+	conn.WriteMessage(websocket.BinaryMessage, data)
+}
+
+func compressMessage(msg *Message) ([]byte, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
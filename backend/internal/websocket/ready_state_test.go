@@ -0,0 +1,94 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeReadyState struct {
+	state *models.ReadyState
+	err   error
+}
+
+func (f *fakeReadyState) ReadyState(ctx context.Context, userID uuid.UUID) (*models.ReadyState, error) {
+	return f.state, f.err
+}
+
+func TestGateway_FillReady(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Username: "alice"}
+	server := &models.Server{ID: uuid.New(), Name: "Hearth"}
+	friend := &models.User{ID: uuid.New(), Username: "bob"}
+	gateway := NewGateway(NewHub(), nil, nil)
+	gateway.SetReadyState(&fakeReadyState{state: &models.ReadyState{
+		User:            user,
+		Guilds:          []*models.ReadyGuild{{Server: server, Channels: []*models.Channel{}, Roles: []*models.Role{}}},
+		PrivateChannels: []*models.Channel{},
+		ReadStates:      []models.ReadStateWithUnread{{UnreadCount: 2}},
+		Relationships:   []models.Relationship{{ID: friend.ID, Type: models.RelationshipFriend, User: friend.ToPublic()}},
+	}})
+
+	ready := ReadyData{Guilds: []interface{}{}}
+	gateway.fillReady(&ready, &Session{UserID: user.ID})
+
+	data, err := json.Marshal(ready)
+	require.NoError(t, err)
+	var decoded struct {
+		User   models.User `json:"user"`
+		Guilds []struct {
+			ID       uuid.UUID         `json:"id"`
+			Channels []*models.Channel `json:"channels"`
+		} `json:"guilds"`
+		ReadStates    []models.ReadStateWithUnread `json:"read_states"`
+		Relationships []models.Relationship        `json:"relationships"`
+	}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	assert.Equal(t, "alice", decoded.User.Username)
+	require.Len(t, decoded.Guilds, 1)
+	assert.Equal(t, server.ID, decoded.Guilds[0].ID)
+	assert.NotNil(t, decoded.Guilds[0].Channels)
+	assert.Equal(t, 2, decoded.ReadStates[0].UnreadCount)
+	assert.Equal(t, "bob", decoded.Relationships[0].User.Username)
+}
+
+func TestGateway_FillReady_Failure(t *testing.T) {
+	gateway := NewGateway(NewHub(), nil, nil)
+	gateway.SetReadyState(&fakeReadyState{err: errors.New("database down")})
+
+	ready := ReadyData{Guilds: []interface{}{}}
+	gateway.fillReady(&ready, &Session{UserID: uuid.New()})
+
+	data, err := json.Marshal(ready)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "read_states")
+	assert.NotContains(t, string(data), "relationships")
+	assert.Empty(t, ready.Guilds)
+}
+
+func TestCompressMessage(t *testing.T) {
+	msg := &Message{Op: OpDispatch, Type: EventReady, Data: json.RawMessage(`{"v":10}`), Sequence: 1}
+
+	compressed, err := compressMessage(msg)
+	require.NoError(t, err)
+
+	r, err := zlib.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+	raw, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	var decoded Message
+	require.NoError(t, json.Unmarshal(raw, &decoded))
+	assert.Equal(t, EventReady, decoded.Type)
+	assert.JSONEq(t, `{"v":10}`, string(decoded.Data))
+}
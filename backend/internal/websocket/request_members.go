@@ -0,0 +1,244 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/google/uuid"
+
+	"hearth/internal/models"
+)
+
+const (
+	// memberChunkSize is the most members one GUILD_MEMBERS_CHUNK carries
+	memberChunkSize = 1000
+
+	// memberRequestMaxMembers is the most members one request is answered
+	// with, including requests for every member
+	memberRequestMaxMembers = 10 * memberChunkSize
+
+	// A connection may send memberRequestBurst member requests at once,
+	// then one more each memberRequestInterval
+	memberRequestBurst    = 5
+	memberRequestInterval = 2 * time.Second
+
+	memberRequestTimeout = 30 * time.Second
+)
+
+// MemberRequestSource finds the members REQUEST_GUILD_MEMBERS asks for. The
+// ServerService implements it.
+type MemberRequestSource interface {
+	GetMember(ctx context.Context, serverID, userID uuid.UUID) (*models.Member, error)
+	SearchMembers(ctx context.Context, serverID uuid.UUID, prefix string, after *uuid.UUID, limit int) ([]*models.Member, error)
+	GetMembersByIDs(ctx context.Context, serverID uuid.UUID, userIDs []uuid.UUID) ([]*models.Member, error)
+}
+
+// GuildMembersChunkData is the GUILD_MEMBERS_CHUNK payload. A request is
+// answered with chunk_count chunks, sent in order of chunk_index.
+type GuildMembersChunkData struct {
+	GuildID    string             `json:"guild_id"`
+	Members    []*models.Member   `json:"members"`
+	ChunkIndex int                `json:"chunk_index"`
+	ChunkCount int                `json:"chunk_count"`
+	NotFound   []uuid.UUID        `json:"not_found,omitempty"`
+	Presences  []*models.Presence `json:"presences,omitempty"`
+	Nonce      string             `json:"nonce,omitempty"`
+}
+
+// requestBucket is a token bucket for one connection's requests of one
+// kind. The zero value is full.
+type requestBucket struct {
+	mu   sync.Mutex
+	used float64
+	last time.Time
+}
+
+// take spends a request if fewer than burst have been spent, refilling one
+// every interval
+func (b *requestBucket) take(now time.Time, burst int, interval time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.last.IsZero() {
+		b.used -= float64(now.Sub(b.last)) / float64(interval)
+		if b.used < 0 {
+			b.used = 0
+		}
+	}
+	b.last = now
+	if b.used+1 > float64(burst) {
+		return false
+	}
+	b.used++
+	return true
+}
+
+// memberRequestsRateLimited answers member requests from connections over
+// their limit. The op is dropped and the connection stays open.
+func memberRequestsRateLimited(op int) *ProtocolError {
+	return &ProtocolError{Code: CloseRateLimited, Op: op, Message: "too many member requests, try again shortly"}
+}
+
+// SetMemberRequestSource answers REQUEST_GUILD_MEMBERS. Without one the
+// op is refused.
+func (g *Gateway) SetMemberRequestSource(source MemberRequestSource) {
+	g.memberRequests = source
+}
+
+// handleRequestMembers streams the members matching a prefix or list of
+// user IDs as GUILD_MEMBERS_CHUNK events, so bots can fill their caches
+// without paging through REST
+func (g *Gateway) handleRequestMembers(conn *websocket.Conn, client *Client, session *Session, msg *Message) {
+	var data requestMembersPayload
+	if msg.Data != nil {
+		json.Unmarshal(msg.Data, &data)
+	}
+
+	serverID := uuid.MustParse(data.GuildID)
+	if !session.memberRequests.take(time.Now(), memberRequestBurst, memberRequestInterval) {
+		g.rejectMessage(conn, memberRequestsRateLimited(msg.Op))
+		return
+	}
+	if !allowServerEvent(g.serverThrottle, serverID) {
+		g.rejectMessage(conn, serverRateLimited(msg.Op))
+		return
+	}
+	if g.memberRequests == nil {
+		g.sendError(conn, "member requests are not available")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), memberRequestTimeout)
+	defer cancel()
+
+	if member, err := g.memberRequests.GetMember(ctx, serverID, session.UserID); err != nil || member == nil {
+		g.rejectMessage(conn, invalidPayload(msg.Op, "d.guild_id", "not a member of this server"))
+		return
+	}
+
+	chunks, err := g.memberChunks(ctx, serverID, session.UserID, &data)
+	if err != nil {
+		log.Printf("[Gateway] Failed to load members of server %s: %v", serverID, err)
+		g.sendError(conn, "members unavailable")
+		return
+	}
+	for _, chunk := range chunks {
+		chunkData, _ := json.Marshal(chunk)
+		if !g.dispatch(client, &Message{Op: OpDispatch, Type: EventGuildMembersChunk, Data: chunkData}) {
+			return
+		}
+	}
+}
+
+// memberChunks finds the requested members and splits them into chunks.
+// There is always at least one chunk, so an empty answer still carries the
+// nonce. Presences are filtered for viewerID, the requester.
+func (g *Gateway) memberChunks(ctx context.Context, serverID, viewerID uuid.UUID, data *requestMembersPayload) ([]*GuildMembersChunkData, error) {
+	var members []*models.Member
+	var notFound []uuid.UUID
+
+	if len(data.UserIDs) > 0 {
+		ids := make([]uuid.UUID, len(data.UserIDs))
+		for i, id := range data.UserIDs {
+			ids[i] = uuid.MustParse(id)
+		}
+		found, err := g.memberRequests.GetMembersByIDs(ctx, serverID, ids)
+		if err != nil {
+			return nil, err
+		}
+		members = found
+		notFound = missingMembers(ids, found)
+	} else {
+		// A limit of 0 asks for every match, up to memberRequestMaxMembers,
+		// read a chunk at a time
+		limit := data.Limit
+		if limit == 0 {
+			limit = memberRequestMaxMembers
+		}
+		var after *uuid.UUID
+		for len(members) < limit {
+			batch := min(memberChunkSize, limit-len(members))
+			page, err := g.memberRequests.SearchMembers(ctx, serverID, data.Query, after, batch)
+			if err != nil {
+				return nil, err
+			}
+			members = append(members, page...)
+			if len(page) < batch {
+				break
+			}
+			after = &page[len(page)-1].UserID
+		}
+	}
+
+	count := (len(members) + memberChunkSize - 1) / memberChunkSize
+	if count == 0 {
+		count = 1
+	}
+	chunks := make([]*GuildMembersChunkData, count)
+	for i := range chunks {
+		end := min((i+1)*memberChunkSize, len(members))
+		chunk := &GuildMembersChunkData{
+			GuildID:    serverID.String(),
+			Members:    members[i*memberChunkSize : end],
+			ChunkIndex: i,
+			ChunkCount: count,
+			Nonce:      data.Nonce,
+		}
+		if chunk.Members == nil {
+			chunk.Members = []*models.Member{}
+		}
+		if i == 0 {
+			chunk.NotFound = notFound
+		}
+		if data.Presences {
+			presences, err := g.chunkPresences(ctx, viewerID, chunk.Members)
+			if err != nil {
+				return nil, err
+			}
+			chunk.Presences = presences
+		}
+		chunks[i] = chunk
+	}
+	return chunks, nil
+}
+
+// chunkPresences returns the members' presences as viewerID may see them,
+// with friends-only presence shown offline to everyone else
+func (g *Gateway) chunkPresences(ctx context.Context, viewerID uuid.UUID, members []*models.Member) ([]*models.Presence, error) {
+	ids := make([]uuid.UUID, len(members))
+	for i, member := range members {
+		ids[i] = member.UserID
+	}
+	presences := g.hub.GetPresences(ids)
+	if g.presenceFilter != nil {
+		if err := g.presenceFilter.FilterPresences(ctx, viewerID, presences); err != nil {
+			return nil, err
+		}
+	}
+	result := make([]*models.Presence, 0, len(ids))
+	for _, id := range ids {
+		if p, ok := presences[id]; ok {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+// missingMembers returns the IDs in ids that found has no member for
+func missingMembers(ids []uuid.UUID, found []*models.Member) []uuid.UUID {
+	members := make(map[uuid.UUID]bool, len(found))
+	for _, member := range found {
+		members[member.UserID] = true
+	}
+	var missing []uuid.UUID
+	for _, id := range ids {
+		if !members[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
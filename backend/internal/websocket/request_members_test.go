@@ -0,0 +1,230 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/models"
+)
+
+type fakeMemberRequestSource struct {
+	members []*models.Member
+	pages   int
+}
+
+func (f *fakeMemberRequestSource) GetMember(ctx context.Context, serverID, userID uuid.UUID) (*models.Member, error) {
+	for _, m := range f.members {
+		if m.UserID == userID {
+			return m, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeMemberRequestSource) SearchMembers(ctx context.Context, serverID uuid.UUID, prefix string, after *uuid.UUID, limit int) ([]*models.Member, error) {
+	f.pages++
+	var page []*models.Member
+	for _, m := range f.members {
+		if after != nil && m.UserID.String() <= after.String() {
+			continue
+		}
+		if len(page) < limit && len(m.User.Username) >= len(prefix) && m.User.Username[:len(prefix)] == prefix {
+			page = append(page, m)
+		}
+	}
+	return page, nil
+}
+
+func (f *fakeMemberRequestSource) GetMembersByIDs(ctx context.Context, serverID uuid.UUID, userIDs []uuid.UUID) ([]*models.Member, error) {
+	var found []*models.Member
+	for _, id := range userIDs {
+		if m, _ := f.GetMember(ctx, serverID, id); m != nil {
+			found = append(found, m)
+		}
+	}
+	return found, nil
+}
+
+func newFakeMemberRequestSource(n int) *fakeMemberRequestSource {
+	source := &fakeMemberRequestSource{}
+	for i := 0; i < n; i++ {
+		source.members = append(source.members, &models.Member{
+			UserID: uuid.New(),
+			User:   &models.PublicUser{Username: fmt.Sprintf("user%04d", i)},
+		})
+	}
+	sort.Slice(source.members, func(i, j int) bool {
+		return source.members[i].UserID.String() < source.members[j].UserID.String()
+	})
+	return source
+}
+
+func TestGateway_MemberChunksStreamEveryMember(t *testing.T) {
+	gateway := NewGateway(NewHub(), nil, nil)
+	source := newFakeMemberRequestSource(2500)
+	gateway.SetMemberRequestSource(source)
+	serverID := uuid.New()
+
+	chunks, err := gateway.memberChunks(context.Background(), serverID, uuid.New(), &requestMembersPayload{GuildID: serverID.String(), Nonce: "all"})
+	require.NoError(t, err)
+	require.Len(t, chunks, 3)
+
+	seen := make(map[uuid.UUID]bool)
+	for i, chunk := range chunks {
+		assert.Equal(t, i, chunk.ChunkIndex)
+		assert.Equal(t, 3, chunk.ChunkCount)
+		assert.Equal(t, "all", chunk.Nonce)
+		for _, m := range chunk.Members {
+			seen[m.UserID] = true
+		}
+	}
+	assert.Len(t, chunks[2].Members, 500)
+	assert.Len(t, seen, 2500)
+	assert.Equal(t, 3, source.pages)
+}
+
+func TestGateway_MemberChunksCapEveryMember(t *testing.T) {
+	gateway := NewGateway(NewHub(), nil, nil)
+	source := newFakeMemberRequestSource(memberRequestMaxMembers + 500)
+	gateway.SetMemberRequestSource(source)
+	serverID := uuid.New()
+
+	chunks, err := gateway.memberChunks(context.Background(), serverID, uuid.New(), &requestMembersPayload{GuildID: serverID.String()})
+	require.NoError(t, err)
+	require.Len(t, chunks, memberRequestMaxMembers/memberChunkSize)
+	assert.Equal(t, memberRequestMaxMembers/memberChunkSize, source.pages, "reading stops at the cap")
+}
+
+func TestGateway_RequestMembersQueuesChunks(t *testing.T) {
+	gateway := NewGateway(NewHub(), nil, nil)
+	source := newFakeMemberRequestSource(1500)
+	gateway.SetMemberRequestSource(source)
+	serverID := uuid.New()
+	session := &Session{UserID: source.members[0].UserID, Sequence: 3}
+	client := NewClient(NewHub(), nil, session.UserID, "bot", "session", "bot")
+
+	request, _ := json.Marshal(requestMembersPayload{GuildID: serverID.String(), Nonce: "all"})
+	gateway.handleRequestMembers(nil, client, session, &Message{Op: OpRequestGuildMembers, Data: request})
+
+	// Chunks go through the write pump, which sequences and remembers them
+	require.Len(t, client.send, 2)
+	for i := 0; i < 2; i++ {
+		var msg Message
+		require.NoError(t, json.Unmarshal(<-client.send, &msg))
+		assert.Equal(t, EventGuildMembersChunk, msg.Type)
+		assert.Zero(t, msg.Sequence)
+
+		var chunk GuildMembersChunkData
+		require.NoError(t, json.Unmarshal(msg.Data, &chunk))
+		assert.Equal(t, i, chunk.ChunkIndex)
+		assert.Equal(t, "all", chunk.Nonce)
+	}
+}
+
+func TestGateway_MemberChunksByPrefixAndLimit(t *testing.T) {
+	gateway := NewGateway(NewHub(), nil, nil)
+	gateway.SetMemberRequestSource(newFakeMemberRequestSource(300))
+	serverID := uuid.New()
+
+	chunks, err := gateway.memberChunks(context.Background(), serverID, uuid.New(), &requestMembersPayload{GuildID: serverID.String(), Query: "user01", Limit: 50})
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	assert.Len(t, chunks[0].Members, 50)
+	for _, m := range chunks[0].Members {
+		assert.Contains(t, m.User.Username, "user01")
+	}
+
+	// No matches still answers, so the client sees its nonce back
+	chunks, err = gateway.memberChunks(context.Background(), serverID, uuid.New(), &requestMembersPayload{GuildID: serverID.String(), Query: "nobody", Nonce: "n"})
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	assert.Empty(t, chunks[0].Members)
+	assert.NotNil(t, chunks[0].Members)
+	assert.Equal(t, "n", chunks[0].Nonce)
+}
+
+func TestGateway_MemberChunksByIDs(t *testing.T) {
+	hub := NewHub()
+	gateway := NewGateway(hub, nil, nil)
+	source := newFakeMemberRequestSource(3)
+	gateway.SetMemberRequestSource(source)
+	serverID := uuid.New()
+	stranger := uuid.New()
+	hub.registerClient(&Client{UserID: source.members[0].UserID, send: make(chan []byte, 8)})
+
+	chunks, err := gateway.memberChunks(context.Background(), serverID, uuid.New(), &requestMembersPayload{
+		GuildID:   serverID.String(),
+		UserIDs:   []string{source.members[0].UserID.String(), stranger.String()},
+		Presences: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	assert.Equal(t, []*models.Member{source.members[0]}, chunks[0].Members)
+	assert.Equal(t, []uuid.UUID{stranger}, chunks[0].NotFound)
+	require.Len(t, chunks[0].Presences, 1)
+	assert.Equal(t, models.StatusOnline, chunks[0].Presences[0].Status)
+}
+
+func TestGateway_MemberChunksHideFriendsOnlyPresence(t *testing.T) {
+	hub := NewHub()
+	gateway := NewGateway(hub, nil, nil)
+	source := newFakeMemberRequestSource(3)
+	gateway.SetMemberRequestSource(source)
+	serverID := uuid.New()
+	hidden, visible, friend := source.members[0].UserID, source.members[1].UserID, source.members[2].UserID
+	gateway.SetPresenceFilter(&fakePresenceFilter{
+		hidden:  map[uuid.UUID]bool{hidden: true},
+		friends: map[uuid.UUID]uuid.UUID{hidden: friend},
+	})
+	hub.registerClient(&Client{UserID: hidden, send: make(chan []byte, 8)})
+	hub.registerClient(&Client{UserID: visible, send: make(chan []byte, 8)})
+
+	request := &requestMembersPayload{
+		GuildID:   serverID.String(),
+		UserIDs:   []string{hidden.String(), visible.String()},
+		Presences: true,
+	}
+	statuses := func(viewerID uuid.UUID) []models.PresenceStatus {
+		chunks, err := gateway.memberChunks(context.Background(), serverID, viewerID, request)
+		require.NoError(t, err)
+		require.Len(t, chunks, 1)
+		var result []models.PresenceStatus
+		for _, p := range chunks[0].Presences {
+			result = append(result, p.Status)
+		}
+		return result
+	}
+
+	assert.Equal(t, []models.PresenceStatus{models.StatusOffline, models.StatusOnline}, statuses(uuid.New()))
+	assert.Equal(t, []models.PresenceStatus{models.StatusOnline, models.StatusOnline}, statuses(friend))
+}
+
+func TestRequestBucket(t *testing.T) {
+	var bucket requestBucket
+	now := time.Now()
+
+	for i := 0; i < memberRequestBurst; i++ {
+		assert.True(t, bucket.take(now, memberRequestBurst, memberRequestInterval), "request %d", i)
+	}
+	assert.False(t, bucket.take(now, memberRequestBurst, memberRequestInterval))
+
+	// One request comes back each interval
+	now = now.Add(memberRequestInterval)
+	assert.True(t, bucket.take(now, memberRequestBurst, memberRequestInterval))
+	assert.False(t, bucket.take(now, memberRequestBurst, memberRequestInterval))
+
+	// and the bucket refills no further than the burst
+	now = now.Add(time.Hour)
+	for i := 0; i < memberRequestBurst; i++ {
+		assert.True(t, bucket.take(now, memberRequestBurst, memberRequestInterval))
+	}
+	assert.False(t, bucket.take(now, memberRequestBurst, memberRequestInterval))
+}
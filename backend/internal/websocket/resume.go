@@ -0,0 +1,454 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/google/uuid"
+)
+
+// resumeStoreTimeout bounds each call to the resume store
+const resumeStoreTimeout = 2 * time.Second
+
+// errResumeGone is returned when buffering for a session that has been
+// resumed elsewhere or whose replay window has passed
+var errResumeGone = errors.New("session is no longer resumable")
+
+// ResumeState is what's kept of a disconnected session so another
+// connection, on any node, can take it over
+type ResumeState struct {
+	SessionID    string       `json:"session_id"`
+	UserID       uuid.UUID    `json:"user_id"`
+	Capabilities Capabilities `json:"capabilities"`
+	Intents      Intents      `json:"intents,omitempty"`
+	Encoding     Encoding     `json:"encoding,omitempty"`
+	Sequence     int64        `json:"seq"`
+	Servers      []uuid.UUID  `json:"servers,omitempty"`
+	Channels     []uuid.UUID  `json:"channels,omitempty"`
+}
+
+// ResumeStore keeps disconnected sessions and the events sent to them for
+// the replay window. Each session keeps its most recent events only.
+type ResumeStore interface {
+	// Save records a session that just disconnected along with the last
+	// events it was sent, replacing any earlier record
+	Save(ctx context.Context, state *ResumeState, events [][]byte) error
+	// Append buffers an event sent while the session is away. It returns
+	// errResumeGone once the session has been resumed or has expired.
+	Append(ctx context.Context, sessionID string, event []byte) error
+	// Load returns a session and its buffered events, oldest first, or nil
+	// if it's unknown or expired
+	Load(ctx context.Context, sessionID string) (*ResumeState, [][]byte, error)
+	// Delete forgets a session, e.g. once it has been resumed
+	Delete(ctx context.Context, sessionID string) error
+}
+
+// memoryResumeStore keeps sessions in this process, for single-node
+// deployments without Redis. Sessions can only be resumed on the node they
+// were connected to.
+type memoryResumeStore struct {
+	mu        sync.Mutex
+	window    time.Duration
+	maxEvents int
+	sessions  map[string]*memoryResume
+	now       func() time.Time
+}
+
+type memoryResume struct {
+	state   ResumeState
+	events  [][]byte
+	expires time.Time
+}
+
+// NewMemoryResumeStore keeps up to maxEvents events per session, for window
+// after it disconnects
+func NewMemoryResumeStore(window time.Duration, maxEvents int) ResumeStore {
+	return &memoryResumeStore{
+		window:    window,
+		maxEvents: maxEvents,
+		sessions:  make(map[string]*memoryResume),
+		now:       time.Now,
+	}
+}
+
+func (s *memoryResumeStore) Save(ctx context.Context, state *ResumeState, events [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for id, session := range s.sessions {
+		if !now.Before(session.expires) {
+			delete(s.sessions, id)
+		}
+	}
+
+	if len(events) > s.maxEvents {
+		events = events[len(events)-s.maxEvents:]
+	}
+	s.sessions[state.SessionID] = &memoryResume{
+		state:   *state,
+		events:  append([][]byte(nil), events...),
+		expires: now.Add(s.window),
+	}
+	return nil
+}
+
+func (s *memoryResumeStore) Append(ctx context.Context, sessionID string, event []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session := s.live(sessionID)
+	if session == nil {
+		return errResumeGone
+	}
+	session.events = append(session.events, event)
+	if len(session.events) > s.maxEvents {
+		session.events = session.events[1:]
+	}
+	return nil
+}
+
+func (s *memoryResumeStore) Load(ctx context.Context, sessionID string) (*ResumeState, [][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session := s.live(sessionID)
+	if session == nil {
+		return nil, nil, nil
+	}
+	state := session.state
+	return &state, append([][]byte(nil), session.events...), nil
+}
+
+func (s *memoryResumeStore) Delete(ctx context.Context, sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, sessionID)
+	return nil
+}
+
+// live returns the session if it hasn't expired. s.mu must be held.
+func (s *memoryResumeStore) live(sessionID string) *memoryResume {
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		return nil
+	}
+	if !s.now().Before(session.expires) {
+		delete(s.sessions, sessionID)
+		return nil
+	}
+	return session
+}
+
+// SetResumeStore replaces where disconnected sessions are kept; a shared
+// store lets clients resume on any node
+func (g *Gateway) SetResumeStore(store ResumeStore) {
+	g.resumes = store
+}
+
+// prepare stamps a hub frame with the next sequence number under delta
+// sync. The hub has already serialized it in the client's format.
+func (s *Session) prepare(message []byte) []byte {
+	if !s.Capabilities().Has(CapabilityDeltaSync) {
+		return message
+	}
+	return stampSequence(s.Encoding, message, func() int64 {
+		return s.dispatchSeq.Add(1)
+	})
+}
+
+// replySequence is the sequence for a reply written straight to the
+// connection. Under delta sync it repeats the last dispatch's, so a client
+// tracking sequences for resume isn't thrown off.
+func (s *Session) replySequence() int64 {
+	if s.Capabilities().Has(CapabilityDeltaSync) {
+		return s.dispatchSeq.Load()
+	}
+	return s.Sequence
+}
+
+// remember keeps message among the last limit frames sent, for resume
+func (s *Session) remember(message []byte, limit int) {
+	if limit <= 0 {
+		return
+	}
+	s.resumeMu.Lock()
+	defer s.resumeMu.Unlock()
+	if len(s.ResumeEvents) >= limit {
+		s.ResumeEvents = s.ResumeEvents[len(s.ResumeEvents)-limit+1:]
+	}
+	s.ResumeEvents = append(s.ResumeEvents, message)
+}
+
+// stop ends buffering for a disconnected session
+func (s *Session) stop() {
+	s.stopOnce.Do(func() { close(s.stopBuffering) })
+}
+
+// handleResume takes over a disconnected session from RESUME (op 6). If it
+// can't be resumed the client is sent INVALID_SESSION and should IDENTIFY.
+func (g *Gateway) handleResume(conn *websocket.Conn, client *Client, session *Session, msg *Message) {
+	if session.identified.Load() {
+		g.sendError(conn, "session already identified")
+		return
+	}
+
+	var data resumePayload
+	if msg.Data != nil {
+		json.Unmarshal(msg.Data, &data)
+	}
+	if code := g.resume(conn, client, session, data.SessionID, data.Seq); code != 0 {
+		g.sendMessage(conn, &Message{
+			Op:   OpInvalidSession,
+			Data: json.RawMessage("false"),
+		})
+	}
+}
+
+// resume makes session take over the disconnected session sessionID,
+// replaying the events sent since lastSeq. It returns the close code the
+// resume was refused with, or 0.
+func (g *Gateway) resume(conn *websocket.Conn, client *Client, session *Session, sessionID string, lastSeq int64) int {
+	if g.resumes == nil || sessionID == "" {
+		return CloseInvalidSession
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), resumeStoreTimeout)
+	defer cancel()
+
+	state, _, err := g.resumes.Load(ctx, sessionID)
+	if err != nil {
+		log.Printf("[Gateway] Failed to load session %s for resume: %v", sessionID, err)
+		return CloseInvalidSession
+	}
+	// Buffered frames are already encoded, so the encoding can't change
+	if state == nil || state.UserID != session.UserID || encodingOrJSON(state.Encoding) != encodingOrJSON(session.Encoding) {
+		return CloseInvalidSession
+	}
+
+	// Subscribe before reading the buffer so nothing falls between the
+	// two; an event can arrive both ways
+	for _, id := range state.Servers {
+		client.SubscribeServer(id)
+	}
+	for _, id := range state.Channels {
+		client.SubscribeChannel(id)
+	}
+	state, events, err := g.resumes.Load(ctx, sessionID)
+	if err != nil || state == nil {
+		g.unsubscribe(client)
+		return CloseInvalidSession
+	}
+	replay, ok := eventsAfter(events, state, lastSeq)
+	if !ok {
+		g.unsubscribe(client)
+		return CloseInvalidSeq
+	}
+
+	// Stop the old connection buffering: here directly, on other nodes
+	// at the next event they try to append
+	if err := g.resumes.Delete(ctx, sessionID); err != nil {
+		log.Printf("[Gateway] Failed to delete resumed session %s: %v", sessionID, err)
+	}
+	g.sessionsMu.Lock()
+	if old, ok := g.sessions[sessionID]; ok {
+		old.stop()
+	}
+	delete(g.sessions, session.ID)
+	session.ID = sessionID
+	client.SessionID = sessionID
+	g.sessions[sessionID] = session
+	g.sessionsMu.Unlock()
+
+	g.devices.setSessionID(session.UserID, client.ID, sessionID)
+	session.SetCapabilities(state.Capabilities)
+	client.omitLegacy.Store(state.Capabilities.Has(CapabilityOmitLegacyFields))
+	client.SetIntents(state.Intents)
+
+	seq := state.Sequence
+	for _, event := range replay {
+		conn.WriteMessage(session.Encoding.messageType(), event)
+		session.remember(event, g.config.ResumeBufferSize)
+		if s := encodedSequence(session.Encoding, event); s > seq {
+			seq = s
+		}
+	}
+	session.dispatchSeq.Store(seq)
+
+	g.sendMessage(conn, &Message{
+		Op:   OpDispatch,
+		Type: EventResumed,
+	})
+	session.identified.Store(true)
+	session.startWrites()
+	return 0
+}
+
+// eventsAfter returns the buffered events a client that last saw lastSeq
+// missed. Without delta sync frames carry no sequence, so all are
+// replayed. It returns false if some have already been dropped from the
+// buffer.
+func eventsAfter(events [][]byte, state *ResumeState, lastSeq int64) ([][]byte, bool) {
+	if !state.Capabilities.Has(CapabilityDeltaSync) {
+		return events, true
+	}
+
+	oldest := state.Sequence + 1
+	if len(events) > 0 {
+		oldest = encodedSequence(state.Encoding, events[0])
+	}
+	if lastSeq+1 < oldest {
+		return nil, false
+	}
+	for i, event := range events {
+		if encodedSequence(state.Encoding, event) > lastSeq {
+			return events[i:], true
+		}
+	}
+	return nil, true
+}
+
+// resumeFailure is the close reason for a refused query string resume
+func resumeFailure(code int) string {
+	if code == CloseInvalidSeq {
+		return "events since seq are no longer available"
+	}
+	return "invalid session"
+}
+
+// unsubscribe drops the subscriptions a refused resume restored
+func (g *Gateway) unsubscribe(client *Client) {
+	servers, channels := client.subscriptions()
+	for _, id := range servers {
+		client.UnsubscribeServer(id)
+	}
+	for _, id := range channels {
+		client.UnsubscribeChannel(id)
+	}
+}
+
+// release is called once a connection has closed. Identified sessions are
+// kept for the replay window, buffering the events sent to them, so the
+// client can resume; anything else, including connections reaped for
+// missing heartbeats, is let go.
+func (g *Gateway) release(client *Client, session *Session) {
+	if !session.identified.Load() || session.timedOut.Load() || g.resumes == nil || g.config.SessionTimeout <= 0 {
+		g.forget(client, session)
+		return
+	}
+
+	session.saveMu.Lock()
+	defer session.saveMu.Unlock()
+	session.detached.Store(true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), resumeStoreTimeout)
+	defer cancel()
+
+	// A handed off session that's no longer stored has already been
+	// resumed on another node
+	if session.handedOff.Load() {
+		if state, _, err := g.resumes.Load(ctx, session.ID); err == nil && state == nil {
+			g.forget(client, session)
+			return
+		}
+	}
+
+	state, events := g.snapshot(client, session)
+	if err := g.resumes.Save(ctx, state, events); err != nil {
+		log.Printf("[Gateway] Failed to save session %s for resume: %v", session.ID, err)
+		g.forget(client, session)
+		return
+	}
+	go g.bufferDetached(client, session)
+}
+
+// snapshot is what's saved of a session for resume: its subscriptions,
+// last sequence and the dispatches it was last sent. Anything else, such as
+// a drain's RECONNECT, mustn't be replayed.
+func (g *Gateway) snapshot(client *Client, session *Session) (*ResumeState, [][]byte) {
+	state := &ResumeState{
+		SessionID:    session.ID,
+		UserID:       session.UserID,
+		Capabilities: session.Capabilities(),
+		Intents:      client.Intents(),
+		Encoding:     session.Encoding,
+		Sequence:     session.dispatchSeq.Load(),
+	}
+	state.Servers, state.Channels = client.subscriptions()
+
+	session.resumeMu.Lock()
+	events := make([][]byte, 0, len(session.ResumeEvents))
+	for _, event := range session.ResumeEvents {
+		if encodedOp(session.Encoding, event) == OpDispatch {
+			events = append(events, event)
+		}
+	}
+	session.resumeMu.Unlock()
+	return state, events
+}
+
+// bufferDetached stores the events sent to a disconnected session until
+// it's resumed or the replay window passes
+func (g *Gateway) bufferDetached(client *Client, session *Session) {
+	timer := time.NewTimer(g.config.SessionTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case message, ok := <-client.send:
+			if !ok {
+				// The hub has let the client go already
+				g.removeSession(session)
+				return
+			}
+			if encodedOp(session.Encoding, message) != OpDispatch {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), resumeStoreTimeout)
+			err := g.resumes.Append(ctx, session.ID, session.prepare(message))
+			cancel()
+			if errors.Is(err, errResumeGone) {
+				g.forget(client, session)
+				return
+			}
+			if err != nil {
+				log.Printf("[Gateway] Failed to buffer event for session %s: %v", session.ID, err)
+			}
+
+		case <-timer.C:
+			g.forget(client, session)
+			return
+
+		case <-session.stopBuffering:
+			g.forget(client, session)
+			return
+		}
+	}
+}
+
+// forget unregisters a session's hub client and drops the session
+func (g *Gateway) forget(client *Client, session *Session) {
+	g.hub.UnregisterClient() <- client
+	g.removeSession(session)
+}
+
+func (g *Gateway) removeSession(session *Session) {
+	g.sessionsMu.Lock()
+	defer g.sessionsMu.Unlock()
+	// A resumed session is now held under the same ID by its new connection
+	if g.sessions[session.ID] == session {
+		delete(g.sessions, session.ID)
+	}
+}
+
+// encodingOrJSON treats sessions saved without an encoding as JSON
+func encodingOrJSON(e Encoding) Encoding {
+	if e == "" {
+		return EncodingJSON
+	}
+	return e
+}
@@ -0,0 +1,113 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisResumeStore keeps disconnected sessions in Redis, with each
+// session's events in a stream, so a client can resume on any node
+type RedisResumeStore struct {
+	client    redis.UniversalClient
+	window    time.Duration
+	maxEvents int64
+}
+
+// NewRedisResumeStore keeps up to maxEvents events per session, for window
+// after it disconnects
+func NewRedisResumeStore(client redis.UniversalClient, window time.Duration, maxEvents int) *RedisResumeStore {
+	return &RedisResumeStore{
+		client:    client,
+		window:    window,
+		maxEvents: int64(maxEvents),
+	}
+}
+
+func resumeStateKey(sessionID string) string {
+	return "gateway:resume:" + sessionID
+}
+
+func resumeEventsKey(sessionID string) string {
+	return "gateway:resume:" + sessionID + ":events"
+}
+
+func (s *RedisResumeStore) Save(ctx context.Context, state *ResumeState, events [][]byte) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if int64(len(events)) > s.maxEvents {
+		events = events[int64(len(events))-s.maxEvents:]
+	}
+
+	eventsKey := resumeEventsKey(state.SessionID)
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, resumeStateKey(state.SessionID), data, s.window)
+	pipe.Del(ctx, eventsKey)
+	for _, event := range events {
+		pipe.XAdd(ctx, s.xadd(eventsKey, event))
+	}
+	pipe.Expire(ctx, eventsKey, s.window)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisResumeStore) Append(ctx context.Context, sessionID string, event []byte) error {
+	exists, err := s.client.Exists(ctx, resumeStateKey(sessionID)).Result()
+	if err != nil {
+		return err
+	}
+	if exists == 0 {
+		return errResumeGone
+	}
+
+	eventsKey := resumeEventsKey(sessionID)
+	pipe := s.client.Pipeline()
+	pipe.XAdd(ctx, s.xadd(eventsKey, event))
+	// Save sets the expiry unless it had no events to write
+	pipe.ExpireNX(ctx, eventsKey, s.window)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisResumeStore) Load(ctx context.Context, sessionID string) (*ResumeState, [][]byte, error) {
+	data, err := s.client.Get(ctx, resumeStateKey(sessionID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var state ResumeState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, nil, err
+	}
+
+	entries, err := s.client.XRange(ctx, resumeEventsKey(sessionID), "-", "+").Result()
+	if err != nil {
+		return nil, nil, err
+	}
+	events := make([][]byte, 0, len(entries))
+	for _, entry := range entries {
+		if event, ok := entry.Values["e"].(string); ok {
+			events = append(events, []byte(event))
+		}
+	}
+	return &state, events, nil
+}
+
+func (s *RedisResumeStore) Delete(ctx context.Context, sessionID string) error {
+	return s.client.Del(ctx, resumeStateKey(sessionID), resumeEventsKey(sessionID)).Err()
+}
+
+func (s *RedisResumeStore) xadd(key string, event []byte) *redis.XAddArgs {
+	return &redis.XAddArgs{
+		Stream: key,
+		MaxLen: s.maxEvents,
+		Approx: true,
+		Values: []interface{}{"e", event},
+	}
+}
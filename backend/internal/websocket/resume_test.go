@@ -0,0 +1,159 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func seqFrame(t *testing.T, seq int64) []byte {
+	data, err := json.Marshal(&Message{Op: OpDispatch, Type: EventMessageCreate, Sequence: seq})
+	require.NoError(t, err)
+	return data
+}
+
+func TestMemoryResumeStore(t *testing.T) {
+	store := NewMemoryResumeStore(time.Minute, 2).(*memoryResumeStore)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+	state := &ResumeState{SessionID: "s-1", UserID: uuid.New(), Sequence: 3}
+
+	require.NoError(t, store.Save(ctx, state, [][]byte{[]byte("1"), []byte("2"), []byte("3")}))
+	require.NoError(t, store.Append(ctx, "s-1", []byte("4")))
+
+	got, events, err := store.Load(ctx, "s-1")
+	require.NoError(t, err)
+	assert.Equal(t, state.UserID, got.UserID)
+	assert.Equal(t, [][]byte{[]byte("3"), []byte("4")}, events, "only the last 2 events are kept")
+
+	// Unknown and expired sessions can't be resumed or buffered for
+	assert.ErrorIs(t, store.Append(ctx, "s-2", []byte("1")), errResumeGone)
+	now = now.Add(time.Minute)
+	got, _, err = store.Load(ctx, "s-1")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	assert.ErrorIs(t, store.Append(ctx, "s-1", []byte("5")), errResumeGone)
+}
+
+func TestRedisResumeStore(t *testing.T) {
+	skipIfNoRedis(t)
+	opts, err := redis.ParseURL(getRedisURL())
+	require.NoError(t, err)
+	client := redis.NewClient(opts)
+	defer client.Close()
+
+	store := NewRedisResumeStore(client, time.Minute, 2)
+	ctx := context.Background()
+	state := &ResumeState{SessionID: uuid.NewString(), UserID: uuid.New(), Servers: []uuid.UUID{uuid.New()}}
+	defer store.Delete(ctx, state.SessionID)
+
+	require.NoError(t, store.Save(ctx, state, [][]byte{[]byte("1")}))
+	require.NoError(t, store.Append(ctx, state.SessionID, []byte("2")))
+
+	got, events, err := store.Load(ctx, state.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, state.Servers, got.Servers)
+	assert.Equal(t, [][]byte{[]byte("1"), []byte("2")}, events)
+
+	require.NoError(t, store.Delete(ctx, state.SessionID))
+	got, _, err = store.Load(ctx, state.SessionID)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	assert.ErrorIs(t, store.Append(ctx, state.SessionID, []byte("3")), errResumeGone)
+}
+
+func TestEventsAfter(t *testing.T) {
+	events := [][]byte{seqFrame(t, 5), seqFrame(t, 6), seqFrame(t, 7)}
+	state := &ResumeState{Capabilities: CapabilityDeltaSync, Sequence: 7}
+
+	replay, ok := eventsAfter(events, state, 4)
+	assert.True(t, ok)
+	assert.Len(t, replay, 3)
+
+	replay, ok = eventsAfter(events, state, 6)
+	assert.True(t, ok)
+	assert.Equal(t, events[2:], replay)
+
+	replay, ok = eventsAfter(events, state, 7)
+	assert.True(t, ok)
+	assert.Empty(t, replay)
+
+	// Events 3 and 4 have been dropped from the buffer
+	_, ok = eventsAfter(events, state, 2)
+	assert.False(t, ok)
+	_, ok = eventsAfter(nil, state, 6)
+	assert.False(t, ok)
+
+	// Without sequence numbers everything buffered is replayed
+	replay, ok = eventsAfter(events, &ResumeState{}, 6)
+	assert.True(t, ok)
+	assert.Len(t, replay, 3)
+}
+
+func TestGateway_ReleaseBuffersUntilResumed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hub := NewHub()
+	go hub.Run(ctx)
+
+	gateway := NewGateway(hub, nil, nil)
+	session := &Session{ID: "s-1", UserID: uuid.New(), stopBuffering: make(chan struct{})}
+	session.SetCapabilities(CapabilityDeltaSync)
+	session.dispatchSeq.Store(3)
+	session.identified.Store(true)
+	session.remember(seqFrame(t, 3), gateway.config.ResumeBufferSize)
+	gateway.sessions[session.ID] = session
+
+	client := newMockClient(hub, session.UserID)
+	serverID := uuid.New()
+	client.servers[serverID] = true
+	hub.RegisterClient() <- client
+
+	gateway.release(client, session)
+	client.send <- []byte(`{"op":0,"t":"MESSAGE_CREATE"}`)
+
+	// The event sent while disconnected is buffered with the next sequence
+	require.Eventually(t, func() bool {
+		_, events, _ := gateway.resumes.Load(ctx, "s-1")
+		return len(events) == 2
+	}, time.Second, 10*time.Millisecond)
+	state, events, err := gateway.resumes.Load(ctx, "s-1")
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{serverID}, state.Servers)
+	assert.Equal(t, int64(4), frameSequence(events[1]))
+
+	// Resuming elsewhere stops the buffering and lets the session go
+	session.stop()
+	require.Eventually(t, func() bool {
+		gateway.sessionsMu.RLock()
+		defer gateway.sessionsMu.RUnlock()
+		return gateway.sessions["s-1"] == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestGateway_ReleaseUnidentified(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hub := NewHub()
+	go hub.Run(ctx)
+
+	gateway := NewGateway(hub, nil, nil)
+	session := &Session{ID: "s-1", UserID: uuid.New(), stopBuffering: make(chan struct{})}
+	gateway.sessions[session.ID] = session
+	client := newMockClient(hub, session.UserID)
+	hub.RegisterClient() <- client
+
+	gateway.release(client, session)
+
+	state, _, err := gateway.resumes.Load(ctx, "s-1")
+	require.NoError(t, err)
+	assert.Nil(t, state, "sessions that never identified can't be resumed")
+	assert.Nil(t, gateway.sessions["s-1"])
+}
@@ -0,0 +1,246 @@
+package websocket
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// registryShards is how many pieces each of the hub's registries is
+	// split into. A power of two, so picking a shard is a mask.
+	registryShards = 64
+
+	// fanoutBatch is how many recipients a fan-out worker takes at a time.
+	// Broadcasts to fewer are queued inline.
+	fanoutBatch = 1024
+)
+
+// clientRegistry maps IDs (users, channels or servers) to the clients
+// registered under them. It's split into shards by ID, so registering,
+// subscribing and broadcasting for different IDs don't wait on one lock.
+type clientRegistry struct {
+	shards [registryShards]registryShard
+}
+
+type registryShard struct {
+	mu  sync.RWMutex
+	ids map[uuid.UUID]map[*Client]bool
+}
+
+func newClientRegistry() *clientRegistry {
+	r := &clientRegistry{}
+	for i := range r.shards {
+		r.shards[i].ids = make(map[uuid.UUID]map[*Client]bool)
+	}
+	return r
+}
+
+// shard picks an ID's shard from its last bytes, which are random in both
+// v4 and v7 UUIDs
+func (r *clientRegistry) shard(id uuid.UUID) *registryShard {
+	return &r.shards[binary.LittleEndian.Uint64(id[8:])&(registryShards-1)]
+}
+
+// add registers client under id
+func (r *clientRegistry) add(id uuid.UUID, client *Client) {
+	s := r.shard(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ids[id] == nil {
+		s.ids[id] = make(map[*Client]bool)
+	}
+	s.ids[id][client] = true
+}
+
+// remove unregisters client from id, forgetting id once it has no clients
+func (r *clientRegistry) remove(id uuid.UUID, client *Client) {
+	s := r.shard(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if clients, ok := s.ids[id]; ok {
+		delete(clients, client)
+		if len(clients) == 0 {
+			delete(s.ids, id)
+		}
+	}
+}
+
+// contains reports whether client is registered under id
+func (r *clientRegistry) contains(id uuid.UUID, client *Client) bool {
+	s := r.shard(id)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ids[id][client]
+}
+
+// has reports whether any client is registered under id
+func (r *clientRegistry) has(id uuid.UUID) bool {
+	return r.count(id) > 0
+}
+
+// count returns how many clients are registered under id
+func (r *clientRegistry) count(id uuid.UUID) int {
+	s := r.shard(id)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.ids[id])
+}
+
+// appendClients appends the clients registered under id to buf. The
+// result is a snapshot, safe to use after the shard changes.
+func (r *clientRegistry) appendClients(buf []*Client, id uuid.UUID) []*Client {
+	s := r.shard(id)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for client := range s.ids[id] {
+		buf = append(buf, client)
+	}
+	return buf
+}
+
+// appendAll appends every registration's client to buf, one shard at a
+// time. A client registered under several IDs is appended for each.
+func (r *clientRegistry) appendAll(buf []*Client) []*Client {
+	for i := range r.shards {
+		s := &r.shards[i]
+		s.mu.RLock()
+		for _, clients := range s.ids {
+			for client := range clients {
+				buf = append(buf, client)
+			}
+		}
+		s.mu.RUnlock()
+	}
+	return buf
+}
+
+// stats returns how many IDs have clients and how many registrations
+// there are in all
+func (r *clientRegistry) stats() (ids, registrations int) {
+	for i := range r.shards {
+		s := &r.shards[i]
+		s.mu.RLock()
+		ids += len(s.ids)
+		for _, clients := range s.ids {
+			registrations += len(clients)
+		}
+		s.mu.RUnlock()
+	}
+	return ids, registrations
+}
+
+// registrations is the reverse of a registry for one client: the IDs it is
+// registered under, so it can be removed without scanning every ID. The
+// zero value is empty.
+type registrations struct {
+	mu  sync.Mutex
+	ids map[uuid.UUID]bool
+}
+
+func (r *registrations) add(id uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ids == nil {
+		r.ids = make(map[uuid.UUID]bool)
+	}
+	r.ids[id] = true
+}
+
+func (r *registrations) remove(id uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.ids, id)
+}
+
+// list returns the IDs
+func (r *registrations) list() []uuid.UUID {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]uuid.UUID, 0, len(r.ids))
+	for id := range r.ids {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// take returns the IDs and forgets them
+func (r *registrations) take() []uuid.UUID {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]uuid.UUID, 0, len(r.ids))
+	for id := range r.ids {
+		ids = append(ids, id)
+	}
+	r.ids = nil
+	return ids
+}
+
+// fanoutPool spreads the recipients of large broadcasts over workers. The
+// caller waits until every recipient is queued, so a broadcast finishes
+// before the next starts and clients still see events in order.
+type fanoutPool struct {
+	workers int
+	jobs    chan fanoutJob
+	start   sync.Once
+}
+
+type fanoutJob struct {
+	clients []*Client
+	frames  *frameSet
+	intent  Intents
+	done    *sync.WaitGroup
+}
+
+// newFanoutPool creates a pool of workers goroutines, started on first use
+func newFanoutPool(workers int) *fanoutPool {
+	return &fanoutPool{workers: workers, jobs: make(chan fanoutJob)}
+}
+
+// send queues the frame for every client that wants intent
+func (p *fanoutPool) send(clients []*Client, frames *frameSet, intent Intents) {
+	if p.workers <= 1 || len(clients) <= fanoutBatch {
+		sendFrames(clients, frames, intent)
+		return
+	}
+	p.start.Do(func() {
+		for i := 0; i < p.workers; i++ {
+			go p.work()
+		}
+	})
+
+	// The caller takes the first batch itself rather than sit idle
+	var wg sync.WaitGroup
+	for start := fanoutBatch; start < len(clients); start += fanoutBatch {
+		wg.Add(1)
+		p.jobs <- fanoutJob{
+			clients: clients[start:min(start+fanoutBatch, len(clients))],
+			frames:  frames,
+			intent:  intent,
+			done:    &wg,
+		}
+	}
+	sendFrames(clients[:fanoutBatch], frames, intent)
+	wg.Wait()
+}
+
+func (p *fanoutPool) work() {
+	for job := range p.jobs {
+		sendFrames(job.clients, job.frames, job.intent)
+		job.done.Done()
+	}
+}
+
+// sendFrames queues the broadcast in each client's format, skipping clients
+// that didn't ask for the event's intent
+func sendFrames(clients []*Client, frames *frameSet, intent Intents) {
+	for _, client := range clients {
+		if client.Intents().wants(intent) {
+			queueFrame(client, frames)
+		}
+	}
+}
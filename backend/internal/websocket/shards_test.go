@@ -0,0 +1,91 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRegistry(t *testing.T) {
+	r := newClientRegistry()
+	id := uuid.New()
+	a := &Client{ID: "a"}
+	b := &Client{ID: "b"}
+
+	r.add(id, a)
+	r.add(id, b)
+	r.add(uuid.New(), a)
+	assert.True(t, r.contains(id, a))
+	assert.Equal(t, 2, r.count(id))
+	assert.ElementsMatch(t, []*Client{a, b}, r.appendClients(nil, id))
+	assert.Len(t, r.appendAll(nil), 3)
+
+	ids, registrations := r.stats()
+	assert.Equal(t, 2, ids)
+	assert.Equal(t, 3, registrations)
+
+	r.remove(id, a)
+	r.remove(id, b)
+	assert.False(t, r.has(id))
+	ids, _ = r.stats()
+	assert.Equal(t, 1, ids, "IDs without clients are forgotten")
+}
+
+func TestHub_UnregisterRemovesOnlyOwnSubscriptions(t *testing.T) {
+	hub := NewHub()
+	serverID, channelID := uuid.New(), uuid.New()
+	leaving := &Client{UserID: uuid.New(), send: make(chan []byte, 8)}
+	staying := &Client{UserID: uuid.New(), send: make(chan []byte, 8)}
+
+	for _, client := range []*Client{leaving, staying} {
+		hub.registerClient(client)
+		hub.SubscribeServer(client, serverID)
+		hub.SubscribeChannel(client, channelID)
+	}
+	hub.SubscribeChannel(leaving, uuid.New())
+	hub.unregisterClient(leaving)
+
+	assert.False(t, hub.servers.contains(serverID, leaving))
+	assert.False(t, hub.channels.contains(channelID, leaving))
+	assert.True(t, hub.servers.contains(serverID, staying))
+	assert.True(t, hub.channels.contains(channelID, staying))
+	ids, _ := hub.channels.stats()
+	assert.Equal(t, 1, ids)
+	assert.Empty(t, leaving.hubChannels.list())
+}
+
+func TestHub_PresenceGoesToTheUsersServers(t *testing.T) {
+	hub := NewHub()
+	theirs, other := uuid.New(), uuid.New()
+	client := &Client{UserID: uuid.New(), send: make(chan []byte, 8)}
+	hub.clients.add(client.UserID, client)
+	hub.SubscribeServer(client, theirs)
+	hub.SubscribeServer(&Client{UserID: uuid.New()}, other)
+
+	hub.broadcastPresenceLocal(offlinePresence(client.UserID))
+	require.Len(t, hub.broadcast, 1)
+	event := <-hub.broadcast
+	assert.Equal(t, EventTypePresenceUpdate, event.Type)
+	assert.Equal(t, theirs, *event.ServerID)
+}
+
+func TestFanoutPool_DeliversEveryBatchInOrder(t *testing.T) {
+	pool := newFanoutPool(4)
+	clients := make([]*Client, 3*fanoutBatch+10)
+	for i := range clients {
+		clients[i] = &Client{send: make(chan []byte, 4)}
+	}
+
+	for _, content := range []string{"1", "2", "3"} {
+		pool.send(clients, newFrameSet([]byte(content)), 0)
+	}
+
+	for _, client := range clients {
+		require.Len(t, client.send, 3)
+		assert.Equal(t, "1", string(<-client.send))
+		assert.Equal(t, "2", string(<-client.send))
+		assert.Equal(t, "3", string(<-client.send))
+	}
+}
@@ -0,0 +1,70 @@
+package websocket
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// EventSubscriptionsUpdate answers UPDATE_SUBSCRIPTIONS with what the
+// connection now receives
+const EventSubscriptionsUpdate = "SUBSCRIPTIONS_UPDATE"
+
+// SubscriptionsData is the SUBSCRIPTIONS_UPDATE payload
+type SubscriptionsData struct {
+	Intents  uint64      `json:"intents"`
+	Servers  []uuid.UUID `json:"servers"`
+	Channels []uuid.UUID `json:"channels"`
+	Nonce    string      `json:"nonce,omitempty"`
+}
+
+// handleUpdateSubscriptions changes which events the hub sends this
+// connection: its intents and the servers and channels it follows.
+// Unsubscribing from a busy server stops its events before fan-out
+// rather than having the client drop them.
+func (g *Gateway) handleUpdateSubscriptions(client *Client, msg *Message) {
+	var data subscriptionsPayload
+	if msg.Data != nil {
+		json.Unmarshal(msg.Data, &data)
+	}
+
+	if data.Intents != nil {
+		client.SetIntents(Intents(*data.Intents))
+	}
+	if sub := data.Subscribe; sub != nil {
+		for _, id := range sub.Servers {
+			client.SubscribeServer(uuid.MustParse(id))
+			g.wsMetrics.ServerSubscribed()
+		}
+		for _, id := range sub.Channels {
+			client.SubscribeChannel(uuid.MustParse(id))
+			g.wsMetrics.ChannelSubscribed()
+		}
+	}
+	if unsub := data.Unsubscribe; unsub != nil {
+		for _, id := range unsub.Servers {
+			client.UnsubscribeServer(uuid.MustParse(id))
+			g.wsMetrics.ServerUnsubscribed()
+		}
+		for _, id := range unsub.Channels {
+			client.UnsubscribeChannel(uuid.MustParse(id))
+			g.wsMetrics.ChannelUnsubscribed()
+		}
+	}
+
+	reply := SubscriptionsData{Intents: uint64(client.Intents()), Nonce: data.Nonce}
+	reply.Servers, reply.Channels = client.subscriptions()
+	if reply.Servers == nil {
+		reply.Servers = []uuid.UUID{}
+	}
+	if reply.Channels == nil {
+		reply.Channels = []uuid.UUID{}
+	}
+
+	replyData, _ := json.Marshal(reply)
+	g.dispatch(client, &Message{
+		Op:   OpDispatch,
+		Type: EventSubscriptionsUpdate,
+		Data: replyData,
+	})
+}
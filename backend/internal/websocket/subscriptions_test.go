@@ -0,0 +1,36 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGateway_UpdateSubscriptionsQueuesReply(t *testing.T) {
+	gateway := NewGateway(NewHub(), nil, nil)
+	client := NewClient(NewHub(), nil, uuid.New(), "alice", "session", "web")
+	serverID := uuid.New()
+
+	request, _ := json.Marshal(map[string]interface{}{
+		"subscribe": map[string]interface{}{"servers": []string{serverID.String()}},
+		"nonce":     "s-1",
+	})
+	gateway.handleUpdateSubscriptions(client, &Message{Op: OpUpdateSubscriptions, Data: request})
+	assert.True(t, client.IsSubscribedToServer(serverID))
+
+	// The write pump sequences the reply and keeps it for resume
+	require.Len(t, client.send, 1)
+	var reply Message
+	require.NoError(t, json.Unmarshal(<-client.send, &reply))
+	assert.Equal(t, EventSubscriptionsUpdate, reply.Type)
+	assert.Zero(t, reply.Sequence)
+
+	var data SubscriptionsData
+	require.NoError(t, json.Unmarshal(reply.Data, &data))
+	assert.Equal(t, []uuid.UUID{serverID}, data.Servers)
+	assert.Empty(t, data.Channels)
+	assert.Equal(t, "s-1", data.Nonce)
+}
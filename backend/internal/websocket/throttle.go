@@ -0,0 +1,45 @@
+package websocket
+
+import (
+	"github.com/google/uuid"
+)
+
+// ServerEventThrottle caps the gateway events each server causes, so one
+// busy or abusive server can't starve the others of gateway capacity. The
+// ServerThrottleService implements it.
+type ServerEventThrottle interface {
+	// AllowServerEvent counts an event for the server and reports whether
+	// it is within the server's limit
+	AllowServerEvent(serverID uuid.UUID) bool
+}
+
+// serverRateLimited answers server-scoped ops from servers over their event
+// limit. The op is dropped and the connection stays open.
+func serverRateLimited(op int) *ProtocolError {
+	return &ProtocolError{Code: CloseRateLimited, Op: op, Field: "guild_id", Message: "this server is too busy right now, try again shortly"}
+}
+
+// SetServerThrottle refuses member requests for servers that are over their
+// event limit
+func (g *Gateway) SetServerThrottle(throttle ServerEventThrottle) {
+	g.serverThrottle = throttle
+}
+
+// SetServerThrottle drops presence updates for servers that are over their
+// event limit. Presence is refreshed often enough that members catch up
+// once the server is back under it.
+func (b *EventBridge) SetServerThrottle(throttle ServerEventThrottle) {
+	b.throttle = throttle
+}
+
+// SetServerThrottle drops presence updates for servers that are over their
+// event limit
+func (b *DistributedEventBridge) SetServerThrottle(throttle ServerEventThrottle) {
+	b.throttle = throttle
+}
+
+// allowServerEvent reports whether throttle lets an event for the server
+// through. A nil throttle allows everything.
+func allowServerEvent(throttle ServerEventThrottle, serverID uuid.UUID) bool {
+	return throttle == nil || throttle.AllowServerEvent(serverID)
+}
@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/events"
+)
+
+// stubServerThrottle refuses events for the servers in over
+type stubServerThrottle struct {
+	mu      sync.Mutex
+	over    map[uuid.UUID]bool
+	counted []uuid.UUID
+}
+
+func (s *stubServerThrottle) AllowServerEvent(serverID uuid.UUID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counted = append(s.counted, serverID)
+	return !s.over[serverID]
+}
+
+func TestEventBridge_ThrottlesPresence(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	busy, quiet := uuid.New(), uuid.New()
+	throttle := &stubServerThrottle{over: map[uuid.UUID]bool{busy: true}}
+	bus := events.NewBus()
+	NewEventBridge(hub, bus).SetServerThrottle(throttle)
+
+	client := &Client{
+		ID:       uuid.New().String(),
+		UserID:   uuid.New(),
+		hub:      hub,
+		send:     make(chan []byte, 256),
+		servers:  make(map[uuid.UUID]bool),
+		channels: make(map[uuid.UUID]bool),
+	}
+	hub.register <- client
+	time.Sleep(50 * time.Millisecond)
+	hub.SubscribeServer(client, busy)
+	hub.SubscribeServer(client, quiet)
+
+	bus.Publish(events.PresenceUpdate, &PresenceEventData{
+		UserID:     uuid.New(),
+		Status:     "online",
+		Activities: []string{},
+		ServerIDs:  []uuid.UUID{busy, quiet},
+	})
+
+	select {
+	case data := <-client.send:
+		var event Event
+		require.NoError(t, json.Unmarshal(data, &event))
+		assert.Equal(t, EventTypePresenceUpdate, event.Type)
+	case <-time.After(time.Second):
+		t.Fatal("Did not receive presence update event")
+	}
+	select {
+	case <-client.send:
+		t.Fatal("only the server under its limit should get the update")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	throttle.mu.Lock()
+	defer throttle.mu.Unlock()
+	assert.ElementsMatch(t, []uuid.UUID{busy, quiet}, throttle.counted)
+}
+
+func TestAllowServerEvent(t *testing.T) {
+	serverID := uuid.New()
+	assert.True(t, allowServerEvent(nil, serverID), "nothing is throttled without a throttle")
+	assert.False(t, allowServerEvent(&stubServerThrottle{over: map[uuid.UUID]bool{serverID: true}}, serverID))
+
+	perr := serverRateLimited(OpRequestGuildMembers)
+	assert.Equal(t, CloseRateLimited, perr.Code)
+	assert.False(t, perr.Fatal, "the connection stays open")
+}
@@ -0,0 +1,208 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hearth/internal/auth"
+	"hearth/internal/services"
+)
+
+const (
+	// EventTokenRefreshed answers a TOKEN_REFRESH with the new token pair
+	EventTokenRefreshed = "TOKEN_REFRESHED"
+	// EventTokenRefreshError answers a TOKEN_REFRESH that was refused
+	EventTokenRefreshError = "TOKEN_REFRESH_ERROR"
+)
+
+const tokenRefreshTimeout = 5 * time.Second
+
+// tokenExpiryGrace is how long past its access token's expiry a connection
+// may stay open, so clients whose clocks run behind have time to refresh
+const tokenExpiryGrace = 30 * time.Second
+
+// GatewaySessions checks gateway tokens against the login sessions store
+// and refreshes them. The SessionService implements it.
+type GatewaySessions interface {
+	Validate(ctx context.Context, claims *auth.Claims) error
+	Refresh(ctx context.Context, claims *auth.Claims, refreshToken string) (*services.AuthTokens, error)
+}
+
+// TokenRefreshedData carries the new pair. The old refresh token no longer
+// works, so clients must store both.
+type TokenRefreshedData struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	Nonce        string `json:"nonce,omitempty"`
+}
+
+// TokenRefreshErrorData says why a TOKEN_REFRESH was refused. Code is
+// stable for clients to switch on; Message is for display.
+type TokenRefreshErrorData struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Nonce   string `json:"nonce,omitempty"`
+}
+
+// SetSessions refuses connections whose login session has been logged out
+// and enables refreshing the access token with the TOKEN_REFRESH op
+func (g *Gateway) SetSessions(sessions GatewaySessions) {
+	g.authSessions = sessions
+}
+
+// validateSession checks a connecting token against the sessions store.
+// It returns the close code to refuse the connection with, or 0.
+func (g *Gateway) validateSession(claims *auth.Claims) int {
+	if g.authSessions == nil {
+		return 0
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), tokenRefreshTimeout)
+	defer cancel()
+
+	err := g.authSessions.Validate(ctx, claims)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, services.ErrSessionRevoked), errors.Is(err, auth.ErrRevokedToken):
+		return CloseSessionRevoked
+	case errors.Is(err, services.ErrAccountSuspended):
+		return CloseAccountSuspended
+	default:
+		log.Printf("[Gateway] Failed to check session of user %s: %v", claims.UserID, err)
+		return CloseUnknownError
+	}
+}
+
+func (g *Gateway) handleTokenRefresh(client *Client, session *Session, msg *Message) {
+	var data tokenRefreshPayload
+	if msg.Data != nil {
+		json.Unmarshal(msg.Data, &data)
+	}
+
+	reply, closeCode := g.refreshToken(session, data)
+	g.dispatch(client, reply)
+	if closeCode == 0 {
+		return
+	}
+	// Closed the way the device limit closes connections, which is safe
+	// alongside the write pump. The close code carries the reason too, in
+	// case the reply is still queued.
+	if device := g.devices.get(session.UserID, client.ID); device != nil {
+		device.close(closeCode, closeReason(closeCode))
+	}
+}
+
+// closeReason is the close frame reason for a code refusing a login session
+func closeReason(code int) string {
+	if code == CloseAccountSuspended {
+		return "account suspended"
+	}
+	return "session revoked"
+}
+
+// refreshToken swaps the refresh token for a new pair and moves the
+// connection's token expiry forward. A refresh token that was already used
+// revokes its session, as it would over REST, and the connection is closed
+// with the returned code.
+func (g *Gateway) refreshToken(session *Session, data tokenRefreshPayload) (*Message, int) {
+	if g.authSessions == nil || g.jwtService == nil {
+		return tokenRefreshError(data.Nonce, "unavailable", "refreshing over the gateway is not enabled"), 0
+	}
+
+	claims, err := g.jwtService.ValidateRefreshToken(data.RefreshToken)
+	if err != nil {
+		return tokenRefreshError(data.Nonce, "invalid_refresh_token", "invalid or expired refresh token"), 0
+	}
+	// Refresh tokens of other logins are refused before they're used, so a
+	// connection can't swap into someone else's session or spend their token
+	if claims.UserID != session.UserID || !sameLoginSession(claims.SessionID, session.AuthSessionID) {
+		return tokenRefreshError(data.Nonce, "session_mismatch", "refresh token belongs to another session"), 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tokenRefreshTimeout)
+	defer cancel()
+
+	tokens, err := g.authSessions.Refresh(ctx, claims, data.RefreshToken)
+	switch {
+	case err == nil:
+	case errors.Is(err, services.ErrRefreshTokenReused):
+		return tokenRefreshError(data.Nonce, "refresh_token_reused", "refresh token was already used, so its session has been logged out"), CloseSessionRevoked
+	case errors.Is(err, services.ErrSessionRevoked), errors.Is(err, auth.ErrRevokedToken):
+		return tokenRefreshError(data.Nonce, "session_revoked", "session has been logged out"), CloseSessionRevoked
+	case errors.Is(err, services.ErrAccountSuspended):
+		return tokenRefreshError(data.Nonce, "account_suspended", "account has been suspended"), CloseAccountSuspended
+	default:
+		log.Printf("[Gateway] Failed to refresh token of user %s: %v", session.UserID, err)
+		return tokenRefreshError(data.Nonce, "internal_error", "failed to refresh token"), 0
+	}
+
+	if access, err := g.jwtService.ValidateAccessToken(tokens.AccessToken); err == nil {
+		session.AuthSessionID = access.SessionID
+		session.setTokenExpiry(access)
+	}
+
+	refreshed, _ := json.Marshal(TokenRefreshedData{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		Nonce:        data.Nonce,
+	})
+	return &Message{
+		Op:   OpDispatch,
+		Type: EventTokenRefreshed,
+		Data: refreshed,
+	}, 0
+}
+
+// sameLoginSession reports whether a refresh token's session is the one
+// the connection was opened with. Connections opened with a token from
+// before sessions were tracked may refresh with one too.
+func sameLoginSession(refresh, current *uuid.UUID) bool {
+	if current == nil {
+		return refresh == nil
+	}
+	return refresh != nil && *refresh == *current
+}
+
+func tokenRefreshError(nonce, code, message string) *Message {
+	errData, _ := json.Marshal(TokenRefreshErrorData{Code: code, Message: message, Nonce: nonce})
+	return &Message{
+		Op:   OpDispatch,
+		Type: EventTokenRefreshError,
+		Data: errData,
+	}
+}
+
+// setTokenExpiry records when the connection's access token expires
+func (s *Session) setTokenExpiry(claims *auth.Claims) {
+	if claims.ExpiresAt == nil {
+		s.tokenExpiry.Store(0)
+		return
+	}
+	s.tokenExpiry.Store(claims.ExpiresAt.UnixNano())
+}
+
+// TokenExpiresAt is when the connection's access token expires, or zero
+// if it doesn't
+func (s *Session) TokenExpiresAt() time.Time {
+	if nanos := s.tokenExpiry.Load(); nanos != 0 {
+		return time.Unix(0, nanos)
+	}
+	return time.Time{}
+}
+
+// tokenExpired reports whether the connection has outlived its access
+// token by more than the grace period
+func (g *Gateway) tokenExpired(session *Session, now time.Time) bool {
+	if !g.config.EnforceTokenExpiry {
+		return false
+	}
+	expires := session.TokenExpiresAt()
+	return !expires.IsZero() && now.After(expires.Add(tokenExpiryGrace))
+}
@@ -0,0 +1,179 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"hearth/internal/auth"
+	"hearth/internal/services"
+)
+
+// fakeGatewaySessions issues pairs in the refresh token's session, or
+// fails with err
+type fakeGatewaySessions struct {
+	jwt       *auth.JWTService
+	err       error
+	refreshed int
+}
+
+func (f *fakeGatewaySessions) Validate(ctx context.Context, claims *auth.Claims) error {
+	return f.err
+}
+
+func (f *fakeGatewaySessions) Refresh(ctx context.Context, claims *auth.Claims, refreshToken string) (*services.AuthTokens, error) {
+	f.refreshed++
+	if f.err != nil {
+		return nil, f.err
+	}
+	access, refresh, err := f.jwt.GenerateSessionTokenPair(claims.UserID, claims.Username, claims.Bot, auth.TokenFamily{SessionID: *claims.SessionID})
+	if err != nil {
+		return nil, err
+	}
+	return &services.AuthTokens{AccessToken: access, RefreshToken: refresh, ExpiresIn: f.jwt.GetExpirySeconds()}, nil
+}
+
+func newTokenRefreshTest(t *testing.T) (*Gateway, *fakeGatewaySessions, *Session, string) {
+	jwtService := auth.NewJWTService("test-secret", 15*time.Minute, 24*time.Hour)
+	sessions := &fakeGatewaySessions{jwt: jwtService}
+	gateway := NewGateway(NewHub(), jwtService, nil)
+	gateway.SetSessions(sessions)
+
+	userID, loginID := uuid.New(), uuid.New()
+	_, refresh, err := jwtService.GenerateSessionTokenPair(userID, "alice", false, auth.TokenFamily{SessionID: loginID})
+	require.NoError(t, err)
+	session := &Session{UserID: userID, AuthSessionID: &loginID, Sequence: 4}
+	return gateway, sessions, session, refresh
+}
+
+func TestGateway_RefreshToken(t *testing.T) {
+	gateway, sessions, session, refresh := newTokenRefreshTest(t)
+
+	reply, closeCode := gateway.refreshToken(session, tokenRefreshPayload{RefreshToken: refresh, Nonce: "r-1"})
+	assert.Zero(t, closeCode)
+	assert.Equal(t, EventTokenRefreshed, reply.Type)
+
+	var data TokenRefreshedData
+	require.NoError(t, json.Unmarshal(reply.Data, &data))
+	assert.Equal(t, "r-1", data.Nonce)
+	assert.Equal(t, 900, data.ExpiresIn)
+	access, err := gateway.jwtService.ValidateAccessToken(data.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, session.UserID, access.UserID)
+	assert.Equal(t, access.ExpiresAt.Unix(), session.TokenExpiresAt().Unix(), "the connection lives on with the new token")
+	assert.NotEmpty(t, data.RefreshToken)
+	assert.Equal(t, 1, sessions.refreshed)
+}
+
+func TestGateway_HandleTokenRefreshQueuesReply(t *testing.T) {
+	gateway, sessions, session, refresh := newTokenRefreshTest(t)
+	sessions.err = services.ErrRefreshTokenReused
+	client := NewClient(NewHub(), nil, session.UserID, "alice", "session", "web")
+	gateway.devices.add(session.UserID, &liveDevice{Device: Device{ID: client.ID}})
+
+	request, _ := json.Marshal(tokenRefreshPayload{RefreshToken: refresh, Nonce: "r-2"})
+	gateway.handleTokenRefresh(client, session, &Message{Op: OpTokenRefresh, Data: request})
+
+	// The write pump sequences the reply and keeps it for resume
+	require.Len(t, client.send, 1)
+	var reply Message
+	require.NoError(t, json.Unmarshal(<-client.send, &reply))
+	assert.Equal(t, EventTokenRefreshError, reply.Type)
+	assert.Zero(t, reply.Sequence)
+}
+
+func TestGateway_RefreshToken_OtherSession(t *testing.T) {
+	gateway, sessions, session, _ := newTokenRefreshTest(t)
+
+	_, otherLogin, err := gateway.jwtService.GenerateSessionTokenPair(session.UserID, "alice", false, auth.TokenFamily{SessionID: uuid.New()})
+	require.NoError(t, err)
+	_, otherUser, err := gateway.jwtService.GenerateSessionTokenPair(uuid.New(), "mallory", false, auth.TokenFamily{SessionID: *session.AuthSessionID})
+	require.NoError(t, err)
+
+	for _, token := range []string{otherLogin, otherUser} {
+		reply, closeCode := gateway.refreshToken(session, tokenRefreshPayload{RefreshToken: token})
+		assert.Zero(t, closeCode)
+		assert.Equal(t, EventTokenRefreshError, reply.Type)
+		var data TokenRefreshErrorData
+		require.NoError(t, json.Unmarshal(reply.Data, &data))
+		assert.Equal(t, "session_mismatch", data.Code)
+	}
+	assert.Zero(t, sessions.refreshed, "the other session's token isn't spent")
+}
+
+func TestGateway_RefreshToken_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		token     string
+		code      string
+		closeCode int
+	}{
+		{"reused", services.ErrRefreshTokenReused, "", "refresh_token_reused", CloseSessionRevoked},
+		{"revoked", services.ErrSessionRevoked, "", "session_revoked", CloseSessionRevoked},
+		{"logged out everywhere", auth.ErrRevokedToken, "", "session_revoked", CloseSessionRevoked},
+		{"suspended", &services.AccountSuspendedError{}, "", "account_suspended", CloseAccountSuspended},
+		{"database down", errors.New("connection refused"), "", "internal_error", 0},
+		{"not a refresh token", nil, "garbage", "invalid_refresh_token", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway, sessions, session, refresh := newTokenRefreshTest(t)
+			sessions.err = tt.err
+			if tt.token != "" {
+				refresh = tt.token
+			}
+
+			reply, closeCode := gateway.refreshToken(session, tokenRefreshPayload{RefreshToken: refresh, Nonce: "r-1"})
+			assert.Equal(t, tt.closeCode, closeCode)
+			assert.Equal(t, EventTokenRefreshError, reply.Type)
+			var data TokenRefreshErrorData
+			require.NoError(t, json.Unmarshal(reply.Data, &data))
+			assert.Equal(t, tt.code, data.Code)
+			assert.Equal(t, "r-1", data.Nonce)
+		})
+	}
+}
+
+func TestGateway_RefreshToken_Unavailable(t *testing.T) {
+	gateway := NewGateway(NewHub(), nil, nil)
+	reply, _ := gateway.refreshToken(&Session{UserID: uuid.New()}, tokenRefreshPayload{RefreshToken: "token"})
+	var data TokenRefreshErrorData
+	require.NoError(t, json.Unmarshal(reply.Data, &data))
+	assert.Equal(t, "unavailable", data.Code)
+}
+
+func TestGateway_ValidateSession(t *testing.T) {
+	gateway, sessions, _, _ := newTokenRefreshTest(t)
+	claims := &auth.Claims{UserID: uuid.New()}
+
+	assert.Zero(t, gateway.validateSession(claims))
+	sessions.err = services.ErrSessionRevoked
+	assert.Equal(t, CloseSessionRevoked, gateway.validateSession(claims))
+	sessions.err = &services.AccountSuspendedError{}
+	assert.Equal(t, CloseAccountSuspended, gateway.validateSession(claims))
+	sessions.err = errors.New("connection refused")
+	assert.Equal(t, CloseUnknownError, gateway.validateSession(claims))
+}
+
+func TestGateway_TokenExpired(t *testing.T) {
+	config := DefaultGatewayConfig()
+	gateway := NewGateway(NewHub(), nil, config)
+	session := &Session{}
+	now := time.Now()
+	session.tokenExpiry.Store(now.UnixNano())
+
+	assert.False(t, gateway.tokenExpired(session, now.Add(time.Hour)), "expiry is only enforced when configured")
+
+	config.EnforceTokenExpiry = true
+	assert.False(t, gateway.tokenExpired(session, now.Add(tokenExpiryGrace/2)))
+	assert.True(t, gateway.tokenExpired(session, now.Add(2*tokenExpiryGrace)))
+	assert.False(t, gateway.tokenExpired(&Session{}, now.Add(time.Hour)), "tokens without expiry never expire")
+}
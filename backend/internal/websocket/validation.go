@@ -0,0 +1,545 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Gateway close codes (see docs/api/WEBSOCKET.md)
+const (
+	CloseUnknownError         = 4000
+	CloseUnknownOpcode        = 4001
+	CloseDecodeError          = 4002
+	CloseNotAuthenticated     = 4003
+	CloseAuthenticationFailed = 4004
+	CloseAlreadyAuthenticated = 4005
+	CloseInvalidSession       = 4006
+	CloseInvalidSeq           = 4007
+	CloseRateLimited          = 4008
+	CloseSessionTimeout       = 4009
+	CloseInvalidIntents       = 4013
+	ClosePayloadTooLarge      = 4015
+	CloseSessionLimit         = 4016
+	CloseSessionRevoked       = 4017
+	CloseTokenExpired         = 4018
+	CloseAccountSuspended     = 4019
+	CloseSlowConsumer         = 4020
+)
+
+// EventError is the dispatch type of structured error frames
+const EventError = "ERROR"
+
+// ProtocolError describes an inbound payload that failed validation.
+// It is sent to the client as the data of an ERROR dispatch; fatal errors
+// are followed by a close frame carrying Code.
+type ProtocolError struct {
+	Code    int    `json:"code"`
+	Op      int    `json:"op"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+	Fatal   bool   `json:"-"`
+}
+
+func (e *ProtocolError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("op %d: %s: %s", e.Op, e.Field, e.Message)
+	}
+	return fmt.Sprintf("op %d: %s", e.Op, e.Message)
+}
+
+// invalidPayload reports a schema violation in an otherwise well-formed frame.
+// These are not fatal: the op is dropped and the connection stays open.
+func invalidPayload(op int, field, message string) *ProtocolError {
+	return &ProtocolError{Code: CloseDecodeError, Op: op, Field: field, Message: message}
+}
+
+// opSchema describes what an inbound opcode is allowed to carry
+type opSchema struct {
+	maxSize  int
+	validate func(op int, d json.RawMessage) *ProtocolError
+}
+
+// inboundSchemas lists every opcode a client may send
+var inboundSchemas = map[int]opSchema{
+	OpDispatch:            {maxSize: 4096, validate: validateClientDispatch},
+	OpHeartbeat:           {maxSize: 128, validate: validateHeartbeat},
+	OpIdentify:            {maxSize: 4096, validate: validateIdentify},
+	OpPresenceUpdate:      {maxSize: 4096, validate: validatePresence},
+	OpVoiceStateUpdate:    {maxSize: 1024, validate: validateVoiceState},
+	OpResume:              {maxSize: 2048, validate: validateResume},
+	OpRequestGuildMembers: {maxSize: 8192, validate: validateRequestMembers},
+	OpJoinGuild:           {maxSize: 512, validate: validateJoinGuild},
+	OpTokenRefresh:        {maxSize: 2048, validate: validateTokenRefresh},
+	OpUpdateSubscriptions: {maxSize: 16384, validate: validateUpdateSubscriptions},
+	OpMemberListSubscribe: {maxSize: 512, validate: validateMemberListSubscribe},
+}
+
+// inboundFrame mirrors Message but with pointer fields so a missing op
+// can be told apart from op 0
+type inboundFrame struct {
+	Op       *int            `json:"op"`
+	Data     json.RawMessage `json:"d"`
+	Sequence *int64          `json:"s"`
+	Type     string          `json:"t"`
+}
+
+// ValidateInbound decodes and validates a client frame against the opcode
+// schemas. Unknown fields, wrong types and oversized payloads are rejected.
+func ValidateInbound(data []byte) (*Message, *ProtocolError) {
+	if len(data) > maxMessageSize {
+		return nil, &ProtocolError{Code: ClosePayloadTooLarge, Op: -1, Message: "payload too large", Fatal: true}
+	}
+
+	var frame inboundFrame
+	if err := decodeStrict(data, &frame); err != nil {
+		return nil, &ProtocolError{Code: CloseDecodeError, Op: -1, Field: err.field, Message: err.message, Fatal: true}
+	}
+	if frame.Op == nil {
+		return nil, &ProtocolError{Code: CloseDecodeError, Op: -1, Field: "op", Message: "op is required", Fatal: true}
+	}
+
+	op := *frame.Op
+	schema, ok := inboundSchemas[op]
+	if !ok {
+		return nil, &ProtocolError{Code: CloseUnknownOpcode, Op: op, Message: "unknown opcode", Fatal: true}
+	}
+	if len(data) > schema.maxSize {
+		return nil, &ProtocolError{Code: ClosePayloadTooLarge, Op: op, Message: fmt.Sprintf("payload exceeds %d bytes", schema.maxSize), Fatal: true}
+	}
+	if perr := schema.validate(op, frame.Data); perr != nil {
+		return nil, perr
+	}
+
+	msg := &Message{Op: op, Data: frame.Data, Type: frame.Type}
+	if frame.Sequence != nil {
+		msg.Sequence = *frame.Sequence
+	}
+	return msg, nil
+}
+
+// decodeError carries the offending field for structured error frames
+type decodeError struct {
+	field   string
+	message string
+}
+
+// decodeStrict unmarshals a single JSON value, rejecting unknown fields
+// and trailing data. Empty and null payloads decode to the zero value.
+func decodeStrict(data []byte, v interface{}) *decodeError {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return toDecodeError(err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return &decodeError{message: "unexpected data after payload"}
+	}
+	return nil
+}
+
+func toDecodeError(err error) *decodeError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &decodeError{
+			field:   typeErr.Field,
+			message: fmt.Sprintf("expected %s, got %s", typeErr.Type.String(), typeErr.Value),
+		}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return &decodeError{message: "malformed JSON"}
+	}
+
+	// encoding/json has no typed error for unknown fields
+	if msg := err.Error(); strings.HasPrefix(msg, "json: unknown field ") {
+		return &decodeError{
+			field:   strings.Trim(strings.TrimPrefix(msg, "json: unknown field "), `"`),
+			message: "unknown field",
+		}
+	}
+
+	return &decodeError{message: "malformed payload"}
+}
+
+// decodePayload decodes d into v, converting failures to invalid payload errors
+func decodePayload(op int, d json.RawMessage, v interface{}) *ProtocolError {
+	if err := decodeStrict(d, v); err != nil {
+		field := "d"
+		if err.field != "" {
+			field = "d." + err.field
+		}
+		return invalidPayload(op, field, err.message)
+	}
+	return nil
+}
+
+func validateUUID(op int, field, value string, required bool) *ProtocolError {
+	if value == "" {
+		if required {
+			return invalidPayload(op, field, "is required")
+		}
+		return nil
+	}
+	if _, err := uuid.Parse(value); err != nil {
+		return invalidPayload(op, field, "must be a valid id")
+	}
+	return nil
+}
+
+// Per-opcode payload schemas
+
+type identifyProperties struct {
+	OS            string `json:"os,omitempty"`
+	Browser       string `json:"browser,omitempty"`
+	Device        string `json:"device,omitempty"`
+	LegacyOS      string `json:"$os,omitempty"`
+	LegacyBrowser string `json:"$browser,omitempty"`
+	LegacyDevice  string `json:"$device,omitempty"`
+}
+
+type identifyPayload struct {
+	Token          string              `json:"token,omitempty"`
+	Properties     *identifyProperties `json:"properties,omitempty"`
+	Compress       bool                `json:"compress,omitempty"`
+	LargeThreshold int                 `json:"large_threshold,omitempty"`
+	Intents        int64               `json:"intents,omitempty"`
+	Capabilities   int64               `json:"capabilities,omitempty"`
+	Presence       *presencePayload    `json:"presence,omitempty"`
+}
+
+type activityPayload struct {
+	Name      string  `json:"name"`
+	Type      int     `json:"type"`
+	URL       *string `json:"url,omitempty"`
+	State     string  `json:"state,omitempty"`
+	Details   string  `json:"details,omitempty"`
+	CreatedAt int64   `json:"created_at,omitempty"`
+}
+
+type presencePayload struct {
+	Status     string            `json:"status"`
+	Activities []activityPayload `json:"activities,omitempty"`
+	Since      *int64            `json:"since,omitempty"`
+	AFK        bool              `json:"afk,omitempty"`
+}
+
+type voiceStatePayload struct {
+	GuildID   string  `json:"guild_id"`
+	ChannelID *string `json:"channel_id"`
+	SelfMute  bool    `json:"self_mute"`
+	SelfDeaf  bool    `json:"self_deaf"`
+	SelfVideo bool    `json:"self_video,omitempty"`
+}
+
+type resumePayload struct {
+	Token     string `json:"token"`
+	SessionID string `json:"session_id"`
+	Seq       int64  `json:"seq"`
+}
+
+type requestMembersPayload struct {
+	GuildID   string   `json:"guild_id"`
+	Query     string   `json:"query,omitempty"`
+	Limit     int      `json:"limit,omitempty"`
+	Presences bool     `json:"presences,omitempty"`
+	UserIDs   []string `json:"user_ids,omitempty"`
+	Nonce     string   `json:"nonce,omitempty"`
+}
+
+type joinGuildPayload struct {
+	Code  string `json:"code"`
+	Nonce string `json:"nonce,omitempty"`
+}
+
+type tokenRefreshPayload struct {
+	RefreshToken string `json:"refresh_token"`
+	Nonce        string `json:"nonce,omitempty"`
+}
+
+type subscriptionSet struct {
+	Servers  []string `json:"servers,omitempty"`
+	Channels []string `json:"channels,omitempty"`
+}
+
+type subscriptionsPayload struct {
+	Intents     *int64           `json:"intents,omitempty"`
+	Subscribe   *subscriptionSet `json:"subscribe,omitempty"`
+	Unsubscribe *subscriptionSet `json:"unsubscribe,omitempty"`
+	Nonce       string           `json:"nonce,omitempty"`
+}
+
+type memberListPayload struct {
+	GuildID   string  `json:"guild_id"`
+	ChannelID string  `json:"channel_id,omitempty"`
+	Ranges    [][]int `json:"ranges"`
+}
+
+type clientDispatchPayload struct {
+	T string          `json:"t"`
+	D json.RawMessage `json:"d"`
+}
+
+type subscriptionPayload struct {
+	ChannelID string `json:"channel_id,omitempty"`
+	ServerID  string `json:"server_id,omitempty"`
+}
+
+const (
+	maxActivities         = 5
+	maxActivityNameLength = 128
+	maxMemberQueryLength  = 100
+	maxMemberRequestLimit = 1000
+	maxMemberRequestIDs   = 100
+	maxNonceLength        = 32
+	maxInviteCodeLength   = 32
+	maxRefreshTokenLength = 1024
+	maxSubscriptionIDs    = 100
+)
+
+var validPresenceStatuses = map[string]bool{
+	"online":    true,
+	"idle":      true,
+	"dnd":       true,
+	"invisible": true,
+	"offline":   true,
+}
+
+func validateHeartbeat(op int, d json.RawMessage) *ProtocolError {
+	var seq *int64
+	return decodePayload(op, d, &seq)
+}
+
+func validateIdentify(op int, d json.RawMessage) *ProtocolError {
+	var p identifyPayload
+	if perr := decodePayload(op, d, &p); perr != nil {
+		return perr
+	}
+	if p.LargeThreshold != 0 && (p.LargeThreshold < 50 || p.LargeThreshold > 250) {
+		return invalidPayload(op, "d.large_threshold", "must be between 50 and 250")
+	}
+	if p.Intents < 0 {
+		return invalidPayload(op, "d.intents", "must not be negative")
+	}
+	if p.Capabilities < 0 {
+		return invalidPayload(op, "d.capabilities", "must not be negative")
+	}
+	if p.Presence != nil {
+		if perr := p.Presence.validate(op, "d.presence"); perr != nil {
+			return perr
+		}
+	}
+	return nil
+}
+
+func validatePresence(op int, d json.RawMessage) *ProtocolError {
+	var p presencePayload
+	if perr := decodePayload(op, d, &p); perr != nil {
+		return perr
+	}
+	return p.validate(op, "d")
+}
+
+func (p *presencePayload) validate(op int, prefix string) *ProtocolError {
+	if p.Status != "" && !validPresenceStatuses[p.Status] {
+		return invalidPayload(op, prefix+".status", "must be one of online, idle, dnd, invisible, offline")
+	}
+	if len(p.Activities) > maxActivities {
+		return invalidPayload(op, prefix+".activities", fmt.Sprintf("at most %d activities allowed", maxActivities))
+	}
+	for i, a := range p.Activities {
+		if len(a.Name) > maxActivityNameLength {
+			return invalidPayload(op, fmt.Sprintf("%s.activities[%d].name", prefix, i), "too long")
+		}
+		if a.Type < 0 || a.Type > 5 {
+			return invalidPayload(op, fmt.Sprintf("%s.activities[%d].type", prefix, i), "unknown activity type")
+		}
+	}
+	return nil
+}
+
+func validateVoiceState(op int, d json.RawMessage) *ProtocolError {
+	var p voiceStatePayload
+	if perr := decodePayload(op, d, &p); perr != nil {
+		return perr
+	}
+	if perr := validateUUID(op, "d.guild_id", p.GuildID, false); perr != nil {
+		return perr
+	}
+	if p.ChannelID != nil {
+		return validateUUID(op, "d.channel_id", *p.ChannelID, false)
+	}
+	return nil
+}
+
+func validateResume(op int, d json.RawMessage) *ProtocolError {
+	var p resumePayload
+	return decodePayload(op, d, &p)
+}
+
+func validateRequestMembers(op int, d json.RawMessage) *ProtocolError {
+	var p requestMembersPayload
+	if perr := decodePayload(op, d, &p); perr != nil {
+		return perr
+	}
+	if perr := validateUUID(op, "d.guild_id", p.GuildID, true); perr != nil {
+		return perr
+	}
+	if len(p.Query) > maxMemberQueryLength {
+		return invalidPayload(op, "d.query", "too long")
+	}
+	if p.Limit < 0 || p.Limit > maxMemberRequestLimit {
+		return invalidPayload(op, "d.limit", fmt.Sprintf("must be between 0 and %d", maxMemberRequestLimit))
+	}
+	if p.Query != "" && len(p.UserIDs) > 0 {
+		return invalidPayload(op, "d.user_ids", "send either query or user_ids, not both")
+	}
+	if len(p.UserIDs) > maxMemberRequestIDs {
+		return invalidPayload(op, "d.user_ids", fmt.Sprintf("at most %d ids allowed", maxMemberRequestIDs))
+	}
+	for i, id := range p.UserIDs {
+		if perr := validateUUID(op, fmt.Sprintf("d.user_ids[%d]", i), id, true); perr != nil {
+			return perr
+		}
+	}
+	if len(p.Nonce) > maxNonceLength {
+		return invalidPayload(op, "d.nonce", "too long")
+	}
+	return nil
+}
+
+func validateJoinGuild(op int, d json.RawMessage) *ProtocolError {
+	var p joinGuildPayload
+	if perr := decodePayload(op, d, &p); perr != nil {
+		return perr
+	}
+	if p.Code == "" {
+		return invalidPayload(op, "d.code", "is required")
+	}
+	if len(p.Code) > maxInviteCodeLength {
+		return invalidPayload(op, "d.code", "too long")
+	}
+	if len(p.Nonce) > maxNonceLength {
+		return invalidPayload(op, "d.nonce", "too long")
+	}
+	return nil
+}
+
+func validateTokenRefresh(op int, d json.RawMessage) *ProtocolError {
+	var p tokenRefreshPayload
+	if perr := decodePayload(op, d, &p); perr != nil {
+		return perr
+	}
+	if p.RefreshToken == "" {
+		return invalidPayload(op, "d.refresh_token", "is required")
+	}
+	if len(p.RefreshToken) > maxRefreshTokenLength {
+		return invalidPayload(op, "d.refresh_token", "too long")
+	}
+	if len(p.Nonce) > maxNonceLength {
+		return invalidPayload(op, "d.nonce", "too long")
+	}
+	return nil
+}
+
+func validateUpdateSubscriptions(op int, d json.RawMessage) *ProtocolError {
+	var p subscriptionsPayload
+	if perr := decodePayload(op, d, &p); perr != nil {
+		return perr
+	}
+	if p.Intents != nil && (*p.Intents < 0 || !Intents(*p.Intents).Valid()) {
+		return invalidPayload(op, "d.intents", "unknown intents")
+	}
+	if perr := p.Subscribe.validate(op, "d.subscribe"); perr != nil {
+		return perr
+	}
+	if perr := p.Unsubscribe.validate(op, "d.unsubscribe"); perr != nil {
+		return perr
+	}
+	if len(p.Nonce) > maxNonceLength {
+		return invalidPayload(op, "d.nonce", "too long")
+	}
+	return nil
+}
+
+func (s *subscriptionSet) validate(op int, prefix string) *ProtocolError {
+	if s == nil {
+		return nil
+	}
+	lists := []struct {
+		field string
+		ids   []string
+	}{{"servers", s.Servers}, {"channels", s.Channels}}
+	for _, list := range lists {
+		if len(list.ids) > maxSubscriptionIDs {
+			return invalidPayload(op, prefix+"."+list.field, fmt.Sprintf("at most %d ids allowed", maxSubscriptionIDs))
+		}
+		for i, id := range list.ids {
+			if perr := validateUUID(op, fmt.Sprintf("%s.%s[%d]", prefix, list.field, i), id, true); perr != nil {
+				return perr
+			}
+		}
+	}
+	return nil
+}
+
+func validateMemberListSubscribe(op int, d json.RawMessage) *ProtocolError {
+	var p memberListPayload
+	if perr := decodePayload(op, d, &p); perr != nil {
+		return perr
+	}
+	if perr := validateUUID(op, "d.guild_id", p.GuildID, true); perr != nil {
+		return perr
+	}
+	if perr := validateUUID(op, "d.channel_id", p.ChannelID, false); perr != nil {
+		return perr
+	}
+	if len(p.Ranges) > maxMemberListRanges {
+		return invalidPayload(op, "d.ranges", fmt.Sprintf("at most %d ranges allowed", maxMemberListRanges))
+	}
+	for i, r := range p.Ranges {
+		if len(r) != 2 || r[0] < 0 || r[1] < r[0] || r[1]-r[0] >= maxMemberListRangeSize {
+			return invalidPayload(op, fmt.Sprintf("d.ranges[%d]", i),
+				fmt.Sprintf("must be [start, end] covering at most %d rows", maxMemberListRangeSize))
+		}
+	}
+	return nil
+}
+
+func validateClientDispatch(op int, d json.RawMessage) *ProtocolError {
+	var p clientDispatchPayload
+	if perr := decodePayload(op, d, &p); perr != nil {
+		return perr
+	}
+
+	switch p.T {
+	case EventTypeSubscribe, EventTypeUnsubscribe:
+		var sub subscriptionPayload
+		if perr := decodePayload(op, p.D, &sub); perr != nil {
+			perr.Field = strings.Replace(perr.Field, "d", "d.d", 1)
+			return perr
+		}
+		if sub.ChannelID == "" && sub.ServerID == "" {
+			return invalidPayload(op, "d.d", "channel_id or server_id is required")
+		}
+		if perr := validateUUID(op, "d.d.channel_id", sub.ChannelID, false); perr != nil {
+			return perr
+		}
+		return validateUUID(op, "d.d.server_id", sub.ServerID, false)
+	case "":
+		return invalidPayload(op, "d.t", "is required")
+	default:
+		return invalidPayload(op, "d.t", "unknown dispatch type")
+	}
+}
@@ -0,0 +1,155 @@
+package websocket
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateInbound_Valid(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		op      int
+	}{
+		{"heartbeat without seq", `{"op":1}`, OpHeartbeat},
+		{"heartbeat with seq", `{"op":1,"d":42}`, OpHeartbeat},
+		{"heartbeat null seq", `{"op":1,"d":null,"s":null,"t":null}`, OpHeartbeat},
+		{"identify", `{"op":2,"d":{"token":"abc","properties":{"$os":"linux","$browser":"hearth"},"compress":false}}`, OpIdentify},
+		{"identify with capabilities", `{"op":2,"d":{"token":"abc","capabilities":3}}`, OpIdentify},
+		{"identify with presence", `{"op":2,"d":{"presence":{"status":"idle","activities":[{"name":"Chess","type":0}]}}}`, OpIdentify},
+		{"presence", `{"op":3,"d":{"status":"dnd","since":null,"afk":false}}`, OpPresenceUpdate},
+		{"voice leave", `{"op":4,"d":{"guild_id":"7f8a5e0e-5f43-4bf4-8c2b-2a0b3b5f1a11","channel_id":null,"self_mute":false,"self_deaf":false}}`, OpVoiceStateUpdate},
+		{"request members", `{"op":8,"d":{"guild_id":"7f8a5e0e-5f43-4bf4-8c2b-2a0b3b5f1a11","query":"al","limit":10}}`, OpRequestGuildMembers},
+		{"join guild", `{"op":12,"d":{"code":"aB3dE6gH","nonce":"join-1"}}`, OpJoinGuild},
+		{"token refresh", `{"op":13,"d":{"refresh_token":"eyJ.abc.def","nonce":"r-1"}}`, OpTokenRefresh},
+		{"subscribe", `{"op":0,"d":{"t":"SUBSCRIBE","d":{"channel_id":"7f8a5e0e-5f43-4bf4-8c2b-2a0b3b5f1a11"}}}`, OpDispatch},
+		{"member list subscribe", `{"op":15,"d":{"guild_id":"7f8a5e0e-5f43-4bf4-8c2b-2a0b3b5f1a11","ranges":[[0,99],[100,199]]}}`, OpMemberListSubscribe},
+		{"update subscriptions", `{"op":14,"d":{"intents":64,"unsubscribe":{"servers":["7f8a5e0e-5f43-4bf4-8c2b-2a0b3b5f1a11"]},"nonce":"s-1"}}`, OpUpdateSubscriptions},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			msg, perr := ValidateInbound([]byte(tc.payload))
+			require.Nil(t, perr, "unexpected error: %v", perr)
+			assert.Equal(t, tc.op, msg.Op)
+		})
+	}
+}
+
+func TestValidateInbound_Violations(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		code    int
+		field   string
+		fatal   bool
+	}{
+		{"not json", `not json`, CloseDecodeError, "", true},
+		{"trailing data", `{"op":1}{"op":1}`, CloseDecodeError, "", true},
+		{"missing op", `{"d":{}}`, CloseDecodeError, "op", true},
+		{"op wrong type", `{"op":"1"}`, CloseDecodeError, "op", true},
+		{"unknown top-level field", `{"op":1,"x":true}`, CloseDecodeError, "x", true},
+		{"unknown opcode", `{"op":99}`, CloseUnknownOpcode, "", true},
+		{"heartbeat too large", `{"op":1,"d":` + strings.Repeat("1", 200) + `}`, ClosePayloadTooLarge, "", true},
+		{"heartbeat wrong type", `{"op":1,"d":"abc"}`, CloseDecodeError, "d", false},
+		{"identify unknown field", `{"op":2,"d":{"tokne":"abc"}}`, CloseDecodeError, "d.tokne", false},
+		{"identify bad threshold", `{"op":2,"d":{"large_threshold":5}}`, CloseDecodeError, "d.large_threshold", false},
+		{"identify negative capabilities", `{"op":2,"d":{"capabilities":-1}}`, CloseDecodeError, "d.capabilities", false},
+		{"presence bad status", `{"op":3,"d":{"status":"busy"}}`, CloseDecodeError, "d.status", false},
+		{"presence wrong type", `{"op":3,"d":{"afk":"yes"}}`, CloseDecodeError, "d.afk", false},
+		{"voice bad channel id", `{"op":4,"d":{"channel_id":"nope","self_mute":false,"self_deaf":false}}`, CloseDecodeError, "d.channel_id", false},
+		{"request members missing guild", `{"op":8,"d":{"query":""}}`, CloseDecodeError, "d.guild_id", false},
+		{"request members limit", `{"op":8,"d":{"guild_id":"7f8a5e0e-5f43-4bf4-8c2b-2a0b3b5f1a11","limit":5000}}`, CloseDecodeError, "d.limit", false},
+		{"request members query and ids", `{"op":8,"d":{"guild_id":"7f8a5e0e-5f43-4bf4-8c2b-2a0b3b5f1a11","query":"al","user_ids":["7f8a5e0e-5f43-4bf4-8c2b-2a0b3b5f1a12"]}}`, CloseDecodeError, "d.user_ids", false},
+		{"join guild missing code", `{"op":12,"d":{"nonce":"join-1"}}`, CloseDecodeError, "d.code", false},
+		{"join guild code too long", `{"op":12,"d":{"code":"` + strings.Repeat("a", 40) + `"}}`, CloseDecodeError, "d.code", false},
+		{"token refresh missing token", `{"op":13,"d":{"nonce":"r-1"}}`, CloseDecodeError, "d.refresh_token", false},
+		{"dispatch unknown type", `{"op":0,"d":{"t":"NUKE"}}`, CloseDecodeError, "d.t", false},
+		{"subscribe without target", `{"op":0,"d":{"t":"SUBSCRIBE","d":{}}}`, CloseDecodeError, "d.d", false},
+		{"subscribe bad id", `{"op":0,"d":{"t":"SUBSCRIBE","d":{"server_id":"123"}}}`, CloseDecodeError, "d.d.server_id", false},
+		{"member list range too wide", `{"op":15,"d":{"guild_id":"7f8a5e0e-5f43-4bf4-8c2b-2a0b3b5f1a11","ranges":[[0,500]]}}`, CloseDecodeError, "d.ranges[0]", false},
+		{"update subscriptions unknown intents", `{"op":14,"d":{"intents":1048576}}`, CloseDecodeError, "d.intents", false},
+		{"update subscriptions bad id", `{"op":14,"d":{"subscribe":{"channels":["nope"]}}}`, CloseDecodeError, "d.subscribe.channels[0]", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			msg, perr := ValidateInbound([]byte(tc.payload))
+			require.NotNil(t, perr, "expected validation error")
+			assert.Nil(t, msg)
+			assert.Equal(t, tc.code, perr.Code)
+			assert.Equal(t, tc.field, perr.Field)
+			assert.Equal(t, tc.fatal, perr.Fatal)
+		})
+	}
+}
+
+func TestValidateInbound_TooLarge(t *testing.T) {
+	data := []byte(`{"op":1,"d":"` + strings.Repeat("a", maxMessageSize) + `"}`)
+
+	_, perr := ValidateInbound(data)
+	require.NotNil(t, perr)
+	assert.Equal(t, ClosePayloadTooLarge, perr.Code)
+	assert.True(t, perr.Fatal)
+}
+
+func TestProtocolError_Error(t *testing.T) {
+	perr := invalidPayload(OpPresenceUpdate, "d.status", "bad")
+	assert.Equal(t, "op 3: d.status: bad", perr.Error())
+
+	perr = &ProtocolError{Op: 99, Message: "unknown opcode"}
+	assert.Equal(t, "op 99: unknown opcode", perr.Error())
+}
+
+// FuzzValidateInbound throws arbitrary frames at the gateway decoder. It
+// must never panic, and must either accept a frame that fits its opcode's
+// schema or return a well-formed protocol error.
+func FuzzValidateInbound(f *testing.F) {
+	for _, seed := range []string{
+		`{"op":1}`,
+		`{"op":1,"d":42}`,
+		`{"op":2,"d":{"token":"abc","properties":{"$os":"linux"},"capabilities":3}}`,
+		`{"op":3,"d":{"status":"dnd","since":null,"afk":false,"activities":[{"name":"Chess","type":0}]}}`,
+		`{"op":4,"d":{"guild_id":"7f8a5e0e-5f43-4bf4-8c2b-2a0b3b5f1a11","channel_id":null}}`,
+		`{"op":6,"d":{"token":"abc","session_id":"s","seq":10}}`,
+		`{"op":8,"d":{"guild_id":"7f8a5e0e-5f43-4bf4-8c2b-2a0b3b5f1a11","query":"al","limit":10}}`,
+		`{"op":0,"d":{"t":"SUBSCRIBE","d":{"channel_id":"7f8a5e0e-5f43-4bf4-8c2b-2a0b3b5f1a11"}}}`,
+		`{"op":0,"d":{"t":"SUBSCRIBE","d":{"d":{"d":{}}}}}`,
+		`{"op":1}{"op":1}`,
+		`{"op":1e400}`,
+		`[[[[[[[[[[[[[[[[`,
+		"\x00\xff",
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msg, perr := ValidateInbound(data)
+		if perr != nil {
+			if msg != nil {
+				t.Fatalf("got both a message and an error: %v", perr)
+			}
+			switch perr.Code {
+			case CloseDecodeError, CloseUnknownOpcode, ClosePayloadTooLarge:
+			default:
+				t.Fatalf("unexpected close code %d", perr.Code)
+			}
+			if perr.Message == "" {
+				t.Fatal("protocol error without a message")
+			}
+			_ = perr.Error()
+			return
+		}
+
+		schema, ok := inboundSchemas[msg.Op]
+		if !ok {
+			t.Fatalf("accepted unknown opcode %d", msg.Op)
+		}
+		if len(data) > schema.maxSize {
+			t.Fatalf("accepted %d bytes for op %d, limit is %d", len(data), msg.Op, schema.maxSize)
+		}
+	})
+}
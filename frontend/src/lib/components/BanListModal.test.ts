@@ -58,7 +58,7 @@ const mockBans = [
 describe('BanListModal', () => {
 	beforeEach(() => {
 		vi.clearAllMocks();
-		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue([]);
+		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue({ items: [] });
 	});
 
 	afterEach(() => {
@@ -78,7 +78,7 @@ describe('BanListModal', () => {
 	});
 
 	it('renders with server name when open', async () => {
-		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue([]);
+		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue({ items: [] });
 		
 		render(BanListModal, {
 			props: {
@@ -93,7 +93,7 @@ describe('BanListModal', () => {
 	});
 
 	it('loads bans when modal opens', async () => {
-		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue(mockBans);
+		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue({ items: mockBans });
 
 		render(BanListModal, {
 			props: {
@@ -110,7 +110,7 @@ describe('BanListModal', () => {
 
 	it('displays loading state while fetching bans', async () => {
 		(api.get as ReturnType<typeof vi.fn>).mockImplementation(
-			() => new Promise((resolve) => setTimeout(() => resolve([]), 100))
+			() => new Promise((resolve) => setTimeout(() => resolve({ items: [] }), 100))
 		);
 
 		render(BanListModal, {
@@ -125,7 +125,7 @@ describe('BanListModal', () => {
 	});
 
 	it('displays empty state when no bans', async () => {
-		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue([]);
+		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue({ items: [] });
 
 		render(BanListModal, {
 			props: {
@@ -142,7 +142,7 @@ describe('BanListModal', () => {
 	});
 
 	it('displays banned users list', async () => {
-		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue(mockBans);
+		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue({ items: mockBans });
 
 		render(BanListModal, {
 			props: {
@@ -160,7 +160,7 @@ describe('BanListModal', () => {
 	});
 
 	it('displays ban reasons', async () => {
-		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue(mockBans);
+		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue({ items: mockBans });
 
 		render(BanListModal, {
 			props: {
@@ -178,7 +178,7 @@ describe('BanListModal', () => {
 	});
 
 	it('displays ban count in footer', async () => {
-		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue(mockBans);
+		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue({ items: mockBans });
 
 		render(BanListModal, {
 			props: {
@@ -194,7 +194,7 @@ describe('BanListModal', () => {
 	});
 
 	it('shows singular form for one banned user', async () => {
-		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue([mockBans[0]]);
+		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue({ items: [mockBans[0]] });
 
 		render(BanListModal, {
 			props: {
@@ -210,7 +210,7 @@ describe('BanListModal', () => {
 	});
 
 	it('filters bans by search query', async () => {
-		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue(mockBans);
+		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue({ items: mockBans });
 
 		render(BanListModal, {
 			props: {
@@ -234,7 +234,7 @@ describe('BanListModal', () => {
 	});
 
 	it('shows no results message when search has no matches', async () => {
-		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue(mockBans);
+		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue({ items: mockBans });
 
 		render(BanListModal, {
 			props: {
@@ -258,7 +258,7 @@ describe('BanListModal', () => {
 	});
 
 	it('does not show unban button when canUnban is false', async () => {
-		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue(mockBans);
+		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue({ items: mockBans });
 
 		render(BanListModal, {
 			props: {
@@ -277,7 +277,7 @@ describe('BanListModal', () => {
 	});
 
 	it('shows unban buttons when canUnban is true', async () => {
-		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue(mockBans);
+		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue({ items: mockBans });
 
 		render(BanListModal, {
 			props: {
@@ -295,7 +295,7 @@ describe('BanListModal', () => {
 	});
 
 	it('unbans user when clicking unban button', async () => {
-		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue(mockBans);
+		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue({ items: mockBans });
 		(api.delete as ReturnType<typeof vi.fn>).mockResolvedValue(undefined);
 
 		const handleUnban = vi.fn();
@@ -329,7 +329,7 @@ describe('BanListModal', () => {
 	});
 
 	it('shows error message when unban fails', async () => {
-		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue(mockBans);
+		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue({ items: mockBans });
 		(api.delete as ReturnType<typeof vi.fn>).mockRejectedValue(
 			new ApiError('Permission denied', 403)
 		);
@@ -397,7 +397,7 @@ describe('BanListModal', () => {
 	});
 
 	it('dispatches close event when modal is closed', async () => {
-		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue([]);
+		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue({ items: [] });
 
 		const { container } = render(BanListModal, {
 			props: {
@@ -422,7 +422,7 @@ describe('BanListModal', () => {
 	});
 
 	it('displays usernames when no display name', async () => {
-		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue([mockBans[1]]);
+		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue({ items: [mockBans[1]] });
 
 		render(BanListModal, {
 			props: {
@@ -439,7 +439,7 @@ describe('BanListModal', () => {
 	});
 
 	it('displays both display name and username when both exist', async () => {
-		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue([mockBans[0]]);
+		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue({ items: [mockBans[0]] });
 
 		render(BanListModal, {
 			props: {
@@ -456,7 +456,7 @@ describe('BanListModal', () => {
 	});
 
 	it('has accessible search input', async () => {
-		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue([]);
+		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue({ items: [] });
 
 		render(BanListModal, {
 			props: {
@@ -471,7 +471,7 @@ describe('BanListModal', () => {
 	});
 
 	it('has accessible unban buttons', async () => {
-		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue([mockBans[0]]);
+		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue({ items: [mockBans[0]] });
 
 		render(BanListModal, {
 			props: {
@@ -489,7 +489,7 @@ describe('BanListModal', () => {
 	});
 
 	it('shows unbanning state on button when unbanning', async () => {
-		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue(mockBans);
+		(api.get as ReturnType<typeof vi.fn>).mockResolvedValue({ items: mockBans });
 		(api.delete as ReturnType<typeof vi.fn>).mockImplementation(
 			() => new Promise((resolve) => setTimeout(resolve, 100))
 		);
@@ -177,7 +177,7 @@ function createGatewayStore() {
         break;
 
       case Op.RECONNECT:
-        reconnect();
+        handleReconnect(msg.d as { session_id?: string; resume_gateway_url?: string } | undefined);
         break;
 
       case Op.INVALID_SESSION:
@@ -227,6 +227,8 @@ function createGatewayStore() {
           $device: 'web',
         },
         compress: false,
+        // Delta sync: dispatches carry sequence numbers, for resume
+        capabilities: 2,
         presence: {
           status: 'online',
           afk: false,
@@ -361,6 +363,18 @@ function createGatewayStore() {
     }
   }
 
+  // A draining node hands its session off, to be resumed on the next connection
+  function handleReconnect(data?: { session_id?: string; resume_gateway_url?: string }) {
+    if (data?.session_id) {
+      state.update(s => ({
+        ...s,
+        sessionId: data.session_id!,
+        resumeUrl: data.resume_gateway_url || s.resumeUrl,
+      }));
+    }
+    reconnect();
+  }
+
   function reconnect() {
     ws?.close();
     setTimeout(() => {
@@ -1,5 +1,6 @@
 import { writable } from 'svelte/store';
 import { api, ApiError } from '$lib/api';
+import type { ReactionEmoji } from '$lib/types';
 
 export interface Message {
 	id: string;
@@ -59,7 +60,7 @@ export interface Attachment {
 }
 
 export interface Reaction {
-	emoji: string;
+	emoji: ReactionEmoji;
 	count: number;
 	me: boolean;
 }
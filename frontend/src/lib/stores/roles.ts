@@ -1,5 +1,6 @@
 import { writable, derived } from 'svelte/store';
 import { api, ApiError } from '$lib/api';
+import { getAllPages } from '$lib/utils/pagination';
 
 export interface Role {
 	id: string;
@@ -92,7 +93,7 @@ export async function loadServerRoles(serverId: string) {
 	rolesError.set(null);
 	
 	try {
-		const data = await api.get<BackendRole[]>(`/servers/${serverId}/roles`);
+		const data = await getAllPages<BackendRole>(`/servers/${serverId}/roles`);
 		const roles = data.map(normalizeRole);
 		
 		rolesMap.update(map => {
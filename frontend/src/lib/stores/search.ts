@@ -25,6 +25,8 @@ export interface SearchResponse {
 	messages: SearchResult[];
 	total_count: number;
 	has_more: boolean;
+	next_cursor?: string;
+	prev_cursor?: string;
 }
 
 export interface SearchSuggestion {
@@ -77,7 +79,7 @@ interface SearchState {
 	hasMore: boolean;
 	loading: boolean;
 	error: string | null;
-	offset: number;
+	cursor: string | null;
 	// Type-ahead suggestions
 	suggestions: SearchSuggestion[];
 	suggestionsLoading: boolean;
@@ -214,7 +216,7 @@ const initialState: SearchState = {
 	hasMore: false,
 	loading: false,
 	error: null,
-	offset: 0,
+	cursor: null,
 	suggestions: [],
 	suggestionsLoading: false,
 	showSuggestions: false,
@@ -277,7 +279,7 @@ function createSearchStore() {
 				...state,
 				filters: { ...state.filters, ...filters },
 				results: [],
-				offset: 0,
+				cursor: null,
 				totalCount: 0,
 				hasMore: false,
 			}));
@@ -438,7 +440,9 @@ function createSearchStore() {
 				const params = new URLSearchParams();
 				params.set('q', fullQuery);
 				params.set('limit', '25');
-				params.set('offset', append ? String(state.offset) : '0');
+				if (append && state.cursor) {
+					params.set('cursor', state.cursor);
+				}
 
 				if (state.filters.guild_id) {
 					params.set('guild_id', state.filters.guild_id);
@@ -492,7 +496,7 @@ function createSearchStore() {
 					results: append ? [...s.results, ...response.messages] : response.messages,
 					totalCount: response.total_count,
 					hasMore: response.has_more,
-					offset: append ? s.offset + response.messages.length : response.messages.length,
+					cursor: response.next_cursor ?? null,
 					loading: false,
 				}));
 			} catch (error) {
@@ -524,7 +528,7 @@ function createSearchStore() {
 						...derivedFilters,
 					},
 					results: [],
-					offset: 0,
+					cursor: null,
 					totalCount: 0,
 					hasMore: false,
 				};
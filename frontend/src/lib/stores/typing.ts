@@ -57,6 +57,12 @@ function createTypingStore() {
     });
   });
 
+  // Clear typing when the server says the user stopped or their indicator lapsed
+  gateway.on('TYPING_STOP', (data) => {
+    const event = data as { channel_id: string; user_id: string };
+    clearUserTyping(event.channel_id, event.user_id);
+  });
+
   // Clear typing when user sends a message
   gateway.on('MESSAGE_CREATE', (data) => {
     const message = data as { channel_id: string; author_id: string };
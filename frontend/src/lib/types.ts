@@ -132,12 +132,25 @@ export interface Attachment {
 
 export interface Reaction {
 	message_id: string;
-	emoji: string;
+	emoji: ReactionEmoji;
 	count: number;
 	me: boolean;
 	user_ids?: string[];
 }
 
+// Unicode emoji have no id; deleted custom emoji have no name
+export interface ReactionEmoji {
+	id: string | null;
+	name: string;
+	animated?: boolean;
+	url?: string;
+}
+
+// reactionEmojiKey is how the reaction routes refer to an emoji
+export function reactionEmojiKey(emoji: ReactionEmoji): string {
+	return emoji.id ?? emoji.name;
+}
+
 // Member types
 export interface Member {
 	userId: string;
@@ -171,6 +184,7 @@ export type WSEventType =
 	| 'MEMBER_UPDATE'
 	| 'PRESENCE_UPDATE'
 	| 'TYPING_START'
+	| 'TYPING_STOP'
 	| 'READY';
 
 export interface WSEvent<T = unknown> {
@@ -0,0 +1,26 @@
+import { api } from '$lib/api';
+
+/**
+ * A page of a keyset-paginated list. Pass a cursor back as `cursor` to get
+ * the page on either side; each is missing when there's nothing that way.
+ */
+export interface Page<T> {
+	items: T[];
+	next_cursor?: string;
+	prev_cursor?: string;
+}
+
+/**
+ * Read every page of a keyset-paginated list, in order
+ */
+export async function getAllPages<T>(path: string): Promise<T[]> {
+	const items: T[] = [];
+	const separator = path.includes('?') ? '&' : '?';
+	let page = await api.get<Page<T>>(path);
+	items.push(...page.items);
+	while (page.next_cursor) {
+		page = await api.get<Page<T>>(`${path}${separator}cursor=${encodeURIComponent(page.next_cursor)}`);
+		items.push(...page.items);
+	}
+	return items;
+}